@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/repository"
+)
+
+// restoreBrokerSessions caches the broker sessions kept in the state store
+// before the server restarted, so the Nordnet keepalive renews them and
+// scheduled syncs go on without a new login.
+func restoreBrokerSessions(connRepo *repository.BrokerConnectionRepository) {
+	conns, err := connRepo.GetAllActive()
+	if err != nil {
+		log.Printf("Error loading broker connections: %v", err)
+		return
+	}
+
+	restored := map[string]int{}
+	for _, conn := range conns {
+		switch conn.BrokerType {
+		case "saxo":
+			if saxo.GetCachedSession(conn.ID) != nil {
+				restored["Saxo"]++
+			}
+		case "nordnet":
+			if nordnet.GetCachedSession(conn.ID) != nil {
+				restored["Nordnet"]++
+			}
+		}
+	}
+	for broker, n := range restored {
+		log.Printf("Restored %d %s session(s)", n, broker)
+	}
+}
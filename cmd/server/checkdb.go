@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"wealth_tracker/internal/config"
+	"wealth_tracker/internal/database"
+)
+
+// runCheckDB implements "server check-db [-repair]". It reports integrity
+// problems in the configured database and, with -repair, fixes orphaned
+// rows. It returns 1 if problems remain.
+func runCheckDB(cfg *config.Config, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("check-db", flag.ContinueOnError)
+	fs.SetOutput(out)
+	repair := fs.Bool("repair", false, "delete or clear rows pointing at deleted parents")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := database.New(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if *repair {
+		repaired, err := db.RepairOrphans()
+		if err != nil {
+			fmt.Fprintf(out, "Repair failed: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "Repaired %d rows\n", repaired)
+	}
+
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		fmt.Fprintf(out, "Integrity check failed: %v\n", err)
+		return 1
+	}
+	for _, issue := range report.Issues {
+		row := ""
+		if issue.Table != "" {
+			row = fmt.Sprintf(" %s #%d:", issue.Table, issue.RowID)
+		}
+		fmt.Fprintf(out, "[%s]%s %s\n", issue.Check, row, issue.Message)
+	}
+	if report.OK() {
+		fmt.Fprintln(out, "No issues found")
+		return 0
+	}
+	fmt.Fprintf(out, "%d issues, %d repairable with -repair\n", len(report.Issues), report.FixableCount())
+	return 1
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/sync"
+)
+
+// credentialCheckInterval is how often broker logins are checked for expiry
+// and staleness.
+const credentialCheckInterval = time.Hour
+
+// startCredentialChecks reminds connection owners of expiring or stale broker
+// logins now and then every credentialCheckInterval until the returned stop
+// function is called. Only the job leader sends reminders.
+func startCredentialChecks(svc *sync.Service, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(credentialCheckInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				remindStaleCredentials(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// remindStaleCredentials sends the due reminders and logs the outcome.
+func remindStaleCredentials(svc *sync.Service) {
+	reminded, err := svc.RemindStaleCredentials(time.Now())
+	if err != nil {
+		log.Printf("[Credentials] Checking broker logins failed: %v", err)
+		return
+	}
+	if reminded > 0 {
+		log.Printf("[Credentials] Reminded the owners of %d connection(s) to log in again", reminded)
+	}
+}
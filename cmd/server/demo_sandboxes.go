@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/demo"
+)
+
+// sandboxCleanupInterval is how often expired demo sandboxes are deleted.
+const sandboxCleanupInterval = 10 * time.Minute
+
+// startSandboxCleanup deletes expired demo sandbox users now and then every
+// sandboxCleanupInterval until the returned stop function is called. Does
+// nothing outside demo mode, where seeder is nil, or on instances other than
+// the job leader.
+func startSandboxCleanup(seeder *demo.Seeder, leader *jobLeader) (stop func()) {
+	if seeder == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(sandboxCleanupInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				deleteExpiredSandboxes(seeder)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// deleteExpiredSandboxes deletes expired demo sandboxes and logs the result.
+func deleteExpiredSandboxes(seeder *demo.Seeder) {
+	n, err := seeder.DeleteExpiredSandboxes(time.Now())
+	if err != nil {
+		log.Printf("[Demo] Deleting expired sandboxes failed: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Demo] Deleted %d expired sandbox(es)", n)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// digestCheckInterval is how often due email digests are looked for.
+const digestCheckInterval = time.Hour
+
+// startDigests sends the email digests that are due now and then every
+// digestCheckInterval until the returned stop function is called. It does
+// nothing if email is not configured. Only the job leader sends digests.
+func startDigests(svc *services.DigestService, leader *jobLeader) (stop func()) {
+	if svc == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(digestCheckInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				sendDigests(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// sendDigests sends the due digests and logs the outcome.
+func sendDigests(svc *services.DigestService) {
+	sent, err := svc.SendDue(time.Now())
+	if err != nil {
+		log.Printf("[Digest] Sending digests failed: %v", err)
+		return
+	}
+	if sent > 0 {
+		log.Printf("[Digest] Sent %d digest(s)", sent)
+	}
+}
@@ -0,0 +1,4185 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/config"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/dates"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/release"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+	"wealth_tracker/internal/store"
+)
+
+// TestMain runs the end-to-end tests from the repository root, where the
+// templates and static files are resolved from.
+func TestMain(m *testing.M) {
+	if err := os.Chdir(filepath.Join("..", "..")); err != nil {
+		fmt.Fprintf(os.Stderr, "changing to repository root: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// clientIP gives every test client its own address, so the per-IP rate
+// limit on the auth endpoints does not carry over between tests.
+var clientIP atomic.Int32
+
+// testServer is the full application served by httptest, backed by a
+// fresh SQLite database in a temp dir.
+type testServer struct {
+	*httptest.Server
+	app *App
+}
+
+// testClient is a browser-like client with its own cookie jar.
+type testClient struct {
+	t      *testing.T
+	srv    *testServer
+	client *http.Client
+}
+
+// newTestServer boots the application with a migrated, empty database.
+// Options can adjust the configuration before the app is created.
+func newTestServer(t *testing.T, opts ...func(*config.Config)) *testServer {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "e2e.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	cfg := &config.Config{
+		Host:                 "127.0.0.1",
+		SessionSecret:        "test-secret",
+		SessionMaxAge:        3600,
+		EncryptionSecret:     "test-encryption-secret-32-chars!",
+		SyncMaxDeletePercent: 50,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	app, err := newApp(cfg, db)
+	if err != nil {
+		t.Fatalf("creating app: %v", err)
+	}
+
+	srv := &testServer{Server: httptest.NewServer(app.router), app: app}
+	t.Cleanup(srv.Close)
+	if app.mockBroker != nil {
+		t.Cleanup(app.mockBroker.Close)
+	}
+	return srv
+}
+
+// createUser adds a user who does not need to change their password.
+func (s *testServer) createUser(t *testing.T, email, password string) *models.User {
+	t.Helper()
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	user := &models.User{
+		Email:           email,
+		PasswordHash:    hash,
+		Name:            "Test User",
+		DefaultCurrency: "DKK",
+		NumberFormat:    "da",
+		Theme:           "dark",
+	}
+	id, err := s.app.userRepo.Create(user)
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	user.ID = id
+	return user
+}
+
+// newClient returns a client that does not follow redirects, so tests can
+// assert on them.
+func (s *testServer) newClient(t *testing.T) *testClient {
+	t.Helper()
+	jar, _ := cookiejar.New(nil)
+	ip := fmt.Sprintf("10.0.0.%d", clientIP.Add(1))
+	return &testClient{
+		t:   t,
+		srv: s,
+		client: &http.Client{
+			Jar:       jar,
+			Transport: forwardedFor{ip: ip},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// forwardedFor sets X-Forwarded-For on every request.
+type forwardedFor struct{ ip string }
+
+func (f forwardedFor) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Forwarded-For", f.ip)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// get performs a GET request and returns the response with its body read.
+func (c *testClient) get(path string) (*http.Response, string) {
+	c.t.Helper()
+	resp, err := c.client.Get(c.srv.URL + path)
+	if err != nil {
+		c.t.Fatalf("GET %s: %v", path, err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// post submits a form and returns the response with its body read.
+func (c *testClient) post(path string, form url.Values) (*http.Response, string) {
+	c.t.Helper()
+	resp, err := c.client.PostForm(c.srv.URL+path, form)
+	if err != nil {
+		c.t.Fatalf("POST %s: %v", path, err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// postFile submits a multipart form with the file in its "file" field.
+func (c *testClient) postFile(path, filename string, data []byte, fields url.Values) (*http.Response, string) {
+	c.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for key, values := range fields {
+		for _, v := range values {
+			mw.WriteField(key, v)
+		}
+	}
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write(data)
+	mw.Close()
+	resp, err := c.client.Post(c.srv.URL+path, mw.FormDataContentType(), &buf)
+	if err != nil {
+		c.t.Fatalf("POST %s: %v", path, err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// postJSON sends v as a JSON request body and returns the response with its
+// body read.
+func (c *testClient) postJSON(path string, v any) (*http.Response, string) {
+	c.t.Helper()
+	data, _ := json.Marshal(v)
+	resp, err := c.client.Post(c.srv.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatalf("POST %s: %v", path, err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// doJSON sends a request with v as its JSON body, or none if v is nil, and
+// returns the response with its body read.
+func (c *testClient) doJSON(method, path string, v any) (*http.Response, string) {
+	c.t.Helper()
+	var body io.Reader
+	if v != nil {
+		data, _ := json.Marshal(v)
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.srv.URL+path, body)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp, readBody(c.t, resp)
+}
+
+// login signs in and fails the test unless it redirects to the dashboard.
+func (c *testClient) login(email, password string) {
+	c.t.Helper()
+	resp, _ := c.post("/login", url.Values{"email": {email}, "password": {password}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/dashboard" {
+		c.t.Fatalf("login: status %d, location %q; want redirect to /dashboard", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(body)
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: status %d; want %d", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want)
+	}
+}
+
+func TestE2E_Login(t *testing.T) {
+	srv := newTestServer(t)
+	srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+
+	// Protected pages redirect to login
+	resp, _ := c.get("/dashboard")
+	if resp.StatusCode != http.StatusSeeOther || !strings.HasPrefix(resp.Header.Get("Location"), "/login") {
+		t.Fatalf("unauthenticated /dashboard: status %d, location %q; want redirect to /login", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// Wrong password re-renders the login page
+	resp, body := c.post("/login", url.Values{"email": {"user@example.com"}, "password": {"wrong"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Invalid email or password") {
+		t.Error("login with wrong password did not show an error")
+	}
+
+	c.login("user@example.com", "password123")
+	resp, _ = c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+
+	// Logging out ends the session
+	resp, _ = c.post("/logout", nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = c.get("/dashboard")
+	expectStatus(t, resp, http.StatusSeeOther)
+}
+
+func TestE2E_DefaultAdminMustChangePassword(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.newClient(t)
+
+	resp, _ := c.post("/login", url.Values{"email": {"admin@localhost"}, "password": {"changeme"}})
+	if resp.Header.Get("Location") != "/change-password" {
+		t.Fatalf("default admin login redirected to %q; want /change-password", resp.Header.Get("Location"))
+	}
+	resp, _ = c.get("/dashboard")
+	if resp.Header.Get("Location") != "/change-password" {
+		t.Errorf("/dashboard before password change redirected to %q; want /change-password", resp.Header.Get("Location"))
+	}
+}
+
+func TestE2E_AccountsTransactionsAndExport(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	// Create an account
+	resp, _ := c.post("/accounts", url.Values{"name": {"Savings"}, "currency": {"DKK"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, body := c.get("/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Savings") {
+		t.Fatal("new account missing from /accounts")
+	}
+
+	accounts, err := srv.app.accountRepo.GetByUserID(user.ID)
+	if err != nil || len(accounts) != 1 {
+		t.Fatalf("GetByUserID() = %d accounts, %v; want 1", len(accounts), err)
+	}
+	accountID := fmt.Sprint(accounts[0].ID)
+
+	// Duplicate names are rejected
+	resp, body = c.post("/accounts", url.Values{"name": {"Savings"}, "currency": {"DKK"}})
+	if resp.StatusCode == http.StatusSeeOther || !strings.Contains(body, "already exists") {
+		t.Errorf("duplicate account: status %d; want error message", resp.StatusCode)
+	}
+
+	// Add two transactions; the balance accumulates
+	for _, tx := range []struct{ amount, description string }{
+		{"1000", "Salary"},
+		{"-250.5", "Groceries"},
+	} {
+		resp, _ = c.post("/transactions", url.Values{
+			"account_id":       {accountID},
+			"amount":           {tx.amount},
+			"description":      {tx.description},
+			"transaction_date": {"2024-03-01"},
+		})
+		expectStatus(t, resp, http.StatusSeeOther)
+	}
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(accounts[0].ID); balance != 749.5 {
+		t.Errorf("balance = %.2f; want 749.50", balance)
+	}
+	resp, body = c.get("/transactions")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Groceries") {
+		t.Error("transaction missing from /transactions")
+	}
+
+	// Rename the account
+	resp, _ = c.post("/accounts/"+accountID, url.Values{"name": {"Emergency fund"}, "currency": {"DKK"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	if acc, _ := srv.app.accountRepo.GetByID(accounts[0].ID); acc == nil || acc.Name != "Emergency fund" {
+		t.Errorf("account after update = %+v; want name Emergency fund", acc)
+	}
+
+	// Export includes both transactions
+	resp, body = c.get("/export/transactions")
+	expectStatus(t, resp, http.StatusOK)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("export Content-Type = %q; want text/csv", ct)
+	}
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing export CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Errorf("export has %d rows; want header + 2", len(records))
+	}
+	if !strings.Contains(body, "Emergency fund") || !strings.Contains(body, "Salary") {
+		t.Error("export missing account name or transaction description")
+	}
+
+	resp, _ = c.get("/export/all")
+	expectStatus(t, resp, http.StatusOK)
+
+	// Chart data exports use the same history as the charts
+	resp, body = c.get("/export/net-worth")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "2024-03-01,749.50") {
+		t.Errorf("net worth export = %q; want 749.50 on 2024-03-01", body)
+	}
+	resp, body = c.get("/export/balances?account=" + accountID)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "2024-03-01,Emergency fund,DKK,749.50") {
+		t.Errorf("balance export = %q; want the account's end-of-day balance", body)
+	}
+	resp, _ = c.get("/export/allocation?type=currency")
+	expectStatus(t, resp, http.StatusOK)
+
+	// Delete the account
+	resp, _ = c.post("/accounts/"+accountID, url.Values{"_method": {"DELETE"}})
+	if resp.StatusCode >= 400 {
+		t.Fatalf("delete account: status %d", resp.StatusCode)
+	}
+	if acc, _ := srv.app.accountRepo.GetByID(accounts[0].ID); acc != nil {
+		t.Error("account still exists after delete")
+	}
+}
+
+func TestE2E_OtherUsersAccountsAreHidden(t *testing.T) {
+	srv := newTestServer(t)
+	owner := srv.createUser(t, "owner@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+
+	id, err := srv.app.accountRepo.Create(&models.Account{UserID: owner.ID, Name: "Private", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("other@example.com", "password123")
+
+	resp, _ := c.post("/accounts/"+fmt.Sprint(id), url.Values{"name": {"Hijacked"}, "currency": {"DKK"}})
+	if resp.StatusCode < 400 {
+		t.Errorf("updating another user's account: status %d; want 4xx", resp.StatusCode)
+	}
+	resp, _ = c.post("/transactions", url.Values{"account_id": {fmt.Sprint(id)}, "amount": {"1"}})
+	if resp.StatusCode < 400 {
+		t.Errorf("adding transaction to another user's account: status %d; want 4xx", resp.StatusCode)
+	}
+	_, body := c.get("/export/transactions")
+	if strings.Contains(body, "Private") {
+		t.Error("export includes another user's account")
+	}
+	resp, _ = c.get("/export/balances?account=" + fmt.Sprint(id))
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_BrokerSyncWithoutJavaScript(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) {
+		cfg.MockBroker = true
+		cfg.IsDevelopment = true
+	})
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	base := "/settings/connections/" + fmt.Sprint(connID)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	_, body := c.get(base + "/accounts")
+	if !strings.Contains(body, "<noscript>") || !strings.Contains(body, `name="nojs"`) {
+		t.Fatal("mapping page has no fallback fetch form")
+	}
+
+	resp, _ := c.post(base+"/fetch-accounts", url.Values{"nojs": {"1"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != base+"/task" {
+		t.Fatalf("fetch accounts: status %d, location %q; want redirect to the task page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	body = c.waitForTask(base+"/task", `name="mapping_`)
+	start := strings.Index(body, `name="mapping_`) + len(`name="mapping_`)
+	externalID := body[start : start+strings.Index(body[start:], `"`)]
+
+	resp, _ = c.post(base+"/accounts", url.Values{"mapping_" + externalID: {fmt.Sprint(accountID)}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	resp, _ = c.post(base+"/sync", url.Values{"nojs": {"1"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != base+"/task" {
+		t.Fatalf("sync: status %d, location %q; want redirect to the task page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	c.waitForTask(base+"/task", "Sync complete")
+
+	holdings, err := srv.app.holdingRepo.GetByAccountID(accountID)
+	if err != nil || len(holdings) == 0 {
+		t.Errorf("holdings after sync = %d, %v; want some", len(holdings), err)
+	}
+}
+
+func TestE2E_WhatsNewShownOnceAfterUpgrade(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	srv.createUser(t, "user@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+
+	// Users created before the release started are existing users
+	if _, err := srv.app.db.Exec(`UPDATE app_versions SET first_started_at = ?`, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("backdating users: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+	resp, _ := c.get("/dashboard")
+	if resp.Header.Get("Location") != "/whats-new" {
+		t.Fatalf("/dashboard after upgrade redirected to %q; want /whats-new", resp.Header.Get("Location"))
+	}
+	resp, body := c.get("/whats-new")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Version "+release.Version) {
+		t.Error("what's new page does not show the current release")
+	}
+	resp, _ = c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+
+	// Dismissing for all users lets the other user through
+	resp, _ = c.post("/admin/whats-new/dismiss", nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	other := srv.newClient(t)
+	other.login("user@example.com", "password123")
+	resp, _ = other.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+	resp, _ = other.post("/admin/whats-new/dismiss", nil)
+	if resp.StatusCode < 400 {
+		t.Errorf("non-admin dismissing notice: status %d; want 4xx", resp.StatusCode)
+	}
+}
+
+func TestRecordRelease_ReportsNewerSchema(t *testing.T) {
+	srv := newTestServer(t)
+	versionRepo := repository.NewAppVersionRepository(srv.app.db)
+
+	if issues := recordRelease(versionRepo); len(issues) != 0 {
+		t.Fatalf("recordRelease() on restart = %v; want none", issues)
+	}
+	if err := versionRepo.Record("99.0.0", database.SchemaVersion()+1); err != nil {
+		t.Fatalf("recording newer release: %v", err)
+	}
+	if issues := recordRelease(versionRepo); len(issues) != 1 || !strings.Contains(issues[0], "newer release") {
+		t.Errorf("recordRelease() after downgrade = %v; want the newer schema reported", issues)
+	}
+}
+
+func TestE2E_AdminIntegrityCheck(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.get("/admin/integrity")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "No issues found") {
+		t.Error("integrity check of a clean database reported issues")
+	}
+	resp, _ = c.post("/admin/integrity/repair", nil)
+	if resp.Header.Get("Location") != "/admin/integrity?repaired=0" {
+		t.Errorf("repair redirected to %q; want /admin/integrity?repaired=0", resp.Header.Get("Location"))
+	}
+}
+
+func TestE2E_AdminRetentionPolicies(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	if _, err := srv.app.db.Exec(`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('USD', 'DKK', '2020-01-01', 6.5), ('USD', 'DKK', ?, 6.9)`, time.Now().Format("2006-01-02")); err != nil {
+		t.Fatalf("inserting rates: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.get("/admin/retention")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Exchange rate history") {
+		t.Error("retention page does not list the exchange rate history")
+	}
+
+	resp, _ = c.post("/admin/retention", url.Values{"table": {"audit_log"}, "thin_after_days": {"30"}})
+	if resp.Header.Get("Location") != "/admin/retention?error=cannot_thin" {
+		t.Errorf("thinning the audit log redirected to %q; want error=cannot_thin", resp.Header.Get("Location"))
+	}
+	resp, _ = c.post("/admin/retention", url.Values{"table": {"currency_rate_history"}, "keep_days": {"365"}})
+	if resp.Header.Get("Location") != "/admin/retention?saved=currency_rate_history" {
+		t.Fatalf("saving a policy redirected to %q", resp.Header.Get("Location"))
+	}
+	resp, _ = c.post("/admin/retention/run", nil)
+	if resp.Header.Get("Location") != "/admin/retention?deleted=1" {
+		t.Errorf("run redirected to %q; want /admin/retention?deleted=1", resp.Header.Get("Location"))
+	}
+
+	srv.createUser(t, "user@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("user@example.com", "password123")
+	resp, _ = other.post("/admin/retention/run", nil)
+	if resp.StatusCode < 400 {
+		t.Errorf("non-admin running retention: status %d; want 4xx", resp.StatusCode)
+	}
+}
+
+func TestE2E_AdminTaxParameters(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.get("/admin/tax-parameters")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Built in") || !strings.Contains(body, `value="166200"`) {
+		t.Error("tax parameters page does not show the built-in figures")
+	}
+
+	year := strconv.Itoa(time.Now().Year())
+	form := url.Values{
+		"year":                    {year},
+		"ask_max_deposit":         {"999999"},
+		"stock_gain_threshold":    {"79400"},
+		"stock_gain_low_rate":     {"42"},
+		"stock_gain_high_rate":    {"27"},
+		"ask_tax_rate":            {"17"},
+		"pension_withdrawal_rate": {"37"},
+	}
+	resp, _ = c.post("/admin/tax-parameters", form)
+	if resp.Header.Get("Location") != "/admin/tax-parameters?error=rate_order" {
+		t.Errorf("swapped rates redirected to %q; want error=rate_order", resp.Header.Get("Location"))
+	}
+	form.Set("stock_gain_low_rate", "27")
+	form.Set("stock_gain_high_rate", "42")
+	resp, _ = c.post("/admin/tax-parameters", form)
+	if resp.Header.Get("Location") != "/admin/tax-parameters?saved="+year {
+		t.Fatalf("saving tax parameters redirected to %q", resp.Header.Get("Location"))
+	}
+
+	// Tax tips use this year's ASK ceiling
+	resp, body = c.get("/api/portfolio/rebalance?type=category")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "999.999 kr") {
+		t.Errorf("tax tips do not use the saved ASK ceiling: %s", body)
+	}
+
+	srv.createUser(t, "user@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("user@example.com", "password123")
+	resp, _ = other.post("/admin/tax-parameters/"+year+"/delete", nil)
+	if resp.StatusCode < 400 {
+		t.Errorf("non-admin deleting tax parameters: status %d; want 4xx", resp.StatusCode)
+	}
+}
+
+func TestE2E_AdminFeatureFlags(t *testing.T) {
+	features := services.Features
+	t.Cleanup(func() { services.Features = features })
+	services.Features = []services.Feature{
+		{Name: "new_analyzer", Description: "New analyzer"},
+		{Name: "saxo_transactions", Description: "Saxo transactions"},
+	}
+
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.FeatureFlags = []string{"saxo_transactions"} })
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	srv.createUser(t, "tester@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.get("/admin/features")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "new_analyzer") || !strings.Contains(body, "(FEATURE_FLAGS)") {
+		t.Error("feature flags page does not list the features and their defaults")
+	}
+
+	resp, _ = c.post("/admin/features/users", url.Values{"name": {"new_analyzer"}, "email": {"nobody@example.com"}, "state": {"on"}})
+	if resp.Header.Get("Location") != "/admin/features?error=user_not_found" {
+		t.Errorf("unknown user redirected to %q; want error=user_not_found", resp.Header.Get("Location"))
+	}
+	resp, _ = c.post("/admin/features", url.Values{"name": {"teleport"}, "state": {"on"}})
+	if resp.Header.Get("Location") != "/admin/features?error=unknown_feature" {
+		t.Errorf("unknown feature redirected to %q; want error=unknown_feature", resp.Header.Get("Location"))
+	}
+	resp, _ = c.post("/admin/features/users", url.Values{"name": {"new_analyzer"}, "email": {"tester@example.com"}, "state": {"on"}})
+	if resp.Header.Get("Location") != "/admin/features?saved=new_analyzer" {
+		t.Fatalf("turning a feature on for a user redirected to %q", resp.Header.Get("Location"))
+	}
+	_, body = c.get("/admin/features")
+	if !strings.Contains(body, "tester@example.com") {
+		t.Error("feature flags page does not list the user's setting")
+	}
+
+	other := srv.newClient(t)
+	other.login("tester@example.com", "password123")
+	resp, _ = other.post("/admin/features", url.Values{"name": {"new_analyzer"}, "state": {"on"}})
+	if resp.StatusCode < 400 {
+		t.Errorf("non-admin setting a feature flag: status %d; want 4xx", resp.StatusCode)
+	}
+
+	services.Features = nil
+	_, body = c.get("/admin/features")
+	if !strings.Contains(body, "No experimental features") {
+		t.Error("feature flags page without features does not say so")
+	}
+}
+
+func TestE2E_AdminLoginLink(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.LoginLinks = true })
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	user := srv.createUser(t, "locked@example.com", "forgotten123")
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.post(fmt.Sprintf("/admin/users/%d/login-link", user.ID), url.Values{})
+	expectStatus(t, resp, http.StatusOK)
+	match := regexp.MustCompile(`/login/link/[0-9a-f]{64}`).FindString(body)
+	if match == "" {
+		t.Fatal("user detail page does not show the login link")
+	}
+
+	u := srv.newClient(t)
+	resp, _ = u.get(match)
+	expectStatus(t, resp, http.StatusOK)
+	resp, _ = u.post(match, url.Values{})
+	if resp.Header.Get("Location") != "/change-password" {
+		t.Fatalf("login link redirected to %q; want /change-password", resp.Header.Get("Location"))
+	}
+	resp, body = u.get("/change-password")
+	expectStatus(t, resp, http.StatusOK)
+	if strings.Contains(body, `name="current_password"`) {
+		t.Error("change password page asks a login link user for their current password")
+	}
+	resp, _ = u.post("/change-password", url.Values{
+		"new_password":     {"Correct-Horse-Battery-9"},
+		"confirm_password": {"Correct-Horse-Battery-9"},
+	})
+	if resp.Header.Get("Location") != "/dashboard" {
+		t.Errorf("setting a new password redirected to %q; want /dashboard", resp.Header.Get("Location"))
+	}
+
+	again := srv.newClient(t)
+	_, body = again.post(match, url.Values{})
+	if !strings.Contains(body, "already used") {
+		t.Error("login link worked twice")
+	}
+	again.login("locked@example.com", "Correct-Horse-Battery-9")
+	resp, _ = again.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+
+	for _, action := range []string{"admin.login_link_created", "user.login_link_used"} {
+		var n int
+		srv.app.db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action = ? AND user_id = ?`, action, user.ID).Scan(&n)
+		if n != 1 {
+			t.Errorf("%d audit entries of %s, want 1", n, action)
+		}
+	}
+}
+
+func TestE2E_AdminLoginLink_Disabled(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	user := srv.createUser(t, "locked@example.com", "forgotten123")
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	_, body := c.get(fmt.Sprintf("/admin/users/%d", user.ID))
+	if strings.Contains(body, "/login-link") {
+		t.Error("user detail page offers login links while they are disabled")
+	}
+	resp, _ := c.post(fmt.Sprintf("/admin/users/%d/login-link", user.ID), url.Values{})
+	expectStatus(t, resp, http.StatusNotFound)
+	resp, _ = srv.newClient(t).get("/login/link/" + strings.Repeat("0", 64))
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_StaleBrokerLoginWarning(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.NordnetAuthStaleDays = 7 })
+	user := srv.createUser(t, "user@example.com", "password123")
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	if _, body := c.get("/dashboard"); strings.Contains(body, "has not synced successfully") {
+		t.Error("dashboard warns of a connection created just now")
+	}
+
+	if _, err := srv.app.db.Exec(`UPDATE broker_connections SET last_success_at = ? WHERE id = ?`, time.Now().Add(-10*24*time.Hour), connID); err != nil {
+		t.Fatalf("backdating last sync: %v", err)
+	}
+	_, body := c.get("/dashboard")
+	if !strings.Contains(body, "Nordnet has not synced successfully for 10 days") || !strings.Contains(body, fmt.Sprintf("/settings/connections/%d", connID)) {
+		t.Error("dashboard does not warn of the stale Nordnet login")
+	}
+
+	// Owners are reminded once a day
+	reminded, err := srv.app.syncService.RemindStaleCredentials(time.Now())
+	if err != nil || reminded != 1 {
+		t.Fatalf("RemindStaleCredentials() = %d, %v; want 1 reminder", reminded, err)
+	}
+	if reminded, _ = srv.app.syncService.RemindStaleCredentials(time.Now().Add(time.Hour)); reminded != 0 {
+		t.Errorf("reminded %d connection(s) again within a day", reminded)
+	}
+	if reminded, _ = srv.app.syncService.RemindStaleCredentials(time.Now().Add(25 * time.Hour)); reminded != 1 {
+		t.Errorf("reminded %d connection(s) a day later; want 1", reminded)
+	}
+
+	// A successful sync clears the warning
+	if err := srv.app.brokerConnRepo.UpdateSyncStatus(connID, "success", ""); err != nil {
+		t.Fatalf("updating sync status: %v", err)
+	}
+	if _, body = c.get("/dashboard"); strings.Contains(body, "has not synced successfully") {
+		t.Error("dashboard still warns after a successful sync")
+	}
+}
+
+func TestE2E_AdminSyncAll(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	if _, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: admin.ID, BrokerType: "nordnet", Country: "dk", IsActive: true}); err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, _ := c.post("/admin/sync-all", url.Values{"spread_minutes": {"-5"}})
+	if loc := resp.Header.Get("Location"); loc != "/admin?sync_all_error=invalid_spread" {
+		t.Errorf("negative spread redirected to %q", loc)
+	}
+	resp, _ = c.post("/admin/sync-all", url.Values{"spread_minutes": {"0"}})
+	if loc := resp.Header.Get("Location"); loc != "/admin" {
+		t.Fatalf("sync all redirected to %q; want /admin", loc)
+	}
+
+	// Without a cached session the Nordnet connection needs a MitID login
+	_, body := c.get("/admin")
+	if !strings.Contains(body, "0 scheduled, 0 synced, 0 failed, 1 skipped needing a login") {
+		t.Error("admin dashboard does not show the skipped connection")
+	}
+
+	// Regular users cannot start it
+	srv.createUser(t, "user@example.com", "password123")
+	user := srv.newClient(t)
+	user.login("user@example.com", "password123")
+	if resp, _ := user.post("/admin/sync-all", nil); resp.StatusCode == http.StatusSeeOther && resp.Header.Get("Location") == "/admin" {
+		t.Error("a regular user started a sync of all connections")
+	}
+}
+
+func TestSyncAll_CLISyncsConnectionsWithoutLogin(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "user@example.com", PasswordHash: "hash", Name: "User"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	connRepo := repository.NewBrokerConnectionRepository(db)
+	for _, brokerType := range []string{"nordnet", "psd2"} {
+		if _, err := connRepo.Create(&models.BrokerConnection{UserID: userID, BrokerType: brokerType, Country: "dk", IsActive: true}); err != nil {
+			t.Fatalf("creating connection: %v", err)
+		}
+	}
+	db.Close()
+
+	cfg := &config.Config{DBPath: dbPath, SessionSecret: "test-secret", EncryptionSecret: "test-encryption-secret-32-chars!", MockBroker: true, IsDevelopment: true}
+	var out strings.Builder
+	if code := runSyncAll(cfg, nil, &out); code != 0 || !strings.Contains(out.String(), "Synced 1, failed 0, skipped 1") {
+		t.Errorf("sync-all = %d, %q; want the Nordnet connection synced and the bank skipped", code, out.String())
+	}
+}
+
+func TestCheckDB_ReportsAndRepairsOrphans(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.Exec(`PRAGMA foreign_keys = OFF`)
+	if _, err := db.Exec(`INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (42, 1, 1, '2024-01-01')`); err != nil {
+		t.Fatalf("inserting orphan: %v", err)
+	}
+	db.Close()
+
+	cfg := &config.Config{DBPath: dbPath}
+	var out strings.Builder
+	if code := runCheckDB(cfg, nil, &out); code != 1 || !strings.Contains(out.String(), "transactions #1") {
+		t.Fatalf("check-db = %d, %q; want the orphan reported", code, out.String())
+	}
+	out.Reset()
+	if code := runCheckDB(cfg, []string{"-repair"}, &out); code != 0 || !strings.Contains(out.String(), "Repaired 1 rows") {
+		t.Errorf("check-db -repair = %d, %q; want the orphan repaired", code, out.String())
+	}
+}
+
+func TestE2E_SyncDiagnosticsDownload(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Username: "secret-user", CPR: "0101011234", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	historyID, err := srv.app.syncHistoryRepo.Start(connID, "full")
+	if err != nil {
+		t.Fatalf("starting sync: %v", err)
+	}
+	srv.app.syncHistoryRepo.Fail(historyID, `login failed: {"session_key": "abc123"}`)
+	srv.app.syncHistoryRepo.SaveDiagnostics(historyID, `{"requests":[{"method":"POST","url":"https://www.nordnet.dk/api/2/login","status":401}]}`)
+	path := fmt.Sprintf("/settings/connections/%d/history/%d/diagnostics", connID, historyID)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get(path)
+	expectStatus(t, resp, http.StatusOK)
+	zr, err := zip.NewReader(strings.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("reading diagnostics zip: %v", err)
+	}
+	var contents strings.Builder
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+		rc, _ := f.Open()
+		io.Copy(&contents, rc)
+		rc.Close()
+	}
+	for _, name := range []string{"sync.json", "connection.json", "requests.json", "app.json", "README.txt"} {
+		if !names[name] {
+			t.Errorf("diagnostics zip has no %s", name)
+		}
+	}
+	if !strings.Contains(contents.String(), "api/2/login") {
+		t.Error("diagnostics zip has no request trail")
+	}
+	for _, secret := range []string{"secret-user", "0101011234", "abc123"} {
+		if strings.Contains(contents.String(), secret) {
+			t.Errorf("diagnostics zip leaks %q", secret)
+		}
+	}
+
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(path)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_EncryptedExportImport(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+	if _, err := srv.app.goalRepo.Create(&models.Goal{UserID: user.ID, Name: "House deposit", TargetAmount: 500000, TargetCurrency: "DKK"}); err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+	passphrase := "correct horse battery"
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.post("/export/all", url.Values{"passphrase": {passphrase}, "passphrase_confirm": {"something else"}})
+	if resp.Header.Get("Location") != "/settings?error=passphrase_mismatch" {
+		t.Errorf("mismatched passphrases redirected to %q; want the settings error", resp.Header.Get("Location"))
+	}
+	resp, backup := c.post("/export/all", url.Values{"passphrase": {passphrase}, "passphrase_confirm": {passphrase}})
+	expectStatus(t, resp, http.StatusOK)
+	if strings.Contains(backup, "House deposit") {
+		t.Fatal("encrypted export contains plain text data")
+	}
+
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	_, body := other.postFile("/goals/import", "backup.json.enc", []byte(backup), url.Values{"passphrase": {"wrong horse battery"}})
+	if !strings.Contains(body, "wrong passphrase") {
+		t.Error("import with the wrong passphrase was not rejected")
+	}
+	resp, _ = other.postFile("/goals/import", "backup.json.enc", []byte(backup), url.Values{"passphrase": {passphrase}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	_, body = other.get("/goals")
+	if !strings.Contains(body, "House deposit") {
+		t.Error("goal from the encrypted export was not imported")
+	}
+}
+
+func TestE2E_EncryptedBackupRestore(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Rainy day fund", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 4200, BalanceAfter: 4200, TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	passphrase := "correct horse battery"
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, backup := c.post("/export/all", url.Values{"passphrase": {passphrase}, "passphrase_confirm": {passphrase}})
+	expectStatus(t, resp, http.StatusOK)
+
+	oc := srv.newClient(t)
+	oc.login("other@example.com", "password123")
+	for passphrase, location := range map[string]string{
+		"":                    "/settings?error=backup_passphrase_required",
+		"wrong horse battery": "/settings?error=backup_passphrase",
+		passphrase:            "/settings?success=backup_restored",
+	} {
+		resp, _ := oc.postFile("/export/all/restore", "backup.json.enc", []byte(backup), url.Values{"passphrase": {passphrase}})
+		if got := resp.Header.Get("Location"); got != location {
+			t.Errorf("restore with passphrase %q redirected to %q; want %q", passphrase, got, location)
+		}
+	}
+
+	accounts, _ := srv.app.accountRepo.GetByUserID(other.ID)
+	if len(accounts) != 1 || accounts[0].Name != "Rainy day fund" {
+		t.Fatalf("accounts after restore = %+v; want the backed up account", accounts)
+	}
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(accounts[0].ID); balance != 4200 {
+		t.Errorf("restored balance = %v; want 4200", balance)
+	}
+}
+
+func TestE2E_UnusualBalanceNeedsConfirmation(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) {
+		cfg.BalanceAnomalyPercent = 50
+	})
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	path := fmt.Sprintf("/accounts/%d/balance", accountID)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.post(path, url.Values{"balance": {"125000"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	// A missing digit is caught and can be saved anyway
+	resp, body := c.post(path, url.Values{"balance": {"12500"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "This balance looks unusual") || !strings.Contains(body, `name="confirm_anomaly"`) {
+		t.Fatal("unusual balance was not held for confirmation")
+	}
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(accountID); balance != 125000 {
+		t.Fatalf("balance before confirming = %.0f; want 125000", balance)
+	}
+	resp, _ = c.post(path, url.Values{"balance": {"12500"}, "confirm_anomaly": {"1"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(accountID); balance != 12500 {
+		t.Errorf("balance after confirming = %.0f; want 12500", balance)
+	}
+}
+
+func TestE2E_WatchlistConvertToHolding(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.postJSON("/api/portfolio/watchlist", map[string]any{"symbol": "novo-b", "name": "Novo Nordisk", "price": 700})
+	expectStatus(t, resp, http.StatusOK)
+	var item models.WatchlistItem
+	if err := json.Unmarshal([]byte(body), &item); err != nil {
+		t.Fatalf("decoding watchlist item: %v", err)
+	}
+	if item.Symbol != "NOVO-B" || item.CurrentPrice == nil || *item.CurrentPrice != 700 {
+		t.Fatalf("added watchlist item = %s; want NOVO-B at 700", body)
+	}
+	_, body = c.get("/tools/portfolio-analyzer")
+	if !strings.Contains(body, "Watchlist") {
+		t.Error("portfolio analyzer does not show the watchlist")
+	}
+
+	// Another user can neither see nor convert the item
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	convertPath := fmt.Sprintf("/api/portfolio/watchlist/%d/convert", item.ID)
+	resp, _ = other.postJSON(convertPath, map[string]any{"account_id": accountID, "quantity": 5, "price": 690})
+	expectStatus(t, resp, http.StatusNotFound)
+	if _, body = other.get("/api/portfolio/watchlist"); strings.Contains(body, "NOVO-B") {
+		t.Error("watchlist of another user is visible")
+	}
+
+	resp, _ = c.postJSON(convertPath, map[string]any{"account_id": accountID, "quantity": 5, "price": 690})
+	expectStatus(t, resp, http.StatusOK)
+	holdings, err := srv.app.holdingRepo.GetByAccountID(accountID)
+	if err != nil || len(holdings) != 1 {
+		t.Fatalf("holdings after buying = %v, %v; want one", holdings, err)
+	}
+	if h := holdings[0]; h.Symbol != "NOVO-B" || h.Quantity != 5 || h.AvgPrice != 690 || h.ExternalID != "" {
+		t.Errorf("holding = %+v; want 5 manual NOVO-B at 690", h)
+	}
+	if _, body = c.get("/api/portfolio/watchlist"); strings.Contains(body, "NOVO-B") {
+		t.Error("bought instrument is still on the watchlist")
+	}
+}
+
+func TestE2E_AccountAPIKeyIsScopedToItsAccount(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	meterID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Electricity", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	savingsID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.post("/settings/api-keys", url.Values{"name": {"Meter script"}, "account_id": {fmt.Sprint(meterID)}})
+	expectStatus(t, resp, http.StatusOK)
+	key := regexp.MustCompile(`wt_[0-9a-f]{64}`).FindString(body)
+	if key == "" {
+		t.Fatal("created API key is not shown")
+	}
+	if _, body = c.get("/settings/api-keys"); strings.Contains(body, key) {
+		t.Error("API key is shown again after creation")
+	}
+
+	resp, body = srv.apiPost(t, key, fmt.Sprintf("/api/v1/accounts/%d/balance", meterID), map[string]any{"balance": 412.5})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `"description":"Balance update"`) {
+		t.Errorf("balance update response = %s; want the default description", body)
+	}
+	resp, _ = srv.apiPost(t, key, fmt.Sprintf("/api/v1/accounts/%d/transactions", meterID), map[string]any{"amount": -12.5, "description": "Usage"})
+	expectStatus(t, resp, http.StatusCreated)
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(meterID); balance != 400 {
+		t.Errorf("meter balance = %.2f; want 400", balance)
+	}
+
+	// The key cannot touch other accounts, and a session cannot replace it
+	resp, _ = srv.apiPost(t, key, fmt.Sprintf("/api/v1/accounts/%d/balance", savingsID), map[string]any{"balance": 1})
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = c.postJSON(fmt.Sprintf("/api/v1/accounts/%d/balance", meterID), map[string]any{"balance": 1})
+	expectStatus(t, resp, http.StatusUnauthorized)
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(savingsID); balance != 0 {
+		t.Errorf("savings balance = %.2f; want untouched", balance)
+	}
+
+	// Revoked keys stop working
+	keys, err := srv.app.apiKeyRepo.GetByUserID(user.ID)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("API keys = %v, %v; want one", keys, err)
+	}
+	resp, _ = c.post(fmt.Sprintf("/settings/api-keys/%d/delete", keys[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = srv.apiPost(t, key, fmt.Sprintf("/api/v1/accounts/%d/balance", meterID), map[string]any{"balance": 1})
+	expectStatus(t, resp, http.StatusUnauthorized)
+}
+
+// apiPost sends v as JSON to path, authenticated by an API key.
+func (s *testServer) apiPost(t *testing.T, key, path string, v any) (*http.Response, string) {
+	t.Helper()
+	data, _ := json.Marshal(v)
+	req, err := http.NewRequest(http.MethodPost, s.URL+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	return resp, readBody(t, resp)
+}
+
+func TestE2E_PortfolioShowsBrokerReportedReturns(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Saxo Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "saxo", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	mappingID, err := srv.app.mappingRepo.Create(&models.AccountMapping{ConnectionID: connID, LocalAccountID: accountID, ExternalAccountID: "acc-key", AutoSync: true})
+	if err != nil {
+		t.Fatalf("creating mapping: %v", err)
+	}
+
+	now := time.Now()
+	for _, bal := range []struct {
+		date    time.Time
+		balance float64
+	}{{now.AddDate(-2, 0, 0), 1000}, {now.AddDate(0, -1, -1), 1000}, {now, 1100}} {
+		if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: bal.balance, BalanceAfter: bal.balance, TransactionDate: bal.date}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+	if err := srv.app.brokerPerfRepo.Upsert(&models.BrokerPerformance{MappingID: mappingID, Period: "Month", ReturnFraction: 0.0425, FetchedAt: now}); err != nil {
+		t.Fatalf("storing performance: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	_, body := c.get("/tools/portfolio-analyzer")
+	for _, want := range []string{"Broker-reported Returns", "Saxo Depot", "&#43;4.25%", "&#43;10.00%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("portfolio page does not show %q", want)
+		}
+	}
+
+	// Other users do not see the account's returns
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	if _, body = other.get("/tools/portfolio-analyzer"); strings.Contains(body, "Saxo Depot") {
+		t.Error("another user sees the broker-reported returns")
+	}
+}
+
+func TestE2E_GoalDetailShowsBurnUp(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	now := time.Now()
+	deadline := now.AddDate(0, 0, 70)
+	goalID, err := srv.app.goalRepo.Create(&models.Goal{UserID: user.ID, Name: "House deposit", TargetAmount: 5000, TargetCurrency: "DKK", Deadline: &deadline})
+	if err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	// Progress recorded three weeks ago, before the latest deposit
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, TransactionDate: now.AddDate(0, 0, -21)}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	if n, err := srv.app.goalSnapshotService.Record(user.ID, now.AddDate(0, 0, -21)); err != nil || n != 1 {
+		t.Fatalf("Record() = %d, %v; want 1 goal recorded", n, err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 600, BalanceAfter: 1600, TransactionDate: now}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	path := fmt.Sprintf("/goals/%d", goalID)
+	resp, body := c.get(path)
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"House deposit", "burnUpChart", "Actual Pace", "Required Pace", "Behind schedule"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("goal page does not show %q", want)
+		}
+	}
+
+	// Goals of other users are not found
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(path)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_DatesFollowLocaleAndTimeZone(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	date := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: date}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	_, body := c.get("/transactions")
+	if !strings.Contains(body, "31-01-2024") {
+		t.Error("transactions page does not show the Danish date 31-01-2024")
+	}
+
+	settings := url.Values{"name": {"User"}, "number_format": {"en"}, "timezone": {"Mars/Olympus"}}
+	_, body = c.post("/settings", settings)
+	if !strings.Contains(body, "Unknown time zone") {
+		t.Error("unknown time zone was accepted")
+	}
+	settings.Set("timezone", "America/New_York")
+	resp, _ := c.post("/settings", settings)
+	expectStatus(t, resp, http.StatusOK)
+	updated, err := srv.app.userRepo.GetByID(user.ID)
+	if err != nil {
+		t.Fatalf("getting user: %v", err)
+	}
+	if updated.Timezone != "America/New_York" {
+		t.Errorf("Timezone = %q, want America/New_York", updated.Timezone)
+	}
+
+	_, body = c.get("/transactions")
+	if !strings.Contains(body, "01/31/2024") {
+		t.Error("transactions page does not show the English date 01/31/2024")
+	}
+}
+
+func TestE2E_PeriodStartDay(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Salary", Currency: "DKK", IsActive: true})
+	periodStart := dates.PeriodStart(time.Now(), 25)
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 30000, BalanceAfter: 30000, TransactionDate: periodStart}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	settings := url.Values{"name": {"User"}, "period_start_day": {"31"}}
+	_, body := c.post("/settings", settings)
+	if !strings.Contains(body, "Statement periods must start on a day from 1 to 28") {
+		t.Error("period start day 31 was accepted")
+	}
+	settings.Set("period_start_day", "25")
+	resp, _ := c.post("/settings", settings)
+	expectStatus(t, resp, http.StatusOK)
+	updated, err := srv.app.userRepo.GetByID(user.ID)
+	if err != nil {
+		t.Fatalf("getting user: %v", err)
+	}
+	if updated.PeriodStartDay != 25 {
+		t.Errorf("PeriodStartDay = %d, want 25", updated.PeriodStartDay)
+	}
+
+	// The salary on the 25th is in the current period's cash flow
+	_, body = c.get("/transactions")
+	if want := "Cash flow by category &middot; " + dates.FormatDate(periodStart, "da") + " &ndash; "; !strings.Contains(body, want) {
+		t.Errorf("transactions page does not show the cash flow of the period from %s", dates.FormatDate(periodStart, "da"))
+	}
+	if !strings.Contains(body, "+30.000") {
+		t.Error("cash flow of the period does not include the salary")
+	}
+}
+
+func TestE2E_CashFlowConvertsCurrencies(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	categoryID, _ := srv.app.categoryRepo.Create(&models.Category{UserID: user.ID, Name: "Dividends", Color: "#6366f1"})
+	dkkID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Checking", Currency: "DKK", IsActive: true})
+	eurID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Euro Depot", Currency: "EUR", IsActive: true})
+	if _, err := srv.app.db.Exec(`INSERT INTO currency_rates (from_currency, to_currency, rate, fetched_at) VALUES ('EUR', 'DKK', 7.5, ?)`, time.Now()); err != nil {
+		t.Fatalf("storing rate: %v", err)
+	}
+	for _, txn := range []*models.Transaction{
+		{AccountID: dkkID, Amount: 250, BalanceAfter: 250, CategoryID: &categoryID, TransactionDate: time.Now()},
+		{AccountID: eurID, Amount: 100, BalanceAfter: 100, CategoryID: &categoryID, TransactionDate: time.Now()},
+	} {
+		if _, err := srv.app.transactionRepo.Create(txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	_, body := c.get("/transactions")
+	if !strings.Contains(body, "+1.000") {
+		t.Error("cash flow does not sum 250 kr. and 100 EUR at 7.5 into 1.000 kr.")
+	}
+}
+
+func TestE2E_DeleteMappedAccountAsksAboutMappings(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	oldID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Old Nordnet", Currency: "DKK", IsActive: true})
+	newID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "New Nordnet", Currency: "DKK", IsActive: true})
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Username: "user", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	if _, err := srv.app.mappingRepo.Create(&models.AccountMapping{ConnectionID: connID, LocalAccountID: oldID, ExternalAccountID: "1", ExternalAccountName: "Depot", AutoSync: true}); err != nil {
+		t.Fatalf("creating mapping: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	path := fmt.Sprintf("/accounts/%d", oldID)
+
+	// A plain delete goes to the confirmation page instead
+	resp, _ := c.post(path, url.Values{"_method": {"DELETE"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != path+"/delete" {
+		t.Fatalf("delete: status %d, location %q; want redirect to the confirmation page", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	resp, body := c.get(path + "/delete")
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"Depot", "Detach", "Sync into another account", "New Nordnet"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("confirmation page does not show %q", want)
+		}
+	}
+
+	// Confirming without choosing what happens to the mapping keeps the account
+	resp, _ = c.post(path, url.Values{"_method": {"DELETE"}, "confirm": {"1"}})
+	if resp.StatusCode != http.StatusSeeOther || !strings.Contains(resp.Header.Get("Location"), "error=choose_mappings") {
+		t.Fatalf("delete without a choice: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if acc, _ := srv.app.accountRepo.GetByID(oldID); acc == nil {
+		t.Fatal("account deleted without a choice for its mapping")
+	}
+
+	// Re-pointing moves the mapping to the other account
+	resp, _ = c.post(path, url.Values{"_method": {"DELETE"}, "confirm": {"1"}, "mappings": {"move"}, "target_id": {fmt.Sprint(newID)}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/accounts" {
+		t.Fatalf("delete with move: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if acc, _ := srv.app.accountRepo.GetByID(oldID); acc != nil {
+		t.Error("account still exists after delete")
+	}
+	mapping, err := srv.app.mappingRepo.GetByExternalAccountID(connID, "1")
+	if err != nil || mapping == nil || mapping.LocalAccountID != newID {
+		t.Errorf("mapping = %+v, %v; want it re-pointed to account %d", mapping, err, newID)
+	}
+
+	// Detaching removes the mapping
+	resp, _ = c.post(fmt.Sprintf("/accounts/%d", newID), url.Values{"_method": {"DELETE"}, "confirm": {"1"}, "mappings": {"detach"}})
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/accounts" {
+		t.Fatalf("delete with detach: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if mappings, _ := srv.app.mappingRepo.GetByConnectionID(connID); len(mappings) != 0 {
+		t.Errorf("connection has %d mappings after detaching; want 0", len(mappings))
+	}
+}
+
+func TestE2E_DemoSandboxPerVisitor(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.DemoMode = true })
+
+	visitor := srv.newClient(t)
+	_, body := visitor.get("/login")
+	if !strings.Contains(body, "Try demo") {
+		t.Fatal("login page does not offer the demo in demo mode")
+	}
+	resp, _ := visitor.post("/demo", nil)
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/dashboard" {
+		t.Fatalf("try demo: status %d, location %q; want redirect to /dashboard", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	resp, body = visitor.get("/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Nordnet Aktiedepot") {
+		t.Error("sandbox does not have the demo accounts")
+	}
+
+	// Each visitor gets a user of their own next to the shared demo user
+	other := srv.newClient(t)
+	other.post("/demo", nil)
+	users, err := srv.app.userRepo.GetAll()
+	if err != nil {
+		t.Fatalf("getting users: %v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("%d users after two demo visits; want the demo user and two sandboxes", len(users))
+	}
+
+	// Expired sandboxes are deleted with their data
+	n, err := srv.app.demoSeeder.DeleteExpiredSandboxes(time.Now().Add(3 * time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteExpiredSandboxes() = %d, %v; want 2 deleted", n, err)
+	}
+	if demoUser, _ := srv.app.userRepo.GetByEmail("demo@example.com"); demoUser == nil {
+		t.Error("shared demo user was deleted with the sandboxes")
+	}
+	resp, _ = visitor.get("/accounts")
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("expired sandbox: status %d; want redirect to login", resp.StatusCode)
+	}
+}
+
+func TestE2E_DemoSandboxOnlyInDemoMode(t *testing.T) {
+	srv := newTestServer(t)
+	c := srv.newClient(t)
+	_, body := c.get("/login")
+	if strings.Contains(body, "Try demo") {
+		t.Error("login page offers the demo outside demo mode")
+	}
+	resp, _ := c.post("/demo", nil)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_ConnectionSyncWindow(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Username: "user", CPR: "0101011234", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	path := fmt.Sprintf("/settings/connections/%d", connID)
+	resp, body := c.get(path + "/edit")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Sync Schedule") {
+		t.Error("connection form does not show the sync schedule")
+	}
+
+	form := url.Values{"username": {"user"}, "cpr": {"0101011234"}, "sync_window_start": {"18"}, "sync_window_end": {"18"}}
+	_, body = c.post(path+"/edit", form)
+	if !strings.Contains(body, "must not start and end at the same hour") {
+		t.Error("empty sync window was accepted")
+	}
+
+	form.Set("sync_window_end", "24")
+	form.Set("skip_weekends", "1")
+	resp, _ = c.post(path+"/edit", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	conn, _ := srv.app.brokerConnRepo.GetByID(connID)
+	if conn.SyncWindowStart != 18 || conn.SyncWindowEnd != 24 || !conn.SkipWeekends {
+		t.Errorf("sync window = %d-%d, skip weekends %v; want 18-24 on weekdays", conn.SyncWindowStart, conn.SyncWindowEnd, conn.SkipWeekends)
+	}
+
+	_, body = c.get(path)
+	if !strings.Contains(body, "from 18:00 until 24:00 market time on trading days") {
+		t.Error("connection page does not show the sync window")
+	}
+}
+
+func TestE2E_LiabilityInterestAccrual(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, _ := c.post("/accounts", url.Values{"name": {"Mortgage"}, "currency": {"DKK"}, "is_liability": {"1"}, "interest_rate": {"6"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = c.post("/accounts", url.Values{"name": {"Savings"}, "currency": {"DKK"}, "is_liability": {"0"}, "interest_rate": {"2"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	accounts, err := srv.app.accountRepo.GetByUserID(user.ID)
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("getting accounts: %v (%d accounts)", err, len(accounts))
+	}
+	mortgage, savings := accounts[0], accounts[1]
+	if mortgage.InterestRate != 6 || savings.InterestRate != 0 {
+		t.Errorf("interest rates = %.1f, %.1f; want 6 for the liability and 0 for the asset", mortgage.InterestRate, savings.InterestRate)
+	}
+
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{
+		AccountID: mortgage.ID, Amount: 100000, BalanceAfter: 100000,
+		TransactionDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	posted, err := srv.app.interestService.AccrueAll(time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil || posted != 1 {
+		t.Fatalf("AccrueAll() = %d, %v; want 1 posting", posted, err)
+	}
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(mortgage.ID); balance != 100500 {
+		t.Errorf("mortgage balance = %.2f; want 100500 after January's interest", balance)
+	}
+
+	_, body := c.get("/accounts")
+	if !strings.Contains(body, "6% p.a.") || !strings.Contains(body, "interest posted") {
+		t.Error("accounts page does not show the interest rate and interest posted")
+	}
+
+	_, body = c.post("/accounts", url.Values{"name": {"Car loan"}, "is_liability": {"1"}, "interest_rate": {"-1"}})
+	if !strings.Contains(body, "Interest rate must be") {
+		t.Error("negative interest rate was accepted")
+	}
+	if !strings.Contains(body, "interest posted") {
+		t.Error("accounts page with an error does not show the interest posted")
+	}
+}
+
+func TestE2E_EmergencyFundCoverage(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	_, body := c.get("/dashboard")
+	if !strings.Contains(body, "Tag your cash categories as liquid") {
+		t.Error("dashboard does not explain how to get the emergency fund metric")
+	}
+
+	resp, _ := c.post("/categories", url.Values{"name": {"Cash"}, "liquidity": {"liquid"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	_, body = c.post("/categories", url.Values{"name": {"Other"}, "liquidity": {"sometimes"}})
+	if !strings.Contains(body, "Unknown liquidity") {
+		t.Error("unknown liquidity was accepted")
+	}
+	categories, err := srv.app.categoryRepo.GetByUserID(user.ID)
+	if err != nil || len(categories) != 1 || categories[0].Liquidity != models.LiquidityLiquid {
+		t.Fatalf("categories = %v, %v; want one liquid category", categories, err)
+	}
+
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, CategoryID: &categories[0].ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	// Two months of history spending 10,000 in total leave 30,000, which
+	// covers 6 months of 5,000
+	thisMonth := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, txn := range []models.Transaction{
+		{Amount: 40000, BalanceAfter: 40000, TransactionDate: thisMonth.AddDate(0, -2, 4)},
+		{Amount: -5000, BalanceAfter: 35000, TransactionDate: thisMonth.AddDate(0, -1, 4)},
+		{Amount: -5000, BalanceAfter: 30000, TransactionDate: thisMonth.AddDate(0, -1, 19)},
+	} {
+		txn.AccountID = accountID
+		if _, err := srv.app.transactionRepo.Create(&txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	_, body = c.get("/dashboard")
+	if !strings.Contains(body, "6.0 <span") || !strings.Contains(body, "months of expenses") {
+		t.Error("dashboard does not show 6.0 months of expenses covered")
+	}
+}
+
+func TestE2E_ImportNetWorthHistory(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 50000, BalanceAfter: 50000, TransactionDate: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	// A row for an account that does not exist rejects the whole file
+	_, body := c.postFile("/accounts/history", "history.csv", []byte("date;account;balance\n2015-01;;250000\n2023-12;Checking;1000\n"), nil)
+	if !strings.Contains(body, "nothing was imported") || !strings.Contains(body, "unknown account") {
+		t.Error("import with an unknown account was not rejected")
+	}
+	if n, _ := srv.app.transactionRepo.CountByAccountID(accountID); n != 1 {
+		t.Errorf("Savings has %d transactions after a rejected import; want 1", n)
+	}
+
+	resp, _ := c.postFile("/accounts/history", "history.csv", []byte("date;account;balance\n2015-01;;250000\n2023-12;Savings;45000\n"), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	_, body = c.get(resp.Header.Get("Location"))
+	if !strings.Contains(body, "Imported 2 balances into 2 accounts") {
+		t.Error("history page does not show the import outcome")
+	}
+
+	accounts, err := srv.app.accountRepo.GetByUserID(user.ID)
+	if err != nil || len(accounts) != 2 {
+		t.Fatalf("accounts = %v, %v; want Savings and the net worth history", accounts, err)
+	}
+	if n, _ := srv.app.transactionRepo.CountByAccountID(accountID); n != 2 {
+		t.Errorf("Savings has %d transactions; want the imported balance before the tracked one", n)
+	}
+}
+
+func TestE2E_AnalyticsExclusions(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	for _, h := range []models.Holding{
+		{Symbol: "DK0060534915", Name: "Novo Nordisk", Quantity: 10, CurrentValue: 30000, Currency: "DKK", InstrumentType: "stock"},
+		{Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 100, CurrentValue: 70000, Currency: "DKK", InstrumentType: "etf"},
+	} {
+		h.AccountID = accountID
+		if _, err := srv.app.holdingRepo.Create(&h); err != nil {
+			t.Fatalf("creating holding: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.postJSON("/api/portfolio/exclusions", map[string]any{"symbol": " dk0060534915 ", "reason": "Employer shares under lockup"})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `"symbol":"DK0060534915"`) {
+		t.Errorf("saved exclusion = %s; want the upper case ISIN", body)
+	}
+	resp, _ = c.postJSON("/api/portfolio/exclusions", map[string]any{"symbol": "ACCOUNT"})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	var composition services.PortfolioComposition
+	_, body = c.get("/api/portfolio/composition")
+	if err := json.Unmarshal([]byte(body), &composition); err != nil {
+		t.Fatalf("decoding composition: %v", err)
+	}
+	if composition.TotalValue != 70000 || composition.ExcludedValue != 30000 || composition.ExcludedPositions != 1 || len(composition.Holdings) != 1 {
+		t.Errorf("composition = %s; want only the ETF with 30000 excluded", body)
+	}
+	if composition.ConcentrationPct != 100 {
+		t.Errorf("concentration = %.1f%%; want 100%% without the excluded shares", composition.ConcentrationPct)
+	}
+
+	// Each analysis can include the excluded instruments again
+	_, body = c.get("/api/portfolio/composition?exclusions=off")
+	composition = services.PortfolioComposition{}
+	if err := json.Unmarshal([]byte(body), &composition); err != nil {
+		t.Fatalf("decoding composition: %v", err)
+	}
+	if composition.TotalValue != 100000 || composition.ExcludedPositions != 0 {
+		t.Errorf("composition without exclusions = %s; want the whole 100000", body)
+	}
+
+	_, body = c.get("/tools/portfolio-analyzer")
+	if !strings.Contains(body, "Excluded Instruments") {
+		t.Error("portfolio analyzer does not show the exclusions")
+	}
+
+	// Another user cannot remove the exclusion
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	if _, body = other.get("/api/portfolio/exclusions"); strings.Contains(body, "DK0060534915") {
+		t.Error("exclusions of another user are visible")
+	}
+}
+
+func TestE2E_HoldingsAPI(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	var accountIDs []int64
+	for _, name := range []string{"Nordnet", "Saxo"} {
+		id, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: name, Currency: "DKK", IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		accountIDs = append(accountIDs, id)
+	}
+	otherID, err := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Other", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	for _, h := range []models.Holding{
+		{AccountID: accountIDs[0], Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 100, CurrentValue: 70000, Currency: "DKK", InstrumentType: "etf"},
+		{AccountID: accountIDs[1], Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 50, CurrentValue: 35000, Currency: "DKK", InstrumentType: "etf"},
+		{AccountID: accountIDs[1], Symbol: "DK0060534915", Name: "Novo Nordisk", Quantity: 10, CurrentValue: 5000, Currency: "DKK", InstrumentType: "stock"},
+		{AccountID: otherID, Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 1, CurrentValue: 700, Currency: "DKK", InstrumentType: "etf"},
+	} {
+		if _, err := srv.app.holdingRepo.Create(&h); err != nil {
+			t.Fatalf("creating holding: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	query := func(path string) services.HoldingQueryResult {
+		t.Helper()
+		resp, body := c.get(path)
+		expectStatus(t, resp, http.StatusOK)
+		var result services.HoldingQueryResult
+		if err := json.Unmarshal([]byte(body), &result); err != nil {
+			t.Fatalf("decoding holdings: %v", err)
+		}
+		return result
+	}
+
+	result := query("/api/holdings")
+	if len(result.Holdings) != 3 || result.TotalValue != 110000 {
+		t.Fatalf("holdings = %+v; want the user's three holdings worth 110000", result)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].Quantity != 150 || result.Groups[0].Count != 2 || result.Groups[0].ValueInBase != 105000 {
+		t.Errorf("groups = %+v; want the ETF of both depots as one group", result.Groups)
+	}
+
+	result = query(fmt.Sprintf("/api/holdings?account=%d&type=ETF", accountIDs[1]))
+	if len(result.Holdings) != 1 || result.Holdings[0].Value != 35000 {
+		t.Errorf("Saxo ETFs = %+v; want the one ETF holding", result.Holdings)
+	}
+	result = query(fmt.Sprintf("/api/holdings?account=%d", otherID))
+	if len(result.Holdings) != 0 {
+		t.Errorf("another user's account returned %d holdings", len(result.Holdings))
+	}
+	result = query("/api/holdings?min_value=10000&group_by=account")
+	if len(result.Holdings) != 2 || len(result.Groups) != 2 || result.Groups[0].Name != "Nordnet" {
+		t.Errorf("holdings over 10000 by account = %+v", result)
+	}
+
+	resp, _ := c.get("/api/holdings?group_by=color")
+	expectStatus(t, resp, http.StatusBadRequest)
+	resp, _ = c.get("/api/holdings?min_value=lots")
+	expectStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestE2E_CompositionCombinesSameInstrument(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	holdings := map[string][]models.Holding{
+		"Nordnet": {{Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 100, CurrentValue: 40000, Currency: "DKK", InstrumentType: "etf"}},
+		"Saxo":    {{Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 50, CurrentValue: 20000, Currency: "DKK", InstrumentType: "etf"}},
+		"Pension": {{Symbol: "DK0060534915", Name: "Novo Nordisk", Quantity: 10, CurrentValue: 40000, Currency: "DKK", InstrumentType: "stock"}},
+	}
+	for name, hs := range holdings {
+		id, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: name, Currency: "DKK", IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		for _, h := range hs {
+			h.AccountID = id
+			if _, err := srv.app.holdingRepo.Create(&h); err != nil {
+				t.Fatalf("creating holding: %v", err)
+			}
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	var composition services.PortfolioComposition
+	_, body := c.get("/api/portfolio/composition")
+	if err := json.Unmarshal([]byte(body), &composition); err != nil {
+		t.Fatalf("decoding composition: %v", err)
+	}
+	if len(composition.Holdings) != 3 || composition.TotalPositions != 2 || len(composition.Positions) != 2 {
+		t.Fatalf("composition = %s; want three holdings as two positions", body)
+	}
+	if top := composition.TopHolding; top == nil || top.Symbol != "IE00B4L5Y983" || top.Percentage != 60 || len(top.Accounts) != 2 {
+		t.Errorf("top holding = %+v; want the ETF of both depots at 60%%", composition.TopHolding)
+	}
+}
+
+func TestE2E_HoldingLabels(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	for _, h := range []models.Holding{
+		{Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", Quantity: 100, CurrentValue: 60000, Currency: "DKK", InstrumentType: "etf"},
+		{Symbol: "DK0060534915", Name: "Novo Nordisk", Quantity: 10, CurrentValue: 30000, Currency: "DKK", InstrumentType: "stock"},
+		{Symbol: "US88160R1014", Name: "Tesla", Quantity: 5, CurrentValue: 10000, Currency: "DKK", InstrumentType: "stock"},
+	} {
+		h.AccountID = accountID
+		if _, err := srv.app.holdingRepo.Create(&h); err != nil {
+			t.Fatalf("creating holding: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.postJSON("/api/portfolio/labels", map[string]any{"symbol": "ie00b4l5y983", "label": " Core "})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `"label":"core"`) {
+		t.Errorf("saved label = %s; want the lower case label", body)
+	}
+	resp, _ = c.postJSON("/api/portfolio/labels", map[string]any{"symbol": "DK0060534915", "label": "satellite"})
+	expectStatus(t, resp, http.StatusOK)
+	resp, _ = c.postJSON("/api/portfolio/labels", map[string]any{"symbol": "CASH", "label": "core"})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	var composition services.PortfolioComposition
+	_, body = c.get("/api/portfolio/composition")
+	if err := json.Unmarshal([]byte(body), &composition); err != nil {
+		t.Fatalf("decoding composition: %v", err)
+	}
+	want := map[string]float64{"core": 60000, "satellite": 30000, "": 10000}
+	if len(composition.ByLabel) != len(want) {
+		t.Fatalf("by_label = %+v; want %v", composition.ByLabel, want)
+	}
+	for _, l := range composition.ByLabel {
+		if l.Value != want[l.Label] {
+			t.Errorf("label %q = %v; want %v", l.Label, l.Value, want[l.Label])
+		}
+	}
+
+	resp, _ = c.postJSON("/api/portfolio/targets", map[string]any{"target_type": "label", "target_key": "Core", "target_pct": 70})
+	expectStatus(t, resp, http.StatusOK)
+	var rebalance services.RebalanceRecommendation
+	_, body = c.get("/api/portfolio/rebalance?type=label&new_money=0")
+	if err := json.Unmarshal([]byte(body), &rebalance); err != nil {
+		t.Fatalf("decoding rebalancing: %v", err)
+	}
+	if len(rebalance.Recommendations) != 1 || rebalance.Recommendations[0].Action != "buy" || rebalance.Recommendations[0].Amount != 10000 {
+		t.Errorf("rebalancing = %s; want buying 10000 of core", body)
+	}
+
+	// Another user cannot see or remove the labels
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	if _, body = other.get("/api/portfolio/labels"); strings.Contains(body, "IE00B4L5Y983") {
+		t.Error("labels of another user are visible")
+	}
+}
+
+func TestE2E_YearlyStatement(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	for _, tx := range []struct{ amount, description, date string }{
+		{"10000", "Deposit", "2023-06-01"},
+		{"250", "Udbytte NOVO B", "2024-03-20"},
+		{"-1000", "Withdrawal", "2024-09-01"},
+		{"500", "Deposit", "2025-01-05"},
+	} {
+		resp, _ := c.post("/transactions", url.Values{
+			"account_id":       {fmt.Sprint(accountID)},
+			"amount":           {tx.amount},
+			"description":      {tx.description},
+			"transaction_date": {tx.date},
+		})
+		expectStatus(t, resp, http.StatusSeeOther)
+	}
+	day := func(s string) time.Time { d, _ := time.Parse("2006-01-02", s); return d }
+	if err := repository.NewHoldingAcquisitionRepository(srv.app.db).ReplaceSymbols(accountID, []*models.HoldingAcquisition{
+		{AccountID: accountID, Symbol: "NOVO B", TradeDate: day("2023-06-02"), Quantity: 10, Price: 800},
+		{AccountID: accountID, Symbol: "NOVO B", TradeDate: day("2024-08-30"), Quantity: -2, Price: 900},
+	}); err != nil {
+		t.Fatalf("storing acquisitions: %v", err)
+	}
+
+	resp, body := c.get(fmt.Sprintf("/export/statement?account=%d&year=2024", accountID))
+	expectStatus(t, resp, http.StatusOK)
+	reader := csv.NewReader(strings.NewReader(body))
+	reader.FieldsPerRecord = -1 // Sections have different numbers of fields
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("parsing statement CSV: %v", err)
+	}
+	rows := make(map[string][]string)
+	for _, record := range records {
+		rows[record[0]+" "+record[1]] = record
+	}
+	if r := rows["2024-01-01 Opening balance"]; r == nil || r[3] != "10000.00" {
+		t.Errorf("opening balance row = %v; want 10000.00", r)
+	}
+	if r := rows["2024-12-31 Closing balance"]; r == nil || r[3] != "9250.00" {
+		t.Errorf("closing balance row = %v; want 9250.00", r)
+	}
+	if rows["2025-01-05 Deposit"] != nil || rows["2024-09-01 Withdrawal"] == nil {
+		t.Error("statement should hold exactly the transactions of the year")
+	}
+	if r := rows["Total dividends "]; r == nil || r[2] != "250.00" {
+		t.Errorf("dividend total row = %v; want 250.00", r)
+	}
+	if r := rows["2024-08-30 NOVO B"]; r == nil || r[5] != "200.00" {
+		t.Errorf("realized gain row = %v; want a gain of 200.00", r)
+	}
+
+	resp, body = c.get(fmt.Sprintf("/export/statement?account=%d&year=2024&format=pdf", accountID))
+	expectStatus(t, resp, http.StatusOK)
+	if resp.Header.Get("Content-Type") != "application/pdf" || !strings.HasPrefix(body, "%PDF-") {
+		t.Error("format=pdf should return a PDF")
+	}
+
+	// Another user's account is not found
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(fmt.Sprintf("/export/statement?account=%d&year=2024", accountID))
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_MergeAccountIntoItselfIsRejected(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.post(fmt.Sprintf("/accounts/%d/merge", accountID), url.Values{"source_id": {fmt.Sprint(accountID)}})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	if account, _ := srv.app.accountRepo.GetByID(accountID); account == nil || !account.IsActive {
+		t.Errorf("account after merging into itself = %+v; want it untouched", account)
+	}
+}
+
+func TestE2E_AccountSnapshotOnDemand(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	novo := &models.Holding{AccountID: accountID, ExternalID: "1", Symbol: "DK0062498333", Name: "Novo Nordisk", Quantity: 20, CurrentValue: 16000, Currency: "DKK"}
+	if err := srv.app.holdingRepo.Upsert(novo); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.post(fmt.Sprintf("/accounts/%d/snapshots", accountID), url.Values{"note": {"Before selling Novo"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, fmt.Sprintf("/accounts/%d/snapshots?snapshot=", accountID)) {
+		t.Fatalf("redirect = %q; want the new snapshot", location)
+	}
+
+	// Sell most of the position after the snapshot
+	novo.Quantity, novo.CurrentValue = 5, 4000
+	if err := srv.app.holdingRepo.Upsert(novo); err != nil {
+		t.Fatalf("updating holding: %v", err)
+	}
+
+	resp, body := c.get(location)
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"Before selling Novo", "Novo Nordisk", "-15"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("snapshot page is missing %q", want)
+		}
+	}
+
+	// Another user cannot see or delete the snapshot
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(location)
+	if resp.StatusCode == http.StatusOK {
+		t.Error("another user can view the snapshot")
+	}
+	snapshotID := strings.TrimPrefix(location, fmt.Sprintf("/accounts/%d/snapshots?snapshot=", accountID))
+	resp, _ = other.post(fmt.Sprintf("/accounts/%d/snapshots/%s/delete", accountID, snapshotID), nil)
+	if resp.StatusCode == http.StatusSeeOther {
+		t.Error("another user can delete the snapshot")
+	}
+
+	resp, _ = c.post(fmt.Sprintf("/accounts/%d/snapshots/%s/delete", accountID, snapshotID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if _, body = c.get(fmt.Sprintf("/accounts/%d/snapshots", accountID)); !strings.Contains(body, "No snapshots yet") {
+		t.Error("snapshot still listed after deleting it")
+	}
+}
+
+func TestE2E_AdminAnonymizedExport(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	user := srv.createUser(t, "jens@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Nordnet Jens", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 123456, BalanceAfter: 123456, Description: "Salary", TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	// Only admins can export
+	c := srv.newClient(t)
+	c.login("jens@example.com", "password123")
+	resp, _ := c.post(fmt.Sprintf("/admin/users/%d/anonymized-export", user.ID), nil)
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("a regular user could export anonymized data")
+	}
+
+	c = srv.newClient(t)
+	c.login("admin@example.com", "password123")
+	resp, body := c.post(fmt.Sprintf("/admin/users/%d/anonymized-export", user.ID), nil)
+	expectStatus(t, resp, http.StatusOK)
+	for _, private := range []string{"jens@example.com", "Nordnet Jens", "Salary", "123456"} {
+		if strings.Contains(body, private) {
+			t.Errorf("anonymized export contains %q", private)
+		}
+	}
+	var export services.AnonymizedExport
+	if err := json.Unmarshal([]byte(body), &export); err != nil {
+		t.Fatalf("decoding export: %v", err)
+	}
+	if len(export.Accounts) != 1 || export.Accounts[0].ID != accountID || len(export.Transactions) != 1 || export.Accounts[0].Balance != export.Transactions[0].BalanceAfter {
+		t.Errorf("export = %s; want the account with its transaction", body)
+	}
+
+	resp, _ = c.post("/admin/users/9999/anonymized-export", nil)
+	expectStatus(t, resp, http.StatusNotFound)
+
+	entries, err := services.NewAuditService(srv.app.db).GetByUserID(user.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if len(entries) == 0 || entries[0].Action != services.AuditAdminAnonymizedExport || entries[0].ActorID != admin.ID {
+		t.Errorf("audit log = %+v; want the export recorded with the admin", entries)
+	}
+}
+
+func TestE2E_NotificationChannelTestSend(t *testing.T) {
+	// The webhook is on loopback, like a notification server on the LAN
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.NotifyPrivateNetworks = true })
+	user := srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+
+	var received atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "Wealth Tracker test notification") {
+			t.Errorf("webhook body = %s; want the test notification", body)
+		}
+		received.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.post("/settings/notifications", url.Values{"kind": {"gotify"}, "name": {"Gotify"}, "url": {webhook.URL}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "application token") {
+		t.Error("Gotify channel without a token was not rejected")
+	}
+	resp, _ = c.post("/settings/notifications", url.Values{"kind": {"discord"}, "name": {"Discord"}, "url": {webhook.URL + "/api/webhooks/1/secret"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	channels, err := srv.app.notifyChannelRepo.GetByUserID(user.ID)
+	if err != nil || len(channels) != 1 {
+		t.Fatalf("channels = %v, %v; want one", channels, err)
+	}
+	if _, body = c.get("/settings/notifications"); strings.Contains(body, "secret") {
+		t.Error("notifications page shows the webhook URL")
+	}
+
+	resp, body = c.post(fmt.Sprintf("/settings/notifications/%d/test", channels[0].ID), nil)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Test notification sent to Discord") || received.Load() != 1 {
+		t.Errorf("test send reached the webhook %d times; want 1", received.Load())
+	}
+
+	// Other users cannot use or delete the channel
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.post(fmt.Sprintf("/settings/notifications/%d/test", channels[0].ID), nil)
+	expectStatus(t, resp, http.StatusNotFound)
+	resp, _ = other.post(fmt.Sprintf("/settings/notifications/%d/delete", channels[0].ID), nil)
+	expectStatus(t, resp, http.StatusNotFound)
+
+	resp, _ = c.post(fmt.Sprintf("/settings/notifications/%d/delete", channels[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if channels, _ = srv.app.notifyChannelRepo.GetByUserID(user.ID); len(channels) != 0 {
+		t.Error("channel was not deleted")
+	}
+}
+
+func TestE2E_NotificationChannelRejectsPrivateURLs(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	for _, target := range []string{srv.URL + "/admin", "http://169.254.169.254/latest/meta-data"} {
+		resp, body := c.post("/settings/notifications", url.Values{"kind": {"slack"}, "name": {"Internal"}, "url": {target}})
+		expectStatus(t, resp, http.StatusOK)
+		if !strings.Contains(body, "private or local address") {
+			t.Errorf("channel to %s was not rejected", target)
+		}
+	}
+	if channels, _ := srv.app.notifyChannelRepo.GetByUserID(user.ID); len(channels) != 0 {
+		t.Errorf("got %d channels; want none", len(channels))
+	}
+}
+
+func TestE2E_AccountOrderingAndPinning(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	var ids []int64
+	for _, name := range []string{"Alpha Bank", "Beta Broker", "Gamma Pension"} {
+		id, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: name, Currency: "DKK", IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	otherID, err := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Other", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.postJSON("/accounts/order", map[string]any{"account_ids": []int64{ids[2], ids[0], ids[1]}})
+	expectStatus(t, resp, http.StatusNoContent)
+	resp, _ = c.post(fmt.Sprintf("/accounts/%d/pin", ids[1]), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	_, body := c.get("/accounts")
+	beta, gamma, alpha := strings.Index(body, "Beta Broker"), strings.Index(body, "Gamma Pension"), strings.Index(body, "Alpha Bank")
+	if beta < 0 || !(beta < gamma && gamma < alpha) {
+		t.Errorf("accounts page order: Beta at %d, Gamma at %d, Alpha at %d; want pinned Beta, then Gamma, then Alpha", beta, gamma, alpha)
+	}
+	_, body = c.get("/dashboard")
+	if !strings.Contains(body, "Pinned Accounts") || !strings.Contains(body, "Beta Broker") || strings.Contains(body, "Gamma Pension") {
+		t.Error("dashboard does not show exactly the pinned account")
+	}
+
+	// Other users' accounts cannot be reordered or pinned
+	resp, _ = c.postJSON("/accounts/order", map[string]any{"account_ids": []int64{otherID, ids[0]}})
+	expectStatus(t, resp, http.StatusNotFound)
+	resp, _ = c.post(fmt.Sprintf("/accounts/%d/pin", otherID), nil)
+	expectStatus(t, resp, http.StatusForbidden)
+	if account, _ := srv.app.accountRepo.GetByID(otherID); account.IsPinned || account.SortOrder != 0 {
+		t.Errorf("other user's account = %+v; want untouched", account)
+	}
+}
+
+func TestE2E_QuickAddTransaction(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	var ids []int64
+	for _, name := range []string{"Alpha Bank", "Beta Broker"} {
+		id, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: name, Currency: "DKK", IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	otherID, err := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Other", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, _ := c.postJSON("/api/transactions/quick-add", map[string]any{
+		"account_id": ids[1], "amount": 1500, "date": "2024-03-01", "description": "Salary",
+	})
+	expectStatus(t, resp, http.StatusCreated)
+
+	// The account last added to is offered first
+	resp, body := c.get("/api/transactions/quick-add/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	var accounts []struct {
+		ID      int64   `json:"id"`
+		Balance float64 `json:"balance"`
+	}
+	if err := json.Unmarshal([]byte(body), &accounts); err != nil {
+		t.Fatalf("decoding accounts: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].ID != ids[1] || accounts[0].Balance != 1500 || accounts[1].ID != ids[0] {
+		t.Errorf("quick-add accounts = %+v; want Beta Broker with 1500 first, then Alpha Bank", accounts)
+	}
+
+	// Other users' accounts cannot be added to
+	resp, _ = c.postJSON("/api/transactions/quick-add", map[string]any{"account_id": otherID, "amount": 100})
+	expectStatus(t, resp, http.StatusNotFound)
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(otherID); balance != 0 {
+		t.Errorf("other user's balance = %v; want 0", balance)
+	}
+}
+
+func TestE2E_CommandPaletteListsPagesAccountsAndActions(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Alpha Bank", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Other Bank", Currency: "DKK", IsActive: true}); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Username: "alice", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get("/api/commands")
+	expectStatus(t, resp, http.StatusOK)
+	var commands []struct {
+		Title   string `json:"title"`
+		Section string `json:"section"`
+		URL     string `json:"url"`
+		Action  string `json:"action"`
+	}
+	if err := json.Unmarshal([]byte(body), &commands); err != nil {
+		t.Fatalf("decoding commands: %v", err)
+	}
+	byTitle := make(map[string]string)
+	for _, cmd := range commands {
+		byTitle[cmd.Title] = cmd.URL + cmd.Action
+	}
+
+	want := map[string]string{
+		"New transaction":      "quick-add",
+		"Sync Nordnet (alice)": fmt.Sprintf("/settings/connections/%d?action=sync", connID),
+		"Portfolio Analyzer":   "/tools/portfolio-analyzer",
+		"Alpha Bank":           fmt.Sprintf("/transactions?account=%d", accountID),
+	}
+	for title, target := range want {
+		if byTitle[title] != target {
+			t.Errorf("command %q = %q; want %q", title, byTitle[title], target)
+		}
+	}
+	if _, ok := byTitle["Other Bank"]; ok {
+		t.Error("another user's account is listed")
+	}
+	if _, ok := byTitle["Admin Panel"]; ok {
+		t.Error("admin panel listed for a regular user")
+	}
+}
+
+func TestE2E_InlineEditRejectsStaleEdits(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	txnID, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, Description: "Deposit", TransactionDate: time.Now()})
+	if err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	versionRe := regexp.MustCompile(`name="version" value="([^"]+)"`)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get(fmt.Sprintf("/transactions/%d/row/edit", txnID))
+	expectStatus(t, resp, http.StatusOK)
+	m := versionRe.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatal("transaction editor has no version")
+	}
+	edit := url.Values{"amount": {"1200"}, "description": {"Corrected deposit"}, "transaction_date": {"2024-05-01"}, "version": {m[1]}}
+	resp, body = c.post(fmt.Sprintf("/transactions/%d/row", txnID), edit)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Corrected deposit") || strings.Contains(body, "<html") {
+		t.Error("saving an inline edit does not return the updated row")
+	}
+	if txn, _ := srv.app.transactionRepo.GetByID(txnID); txn.Amount != 1200 || txn.BalanceAfter != 1200 {
+		t.Errorf("transaction = %+v; want amount and balance 1200", txn)
+	}
+
+	// A second tab still holding the old version cannot overwrite the edit
+	edit.Set("description", "Second tab")
+	resp, body = c.post(fmt.Sprintf("/transactions/%d/row", txnID), edit)
+	expectStatus(t, resp, http.StatusConflict)
+	if !strings.Contains(body, "changed elsewhere") || !strings.Contains(body, "Corrected deposit") {
+		t.Error("stale edit is not answered with the current values and a conflict message")
+	}
+
+	resp, body = c.get(fmt.Sprintf("/accounts/%d/name/edit", accountID))
+	expectStatus(t, resp, http.StatusOK)
+	if m = versionRe.FindStringSubmatch(body); m == nil {
+		t.Fatal("account editor has no version")
+	}
+	rename := url.Values{"name": {"Rainy Day"}, "version": {m[1]}}
+	resp, body = c.post(fmt.Sprintf("/accounts/%d/name", accountID), rename)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Rainy Day") {
+		t.Error("renaming does not return the new name")
+	}
+	rename.Set("name", "Second tab")
+	resp, _ = c.post(fmt.Sprintf("/accounts/%d/name", accountID), rename)
+	expectStatus(t, resp, http.StatusConflict)
+	if account, _ := srv.app.accountRepo.GetByID(accountID); account.Name != "Rainy Day" {
+		t.Errorf("account name = %s, want Rainy Day", account.Name)
+	}
+
+	// Other users cannot open or save the editors
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(fmt.Sprintf("/transactions/%d/row/edit", txnID))
+	expectStatus(t, resp, http.StatusNotFound)
+	resp, _ = other.post(fmt.Sprintf("/accounts/%d/name", accountID), url.Values{"name": {"Mine"}, "version": {m[1]}})
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_StaleGoalEditOffersReapply(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	goalID, err := srv.app.goalRepo.Create(&models.Goal{UserID: user.ID, Name: "House", TargetAmount: 500000, TargetCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	form := url.Values{"name": {"House deposit"}, "target_amount": {"600000"}, "target_currency": {"DKK"}, "version": {time.Time{}.Format(time.RFC3339Nano)}}
+	resp, _ := c.post(fmt.Sprintf("/goals/%d", goalID), form)
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	// A second tab opened before the first edit is turned away
+	form.Set("name", "Summer house")
+	resp, body := c.post(fmt.Sprintf("/goals/%d", goalID), form)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "changed elsewhere") || !strings.Contains(body, "Reapply my changes") {
+		t.Error("stale goal edit does not offer to reapply it")
+	}
+	goal, _ := srv.app.goalRepo.GetByID(goalID)
+	if goal.Name != "House deposit" {
+		t.Fatalf("goal name = %s, want House deposit", goal.Name)
+	}
+
+	// Reapplying sends the current version
+	form.Set("version", goal.UpdatedAt.Format(time.RFC3339Nano))
+	resp, _ = c.post(fmt.Sprintf("/goals/%d", goalID), form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if goal, _ = srv.app.goalRepo.GetByID(goalID); goal.Name != "Summer house" {
+		t.Errorf("goal name after reapplying = %s, want Summer house", goal.Name)
+	}
+}
+
+func TestE2E_APIQuotaRefusesRequestsAndShowsUsage(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.APIQuota = 2 })
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Electricity", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	_, body := c.post("/settings/api-keys", url.Values{"name": {"Meter script"}, "account_id": {fmt.Sprint(accountID)}})
+	key := regexp.MustCompile(`wt_[0-9a-f]{64}`).FindString(body)
+	if key == "" {
+		t.Fatal("created API key is not shown")
+	}
+
+	path := fmt.Sprintf("/api/v1/accounts/%d/balance", accountID)
+	for i := 0; i < 2; i++ {
+		resp, _ := srv.apiPost(t, key, path, map[string]any{"balance": 100 + i})
+		expectStatus(t, resp, http.StatusOK)
+	}
+	resp, body := srv.apiPost(t, key, path, map[string]any{"balance": 200})
+	expectStatus(t, resp, http.StatusTooManyRequests)
+	if !strings.Contains(body, "monthly API call quota of 2 reached") {
+		t.Errorf("over-quota response = %q; want the quota and reset date", body)
+	}
+
+	resp, body = c.get("/settings/usage")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "API calls") || !strings.Contains(body, "2 / 2") {
+		t.Error("usage page does not show 2 of 2 API calls used")
+	}
+	if !strings.Contains(body, "Limit reached until") {
+		t.Error("usage page does not say the API limit is reached")
+	}
+}
+
+func TestE2E_ChangePasswordEnforcesPolicy(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.PasswordMinScore = 2 })
+	c := srv.newClient(t)
+	c.post("/login", url.Values{"email": {"admin@localhost"}, "password": {"changeme"}})
+
+	resp, body := c.get("/change-password")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "avoid common words") {
+		t.Error("change password page does not describe the password policy")
+	}
+
+	// Common passwords are refused even when long enough
+	resp, body = c.post("/change-password", url.Values{
+		"current_password": {"changeme"},
+		"new_password":     {"Password2024!"},
+		"confirm_password": {"Password2024!"},
+	})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "too easy to guess") {
+		t.Error("weak password was not refused")
+	}
+
+	resp, _ = c.post("/change-password", url.Values{
+		"current_password": {"changeme"},
+		"new_password":     {"quiet-lantern-orbit"},
+		"confirm_password": {"quiet-lantern-orbit"},
+	})
+	if resp.Header.Get("Location") != "/dashboard" {
+		t.Errorf("strong password change redirected to %q; want /dashboard", resp.Header.Get("Location"))
+	}
+}
+
+func TestE2E_AdminSupportViewIsReadOnlyAndAudited(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	user := srv.createUser(t, "user@example.com", "password123")
+	if _, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Travel Fund", Currency: "DKK"}); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+	resp, _ := c.post(fmt.Sprintf("/admin/users/%d/support", user.ID), nil)
+	if resp.Header.Get("Location") != "/dashboard" {
+		t.Fatalf("starting support view redirected to %q; want /dashboard", resp.Header.Get("Location"))
+	}
+
+	// The user's pages are shown, watermarked, without a session as the user
+	resp, body := c.get("/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Travel Fund") || !strings.Contains(body, "Exit Support View") {
+		t.Error("support view of /accounts lacks the user's account or the support banner")
+	}
+	var sessions int
+	if err := srv.app.db.QueryRow("SELECT COUNT(*) FROM sessions WHERE user_id = ?", user.ID).Scan(&sessions); err != nil || sessions != 0 {
+		t.Errorf("user has %d sessions, %v; want none", sessions, err)
+	}
+
+	// Changes and pages outside accounts, transactions and goals are refused
+	resp, _ = c.post("/accounts", url.Values{"name": {"Sneaky"}, "currency": {"DKK"}})
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = c.get("/settings")
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = c.get("/admin/users")
+	expectStatus(t, resp, http.StatusOK)
+
+	resp, _ = c.post("/admin/support/exit", nil)
+	if want := fmt.Sprintf("/admin/users/%d", user.ID); resp.Header.Get("Location") != want {
+		t.Errorf("exiting support view redirected to %q; want %s", resp.Header.Get("Location"), want)
+	}
+	if _, body = c.get("/accounts"); strings.Contains(body, "Travel Fund") {
+		t.Error("/accounts still shows the user's data after exiting support view")
+	}
+
+	entries, err := services.NewAuditService(srv.app.db).GetByUserID(user.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	actions := map[services.AuditAction]bool{}
+	for _, e := range entries {
+		if e.ActorID != admin.ID {
+			t.Errorf("audit entry %s by user %d; want the admin", e.Action, e.ActorID)
+		}
+		actions[e.Action] = true
+	}
+	for _, want := range []services.AuditAction{services.AuditAdminSupportStarted, services.AuditAdminSupportViewed, services.AuditAdminSupportEnded} {
+		if !actions[want] {
+			t.Errorf("audit log lacks %s", want)
+		}
+	}
+}
+
+func TestE2E_AdminSupportViewLeavesMilestonesAlone(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{
+		AccountID: accountID, Amount: 250000, BalanceAfter: 250000, TransactionDate: time.Now().AddDate(0, 0, -1),
+	}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	// 100,000 was reached but not celebrated yet; 200,000 is not recorded
+	milestoneRepo := repository.NewMilestoneRepository(srv.app.db)
+	if _, err := milestoneRepo.Record(user.ID, services.DefaultMilestoneStep, []*models.NetWorthMilestone{
+		{Amount: 100000, ReachedAt: time.Now().AddDate(0, 0, -1)},
+	}); err != nil {
+		t.Fatalf("recording milestone: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+	c.post(fmt.Sprintf("/admin/users/%d/support", user.ID), nil)
+	resp, _ := c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+
+	milestones, err := milestoneRepo.GetByUserID(user.ID)
+	if err != nil || len(milestones) != 1 || milestones[0].Celebrated {
+		t.Errorf("milestones after support view = %+v, %v; want only 100,000, still to celebrate", milestones, err)
+	}
+}
+
+func TestE2E_CompareShowsChangesBetweenDates(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Broker", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, txn := range []*models.Transaction{
+		{Amount: 1000, BalanceAfter: 1000, Description: "Deposit", TransactionDate: today.AddDate(0, 0, -60)},
+		{Amount: 250, BalanceAfter: 1250, Description: "Balance update", Kind: models.TransactionValuation, TransactionDate: today.AddDate(0, 0, -10)},
+		{Amount: 200, BalanceAfter: 1450, Description: "Deposit", TransactionDate: today.AddDate(0, 0, -5)},
+	} {
+		txn.AccountID = accountID
+		if _, err := srv.app.transactionRepo.Create(txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+	if err := srv.app.holdingRepo.Upsert(&models.Holding{AccountID: accountID, Symbol: "DK0060534915", Name: "Novo Nordisk", Quantity: 3, CurrentValue: 1450, Currency: "DKK"}); err != nil {
+		t.Fatalf("upserting holding: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get("/compare?from=" + today.AddDate(0, 0, -30).Format("2006-01-02") + "&to=" + today.Format("2006-01-02"))
+	expectStatus(t, resp, http.StatusOK)
+
+	// The deposit counts as a contribution and the balance update as market
+	// movement
+	for _, want := range []string{"Broker", "1.000,00", "1.450,00", "200,00", "250,00", "Novo Nordisk", "Bought"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("compare page lacks %q", want)
+		}
+	}
+}
+
+func TestE2E_DashboardDecomposesNetWorthChange(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, txn := range []*models.Transaction{
+		{Amount: 500, BalanceAfter: 500, Description: "Deposit", TransactionDate: today},
+		{Amount: 100, BalanceAfter: 600, Description: "Balance update", Kind: models.TransactionValuation, TransactionDate: today},
+	} {
+		txn.AccountID = accountID
+		if _, err := srv.app.transactionRepo.Create(txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"netWorthChangeChart", `"deposits":500`, `"market":100`, `"end":600`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard lacks %s", want)
+		}
+	}
+}
+
+func TestE2E_DashboardNetWorthExclusions(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	for _, account := range []*models.Account{
+		{Name: "Savings", Currency: "DKK", IsActive: true},
+		{Name: "Ratepension", Currency: "DKK", IsActive: true, NetWorthGroup: models.NetWorthGroupPension},
+	} {
+		account.UserID = user.ID
+		id, err := srv.app.accountRepo.Create(account)
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		balance := 100000.0
+		if account.NetWorthGroup != "" {
+			balance = 450000
+		}
+		srv.app.transactionRepo.Create(&models.Transaction{AccountID: id, Amount: balance, BalanceAfter: balance, TransactionDate: today})
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "550.000") || !strings.Contains(body, "Exclude pension") {
+		t.Error("dashboard does not show the whole net worth with a pension toggle")
+	}
+	if strings.Contains(body, "Exclude spouse accounts") {
+		t.Error("dashboard shows a toggle of a group without accounts")
+	}
+
+	resp, _ = c.post("/dashboard/net-worth-exclusions", url.Values{"exclude": {"pension", "bogus"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	if loc := resp.Header.Get("Location"); loc != "/dashboard" {
+		t.Errorf("Location = %q; want /dashboard", loc)
+	}
+	saved, _ := srv.app.userRepo.GetByID(user.ID)
+	if len(saved.NetWorthExclusions) != 1 || saved.NetWorthExclusions[0] != models.NetWorthGroupPension {
+		t.Errorf("NetWorthExclusions = %v; want [pension]", saved.NetWorthExclusions)
+	}
+
+	resp, body = c.get("/dashboard")
+	expectStatus(t, resp, http.StatusOK)
+	// Milestones are of the whole net worth, so only the total is checked
+	if strings.Contains(body, "550.000") {
+		t.Error("net worth still includes the excluded pension")
+	}
+	if !strings.Contains(strings.Join(strings.Fields(body), " "), "value: 100000 }") {
+		t.Error("net worth chart still includes the excluded pension")
+	}
+
+	resp, _ = c.post("/dashboard/net-worth-exclusions", url.Values{})
+	expectStatus(t, resp, http.StatusSeeOther)
+	if _, body = c.get("/dashboard"); !strings.Contains(body, "550.000") {
+		t.Error("clearing the exclusions does not restore the whole net worth")
+	}
+}
+
+// waitForTask reloads a broker task page, like its refresh header does,
+// until the body contains want.
+func (c *testClient) waitForTask(path, want string) string {
+	c.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, body := c.get(path)
+		expectStatus(c.t, resp, http.StatusOK)
+		if strings.Contains(body, want) {
+			return body
+		}
+		if time.Now().After(deadline) {
+			c.t.Fatalf("GET %s: %q not shown in time", path, want)
+		}
+		if !strings.Contains(body, `http-equiv="refresh"`) {
+			c.t.Fatalf("GET %s: task finished without %q", path, want)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestE2E_CategorizationRules(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 50000, BalanceAfter: 50000, Description: "Imported balance", TransactionDate: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	rule := url.Values{"name": {"Old savings"}, "payee": {"imported"}, "min_amount": {"1000"}, "tag": {"legacy"}, "kind": {"flow"}}
+
+	// A dry run lists matching transactions without saving the rule
+	resp, body := c.post("/settings/rules/preview", rule)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Matches 1 of your last") {
+		t.Error("preview does not list the matching transaction")
+	}
+	if rules, _ := srv.app.ruleRepo.GetByUserID(user.ID); len(rules) != 0 {
+		t.Errorf("preview saved %d rules; want none", len(rules))
+	}
+
+	_, body = c.post("/settings/rules", url.Values{"name": {"Nothing"}, "payee": {"x"}})
+	if !strings.Contains(body, "must set a category, a tag or a kind") {
+		t.Error("rule without an action was not rejected")
+	}
+
+	resp, _ = c.post("/settings/rules", rule)
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	// Imported balances are categorized by the rule
+	resp, _ = c.postFile("/accounts/history", "history.csv", []byte("date;account;balance\n2023-12;Savings;45000\n"), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	txns, err := srv.app.transactionRepo.GetByAccountID(accountID, 10, 0)
+	if err != nil {
+		t.Fatalf("getting transactions: %v", err)
+	}
+	var imported *models.Transaction
+	for _, txn := range txns {
+		if txn.TransactionDate.Year() == 2023 {
+			imported = txn
+		}
+	}
+	if imported == nil || imported.Tag != "legacy" || imported.Kind != "" {
+		t.Errorf("imported balance = %+v; want it tagged legacy as money moved", imported)
+	}
+
+	// Another user cannot delete the rule
+	rules, _ := srv.app.ruleRepo.GetByUserID(user.ID)
+	if len(rules) != 1 {
+		t.Fatalf("got %d rules; want 1", len(rules))
+	}
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.post(fmt.Sprintf("/settings/rules/%d/delete", rules[0].ID), nil)
+	expectStatus(t, resp, http.StatusNotFound)
+
+	resp, _ = c.post(fmt.Sprintf("/settings/rules/%d/delete", rules[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+}
+
+func TestE2E_ImportBankStatement(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Checking", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	statement := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt>
+	<Acct><Id><IBAN>DK5000400440116243</IBAN></Id><Ccy>DKK</Ccy></Acct>
+	<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="DKK">500.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2025-03-01</Dt></Dt></Bal>
+	<Ntry><Amt Ccy="DKK">30000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2025-03-01</Dt></BookgDt><AddtlNtryInf>Løn marts</AddtlNtryInf></Ntry>
+	<Ntry><Amt Ccy="DKK">8000.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2025-03-02</Dt></BookgDt><AddtlNtryInf>Husleje</AddtlNtryInf></Ntry>
+</Stmt></BkToCstmrStmt></Document>`)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	path := fmt.Sprintf("/accounts/%d/statement/import", accountID)
+
+	resp, _ := c.postFile(path, "statement.xml", statement, nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "imported=2&duplicates=0") {
+		t.Errorf("redirected to %q; want 2 imported entries", loc)
+	}
+	if balance, _ := srv.app.transactionRepo.GetLatestBalance(accountID); balance != 22500 {
+		t.Errorf("balance = %.2f; want 22500", balance)
+	}
+
+	_, body := c.get(fmt.Sprintf("/transactions?account=%d&imported=2&duplicates=0", accountID))
+	if !strings.Contains(body, "Imported 2 bank statement entries") {
+		t.Error("transactions page does not confirm the import")
+	}
+
+	// Uploading the same statement again records nothing
+	resp, _ = c.postFile(path, "statement.xml", statement, nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "imported=0&duplicates=2") {
+		t.Errorf("redirected to %q; want 2 duplicates", loc)
+	}
+
+	eurID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Euro", Currency: "EUR", IsActive: true})
+	_, body = c.postFile(fmt.Sprintf("/accounts/%d/statement/import", eurID), "statement.xml", statement, nil)
+	if !strings.Contains(body, "Bank statement import failed") {
+		t.Error("statement in another currency was not rejected")
+	}
+
+	// Another user cannot import into the account
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.postFile(path, "statement.xml", statement, nil)
+	expectStatus(t, resp, http.StatusForbidden)
+}
+
+func TestE2E_StressTest(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.holdingRepo.Create(&models.Holding{AccountID: accountID, Symbol: "SPY", Name: "S&P 500 ETF", Quantity: 10, CurrentValue: 100000, Currency: "USD", InstrumentType: "etf"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	if _, err := srv.app.goalRepo.Create(&models.Goal{UserID: user.ID, Name: "House deposit", TargetAmount: 500000, TargetCurrency: "DKK"}); err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/tools/stress-test")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "S&amp;P 500 ETF") || !strings.Contains(body, `name="equity" step="any" value="-30"`) {
+		t.Error("stress test does not show the holding under the default scenario")
+	}
+	if !strings.Contains(body, "House deposit") {
+		t.Error("stress test does not show the impact on goals")
+	}
+
+	resp, body = c.get("/tools/stress-test?equity=-150")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Shocks cannot be below -100%") {
+		t.Error("shock below -100% was not rejected")
+	}
+}
+
+func TestE2E_Liquidation(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.holdingRepo.Create(&models.Holding{AccountID: accountID, Symbol: "SPY", Name: "S&P 500 ETF", Quantity: 10, AvgPrice: 8000, CurrentValue: 100000, Currency: "DKK"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/tools/liquidation")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "S&amp;P 500 ETF") || !strings.Contains(body, `name="fee_pct" step="any" min="0" value="0.1"`) {
+		t.Error("liquidation does not list the holding under the default costs")
+	}
+
+	resp, body = c.get(fmt.Sprintf("/tools/liquidation?account=%d", accountID))
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, fmt.Sprintf(`name="account" value="%d" checked`, accountID)) || !strings.Contains(body, ">1 selected<") {
+		t.Error("liquidation does not sell the ticked account")
+	}
+
+	resp, body = c.get("/tools/liquidation?fee_pct=-1")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Fees and spreads cannot be negative") {
+		t.Error("negative fee was not rejected")
+	}
+}
+
+func TestE2E_ChartColorsAreConsistent(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	categoryID, err := srv.app.categoryRepo.Create(&models.Category{UserID: user.ID, Name: "Aktier", Color: "#22c55e"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	savingsID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Aktiesparekonto", Currency: "DKK", CategoryID: &categoryID, IsActive: true})
+	srv.app.transactionRepo.Create(&models.Transaction{AccountID: savingsID, Amount: 50000, BalanceAfter: 50000, TransactionDate: time.Now()})
+	depotID, _ := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	srv.app.holdingRepo.Create(&models.Holding{AccountID: depotID, Symbol: "SPY", Name: "S&P 500", Quantity: 1, CurrentValue: 20000, Currency: "USD", InstrumentType: "etf"})
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	composition := func() services.PortfolioComposition {
+		var composition services.PortfolioComposition
+		_, body := c.get("/api/portfolio/composition")
+		if err := json.Unmarshal([]byte(body), &composition); err != nil {
+			t.Fatalf("decoding composition: %v", err)
+		}
+		return composition
+	}
+
+	first := composition()
+	colors := make(map[string]string)
+	for _, at := range first.ByAssetType {
+		colors[at.AssetType] = at.Color
+	}
+	// The balance is classed by its category, and drawn in its color
+	if colors["Aktier"] != "#22c55e" {
+		t.Errorf("asset type Aktier = %q; want the category color", colors["Aktier"])
+	}
+	if colors["etf"] == "" || colors["etf"] == "#22c55e" {
+		t.Errorf("asset type etf = %q; want a color of its own", colors["etf"])
+	}
+
+	// The colors are kept for later charts
+	for _, at := range composition().ByAssetType {
+		if at.Color != colors[at.AssetType] {
+			t.Errorf("asset type %s = %s on the next chart; want %s", at.AssetType, at.Color, colors[at.AssetType])
+		}
+	}
+}
+
+func TestE2E_AsOfMode(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	openedAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Opsparing", Currency: "DKK", IsActive: true, OpenedAt: &openedAt})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	for _, txn := range []*models.Transaction{
+		{AccountID: accountID, Amount: 12345, BalanceAfter: 12345, TransactionDate: time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{AccountID: accountID, Amount: 87655, BalanceAfter: 100000, TransactionDate: time.Now()},
+	} {
+		if _, err := srv.app.transactionRepo.Create(txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/dashboard?asof=2023-06-30")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Back to today") || !strings.Contains(body, "12.345") {
+		t.Error("dashboard does not show the balance at the as-of date")
+	}
+
+	resp, body = c.get("/accounts?asof=2023-06-30")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "12.345") || strings.Contains(body, "100.000") {
+		t.Error("accounts page does not show the balance at the as-of date")
+	}
+
+	resp, body = c.get("/api/portfolio/composition?asof=2023-06-30")
+	expectStatus(t, resp, http.StatusOK)
+	var composition struct {
+		TotalValue float64 `json:"total_value"`
+	}
+	if err := json.Unmarshal([]byte(body), &composition); err != nil {
+		t.Fatalf("decoding composition: %v", err)
+	}
+	if composition.TotalValue != 12345 {
+		t.Errorf("total value = %v; want 12345", composition.TotalValue)
+	}
+
+	// Accounts opened after the as-of date are left out
+	resp, body = c.get("/accounts?asof=2020-01-01")
+	expectStatus(t, resp, http.StatusOK)
+	if strings.Contains(body, "Opsparing") {
+		t.Error("account opened after the as-of date is listed")
+	}
+}
+
+func TestE2E_PendingInvestmentsOnAccounts(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Nordnet", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 50000, BalanceAfter: 50000, TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	if err := repository.NewPendingInvestmentRepository(srv.app.db).Set(accountID, 2500, 1); err != nil {
+		t.Fatalf("setting pending investments: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "incl. 2.500 pending investments") {
+		t.Error("accounts page does not show the pending investments")
+	}
+}
+
+func TestE2E_DataQualityPage(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Forgotten savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, TransactionDate: time.Now().AddDate(-1, 0, 0)}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/settings/data-quality")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Forgotten savings") || !strings.Contains(body, "2 findings") {
+		t.Error("data quality page does not report the stale, uncategorized account")
+	}
+	if !strings.Contains(body, fmt.Sprintf(`href="/accounts?account=%d&amp;fix=balance"`, accountID)) {
+		t.Error("data quality page does not link to updating the balance")
+	}
+}
+
+func TestE2E_MarkdownDescriptions(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	notes := "## Loan terms\n- Fixed **4%**\n<script>alert(1)</script>"
+	if _, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Mortgage", Currency: "DKK", IsActive: true, IsLiability: true, Notes: notes}); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/accounts")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "<h4>Loan terms</h4>") || !strings.Contains(body, "<li>Fixed <strong>4%</strong></li>") {
+		t.Error("account notes are not rendered as Markdown")
+	}
+	if strings.Contains(body, "<script>alert(1)</script>") || !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("HTML in account notes is not escaped")
+	}
+
+	form := url.Values{"name": {"Pension"}, "description": {"Paid out from *age 64*\n[Terms](javascript:alert(1))"}}
+	resp, _ = c.post("/categories", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, body = c.get("/categories")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Paid out from <em>age 64</em><br>\nTerms") {
+		t.Error("category description is not rendered as Markdown")
+	}
+	if strings.Contains(body, `href="javascript:`) {
+		t.Error("category description links to a script")
+	}
+
+	form = url.Values{"name": {"House"}, "target_amount": {"250000"}, "target_currency": {"DKK"}, "description": {"Deposit for [the house](https://example.com/house)"}}
+	resp, _ = c.post("/goals", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	goals, err := srv.app.goalRepo.GetByUserID(user.ID)
+	if err != nil || len(goals) != 1 {
+		t.Fatalf("GetByUserID() = %d goals, %v; want 1", len(goals), err)
+	}
+	resp, body = c.get(fmt.Sprintf("/goals/%d", goals[0].ID))
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `<a href="https://example.com/house" rel="nofollow noopener noreferrer" target="_blank">the house</a>`) {
+		t.Error("goal description is not rendered as Markdown")
+	}
+}
+
+func TestE2E_CurrencyConverter(t *testing.T) {
+	srv := newTestServer(t)
+	srv.createUser(t, "user@example.com", "password123")
+	now := time.Now()
+	for _, q := range []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO currency_rates (from_currency, to_currency, rate, fetched_at) VALUES ('EUR', 'DKK', 7.45, ?)`, []any{now}},
+		{`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('EUR', 'DKK', '2024-01-02', 7.5)`, nil},
+		{`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('EUR', 'DKK', ?, 7.46)`, []any{now.AddDate(0, 0, -10).Format("2006-01-02")}},
+		{`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('EUR', 'DKK', ?, 7.45)`, []any{now.AddDate(0, 0, -5).Format("2006-01-02")}},
+	} {
+		if _, err := srv.app.db.Exec(q.query, q.args...); err != nil {
+			t.Fatalf("seeding rates: %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.get("/tools")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `href="/tools/currency"`) {
+		t.Error("tools page does not link to the currency converter")
+	}
+
+	resp, body = c.get("/tools/currency?from=EUR&to=DKK&amount=100&date=2024-01-10")
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"745,00 DKK", "1 EUR = 7.45 DKK", "750,00 DKK", "1 EUR = 7.5 DKK", "rateChart", `<option value="EUR" selected>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("currency converter does not show %q", want)
+		}
+	}
+
+	resp, body = c.get("/tools/currency?from=EUR&to=DKK&amount=-1")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Amount must be a positive number") {
+		t.Error("currency converter accepts a negative amount")
+	}
+}
+
+func TestE2E_AdminHousekeeping(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	if _, err := srv.app.db.Exec(`INSERT INTO sessions (id, user_id, expires_at) VALUES ('expired', ?, ?)`, admin.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("inserting session: %v", err)
+	}
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	_, body := c.get("/admin")
+	if !strings.Contains(body, "Housekeeping has not run yet") {
+		t.Error("admin dashboard does not say housekeeping has not run")
+	}
+
+	runHousekeeping(srv.app.housekeepingService)
+
+	var n int
+	if err := srv.app.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE id = 'expired'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("expired session left after housekeeping (count %d, err %v)", n, err)
+	}
+	resp, body := c.get("/admin")
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"Expired sessions", "MitID QR files", "Rate limit buckets"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("admin dashboard does not show housekeeping task %q", want)
+		}
+	}
+	if !strings.Contains(body, "1 removed") {
+		t.Error("admin dashboard does not show the removed expired session")
+	}
+}
+
+func TestE2E_BrokerTaskPolledAsJSON(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) {
+		cfg.MockBroker = true
+		cfg.IsDevelopment = true
+	})
+	user := srv.createUser(t, "user@example.com", "password123")
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+	base := "/settings/connections/" + fmt.Sprint(connID)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, _ := c.get(base + "/task?format=json")
+	expectStatus(t, resp, http.StatusNotFound)
+
+	resp, body := c.post(base+"/fetch-accounts", url.Values{})
+	expectStatus(t, resp, http.StatusAccepted)
+	var started struct {
+		TaskURL string `json:"task_url"`
+	}
+	if err := json.Unmarshal([]byte(body), &started); err != nil || started.TaskURL != base+"/task?format=json" {
+		t.Fatalf("fetch accounts: body %q; want the task URL", body)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, body = c.get(started.TaskURL)
+		expectStatus(t, resp, http.StatusOK)
+		var task struct {
+			Kind   string            `json:"kind"`
+			Done   bool              `json:"done"`
+			Error  string            `json:"error"`
+			Result []json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(body), &task); err != nil {
+			t.Fatalf("task status: %v in %q", err, body)
+		}
+		if task.Done {
+			if task.Kind != "fetch" || task.Error != "" || len(task.Result) == 0 {
+				t.Fatalf("finished task = %q; want fetched accounts", body)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fetch task did not finish in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestE2E_ConnectionSyncSchedule(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	connID, err := srv.app.brokerConnRepo.Create(&models.BrokerConnection{UserID: user.ID, BrokerType: "nordnet", Username: "user", CPR: "0101011234", Country: "dk", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating connection: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	path := fmt.Sprintf("/settings/connections/%d", connID)
+	_, body := c.get(path)
+	if !strings.Contains(body, "Syncs run only when you start them") {
+		t.Error("connection page does not say it is synced on request only")
+	}
+
+	form := url.Values{"username": {"user"}, "cpr": {"0101011234"}, "sync_schedule": {"custom"}, "sync_cron": {"61 * * * *"}}
+	_, body = c.post(path+"/edit", form)
+	if !strings.Contains(body, "Invalid sync schedule") {
+		t.Error("invalid cron expression was accepted")
+	}
+
+	form.Set("sync_cron", "30  18 * * 1-5")
+	resp, _ := c.post(path+"/edit", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	conn, _ := srv.app.brokerConnRepo.GetByID(connID)
+	if conn.SyncSchedule != "30 18 * * 1-5" {
+		t.Errorf("sync schedule = %q; want the custom expression", conn.SyncSchedule)
+	}
+
+	_, body = c.get(path)
+	if !strings.Contains(body, "Next scheduled sync") {
+		t.Error("connection page does not show the next scheduled sync")
+	}
+	_, body = c.get(path + "/edit")
+	if !strings.Contains(body, `value="30 18 * * 1-5"`) {
+		t.Error("connection form does not show the custom schedule")
+	}
+}
+
+func TestE2E_RESTAPI(t *testing.T) {
+	srv := newTestServer(t)
+	srv.createUser(t, "user@example.com", "password123")
+	srv.createUser(t, "other@example.com", "password123")
+
+	resp, _ := srv.newClient(t).get("/api/v1/accounts")
+	expectStatus(t, resp, http.StatusUnauthorized)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.postJSON("/api/v1/categories", map[string]any{"name": "Stocks", "expected_return": 7})
+	expectStatus(t, resp, http.StatusCreated)
+	var category models.Category
+	json.Unmarshal([]byte(body), &category)
+
+	resp, _ = c.postJSON("/api/v1/accounts", map[string]any{"name": ""})
+	expectStatus(t, resp, http.StatusBadRequest)
+	resp, body = c.postJSON("/api/v1/accounts", map[string]any{"name": "Nordnet", "category_id": category.ID})
+	expectStatus(t, resp, http.StatusCreated)
+	var account models.Account
+	json.Unmarshal([]byte(body), &account)
+	if account.Currency != "DKK" || account.CategoryID == nil || *account.CategoryID != category.ID || !account.IsActive {
+		t.Errorf("created account = %+v; want an active DKK account in the category", account)
+	}
+
+	resp, _ = c.postJSON("/api/v1/transactions", map[string]any{"account_id": account.ID, "amount": 1000, "transaction_date": "2024-05-15"})
+	expectStatus(t, resp, http.StatusCreated)
+	resp, body = c.postJSON("/api/v1/transactions", map[string]any{"account_id": account.ID, "amount": 500})
+	expectStatus(t, resp, http.StatusCreated)
+	var txn models.Transaction
+	json.Unmarshal([]byte(body), &txn)
+
+	// PUT back what was read, with a change
+	txn.Amount = 1000
+	resp, _ = c.doJSON(http.MethodPut, fmt.Sprintf("/api/v1/transactions/%d", txn.ID), txn)
+	expectStatus(t, resp, http.StatusOK)
+	_, body = c.get(fmt.Sprintf("/api/v1/accounts/%d", account.ID))
+	json.Unmarshal([]byte(body), &account)
+	if account.Balance != 2000 {
+		t.Errorf("balance = %v; want 2000", account.Balance)
+	}
+
+	resp, body = c.get(fmt.Sprintf("/api/v1/transactions?account=%d&limit=1", account.ID))
+	expectStatus(t, resp, http.StatusOK)
+	var page struct {
+		Items   []models.Transaction `json:"items"`
+		Total   int                  `json:"total"`
+		HasMore bool                 `json:"has_more"`
+	}
+	json.Unmarshal([]byte(body), &page)
+	if len(page.Items) != 1 || page.Total != 2 || !page.HasMore {
+		t.Errorf("transactions page = %+v; want 1 of 2", page)
+	}
+
+	// An edit based on an outdated copy is rejected
+	stale := account
+	account.Name = "Nordnet ISK"
+	resp, body = c.doJSON(http.MethodPut, fmt.Sprintf("/api/v1/accounts/%d", account.ID), account)
+	expectStatus(t, resp, http.StatusOK)
+	json.Unmarshal([]byte(body), &account)
+	stale.Name = "Nordnet Depot"
+	resp, _ = c.doJSON(http.MethodPut, fmt.Sprintf("/api/v1/accounts/%d", account.ID), stale)
+	expectStatus(t, resp, http.StatusConflict)
+
+	resp, body = c.postJSON("/api/v1/goals", map[string]any{"name": "First 10k", "target_amount": 10000, "category_id": category.ID})
+	expectStatus(t, resp, http.StatusCreated)
+	var goal models.Goal
+	json.Unmarshal([]byte(body), &goal)
+	if goal.Progress != 20 {
+		t.Errorf("goal progress = %v; want 20", goal.Progress)
+	}
+
+	resp, body = c.postJSON("/api/v1/holdings", map[string]any{"account_id": account.ID, "symbol": "novo-b", "quantity": 10, "current_price": 150})
+	expectStatus(t, resp, http.StatusCreated)
+	var holding models.Holding
+	json.Unmarshal([]byte(body), &holding)
+	if holding.Symbol != "NOVO-B" || holding.CurrentValue != 1500 || holding.Currency != "DKK" {
+		t.Errorf("created holding = %+v; want 10 NOVO-B worth 1500 DKK", holding)
+	}
+	_, body = c.get("/api/v1/holdings")
+	if !strings.Contains(body, "NOVO-B") {
+		t.Error("holdings list does not include the new holding")
+	}
+
+	// Another user sees none of it
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	for _, path := range []string{
+		fmt.Sprintf("/api/v1/accounts/%d", account.ID),
+		fmt.Sprintf("/api/v1/transactions/%d", txn.ID),
+		fmt.Sprintf("/api/v1/categories/%d", category.ID),
+		fmt.Sprintf("/api/v1/goals/%d", goal.ID),
+		fmt.Sprintf("/api/v1/holdings/%d", holding.ID),
+	} {
+		resp, _ := other.get(path)
+		expectStatus(t, resp, http.StatusNotFound)
+		resp, _ = other.doJSON(http.MethodDelete, path, nil)
+		expectStatus(t, resp, http.StatusNotFound)
+	}
+	_, body = other.get("/api/v1/accounts")
+	if strings.TrimSpace(body) != "[]" {
+		t.Errorf("other user's accounts = %s; want none", body)
+	}
+
+	// A new client, as c has used up the API rate limit's burst
+	c = srv.newClient(t)
+	c.login("user@example.com", "password123")
+	for _, path := range []string{
+		fmt.Sprintf("/api/v1/holdings/%d", holding.ID),
+		fmt.Sprintf("/api/v1/goals/%d", goal.ID),
+		fmt.Sprintf("/api/v1/transactions/%d", txn.ID),
+		fmt.Sprintf("/api/v1/accounts/%d", account.ID),
+		fmt.Sprintf("/api/v1/categories/%d", category.ID),
+	} {
+		resp, _ := c.doJSON(http.MethodDelete, path, nil)
+		expectStatus(t, resp, http.StatusNoContent)
+		resp, _ = c.get(path)
+		expectStatus(t, resp, http.StatusNotFound)
+	}
+}
+
+func TestE2E_APITokens(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	if _, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true}); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	newToken := func(name, scope string) string {
+		t.Helper()
+		_, body := c.post("/settings/api-tokens", url.Values{"name": {name}, "scope": {scope}})
+		_, rest, ok := strings.Cut(body, `id="new-api-token">`)
+		if !ok {
+			t.Fatalf("token %s was not shown after creating it", name)
+		}
+		token, _, _ := strings.Cut(rest, "<")
+		return token
+	}
+	readToken := newToken("Backup", "read")
+	writeToken := newToken("Sync", "read_write")
+
+	request := func(method, path, token string, v any) *http.Response {
+		t.Helper()
+		var body io.Reader
+		if v != nil {
+			data, _ := json.Marshal(v)
+			body = bytes.NewReader(data)
+		}
+		req, _ := http.NewRequest(method, srv.URL+path, body)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		readBody(t, resp)
+		return resp
+	}
+
+	// Without a session, a browser is still sent to the login page
+	expectStatus(t, request(http.MethodGet, "/export/accounts", "", nil), http.StatusSeeOther)
+	expectStatus(t, request(http.MethodGet, "/export/accounts", readToken, nil), http.StatusOK)
+	expectStatus(t, request(http.MethodGet, "/api/holdings", readToken, nil), http.StatusOK)
+	expectStatus(t, request(http.MethodGet, "/api/v1/accounts", readToken, nil), http.StatusOK)
+	expectStatus(t, request(http.MethodGet, "/dashboard", readToken, nil), http.StatusSeeOther)
+	expectStatus(t, request(http.MethodGet, "/api/v1/accounts", "wtu_invalid", nil), http.StatusUnauthorized)
+
+	category := map[string]any{"name": "Stocks"}
+	expectStatus(t, request(http.MethodPost, "/api/v1/categories", readToken, category), http.StatusForbidden)
+	expectStatus(t, request(http.MethodPost, "/api/v1/categories", writeToken, category), http.StatusCreated)
+
+	_, body := c.get("/settings/api-tokens")
+	if !strings.Contains(body, "Backup") || !strings.Contains(body, "Last used") {
+		t.Error("tokens page does not list the used tokens")
+	}
+	if strings.Contains(body, readToken) {
+		t.Error("tokens page shows a token after it was created")
+	}
+
+	tokens, err := srv.app.apiTokenRepo.GetByUserID(user.ID)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("GetByUserID() = %d tokens, %v; want 2", len(tokens), err)
+	}
+	resp, _ := c.post(fmt.Sprintf("/settings/api-tokens/%d/delete", tokens[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	expectStatus(t, request(http.MethodGet, "/export/accounts", readToken, nil), http.StatusUnauthorized)
+}
+
+func TestE2E_AdvisorSharing(t *testing.T) {
+	srv := newTestServer(t)
+	owner := srv.createUser(t, "owner@example.com", "password123")
+	advisor := srv.createUser(t, "advisor@example.com", "password123")
+	savings, err := srv.app.accountRepo.Create(&models.Account{UserID: owner.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	private, err := srv.app.accountRepo.Create(&models.Account{UserID: owner.ID, Name: "Private", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: savings, Amount: 5000, BalanceAfter: 5000, TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	goal, err := srv.app.goalRepo.Create(&models.Goal{UserID: owner.ID, Name: "House", TargetAmount: 10000, TargetCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	o := srv.newClient(t)
+	o.login("owner@example.com", "password123")
+	// An unknown email is answered like a share, not to reveal who has an account
+	resp, _ := o.post("/settings/advisors", url.Values{"email": {"nobody@example.com"}, "account_ids": {fmt.Sprint(savings)}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = o.post("/settings/advisors", url.Values{
+		"email":       {"advisor@example.com"},
+		"account_ids": {fmt.Sprint(savings)},
+		"goal_ids":    {fmt.Sprint(goal)},
+	})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	a := srv.newClient(t)
+	a.login("advisor@example.com", "password123")
+	_, body := a.get("/shared")
+	if !strings.Contains(body, "owner@example.com") || !strings.Contains(body, "Savings") || !strings.Contains(body, "House") {
+		t.Error("shared page does not list the shared account and goal")
+	}
+	if strings.Contains(body, "Private") {
+		t.Error("shared page lists an account that was not shared")
+	}
+
+	accountThread := fmt.Sprintf("/threads/accounts/%d", savings)
+	resp, body = a.get(accountThread)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, `id="thread-balance">5.000`) {
+		t.Error("account thread does not show the balance")
+	}
+	resp, body = a.get(fmt.Sprintf("/threads/goals/%d", goal))
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "50.0% of target") {
+		t.Error("goal thread does not show the progress")
+	}
+	if strings.Contains(body, "10.000") {
+		t.Error("goal thread shows the advisor the target, giving away the owner's worth")
+	}
+	resp, _ = a.get(fmt.Sprintf("/threads/accounts/%d", private))
+	expectStatus(t, resp, http.StatusNotFound)
+
+	// Both sides comment on the thread; only the author deletes a comment
+	resp, _ = a.post(accountThread, url.Values{"body": {"Consider a higher interest rate"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = o.post(accountThread, url.Values{"body": {"Will look into it"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	_, body = o.get(accountThread)
+	if !strings.Contains(body, "Consider a higher interest rate") || !strings.Contains(body, "Will look into it") {
+		t.Error("account thread does not show both comments")
+	}
+	comments, err := srv.app.commentRepo.GetByAccountID(savings)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("GetByAccountID() = %d comments, %v; want 2", len(comments), err)
+	}
+	resp, _ = o.post(fmt.Sprintf("/comments/%d/delete", comments[0].ID), nil)
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = a.post(fmt.Sprintf("/comments/%d/delete", comments[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	resp, _ = o.post(fmt.Sprintf("/settings/advisors/%d/delete", advisor.ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = a.get(accountThread)
+	expectStatus(t, resp, http.StatusNotFound)
+	resp, _ = a.post(accountThread, url.Values{"body": {"Still here?"}})
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_TransactionImport(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Checking", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	fields := url.Values{"account_id": {fmt.Sprint(accountID)}}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	// A CSV is previewed with its columns guessed, then imported as mapped
+	csvData := "Dato;Tekst;Beløb\n01-05-2024;Løn;30.000,00\n15-05-2024;Netto;-249,50\n"
+	resp, body := c.postFile("/tools/import", "bank.csv", []byte(csvData), fields)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Map Columns") || !strings.Contains(body, `<option value="2" selected>Beløb</option>`) {
+		t.Fatal("CSV upload does not show the column mapping with the amount column guessed")
+	}
+
+	mapping := url.Values{"account_id": {fmt.Sprint(accountID)}, "csv": {csvData}, "date": {"0"}, "amount": {"1"}, "description": {"2"}}
+	_, body = c.post("/tools/import/csv", mapping)
+	if !strings.Contains(body, "nothing was imported") {
+		t.Error("a wrong mapping was not reported")
+	}
+
+	mapping.Set("amount", "2")
+	mapping.Set("description", "1")
+	resp, _ = c.post("/tools/import/csv", mapping)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "imported=2&duplicates=0") {
+		t.Errorf("redirect = %s; want 2 imported", loc)
+	}
+	resp, _ = c.post("/tools/import/csv", mapping)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "imported=0&duplicates=2") {
+		t.Errorf("re-import redirect = %s; want 2 duplicates", loc)
+	}
+
+	// An OFX file is imported right away, overlapping the CSV
+	ofx := "OFXHEADER:100\n<OFX><STMTRS><CURDEF>DKK<BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240515<TRNAMT>-249.50<NAME>Netto</STMTTRN>" +
+		"<STMTTRN><DTPOSTED>20240520<TRNAMT>-100.00<NAME>Rema 1000</STMTTRN>" +
+		"</BANKTRANLIST></STMTRS></OFX>"
+	resp, _ = c.postFile("/tools/import", "bank.ofx", []byte(ofx), fields)
+	expectStatus(t, resp, http.StatusSeeOther)
+	if loc := resp.Header.Get("Location"); !strings.Contains(loc, "imported=1&duplicates=1") {
+		t.Errorf("OFX redirect = %s; want 1 imported and 1 duplicate", loc)
+	}
+
+	balance, err := srv.app.transactionRepo.GetLatestBalance(accountID)
+	if err != nil || balance != 29650.5 {
+		t.Errorf("balance = %v, %v; want 29650.50", balance, err)
+	}
+
+	other := srv.createUser(t, "other@example.com", "password123")
+	otherAccount, _ := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Other", Currency: "DKK", IsActive: true})
+	resp, _ = c.postFile("/tools/import", "bank.ofx", []byte(ofx), url.Values{"account_id": {fmt.Sprint(otherAccount)}})
+	expectStatus(t, resp, http.StatusForbidden)
+}
+
+func TestE2E_SavedTransactionFilters(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Exchange", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	if err := srv.app.transactionRepo.CreateBatch([]*models.Transaction{
+		{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, Description: "Deposit", TransactionDate: day("2024-01-10")},
+		{AccountID: accountID, Amount: -400, BalanceAfter: 600, Description: "BTC buy", Tag: "crypto", TransactionDate: day("2024-03-01")},
+		{AccountID: accountID, Amount: -300, BalanceAfter: 300, Description: "ETH buy", Tag: "crypto", TransactionDate: day("2025-02-01")},
+	}); err != nil {
+		t.Fatalf("creating transactions: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	// Criteria in the query filter the list server-side
+	resp, body := c.get("/transactions?tag=crypto&from=2024-01-01&to=2024-12-31")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "BTC buy") || strings.Contains(body, "ETH buy") || strings.Contains(body, "Deposit") {
+		t.Error("filtered list does not show just the 2024 crypto buy")
+	}
+
+	// Saved under a name, the criteria are a view addressable by URL
+	criteria := url.Values{"name": {"2024 crypto buys"}, "tag": {"crypto"}, "from": {"2024-01-01"}, "to": {"2024-12-31"}}
+	resp, _ = c.post("/transactions/filters", criteria)
+	expectStatus(t, resp, http.StatusSeeOther)
+	viewURL := resp.Header.Get("Location")
+	if !strings.HasPrefix(viewURL, "/transactions?filter=") {
+		t.Fatalf("redirect = %s; want the saved view", viewURL)
+	}
+	resp, body = c.get(viewURL)
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "2024 crypto buys") || !strings.Contains(body, "BTC buy") || strings.Contains(body, "ETH buy") {
+		t.Error("saved view is not listed or does not apply its criteria")
+	}
+
+	resp, _ = c.post("/transactions/filters", criteria)
+	expectStatus(t, resp, http.StatusBadRequest)
+	resp, _ = c.post("/transactions/filters", url.Values{"name": {"Everything"}})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	// Views are private to their owner
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.get(viewURL)
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = other.post("/transactions/filters", url.Values{"name": {"Theirs"}, "account": {fmt.Sprint(accountID)}})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	filters, _ := srv.app.savedFilterRepo.GetByUserID(user.ID)
+	if len(filters) != 1 {
+		t.Fatalf("saved filters = %d; want 1", len(filters))
+	}
+	resp, _ = other.post(fmt.Sprintf("/transactions/filters/%d/delete", filters[0].ID), nil)
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = c.post(fmt.Sprintf("/transactions/filters/%d/delete", filters[0].ID), nil)
+	expectStatus(t, resp, http.StatusSeeOther)
+	resp, _ = c.get(viewURL)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_BulkAccountCategory(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	stocks, _ := srv.app.categoryRepo.Create(&models.Category{UserID: user.ID, Name: "Stocks", Color: "#6366f1"})
+	pension, _ := srv.app.categoryRepo.Create(&models.Category{UserID: user.ID, Name: "Pension", Color: "#10b981"})
+
+	var ids []int64
+	for i, balance := range []float64{6000, 3000, 1000} {
+		id, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, CategoryID: &stocks, Name: fmt.Sprintf("Account %d", i), Currency: "DKK", IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		srv.app.transactionRepo.Create(&models.Transaction{AccountID: id, Amount: balance, BalanceAfter: balance, TransactionDate: time.Now()})
+		ids = append(ids, id)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	// The preview shows the dashboard's distribution before and after
+	selection := fmt.Sprintf("account=%d&account=%d&category=%d", ids[1], ids[2], pension)
+	resp, body := c.get("/accounts/category?" + selection)
+	expectStatus(t, resp, http.StatusOK)
+	for _, want := range []string{"Move to Pension", "100.0%", "60.0%", "40.0%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview does not show %q", want)
+		}
+	}
+	if account, _ := srv.app.accountRepo.GetByID(ids[1]); *account.CategoryID != stocks {
+		t.Fatal("previewing moved an account")
+	}
+
+	form := url.Values{"account": {fmt.Sprint(ids[1]), fmt.Sprint(ids[2])}, "category": {fmt.Sprint(pension)}}
+	resp, _ = c.post("/accounts/category", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+	for i, id := range ids {
+		want := pension
+		if i == 0 {
+			want = stocks
+		}
+		if account, _ := srv.app.accountRepo.GetByID(id); account.CategoryID == nil || *account.CategoryID != want {
+			t.Errorf("account %d category = %v; want %d", id, account.CategoryID, want)
+		}
+	}
+
+	// Accounts and categories of other users are refused, and nothing moves
+	other := srv.createUser(t, "other@example.com", "password123")
+	theirs, _ := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Theirs", Currency: "DKK", IsActive: true})
+	theirCategory, _ := srv.app.categoryRepo.Create(&models.Category{UserID: other.ID, Name: "Theirs", Color: "#000000"})
+	resp, _ = c.post("/accounts/category", url.Values{"account": {fmt.Sprint(ids[0]), fmt.Sprint(theirs)}, "category": {fmt.Sprint(pension)}})
+	expectStatus(t, resp, http.StatusForbidden)
+	resp, _ = c.post("/accounts/category", url.Values{"account": {fmt.Sprint(ids[0])}, "category": {fmt.Sprint(theirCategory)}})
+	expectStatus(t, resp, http.StatusNotFound)
+	if account, _ := srv.app.accountRepo.GetByID(ids[0]); *account.CategoryID != stocks {
+		t.Error("a refused bulk change moved an account")
+	}
+
+	// No category takes the accounts out of the distribution
+	resp, _ = c.post("/accounts/category", url.Values{"account": {fmt.Sprint(ids[0])}, "category": {""}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	if account, _ := srv.app.accountRepo.GetByID(ids[0]); account.CategoryID != nil {
+		t.Errorf("category = %v; want none", account.CategoryID)
+	}
+}
+
+func TestE2E_PSD2BankConnection(t *testing.T) {
+	var redirectURI string
+	bank := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tpp-token" {
+			http.Error(w, `{"tppMessages":[{"code":"TOKEN_INVALID"}]}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/consents":
+			redirectURI = r.Header.Get("TPP-Redirect-URI")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"consentId":"c1","consentStatus":"received","_links":{"scaRedirect":{"href":"https://bank.example/authorize/c1"}}}`)
+		case "GET /v1/consents/c1/status":
+			fmt.Fprint(w, `{"consentStatus":"valid"}`)
+		case "GET /v1/accounts":
+			fmt.Fprint(w, `{"accounts":[{"resourceId":"r1","iban":"DK5000400440116243","currency":"DKK","name":"Budget","cashAccountType":"CACC"}]}`)
+		case "GET /v1/accounts/r1/balances":
+			fmt.Fprint(w, `{"balances":[{"balanceType":"interimAvailable","balanceAmount":{"currency":"DKK","amount":"20000.00"}},{"balanceType":"closingBooked","balanceAmount":{"currency":"DKK","amount":"12345.67"}}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(bank.Close)
+
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.AllowLoopbackAPIURLs = true })
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Budget", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.post("/settings/connections", url.Values{"broker_type": {"psd2"}, "country": {"dk"}, "api_url": {"http://bank.example/psd2"}, "tpp_token": {"tpp-token"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Bank API URL must be an https:// address") {
+		t.Error("plain HTTP bank API on another host was accepted")
+	}
+	resp, body = c.post("/settings/connections", url.Values{"broker_type": {"psd2"}, "country": {"dk"}, "api_url": {"https://169.254.169.254/psd2"}, "tpp_token": {"tpp-token"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Bank API URL must not point to a private or local address") {
+		t.Error("bank API on a link-local address was accepted")
+	}
+	resp, _ = c.post("/settings/connections", url.Values{"broker_type": {"psd2"}, "country": {"dk"}, "api_url": {bank.URL}, "tpp_token": {"tpp-token"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	base := resp.Header.Get("Location")
+	conn, err := srv.app.brokerConnRepo.GetByUserAndBroker(user.ID, "psd2")
+	if err != nil || conn == nil || conn.APIURL != bank.URL || conn.AppSecret != "tpp-token" {
+		t.Fatalf("connection = %+v, %v; want the bank API and token", conn, err)
+	}
+	t.Cleanup(func() { psd2.ClearConsent(conn.ID) })
+
+	if _, body = c.get(base); !strings.Contains(body, "Bank Access Required") {
+		t.Error("connection page does not ask to authorize bank access")
+	}
+
+	resp, _ = c.post(base+"/psd2/consent", nil)
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "https://bank.example/authorize/c1" {
+		t.Fatalf("start consent: status %d, location %q; want redirect to the bank", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if redirectURI != srv.URL+base+"/psd2/callback" {
+		t.Errorf("redirect URI = %q; want the callback", redirectURI)
+	}
+
+	resp, _ = c.get(base + "/psd2/callback")
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != base+"?psd2=ok" {
+		t.Fatalf("callback: status %d, location %q; want authorized", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if _, body = c.get(base + "?psd2=ok"); !strings.Contains(body, "Bank Access Authorized") {
+		t.Error("connection page does not show the authorized access")
+	}
+
+	resp, _ = c.post(base+"/fetch-accounts", url.Values{"nojs": {"1"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	c.waitForTask(base+"/task", `name="mapping_r1"`)
+	resp, _ = c.post(base+"/accounts", url.Values{"mapping_r1": {fmt.Sprint(accountID)}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	resp, _ = c.post(base+"/sync", url.Values{"nojs": {"1"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	c.waitForTask(base+"/task", "Sync complete")
+
+	if balance, err := srv.app.transactionRepo.GetLatestBalance(accountID); err != nil || balance != 12345.67 {
+		t.Errorf("balance after sync = %v, %v; want the booked balance 12345.67", balance, err)
+	}
+
+	// Another user cannot start a consent for the connection
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.post(base+"/psd2/consent", nil)
+	expectStatus(t, resp, http.StatusNotFound)
+}
+
+func TestE2E_LoopbackAPIURLsOnlyInTests(t *testing.T) {
+	srv := newTestServer(t)
+	srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	for _, apiURL := range []string{"http://localhost:8080/psd2", "http://127.0.0.1:8080/psd2", "http://[::1]/psd2"} {
+		resp, body := c.post("/settings/connections", url.Values{"broker_type": {"psd2"}, "country": {"dk"}, "api_url": {apiURL}, "tpp_token": {"tpp-token"}})
+		expectStatus(t, resp, http.StatusOK)
+		if !strings.Contains(body, "Bank API URL must be an https:// address") {
+			t.Errorf("plain HTTP bank API at %s was accepted", apiURL)
+		}
+	}
+	resp, body := c.post("/settings/connections", url.Values{"broker_type": {"coinbase"}, "country": {"dk"},
+		"exchange_key": {"cb-key"}, "exchange_secret": {"cb-secret"}, "exchange_api_url": {"http://localhost:8080"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Exchange API URL must be an https:// address") {
+		t.Error("plain HTTP exchange API on this machine was accepted")
+	}
+}
+
+func TestE2E_CryptoExchangeSync(t *testing.T) {
+	exchange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v2/accounts") && r.Header.Get("CB-ACCESS-KEY") != "cb-key" {
+			http.Error(w, `{"errors":[{"id":"authentication_error"}]}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2/accounts":
+			fmt.Fprint(w, `{"pagination":{"next_uri":null},"data":[
+				{"id":"a1","balance":{"amount":"0.5","currency":"BTC"}},
+				{"id":"a2","balance":{"amount":"100.00","currency":"USD"}},
+				{"id":"a3","balance":{"amount":"42","currency":"NOPE"}}]}`)
+		case "/v2/prices/BTC-USD/spot":
+			fmt.Fprint(w, `{"data":{"amount":"60000.00","base":"BTC","currency":"USD"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(exchange.Close)
+
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.AllowLoopbackAPIURLs = true })
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Coinbase", Currency: "USD", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+
+	resp, body := c.post("/settings/connections", url.Values{"broker_type": {"coinbase"}, "country": {"dk"}, "exchange_key": {"cb-key"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Exchange API key and secret are required") {
+		t.Error("connection without an API secret was accepted")
+	}
+	resp, body = c.post("/settings/connections", url.Values{"broker_type": {"coinbase"}, "country": {"dk"},
+		"exchange_key": {"cb-key"}, "exchange_secret": {"cb-secret"}, "exchange_api_url": {"https://10.0.0.1"}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Exchange API URL must not point to a private or local address") {
+		t.Error("exchange API on a private address was accepted")
+	}
+	resp, _ = c.post("/settings/connections", url.Values{"broker_type": {"coinbase"}, "country": {"dk"},
+		"exchange_key": {"cb-key"}, "exchange_secret": {"cb-secret"}, "exchange_api_url": {exchange.URL}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	base := resp.Header.Get("Location")
+
+	resp, _ = c.post(base+"/edit", url.Values{"broker_type": {"coinbase"}, "exchange_key": {"cb-key"}, "exchange_api_url": {exchange.URL}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	conn, err := srv.app.brokerConnRepo.GetByUserAndBroker(user.ID, "coinbase")
+	if err != nil || conn == nil || conn.AppKey != "cb-key" || conn.AppSecret != "cb-secret" || conn.APIURL != exchange.URL {
+		t.Fatalf("connection = %+v, %v; want the API key, the kept secret and the API URL", conn, err)
+	}
+
+	resp, _ = c.post(base+"/fetch-accounts", url.Values{"nojs": {"1"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	c.waitForTask(base+"/task", `name="mapping_spot"`)
+	resp, _ = c.post(base+"/accounts", url.Values{"mapping_spot": {fmt.Sprint(accountID)}})
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	resp, _ = c.post(base+"/sync", url.Values{"nojs": {"1"}})
+	expectStatus(t, resp, http.StatusSeeOther)
+	c.waitForTask(base+"/task", "Sync complete")
+
+	holdings, err := srv.app.holdingRepo.GetByAccountID(accountID)
+	if err != nil {
+		t.Fatalf("getting holdings: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, h := range holdings {
+		if h.InstrumentType != "crypto" || h.Currency != "USD" {
+			t.Errorf("holding %s = %s in %s; want crypto in USD", h.Symbol, h.InstrumentType, h.Currency)
+		}
+		values[h.Symbol] = h.CurrentValue
+	}
+	if len(values) != 2 || values["BTC"] != 30000 || values["NOPE"] != 0 {
+		t.Errorf("holding values = %v; want BTC at 30000 and NOPE without a value", values)
+	}
+	if balance, err := srv.app.transactionRepo.GetLatestBalance(accountID); err != nil || balance != 30100 {
+		t.Errorf("balance after sync = %v, %v; want the holdings and cash 30100", balance, err)
+	}
+}
+
+func TestE2E_AdminDatabaseMaintenance(t *testing.T) {
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.MaintenanceHour = 4 })
+	admin := srv.createUser(t, "admin@example.com", "password123")
+	if err := srv.app.userRepo.SetAdmin(admin.ID, true); err != nil {
+		t.Fatalf("setting admin: %v", err)
+	}
+	srv.createUser(t, "user@example.com", "password123")
+	c := srv.newClient(t)
+	c.login("admin@example.com", "password123")
+
+	resp, body := c.get("/admin/maintenance")
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Write-ahead log") || !strings.Contains(body, "between 04:00 and 04:59") {
+		t.Error("maintenance page does not show the database size and the maintenance window")
+	}
+
+	resp, _ = c.post("/admin/maintenance", url.Values{"operation": {"defrag"}})
+	if resp.Header.Get("Location") != "/admin/maintenance?error=unknown_operation" {
+		t.Errorf("unknown operation redirected to %q; want error=unknown_operation", resp.Header.Get("Location"))
+	}
+
+	resp, _ = c.post("/admin/maintenance", url.Values{"operation": {"all"}})
+	if resp.Header.Get("Location") != "/admin/maintenance" {
+		t.Fatalf("run all redirected to %q; want the maintenance page", resp.Header.Get("Location"))
+	}
+	body = c.waitForTask("/admin/maintenance", "Last run")
+	for _, want := range []string{"WAL frames copied", "Statistics updated", "vacuum"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("finished run does not show %q", want)
+		}
+	}
+
+	// Other users cannot run maintenance
+	other := srv.newClient(t)
+	other.login("user@example.com", "password123")
+	resp, _ = other.post("/admin/maintenance", url.Values{"operation": {"vacuum"}})
+	if resp.StatusCode == http.StatusSeeOther && resp.Header.Get("Location") == "/admin/maintenance" {
+		t.Error("a non-admin started maintenance")
+	}
+}
+
+func TestE2E_NetWorthHistoryAPI(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	categoryID, err := srv.app.categoryRepo.Create(&models.Category{UserID: user.ID, Name: "Aktier", Color: "#123456"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, CategoryID: &categoryID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	// Two days recorded, the second one twice
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	for i, balance := range []float64{1000, 1200, 1500} {
+		if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: balance, BalanceAfter: balance, TransactionDate: day}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+		if err := srv.app.netWorthSnapshots.Record(user, day.Add(time.Duration(i)*12*time.Hour)); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	resp, body := c.get("/api/networth/history")
+	expectStatus(t, resp, http.StatusOK)
+	var history struct {
+		Categories map[int64]struct{ Name string }
+		Snapshots  []*models.NetWorthSnapshot
+	}
+	if err := json.Unmarshal([]byte(body), &history); err != nil {
+		t.Fatalf("decoding history: %v", err)
+	}
+	if len(history.Snapshots) != 2 || history.Snapshots[0].NetWorth != 1000 || history.Snapshots[1].NetWorth != 1500 {
+		t.Fatalf("snapshots = %s; want 1000 then the day's latest 1500", body)
+	}
+	if history.Snapshots[1].Categories[categoryID] != 1500 || history.Categories[categoryID].Name != "Aktier" {
+		t.Errorf("history = %s; want the depot's category total and name", body)
+	}
+
+	_, body = c.get("/api/networth/history?from=2024-03-02&to=2024-03-31")
+	if err := json.Unmarshal([]byte(body), &history); err != nil || len(history.Snapshots) != 1 {
+		t.Errorf("ranged history = %s; want only the second day", body)
+	}
+	resp, _ = c.get("/api/networth/history?from=March")
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	// Other users see only their own history
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	_, body = other.get("/api/networth/history")
+	if err := json.Unmarshal([]byte(body), &history); err != nil || len(history.Snapshots) != 0 {
+		t.Errorf("other user's history = %s; want none", body)
+	}
+}
+
+func TestE2E_GoalFundingComparesContributionsWithPlan(t *testing.T) {
+	srv := newTestServer(t)
+	user := srv.createUser(t, "user@example.com", "password123")
+	other := srv.createUser(t, "other@example.com", "password123")
+	savingsID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	otherID, err := srv.app.accountRepo.Create(&models.Account{UserID: other.ID, Name: "Not mine", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	form := url.Values{
+		"name":                 {"Emergency fund"},
+		"target_amount":        {"50000"},
+		"target_currency":      {"DKK"},
+		"monthly_contribution": {"2000"},
+		"funding_account_id":   {fmt.Sprint(savingsID), fmt.Sprint(otherID)},
+	}
+	resp, _ := c.post("/goals", form)
+	expectStatus(t, resp, http.StatusSeeOther)
+
+	goals, err := srv.app.goalRepo.GetByUserID(user.ID)
+	if err != nil || len(goals) != 1 {
+		t.Fatalf("GetByUserID() = %v, %v; want the created goal", goals, err)
+	}
+	goal := goals[0]
+	if goal.MonthlyContribution != 2000 || len(goal.FundingAccountIDs) != 1 || goal.FundingAccountIDs[0] != savingsID {
+		t.Fatalf("goal = %+v; want 2000 planned into the user's own savings account only", goal)
+	}
+
+	// A deposit and a revaluation this period; only the deposit is contributed
+	now := time.Now()
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: savingsID, Amount: 1500, BalanceAfter: 1500, TransactionDate: now}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	if _, err := srv.app.transactionRepo.Create(&models.Transaction{AccountID: savingsID, Kind: models.TransactionValuation, Amount: 300, BalanceAfter: 1800, TransactionDate: now}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	_, body := c.get("/goals")
+	if !strings.Contains(body, "Contributed this month") || !strings.Contains(body, "1.500 of 2.000 planned") {
+		t.Error("goals page does not compare the contribution with the plan")
+	}
+	_, body = c.get(fmt.Sprintf("/goals/%d", goal.ID))
+	if !strings.Contains(body, "Contributed This Month") || !strings.Contains(body, "still to go this month") {
+		t.Error("goal page does not show the contribution this month")
+	}
+
+	form.Set("monthly_contribution", "-5")
+	resp, _ = c.post(fmt.Sprintf("/goals/%d", goal.ID), form)
+	expectStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestE2E_HoldingHistoryBackfill(t *testing.T) {
+	today := time.Now()
+	prices := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/history/DK0010244508" {
+			http.NotFound(w, r)
+			return
+		}
+		var points []map[string]any
+		for daysAgo := 5; daysAgo >= 1; daysAgo-- {
+			points = append(points, map[string]any{"date": today.AddDate(0, 0, -daysAgo).Format("2006-01-02"), "close": 100 + daysAgo})
+		}
+		json.NewEncoder(w).Encode(points)
+	}))
+	defer prices.Close()
+
+	srv := newTestServer(t, func(cfg *config.Config) { cfg.PriceHistoryURL = prices.URL + "/history/{isin}?from={from}&to={to}" })
+	user := srv.createUser(t, "user@example.com", "password123")
+	accountID, err := srv.app.accountRepo.Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	holding := &models.Holding{AccountID: accountID, ExternalID: "1", Symbol: "DK0010244508", Name: "Maersk B", Quantity: 2, CurrentValue: 200, Currency: "DKK"}
+	if err := srv.app.holdingRepo.Upsert(holding); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	holdings, _ := srv.app.holdingRepo.GetByAccountID(accountID)
+	path := fmt.Sprintf("/accounts/%d/holdings/%d/backfill", accountID, holdings[0].ID)
+
+	c := srv.newClient(t)
+	c.login("user@example.com", "password123")
+	_, body := c.get("/accounts")
+	if !strings.Contains(body, "Backfill history") {
+		t.Error("accounts page does not offer to backfill holdings")
+	}
+
+	// Without acquisitions the start must be chosen
+	_, body = c.post(path, url.Values{})
+	if !strings.Contains(body, "choose a date to backfill from") {
+		t.Error("backfill without a start date is not refused")
+	}
+
+	resp, body := c.post(path, url.Values{"from": {today.AddDate(0, 0, -10).Format("2006-01-02")}})
+	expectStatus(t, resp, http.StatusOK)
+	if !strings.Contains(body, "Backfilled 5 days of Maersk B history") {
+		t.Errorf("backfill does not report the days added")
+	}
+	snapshots, err := srv.app.holdingRepo.GetSnapshotsAt(user.ID, today.AddDate(0, 0, -3))
+	if err != nil || len(snapshots) != 1 || snapshots[0].Quantity != 2 || snapshots[0].CurrentValue != 206 {
+		t.Errorf("snapshot 3 days ago = %+v, %v; want 2 at 103", snapshots, err)
+	}
+
+	// Holdings of other users cannot be backfilled
+	srv.createUser(t, "other@example.com", "password123")
+	other := srv.newClient(t)
+	other.login("other@example.com", "password123")
+	resp, _ = other.post(path, url.Values{"from": {today.AddDate(0, 0, -10).Format("2006-01-02")}})
+	expectStatus(t, resp, http.StatusForbidden)
+}
+
+func TestJobLeader_OneInstanceLeads(t *testing.T) {
+	srv := newTestServer(t)
+	encryptor, err := broker.NewEncryptor("test-encryption-secret-32-chars!")
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+	shared := store.NewSQLite(srv.app.db, encryptor)
+
+	if !newJobLeader(nil).Leads() {
+		t.Error("an instance without shared state does not lead")
+	}
+
+	first, second := newJobLeader(shared), newJobLeader(shared)
+	stopFirst := first.start()
+	stopSecond := second.start()
+	defer stopSecond()
+	if !first.Leads() || second.Leads() {
+		t.Fatalf("Leads() = %v, %v; want only the first instance to lead", first.Leads(), second.Leads())
+	}
+
+	// The lease passes on when its holder stops
+	stopFirst()
+	second.renew()
+	if first.Leads() || !second.Leads() {
+		t.Errorf("after the first stopped Leads() = %v, %v; want the second to lead", first.Leads(), second.Leads())
+	}
+}
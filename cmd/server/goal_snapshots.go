@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// goalSnapshotInterval is how often goal progress is recorded. Each run
+// updates the snapshot of the current week.
+const goalSnapshotInterval = 6 * time.Hour
+
+// startGoalSnapshots records the progress of all goals now and then every
+// goalSnapshotInterval until the returned stop function is called, when
+// this instance is the job leader.
+func startGoalSnapshots(svc *services.GoalSnapshotService, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(goalSnapshotInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				recordGoalSnapshots(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// recordGoalSnapshots records the progress of all goals and logs failures.
+func recordGoalSnapshots(svc *services.GoalSnapshotService) {
+	if _, err := svc.RecordAll(time.Now()); err != nil {
+		log.Printf("[Goals] Recording goal progress failed: %v", err)
+	}
+}
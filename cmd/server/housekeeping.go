@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+	"wealth_tracker/internal/store"
+)
+
+// staleQRDirAge is how long the QR code directory of a MitID login that is
+// no longer running is kept.
+const staleQRDirAge = time.Hour
+
+// newHousekeepingService creates the service cleaning up expired sessions,
+// leftover MitID QR files, expired broker sessions and idle rate limit
+// buckets, and expired state in the state store.
+func newHousekeepingService(sessionManager *auth.SessionManager, stateStore store.Store) *services.HousekeepingService {
+	tasks := []services.HousekeepingTask{
+		{Name: "Expired sessions", Clean: func(time.Time) (int, error) {
+			n, err := sessionManager.CleanExpired()
+			return int(n), err
+		}},
+		{Name: "MitID QR files", Clean: func(now time.Time) (int, error) {
+			return nordnet.RemoveStaleQRDirs(now.Add(-staleQRDirAge))
+		}},
+		{Name: "Nordnet sessions", Clean: func(now time.Time) (int, error) {
+			return nordnet.DropExpiredSessions(now), nil
+		}},
+		{Name: "Saxo sessions", Clean: func(now time.Time) (int, error) {
+			return saxo.DropExpiredSessions(now), nil
+		}},
+		{Name: "Rate limit buckets", Clean: func(now time.Time) (int, error) {
+			return middleware.SweepRateLimiters(now), nil
+		}},
+		{Name: "Shared state", Clean: stateStore.DeleteExpired},
+	}
+	return services.NewHousekeepingService(tasks...)
+}
+
+// startHousekeeping runs housekeeping now and then every interval until the
+// returned stop function is called. It does nothing if interval is not
+// positive.
+func startHousekeeping(svc *services.HousekeepingService, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			runHousekeeping(svc)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// runHousekeeping runs the housekeeping tasks and logs what they removed.
+func runHousekeeping(svc *services.HousekeepingService) {
+	run := svc.Run(time.Now())
+	for _, result := range run.Results {
+		if result.Err != nil {
+			log.Printf("[Housekeeping] %s failed: %v", result.Task, result.Err)
+		}
+		if result.Removed > 0 {
+			log.Printf("[Housekeeping] %s: removed %d", result.Task, result.Removed)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// interestAccrualInterval is how often liabilities are checked for months to
+// post interest for. Each month is posted once, soon after it ends.
+const interestAccrualInterval = 6 * time.Hour
+
+// startInterestAccrual posts the interest of liabilities now and then every
+// interestAccrualInterval until the returned stop function is called, when
+// this instance is the job leader.
+func startInterestAccrual(svc *services.InterestAccrualService, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(interestAccrualInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				accrueInterest(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// accrueInterest posts the interest of all liabilities and logs the result.
+func accrueInterest(svc *services.InterestAccrualService) {
+	posted, err := svc.AccrueAll(time.Now())
+	if err != nil {
+		log.Printf("[Interest] Accruing interest failed: %v", err)
+		return
+	}
+	if posted > 0 {
+		log.Printf("[Interest] Posted %d monthly interest transaction(s)", posted)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	stdsync "sync"
+	"sync/atomic"
+	"time"
+
+	"wealth_tracker/internal/store"
+)
+
+// jobLeaseKey is the shared state key of the lease held by the instance that
+// runs the periodic jobs.
+const jobLeaseKey = "lease:jobs"
+
+// The lease is renewed well before it expires, so a held lease only moves to
+// another instance when its holder stops.
+const (
+	jobLeaseTTL           = 2 * time.Minute
+	jobLeaseRenewInterval = 30 * time.Second
+)
+
+// jobLeader tells whether this instance runs the periodic jobs that must run
+// once for all instances, such as digests, snapshots and scheduled syncs.
+// Instances sharing state hold a lease in turn; an instance without shared
+// state is the only one and always runs them.
+type jobLeader struct {
+	store   store.Store // Nil if state is not shared
+	owner   string
+	leading atomic.Bool
+}
+
+// newJobLeader creates a jobLeader taking the lease in shared, which may be
+// nil.
+func newJobLeader(shared store.Store) *jobLeader {
+	l := &jobLeader{store: shared, owner: instanceName()}
+	l.leading.Store(shared == nil)
+	return l
+}
+
+// Leads reports whether this instance runs the periodic jobs.
+func (l *jobLeader) Leads() bool {
+	return l.leading.Load()
+}
+
+// start takes the lease if it is free and renews it every
+// jobLeaseRenewInterval until the returned stop function is called, which
+// gives it up.
+func (l *jobLeader) start() (stop func()) {
+	if l.store == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	l.renew()
+	go func() {
+		ticker := time.NewTicker(jobLeaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.renew()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			if l.leading.Swap(false) {
+				if err := l.store.Delete(jobLeaseKey); err != nil {
+					log.Printf("[Jobs] Giving up the job lease failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// renew claims the lease and logs when this instance starts or stops running
+// the jobs. An instance that cannot reach the store stops running them.
+func (l *jobLeader) renew() {
+	leads, err := l.store.Claim(jobLeaseKey, l.owner, jobLeaseTTL)
+	if err != nil {
+		log.Printf("[Jobs] Renewing the job lease failed: %v", err)
+	}
+	if was := l.leading.Swap(leads); was != leads {
+		if leads {
+			log.Printf("[Jobs] Instance %s now runs the periodic jobs", l.owner)
+		} else {
+			log.Printf("[Jobs] Instance %s no longer runs the periodic jobs", l.owner)
+		}
+	}
+}
+
+// instanceName returns a name for this instance that is unique among the
+// instances sharing state.
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "instance"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
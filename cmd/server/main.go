@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -18,52 +19,110 @@ import (
 	chimw "github.com/go-chi/chi/v5/middleware"
 
 	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/mock"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/broker/saxo"
 	"wealth_tracker/internal/config"
 	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/dates"
 	"wealth_tracker/internal/demo"
 	"wealth_tracker/internal/handlers"
+	"wealth_tracker/internal/logfile"
+	"wealth_tracker/internal/mail"
+	"wealth_tracker/internal/markdown"
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/money"
+	"wealth_tracker/internal/release"
 	"wealth_tracker/internal/repository"
 	"wealth_tracker/internal/services"
+	"wealth_tracker/internal/store"
 	"wealth_tracker/internal/sync"
 )
 
 // App holds the application dependencies.
 type App struct {
-	config             *config.Config
-	db                 *database.DB
-	templates          TemplateCache
-	router             *chi.Mux
-	userRepo           *repository.UserRepository
-	categoryRepo       *repository.CategoryRepository
-	accountRepo        *repository.AccountRepository
-	transactionRepo    *repository.TransactionRepository
-	goalRepo           *repository.GoalRepository
-	brokerConnRepo     *repository.BrokerConnectionRepository
-	holdingRepo        *repository.HoldingRepository
-	mappingRepo        *repository.AccountMappingRepository
-	syncHistoryRepo    *repository.SyncHistoryRepository
-	sessionManager     *auth.SessionManager
-	authMiddleware     *middleware.AuthMiddleware
-	authHandler        *handlers.AuthHandler
-	dashHandler        *handlers.DashboardHandler
-	categoryHandler    *handlers.CategoryHandler
-	accountHandler     *handlers.AccountHandler
-	transactionHandler *handlers.TransactionHandler
-	goalHandler        *handlers.GoalHandler
-	settingsHandler    *handlers.SettingsHandler
-	toolsHandler       *handlers.ToolsHandler
-	adminHandler       *handlers.AdminHandler
-	exportHandler      *handlers.ExportHandler
-	brokerHandler      *handlers.BrokerHandler
-	portfolioHandler   *handlers.PortfolioHandler
+	config              *config.Config
+	db                  *database.DB
+	templates           TemplateCache
+	router              *chi.Mux
+	userRepo            *repository.UserRepository
+	categoryRepo        *repository.CategoryRepository
+	accountRepo         *repository.AccountRepository
+	transactionRepo     *repository.TransactionRepository
+	goalRepo            *repository.GoalRepository
+	brokerConnRepo      *repository.BrokerConnectionRepository
+	holdingRepo         *repository.HoldingRepository
+	mappingRepo         *repository.AccountMappingRepository
+	syncHistoryRepo     *repository.SyncHistoryRepository
+	apiKeyRepo          *repository.AccountAPIKeyRepository
+	apiTokenRepo        *repository.APITokenRepository
+	commentRepo         *repository.CommentRepository
+	savedFilterRepo     *repository.SavedFilterRepository
+	brokerPerfRepo      *repository.BrokerPerformanceRepository
+	notifyChannelRepo   *repository.NotificationChannelRepository
+	ruleRepo            *repository.CategorizationRuleRepository
+	usageService        *services.UsageService
+	digestService       *services.DigestService // Nil if email is not configured
+	goalSnapshotService *services.GoalSnapshotService
+	netWorthSnapshots   *services.NetWorthSnapshotService
+	interestService     *services.InterestAccrualService
+	retentionService    *services.RetentionService
+	housekeepingService *services.HousekeepingService
+	maintenanceService  *services.MaintenanceService
+	sharedStore         store.Store  // Nil unless state is shared with other instances
+	demoSeeder          *demo.Seeder // Nil outside demo mode
+	syncService         *sync.Service
+	mockBroker          *mock.Server // Answers broker requests with MOCK_BROKER; nil otherwise
+	sessionManager      *auth.SessionManager
+	authMiddleware      *middleware.AuthMiddleware
+	apiKeyAuth          *middleware.AccountAPIKeyAuth
+	apiTokenAuth        *middleware.APITokenAuth
+	authHandler         *handlers.AuthHandler
+	dashHandler         *handlers.DashboardHandler
+	categoryHandler     *handlers.CategoryHandler
+	accountHandler      *handlers.AccountHandler
+	transactionHandler  *handlers.TransactionHandler
+	goalHandler         *handlers.GoalHandler
+	settingsHandler     *handlers.SettingsHandler
+	exchangeRateHandler *handlers.ExchangeRateHandler
+	apiKeyHandler       *handlers.APIKeyHandler
+	apiHandler          *handlers.APIHandler
+	apiTokenHandler     *handlers.APITokenHandler
+	advisorHandler      *handlers.AdvisorHandler
+	notificationHandler *handlers.NotificationHandler
+	usageHandler        *handlers.UsageHandler
+	dataQualityHandler  *handlers.DataQualityHandler
+	compareHandler      *handlers.CompareHandler
+	commandHandler      *handlers.CommandHandler
+	ruleHandler         *handlers.RuleHandler
+	toolsHandler        *handlers.ToolsHandler
+	adminHandler        *handlers.AdminHandler
+	exportHandler       *handlers.ExportHandler
+	brokerHandler       *handlers.BrokerHandler
+	portfolioHandler    *handlers.PortfolioHandler
+	grafanaHandler      *handlers.GrafanaHandler
+	releaseHandler      *handlers.ReleaseHandler
+	accessLog           io.Writer
+	startupIssues       []string
 }
 
 func main() {
 	// Load configuration
 	cfg := config.New()
 
+	if len(os.Args) > 1 && os.Args[1] == "check-db" {
+		os.Exit(runCheckDB(cfg, os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export-replica" {
+		os.Exit(runExportReplica(cfg, os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sync-all" {
+		os.Exit(runSyncAll(cfg, os.Args[2:], os.Stdout))
+	}
+
 	// Initialize database
 	db, err := database.New(cfg.DBPath)
 	if err != nil {
@@ -71,32 +130,159 @@ func main() {
 	}
 	defer db.Close()
 
-	// Run migrations
+	// Run migrations. Failures and config problems are shown to admins
+	// instead of stopping the app, so they can be seen without server access.
+	var startupIssues []string
 	if err := db.RunMigrations(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		log.Printf("Failed to run migrations: %v", err)
+		startupIssues = append(startupIssues, fmt.Sprintf("Database migrations are pending: %v", err))
+	} else {
+		log.Println("Database migrations completed")
+	}
+	for _, problem := range cfg.Problems() {
+		log.Printf("Configuration problem: %s", problem)
+		startupIssues = append(startupIssues, problem)
+	}
+
+	app, err := newApp(cfg, db, startupIssues...)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
+	}
+
+	// Create server
+	server := &http.Server{
+		Addr:         cfg.Address(),
+		Handler:      app.router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Keep cached Nordnet sessions alive between syncs
+	stopKeepAlive := nordnet.StartSessionKeepAlive(nordnet.SessionKeepAliveInterval)
+
+	// Keep the analytics replica fresh
+	stopReplicaExport := startReplicaExport(db, cfg)
+
+	// Run the periodic jobs below, but for housekeeping, on one of the
+	// instances sharing state
+	leader := newJobLeader(app.sharedStore)
+	stopLeader := leader.start()
+
+	// Send email digests as they fall due
+	stopDigests := startDigests(app.digestService, leader)
+
+	// Record goal progress for the burn-up charts
+	stopGoalSnapshots := startGoalSnapshots(app.goalSnapshotService, leader)
+
+	// Record daily net worth for the history API
+	stopNetWorthSnapshots := startNetWorthSnapshots(app.netWorthSnapshots, leader)
+
+	// Post monthly interest on liabilities
+	stopInterestAccrual := startInterestAccrual(app.interestService, leader)
+
+	// Delete expired demo sandboxes
+	stopSandboxCleanup := startSandboxCleanup(app.demoSeeder, leader)
+
+	// Delete and thin history rows past their retention
+	stopRetention := startRetention(app.retentionService, leader)
+
+	// Clean up expired sessions, leftover QR files and idle rate limits
+	stopHousekeeping := startHousekeeping(app.housekeepingService, time.Duration(cfg.HousekeepingIntervalMinutes)*time.Minute)
+
+	// Checkpoint, analyze and vacuum the database in its maintenance window
+	stopMaintenance := startMaintenance(app.maintenanceService, leader)
+
+	// Remind users of broker logins that are expiring or stale
+	stopCredentialChecks := startCredentialChecks(app.syncService, leader)
+
+	// Sync connections on their schedules
+	stopScheduledSyncs := startScheduledSyncs(app.syncService, leader)
+
+	// Start server in goroutine
+	go func() {
+		log.Printf("Server starting on http://%s", cfg.Address())
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+	stopKeepAlive()
+	stopReplicaExport()
+	stopDigests()
+	stopGoalSnapshots()
+	stopNetWorthSnapshots()
+	stopInterestAccrual()
+	stopSandboxCleanup()
+	stopRetention()
+	stopHousekeeping()
+	stopMaintenance()
+	stopCredentialChecks()
+	stopScheduledSyncs()
+	stopLeader()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
 	}
-	log.Println("Database migrations completed")
+
+	log.Println("Server stopped")
+}
+
+// newApp wires repositories, services and handlers on top of a migrated
+// database and sets up the router. Startup issues are shown to admins.
+func newApp(cfg *config.Config, db *database.DB, startupIssues ...string) (*App, error) {
+	// Record the release before any default users are created, so users
+	// from a fresh install are not shown what's new
+	versionRepo := repository.NewAppVersionRepository(db)
+	startupIssues = append(startupIssues, recordRelease(versionRepo)...)
 
 	// Create repositories early for admin check
 	userRepo := repository.NewUserRepository(db)
 
 	// In demo mode, seed demo data; otherwise create default admin
+	var demoSeeder *demo.Seeder
 	if cfg.DemoMode {
-		seeder := demo.NewSeeder(db)
-		if err := seeder.SeedIfEmpty(); err != nil {
-			log.Fatalf("Failed to seed demo data: %v", err)
+		demoSeeder = demo.NewSeeder(db)
+		if err := demoSeeder.SeedIfEmpty(); err != nil {
+			return nil, fmt.Errorf("seeding demo data: %w", err)
 		}
 	} else {
 		// Create default admin if no users exist
 		if err := ensureDefaultAdmin(userRepo); err != nil {
-			log.Fatalf("Failed to ensure default admin: %v", err)
+			return nil, fmt.Errorf("ensuring default admin: %w", err)
+		}
+	}
+
+	// Feature flags are checked by templates, so they come before parsing
+	featureFlagService := services.NewFeatureFlagService(repository.NewFeatureFlagRepository(db), cfg.FeatureFlags)
+	if unknown := services.UnknownFeatures(cfg.FeatureFlags); len(unknown) > 0 {
+		startupIssues = append(startupIssues, fmt.Sprintf("FEATURE_FLAGS has unknown features: %s.", strings.Join(unknown, ", ")))
+	}
+
+	// Access log goes to a rotated file when configured, otherwise stdout
+	var accessLog io.Writer = os.Stdout
+	if cfg.AccessLogFile != "" {
+		file, err := logfile.Open(cfg.AccessLogFile, cfg.AccessLogRetentionDays)
+		if err != nil {
+			log.Printf("Failed to open access log: %v", err)
+			startupIssues = append(startupIssues, fmt.Sprintf("ACCESS_LOG_FILE cannot be opened, so requests are logged to stdout: %v", err))
+		} else {
+			accessLog = file
 		}
 	}
 
 	// Parse templates
-	templates, err := parseTemplates()
+	templates, err := parseTemplates(startupIssues, featureFlagService)
 	if err != nil {
-		log.Fatalf("Failed to parse templates: %v", err)
+		return nil, fmt.Errorf("parsing templates: %w", err)
 	}
 
 	// Create repositories (userRepo already created above for admin check)
@@ -104,123 +290,329 @@ func main() {
 	accountRepo := repository.NewAccountRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
 	goalRepo := repository.NewGoalRepository(db)
+	goalSnapshotRepo := repository.NewGoalSnapshotRepository(db)
+	interestAccrualRepo := repository.NewInterestAccrualRepository(db)
 	brokerConnRepo := repository.NewBrokerConnectionRepository(db)
 	holdingRepo := repository.NewHoldingRepository(db)
+	holdingAcquisitionRepo := repository.NewHoldingAcquisitionRepository(db)
 	mappingRepo := repository.NewAccountMappingRepository(db)
 	syncHistoryRepo := repository.NewSyncHistoryRepository(db)
+	mitidAttemptRepo := repository.NewMitIDAttemptRepository(db)
 	allocationTargetRepo := repository.NewAllocationTargetRepository(db)
+	rebalanceSessionRepo := repository.NewRebalanceSessionRepository(db)
+	watchlistRepo := repository.NewWatchlistRepository(db)
+	exclusionRepo := repository.NewAnalyticsExclusionRepository(db)
+	labelRepo := repository.NewHoldingLabelRepository(db)
+	chartColorRepo := repository.NewChartColorRepository(db)
+	exchangeRateRepo := repository.NewExchangeRateRepository(db)
+	apiKeyRepo := repository.NewAccountAPIKeyRepository(db)
+	apiTokenRepo := repository.NewAPITokenRepository(db)
+	advisorShareRepo := repository.NewAdvisorShareRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	savedFilterRepo := repository.NewSavedFilterRepository(db)
+	digestRepo := repository.NewEmailDigestRepository(db)
+	milestoneRepo := repository.NewMilestoneRepository(db)
+	brokerPerfRepo := repository.NewBrokerPerformanceRepository(db)
+	pendingInvestmentRepo := repository.NewPendingInvestmentRepository(db)
+	encryptor, err := broker.NewEncryptor(cfg.EncryptionSecret)
+	if err != nil {
+		return nil, fmt.Errorf("creating encryptor: %w", err)
+	}
+	notificationChannelRepo := repository.NewNotificationChannelRepository(db, encryptor)
+	ruleRepo := repository.NewCategorizationRuleRepository(db)
+	categorizer := services.NewCategorizer(ruleRepo)
+	notifier := services.NewNotifier(notificationChannelRepo)
+	notifier.SetAllowPrivateNetworks(cfg.NotifyPrivateNetworks)
+	usageService := services.NewUsageService(repository.NewUsageRepository(db), map[string]int{
+		models.UsageSync:       cfg.SyncQuota,
+		models.UsageMarketData: cfg.MarketDataQuota,
+		models.UsageAPI:        cfg.APIQuota,
+	})
+
+	if n, err := notificationChannelRepo.EncryptStored(); err != nil {
+		log.Printf("Error encrypting notification channels: %v", err)
+	} else if n > 0 {
+		log.Printf("Encrypted the secrets of %d notification channels", n)
+	}
+
+	// Holdings synced before currencies were recorded count towards the
+	// currency exposure of their instrument, not that of their account
+	if n, err := holdingRepo.BackfillCurrencies(); err != nil {
+		log.Printf("Error backfilling holding currencies: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled the currency of %d holdings", n)
+	}
 
 	// Get scripts directory for MitID authentication
 	workDir, _ := os.Getwd()
 	scriptDir := filepath.Join(workDir, "scripts")
 
 	// Create sync service
-	syncService := sync.NewService(brokerConnRepo, holdingRepo, mappingRepo, syncHistoryRepo, transactionRepo, scriptDir)
+	syncService := sync.NewService(brokerConnRepo, holdingRepo, mappingRepo, syncHistoryRepo, transactionRepo, mitidAttemptRepo, scriptDir)
+	balanceChecker := services.NewBalanceChecker(transactionRepo, float64(cfg.BalanceAnomalyPercent))
+	syncService.SetStaleDeleteThreshold(float64(cfg.SyncMaxDeletePercent) / 100)
+	syncService.SetBalanceChecker(balanceChecker)
+	syncService.SetCategorizer(categorizer)
+	syncService.SetUserRepository(userRepo)
+	syncService.SetAccountRepository(accountRepo)
+	syncService.SetPerformanceRepository(brokerPerfRepo)
+	syncService.SetNotifier(notifier)
+	syncService.SetUsage(usageService)
+	syncService.SetPendingInvestmentRepository(pendingInvestmentRepo)
+	syncService.SetCredentialFreshness(time.Duration(cfg.SaxoRefreshWarnDays)*24*time.Hour, time.Duration(cfg.NordnetAuthStaleDays)*24*time.Hour)
+	broker.SetDefaultHTTPConfig(broker.HTTPConfig{ProxyURL: cfg.BrokerProxyURL, UserAgent: cfg.BrokerUserAgent})
+
+	// Keep broker sessions and bank consents in the database across
+	// restarts, and share rate limits with other instances
+	stateStore := store.NewSQLite(db, encryptor)
+	nordnet.SetSessionStore(stateStore)
+	saxo.SetSessionStore(stateStore)
+	psd2.SetConsentStore(stateStore)
+	restoreBrokerSessions(brokerConnRepo)
+	var sharedStore store.Store
+	if cfg.StateStore == "sqlite" {
+		sharedStore = stateStore
+		middleware.SetRateLimitStore(sharedStore)
+	}
+	// Answer Nordnet and Saxo requests from recorded payloads, and sign in
+	// without MitID or OAuth
+	var mockBroker *mock.Server
+	if cfg.MockBroker && cfg.IsDevelopment {
+		mockBroker = mock.Start()
+		syncService.SetSignIn(mockBroker.SignIn)
+		log.Printf("Mock broker enabled at %s", mockBroker.URL)
+	}
+
+	// Create portfolio service; values are converted to DKK using provider
+	// rates, falling back to the user's manual rates
+	currencyService := services.NewCurrencyService(db)
+	currencyService.SetUsage(usageService)
+	portfolioService := services.NewPortfolioServiceWithCurrency(accountRepo, holdingRepo, categoryRepo, transactionRepo, allocationTargetRepo, currencyService, "DKK")
+	portfolioService.SetBrokerPerformanceRepository(brokerPerfRepo)
+	portfolioService.SetExclusionRepository(exclusionRepo)
+	portfolioService.SetLabelRepository(labelRepo)
+	portfolioService.SetChartColorService(services.NewChartColorService(chartColorRepo, categoryRepo, accountRepo))
+	taxParameterService := services.NewTaxParameterService(repository.NewTaxParameterRepository(db))
+	portfolioService.SetTaxParameterService(taxParameterService)
+	watchlistService := services.NewWatchlistService(watchlistRepo, holdingRepo)
+
+	// Net worth in each user's currency, shared by every page and job showing it
+	netWorthService := services.NewNetWorthService(userRepo, accountRepo, transactionRepo, currencyService)
+
+	// Create digest service if the server can send email
+	var digestService *services.DigestService
+	if cfg.EmailEnabled() && !cfg.DemoMode {
+		sender := mail.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		digestService = services.NewDigestService(userRepo, netWorthService, transactionRepo, goalRepo, digestRepo, sender)
+	}
+
+	// Create goal progress history service
+	goalSnapshotService := services.NewGoalSnapshotService(userRepo, netWorthService, goalRepo, goalSnapshotRepo)
+	netWorthSnapshots := services.NewNetWorthSnapshotService(userRepo, netWorthService, transactionRepo, repository.NewNetWorthSnapshotRepository(db))
+
+	// Create liability interest service
+	interestService := services.NewInterestAccrualService(accountRepo, transactionRepo, interestAccrualRepo)
+
+	// Create history table retention service
+	retentionService := services.NewRetentionService(repository.NewRetentionPolicyRepository(db))
 
-	// Create portfolio service
-	portfolioService := services.NewPortfolioService(accountRepo, holdingRepo, categoryRepo, transactionRepo, allocationTargetRepo)
+	// Create Grafana datasource service
+	grafanaService := services.NewGrafanaService(accountRepo, transactionRepo, categoryRepo, netWorthService)
 
 	// Create session manager
 	sessionManager := auth.NewSessionManager(db)
 
+	// Create housekeeping service
+	housekeepingService := newHousekeepingService(sessionManager, stateStore)
+
+	// Create database maintenance service
+	maintenanceService := services.NewMaintenanceService(db, cfg.MaintenanceHour)
+
+	// Out-of-range settings are reported by cfg.Problems and fall back to
+	// the defaults
+	passwordPolicy := auth.DefaultPasswordPolicy
+	if cfg.PasswordMinLength > passwordPolicy.MinLength {
+		passwordPolicy.MinLength = cfg.PasswordMinLength
+	}
+	if cfg.PasswordMinScore >= 0 && cfg.PasswordMinScore <= 4 {
+		passwordPolicy.MinScore = cfg.PasswordMinScore
+	}
+	passwordPolicy.BreachDir = cfg.PasswordBreachDir
+
 	// Create middleware
 	authMiddleware := middleware.NewAuthMiddleware(sessionManager, userRepo)
+	apiKeyAuth := middleware.NewAccountAPIKeyAuth(apiKeyRepo)
+	apiTokenAuth := middleware.NewAPITokenAuth(apiTokenRepo, userRepo)
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(templates, userRepo, sessionManager)
-	dashHandler := handlers.NewDashboardHandler(templates, accountRepo, transactionRepo, goalRepo, categoryRepo)
+	authHandler.SetPasswordPolicy(passwordPolicy)
+	if demoSeeder != nil {
+		authHandler.SetDemoSeeder(demoSeeder)
+	}
+	dashHandler := handlers.NewDashboardHandler(templates, userRepo, accountRepo, transactionRepo, goalRepo, categoryRepo, milestoneRepo, netWorthService)
+	dashHandler.SetNetWorthChangeService(services.NewNetWorthChangeService(accountRepo, transactionRepo, currencyService))
+	dashHandler.SetCredentialChecker(syncService)
+	dashHandler.SetNetWorthSnapshotService(netWorthSnapshots)
 	categoryHandler := handlers.NewCategoryHandler(templates, categoryRepo, accountRepo)
-	accountHandler := handlers.NewAccountHandler(templates, accountRepo, categoryRepo, transactionRepo, holdingRepo)
-	transactionHandler := handlers.NewTransactionHandler(templates, transactionRepo, accountRepo, categoryRepo)
-	goalHandler := handlers.NewGoalHandler(templates, goalRepo, accountRepo, transactionRepo, categoryRepo)
+	accountHandler := handlers.NewAccountHandler(templates, accountRepo, categoryRepo, transactionRepo, holdingRepo, holdingAcquisitionRepo, mappingRepo, brokerConnRepo, interestAccrualRepo, balanceChecker)
+	accountHandler.SetSnapshotRepository(repository.NewAccountSnapshotRepository(db))
+	accountHandler.SetCategorizer(categorizer)
+	accountHandler.SetPendingInvestmentRepository(pendingInvestmentRepo)
+	if cfg.PriceHistoryURL != "" {
+		priceBackfill := services.NewPriceBackfillService(holdingRepo, holdingAcquisitionRepo, cfg.PriceHistoryURL)
+		priceBackfill.SetUsage(usageService)
+		accountHandler.SetPriceBackfillService(priceBackfill)
+	}
+	transactionHandler := handlers.NewTransactionHandler(templates, transactionRepo, accountRepo, categoryRepo, balanceChecker)
+	transactionHandler.SetSavedFilterRepository(savedFilterRepo)
+	transactionHandler.SetCurrencyService(currencyService)
+	goalHandler := handlers.NewGoalHandler(templates, goalRepo, goalSnapshotRepo, accountRepo, transactionRepo, categoryRepo, netWorthService)
 	settingsHandler := handlers.NewSettingsHandler(templates, userRepo)
+	settingsHandler.SetEmailEnabled(digestService != nil)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(templates, exchangeRateRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(templates, apiKeyRepo, accountRepo, transactionRepo, userRepo)
+	apiTokenHandler := handlers.NewAPITokenHandler(templates, apiTokenRepo)
+	advisorHandler := handlers.NewAdvisorHandler(templates, advisorShareRepo, commentRepo, userRepo, accountRepo, goalRepo, transactionRepo, holdingRepo, netWorthService)
+	apiHandler := handlers.NewAPIHandler(accountRepo, transactionRepo, categoryRepo, goalRepo, holdingRepo, mappingRepo, netWorthService)
+	notificationHandler := handlers.NewNotificationHandler(templates, notificationChannelRepo, notifier)
+	usageHandler := handlers.NewUsageHandler(templates, usageService)
+	dataQualityHandler := handlers.NewDataQualityHandler(templates, services.NewDataQualityService(accountRepo, transactionRepo, holdingRepo))
+	compareHandler := handlers.NewCompareHandler(templates, accountRepo, transactionRepo, holdingRepo)
+	commandHandler := handlers.NewCommandHandler(accountRepo, brokerConnRepo)
+	ruleHandler := handlers.NewRuleHandler(templates, ruleRepo, categoryRepo, accountRepo, transactionRepo)
 	toolsHandler := handlers.NewToolsHandler(templates, accountRepo, transactionRepo, categoryRepo)
-	adminHandler := handlers.NewAdminHandler(templates, db, userRepo, accountRepo, categoryRepo, goalRepo, transactionRepo, sessionManager)
-	exportHandler := handlers.NewExportHandler(accountRepo, transactionRepo, categoryRepo, goalRepo)
+	toolsHandler.SetCurrencyService(currencyService)
+	toolsHandler.SetCategorizer(categorizer)
+	adminHandler := handlers.NewAdminHandler(templates, db, userRepo, accountRepo, categoryRepo, goalRepo, transactionRepo, holdingRepo, sessionManager)
+	adminHandler.SetSyncService(syncService)
+	adminHandler.SetPasswordPolicy(passwordPolicy)
+	adminHandler.SetAuditService(services.NewAuditService(db))
+	adminHandler.SetRetentionService(retentionService)
+	adminHandler.SetHousekeepingService(housekeepingService)
+	adminHandler.SetMaintenanceService(maintenanceService)
+	adminHandler.SetTaxParameterService(taxParameterService)
+	adminHandler.SetFeatureFlagService(featureFlagService)
+	if cfg.LoginLinks {
+		loginLinks := auth.NewLoginLinkManager(db, sessionManager)
+		authHandler.SetLoginLinkManager(loginLinks, services.NewAuditService(db))
+		adminHandler.SetLoginLinkManager(loginLinks)
+	}
+	exportHandler := handlers.NewExportHandler(accountRepo, transactionRepo, categoryRepo, goalRepo, portfolioService)
+	exportHandler.SetAcquisitionRepository(holdingAcquisitionRepo)
+	exportHandler.SetNetWorthSnapshotService(netWorthSnapshots)
+	exportHandler.SetBackupService(services.NewBackupService(categoryRepo, accountRepo, transactionRepo, goalRepo))
 	brokerHandler := handlers.NewBrokerHandler(templates, brokerConnRepo, mappingRepo, holdingRepo, syncHistoryRepo, accountRepo, syncService)
-	portfolioHandler := handlers.NewPortfolioHandler(templates, portfolioService, allocationTargetRepo, categoryRepo)
+	brokerHandler.SetAllowLoopbackAPIURLs(cfg.AllowLoopbackAPIURLs)
+	broker.SetLoopbackAPIsAllowed(cfg.AllowLoopbackAPIURLs)
+	portfolioHandler := handlers.NewPortfolioHandler(templates, portfolioService, allocationTargetRepo, categoryRepo, rebalanceSessionRepo, watchlistService, watchlistRepo, accountRepo, exclusionRepo, labelRepo)
+	portfolioHandler.SetGoalRepository(goalRepo)
+	grafanaHandler := handlers.NewGrafanaHandler(grafanaService)
+	releaseHandler := handlers.NewReleaseHandler(templates, userRepo, versionRepo)
 
 	// Create application
 	app := &App{
-		config:             cfg,
-		db:                 db,
-		templates:          templates,
-		userRepo:           userRepo,
-		categoryRepo:       categoryRepo,
-		accountRepo:        accountRepo,
-		transactionRepo:    transactionRepo,
-		goalRepo:           goalRepo,
-		brokerConnRepo:     brokerConnRepo,
-		holdingRepo:        holdingRepo,
-		mappingRepo:        mappingRepo,
-		syncHistoryRepo:    syncHistoryRepo,
-		sessionManager:     sessionManager,
-		authMiddleware:     authMiddleware,
-		authHandler:        authHandler,
-		dashHandler:        dashHandler,
-		categoryHandler:    categoryHandler,
-		accountHandler:     accountHandler,
-		transactionHandler: transactionHandler,
-		goalHandler:        goalHandler,
-		settingsHandler:    settingsHandler,
-		toolsHandler:       toolsHandler,
-		adminHandler:       adminHandler,
-		exportHandler:      exportHandler,
-		brokerHandler:      brokerHandler,
-		portfolioHandler:   portfolioHandler,
+		config:              cfg,
+		db:                  db,
+		templates:           templates,
+		userRepo:            userRepo,
+		categoryRepo:        categoryRepo,
+		accountRepo:         accountRepo,
+		transactionRepo:     transactionRepo,
+		goalRepo:            goalRepo,
+		brokerConnRepo:      brokerConnRepo,
+		holdingRepo:         holdingRepo,
+		mappingRepo:         mappingRepo,
+		syncHistoryRepo:     syncHistoryRepo,
+		apiKeyRepo:          apiKeyRepo,
+		apiTokenRepo:        apiTokenRepo,
+		commentRepo:         commentRepo,
+		savedFilterRepo:     savedFilterRepo,
+		brokerPerfRepo:      brokerPerfRepo,
+		notifyChannelRepo:   notificationChannelRepo,
+		ruleRepo:            ruleRepo,
+		usageService:        usageService,
+		digestService:       digestService,
+		goalSnapshotService: goalSnapshotService,
+		netWorthSnapshots:   netWorthSnapshots,
+		interestService:     interestService,
+		retentionService:    retentionService,
+		housekeepingService: housekeepingService,
+		sharedStore:         sharedStore,
+		maintenanceService:  maintenanceService,
+		demoSeeder:          demoSeeder,
+		syncService:         syncService,
+		mockBroker:          mockBroker,
+		sessionManager:      sessionManager,
+		authMiddleware:      authMiddleware,
+		apiKeyAuth:          apiKeyAuth,
+		apiTokenAuth:        apiTokenAuth,
+		authHandler:         authHandler,
+		dashHandler:         dashHandler,
+		categoryHandler:     categoryHandler,
+		accountHandler:      accountHandler,
+		transactionHandler:  transactionHandler,
+		goalHandler:         goalHandler,
+		settingsHandler:     settingsHandler,
+		exchangeRateHandler: exchangeRateHandler,
+		apiKeyHandler:       apiKeyHandler,
+		apiHandler:          apiHandler,
+		apiTokenHandler:     apiTokenHandler,
+		advisorHandler:      advisorHandler,
+		notificationHandler: notificationHandler,
+		usageHandler:        usageHandler,
+		dataQualityHandler:  dataQualityHandler,
+		compareHandler:      compareHandler,
+		commandHandler:      commandHandler,
+		ruleHandler:         ruleHandler,
+		toolsHandler:        toolsHandler,
+		adminHandler:        adminHandler,
+		exportHandler:       exportHandler,
+		brokerHandler:       brokerHandler,
+		portfolioHandler:    portfolioHandler,
+		grafanaHandler:      grafanaHandler,
+		releaseHandler:      releaseHandler,
+		accessLog:           accessLog,
+		startupIssues:       startupIssues,
 	}
 
 	// Setup router
 	app.setupRouter()
 
-	// Create server
-	server := &http.Server{
-		Addr:         cfg.Address(),
-		Handler:      app.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-
-	// Start server in goroutine
-	go func() {
-		log.Printf("Server starting on http://%s", cfg.Address())
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
-
-	log.Println("Server stopped")
+	return app, nil
 }
 
+// Request timeouts. Pages and API calls answer within pageTimeout; imports,
+// exports and admin maintenance get longTimeout. Broker fetches and syncs run
+// in the background and are polled, so no route waits on a broker.
+const (
+	pageTimeout = 15 * time.Second
+	longTimeout = 2 * time.Minute
+)
+
 func (app *App) setupRouter() {
 	r := chi.NewRouter()
 
-	// Chi middleware (aliased as chimw to avoid conflict with our middleware package)
-	r.Use(chimw.Logger)
-	r.Use(chimw.Recoverer)
+	// Chi middleware (aliased as chimw to avoid conflict with our middleware package).
+	// RealIP comes first so the access log anonymizes the client's IP, not
+	// the proxy's.
 	r.Use(chimw.RealIP)
+	r.Use(middleware.AccessLog(log.New(app.accessLog, "", log.LstdFlags), middleware.AccessLogConfig{
+		IPMode:       app.config.AccessLogIP,
+		HashKey:      app.config.SessionSecret,
+		RedactParams: app.config.AccessLogRedactParams,
+	}))
+	r.Use(chimw.Recoverer)
 	r.Use(chimw.RequestID)
 	r.Use(chimw.Compress(5))
 
 	// Security headers for all responses
 	r.Use(middleware.SecurityHeaders)
 
-	// Load user from session for all routes
+	// Load user from session for all routes; admins in support mode get the
+	// supported user's pages
 	r.Use(app.authMiddleware.LoadUser)
+	r.Use(app.adminHandler.SupportView)
 
 	// Static files
 	workDir, _ := os.Getwd()
@@ -236,10 +628,59 @@ func (app *App) setupRouter() {
 	r.Group(func(r chi.Router) {
 		r.Use(app.authMiddleware.RedirectIfAuthenticated)
 		r.Use(middleware.LimitAuth)
+		r.Use(middleware.Timeout(pageTimeout))
 		r.Get("/login", app.authHandler.LoginPage)
 		r.Post("/login", app.authHandler.Login)
 		r.Get("/register", app.authHandler.RegisterPage)
 		r.Post("/register", app.authHandler.Register)
+		r.Get("/login/link/{token}", app.authHandler.LoginLinkPage)
+		r.Post("/login/link/{token}", app.authHandler.UseLoginLink)
+		r.With(middleware.LimitStrict).Post("/demo", app.authHandler.TryDemo)
+	})
+
+	// Automation API, authenticated by account-scoped API keys
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.LimitAPI)
+		r.Use(app.apiKeyAuth.RequireKey)
+		r.Use(middleware.CountUsage(app.usageService, models.UsageAPI))
+		r.Use(middleware.Timeout(pageTimeout))
+		r.Post("/api/v1/accounts/{id}/balance", app.apiKeyHandler.APIUpdateBalance)
+		r.Post("/api/v1/accounts/{id}/transactions", app.apiKeyHandler.APICreateTransaction)
+	})
+
+	// JSON REST API for the web app's resources, authenticated by the session
+	// or an API token
+	r.Group(func(r chi.Router) {
+		r.Use(app.apiTokenAuth.RequireAPIToken)
+		r.Use(app.authMiddleware.RequireAPIUser)
+		r.Use(middleware.LimitAPI)
+		r.Use(middleware.CountUsage(app.usageService, models.UsageAPI))
+		r.Use(middleware.Timeout(pageTimeout))
+		r.Get("/api/v1/accounts", app.apiHandler.ListAccounts)
+		r.Post("/api/v1/accounts", app.apiHandler.CreateAccount)
+		r.Get("/api/v1/accounts/{id}", app.apiHandler.GetAccount)
+		r.Put("/api/v1/accounts/{id}", app.apiHandler.UpdateAccount)
+		r.Delete("/api/v1/accounts/{id}", app.apiHandler.DeleteAccount)
+		r.Get("/api/v1/transactions", app.apiHandler.ListTransactions)
+		r.Post("/api/v1/transactions", app.apiHandler.CreateTransaction)
+		r.Get("/api/v1/transactions/{id}", app.apiHandler.GetTransaction)
+		r.Put("/api/v1/transactions/{id}", app.apiHandler.UpdateTransaction)
+		r.Delete("/api/v1/transactions/{id}", app.apiHandler.DeleteTransaction)
+		r.Get("/api/v1/categories", app.apiHandler.ListCategories)
+		r.Post("/api/v1/categories", app.apiHandler.CreateCategory)
+		r.Get("/api/v1/categories/{id}", app.apiHandler.GetCategory)
+		r.Put("/api/v1/categories/{id}", app.apiHandler.UpdateCategory)
+		r.Delete("/api/v1/categories/{id}", app.apiHandler.DeleteCategory)
+		r.Get("/api/v1/goals", app.apiHandler.ListGoals)
+		r.Post("/api/v1/goals", app.apiHandler.CreateGoal)
+		r.Get("/api/v1/goals/{id}", app.apiHandler.GetGoal)
+		r.Put("/api/v1/goals/{id}", app.apiHandler.UpdateGoal)
+		r.Delete("/api/v1/goals/{id}", app.apiHandler.DeleteGoal)
+		r.Get("/api/v1/holdings", app.apiHandler.ListHoldings)
+		r.Post("/api/v1/holdings", app.apiHandler.CreateHolding)
+		r.Get("/api/v1/holdings/{id}", app.apiHandler.GetHolding)
+		r.Put("/api/v1/holdings/{id}", app.apiHandler.UpdateHolding)
+		r.Delete("/api/v1/holdings/{id}", app.apiHandler.DeleteHolding)
 	})
 
 	// Change password route (requires auth but NOT password changed)
@@ -247,6 +688,7 @@ func (app *App) setupRouter() {
 	r.Group(func(r chi.Router) {
 		r.Use(app.authMiddleware.RequireAuth)
 		r.Use(middleware.LimitAuth)
+		r.Use(middleware.Timeout(pageTimeout))
 		r.Get("/change-password", app.authHandler.ChangePasswordPage)
 		r.Post("/change-password", app.authHandler.ChangePassword)
 	})
@@ -255,70 +697,195 @@ func (app *App) setupRouter() {
 	r.Group(func(r chi.Router) {
 		r.Use(app.authMiddleware.RequireAuth)
 		r.Use(app.authMiddleware.RequirePasswordChanged)
-		r.Get("/dashboard", app.dashHandler.Dashboard)
+		page := r.With(middleware.Timeout(pageTimeout))
+		long := r.With(middleware.Timeout(longTimeout))
+		page.With(app.releaseHandler.ShowWhatsNew).Get("/dashboard", app.dashHandler.Dashboard)
+		page.Post("/dashboard/net-worth-exclusions", app.dashHandler.SetNetWorthExclusions)
+		page.Get("/compare", app.compareHandler.Compare)
+		page.Get("/whats-new", app.releaseHandler.WhatsNew)
+		page.Get("/api/commands", app.commandHandler.Commands)
 
 		// Categories
-		r.Get("/categories", app.categoryHandler.List)
-		r.Post("/categories", app.categoryHandler.Create)
-		r.Post("/categories/{id}", app.categoryHandler.Update)
+		page.Get("/categories", app.categoryHandler.List)
+		page.Post("/categories", app.categoryHandler.Create)
+		page.Post("/categories/{id}", app.categoryHandler.Update)
 
 		// Accounts
-		r.Get("/accounts", app.accountHandler.List)
-		r.Post("/accounts", app.accountHandler.Create)
-		r.Post("/accounts/{id}", app.accountHandler.Update)
-		r.Post("/accounts/{id}/balance", app.accountHandler.UpdateBalance)
+		page.Get("/accounts", app.accountHandler.List)
+		page.Post("/accounts", app.accountHandler.Create)
+		page.Get("/accounts/history", app.accountHandler.HistoryForm)
+		long.Post("/accounts/history", app.accountHandler.ImportHistory)
+		page.Post("/accounts/order", app.accountHandler.Reorder)
+		page.Get("/accounts/category", app.accountHandler.BulkCategoryForm)
+		page.Post("/accounts/category", app.accountHandler.BulkCategory)
+		page.Post("/accounts/{id}", app.accountHandler.Update)
+		page.Post("/accounts/{id}/balance", app.accountHandler.UpdateBalance)
+		page.Post("/accounts/{id}/pin", app.accountHandler.TogglePin)
+		page.Get("/accounts/{id}/name", app.accountHandler.AccountName)
+		page.Get("/accounts/{id}/name/edit", app.accountHandler.EditAccountName)
+		page.Post("/accounts/{id}/name", app.accountHandler.UpdateAccountName)
+		long.Post("/accounts/{id}/holdings/import", app.accountHandler.ImportHoldings)
+		page.Post("/accounts/{id}/holdings/{holdingID}/cost-basis", app.accountHandler.SetCostBasisMode)
+		long.Post("/accounts/{id}/holdings/{holdingID}/backfill", app.accountHandler.BackfillHolding)
+		long.Post("/accounts/{id}/acquisitions/import", app.accountHandler.ImportAcquisitions)
+		long.Post("/accounts/{id}/statement/import", app.accountHandler.ImportBankStatement)
+		page.Get("/accounts/{id}/delete", app.accountHandler.DeleteForm)
+		page.Get("/accounts/{id}/merge", app.accountHandler.MergeForm)
+		page.Post("/accounts/{id}/merge", app.accountHandler.Merge)
+		page.Get("/accounts/{id}/snapshots", app.accountHandler.Snapshots)
+		page.Post("/accounts/{id}/snapshots", app.accountHandler.TakeSnapshot)
+		page.Post("/accounts/{id}/snapshots/{snapshotID}/delete", app.accountHandler.DeleteSnapshot)
 
 		// Transactions
-		r.Get("/transactions", app.transactionHandler.List)
-		r.Post("/transactions", app.transactionHandler.Create)
-		r.Post("/transactions/{id}", app.transactionHandler.Update)
+		page.Get("/transactions", app.transactionHandler.List)
+		page.Post("/transactions/filters", app.transactionHandler.SaveFilter)
+		page.Post("/transactions/filters/{id}/delete", app.transactionHandler.DeleteFilter)
+		page.Post("/transactions", app.transactionHandler.Create)
+		page.Post("/transactions/{id}", app.transactionHandler.Update)
+		page.Get("/transactions/{id}/row", app.transactionHandler.TransactionRow)
+		page.Get("/transactions/{id}/row/edit", app.transactionHandler.EditTransactionRow)
+		page.Post("/transactions/{id}/row", app.transactionHandler.UpdateTransactionRow)
+		page.Get("/api/transactions/quick-add/accounts", app.transactionHandler.QuickAddAccounts)
+		page.Post("/api/transactions/quick-add", app.transactionHandler.QuickAdd)
 
 		// Goals
-		r.Get("/goals", app.goalHandler.List)
-		r.Post("/goals", app.goalHandler.Create)
-		r.Post("/goals/{id}", app.goalHandler.Update)
+		page.Get("/goals", app.goalHandler.List)
+		page.Post("/goals", app.goalHandler.Create)
+		long.Post("/goals/import", app.goalHandler.Import)
+		page.Get("/goals/{id}", app.goalHandler.Detail)
+		page.Post("/goals/{id}", app.goalHandler.Update)
+
+		// Accounts and goals shared with advisors
+		page.Get("/shared", app.advisorHandler.Shared)
+		page.Get("/threads/accounts/{id}", app.advisorHandler.AccountThread)
+		page.Post("/threads/accounts/{id}", app.advisorHandler.CommentAccount)
+		page.Get("/threads/goals/{id}", app.advisorHandler.GoalThread)
+		page.Post("/threads/goals/{id}", app.advisorHandler.CommentGoal)
+		page.Post("/comments/{id}/delete", app.advisorHandler.DeleteComment)
 
 		// Settings
-		r.Get("/settings", app.settingsHandler.Settings)
-		r.Post("/settings", app.settingsHandler.Update)
+		page.Get("/settings", app.settingsHandler.Settings)
+		page.Post("/settings", app.settingsHandler.Update)
+		page.Get("/settings/exchange-rates", app.exchangeRateHandler.List)
+		page.Post("/settings/exchange-rates", app.exchangeRateHandler.Create)
+		page.Post("/settings/exchange-rates/{id}/delete", app.exchangeRateHandler.Delete)
+		page.Get("/settings/api-keys", app.apiKeyHandler.List)
+		page.Post("/settings/api-keys", app.apiKeyHandler.Create)
+		page.Post("/settings/api-keys/{id}/delete", app.apiKeyHandler.Delete)
+		page.Get("/settings/api-tokens", app.apiTokenHandler.List)
+		page.Post("/settings/api-tokens", app.apiTokenHandler.Create)
+		page.Post("/settings/api-tokens/{id}/delete", app.apiTokenHandler.Delete)
+		page.Get("/settings/advisors", app.advisorHandler.List)
+		page.Post("/settings/advisors", app.advisorHandler.Save)
+		page.Post("/settings/advisors/{id}/delete", app.advisorHandler.Delete)
+		page.Get("/settings/notifications", app.notificationHandler.List)
+		page.Post("/settings/notifications", app.notificationHandler.Create)
+		page.Post("/settings/notifications/{id}/test", app.notificationHandler.Test)
+		page.Post("/settings/notifications/{id}/toggle", app.notificationHandler.Toggle)
+		page.Post("/settings/notifications/{id}/delete", app.notificationHandler.Delete)
+		page.Get("/settings/usage", app.usageHandler.Usage)
+		page.Get("/settings/data-quality", app.dataQualityHandler.DataQuality)
+		page.Get("/settings/rules", app.ruleHandler.List)
+		page.Post("/settings/rules", app.ruleHandler.Create)
+		page.Post("/settings/rules/preview", app.ruleHandler.Preview)
+		page.Post("/settings/rules/{id}/delete", app.ruleHandler.Delete)
 
 		// Broker Connections
-		r.Get("/settings/connections", app.brokerHandler.Connections)
-		r.Get("/settings/connections/new", app.brokerHandler.NewConnectionForm)
-		r.Post("/settings/connections", app.brokerHandler.CreateConnection)
-		r.Get("/settings/connections/{id}", app.brokerHandler.ViewConnection)
-		r.Get("/settings/connections/{id}/edit", app.brokerHandler.EditConnectionForm)
-		r.Post("/settings/connections/{id}/edit", app.brokerHandler.UpdateConnection)
-		r.Get("/settings/connections/{id}/accounts", app.brokerHandler.AccountMappingForm)
-		r.Post("/settings/connections/{id}/accounts", app.brokerHandler.SaveAccountMappings)
-		r.Post("/settings/connections/{id}/fetch-accounts", app.brokerHandler.FetchExternalAccounts)
-		r.Post("/settings/connections/{id}/sync", app.brokerHandler.SyncConnection)
-		r.Post("/settings/connections/{id}/delete", app.brokerHandler.DeleteConnection)
-		r.Get("/settings/connections/{id}/mitid/status", app.brokerHandler.MitIDStatus)
-		r.Get("/settings/connections/{id}/mitid/qr", app.brokerHandler.MitIDQRCode)
+		page.Get("/settings/connections", app.brokerHandler.Connections)
+		page.Get("/settings/connections/new", app.brokerHandler.NewConnectionForm)
+		page.Post("/settings/connections", app.brokerHandler.CreateConnection)
+		page.Get("/settings/connections/{id}", app.brokerHandler.ViewConnection)
+		page.Get("/settings/connections/{id}/edit", app.brokerHandler.EditConnectionForm)
+		page.Post("/settings/connections/{id}/edit", app.brokerHandler.UpdateConnection)
+		page.Get("/settings/connections/{id}/accounts", app.brokerHandler.AccountMappingForm)
+		page.Post("/settings/connections/{id}/accounts", app.brokerHandler.SaveAccountMappings)
+		page.Post("/settings/connections/{id}/fetch-accounts", app.brokerHandler.FetchExternalAccounts)
+		page.Post("/settings/connections/{id}/sync", app.brokerHandler.SyncConnection)
+		page.Get("/settings/connections/{id}/task", app.brokerHandler.TaskStatus)
+		page.Get("/settings/connections/{id}/history/{historyID}/diagnostics", app.brokerHandler.DownloadDiagnostics)
+		page.Post("/settings/connections/{id}/confirm-deletions", app.brokerHandler.ConfirmDeletions)
+		page.Post("/settings/connections/{id}/confirm-balances", app.brokerHandler.ConfirmBalances)
+		page.Post("/settings/connections/{id}/delete", app.brokerHandler.DeleteConnection)
+		page.Get("/settings/connections/{id}/mitid/status", app.brokerHandler.MitIDStatus)
+		page.Get("/settings/connections/{id}/mitid/qr", app.brokerHandler.MitIDQRCode)
+		page.Post("/settings/connections/{id}/ftn/start", app.brokerHandler.StartFTNLogin)
+		page.Post("/settings/connections/{id}/ftn", app.brokerHandler.CompleteFTNLogin)
 		// Saxo OAuth
-		r.Get("/settings/connections/{id}/saxo/status", app.brokerHandler.SaxoOAuthStatus)
-		r.Post("/settings/connections/{id}/saxo/auth", app.brokerHandler.SaxoStartOAuth)
+		page.Get("/settings/connections/{id}/saxo/status", app.brokerHandler.SaxoOAuthStatus)
+		page.Post("/settings/connections/{id}/saxo/auth", app.brokerHandler.SaxoStartOAuth)
+		// PSD2 bank access
+		page.Post("/settings/connections/{id}/psd2/consent", app.brokerHandler.StartPSD2Consent)
+		page.Get("/settings/connections/{id}/psd2/callback", app.brokerHandler.PSD2Callback)
 
 		// Tools
-		r.Get("/tools", app.toolsHandler.List)
-		r.Get("/tools/compound-interest", app.toolsHandler.CompoundInterest)
-		r.Get("/tools/salary-calculator", app.toolsHandler.SalaryCalculator)
-		r.Get("/tools/fire-calculator", app.toolsHandler.FIRECalculator)
-		r.Get("/tools/portfolio-analyzer", app.portfolioHandler.Analyzer)
+		page.Get("/tools", app.toolsHandler.List)
+		page.Get("/tools/compound-interest", app.toolsHandler.CompoundInterest)
+		page.Get("/tools/salary-calculator", app.toolsHandler.SalaryCalculator)
+		page.Get("/tools/fire-calculator", app.toolsHandler.FIRECalculator)
+		page.Get("/tools/currency", app.toolsHandler.CurrencyConverter)
+		page.Get("/tools/import", app.toolsHandler.ImportForm)
+		long.Post("/tools/import", app.toolsHandler.ImportUpload)
+		long.Post("/tools/import/csv", app.toolsHandler.ImportCSV)
+		page.Get("/tools/portfolio-analyzer", app.portfolioHandler.Analyzer)
+		page.Get("/tools/stress-test", app.portfolioHandler.StressTest)
+		page.Get("/tools/liquidation", app.portfolioHandler.Liquidation)
+
+		// Grafana SimpleJSON datasource
+		page.Group(func(r chi.Router) {
+			r.Use(middleware.CountUsage(app.usageService, models.UsageAPI))
+			r.Get("/api/grafana", app.grafanaHandler.TestConnection)
+			r.Get("/api/grafana/", app.grafanaHandler.TestConnection)
+			r.Post("/api/grafana/search", app.grafanaHandler.Search)
+			r.Post("/api/grafana/query", app.grafanaHandler.Query)
+		})
+	})
+
+	// Exports and the portfolio API, for a session or an API token
+	r.Group(func(r chi.Router) {
+		r.Use(app.apiTokenAuth.RequireAPIToken)
+		r.Use(app.authMiddleware.RequireAuth)
+		r.Use(app.authMiddleware.RequirePasswordChanged)
+		page := r.With(middleware.Timeout(pageTimeout))
+		long := r.With(middleware.Timeout(longTimeout))
+
+		// Net worth history API
+		page.Get("/api/networth/history", app.dashHandler.NetWorthHistory)
 
 		// Portfolio API
-		r.Get("/api/portfolio/composition", app.portfolioHandler.GetComposition)
-		r.Get("/api/portfolio/targets", app.portfolioHandler.GetTargets)
-		r.Post("/api/portfolio/targets", app.portfolioHandler.SaveTarget)
-		r.Delete("/api/portfolio/targets", app.portfolioHandler.DeleteTarget)
-		r.Get("/api/portfolio/comparison", app.portfolioHandler.GetComparison)
-		r.Get("/api/portfolio/rebalance", app.portfolioHandler.GetRebalancing)
+		page.Get("/api/holdings", app.portfolioHandler.GetHoldings)
+		page.Get("/api/portfolio/composition", app.portfolioHandler.GetComposition)
+		page.Get("/api/portfolio/targets", app.portfolioHandler.GetTargets)
+		page.Post("/api/portfolio/targets", app.portfolioHandler.SaveTarget)
+		page.Delete("/api/portfolio/targets", app.portfolioHandler.DeleteTarget)
+		page.Get("/api/portfolio/comparison", app.portfolioHandler.GetComparison)
+		page.Get("/api/portfolio/rebalance", app.portfolioHandler.GetRebalancing)
+		page.Get("/api/portfolio/rebalance/sessions", app.portfolioHandler.GetRebalanceSessions)
+		page.Post("/api/portfolio/rebalance/sessions", app.portfolioHandler.SaveRebalanceSession)
+		page.Post("/api/portfolio/rebalance/sessions/{id}/actions/{actionID}", app.portfolioHandler.UpdateRebalanceAction)
+		page.Delete("/api/portfolio/rebalance/sessions/{id}", app.portfolioHandler.DeleteRebalanceSession)
+		page.Get("/api/portfolio/watchlist", app.portfolioHandler.GetWatchlist)
+		page.Post("/api/portfolio/watchlist", app.portfolioHandler.AddWatchlistItem)
+		page.Post("/api/portfolio/watchlist/{id}/price", app.portfolioHandler.SetWatchlistPrice)
+		page.Post("/api/portfolio/watchlist/{id}/convert", app.portfolioHandler.ConvertWatchlistItem)
+		page.Delete("/api/portfolio/watchlist/{id}", app.portfolioHandler.DeleteWatchlistItem)
+		page.Get("/api/portfolio/exclusions", app.portfolioHandler.GetExclusions)
+		page.Post("/api/portfolio/exclusions", app.portfolioHandler.SaveExclusion)
+		page.Delete("/api/portfolio/exclusions/{id}", app.portfolioHandler.DeleteExclusion)
+		page.Get("/api/portfolio/labels", app.portfolioHandler.GetLabels)
+		page.Post("/api/portfolio/labels", app.portfolioHandler.SaveLabel)
+		page.Delete("/api/portfolio/labels/{id}", app.portfolioHandler.DeleteLabel)
 
 		// Export
-		r.Get("/export/transactions", app.exportHandler.ExportTransactions)
-		r.Get("/export/accounts", app.exportHandler.ExportAccounts)
-		r.Get("/export/all", app.exportHandler.ExportAll)
+		long.Get("/export/transactions", app.exportHandler.ExportTransactions)
+		long.Get("/export/accounts", app.exportHandler.ExportAccounts)
+		long.Get("/export/all", app.exportHandler.ExportAll)
+		long.Post("/export/all", app.exportHandler.ExportAllEncrypted)
+		long.Post("/export/all/restore", app.exportHandler.RestoreBackup)
+		long.Get("/export/net-worth", app.exportHandler.ExportNetWorthHistory)
+		long.Get("/export/balances", app.exportHandler.ExportBalanceHistory)
+		long.Get("/export/statement", app.exportHandler.ExportStatement)
+		long.Get("/export/composition", app.exportHandler.ExportComposition)
+		long.Get("/export/allocation", app.exportHandler.ExportAllocationDrift)
 	})
 
 	// Admin return route (accessible when impersonating - only requires auth)
@@ -332,20 +899,43 @@ func (app *App) setupRouter() {
 		r.Use(app.authMiddleware.RequireAuth)
 		r.Use(app.authMiddleware.RequirePasswordChanged)
 		r.Use(app.authMiddleware.RequireAdmin)
-
-		r.Get("/admin", app.adminHandler.Dashboard)
-		r.Get("/admin/users", app.adminHandler.UserList)
-		r.Get("/admin/users/{id}", app.adminHandler.UserView)
-		r.Post("/admin/users/{id}", app.adminHandler.UserEdit)
-		r.Post("/admin/users/{id}/reset-password", app.adminHandler.UserResetPassword)
-		r.Post("/admin/users/{id}/delete", app.adminHandler.UserDelete)
-		r.Post("/admin/users/{id}/impersonate", app.adminHandler.UserImpersonate)
-
-		r.Get("/admin/database", app.adminHandler.DatabaseOverview)
-		r.Get("/admin/database/{table}", app.adminHandler.TableView)
-		r.Get("/admin/database/{table}/{id}", app.adminHandler.TableRowView)
-		r.Get("/admin/sql", app.adminHandler.SQLQueryPage)
-		r.Post("/admin/sql", app.adminHandler.SQLQueryExecute)
+		page := r.With(middleware.Timeout(pageTimeout))
+		long := r.With(middleware.Timeout(longTimeout))
+
+		page.Get("/admin", app.adminHandler.Dashboard)
+		page.Post("/admin/whats-new/dismiss", app.releaseHandler.DismissNotice)
+		page.Get("/admin/users", app.adminHandler.UserList)
+		page.Get("/admin/users/{id}", app.adminHandler.UserView)
+		page.Post("/admin/users/{id}", app.adminHandler.UserEdit)
+		page.Post("/admin/users/{id}/reset-password", app.adminHandler.UserResetPassword)
+		page.Post("/admin/users/{id}/delete", app.adminHandler.UserDelete)
+		page.Post("/admin/users/{id}/impersonate", app.adminHandler.UserImpersonate)
+		page.Post("/admin/users/{id}/support", app.adminHandler.UserSupport)
+		page.Post("/admin/users/{id}/login-link", app.adminHandler.CreateLoginLink)
+		page.Post("/admin/support/exit", app.adminHandler.ExitSupport)
+		long.Post("/admin/users/{id}/anonymized-export", app.adminHandler.UserAnonymizedExport)
+
+		page.Get("/admin/holdings", app.adminHandler.HoldingsReport)
+		page.Post("/admin/holdings/archive/{id}/restore", app.adminHandler.RestoreHolding)
+		page.Get("/admin/database", app.adminHandler.DatabaseOverview)
+		page.Get("/admin/database/{table}", app.adminHandler.TableView)
+		page.Get("/admin/database/{table}/{id}", app.adminHandler.TableRowView)
+		long.Get("/admin/integrity", app.adminHandler.IntegrityCheck)
+		long.Post("/admin/integrity/repair", app.adminHandler.IntegrityRepair)
+		page.Get("/admin/maintenance", app.adminHandler.Maintenance)
+		page.Post("/admin/maintenance", app.adminHandler.RunMaintenance)
+		page.Get("/admin/retention", app.adminHandler.RetentionPolicies)
+		page.Post("/admin/retention", app.adminHandler.SaveRetentionPolicy)
+		long.Post("/admin/retention/run", app.adminHandler.RunRetention)
+		page.Get("/admin/tax-parameters", app.adminHandler.TaxParameters)
+		page.Post("/admin/tax-parameters", app.adminHandler.SaveTaxParameters)
+		page.Post("/admin/tax-parameters/{year}/delete", app.adminHandler.DeleteTaxParameters)
+		page.Get("/admin/features", app.adminHandler.FeatureFlags)
+		page.Post("/admin/features", app.adminHandler.SaveFeatureFlag)
+		page.Post("/admin/features/users", app.adminHandler.SaveFeatureFlagUser)
+		page.Post("/admin/sync-all", app.adminHandler.SyncAll)
+		page.Get("/admin/sql", app.adminHandler.SQLQueryPage)
+		long.Post("/admin/sql", app.adminHandler.SQLQueryExecute)
 	})
 
 	// Logout (needs to be accessible when logged in)
@@ -357,11 +947,17 @@ func (app *App) setupRouter() {
 	app.router = r
 }
 
-// handleHealth returns the server health status.
+// handleHealth returns the server health status. The status is degraded
+// when problems were detected at startup.
 func (app *App) handleHealth(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	if len(app.startupIssues) > 0 {
+		status = "degraded"
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ok",
+		"status":  status,
+		"version": release.Version,
 	})
 }
 
@@ -378,8 +974,9 @@ func (app *App) handleIndex(w http.ResponseWriter, r *http.Request) {
 // TemplateCache holds parsed templates.
 type TemplateCache map[string]*template.Template
 
-// parseTemplates loads and parses all templates.
-func parseTemplates() (TemplateCache, error) {
+// parseTemplates loads and parses all templates. Startup issues are shown
+// to admins on every page, and features hide experimental parts of pages.
+func parseTemplates(startupIssues []string, features *services.FeatureFlagService) (TemplateCache, error) {
 	cache := make(TemplateCache)
 
 	// Template functions
@@ -393,16 +990,59 @@ func parseTemplates() (TemplateCache, error) {
 		// formatNumber formats a number according to locale
 		// format: "da" (Danish: 1.234,56), "en" (English: 1,234.56), "fr" (French: 1 234,56)
 		"formatNumber": func(n float64, format string) string {
-			return formatNumberWithLocale(n, format, false)
+			return money.Format(n, format, 0)
 		},
 		// formatNumberDecimals formats a number with 2 decimal places
 		"formatNumberDecimals": func(n float64, format string) string {
-			return formatNumberWithLocale(n, format, true)
+			return money.Format(n, format, money.DefaultPrecision)
+		},
+		// formatMoney formats an amount with the precision of its currency
+		// (e.g. 0 for JPY, 8 for BTC), honouring the user's hide decimals setting
+		"formatMoney": func(n float64, currency string, user *models.User) string {
+			if user == nil {
+				return money.FormatAmount(n, currency, "da", false)
+			}
+			return money.FormatAmount(n, currency, user.NumberFormat, user.HideDecimals)
+		},
+		// formatBytes formats a size in bytes, e.g. 1.5 MB
+		"formatBytes": database.FormatBytes,
+		// formatDate formats a date in the user's number format, e.g.
+		// 31-01-2024 for Danish and 01/31/2024 for English
+		"formatDate": func(t time.Time, user *models.User) string {
+			if user == nil {
+				return dates.FormatDate(t, "da")
+			}
+			return dates.FormatDate(t, user.NumberFormat)
+		},
+		// formatDateTime formats a time in the user's number format and time zone
+		"formatDateTime": func(t time.Time, user *models.User) string {
+			if user == nil {
+				return dates.FormatDateTime(t, "da", "")
+			}
+			return dates.FormatDateTime(t, user.NumberFormat, user.Timezone)
+		},
+		// timeAgo describes a time relative to now, e.g. "2 days ago"
+		"timeAgo": func(t time.Time) string {
+			return dates.Relative(t, time.Now())
 		},
 		// upper converts a string to uppercase
 		"upper": func(s string) string {
 			return strings.ToUpper(s)
 		},
+		// markdown renders notes and descriptions as sanitized HTML
+		"markdown": markdown.Render,
+		// excerpt is the first line of Markdown as plain text
+		"excerpt": markdown.Excerpt,
+		"appVersion": func() string {
+			return release.Version
+		},
+		"startupIssues": func() []string {
+			return startupIssues
+		},
+		// feature reports whether an experimental feature is on for the user
+		"feature": func(name string, user *models.User) bool {
+			return features.Enabled(name, user)
+		},
 	}
 
 	// Get layout path
@@ -431,73 +1071,26 @@ func parseTemplates() (TemplateCache, error) {
 	return cache, nil
 }
 
-// formatNumberWithLocale formats a number according to the specified locale.
-// Supported formats: "da" (Danish), "en" (English), "fr" (French)
-func formatNumberWithLocale(n float64, format string, withDecimals bool) string {
-	// Handle negative numbers
-	negative := n < 0
-	if negative {
-		n = -n
-	}
-
-	var intPart int64
-	var decPart int
-	if withDecimals {
-		intPart = int64(n)
-		decPart = int((n - float64(intPart)) * 100)
-	} else {
-		intPart = int64(n + 0.5) // Round to nearest
-	}
-
-	// Format the integer part with thousand separators
-	intStr := formatIntWithSeparator(intPart, format)
-
-	var result string
-	if withDecimals {
-		decSep := ","
-		if format == "en" {
-			decSep = "."
-		}
-		result = fmt.Sprintf("%s%s%02d", intStr, decSep, decPart)
-	} else {
-		result = intStr
+// recordRelease records the running release and reports a database last used
+// by a newer build, whose schema this build may not understand.
+func recordRelease(versionRepo *repository.AppVersionRepository) []string {
+	latest, err := versionRepo.MaxSchemaVersion()
+	if err != nil {
+		log.Printf("Failed to read app versions: %v", err)
+		return []string{fmt.Sprintf("Could not read the release history: %v", err)}
 	}
 
-	if negative {
-		result = "-" + result
+	var issues []string
+	if latest > database.SchemaVersion() {
+		log.Printf("Database schema version %d is newer than this build's %d", latest, database.SchemaVersion())
+		issues = append(issues, fmt.Sprintf("The database was used by a newer release (schema version %d, this build has %d). Downgrades are not supported; upgrade to the newer release.", latest, database.SchemaVersion()))
 	}
 
-	return result
-}
-
-// formatIntWithSeparator formats an integer with thousand separators.
-func formatIntWithSeparator(n int64, format string) string {
-	// Determine separator based on format
-	sep := "."
-	switch format {
-	case "en":
-		sep = ","
-	case "fr":
-		sep = " "
-	case "da", "de":
-		sep = "."
-	}
-
-	// Convert to string and add separators
-	s := fmt.Sprintf("%d", n)
-	if len(s) <= 3 {
-		return s
-	}
-
-	// Add separators every 3 digits from the right
-	var result []byte
-	for i, c := range s {
-		if i > 0 && (len(s)-i)%3 == 0 {
-			result = append(result, sep[0])
-		}
-		result = append(result, byte(c))
+	if err := versionRepo.Record(release.Version, database.SchemaVersion()); err != nil {
+		log.Printf("Failed to record app version: %v", err)
+		issues = append(issues, fmt.Sprintf("Could not record the running release: %v", err))
 	}
-	return string(result)
+	return issues
 }
 
 // ensureDefaultAdmin creates a default admin user if no users exist.
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// maintenanceCheckInterval is how often the maintenance window is checked
+// for; shorter than an hour so every window is seen.
+const maintenanceCheckInterval = 15 * time.Minute
+
+// startMaintenance runs database maintenance in its daily window, checking
+// every maintenanceCheckInterval until the returned stop function is called.
+// It does nothing if there is no maintenance window, and only the job leader
+// runs it.
+func startMaintenance(svc *services.MaintenanceService, leader *jobLeader) (stop func()) {
+	if svc.WindowHour() < 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(maintenanceCheckInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				if finished := svc.StartDue(time.Now()); finished != nil {
+					<-finished
+					logMaintenance(svc.LastRun())
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// logMaintenance logs the outcome of each step of a maintenance run.
+func logMaintenance(run *services.MaintenanceRun) {
+	for _, step := range run.Steps {
+		if step.Err != nil {
+			log.Printf("[Maintenance] %s failed after %v: %v", step.Operation, step.Duration, step.Err)
+			continue
+		}
+		log.Printf("[Maintenance] %s in %v: %s", step.Operation, step.Duration, step.Detail)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// netWorthSnapshotInterval is how often net worth is recorded. Each run
+// updates the snapshot of the current day, so the last run of a day holds
+// its closing figures.
+const netWorthSnapshotInterval = time.Hour
+
+// startNetWorthSnapshots records the net worth of all users now and then
+// every netWorthSnapshotInterval until the returned stop function is called,
+// when this instance is the job leader.
+func startNetWorthSnapshots(svc *services.NetWorthSnapshotService, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(netWorthSnapshotInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				recordNetWorthSnapshots(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// recordNetWorthSnapshots records the net worth of all users and logs
+// failures.
+func recordNetWorthSnapshots(svc *services.NetWorthSnapshotService) {
+	if _, err := svc.RecordAll(time.Now()); err != nil {
+		log.Printf("[Net worth] Recording net worth failed: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/config"
+	"wealth_tracker/internal/database"
+)
+
+// runExportReplica implements "server export-replica [-o path]". It writes
+// the sanitized analytics replica once, to REPLICA_PATH unless -o is given.
+func runExportReplica(cfg *config.Config, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("export-replica", flag.ContinueOnError)
+	fs.SetOutput(out)
+	path := fs.String("o", cfg.ReplicaPath, "file to write the replica to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *path == "" {
+		fmt.Fprintln(out, "No replica path; set REPLICA_PATH or pass -o")
+		return 2
+	}
+	if filepath.Clean(*path) == filepath.Clean(cfg.DBPath) {
+		fmt.Fprintln(out, "The replica path must not be the live database")
+		return 2
+	}
+
+	db, err := database.New(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := db.ExportReplica(*path); err != nil {
+		fmt.Fprintf(out, "Replica export failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Replica written to %s\n", *path)
+	return 0
+}
+
+// startReplicaExport writes the analytics replica now and then every
+// REPLICA_INTERVAL_HOURS until the returned stop function is called. It does
+// nothing if no replica path is configured or the settings are invalid.
+func startReplicaExport(db *database.DB, cfg *config.Config) (stop func()) {
+	if cfg.ReplicaPath == "" || cfg.ReplicaIntervalHours < 1 ||
+		filepath.Clean(cfg.ReplicaPath) == filepath.Clean(cfg.DBPath) {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.ReplicaIntervalHours) * time.Hour)
+		defer ticker.Stop()
+		for {
+			exportReplica(db, cfg.ReplicaPath)
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// exportReplica writes the replica and logs the outcome.
+func exportReplica(db *database.DB, path string) {
+	start := time.Now()
+	if err := db.ExportReplica(path); err != nil {
+		log.Printf("[Replica] Export to %s failed: %v", path, err)
+		return
+	}
+	log.Printf("[Replica] Exported to %s in %v", path, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/services"
+)
+
+// retentionInterval is how often retention policies are enforced.
+const retentionInterval = 24 * time.Hour
+
+// startRetention enforces the retention policies of history tables now and
+// then every retentionInterval until the returned stop function is called,
+// when this instance is the job leader.
+func startRetention(svc *services.RetentionService, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for {
+			if leader.Leads() {
+				enforceRetention(svc)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// enforceRetention enforces the retention policies and logs the result.
+func enforceRetention(svc *services.RetentionService) {
+	deleted, err := svc.Enforce(time.Now())
+	if err != nil {
+		log.Printf("[Retention] Enforcing retention policies failed: %v", err)
+	}
+	for table, n := range deleted {
+		if n > 0 {
+			log.Printf("[Retention] Deleted %d row(s) from %s", n, table)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/sync"
+)
+
+// scheduledSyncInterval is how often connections are checked for a due
+// scheduled sync.
+const scheduledSyncInterval = time.Minute
+
+// startScheduledSyncs syncs connections as their schedules fall due, checking
+// every scheduledSyncInterval until the returned stop function is called.
+// Only the job leader syncs, so a connection is synced once for all
+// instances.
+func startScheduledSyncs(svc *sync.Service, leader *jobLeader) (stop func()) {
+	done := make(chan struct{})
+	var once stdsync.Once
+
+	go func() {
+		ticker := time.NewTicker(scheduledSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if leader.Leads() {
+					runScheduledSyncs(svc)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// runScheduledSyncs runs the due syncs and logs the outcome.
+func runScheduledSyncs(svc *sync.Service) {
+	run, err := svc.RunScheduledSyncs(time.Now())
+	if err != nil {
+		log.Printf("[Scheduled Sync] Checking schedules failed: %v", err)
+		return
+	}
+	if run.Synced+run.Failed+run.Skipped > 0 {
+		log.Printf("[Scheduled Sync] Synced %d connection(s), %d failed, %d skipped needing a login", run.Synced, run.Failed, run.Skipped)
+	}
+}
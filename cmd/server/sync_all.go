@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"wealth_tracker/internal/config"
+	"wealth_tracker/internal/database"
+)
+
+// runSyncAll implements "server sync-all [-spread 10m]". It syncs every
+// active connection that can be synced without a login, spread over the
+// given period, and waits for them. The broker sessions kept in the
+// database are restored when the application is set up, so only connections
+// without a live session or consent are skipped; log in to those on the
+// running server.
+func runSyncAll(cfg *config.Config, args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("sync-all", flag.ContinueOnError)
+	fs.SetOutput(out)
+	spread := fs.Duration("spread", 0, "period to spread the syncs over, such as 10m")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *spread < 0 {
+		fmt.Fprintln(out, "The spread must not be negative")
+		return 2
+	}
+
+	db, err := database.New(cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		fmt.Fprintf(out, "Failed to run migrations: %v\n", err)
+		return 1
+	}
+
+	app, err := newApp(cfg, db)
+	if err != nil {
+		fmt.Fprintf(out, "Failed to initialize application: %v\n", err)
+		return 1
+	}
+	if app.mockBroker != nil {
+		defer app.mockBroker.Close()
+	}
+
+	done, err := app.syncService.StartSyncAll(*spread)
+	if err != nil {
+		fmt.Fprintf(out, "Sync failed: %v\n", err)
+		return 1
+	}
+	started := app.syncService.SyncAllStatus()
+	fmt.Fprintf(out, "Syncing %d connection(s) over %v\n", started.Queued, *spread)
+	if started.Deferred > 0 {
+		fmt.Fprintf(out, "%d connection(s) wait for their sync window\n", started.Deferred)
+	}
+	<-done
+
+	status := app.syncService.SyncAllStatus()
+	fmt.Fprintf(out, "Synced %d, failed %d, skipped %d needing a login\n", status.Succeeded, status.Failed, status.Skipped)
+	if status.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so leaked keys are easy to spot.
+	APIKeyPrefix = "wt_"
+
+	// APITokenPrefix starts every user-wide API token, so tokens and
+	// account-scoped keys are told apart.
+	APITokenPrefix = "wtu_"
+
+	// apiKeyDisplayLength is the number of random characters after the
+	// prefix that are kept to identify a key.
+	apiKeyDisplayLength = 8
+)
+
+// GenerateAPIKey creates a random API key. It returns the key, which must be
+// shown to the user once, its hash for storage and its displayable start.
+func GenerateAPIKey() (key, hash, displayPrefix string, err error) {
+	return generateKey(APIKeyPrefix)
+}
+
+// GenerateAPIToken creates a random user-wide API token, returned like
+// GenerateAPIKey returns a key.
+func GenerateAPIToken() (token, hash, displayPrefix string, err error) {
+	return generateKey(APITokenPrefix)
+}
+
+// generateKey creates a random key starting with prefix.
+func generateKey(prefix string) (key, hash, displayPrefix string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", "", fmt.Errorf("generating api key: %w", err)
+	}
+	key = prefix + hex.EncodeToString(bytes)
+	return key, HashAPIKey(key), key[:len(prefix)+apiKeyDisplayLength], nil
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. The keys
+// are random, so an unsalted fast hash suffices.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
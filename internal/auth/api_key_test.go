@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, hash, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) != len(APIKeyPrefix)+64 {
+		t.Errorf("key = %q; want %s followed by 64 hex digits", key, APIKeyPrefix)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) >= len(key)/2 {
+		t.Errorf("prefix = %q; want a short start of the key", prefix)
+	}
+	if hash != HashAPIKey(key) || strings.Contains(hash, key[len(APIKeyPrefix):]) {
+		t.Error("hash does not match HashAPIKey or contains the key")
+	}
+
+	other, _, _, _ := GenerateAPIKey()
+	if other == key {
+		t.Error("GenerateAPIKey() returned the same key twice")
+	}
+}
+
+func TestGenerateAPIToken(t *testing.T) {
+	token, hash, prefix, err := GenerateAPIToken()
+	if err != nil {
+		t.Fatalf("GenerateAPIToken() error = %v", err)
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) || len(token) != len(APITokenPrefix)+64 {
+		t.Errorf("token = %q; want %s followed by 64 hex digits", token, APITokenPrefix)
+	}
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		t.Error("token starts like an account API key")
+	}
+	if prefix != token[:len(APITokenPrefix)+8] || hash != HashAPIKey(token) {
+		t.Errorf("prefix = %q, hash = %q; want the token's start and hash", prefix, hash)
+	}
+}
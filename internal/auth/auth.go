@@ -74,6 +74,12 @@ func (sm *SessionManager) WithDuration(d time.Duration) *SessionManager {
 
 // Create creates a new session for a user.
 func (sm *SessionManager) Create(userID int64) (*models.Session, error) {
+	return sm.CreateUntil(userID, time.Now().Add(sm.duration))
+}
+
+// CreateUntil creates a new session for a user that expires at the given
+// time, such as the expiry of a temporary demo user.
+func (sm *SessionManager) CreateUntil(userID int64, expiresAt time.Time) (*models.Session, error) {
 	// Generate random session ID
 	id, err := generateSessionID()
 	if err != nil {
@@ -83,7 +89,7 @@ func (sm *SessionManager) Create(userID int64) (*models.Session, error) {
 	session := &models.Session{
 		ID:        id,
 		UserID:    userID,
-		ExpiresAt: time.Now().Add(sm.duration),
+		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 	}
 
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// LoginLinkDuration is how long a login link can be used after it is created.
+const LoginLinkDuration = 15 * time.Minute
+
+// ErrLoginLinkInvalid is returned when a login link does not exist, has
+// expired or was already used.
+var ErrLoginLinkInvalid = errors.New("login link is invalid, expired or already used")
+
+// LoginLinkManager handles the one-time login links admins send to users who
+// are locked out, so they can log in and set a new password themselves.
+type LoginLinkManager struct {
+	db       *database.DB
+	sessions *SessionManager
+}
+
+// NewLoginLinkManager creates a new LoginLinkManager.
+func NewLoginLinkManager(db *database.DB, sessions *SessionManager) *LoginLinkManager {
+	return &LoginLinkManager{db: db, sessions: sessions}
+}
+
+// Create creates a login link for a user on behalf of an admin. It returns
+// the link's token, which must be shown to the admin once, and its expiry.
+func (m *LoginLinkManager) Create(userID, createdBy int64) (string, time.Time, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", time.Time{}, fmt.Errorf("generating login link: %w", err)
+	}
+	token := hex.EncodeToString(bytes)
+
+	now := time.Now()
+	expiresAt := now.Add(LoginLinkDuration)
+
+	// Expired links are of no use to anyone, so drop them while here
+	if _, err := m.db.Exec(`DELETE FROM login_links WHERE expires_at < ? AND used_at IS NULL`, now); err != nil {
+		return "", time.Time{}, fmt.Errorf("cleaning expired login links: %w", err)
+	}
+
+	// Tokens are random like API keys, so they are hashed the same way
+	_, err := m.db.Exec(`
+		INSERT INTO login_links (token_hash, user_id, created_by, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, HashAPIKey(token), userID, createdBy, expiresAt, now)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating login link: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Use uses up a login link and creates a session for its user. A link works
+// once, and only until it expires.
+func (m *LoginLinkManager) Use(token string) (*models.Session, error) {
+	hash := HashAPIKey(token)
+	now := time.Now()
+
+	result, err := m.db.Exec(`
+		UPDATE login_links SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+	`, now, hash, now)
+	if err != nil {
+		return nil, fmt.Errorf("using login link: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("getting affected rows: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrLoginLinkInvalid
+	}
+
+	var userID int64
+	if err := m.db.QueryRow(`SELECT user_id FROM login_links WHERE token_hash = ?`, hash).Scan(&userID); err != nil {
+		return nil, fmt.Errorf("getting login link: %w", err)
+	}
+
+	session, err := m.sessions.Create(userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.db.Exec(`UPDATE login_links SET session_id = ? WHERE token_hash = ?`, session.ID, hash); err != nil {
+		return nil, fmt.Errorf("recording login link session: %w", err)
+	}
+	return session, nil
+}
+
+// IsLinkSession returns true if the session was created by a login link.
+func (m *LoginLinkManager) IsLinkSession(sessionID string) (bool, error) {
+	var exists int
+	err := m.db.QueryRow(`SELECT 1 FROM login_links WHERE session_id = ?`, sessionID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking login link session: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoginLinkManager_Use_CreatesSessionOnce(t *testing.T) {
+	db := setupTestDB(t)
+	userID := createTestUser(t, db)
+	m := NewLoginLinkManager(db, NewSessionManager(db))
+
+	token, expiresAt, err := m.Create(userID, userID)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if token == "" || expiresAt.After(time.Now().Add(LoginLinkDuration)) {
+		t.Errorf("Create() = %q, %v; want a token expiring within %v", token, expiresAt, LoginLinkDuration)
+	}
+
+	var stored int
+	db.QueryRow(`SELECT COUNT(*) FROM login_links WHERE token_hash = ?`, token).Scan(&stored)
+	if stored != 0 {
+		t.Error("login link token stored in plain text")
+	}
+
+	session, err := m.Use(token)
+	if err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if session.UserID != userID {
+		t.Errorf("session UserID = %d, want %d", session.UserID, userID)
+	}
+	if ok, err := m.IsLinkSession(session.ID); err != nil || !ok {
+		t.Errorf("IsLinkSession() = %v, %v; want true", ok, err)
+	}
+
+	if _, err := m.Use(token); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Errorf("second Use() error = %v, want ErrLoginLinkInvalid", err)
+	}
+}
+
+func TestLoginLinkManager_Use_RejectsExpiredAndUnknown(t *testing.T) {
+	db := setupTestDB(t)
+	userID := createTestUser(t, db)
+	m := NewLoginLinkManager(db, NewSessionManager(db))
+
+	token, _, err := m.Create(userID, userID)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	db.Exec(`UPDATE login_links SET expires_at = ?`, time.Now().Add(-time.Minute))
+
+	if _, err := m.Use(token); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Errorf("Use() of expired link error = %v, want ErrLoginLinkInvalid", err)
+	}
+	if _, err := m.Use("unknown"); !errors.Is(err, ErrLoginLinkInvalid) {
+		t.Errorf("Use() of unknown link error = %v, want ErrLoginLinkInvalid", err)
+	}
+}
+
+func TestLoginLinkManager_IsLinkSession_PasswordLogin(t *testing.T) {
+	db := setupTestDB(t)
+	userID := createTestUser(t, db)
+	sm := NewSessionManager(db)
+	m := NewLoginLinkManager(db, sm)
+
+	session, err := sm.Create(userID)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if ok, err := m.IsLinkSession(session.ID); err != nil || ok {
+		t.Errorf("IsLinkSession() = %v, %v; want false", ok, err)
+	}
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Reasons a password is refused, as used in PasswordError.Reason.
+const (
+	PasswordTooShort = "too_short"
+	PasswordTooWeak  = "too_weak"
+	PasswordBreached = "breached"
+)
+
+// PasswordError tells why a password was refused, in words for the user.
+type PasswordError struct {
+	Reason  string
+	Message string
+}
+
+func (e *PasswordError) Error() string {
+	return e.Message
+}
+
+// PasswordPolicy decides which passwords users may choose when they
+// register, change their password or have it reset by an admin.
+type PasswordPolicy struct {
+	MinLength int
+	MinScore  int // 0-4, as estimated by PasswordScore
+
+	// BreachDir holds Have I Been Pwned range files, one per 5-character
+	// SHA-1 prefix named like "21BD1.txt" with "SUFFIX:COUNT" lines, as
+	// the Pwned Passwords downloader writes them. Only the prefix file of a
+	// password is read. Empty skips the breach check.
+	BreachDir string
+}
+
+// DefaultPasswordPolicy is the policy used when none is configured.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, MinScore: 2}
+
+// Check returns a *PasswordError if the password is not allowed. userInputs,
+// such as the user's name and email, make passwords built from them weaker.
+// A breach check that cannot be done is logged and skipped.
+func (p PasswordPolicy) Check(password string, userInputs ...string) error {
+	if len([]rune(password)) < p.MinLength {
+		return &PasswordError{
+			Reason:  PasswordTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength),
+		}
+	}
+	if PasswordScore(password, userInputs...) < p.MinScore {
+		return &PasswordError{
+			Reason:  PasswordTooWeak,
+			Message: "Password is too easy to guess; use a few uncommon words, or add length rather than symbols",
+		}
+	}
+	if p.BreachDir != "" {
+		breached, err := p.Breached(password)
+		if err != nil {
+			log.Printf("Error checking password against breaches: %v", err)
+		} else if breached {
+			return &PasswordError{
+				Reason:  PasswordBreached,
+				Message: "Password has appeared in a known data breach; choose one you have not used elsewhere",
+			}
+		}
+	}
+	return nil
+}
+
+// Hint describes the policy to users choosing a password.
+func (p PasswordPolicy) Hint() string {
+	hint := fmt.Sprintf("At least %d characters", p.MinLength)
+	if p.MinScore > 0 {
+		hint += "; avoid common words, names and patterns like 1234 or qwerty"
+	}
+	if p.BreachDir != "" {
+		hint += "; passwords from known data breaches are refused"
+	}
+	return hint
+}
+
+// Breached returns true if the password is in the range file of its SHA-1
+// prefix, k-anonymity style, so full hashes are never looked up.
+func (p PasswordPolicy) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(p.BreachDir, prefix+".txt"))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// commonPasswords are among the most used passwords and words in them; a
+// password made of one of them, with digits or symbols around it, is the
+// first thing guessed.
+var commonPasswords = []string{
+	"password", "passw0rd", "qwerty", "qwertz", "azerty", "letmein", "welcome",
+	"admin", "administrator", "login", "master", "dragon", "monkey", "football",
+	"baseball", "soccer", "hockey", "superman", "batman", "starwars", "shadow",
+	"sunshine", "princess", "iloveyou", "trustno1", "whatever", "freedom",
+	"secret", "hello", "charlie", "michael", "jennifer", "jordan", "thomas",
+	"hunter", "ranger", "buster", "killer", "pepper", "ginger", "summer",
+	"winter", "spring", "autumn", "flower", "computer", "internet", "changeme",
+	"default", "guest", "access", "money", "wealth", "tracker", "finance",
+	"banking", "nordnet", "saxo", "danmark", "denmark", "kodeord", "adgangskode",
+	"hemmelig", "sommer", "vinter", "elskerdig", "kobenhavn", "copenhagen",
+	"abc123", "qazwsx", "zxcvbn", "asdfgh", "1q2w3e4r", "q1w2e3r4",
+}
+
+// keyboardRows are walked by passwords like "qwerty" and "asdf".
+var keyboardRows = []string{
+	"`1234567890-=", "qwertyuiop[]", "asdfghjkl;'", "zxcvbnm,./", "qwertzuiop", "yxcvbnm",
+}
+
+// PasswordScore estimates how hard a password is to guess on zxcvbn's scale:
+// 0 is too guessable, 1 very guessable, 2 somewhat guessable, 3 safely
+// unguessable and 4 very unguessable. It counts the bits an attacker needs,
+// with dictionary words, user inputs, repeats, sequences and keyboard walks
+// costing only a few bits each.
+func PasswordScore(password string, userInputs ...string) int {
+	bits := passwordBits(password, userInputs)
+	// zxcvbn's thresholds of 10^3, 10^6, 10^8 and 10^10 guesses
+	switch {
+	case bits < 3*math.Log2(10):
+		return 0
+	case bits < 6*math.Log2(10):
+		return 1
+	case bits < 8*math.Log2(10):
+		return 2
+	case bits < 10*math.Log2(10):
+		return 3
+	}
+	return 4
+}
+
+// passwordBits estimates the entropy of a password in bits.
+func passwordBits(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	unleeted := []rune(unleet(string(lower)))
+
+	// Words to look for: common passwords and the parts of user inputs
+	words := append([]string(nil), commonPasswords...)
+	for _, input := range userInputs {
+		for _, part := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(part)) >= 3 {
+				words = append(words, part)
+			}
+		}
+	}
+
+	// Like zxcvbn, characters matching no pattern cost 10 guesses each
+	perChar := math.Log2(10)
+	wordBits := math.Log2(float64(len(words)))
+
+	// best[i] is the fewest bits to produce the first i characters
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + perChar
+
+		// A character repeating or continuing the previous one
+		if i >= 2 {
+			d := lower[i-1] - lower[i-2]
+			if d >= -1 && d <= 1 || keyboardAdjacent(lower[i-2], lower[i-1]) {
+				best[i] = math.Min(best[i], best[i-1]+1)
+			}
+		}
+
+		// A year from 1900 to 2099 ending here
+		if i >= 4 && isYear(lower[i-4:i]) {
+			best[i] = math.Min(best[i], best[i-4]+math.Log2(200))
+		}
+
+		// A dictionary word ending here, with a bit each for capitals and
+		// substitutions
+		for _, w := range words {
+			start := i - len([]rune(w))
+			if start < 0 {
+				continue
+			}
+			bits := wordBits
+			switch w {
+			case string(lower[start:i]):
+			case string(unleeted[start:i]):
+				bits++
+			default:
+				continue
+			}
+			if hasUpper(runes[start:i]) {
+				bits++
+			}
+			best[i] = math.Min(best[i], best[start]+bits)
+		}
+	}
+	return best[len(runes)]
+}
+
+// keyboardAdjacent returns true if b is next to a on a keyboard row.
+func keyboardAdjacent(a, b rune) bool {
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		if i < 0 {
+			continue
+		}
+		if (i > 0 && rune(row[i-1]) == b) || (i+1 < len(row) && rune(row[i+1]) == b) {
+			return true
+		}
+	}
+	return false
+}
+
+// unleet undoes common letter substitutions, like "p@ssw0rd".
+func unleet(s string) string {
+	return strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t").Replace(s)
+}
+
+// isYear returns true if the four runes are a year from 1900 to 2099.
+func isYear(r []rune) bool {
+	for _, c := range r {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	century := string(r[:2])
+	return century == "19" || century == "20"
+}
+
+func hasUpper(runes []rune) bool {
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
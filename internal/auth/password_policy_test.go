@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordScore(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+	}{
+		{"password", 0, 0},
+		{"Password123", 1, 0},
+		{"p@ssw0rd!", 1, 0},
+		{"12345678", 1, 0},
+		{"aaaaaaaaaaaa", 1, 0},
+		{"qwertyuiop", 1, 0},
+		{"kX9#vLq2", 4, 3},
+		{"correct horse battery staple", 4, 4},
+	}
+	for _, tt := range tests {
+		got := PasswordScore(tt.password)
+		if got < tt.minScore || got > tt.maxScore {
+			t.Errorf("PasswordScore(%q) = %d; want %d-%d", tt.password, got, tt.minScore, tt.maxScore)
+		}
+	}
+
+	// Passwords built from the user's own details are weak
+	if got := PasswordScore("jensen1985", "Anna Jensen", "anna.jensen@example.com"); got > 1 {
+		t.Errorf("PasswordScore of the user's name = %d; want at most 1", got)
+	}
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("tumbling-vortex-cabbage"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	rangeFile := "0123456789ABCDEF0123456789ABCDEF012:3\n" + strings.ToLower(hash[5:]) + ":42\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(rangeFile), 0o644); err != nil {
+		t.Fatalf("writing range file: %v", err)
+	}
+	policy := PasswordPolicy{MinLength: 10, MinScore: 2, BreachDir: dir}
+
+	tests := []struct {
+		password string
+		reason   string
+	}{
+		{"short", PasswordTooShort},
+		{"password12345", PasswordTooWeak},
+		{"tumbling-vortex-cabbage", PasswordBreached},
+		{"quiet-lantern-orbit", ""},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.password)
+		var pwErr *PasswordError
+		switch {
+		case tt.reason == "" && err != nil:
+			t.Errorf("Check(%q) error = %v; want nil", tt.password, err)
+		case tt.reason != "" && (!errors.As(err, &pwErr) || pwErr.Reason != tt.reason):
+			t.Errorf("Check(%q) error = %v; want reason %s", tt.password, err, tt.reason)
+		}
+	}
+
+	// A missing range file skips the breach check
+	policy.BreachDir = filepath.Join(dir, "missing")
+	if err := policy.Check("tumbling-vortex-cabbage"); err != nil {
+		t.Errorf("Check() without range files error = %v; want nil", err)
+	}
+}
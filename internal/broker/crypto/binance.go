@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binanceClient reads a Binance spot account through its REST API, signing
+// account requests with an API key.
+type binanceClient struct {
+	*client
+}
+
+// binanceAccount is the spot account of an API key.
+type binanceAccount struct {
+	Balances []struct {
+		Asset  string `json:"asset"`
+		Free   string `json:"free"`
+		Locked string `json:"locked"`
+	} `json:"balances"`
+}
+
+// binancePrice is the last price of a symbol.
+type binancePrice struct {
+	Price string `json:"price"`
+}
+
+// binanceError is the body of a refused request.
+type binanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// binanceInvalidSymbol is the error code of an unknown market.
+const binanceInvalidSymbol = -1121
+
+// Balances returns the spot assets held, including those locked in open
+// orders.
+func (c *binanceClient) Balances() ([]Balance, error) {
+	query := url.Values{
+		"omitZeroBalances": {"true"},
+		"timestamp":        {strconv.FormatInt(time.Now().UnixMilli(), 10)},
+	}.Encode()
+	req, err := http.NewRequest("GET", c.baseURL+"/api/v3/account?"+query+"&signature="+c.signature(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-MBX-APIKEY", c.apiKey)
+
+	body, err := c.do(req, "account")
+	if err != nil {
+		return nil, err
+	}
+	var account binanceAccount
+	if err := decode(body, "account", &account); err != nil {
+		return nil, err
+	}
+
+	var balances []Balance
+	for _, b := range account.Balances {
+		free, _ := strconv.ParseFloat(b.Free, 64)
+		locked, _ := strconv.ParseFloat(b.Locked, 64)
+		if free+locked == 0 {
+			continue
+		}
+		balances = append(balances, Balance{Asset: strings.ToUpper(b.Asset), Amount: free + locked})
+	}
+	return balances, nil
+}
+
+// Price returns the last price of an asset in a currency.
+func (c *binanceClient) Price(asset, currency string) (float64, error) {
+	symbol := strings.ToUpper(asset) + strings.ToUpper(currency)
+	req, err := http.NewRequest("GET", c.baseURL+"/api/v3/ticker/price?symbol="+url.QueryEscape(symbol), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := c.do(req, "price")
+	var status *statusError
+	if errors.As(err, &status) {
+		var refused binanceError
+		if decode(body, "error", &refused) == nil && refused.Code == binanceInvalidSymbol {
+			return 0, ErrNoMarket
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	var price binancePrice
+	if err := decode(body, "price", &price); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(price.Price, 64)
+}
+
+// signature returns the hex HMAC-SHA256, keyed with the secret, of a
+// request's query.
+func (c *binanceClient) signature(query string) string {
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(query))
+	return hex.EncodeToString(mac.Sum(nil))
+}
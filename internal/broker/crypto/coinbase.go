@@ -0,0 +1,124 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// coinbaseVersion is the API version requests are made against.
+const coinbaseVersion = "2024-01-01"
+
+// coinbaseClient reads a Coinbase account through the v2 API, signing
+// requests with an API key.
+type coinbaseClient struct {
+	*client
+}
+
+// coinbaseAccounts is a page of the wallets of a Coinbase account.
+type coinbaseAccounts struct {
+	Pagination struct {
+		NextURI string `json:"next_uri"`
+	} `json:"pagination"`
+	Data []struct {
+		ID      string `json:"id"`
+		Balance struct {
+			Amount   string `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"balance"`
+	} `json:"data"`
+}
+
+// coinbasePrice is a spot price.
+type coinbasePrice struct {
+	Data struct {
+		Amount string `json:"amount"`
+	} `json:"data"`
+}
+
+// Balances returns the balance of every wallet holding something. Wallets
+// of the same asset, such as a vault next to the wallet, are added up.
+func (c *coinbaseClient) Balances() ([]Balance, error) {
+	totals := make(map[string]float64)
+	var assets []string
+	for path := "/v2/accounts?limit=100"; path != ""; {
+		req, err := http.NewRequest("GET", c.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.sign(req, path)
+
+		body, err := c.do(req, "accounts")
+		if err != nil {
+			return nil, err
+		}
+		var page coinbaseAccounts
+		if err := decode(body, "accounts", &page); err != nil {
+			return nil, err
+		}
+
+		for _, wallet := range page.Data {
+			amount, err := strconv.ParseFloat(wallet.Balance.Amount, 64)
+			if err != nil || amount == 0 {
+				continue
+			}
+			asset := strings.ToUpper(wallet.Balance.Currency)
+			if _, ok := totals[asset]; !ok {
+				assets = append(assets, asset)
+			}
+			totals[asset] += amount
+		}
+		path = page.Pagination.NextURI
+	}
+
+	balances := make([]Balance, len(assets))
+	for i, asset := range assets {
+		balances[i] = Balance{Asset: asset, Amount: totals[asset]}
+	}
+	return balances, nil
+}
+
+// Price returns the spot price of an asset in a currency. Prices are
+// public, so the request is not signed.
+func (c *coinbaseClient) Price(asset, currency string) (float64, error) {
+	pair := url.PathEscape(strings.ToUpper(asset) + "-" + strings.ToUpper(currency))
+	req, err := http.NewRequest("GET", c.baseURL+"/v2/prices/"+pair+"/spot", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("CB-VERSION", coinbaseVersion)
+
+	body, err := c.do(req, "price")
+	var status *statusError
+	if errors.As(err, &status) && (status.status == http.StatusNotFound || status.status == http.StatusBadRequest) {
+		return 0, ErrNoMarket
+	}
+	if err != nil {
+		return 0, err
+	}
+	var price coinbasePrice
+	if err := decode(body, "price", &price); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(price.Data.Amount, 64)
+}
+
+// sign adds the API key headers to a request of path, which includes the
+// query. The signature is the hex HMAC-SHA256, keyed with the secret, of
+// the timestamp, method, path and body; requests here have no body.
+func (c *coinbaseClient) sign(req *http.Request, path string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(timestamp + req.Method + path))
+
+	req.Header.Set("CB-ACCESS-KEY", c.apiKey)
+	req.Header.Set("CB-ACCESS-SIGN", hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("CB-VERSION", coinbaseVersion)
+}
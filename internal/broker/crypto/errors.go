@@ -0,0 +1,19 @@
+// Package crypto provides API-key clients for the balances and prices of
+// cryptocurrency exchanges: Coinbase, Kraken and Binance.
+package crypto
+
+import "errors"
+
+var (
+	// ErrInvalidKey indicates the exchange refused the API key or its
+	// signature, such as a revoked key or one without read permission.
+	ErrInvalidKey = errors.New("exchange refused the API key - check the key, its secret and that it may view balances")
+
+	// ErrNoMarket indicates the exchange has no market pricing an asset in
+	// the requested currency.
+	ErrNoMarket = errors.New("no market for the asset in this currency")
+
+	// ErrUnknownExchange indicates a broker type that is not a supported
+	// exchange.
+	ErrUnknownExchange = errors.New("unknown exchange")
+)
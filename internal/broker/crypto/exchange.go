@@ -0,0 +1,180 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/broker"
+)
+
+const httpClientTimeout = 30 * time.Second
+
+// Broker types of the supported exchanges.
+const (
+	Coinbase = "coinbase"
+	Kraken   = "kraken"
+	Binance  = "binance"
+)
+
+// Exchanges are the broker types of the supported exchanges.
+var Exchanges = []string{Coinbase, Kraken, Binance}
+
+// IsExchange reports whether a broker type is a supported exchange.
+func IsExchange(brokerType string) bool {
+	for _, name := range Exchanges {
+		if name == brokerType {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName returns the name of an exchange as shown to users.
+func DisplayName(exchange string) string {
+	switch exchange {
+	case Coinbase:
+		return "Coinbase"
+	case Kraken:
+		return "Kraken"
+	case Binance:
+		return "Binance"
+	}
+	return exchange
+}
+
+// Balance is the amount of an asset held at an exchange.
+type Balance struct {
+	Asset  string // Ticker such as "BTC" or "EUR", in the common spelling
+	Amount float64
+}
+
+// Exchange reads the balances of an API key's account and the prices of
+// assets.
+type Exchange interface {
+	// Balances returns the assets held, leaving out those with nothing
+	// held.
+	Balances() ([]Balance, error)
+
+	// Price returns the last traded price of an asset in a currency, or
+	// ErrNoMarket if the exchange has no market between them.
+	Price(asset, currency string) (float64, error)
+
+	// SetHTTPConfig routes requests through the configured proxy and
+	// User-Agent. Call it before SetTrail.
+	SetHTTPConfig(cfg broker.HTTPConfig)
+
+	// SetTrail records requests in t for diagnostics.
+	SetTrail(t *broker.Trail)
+}
+
+// New returns the client of an exchange for an API key and its secret.
+// baseURL overrides the exchange's API address, such as for a sandbox;
+// empty uses the production API.
+func New(exchange, baseURL, apiKey, apiSecret string) (Exchange, error) {
+	c := &client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		apiSecret:  apiSecret,
+		httpClient: &http.Client{Timeout: httpClientTimeout},
+	}
+	switch exchange {
+	case Coinbase:
+		c.useDefaultURL("https://api.coinbase.com")
+		return &coinbaseClient{c}, nil
+	case Kraken:
+		c.useDefaultURL("https://api.kraken.com")
+		return &krakenClient{c}, nil
+	case Binance:
+		c.useDefaultURL("https://api.binance.com")
+		return &binanceClient{c}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownExchange, exchange)
+}
+
+// Value returns the value of an amount of an asset in currency. Assets in
+// the currency itself are worth their amount.
+func Value(e Exchange, b Balance, currency string) (price, value float64, err error) {
+	if strings.EqualFold(b.Asset, currency) {
+		return 1, b.Amount, nil
+	}
+	price, err = e.Price(b.Asset, currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return price, b.Amount * price, nil
+}
+
+// client holds what the exchange clients share.
+type client struct {
+	baseURL    string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+}
+
+// useDefaultURL sets the API address unless one was given.
+func (c *client) useDefaultURL(url string) {
+	if c.baseURL == "" {
+		c.baseURL = url
+	}
+}
+
+// SetHTTPConfig routes the client's requests through the configured proxy
+// and User-Agent. Call it before SetTrail.
+func (c *client) SetHTTPConfig(cfg broker.HTTPConfig) {
+	c.httpClient.Transport = cfg.Transport()
+}
+
+// SetTrail records the client's requests in t for diagnostics.
+func (c *client) SetTrail(t *broker.Trail) {
+	c.httpClient.Transport = t.Wrap(c.httpClient.Transport)
+}
+
+// do sends a request and returns the body of a successful response.
+// Responses refusing the credentials are returned as ErrInvalidKey.
+func (c *client) do(req *http.Request, what string) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s response: %w", what, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, string(body))
+	case resp.StatusCode != http.StatusOK:
+		return body, &statusError{what: what, status: resp.StatusCode, body: string(body)}
+	}
+	return body, nil
+}
+
+// decode decodes the JSON body of a response into v.
+func decode(body []byte, what string, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", what, err)
+	}
+	return nil
+}
+
+// statusError is an unexpected response status, which the exchange clients
+// inspect for errors such as an unknown market.
+type statusError struct {
+	what   string
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failed to get %s: status %d, body: %s", e.what, e.status, e.body)
+}
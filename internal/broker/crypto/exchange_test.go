@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestExchange returns the client of an exchange served by handler for
+// the duration of a test.
+func newTestExchange(t *testing.T, exchange, secret string, handler http.HandlerFunc) Exchange {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	e, err := New(exchange, srv.URL+"/", "key", secret)
+	if err != nil {
+		t.Fatalf("New(%s): %v", exchange, err)
+	}
+	return e
+}
+
+func TestNew_UnknownExchange(t *testing.T) {
+	if _, err := New("mtgox", "", "key", "secret"); !errors.Is(err, ErrUnknownExchange) {
+		t.Errorf("err = %v, want ErrUnknownExchange", err)
+	}
+	if !IsExchange(Kraken) || IsExchange("saxo") {
+		t.Error("IsExchange misjudged a broker type")
+	}
+}
+
+func TestCoinbase_BalancesSignsAndFollowsPages(t *testing.T) {
+	e := newTestExchange(t, Coinbase, "cb-secret", func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.RequestURI()
+		mac := hmac.New(sha256.New, []byte("cb-secret"))
+		mac.Write([]byte(r.Header.Get("CB-ACCESS-TIMESTAMP") + r.Method + path))
+		if r.Header.Get("CB-ACCESS-KEY") != "key" || r.Header.Get("CB-ACCESS-SIGN") != hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("request %s is not signed with the API key", path)
+		}
+
+		switch path {
+		case "/v2/accounts?limit=100":
+			io.WriteString(w, `{"pagination":{"next_uri":"/v2/accounts?limit=100&starting_after=a2"},"data":[
+				{"id":"a1","balance":{"amount":"0.5","currency":"BTC"}},
+				{"id":"a2","balance":{"amount":"0.00","currency":"ETH"}}]}`)
+		case "/v2/accounts?limit=100&starting_after=a2":
+			io.WriteString(w, `{"pagination":{"next_uri":null},"data":[
+				{"id":"a3","balance":{"amount":"0.25","currency":"BTC"}},
+				{"id":"a4","balance":{"amount":"120.50","currency":"USD"}}]}`)
+		default:
+			t.Errorf("unexpected request %s", path)
+		}
+	})
+
+	balances, err := e.Balances()
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	want := []Balance{{Asset: "BTC", Amount: 0.75}, {Asset: "USD", Amount: 120.5}}
+	if len(balances) != len(want) || balances[0] != want[0] || balances[1] != want[1] {
+		t.Errorf("balances = %+v, want %+v", balances, want)
+	}
+}
+
+func TestCoinbase_Price(t *testing.T) {
+	e := newTestExchange(t, Coinbase, "cb-secret", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/prices/BTC-USD/spot":
+			io.WriteString(w, `{"data":{"amount":"60000.50","base":"BTC","currency":"USD"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"errors":[{"id":"not_found","message":"Invalid currency"}]}`)
+		}
+	})
+
+	price, value, err := Value(e, Balance{Asset: "BTC", Amount: 2}, "USD")
+	if err != nil || price != 60000.5 || value != 120001 {
+		t.Errorf("Value(2 BTC) = %v, %v, %v; want 60000.5, 120001", price, value, err)
+	}
+	if _, err := e.Price("NOPE", "USD"); !errors.Is(err, ErrNoMarket) {
+		t.Errorf("Price(NOPE) err = %v, want ErrNoMarket", err)
+	}
+	if price, value, err := Value(e, Balance{Asset: "USD", Amount: 10}, "usd"); err != nil || price != 1 || value != 10 {
+		t.Errorf("Value(10 USD) = %v, %v, %v; want the amount itself", price, value, err)
+	}
+}
+
+func TestKraken_BalancesSignsAndNormalizesAssets(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString([]byte("kraken-secret"))
+	e := newTestExchange(t, Kraken, secret, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/0/private/Balance" {
+			t.Errorf("request = %s %s, want POST /0/private/Balance", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parsing form: %v", err)
+		}
+		digest := sha256.Sum256([]byte(r.PostForm.Get("nonce") + r.PostForm.Encode()))
+		mac := hmac.New(sha512.New, []byte("kraken-secret"))
+		mac.Write([]byte(r.URL.Path))
+		mac.Write(digest[:])
+		if r.Header.Get("API-Key") != "key" || r.Header.Get("API-Sign") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			t.Error("balance request is not signed with the API key")
+		}
+		io.WriteString(w, `{"error":[],"result":{"XXBT":"0.1000000000","XETH":"2.5","ZEUR":"150.20","DOT":"0.0000","ETH2.S":"1.0"}}`)
+	})
+
+	balances, err := e.Balances()
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	want := []Balance{{Asset: "BTC", Amount: 0.1}, {Asset: "ETH", Amount: 2.5}, {Asset: "ETH2.S", Amount: 1}, {Asset: "EUR", Amount: 150.2}}
+	if len(balances) != len(want) {
+		t.Fatalf("balances = %+v, want %+v", balances, want)
+	}
+	for i := range want {
+		if balances[i] != want[i] {
+			t.Errorf("balances[%d] = %+v, want %+v", i, balances[i], want[i])
+		}
+	}
+}
+
+func TestKraken_PriceAndErrors(t *testing.T) {
+	e := newTestExchange(t, Kraken, base64.StdEncoding.EncodeToString([]byte("s")), func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/0/private/Balance" {
+			io.WriteString(w, `{"error":["EAPI:Invalid key"]}`)
+			return
+		}
+		switch r.URL.Query().Get("pair") {
+		case "XBTEUR":
+			io.WriteString(w, `{"error":[],"result":{"XXBTZEUR":{"a":["55001.0","1","1.0"],"c":["55000.1","0.01"]}}}`)
+		default:
+			io.WriteString(w, `{"error":["EQuery:Unknown asset pair"]}`)
+		}
+	})
+
+	if price, err := e.Price("BTC", "EUR"); err != nil || price != 55000.1 {
+		t.Errorf("Price(BTC, EUR) = %v, %v; want 55000.1", price, err)
+	}
+	if _, err := e.Price("BTC", "DKK"); !errors.Is(err, ErrNoMarket) {
+		t.Errorf("Price(BTC, DKK) err = %v, want ErrNoMarket", err)
+	}
+	if _, err := e.Balances(); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Balances err = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestBinance_BalancesSignsQuery(t *testing.T) {
+	e := newTestExchange(t, Binance, "bn-secret", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		signature := query.Get("signature")
+		query.Del("signature")
+		mac := hmac.New(sha256.New, []byte("bn-secret"))
+		mac.Write([]byte(query.Encode()))
+		if r.Header.Get("X-MBX-APIKEY") != "key" || signature != hex.EncodeToString(mac.Sum(nil)) {
+			t.Error("account request is not signed with the API key")
+		}
+		io.WriteString(w, `{"balances":[{"asset":"BTC","free":"0.2","locked":"0.05"},{"asset":"BNB","free":"0.0","locked":"0.0"}]}`)
+	})
+
+	balances, err := e.Balances()
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	if len(balances) != 1 || balances[0] != (Balance{Asset: "BTC", Amount: 0.25}) {
+		t.Errorf("balances = %+v, want 0.25 BTC with the locked amount", balances)
+	}
+}
+
+func TestBinance_PriceAndErrors(t *testing.T) {
+	e := newTestExchange(t, Binance, "bn-secret", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/account" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`)
+			return
+		}
+		if r.URL.Query().Get("symbol") == "ETHEUR" {
+			io.WriteString(w, `{"symbol":"ETHEUR","price":"3000.25"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+	})
+
+	if price, err := e.Price("eth", "eur"); err != nil || price != 3000.25 {
+		t.Errorf("Price(ETH, EUR) = %v, %v; want 3000.25", price, err)
+	}
+	if _, err := e.Price("ETH", "DKK"); !errors.Is(err, ErrNoMarket) {
+		t.Errorf("Price(ETH, DKK) err = %v, want ErrNoMarket", err)
+	}
+	if _, err := e.Balances(); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Balances err = %v, want ErrInvalidKey", err)
+	}
+}
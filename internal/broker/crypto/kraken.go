@@ -0,0 +1,161 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// krakenClient reads a Kraken account through its REST API, signing
+// private requests with an API key.
+type krakenClient struct {
+	*client
+}
+
+// krakenResponse is the envelope of every Kraken response, which reports
+// errors in the body with status 200.
+type krakenResponse[T any] struct {
+	Error  []string `json:"error"`
+	Result T        `json:"result"`
+}
+
+// krakenTicker is the ticker of a pair; C holds the last trade's price and
+// volume.
+type krakenTicker struct {
+	C []string `json:"c"`
+}
+
+// krakenAliases are Kraken's names of assets whose common ticker differs.
+var krakenAliases = map[string]string{
+	"XBT":  "BTC",
+	"XDG":  "DOGE",
+	"XXBT": "BTC",
+	"XXDG": "DOGE",
+}
+
+// krakenAsset returns the common ticker of a Kraken asset name: legacy
+// names carry an X (crypto) or Z (fiat) prefix, such as XETH and ZEUR, and
+// Bitcoin is XBT.
+func krakenAsset(name string) string {
+	if alias, ok := krakenAliases[name]; ok {
+		return alias
+	}
+	if len(name) == 4 && (name[0] == 'X' || name[0] == 'Z') {
+		name = name[1:]
+		if alias, ok := krakenAliases[name]; ok {
+			return alias
+		}
+	}
+	return name
+}
+
+// Balances returns the assets held. Staked and earning balances, such as
+// ETH2.S or DOT.F, are kept under their own names.
+func (c *krakenClient) Balances() ([]Balance, error) {
+	path := "/0/private/Balance"
+	form := url.Values{"nonce": {strconv.FormatInt(time.Now().UnixMilli(), 10)}}
+	body := form.Encode()
+
+	req, err := http.NewRequest("POST", c.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := c.sign(req, path, form.Get("nonce"), body); err != nil {
+		return nil, err
+	}
+
+	result, err := krakenCall[map[string]string](c, req, "balances")
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]Balance, 0, len(result))
+	for name, value := range result {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount == 0 {
+			continue
+		}
+		balances = append(balances, Balance{Asset: krakenAsset(name), Amount: amount})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+	return balances, nil
+}
+
+// Price returns the last traded price of an asset in a currency.
+func (c *krakenClient) Price(asset, currency string) (float64, error) {
+	asset = strings.ToUpper(asset)
+	if asset == "BTC" {
+		asset = "XBT"
+	}
+	pair := asset + strings.ToUpper(currency)
+	req, err := http.NewRequest("GET", c.baseURL+"/0/public/Ticker?pair="+url.QueryEscape(pair), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := krakenCall[map[string]krakenTicker](c, req, "ticker")
+	if err != nil {
+		return 0, err
+	}
+	// The result is keyed by Kraken's own name of the pair, such as XXBTZEUR
+	for _, ticker := range result {
+		if len(ticker.C) == 0 {
+			break
+		}
+		return strconv.ParseFloat(ticker.C[0], 64)
+	}
+	return 0, ErrNoMarket
+}
+
+// krakenCall sends a request and returns the result of the response,
+// turning the errors Kraken reports in the body into Go errors.
+func krakenCall[T any](c *krakenClient, req *http.Request, what string) (T, error) {
+	var resp krakenResponse[T]
+	body, err := c.do(req, what)
+	if err != nil {
+		return resp.Result, err
+	}
+	if err := decode(body, what, &resp); err != nil {
+		return resp.Result, err
+	}
+	if len(resp.Error) == 0 {
+		return resp.Result, nil
+	}
+
+	message := strings.Join(resp.Error, ", ")
+	switch {
+	case strings.Contains(message, "Unknown asset pair"):
+		return resp.Result, ErrNoMarket
+	case strings.HasPrefix(message, "EAPI:Invalid key"), strings.HasPrefix(message, "EAPI:Invalid signature"),
+		strings.HasPrefix(message, "EGeneral:Permission denied"):
+		return resp.Result, fmt.Errorf("%w: %s", ErrInvalidKey, message)
+	}
+	return resp.Result, fmt.Errorf("failed to get %s: %s", what, message)
+}
+
+// sign adds the API key headers to a private request. The signature is the
+// base64 HMAC-SHA512, keyed with the base64-decoded secret, of the path and
+// the SHA-256 of the nonce and the form body.
+func (c *krakenClient) sign(req *http.Request, path, nonce, body string) error {
+	secret, err := base64.StdEncoding.DecodeString(c.apiSecret)
+	if err != nil {
+		return fmt.Errorf("%w: the private key is not base64", ErrInvalidKey)
+	}
+	digest := sha256.Sum256([]byte(nonce + body))
+	mac := hmac.New(sha512.New, secret)
+	mac.Write([]byte(path))
+	mac.Write(digest[:])
+
+	req.Header.Set("API-Key", c.apiKey)
+	req.Header.Set("API-Sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return nil
+}
@@ -32,6 +32,7 @@ var (
 // Encryptor handles credential encryption and decryption.
 type Encryptor struct {
 	masterKey []byte
+	aead      cipher.AEAD // Keyed with the master key, for Seal and Open
 }
 
 // NewEncryptor creates a new Encryptor with the given master secret.
@@ -42,7 +43,15 @@ func NewEncryptor(secret string) (*Encryptor, error) {
 	}
 	// Use SHA-256 to normalize the key length
 	hash := sha256.Sum256([]byte(secret))
-	return &Encryptor{masterKey: hash[:]}, nil
+	block, err := aes.NewCipher(hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCM: %w", err)
+	}
+	return &Encryptor{masterKey: hash[:], aead: aead}, nil
 }
 
 // DeriveKey derives a unique encryption key using PBKDF2 with the user ID as additional salt.
@@ -105,3 +114,27 @@ func (e *Encryptor) Decrypt(ciphertext, nonce []byte, userID int64) (string, err
 
 	return string(plaintext), nil
 }
+
+// Seal encrypts plaintext with the master key and returns the nonce followed
+// by the ciphertext. The additional data ad must be passed to Open again,
+// binding the value to, for example, the key it is stored under. Unlike
+// Encrypt it derives no key per call, for values read on every request.
+func (e *Encryptor) Seal(plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// Open decrypts a value sealed by Seal with the same additional data.
+func (e *Encryptor) Open(sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < NonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := e.aead.Open(nil, sealed[:NonceSize], sealed[NonceSize:], ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
@@ -179,3 +179,24 @@ func TestEncryptor_DeriveKey_Deterministic(t *testing.T) {
 		t.Errorf("DeriveKey() length = %d, want %d", len(key1), KeySize)
 	}
 }
+
+func TestEncryptor_SealOpen(t *testing.T) {
+	secret := "this-is-a-valid-32-character-key"
+	enc, _ := NewEncryptor(secret)
+
+	sealed, err := enc.Seal([]byte("session token"), []byte("session:1"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	got, err := enc.Open(sealed, []byte("session:1"))
+	if err != nil || string(got) != "session token" {
+		t.Errorf("Open() = %q, %v; want session token", got, err)
+	}
+
+	if _, err := enc.Open(sealed, []byte("session:2")); err != ErrDecryptionFailed {
+		t.Errorf("Open() with other additional data error = %v, want %v", err, ErrDecryptionFailed)
+	}
+	if _, err := enc.Open(sealed[:NonceSize-1], nil); err != ErrInvalidCiphertext {
+		t.Errorf("Open() of a short value error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
@@ -0,0 +1,255 @@
+package broker
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"wealth_tracker/internal/netguard"
+)
+
+var (
+	// ErrInvalidProxyURL is returned for a proxy that is not an absolute
+	// http, https or socks5 URL.
+	ErrInvalidProxyURL = errors.New("proxy must be an http://, https:// or socks5:// URL with a host")
+
+	// ErrPrivateProxy is returned for a connection's own proxy on a
+	// private or local address.
+	ErrPrivateProxy = errors.New("proxy must not point to a private or local address")
+
+	// ErrPrivateAPIURL is returned for an API URL set by a user, such as a
+	// bank's, on a private or local address.
+	ErrPrivateAPIURL = errors.New("API URL must not point to a private or local address")
+)
+
+// privateProxiesAllowed lets connections use proxies on private addresses;
+// set in tests, whose proxies run on loopback.
+var privateProxiesAllowed bool
+
+// HTTPConfig is how broker HTTP clients reach the broker: through an
+// optional outbound proxy and with an optional User-Agent replacing the
+// built-in browser string. Empty fields fall back to the global default.
+type HTTPConfig struct {
+	ProxyURL  string
+	UserAgent string
+
+	// defaultProxy is set if ProxyURL is the global default, which the
+	// admin set and may be on the private network.
+	defaultProxy bool
+
+	// publicAPI is set if the API address was set by the user, so direct
+	// connections must only go to public addresses. See PublicAPI.
+	publicAPI bool
+}
+
+// Validate checks that the proxy, if any, is usable.
+func (c HTTPConfig) Validate() error {
+	if c.ProxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.ProxyURL)
+	if err != nil || u.Host == "" {
+		return ErrInvalidProxyURL
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	}
+	return ErrInvalidProxyURL
+}
+
+// ValidateConnection checks the config of a connection, set by its user:
+// the proxy must be usable and must not be a private or local address, so
+// users cannot reach the server's network through it.
+func (c HTTPConfig) ValidateConnection() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if c.ProxyURL == "" || privateProxiesAllowed {
+		return nil
+	}
+	u, _ := url.Parse(c.ProxyURL)
+	if netguard.ResolvesPrivate(u.Hostname()) {
+		return ErrPrivateProxy
+	}
+	return nil
+}
+
+// PublicAPI returns the config for a client whose API URL was set by the
+// user, which only connects directly to public addresses.
+func (c HTTPConfig) PublicAPI() HTTPConfig {
+	c.publicAPI = true
+	return c
+}
+
+// ValidateAPIURL checks that an API URL set by a user does not point to a
+// private or local address, so users cannot read the server's network
+// through it. Hosts that do not resolve are checked again when dialed.
+func ValidateAPIURL(apiURL string) error {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if loopbackAPIsAllowed() && (host == "localhost" || net.ParseIP(host).IsLoopback()) {
+		return nil
+	}
+	if netguard.ResolvesPrivate(host) {
+		return ErrPrivateAPIURL
+	}
+	return nil
+}
+
+// IsZero returns true if neither a proxy nor a User-Agent is set.
+func (c HTTPConfig) IsZero() bool {
+	return c.ProxyURL == "" && c.UserAgent == ""
+}
+
+// Transport returns a RoundTripper that applies the config, or nil to use
+// http.DefaultTransport unchanged. An invalid proxy is ignored, as it is
+// rejected when saved. A connection's own proxy, and a user's API address
+// reached without a proxy, are only dialed on a public address, checked on
+// the address actually dialed.
+func (c HTTPConfig) Transport() http.RoundTripper {
+	if t := overrideTransport(); t != nil {
+		if c.UserAgent == "" {
+			return t
+		}
+		return &userAgentTransport{userAgent: c.UserAgent, next: t}
+	}
+	if c.IsZero() && !c.publicAPI {
+		return nil
+	}
+	var next http.RoundTripper = http.DefaultTransport
+	if c.ProxyURL != "" && c.Validate() == nil {
+		proxy, _ := url.Parse(c.ProxyURL)
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxy)
+		if !c.defaultProxy && !privateProxiesAllowed {
+			dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: netguard.Control(ErrPrivateProxy)}
+			transport.DialContext = dialer.DialContext
+		}
+		next = transport
+	} else if c.publicAPI {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: publicAPIControl}
+		transport.DialContext = dialer.DialContext
+		next = transport
+	}
+	if c.UserAgent == "" {
+		return next
+	}
+	return &userAgentTransport{userAgent: c.UserAgent, next: next}
+}
+
+// userAgentTransport replaces the User-Agent of every request, including
+// the browser string the login flows set.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
+
+var (
+	httpConfigMu      sync.RWMutex
+	defaultHTTPConfig HTTPConfig
+	connectionConfigs = make(map[int64]HTTPConfig)
+	transport         http.RoundTripper // Replaces the network and proxies, see SetTransport
+	loopbackAPIs      bool              // See SetLoopbackAPIsAllowed
+)
+
+// SetLoopbackAPIsAllowed lets API URLs set by users point at this machine,
+// for tests against local fakes. Other private addresses stay refused.
+func SetLoopbackAPIsAllowed(allow bool) {
+	httpConfigMu.Lock()
+	defer httpConfigMu.Unlock()
+	loopbackAPIs = allow
+}
+
+// loopbackAPIsAllowed returns the setting of SetLoopbackAPIsAllowed.
+func loopbackAPIsAllowed() bool {
+	httpConfigMu.RLock()
+	defer httpConfigMu.RUnlock()
+	return loopbackAPIs
+}
+
+// publicAPIControl refuses connections to private addresses, apart from
+// loopback if SetLoopbackAPIsAllowed allows it.
+func publicAPIControl(network, address string, c syscall.RawConn) error {
+	if host, _, err := net.SplitHostPort(address); err == nil && loopbackAPIsAllowed() && net.ParseIP(host).IsLoopback() {
+		return nil
+	}
+	return netguard.Control(ErrPrivateAPIURL)(network, address, c)
+}
+
+// SetTransport sends the requests of all broker clients through t instead
+// of the network and their proxies; nil sends them over the network again.
+// The mock broker uses it to answer them from recorded payloads.
+func SetTransport(t http.RoundTripper) {
+	httpConfigMu.Lock()
+	defer httpConfigMu.Unlock()
+	transport = t
+}
+
+// overrideTransport returns the transport set with SetTransport, if any.
+func overrideTransport() http.RoundTripper {
+	httpConfigMu.RLock()
+	defer httpConfigMu.RUnlock()
+	return transport
+}
+
+// SetDefaultHTTPConfig sets the config of connections without their own
+// proxy or User-Agent.
+func SetDefaultHTTPConfig(c HTTPConfig) {
+	httpConfigMu.Lock()
+	defer httpConfigMu.Unlock()
+	defaultHTTPConfig = c
+}
+
+// SetConnectionHTTPConfig sets the config of a connection, used by the
+// login flows and clients that only know its ID.
+func SetConnectionHTTPConfig(connectionID int64, c HTTPConfig) {
+	httpConfigMu.Lock()
+	defer httpConfigMu.Unlock()
+	if c.IsZero() {
+		delete(connectionConfigs, connectionID)
+		return
+	}
+	connectionConfigs[connectionID] = c
+}
+
+// HTTPConfigFor returns the config of a connection, with the global default
+// filling in what the connection does not set.
+func HTTPConfigFor(connectionID int64) HTTPConfig {
+	httpConfigMu.RLock()
+	defer httpConfigMu.RUnlock()
+	c := connectionConfigs[connectionID]
+	if c.ProxyURL == "" {
+		c.ProxyURL = defaultHTTPConfig.ProxyURL
+		c.defaultProxy = true
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = defaultHTTPConfig.UserAgent
+	}
+	return c
+}
+
+// NewHTTPClient creates an HTTP client for a connection's requests to its
+// broker. jar may be nil.
+func NewHTTPClient(connectionID int64, timeout time.Duration, jar http.CookieJar) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Jar:       jar,
+		Transport: HTTPConfigFor(connectionID).Transport(),
+	}
+}
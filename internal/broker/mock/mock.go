@@ -0,0 +1,245 @@
+// Package mock answers Nordnet and Saxo API requests with recorded payloads
+// from a local HTTP server. The real broker clients are pointed at it, so
+// syncs and handlers can be tested end to end, through the clients' parsing
+// and the sync paths, without calling real broker APIs or going through
+// MitID/OAuth.
+package mock
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/models"
+)
+
+// fixtures holds recorded API responses, one directory per broker:
+//
+//	nordnet/accounts.json, positions_<accid>.json, ledgers_<accid>.json, orders_<accid>.json
+//	saxo/client.json, accounts.json, instruments.json, positions_<key>.json, balance_<key>.json
+//
+//go:embed fixtures
+var fixtures embed.FS
+
+// hosts are the broker API hosts whose requests the server answers.
+var hosts = map[string]bool{
+	"www.nordnet.dk":       true,
+	"www.nordnet.se":       true,
+	"www.nordnet.no":       true,
+	"www.nordnet.fi":       true,
+	"gateway.saxobank.com": true,
+}
+
+// The credentials of the sessions SignIn creates, which the server requires.
+const (
+	nordnetSessionTag = "mock-ntag"
+	saxoAccessToken   = "mock-access-token"
+)
+
+// Server is an HTTP server answering Nordnet and Saxo API requests with the
+// recorded payloads.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	payloads map[string]string // Replaced fixtures by name, see SetFixture
+	signedIn map[int64]string  // Broker type by connection ID, see SignIn
+}
+
+// Start starts a server and sends the Nordnet and Saxo requests of the
+// broker clients to it. Other requests go over the network as before.
+func Start() *Server {
+	s := &Server{payloads: make(map[string]string), signedIn: make(map[int64]string)}
+	s.Server = httptest.NewServer(s.routes())
+	broker.SetTransport(&rewriteTransport{target: s.URL, next: http.DefaultTransport})
+	return s
+}
+
+// Close shuts the server down, drops the sessions SignIn cached and sends
+// broker requests over the network again.
+func (s *Server) Close() {
+	broker.SetTransport(nil)
+	s.Server.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, brokerType := range s.signedIn {
+		if brokerType == "nordnet" {
+			nordnet.InvalidateCachedSession(id)
+		} else {
+			saxo.ClearCachedSession(id)
+		}
+	}
+	clear(s.signedIn)
+}
+
+// SetFixture replaces a recorded payload, such as "nordnet/positions_1.json",
+// e.g. to simulate a trade between two syncs.
+func (s *Server) SetFixture(name, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads[name] = payload
+}
+
+// SignIn caches a session for a Nordnet or Saxo connection, standing in for
+// the interactive MitID, BankID or OAuth login the server cannot answer.
+func (s *Server) SignIn(conn *models.BrokerConnection) error {
+	switch conn.BrokerType {
+	case "nordnet":
+		nordnet.CacheSession(conn.ID, &nordnet.Session{
+			JWT:       "mock-jwt",
+			NTag:      nordnetSessionTag,
+			ExpiresAt: time.Now().Add(24 * time.Hour),
+		})
+	case "saxo":
+		saxo.CacheSession(conn.ID, &saxo.Session{
+			AccessToken:      saxoAccessToken,
+			TokenType:        "Bearer",
+			ExpiresAt:        time.Now().Add(time.Hour),
+			RefreshExpiresAt: time.Now().Add(24 * time.Hour),
+		})
+	default:
+		return fmt.Errorf("the mock broker does not answer %s requests", conn.BrokerType)
+	}
+	s.mu.Lock()
+	s.signedIn[conn.ID] = conn.BrokerType
+	s.mu.Unlock()
+	return nil
+}
+
+// routes returns the handler of the API endpoints the clients call.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// Nordnet
+	mux.HandleFunc("PUT /api/2/login", s.nordnet(func(r *http.Request) (string, string) {
+		return "", `{"logged_in": true}`
+	}))
+	mux.HandleFunc("GET /api/2/accounts", s.nordnet(func(r *http.Request) (string, string) {
+		return "nordnet/accounts.json", ""
+	}))
+	mux.HandleFunc("GET /api/2/accounts/{id}/positions", s.nordnet(func(r *http.Request) (string, string) {
+		return fmt.Sprintf("nordnet/positions_%s.json", r.PathValue("id")), ""
+	}))
+	mux.HandleFunc("GET /api/2/accounts/{id}/ledgers", s.nordnet(func(r *http.Request) (string, string) {
+		return fmt.Sprintf("nordnet/ledgers_%s.json", r.PathValue("id")), `{"ledgers": []}`
+	}))
+	mux.HandleFunc("GET /api/2/accounts/{id}/orders", s.nordnet(func(r *http.Request) (string, string) {
+		return fmt.Sprintf("nordnet/orders_%s.json", r.PathValue("id")), "[]"
+	}))
+	mux.HandleFunc("GET /api/2/accounts/{id}/trades", s.nordnet(func(r *http.Request) (string, string) {
+		return fmt.Sprintf("nordnet/trades_%s.json", r.PathValue("id")), "[]"
+	}))
+
+	// Saxo
+	mux.HandleFunc("GET /openapi/port/v1/clients/me", s.saxo(func(r *http.Request) string {
+		return "saxo/client.json"
+	}))
+	mux.HandleFunc("GET /openapi/port/v1/accounts", s.saxo(func(r *http.Request) string {
+		return "saxo/accounts.json"
+	}))
+	mux.HandleFunc("GET /openapi/port/v1/positions", s.saxo(func(r *http.Request) string {
+		return fmt.Sprintf("saxo/positions_%s.json", r.URL.Query().Get("AccountKey"))
+	}))
+	mux.HandleFunc("GET /openapi/port/v1/balances", s.saxo(func(r *http.Request) string {
+		return fmt.Sprintf("saxo/balance_%s.json", r.URL.Query().Get("AccountKey"))
+	}))
+	mux.HandleFunc("GET /openapi/ref/v1/instruments/details", s.saxo(func(r *http.Request) string {
+		return "saxo/instruments.json"
+	}))
+
+	return mux
+}
+
+// nordnet answers a Nordnet request of a signed in session with the fixture
+// named by fixture, or with the fallback payload if it has none.
+func (s *Server) nordnet(fixture func(r *http.Request) (name, fallback string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("ntag") != nordnetSessionTag {
+			http.Error(w, `{"code": "NEXT_INVALID_SESSION"}`, http.StatusUnauthorized)
+			return
+		}
+		name, fallback := fixture(r)
+		s.serve(w, name, fallback)
+	}
+}
+
+// saxo answers a Saxo request of a signed in session with the fixture named
+// by fixture.
+func (s *Server) saxo(fixture func(r *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+saxoAccessToken {
+			http.Error(w, `{"ErrorCode": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		s.serve(w, fixture(r), "")
+	}
+}
+
+// serve writes a fixture, or the fallback payload if there is no such
+// fixture. Without either, the request is answered with 404.
+func (s *Server) serve(w http.ResponseWriter, name, fallback string) {
+	payload, err := s.fixture(name)
+	if errors.Is(err, fs.ErrNotExist) && fallback != "" {
+		payload, err = fallback, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, nil)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, payload)
+}
+
+// fixture returns a recorded payload, or the one it was replaced with.
+func (s *Server) fixture(name string) (string, error) {
+	if name == "" {
+		return "", fs.ErrNotExist
+	}
+	s.mu.Lock()
+	payload, ok := s.payloads[name]
+	s.mu.Unlock()
+	if ok {
+		return payload, nil
+	}
+	data, err := fixtures.ReadFile(path.Join("fixtures", name))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// rewriteTransport sends requests to the broker API hosts to the server.
+type rewriteTransport struct {
+	target string
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !hosts[req.URL.Hostname()] {
+		return t.next.RoundTrip(req)
+	}
+	target, err := url.Parse(t.target)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.Host = target.Host
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,114 @@
+package mock
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/models"
+)
+
+func TestServer_Nordnet(t *testing.T) {
+	s := Start()
+	t.Cleanup(s.Close)
+
+	client, err := nordnet.NewClient("dk")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetHTTPConfig(broker.HTTPConfigFor(9101))
+
+	// Requests without a session are rejected like Nordnet does
+	if _, err := client.GetAccounts(&nordnet.Session{NTag: "other", JWT: "other", ExpiresAt: time.Now().Add(time.Hour)}); err == nil {
+		t.Error("GetAccounts() with an unknown session error = nil; want error")
+	}
+
+	conn := &models.BrokerConnection{ID: 9101, BrokerType: "nordnet", Country: "dk"}
+	if err := s.SignIn(conn); err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	session := nordnet.GetCachedSession(conn.ID)
+
+	accounts, err := client.GetAccounts(session)
+	if err != nil {
+		t.Fatalf("GetAccounts() error = %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].AccID.String() != "1" || accounts[1].Alias != "ASK" {
+		t.Fatalf("accounts = %+v; want Frie midler and ASK", accounts)
+	}
+
+	positions, err := client.GetPositions(session, "1")
+	if err != nil {
+		t.Fatalf("GetPositions() error = %v", err)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("len(positions) = %d; want 2", len(positions))
+	}
+	iwda := positions[1]
+	if iwda.ISIN() != "IE00B4L5Y983" || iwda.MarketValueAccValue() != 14920 || math.Abs(iwda.MarketPriceValue()-100) > 1e-9 {
+		t.Errorf("IWDA position = %+v", iwda)
+	}
+
+	ledgers, err := client.GetLedgers(session, "1")
+	if err != nil || len(ledgers) != 1 || ledgers[0].AccountSum.Value != 1080 {
+		t.Errorf("GetLedgers() = %+v, %v; want 1080 DKK", ledgers, err)
+	}
+
+	s.SetFixture("nordnet/positions_1.json", "[]")
+	if positions, _ := client.GetPositions(session, "1"); len(positions) != 0 {
+		t.Errorf("positions after SetFixture() = %+v; want none", positions)
+	}
+	if _, err := client.GetPositions(session, "unknown"); err == nil {
+		t.Error("GetPositions(unknown) error = nil; want error")
+	}
+}
+
+func TestServer_Saxo(t *testing.T) {
+	s := Start()
+	t.Cleanup(s.Close)
+
+	conn := &models.BrokerConnection{ID: 9102, BrokerType: "saxo"}
+	if err := s.SignIn(conn); err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	session := saxo.GetCachedSession(conn.ID)
+
+	client := saxo.NewClient()
+	client.SetHTTPConfig(broker.HTTPConfigFor(conn.ID))
+	data, err := client.FetchAccounts(session, []string{"key-ask"})
+	if err != nil {
+		t.Fatalf("FetchAccounts() error = %v", err)
+	}
+	account := data["key-ask"]
+	if account == nil || account.Err != nil || account.Balance == nil || account.Balance.TotalValue != 21000 {
+		t.Fatalf("FetchAccounts() = %+v; want key-ask with value 21000", account)
+	}
+	if len(account.Positions) != 1 || account.Positions[0].Symbol() != "DSV:xcse" || account.Positions[0].MarketValue() != 20250 {
+		t.Errorf("positions = %+v", account.Positions)
+	}
+}
+
+func TestSignIn_OtherBrokers(t *testing.T) {
+	s := Start()
+	t.Cleanup(s.Close)
+
+	if err := s.SignIn(&models.BrokerConnection{ID: 9103, BrokerType: "psd2"}); err == nil {
+		t.Error("SignIn() for a PSD2 connection error = nil; want error")
+	}
+}
+
+func TestClose_DropsSessions(t *testing.T) {
+	s := Start()
+	conn := &models.BrokerConnection{ID: 9104, BrokerType: "nordnet", Country: "dk"}
+	if err := s.SignIn(conn); err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	s.Close()
+
+	if session := nordnet.GetCachedSession(conn.ID); session != nil {
+		t.Errorf("session after Close() = %+v; want none", session)
+	}
+}
@@ -0,0 +1,351 @@
+package nordnet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/nordnet/mitid"
+)
+
+// BankID and FTN authentication errors.
+var (
+	ErrBankIDFailed     = errors.New("BankID authentication failed")
+	ErrBankIDTimeout    = errors.New("BankID authentication timed out - user did not approve in time")
+	ErrFTNLoginRequired = errors.New("log in with your bank on the connection page first")
+	ErrFTNLoginInvalid  = errors.New("the pasted address is not a Nordnet login from this connection")
+)
+
+// FTNLoginTimeout is how long a bank login started in the browser can be
+// completed by pasting the address Nordnet returns to.
+const FTNLoginTimeout = 10 * time.Minute
+
+// bankIDPollInterval is how often the status of a BankID login is checked.
+const bankIDPollInterval = 2 * time.Second
+
+// bankIDOrder is the BankID order Signicat starts for a login.
+type bankIDOrder struct {
+	OrderRef       string `json:"orderRef"`
+	AutoStartToken string `json:"autoStartToken"`
+	QRStartToken   string `json:"qrStartToken"`
+	QRStartSecret  string `json:"qrStartSecret"`
+}
+
+// bankIDCollectResult is the status of a BankID order.
+type bankIDCollectResult struct {
+	Status   string `json:"status"` // pending, complete or failed
+	HintCode string `json:"hintCode"`
+}
+
+// bankIDQRData returns the content of the animated Swedish BankID QR code
+// the given time after the order was started. The code changes every second
+// and proves the QR code was scanned from the live screen.
+func bankIDQRData(qrStartToken, qrStartSecret string, elapsed time.Duration) string {
+	qrTime := fmt.Sprintf("%d", int(elapsed.Seconds()))
+	mac := hmac.New(sha256.New, []byte(qrStartSecret))
+	mac.Write([]byte(qrTime))
+	return "bankid." + qrStartToken + "." + qrTime + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+// bankIDHintMessage returns a user-facing explanation of a failed BankID
+// order's hint code.
+func bankIDHintMessage(hintCode string) string {
+	switch hintCode {
+	case "userCancel":
+		return "the login was cancelled in the BankID app"
+	case "expiredTransaction":
+		return "the login expired before it was approved"
+	case "startFailed":
+		return "the BankID app was not started in time"
+	case "certificateErr":
+		return "your BankID is blocked or no longer valid"
+	case "cancelled":
+		return "another BankID login was started"
+	case "":
+		return "unknown error"
+	}
+	return hintCode
+}
+
+// AuthenticateWithBankID logs in to Nordnet Sweden or Norway with BankID via
+// Signicat. Swedish logins are started by scanning a QR code, which is
+// served like the MitID QR code; Norwegian logins are started for the given
+// national identity number and approved in the BankID app.
+func AuthenticateWithBankID(connectionID int64, country, nationalID string) (*Session, error) {
+	method := AuthMethod(country)
+	if method != AuthBankIDSE && method != AuthBankIDNO {
+		return nil, fmt.Errorf("BankID login is not used for Nordnet %s", country)
+	}
+	if method == AuthBankIDNO && nationalID == "" {
+		return nil, fmt.Errorf("national identity number is required for Norwegian BankID")
+	}
+
+	// Check for a cached session Nordnet still accepts - avoids re-authentication
+	if cachedSession := GetValidatedSession(connectionID); cachedSession != nil {
+		return cachedSession, nil
+	}
+
+	qrDir, done, err := beginAuthSession(connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	jar, _ := cookiejar.New(nil)
+	httpClient := broker.NewHTTPClient(connectionID, 2*time.Minute, jar)
+	qrManager := mitid.NewQRManager(qrDir)
+	qrManager.SetStatus("initializing")
+
+	session, err := bankIDLogin(httpClient, jar, qrManager, country, nationalID)
+	if err != nil {
+		qrManager.SetStatus("failed")
+		return nil, err
+	}
+	qrManager.SetStatus("complete")
+
+	CacheSession(connectionID, session)
+	return session, nil
+}
+
+// bankIDLogin runs the Signicat BankID login and exchanges its code for a
+// Nordnet session.
+func bankIDLogin(httpClient *http.Client, jar *cookiejar.Jar, qrManager *mitid.QRManager, country, nationalID string) (*Session, error) {
+	domain, loginURL := signicatLoginURL(country, newOIDCState())
+
+	log.Printf("[BankID] Step 1: Initiating Signicat OIDC flow for %s", domain)
+	page, _, err := getSignicatPage(httpClient, loginURL)
+	if err != nil {
+		return nil, fmt.Errorf("initiating login: %w", err)
+	}
+	indexURL := extractDataAttribute(page, "data-index-url")
+	if indexURL == "" {
+		return nil, fmt.Errorf("could not find data-index-url in response")
+	}
+
+	log.Printf("[BankID] Step 2: Fetching index page")
+	page, _, err = getSignicatPage(httpClient, indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching index: %w", err)
+	}
+	baseURL := extractDataAttribute(page, "data-base-url")
+	initAuthPath := extractDataAttribute(page, "data-init-auth-path")
+	collectPath := extractDataAttribute(page, "data-collect-path")
+	finalizeAuthPath := extractDataAttribute(page, "data-finalize-auth-path")
+	if baseURL == "" || initAuthPath == "" || collectPath == "" {
+		return nil, fmt.Errorf("could not find required paths in HTML")
+	}
+
+	log.Printf("[BankID] Step 3: Starting BankID order")
+	initBody := map[string]string{}
+	if nationalID != "" && AuthMethod(country) == AuthBankIDNO {
+		initBody["nationalIdentityNumber"] = nationalID
+	}
+	var order bankIDOrder
+	if err := postSignicatJSON(httpClient, baseURL+initAuthPath, initBody, &order); err != nil {
+		return nil, fmt.Errorf("starting BankID order: %w", err)
+	}
+	if order.OrderRef == "" {
+		return nil, fmt.Errorf("%w: no order was started", ErrBankIDFailed)
+	}
+
+	log.Printf("[BankID] Step 4: Waiting for approval")
+	if err := waitForBankID(httpClient, qrManager, baseURL+collectPath, &order); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[BankID] Step 5: Finalizing auth")
+	_, finalURL, err := getSignicatPage(httpClient, baseURL+finalizeAuthPath)
+	if err != nil {
+		return nil, fmt.Errorf("finalizing auth: %w", err)
+	}
+	code := finalURL.Query().Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("could not extract Signicat code from redirect URL: %s", finalURL)
+	}
+
+	return createNordnetSession(httpClient, jar, country, domain, code)
+}
+
+// waitForBankID polls a BankID order until it is approved, refreshing the
+// Swedish QR code every second meanwhile. Fails when the order fails or is
+// not approved within MitIDTimeout.
+func waitForBankID(httpClient *http.Client, qrManager *mitid.QRManager, collectURL string, order *bankIDOrder) error {
+	started := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastPoll := time.Time{}
+	for {
+		elapsed := time.Since(started)
+		if elapsed > MitIDTimeout {
+			return ErrBankIDTimeout
+		}
+
+		if order.QRStartToken != "" {
+			if err := qrManager.GenerateSingleQRCode(bankIDQRData(order.QRStartToken, order.QRStartSecret, elapsed)); err != nil {
+				log.Printf("[BankID] Error writing QR code: %v", err)
+			}
+			qrManager.SetStatus("qr_ready")
+		} else {
+			qrManager.SetStatus("waiting_for_approval")
+		}
+
+		if time.Since(lastPoll) >= bankIDPollInterval {
+			lastPoll = time.Now()
+			var result bankIDCollectResult
+			if err := postSignicatJSON(httpClient, collectURL, map[string]string{"orderRef": order.OrderRef}, &result); err != nil {
+				return fmt.Errorf("checking BankID status: %w", err)
+			}
+			switch result.Status {
+			case "complete":
+				return nil
+			case "failed":
+				return fmt.Errorf("%w: %s", ErrBankIDFailed, bankIDHintMessage(result.HintCode))
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// getSignicatPage fetches a Signicat page, following redirects, and returns
+// its body and final URL.
+func getSignicatPage(httpClient *http.Client, pageURL string) (string, *url.URL, error) {
+	resp, err := httpClient.Get(pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(body))
+	}
+	return string(body), resp.Request.URL, nil
+}
+
+// postSignicatJSON posts a JSON body to Signicat and decodes the JSON reply.
+func postSignicatJSON(httpClient *http.Client, endpoint string, body, result any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(endpoint, "application/json", strings.NewReader(string(payload)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// newOIDCState returns a random state for a Signicat login, in the format
+// Nordnet's own logins use.
+func newOIDCState() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("NEXT_OIDC_STATE_%d", time.Now().UnixNano())
+	}
+	return "NEXT_OIDC_STATE_" + hex.EncodeToString(b)
+}
+
+// ftnLogin is a Finnish bank login started in the user's browser.
+type ftnLogin struct {
+	country   string
+	state     string
+	startedAt time.Time
+}
+
+// pendingFTNLogins holds the bank logins started per connection ID.
+var (
+	pendingFTNLogins      = make(map[int64]*ftnLogin)
+	pendingFTNLoginsMutex sync.Mutex
+)
+
+// StartFTNLogin returns the address at which the user logs in with their
+// Finnish bank. FTN logins go through the bank's own pages, so they cannot
+// run on the server like MitID and BankID: the user completes the login in
+// their browser, ends on Nordnet's login page, and pastes its address into
+// CompleteFTNLogin.
+func StartFTNLogin(connectionID int64, country string) string {
+	state := newOIDCState()
+	_, loginURL := signicatLoginURL(country, state)
+
+	pendingFTNLoginsMutex.Lock()
+	pendingFTNLogins[connectionID] = &ftnLogin{country: country, state: state, startedAt: time.Now()}
+	pendingFTNLoginsMutex.Unlock()
+
+	return loginURL
+}
+
+// CompleteFTNLogin exchanges the code in the address Nordnet returned to
+// after a bank login started by StartFTNLogin for a Nordnet session, and
+// caches it for the connection's syncs.
+func CompleteFTNLogin(connectionID int64, redirectURL string) (*Session, error) {
+	pendingFTNLoginsMutex.Lock()
+	pending := pendingFTNLogins[connectionID]
+	pendingFTNLoginsMutex.Unlock()
+	if pending == nil || time.Since(pending.startedAt) > FTNLoginTimeout {
+		return nil, fmt.Errorf("no bank login in progress - start a new one")
+	}
+
+	domain, _ := signicatLoginURL(pending.country, "")
+	code, err := ftnCodeFromRedirect(redirectURL, domain, pending.state)
+	if err != nil {
+		return nil, err
+	}
+
+	// The code can only be used once, whatever the outcome
+	pendingFTNLoginsMutex.Lock()
+	delete(pendingFTNLogins, connectionID)
+	pendingFTNLoginsMutex.Unlock()
+
+	jar, _ := cookiejar.New(nil)
+	httpClient := broker.NewHTTPClient(connectionID, 2*time.Minute, jar)
+	session, err := createNordnetSession(httpClient, jar, pending.country, domain, code)
+	if err != nil {
+		return nil, err
+	}
+
+	CacheSession(connectionID, session)
+	return session, nil
+}
+
+// ftnCodeFromRedirect returns the Signicat code of the address Nordnet
+// returned to, checking that it is the login page of the expected domain and
+// carries the state the login was started with.
+func ftnCodeFromRedirect(redirectURL, domain, state string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(redirectURL))
+	if err != nil || parsed.Scheme != "https" || !strings.EqualFold(parsed.Host, domain) || parsed.Path != "/login" {
+		return "", ErrFTNLoginInvalid
+	}
+	query := parsed.Query()
+	if state == "" || !hmac.Equal([]byte(query.Get("state")), []byte(state)) {
+		return "", ErrFTNLoginInvalid
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", ErrFTNLoginInvalid
+	}
+	return code, nil
+}
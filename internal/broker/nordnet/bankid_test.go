@@ -0,0 +1,90 @@
+package nordnet
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestBankIDQRData(t *testing.T) {
+	// Example from the BankID relying party documentation
+	token := "67df3917-fa0d-44e5-b327-edcc928297f8"
+	secret := "d28db9a7-4cde-429e-a983-359be676944c"
+
+	tests := []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{0, "bankid.67df3917-fa0d-44e5-b327-edcc928297f8.0.dc69358e712458a66a7525beef148ae8526b1c71610eff2c16cdffb4cdac9bf8"},
+		{1500 * time.Millisecond, "bankid.67df3917-fa0d-44e5-b327-edcc928297f8.1.949d559bf23403952a94d103e67743126381eda00f0b3cbddbf7c96b1adcbce2"},
+	}
+	for _, tc := range tests {
+		if got := bankIDQRData(token, secret, tc.elapsed); got != tc.want {
+			t.Errorf("bankIDQRData(%v) = %s; want %s", tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestSignicatLoginURL_UsesCountryMethod(t *testing.T) {
+	tests := []struct {
+		country, domain, method string
+	}{
+		{"dk", "www.nordnet.dk", "urn:signicat:oidc:method:mitid-cpr"},
+		{"se", "www.nordnet.se", "urn:signicat:oidc:method:sbid"},
+		{"no", "www.nordnet.no", "urn:signicat:oidc:method:nbid"},
+		{"fi", "www.nordnet.fi", "urn:signicat:oidc:method:ftn"},
+		{"xx", "www.nordnet.dk", "urn:signicat:oidc:method:mitid-cpr"},
+	}
+	for _, tc := range tests {
+		domain, loginURL := signicatLoginURL(tc.country, "state-1")
+		parsed, err := url.Parse(loginURL)
+		if err != nil {
+			t.Fatalf("%s: invalid login URL %q", tc.country, loginURL)
+		}
+		query := parsed.Query()
+		if domain != tc.domain || query.Get("acr_values") != tc.method || query.Get("state") != "state-1" {
+			t.Errorf("%s: domain %s, method %s, state %s; want %s, %s, state-1",
+				tc.country, domain, query.Get("acr_values"), query.Get("state"), tc.domain, tc.method)
+		}
+		if query.Get("redirect_uri") != "https://"+tc.domain+"/login" {
+			t.Errorf("%s: redirect_uri = %s", tc.country, query.Get("redirect_uri"))
+		}
+	}
+}
+
+func TestFTNCodeFromRedirect(t *testing.T) {
+	const domain, state = "www.nordnet.fi", "NEXT_OIDC_STATE_abc"
+
+	code, err := ftnCodeFromRedirect(" https://www.nordnet.fi/login?code=the-code&state=NEXT_OIDC_STATE_abc\n", domain, state)
+	if err != nil || code != "the-code" {
+		t.Fatalf("ftnCodeFromRedirect() = %q, %v; want the-code", code, err)
+	}
+
+	for _, redirect := range []string{
+		"https://www.nordnet.fi/login?code=the-code&state=other",       // Another login
+		"https://evil.example/login?code=the-code&state=" + state,      // Another site
+		"http://www.nordnet.fi/login?code=the-code&state=" + state,     // Not HTTPS
+		"https://www.nordnet.fi/login?state=" + state,                  // No code
+		"https://www.nordnet.fi/overview?code=the-code&state=" + state, // Not the login page
+	} {
+		if _, err := ftnCodeFromRedirect(redirect, domain, state); !errors.Is(err, ErrFTNLoginInvalid) {
+			t.Errorf("ftnCodeFromRedirect(%q) error = %v; want ErrFTNLoginInvalid", redirect, err)
+		}
+	}
+}
+
+func TestCompleteFTNLogin_RequiresStartedLogin(t *testing.T) {
+	if _, err := CompleteFTNLogin(9001, "https://www.nordnet.fi/login?code=x&state=y"); err == nil {
+		t.Error("CompleteFTNLogin() without a started login succeeded")
+	}
+
+	loginURL := StartFTNLogin(9001, "fi")
+	parsed, _ := url.Parse(loginURL)
+	if parsed.Query().Get("acr_values") != signicatMethods[AuthFTN] {
+		t.Errorf("StartFTNLogin() URL = %s; want an FTN login", loginURL)
+	}
+	if _, err := CompleteFTNLogin(9001, "https://www.nordnet.fi/login?code=x&state=y"); !errors.Is(err, ErrFTNLoginInvalid) {
+		t.Errorf("CompleteFTNLogin() with a foreign state error = %v; want ErrFTNLoginInvalid", err)
+	}
+}
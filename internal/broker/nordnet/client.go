@@ -8,6 +8,8 @@ import (
 	"net/http/cookiejar"
 	"sync"
 	"time"
+
+	"wealth_tracker/internal/broker"
 )
 
 const (
@@ -53,6 +55,20 @@ func NewClient(country string) (*Client, error) {
 	}, nil
 }
 
+// SetHTTPConfig routes the client's requests through the configured proxy
+// and User-Agent. Call it before SetTrail.
+func (c *Client) SetHTTPConfig(cfg broker.HTTPConfig) {
+	c.httpClient.Transport = cfg.Transport()
+	if cfg.UserAgent != "" {
+		c.userAgent = cfg.UserAgent
+	}
+}
+
+// SetTrail records the client's requests in t for diagnostics.
+func (c *Client) SetTrail(t *broker.Trail) {
+	c.httpClient.Transport = t.Wrap(c.httpClient.Transport)
+}
+
 // Country returns the country code for this client.
 func (c *Client) Country() string {
 	return c.country
@@ -97,6 +113,11 @@ func (c *Client) doRequest(req *http.Request, session *Session) (*http.Response,
 		return nil, err
 	}
 
+	// Nordnet invalidated the session before our expiry estimate
+	if resp.StatusCode == http.StatusUnauthorized {
+		expireSession(session)
+	}
+
 	// Handle rate limiting (429 Too Many Requests)
 	if resp.StatusCode == http.StatusTooManyRequests {
 		resp.Body.Close()
@@ -0,0 +1,71 @@
+package nordnet
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qrDirPrefix is the name prefix of the temp directories MitID logins write
+// their QR codes to, followed by the connection ID.
+const qrDirPrefix = "mitid_qr_"
+
+// RemoveStaleQRDirs removes the QR code directories of MitID logins that
+// are no longer running and were last written before a time, and returns
+// how many it removed. Directories of a failed or abandoned login are
+// otherwise left behind until the connection logs in again.
+func RemoveStaleQRDirs(before time.Time) (int, error) {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), qrDirPrefix) {
+			continue
+		}
+		connectionID, err := strconv.ParseInt(strings.TrimPrefix(entry.Name(), qrDirPrefix), 10, 64)
+		if err != nil || mitIDLoginRunning(connectionID) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(os.TempDir(), entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// mitIDLoginRunning reports whether a MitID login of a connection is running.
+func mitIDLoginRunning(connectionID int64) bool {
+	if GetActiveMitIDSession(connectionID) != nil {
+		return true
+	}
+	mitidSessionsNativeMutex.RLock()
+	defer mitidSessionsNativeMutex.RUnlock()
+	return activeMitIDSessionsNative[connectionID] != nil
+}
+
+// DropExpiredSessions removes cached sessions past their expiry, which
+// keep-alive pings no longer renew, and returns how many it removed.
+func DropExpiredSessions(now time.Time) int {
+	cachedNordnetSessionsMutex.Lock()
+	defer cachedNordnetSessionsMutex.Unlock()
+
+	dropped := 0
+	for id, session := range cachedNordnetSessions {
+		if now.After(session.ExpiresAt) {
+			delete(cachedNordnetSessions, id)
+			delete(sessionValidatedAt, id)
+			dropped++
+		}
+	}
+	return dropped
+}
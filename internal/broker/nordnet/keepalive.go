@@ -0,0 +1,193 @@
+package nordnet
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"wealth_tracker/internal/broker"
+)
+
+// Nordnet does not tell us when a session expires; the 24h ExpiresAt set at
+// login is only an upper bound. Cached sessions are therefore pinged before
+// reuse and in the background, and dropped as soon as the API answers 401.
+const (
+	// SessionKeepAliveInterval is how often cached sessions are pinged.
+	SessionKeepAliveInterval = 10 * time.Minute
+
+	// sessionValidateInterval is how long a successful ping is trusted before
+	// a cached session is pinged again on reuse.
+	sessionValidateInterval = 2 * time.Minute
+
+	// sessionRenewal is how far ExpiresAt is pushed out after a successful ping.
+	sessionRenewal = 24 * time.Hour
+)
+
+// sessionValidatedAt records when each cached session last answered a ping.
+// Guarded by cachedNordnetSessionsMutex.
+var sessionValidatedAt = make(map[int64]time.Time)
+
+// Ping touches the session so Nordnet keeps it alive. Returns
+// ErrSessionExpired if Nordnet no longer accepts the session.
+func (c *Client) Ping(session *Session) error {
+	if session == nil || session.IsExpired() {
+		return ErrSessionExpired
+	}
+
+	req, err := http.NewRequest("PUT", c.baseURL+"/api/2/login", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequest(req, session)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrSessionExpired
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("session ping failed: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// GetValidatedSession returns the cached session for a connection after
+// confirming with Nordnet that it is still accepted. A session Nordnet has
+// invalidated is removed from the cache and nil is returned, so the caller
+// renews it with a new login. Network errors keep the session, since they
+// say nothing about its validity.
+func GetValidatedSession(connectionID int64) *Session {
+	session := GetCachedSession(connectionID)
+	if session == nil {
+		return nil
+	}
+
+	cachedNordnetSessionsMutex.RLock()
+	validatedAt := sessionValidatedAt[connectionID]
+	cachedNordnetSessionsMutex.RUnlock()
+	if time.Since(validatedAt) < sessionValidateInterval {
+		return session
+	}
+
+	if !pingCachedSession(connectionID, session) {
+		return nil
+	}
+	return GetCachedSession(connectionID)
+}
+
+// KeepAliveSessions pings every cached session once, renewing the ones
+// Nordnet still accepts and dropping the ones it has invalidated.
+func KeepAliveSessions() {
+	cachedNordnetSessionsMutex.RLock()
+	sessions := make(map[int64]*Session, len(cachedNordnetSessions))
+	for id, session := range cachedNordnetSessions {
+		sessions[id] = session
+	}
+	cachedNordnetSessionsMutex.RUnlock()
+
+	for id, session := range sessions {
+		pingCachedSession(id, session)
+	}
+}
+
+// StartSessionKeepAlive pings cached sessions every interval until the
+// returned stop function is called.
+func StartSessionKeepAlive(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				KeepAliveSessions()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// pingCachedSession pings a cached session and updates the cache with the
+// outcome. Returns false if Nordnet rejected the session.
+func pingCachedSession(connectionID int64, session *Session) bool {
+	client, err := NewClient(sessionCountry(session))
+	if err != nil {
+		log.Printf("[Session Cache] Cannot ping session for connection %d: %v", connectionID, err)
+		return true
+	}
+	client.SetHTTPConfig(broker.HTTPConfigFor(connectionID))
+
+	err = client.Ping(session)
+	if err == ErrSessionExpired {
+		log.Printf("[Session Cache] Session for connection %d was rejected by Nordnet", connectionID)
+		InvalidateCachedSession(connectionID)
+		return false
+	}
+	if err != nil {
+		log.Printf("[Session Cache] Error pinging session for connection %d: %v", connectionID, err)
+		return true
+	}
+
+	// Replace rather than modify the cached session, which may be in use by
+	// a running sync.
+	renewed := *session
+	renewed.ExpiresAt = time.Now().Add(sessionRenewal)
+
+	cachedNordnetSessionsMutex.Lock()
+	current := cachedNordnetSessions[connectionID] == session
+	if current {
+		cachedNordnetSessions[connectionID] = &renewed
+		sessionValidatedAt[connectionID] = time.Now()
+	}
+	cachedNordnetSessionsMutex.Unlock()
+	if current {
+		saveSharedSession(connectionID, &renewed)
+	}
+	return true
+}
+
+// expireSession drops every cached entry for a session Nordnet answered 401
+// to, so the next sync logs in again instead of reusing it.
+func expireSession(session *Session) {
+	if session == nil || session.NTag == "" {
+		return
+	}
+
+	cachedNordnetSessionsMutex.Lock()
+	defer cachedNordnetSessionsMutex.Unlock()
+
+	for id, cached := range cachedNordnetSessions {
+		if cached.NTag == session.NTag {
+			delete(cachedNordnetSessions, id)
+			delete(sessionValidatedAt, id)
+			deleteSharedSession(id)
+			log.Printf("[Session Cache] Dropped session for connection %d after 401 from Nordnet", id)
+		}
+	}
+}
+
+// sessionCountry returns the country code of the Nordnet domain a session
+// was created for.
+func sessionCountry(session *Session) string {
+	for country, domain := range nordnetDomains {
+		if strings.EqualFold(session.Domain, domain) {
+			return country
+		}
+	}
+	return "dk"
+}
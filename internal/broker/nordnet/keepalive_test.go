@@ -0,0 +1,62 @@
+package nordnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, status int) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/api/2/login" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("dk")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.baseURL = server.URL
+	return client
+}
+
+func TestPing_AcceptedSession(t *testing.T) {
+	client := newTestClient(t, http.StatusOK)
+	session := &Session{NTag: "tag-ok", ExpiresAt: time.Now().Add(time.Hour)}
+
+	if err := client.Ping(session); err != nil {
+		t.Errorf("Ping() error = %v; want nil", err)
+	}
+}
+
+func TestPing_RejectedSessionIsDroppedFromCache(t *testing.T) {
+	client := newTestClient(t, http.StatusUnauthorized)
+	session := &Session{NTag: "tag-rejected", ExpiresAt: time.Now().Add(time.Hour)}
+	CacheSession(9001, session)
+	t.Cleanup(func() { InvalidateCachedSession(9001) })
+
+	if err := client.Ping(session); err != ErrSessionExpired {
+		t.Errorf("Ping() error = %v; want ErrSessionExpired", err)
+	}
+	if GetCachedSession(9001) != nil {
+		t.Error("session still cached after 401")
+	}
+}
+
+func TestSessionCountry(t *testing.T) {
+	tests := map[string]string{
+		"www.nordnet.se": "se",
+		"www.nordnet.fi": "fi",
+		"":               "dk",
+	}
+	for domain, want := range tests {
+		if got := sessionCountry(&Session{Domain: domain}); got != want {
+			t.Errorf("sessionCountry(%q) = %q; want %q", domain, got, want)
+		}
+	}
+}
@@ -77,6 +77,26 @@ func (m *QRManager) GenerateQRCodePair(channelBindingValue string, updateCount i
 	return nil
 }
 
+// GenerateSingleQRCode writes a QR code of content as the current frame. Used
+// by logins that show one QR code at a time, such as Swedish BankID.
+func (m *QRManager) GenerateSingleQRCode(content string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := os.MkdirAll(m.qrDir, 0755); err != nil {
+		return fmt.Errorf("ensuring QR directory: %w", err)
+	}
+
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("creating QR code: %w", err)
+	}
+	if err := qr.WriteFile(256, filepath.Join(m.qrDir, "qr_frame1.png")); err != nil {
+		return fmt.Errorf("writing QR file: %w", err)
+	}
+	return os.WriteFile(filepath.Join(m.qrDir, "current_frame"), []byte("1"), 0644)
+}
+
 // writeQRCode generates and writes a single QR code to a file.
 func (m *QRManager) writeQRCode(data QRData, filename string) error {
 	// Encode data as compact JSON (no spaces)
@@ -9,12 +9,12 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"os"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"wealth_tracker/internal/broker"
 	"wealth_tracker/internal/broker/nordnet/mitid"
 )
 
@@ -51,11 +51,22 @@ var (
 // Returns nil if no valid cached session exists.
 func GetCachedSession(connectionID int64) *Session {
 	cachedNordnetSessionsMutex.RLock()
-	defer cachedNordnetSessionsMutex.RUnlock()
-
 	session := cachedNordnetSessions[connectionID]
+	cachedNordnetSessionsMutex.RUnlock()
+
+	// Another instance may have logged in
 	if session == nil {
-		return nil
+		session = loadSharedSession(connectionID)
+		if session == nil {
+			return nil
+		}
+		cachedNordnetSessionsMutex.Lock()
+		if cached := cachedNordnetSessions[connectionID]; cached != nil {
+			session = cached
+		} else {
+			cachedNordnetSessions[connectionID] = session
+		}
+		cachedNordnetSessionsMutex.Unlock()
 	}
 
 	// Check if session is still valid (with 5 minute buffer)
@@ -78,6 +89,8 @@ func CacheSession(connectionID int64, session *Session) {
 	defer cachedNordnetSessionsMutex.Unlock()
 
 	cachedNordnetSessions[connectionID] = session
+	sessionValidatedAt[connectionID] = time.Now()
+	saveSharedSession(connectionID, session)
 	log.Printf("[Session Cache] Cached session for connection %d (expires at %v)", connectionID, session.ExpiresAt)
 }
 
@@ -87,6 +100,8 @@ func InvalidateCachedSession(connectionID int64) {
 	defer cachedNordnetSessionsMutex.Unlock()
 
 	delete(cachedNordnetSessions, connectionID)
+	delete(sessionValidatedAt, connectionID)
+	deleteSharedSession(connectionID)
 	log.Printf("[Session Cache] Invalidated cached session for connection %d", connectionID)
 }
 
@@ -146,74 +161,21 @@ func AuthenticateWithMitIDNative(connectionID int64, country, userID, cpr, metho
 		return nil, fmt.Errorf("native implementation only supports APP method, got %s", method)
 	}
 
-	// Check for a cached valid session first - avoids re-authentication
-	if cachedSession := GetCachedSession(connectionID); cachedSession != nil {
+	// Check for a cached session Nordnet still accepts - avoids re-authentication
+	if cachedSession := GetValidatedSession(connectionID); cachedSession != nil {
 		return cachedSession, nil
 	}
 
-	// Check if there's already an active authentication in progress
-	// This prevents double authentication when multiple requests come in
-	mitidSessionsNativeMutex.Lock()
-	existingSession := activeMitIDSessionsNative[connectionID]
-	if existingSession != nil {
-		// Only block if it's very recent (within last 90 seconds) - allows retry after timeout
-		if time.Since(existingSession.StartedAt) < 90*time.Second {
-			mitidSessionsNativeMutex.Unlock()
-			log.Printf("[MitID Native] Blocking concurrent auth attempt for connection %d - auth already in progress (started %v ago)",
-				connectionID, time.Since(existingSession.StartedAt))
-			return nil, fmt.Errorf("authentication already in progress for this connection - please wait for the current request to complete")
-		}
-		// Stale session (older than 90s), clean it up and allow new auth
-		log.Printf("[MitID Native] Cleaning up stale session for connection %d (was started %v ago)", connectionID, time.Since(existingSession.StartedAt))
-		delete(activeMitIDSessionsNative, connectionID)
-	}
-	mitidSessionsNativeMutex.Unlock()
-
-	// Get domain and client ID
-	domain := nordnetDomains[country]
-	if domain == "" {
-		domain = nordnetDomains["dk"]
-	}
-	clientID := signicatClients[country]
-	if clientID == "" {
-		clientID = signicatClients["dk"]
-	}
-
-	// Create QR output directory
-	qrDir := fmt.Sprintf("%s/mitid_qr_%d", os.TempDir(), connectionID)
-	os.RemoveAll(qrDir)
-	if err := os.MkdirAll(qrDir, 0755); err != nil {
-		return nil, fmt.Errorf("creating QR directory: %w", err)
-	}
-
-	// Track this session
-	mitidSessionsNativeMutex.Lock()
-	activeMitIDSessionsNative[connectionID] = &MitIDSession{
-		ConnectionID: connectionID,
-		QRDir:        qrDir,
-		StartedAt:    time.Now(),
+	// Track the session so the QR code and status can be served
+	qrDir, done, err := beginAuthSession(connectionID)
+	if err != nil {
+		return nil, err
 	}
-	mitidSessionsNativeMutex.Unlock()
-
-	// Clean up session when done
-	defer func() {
-		mitidSessionsNativeMutex.Lock()
-		delete(activeMitIDSessionsNative, connectionID)
-		mitidSessionsNativeMutex.Unlock()
-
-		// Clean up QR files in background
-		go func() {
-			time.Sleep(5 * time.Second)
-			os.RemoveAll(qrDir)
-		}()
-	}()
+	defer done()
 
 	// Create HTTP client with cookie jar
 	jar, _ := cookiejar.New(nil)
-	httpClient := &http.Client{
-		Jar:     jar,
-		Timeout: 2 * time.Minute,
-	}
+	httpClient := broker.NewHTTPClient(connectionID, 2*time.Minute, jar)
 
 	// Set initial status
 	qrManager := mitid.NewQRManager(qrDir)
@@ -222,15 +184,7 @@ func AuthenticateWithMitIDNative(connectionID int64, country, userID, cpr, metho
 	log.Printf("[MitID Native] Starting authentication for connection %d, user %s", connectionID, userID)
 
 	// Step 1: Initiate Signicat OIDC flow
-	loginURL := fmt.Sprintf(
-		"https://id.signicat.com/oidc/authorize?"+
-			"client_id=%s&"+
-			"response_type=code&"+
-			"redirect_uri=https://%s/login&"+
-			"scope=openid%%20signicat.national_id&"+
-			"acr_values=urn:signicat:oidc:method:mitid-cpr&"+
-			"state=NEXT_OIDC_STATE_%d",
-		clientID, domain, time.Now().UnixNano())
+	domain, loginURL := signicatLoginURL(country, fmt.Sprintf("NEXT_OIDC_STATE_%d", time.Now().UnixNano()))
 
 	log.Printf("[MitID Native] Step 1: Initiating Signicat OIDC flow")
 	resp, err := httpClient.Get(loginURL)
@@ -510,136 +464,14 @@ func AuthenticateWithMitIDNative(connectionID int64, country, userID, cpr, metho
 	}
 	log.Printf("[MitID Native] Step 5: Got Signicat code: %s...", signicatCode[:20])
 
-	// Step 5: Exchange code for Nordnet session
-	sessionPayload := map[string]interface{}{
-		"authenticationProvider": "SIGNICAT",
-		"countryCode":            strings.ToUpper(country),
-		"signicat": map[string]string{
-			"authorizationCode": signicatCode,
-			"redirectUri":       fmt.Sprintf("https://%s/login", domain),
-		},
-	}
-
-	sessionBody, _ := json.Marshal(sessionPayload)
-	req, _ = http.NewRequest(http.MethodPost,
-		fmt.Sprintf("https://%s/nnxapi/authentication/v2/sessions", domain),
-		strings.NewReader(string(sessionBody)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("client-id", "NEXT")
-
-	resp, err = httpClient.Do(req)
-	if err != nil {
-		qrManager.SetStatus("failed")
-		return nil, fmt.Errorf("creating Nordnet session: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		qrManager.SetStatus("failed")
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Nordnet session creation failed (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// Read session response - may contain useful data
-	sessionRespBody, _ := io.ReadAll(resp.Body)
-	log.Printf("[MitID Native] Step 5: Session response (status %d): %s", resp.StatusCode, string(sessionRespBody))
-
-	// Check for ntag in the session response headers
-	sessionNtag := resp.Header.Get("ntag")
-	log.Printf("[MitID Native] Step 5: Session ntag from header: %s", sessionNtag)
-
-	// Also check for set-cookie headers
-	for _, cookie := range resp.Cookies() {
-		log.Printf("[MitID Native] Step 5: Cookie: %s=%s", cookie.Name, cookie.Value[:min(20, len(cookie.Value))]+"...")
-	}
-
-	// Step 6: Login to get ntag
-	loginBody, _ := json.Marshal(map[string]interface{}{})
-	req, _ = http.NewRequest(http.MethodPost,
-		fmt.Sprintf("https://%s/api/2/authentication/nnx-session/login", domain),
-		strings.NewReader(string(loginBody)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("client-id", "NEXT")
-	req.Header.Set("x-locale", "da-DK")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	// Use ntag from session if available
-	if sessionNtag != "" {
-		req.Header.Set("ntag", sessionNtag)
-	}
-
-	resp, err = httpClient.Do(req)
+	// Steps 5-7: Exchange code for a Nordnet session
+	session, err := createNordnetSession(httpClient, jar, country, domain, signicatCode)
 	if err != nil {
 		qrManager.SetStatus("failed")
-		return nil, fmt.Errorf("login request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	loginRespBody, _ := io.ReadAll(resp.Body)
-	log.Printf("[MitID Native] Step 6: Login response (status %d): %s", resp.StatusCode, string(loginRespBody))
-
-	ntag := resp.Header.Get("ntag")
-	log.Printf("[MitID Native] Step 6: Got ntag from login response: %s", ntag)
-
-	// If login didn't return ntag, try to use the one from session creation
-	if ntag == "" && sessionNtag != "" {
-		log.Printf("[MitID Native] Step 6: Using ntag from session creation instead")
-		ntag = sessionNtag
+		return nil, err
 	}
-
-	// Step 7: Get JWT bearer token
-	req, _ = http.NewRequest(http.MethodPost,
-		fmt.Sprintf("https://%s/nnxapi/authorization/v1/tokens", domain),
-		strings.NewReader(string(loginBody)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("client-id", "NEXT")
-	req.Header.Set("x-locale", "da-DK")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("ntag", ntag)
-
-	resp, err = httpClient.Do(req)
-	if err != nil {
-		qrManager.SetStatus("failed")
-		return nil, fmt.Errorf("token request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	tokenBody, _ := io.ReadAll(resp.Body)
-	log.Printf("[MitID Native] Step 7: Token response (status %d): %s", resp.StatusCode, string(tokenBody))
-
-	var tokenResp struct {
-		JWT string `json:"jwt"`
-	}
-	if err := json.Unmarshal(tokenBody, &tokenResp); err != nil {
-		qrManager.SetStatus("failed")
-		return nil, fmt.Errorf("decoding token response: %w", err)
-	}
-
-	if tokenResp.JWT == "" {
-		log.Printf("[MitID Native] Step 7: WARNING - JWT is empty!")
-	} else {
-		log.Printf("[MitID Native] Step 7: Got JWT (length %d)", len(tokenResp.JWT))
-	}
-
 	qrManager.SetStatus("complete")
 
-	// Get cookies from cookie jar for the Nordnet domain
-	nordnetURL, _ := url.Parse(fmt.Sprintf("https://%s", domain))
-	cookies := jar.Cookies(nordnetURL)
-	log.Printf("[MitID Native] Got %d cookies from session", len(cookies))
-	for _, c := range cookies {
-		log.Printf("[MitID Native] Cookie: %s (len=%d)", c.Name, len(c.Value))
-	}
-
-	session := &Session{
-		JWT:       tokenResp.JWT,
-		NTag:      ntag,
-		Domain:    domain,
-		Cookies:   cookies,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	}
-
 	// Cache the session for future requests
 	CacheSession(connectionID, session)
 
@@ -0,0 +1,112 @@
+package nordnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Closed order states; orders in any other state are still to be executed.
+var closedOrderStates = map[string]bool{
+	"FILLED":   true,
+	"DELETED":  true,
+	"EXPIRED":  true,
+	"REJECTED": true,
+}
+
+// OrderReference identifies what placed an order.
+type OrderReference struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Order is an order of an account. Savings plans (Månedsopsparing) place fund
+// and ETF buy orders that are executed days after the money is debited.
+type Order struct {
+	OrderID      int64          `json:"order_id"`
+	AccNo        int64          `json:"accno"`
+	Side         string         `json:"side"` // "BUY" or "SELL"
+	Price        Amount         `json:"price"`
+	Volume       float64        `json:"volume"`
+	OpenVolume   float64        `json:"open_volume"`
+	TradedVolume float64        `json:"traded_volume"`
+	Amount       FlexibleFloat  `json:"amount"` // Set on amount-based fund orders
+	OrderState   string         `json:"order_state"`
+	Reference    OrderReference `json:"reference"`
+}
+
+// IsSavingsPlan reports whether a savings plan placed the order.
+func (o *Order) IsSavingsPlan() bool {
+	return strings.Contains(strings.ToUpper(o.Reference.Type), "SAVING")
+}
+
+// IsPending reports whether the order is still to be executed.
+func (o *Order) IsPending() bool {
+	return !closedOrderStates[strings.ToUpper(o.OrderState)]
+}
+
+// PendingValue returns the value of the order's unexecuted volume, or its
+// amount for amount-based fund orders.
+func (o *Order) PendingValue() float64 {
+	open := o.OpenVolume
+	if open == 0 {
+		open = o.Volume - o.TradedVolume
+	}
+	if open > 0 && o.Price.Value > 0 {
+		return open * o.Price.Value
+	}
+	return float64(o.Amount)
+}
+
+// PendingSavingsPlanBuys returns the total value and number of the savings
+// plan buy orders among orders that are still to be executed. Their money has
+// usually been debited already, so they count towards the account value.
+func PendingSavingsPlanBuys(orders []Order) (float64, int) {
+	var total float64
+	var count int
+	for _, o := range orders {
+		if strings.ToUpper(o.Side) != "BUY" || !o.IsSavingsPlan() || !o.IsPending() {
+			continue
+		}
+		total += o.PendingValue()
+		count++
+	}
+	return total, count
+}
+
+// GetOrders fetches the orders of a specific account.
+func (c *Client) GetOrders(session *Session, accountID string) ([]Order, error) {
+	if session == nil || session.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+
+	url := fmt.Sprintf("%s/api/2/accounts/%s/orders", c.baseURL, accountID)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(req, session)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrSessionExpired
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get orders: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var orders []Order
+	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+		return nil, fmt.Errorf("decoding orders: %w", err)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,41 @@
+package nordnet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetOrders_PendingSavingsPlanBuys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/2/accounts/123/orders" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[
+			{"order_id": 1, "side": "BUY", "price": {"value": 250, "currency": "DKK"}, "volume": 4, "open_volume": 4, "order_state": "LOCAL", "reference": {"type": "MONTHLY_SAVINGS"}},
+			{"order_id": 2, "side": "BUY", "amount": {"value": 1500}, "order_state": "ON_MARKET", "reference": {"type": "MONTHLY_SAVINGS"}},
+			{"order_id": 3, "side": "BUY", "price": {"value": 100}, "volume": 10, "order_state": "FILLED", "reference": {"type": "MONTHLY_SAVINGS"}},
+			{"order_id": 4, "side": "BUY", "price": {"value": 100}, "volume": 10, "order_state": "ON_MARKET", "reference": {"type": "ORDER"}},
+			{"order_id": 5, "side": "SELL", "price": {"value": 100}, "volume": 10, "order_state": "ON_MARKET", "reference": {"type": "MONTHLY_SAVINGS"}}
+		]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("dk")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.baseURL = server.URL
+
+	orders, err := client.GetOrders(&Session{NTag: "tag", ExpiresAt: time.Now().Add(time.Hour)}, "123")
+	if err != nil {
+		t.Fatalf("GetOrders() error = %v", err)
+	}
+
+	// Only the open savings plan buys count: 4 x 250 and an amount order of 1500
+	total, count := PendingSavingsPlanBuys(orders)
+	if total != 2500 || count != 2 {
+		t.Errorf("PendingSavingsPlanBuys() = %v, %d; want 2500, 2", total, count)
+	}
+}
@@ -0,0 +1,73 @@
+package nordnet
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/store"
+)
+
+// sharedSessions, if set, keeps cached sessions in the database, so a
+// session logged in before a restart, or on another instance, is reused
+// without another MitID approval.
+var sharedSessions store.Store
+
+// SetSessionStore keeps cached sessions in a store. It must be called before
+// sessions are cached.
+func SetSessionStore(s store.Store) {
+	sharedSessions = s
+}
+
+// sessionKey is the shared store key of a connection's session.
+func sessionKey(connectionID int64) string {
+	return "nordnet:session:" + strconv.FormatInt(connectionID, 10)
+}
+
+// loadSharedSession returns a connection's session from the shared store,
+// or nil.
+func loadSharedSession(connectionID int64) *Session {
+	if sharedSessions == nil {
+		return nil
+	}
+	data, err := sharedSessions.Get(sessionKey(connectionID))
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("[Session Cache] Error loading shared session for connection %d: %v", connectionID, err)
+		}
+		return nil
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		log.Printf("[Session Cache] Error decoding shared session for connection %d: %v", connectionID, err)
+		return nil
+	}
+	return &session
+}
+
+// saveSharedSession writes a connection's session to the shared store until
+// it expires.
+func saveSharedSession(connectionID int64, session *Session) {
+	if sharedSessions == nil {
+		return
+	}
+	data, err := json.Marshal(session)
+	if err == nil {
+		err = sharedSessions.Set(sessionKey(connectionID), data, time.Until(session.ExpiresAt))
+	}
+	if err != nil {
+		log.Printf("[Session Cache] Error sharing session for connection %d: %v", connectionID, err)
+	}
+}
+
+// deleteSharedSession removes a connection's session from the shared store.
+func deleteSharedSession(connectionID int64) {
+	if sharedSessions == nil {
+		return
+	}
+	if err := sharedSessions.Delete(sessionKey(connectionID)); err != nil {
+		log.Printf("[Session Cache] Error removing shared session for connection %d: %v", connectionID, err)
+	}
+}
@@ -0,0 +1,96 @@
+package nordnet
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/store"
+)
+
+// setupSessionStore keeps sessions in a state store in a new database until
+// the test ends.
+func setupSessionStore(t *testing.T) store.Store {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	encryptor, err := broker.NewEncryptor("test-secret-that-is-32-chars-long")
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	shared := store.NewSQLite(db, encryptor)
+	SetSessionStore(shared)
+	t.Cleanup(func() { SetSessionStore(nil) })
+	return shared
+}
+
+func TestSharedSessionStore(t *testing.T) {
+	shared := setupSessionStore(t)
+
+	const connectionID = 9101
+	CacheSession(connectionID, &Session{JWT: "jwt", NTag: "tag-shared", Domain: "www.nordnet.dk", ExpiresAt: time.Now().Add(time.Hour)})
+
+	// Another instance has nothing cached locally
+	cachedNordnetSessionsMutex.Lock()
+	delete(cachedNordnetSessions, connectionID)
+	cachedNordnetSessionsMutex.Unlock()
+
+	session := GetCachedSession(connectionID)
+	if session == nil || session.NTag != "tag-shared" || session.JWT != "jwt" {
+		t.Fatalf("GetCachedSession() = %+v; want the shared session", session)
+	}
+
+	InvalidateCachedSession(connectionID)
+	if _, err := shared.Get(sessionKey(connectionID)); err != store.ErrNotFound {
+		t.Errorf("shared session after invalidation: error = %v; want ErrNotFound", err)
+	}
+}
+
+func TestStoredSessions_SurviveRestart(t *testing.T) {
+	shared := setupSessionStore(t)
+
+	now := time.Now()
+	session := &Session{
+		Cookies:   []*http.Cookie{{Name: "NOW", Value: "cookie", Domain: "www.nordnet.dk"}},
+		JWT:       "jwt",
+		NTag:      "tag-kept",
+		Domain:    "www.nordnet.dk",
+		ExpiresAt: now.Add(24 * time.Hour),
+	}
+	CacheSession(9201, session)
+	stale, _ := json.Marshal(&Session{NTag: "tag-stale", ExpiresAt: now.Add(-time.Minute)})
+	if err := shared.Set(sessionKey(9203), stale, time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// A restart empties the cache; the stored sessions fill it again
+	cachedNordnetSessionsMutex.Lock()
+	delete(cachedNordnetSessions, 9201)
+	delete(sessionValidatedAt, 9201)
+	cachedNordnetSessionsMutex.Unlock()
+	t.Cleanup(func() { InvalidateCachedSession(9201) })
+
+	got := GetCachedSession(9201)
+	if got == nil || got.NTag != "tag-kept" || len(got.Cookies) != 1 || got.Cookies[0].Value != "cookie" {
+		t.Fatalf("restored session = %+v; want the tag and cookies from before the restart", got)
+	}
+	if GetCachedSession(9203) != nil {
+		t.Error("an expired session was restored")
+	}
+
+	// A session Nordnet answers 401 to is no longer kept
+	expireSession(got)
+	if _, err := shared.Get(sessionKey(9201)); err != store.ErrNotFound {
+		t.Errorf("stored session after a 401: error = %v; want ErrNotFound", err)
+	}
+}
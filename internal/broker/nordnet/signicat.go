@@ -0,0 +1,249 @@
+package nordnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Authentication methods of Nordnet logins, by country.
+const (
+	AuthMitID    = "mitid"     // Denmark: MitID app with CPR check
+	AuthBankIDSE = "bankid-se" // Sweden: Mobile BankID, started by QR code
+	AuthBankIDNO = "bankid-no" // Norway: BankID app, started by national ID
+	AuthFTN      = "ftn"       // Finland: Finnish Trust Network bank login in the browser
+)
+
+// signicatMethods are the Signicat OIDC methods (acr_values) of each
+// authentication method.
+var signicatMethods = map[string]string{
+	AuthMitID:    "urn:signicat:oidc:method:mitid-cpr",
+	AuthBankIDSE: "urn:signicat:oidc:method:sbid",
+	AuthBankIDNO: "urn:signicat:oidc:method:nbid",
+	AuthFTN:      "urn:signicat:oidc:method:ftn",
+}
+
+// AuthMethod returns how users of the Nordnet site of a country log in.
+// Unknown countries use the Danish MitID login.
+func AuthMethod(country string) string {
+	switch strings.ToLower(country) {
+	case "se":
+		return AuthBankIDSE
+	case "no":
+		return AuthBankIDNO
+	case "fi":
+		return AuthFTN
+	}
+	return AuthMitID
+}
+
+// AuthMethodName returns the name of a country's login method as shown to
+// users.
+func AuthMethodName(country string) string {
+	switch AuthMethod(country) {
+	case AuthBankIDSE, AuthBankIDNO:
+		return "BankID"
+	case AuthFTN:
+		return "bank login"
+	}
+	return "MitID"
+}
+
+// signicatLoginURL returns the Nordnet domain of a country and the Signicat
+// URL that starts its login with the given OIDC state.
+func signicatLoginURL(country, state string) (domain, loginURL string) {
+	domain, clientID := nordnetDomains[country], signicatClients[country]
+	if domain == "" || clientID == "" {
+		domain, clientID = nordnetDomains["dk"], signicatClients["dk"]
+	}
+
+	loginURL = "https://id.signicat.com/oidc/authorize?" + url.Values{
+		"client_id":     {clientID},
+		"response_type": {"code"},
+		"redirect_uri":  {fmt.Sprintf("https://%s/login", domain)},
+		"scope":         {"openid signicat.national_id"},
+		"acr_values":    {signicatMethods[AuthMethod(country)]},
+		"state":         {state},
+	}.Encode()
+	return domain, loginURL
+}
+
+// beginAuthSession registers an interactive login of a connection, so its QR
+// code and status can be served while it runs, and creates the directory
+// they are written to. A login started less than 90 seconds ago blocks new
+// ones, which prevents double authentication when several requests come in.
+// done must be called when the login ends.
+func beginAuthSession(connectionID int64) (qrDir string, done func(), err error) {
+	mitidSessionsNativeMutex.Lock()
+	if existing := activeMitIDSessionsNative[connectionID]; existing != nil {
+		if time.Since(existing.StartedAt) < 90*time.Second {
+			mitidSessionsNativeMutex.Unlock()
+			log.Printf("[Nordnet Auth] Blocking concurrent auth attempt for connection %d - auth already in progress (started %v ago)",
+				connectionID, time.Since(existing.StartedAt))
+			return "", nil, fmt.Errorf("authentication already in progress for this connection - please wait for the current request to complete")
+		}
+		// Stale session, clean it up and allow new auth
+		log.Printf("[Nordnet Auth] Cleaning up stale session for connection %d (was started %v ago)", connectionID, time.Since(existing.StartedAt))
+		delete(activeMitIDSessionsNative, connectionID)
+	}
+	mitidSessionsNativeMutex.Unlock()
+
+	qrDir = fmt.Sprintf("%s/mitid_qr_%d", os.TempDir(), connectionID)
+	os.RemoveAll(qrDir)
+	if err := os.MkdirAll(qrDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("creating QR directory: %w", err)
+	}
+
+	mitidSessionsNativeMutex.Lock()
+	activeMitIDSessionsNative[connectionID] = &MitIDSession{
+		ConnectionID: connectionID,
+		QRDir:        qrDir,
+		StartedAt:    time.Now(),
+	}
+	mitidSessionsNativeMutex.Unlock()
+
+	return qrDir, func() {
+		mitidSessionsNativeMutex.Lock()
+		delete(activeMitIDSessionsNative, connectionID)
+		mitidSessionsNativeMutex.Unlock()
+
+		// Keep QR files briefly for the final status check
+		go func() {
+			time.Sleep(5 * time.Second)
+			os.RemoveAll(qrDir)
+		}()
+	}, nil
+}
+
+// createNordnetSession exchanges the authorization code of a completed
+// Signicat login for a Nordnet session, whatever the login method.
+func createNordnetSession(httpClient *http.Client, jar *cookiejar.Jar, country, domain, signicatCode string) (*Session, error) {
+	sessionPayload := map[string]interface{}{
+		"authenticationProvider": "SIGNICAT",
+		"countryCode":            strings.ToUpper(country),
+		"signicat": map[string]string{
+			"authorizationCode": signicatCode,
+			"redirectUri":       fmt.Sprintf("https://%s/login", domain),
+		},
+	}
+
+	sessionBody, _ := json.Marshal(sessionPayload)
+	req, _ := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("https://%s/nnxapi/authentication/v2/sessions", domain),
+		strings.NewReader(string(sessionBody)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("client-id", "NEXT")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("creating Nordnet session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Nordnet session creation failed (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Read session response - may contain useful data
+	sessionRespBody, _ := io.ReadAll(resp.Body)
+	log.Printf("[Nordnet Auth] Session response (status %d): %s", resp.StatusCode, string(sessionRespBody))
+
+	// Check for ntag in the session response headers
+	sessionNtag := resp.Header.Get("ntag")
+	log.Printf("[Nordnet Auth] Session ntag from header: %s", sessionNtag)
+
+	// Also check for set-cookie headers
+	for _, cookie := range resp.Cookies() {
+		log.Printf("[Nordnet Auth] Cookie: %s=%s", cookie.Name, cookie.Value[:min(20, len(cookie.Value))]+"...")
+	}
+
+	// Login to get ntag
+	loginBody, _ := json.Marshal(map[string]interface{}{})
+	req, _ = http.NewRequest(http.MethodPost,
+		fmt.Sprintf("https://%s/api/2/authentication/nnx-session/login", domain),
+		strings.NewReader(string(loginBody)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("client-id", "NEXT")
+	req.Header.Set("x-locale", "da-DK")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	// Use ntag from session if available
+	if sessionNtag != "" {
+		req.Header.Set("ntag", sessionNtag)
+	}
+
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("login request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	loginRespBody, _ := io.ReadAll(resp.Body)
+	log.Printf("[Nordnet Auth] Login response (status %d): %s", resp.StatusCode, string(loginRespBody))
+
+	ntag := resp.Header.Get("ntag")
+	log.Printf("[Nordnet Auth] Got ntag from login response: %s", ntag)
+
+	// If login didn't return ntag, try to use the one from session creation
+	if ntag == "" && sessionNtag != "" {
+		log.Printf("[Nordnet Auth] Using ntag from session creation instead")
+		ntag = sessionNtag
+	}
+
+	// Get JWT bearer token
+	req, _ = http.NewRequest(http.MethodPost,
+		fmt.Sprintf("https://%s/nnxapi/authorization/v1/tokens", domain),
+		strings.NewReader(string(loginBody)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("client-id", "NEXT")
+	req.Header.Set("x-locale", "da-DK")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("ntag", ntag)
+
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	tokenBody, _ := io.ReadAll(resp.Body)
+	log.Printf("[Nordnet Auth] Token response (status %d): %s", resp.StatusCode, string(tokenBody))
+
+	var tokenResp struct {
+		JWT string `json:"jwt"`
+	}
+	if err := json.Unmarshal(tokenBody, &tokenResp); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+
+	if tokenResp.JWT == "" {
+		log.Printf("[Nordnet Auth] WARNING - JWT is empty!")
+	} else {
+		log.Printf("[Nordnet Auth] Got JWT (length %d)", len(tokenResp.JWT))
+	}
+
+	// Get cookies from cookie jar for the Nordnet domain
+	nordnetURL, _ := url.Parse(fmt.Sprintf("https://%s", domain))
+	cookies := jar.Cookies(nordnetURL)
+	log.Printf("[Nordnet Auth] Got %d cookies from session", len(cookies))
+	for _, c := range cookies {
+		log.Printf("[Nordnet Auth] Cookie: %s (len=%d)", c.Name, len(c.Value))
+	}
+
+	return &Session{
+		JWT:       tokenResp.JWT,
+		NTag:      ntag,
+		Domain:    domain,
+		Cookies:   cookies,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, nil
+}
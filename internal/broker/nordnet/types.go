@@ -45,7 +45,7 @@ type Session struct {
 	NTag   string // Session tag header
 	Domain string // Nordnet domain (e.g., www.nordnet.dk)
 
-	ExpiresAt time.Time
+	ExpiresAt time.Time // Upper bound; renewed by keep-alive pings, cleared on 401
 }
 
 // IsExpired returns true if the session has expired.
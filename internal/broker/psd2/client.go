@@ -0,0 +1,200 @@
+package psd2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/broker"
+)
+
+const (
+	httpClientTimeout = 30 * time.Second
+
+	// ConsentValidity is how long a consent is requested for; PSD2 lets
+	// banks require the account holder to renew access every 90 days.
+	ConsentValidity = 90 * 24 * time.Hour
+
+	// consentFrequencyPerDay is how often a day the accounts may be read
+	// without the account holder present. PSD2 guarantees four.
+	consentFrequencyPerDay = 4
+)
+
+// Client provides methods for accessing a bank's Berlin Group API.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL, such as
+// "https://api.bank.example/psd2". token is the access token the bank or
+// gateway issued to this app (the TPP); empty if the API is authorized by
+// client certificate only.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// SetHTTPConfig routes the client's requests through the configured proxy
+// and User-Agent. Call it before SetTrail.
+func (c *Client) SetHTTPConfig(cfg broker.HTTPConfig) {
+	c.httpClient.Transport = cfg.Transport()
+}
+
+// SetTrail records the client's requests in t for diagnostics.
+func (c *Client) SetTrail(t *broker.Trail) {
+	c.httpClient.Transport = t.Wrap(c.httpClient.Transport)
+}
+
+// CreateConsent asks for recurring read access to all accounts of the
+// account holder. The bank returns the consent in status "received" and a
+// link where the account holder authorizes it, after which they are sent
+// back to redirectURI. psuIP is the IP address of the account holder's
+// browser.
+func (c *Client) CreateConsent(redirectURI, psuIP string) (*ConsentResponse, error) {
+	body, err := json.Marshal(ConsentRequest{
+		Access:                   ConsentAccess{AllPSD2: "allAccounts"},
+		RecurringIndicator:       true,
+		ValidUntil:               time.Now().Add(ConsentValidity).Format("2006-01-02"),
+		FrequencyPerDay:          consentFrequencyPerDay,
+		CombinedServiceIndicator: false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/v1/consents", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("TPP-Redirect-URI", redirectURI)
+	req.Header.Set("TPP-Redirect-Preferred", "true")
+	if psuIP != "" {
+		req.Header.Set("PSU-IP-Address", psuIP)
+	}
+
+	var consent ConsentResponse
+	if err := c.do(req, "consent", http.StatusCreated, &consent); err != nil {
+		return nil, err
+	}
+	if consent.ConsentID == "" {
+		return nil, fmt.Errorf("bank returned no consent ID")
+	}
+	if consent.Links.SCARedirect == nil || consent.Links.SCARedirect.Href == "" {
+		return nil, ErrNoRedirect
+	}
+	log.Printf("[PSD2] Created consent %s (%s)", consent.ConsentID, consent.ConsentStatus)
+	return &consent, nil
+}
+
+// ConsentStatus returns the status of a consent, "valid" once the account
+// holder has authorized it.
+func (c *Client) ConsentStatus(consentID string) (string, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/v1/consents/"+url.PathEscape(consentID)+"/status", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var status ConsentStatusResponse
+	if err := c.do(req, "consent status", http.StatusOK, &status); err != nil {
+		return "", err
+	}
+	return status.ConsentStatus, nil
+}
+
+// GetAccounts retrieves the accounts a consent covers.
+func (c *Client) GetAccounts(consentID string) ([]Account, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/v1/accounts", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Consent-ID", consentID)
+
+	var accounts AccountsResponse
+	if err := c.do(req, "accounts", http.StatusOK, &accounts); err != nil {
+		return nil, err
+	}
+	log.Printf("[PSD2] Got %d accounts", len(accounts.Accounts))
+	return accounts.Accounts, nil
+}
+
+// GetBalances retrieves the balances of an account by its resource ID.
+func (c *Client) GetBalances(consentID, accountID string) ([]Balance, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/v1/accounts/"+url.PathEscape(accountID)+"/balances", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Consent-ID", consentID)
+
+	var balances BalancesResponse
+	if err := c.do(req, "balances", http.StatusOK, &balances); err != nil {
+		return nil, err
+	}
+	return balances.Balances, nil
+}
+
+// do sends a request and decodes the JSON response into v. Responses the
+// standard uses for a missing, expired or revoked consent are returned as
+// ErrConsentRequired.
+func (c *Client) do(req *http.Request, what string, wantStatus int, v any) error {
+	requestID, err := newRequestID()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Request-ID", requestID)
+	req.Header.Set("Accept", "application/json")
+	if req.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s response: %w", what, err)
+	}
+
+	if resp.StatusCode != wantStatus && resp.StatusCode != http.StatusOK {
+		if (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && bytes.Contains(body, []byte("CONSENT_")) {
+			return fmt.Errorf("%w: %s", ErrConsentRequired, string(body))
+		}
+		return fmt.Errorf("failed to get %s: status %d, body: %s", what, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", what, err)
+	}
+	return nil
+}
+
+// newRequestID returns a random UUID, which the standard requires to
+// identify each request.
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating request ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
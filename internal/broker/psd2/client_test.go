@@ -0,0 +1,126 @@
+package psd2
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient returns a client of a test server for the duration of a test.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL+"/", "tpp-token")
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encoding response: %v", err)
+	}
+}
+
+func TestCreateConsent_RequestsRecurringAccessAndReturnsRedirect(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/consents" {
+			t.Errorf("request = %s %s, want POST /v1/consents", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer tpp-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.Header.Get("TPP-Redirect-URI"); got != "https://app.example/callback" {
+			t.Errorf("TPP-Redirect-URI = %q", got)
+		}
+		if got := r.Header.Get("PSU-IP-Address"); got != "192.0.2.1" {
+			t.Errorf("PSU-IP-Address = %q", got)
+		}
+		if len(r.Header.Get("X-Request-ID")) != 36 {
+			t.Errorf("X-Request-ID = %q, want a UUID", r.Header.Get("X-Request-ID"))
+		}
+
+		var req ConsentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decoding request: %v", err)
+		}
+		if req.Access.AllPSD2 != "allAccounts" || !req.RecurringIndicator || req.ValidUntil == "" {
+			t.Errorf("consent request = %+v", req)
+		}
+
+		writeJSON(t, w, http.StatusCreated, ConsentResponse{
+			ConsentID:     "c1",
+			ConsentStatus: "received",
+			Links:         Links{SCARedirect: &Link{Href: "https://bank.example/authorize/c1"}},
+		})
+	})
+
+	consent, err := client.CreateConsent("https://app.example/callback", "192.0.2.1")
+	if err != nil {
+		t.Fatalf("CreateConsent: %v", err)
+	}
+	if consent.ConsentID != "c1" || consent.Links.SCARedirect.Href != "https://bank.example/authorize/c1" {
+		t.Errorf("consent = %+v", consent)
+	}
+}
+
+func TestCreateConsent_NoRedirectLink(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusCreated, ConsentResponse{ConsentID: "c1", ConsentStatus: "received"})
+	})
+
+	if _, err := client.CreateConsent("https://app.example/callback", ""); !errors.Is(err, ErrNoRedirect) {
+		t.Errorf("err = %v, want ErrNoRedirect", err)
+	}
+}
+
+func TestGetBalances_SendsConsentAndParsesAmounts(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/v1/accounts/acc%201/balances" {
+			t.Errorf("path = %s", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("Consent-ID"); got != "c1" {
+			t.Errorf("Consent-ID = %q", got)
+		}
+		writeJSON(t, w, http.StatusOK, BalancesResponse{Balances: []Balance{
+			{BalanceType: "interimAvailable", BalanceAmount: Amount{Currency: "DKK", Amount: "15000.00"}},
+			{BalanceType: "closingBooked", BalanceAmount: Amount{Currency: "DKK", Amount: "-1234.56"}},
+		}})
+	})
+
+	balances, err := client.GetBalances("c1", "acc 1")
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	balance, ok := PreferredBalance(balances)
+	if !ok || balance.BalanceType != "closingBooked" {
+		t.Fatalf("preferred balance = %+v, %v; want closingBooked", balance, ok)
+	}
+	if value, err := balance.BalanceAmount.Value(); err != nil || value != -1234.56 {
+		t.Errorf("value = %v, %v; want -1234.56", value, err)
+	}
+}
+
+func TestGetAccounts_ExpiredConsent(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusUnauthorized, map[string]any{
+			"tppMessages": []map[string]string{{"category": "ERROR", "code": "CONSENT_EXPIRED"}},
+		})
+	})
+
+	if _, err := client.GetAccounts("c1"); !errors.Is(err, ErrConsentRequired) {
+		t.Errorf("err = %v, want ErrConsentRequired", err)
+	}
+}
+
+func TestPreferredBalance_FallsBackToFirst(t *testing.T) {
+	balances := []Balance{{BalanceType: "forwardAvailable"}, {BalanceType: "openingBooked"}}
+	if balance, ok := PreferredBalance(balances); !ok || balance.BalanceType != "forwardAvailable" {
+		t.Errorf("preferred balance = %+v, %v; want forwardAvailable", balance, ok)
+	}
+	if _, ok := PreferredBalance(nil); ok {
+		t.Error("preferred balance of no balances should not be ok")
+	}
+}
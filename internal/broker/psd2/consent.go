@@ -0,0 +1,128 @@
+package psd2
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"wealth_tracker/internal/store"
+)
+
+// Consent is a connection's access to the account holder's accounts.
+type Consent struct {
+	ID         string    `json:"id"`
+	Status     string    `json:"status"` // As reported by the bank, "valid" once authorized
+	ValidUntil time.Time `json:"valid_until"`
+}
+
+// IsValid reports whether the consent is authorized and not expired.
+func (c *Consent) IsValid(now time.Time) bool {
+	return c != nil && c.Status == "valid" && now.Before(c.ValidUntil)
+}
+
+var (
+	consents      = make(map[int64]*Consent)
+	consentsMutex sync.RWMutex
+
+	// sharedConsents, if set, keeps consents in the database, encrypted, so
+	// a consent authorized before a restart, or through another instance, is
+	// used without the account holder authorizing it again.
+	sharedConsents store.Store
+)
+
+// SetConsentStore keeps consents in a store. It must be called before
+// consents are saved.
+func SetConsentStore(s store.Store) {
+	sharedConsents = s
+}
+
+// GetConsent returns a connection's consent, or nil if there is none. With a
+// shared store, the consent there wins.
+func GetConsent(connectionID int64) *Consent {
+	if consent, ok := loadSharedConsent(connectionID); ok {
+		consentsMutex.Lock()
+		defer consentsMutex.Unlock()
+		if consent == nil {
+			delete(consents, connectionID)
+		} else {
+			consents[connectionID] = consent
+		}
+		return consent
+	}
+
+	consentsMutex.RLock()
+	defer consentsMutex.RUnlock()
+	return consents[connectionID]
+}
+
+// SaveConsent stores a connection's consent, replacing any previous one.
+func SaveConsent(connectionID int64, consent *Consent) {
+	consentsMutex.Lock()
+	consents[connectionID] = consent
+	consentsMutex.Unlock()
+	saveSharedConsent(connectionID, consent)
+}
+
+// ClearConsent removes a connection's consent.
+func ClearConsent(connectionID int64) {
+	consentsMutex.Lock()
+	delete(consents, connectionID)
+	consentsMutex.Unlock()
+	deleteSharedConsent(connectionID)
+}
+
+// consentKey is the shared store key of a connection's consent.
+func consentKey(connectionID int64) string {
+	return "psd2:consent:" + strconv.FormatInt(connectionID, 10)
+}
+
+// loadSharedConsent returns a connection's consent from the shared store.
+// ok is false if the store is not set or could not be read, in which case
+// the local copy is used.
+func loadSharedConsent(connectionID int64) (consent *Consent, ok bool) {
+	if sharedConsents == nil {
+		return nil, false
+	}
+	data, err := sharedConsents.Get(consentKey(connectionID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		log.Printf("[PSD2] Error loading shared consent for connection %d: %v", connectionID, err)
+		return nil, false
+	}
+	consent = &Consent{}
+	if err := json.Unmarshal(data, consent); err != nil {
+		log.Printf("[PSD2] Error decoding shared consent for connection %d: %v", connectionID, err)
+		return nil, false
+	}
+	return consent, true
+}
+
+// saveSharedConsent writes a connection's consent to the shared store until
+// it expires.
+func saveSharedConsent(connectionID int64, consent *Consent) {
+	if sharedConsents == nil {
+		return
+	}
+	data, err := json.Marshal(consent)
+	if err == nil {
+		err = sharedConsents.Set(consentKey(connectionID), data, time.Until(consent.ValidUntil))
+	}
+	if err != nil {
+		log.Printf("[PSD2] Error sharing consent for connection %d: %v", connectionID, err)
+	}
+}
+
+// deleteSharedConsent removes a connection's consent from the shared store.
+func deleteSharedConsent(connectionID int64) {
+	if sharedConsents == nil {
+		return
+	}
+	if err := sharedConsents.Delete(consentKey(connectionID)); err != nil {
+		log.Printf("[PSD2] Error removing shared consent for connection %d: %v", connectionID, err)
+	}
+}
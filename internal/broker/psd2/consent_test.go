@@ -0,0 +1,53 @@
+package psd2
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/store"
+)
+
+func TestStoredConsents_SurviveRestart(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	encryptor, err := broker.NewEncryptor("test-secret-that-is-32-chars-long")
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	SetConsentStore(store.NewSQLite(db, encryptor))
+	t.Cleanup(func() { SetConsentStore(nil) })
+
+	const connectionID = 4101
+	SaveConsent(connectionID, &Consent{ID: "consent-kept", Status: "valid", ValidUntil: time.Now().Add(90 * 24 * time.Hour)})
+
+	var raw []byte
+	if err := db.QueryRow(`SELECT value FROM shared_state WHERE key = ?`, consentKey(connectionID)).Scan(&raw); err != nil {
+		t.Fatalf("reading stored consent: %v", err)
+	}
+	if bytes.Contains(raw, []byte("consent-kept")) {
+		t.Error("consent is stored unencrypted")
+	}
+
+	// A restart empties the local copies; the stored consent is used
+	consentsMutex.Lock()
+	delete(consents, connectionID)
+	consentsMutex.Unlock()
+	if consent := GetConsent(connectionID); consent == nil || consent.ID != "consent-kept" || !consent.IsValid(time.Now()) {
+		t.Fatalf("GetConsent() after restart = %+v; want the stored consent", consent)
+	}
+
+	ClearConsent(connectionID)
+	if consent := GetConsent(connectionID); consent != nil {
+		t.Errorf("GetConsent() after ClearConsent() = %+v; want nil", consent)
+	}
+}
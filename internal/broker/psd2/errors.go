@@ -0,0 +1,19 @@
+// Package psd2 provides a client for bank account APIs following the Berlin
+// Group NextGenPSD2 standard, as offered by Nordic banks and API gateways.
+package psd2
+
+import "errors"
+
+var (
+	// ErrConsentRequired indicates the account holder has not authorized
+	// access to their accounts, or the consent has expired or was revoked.
+	ErrConsentRequired = errors.New("bank access is not authorized - authorize it on the connection page")
+
+	// ErrConsentRejected indicates the account holder did not complete or
+	// declined the authorization at the bank.
+	ErrConsentRejected = errors.New("bank access was not authorized")
+
+	// ErrNoRedirect indicates the bank did not return a URL to authorize
+	// the consent at.
+	ErrNoRedirect = errors.New("bank returned no authorization link")
+)
@@ -0,0 +1,131 @@
+package psd2
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ConsentRequest asks for access to the accounts and balances of the
+// account holder.
+type ConsentRequest struct {
+	Access                   ConsentAccess `json:"access"`
+	RecurringIndicator       bool          `json:"recurringIndicator"`
+	ValidUntil               string        `json:"validUntil"` // ISO date
+	FrequencyPerDay          int           `json:"frequencyPerDay"`
+	CombinedServiceIndicator bool          `json:"combinedServiceIndicator"`
+}
+
+// ConsentAccess is the scope of a consent. AllPSD2 set to "allAccounts"
+// covers all payment accounts of the account holder.
+type ConsentAccess struct {
+	AllPSD2 string `json:"allPsd2,omitempty"`
+}
+
+// ConsentResponse is the bank's answer to a consent request.
+type ConsentResponse struct {
+	ConsentID     string `json:"consentId"`
+	ConsentStatus string `json:"consentStatus"`
+	Links         Links  `json:"_links"`
+}
+
+// Links holds the hypermedia links of a response.
+type Links struct {
+	SCARedirect *Link `json:"scaRedirect,omitempty"`
+}
+
+// Link is a single hypermedia link.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// ConsentStatusResponse is the status of a consent: "received", "valid",
+// "rejected", "revokedByPsu", "expired" or "terminatedByTpp".
+type ConsentStatusResponse struct {
+	ConsentStatus string `json:"consentStatus"`
+}
+
+// AccountsResponse is the list of accounts a consent covers.
+type AccountsResponse struct {
+	Accounts []Account `json:"accounts"`
+}
+
+// Account is a bank account.
+type Account struct {
+	ResourceID      string `json:"resourceId"` // Stable ID used in API paths
+	IBAN            string `json:"iban,omitempty"`
+	BBAN            string `json:"bban,omitempty"`
+	Currency        string `json:"currency"`
+	Name            string `json:"name,omitempty"`
+	Product         string `json:"product,omitempty"`
+	CashAccountType string `json:"cashAccountType,omitempty"` // ISO 20022 code, e.g. "CACC" or "SVGS"
+	Status          string `json:"status,omitempty"`          // "enabled", "deleted" or "blocked"
+}
+
+// Number returns the account number shown to the account holder.
+func (a Account) Number() string {
+	if a.IBAN != "" {
+		return a.IBAN
+	}
+	return a.BBAN
+}
+
+// DisplayName returns the name of the account, falling back to the product
+// and then the account number.
+func (a Account) DisplayName() string {
+	switch {
+	case a.Name != "":
+		return a.Name
+	case a.Product != "":
+		return a.Product
+	}
+	return a.Number()
+}
+
+// IsActive reports whether the account can be used. Banks that leave the
+// status out only list usable accounts.
+func (a Account) IsActive() bool {
+	return a.Status == "" || a.Status == "enabled"
+}
+
+// BalancesResponse is the list of balances of an account.
+type BalancesResponse struct {
+	Balances []Balance `json:"balances"`
+}
+
+// Balance is one kind of balance of an account.
+type Balance struct {
+	BalanceAmount Amount `json:"balanceAmount"`
+	BalanceType   string `json:"balanceType"` // e.g. "closingBooked", "interimAvailable"
+}
+
+// Amount is an amount of money. The standard encodes the value as a string.
+type Amount struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+// Value parses the amount.
+func (a Amount) Value() (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(a.Amount), 64)
+}
+
+// balancePreference orders balance types from most to least like the
+// balance shown in the account holder's online bank.
+var balancePreference = []string{"closingBooked", "interimBooked", "expected", "interimAvailable"}
+
+// PreferredBalance picks the balance of an account to record: booked
+// balances before available ones, which may include credit lines. Falls back
+// to the first balance of another type; ok is false if there are none.
+func PreferredBalance(balances []Balance) (balance Balance, ok bool) {
+	for _, balanceType := range balancePreference {
+		for _, b := range balances {
+			if b.BalanceType == balanceType {
+				return b, true
+			}
+		}
+	}
+	if len(balances) > 0 {
+		return balances[0], true
+	}
+	return Balance{}, false
+}
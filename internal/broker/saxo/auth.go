@@ -18,6 +18,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"wealth_tracker/internal/broker"
 )
 
 const (
@@ -138,25 +140,57 @@ func GetOAuthURL(connectionID int64) string {
 	return session.AuthURL
 }
 
-// GetCachedSession returns a cached session if still valid.
+// GetCachedSession returns a cached session if still valid. With a shared
+// store, the session there wins, as another instance may have refreshed it.
 func GetCachedSession(connectionID int64) *Session {
+	if session, ok := loadSharedSession(connectionID); ok {
+		cachedSessionsMutex.Lock()
+		defer cachedSessionsMutex.Unlock()
+		if session == nil {
+			delete(cachedSessions, connectionID)
+		} else {
+			cachedSessions[connectionID] = session
+		}
+		return session
+	}
+
 	cachedSessionsMutex.RLock()
 	defer cachedSessionsMutex.RUnlock()
 	return cachedSessions[connectionID]
 }
 
-// CacheSession stores a session for future use.
+// CacheSession stores a session for future use, and keeps it across restarts
+// if a store is set.
 func CacheSession(connectionID int64, session *Session) {
 	cachedSessionsMutex.Lock()
-	defer cachedSessionsMutex.Unlock()
 	cachedSessions[connectionID] = session
+	cachedSessionsMutex.Unlock()
+	saveSharedSession(connectionID, session)
 }
 
 // ClearCachedSession removes a cached session.
 func ClearCachedSession(connectionID int64) {
 	cachedSessionsMutex.Lock()
-	defer cachedSessionsMutex.Unlock()
 	delete(cachedSessions, connectionID)
+	cachedSessionsMutex.Unlock()
+	deleteSharedSession(connectionID)
+}
+
+// DropExpiredSessions removes cached sessions whose refresh token has
+// expired, which can no longer be used or renewed, and returns how many it
+// removed.
+func DropExpiredSessions(now time.Time) int {
+	cachedSessionsMutex.Lock()
+	defer cachedSessionsMutex.Unlock()
+
+	dropped := 0
+	for id, session := range cachedSessions {
+		if now.After(session.ExpiresAt) && now.After(session.RefreshExpiresAt) {
+			delete(cachedSessions, id)
+			dropped++
+		}
+	}
+	return dropped
 }
 
 // ClearActiveOAuthSession removes an active OAuth session, allowing a new auth to start.
@@ -447,7 +481,7 @@ func AuthenticateWithOAuth(connectionID int64, appKey, appSecret, redirectURI st
 
 	// Exchange code for tokens
 	oauthSession.Status = "exchanging"
-	session, err := exchangeCodeForTokens(code, oauthSession.Verifier, clientID, appSecret, callbackURI)
+	session, err := exchangeCodeForTokens(connectionID, code, oauthSession.Verifier, clientID, appSecret, callbackURI)
 	if err != nil {
 		oauthSession.Status = "failed"
 		oauthSession.ErrorMsg = err.Error()
@@ -465,7 +499,7 @@ func AuthenticateWithOAuth(connectionID int64, appKey, appSecret, redirectURI st
 
 // exchangeCodeForTokens exchanges authorization code for tokens.
 // If appSecret is provided, uses client_secret flow. Otherwise uses PKCE with code_verifier.
-func exchangeCodeForTokens(code, verifier, appKey, appSecret, redirectURI string) (*Session, error) {
+func exchangeCodeForTokens(connectionID int64, code, verifier, appKey, appSecret, redirectURI string) (*Session, error) {
 	// Use provided appKey or fall back to environment variable
 	clientID := appKey
 	if clientID == "" {
@@ -505,7 +539,7 @@ func exchangeCodeForTokens(code, verifier, appKey, appSecret, redirectURI string
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "application/json")
 
-	client := &http.Client{Timeout: httpClientTimeout}
+	client := broker.NewHTTPClient(connectionID, httpClientTimeout, nil)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("token request failed: %w", err)
@@ -549,8 +583,9 @@ func exchangeCodeForTokens(code, verifier, appKey, appSecret, redirectURI string
 	return session, nil
 }
 
-// RefreshAccessToken refreshes an expired access token using the refresh token.
-func RefreshAccessToken(session *Session) (*Session, error) {
+// RefreshAccessToken refreshes an expired access token of a connection using
+// the refresh token.
+func RefreshAccessToken(connectionID int64, session *Session) (*Session, error) {
 	if session == nil || session.RefreshToken == "" {
 		return nil, ErrRefreshTokenExpired
 	}
@@ -572,7 +607,7 @@ func RefreshAccessToken(session *Session) (*Session, error) {
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: httpClientTimeout}
+	client := broker.NewHTTPClient(connectionID, httpClientTimeout, nil)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("refresh request failed: %w", err)
@@ -623,7 +658,7 @@ func GetOrRefreshSession(connectionID int64) (*Session, error) {
 
 	// Try to refresh
 	if session.CanRefresh() {
-		refreshed, err := RefreshAccessToken(session)
+		refreshed, err := RefreshAccessToken(connectionID, session)
 		if err != nil {
 			log.Printf("[Saxo OAuth] Refresh failed: %v", err)
 			ClearCachedSession(connectionID)
@@ -7,7 +7,10 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"wealth_tracker/internal/broker"
 )
 
 const (
@@ -17,14 +20,19 @@ const (
 
 	// Rate limiting
 	requestDelay = 200 * time.Millisecond
+
+	// maxPages bounds how many __next links a list request follows.
+	maxPages = 100
 )
 
 var (
 	// Use production API by default
 	apiBaseURL = apiURLProduction
 
-	// Track last request time for rate limiting
-	lastRequestTime time.Time
+	// Track last request time for rate limiting. Requests may come from
+	// several goroutines, see FetchAccounts.
+	lastRequestTime  time.Time
+	lastRequestMutex sync.Mutex
 )
 
 // Client provides methods for accessing the Saxo OpenAPI.
@@ -41,6 +49,17 @@ func NewClient() *Client {
 	}
 }
 
+// SetHTTPConfig routes the client's requests through the configured proxy
+// and User-Agent. Call it before SetTrail.
+func (c *Client) SetHTTPConfig(cfg broker.HTTPConfig) {
+	c.httpClient.Transport = cfg.Transport()
+}
+
+// SetTrail records the client's requests in t for diagnostics.
+func (c *Client) SetTrail(t *broker.Trail) {
+	c.httpClient.Transport = t.Wrap(c.httpClient.Transport)
+}
+
 // SetSimulationAPI switches to the simulation API environment.
 func SetSimulationAPI(simulation bool) {
 	if simulation {
@@ -52,11 +71,15 @@ func SetSimulationAPI(simulation bool) {
 
 // doRequest performs an authenticated API request.
 func (c *Client) doRequest(req *http.Request, session *Session) (*http.Response, error) {
-	// Rate limiting
-	if elapsed := time.Since(lastRequestTime); elapsed < requestDelay {
-		time.Sleep(requestDelay - elapsed)
+	// Rate limiting: reserve the next free slot, then wait for it
+	lastRequestMutex.Lock()
+	slot := lastRequestTime.Add(requestDelay)
+	if now := time.Now(); slot.Before(now) {
+		slot = now
 	}
-	lastRequestTime = time.Now()
+	lastRequestTime = slot
+	lastRequestMutex.Unlock()
+	time.Sleep(time.Until(slot))
 
 	// Set required headers
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", session.AccessToken))
@@ -134,38 +157,26 @@ func (c *Client) GetAccounts(session *Session) ([]Account, error) {
 	}
 
 	url := fmt.Sprintf("%s/port/v1/accounts?ClientKey=%s", apiBaseURL, session.ClientKey)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.doRequest(req, session)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, ErrSessionExpired
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading accounts response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get accounts: status %d, body: %s", resp.StatusCode, string(body))
-	}
+	var accounts []Account
+	for page := 1; url != ""; page++ {
+		if page > maxPages {
+			return nil, fmt.Errorf("accounts exceed %d pages", maxPages)
+		}
 
-	log.Printf("[Saxo] Accounts response: %s", string(body))
+		var accountsResp AccountsResponse
+		if err := c.getJSON(session, url, "accounts", &accountsResp); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, accountsResp.Data...)
 
-	var accountsResp AccountsResponse
-	if err := json.Unmarshal(body, &accountsResp); err != nil {
-		return nil, fmt.Errorf("decoding accounts: %w", err)
+		var err error
+		if url, err = nextPageURL(accountsResp.Next); err != nil {
+			return nil, err
+		}
 	}
 
-	return accountsResp.Data, nil
+	log.Printf("[Saxo] Got %d accounts", len(accounts))
+	return accounts, nil
 }
 
 // GetPositions retrieves all positions for a specific account.
@@ -184,38 +195,71 @@ func (c *Client) GetPositions(session *Session, accountKey string) ([]Position,
 	}
 
 	url := fmt.Sprintf("%s/port/v1/positions?ClientKey=%s&AccountKey=%s", apiBaseURL, session.ClientKey, accountKey)
+	var positions []Position
+	for page := 1; url != ""; page++ {
+		if page > maxPages {
+			return nil, fmt.Errorf("positions for account %s exceed %d pages", accountKey, maxPages)
+		}
+
+		var positionsResp PositionsResponse
+		if err := c.getJSON(session, url, "positions", &positionsResp); err != nil {
+			return nil, err
+		}
+		log.Printf("[Saxo] Positions page %d for account %s: %d of %d positions", page, accountKey, len(positionsResp.Data), positionsResp.Count)
+		positions = append(positions, positionsResp.Data...)
+
+		var err error
+		if url, err = nextPageURL(positionsResp.Next); err != nil {
+			return nil, err
+		}
+	}
+
+	return positions, nil
+}
+
+// getJSON performs an authenticated GET request and decodes the response.
+// what names the resource in errors.
+func (c *Client) getJSON(session *Session, url, what string, out any) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := c.doRequest(req, session)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, ErrSessionExpired
+		return ErrSessionExpired
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading positions response: %w", err)
+		return fmt.Errorf("reading %s response: %w", what, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get positions: status %d, body: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("failed to get %s: status %d, body: %s", what, resp.StatusCode, string(body))
 	}
 
-	log.Printf("[Saxo] Positions response for account %s: %s", accountKey, string(body))
-
-	var positionsResp PositionsResponse
-	if err := json.Unmarshal(body, &positionsResp); err != nil {
-		return nil, fmt.Errorf("decoding positions: %w", err)
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding %s: %w", what, err)
 	}
+	return nil
+}
 
-	return positionsResp.Data, nil
+// nextPageURL validates the __next link of a list response. The link must
+// point at the API, so the access token is never sent anywhere else.
+func nextPageURL(next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(next, apiBaseURL+"/") {
+		return "", fmt.Errorf("unexpected next page URL: %s", next)
+	}
+	return next, nil
 }
 
 // GetBalance retrieves the balance for a specific account.
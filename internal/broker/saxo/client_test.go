@@ -0,0 +1,140 @@
+package saxo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestAPI points the client at a test server for the duration of a test.
+func newTestAPI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	previous := apiBaseURL
+	apiBaseURL = srv.URL
+	t.Cleanup(func() { apiBaseURL = previous })
+}
+
+func testSession() *Session {
+	return &Session{AccessToken: "token", ClientKey: "client", ExpiresAt: time.Now().Add(time.Hour)}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encoding response: %v", err)
+	}
+}
+
+func position(uic int64) Position {
+	return Position{PositionBase: PositionBase{Uic: uic, AssetType: "Stock", Amount: 1}}
+}
+
+func TestFetchAccounts_FollowsPaginationAndBatchesInstruments(t *testing.T) {
+	instrumentRequests := 0
+	newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case "/port/v1/positions":
+			switch {
+			case q.Get("AccountKey") == "a" && q.Get("$skip") == "":
+				writeJSON(t, w, PositionsResponse{
+					Count: 3,
+					Data:  []Position{position(1), position(2)},
+					Next:  apiBaseURL + "/port/v1/positions?ClientKey=client&AccountKey=a&$skip=2",
+				})
+			case q.Get("AccountKey") == "a":
+				writeJSON(t, w, PositionsResponse{Count: 3, Data: []Position{position(3)}})
+			default:
+				writeJSON(t, w, PositionsResponse{Count: 1, Data: []Position{position(1)}})
+			}
+		case "/port/v1/balances":
+			writeJSON(t, w, BalanceResponse{CashBalance: 100, Currency: "DKK"})
+		case "/ref/v1/instruments/details":
+			instrumentRequests++
+			writeJSON(t, w, InstrumentDetailsResponse{Data: []InstrumentDetails{
+				{Uic: 1, Symbol: "ONE"}, {Uic: 2, Symbol: "TWO"}, {Uic: 3, Symbol: "THREE"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	data, err := NewClient().FetchAccounts(testSession(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("FetchAccounts() error = %v", err)
+	}
+
+	a := data["a"]
+	if a.Err != nil || len(a.Positions) != 3 {
+		t.Fatalf("account a: %d positions, error %v; want 3 positions from two pages", len(a.Positions), a.Err)
+	}
+	if a.Positions[2].Symbol() != "THREE" {
+		t.Errorf("account a position 3 symbol = %q; want THREE", a.Positions[2].Symbol())
+	}
+	if a.Balance == nil || a.Balance.CashBalance != 100 {
+		t.Errorf("account a balance = %+v; want cash 100", a.Balance)
+	}
+	if b := data["b"]; b.Err != nil || len(b.Positions) != 1 || b.Positions[0].Symbol() != "ONE" {
+		t.Errorf("account b = %+v; want one position ONE", b)
+	}
+	if instrumentRequests != 1 {
+		t.Errorf("instrument detail requests = %d; want 1 for all accounts", instrumentRequests)
+	}
+}
+
+func TestGetPositions_RejectsForeignNextURL(t *testing.T) {
+	newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, PositionsResponse{
+			Data: []Position{position(1)},
+			Next: "https://attacker.example/port/v1/positions",
+		})
+	})
+
+	if _, err := NewClient().GetPositions(testSession(), "a"); err == nil {
+		t.Error("GetPositions() followed a next URL outside the API")
+	}
+}
+
+func TestGetPerformance(t *testing.T) {
+	newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/hist/v3/perf/client" || q.Get("AccountKey") != "a" || q.Get("FieldGroups") != "TimeWeightedPerformance" {
+			http.NotFound(w, r)
+			return
+		}
+		switch q.Get("StandardPeriod") {
+		case "Year":
+			writeJSON(t, w, PerformanceResponse{TimeWeightedPerformance: &TimeWeightedPerformance{PerformanceFraction: 0.0825}})
+		case "Month":
+			writeJSON(t, w, PerformanceResponse{TimeWeightedPerformance: &TimeWeightedPerformance{
+				AccumulatedTimeWeightedTimeSeries: []PerformanceSeriesPoint{{Date: "2024-03-01", Value: 0.01}, {Date: "2024-03-29", Value: -0.02}},
+			}})
+		default:
+			writeJSON(t, w, PerformanceResponse{})
+		}
+	})
+
+	client := NewClient()
+	tests := []struct {
+		period string
+		want   float64
+		wantOK bool
+	}{
+		{"Year", 0.0825, true},
+		{"Month", -0.02, true},
+		{"AllTime", 0, false},
+	}
+	for _, tt := range tests {
+		perf, err := client.GetPerformance(testSession(), "a", tt.period)
+		if err != nil {
+			t.Fatalf("GetPerformance(%s) error = %v", tt.period, err)
+		}
+		if got, ok := perf.ReturnFraction(); got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetPerformance(%s).ReturnFraction() = %v, %v; want %v, %v", tt.period, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package saxo
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// PerformancePeriods are the standard periods of the performance endpoint
+// that are fetched after a sync.
+var PerformancePeriods = []string{"Month", "Quarter", "Year", "AllTime"}
+
+// PerformanceResponse from /hist/v3/perf/{ClientKey}, requested with the
+// TimeWeightedPerformance field group. Fractions are 0.05 for 5%.
+type PerformanceResponse struct {
+	TimeWeightedPerformance *TimeWeightedPerformance `json:"TimeWeightedPerformance,omitempty"`
+}
+
+// TimeWeightedPerformance is the time-weighted return of an account over the
+// requested period.
+type TimeWeightedPerformance struct {
+	PerformanceFraction               float64                  `json:"PerformanceFraction"`
+	AccumulatedTimeWeightedTimeSeries []PerformanceSeriesPoint `json:"AccumulatedTimeWeightedTimeSeries,omitempty"`
+}
+
+// PerformanceSeriesPoint is the accumulated return up to a date.
+type PerformanceSeriesPoint struct {
+	Date  string  `json:"Date"`
+	Value float64 `json:"Value"`
+}
+
+// ReturnFraction returns the time-weighted return of the period. Saxo omits
+// PerformanceFraction for some account types; the last point of the
+// accumulated series is used then. ok is false if neither is present.
+func (p *PerformanceResponse) ReturnFraction() (fraction float64, ok bool) {
+	twp := p.TimeWeightedPerformance
+	if twp == nil {
+		return 0, false
+	}
+	if twp.PerformanceFraction != 0 {
+		return twp.PerformanceFraction, true
+	}
+	if n := len(twp.AccumulatedTimeWeightedTimeSeries); n > 0 {
+		return twp.AccumulatedTimeWeightedTimeSeries[n-1].Value, true
+	}
+	return 0, false
+}
+
+// GetPerformance retrieves the time-weighted return of an account over a
+// standard period, one of PerformancePeriods.
+func (c *Client) GetPerformance(session *Session, accountKey, period string) (*PerformanceResponse, error) {
+	if session == nil || session.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+
+	// Ensure we have the ClientKey
+	if session.ClientKey == "" {
+		clientInfo, err := c.GetClientInfo(session)
+		if err != nil {
+			return nil, fmt.Errorf("getting client key: %w", err)
+		}
+		session.ClientKey = clientInfo.ClientKey
+	}
+
+	query := url.Values{
+		"ClientKey":      {session.ClientKey},
+		"AccountKey":     {accountKey},
+		"StandardPeriod": {period},
+		"FieldGroups":    {"TimeWeightedPerformance"},
+	}
+	endpoint := fmt.Sprintf("%s/hist/v3/perf/%s?%s", apiBaseURL, url.PathEscape(session.ClientKey), query.Encode())
+
+	var perf PerformanceResponse
+	if err := c.getJSON(session, endpoint, "performance", &perf); err != nil {
+		return nil, err
+	}
+	return &perf, nil
+}
@@ -1,8 +1,10 @@
 package saxo
 
 import (
+	"fmt"
 	"log"
 	"math"
+	"sync"
 )
 
 // PositionWithDetails combines a position with its instrument details.
@@ -11,6 +13,13 @@ type PositionWithDetails struct {
 	Instrument *InstrumentDetails
 }
 
+// maxConcurrentRequests bounds the parallel requests of FetchAccounts. All
+// requests still share the client's rate limit.
+const maxConcurrentRequests = 4
+
+// maxUicsPerRequest bounds the instruments looked up in one request.
+const maxUicsPerRequest = 100
+
 // GetPositionsWithDetails fetches positions and enriches them with instrument details.
 func (c *Client) GetPositionsWithDetails(session *Session, accountKey string) ([]PositionWithDetails, error) {
 	// Fetch positions
@@ -23,6 +32,92 @@ func (c *Client) GetPositionsWithDetails(session *Session, accountKey string) ([
 		return nil, nil
 	}
 
+	return c.withInstrumentDetails(session, positions), nil
+}
+
+// AccountData holds the positions and balance fetched for one account.
+type AccountData struct {
+	Positions  []PositionWithDetails
+	Balance    *BalanceResponse
+	Err        error // Fetching positions failed
+	BalanceErr error // Fetching the balance failed
+
+	positions []Position // Raw positions, before the instrument lookup
+}
+
+// FetchAccounts fetches positions and balances for several accounts in one
+// authenticated pass. The requests run on a bounded worker pool, and the
+// instrument details are looked up once for the positions of all accounts.
+func (c *Client) FetchAccounts(session *Session, accountKeys []string) (map[string]*AccountData, error) {
+	if session == nil || session.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+
+	// Resolve the ClientKey before the workers share the session
+	if session.ClientKey == "" {
+		if _, err := c.GetClientInfo(session); err != nil {
+			return nil, fmt.Errorf("getting client key: %w", err)
+		}
+	}
+
+	type request struct {
+		accountKey string
+		balance    bool
+	}
+
+	results := make(map[string]*AccountData, len(accountKeys))
+	var keys []string
+	var requests []request
+	for _, key := range accountKeys {
+		if _, ok := results[key]; ok {
+			continue
+		}
+		results[key] = &AccountData{}
+		keys = append(keys, key)
+		requests = append(requests, request{accountKey: key}, request{accountKey: key, balance: true})
+	}
+
+	// Each request writes only its own fields, so no locking is needed
+	queue := make(chan request)
+	var wg sync.WaitGroup
+	for range min(maxConcurrentRequests, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for req := range queue {
+				data := results[req.accountKey]
+				if req.balance {
+					data.Balance, data.BalanceErr = c.GetBalance(session, req.accountKey)
+				} else {
+					data.positions, data.Err = c.GetPositions(session, req.accountKey)
+				}
+			}
+		}()
+	}
+	for _, req := range requests {
+		queue <- req
+	}
+	close(queue)
+	wg.Wait()
+
+	// Look up the instruments of all accounts together
+	var all []Position
+	for _, key := range keys {
+		all = append(all, results[key].positions...)
+	}
+	enriched := c.withInstrumentDetails(session, all)
+	for _, key := range keys {
+		data := results[key]
+		data.Positions, enriched = enriched[:len(data.positions)], enriched[len(data.positions):]
+		data.positions = nil
+	}
+
+	return results, nil
+}
+
+// withInstrumentDetails enriches positions with their instrument details.
+// Positions are returned without details if the lookup fails.
+func (c *Client) withInstrumentDetails(session *Session, positions []Position) []PositionWithDetails {
 	// Collect unique UICs and their asset types for instrument lookup
 	uicMap := make(map[int64]string) // UIC -> AssetType
 	for _, pos := range positions {
@@ -40,11 +135,16 @@ func (c *Client) GetPositionsWithDetails(session *Session, accountKey string) ([
 		}
 	}
 
-	// Fetch instrument details
-	instruments, err := c.GetInstrumentDetails(session, uics, assetTypes)
-	if err != nil {
-		log.Printf("[Saxo] Warning: failed to fetch instrument details: %v", err)
-		// Continue without instrument details
+	// Fetch instrument details, in chunks to keep the URL short
+	var instruments []InstrumentDetails
+	for start := 0; start < len(uics); start += maxUicsPerRequest {
+		chunk, err := c.GetInstrumentDetails(session, uics[start:min(start+maxUicsPerRequest, len(uics))], assetTypes)
+		if err != nil {
+			log.Printf("[Saxo] Warning: failed to fetch instrument details: %v", err)
+			// Continue without instrument details
+			continue
+		}
+		instruments = append(instruments, chunk...)
 	}
 
 	// Build UIC -> InstrumentDetails map
@@ -62,7 +162,7 @@ func (c *Client) GetPositionsWithDetails(session *Session, accountKey string) ([
 		}
 	}
 
-	return result, nil
+	return result
 }
 
 // PositionHelpers - convenience methods for Position
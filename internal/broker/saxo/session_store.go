@@ -0,0 +1,85 @@
+package saxo
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/store"
+)
+
+// sharedSessions, if set, keeps cached sessions in the database, so they
+// survive restarts and are shared by all instances. Saxo rotates refresh
+// tokens, so instances read the latest session from it rather than keep
+// using their own copy.
+var sharedSessions store.Store
+
+// SetSessionStore keeps cached sessions in a store. It must be called before
+// sessions are cached.
+func SetSessionStore(s store.Store) {
+	sharedSessions = s
+}
+
+// sessionKey is the shared store key of a connection's session.
+func sessionKey(connectionID int64) string {
+	return "saxo:session:" + strconv.FormatInt(connectionID, 10)
+}
+
+// loadSharedSession returns a connection's session from the shared store.
+// ok is false if the store is not set or could not be read, in which case
+// the local cache is used.
+func loadSharedSession(connectionID int64) (session *Session, ok bool) {
+	if sharedSessions == nil {
+		return nil, false
+	}
+	data, err := sharedSessions.Get(sessionKey(connectionID))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		log.Printf("[Saxo] Error loading shared session for connection %d: %v", connectionID, err)
+		return nil, false
+	}
+	session = &Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		log.Printf("[Saxo] Error decoding shared session for connection %d: %v", connectionID, err)
+		return nil, false
+	}
+	return session, true
+}
+
+// saveSharedSession writes a connection's session to the shared store until
+// its refresh token expires.
+func saveSharedSession(connectionID int64, session *Session) {
+	if sharedSessions == nil {
+		return
+	}
+	data, err := json.Marshal(session)
+	if err == nil {
+		err = sharedSessions.Set(sessionKey(connectionID), data, time.Until(sessionExpiry(session)))
+	}
+	if err != nil {
+		log.Printf("[Saxo] Error sharing session for connection %d: %v", connectionID, err)
+	}
+}
+
+// deleteSharedSession removes a connection's session from the shared store.
+func deleteSharedSession(connectionID int64) {
+	if sharedSessions == nil {
+		return
+	}
+	if err := sharedSessions.Delete(sessionKey(connectionID)); err != nil {
+		log.Printf("[Saxo] Error removing shared session for connection %d: %v", connectionID, err)
+	}
+}
+
+// sessionExpiry returns when a session can no longer be used: when its
+// refresh token expires, or its access token if that lasts longer.
+func sessionExpiry(session *Session) time.Time {
+	if session.ExpiresAt.After(session.RefreshExpiresAt) {
+		return session.ExpiresAt
+	}
+	return session.RefreshExpiresAt
+}
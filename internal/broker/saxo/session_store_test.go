@@ -0,0 +1,57 @@
+package saxo
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/store"
+)
+
+func TestStoredSessions_SurviveRestart(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	encryptor, err := broker.NewEncryptor("test-secret-that-is-32-chars-long")
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	shared := store.NewSQLite(db, encryptor)
+	SetSessionStore(shared)
+	t.Cleanup(func() { SetSessionStore(nil) })
+
+	now := time.Now()
+	session := &Session{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: now.Add(20 * time.Minute), RefreshExpiresAt: now.Add(time.Hour)}
+	CacheSession(901, session)
+	CacheSession(902, &Session{RefreshToken: "gone", ExpiresAt: now.Add(time.Hour)})
+	ClearCachedSession(902)
+	if _, err := shared.Get(sessionKey(902)); err != store.ErrNotFound {
+		t.Errorf("cleared session: error = %v; want ErrNotFound", err)
+	}
+
+	// A restart empties the cache; the stored session fills it again
+	cachedSessionsMutex.Lock()
+	delete(cachedSessions, 901)
+	cachedSessionsMutex.Unlock()
+	t.Cleanup(func() { ClearCachedSession(901) })
+
+	if got := GetCachedSession(901); got == nil || got.RefreshToken != "refresh" || got.AccessToken != "access" {
+		t.Errorf("restored session = %+v; want the tokens from before the restart", got)
+	}
+
+	// The session is kept until its refresh token expires
+	var expiresAt int64
+	if err := db.QueryRow(`SELECT expires_at FROM shared_state WHERE key = ?`, sessionKey(901)).Scan(&expiresAt); err != nil {
+		t.Fatalf("reading stored session: %v", err)
+	}
+	if got := time.UnixMilli(expiresAt); got.Sub(session.RefreshExpiresAt).Abs() > time.Second {
+		t.Errorf("stored session expires at %v; want %v", got, session.RefreshExpiresAt)
+	}
+}
@@ -42,6 +42,7 @@ type ClientInfo struct {
 // AccountsResponse wraps the account list from /port/v1/accounts.
 type AccountsResponse struct {
 	Data []Account `json:"Data"`
+	Next string    `json:"__next,omitempty"` // URL of the next page, if any
 }
 
 // Account represents a Saxo trading account.
@@ -64,6 +65,7 @@ type Account struct {
 type PositionsResponse struct {
 	Count int        `json:"__count"`
 	Data  []Position `json:"Data"`
+	Next  string     `json:"__next,omitempty"` // URL of the next page, if any
 }
 
 // Position represents a trading position.
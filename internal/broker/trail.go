@@ -0,0 +1,148 @@
+package broker
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Trail limits keep a diagnostic bundle small enough to attach to an issue.
+const (
+	maxTrailEntries   = 200
+	maxTrailBodyBytes = 2048
+)
+
+// sensitiveKey matches query parameter and JSON field names whose values
+// must never leave the server.
+var sensitiveKey = regexp.MustCompile(`(?i)(token|secret|password|passwd|cpr|ssn|session|cookie|code_verifier|^code$|authorization|ntag|jwt)`)
+
+// sensitiveJSONField matches "key": "value" pairs with a sensitive key.
+var sensitiveJSONField = regexp.MustCompile(`(?i)("[^"]*(?:token|secret|password|passwd|cpr|ssn|session|cookie|code_verifier|authorization|ntag|jwt)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// bearerToken matches bearer tokens quoted in error messages.
+var bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[^\s"]+`)
+
+// TrailEntry is one recorded broker API request.
+type TrailEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Status     int       `json:"status,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Body       string    `json:"response_body,omitempty"` // Redacted and truncated; only kept for failed requests
+}
+
+// Trail records the broker API requests of one sync for bug reports.
+// Credentials are redacted before anything is stored, request bodies and
+// headers are never recorded, and response bodies only for failed requests.
+type Trail struct {
+	mu      sync.Mutex
+	entries []TrailEntry
+	dropped int
+}
+
+// NewTrail creates an empty Trail.
+func NewTrail() *Trail {
+	return &Trail{}
+}
+
+// Entries returns a copy of the recorded requests.
+func (t *Trail) Entries() []TrailEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TrailEntry(nil), t.entries...)
+}
+
+// Dropped returns how many requests were not recorded once the trail was full.
+func (t *Trail) Dropped() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+func (t *Trail) add(e TrailEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.entries) >= maxTrailEntries {
+		t.dropped++
+		return
+	}
+	t.entries = append(t.entries, e)
+}
+
+// Wrap returns a RoundTripper that records requests made through next. A nil
+// next uses http.DefaultTransport.
+func (t *Trail) Wrap(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &trailTransport{trail: t, next: next}
+}
+
+type trailTransport struct {
+	trail *Trail
+	next  http.RoundTripper
+}
+
+func (tt *trailTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := tt.next.RoundTrip(req)
+
+	entry := TrailEntry{
+		Time:       start,
+		Method:     req.Method,
+		URL:        RedactURL(req.URL),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = RedactText(err.Error())
+		tt.trail.add(entry)
+		return nil, err
+	}
+	entry.Status = resp.StatusCode
+
+	if resp.StatusCode >= 400 && resp.Body != nil {
+		// Keep a redacted prefix of the body and hand the caller the full body
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr == nil {
+			if len(body) > maxTrailBodyBytes {
+				body = body[:maxTrailBodyBytes]
+			}
+			entry.Body = RedactText(string(body))
+		}
+	}
+
+	tt.trail.add(entry)
+	return resp, nil
+}
+
+// RedactURL returns the URL without user info and with sensitive query
+// values replaced.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	for key := range query {
+		if sensitiveKey.MatchString(key) {
+			query[key] = []string{"REDACTED"}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// RedactText replaces the values of sensitive JSON fields and bearer tokens.
+func RedactText(s string) string {
+	s = sensitiveJSONField.ReplaceAllString(s, `$1"REDACTED"`)
+	return bearerToken.ReplaceAllString(s, `${1}REDACTED`)
+}
@@ -2,8 +2,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/broker"
 )
 
 // Config holds the application configuration.
@@ -22,6 +27,107 @@ type Config struct {
 	// Broker integration settings
 	EncryptionSecret string // Used for encrypting broker credentials
 
+	// SyncMaxDeletePercent is the largest share of an account's holdings a
+	// broker sync may delete without user confirmation.
+	SyncMaxDeletePercent int
+
+	// BalanceAnomalyPercent is how far, in percent, a synced or entered
+	// balance may deviate from the account's recent trend before it needs
+	// confirmation. 0 disables the check.
+	BalanceAnomalyPercent int
+
+	// ReplicaPath is where a sanitized copy of the database is written for
+	// analytics tools. Empty disables the export.
+	ReplicaPath string
+
+	// ReplicaIntervalHours is how often the replica is refreshed.
+	ReplicaIntervalHours int
+
+	// StateStore is where rate limit counters are kept: "memory" for a
+	// single instance, or "sqlite" to share them through the database between
+	// instances behind a load balancer.
+	StateStore string
+
+	// HousekeepingIntervalMinutes is how often expired sessions, leftover
+	// MitID QR files, stale broker sessions and idle rate limit buckets are
+	// cleaned up. 0 disables housekeeping.
+	HousekeepingIntervalMinutes int
+
+	// MaintenanceHour is the hour of the day, in server time, in which the
+	// database WAL is checkpointed, its statistics refreshed and its file
+	// vacuumed. -1 disables scheduled maintenance.
+	MaintenanceHour int
+
+	// Access log privacy. AccessLogIP is how client IPs are logged: full,
+	// truncate, hash or off. AccessLogRedactParams are query parameters whose
+	// values are redacted on top of the built-in token and credential ones.
+	// An AccessLogFile is rotated daily and its rotated files are deleted
+	// after AccessLogRetentionDays (0 keeps them); empty logs to stdout.
+	AccessLogIP            string
+	AccessLogRedactParams  []string
+	AccessLogFile          string
+	AccessLogRetentionDays int
+
+	// SMTP server for outgoing email, such as digests. An empty SMTPHost
+	// disables email.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Broker logins to remind owners of: Saxo logins whose refresh token
+	// expires within SaxoRefreshWarnDays, and Nordnet connections that have
+	// not synced successfully for NordnetAuthStaleDays.
+	SaxoRefreshWarnDays  int
+	NordnetAuthStaleDays int
+
+	// Outbound proxy and User-Agent of broker requests, for connections
+	// without their own. Empty connects directly with a browser User-Agent.
+	BrokerProxyURL  string
+	BrokerUserAgent string
+
+	// NotifyPrivateNetworks lets notification channels reach loopback,
+	// private and link-local addresses, for ntfy or Gotify servers on the
+	// LAN. Off by default, so users cannot probe the server's network.
+	NotifyPrivateNetworks bool
+
+	// AllowLoopbackAPIURLs lets bank and exchange API URLs use plain HTTP on
+	// this machine, for tests against local fakes. No environment variable
+	// sets it, so deployments cannot turn it on.
+	AllowLoopbackAPIURLs bool
+
+	// PriceHistoryURL is where the daily closing prices of an ISIN are
+	// fetched to backfill holdings, with {isin}, {from} and {to} filled in.
+	// Empty disables backfilling.
+	PriceHistoryURL string
+
+	// Monthly quotas per user for hosted instances; 0 is unlimited.
+	SyncQuota       int
+	MarketDataQuota int
+	APIQuota        int
+
+	// Password policy for registration, password changes and admin resets.
+	// PasswordMinScore is on zxcvbn's 0-4 scale. PasswordBreachDir holds
+	// Have I Been Pwned range files; empty skips the breach check.
+	PasswordMinLength int
+	PasswordMinScore  int
+	PasswordBreachDir string
+
+	// LoginLinks lets admins create one-time login links for locked-out
+	// users. Security-sensitive deployments can turn them off.
+	LoginLinks bool
+
+	// MockBroker answers Nordnet and Saxo requests from recorded payloads and
+	// signs connections in without MitID or OAuth, for local development.
+	// Ignored outside development.
+	MockBroker bool
+
+	// FeatureFlags are experimental features that are on for everyone unless
+	// an admin turns them off, such as for dark launches on the hosted demo
+	// where the admin panel is disabled.
+	FeatureFlags []string
+
 	// Environment
 	IsDevelopment bool
 
@@ -29,17 +135,54 @@ type Config struct {
 	DemoMode bool
 }
 
+// Built-in secrets used when none are configured; fine for development only.
+const (
+	defaultSessionSecret    = "change-me-in-production-please"
+	defaultEncryptionSecret = "change-me-in-production-32chars!"
+)
+
 // New creates a new Config with values from environment variables or defaults.
 func New() *Config {
 	return &Config{
-		Port:             getEnv("PORT", "8080"),
-		Host:             getEnv("HOST", "localhost"),
-		DBPath:           getEnv("DB_PATH", filepath.Join("data", "wealth.db")),
-		SessionSecret:    getEnv("SESSION_SECRET", "change-me-in-production-please"),
-		SessionMaxAge:    86400 * 7, // 7 days
-		EncryptionSecret: getEnv("ENCRYPTION_SECRET", "change-me-in-production-32chars!"),
-		IsDevelopment:    getEnv("ENV", "development") == "development",
-		DemoMode:         getEnv("DEMO_MODE", "false") == "true",
+		Port:                        getEnv("PORT", "8080"),
+		Host:                        getEnv("HOST", "localhost"),
+		DBPath:                      getEnv("DB_PATH", filepath.Join("data", "wealth.db")),
+		SessionSecret:               getEnv("SESSION_SECRET", defaultSessionSecret),
+		SessionMaxAge:               86400 * 7, // 7 days
+		EncryptionSecret:            getEnv("ENCRYPTION_SECRET", defaultEncryptionSecret),
+		SyncMaxDeletePercent:        getEnvInt("SYNC_MAX_DELETE_PERCENT", 50),
+		BalanceAnomalyPercent:       getEnvInt("BALANCE_ANOMALY_PERCENT", 50),
+		ReplicaPath:                 getEnv("REPLICA_PATH", ""),
+		ReplicaIntervalHours:        getEnvInt("REPLICA_INTERVAL_HOURS", 24),
+		StateStore:                  getEnv("STATE_STORE", "memory"),
+		HousekeepingIntervalMinutes: getEnvInt("HOUSEKEEPING_INTERVAL_MINUTES", 15),
+		MaintenanceHour:             getEnvInt("MAINTENANCE_HOUR", 4),
+		AccessLogIP:                 getEnv("ACCESS_LOG_IP", "truncate"),
+		AccessLogRedactParams:       getEnvList("ACCESS_LOG_REDACT_PARAMS"),
+		AccessLogFile:               getEnv("ACCESS_LOG_FILE", ""),
+		AccessLogRetentionDays:      getEnvInt("ACCESS_LOG_RETENTION_DAYS", 30),
+		SMTPHost:                    getEnv("SMTP_HOST", ""),
+		SMTPPort:                    getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:                getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                    getEnv("SMTP_FROM", ""),
+		SaxoRefreshWarnDays:         getEnvInt("SAXO_REFRESH_WARN_DAYS", 3),
+		NordnetAuthStaleDays:        getEnvInt("NORDNET_AUTH_STALE_DAYS", 7),
+		BrokerProxyURL:              getEnv("BROKER_PROXY_URL", ""),
+		BrokerUserAgent:             getEnv("BROKER_USER_AGENT", ""),
+		NotifyPrivateNetworks:       getEnv("NOTIFY_PRIVATE_NETWORKS", "false") == "true",
+		PriceHistoryURL:             getEnv("PRICE_HISTORY_URL", ""),
+		SyncQuota:                   getEnvInt("SYNC_MONTHLY_QUOTA", 0),
+		MarketDataQuota:             getEnvInt("MARKET_DATA_MONTHLY_QUOTA", 0),
+		APIQuota:                    getEnvInt("API_MONTHLY_QUOTA", 0),
+		PasswordMinLength:           getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinScore:            getEnvInt("PASSWORD_MIN_SCORE", 2),
+		PasswordBreachDir:           getEnv("PASSWORD_BREACH_DIR", ""),
+		LoginLinks:                  getEnv("LOGIN_LINKS", "true") == "true",
+		MockBroker:                  getEnv("MOCK_BROKER", "false") == "true",
+		FeatureFlags:                getEnvList("FEATURE_FLAGS"),
+		IsDevelopment:               getEnv("ENV", "development") == "development",
+		DemoMode:                    getEnv("DEMO_MODE", "false") == "true",
 	}
 }
 
@@ -48,6 +191,104 @@ func (c *Config) Address() string {
 	return c.Host + ":" + c.Port
 }
 
+// Problems returns settings that are unsafe or out of range. The app still
+// starts with them, and admins are shown the problems in a banner.
+func (c *Config) Problems() []string {
+	var problems []string
+	if !c.IsDevelopment {
+		if c.SessionSecret == defaultSessionSecret {
+			problems = append(problems, "SESSION_SECRET is not set; sessions are signed with the built-in development secret.")
+		}
+		if c.EncryptionSecret == defaultEncryptionSecret {
+			problems = append(problems, "ENCRYPTION_SECRET is not set; the built-in development secret is used.")
+		}
+		if c.MockBroker {
+			problems = append(problems, "MOCK_BROKER is ignored outside development.")
+		}
+	}
+	if len(c.EncryptionSecret) < 32 {
+		problems = append(problems, "ENCRYPTION_SECRET must be at least 32 characters.")
+	}
+	if c.SyncMaxDeletePercent < 0 || c.SyncMaxDeletePercent > 100 {
+		problems = append(problems, fmt.Sprintf("SYNC_MAX_DELETE_PERCENT must be between 0 and 100, got %d.", c.SyncMaxDeletePercent))
+	}
+	if c.BalanceAnomalyPercent < 0 {
+		problems = append(problems, fmt.Sprintf("BALANCE_ANOMALY_PERCENT must be 0 (off) or more, got %d.", c.BalanceAnomalyPercent))
+	}
+	if c.ReplicaPath != "" {
+		if c.ReplicaIntervalHours < 1 {
+			problems = append(problems, fmt.Sprintf("REPLICA_INTERVAL_HOURS must be at least 1, got %d; the replica is not refreshed.", c.ReplicaIntervalHours))
+		}
+		if filepath.Clean(c.ReplicaPath) == filepath.Clean(c.DBPath) {
+			problems = append(problems, "REPLICA_PATH must not be the live database; the replica is not exported.")
+		}
+	}
+	if c.StateStore != "memory" && c.StateStore != "sqlite" {
+		problems = append(problems, fmt.Sprintf("STATE_STORE must be memory or sqlite, got %q; state is kept in memory.", c.StateStore))
+	}
+	if c.HousekeepingIntervalMinutes < 0 {
+		problems = append(problems, fmt.Sprintf("HOUSEKEEPING_INTERVAL_MINUTES must be 0 (off) or more, got %d.", c.HousekeepingIntervalMinutes))
+	}
+	if c.MaintenanceHour < -1 || c.MaintenanceHour > 23 {
+		problems = append(problems, fmt.Sprintf("MAINTENANCE_HOUR must be an hour from 0 to 23, or -1 (off), got %d; scheduled maintenance is off.", c.MaintenanceHour))
+	}
+	switch c.AccessLogIP {
+	case "full", "truncate", "hash", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("ACCESS_LOG_IP must be full, truncate, hash or off, got %q; IPs are truncated.", c.AccessLogIP))
+	}
+	if c.AccessLogRetentionDays < 0 {
+		problems = append(problems, fmt.Sprintf("ACCESS_LOG_RETENTION_DAYS must be 0 (keep forever) or more, got %d; rotated logs are kept.", c.AccessLogRetentionDays))
+	}
+	if c.SaxoRefreshWarnDays < 0 {
+		problems = append(problems, fmt.Sprintf("SAXO_REFRESH_WARN_DAYS must be 0 (off) or more, got %d.", c.SaxoRefreshWarnDays))
+	}
+	if c.NordnetAuthStaleDays < 0 {
+		problems = append(problems, fmt.Sprintf("NORDNET_AUTH_STALE_DAYS must be 0 (off) or more, got %d.", c.NordnetAuthStaleDays))
+	}
+	if (broker.HTTPConfig{ProxyURL: c.BrokerProxyURL}).Validate() != nil {
+		problems = append(problems, "BROKER_PROXY_URL must be an http://, https:// or socks5:// URL; broker requests are not proxied.")
+	}
+	if u := c.PriceHistoryURL; u != "" {
+		if !(strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")) || !strings.Contains(u, "{isin}") {
+			problems = append(problems, "PRICE_HISTORY_URL must be an http:// or https:// URL with an {isin} placeholder; backfilling holdings fails.")
+		}
+	}
+	for _, q := range []struct {
+		name  string
+		quota int
+	}{
+		{"SYNC_MONTHLY_QUOTA", c.SyncQuota},
+		{"MARKET_DATA_MONTHLY_QUOTA", c.MarketDataQuota},
+		{"API_MONTHLY_QUOTA", c.APIQuota},
+	} {
+		if q.quota < 0 {
+			problems = append(problems, fmt.Sprintf("%s must be 0 (unlimited) or more, got %d.", q.name, q.quota))
+		}
+	}
+	if c.PasswordMinLength < 8 {
+		problems = append(problems, fmt.Sprintf("PASSWORD_MIN_LENGTH must be at least 8, got %d; 8 is used.", c.PasswordMinLength))
+	}
+	if c.PasswordMinScore < 0 || c.PasswordMinScore > 4 {
+		problems = append(problems, fmt.Sprintf("PASSWORD_MIN_SCORE must be between 0 and 4, got %d; 2 is used.", c.PasswordMinScore))
+	}
+	if c.PasswordBreachDir != "" {
+		if _, err := os.Stat(filepath.Join(c.PasswordBreachDir, "00000.txt")); err != nil {
+			problems = append(problems, "PASSWORD_BREACH_DIR has no range files like 00000.txt; passwords are not checked against breaches.")
+		}
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		problems = append(problems, "SMTP_FROM is not set; no email is sent.")
+	}
+	return problems
+}
+
+// EmailEnabled returns true if an SMTP server and sender address are
+// configured.
+func (c *Config) EmailEnabled() bool {
+	return c.SMTPHost != "" && c.SMTPFrom != ""
+}
+
 // IsDemoMode returns true if the app is running in demo mode.
 // This is a convenience method that can be used without a Config instance.
 func (c *Config) IsDemoMode() bool {
@@ -79,3 +320,24 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt returns an environment variable parsed as an integer, or a default
+// value if it is unset or invalid.
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList returns a comma-separated environment variable as a list,
+// skipping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
@@ -24,8 +25,9 @@ func New(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("creating database directory: %w", err)
 	}
 
-	// Open database connection
-	sqlDB, err := sql.Open("sqlite", dbPath)
+	// Open database connection. Foreign keys are a per-connection setting,
+	// so they are enabled in the DSN for every pooled connection.
+	sqlDB, err := sql.Open("sqlite", dbPath+"?_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -37,9 +39,8 @@ func New(dbPath string) (*DB, error) {
 	sqlDB.SetMaxOpenConns(10)
 	sqlDB.SetMaxIdleConns(2)
 
-	// Enable foreign keys and WAL mode via PRAGMA
+	// Enable WAL mode via PRAGMA; it is stored in the database file
 	pragmas := []string{
-		"PRAGMA foreign_keys = ON",
 		"PRAGMA journal_mode = WAL",
 	}
 	for _, pragma := range pragmas {
@@ -58,59 +59,208 @@ func New(dbPath string) (*DB, error) {
 	return &DB{DB: sqlDB}, nil
 }
 
+// createMigrations create tables and indexes. Migrations are idempotent and
+// only ever appended to, so their count doubles as the schema version.
+var createMigrations = []string{
+	migrationUsers,
+	migrationCategories,
+	migrationAccounts,
+	migrationTransactions,
+	migrationGoals,
+	migrationCurrencyRates,
+	migrationSessions,
+	migrationIndexes,
+	// Broker integration tables
+	migrationBrokerConnections,
+	migrationBrokerSessions,
+	migrationHoldings,
+	migrationAccountMappings,
+	migrationSyncHistory,
+	migrationBrokerIndexes,
+	// Portfolio optimization
+	migrationAllocationTargets,
+	migrationAllocationTargetsIndex,
+	// Audit logging
+	migrationAuditLog,
+	migrationAuditLogIndexes,
+	// Performance optimizations
+	migrationPerformanceIndexes,
+	// Holdings removed by sync, kept for restore
+	migrationHoldingHistory,
+	// MitID authentication attempt tracking
+	migrationMitIDAttempts,
+	// Rebalancing checklists
+	migrationRebalanceSessions,
+	// User-defined exchange rates
+	migrationManualExchangeRates,
+	// Net worth milestones
+	migrationNetWorthMilestones,
+	// Recomputed cost basis
+	migrationHoldingAcquisitions,
+	// Release tracking
+	migrationAppVersions,
+	// Sync diagnostic bundles
+	migrationSyncDiagnostics,
+	// Watchlist
+	migrationWatchlist,
+	// Account-scoped API keys
+	migrationAccountAPIKeys,
+	// Email digests
+	migrationEmailDigests,
+	// Broker-reported returns
+	migrationBrokerPerformance,
+	// Goal progress history
+	migrationGoalSnapshots,
+	// Liability interest postings
+	migrationInterestAccruals,
+	// Holdings left out of portfolio analytics
+	migrationAnalyticsExclusions,
+	// Alert delivery channels
+	migrationNotificationChannels,
+	// Usage against monthly quotas
+	migrationUsageCounts,
+	// Holdings over time
+	migrationHoldingSnapshots,
+	// Exchange rates over time
+	migrationCurrencyRateHistory,
+	// Strategy labels on holdings
+	migrationHoldingLabels,
+	// Account snapshots on demand
+	migrationAccountSnapshots,
+	// Automatic categorization of imported transactions
+	migrationCategorizationRules,
+	// Chart colors kept consistent across charts
+	migrationChartColors,
+	// Retention of history tables
+	migrationRetentionPolicies,
+	// Danish tax figures per year
+	migrationTaxParameters,
+	// Savings plan buys awaiting execution
+	migrationPendingInvestments,
+	// One-time login links for support
+	migrationLoginLinks,
+	// State shared by instances, such as rate limits and broker sessions
+	migrationSharedState,
+	// User-wide API tokens for scripts
+	migrationAPITokens,
+	// Accounts and goals shared with advisors, and their comment threads
+	migrationAdvisorShares,
+	migrationComments,
+	// Named transaction filters
+	migrationSavedFilters,
+	// Experimental features turned on by admins
+	migrationFeatureFlags,
+	// Daily net worth history
+	migrationNetWorthSnapshots,
+	// Accounts goals are funded from
+	migrationGoalFundingAccounts,
+}
+
+// alterMigrations add columns to existing tables. They are run separately as
+// they fail with "duplicate column" once applied.
+var alterMigrations = []string{
+	migrationAddNumberFormat,
+	migrationAddGoalCategory,
+	migrationAddIsAdmin,
+	migrationAddMustChangePassword,
+	migrationAddBrokerCPR,
+	// Saxo OAuth token storage
+	migrationAddSaxoRefreshToken,
+	migrationAddSaxoTokenExpiry,
+	migrationAddSaxoRefreshExpiry,
+	migrationAddSaxoAppKey,
+	migrationAddSaxoAppSecret,
+	migrationAddSaxoRedirectURI,
+	// Sync deletion safeguards
+	migrationAddHeldDeletionsSince,
+	// Display preferences
+	migrationAddHideDecimals,
+	// Transaction categories
+	migrationAddTransactionCategory,
+	// Projection assumptions
+	migrationAddCategoryExpectedReturn,
+	// Net worth milestones
+	migrationAddMilestoneStep,
+	// Recomputed cost basis
+	migrationAddHoldingCostBasisMode,
+	// Account lifetime
+	migrationAddAccountOpenedAt,
+	migrationAddAccountClosedAt,
+	// Release tracking
+	migrationAddUserSeenVersion,
+	// Balance anomaly detection
+	migrationAddHeldBalance,
+	migrationAddHeldBalanceAt,
+	// Transaction description templates
+	migrationAddUserBalanceDescription,
+	migrationAddUserSyncDescription,
+	// Email digest frequency
+	migrationAddUserDigestFrequency,
+	// Time zone for displayed times
+	migrationAddUserTimezone,
+	// Expiry of temporary demo users
+	migrationAddUserExpiresAt,
+	// Preferred hours and days of unattended syncs
+	migrationAddConnectionSyncWindowStart,
+	migrationAddConnectionSyncWindowEnd,
+	migrationAddConnectionSkipWeekends,
+	// Interest rate of liabilities
+	migrationAddAccountInterestRate,
+	// Liquidity of categories
+	migrationAddCategoryLiquidity,
+	// Account order and pinning
+	migrationAddAccountSortOrder,
+	migrationAddAccountPinned,
+	// Edit conflict detection
+	migrationAddAccountUpdatedAt,
+	migrationAddTransactionUpdatedAt,
+	migrationAddGoalUpdatedAt,
+	// Broker HTTP settings
+	migrationAddConnectionProxyURL,
+	migrationAddConnectionUserAgent,
+	// Balance updates apart from money moved
+	migrationAddTransactionKind,
+	// Credential freshness reminders
+	migrationAddConnectionLastSuccessAt,
+	migrationAddConnectionCredentialRemindedAt,
+	// Tags set by categorization rules
+	migrationAddTransactionTag,
+	// Net worth definitions of the dashboard
+	migrationAddAccountNetWorthGroup,
+	migrationAddUserNetWorthExclusions,
+	// Markdown descriptions
+	migrationAddCategoryDescription,
+	migrationAddGoalDescription,
+	// Scheduled syncs
+	migrationAddConnectionSyncSchedule,
+	// PSD2 bank connections
+	migrationAddConnectionAPIURL,
+	// Statement periods starting on payday
+	migrationAddUserPeriodStartDay,
+	// Planned goal contributions
+	migrationAddGoalMonthlyContribution,
+	// Encrypted notification channel secrets
+	migrationAddChannelSecretsEncrypted,
+	// Milestone step changes
+	migrationAddMilestoneRecordedStep,
+}
+
 // RunMigrations executes all database migrations.
 // Migrations are idempotent and can be run multiple times safely.
 func (db *DB) RunMigrations() error {
-	migrations := []string{
-		migrationUsers,
-		migrationCategories,
-		migrationAccounts,
-		migrationTransactions,
-		migrationGoals,
-		migrationCurrencyRates,
-		migrationSessions,
-		migrationIndexes,
-		// Broker integration tables
-		migrationBrokerConnections,
-		migrationBrokerSessions,
-		migrationHoldings,
-		migrationAccountMappings,
-		migrationSyncHistory,
-		migrationBrokerIndexes,
-		// Portfolio optimization
-		migrationAllocationTargets,
-		migrationAllocationTargetsIndex,
-		// Audit logging
-		migrationAuditLog,
-		migrationAuditLogIndexes,
-		// Performance optimizations
-		migrationPerformanceIndexes,
-	}
-
-	for i, migration := range migrations {
+	for i, migration := range createMigrations {
 		if _, err := db.Exec(migration); err != nil {
 			return fmt.Errorf("migration %d failed: %w", i+1, err)
 		}
 	}
 
-	// Run ALTER TABLE migrations separately (they fail silently if column exists)
-	alterMigrations := []string{
-		migrationAddNumberFormat,
-		migrationAddGoalCategory,
-		migrationAddIsAdmin,
-		migrationAddMustChangePassword,
-		migrationAddBrokerCPR,
-		// Saxo OAuth token storage
-		migrationAddSaxoRefreshToken,
-		migrationAddSaxoTokenExpiry,
-		migrationAddSaxoRefreshExpiry,
-		migrationAddSaxoAppKey,
-		migrationAddSaxoAppSecret,
-		migrationAddSaxoRedirectURI,
-	}
-	for _, migration := range alterMigrations {
-		// Ignore "duplicate column" errors for idempotency
-		db.Exec(migration)
+	// "duplicate column" means the migration was applied before; any other
+	// error leaves the schema behind, which is reported once all ran.
+	var pending []string
+	for i, migration := range alterMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			pending = append(pending, fmt.Sprintf("alter migration %d: %v", i+1, err))
+		}
 	}
 
 	// Run DROP COLUMN migrations for deprecated password columns
@@ -124,5 +274,14 @@ func (db *DB) RunMigrations() error {
 		db.Exec(migration)
 	}
 
+	if len(pending) > 0 {
+		return fmt.Errorf("migrations failed: %s", strings.Join(pending, "; "))
+	}
 	return nil
 }
+
+// SchemaVersion returns the schema version of this build. It is recorded per
+// release so a database last used by a newer build can be detected.
+func SchemaVersion() int {
+	return len(createMigrations) + len(alterMigrations)
+}
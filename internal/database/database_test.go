@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNew_CreatesConnection(t *testing.T) {
@@ -143,7 +144,7 @@ func TestRunMigrations_Idempotent(t *testing.T) {
 		t.Fatalf("counting tables: %v", err)
 	}
 
-	expectedCount := 14 // users, categories, accounts, transactions, goals, currency_rates, sessions + broker_connections, broker_sessions, holdings, account_mappings, sync_history + allocation_targets + audit_log
+	expectedCount := 52 // users, categories, accounts, transactions, goals, currency_rates, sessions + broker_connections, broker_sessions, holdings, account_mappings, sync_history + allocation_targets + audit_log + holding_history + mitid_attempts + rebalance_sessions, rebalance_actions + manual_exchange_rates + net_worth_milestones + holding_acquisitions + app_versions + sync_diagnostics + watchlist_items + account_api_keys + email_digests + broker_performance + goal_snapshots + interest_accruals + analytics_exclusions + notification_channels + usage_counts + holding_snapshots + currency_rate_history + holding_labels + account_snapshots, account_snapshot_holdings + categorization_rules + chart_colors + retention_policies + tax_parameters + pending_investments + login_links + shared_state + api_tokens + advisor_shares, comments + saved_filters + feature_flags + net_worth_snapshots, net_worth_snapshot_categories + goal_funding_accounts
 	if tableCount != expectedCount {
 		t.Errorf("table count = %d, want %d", tableCount, expectedCount)
 	}
@@ -315,3 +316,71 @@ func TestDB_CascadeDelete(t *testing.T) {
 		t.Error("transaction should be deleted after account delete")
 	}
 }
+
+func TestMigrationAddTransactionKind_BackfillsFromSources(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer db.Close()
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+
+	// Syncs are stored in the server's zone, created_at in UTC
+	syncStart := time.Now().Add(-time.Hour).In(time.FixedZone("CEST", 2*60*60))
+	for _, stmt := range []string{
+		`INSERT INTO users (id, email, password_hash, name) VALUES (1, 'a@example.com', 'x', 'A')`,
+		`INSERT INTO accounts (id, user_id, name) VALUES (1, 1, 'Depot'), (2, 1, 'Savings')`,
+		`INSERT INTO broker_connections (id, user_id, broker_type, username) VALUES (1, 1, 'nordnet', 'a')`,
+		`INSERT INTO account_mappings (connection_id, local_account_id, external_account_id) VALUES (1, 1, 'x')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Exec(%s) error = %v", stmt, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO sync_history (connection_id, sync_type, status, started_at, completed_at) VALUES (1, 'full', 'success', ?, ?)`,
+		syncStart, syncStart.Add(time.Minute)); err != nil {
+		t.Fatalf("inserting sync: %v", err)
+	}
+	during := syncStart.UTC().Add(30 * time.Second).Format("2006-01-02 15:04:05")
+	after := syncStart.UTC().Add(30 * time.Minute).Format("2006-01-02 15:04:05")
+	for _, txn := range []struct {
+		id, accountID int
+		description   string
+		createdAt     string
+	}{
+		{1, 1, "Nordnet sync", during},    // Recorded by the sync
+		{2, 1, "Deposit", after},          // Recorded by hand on the synced account
+		{3, 2, "Interest 2024", after},    // Booked by an accrual below
+		{4, 2, "Balance update", after},   // Ambiguous without a source
+		{5, 2, "Interest on loan", after}, // Only described as interest
+	} {
+		if _, err := db.Exec(`INSERT INTO transactions (id, account_id, amount, balance_after, description, transaction_date, created_at) VALUES (?, ?, 1, 1, ?, '2024-01-01', ?)`,
+			txn.id, txn.accountID, txn.description, txn.createdAt); err != nil {
+			t.Fatalf("inserting transaction: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO interest_accruals (account_id, transaction_id, period_end, rate, amount) VALUES (2, 3, '2024-01-01', 2, 1)`); err != nil {
+		t.Fatalf("inserting accrual: %v", err)
+	}
+
+	// Run the migration again as on a database from before kinds
+	if _, err := db.Exec(`ALTER TABLE transactions DROP COLUMN kind`); err != nil {
+		t.Fatalf("dropping kind: %v", err)
+	}
+	if _, err := db.Exec(migrationAddTransactionKind); err != nil {
+		t.Fatalf("migrationAddTransactionKind error = %v", err)
+	}
+
+	want := map[int]string{1: "valuation", 2: "", 3: "valuation", 4: "", 5: ""}
+	for id, kind := range want {
+		var got string
+		if err := db.QueryRow(`SELECT kind FROM transactions WHERE id = ?`, id).Scan(&got); err != nil {
+			t.Fatalf("reading kind: %v", err)
+		}
+		if got != kind {
+			t.Errorf("kind of transaction %d = %q; want %q", id, got, kind)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Integrity check names.
+const (
+	CheckSQLite            = "integrity_check"
+	CheckForeignKey        = "foreign_key"
+	CheckBalanceContinuity = "balance_continuity"
+	CheckUnmappedHoldings  = "unmapped_holdings"
+)
+
+// balanceTolerance absorbs floating point noise in stored balances.
+const balanceTolerance = 0.005
+
+// maxRepairPasses bounds RepairOrphans; each pass fixes the orphans left by
+// deleting the previous pass's rows.
+const maxRepairPasses = 10
+
+// IntegrityIssue is a problem found by CheckIntegrity.
+type IntegrityIssue struct {
+	Check   string
+	Table   string
+	RowID   int64
+	Message string
+	Fixable bool // RepairOrphans removes or clears it
+}
+
+// IntegrityReport lists the problems found by CheckIntegrity.
+type IntegrityReport struct {
+	Issues    []IntegrityIssue
+	CheckedAt time.Time
+}
+
+// OK returns true if no problems were found.
+func (r *IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// FixableCount returns the number of problems RepairOrphans can fix.
+func (r *IntegrityReport) FixableCount() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Fixable {
+			n++
+		}
+	}
+	return n
+}
+
+// orphan is a row whose foreign key points at a missing parent row.
+type orphan struct {
+	table    string
+	rowID    int64
+	parent   string
+	column   string
+	value    sql.NullString
+	onDelete string
+}
+
+// CheckIntegrity runs SQLite's integrity check and verifies invariants that
+// foreign keys can't enforce while they are off on a connection: rows
+// pointing at deleted parents, running balances that don't add up and
+// synced holdings no broker mapping will update.
+func (db *DB) CheckIntegrity() (*IntegrityReport, error) {
+	report := &IntegrityReport{CheckedAt: time.Now()}
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("running integrity check: %w", err)
+	}
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if msg != "ok" {
+			report.Issues = append(report.Issues, IntegrityIssue{Check: CheckSQLite, Message: msg})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	orphans, err := db.findOrphans()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orphans {
+		report.Issues = append(report.Issues, IntegrityIssue{
+			Check:   CheckForeignKey,
+			Table:   o.table,
+			RowID:   o.rowID,
+			Message: fmt.Sprintf("%s %s points at a missing %s row", o.column, o.value.String, o.parent),
+			Fixable: o.onDelete == "CASCADE" || o.onDelete == "SET NULL",
+		})
+	}
+
+	continuity, err := db.checkBalanceContinuity()
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, continuity...)
+
+	unmapped, err := db.checkUnmappedHoldings()
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, unmapped...)
+
+	return report, nil
+}
+
+// RepairOrphans applies the ON DELETE action of each broken foreign key:
+// orphaned rows are deleted, or their reference cleared for SET NULL keys.
+// It returns the number of rows changed.
+func (db *DB) RepairOrphans() (int, error) {
+	repaired := 0
+	for pass := 0; pass < maxRepairPasses; pass++ {
+		orphans, err := db.findOrphans()
+		if err != nil {
+			return repaired, err
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return repaired, err
+		}
+		fixed := 0
+		for _, o := range orphans {
+			var query string
+			switch o.onDelete {
+			case "CASCADE":
+				query = fmt.Sprintf(`DELETE FROM "%s" WHERE rowid = ?`, o.table)
+			case "SET NULL":
+				query = fmt.Sprintf(`UPDATE "%s" SET "%s" = NULL WHERE rowid = ?`, o.table, o.column)
+			default:
+				continue
+			}
+			if _, err := tx.Exec(query, o.rowID); err != nil {
+				tx.Rollback()
+				return repaired, fmt.Errorf("repairing %s row %d: %w", o.table, o.rowID, err)
+			}
+			fixed++
+		}
+		if err := tx.Commit(); err != nil {
+			return repaired, err
+		}
+
+		repaired += fixed
+		if fixed == 0 {
+			break
+		}
+	}
+	return repaired, nil
+}
+
+// findOrphans lists rows whose foreign key points at a missing parent row.
+func (db *DB) findOrphans() ([]orphan, error) {
+	rows, err := db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("checking foreign keys: %w", err)
+	}
+	type violation struct {
+		table  string
+		rowID  int64
+		parent string
+		fkID   int
+	}
+	var violations []violation
+	for rows.Next() {
+		var v violation
+		var rowID sql.NullInt64
+		if err := rows.Scan(&v.table, &rowID, &v.parent, &v.fkID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		v.rowID = rowID.Int64
+		violations = append(violations, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type foreignKey struct {
+		column   string
+		onDelete string
+	}
+	keys := make(map[string]map[int]foreignKey)
+	orphans := make([]orphan, 0, len(violations))
+	for _, v := range violations {
+		if keys[v.table] == nil {
+			keys[v.table] = make(map[int]foreignKey)
+			fkRows, err := db.Query(fmt.Sprintf(`PRAGMA foreign_key_list("%s")`, v.table))
+			if err != nil {
+				return nil, err
+			}
+			for fkRows.Next() {
+				var id, seq int
+				var parent, from, onUpdate, onDelete, match string
+				var to sql.NullString
+				if err := fkRows.Scan(&id, &seq, &parent, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+					fkRows.Close()
+					return nil, err
+				}
+				keys[v.table][id] = foreignKey{column: from, onDelete: strings.ToUpper(onDelete)}
+			}
+			fkRows.Close()
+		}
+
+		key := keys[v.table][v.fkID]
+		o := orphan{table: v.table, rowID: v.rowID, parent: v.parent, column: key.column, onDelete: key.onDelete}
+		if key.column != "" {
+			query := fmt.Sprintf(`SELECT CAST("%s" AS TEXT) FROM "%s" WHERE rowid = ?`, key.column, v.table)
+			if err := db.QueryRow(query, v.rowID).Scan(&o.value); err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+		}
+		orphans = append(orphans, o)
+	}
+	return orphans, nil
+}
+
+// checkBalanceContinuity verifies that each transaction's balance_after is
+// the previous balance plus its amount. Breaks are reported, not repaired:
+// they usually come from backdated or edited transactions and only the user
+// knows which balance is right.
+func (db *DB) checkBalanceContinuity() ([]IntegrityIssue, error) {
+	rows, err := db.Query(`
+		SELECT id, account_id, amount, balance_after
+		FROM transactions
+		ORDER BY account_id, transaction_date, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("checking balances: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	var prevAccount int64
+	var prevBalance float64
+	first := true
+	for rows.Next() {
+		var id, accountID int64
+		var amount, balance float64
+		if err := rows.Scan(&id, &accountID, &amount, &balance); err != nil {
+			return nil, err
+		}
+		if !first && accountID == prevAccount && math.Abs(prevBalance+amount-balance) > balanceTolerance {
+			issues = append(issues, IntegrityIssue{
+				Check: CheckBalanceContinuity,
+				Table: "transactions",
+				RowID: id,
+				Message: fmt.Sprintf("account %d: balance %.2f does not follow previous balance %.2f plus amount %.2f",
+					accountID, balance, prevBalance, amount),
+			})
+		}
+		first = false
+		prevAccount = accountID
+		prevBalance = balance
+	}
+	return issues, rows.Err()
+}
+
+// checkUnmappedHoldings reports broker-synced holdings on accounts that are
+// no longer mapped to a connection. They are handled on the holdings report.
+func (db *DB) checkUnmappedHoldings() ([]IntegrityIssue, error) {
+	rows, err := db.Query(`
+		SELECT h.id, h.account_id, h.symbol
+		FROM holdings h
+		WHERE h.external_id IS NOT NULL AND h.external_id != ''
+		  AND NOT EXISTS (SELECT 1 FROM account_mappings m WHERE m.local_account_id = h.account_id)
+		ORDER BY h.account_id, h.symbol
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("checking holdings: %w", err)
+	}
+	defer rows.Close()
+
+	var issues []IntegrityIssue
+	for rows.Next() {
+		var id, accountID int64
+		var symbol string
+		if err := rows.Scan(&id, &accountID, &symbol); err != nil {
+			return nil, err
+		}
+		issues = append(issues, IntegrityIssue{
+			Check:   CheckUnmappedHoldings,
+			Table:   "holdings",
+			RowID:   id,
+			Message: fmt.Sprintf("synced holding %s on account %d is no longer mapped to a broker connection", symbol, accountID),
+		})
+	}
+	return issues, rows.Err()
+}
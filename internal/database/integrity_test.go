@@ -0,0 +1,126 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newIntegrityTestDB returns a migrated database on a single connection with
+// foreign keys off, so tests can create the orphans a repair must clean up.
+func newIntegrityTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatalf("disabling foreign keys: %v", err)
+	}
+	return db
+}
+
+func mustExec(t *testing.T, db *DB, query string, args ...any) int64 {
+	t.Helper()
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+func TestCheckIntegrity_CleanDatabase(t *testing.T) {
+	db := newIntegrityTestDB(t)
+	userID := mustExec(t, db, `INSERT INTO users (email, password_hash, name) VALUES ('a@example.com', 'x', 'A')`)
+	accountID := mustExec(t, db, `INSERT INTO accounts (user_id, name) VALUES (?, 'Savings')`, userID)
+	mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, 100, 100, '2024-01-01')`, accountID)
+	mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, -30, 70, '2024-02-01')`, accountID)
+
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity() error = %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("CheckIntegrity() issues = %+v; want none", report.Issues)
+	}
+}
+
+func TestCheckIntegrity_FindsAndRepairsOrphans(t *testing.T) {
+	db := newIntegrityTestDB(t)
+	userID := mustExec(t, db, `INSERT INTO users (email, password_hash, name) VALUES ('a@example.com', 'x', 'A')`)
+	categoryID := mustExec(t, db, `INSERT INTO categories (user_id, name) VALUES (?, 'Stocks')`, userID)
+	accountID := mustExec(t, db, `INSERT INTO accounts (user_id, category_id, name) VALUES (?, ?, 'Savings')`, userID, categoryID)
+	goneAccountID := mustExec(t, db, `INSERT INTO accounts (user_id, name) VALUES (?, 'Gone')`, userID)
+	mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, 100, 100, '2024-01-01')`, goneAccountID)
+
+	// Deleting with foreign keys off leaves the transaction and category reference behind
+	mustExec(t, db, `DELETE FROM accounts WHERE id = ?`, goneAccountID)
+	mustExec(t, db, `DELETE FROM categories WHERE id = ?`, categoryID)
+
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity() error = %v", err)
+	}
+	if len(report.Issues) != 2 || report.FixableCount() != 2 {
+		t.Fatalf("CheckIntegrity() issues = %+v; want 2 fixable orphans", report.Issues)
+	}
+
+	repaired, err := db.RepairOrphans()
+	if err != nil || repaired != 2 {
+		t.Fatalf("RepairOrphans() = %d, %v; want 2, nil", repaired, err)
+	}
+
+	var transactions int
+	db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&transactions)
+	if transactions != 0 {
+		t.Errorf("orphaned transaction was kept")
+	}
+	var accounts int
+	db.QueryRow(`SELECT COUNT(*) FROM accounts WHERE id = ? AND category_id IS NULL`, accountID).Scan(&accounts)
+	if accounts != 1 {
+		t.Errorf("account was not kept with its category cleared")
+	}
+
+	report, _ = db.CheckIntegrity()
+	if !report.OK() {
+		t.Errorf("CheckIntegrity() after repair = %+v; want none", report.Issues)
+	}
+}
+
+func TestCheckIntegrity_RepairFollowsCascades(t *testing.T) {
+	db := newIntegrityTestDB(t)
+	userID := mustExec(t, db, `INSERT INTO users (email, password_hash, name) VALUES ('a@example.com', 'x', 'A')`)
+	accountID := mustExec(t, db, `INSERT INTO accounts (user_id, name) VALUES (?, 'Savings')`, userID)
+	mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, 100, 100, '2024-01-01')`, accountID)
+	mustExec(t, db, `DELETE FROM users WHERE id = ?`, userID)
+
+	// Only the account is orphaned until it is removed
+	repaired, err := db.RepairOrphans()
+	if err != nil || repaired != 2 {
+		t.Fatalf("RepairOrphans() = %d, %v; want account and transaction removed", repaired, err)
+	}
+}
+
+func TestCheckIntegrity_ReportsBalanceBreaks(t *testing.T) {
+	db := newIntegrityTestDB(t)
+	userID := mustExec(t, db, `INSERT INTO users (email, password_hash, name) VALUES ('a@example.com', 'x', 'A')`)
+	accountID := mustExec(t, db, `INSERT INTO accounts (user_id, name) VALUES (?, 'Savings')`, userID)
+	mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, 100, 100, '2024-01-01')`, accountID)
+	brokenID := mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, transaction_date) VALUES (?, 50, 120, '2024-02-01')`, accountID)
+
+	report, err := db.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity() error = %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Check != CheckBalanceContinuity || report.Issues[0].RowID != brokenID {
+		t.Fatalf("CheckIntegrity() issues = %+v; want a balance break on transaction %d", report.Issues, brokenID)
+	}
+	if report.FixableCount() != 0 {
+		t.Error("balance breaks should not be repaired automatically")
+	}
+}
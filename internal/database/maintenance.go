@@ -0,0 +1,179 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Maintenance operations.
+const (
+	MaintenanceCheckpoint = "checkpoint"
+	MaintenanceAnalyze    = "analyze"
+	MaintenanceVacuum     = "vacuum"
+)
+
+// MaintenanceOperations lists the maintenance operations in the order a full
+// run performs them: the WAL is folded into the database first so VACUUM
+// rewrites all pages, and statistics are gathered before the rewrite.
+var MaintenanceOperations = []string{MaintenanceCheckpoint, MaintenanceAnalyze, MaintenanceVacuum}
+
+// IsMaintenanceOperation reports whether op is a maintenance operation.
+func IsMaintenanceOperation(op string) bool {
+	for _, known := range MaintenanceOperations {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// StorageStats describes the space the database takes on disk.
+type StorageStats struct {
+	FileSize  int64 // Size of the database file
+	WALSize   int64 // Size of the write-ahead log, 0 if it has none
+	FreeBytes int64 // Unused pages in the database file that VACUUM returns
+}
+
+// MaintenanceStep is the outcome of a maintenance operation.
+type MaintenanceStep struct {
+	Operation string
+	Duration  time.Duration
+	Detail    string
+	Err       error
+}
+
+// Storage returns the space the database takes on disk. The file sizes are
+// 0 for an in-memory database.
+func (db *DB) Storage() (*StorageStats, error) {
+	var pageSize, freePages int64
+	if err := db.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return nil, fmt.Errorf("reading page size: %w", err)
+	}
+	if err := db.QueryRow(`PRAGMA freelist_count`).Scan(&freePages); err != nil {
+		return nil, fmt.Errorf("reading free pages: %w", err)
+	}
+	stats := &StorageStats{FreeBytes: pageSize * freePages}
+
+	path, err := db.path()
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if info, err := os.Stat(path); err == nil {
+			stats.FileSize = info.Size()
+		}
+		if info, err := os.Stat(path + "-wal"); err == nil {
+			stats.WALSize = info.Size()
+		}
+	}
+	return stats, nil
+}
+
+// path returns the file of the main database, or "" if it is in memory.
+func (db *DB) path() (string, error) {
+	rows, err := db.Query(`PRAGMA database_list`)
+	if err != nil {
+		return "", fmt.Errorf("listing databases: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return "", fmt.Errorf("scanning database: %w", err)
+		}
+		if name == "main" {
+			return file, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// Maintain runs a maintenance operation and reports how long it took and
+// what it did. VACUUM rewrites the whole file and blocks writers while it
+// runs, so it is best run when the server is quiet.
+func (db *DB) Maintain(op string) MaintenanceStep {
+	step := MaintenanceStep{Operation: op}
+	start := time.Now()
+	switch op {
+	case MaintenanceCheckpoint:
+		step.Detail, step.Err = db.checkpointWAL()
+	case MaintenanceAnalyze:
+		step.Detail, step.Err = db.analyze()
+	case MaintenanceVacuum:
+		step.Detail, step.Err = db.vacuum()
+	default:
+		step.Err = fmt.Errorf("unknown maintenance operation %q", op)
+	}
+	step.Duration = time.Since(start).Round(time.Millisecond)
+	return step
+}
+
+// checkpointWAL copies the write-ahead log into the database file and
+// truncates it. A checkpoint blocked by a reader is reported, not failed;
+// the next one catches up.
+func (db *DB) checkpointWAL() (string, error) {
+	var busy, logFrames, checkpointed int
+	if err := db.QueryRow(`PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return "", fmt.Errorf("checkpointing WAL: %w", err)
+	}
+	if busy != 0 {
+		return fmt.Sprintf("%d of %d WAL frames copied; readers kept the log from being truncated", checkpointed, logFrames), nil
+	}
+	if logFrames < 0 {
+		return "The database is not in WAL mode", nil
+	}
+	return fmt.Sprintf("%d WAL frames copied and the log truncated", checkpointed), nil
+}
+
+// analyze refreshes the statistics the query planner chooses indexes by.
+func (db *DB) analyze() (string, error) {
+	if _, err := db.Exec(`ANALYZE`); err != nil {
+		return "", fmt.Errorf("analyzing: %w", err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(DISTINCT tbl) FROM sqlite_stat1`).Scan(&tables); err != nil {
+		return "Statistics updated", nil
+	}
+	return fmt.Sprintf("Statistics updated for %d tables", tables), nil
+}
+
+// vacuum rebuilds the database file without its free pages. In WAL mode the
+// rebuilt pages go through the log, so it is checkpointed to shrink the file.
+func (db *DB) vacuum() (string, error) {
+	before, err := db.Storage()
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return "", fmt.Errorf("vacuuming: %w", err)
+	}
+	if _, err := db.checkpointWAL(); err != nil {
+		return "", err
+	}
+	after, err := db.Storage()
+	if err != nil {
+		return "", err
+	}
+	if before.FileSize == 0 {
+		return fmt.Sprintf("%s of free pages returned", FormatBytes(before.FreeBytes)), nil
+	}
+	return fmt.Sprintf("File size went from %s to %s", FormatBytes(before.FileSize), FormatBytes(after.FileSize)), nil
+}
+
+// FormatBytes returns a size in bytes in the largest unit of at least one,
+// such as "1.5 MB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, exp := float64(n)/unit, 0
+	for size >= unit && exp < 3 {
+		size /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", size, "KMGT"[exp])
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaintain_ReclaimsFreePagesAndTruncatesWAL(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+
+	userID := mustExec(t, db, `INSERT INTO users (email, password_hash, name) VALUES ('a@example.com', 'x', 'A')`)
+	accountID := mustExec(t, db, `INSERT INTO accounts (user_id, name) VALUES (?, 'Savings')`, userID)
+	for i := 0; i < 2000; i++ {
+		mustExec(t, db, `INSERT INTO transactions (account_id, amount, balance_after, description, transaction_date) VALUES (?, 1, 1, ?, '2024-01-01')`,
+			accountID, strings.Repeat("padding ", 20))
+	}
+	mustExec(t, db, `DELETE FROM transactions`)
+
+	for _, op := range MaintenanceOperations {
+		if step := db.Maintain(op); step.Err != nil || step.Detail == "" {
+			t.Fatalf("Maintain(%s) = %+v; want a detail and no error", op, step)
+		}
+	}
+
+	stats, err := db.Storage()
+	if err != nil {
+		t.Fatalf("Storage() error = %v", err)
+	}
+	if stats.FileSize == 0 || stats.FreeBytes != 0 || stats.WALSize != 0 {
+		t.Errorf("storage after maintenance = %+v; want a file without free pages or WAL", stats)
+	}
+
+	if step := db.Maintain("defrag"); step.Err == nil {
+		t.Error("Maintain accepted an unknown operation")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		1536:            "1.5 KB",
+		5 * 1024 * 1024: "5.0 MB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -313,3 +313,843 @@ CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id);
 CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
 CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
 `
+
+// migrationHoldingHistory archives holdings removed by a broker sync so they
+// can be reviewed and restored if the broker returned an incomplete snapshot.
+const migrationHoldingHistory = `
+CREATE TABLE IF NOT EXISTS holding_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    external_id TEXT,
+    symbol TEXT NOT NULL,
+    name TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    avg_price REAL,
+    current_price REAL,
+    current_value REAL NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'DKK',
+    instrument_type TEXT,
+    last_updated DATETIME,
+    reason TEXT NOT NULL,
+    deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    restored_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_holding_history_account ON holding_history(account_id, deleted_at DESC);
+`
+
+// migrationMitIDAttempts tracks MitID authentication attempts per broker
+// connection, used to enforce a cool-down after consecutive failures.
+const migrationMitIDAttempts = `
+CREATE TABLE IF NOT EXISTS mitid_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    connection_id INTEGER NOT NULL REFERENCES broker_connections(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error_message TEXT,
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_mitid_attempts_connection ON mitid_attempts(connection_id, started_at DESC);
+`
+
+// migrationRebalanceSessions stores rebalancing suggestions the user chose to
+// execute, with a checklist of the suggested trades.
+const migrationRebalanceSessions = `
+CREATE TABLE IF NOT EXISTS rebalance_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type TEXT NOT NULL,
+    new_money REAL NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_rebalance_sessions_user ON rebalance_sessions(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS rebalance_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL REFERENCES rebalance_sessions(id) ON DELETE CASCADE,
+    target_key TEXT NOT NULL,
+    target_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    amount REAL NOT NULL,
+    current_pct REAL NOT NULL,
+    target_pct REAL NOT NULL,
+    completed INTEGER NOT NULL DEFAULT 0,
+    completed_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_rebalance_actions_session ON rebalance_actions(session_id);
+`
+
+// migrationManualExchangeRates stores exchange rates entered by users for
+// currencies the rate provider does not cover. valid_to is NULL while the
+// rate is open-ended.
+const migrationManualExchangeRates = `
+CREATE TABLE IF NOT EXISTS manual_exchange_rates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency TEXT NOT NULL,
+    to_currency TEXT NOT NULL,
+    rate REAL NOT NULL,
+    valid_from DATE NOT NULL,
+    valid_to DATE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_manual_exchange_rates_user ON manual_exchange_rates(user_id, from_currency, to_currency);
+`
+
+// migrationNetWorthMilestones records when net worth first crossed each
+// multiple of the user's milestone step.
+const migrationNetWorthMilestones = `
+CREATE TABLE IF NOT EXISTS net_worth_milestones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount REAL NOT NULL,
+    reached_at DATE NOT NULL,
+    celebrated INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, amount)
+);
+`
+
+// migrationAddHeldDeletionsSince records when a sync kept stale holdings
+// because removing them exceeded the deletion safety threshold.
+const migrationAddHeldDeletionsSince = `
+ALTER TABLE account_mappings ADD COLUMN held_deletions_since DATETIME;
+`
+
+// migrationAddHideDecimals adds the hide_decimals display preference to users.
+const migrationAddHideDecimals = `
+ALTER TABLE users ADD COLUMN hide_decimals INTEGER DEFAULT 0;
+`
+
+// migrationAddTransactionCategory lets a transaction override the category
+// of its account, e.g. a dividend landing in a cash account.
+const migrationAddTransactionCategory = `
+ALTER TABLE transactions ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+`
+
+// migrationAddCategoryExpectedReturn adds the expected annual return (percent)
+// used to project the value of a category's accounts.
+const migrationAddCategoryExpectedReturn = `
+ALTER TABLE categories ADD COLUMN expected_return REAL;
+`
+
+// migrationAddMilestoneStep adds the net worth milestone interval to users.
+const migrationAddMilestoneStep = `
+ALTER TABLE users ADD COLUMN milestone_step REAL DEFAULT 100000;
+`
+
+// migrationHoldingAcquisitions stores imported buy transactions, used to
+// recompute the average price of a holding independently of the broker.
+const migrationHoldingAcquisitions = `
+CREATE TABLE IF NOT EXISTS holding_acquisitions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    trade_date DATE NOT NULL,
+    quantity REAL NOT NULL,
+    price REAL NOT NULL,
+    fees REAL NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_holding_acquisitions_account ON holding_acquisitions(account_id, symbol);
+`
+
+// migrationAddHoldingCostBasisMode selects whether a holding's P/L uses the
+// broker's average price or the one recomputed from acquisitions.
+const migrationAddHoldingCostBasisMode = `
+ALTER TABLE holdings ADD COLUMN cost_basis_mode TEXT NOT NULL DEFAULT 'broker';
+`
+
+// migrationAddAccountOpenedAt records when an account was opened; earlier
+// balances are treated as its opening balance.
+const migrationAddAccountOpenedAt = `
+ALTER TABLE accounts ADD COLUMN opened_at DATE;
+`
+
+// migrationAddAccountClosedAt records when an account was closed; it stops
+// counting towards net worth from that date.
+const migrationAddAccountClosedAt = `
+ALTER TABLE accounts ADD COLUMN closed_at DATE;
+`
+
+// migrationAppVersions records each release the app has started with, the
+// schema version it ran and whether its what's new notice was dismissed.
+const migrationAppVersions = `
+CREATE TABLE IF NOT EXISTS app_versions (
+    version TEXT PRIMARY KEY,
+    schema_version INTEGER NOT NULL,
+    first_started_at DATETIME NOT NULL,
+    notice_dismissed INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// migrationAddUserSeenVersion stores the last release whose what's new page
+// the user has seen.
+const migrationAddUserSeenVersion = `
+ALTER TABLE users ADD COLUMN seen_version TEXT NOT NULL DEFAULT '';
+`
+
+// migrationSyncDiagnostics keeps the redacted broker request trail of failed
+// and partial syncs for diagnostic bundles.
+const migrationSyncDiagnostics = `
+CREATE TABLE IF NOT EXISTS sync_diagnostics (
+    sync_history_id INTEGER PRIMARY KEY REFERENCES sync_history(id) ON DELETE CASCADE,
+    requests TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrationAddHeldBalance and migrationAddHeldBalanceAt store a synced
+// balance that deviated too far from the account's trend, pending user
+// confirmation.
+const migrationAddHeldBalance = `
+ALTER TABLE account_mappings ADD COLUMN held_balance REAL;
+`
+
+const migrationAddHeldBalanceAt = `
+ALTER TABLE account_mappings ADD COLUMN held_balance_at DATETIME;
+`
+
+// migrationWatchlist stores instruments a user is watching but does not own.
+const migrationWatchlist = `
+CREATE TABLE IF NOT EXISTS watchlist_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    name TEXT NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'DKK',
+    instrument_type TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    added_price REAL,
+    current_price REAL,
+    price_source TEXT NOT NULL DEFAULT '',
+    price_updated_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, symbol)
+);
+`
+
+// migrationAddUserBalanceDescription and migrationAddUserSyncDescription store
+// the user's templates for system-generated transaction descriptions.
+const migrationAddUserBalanceDescription = `
+ALTER TABLE users ADD COLUMN balance_description TEXT NOT NULL DEFAULT '';
+`
+
+const migrationAddUserSyncDescription = `
+ALTER TABLE users ADD COLUMN sync_description TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAccountAPIKeys stores API keys that may only update the balance
+// and add transactions of a single account. Only a hash of the key is kept.
+const migrationAccountAPIKeys = `
+CREATE TABLE IF NOT EXISTS account_api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    key_prefix TEXT NOT NULL,
+    last_used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_account_api_keys_user ON account_api_keys(user_id);
+`
+
+// migrationEmailDigests records the digests sent to each user, with a
+// snapshot of the figures they reported so the next digest can show what
+// changed since.
+const migrationEmailDigests = `
+CREATE TABLE IF NOT EXISTS email_digests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sent_at DATETIME NOT NULL,
+    snapshot TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_digests_user ON email_digests(user_id, sent_at);
+`
+
+// migrationAddUserDigestFrequency stores how often the user gets the email
+// digest: "weekly", "monthly" or "off".
+const migrationAddUserDigestFrequency = `
+ALTER TABLE users ADD COLUMN digest_frequency TEXT NOT NULL DEFAULT 'off';
+`
+
+// migrationBrokerPerformance stores the returns brokers report for mapped
+// accounts, one row per standard period, replaced on every sync.
+const migrationBrokerPerformance = `
+CREATE TABLE IF NOT EXISTS broker_performance (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mapping_id INTEGER NOT NULL REFERENCES account_mappings(id) ON DELETE CASCADE,
+    period TEXT NOT NULL,
+    return_fraction REAL NOT NULL,
+    fetched_at DATETIME NOT NULL,
+    UNIQUE(mapping_id, period)
+);
+`
+
+// migrationGoalSnapshots stores a goal's progress once a week so its detail
+// page can chart the progress over time.
+const migrationGoalSnapshots = `
+CREATE TABLE IF NOT EXISTS goal_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    goal_id INTEGER NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    week_start DATE NOT NULL,
+    current_worth REAL NOT NULL,
+    target_amount REAL NOT NULL,
+    recorded_at DATETIME NOT NULL,
+    UNIQUE(goal_id, week_start)
+);
+`
+
+// migrationAddUserTimezone stores the IANA time zone times are shown in;
+// empty uses the server's time zone.
+const migrationAddUserTimezone = `
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddUserExpiresAt marks temporary users, such as sandbox users of
+// the public demo, which are deleted once it has passed.
+const migrationAddUserExpiresAt = `
+ALTER TABLE users ADD COLUMN expires_at DATETIME;
+`
+
+// migrationAddConnectionSyncWindowStart stores the hour, in the broker's
+// market time zone, from which unattended syncs of a connection may start.
+const migrationAddConnectionSyncWindowStart = `
+ALTER TABLE broker_connections ADD COLUMN sync_window_start INTEGER NOT NULL DEFAULT 0;
+`
+
+// migrationAddConnectionSyncWindowEnd stores the hour before which unattended
+// syncs of a connection must start; 24 is the end of the day.
+const migrationAddConnectionSyncWindowEnd = `
+ALTER TABLE broker_connections ADD COLUMN sync_window_end INTEGER NOT NULL DEFAULT 24;
+`
+
+// migrationAddConnectionSkipWeekends stops unattended syncs of a connection
+// on Saturdays and Sundays.
+const migrationAddConnectionSkipWeekends = `
+ALTER TABLE broker_connections ADD COLUMN skip_weekends INTEGER NOT NULL DEFAULT 0;
+`
+
+// migrationAddConnectionSyncSchedule stores when a connection is synced
+// unattended: "daily", "weekly" or a cron expression; empty syncs only on
+// request.
+const migrationAddConnectionSyncSchedule = `
+ALTER TABLE broker_connections ADD COLUMN sync_schedule TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddAccountInterestRate stores the annual interest rate of a
+// liability in percent; 0 accrues no interest.
+const migrationAddAccountInterestRate = `
+ALTER TABLE accounts ADD COLUMN interest_rate REAL NOT NULL DEFAULT 0;
+`
+
+// migrationInterestAccruals records the monthly interest posted to
+// liabilities, so each month is posted once and interest can be told apart
+// from payments. The row outlives a deleted posting, which is then not
+// posted again.
+const migrationInterestAccruals = `
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    transaction_id INTEGER REFERENCES transactions(id) ON DELETE SET NULL,
+    period_end DATE NOT NULL,
+    rate REAL NOT NULL,
+    amount REAL NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(account_id, period_end)
+);
+`
+
+// migrationAddCategoryLiquidity stores how quickly the accounts of a category
+// can be turned into cash: liquid, illiquid or locked; empty is unclassified.
+const migrationAddCategoryLiquidity = `
+ALTER TABLE categories ADD COLUMN liquidity TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAnalyticsExclusions stores the instruments, by ISIN or ticker, a
+// user leaves out of portfolio analytics, such as employer shares under
+// lockup. Account values still include them.
+const migrationAnalyticsExclusions = `
+CREATE TABLE IF NOT EXISTS analytics_exclusions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, symbol)
+);
+`
+
+// migrationNotificationChannels stores where a user's alerts are delivered:
+// an ntfy topic, a Gotify server or a Slack or Discord webhook.
+const migrationNotificationChannels = `
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    topic TEXT NOT NULL DEFAULT '',
+    token TEXT NOT NULL DEFAULT '',
+    is_active INTEGER NOT NULL DEFAULT 1,
+    last_sent_at DATETIME,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_notification_channels_user ON notification_channels(user_id);
+`
+
+// migrationUsageCounts counts a user's broker syncs, market data refreshes
+// and API calls per day, for monthly quotas.
+const migrationUsageCounts = `
+CREATE TABLE IF NOT EXISTS usage_counts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day, kind)
+);
+`
+
+// migrationAddAccountSortOrder stores the position of an account in the
+// user's own order, from 1; 0 is an account never reordered.
+const migrationAddAccountSortOrder = `
+ALTER TABLE accounts ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+`
+
+// migrationAddAccountPinned marks accounts shown first in lists and on the
+// dashboard.
+const migrationAddAccountPinned = `
+ALTER TABLE accounts ADD COLUMN is_pinned INTEGER NOT NULL DEFAULT 0;
+`
+
+// migrationAddAccountUpdatedAt records when an account was last edited, so
+// edits made against an older version are rejected. NULL until first edited.
+const migrationAddAccountUpdatedAt = `
+ALTER TABLE accounts ADD COLUMN updated_at DATETIME;
+`
+
+// migrationAddTransactionUpdatedAt records when a transaction was last
+// edited. NULL until first edited.
+const migrationAddTransactionUpdatedAt = `
+ALTER TABLE transactions ADD COLUMN updated_at DATETIME;
+`
+
+// migrationAddGoalUpdatedAt records when a goal was last edited. NULL until
+// first edited.
+const migrationAddGoalUpdatedAt = `
+ALTER TABLE goals ADD COLUMN updated_at DATETIME;
+`
+
+// migrationAddConnectionProxyURL stores the outbound proxy a connection's
+// broker requests go through. Empty uses the global BROKER_PROXY_URL.
+const migrationAddConnectionProxyURL = `
+ALTER TABLE broker_connections ADD COLUMN proxy_url TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddConnectionUserAgent stores the User-Agent of a connection's
+// broker requests. Empty uses the global BROKER_USER_AGENT.
+const migrationAddConnectionUserAgent = `
+ALTER TABLE broker_connections ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddConnectionAPIURL stores the base URL of the bank API a PSD2
+// connection syncs from.
+const migrationAddConnectionAPIURL = `
+ALTER TABLE broker_connections ADD COLUMN api_url TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddUserPeriodStartDay stores the day of the month a user's
+// statement periods start on, such as the 25th when salary arrives then.
+const migrationAddUserPeriodStartDay = `
+ALTER TABLE users ADD COLUMN period_start_day INTEGER NOT NULL DEFAULT 1;
+`
+
+// migrationAddGoalMonthlyContribution stores how much the user plans to put
+// into a goal each month; 0 means no plan.
+const migrationAddGoalMonthlyContribution = `
+ALTER TABLE goals ADD COLUMN monthly_contribution REAL NOT NULL DEFAULT 0;
+`
+
+// migrationAddChannelSecretsEncrypted marks notification channels whose URL
+// and token are encrypted. Channels saved before are encrypted on startup.
+const migrationAddChannelSecretsEncrypted = `
+ALTER TABLE notification_channels ADD COLUMN secrets_encrypted INTEGER NOT NULL DEFAULT 0;
+`
+
+// migrationAddMilestoneRecordedStep records the milestone step a milestone
+// was reached under, so changing the step does not celebrate again. Recorded
+// milestones get the current step of their user.
+const migrationAddMilestoneRecordedStep = `
+ALTER TABLE net_worth_milestones ADD COLUMN step REAL;
+UPDATE net_worth_milestones SET step = (SELECT COALESCE(milestone_step, 100000) FROM users WHERE users.id = net_worth_milestones.user_id);
+`
+
+// migrationHoldingSnapshots stores the quantity and value of each holding at
+// the end of every day it changed, with a zero quantity once removed, so
+// holdings can be compared between dates.
+const migrationHoldingSnapshots = `
+CREATE TABLE IF NOT EXISTS holding_snapshots (
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    name TEXT NOT NULL,
+    day DATE NOT NULL,
+    quantity REAL NOT NULL,
+    current_value REAL NOT NULL,
+    PRIMARY KEY (account_id, symbol, day)
+);
+`
+
+// migrationAddTransactionKind separates balance updates, which revalue an
+// account, from money moved in or out. Existing transactions are only marked
+// as revaluations when their source shows it: interest booked by an accrual,
+// or a transaction recorded on a synced account while one of its connection's
+// syncs ran. Descriptions can be edited, so other transactions stay flows.
+var migrationAddTransactionKind = `
+ALTER TABLE transactions ADD COLUMN kind TEXT NOT NULL DEFAULT '';
+UPDATE transactions SET kind = 'valuation'
+WHERE id IN (SELECT transaction_id FROM interest_accruals WHERE transaction_id IS NOT NULL)
+   OR EXISTS (
+       SELECT 1 FROM account_mappings m
+       JOIN sync_history h ON h.connection_id = m.connection_id
+       WHERE m.local_account_id = transactions.account_id
+         AND julianday(transactions.created_at)
+             BETWEEN ` + julianDriverTime("h.started_at") + ` - 1.0 / 86400
+             AND ` + julianDriverTime("COALESCE(h.completed_at, h.started_at)") + ` + 1.0 / 86400
+   );
+`
+
+// julianDriverTime returns SQL for the Julian day of a column holding a time
+// written by the database driver, such as "2024-03-10 12:00:00.5 +0100 CET",
+// which SQLite's date functions do not read as is.
+func julianDriverTime(column string) string {
+	zone := "instr(substr(" + column + ", 20), ' ') + 20"
+	return "julianday(substr(" + column + ", 1, 19) || substr(" + column + ", " + zone + ", 3) || ':' || substr(" + column + ", " + zone + " + 3, 2))"
+}
+
+// migrationCurrencyRateHistory keeps the last fetched rate of each currency
+// pair per day, where currency_rates only has the latest, so changes in net
+// worth from exchange rates can be told apart.
+const migrationCurrencyRateHistory = `
+CREATE TABLE IF NOT EXISTS currency_rate_history (
+    from_currency TEXT NOT NULL,
+    to_currency TEXT NOT NULL,
+    day DATE NOT NULL,
+    rate REAL NOT NULL,
+    PRIMARY KEY (from_currency, to_currency, day)
+);
+`
+
+// migrationHoldingLabels stores the label, such as "core" or "satellite", a
+// user gives an instrument, by ISIN or ticker, to group holdings within and
+// across depots by strategy.
+const migrationHoldingLabels = `
+CREATE TABLE IF NOT EXISTS holding_labels (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    label TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, symbol)
+);
+`
+
+// migrationAddConnectionLastSuccessAt records when a connection last synced
+// successfully, which shows its broker login still works. Connections whose
+// last sync succeeded start from that sync.
+const migrationAddConnectionLastSuccessAt = `
+ALTER TABLE broker_connections ADD COLUMN last_success_at DATETIME;
+UPDATE broker_connections SET last_success_at = last_sync_at WHERE last_sync_status = 'success';
+`
+
+// migrationAddConnectionCredentialRemindedAt records when the owner of a
+// connection was last reminded to log in to the broker again.
+const migrationAddConnectionCredentialRemindedAt = `
+ALTER TABLE broker_connections ADD COLUMN credential_reminded_at DATETIME;
+`
+
+// migrationAccountSnapshots stores the balance and holdings of an account at
+// the moment its owner takes a snapshot, such as right before a large trade,
+// so the state before and after can be compared exactly.
+const migrationAccountSnapshots = `
+CREATE TABLE IF NOT EXISTS account_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    balance REAL NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    taken_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_snapshots_account ON account_snapshots(account_id, taken_at);
+
+CREATE TABLE IF NOT EXISTS account_snapshot_holdings (
+    snapshot_id INTEGER NOT NULL REFERENCES account_snapshots(id) ON DELETE CASCADE,
+    symbol TEXT NOT NULL,
+    name TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    current_price REAL NOT NULL,
+    current_value REAL NOT NULL,
+    currency TEXT NOT NULL,
+    PRIMARY KEY (snapshot_id, symbol)
+);
+`
+
+// migrationCategorizationRules stores the rules that categorize imported and
+// synced transactions. A transaction matching a rule's description pattern,
+// payee and amount range gets its category, tag and kind; rules are tried in
+// order of priority.
+const migrationCategorizationRules = `
+CREATE TABLE IF NOT EXISTS categorization_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    pattern TEXT NOT NULL DEFAULT '',
+    payee TEXT NOT NULL DEFAULT '',
+    min_amount REAL,
+    max_amount REAL,
+    category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    tag TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL DEFAULT '',
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_categorization_rules_user ON categorization_rules(user_id, priority);
+`
+
+// migrationAddTransactionTag stores a free-form tag on a transaction, such as
+// "salary" or "rent", set by categorization rules.
+const migrationAddTransactionTag = `
+ALTER TABLE transactions ADD COLUMN tag TEXT NOT NULL DEFAULT '';
+`
+
+// migrationChartColors stores the chart color assigned to an asset type,
+// currency, label or uncategorized account, so it has the same color on every
+// chart. Categories keep their own color, which accounts derive theirs from.
+const migrationChartColors = `
+CREATE TABLE IF NOT EXISTS chart_colors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity_type TEXT NOT NULL,
+    entity_key TEXT NOT NULL,
+    color TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, entity_type, entity_key)
+);
+`
+
+// migrationRetentionPolicies stores how long an admin keeps the rows of a
+// history table. Rows older than keep_days are deleted, and daily or weekly
+// rows older than thin_after_days are thinned to the last one of each month.
+// Zero keeps rows forever.
+const migrationRetentionPolicies = `
+CREATE TABLE IF NOT EXISTS retention_policies (
+    table_name TEXT PRIMARY KEY,
+    keep_days INTEGER NOT NULL DEFAULT 0,
+    thin_after_days INTEGER NOT NULL DEFAULT 0,
+    last_run_at DATETIME,
+    last_deleted INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrationTaxParameters stores the Danish tax figures of each year, such as
+// the ASK deposit ceiling and the stock income threshold, which change every
+// year. Rates are percentages.
+const migrationTaxParameters = `
+CREATE TABLE IF NOT EXISTS tax_parameters (
+    year INTEGER PRIMARY KEY,
+    ask_max_deposit REAL NOT NULL,
+    stock_gain_threshold REAL NOT NULL,
+    stock_gain_low_rate REAL NOT NULL,
+    stock_gain_high_rate REAL NOT NULL,
+    ask_tax_rate REAL NOT NULL,
+    pension_withdrawal_rate REAL NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrationPendingInvestments stores the savings plan buys of an account that
+// were debited but not yet executed, as last reported by its broker. They
+// are included in the synced account value.
+const migrationPendingInvestments = `
+CREATE TABLE IF NOT EXISTS pending_investments (
+    account_id INTEGER PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    amount REAL NOT NULL,
+    order_count INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrationLoginLinks stores the one-time login links admins create for
+// locked-out users. Only a hash of the link's token is kept. session_id is
+// the session the link was used for, which may set a new password without
+// the current one.
+const migrationLoginLinks = `
+CREATE TABLE IF NOT EXISTS login_links (
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    session_id TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_links_user ON login_links(user_id);
+CREATE INDEX IF NOT EXISTS idx_login_links_session ON login_links(session_id);
+`
+
+// migrationSharedState holds short-lived state instances serving the app
+// share when STATE_STORE is sqlite, such as rate limit counters and cached
+// broker sessions. Values are encrypted. expires_at is in Unix milliseconds;
+// rows past it are ignored and removed by housekeeping.
+const migrationSharedState = `
+CREATE TABLE IF NOT EXISTS shared_state (
+    key TEXT PRIMARY KEY,
+    value BLOB NOT NULL,
+    expires_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_shared_state_expires ON shared_state(expires_at);
+`
+
+// migrationAPITokens stores API tokens that let scripts act as their user,
+// read-only or read-write. Only a hash of the token is kept.
+const migrationAPITokens = `
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'read',
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    last_used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+`
+
+// migrationAdvisorShares stores the accounts and goals a user shares with
+// another user, such as a financial advisor, who may read and comment on
+// them. Each row shares either an account or a goal.
+const migrationAdvisorShares = `
+CREATE TABLE IF NOT EXISTS advisor_shares (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    advisor_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE,
+    goal_id INTEGER REFERENCES goals(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account_id IS NULL) != (goal_id IS NULL)),
+    UNIQUE(advisor_id, account_id),
+    UNIQUE(advisor_id, goal_id)
+);
+CREATE INDEX IF NOT EXISTS idx_advisor_shares_owner ON advisor_shares(owner_id);
+`
+
+// migrationComments stores the comment threads of accounts and goals. Each
+// comment belongs to either an account or a goal.
+const migrationComments = `
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE,
+    goal_id INTEGER REFERENCES goals(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account_id IS NULL) != (goal_id IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_comments_account ON comments(account_id);
+CREATE INDEX IF NOT EXISTS idx_comments_goal ON comments(goal_id);
+`
+
+// migrationSavedFilters stores the named transaction filters of users. A
+// filter on an account or category goes with it, rather than widening to all
+// transactions.
+const migrationSavedFilters = `
+CREATE TABLE IF NOT EXISTS saved_filters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE,
+    category_id INTEGER REFERENCES categories(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL DEFAULT '',
+    min_amount REAL,
+    max_amount REAL,
+    date_from TEXT,
+    date_to TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+`
+
+// migrationFeatureFlags stores which experimental features admins turned on
+// or off, for everyone (user_id NULL) or for single users.
+const migrationFeatureFlags = `
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    enabled INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feature_flags_name_user ON feature_flags(name, IFNULL(user_id, 0));
+`
+
+// migrationNetWorthSnapshots stores each user's assets, liabilities and net
+// worth at the end of every day, with the assets per category, so history
+// can be charted without replaying every transaction.
+const migrationNetWorthSnapshots = `
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    assets REAL NOT NULL,
+    liabilities REAL NOT NULL,
+    net_worth REAL NOT NULL,
+    recorded_at DATETIME NOT NULL,
+    UNIQUE(user_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS net_worth_snapshot_categories (
+    snapshot_id INTEGER NOT NULL REFERENCES net_worth_snapshots(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    total REAL NOT NULL,
+    PRIMARY KEY (snapshot_id, category_id)
+);
+`
+
+// migrationGoalFundingAccounts links goals to the accounts saved into for
+// them, so a goal can show what was contributed this month.
+const migrationGoalFundingAccounts = `
+CREATE TABLE IF NOT EXISTS goal_funding_accounts (
+    goal_id INTEGER NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    PRIMARY KEY (goal_id, account_id)
+);
+`
+
+// migrationAddAccountNetWorthGroup stores the net worth group of an account,
+// such as pension or home, which the dashboard can leave out of net worth.
+const migrationAddAccountNetWorthGroup = `
+ALTER TABLE accounts ADD COLUMN net_worth_group TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddUserNetWorthExclusions stores the comma-separated net worth
+// groups a user leaves out of the dashboard's net worth.
+const migrationAddUserNetWorthExclusions = `
+ALTER TABLE users ADD COLUMN net_worth_exclusions TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddCategoryDescription stores the Markdown description of a
+// category.
+const migrationAddCategoryDescription = `
+ALTER TABLE categories ADD COLUMN description TEXT NOT NULL DEFAULT '';
+`
+
+// migrationAddGoalDescription stores the Markdown description of a goal.
+const migrationAddGoalDescription = `
+ALTER TABLE goals ADD COLUMN description TEXT NOT NULL DEFAULT '';
+`
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// replicaClearedTables hold sessions and broker traffic, which have no
+// analytics value. Their rows are deleted from replicas; the tables are kept
+// so the schema matches the live database.
+var replicaClearedTables = []string{
+	"sessions",
+	"broker_sessions",
+	"shared_state",
+	"sync_diagnostics",
+}
+
+// replicaSecretColumns are blanked in replicas, with the value they are set
+// to. Columns missing from the schema, such as the legacy broker password
+// columns on databases where dropping them succeeded, are skipped.
+var replicaSecretColumns = map[string]map[string]string{
+	"users": {
+		"password_hash": "''",
+	},
+	"broker_connections": {
+		"username":                "''",
+		"cpr":                     "NULL",
+		"refresh_token_encrypted": "NULL",
+		"app_key":                 "NULL",
+		"app_secret":              "NULL",
+		"password_encrypted":      "NULL",
+		"encryption_iv":           "NULL",
+		"proxy_url":               "''",
+	},
+	"notification_channels": {
+		"url":   "''",
+		"token": "''",
+	},
+	"audit_log": {
+		"ip_address": "NULL",
+		"user_agent": "NULL",
+	},
+}
+
+// ExportReplica writes a consistent copy of the database to path with
+// credentials, sessions and broker traffic removed, for analytics tools.
+// Row IDs are kept so the copy can be joined like the live database. The
+// copy is built next to path and renamed into place, so readers never see a
+// partial file, and is made read-only.
+func (db *DB) ExportReplica(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating replica directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing stale replica: %w", err)
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, tmp); err != nil {
+		return fmt.Errorf("copying database: %w", err)
+	}
+
+	if err := sanitizeReplica(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0444); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("making replica read-only: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replacing replica: %w", err)
+	}
+	return nil
+}
+
+// sanitizeReplica strips secrets from the copy at path, then vacuums it so
+// the removed values do not linger in free pages.
+func sanitizeReplica(path string) error {
+	replica, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("opening replica: %w", err)
+	}
+	defer replica.Close()
+
+	for _, table := range replicaClearedTables {
+		if _, err := replica.Exec(`DELETE FROM ` + table); err != nil {
+			return fmt.Errorf("clearing %s: %w", table, err)
+		}
+	}
+
+	for table, columns := range replicaSecretColumns {
+		existing, err := tableColumns(replica, table)
+		if err != nil {
+			return fmt.Errorf("reading columns of %s: %w", table, err)
+		}
+		for column, value := range columns {
+			if !existing[column] {
+				continue
+			}
+			if _, err := replica.Exec(fmt.Sprintf(`UPDATE %s SET %s = %s`, table, column, value)); err != nil {
+				return fmt.Errorf("clearing %s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	if _, err := replica.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("compacting replica: %w", err)
+	}
+	return nil
+}
+
+// tableColumns returns the set of column names of a table.
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,140 @@
+// Package dates formats dates and times for display, following the user's
+// number format preference and time zone.
+package dates
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Time zones work in containers without zoneinfo
+)
+
+// layouts holds the date and date-time layouts of each number format
+// preference.
+var layouts = map[string]struct{ date, dateTime string }{
+	"da": {"02-01-2006", "02-01-2006 15:04"},
+	"en": {"01/02/2006", "01/02/2006 3:04 PM"},
+	"de": {"02.01.2006", "02.01.2006 15:04"},
+	"fr": {"02/01/2006", "02/01/2006 15:04"},
+}
+
+// layoutsFor returns the layouts of a number format, defaulting to Danish.
+func layoutsFor(format string) (date, dateTime string) {
+	l, ok := layouts[format]
+	if !ok {
+		l = layouts["da"]
+	}
+	return l.date, l.dateTime
+}
+
+// Location returns the time zone of an IANA name, such as
+// "Europe/Copenhagen". An empty or unknown name gives the server's zone.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ValidTimezone reports whether name is empty or a known IANA time zone.
+func ValidTimezone(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// FormatDate formats the calendar date of t in a number format's layout:
+// 31-01-2024 for "da" and 01/31/2024 for "en". Dates are shown as stored,
+// without time zone conversion, since dates such as transaction dates are
+// stored as midnight UTC.
+func FormatDate(t time.Time, format string) string {
+	if t.IsZero() {
+		return ""
+	}
+	date, _ := layoutsFor(format)
+	return t.Format(date)
+}
+
+// FormatDateTime formats t in a number format's layout, converted to the
+// time zone of the given IANA name.
+func FormatDateTime(t time.Time, format, timezone string) string {
+	if t.IsZero() {
+		return ""
+	}
+	_, dateTime := layoutsFor(format)
+	return t.In(Location(timezone)).Format(dateTime)
+}
+
+// MaxPeriodStartDay is the latest day of the month statement periods can
+// start on, so that every month has the day.
+const MaxPeriodStartDay = 28
+
+// PeriodStart returns the first day, at midnight in t's location, of the
+// statement period containing t, for periods starting on day of the month.
+// With day 25, 10 March is in the period from 25 February. Days outside 1
+// to MaxPeriodStartDay give calendar months.
+func PeriodStart(t time.Time, day int) time.Time {
+	if day < 1 || day > MaxPeriodStartDay {
+		day = 1
+	}
+	start := time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location())
+	if t.Day() < day {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// PeriodEnd returns the last day of the statement period that starts on
+// start.
+func PeriodEnd(start time.Time) time.Time {
+	return start.AddDate(0, 1, -1)
+}
+
+// Relative describes how long before or after now t is, such as
+// "2 days ago" or "in 3 hours".
+func Relative(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	var amount string
+	switch {
+	case d < time.Hour:
+		amount = plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		amount = plural(int(d/time.Hour), "hour")
+	case d < 48*time.Hour:
+		if future {
+			return "tomorrow"
+		}
+		return "yesterday"
+	case d < 30*24*time.Hour:
+		amount = plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		amount = plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		amount = plural(int(d/(365*24*time.Hour)), "year")
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// plural returns n with a unit, pluralized unless n is 1.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
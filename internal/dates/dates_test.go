@@ -0,0 +1,87 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"da": "31-01-2024",
+		"en": "01/31/2024",
+		"de": "31.01.2024",
+		"fr": "31/01/2024",
+		"":   "31-01-2024",
+	}
+	for format, want := range tests {
+		if got := FormatDate(date, format); got != want {
+			t.Errorf("FormatDate(%q) = %q; want %q", format, got, want)
+		}
+	}
+	if got := FormatDate(time.Time{}, "da"); got != "" {
+		t.Errorf("FormatDate(zero) = %q; want empty", got)
+	}
+}
+
+func TestFormatDateTime_ConvertsToTimezone(t *testing.T) {
+	ts := time.Date(2024, 1, 31, 23, 30, 0, 0, time.UTC)
+	if got := FormatDateTime(ts, "da", "Europe/Copenhagen"); got != "01-02-2024 00:30" {
+		t.Errorf("FormatDateTime(da, Copenhagen) = %q", got)
+	}
+	if got := FormatDateTime(ts, "en", "America/New_York"); got != "01/31/2024 6:30 PM" {
+		t.Errorf("FormatDateTime(en, New York) = %q", got)
+	}
+	if !ValidTimezone("Europe/Copenhagen") || !ValidTimezone("") || ValidTimezone("Mars/Base") {
+		t.Error("ValidTimezone() misjudged a time zone")
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		t     time.Time
+		start int
+		want  time.Time
+	}{
+		{time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC), 1, day(2024, 3, 1)},
+		{day(2024, 3, 10), 25, day(2024, 2, 25)},
+		{day(2024, 3, 25), 25, day(2024, 3, 25)},
+		{day(2024, 1, 5), 25, day(2023, 12, 25)},
+		{day(2024, 3, 10), 31, day(2024, 3, 1)},
+		{day(2024, 3, 10), 0, day(2024, 3, 1)},
+	}
+	for _, tc := range tests {
+		if got := PeriodStart(tc.t, tc.start); !got.Equal(tc.want) {
+			t.Errorf("PeriodStart(%s, %d) = %s; want %s", tc.t.Format("2006-01-02"), tc.start, got.Format("2006-01-02"), tc.want.Format("2006-01-02"))
+		}
+	}
+	if got := PeriodEnd(day(2024, 1, 25)); !got.Equal(day(2024, 2, 24)) {
+		t.Errorf("PeriodEnd(25 January) = %s; want 2024-02-24", got.Format("2006-01-02"))
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-5 * time.Hour), "5 hours ago"},
+		{now.Add(-30 * time.Hour), "yesterday"},
+		{now.AddDate(0, 0, -2), "2 days ago"},
+		{now.AddDate(0, -3, 0), "3 months ago"},
+		{now.AddDate(-2, 0, 0), "2 years ago"},
+		{now.Add(3 * time.Hour), "in 3 hours"},
+		{now.AddDate(0, 0, 10), "in 10 days"},
+	}
+	for _, tc := range tests {
+		if got := Relative(tc.t, now); got != tc.want {
+			t.Errorf("Relative(%v) = %q; want %q", tc.t, got, tc.want)
+		}
+	}
+}
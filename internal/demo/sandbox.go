@@ -0,0 +1,82 @@
+package demo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/models"
+)
+
+// SandboxLifetime is how long a sandbox user of the public demo lives,
+// including its session.
+const SandboxLifetime = 2 * time.Hour
+
+// MaxSandboxes caps the number of live sandbox users, so the demo database
+// cannot be filled by visitors.
+const MaxSandboxes = 200
+
+// ErrTooManySandboxes is returned when MaxSandboxes sandboxes are live.
+var ErrTooManySandboxes = errors.New("too many demo sandboxes are in use")
+
+// CreateSandbox creates a temporary demo user with its own copy of the demo
+// data, so visitors of the hosted demo don't change each other's data. The
+// user can only log in through the returned user ID and is deleted with its
+// data after SandboxLifetime.
+func (s *Seeder) CreateSandbox(now time.Time) (*models.User, error) {
+	count, err := s.userRepo.CountTemporary(now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxSandboxes {
+		return nil, ErrTooManySandboxes
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating sandbox ID: %w", err)
+	}
+	id := hex.EncodeToString(token)
+
+	// Nobody knows the password; sandboxes are entered by auto-login
+	passwordHash, err := auth.HashPassword(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Email:           fmt.Sprintf("sandbox-%s@demo.invalid", id[:12]),
+		PasswordHash:    passwordHash,
+		Name:            "Demo User",
+		DefaultCurrency: "DKK",
+		NumberFormat:    "da",
+		Theme:           "dark",
+	}
+	user.ID, err = s.userRepo.Create(user)
+	if err != nil {
+		return nil, fmt.Errorf("creating sandbox user: %w", err)
+	}
+
+	// Set the expiry first, so a failed seed is cleaned up too
+	expiresAt := now.Add(SandboxLifetime)
+	if err := s.userRepo.SetExpiresAt(user.ID, expiresAt); err != nil {
+		s.userRepo.Delete(user.ID)
+		return nil, err
+	}
+	if err := s.seedData(user.ID, now); err != nil {
+		return nil, fmt.Errorf("seeding sandbox: %w", err)
+	}
+
+	log.Printf("[Demo] Created sandbox user %d, expiring %s", user.ID, expiresAt.Format(time.RFC3339))
+	return user, nil
+}
+
+// DeleteExpiredSandboxes deletes sandbox users whose lifetime has passed,
+// with all their data, and returns the number deleted.
+func (s *Seeder) DeleteExpiredSandboxes(now time.Time) (int64, error) {
+	return s.userRepo.DeleteExpired(now)
+}
@@ -74,12 +74,30 @@ func (s *Seeder) Seed() error {
 	}
 	log.Printf("Created demo user (ID: %d)", userID)
 
+	if err := s.seedData(userID, time.Now()); err != nil {
+		return err
+	}
+
+	log.Println("========================================")
+	log.Println("DEMO MODE ENABLED")
+	log.Println("Login with:")
+	log.Println("Email:    demo@example.com")
+	log.Println("Password: demo1234")
+	log.Println("========================================")
+
+	return nil
+}
+
+// seedData creates the sample categories, accounts, transactions and goals
+// of a demo user.
+func (s *Seeder) seedData(userID int64, now time.Time) error {
 	// Create categories
 	categories := []models.Category{
 		{UserID: userID, Name: "Aktier", Color: "#6366f1", Icon: "trending-up", SortOrder: 1},
 		{UserID: userID, Name: "ETF'er", Color: "#8b5cf6", Icon: "bar-chart-2", SortOrder: 2},
-		{UserID: userID, Name: "Pension", Color: "#10b981", Icon: "shield", SortOrder: 3},
-		{UserID: userID, Name: "Opsparing", Color: "#f59e0b", Icon: "piggy-bank", SortOrder: 4},
+		{UserID: userID, Name: "Pension", Color: "#10b981", Icon: "shield", SortOrder: 3, Liquidity: models.LiquidityLocked,
+			Description: "**Udbetaling** fra folkepensionsalderen.\n\n- Ratepension: udbetales over 10-30 år\n- Aldersopsparing: udbetales skattefrit"},
+		{UserID: userID, Name: "Opsparing", Color: "#f59e0b", Icon: "piggy-bank", SortOrder: 4, Liquidity: models.LiquidityLiquid},
 		{UserID: userID, Name: "Krypto", Color: "#ec4899", Icon: "bitcoin", SortOrder: 5},
 		{UserID: userID, Name: "Gæld", Color: "#ef4444", Icon: "credit-card", SortOrder: 6},
 	}
@@ -111,7 +129,8 @@ func (s *Seeder) Seed() error {
 		{UserID: userID, CategoryID: &opsparingID, Name: "Nødopsparing", Currency: "DKK", IsLiability: false, IsActive: true},
 		{UserID: userID, CategoryID: &opsparingID, Name: "Ferieopsparing", Currency: "DKK", IsLiability: false, IsActive: true},
 		{UserID: userID, CategoryID: &kryptoID, Name: "Coinbase", Currency: "USD", IsLiability: false, IsActive: true},
-		{UserID: userID, CategoryID: &gaeldID, Name: "Boliglån", Currency: "DKK", IsLiability: true, IsActive: true},
+		{UserID: userID, CategoryID: &gaeldID, Name: "Boliglån", Currency: "DKK", IsLiability: true, IsActive: true,
+			Notes: "## Lånevilkår\n- Fastforrentet 4%, 30 år\n- Afdragsfrihed udløber 2027"},
 		{UserID: userID, CategoryID: &gaeldID, Name: "Billån", Currency: "DKK", IsLiability: true, IsActive: true},
 	}
 
@@ -126,7 +145,6 @@ func (s *Seeder) Seed() error {
 	log.Printf("Created %d accounts", len(accounts))
 
 	// Create transactions with realistic growth over 2 years
-	now := time.Now()
 	transactions := s.generateTransactions(accountIDs, now)
 
 	for _, txn := range transactions {
@@ -158,13 +176,6 @@ func (s *Seeder) Seed() error {
 	}
 	log.Printf("Created %d goals", len(goals))
 
-	log.Println("========================================")
-	log.Println("DEMO MODE ENABLED")
-	log.Println("Login with:")
-	log.Println("Email:    demo@example.com")
-	log.Println("Password: demo1234")
-	log.Println("========================================")
-
 	return nil
 }
 
@@ -324,6 +335,7 @@ func (s *Seeder) generateAccountGrowth(accountID int64, startDate, endDate time.
 				Amount:          marketMove,
 				BalanceAfter:    currentBalance,
 				Description:     "Kursregulering",
+				Kind:            models.TransactionValuation,
 				TransactionDate: currentDate.AddDate(0, 0, 15),
 			})
 		}
@@ -338,6 +350,7 @@ func (s *Seeder) generateAccountGrowth(accountID int64, startDate, endDate time.
 				Amount:          diff,
 				BalanceAfter:    endBalance,
 				Description:     "Kursregulering",
+				Kind:            models.TransactionValuation,
 				TransactionDate: endDate,
 			})
 		}
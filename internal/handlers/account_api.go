@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxAPITransactionDescriptionLength bounds descriptions sent by scripts.
+const maxAPITransactionDescriptionLength = 200
+
+// accountAPIResponse is returned by the account API endpoints.
+type accountAPIResponse struct {
+	AccountID   int64               `json:"account_id"`
+	Balance     float64             `json:"balance"`
+	Transaction *models.Transaction `json:"transaction,omitempty"` // Nil if the balance was unchanged
+}
+
+// APIUpdateBalance sets the balance of the key's account, recording the
+// difference as a transaction like a balance update in the web interface.
+// Balances from scripts are not held for trend confirmation, as there is
+// nobody to confirm them.
+func (h *APIKeyHandler) APIUpdateBalance(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.keyAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Balance *float64 `json:"balance"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Balance == nil || math.IsNaN(*req.Balance) || math.IsInf(*req.Balance, 0) {
+		http.Error(w, "Invalid balance value", http.StatusBadRequest)
+		return
+	}
+	newBalance := *req.Balance
+
+	currentBalance, err := h.transactionRepo.GetLatestBalance(account.ID)
+	if err != nil {
+		log.Printf("Error getting balance of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+		return
+	}
+
+	resp := accountAPIResponse{AccountID: account.ID, Balance: newBalance}
+	amount := newBalance - currentBalance
+	if amount != 0 {
+		// The owner's description template applies to script updates too
+		user, err := h.userRepo.GetByID(account.UserID)
+		if err != nil {
+			log.Printf("Error getting owner of account %d: %v", account.ID, err)
+		}
+
+		now := time.Now()
+		txn := &models.Transaction{
+			AccountID:       account.ID,
+			Amount:          amount,
+			BalanceAfter:    newBalance,
+			Description:     services.BalanceDescription(user, services.DescriptionValues{Date: now, Delta: amount, Balance: newBalance, Currency: account.Currency}),
+			Kind:            models.TransactionValuation,
+			TransactionDate: now,
+		}
+		if resp.Transaction, err = h.createTransaction(txn); err != nil {
+			log.Printf("Error creating balance update transaction: %v", err)
+			http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeAccountAPIJSON(w, http.StatusOK, resp)
+}
+
+// APICreateTransaction adds a transaction to the key's account. The date is
+// optional and defaults to today.
+func (h *APIKeyHandler) APICreateTransaction(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.keyAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount      *float64 `json:"amount"`
+		Description string   `json:"description"`
+		Date        string   `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Amount == nil || math.IsNaN(*req.Amount) || math.IsInf(*req.Amount, 0) {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if len(description) > maxAPITransactionDescriptionLength {
+		http.Error(w, "Description is too long", http.StatusBadRequest)
+		return
+	}
+
+	transactionDate := time.Now()
+	if req.Date != "" {
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			http.Error(w, "Invalid date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		transactionDate = date
+	}
+
+	currentBalance, err := h.transactionRepo.GetLatestBalance(account.ID)
+	if err != nil {
+		log.Printf("Error getting balance of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+
+	txn := &models.Transaction{
+		AccountID:       account.ID,
+		Amount:          *req.Amount,
+		BalanceAfter:    currentBalance + *req.Amount,
+		Description:     description,
+		TransactionDate: transactionDate,
+	}
+	created, err := h.createTransaction(txn)
+	if err != nil {
+		log.Printf("Error creating transaction: %v", err)
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+
+	writeAccountAPIJSON(w, http.StatusCreated, accountAPIResponse{
+		AccountID:   account.ID,
+		Balance:     created.BalanceAfter,
+		Transaction: created,
+	})
+}
+
+// createTransaction stores txn and returns it as stored.
+func (h *APIKeyHandler) createTransaction(txn *models.Transaction) (*models.Transaction, error) {
+	id, err := h.transactionRepo.Create(txn)
+	if err != nil {
+		return nil, err
+	}
+	return h.transactionRepo.GetByID(id)
+}
+
+// keyAccount loads the account in the URL and verifies that the request's
+// API key is scoped to it. Writes an error response and returns false
+// otherwise.
+func (h *APIKeyHandler) keyAccount(w http.ResponseWriter, r *http.Request) (*models.Account, bool) {
+	key := middleware.GetAccountAPIKey(r)
+	if key == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return nil, false
+	}
+	if id != key.AccountID {
+		http.Error(w, "API key is not valid for this account", http.StatusForbidden)
+		return nil, false
+	}
+
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil || account.UserID != key.UserID {
+		if err != nil {
+			log.Printf("Error getting account %d: %v", id, err)
+		}
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil, false
+	}
+	return account, true
+}
+
+// writeAccountAPIJSON writes v as a JSON response with the given status.
+func writeAccountAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding account API response: %v", err)
+	}
+}
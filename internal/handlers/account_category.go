@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// categoryAllocationChange is a category's share of the dashboard's asset
+// distribution before and after accounts are moved.
+type categoryAllocationChange struct {
+	Category      *models.Category // Nil for accounts without a category, which the dashboard leaves out
+	Before        float64
+	After         float64
+	BeforePercent float64
+	AfterPercent  float64
+}
+
+// Change returns how much the category's total moves.
+func (c categoryAllocationChange) Change() float64 {
+	return c.After - c.Before
+}
+
+// BulkCategoryForm previews moving the selected accounts to a category: the
+// accounts and how the dashboard's asset distribution by category changes.
+func (h *AccountHandler) BulkCategoryForm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	selected, target, ok := h.bulkCategorySelection(w, r.URL.Query(), user.ID)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+	categories, err := h.categoryRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		http.Error(w, "Error loading categories", http.StatusInternalServerError)
+		return
+	}
+
+	moving := make(map[int64]bool, len(selected))
+	for _, acc := range selected {
+		moving[acc.ID] = true
+	}
+	balances := make(map[int64]float64, len(accounts))
+	for _, acc := range accounts {
+		balances[acc.ID], _ = h.transactionRepo.GetLatestBalance(acc.ID)
+	}
+
+	h.render(w, "account-category.html", map[string]any{
+		"Title":       "Change Category",
+		"User":        user,
+		"ActiveNav":   "accounts",
+		"Accounts":    selected,
+		"Target":      target,
+		"Categories":  categories,
+		"Allocations": previewCategoryAllocation(accounts, balances, categories, moving, target),
+		"DemoMode":    IsDemoMode(),
+	})
+}
+
+// BulkCategory moves the selected accounts to a category, or out of any, all
+// at once.
+func (h *AccountHandler) BulkCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	selected, target, ok := h.bulkCategorySelection(w, r.PostForm, user.ID)
+	if !ok {
+		return
+	}
+
+	ids := make([]int64, len(selected))
+	for i, acc := range selected {
+		ids[i] = acc.ID
+	}
+	var categoryID *int64
+	if target != nil {
+		categoryID = &target.ID
+	}
+	if err := h.accountRepo.SetCategory(user.ID, ids, categoryID); err != nil {
+		log.Printf("Error changing category of accounts %v: %v", ids, err)
+		h.renderError(w, r, user, "Failed to change the category of the accounts")
+		return
+	}
+	log.Printf("Moved %d accounts of user %d to category %v", len(ids), user.ID, categoryID)
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
+
+// bulkCategorySelection reads the selected accounts and the category to move
+// them to, nil for none, and verifies both belong to the user. Writes an
+// error response and returns false otherwise.
+func (h *AccountHandler) bulkCategorySelection(w http.ResponseWriter, values url.Values, userID int64) ([]*models.Account, *models.Category, bool) {
+	idStrs := values["account"]
+	if len(idStrs) == 0 {
+		http.Error(w, "Select at least one account", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	accounts := make([]*models.Account, 0, len(idStrs))
+	seen := make(map[int64]bool, len(idStrs))
+	for _, idStr := range idStrs {
+		account, ok := h.ownedAccount(w, idStr, userID)
+		if !ok {
+			return nil, nil, false
+		}
+		if !seen[account.ID] {
+			seen[account.ID] = true
+			accounts = append(accounts, account)
+		}
+	}
+
+	var category *models.Category
+	if v := values.Get("category"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return nil, nil, false
+		}
+		category, err = h.categoryRepo.GetByID(id)
+		if err != nil || category == nil || category.UserID != userID {
+			http.Error(w, "Category not found", http.StatusNotFound)
+			return nil, nil, false
+		}
+	}
+	return accounts, category, true
+}
+
+// previewCategoryAllocation returns the total of each category in the
+// dashboard's asset distribution before and after the moving accounts go to
+// target. Like the dashboard, liabilities are left out, balances are not
+// converted and shares are of the categorized assets; accounts without a
+// category are totalled in an entry of their own. Categories without assets
+// either way are skipped; the largest come first.
+func previewCategoryAllocation(accounts []*models.Account, balances map[int64]float64, categories []*models.Category, moving map[int64]bool, target *models.Category) []categoryAllocationChange {
+	byID := make(map[int64]*categoryAllocationChange, len(categories)+1)
+	key := func(cat *models.Category) int64 {
+		if cat == nil {
+			return 0
+		}
+		return cat.ID
+	}
+	byID[0] = &categoryAllocationChange{}
+	for _, cat := range categories {
+		byID[cat.ID] = &categoryAllocationChange{Category: cat}
+	}
+
+	var totalBefore, totalAfter float64
+	for _, acc := range accounts {
+		if acc.IsLiability {
+			continue
+		}
+		balance := balances[acc.ID]
+
+		var from int64
+		if acc.CategoryID != nil {
+			from = *acc.CategoryID
+		}
+		to := from
+		if moving[acc.ID] {
+			to = key(target)
+		}
+		if c, ok := byID[from]; ok {
+			c.Before += balance
+			if from != 0 {
+				totalBefore += balance
+			}
+		}
+		if c, ok := byID[to]; ok {
+			c.After += balance
+			if to != 0 {
+				totalAfter += balance
+			}
+		}
+	}
+
+	result := make([]categoryAllocationChange, 0, len(byID))
+	for _, c := range byID {
+		if c.Before <= 0 && c.After <= 0 {
+			continue
+		}
+		if c.Category != nil && totalBefore > 0 {
+			c.BeforePercent = c.Before / totalBefore * 100
+		}
+		if c.Category != nil && totalAfter > 0 {
+			c.AfterPercent = c.After / totalAfter * 100
+		}
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].After != result[j].After {
+			return result[i].After > result[j].After
+		}
+		return key(result[i].Category) < key(result[j].Category)
+	})
+	return result
+}
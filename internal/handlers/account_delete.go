@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Ways of handling the broker mappings of a deleted account.
+const (
+	mappingsDetach = "detach" // Unmap the broker accounts; the next sync skips them
+	mappingsMove   = "move"   // Sync the broker accounts into another account
+)
+
+// deleteErrors are the messages of the error codes the delete confirmation
+// page is redirected with.
+var deleteErrors = map[string]string{
+	"choose_mappings": "Choose what happens to the broker accounts synced into this account.",
+	"choose_target":   "Choose the account the broker accounts should sync into.",
+	"already_mapped":  "That account is already synced from the same connection. Choose another account or detach the broker accounts.",
+}
+
+// accountMappingView is a broker mapping of an account with its connection,
+// for display.
+type accountMappingView struct {
+	Mapping    *models.AccountMapping
+	Connection *models.BrokerConnection
+}
+
+// DeleteForm renders the confirmation page for deleting an account, which
+// shows the broker mappings and holdings that would go with it and offers to
+// detach the mappings or re-point them to another account.
+func (h *AccountHandler) DeleteForm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	account, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+
+	mappings, err := h.mappingRepo.GetAllByLocalAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching mappings of account %d: %v", account.ID, err)
+		http.Error(w, "Error loading account", http.StatusInternalServerError)
+		return
+	}
+	views := make([]accountMappingView, 0, len(mappings))
+	for _, mapping := range mappings {
+		conn, err := h.connRepo.GetByID(mapping.ConnectionID)
+		if err != nil {
+			log.Printf("Error fetching connection %d: %v", mapping.ConnectionID, err)
+			continue
+		}
+		views = append(views, accountMappingView{Mapping: mapping, Connection: conn})
+	}
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+	candidates := make([]*models.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		if acc.ID != account.ID {
+			candidates = append(candidates, acc)
+		}
+	}
+
+	holdings, _ := h.holdingRepo.CountByAccountID(account.ID)
+	transactions, _ := h.transactionRepo.CountByAccountID(account.ID)
+
+	h.render(w, "account-delete.html", map[string]any{
+		"Title":        "Delete Account",
+		"User":         user,
+		"ActiveNav":    "accounts",
+		"Account":      account,
+		"Mappings":     views,
+		"Candidates":   candidates,
+		"Holdings":     holdings,
+		"Transactions": transactions,
+		"Error":        deleteErrors[r.URL.Query().Get("error")],
+		"DemoMode":     IsDemoMode(),
+	})
+}
+
+// resolveMappingsForDelete handles the broker mappings of an account about to
+// be deleted, as chosen on the confirmation page: detached, or moved to
+// another account. Deletes of accounts with mappings or holdings that did not
+// come from that page are redirected to it. Returns false if the delete
+// should not go ahead, after writing the response.
+func (h *AccountHandler) resolveMappingsForDelete(w http.ResponseWriter, r *http.Request, user *models.User, account *models.Account) bool {
+	mappings, err := h.mappingRepo.GetAllByLocalAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching mappings of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return false
+	}
+	holdings, err := h.holdingRepo.CountByAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error counting holdings of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return false
+	}
+
+	confirmPage := fmt.Sprintf("/accounts/%d/delete", account.ID)
+	if (len(mappings) > 0 || holdings > 0) && r.FormValue("confirm") != "1" {
+		http.Redirect(w, r, confirmPage, http.StatusSeeOther)
+		return false
+	}
+	if len(mappings) == 0 {
+		return true
+	}
+
+	fail := func(code string) bool {
+		http.Redirect(w, r, confirmPage+"?"+url.Values{"error": {code}}.Encode(), http.StatusSeeOther)
+		return false
+	}
+
+	switch r.FormValue("mappings") {
+	case mappingsDetach:
+		for _, mapping := range mappings {
+			if err := h.mappingRepo.Delete(mapping.ID); err != nil {
+				log.Printf("Error detaching mapping %d: %v", mapping.ID, err)
+				http.Error(w, "Failed to detach broker accounts", http.StatusInternalServerError)
+				return false
+			}
+		}
+		log.Printf("Detached %d broker mapping(s) from account %d before deleting it", len(mappings), account.ID)
+		return true
+
+	case mappingsMove:
+		if r.FormValue("target_id") == "" {
+			return fail("choose_target")
+		}
+		target, ok := h.ownedAccount(w, r.FormValue("target_id"), user.ID)
+		if !ok {
+			return false
+		}
+		if target.ID == account.ID {
+			return fail("choose_target")
+		}
+		moved, err := h.mappingRepo.MoveToAccount(account.ID, target.ID)
+		if errors.Is(err, repository.ErrAccountAlreadyMapped) {
+			return fail("already_mapped")
+		}
+		if err != nil {
+			log.Printf("Error moving mappings of account %d to %d: %v", account.ID, target.ID, err)
+			http.Error(w, "Failed to move broker accounts", http.StatusInternalServerError)
+			return false
+		}
+		log.Printf("Moved %d broker mapping(s) from account %d to %d before deleting it", moved, account.ID, target.ID)
+		return true
+	}
+
+	return fail("choose_mappings")
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// mergePreviewPoints is how many points of the merged balance history are
+// shown in the preview.
+const mergePreviewPoints = 12
+
+// MergeForm renders the page for merging a duplicate account into this one,
+// with a preview of the result if a source account is selected.
+func (h *AccountHandler) MergeForm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	target, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+	candidates := make([]*models.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		if acc.ID != target.ID {
+			candidates = append(candidates, acc)
+		}
+	}
+
+	data := map[string]any{
+		"Title":      "Merge Accounts",
+		"User":       user,
+		"ActiveNav":  "accounts",
+		"Target":     target,
+		"Candidates": candidates,
+		"DemoMode":   IsDemoMode(),
+	}
+
+	if sourceIDStr := r.URL.Query().Get("source"); sourceIDStr != "" {
+		source, ok := h.ownedAccount(w, sourceIDStr, user.ID)
+		if !ok {
+			return
+		}
+		if source.ID == target.ID {
+			http.Error(w, "Cannot merge an account into itself", http.StatusBadRequest)
+			return
+		}
+
+		targetBalance, _ := h.transactionRepo.GetLatestBalance(target.ID)
+		sourceBalance, _ := h.transactionRepo.GetLatestBalance(source.ID)
+		targetTxns, _ := h.transactionRepo.CountByAccountID(target.ID)
+		sourceTxns, _ := h.transactionRepo.CountByAccountID(source.ID)
+		sourceHoldings, _ := h.holdingRepo.GetByAccountID(source.ID)
+
+		history, err := h.transactionRepo.GetCombinedBalanceHistory(target.ID, source.ID)
+		if err != nil {
+			log.Printf("Error building merged balance history: %v", err)
+		}
+		mergedBalance := 0.0
+		if len(history) > 0 {
+			mergedBalance = history[len(history)-1].Balance
+		}
+		if len(history) > mergePreviewPoints {
+			history = history[len(history)-mergePreviewPoints:]
+		}
+
+		data["Source"] = source
+		data["TargetBalance"] = targetBalance
+		data["SourceBalance"] = sourceBalance
+		data["MergedBalance"] = mergedBalance
+		data["TargetTransactions"] = targetTxns
+		data["SourceTransactions"] = sourceTxns
+		data["SourceHoldings"] = len(sourceHoldings)
+		data["History"] = history
+	}
+
+	h.render(w, "account-merge.html", data)
+}
+
+// Merge moves all transactions, holdings, broker mappings and goal links of
+// the source account into this account and archives the source account.
+func (h *AccountHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	target, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+	source, ok := h.ownedAccount(w, r.FormValue("source_id"), user.ID)
+	if !ok {
+		return
+	}
+	if source.ID == target.ID {
+		http.Error(w, "Cannot merge an account into itself", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.accountRepo.Merge(target.ID, source.ID)
+	if err != nil {
+		log.Printf("Error merging account %d into %d: %v", source.ID, target.ID, err)
+		http.Error(w, "Failed to merge accounts", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Merged account %d into %d: %d transactions, %d holdings, %d mappings moved",
+		source.ID, target.ID, result.Transactions, result.Holdings, result.Mappings)
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
+
+// ownedAccount parses an account ID and verifies the account belongs to the
+// user. Writes an error response and returns false otherwise.
+func (h *AccountHandler) ownedAccount(w http.ResponseWriter, idStr string, userID int64) (*models.Account, bool) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil, false
+	}
+	if account.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return account, true
+}
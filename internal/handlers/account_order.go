@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+)
+
+// Reorder stores the order the user dragged their accounts into. The body
+// lists account IDs in their new order; accounts left out keep their place.
+func (h *AccountHandler) Reorder(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		AccountIDs []int64 `json:"account_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.AccountIDs) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Failed to reorder accounts", http.StatusInternalServerError)
+		return
+	}
+	owned := make(map[int64]bool, len(accounts))
+	for _, account := range accounts {
+		owned[account.ID] = true
+	}
+	seen := make(map[int64]bool, len(req.AccountIDs))
+	for _, id := range req.AccountIDs {
+		// Consistent error to prevent enumeration
+		if !owned[id] || seen[id] {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		seen[id] = true
+	}
+
+	if err := h.accountRepo.Reorder(user.ID, req.AccountIDs); err != nil {
+		log.Printf("Error reordering accounts: %v", err)
+		http.Error(w, "Failed to reorder accounts", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TogglePin pins an account to the top of the accounts page and the
+// dashboard, or unpins it.
+func (h *AccountHandler) TogglePin(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if account.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.accountRepo.SetPinned(id, !account.IsPinned); err != nil {
+		log.Printf("Error pinning account: %v", err)
+		h.renderError(w, r, user, "Failed to pin account")
+		return
+	}
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
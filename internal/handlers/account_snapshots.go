@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// maxSnapshotNoteLength bounds the note of a snapshot.
+const maxSnapshotNoteLength = 200
+
+// SetSnapshotRepository sets the repository of account snapshots taken on
+// demand.
+func (h *AccountHandler) SetSnapshotRepository(repo *repository.AccountSnapshotRepository) {
+	h.snapshotRepo = repo
+}
+
+// Snapshots lists the snapshots of an account and compares the one in the
+// snapshot parameter, or the newest, with the account as it is now.
+func (h *AccountHandler) Snapshots(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	account, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.snapshotRepo.GetByAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching snapshots of account %d: %v", account.ID, err)
+		http.Error(w, "Error loading snapshots", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "Snapshots",
+		"User":      user,
+		"ActiveNav": "accounts",
+		"Account":   account,
+		"Snapshots": snapshots,
+		"DemoMode":  IsDemoMode(),
+	}
+
+	var selectedID int64
+	if idStr := r.URL.Query().Get("snapshot"); idStr != "" {
+		if selectedID, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+			http.Error(w, "Invalid snapshot ID", http.StatusBadRequest)
+			return
+		}
+	} else if len(snapshots) > 0 {
+		selectedID = snapshots[0].ID
+	}
+	if selectedID != 0 {
+		snapshot, ok := h.ownedSnapshot(w, selectedID, account.ID)
+		if !ok {
+			return
+		}
+		balance, _ := h.transactionRepo.GetLatestBalance(account.ID)
+		holdings, err := h.holdingRepo.GetByAccountID(account.ID)
+		if err != nil {
+			log.Printf("Error fetching holdings of account %d: %v", account.ID, err)
+			http.Error(w, "Error loading holdings", http.StatusInternalServerError)
+			return
+		}
+		data["Comparison"] = services.CompareSnapshot(account, snapshot, balance, holdings)
+	}
+
+	h.render(w, "account-snapshots.html", data)
+}
+
+// TakeSnapshot records the account's balance and holdings as they are now.
+func (h *AccountHandler) TakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	account, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if len([]rune(note)) > maxSnapshotNoteLength {
+		http.Error(w, fmt.Sprintf("Note must be at most %d characters", maxSnapshotNoteLength), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.snapshotRepo.Create(account.ID, note, time.Now())
+	if err != nil {
+		log.Printf("Error taking snapshot of account %d: %v", account.ID, err)
+		h.renderError(w, r, user, "Failed to take snapshot")
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/accounts/%d/snapshots?snapshot=%d", account.ID, id), http.StatusSeeOther)
+}
+
+// DeleteSnapshot removes a snapshot of the account.
+func (h *AccountHandler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "snapshotID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid snapshot ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.ownedSnapshot(w, id, account.ID); !ok {
+		return
+	}
+
+	if err := h.snapshotRepo.Delete(id); err != nil {
+		log.Printf("Error deleting snapshot %d: %v", id, err)
+		h.renderError(w, r, user, "Failed to delete snapshot")
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/accounts/%d/snapshots", account.ID), http.StatusSeeOther)
+}
+
+// ownedSnapshot loads a snapshot with its holdings and verifies it belongs
+// to the account. Writes an error response and returns false otherwise.
+func (h *AccountHandler) ownedSnapshot(w http.ResponseWriter, id, accountID int64) (*models.AccountSnapshot, bool) {
+	snapshot, err := h.snapshotRepo.GetByID(id)
+	if err != nil {
+		log.Printf("Error fetching snapshot %d: %v", id, err)
+		http.Error(w, "Error loading snapshot", http.StatusInternalServerError)
+		return nil, false
+	}
+	if snapshot == nil || snapshot.AccountID != accountID {
+		http.Error(w, "Snapshot not found", http.StatusNotFound)
+		return nil, false
+	}
+	return snapshot, true
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +16,7 @@ import (
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // AccountHandler handles account routes.
@@ -22,6 +26,15 @@ type AccountHandler struct {
 	categoryRepo    *repository.CategoryRepository
 	transactionRepo *repository.TransactionRepository
 	holdingRepo     *repository.HoldingRepository
+	acquisitionRepo *repository.HoldingAcquisitionRepository
+	mappingRepo     *repository.AccountMappingRepository
+	connRepo        *repository.BrokerConnectionRepository
+	interestRepo    *repository.InterestAccrualRepository
+	balanceChecker  *services.BalanceChecker
+	snapshotRepo    *repository.AccountSnapshotRepository
+	categorizer     *services.Categorizer
+	pendingRepo     *repository.PendingInvestmentRepository
+	priceBackfill   *services.PriceBackfillService
 }
 
 // NewAccountHandler creates a new AccountHandler.
@@ -31,6 +44,11 @@ func NewAccountHandler(
 	categoryRepo *repository.CategoryRepository,
 	transactionRepo *repository.TransactionRepository,
 	holdingRepo *repository.HoldingRepository,
+	acquisitionRepo *repository.HoldingAcquisitionRepository,
+	mappingRepo *repository.AccountMappingRepository,
+	connRepo *repository.BrokerConnectionRepository,
+	interestRepo *repository.InterestAccrualRepository,
+	balanceChecker *services.BalanceChecker,
 ) *AccountHandler {
 	return &AccountHandler{
 		templates:       templates,
@@ -38,9 +56,33 @@ func NewAccountHandler(
 		categoryRepo:    categoryRepo,
 		transactionRepo: transactionRepo,
 		holdingRepo:     holdingRepo,
+		acquisitionRepo: acquisitionRepo,
+		mappingRepo:     mappingRepo,
+		connRepo:        connRepo,
+		interestRepo:    interestRepo,
+		balanceChecker:  balanceChecker,
 	}
 }
 
+// SetPendingInvestmentRepository shows the pending savings plan buys
+// included in synced account values.
+func (h *AccountHandler) SetPendingInvestmentRepository(repo *repository.PendingInvestmentRepository) {
+	h.pendingRepo = repo
+}
+
+// pendingInvestments returns the pending investments of a user's accounts
+// by account ID, or nil if they are not tracked.
+func (h *AccountHandler) pendingInvestments(userID int64) map[int64]*models.PendingInvestment {
+	if h.pendingRepo == nil {
+		return nil
+	}
+	pending, err := h.pendingRepo.GetByUserID(userID)
+	if err != nil {
+		log.Printf("Error fetching pending investments: %v", err)
+	}
+	return pending
+}
+
 // List renders the accounts list page.
 func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -49,13 +91,28 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	// Accounts as they were at the end of the as-of day, if any
+	asOf := parseAsOf(r)
+	var accounts []*models.Account
+	var err error
+	if asOf != nil {
+		accounts, err = h.accountRepo.GetByUserIDOpenOn(user.ID, *asOf)
+	} else {
+		accounts, err = h.accountRepo.GetByUserID(user.ID)
+	}
 	if err != nil {
 		log.Printf("Error fetching accounts: %v", err)
 		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
 		return
 	}
 
+	var holdingsAt map[int64][]*models.Holding
+	if asOf != nil {
+		if holdingsAt, err = h.holdingRepo.GetHoldingsAt(user.ID, *asOf); err != nil {
+			log.Printf("Error fetching holding snapshots: %v", err)
+		}
+	}
+
 	categories, err := h.categoryRepo.GetByUserID(user.ID)
 	if err != nil {
 		log.Printf("Error fetching categories: %v", err)
@@ -69,6 +126,17 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 		categoryMap[cat.ID] = cat
 	}
 
+	interest, err := h.interestRepo.GetTotalsByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching interest totals: %v", err)
+	}
+
+	// Pending buys are only known for the latest sync
+	var pending map[int64]*models.PendingInvestment
+	if asOf == nil {
+		pending = h.pendingInvestments(user.ID)
+	}
+
 	// Build accounts with category info, balance, and holdings
 	type AccountWithCategory struct {
 		*models.Account
@@ -76,6 +144,8 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 		Balance       float64
 		Holdings      []*models.Holding
 		HoldingsValue float64
+		InterestPaid  float64                   // Interest posted by the accrual job
+		Pending       *models.PendingInvestment // Savings plan buys included in the balance
 	}
 	accountsWithCat := make([]AccountWithCategory, len(accounts))
 	for i, acc := range accounts {
@@ -83,10 +153,13 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 		if acc.CategoryID != nil {
 			cat = categoryMap[*acc.CategoryID]
 		}
-		balance, _ := h.transactionRepo.GetLatestBalance(acc.ID)
+		balance, _ := balanceAsOf(h.transactionRepo, acc.ID, asOf)
 
 		// Fetch holdings for this account
-		holdings, _ := h.holdingRepo.GetByAccountID(acc.ID)
+		holdings := holdingsAt[acc.ID]
+		if asOf == nil {
+			holdings, _ = h.holdingRepo.GetByAccountID(acc.ID)
+		}
 		var holdingsValue float64
 		for _, h := range holdings {
 			holdingsValue += h.CurrentValue
@@ -98,12 +171,24 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 			Balance:       balance,
 			Holdings:      holdings,
 			HoldingsValue: holdingsValue,
+			InterestPaid:  interest[acc.ID],
+			Pending:       pending[acc.ID],
 		}
 	}
 
 	// Count assets and liabilities
 	assetCount, _ := h.accountRepo.CountActiveAssets(user.ID)
 	liabilityCount, _ := h.accountRepo.CountActiveLiabilities(user.ID)
+	if asOf != nil {
+		assetCount, liabilityCount = 0, 0
+		for _, acc := range accounts {
+			if acc.IsLiability {
+				liabilityCount++
+			} else {
+				assetCount++
+			}
+		}
+	}
 
 	h.render(w, "accounts.html", map[string]any{
 		"Title":          "Accounts",
@@ -113,6 +198,8 @@ func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
 		"Categories":     categories,
 		"AssetCount":     assetCount,
 		"LiabilityCount": liabilityCount,
+		"AsOf":           asOfData("/accounts", asOf),
+		"PriceBackfill":  h.priceBackfill != nil,
 		"DemoMode":       IsDemoMode(),
 	})
 }
@@ -160,14 +247,29 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	openedAt, closedAt, errMsg := parseAccountDates(r)
+	if errMsg != "" {
+		h.renderError(w, r, user, errMsg)
+		return
+	}
+	interestRate, errMsg := parseInterestRate(r, isLiability)
+	if errMsg != "" {
+		h.renderError(w, r, user, errMsg)
+		return
+	}
+
 	account := &models.Account{
-		UserID:      user.ID,
-		CategoryID:  categoryID,
-		Name:        name,
-		Currency:    currency,
-		IsLiability: isLiability,
-		IsActive:    true,
-		Notes:       notes,
+		UserID:        user.ID,
+		CategoryID:    categoryID,
+		Name:          name,
+		Currency:      currency,
+		IsLiability:   isLiability,
+		IsActive:      true,
+		Notes:         notes,
+		OpenedAt:      openedAt,
+		ClosedAt:      closedAt,
+		InterestRate:  interestRate,
+		NetWorthGroup: parseNetWorthGroup(r),
 	}
 
 	_, err := h.accountRepo.Create(account)
@@ -247,14 +349,45 @@ func (h *AccountHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	openedAt, closedAt, errMsg := parseAccountDates(r)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	interestRate, errMsg := parseInterestRate(r, isLiability)
+	if errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
 	existing.Name = name
 	existing.Currency = currency
 	existing.CategoryID = categoryID
 	existing.Notes = notes
 	existing.IsLiability = isLiability
 	existing.IsActive = isActive
+	existing.OpenedAt = openedAt
+	existing.ClosedAt = closedAt
+	existing.InterestRate = interestRate
+	existing.NetWorthGroup = parseNetWorthGroup(r)
+
+	// Reject the edit if the account changed since the form was opened
+	currentVersion := existing.UpdatedAt
+	if v := r.FormValue("version"); v != "" {
+		version, err := parseVersion(v)
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		existing.UpdatedAt = version
+	}
 
 	err = h.accountRepo.Update(existing)
+	if errors.Is(err, repository.ErrStaleUpdate) {
+		existing.UpdatedAt = currentVersion
+		h.renderConflict(w, user, existing)
+		return
+	}
 	if err != nil {
 		log.Printf("Error updating account: %v", err)
 		if strings.Contains(err.Error(), "UNIQUE constraint") {
@@ -268,6 +401,52 @@ func (h *AccountHandler) Update(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
 }
 
+// parseAccountDates reads the optional opened_at and closed_at form fields.
+func parseAccountDates(r *http.Request) (openedAt, closedAt *time.Time, errMsg string) {
+	if v := r.FormValue("opened_at"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, nil, "Invalid opening date"
+		}
+		openedAt = &d
+	}
+	if v := r.FormValue("closed_at"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, nil, "Invalid closing date"
+		}
+		closedAt = &d
+	}
+	if openedAt != nil && closedAt != nil && closedAt.Before(*openedAt) {
+		return nil, nil, "Closing date cannot be before the opening date"
+	}
+	return openedAt, closedAt, ""
+}
+
+// parseInterestRate reads the optional annual interest_rate form field, in
+// percent. Only liabilities accrue interest, so assets get no rate.
+func parseInterestRate(r *http.Request, isLiability bool) (float64, string) {
+	v := strings.TrimSpace(r.FormValue("interest_rate"))
+	if v == "" || !isLiability {
+		return 0, ""
+	}
+	rate, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+	if err != nil || rate < 0 || rate > 100 {
+		return 0, "Interest rate must be a percentage between 0 and 100"
+	}
+	return rate, ""
+}
+
+// parseNetWorthGroup reads the optional net_worth_group form field. Unknown
+// groups count as none.
+func parseNetWorthGroup(r *http.Request) string {
+	group := r.FormValue("net_worth_group")
+	if !slices.Contains(models.NetWorthGroups, group) {
+		return ""
+	}
+	return group
+}
+
 // Delete handles deleting an account.
 func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -294,6 +473,12 @@ func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Accounts that brokers sync into, or that have holdings, are deleted
+	// from the confirmation page, which asks what to do with the mappings
+	if !h.resolveMappingsForDelete(w, r, user, existing) {
+		return
+	}
+
 	err = h.accountRepo.Delete(id)
 	if err != nil {
 		log.Printf("Error deleting account: %v", err)
@@ -351,12 +536,18 @@ func (h *AccountHandler) UpdateBalance(w http.ResponseWriter, r *http.Request) {
 
 	// Only create transaction if there's a change
 	if amount != 0 {
+		if confirmBalanceAnomaly(w, r, h.render, h.balanceChecker, user, account, newBalance, "/accounts") {
+			return
+		}
+
+		now := time.Now()
 		txn := &models.Transaction{
 			AccountID:       id,
 			Amount:          amount,
 			BalanceAfter:    newBalance,
-			Description:     "Balance update",
-			TransactionDate: time.Now(),
+			Description:     services.BalanceDescription(user, services.DescriptionValues{Date: now, Delta: amount, Balance: newBalance, Currency: account.Currency}),
+			Kind:            models.TransactionValuation,
+			TransactionDate: now,
 		}
 
 		_, err = h.transactionRepo.Create(txn)
@@ -370,6 +561,97 @@ func (h *AccountHandler) UpdateBalance(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
 }
 
+// maxHoldingsUploadSize limits the size of an uploaded holdings CSV.
+const maxHoldingsUploadSize = 1 << 20 // 1 MB
+
+// ImportHoldings handles uploading a holdings CSV into a manually tracked account.
+// In merge mode existing holdings with the same ISIN or ticker are updated; in replace
+// mode all manual holdings are removed first. Nothing is written if any row is invalid.
+func (h *AccountHandler) ImportHoldings(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify account belongs to user
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if account.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHoldingsUploadSize)
+	if err := r.ParseMultipartForm(maxHoldingsUploadSize); err != nil {
+		h.renderError(w, r, user, "File is too large or the form is invalid")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderError(w, r, user, "Please choose a CSV file to import")
+		return
+	}
+	defer file.Close()
+
+	mode := r.FormValue("mode")
+	if mode != services.HoldingImportReplace {
+		mode = services.HoldingImportMerge
+	}
+
+	// Broker-synced accounts manage their own holdings
+	existing, err := h.holdingRepo.GetByAccountID(id)
+	if err != nil {
+		log.Printf("Error fetching holdings: %v", err)
+		h.renderError(w, r, user, "Failed to import holdings")
+		return
+	}
+	for _, hld := range existing {
+		if hld.ExternalID != "" {
+			h.renderError(w, r, user, "Holdings import is only available for accounts without a broker connection")
+			return
+		}
+	}
+
+	imported, rowErrors, err := services.ParseHoldingsCSV(file, id, account.Currency)
+	if err != nil {
+		h.renderError(w, r, user, "Holdings import failed: "+err.Error())
+		return
+	}
+	if len(rowErrors) > 0 {
+		msgs := make([]string, len(rowErrors))
+		for i, e := range rowErrors {
+			msgs[i] = e.Error()
+		}
+		h.renderError(w, r, user, fmt.Sprintf("Holdings import failed, nothing was imported. %s", strings.Join(msgs, "; ")))
+		return
+	}
+	if len(imported) == 0 {
+		h.renderError(w, r, user, "The file contains no holdings")
+		return
+	}
+
+	holdings := services.MatchImportedHoldings(imported, existing)
+	if err := h.holdingRepo.ImportManual(id, holdings, mode == services.HoldingImportReplace); err != nil {
+		log.Printf("Error importing holdings: %v", err)
+		h.renderError(w, r, user, "Failed to import holdings")
+		return
+	}
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
+
 // render renders a template with the given data.
 func (h *AccountHandler) render(w http.ResponseWriter, name string, data map[string]any) {
 	if data == nil {
@@ -391,6 +673,18 @@ func (h *AccountHandler) render(w http.ResponseWriter, name string, data map[str
 
 // renderError re-renders the accounts page with an error message.
 func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, user *models.User, errMsg string) {
+	h.renderPage(w, user, map[string]any{"Error": errMsg})
+}
+
+// renderConflict re-renders the accounts page with the account as edited
+// elsewhere, offering to reapply the user's edit to it. submitted holds the
+// rejected edit with the current version.
+func (h *AccountHandler) renderConflict(w http.ResponseWriter, user *models.User, submitted *models.Account) {
+	h.renderPage(w, user, map[string]any{"Error": repository.ErrStaleUpdate.Error(), "Conflict": submitted})
+}
+
+// renderPage renders the accounts page with extra data, such as an error.
+func (h *AccountHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
 	accounts, _ := h.accountRepo.GetByUserID(user.ID)
 	categories, _ := h.categoryRepo.GetByUserID(user.ID)
 
@@ -398,6 +692,8 @@ func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, use
 	for _, cat := range categories {
 		categoryMap[cat.ID] = cat
 	}
+	interest, _ := h.interestRepo.GetTotalsByUserID(user.ID)
+	pending := h.pendingInvestments(user.ID)
 
 	type AccountWithCategory struct {
 		*models.Account
@@ -405,6 +701,8 @@ func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, use
 		Balance       float64
 		Holdings      []*models.Holding
 		HoldingsValue float64
+		InterestPaid  float64
+		Pending       *models.PendingInvestment
 	}
 	accountsWithCat := make([]AccountWithCategory, len(accounts))
 	for i, acc := range accounts {
@@ -426,13 +724,15 @@ func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, use
 			Balance:       balance,
 			Holdings:      holdings,
 			HoldingsValue: holdingsValue,
+			InterestPaid:  interest[acc.ID],
+			Pending:       pending[acc.ID],
 		}
 	}
 
 	assetCount, _ := h.accountRepo.CountActiveAssets(user.ID)
 	liabilityCount, _ := h.accountRepo.CountActiveLiabilities(user.ID)
 
-	h.render(w, "accounts.html", map[string]any{
+	data := map[string]any{
 		"Title":          "Accounts",
 		"User":           user,
 		"ActiveNav":      "accounts",
@@ -440,6 +740,10 @@ func (h *AccountHandler) renderError(w http.ResponseWriter, r *http.Request, use
 		"Categories":     categories,
 		"AssetCount":     assetCount,
 		"LiabilityCount": liabilityCount,
-		"Error":          errMsg,
-	})
+		"PriceBackfill":  h.priceBackfill != nil,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "accounts.html", data)
 }
@@ -3,6 +3,7 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -18,6 +19,8 @@ import (
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+	"wealth_tracker/internal/sync"
 )
 
 // ImpersonationCookieName is the cookie name for storing the original admin session.
@@ -32,7 +35,17 @@ type AdminHandler struct {
 	categoryRepo    *repository.CategoryRepository
 	goalRepo        *repository.GoalRepository
 	transactionRepo *repository.TransactionRepository
+	holdingRepo     *repository.HoldingRepository
 	sessionManager  *auth.SessionManager
+	syncService     *sync.Service // Nil disables syncing all connections
+	passwordPolicy  auth.PasswordPolicy
+	auditService    *services.AuditService        // Nil skips audit logging
+	retention       *services.RetentionService    // Nil hides the retention page
+	taxParams       *services.TaxParameterService // Nil hides the tax parameters page
+	loginLinks      *auth.LoginLinkManager        // Nil disables login links
+	housekeeping    *services.HousekeepingService // Nil hides housekeeping on the dashboard
+	features        *services.FeatureFlagService  // Nil hides the feature flags page
+	maintenance     *services.MaintenanceService  // Nil hides the maintenance page
 }
 
 // NewAdminHandler creates a new AdminHandler.
@@ -44,6 +57,7 @@ func NewAdminHandler(
 	categoryRepo *repository.CategoryRepository,
 	goalRepo *repository.GoalRepository,
 	transactionRepo *repository.TransactionRepository,
+	holdingRepo *repository.HoldingRepository,
 	sessionManager *auth.SessionManager,
 ) *AdminHandler {
 	return &AdminHandler{
@@ -54,10 +68,32 @@ func NewAdminHandler(
 		categoryRepo:    categoryRepo,
 		goalRepo:        goalRepo,
 		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
 		sessionManager:  sessionManager,
+		passwordPolicy:  auth.DefaultPasswordPolicy,
 	}
 }
 
+// SetPasswordPolicy sets the policy passwords reset by admins must meet.
+func (h *AdminHandler) SetPasswordPolicy(policy auth.PasswordPolicy) {
+	h.passwordPolicy = policy
+}
+
+// SetHousekeepingService shows the last housekeeping run on the dashboard.
+func (h *AdminHandler) SetHousekeepingService(housekeeping *services.HousekeepingService) {
+	h.housekeeping = housekeeping
+}
+
+// userErrors are the messages of the user detail page's error codes.
+var userErrors = map[string]string{
+	"name_email_required":               "Name and email are required",
+	"update_failed":                     "The user could not be updated",
+	"hash_failed":                       "The password could not be set",
+	"login_link_failed":                 "The login link could not be created",
+	"password_" + auth.PasswordTooWeak:  "The password is too easy to guess; use a few uncommon words, or add length rather than symbols",
+	"password_" + auth.PasswordBreached: "The password has appeared in a known data breach; choose another",
+}
+
 // Dashboard renders the admin dashboard.
 func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -72,14 +108,26 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	// Get table counts
 	tableCounts := h.getTableCounts()
 
-	h.render(w, "admin-dashboard.html", map[string]any{
+	data := map[string]any{
 		"Title":       "Admin Dashboard",
 		"User":        user,
 		"ActiveNav":   "admin",
 		"UserCount":   userCount,
 		"TableCounts": tableCounts,
 		"Impersonating": h.isImpersonating(r),
-	})
+	}
+	if h.syncService != nil {
+		data["CanSyncAll"] = true
+		data["SyncAll"] = h.syncService.SyncAllStatus()
+		data["SyncAllSpread"] = int(sync.DefaultSyncAllSpread.Minutes())
+		data["SyncAllError"] = syncAllErrors[r.URL.Query().Get("sync_all_error")]
+	}
+	if h.housekeeping != nil {
+		data["ShowHousekeeping"] = true
+		data["Housekeeping"] = h.housekeeping.LastRun()
+	}
+
+	h.render(w, "admin-dashboard.html", data)
 }
 
 // UserList renders the user list page.
@@ -154,12 +202,17 @@ func (h *AdminHandler) UserView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.render(w, "admin-user-detail.html", h.userDetailData(r, user, targetUser))
+}
+
+// userDetailData returns the data of the user detail page.
+func (h *AdminHandler) userDetailData(r *http.Request, user, targetUser *models.User) map[string]any {
 	// Get user stats
-	accountCount, _ := h.accountRepo.CountByUserID(id)
-	categoryCount, _ := h.categoryRepo.CountByUserID(id)
-	goalCount, _ := h.goalRepo.CountByUserID(id)
+	accountCount, _ := h.accountRepo.CountByUserID(targetUser.ID)
+	categoryCount, _ := h.categoryRepo.CountByUserID(targetUser.ID)
+	goalCount, _ := h.goalRepo.CountByUserID(targetUser.ID)
 
-	h.render(w, "admin-user-detail.html", map[string]any{
+	return map[string]any{
 		"Title":         "User Details",
 		"User":          user,
 		"ActiveNav":     "admin",
@@ -168,7 +221,10 @@ func (h *AdminHandler) UserView(w http.ResponseWriter, r *http.Request) {
 		"CategoryCount": categoryCount,
 		"GoalCount":     goalCount,
 		"Impersonating": h.isImpersonating(r),
-	})
+		"Error":         h.userError(r.URL.Query().Get("error")),
+		"PasswordHint":  h.passwordPolicy.Hint(),
+		"LoginLinks":    h.loginLinks != nil,
+	}
 }
 
 // UserEdit handles the user edit form submission.
@@ -243,9 +299,20 @@ func (h *AdminHandler) UserResetPassword(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	targetUser, err := h.userRepo.GetByID(id)
+	if err != nil || targetUser == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
 	newPassword := r.FormValue("new_password")
-	if len(newPassword) < 8 {
-		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d?error=password_too_short", id), http.StatusSeeOther)
+	if err := h.passwordPolicy.Check(newPassword, targetUser.Name, targetUser.Email); err != nil {
+		reason := "invalid"
+		var pwErr *auth.PasswordError
+		if errors.As(err, &pwErr) {
+			reason = pwErr.Reason
+		}
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d?error=password_%s", id, reason), http.StatusSeeOther)
 		return
 	}
 
@@ -376,6 +443,64 @@ func (h *AdminHandler) ReturnFromImpersonation(w http.ResponseWriter, r *http.Re
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
 
+// HoldingsReport renders orphaned holdings and holdings removed by syncs.
+func (h *AdminHandler) HoldingsReport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	orphaned, err := h.holdingRepo.GetOrphaned()
+	if err != nil {
+		log.Printf("AdminHandler.HoldingsReport error loading orphaned holdings: %v", err)
+		http.Error(w, "Error loading holdings", http.StatusInternalServerError)
+		return
+	}
+
+	archived, err := h.holdingRepo.GetArchived(100)
+	if err != nil {
+		log.Printf("AdminHandler.HoldingsReport error loading archived holdings: %v", err)
+		http.Error(w, "Error loading holdings", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "admin-holdings.html", map[string]any{
+		"Title":         "Holdings Report",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Orphaned":      orphaned,
+		"Archived":      archived,
+		"Success":       r.URL.Query().Get("success"),
+		"Error":         r.URL.Query().Get("error"),
+		"Impersonating": h.isImpersonating(r),
+	})
+}
+
+// RestoreHolding restores a holding removed by a sync from the archive.
+func (h *AdminHandler) RestoreHolding(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.holdingRepo.RestoreArchived(id); err != nil {
+		log.Printf("AdminHandler.RestoreHolding error: %v", err)
+		http.Redirect(w, r, "/admin/holdings?error=restore_failed", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/holdings?success=restored", http.StatusSeeOther)
+}
+
 // DatabaseOverview renders the database overview page.
 func (h *AdminHandler) DatabaseOverview(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -754,3 +879,11 @@ func (h *AdminHandler) getTableRows(tableName string, columns []string, limit, o
 
 	return results, nil
 }
+
+// userError returns the message of a user detail page error code, or "".
+func (h *AdminHandler) userError(code string) string {
+	if code == "password_"+auth.PasswordTooShort {
+		return fmt.Sprintf("The password must be at least %d characters", h.passwordPolicy.MinLength)
+	}
+	return userErrors[code]
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// UserAnonymizedExport downloads an anonymized copy of a user's data, with
+// names hashed, amounts scaled by a random factor and secrets left out, so a
+// bug can be reproduced and shared without the user's finances.
+func (h *AdminHandler) UserAnonymizedExport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.userData(id)
+	if err != nil {
+		log.Printf("AdminHandler.UserAnonymizedExport error: %v", err)
+		http.Error(w, "Error loading user data", http.StatusInternalServerError)
+		return
+	}
+	if data == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	anonymizer, err := services.NewAnonymizer()
+	if err != nil {
+		log.Printf("AdminHandler.UserAnonymizedExport error: %v", err)
+		http.Error(w, "Failed to create export", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	export := anonymizer.Anonymize(data, now)
+
+	h.audit(r, user.ID, id, services.AuditAdminAnonymizedExport, nil)
+
+	filename := fmt.Sprintf("wealth_tracker_anonymized_%s.json", now.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		log.Printf("AdminHandler.UserAnonymizedExport error encoding: %v", err)
+	}
+}
+
+// userData collects the data of a user for an anonymized export. Returns nil
+// if the user does not exist.
+func (h *AdminHandler) userData(userID int64) (*services.UserData, error) {
+	user, err := h.userRepo.GetByID(userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	data := &services.UserData{User: user}
+	if data.Categories, err = h.categoryRepo.GetByUserID(userID); err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+	if data.Goals, err = h.goalRepo.GetByUserID(userID); err != nil {
+		return nil, fmt.Errorf("getting goals: %w", err)
+	}
+	if data.Accounts, err = h.accountRepo.GetByUserID(userID); err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+
+	for _, account := range data.Accounts {
+		if account.Balance, err = h.transactionRepo.GetLatestBalance(account.ID); err != nil {
+			return nil, fmt.Errorf("getting balance of account %d: %w", account.ID, err)
+		}
+		transactions, err := h.transactionRepo.GetByAccountID(account.ID, 10000, 0)
+		if err != nil {
+			return nil, fmt.Errorf("getting transactions of account %d: %w", account.ID, err)
+		}
+		data.Transactions = append(data.Transactions, transactions...)
+
+		holdings, err := h.holdingRepo.GetByAccountID(account.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting holdings of account %d: %w", account.ID, err)
+		}
+		data.Holdings = append(data.Holdings, holdings...)
+	}
+	return data, nil
+}
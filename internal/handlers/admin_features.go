@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// featureFlagErrors are the messages of the feature flags page's error codes.
+var featureFlagErrors = map[string]string{
+	"unknown_feature": "Unknown feature",
+	"state":           "Choose on, off or default",
+	"user_not_found":  "No user has that email address",
+	"save_failed":     "The feature flag could not be saved",
+}
+
+// SetFeatureFlagService enables the feature flags page.
+func (h *AdminHandler) SetFeatureFlagService(features *services.FeatureFlagService) {
+	h.features = features
+}
+
+// FeatureFlags renders the experimental features and who they are on for.
+func (h *AdminHandler) FeatureFlags(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.features == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	statuses, err := h.features.Statuses()
+	if err != nil {
+		log.Printf("AdminHandler.FeatureFlags error: %v", err)
+		http.Error(w, "Error loading feature flags", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "admin-features.html", map[string]any{
+		"Title":         "Feature Flags",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Features":      statuses,
+		"Saved":         r.URL.Query().Get("saved"),
+		"Error":         featureFlagErrors[r.URL.Query().Get("error")],
+		"Impersonating": h.isImpersonating(r),
+	})
+}
+
+// SaveFeatureFlag turns a feature on or off for everyone, or returns it to
+// the instance's default.
+func (h *AdminHandler) SaveFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.features == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := r.FormValue("name")
+	enabled, ok := parseFeatureState(r.FormValue("state"))
+	if !ok {
+		http.Redirect(w, r, "/admin/features?error=state", http.StatusSeeOther)
+		return
+	}
+	if err := h.features.SetForEveryone(name, enabled); err != nil {
+		h.featureFlagError(w, r, "SaveFeatureFlag", err)
+		return
+	}
+	log.Printf("Admin %s set feature %s to %s for everyone", user.Email, name, r.FormValue("state"))
+
+	http.Redirect(w, r, "/admin/features?saved="+url.QueryEscape(name), http.StatusSeeOther)
+}
+
+// SaveFeatureFlagUser turns a feature on or off for a single user, or
+// removes the user's setting.
+func (h *AdminHandler) SaveFeatureFlagUser(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.features == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := r.FormValue("name")
+	enabled, ok := parseFeatureState(r.FormValue("state"))
+	if !ok {
+		http.Redirect(w, r, "/admin/features?error=state", http.StatusSeeOther)
+		return
+	}
+	target, err := h.userRepo.GetByEmail(strings.TrimSpace(r.FormValue("email")))
+	if err != nil {
+		h.featureFlagError(w, r, "SaveFeatureFlagUser", err)
+		return
+	}
+	if target == nil {
+		http.Redirect(w, r, "/admin/features?error=user_not_found", http.StatusSeeOther)
+		return
+	}
+	if err := h.features.SetForUser(name, target.ID, enabled); err != nil {
+		h.featureFlagError(w, r, "SaveFeatureFlagUser", err)
+		return
+	}
+	log.Printf("Admin %s set feature %s to %s for %s", user.Email, name, r.FormValue("state"), target.Email)
+
+	http.Redirect(w, r, "/admin/features?saved="+url.QueryEscape(name), http.StatusSeeOther)
+}
+
+// featureFlagError redirects back to the feature flags page with the error
+// code of err.
+func (h *AdminHandler) featureFlagError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	code := "unknown_feature"
+	if err != services.ErrUnknownFeature {
+		log.Printf("AdminHandler.%s error: %v", action, err)
+		code = "save_failed"
+	}
+	http.Redirect(w, r, "/admin/features?error="+code, http.StatusSeeOther)
+}
+
+// parseFeatureState reads a feature flag form's state: "on" and "off" set
+// the flag, and "default" removes it.
+func parseFeatureState(state string) (enabled *bool, ok bool) {
+	switch state {
+	case "on", "off":
+		on := state == "on"
+		return &on, true
+	case "default":
+		return nil, true
+	}
+	return nil, false
+}
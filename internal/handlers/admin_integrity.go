@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"wealth_tracker/internal/middleware"
+)
+
+// IntegrityCheck runs the database integrity checker and renders its report.
+func (h *AdminHandler) IntegrityCheck(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	report, err := h.db.CheckIntegrity()
+	if err != nil {
+		log.Printf("AdminHandler.IntegrityCheck error: %v", err)
+		http.Error(w, "Error checking database", http.StatusInternalServerError)
+		return
+	}
+
+	repaired := -1
+	if v := r.URL.Query().Get("repaired"); v != "" {
+		repaired, _ = strconv.Atoi(v)
+	}
+
+	h.render(w, "admin-integrity.html", map[string]any{
+		"Title":         "Database Integrity",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Report":        report,
+		"Repaired":      repaired,
+		"Error":         r.URL.Query().Get("error"),
+		"Impersonating": h.isImpersonating(r),
+	})
+}
+
+// IntegrityRepair removes or clears rows pointing at deleted parents.
+func (h *AdminHandler) IntegrityRepair(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	repaired, err := h.db.RepairOrphans()
+	if err != nil {
+		log.Printf("AdminHandler.IntegrityRepair error: %v", err)
+		http.Redirect(w, r, "/admin/integrity?error=repair_failed", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s repaired %d orphaned rows", user.Email, repaired)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/integrity?repaired=%d", repaired), http.StatusSeeOther)
+}
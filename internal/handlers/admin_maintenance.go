@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// maintenanceRefreshSeconds is how often the maintenance page reloads while
+// a run is in progress.
+const maintenanceRefreshSeconds = 2
+
+// maintenanceErrors are the messages of the maintenance page's error codes.
+var maintenanceErrors = map[string]string{
+	"unknown_operation": "Unknown maintenance operation",
+	"running":           "Maintenance is already running; wait for it to finish.",
+	"storage_failed":    "The database size could not be read; see the server log.",
+}
+
+// maintenanceDescriptions explain the maintenance operations on the page.
+var maintenanceDescriptions = map[string]string{
+	database.MaintenanceCheckpoint: "Copies the write-ahead log into the database file and truncates it",
+	database.MaintenanceAnalyze:    "Refreshes the statistics the query planner chooses indexes by",
+	database.MaintenanceVacuum:     "Rebuilds the database file without its free pages; blocks writes while it runs",
+}
+
+// SetMaintenanceService enables the database maintenance page.
+func (h *AdminHandler) SetMaintenanceService(maintenance *services.MaintenanceService) {
+	h.maintenance = maintenance
+}
+
+// Maintenance renders the database size, the maintenance operations and the
+// progress of the latest run. The page reloads itself while a run is going.
+func (h *AdminHandler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.maintenance == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	errorCode := r.URL.Query().Get("error")
+	storage, err := h.db.Storage()
+	if err != nil {
+		log.Printf("AdminHandler.Maintenance error: %v", err)
+		errorCode = "storage_failed"
+	}
+
+	run := h.maintenance.LastRun()
+	data := map[string]any{
+		"Title":         "Database Maintenance",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Storage":       storage,
+		"Operations":    database.MaintenanceOperations,
+		"Descriptions":  maintenanceDescriptions,
+		"Run":           run,
+		"WindowHour":    h.maintenance.WindowHour(),
+		"Error":         maintenanceErrors[errorCode],
+		"Impersonating": h.isImpersonating(r),
+	}
+	if run != nil && !run.Done {
+		data["RefreshSeconds"] = maintenanceRefreshSeconds
+	}
+	h.render(w, "admin-maintenance.html", data)
+}
+
+// RunMaintenance starts a maintenance operation, or all of them, in the
+// background and sends the admin to the page showing its progress.
+func (h *AdminHandler) RunMaintenance(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.maintenance == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ops := database.MaintenanceOperations
+	if op := r.FormValue("operation"); op != "all" {
+		if !database.IsMaintenanceOperation(op) {
+			http.Redirect(w, r, "/admin/maintenance?error=unknown_operation", http.StatusSeeOther)
+			return
+		}
+		ops = []string{op}
+	}
+
+	// Another run is the only reason maintenance does not start
+	if _, err := h.maintenance.Start(ops, time.Now()); err != nil {
+		http.Redirect(w, r, "/admin/maintenance?error=running", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s started database maintenance: %v", user.Email, ops)
+
+	http.Redirect(w, r, "/admin/maintenance", http.StatusSeeOther)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// retentionErrors are the messages of the retention page's error codes.
+var retentionErrors = map[string]string{
+	"invalid_days":  "Days must be whole numbers",
+	"unknown_table": "Unknown history table",
+	"negative":      "Days cannot be negative",
+	"cannot_thin":   "That table cannot be thinned to monthly",
+	"thin_after":    "Thinning must start before rows are deleted",
+	"save_failed":   "The policy could not be saved",
+	"run_failed":    "Enforcing the policies failed for some tables; see the server log",
+}
+
+// retentionErrorCodes maps policy validation errors to error codes.
+var retentionErrorCodes = map[error]string{
+	services.ErrRetentionUnknownTable: "unknown_table",
+	services.ErrRetentionNegative:     "negative",
+	services.ErrRetentionCannotThin:   "cannot_thin",
+	services.ErrRetentionThinAfter:    "thin_after",
+}
+
+// SetRetentionService enables the retention policy page.
+func (h *AdminHandler) SetRetentionService(retention *services.RetentionService) {
+	h.retention = retention
+}
+
+// RetentionPolicies renders the history tables with their retention policies.
+func (h *AdminHandler) RetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.retention == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	statuses, err := h.retention.Statuses()
+	if err != nil {
+		log.Printf("AdminHandler.RetentionPolicies error: %v", err)
+		http.Error(w, "Error loading retention policies", http.StatusInternalServerError)
+		return
+	}
+
+	deleted := int64(-1)
+	if v := r.URL.Query().Get("deleted"); v != "" {
+		deleted, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	h.render(w, "admin-retention.html", map[string]any{
+		"Title":         "Data Retention",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Statuses":      statuses,
+		"Saved":         r.URL.Query().Get("saved"),
+		"Deleted":       deleted,
+		"Error":         retentionErrors[r.URL.Query().Get("error")],
+		"Impersonating": h.isImpersonating(r),
+	})
+}
+
+// SaveRetentionPolicy sets how long the rows of a history table are kept.
+func (h *AdminHandler) SaveRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.retention == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	keepDays, err := retentionDays(r.FormValue("keep_days"))
+	if err != nil {
+		http.Redirect(w, r, "/admin/retention?error=invalid_days", http.StatusSeeOther)
+		return
+	}
+	thinAfterDays, err := retentionDays(r.FormValue("thin_after_days"))
+	if err != nil {
+		http.Redirect(w, r, "/admin/retention?error=invalid_days", http.StatusSeeOther)
+		return
+	}
+
+	policy := &models.RetentionPolicy{
+		TableName:     r.FormValue("table"),
+		KeepDays:      keepDays,
+		ThinAfterDays: thinAfterDays,
+	}
+	if err := h.retention.SetPolicy(policy); err != nil {
+		for target, code := range retentionErrorCodes {
+			if errors.Is(err, target) {
+				http.Redirect(w, r, "/admin/retention?error="+code, http.StatusSeeOther)
+				return
+			}
+		}
+		log.Printf("AdminHandler.SaveRetentionPolicy error: %v", err)
+		http.Redirect(w, r, "/admin/retention?error=save_failed", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s set retention of %s to keep %d days, thin after %d days", user.Email, policy.TableName, keepDays, thinAfterDays)
+
+	http.Redirect(w, r, "/admin/retention?saved="+policy.TableName, http.StatusSeeOther)
+}
+
+// RunRetention enforces the retention policies now rather than waiting for
+// the maintenance job.
+func (h *AdminHandler) RunRetention(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.retention == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	deleted, err := h.retention.Enforce(time.Now())
+	var total int64
+	for _, n := range deleted {
+		total += n
+	}
+	if err != nil {
+		log.Printf("AdminHandler.RunRetention error: %v", err)
+		http.Redirect(w, r, fmt.Sprintf("/admin/retention?deleted=%d&error=run_failed", total), http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s enforced retention policies, deleting %d rows", user.Email, total)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/retention?deleted=%d", total), http.StatusSeeOther)
+}
+
+// retentionDays parses a number of days, where empty means zero, keeping
+// rows forever.
+func retentionDays(v string) (int, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
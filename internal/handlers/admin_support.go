@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// SupportCookieName is the cookie holding the ID of the user an admin views
+// in support mode.
+const SupportCookieName = "support_user_id"
+
+// supportPaths are the pages an admin can view in support mode.
+var supportPaths = []string{"/dashboard", "/compare", "/accounts", "/transactions", "/goals"}
+
+// SetAuditService records support mode and anonymized exports in the audit
+// log.
+func (h *AdminHandler) SetAuditService(auditService *services.AuditService) {
+	h.auditService = auditService
+}
+
+// UserSupport starts support mode, where the admin views the user's pages
+// read-only while staying logged in as themselves. Unlike impersonation, no
+// session is created for the user.
+func (h *AdminHandler) UserSupport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if id == user.ID {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	targetUser, err := h.userRepo.GetByID(id)
+	if err != nil || targetUser == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SupportCookieName,
+		Value:    strconv.FormatInt(id, 10),
+		Path:     "/",
+		MaxAge:   3600, // 1 hour
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	h.audit(r, user.ID, id, services.AuditAdminSupportStarted, nil)
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// ExitSupport ends support mode and returns to the user's admin page.
+func (h *AdminHandler) ExitSupport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	clearSupportCookie(w)
+
+	cookie, err := r.Cookie(SupportCookieName)
+	if err != nil {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	id, err := strconv.ParseInt(cookie.Value, 10, 64)
+	if err != nil {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	h.audit(r, user.ID, id, services.AuditAdminSupportEnded, nil)
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", id), http.StatusSeeOther)
+}
+
+// SupportView serves the pages of supportPaths as the user viewed in support
+// mode, read-only and with every view logged. Admin pages are served to the
+// admin as usual and other pages are refused until support mode ends.
+func (h *AdminHandler) SupportView(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(SupportCookieName)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		admin := middleware.GetUser(r)
+		if admin == nil || !admin.IsAdmin || IsDemoMode() {
+			clearSupportCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		if path == "/logout" || path == "/health" || strings.HasPrefix(path, "/admin") || strings.HasPrefix(path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := strconv.ParseInt(cookie.Value, 10, 64)
+		if err != nil {
+			clearSupportCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+		targetUser, err := h.userRepo.GetByID(id)
+		if err != nil || targetUser == nil {
+			clearSupportCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !isSupportPath(path) {
+			http.Error(w, "This page is not available in support mode; exit support mode first", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Support mode is read-only", http.StatusForbidden)
+			return
+		}
+
+		h.audit(r, admin.ID, id, services.AuditAdminSupportViewed, map[string]string{"path": r.URL.RequestURI()})
+
+		// A copy, so the banner of the user's pages shows who is viewing
+		viewed := *targetUser
+		viewed.SupportViewer = admin.Name
+		ctx := context.WithValue(r.Context(), middleware.UserContextKey, &viewed)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// audit records an admin action on the user, if audit logging is on.
+func (h *AdminHandler) audit(r *http.Request, adminID, userID int64, action services.AuditAction, values any) {
+	if h.auditService == nil {
+		return
+	}
+	h.auditService.LogAction(userID, adminID, action, "user", userID, nil, values, r.RemoteAddr, r.UserAgent())
+}
+
+// isSupportPath returns true if the page can be viewed in support mode.
+func isSupportPath(path string) bool {
+	for _, p := range supportPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// clearSupportCookie ends support mode.
+func clearSupportCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   SupportCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/sync"
+)
+
+// maxSyncAllSpreadMinutes bounds the period a sync of all connections can be
+// spread over.
+const maxSyncAllSpreadMinutes = 24 * 60
+
+// SetSyncService enables syncing all connections from the admin dashboard.
+func (h *AdminHandler) SetSyncService(syncService *sync.Service) {
+	h.syncService = syncService
+}
+
+// SyncAll schedules a sync of every active connection that can be synced
+// without its owner logging in, spread over the requested number of minutes.
+func (h *AdminHandler) SyncAll(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.syncService == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	spread := sync.DefaultSyncAllSpread
+	if v := r.FormValue("spread_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 0 || minutes > maxSyncAllSpreadMinutes {
+			http.Redirect(w, r, "/admin?sync_all_error=invalid_spread", http.StatusSeeOther)
+			return
+		}
+		spread = time.Duration(minutes) * time.Minute
+	}
+
+	if _, err := h.syncService.StartSyncAll(spread); err != nil {
+		log.Printf("AdminHandler.SyncAll error: %v", err)
+		reason := "failed"
+		if errors.Is(err, sync.ErrSyncAllRunning) {
+			reason = "running"
+		}
+		http.Redirect(w, r, "/admin?sync_all_error="+reason, http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s started a sync of all connections over %v", user.Email, spread)
+
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
+// syncAllErrors are the messages shown for the sync_all_error query value.
+var syncAllErrors = map[string]string{
+	"invalid_spread": "The spread must be between 0 and 1440 minutes.",
+	"running":        "A sync of all connections is already running.",
+	"failed":         "The sync could not be started; see the server log.",
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// taxParameterErrors are the messages of the tax parameters page's error
+// codes.
+var taxParameterErrors = map[string]string{
+	"invalid_number": "Amounts and rates must be numbers",
+	"year":           "Year must be between 2000 and 2100",
+	"negative":       "Amounts cannot be negative",
+	"rate":           "Rates must be between 0 and 100",
+	"rate_order":     "The high stock income rate cannot be below the low rate",
+	"save_failed":    "The tax parameters could not be saved",
+	"delete_failed":  "The tax parameters could not be deleted",
+}
+
+// taxParameterErrorCodes maps tax parameter validation errors to error codes.
+var taxParameterErrorCodes = map[error]string{
+	services.ErrTaxYear:      "year",
+	services.ErrTaxNegative:  "negative",
+	services.ErrTaxRate:      "rate",
+	services.ErrTaxRateOrder: "rate_order",
+}
+
+// SetTaxParameterService enables the tax parameters page.
+func (h *AdminHandler) SetTaxParameterService(taxParams *services.TaxParameterService) {
+	h.taxParams = taxParams
+}
+
+// TaxParameters renders the Danish tax figures of each year.
+func (h *AdminHandler) TaxParameters(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.taxParams == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	params, err := h.taxParams.All()
+	if err != nil {
+		log.Printf("AdminHandler.TaxParameters error: %v", err)
+		http.Error(w, "Error loading tax parameters", http.StatusInternalServerError)
+		return
+	}
+
+	// The form for a new year starts from the latest year's figures
+	next := *params[0]
+	next.Year++
+
+	h.render(w, "admin-tax-parameters.html", map[string]any{
+		"Title":         "Tax Parameters",
+		"User":          user,
+		"ActiveNav":     "admin",
+		"Parameters":    params,
+		"Next":          next,
+		"Saved":         r.URL.Query().Get("saved"),
+		"Error":         taxParameterErrors[r.URL.Query().Get("error")],
+		"Impersonating": h.isImpersonating(r),
+	})
+}
+
+// SaveTaxParameters sets the tax figures of a year.
+func (h *AdminHandler) SaveTaxParameters(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.taxParams == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	params, err := parseTaxParameters(r)
+	if err != nil {
+		http.Redirect(w, r, "/admin/tax-parameters?error=invalid_number", http.StatusSeeOther)
+		return
+	}
+	if err := h.taxParams.Save(params); err != nil {
+		for target, code := range taxParameterErrorCodes {
+			if errors.Is(err, target) {
+				http.Redirect(w, r, "/admin/tax-parameters?error="+code, http.StatusSeeOther)
+				return
+			}
+		}
+		log.Printf("AdminHandler.SaveTaxParameters error: %v", err)
+		http.Redirect(w, r, "/admin/tax-parameters?error=save_failed", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s set the tax parameters of %d", user.Email, params.Year)
+
+	http.Redirect(w, r, "/admin/tax-parameters?saved="+strconv.Itoa(params.Year), http.StatusSeeOther)
+}
+
+// DeleteTaxParameters removes the tax figures of a year.
+func (h *AdminHandler) DeleteTaxParameters(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.taxParams == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil {
+		http.Error(w, "Invalid year", http.StatusBadRequest)
+		return
+	}
+	if err := h.taxParams.Delete(year); err != nil {
+		log.Printf("AdminHandler.DeleteTaxParameters error: %v", err)
+		http.Redirect(w, r, "/admin/tax-parameters?error=delete_failed", http.StatusSeeOther)
+		return
+	}
+	log.Printf("Admin %s deleted the tax parameters of %d", user.Email, year)
+
+	http.Redirect(w, r, "/admin/tax-parameters", http.StatusSeeOther)
+}
+
+// parseTaxParameters reads a year's tax figures from the form.
+func parseTaxParameters(r *http.Request) (*models.TaxParameters, error) {
+	year, err := strconv.Atoi(strings.TrimSpace(r.FormValue("year")))
+	if err != nil {
+		return nil, err
+	}
+	params := &models.TaxParameters{Year: year}
+	for name, field := range map[string]*float64{
+		"ask_max_deposit":         &params.ASKMaxDeposit,
+		"stock_gain_threshold":    &params.StockGainThreshold,
+		"stock_gain_low_rate":     &params.StockGainLowRate,
+		"stock_gain_high_rate":    &params.StockGainHighRate,
+		"ask_tax_rate":            &params.ASKTaxRate,
+		"pension_withdrawal_rate": &params.PensionWithdrawalRate,
+	} {
+		if *field, err = strconv.ParseFloat(strings.TrimSpace(r.FormValue(name)), 64); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// maxCommentLength is the longest comment accepted on a thread.
+const maxCommentLength = 2000
+
+// AdvisorHandler handles sharing accounts and goals with advisors, who get
+// read access to them, and the comment threads on shared items.
+type AdvisorHandler struct {
+	templates       map[string]*template.Template
+	shareRepo       *repository.AdvisorShareRepository
+	commentRepo     *repository.CommentRepository
+	userRepo        *repository.UserRepository
+	accountRepo     *repository.AccountRepository
+	goalRepo        *repository.GoalRepository
+	transactionRepo *repository.TransactionRepository
+	holdingRepo     *repository.HoldingRepository
+	netWorth        *services.NetWorthService
+}
+
+// NewAdvisorHandler creates a new AdvisorHandler.
+func NewAdvisorHandler(
+	templates map[string]*template.Template,
+	shareRepo *repository.AdvisorShareRepository,
+	commentRepo *repository.CommentRepository,
+	userRepo *repository.UserRepository,
+	accountRepo *repository.AccountRepository,
+	goalRepo *repository.GoalRepository,
+	transactionRepo *repository.TransactionRepository,
+	holdingRepo *repository.HoldingRepository,
+	netWorth *services.NetWorthService,
+) *AdvisorHandler {
+	return &AdvisorHandler{
+		templates:       templates,
+		shareRepo:       shareRepo,
+		commentRepo:     commentRepo,
+		userRepo:        userRepo,
+		accountRepo:     accountRepo,
+		goalRepo:        goalRepo,
+		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
+		netWorth:        netWorth,
+	}
+}
+
+// sharedGroup is the accounts and goals shared between a user and one other
+// user, either an advisor or an owner.
+type sharedGroup struct {
+	UserID   int64
+	Email    string
+	Accounts []*models.AdvisorShare
+	Goals    []*models.AdvisorShare
+}
+
+// groupShares groups shares by the other user: the advisor when byAdvisor is
+// set, otherwise the owner. Shares must be ordered by that user.
+func groupShares(shares []*models.AdvisorShare, byAdvisor bool) []*sharedGroup {
+	var groups []*sharedGroup
+	for _, share := range shares {
+		id, email := share.OwnerID, share.OwnerEmail
+		if byAdvisor {
+			id, email = share.AdvisorID, share.AdvisorEmail
+		}
+		if len(groups) == 0 || groups[len(groups)-1].UserID != id {
+			groups = append(groups, &sharedGroup{UserID: id, Email: email})
+		}
+		group := groups[len(groups)-1]
+		if share.AccountID != nil {
+			group.Accounts = append(group.Accounts, share)
+		} else {
+			group.Goals = append(group.Goals, share)
+		}
+	}
+	return groups
+}
+
+// List renders the advisors page: who the user shares with, and a form to
+// share accounts and goals with an advisor.
+func (h *AdvisorHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, user, nil)
+}
+
+// Save handles sharing accounts and goals with an advisor, by email. The
+// selection replaces whatever was shared with that advisor before.
+func (h *AdvisorHandler) Save(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if IsDemoMode() {
+		h.renderPage(w, user, map[string]any{"Error": "Sharing is disabled in demo mode"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Invalid form data"})
+		return
+	}
+
+	var accountIDs, goalIDs []int64
+	for _, value := range r.Form["account_ids"] {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if account, err := h.accountRepo.GetByID(id); err != nil || account == nil || account.UserID != user.ID {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		accountIDs = append(accountIDs, id)
+	}
+	for _, value := range r.Form["goal_ids"] {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if goal, err := h.goalRepo.GetByID(id); err != nil || goal == nil || goal.UserID != user.ID {
+			http.Error(w, "Goal not found", http.StatusNotFound)
+			return
+		}
+		goalIDs = append(goalIDs, id)
+	}
+	if len(accountIDs) == 0 && len(goalIDs) == 0 {
+		h.renderPage(w, user, map[string]any{"Error": "Select at least one account or goal to share"})
+		return
+	}
+
+	// An unknown email is answered like a share, so the form does not tell
+	// which email addresses have an account
+	email := strings.TrimSpace(r.FormValue("email"))
+	advisor, err := h.userRepo.GetByEmail(email)
+	if err != nil || advisor == nil {
+		http.Redirect(w, r, "/settings/advisors", http.StatusSeeOther)
+		return
+	}
+	if advisor.ID == user.ID {
+		h.renderPage(w, user, map[string]any{"Error": "You cannot share with yourself"})
+		return
+	}
+
+	if err := h.shareRepo.SetShares(user.ID, advisor.ID, accountIDs, goalIDs); err != nil {
+		log.Printf("Error sharing with advisor: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to share"})
+		return
+	}
+
+	http.Redirect(w, r, "/settings/advisors", http.StatusSeeOther)
+}
+
+// Delete handles revoking everything shared with an advisor.
+func (h *AdvisorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	advisorID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid advisor ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shareRepo.SetShares(user.ID, advisorID, nil, nil); err != nil {
+		log.Printf("Error revoking advisor: %v", err)
+		http.Error(w, "Failed to revoke advisor", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/advisors", http.StatusSeeOther)
+}
+
+// Shared renders the accounts and goals other users share with the user.
+func (h *AdvisorHandler) Shared(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	shares, err := h.shareRepo.GetByAdvisorID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching shared items: %v", err)
+		http.Error(w, "Error loading shared items", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "shared.html", map[string]any{
+		"Title":     "Shared with Me",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Owners":    groupShares(shares, false),
+	})
+}
+
+// AccountThread renders the summary and comment thread of an account, for its
+// owner or an advisor it is shared with.
+func (h *AdvisorHandler) AccountThread(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	account := h.sharedAccount(w, r, user)
+	if account == nil {
+		return
+	}
+
+	balance, err := h.transactionRepo.GetLatestBalance(account.ID)
+	if err != nil {
+		log.Printf("Error fetching balance: %v", err)
+	}
+	account.Balance = balance
+
+	transactions, err := h.transactionRepo.GetByAccountID(account.ID, 10, 0)
+	if err != nil {
+		log.Printf("Error fetching transactions: %v", err)
+	}
+	holdings, err := h.holdingRepo.GetByAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching holdings: %v", err)
+	}
+	comments, err := h.commentRepo.GetByAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching comments: %v", err)
+	}
+
+	h.render(w, "thread.html", map[string]any{
+		"Title":        account.Name,
+		"User":         user,
+		"ActiveNav":    "settings",
+		"Account":      account,
+		"Transactions": transactions,
+		"Holdings":     holdings,
+		"Comments":     comments,
+		"IsOwner":      account.UserID == user.ID,
+		"ThreadURL":    "/threads/accounts/" + strconv.FormatInt(account.ID, 10),
+	})
+}
+
+// GoalThread renders the progress and comment thread of a goal, for its owner
+// or an advisor it is shared with. Advisors see the progress as a percentage
+// only, as with the target it would give away the owner's total worth.
+func (h *AdvisorHandler) GoalThread(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	goal := h.sharedGoal(w, r, user)
+	if goal == nil {
+		return
+	}
+	setGoalProgress(h.netWorth, goal)
+
+	comments, err := h.commentRepo.GetByGoalID(goal.ID)
+	if err != nil {
+		log.Printf("Error fetching comments: %v", err)
+	}
+
+	h.render(w, "thread.html", map[string]any{
+		"Title":     goal.Name,
+		"User":      user,
+		"ActiveNav": "settings",
+		"Goal":      goal,
+		"Comments":  comments,
+		"IsOwner":   goal.UserID == user.ID,
+		"ThreadURL": "/threads/goals/" + strconv.FormatInt(goal.ID, 10),
+	})
+}
+
+// CommentAccount handles commenting on the thread of an account.
+func (h *AdvisorHandler) CommentAccount(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account := h.sharedAccount(w, r, user)
+	if account == nil {
+		return
+	}
+	h.addComment(w, r, &models.Comment{AuthorID: user.ID, AccountID: &account.ID},
+		"/threads/accounts/"+strconv.FormatInt(account.ID, 10))
+}
+
+// CommentGoal handles commenting on the thread of a goal.
+func (h *AdvisorHandler) CommentGoal(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	goal := h.sharedGoal(w, r, user)
+	if goal == nil {
+		return
+	}
+	h.addComment(w, r, &models.Comment{AuthorID: user.ID, GoalID: &goal.ID},
+		"/threads/goals/"+strconv.FormatInt(goal.ID, 10))
+}
+
+// DeleteComment handles deleting a comment, which only its author may do.
+func (h *AdvisorHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.commentRepo.GetByID(id)
+	if err != nil || comment == nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if comment.AuthorID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.commentRepo.Delete(id); err != nil {
+		log.Printf("Error deleting comment: %v", err)
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	if comment.AccountID != nil {
+		http.Redirect(w, r, "/threads/accounts/"+strconv.FormatInt(*comment.AccountID, 10), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/threads/goals/"+strconv.FormatInt(*comment.GoalID, 10), http.StatusSeeOther)
+}
+
+// addComment stores the posted comment body on a thread and redirects back to
+// it.
+func (h *AdvisorHandler) addComment(w http.ResponseWriter, r *http.Request, comment *models.Comment, threadURL string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	comment.Body = strings.TrimSpace(r.FormValue("body"))
+	if comment.Body == "" || len(comment.Body) > maxCommentLength {
+		http.Error(w, "Comment must be 1-2000 characters", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.commentRepo.Create(comment); err != nil {
+		log.Printf("Error creating comment: %v", err)
+		http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, threadURL, http.StatusSeeOther)
+}
+
+// sharedAccount returns the account of the route if the user owns it or it
+// is shared with them. Otherwise it responds 404 Not Found and returns nil.
+func (h *AdvisorHandler) sharedAccount(w http.ResponseWriter, r *http.Request, user *models.User) *models.Account {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return nil
+	}
+
+	// Accounts not shared with the user are reported as missing
+	account, err := h.accountRepo.GetByID(id)
+	if err == nil && account != nil && account.UserID != user.ID {
+		var shared bool
+		shared, err = h.shareRepo.SharesAccount(user.ID, id)
+		if !shared {
+			account = nil
+		}
+	}
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil
+	}
+	return account
+}
+
+// sharedGoal returns the goal of the route if the user owns it or it is
+// shared with them. Otherwise it responds 404 Not Found and returns nil.
+func (h *AdvisorHandler) sharedGoal(w http.ResponseWriter, r *http.Request, user *models.User) *models.Goal {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return nil
+	}
+
+	// Goals not shared with the user are reported as missing
+	goal, err := h.goalRepo.GetByID(id)
+	if err == nil && goal != nil && goal.UserID != user.ID {
+		var shared bool
+		shared, err = h.shareRepo.SharesGoal(user.ID, id)
+		if !shared {
+			goal = nil
+		}
+	}
+	if err != nil || goal == nil {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return nil
+	}
+	return goal
+}
+
+// renderPage renders the advisors page with extra data, such as an error.
+func (h *AdvisorHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
+	shares, err := h.shareRepo.GetByOwnerID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching advisor shares: %v", err)
+		http.Error(w, "Error loading advisors", http.StatusInternalServerError)
+		return
+	}
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading advisors", http.StatusInternalServerError)
+		return
+	}
+	goals, err := h.goalRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching goals: %v", err)
+		http.Error(w, "Error loading advisors", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "Advisors",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Advisors":  groupShares(shares, true),
+		"Accounts":  accounts,
+		"Goals":     goals,
+		"DemoMode":  IsDemoMode(),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "advisors.html", data)
+}
+
+// render renders a template with the given data.
+func (h *AdvisorHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
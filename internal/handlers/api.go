@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// maxAPIBodySize limits the JSON body of REST API requests.
+const maxAPIBodySize = 1 << 20 // 1 MB
+
+// APIHandler serves the JSON REST API under /api/v1: accounts, transactions,
+// categories, goals and holdings of the signed-in user. It validates like the
+// HTML handlers, but takes and returns JSON. PUT replaces a resource like
+// saving its edit form, so fields left out are cleared or get their default;
+// a client can PUT back what it got, with its changes. Resources of other
+// users are not found.
+type APIHandler struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	categoryRepo    *repository.CategoryRepository
+	goalRepo        *repository.GoalRepository
+	holdingRepo     *repository.HoldingRepository
+	mappingRepo     *repository.AccountMappingRepository
+	netWorth        *services.NetWorthService
+}
+
+// NewAPIHandler creates a new APIHandler.
+func NewAPIHandler(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	categoryRepo *repository.CategoryRepository,
+	goalRepo *repository.GoalRepository,
+	holdingRepo *repository.HoldingRepository,
+	mappingRepo *repository.AccountMappingRepository,
+	netWorth *services.NetWorthService,
+) *APIHandler {
+	return &APIHandler{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		categoryRepo:    categoryRepo,
+		goalRepo:        goalRepo,
+		holdingRepo:     holdingRepo,
+		mappingRepo:     mappingRepo,
+		netWorth:        netWorth,
+	}
+}
+
+// writeAPIJSON writes v as a JSON response with the given status.
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding API response: %v", err)
+	}
+}
+
+// decodeAPIJSON decodes the request body into v. Fields that cannot be set,
+// such as id and balance, are ignored. Writes an error response and returns
+// false if the body is invalid.
+func decodeAPIJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize)).Decode(v); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// apiID parses the ID in the URL. Writes an error response and returns false
+// if it is invalid.
+func apiID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// apiDate parses an optional date, as YYYY-MM-DD or as the RFC 3339 time the
+// API returns dates in. Empty is nil.
+func apiDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return &d, nil
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, errors.New("use YYYY-MM-DD")
+	}
+	return &d, nil
+}
+
+// applyAPIVersion sets the version an update is checked against to the
+// updated_at the client read, so edits made since are rejected. Without it
+// the update is not checked. Writes an error response and returns false if
+// it is invalid.
+func applyAPIVersion(w http.ResponseWriter, s string, updatedAt *time.Time) bool {
+	if s == "" {
+		return true
+	}
+	version, err := parseVersion(s)
+	if err != nil {
+		http.Error(w, "Invalid updated_at", http.StatusBadRequest)
+		return false
+	}
+	*updatedAt = version
+	return true
+}
+
+// writeAPIUpdateError writes the response of a failed update: 409 if the
+// resource changed since the client read it.
+func writeAPIUpdateError(w http.ResponseWriter, err error, what string) {
+	if errors.Is(err, repository.ErrStaleUpdate) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("Error updating %s: %v", what, err)
+	http.Error(w, "Failed to update "+what, http.StatusInternalServerError)
+}
+
+// ownedCategoryID returns the category ID if it is one of the user's
+// categories; 0 is no category. Returns false for other categories.
+func (h *APIHandler) ownedCategoryID(userID, categoryID int64) (*int64, bool) {
+	if categoryID == 0 {
+		return nil, true
+	}
+	category, err := h.categoryRepo.GetByID(categoryID)
+	if err != nil || category == nil || category.UserID != userID {
+		return nil, false
+	}
+	return &categoryID, true
+}
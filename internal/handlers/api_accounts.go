@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// apiAccountInput is the body of creating or replacing an account.
+type apiAccountInput struct {
+	Name          string  `json:"name"`
+	Currency      string  `json:"currency"`
+	CategoryID    int64   `json:"category_id"`
+	IsLiability   bool    `json:"is_liability"`
+	IsActive      *bool   `json:"is_active"` // Default true
+	Notes         string  `json:"notes"`
+	OpenedAt      string  `json:"opened_at"`
+	ClosedAt      string  `json:"closed_at"`
+	InterestRate  float64 `json:"interest_rate"`
+	NetWorthGroup string  `json:"net_worth_group"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+// apply validates the input and sets it on account. Returns an error message
+// if it is invalid.
+func (in *apiAccountInput) apply(h *APIHandler, userID int64, account *models.Account) string {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return "Name is required"
+	}
+	currency := strings.TrimSpace(in.Currency)
+	if currency == "" {
+		currency = "DKK"
+	}
+	categoryID, ok := h.ownedCategoryID(userID, in.CategoryID)
+	if !ok {
+		return "Invalid category"
+	}
+
+	openedAt, err := apiDate(in.OpenedAt)
+	if err != nil {
+		return "Invalid opening date"
+	}
+	closedAt, err := apiDate(in.ClosedAt)
+	if err != nil {
+		return "Invalid closing date"
+	}
+	if openedAt != nil && closedAt != nil && closedAt.Before(*openedAt) {
+		return "Closing date cannot be before the opening date"
+	}
+
+	// Only liabilities accrue interest
+	interestRate := 0.0
+	if in.IsLiability {
+		if in.InterestRate < 0 || in.InterestRate > 100 {
+			return "Interest rate must be a percentage between 0 and 100"
+		}
+		interestRate = in.InterestRate
+	}
+	group := in.NetWorthGroup
+	if !slices.Contains(models.NetWorthGroups, group) {
+		group = ""
+	}
+
+	account.Name = name
+	account.Currency = currency
+	account.CategoryID = categoryID
+	account.IsLiability = in.IsLiability
+	account.IsActive = in.IsActive == nil || *in.IsActive
+	account.Notes = strings.TrimSpace(in.Notes)
+	account.OpenedAt = openedAt
+	account.ClosedAt = closedAt
+	account.InterestRate = interestRate
+	account.NetWorthGroup = group
+	return ""
+}
+
+// ListAccounts returns the user's accounts with their balances.
+func (h *APIHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Failed to load accounts", http.StatusInternalServerError)
+		return
+	}
+	for _, account := range accounts {
+		account.Balance, _ = h.transactionRepo.GetLatestBalance(account.ID)
+	}
+	writeAPIJSON(w, http.StatusOK, accounts)
+}
+
+// GetAccount returns an account with its balance.
+func (h *APIHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.ownedAccount(w, r)
+	if !ok {
+		return
+	}
+	account.Balance, _ = h.transactionRepo.GetLatestBalance(account.ID)
+	writeAPIJSON(w, http.StatusOK, account)
+}
+
+// CreateAccount creates an account.
+func (h *APIHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var in apiAccountInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	account := &models.Account{UserID: user.ID}
+	if errMsg := in.apply(h, user.ID, account); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.accountRepo.Create(account)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			http.Error(w, "An account with this name already exists", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error creating account: %v", err)
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+	h.writeAccount(w, id, http.StatusCreated)
+}
+
+// UpdateAccount replaces an account.
+func (h *APIHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	account, ok := h.ownedAccount(w, r)
+	if !ok {
+		return
+	}
+
+	var in apiAccountInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	if errMsg := in.apply(h, user.ID, account); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	if !applyAPIVersion(w, in.UpdatedAt, &account.UpdatedAt) {
+		return
+	}
+
+	if err := h.accountRepo.Update(account); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			http.Error(w, "An account with this name already exists", http.StatusBadRequest)
+			return
+		}
+		writeAPIUpdateError(w, err, "account")
+		return
+	}
+	h.writeAccount(w, account.ID, http.StatusOK)
+}
+
+// DeleteAccount deletes an account with its transactions. Accounts that
+// brokers sync into are refused, as the web app asks what to do with their
+// mappings first.
+func (h *APIHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	account, ok := h.ownedAccount(w, r)
+	if !ok {
+		return
+	}
+
+	mappings, err := h.mappingRepo.GetAllByLocalAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching mappings of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	if len(mappings) > 0 {
+		http.Error(w, fmt.Sprintf("Broker accounts sync into this account; delete it from the web app to detach or move %d mapping(s)", len(mappings)), http.StatusConflict)
+		return
+	}
+
+	if err := h.accountRepo.Delete(account.ID); err != nil {
+		log.Printf("Error deleting account: %v", err)
+		http.Error(w, "Failed to delete account", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedAccount returns the account in the URL if it belongs to the user.
+// Writes an error response and returns false otherwise.
+func (h *APIHandler) ownedAccount(w http.ResponseWriter, r *http.Request) (*models.Account, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return nil, false
+	}
+	return h.accountOf(w, middleware.GetUser(r).ID, id)
+}
+
+// accountOf returns an account if it belongs to the user. Writes an error
+// response and returns false otherwise.
+func (h *APIHandler) accountOf(w http.ResponseWriter, userID, accountID int64) (*models.Account, bool) {
+	account, err := h.accountRepo.GetByID(accountID)
+	if err != nil || account == nil || account.UserID != userID {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil, false
+	}
+	return account, true
+}
+
+// writeAccount writes an account as stored, with its balance.
+func (h *APIHandler) writeAccount(w http.ResponseWriter, id int64, status int) {
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		log.Printf("Error fetching account %d: %v", id, err)
+		http.Error(w, "Failed to load account", http.StatusInternalServerError)
+		return
+	}
+	account.Balance, _ = h.transactionRepo.GetLatestBalance(id)
+	writeAPIJSON(w, status, account)
+}
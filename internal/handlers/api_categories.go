@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// apiCategoryInput is the body of creating or replacing a category.
+type apiCategoryInput struct {
+	Name           string   `json:"name"`
+	Color          string   `json:"color"` // Default #6366f1
+	Icon           string   `json:"icon"`
+	SortOrder      *int     `json:"sort_order"` // Default 0, or unchanged
+	ExpectedReturn *float64 `json:"expected_return"`
+	Liquidity      string   `json:"liquidity"`
+	Description    string   `json:"description"`
+}
+
+// apply validates the input and sets it on category. Returns an error
+// message if it is invalid.
+func (in *apiCategoryInput) apply(h *APIHandler, userID int64, category *models.Category) string {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return "Name is required"
+	}
+	exists, err := h.categoryRepo.NameExists(userID, name, category.ID)
+	if err != nil {
+		log.Printf("Error checking category name: %v", err)
+		return "An error occurred"
+	}
+	if exists {
+		return "A category with this name already exists"
+	}
+	if in.ExpectedReturn != nil && (*in.ExpectedReturn < -100 || *in.ExpectedReturn > 100) {
+		return "Expected return must be a percentage between -100 and 100"
+	}
+	liquidity, ok := parseLiquidity(in.Liquidity)
+	if !ok {
+		return "Unknown liquidity"
+	}
+	color := strings.TrimSpace(in.Color)
+	if color == "" {
+		color = "#6366f1"
+	}
+
+	category.Name = name
+	category.Color = color
+	category.Icon = strings.TrimSpace(in.Icon)
+	if in.SortOrder != nil {
+		category.SortOrder = *in.SortOrder
+	}
+	category.ExpectedReturn = in.ExpectedReturn
+	category.Liquidity = liquidity
+	category.Description = strings.TrimSpace(in.Description)
+	return ""
+}
+
+// ListCategories returns the user's categories.
+func (h *APIHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	categories, err := h.categoryRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		http.Error(w, "Failed to load categories", http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, categories)
+}
+
+// GetCategory returns a category.
+func (h *APIHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := h.ownedCategory(w, r)
+	if !ok {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, category)
+}
+
+// CreateCategory creates a category.
+func (h *APIHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var in apiCategoryInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	category := &models.Category{UserID: user.ID}
+	if errMsg := in.apply(h, user.ID, category); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.categoryRepo.Create(category)
+	if err != nil {
+		log.Printf("Error creating category: %v", err)
+		http.Error(w, "Failed to create category", http.StatusInternalServerError)
+		return
+	}
+	h.writeCategory(w, id, http.StatusCreated)
+}
+
+// UpdateCategory replaces a category.
+func (h *APIHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	category, ok := h.ownedCategory(w, r)
+	if !ok {
+		return
+	}
+
+	var in apiCategoryInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	if errMsg := in.apply(h, user.ID, category); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.categoryRepo.Update(category); err != nil {
+		writeAPIUpdateError(w, err, "category")
+		return
+	}
+	h.writeCategory(w, category.ID, http.StatusOK)
+}
+
+// DeleteCategory deletes a category. Its accounts are left without one.
+func (h *APIHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := h.ownedCategory(w, r)
+	if !ok {
+		return
+	}
+	if err := h.categoryRepo.Delete(category.ID); err != nil {
+		log.Printf("Error deleting category: %v", err)
+		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedCategory returns the category in the URL if it belongs to the user.
+// Writes an error response and returns false otherwise.
+func (h *APIHandler) ownedCategory(w http.ResponseWriter, r *http.Request) (*models.Category, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return nil, false
+	}
+	category, err := h.categoryRepo.GetByID(id)
+	if err != nil || category == nil || category.UserID != middleware.GetUser(r).ID {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return nil, false
+	}
+	return category, true
+}
+
+// writeCategory writes a category as stored.
+func (h *APIHandler) writeCategory(w http.ResponseWriter, id int64, status int) {
+	category, err := h.categoryRepo.GetByID(id)
+	if err != nil || category == nil {
+		log.Printf("Error fetching category %d: %v", id, err)
+		http.Error(w, "Failed to load category", http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, status, category)
+}
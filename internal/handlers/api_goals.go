@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// apiGoalInput is the body of creating or replacing a goal.
+type apiGoalInput struct {
+	Name           string  `json:"name"`
+	TargetAmount   float64 `json:"target_amount"`
+	TargetCurrency string  `json:"target_currency"` // Default the user's currency
+	Deadline       string  `json:"deadline"`
+	ReachedDate    string  `json:"reached_date"`
+	CategoryID     int64   `json:"category_id"` // 0 = net worth
+	Description    string  `json:"description"`
+	UpdatedAt      string  `json:"updated_at"`
+
+	// Accounts saved into for the goal, and the amount planned to go into
+	// them each month
+	FundingAccountIDs   []int64 `json:"funding_account_ids"`
+	MonthlyContribution float64 `json:"monthly_contribution"`
+}
+
+// apply validates the input and sets it on goal. Returns an error message if
+// it is invalid.
+func (in *apiGoalInput) apply(h *APIHandler, user *models.User, goal *models.Goal) string {
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return "Name is required"
+	}
+	if in.TargetAmount <= 0 {
+		return "Target amount must be a positive number"
+	}
+	currency := strings.TrimSpace(in.TargetCurrency)
+	if currency == "" {
+		currency = user.DefaultCurrency
+	}
+	deadline, err := apiDate(in.Deadline)
+	if err != nil {
+		return "Invalid deadline"
+	}
+	reachedDate, err := apiDate(in.ReachedDate)
+	if err != nil {
+		return "Invalid reached date"
+	}
+	categoryID, ok := h.ownedCategoryID(user.ID, in.CategoryID)
+	if !ok {
+		return "Invalid category"
+	}
+	if in.MonthlyContribution < 0 {
+		return "Monthly contribution must be zero or a positive number"
+	}
+
+	goal.Name = name
+	goal.TargetAmount = in.TargetAmount
+	goal.TargetCurrency = currency
+	goal.Deadline = deadline
+	goal.ReachedDate = reachedDate
+	goal.CategoryID = categoryID
+	goal.Description = strings.TrimSpace(in.Description)
+	goal.FundingAccountIDs = in.FundingAccountIDs
+	goal.MonthlyContribution = in.MonthlyContribution
+	return ""
+}
+
+// ListGoals returns the user's goals with their progress.
+func (h *APIHandler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	goals, err := h.goalRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching goals: %v", err)
+		http.Error(w, "Failed to load goals", http.StatusInternalServerError)
+		return
+	}
+	for _, goal := range goals {
+		setGoalProgress(h.netWorth, goal)
+	}
+	writeAPIJSON(w, http.StatusOK, goals)
+}
+
+// GetGoal returns a goal with its progress.
+func (h *APIHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+	setGoalProgress(h.netWorth, goal)
+	writeAPIJSON(w, http.StatusOK, goal)
+}
+
+// CreateGoal creates a goal.
+func (h *APIHandler) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var in apiGoalInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	goal := &models.Goal{UserID: user.ID}
+	if errMsg := in.apply(h, user, goal); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.goalRepo.Create(goal)
+	if err != nil {
+		log.Printf("Error creating goal: %v", err)
+		http.Error(w, "Failed to create goal", http.StatusInternalServerError)
+		return
+	}
+	h.writeGoal(w, id, http.StatusCreated)
+}
+
+// UpdateGoal replaces a goal.
+func (h *APIHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+
+	var in apiGoalInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	if errMsg := in.apply(h, user, goal); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	if !applyAPIVersion(w, in.UpdatedAt, &goal.UpdatedAt) {
+		return
+	}
+
+	if err := h.goalRepo.Update(goal); err != nil {
+		writeAPIUpdateError(w, err, "goal")
+		return
+	}
+	h.writeGoal(w, goal.ID, http.StatusOK)
+}
+
+// DeleteGoal deletes a goal.
+func (h *APIHandler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	goal, ok := h.ownedGoal(w, r)
+	if !ok {
+		return
+	}
+	if err := h.goalRepo.Delete(goal.ID); err != nil {
+		log.Printf("Error deleting goal: %v", err)
+		http.Error(w, "Failed to delete goal", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedGoal returns the goal in the URL if it belongs to the user. Writes an
+// error response and returns false otherwise.
+func (h *APIHandler) ownedGoal(w http.ResponseWriter, r *http.Request) (*models.Goal, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return nil, false
+	}
+	goal, err := h.goalRepo.GetByID(id)
+	if err != nil || goal == nil || goal.UserID != middleware.GetUser(r).ID {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return nil, false
+	}
+	return goal, true
+}
+
+// writeGoal writes a goal as stored, with its progress.
+func (h *APIHandler) writeGoal(w http.ResponseWriter, id int64, status int) {
+	goal, err := h.goalRepo.GetByID(id)
+	if err != nil || goal == nil {
+		log.Printf("Error fetching goal %d: %v", id, err)
+		http.Error(w, "Failed to load goal", http.StatusInternalServerError)
+		return
+	}
+	setGoalProgress(h.netWorth, goal)
+	writeAPIJSON(w, status, goal)
+}
+
+// setGoalProgress sets the progress of a goal as the goals page shows it:
+// the user's net worth, or that of the goal's category, as a percentage of
+// the target, up to 100.
+func setGoalProgress(netWorth *services.NetWorthService, goal *models.Goal) {
+	worth, err := netWorth.Compute(goal.UserID, nil)
+	if err != nil {
+		log.Printf("Error computing net worth: %v", err)
+		return
+	}
+	if goal.TargetAmount > 0 {
+		goal.Progress = min(worth.ForGoal(goal)/goal.TargetAmount*100, 100)
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// apiHoldingInput is the body of creating or replacing a manual holding.
+// The account of a holding cannot be changed.
+type apiHoldingInput struct {
+	AccountID      int64   `json:"account_id"`
+	Symbol         string  `json:"symbol"`
+	Name           string  `json:"name"` // Default the symbol
+	Quantity       float64 `json:"quantity"`
+	AvgPrice       float64 `json:"avg_price"`
+	CurrentPrice   float64 `json:"current_price"`
+	Currency       string  `json:"currency"` // Default the account's currency
+	InstrumentType string  `json:"instrument_type"`
+}
+
+// apply validates the input like a row of a holdings import and sets it on
+// holding. Returns an error message if it is invalid.
+func (in *apiHoldingInput) apply(account *models.Account, holding *models.Holding) string {
+	symbol := strings.ToUpper(strings.TrimSpace(in.Symbol))
+	if symbol == "" {
+		return "Symbol is required"
+	}
+	if in.Quantity <= 0 {
+		return "Quantity must be positive"
+	}
+	if in.CurrentPrice < 0 || in.AvgPrice < 0 {
+		return "Prices cannot be negative"
+	}
+	currency := strings.ToUpper(strings.TrimSpace(in.Currency))
+	if currency == "" {
+		currency = account.Currency
+	}
+	if len(currency) != 3 {
+		return "Invalid currency"
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		name = symbol
+	}
+
+	holding.Symbol = symbol
+	holding.Name = name
+	holding.Quantity = in.Quantity
+	holding.AvgPrice = in.AvgPrice
+	holding.CurrentPrice = in.CurrentPrice
+	holding.CurrentValue = in.Quantity * in.CurrentPrice
+	holding.Currency = currency
+	holding.InstrumentType = strings.TrimSpace(in.InstrumentType)
+	return ""
+}
+
+// ListHoldings returns the holdings of one of the user's accounts, with
+// ?account=, or of all of them.
+func (h *APIHandler) ListHoldings(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var accounts []*models.Account
+	if v := r.URL.Query().Get("account"); v != "" {
+		accountID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid account", http.StatusBadRequest)
+			return
+		}
+		account, ok := h.accountOf(w, user.ID, accountID)
+		if !ok {
+			return
+		}
+		accounts = []*models.Account{account}
+	} else {
+		var err error
+		if accounts, err = h.accountRepo.GetByUserID(user.ID); err != nil {
+			log.Printf("Error fetching accounts: %v", err)
+			http.Error(w, "Failed to load holdings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	holdings := []*models.Holding{}
+	for _, account := range accounts {
+		accountHoldings, err := h.holdingRepo.GetByAccountID(account.ID)
+		if err != nil {
+			log.Printf("Error fetching holdings of account %d: %v", account.ID, err)
+			http.Error(w, "Failed to load holdings", http.StatusInternalServerError)
+			return
+		}
+		holdings = append(holdings, accountHoldings...)
+	}
+	writeAPIJSON(w, http.StatusOK, holdings)
+}
+
+// GetHolding returns a holding.
+func (h *APIHandler) GetHolding(w http.ResponseWriter, r *http.Request) {
+	holding, _, ok := h.ownedHolding(w, r)
+	if !ok {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, holding)
+}
+
+// CreateHolding adds a manual holding to an account without a broker
+// connection.
+func (h *APIHandler) CreateHolding(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var in apiHoldingInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	account, ok := h.accountOf(w, user.ID, in.AccountID)
+	if !ok {
+		return
+	}
+
+	// Broker-synced accounts manage their own holdings
+	existing, err := h.holdingRepo.GetByAccountID(account.ID)
+	if err != nil {
+		log.Printf("Error fetching holdings: %v", err)
+		http.Error(w, "Failed to create holding", http.StatusInternalServerError)
+		return
+	}
+	for _, hld := range existing {
+		if hld.ExternalID != "" {
+			http.Error(w, "Holdings of accounts with a broker connection are synced", http.StatusConflict)
+			return
+		}
+	}
+
+	holding := &models.Holding{AccountID: account.ID}
+	if errMsg := in.apply(account, holding); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.holdingRepo.Create(holding)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			http.Error(w, "The account already holds this symbol", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error creating holding: %v", err)
+		http.Error(w, "Failed to create holding", http.StatusInternalServerError)
+		return
+	}
+	h.writeHolding(w, id, http.StatusCreated)
+}
+
+// UpdateHolding replaces a manual holding.
+func (h *APIHandler) UpdateHolding(w http.ResponseWriter, r *http.Request) {
+	holding, account, ok := h.ownedHolding(w, r)
+	if !ok {
+		return
+	}
+	if holding.ExternalID != "" {
+		http.Error(w, "Synced holdings cannot be edited", http.StatusConflict)
+		return
+	}
+
+	var in apiHoldingInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	if errMsg := in.apply(account, holding); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.holdingRepo.Update(holding); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			http.Error(w, "The account already holds this symbol", http.StatusBadRequest)
+			return
+		}
+		writeAPIUpdateError(w, err, "holding")
+		return
+	}
+	h.writeHolding(w, holding.ID, http.StatusOK)
+}
+
+// DeleteHolding deletes a manual holding.
+func (h *APIHandler) DeleteHolding(w http.ResponseWriter, r *http.Request) {
+	holding, _, ok := h.ownedHolding(w, r)
+	if !ok {
+		return
+	}
+	if holding.ExternalID != "" {
+		http.Error(w, "Synced holdings cannot be deleted", http.StatusConflict)
+		return
+	}
+	if err := h.holdingRepo.Delete(holding.ID); err != nil {
+		log.Printf("Error deleting holding: %v", err)
+		http.Error(w, "Failed to delete holding", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedHolding returns the holding in the URL and its account if the account
+// belongs to the user. Writes an error response and returns false otherwise.
+func (h *APIHandler) ownedHolding(w http.ResponseWriter, r *http.Request) (*models.Holding, *models.Account, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	holding, err := h.holdingRepo.GetByID(id)
+	if err == nil && holding != nil {
+		account, _ := h.accountRepo.GetByID(holding.AccountID)
+		if account != nil && account.UserID == middleware.GetUser(r).ID {
+			return holding, account, true
+		}
+	}
+	http.Error(w, "Holding not found", http.StatusNotFound)
+	return nil, nil, false
+}
+
+// writeHolding writes a holding as stored.
+func (h *APIHandler) writeHolding(w http.ResponseWriter, id int64, status int) {
+	holding, err := h.holdingRepo.GetByID(id)
+	if err != nil || holding == nil {
+		log.Printf("Error fetching holding %d: %v", id, err)
+		http.Error(w, "Failed to load holding", http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, status, holding)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// maxAPIKeyNameLength bounds the name of an API key.
+const maxAPIKeyNameLength = 100
+
+// APIKeyHandler handles account-scoped API keys: managing them in settings
+// and the automation endpoints they authenticate.
+type APIKeyHandler struct {
+	templates       map[string]*template.Template
+	keyRepo         *repository.AccountAPIKeyRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	userRepo        *repository.UserRepository
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(
+	templates map[string]*template.Template,
+	keyRepo *repository.AccountAPIKeyRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	userRepo *repository.UserRepository,
+) *APIKeyHandler {
+	return &APIKeyHandler{
+		templates:       templates,
+		keyRepo:         keyRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		userRepo:        userRepo,
+	}
+}
+
+// List renders the API keys page.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, user, nil)
+}
+
+// Create handles creating an API key for one of the user's accounts. The key
+// is shown once on the rendered page; only its hash is stored.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if IsDemoMode() {
+		h.renderPage(w, user, map[string]any{"Error": "API keys are disabled in demo mode"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Invalid form data"})
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		h.renderPage(w, user, map[string]any{"Error": "Name must be 1-100 characters"})
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.FormValue("account_id"), 10, 64)
+	if err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Choose an account"})
+		return
+	}
+
+	// Consistent error to prevent enumeration
+	account, err := h.accountRepo.GetByID(accountID)
+	if err != nil || account == nil || account.UserID != user.ID {
+		h.renderPage(w, user, map[string]any{"Error": "Account not found"})
+		return
+	}
+
+	key, hash, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		log.Printf("Error generating API key: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to create API key"})
+		return
+	}
+
+	if _, err := h.keyRepo.Create(&models.AccountAPIKey{
+		UserID:    user.ID,
+		AccountID: account.ID,
+		Name:      name,
+		KeyHash:   hash,
+		KeyPrefix: prefix,
+	}); err != nil {
+		log.Printf("Error creating API key: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to create API key"})
+		return
+	}
+
+	h.renderPage(w, user, map[string]any{
+		"NewKey":        key,
+		"NewKeyAccount": account,
+	})
+}
+
+// Delete handles revoking an API key.
+func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.keyRepo.GetByID(id)
+	if err != nil || key == nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if key.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.keyRepo.Delete(id); err != nil {
+		log.Printf("Error deleting API key: %v", err)
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/api-keys", http.StatusSeeOther)
+}
+
+// renderPage renders the API keys page with extra data, such as an error or
+// a newly created key.
+func (h *APIKeyHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
+	keys, err := h.keyRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching API keys: %v", err)
+		http.Error(w, "Error loading API keys", http.StatusInternalServerError)
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "API Keys",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Keys":      keys,
+		"Accounts":  accounts,
+		"DemoMode":  IsDemoMode(),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "api-keys.html", data)
+}
+
+// render renders a template with the given data.
+func (h *APIKeyHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// APITokenHandler handles managing user-wide API tokens in settings.
+type APITokenHandler struct {
+	templates map[string]*template.Template
+	tokenRepo *repository.APITokenRepository
+}
+
+// NewAPITokenHandler creates a new APITokenHandler.
+func NewAPITokenHandler(templates map[string]*template.Template, tokenRepo *repository.APITokenRepository) *APITokenHandler {
+	return &APITokenHandler{
+		templates: templates,
+		tokenRepo: tokenRepo,
+	}
+}
+
+// List renders the API tokens page.
+func (h *APITokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, user, nil)
+}
+
+// Create handles creating an API token. The token is shown once on the
+// rendered page; only its hash is stored.
+func (h *APITokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if IsDemoMode() {
+		h.renderPage(w, user, map[string]any{"Error": "API tokens are disabled in demo mode"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Invalid form data"})
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		h.renderPage(w, user, map[string]any{"Error": "Name must be 1-100 characters"})
+		return
+	}
+
+	scope := r.FormValue("scope")
+	if scope != models.APITokenScopeReadWrite {
+		scope = models.APITokenScopeRead
+	}
+
+	value, hash, prefix, err := auth.GenerateAPIToken()
+	if err != nil {
+		log.Printf("Error generating API token: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to create API token"})
+		return
+	}
+
+	token := &models.APIToken{
+		UserID:      user.ID,
+		Name:        name,
+		Scope:       scope,
+		TokenHash:   hash,
+		TokenPrefix: prefix,
+	}
+	if _, err := h.tokenRepo.Create(token); err != nil {
+		log.Printf("Error creating API token: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to create API token"})
+		return
+	}
+
+	h.renderPage(w, user, map[string]any{
+		"NewToken":      value,
+		"NewTokenEntry": token,
+	})
+}
+
+// Delete handles revoking an API token.
+func (h *APITokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid API token ID", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.tokenRepo.GetByID(id)
+	if err != nil || token == nil {
+		http.Error(w, "API token not found", http.StatusNotFound)
+		return
+	}
+	if token.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.tokenRepo.Delete(id); err != nil {
+		log.Printf("Error deleting API token: %v", err)
+		http.Error(w, "Failed to revoke API token", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/api-tokens", http.StatusSeeOther)
+}
+
+// renderPage renders the API tokens page with extra data, such as an error
+// or a newly created token.
+func (h *APITokenHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
+	tokens, err := h.tokenRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching API tokens: %v", err)
+		http.Error(w, "Error loading API tokens", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "API Tokens",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Tokens":    tokens,
+		"DemoMode":  IsDemoMode(),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "api-tokens.html", data)
+}
+
+// render renders a template with the given data.
+func (h *APITokenHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
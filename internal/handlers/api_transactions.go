@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// apiTransactionInput is the body of creating or replacing a transaction.
+// The account of a transaction cannot be changed.
+type apiTransactionInput struct {
+	AccountID       int64    `json:"account_id"`
+	Amount          *float64 `json:"amount"`
+	Description     string   `json:"description"`
+	CategoryID      int64    `json:"category_id"`
+	TransactionDate string   `json:"transaction_date"` // Default today, or unchanged
+	UpdatedAt       string   `json:"updated_at"`
+}
+
+// apply validates the input and sets it on txn; without a date, txn keeps
+// its date. Returns an error message if it is invalid.
+func (in *apiTransactionInput) apply(h *APIHandler, userID int64, txn *models.Transaction) string {
+	if in.Amount == nil {
+		return "Amount is required"
+	}
+	description := strings.TrimSpace(in.Description)
+	if len(description) > maxAPITransactionDescriptionLength {
+		return "Description is too long"
+	}
+	categoryID, ok := h.ownedCategoryID(userID, in.CategoryID)
+	if !ok {
+		return "Invalid category"
+	}
+	date, err := apiDate(in.TransactionDate)
+	if err != nil {
+		return "Invalid transaction date"
+	}
+
+	txn.Amount = *in.Amount
+	txn.Description = description
+	txn.CategoryID = categoryID
+	if date != nil {
+		txn.TransactionDate = *date
+	}
+	return ""
+}
+
+// ListTransactions returns a page of the user's transactions, newest first,
+// optionally of one account: ?account=, ?limit= and ?offset=.
+func (h *APIHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	p := repository.NewPagination(limit, offset)
+
+	var result *repository.PaginatedResult[*models.Transaction]
+	var err error
+	if v := r.URL.Query().Get("account"); v != "" {
+		accountID, parseErr := strconv.ParseInt(v, 10, 64)
+		if parseErr != nil {
+			http.Error(w, "Invalid account", http.StatusBadRequest)
+			return
+		}
+		if _, ok := h.accountOf(w, user.ID, accountID); !ok {
+			return
+		}
+		result, err = h.transactionRepo.GetByAccountIDPaginated(accountID, p)
+	} else {
+		result, err = h.transactionRepo.GetByUserIDPaginated(user.ID, p)
+	}
+	if err != nil {
+		log.Printf("Error fetching transactions: %v", err)
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+// GetTransaction returns a transaction.
+func (h *APIHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	txn, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, txn)
+}
+
+// CreateTransaction adds a transaction to an account, moving its balance by
+// the amount.
+func (h *APIHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	var in apiTransactionInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	account, ok := h.accountOf(w, user.ID, in.AccountID)
+	if !ok {
+		return
+	}
+	txn := &models.Transaction{AccountID: account.ID, TransactionDate: time.Now()}
+	if errMsg := in.apply(h, user.ID, txn); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	currentBalance, _ := h.transactionRepo.GetLatestBalance(account.ID)
+	txn.BalanceAfter = currentBalance + txn.Amount
+
+	id, err := h.transactionRepo.Create(txn)
+	if err != nil {
+		log.Printf("Error creating transaction: %v", err)
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+	h.writeTransaction(w, id, http.StatusCreated)
+}
+
+// UpdateTransaction replaces a transaction, moving its balance by the change
+// in amount.
+func (h *APIHandler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	txn, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+
+	var in apiTransactionInput
+	if !decodeAPIJSON(w, r, &in) {
+		return
+	}
+	previousAmount := txn.Amount
+	if errMsg := in.apply(h, user.ID, txn); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	txn.BalanceAfter += txn.Amount - previousAmount
+	if !applyAPIVersion(w, in.UpdatedAt, &txn.UpdatedAt) {
+		return
+	}
+
+	if err := h.transactionRepo.Update(txn); err != nil {
+		writeAPIUpdateError(w, err, "transaction")
+		return
+	}
+	h.writeTransaction(w, txn.ID, http.StatusOK)
+}
+
+// DeleteTransaction deletes a transaction.
+func (h *APIHandler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	txn, ok := h.ownedTransaction(w, r)
+	if !ok {
+		return
+	}
+	if err := h.transactionRepo.Delete(txn.ID); err != nil {
+		log.Printf("Error deleting transaction: %v", err)
+		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedTransaction returns the transaction in the URL if its account belongs
+// to the user. Writes an error response and returns false otherwise.
+func (h *APIHandler) ownedTransaction(w http.ResponseWriter, r *http.Request) (*models.Transaction, bool) {
+	id, ok := apiID(w, r)
+	if !ok {
+		return nil, false
+	}
+	txn, err := h.transactionRepo.GetByID(id)
+	if err == nil && txn != nil {
+		account, _ := h.accountRepo.GetByID(txn.AccountID)
+		if account != nil && account.UserID == middleware.GetUser(r).ID {
+			return txn, true
+		}
+	}
+	http.Error(w, "Transaction not found", http.StatusNotFound)
+	return nil, false
+}
+
+// writeTransaction writes a transaction as stored.
+func (h *APIHandler) writeTransaction(w http.ResponseWriter, id int64, status int) {
+	txn, err := h.transactionRepo.GetByID(id)
+	if err != nil || txn == nil {
+		log.Printf("Error fetching transaction %d: %v", id, err)
+		http.Error(w, "Failed to load transaction", http.StatusInternalServerError)
+		return
+	}
+	writeAPIJSON(w, status, txn)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"wealth_tracker/internal/repository"
+)
+
+// parseAsOf returns the day of the asof query parameter, which shows pages as
+// they were at the end of that day, or nil for today, a later day or no
+// valid date.
+func parseAsOf(r *http.Request) *time.Time {
+	day, err := time.Parse("2006-01-02", r.URL.Query().Get("asof"))
+	if err != nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !day.Before(today) {
+		return nil
+	}
+	return &day
+}
+
+// asOfData returns the template data of the as-of date picker on the page at
+// path, which is shown in the page header.
+func asOfData(path string, asOf *time.Time) map[string]any {
+	return map[string]any{
+		"Path":  path,
+		"AsOf":  asOf,
+		"Today": time.Now().UTC().Format("2006-01-02"),
+	}
+}
+
+// balanceAsOf returns an account's latest balance, or its balance at the end
+// of the as-of day if set.
+func balanceAsOf(transactionRepo *repository.TransactionRepository, accountID int64, asOf *time.Time) (float64, error) {
+	if asOf != nil {
+		return transactionRepo.GetBalanceAt(accountID, *asOf)
+	}
+	return transactionRepo.GetLatestBalance(accountID)
+}
@@ -2,16 +2,20 @@
 package handlers
 
 import (
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/demo"
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // AuthHandler handles authentication routes.
@@ -19,6 +23,10 @@ type AuthHandler struct {
 	templates      map[string]*template.Template
 	userRepo       *repository.UserRepository
 	sessionManager *auth.SessionManager
+	demoSeeder     *demo.Seeder
+	passwordPolicy auth.PasswordPolicy
+	loginLinks     *auth.LoginLinkManager // Nil disables login links
+	auditService   *services.AuditService // Nil skips audit logging
 }
 
 // NewAuthHandler creates a new AuthHandler.
@@ -31,17 +39,60 @@ func NewAuthHandler(
 		templates:      templates,
 		userRepo:       userRepo,
 		sessionManager: sessionManager,
+		passwordPolicy: auth.DefaultPasswordPolicy,
 	}
 }
 
+// SetPasswordPolicy sets the policy new passwords must meet.
+func (h *AuthHandler) SetPasswordPolicy(policy auth.PasswordPolicy) {
+	h.passwordPolicy = policy
+}
+
+// SetDemoSeeder enables the "Try demo" button of the login page, which logs
+// visitors in to a sandbox user of their own. Only set in demo mode.
+func (h *AuthHandler) SetDemoSeeder(seeder *demo.Seeder) {
+	h.demoSeeder = seeder
+}
+
 // LoginPage renders the login page.
 func (h *AuthHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
 	h.render(w, "login.html", map[string]any{
-		"Title":    "Login",
-		"DemoMode": os.Getenv("DEMO_MODE") == "true",
+		"Title":       "Login",
+		"DemoMode":    os.Getenv("DEMO_MODE") == "true",
+		"DemoSandbox": h.demoSeeder != nil,
 	})
 }
 
+// TryDemo creates a sandbox user with a copy of the demo data and logs the
+// visitor in to it until the sandbox expires.
+func (h *AuthHandler) TryDemo(w http.ResponseWriter, r *http.Request) {
+	if h.demoSeeder == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, err := h.demoSeeder.CreateSandbox(time.Now())
+	if errors.Is(err, demo.ErrTooManySandboxes) {
+		h.renderLoginError(w, "The demo is busy right now. Please try again later.")
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating demo sandbox: %v", err)
+		h.renderLoginError(w, "An error occurred. Please try again.")
+		return
+	}
+
+	session, err := h.sessionManager.CreateUntil(user.ID, time.Now().Add(demo.SandboxLifetime))
+	if err != nil {
+		log.Printf("Error creating demo session: %v", err)
+		h.renderLoginError(w, "An error occurred. Please try again.")
+		return
+	}
+	middleware.SetSessionCookie(w, session.ID, int(demo.SandboxLifetime.Seconds()))
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
 // Login handles the login form submission.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
@@ -111,7 +162,8 @@ func (h *AuthHandler) RegisterPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.render(w, "register.html", map[string]any{
-		"Title": "Register",
+		"Title":        "Register",
+		"PasswordHint": h.passwordPolicy.Hint(),
 	})
 }
 
@@ -162,14 +214,14 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		h.renderRegisterError(w, "Password is required")
 		return
 	}
-	if len(password) < 8 {
-		h.renderRegisterError(w, "Password must be at least 8 characters")
-		return
-	}
 	if password != confirmPassword {
 		h.renderRegisterError(w, "Passwords do not match")
 		return
 	}
+	if err := h.passwordPolicy.Check(password, name, email); err != nil {
+		h.renderRegisterError(w, err.Error())
+		return
+	}
 
 	// Check if email already exists
 	exists, err := h.userRepo.EmailExists(email)
@@ -246,9 +298,11 @@ func (h *AuthHandler) ChangePasswordPage(w http.ResponseWriter, r *http.Request)
 	}
 
 	h.render(w, "change-password.html", map[string]any{
-		"Title":    "Change Password",
-		"User":     user,
-		"Required": user.MustChangePassword,
+		"Title":        "Change Password",
+		"User":         user,
+		"Required":     user.MustChangePassword,
+		"FromLink":     h.isLinkSession(r),
+		"PasswordHint": h.passwordPolicy.Hint(),
 	})
 }
 
@@ -261,7 +315,7 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := r.ParseForm(); err != nil {
-		h.renderChangePasswordError(w, user, "Invalid form data")
+		h.renderChangePasswordError(w, r, user, "Invalid form data")
 		return
 	}
 
@@ -269,27 +323,29 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	newPassword := r.FormValue("new_password")
 	confirmPassword := r.FormValue("confirm_password")
 
-	// Validate current password
-	if !auth.CheckPassword(currentPassword, user.PasswordHash) {
-		h.renderChangePasswordError(w, user, "Current password is incorrect")
+	// Validate current password, which users logged in by a login link
+	// have forgotten
+	fromLink := h.isLinkSession(r)
+	if !fromLink && !auth.CheckPassword(currentPassword, user.PasswordHash) {
+		h.renderChangePasswordError(w, r, user, "Current password is incorrect")
 		return
 	}
 
 	// Validate new password
 	if newPassword == "" {
-		h.renderChangePasswordError(w, user, "New password is required")
+		h.renderChangePasswordError(w, r, user, "New password is required")
 		return
 	}
-	if len(newPassword) < 8 {
-		h.renderChangePasswordError(w, user, "New password must be at least 8 characters")
+	if newPassword != confirmPassword {
+		h.renderChangePasswordError(w, r, user, "New passwords do not match")
 		return
 	}
-	if newPassword != confirmPassword {
-		h.renderChangePasswordError(w, user, "New passwords do not match")
+	if !fromLink && newPassword == currentPassword {
+		h.renderChangePasswordError(w, r, user, "New password must be different from current password")
 		return
 	}
-	if newPassword == currentPassword {
-		h.renderChangePasswordError(w, user, "New password must be different from current password")
+	if err := h.passwordPolicy.Check(newPassword, user.Name, user.Email); err != nil {
+		h.renderChangePasswordError(w, r, user, err.Error())
 		return
 	}
 
@@ -297,14 +353,14 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 	passwordHash, err := auth.HashPassword(newPassword)
 	if err != nil {
 		log.Printf("ChangePassword error hashing: %v", err)
-		h.renderChangePasswordError(w, user, "An error occurred. Please try again.")
+		h.renderChangePasswordError(w, r, user, "An error occurred. Please try again.")
 		return
 	}
 
 	// Update password
 	if err := h.userRepo.UpdatePassword(user.ID, passwordHash); err != nil {
 		log.Printf("ChangePassword error updating: %v", err)
-		h.renderChangePasswordError(w, user, "An error occurred. Please try again.")
+		h.renderChangePasswordError(w, r, user, "An error occurred. Please try again.")
 		return
 	}
 
@@ -320,12 +376,14 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 }
 
 // renderChangePasswordError renders the change password page with an error.
-func (h *AuthHandler) renderChangePasswordError(w http.ResponseWriter, user *models.User, errMsg string) {
+func (h *AuthHandler) renderChangePasswordError(w http.ResponseWriter, r *http.Request, user *models.User, errMsg string) {
 	h.render(w, "change-password.html", map[string]any{
-		"Title":    "Change Password",
-		"User":     user,
-		"Required": user.MustChangePassword,
-		"Error":    errMsg,
+		"Title":        "Change Password",
+		"User":         user,
+		"Required":     user.MustChangePassword,
+		"FromLink":     h.isLinkSession(r),
+		"Error":        errMsg,
+		"PasswordHint": h.passwordPolicy.Hint(),
 	})
 }
 
@@ -351,16 +409,18 @@ func (h *AuthHandler) render(w http.ResponseWriter, name string, data map[string
 // renderLoginError renders the login page with an error message.
 func (h *AuthHandler) renderLoginError(w http.ResponseWriter, errMsg string) {
 	h.render(w, "login.html", map[string]any{
-		"Title":    "Login",
-		"Error":    errMsg,
-		"DemoMode": os.Getenv("DEMO_MODE") == "true",
+		"Title":       "Login",
+		"Error":       errMsg,
+		"DemoMode":    os.Getenv("DEMO_MODE") == "true",
+		"DemoSandbox": h.demoSeeder != nil,
 	})
 }
 
 // renderRegisterError renders the register page with an error message.
 func (h *AuthHandler) renderRegisterError(w http.ResponseWriter, errMsg string) {
 	h.render(w, "register.html", map[string]any{
-		"Title": "Register",
-		"Error": errMsg,
+		"Title":        "Register",
+		"Error":        errMsg,
+		"PasswordHint": h.passwordPolicy.Hint(),
 	})
 }
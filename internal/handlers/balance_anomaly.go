@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// confirmAnomalyField is set by the balance confirmation page when the user
+// resubmits a balance that breaks the account's trend.
+const confirmAnomalyField = "confirm_anomaly"
+
+// confirmBalanceAnomaly renders a confirmation page instead of saving if
+// newBalance breaks the account's recent trend and the form was not already
+// confirmed. It returns true if the page was rendered and the caller should
+// stop. The form must have been parsed.
+func confirmBalanceAnomaly(
+	w http.ResponseWriter,
+	r *http.Request,
+	render func(http.ResponseWriter, string, map[string]any),
+	checker *services.BalanceChecker,
+	user *models.User,
+	account *models.Account,
+	newBalance float64,
+	cancelURL string,
+) bool {
+	if r.PostForm.Get(confirmAnomalyField) == "1" {
+		return false
+	}
+	anomaly, err := checker.Check(account.ID, newBalance)
+	if err != nil {
+		log.Printf("Error checking balance of account %d: %v", account.ID, err)
+		return false
+	}
+	if anomaly == nil {
+		return false
+	}
+
+	// Resubmit the same form, with the confirmation
+	fields := make(map[string][]string, len(r.PostForm))
+	for key, values := range r.PostForm {
+		if key != confirmAnomalyField {
+			fields[key] = values
+		}
+	}
+	render(w, "balance-confirm.html", map[string]any{
+		"Title":     "Confirm Balance",
+		"User":      user,
+		"ActiveNav": "accounts",
+		"Account":   account,
+		"Anomaly":   anomaly,
+		"Action":    r.URL.Path,
+		"Fields":    fields,
+		"CancelURL": cancelURL,
+	})
+	return true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// maxStatementUploadSize limits the size of an uploaded bank statement.
+const maxStatementUploadSize = 10 << 20 // 10 MB
+
+// ImportBankStatement handles uploading an ISO 20022 camt.053 bank statement,
+// whose booked entries become transactions of the account. Entries recorded
+// before, e.g. from an overlapping statement, are skipped.
+func (h *AccountHandler) ImportBankStatement(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, ok := h.ownedAccount(w, chi.URLParam(r, "id"), user.ID)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxStatementUploadSize)
+	if err := r.ParseMultipartForm(maxStatementUploadSize); err != nil {
+		h.renderError(w, r, user, "File is too large or the form is invalid")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderError(w, r, user, "Please choose a camt.053 XML file to import")
+		return
+	}
+	defer file.Close()
+
+	statement, err := services.ParseCamt053(file)
+	if err != nil {
+		h.renderError(w, r, user, "Bank statement import failed: "+err.Error())
+		return
+	}
+	if len(statement.Entries) == 0 {
+		h.renderError(w, r, user, "The statement contains no booked entries")
+		return
+	}
+
+	importer := services.NewBankStatementImporter(h.transactionRepo)
+	importer.SetCategorizer(h.categorizer)
+	result, err := importer.Import(account, statement)
+	if err != nil {
+		log.Printf("Error importing bank statement into account %d: %v", account.ID, err)
+		h.renderError(w, r, user, "Bank statement import failed: "+err.Error())
+		return
+	}
+
+	log.Printf("Imported %d bank statement entries into account %d for user %d, skipped %d duplicates",
+		result.Imported, account.ID, user.ID, result.Duplicates)
+	http.Redirect(w, r, fmt.Sprintf("/transactions?account=%d&imported=%d&duplicates=%d", account.ID, result.Imported, result.Duplicates), http.StatusSeeOther)
+}
@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/crypto"
 	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/psd2"
 	"wealth_tracker/internal/broker/saxo"
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
@@ -26,6 +31,11 @@ type BrokerHandler struct {
 	historyRepo *repository.SyncHistoryRepository
 	accountRepo *repository.AccountRepository
 	syncService *sync.Service
+	tasks       *brokerTasks
+
+	// allowLoopbackAPIURLs accepts plain HTTP API URLs on this machine, for
+	// tests against local fake banks and exchanges
+	allowLoopbackAPIURLs bool
 }
 
 // NewBrokerHandler creates a new BrokerHandler.
@@ -46,9 +56,16 @@ func NewBrokerHandler(
 		historyRepo: historyRepo,
 		accountRepo: accountRepo,
 		syncService: syncService,
+		tasks:       newBrokerTasks(),
 	}
 }
 
+// SetAllowLoopbackAPIURLs accepts plain HTTP bank and exchange API URLs on
+// this machine. Only tests against local fake APIs turn it on.
+func (h *BrokerHandler) SetAllowLoopbackAPIURLs(allow bool) {
+	h.allowLoopbackAPIURLs = allow
+}
+
 // Connections lists all broker connections for the user.
 func (h *BrokerHandler) Connections(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -110,11 +127,12 @@ func (h *BrokerHandler) CreateConnection(w http.ResponseWriter, r *http.Request)
 
 	brokerType := strings.TrimSpace(r.FormValue("broker_type"))
 	country := strings.TrimSpace(r.FormValue("country"))
-	username := strings.TrimSpace(r.FormValue("username"))       // For Nordnet: MitID user identifier
-	cpr := strings.TrimSpace(r.FormValue("cpr"))                 // For Nordnet: CPR number for Signicat
+	username := strings.TrimSpace(r.FormValue("username"))        // For Nordnet: MitID user identifier
+	cpr := strings.TrimSpace(r.FormValue("cpr"))                  // For Nordnet: CPR number for Signicat
 	appKey := strings.TrimSpace(r.FormValue("app_key"))           // For Saxo: App Key (client_id)
 	appSecret := strings.TrimSpace(r.FormValue("app_secret"))     // For Saxo: App Secret (client_secret)
 	redirectURI := strings.TrimSpace(r.FormValue("redirect_uri")) // For Saxo: OAuth redirect URI
+	apiURL := strings.TrimSpace(r.FormValue("api_url"))           // For PSD2: base URL of the bank API
 
 	// Validate broker type and country
 	if brokerType == "" || country == "" {
@@ -125,24 +143,12 @@ func (h *BrokerHandler) CreateConnection(w http.ResponseWriter, r *http.Request)
 	// Broker-specific validation
 	switch brokerType {
 	case "nordnet":
-		// Nordnet requires MitID credentials
-		if username == "" {
-			h.renderConnectionForm(w, user, true, nil, "MitID user ID is required for Nordnet")
+		// The credentials depend on the country's login method
+		username, cpr = nordnetCredentials(r, country)
+		if msg := validateNordnetCredentials(country, username, cpr); msg != "" {
+			h.renderConnectionForm(w, user, true, nil, msg)
 			return
 		}
-		// Validate CPR format (10 digits) for Danish Nordnet
-		if country == "dk" {
-			if len(cpr) != 10 {
-				h.renderConnectionForm(w, user, true, nil, "CPR number must be 10 digits")
-				return
-			}
-			for _, c := range cpr {
-				if c < '0' || c > '9' {
-					h.renderConnectionForm(w, user, true, nil, "CPR number must contain only digits")
-					return
-				}
-			}
-		}
 	case "saxo":
 		// Saxo requires App Key and Redirect URI for OAuth
 		if appKey == "" {
@@ -153,11 +159,40 @@ func (h *BrokerHandler) CreateConnection(w http.ResponseWriter, r *http.Request)
 			h.renderConnectionForm(w, user, true, nil, "Saxo Redirect URI is required")
 			return
 		}
+	case "psd2":
+		// The bank API and this app's token from its developer portal
+		appSecret = strings.TrimSpace(r.FormValue("tpp_token"))
+		if msg := h.validatePSD2APIURL(apiURL); msg != "" {
+			h.renderConnectionForm(w, user, true, nil, msg)
+			return
+		}
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		// A read-only API key; the API URL is only set to override the exchange's
+		appKey, appSecret, apiURL = exchangeCredentials(r)
+		if msg := h.validateExchangeCredentials(appKey, appSecret, apiURL); msg != "" {
+			h.renderConnectionForm(w, user, true, nil, msg)
+			return
+		}
 	default:
 		h.renderConnectionForm(w, user, true, nil, "Unsupported broker type")
 		return
 	}
 
+	// Preferred sync hours
+	window := &models.BrokerConnection{}
+	if msg := parseSyncWindow(r, window); msg != "" {
+		h.renderConnectionForm(w, user, true, nil, msg)
+		return
+	}
+	if msg := parseSyncSchedule(r, window); msg != "" {
+		h.renderConnectionForm(w, user, true, nil, msg)
+		return
+	}
+	if msg := parseHTTPSettings(r, window); msg != "" {
+		h.renderConnectionForm(w, user, true, nil, msg)
+		return
+	}
+
 	// Check if connection already exists
 	existing, _ := h.connRepo.GetByUserAndBroker(user.ID, brokerType)
 	if existing != nil {
@@ -175,13 +210,21 @@ func (h *BrokerHandler) CreateConnection(w http.ResponseWriter, r *http.Request)
 	conn := &models.BrokerConnection{
 		UserID:      user.ID,
 		BrokerType:  brokerType,
-		Username:    username,    // Stores MitID user identifier (empty for Saxo)
-		CPR:         cpr,         // Stores CPR for Signicat verification (empty for Saxo)
-		AppKey:      appKey,      // Stores Saxo App Key (empty for Nordnet)
-		AppSecret:   appSecret,   // Stores Saxo App Secret (empty for Nordnet and PKCE apps)
+		Username:    username,    // Stores MitID user identifier (empty for Saxo and BankID)
+		CPR:         cpr,         // Stores CPR, or the Norwegian national ID for BankID (empty for Saxo)
+		AppKey:      appKey,      // Stores Saxo App Key or the exchange API key (empty for Nordnet)
+		AppSecret:   appSecret,   // Stores Saxo App Secret, the PSD2 TPP token or the exchange API secret (empty for Nordnet and PKCE apps)
 		RedirectURI: redirectURI, // Stores Saxo OAuth redirect URI (empty for Nordnet)
+		APIURL:      apiURL,      // Stores the PSD2 bank API or an exchange API override (empty for brokers)
 		Country:     country,
 		IsActive:    true,
+
+		SyncWindowStart: window.SyncWindowStart,
+		SyncWindowEnd:   window.SyncWindowEnd,
+		SkipWeekends:    window.SkipWeekends,
+		SyncSchedule:    window.SyncSchedule,
+		ProxyURL:        window.ProxyURL,
+		UserAgent:       window.UserAgent,
 	}
 
 	id, err := h.connRepo.Create(conn)
@@ -256,17 +299,13 @@ func (h *BrokerHandler) UpdateConnection(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Update fields based on broker type
+	var clearConsent bool
 	if conn.BrokerType == "nordnet" {
-		conn.Username = strings.TrimSpace(r.FormValue("username"))
-		conn.CPR = strings.TrimSpace(r.FormValue("cpr"))
+		conn.Username, conn.CPR = nordnetCredentials(r, conn.Country)
 
 		// Validate
-		if conn.Username == "" {
-			h.renderConnectionForm(w, user, false, conn, "MitID user ID is required for Nordnet")
-			return
-		}
-		if conn.Country == "dk" && len(conn.CPR) != 10 {
-			h.renderConnectionForm(w, user, false, conn, "CPR number must be 10 digits")
+		if msg := validateNordnetCredentials(conn.Country, conn.Username, conn.CPR); msg != "" {
+			h.renderConnectionForm(w, user, false, conn, msg)
 			return
 		}
 	} else if conn.BrokerType == "saxo" {
@@ -283,6 +322,45 @@ func (h *BrokerHandler) UpdateConnection(w http.ResponseWriter, r *http.Request)
 			h.renderConnectionForm(w, user, false, conn, "Saxo Redirect URI is required")
 			return
 		}
+	} else if conn.BrokerType == "psd2" {
+		previousAPIURL := conn.APIURL
+		conn.APIURL = strings.TrimSpace(r.FormValue("api_url"))
+		// A blank token keeps the stored one, which the form does not show
+		if token := strings.TrimSpace(r.FormValue("tpp_token")); token != "" {
+			conn.AppSecret = token
+		}
+
+		if msg := h.validatePSD2APIURL(conn.APIURL); msg != "" {
+			h.renderConnectionForm(w, user, false, conn, msg)
+			return
+		}
+		// A consent is only valid at the bank that granted it
+		clearConsent = conn.APIURL != previousAPIURL
+	} else if crypto.IsExchange(conn.BrokerType) {
+		key, secret, apiURL := exchangeCredentials(r)
+		conn.AppKey, conn.APIURL = key, apiURL
+		// A blank secret keeps the stored one, which the form does not show
+		if secret != "" {
+			conn.AppSecret = secret
+		}
+
+		if msg := h.validateExchangeCredentials(conn.AppKey, conn.AppSecret, conn.APIURL); msg != "" {
+			h.renderConnectionForm(w, user, false, conn, msg)
+			return
+		}
+	}
+
+	if msg := parseSyncWindow(r, conn); msg != "" {
+		h.renderConnectionForm(w, user, false, conn, msg)
+		return
+	}
+	if msg := parseSyncSchedule(r, conn); msg != "" {
+		h.renderConnectionForm(w, user, false, conn, msg)
+		return
+	}
+	if msg := parseHTTPSettings(r, conn); msg != "" {
+		h.renderConnectionForm(w, user, false, conn, msg)
+		return
 	}
 
 	// Update in database
@@ -297,6 +375,9 @@ func (h *BrokerHandler) UpdateConnection(w http.ResponseWriter, r *http.Request)
 		saxo.ClearCachedSession(conn.ID)
 		saxo.ClearActiveOAuthSession(conn.ID)
 	}
+	if clearConsent {
+		psd2.ClearConsent(conn.ID)
+	}
 
 	// Redirect back to connection detail page
 	http.Redirect(w, r, "/settings/connections/"+strconv.FormatInt(id, 10), http.StatusSeeOther)
@@ -327,19 +408,50 @@ func (h *BrokerHandler) ViewConnection(w http.ResponseWriter, r *http.Request) {
 
 	mappings, _ := h.mappingRepo.GetByConnectionID(id)
 	history, _ := h.historyRepo.GetByConnectionID(id, 10)
+	pendingDeletions, _ := h.syncService.PendingDeletions(id)
+	pendingBalances, _ := h.syncService.PendingBalances(id)
+	mitidAttempts, _ := h.syncService.MitIDAttempts(id, 10)
+	mitidCooldown, _ := h.syncService.MitIDCooldownRemaining(id)
+	cooldownMinutes := 0
+	if mitidCooldown > 0 {
+		cooldownMinutes = int(mitidCooldown.Minutes()) + 1
+	}
+	var nextSync *time.Time
+	if at, ok := h.syncService.NextScheduledSync(conn); ok {
+		nextSync = &at
+	}
+	authName := nordnet.AuthMethodName(conn.Country)
+	consent := h.syncService.PSD2Consent(conn.ID)
+	if conn.BrokerType == "psd2" {
+		authName = "bank"
+	}
 
 	h.render(w, "connection-detail.html", map[string]any{
-		"Title":      "Connection Details",
-		"User":       user,
-		"ActiveNav":  "settings",
-		"Connection": conn,
-		"Mappings":   mappings,
-		"History":    history,
+		"Title":            "Connection Details",
+		"User":             user,
+		"ActiveNav":        "settings",
+		"Connection":       conn,
+		"Mappings":         mappings,
+		"History":          history,
+		"PendingDeletions": pendingDeletions,
+		"PendingBalances":  pendingBalances,
+		"MitIDAttempts":    mitidAttempts,
+		"MitIDCooldown":    cooldownMinutes,
+		"AuthMethod":       nordnet.AuthMethod(conn.Country),
+		"AuthName":         authName,
+		"FTNMessage":       ftnMessages[r.URL.Query().Get("ftn")],
+		"FTNFailed":        r.URL.Query().Get("ftn") != "ok",
+		"PSD2Consent":      consent,
+		"PSD2Authorized":   consent.IsValid(time.Now()),
+		"PSD2Message":      psd2Messages[r.URL.Query().Get("psd2")],
+		"PSD2Failed":       r.URL.Query().Get("psd2") != "ok",
+		"NextSync":         nextSync,
 	})
 }
 
 // AccountMappingForm shows the form to map broker accounts to local accounts.
 // Initially shows a loading state, accounts are fetched via AJAX to allow QR code display.
+// Without JavaScript the page offers a form that fetches them via TaskStatus.
 func (h *BrokerHandler) AccountMappingForm(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
 	if user == nil {
@@ -362,34 +474,13 @@ func (h *BrokerHandler) AccountMappingForm(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Get existing mappings
-	existingMappings, _ := h.mappingRepo.GetByConnectionID(id)
-	mappingsByExternal := make(map[string]*models.AccountMapping)
-	for _, m := range existingMappings {
-		mappingsByExternal[m.ExternalAccountID] = m
-	}
-
-	// Convert mappings to JSON for JavaScript
-	mappingsJSON, _ := json.Marshal(mappingsByExternal)
-
-	// Get local accounts for dropdown
-	localAccounts, _ := h.accountRepo.GetByUserIDActiveOnly(user.ID)
-
-	// Render page immediately - accounts will be fetched via AJAX
-	h.render(w, "account-mapping.html", map[string]any{
-		"Title":               "Map Accounts",
-		"User":                user,
-		"ActiveNav":           "settings",
-		"Connection":          conn,
-		"ExternalAccounts":    nil, // Will be fetched via AJAX
-		"ExistingMappings":    mappingsByExternal,
-		"ExistingMappingsJSON": template.JS(mappingsJSON), // Safe for embedding in JavaScript
-		"LocalAccounts":       localAccounts,
-		"NeedsFetch":          true, // Flag to trigger AJAX fetch
-	})
+	// Render page immediately - accounts will be fetched via AJAX, or via
+	// the task page without JavaScript
+	h.renderAccountMapping(w, user, conn, nil, "")
 }
 
-// FetchExternalAccounts fetches accounts from broker via AJAX (triggers MitID auth).
+// FetchExternalAccounts starts fetching the accounts from the broker in the
+// background, which may need a MitID login.
 func (h *BrokerHandler) FetchExternalAccounts(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
 	if user == nil {
@@ -412,17 +503,7 @@ func (h *BrokerHandler) FetchExternalAccounts(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Fetch external accounts from broker (this triggers MitID auth)
-	externalAccounts, err := h.syncService.GetExternalAccounts(id)
-	if err != nil {
-		log.Printf("Error fetching external accounts: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Return accounts as JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(externalAccounts)
+	h.startFetchTask(w, r, id)
 }
 
 // SaveAccountMappings saves account mappings.
@@ -507,7 +588,8 @@ func (h *BrokerHandler) SaveAccountMappings(w http.ResponseWriter, r *http.Reque
 	http.Redirect(w, r, "/settings/connections/"+strconv.FormatInt(connectionID, 10), http.StatusSeeOther)
 }
 
-// SyncConnection triggers a manual sync for a connection.
+// SyncConnection starts a manual sync of a connection in the background, or
+// with dry_run=1 a preview of its changes.
 func (h *BrokerHandler) SyncConnection(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
 	if user == nil {
@@ -530,19 +612,77 @@ func (h *BrokerHandler) SyncConnection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Run sync
-	result, err := h.syncService.SyncConnection(connectionID)
+	h.startSyncTask(w, r, connectionID, r.URL.Query().Get("dry_run") == "1")
+}
+
+// ConfirmDeletions deletes holdings a sync kept back because removing them
+// exceeded the deletion safety threshold.
+func (h *BrokerHandler) ConfirmDeletions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Extract connection ID
+	idStr := strings.TrimPrefix(r.URL.Path, "/settings/connections/")
+	idStr = strings.TrimSuffix(idStr, "/confirm-deletions")
+	connectionID, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		log.Printf("Error syncing connection %d: %v", connectionID, err)
-		// Return error via HTMX
-		w.Header().Set("HX-Trigger", `{"showToast": "Sync failed: `+err.Error()+`"}`)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.NotFound(w, r)
 		return
 	}
 
-	// Return success via HTMX
-	w.Header().Set("HX-Trigger", `{"showToast": "Synced `+strconv.Itoa(result.PositionsSynced)+` positions from `+strconv.Itoa(result.AccountsSynced)+` accounts"}`)
-	w.WriteHeader(http.StatusOK)
+	conn, err := h.connRepo.GetByID(connectionID)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	deleted, err := h.syncService.ConfirmDeletions(connectionID)
+	if err != nil {
+		log.Printf("Error confirming holding deletions for connection %d: %v", connectionID, err)
+		http.Error(w, "Failed to delete holdings", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Deleted %d held holdings for connection %d", deleted, connectionID)
+
+	http.Redirect(w, r, "/settings/connections/"+idStr, http.StatusSeeOther)
+}
+
+// ConfirmBalances records the account balances a previous sync kept back
+// because they broke the accounts' trend.
+func (h *BrokerHandler) ConfirmBalances(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Extract connection ID
+	idStr := strings.TrimPrefix(r.URL.Path, "/settings/connections/")
+	idStr = strings.TrimSuffix(idStr, "/confirm-balances")
+	connectionID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	conn, err := h.connRepo.GetByID(connectionID)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	confirmed, err := h.syncService.ConfirmBalances(connectionID)
+	if err != nil {
+		log.Printf("Error confirming held balances for connection %d: %v", connectionID, err)
+		http.Error(w, "Failed to record balances", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Recorded %d held balances for connection %d", confirmed, connectionID)
+
+	http.Redirect(w, r, "/settings/connections/"+idStr, http.StatusSeeOther)
 }
 
 // DeleteConnection removes a broker connection.
@@ -578,11 +718,127 @@ func (h *BrokerHandler) DeleteConnection(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/settings/connections", http.StatusSeeOther)
 }
 
+// nordnetCredentials returns the login credentials of a Nordnet connection
+// form for the country's login method: the MitID user ID and CPR number in
+// Denmark, the national identity number for Norwegian BankID, and nothing for
+// the QR and browser logins of Sweden and Finland.
+func nordnetCredentials(r *http.Request, country string) (username, nationalID string) {
+	switch nordnet.AuthMethod(country) {
+	case nordnet.AuthMitID:
+		return strings.TrimSpace(r.FormValue("username")), strings.TrimSpace(r.FormValue("cpr"))
+	case nordnet.AuthBankIDNO:
+		return "", strings.TrimSpace(r.FormValue("national_id"))
+	}
+	return "", ""
+}
+
+// validateNordnetCredentials returns the problem with the credentials of a
+// Nordnet connection, or "" if they are valid.
+func validateNordnetCredentials(country, username, nationalID string) string {
+	switch country {
+	case "dk":
+		if username == "" {
+			return "MitID user ID is required for Nordnet"
+		}
+		if len(nationalID) != 10 {
+			return "CPR number must be 10 digits"
+		}
+		if !isDigits(nationalID) {
+			return "CPR number must contain only digits"
+		}
+	case "no":
+		if len(nationalID) != 11 || !isDigits(nationalID) {
+			return "National identity number must be 11 digits"
+		}
+	case "se", "fi":
+	default:
+		return "Unsupported Nordnet country"
+	}
+	return ""
+}
+
+// parseHTTPSettings sets the outbound proxy and User-Agent of a connection
+// from its form and returns the problem with them, or "" if they are valid.
+// Empty fields use the global default.
+func parseHTTPSettings(r *http.Request, conn *models.BrokerConnection) string {
+	conn.ProxyURL = strings.TrimSpace(r.FormValue("proxy_url"))
+	conn.UserAgent = strings.TrimSpace(r.FormValue("user_agent"))
+	switch err := (broker.HTTPConfig{ProxyURL: conn.ProxyURL}).ValidateConnection(); {
+	case errors.Is(err, broker.ErrPrivateProxy):
+		return "Proxy must not point to a private or local address"
+	case err != nil:
+		return "Proxy must be an http://, https:// or socks5:// address"
+	}
+	return ""
+}
+
+// syncHours are the hours offered for the sync window of a connection.
+var syncHours = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24}
+
+// parseSyncWindow sets the sync window of a connection from its form and
+// returns the problem with it, or "" if it is valid. Forms without the
+// fields leave syncs unrestricted.
+func parseSyncWindow(r *http.Request, conn *models.BrokerConnection) string {
+	conn.SyncWindowStart, conn.SyncWindowEnd = 0, 24
+	conn.SkipWeekends = r.FormValue("skip_weekends") == "1"
+
+	if v := r.FormValue("sync_window_start"); v != "" {
+		start, err := strconv.Atoi(v)
+		if err != nil || start < 0 || start > 23 {
+			return "Sync window must start between 0 and 23"
+		}
+		conn.SyncWindowStart = start
+	}
+	if v := r.FormValue("sync_window_end"); v != "" {
+		end, err := strconv.Atoi(v)
+		if err != nil || end < 1 || end > 24 {
+			return "Sync window must end between 1 and 24"
+		}
+		conn.SyncWindowEnd = end
+	}
+	if conn.SyncWindowStart == conn.SyncWindowEnd {
+		return "Sync window must not start and end at the same hour"
+	}
+	return ""
+}
+
+// parseSyncSchedule sets the schedule of a connection from its form and
+// returns the problem with it, or "" if it is valid. "custom" takes the cron
+// expression of the sync_cron field. Call it after parseSyncWindow, as daily
+// and weekly syncs run at the start of the window.
+func parseSyncSchedule(r *http.Request, conn *models.BrokerConnection) string {
+	schedule := r.FormValue("sync_schedule")
+	if schedule == "custom" {
+		schedule = strings.Join(strings.Fields(r.FormValue("sync_cron")), " ")
+		if schedule == "" {
+			return "Enter a cron expression for the custom schedule"
+		}
+	}
+	if _, err := sync.ParseSchedule(schedule, conn.SyncWindowStart); err != nil {
+		return "Invalid sync schedule: " + err.Error()
+	}
+	conn.SyncSchedule = schedule
+	return ""
+}
+
+// isDigits returns true if s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // render renders a template with the given data.
 func (h *BrokerHandler) render(w http.ResponseWriter, name string, data map[string]any) {
 	if data == nil {
 		data = make(map[string]any)
 	}
+	if name == "connection-form.html" {
+		data["SyncHours"] = syncHours
+	}
 
 	tmpl, ok := h.templates[name]
 	if !ok {
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"wealth_tracker/internal/broker"
+)
+
+// exchangeCredentials returns the API key, secret and API URL override of a
+// crypto exchange connection from the connection form.
+func exchangeCredentials(r *http.Request) (key, secret, apiURL string) {
+	return strings.TrimSpace(r.FormValue("exchange_key")),
+		strings.TrimSpace(r.FormValue("exchange_secret")),
+		strings.TrimSpace(r.FormValue("exchange_api_url"))
+}
+
+// validateExchangeCredentials returns the problem with the API key of a
+// crypto exchange connection, or "" if it is valid. The key is sent with
+// every request and exchange errors are shown to the user, so an API URL
+// override must be secure and public like a bank's.
+func (h *BrokerHandler) validateExchangeCredentials(key, secret, apiURL string) string {
+	if key == "" || secret == "" {
+		return "Exchange API key and secret are required"
+	}
+	if apiURL == "" {
+		return ""
+	}
+	if !secureAPIURL(apiURL, h.allowLoopbackAPIURLs) {
+		return "Exchange API URL must be an https:// address"
+	}
+	if err := broker.ValidateAPIURL(apiURL); err != nil {
+		return "Exchange API URL must not point to a private or local address"
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/release"
+	"wealth_tracker/internal/sync"
+)
+
+// diagnosticsReadme explains the bundle to whoever opens it.
+const diagnosticsReadme = `Wealth Tracker sync diagnostics
+
+This bundle describes one broker sync, for attaching to a GitHub issue.
+
+  sync.json        the sync history entry
+  connection.json  the broker connection and its account mappings
+  requests.json    the broker API requests made during the sync
+  app.json         the Wealth Tracker and Go versions
+
+Credentials, CPR numbers, app keys, tokens and cookies are not included, and
+sensitive values in URLs and error responses are replaced with REDACTED.
+Account names and numbers are included; remove them before sharing if you
+prefer.
+`
+
+// diagnosticsConnection is the connection metadata included in a bundle.
+// Credentials are left out.
+type diagnosticsConnection struct {
+	ID             int64                    `json:"id"`
+	BrokerType     string                   `json:"broker_type"`
+	Country        string                   `json:"country"`
+	IsActive       bool                     `json:"is_active"`
+	LastSyncAt     *time.Time               `json:"last_sync_at,omitempty"`
+	LastSyncStatus string                   `json:"last_sync_status,omitempty"`
+	LastSyncError  string                   `json:"last_sync_error,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Mappings       []*models.AccountMapping `json:"mappings"`
+}
+
+// DownloadDiagnostics serves a zip with everything needed to report a failed
+// sync: the history entry, connection metadata without credentials, the
+// redacted request trail and the app version.
+func (h *BrokerHandler) DownloadDiagnostics(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	historyID, err := strconv.ParseInt(chi.URLParam(r, "historyID"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	conn, err := h.connRepo.GetByID(id)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return
+	}
+	history, err := h.historyRepo.GetByID(historyID)
+	if err != nil || history == nil || history.ConnectionID != conn.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	mappings, _ := h.mappingRepo.GetByConnectionID(conn.ID)
+	diagnostics, err := h.syncService.GetDiagnostics(historyID)
+	if err != nil {
+		log.Printf("BrokerHandler.DownloadDiagnostics error: %v", err)
+	}
+	if diagnostics == nil {
+		diagnostics = &sync.Diagnostics{}
+	}
+
+	syncEntry := *history
+	syncEntry.ErrorMessage = broker.RedactText(syncEntry.ErrorMessage)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"sync.json", syncEntry},
+		{"connection.json", diagnosticsConnection{
+			ID:             conn.ID,
+			BrokerType:     conn.BrokerType,
+			Country:        conn.Country,
+			IsActive:       conn.IsActive,
+			LastSyncAt:     conn.LastSyncAt,
+			LastSyncStatus: conn.LastSyncStatus,
+			LastSyncError:  broker.RedactText(conn.LastSyncError),
+			CreatedAt:      conn.CreatedAt,
+			Mappings:       mappings,
+		}},
+		{"requests.json", diagnostics},
+		{"app.json", map[string]string{
+			"version":    release.Version,
+			"go_version": runtime.Version(),
+			"platform":   runtime.GOOS + "/" + runtime.GOARCH,
+		}},
+	}
+
+	filename := fmt.Sprintf("sync-diagnostics-%s-%d.zip", conn.BrokerType, historyID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			log.Printf("BrokerHandler.DownloadDiagnostics error: %v", err)
+			return
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			log.Printf("BrokerHandler.DownloadDiagnostics error: %v", err)
+			return
+		}
+	}
+	if fw, err := zw.Create("README.txt"); err == nil {
+		fw.Write([]byte(diagnosticsReadme))
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("BrokerHandler.DownloadDiagnostics error: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// ftnMessages are the outcomes of a Finnish bank login shown on the
+// connection page, by the value of its ftn query parameter.
+var ftnMessages = map[string]string{
+	"ok":      "Logged in to Nordnet. You can now sync or map accounts.",
+	"invalid": "That address is not the Nordnet login page of the bank login started here. Start a new bank login and paste the address right after logging in.",
+	"failed":  "Nordnet did not accept the bank login. It may have expired; start a new one.",
+}
+
+// StartFTNLogin sends the owner of a Finnish Nordnet connection to their
+// bank's login. Finnish bank logins cannot run on the server, so the user
+// logs in in the browser and pastes the address Nordnet returns to into
+// CompleteFTNLogin.
+func (h *BrokerHandler) StartFTNLogin(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ftnConnection(w, r)
+	if !ok {
+		return
+	}
+
+	http.Redirect(w, r, h.syncService.StartFTNLogin(conn), http.StatusSeeOther)
+}
+
+// CompleteFTNLogin creates the Nordnet session of a Finnish connection from
+// the address the bank login returned to.
+func (h *BrokerHandler) CompleteFTNLogin(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.ftnConnection(w, r)
+	if !ok {
+		return
+	}
+
+	outcome := "ok"
+	if err := h.syncService.CompleteFTNLogin(conn, r.FormValue("redirect_url")); err != nil {
+		log.Printf("Error completing bank login for connection %d: %v", conn.ID, err)
+		outcome = "failed"
+		if errors.Is(err, nordnet.ErrFTNLoginInvalid) {
+			outcome = "invalid"
+		}
+	}
+
+	http.Redirect(w, r, "/settings/connections/"+strconv.FormatInt(conn.ID, 10)+"?ftn="+outcome, http.StatusSeeOther)
+}
+
+// ftnConnection loads the connection in the URL and verifies that it is a
+// Finnish Nordnet connection of the user. Writes an error response and
+// returns false otherwise.
+func (h *BrokerHandler) ftnConnection(w http.ResponseWriter, r *http.Request) (*models.BrokerConnection, bool) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	conn, err := h.connRepo.GetByID(id)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if conn.BrokerType != "nordnet" || nordnet.AuthMethod(conn.Country) != nordnet.AuthFTN {
+		http.Error(w, "Not a Finnish Nordnet connection", http.StatusBadRequest)
+		return nil, false
+	}
+	return conn, true
+}
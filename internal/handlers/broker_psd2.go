@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// psd2Messages are the outcomes of authorizing bank access shown on the
+// connection page, by the value of its psd2 query parameter.
+var psd2Messages = map[string]string{
+	"ok":       "Bank access authorized. You can now map and sync accounts.",
+	"rejected": "The bank did not authorize access. Start again and complete the authorization at your bank.",
+	"failed":   "Could not reach the bank to authorize access. Check the API URL and token of the connection.",
+}
+
+// StartPSD2Consent asks the bank of a PSD2 connection for access to the
+// user's accounts and sends them to the bank to authorize it. The bank then
+// sends them back to PSD2Callback.
+func (h *BrokerHandler) StartPSD2Consent(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.psd2Connection(w, r)
+	if !ok {
+		return
+	}
+
+	authURL, err := h.syncService.StartPSD2Consent(conn, psd2RedirectURI(r, conn.ID), psuIP(r))
+	if err != nil {
+		log.Printf("Error requesting bank access for connection %d: %v", conn.ID, err)
+		http.Redirect(w, r, "/settings/connections/"+strconv.FormatInt(conn.ID, 10)+"?psd2=failed", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// PSD2Callback is where the bank sends the user back to after they
+// authorized, or declined, access to their accounts.
+func (h *BrokerHandler) PSD2Callback(w http.ResponseWriter, r *http.Request) {
+	conn, ok := h.psd2Connection(w, r)
+	if !ok {
+		return
+	}
+
+	outcome := "ok"
+	if err := h.syncService.CompletePSD2Consent(conn); err != nil {
+		log.Printf("Error completing bank access for connection %d: %v", conn.ID, err)
+		outcome = "failed"
+		if errors.Is(err, psd2.ErrConsentRejected) || errors.Is(err, psd2.ErrConsentRequired) {
+			outcome = "rejected"
+		}
+	}
+
+	http.Redirect(w, r, "/settings/connections/"+strconv.FormatInt(conn.ID, 10)+"?psd2="+outcome, http.StatusSeeOther)
+}
+
+// psd2Connection loads the connection in the URL and verifies that it is a
+// PSD2 connection of the user. Writes an error response and returns false
+// otherwise.
+func (h *BrokerHandler) psd2Connection(w http.ResponseWriter, r *http.Request) (*models.BrokerConnection, bool) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	conn, err := h.connRepo.GetByID(id)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if conn.BrokerType != "psd2" {
+		http.Error(w, "Not a bank connection", http.StatusBadRequest)
+		return nil, false
+	}
+	return conn, true
+}
+
+// psd2RedirectURI returns the address on the host the user reached the app
+// by that the bank sends them back to.
+func psd2RedirectURI(r *http.Request, connectionID int64) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/settings/connections/%d/psd2/callback", scheme, r.Host, connectionID)
+}
+
+// psuIP returns the IP address of the user's browser, which banks require
+// with consent requests. Behind a reverse proxy it is the first address of
+// X-Forwarded-For.
+func psuIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		first, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// validatePSD2APIURL returns the problem with the bank API URL of a PSD2
+// connection, or "" if it is valid. The token is sent with every request, so
+// it must be https://, and bank errors are shown to the user, so it must not
+// reach the server's own network.
+func (h *BrokerHandler) validatePSD2APIURL(apiURL string) string {
+	if apiURL == "" {
+		return "Bank API URL is required"
+	}
+	if !secureAPIURL(apiURL, h.allowLoopbackAPIURLs) {
+		return "Bank API URL must be an https:// address"
+	}
+	if err := broker.ValidateAPIURL(apiURL); err != nil {
+		return "Bank API URL must not point to a private or local address"
+	}
+	return ""
+}
+
+// secureAPIURL reports whether credentials may be sent to an API URL: it
+// must be https://, or plain HTTP on this machine if allowLoopback is set
+// for tests.
+func secureAPIURL(apiURL string, allowLoopback bool) bool {
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return allowLoopback && (host == "localhost" || net.ParseIP(host).IsLoopback())
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/sync"
+)
+
+// taskRefreshSeconds is how often the task page reloads while a task runs.
+const taskRefreshSeconds = 2
+
+// brokerTask is an account fetch, sync or sync preview. Logging in can take
+// minutes while the user approves in their MitID or banking app, so broker
+// work never runs on the request path: posts start a task and return
+// straight away, and the page polls the task's JSON status or, without
+// JavaScript, the task page refreshes until it is done.
+type brokerTask struct {
+	Kind      string // "fetch", "sync" or "preview"
+	StartedAt time.Time
+	Done      bool
+	Err       error
+	Accounts  []sync.ExternalAccount // Set when a fetch succeeds
+	Result    *sync.SyncResult       // Set when a sync or preview succeeds
+}
+
+// brokerTasks holds the latest task per connection.
+type brokerTasks struct {
+	mu    stdsync.Mutex
+	tasks map[int64]*brokerTask
+}
+
+func newBrokerTasks() *brokerTasks {
+	return &brokerTasks{tasks: make(map[int64]*brokerTask)}
+}
+
+// start runs fn in the background unless a task is already running for the
+// connection. It returns false if one was.
+func (t *brokerTasks) start(connectionID int64, kind string, fn func(task *brokerTask)) bool {
+	t.mu.Lock()
+	if task := t.tasks[connectionID]; task != nil && !task.Done {
+		t.mu.Unlock()
+		return false
+	}
+	task := &brokerTask{Kind: kind, StartedAt: time.Now()}
+	t.tasks[connectionID] = task
+	t.mu.Unlock()
+
+	go func() {
+		result := &brokerTask{Kind: kind, StartedAt: task.StartedAt}
+		fn(result)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*task = *result
+		task.Done = true
+	}()
+	return true
+}
+
+// get returns a copy of the connection's latest task, or nil.
+func (t *brokerTasks) get(connectionID int64) *brokerTask {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	task := t.tasks[connectionID]
+	if task == nil {
+		return nil
+	}
+	copied := *task
+	return &copied
+}
+
+// isNoJS reports whether a request was sent by one of the plain form
+// fallbacks rather than by the page's JavaScript.
+func isNoJS(r *http.Request) bool {
+	return r.FormValue("nojs") == "1"
+}
+
+// startFetchTask fetches the broker accounts in the background.
+func (h *BrokerHandler) startFetchTask(w http.ResponseWriter, r *http.Request, connectionID int64) {
+	started := h.tasks.start(connectionID, "fetch", func(task *brokerTask) {
+		task.Accounts, task.Err = h.syncService.GetExternalAccounts(connectionID)
+		if task.Err != nil {
+			log.Printf("Error fetching external accounts: %v", task.Err)
+		}
+	})
+	h.taskStarted(w, r, connectionID, "fetch", started)
+}
+
+// startSyncTask syncs the connection in the background, or only compares
+// the broker's data with the accounts if preview is set.
+func (h *BrokerHandler) startSyncTask(w http.ResponseWriter, r *http.Request, connectionID int64, preview bool) {
+	kind := "sync"
+	if preview {
+		kind = "preview"
+	}
+	started := h.tasks.start(connectionID, kind, func(task *brokerTask) {
+		if preview {
+			task.Result, task.Err = h.syncService.DryRunConnection(connectionID)
+		} else {
+			task.Result, task.Err = h.syncService.SyncConnection(connectionID)
+		}
+		if task.Err != nil {
+			log.Printf("Error running %s for connection %d: %v", kind, connectionID, task.Err)
+		}
+	})
+	h.taskStarted(w, r, connectionID, kind, started)
+}
+
+// taskStarted answers the post that started a task. Plain form posts are
+// redirected to the task page; the page's JavaScript gets 202 Accepted with
+// the URL of the task's JSON status. A running task of the same kind is
+// joined, while one of another kind is a conflict.
+func (h *BrokerHandler) taskStarted(w http.ResponseWriter, r *http.Request, connectionID int64, kind string, started bool) {
+	taskURL := "/settings/connections/" + strconv.FormatInt(connectionID, 10) + "/task"
+	if isNoJS(r) {
+		http.Redirect(w, r, taskURL, http.StatusSeeOther)
+		return
+	}
+	if !started {
+		if running := h.tasks.get(connectionID); running == nil || running.Kind != kind {
+			http.Error(w, "Another fetch or sync is running for this connection", http.StatusConflict)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"task_url": taskURL + "?format=json"})
+}
+
+// writeTaskJSON writes the status of a task for the page's JavaScript. A
+// finished task has either an error or a result: the accounts of a fetch,
+// the would-be changes of a preview, or a summary of a sync.
+func writeTaskJSON(w http.ResponseWriter, task *brokerTask) {
+	status := map[string]any{
+		"kind": task.Kind,
+		"done": task.Done,
+	}
+	switch {
+	case !task.Done:
+	case task.Err != nil:
+		status["error"] = task.Err.Error()
+	case task.Kind == "fetch":
+		status["result"] = task.Accounts
+	case task.Kind == "preview":
+		status["result"] = map[string]any{
+			"dry_run":          true,
+			"accounts_synced":  task.Result.AccountsSynced,
+			"positions_synced": task.Result.PositionsSynced,
+			"accounts":         task.Result.Changes,
+		}
+	default:
+		status["result"] = map[string]string{"message": syncResultMessage(task.Result)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Error encoding task status: %v", err)
+	}
+}
+
+// syncResultMessage summarizes the outcome of a sync.
+func syncResultMessage(result *sync.SyncResult) string {
+	msg := "Synced " + strconv.Itoa(result.PositionsSynced) + " positions from " + strconv.Itoa(result.AccountsSynced) + " accounts"
+	if result.HeldDeletions > 0 {
+		msg += ". " + strconv.Itoa(result.HeldDeletions) + " missing holdings were kept - review them on the connection page"
+	}
+	if result.HeldBalances > 0 {
+		msg += ". " + strconv.Itoa(result.HeldBalances) + " unusual balances were kept - review them on the connection page"
+	}
+	if len(result.AccountErrors) > 0 {
+		msg += ". " + strconv.Itoa(len(result.AccountErrors)) + " accounts failed - see the connection page"
+	}
+	return msg
+}
+
+// TaskStatus renders the progress of a background fetch or sync, or with
+// format=json writes its status for the page's JavaScript. While the task
+// runs the page reloads itself and shows the MitID QR code or the Saxo
+// login link; a finished fetch shows the account mapping form.
+func (h *BrokerHandler) TaskStatus(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// Extract connection ID from URL: /settings/connections/{id}/task
+	idStr := strings.TrimPrefix(r.URL.Path, "/settings/connections/")
+	idStr = strings.TrimSuffix(idStr, "/task")
+	connectionID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	conn, err := h.connRepo.GetByID(connectionID)
+	if err != nil || conn == nil || conn.UserID != user.ID {
+		http.NotFound(w, r)
+		return
+	}
+
+	task := h.tasks.get(connectionID)
+	if r.URL.Query().Get("format") == "json" {
+		if task == nil {
+			http.Error(w, "No fetch or sync has been started", http.StatusNotFound)
+			return
+		}
+		writeTaskJSON(w, task)
+		return
+	}
+	if task == nil {
+		http.Redirect(w, r, "/settings/connections/"+idStr, http.StatusSeeOther)
+		return
+	}
+
+	if task.Done && task.Kind == "fetch" {
+		if task.Err != nil {
+			h.renderAccountMapping(w, user, conn, nil, task.Err.Error())
+			return
+		}
+		h.renderAccountMapping(w, user, conn, task.Accounts, "")
+		return
+	}
+
+	data := map[string]any{
+		"Title":      "Connection Details",
+		"User":       user,
+		"ActiveNav":  "settings",
+		"Connection": conn,
+		"Task":       task,
+	}
+	if !task.Done {
+		data["RefreshSeconds"] = taskRefreshSeconds
+		if conn.BrokerType == "saxo" {
+			data["AuthStatus"] = saxo.GetOAuthStatus(connectionID)
+			data["AuthURL"] = saxo.GetOAuthURL(connectionID)
+		} else {
+			data["AuthStatus"] = nordnet.GetMitIDStatusNative(connectionID)
+		}
+		data["QRTimestamp"] = time.Now().Unix()
+	}
+	h.render(w, "broker-task.html", data)
+}
+
+// renderAccountMapping renders the account mapping page. Without external
+// accounts the page fetches them itself.
+func (h *BrokerHandler) renderAccountMapping(w http.ResponseWriter, user *models.User, conn *models.BrokerConnection, accounts []sync.ExternalAccount, errMsg string) {
+	// Get existing mappings
+	existingMappings, _ := h.mappingRepo.GetByConnectionID(conn.ID)
+	mappingsByExternal := make(map[string]*models.AccountMapping)
+	for _, m := range existingMappings {
+		mappingsByExternal[m.ExternalAccountID] = m
+	}
+
+	// Convert mappings to JSON for JavaScript
+	mappingsJSON, _ := json.Marshal(mappingsByExternal)
+
+	// Get local accounts for dropdown
+	localAccounts, _ := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+
+	data := map[string]any{
+		"Title":                "Map Accounts",
+		"User":                 user,
+		"ActiveNav":            "settings",
+		"Connection":           conn,
+		"ExternalAccounts":     accounts,
+		"ExistingMappings":     mappingsByExternal,
+		"ExistingMappingsJSON": template.JS(mappingsJSON), // Safe for embedding in JavaScript
+		"LocalAccounts":        localAccounts,
+		"Fetched":              accounts != nil,
+		"NeedsFetch":           accounts == nil && errMsg == "",
+	}
+	if errMsg != "" {
+		data["Error"] = errMsg
+	}
+	h.render(w, "account-mapping.html", data)
+}
@@ -12,6 +12,7 @@ import (
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // CategoryHandler handles category routes.
@@ -69,6 +70,8 @@ func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
 		"ActiveNav":  "categories",
 		"Categories": categoriesWithCounts,
 		"DemoMode":   IsDemoMode(),
+
+		"DefaultExpectedReturn": services.DefaultExpectedReturn,
 	})
 }
 
@@ -89,6 +92,7 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	color := strings.TrimSpace(r.FormValue("color"))
 	icon := strings.TrimSpace(r.FormValue("icon"))
 	sortOrderStr := r.FormValue("sort_order")
+	description := strings.TrimSpace(r.FormValue("description"))
 
 	// Validate
 	if name == "" {
@@ -108,6 +112,18 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedReturn, ok := parseExpectedReturn(r.FormValue("expected_return"))
+	if !ok {
+		h.renderError(w, r, user, "Expected return must be a percentage between -100 and 100")
+		return
+	}
+
+	liquidity, ok := parseLiquidity(r.FormValue("liquidity"))
+	if !ok {
+		h.renderError(w, r, user, "Unknown liquidity")
+		return
+	}
+
 	// Default color if not provided
 	if color == "" {
 		color = "#6366f1"
@@ -125,6 +141,10 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Color:     color,
 		Icon:      icon,
 		SortOrder: sortOrder,
+
+		ExpectedReturn: expectedReturn,
+		Liquidity:      liquidity,
+		Description:    description,
 	}
 
 	_, err = h.categoryRepo.Create(category)
@@ -179,6 +199,7 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	color := strings.TrimSpace(r.FormValue("color"))
 	icon := strings.TrimSpace(r.FormValue("icon"))
 	sortOrderStr := r.FormValue("sort_order")
+	description := strings.TrimSpace(r.FormValue("description"))
 
 	// Validate
 	if name == "" {
@@ -198,6 +219,18 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedReturn, ok := parseExpectedReturn(r.FormValue("expected_return"))
+	if !ok {
+		http.Error(w, "Expected return must be a percentage between -100 and 100", http.StatusBadRequest)
+		return
+	}
+
+	liquidity, ok := parseLiquidity(r.FormValue("liquidity"))
+	if !ok {
+		http.Error(w, "Unknown liquidity", http.StatusBadRequest)
+		return
+	}
+
 	// Parse sort order
 	sortOrder := existing.SortOrder
 	if sortOrderStr != "" {
@@ -208,6 +241,9 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	existing.Color = color
 	existing.Icon = icon
 	existing.SortOrder = sortOrder
+	existing.ExpectedReturn = expectedReturn
+	existing.Liquidity = liquidity
+	existing.Description = description
 
 	err = h.categoryRepo.Update(existing)
 	if err != nil {
@@ -255,6 +291,30 @@ func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/categories", http.StatusSeeOther)
 }
 
+// parseExpectedReturn parses the optional expected annual return in percent.
+// An empty value means the category uses the default assumption.
+func parseExpectedReturn(value string) (*float64, bool) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, ",", "."))
+	if value == "" {
+		return nil, true
+	}
+	expectedReturn, err := strconv.ParseFloat(value, 64)
+	if err != nil || expectedReturn < -100 || expectedReturn > 100 {
+		return nil, false
+	}
+	return &expectedReturn, true
+}
+
+// parseLiquidity parses the optional liquidity class of a category. An empty
+// value leaves the category unclassified.
+func parseLiquidity(value string) (string, bool) {
+	switch value = strings.TrimSpace(value); value {
+	case "", models.LiquidityLiquid, models.LiquidityIlliquid, models.LiquidityLocked:
+		return value, true
+	}
+	return "", false
+}
+
 // render renders a template with the given data.
 func (h *CategoryHandler) render(w http.ResponseWriter, name string, data map[string]any) {
 	if data == nil {
@@ -297,5 +357,7 @@ func (h *CategoryHandler) renderError(w http.ResponseWriter, r *http.Request, us
 		"ActiveNav":  "categories",
 		"Categories": categoriesWithCounts,
 		"Error":      errMsg,
+
+		"DefaultExpectedReturn": services.DefaultExpectedReturn,
 	})
 }
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// newCSVDownload sets the download headers for a dated CSV file and returns
+// a writer for it. The caller must flush the writer.
+func newCSVDownload(w http.ResponseWriter, name string) *csv.Writer {
+	filename := fmt.Sprintf("%s_%s.csv", name, time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	return csv.NewWriter(w)
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// SetNetWorthSnapshotService exports the daily net worth history from s,
+// like the dashboard's chart shows it.
+func (h *ExportHandler) SetNetWorthSnapshotService(s *services.NetWorthSnapshotService) {
+	h.snapshots = s
+}
+
+// ExportNetWorthHistory exports the data of the dashboard's net worth chart.
+func (h *ExportHandler) ExportNetWorthHistory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var history []repository.NetWorthPoint
+	var err error
+	if h.snapshots != nil {
+		history, err = h.snapshots.Points(user, time.Time{})
+	} else {
+		history, err = h.transactionRepo.GetNetWorthHistory(user.ID)
+	}
+	if err != nil {
+		http.Error(w, "Failed to get net worth history", http.StatusInternalServerError)
+		return
+	}
+
+	writer := newCSVDownload(w, "net_worth")
+	defer writer.Flush()
+
+	writer.Write([]string{"Date", "Net Worth"})
+	for _, p := range history {
+		writer.Write([]string{p.Date.Format("2006-01-02"), formatAmount(p.NetWorth)})
+	}
+}
+
+// ExportBalanceHistory exports the end-of-day balance history of the user's
+// accounts, or of a single account when the account parameter is set.
+func (h *ExportHandler) ExportBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserIDWithHistory(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get accounts", http.StatusInternalServerError)
+		return
+	}
+
+	if idStr := r.URL.Query().Get("account"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid account ID", http.StatusBadRequest)
+			return
+		}
+		var selected []*models.Account
+		for _, acc := range accounts {
+			if acc.ID == id {
+				selected = append(selected, acc)
+			}
+		}
+		if selected == nil {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		accounts = selected
+	}
+
+	history, err := h.transactionRepo.GetBalanceHistoryByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to get balance history", http.StatusInternalServerError)
+		return
+	}
+
+	type balanceRow struct {
+		date    time.Time
+		account *models.Account
+		balance float64
+	}
+	var rows []balanceRow
+	for _, acc := range accounts {
+		for _, p := range history[acc.ID] {
+			rows = append(rows, balanceRow{date: p.Date, account: acc, balance: p.Balance})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].date.Before(rows[j].date) })
+
+	writer := newCSVDownload(w, "balances")
+	defer writer.Flush()
+
+	writer.Write([]string{"Date", "Account", "Currency", "Balance"})
+	for _, row := range rows {
+		writer.Write([]string{
+			row.date.Format("2006-01-02"),
+			row.account.Name,
+			row.account.Currency,
+			formatAmount(row.balance),
+		})
+	}
+}
+
+// ExportComposition exports the portfolio composition chart for the view in
+// the type parameter: category (default), asset_type, currency or label.
+func (h *ExportHandler) ExportComposition(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	composition, err := h.portfolio.GetPortfolioComposition(user.ID, applyExclusions(r))
+	if err != nil {
+		http.Error(w, "Failed to get portfolio composition", http.StatusInternalServerError)
+		return
+	}
+
+	var header []string
+	var rows [][]string
+	switch r.URL.Query().Get("type") {
+	case "", models.TargetTypeCategory:
+		header = []string{"Category", "Value", "Percentage", "Accounts"}
+		for _, c := range composition.ByCategory {
+			rows = append(rows, []string{c.CategoryName, formatAmount(c.Value), formatAmount(c.Percentage), strconv.Itoa(c.AccountCount)})
+		}
+	case models.TargetTypeAssetType:
+		header = []string{"Asset Type", "Value", "Percentage", "Positions"}
+		for _, a := range composition.ByAssetType {
+			rows = append(rows, []string{a.AssetType, formatAmount(a.Value), formatAmount(a.Percentage), strconv.Itoa(a.Count)})
+		}
+	case models.TargetTypeCurrency:
+		header = []string{"Currency", "Value", "Percentage"}
+		for _, c := range composition.ByCurrency {
+			rows = append(rows, []string{c.Currency, formatAmount(c.Value), formatAmount(c.Percentage)})
+		}
+	case models.TargetTypeLabel:
+		header = []string{"Label", "Value", "Percentage", "Positions"}
+		for _, l := range composition.ByLabel {
+			rows = append(rows, []string{l.Name(), formatAmount(l.Value), formatAmount(l.Percentage), strconv.Itoa(l.Count)})
+		}
+	default:
+		http.Error(w, "Invalid composition type", http.StatusBadRequest)
+		return
+	}
+	header[1] += " (" + composition.BaseCurrency + ")"
+
+	writer := newCSVDownload(w, "composition")
+	defer writer.Flush()
+
+	writer.Write(header)
+	writer.WriteAll(rows)
+}
+
+// ExportAllocationDrift exports the actual against target allocation for the
+// view in the type parameter: category (default), asset_type, currency or
+// label.
+func (h *ExportHandler) ExportAllocationDrift(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetType := r.URL.Query().Get("type")
+	if targetType == "" {
+		targetType = models.TargetTypeCategory
+	}
+	switch targetType {
+	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency, models.TargetTypeLabel:
+		// Valid
+	default:
+		http.Error(w, "Invalid target type", http.StatusBadRequest)
+		return
+	}
+
+	comparison, err := h.portfolio.GetAllocationComparison(user.ID, targetType, applyExclusions(r))
+	if err != nil {
+		http.Error(w, "Failed to get allocation comparison", http.StatusInternalServerError)
+		return
+	}
+
+	writer := newCSVDownload(w, "allocation_"+targetType)
+	defer writer.Flush()
+
+	writer.Write([]string{"Name", "Actual %", "Target %", "Drift %", "Value"})
+	for _, item := range comparison.Items {
+		writer.Write([]string{
+			item.Name,
+			formatAmount(item.ActualPct),
+			formatAmount(item.TargetPct),
+			formatAmount(item.DriftPct),
+			formatAmount(item.ActualValue),
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/repository"
+)
+
+// Command palette sections, in the order they are listed.
+const (
+	commandSectionActions  = "Actions"
+	commandSectionPages    = "Pages"
+	commandSectionAccounts = "Accounts"
+)
+
+// command is an entry of the command palette. It either navigates to URL or
+// runs Action in the browser.
+type command struct {
+	Title    string `json:"title"`
+	Section  string `json:"section"`
+	URL      string `json:"url,omitempty"`
+	Action   string `json:"action,omitempty"`   // "quick-add" or "toggle-theme"
+	Hint     string `json:"hint,omitempty"`     // Shown on the right, e.g. a shortcut
+	Keywords string `json:"keywords,omitempty"` // Matched but not shown
+}
+
+// commandPages are the pages every user can open from the command palette.
+var commandPages = []command{
+	{Title: "Dashboard", URL: "/dashboard", Keywords: "home net worth"},
+	{Title: "Accounts", URL: "/accounts"},
+	{Title: "Categories", URL: "/categories"},
+	{Title: "Transactions", URL: "/transactions"},
+	{Title: "Goals", URL: "/goals"},
+	{Title: "Compare", URL: "/compare", Keywords: "dates changes"},
+	{Title: "Tools", URL: "/tools", Keywords: "calculators"},
+	{Title: "Portfolio Analyzer", URL: "/tools/portfolio-analyzer", Keywords: "allocation rebalance holdings"},
+	{Title: "FIRE Calculator", URL: "/tools/fire-calculator", Keywords: "retire"},
+	{Title: "Compound Interest", URL: "/tools/compound-interest"},
+	{Title: "Salary Calculator", URL: "/tools/salary-calculator", Keywords: "tax"},
+	{Title: "Stress Test", URL: "/tools/stress-test", Keywords: "crash scenario shock"},
+	{Title: "Import History", URL: "/accounts/history", Keywords: "csv json"},
+	{Title: "Settings", URL: "/settings", Keywords: "preferences export backup"},
+	{Title: "Broker Connections", URL: "/settings/connections", Keywords: "nordnet saxo"},
+	{Title: "Exchange Rates", URL: "/settings/exchange-rates", Keywords: "currency"},
+	{Title: "API Keys", URL: "/settings/api-keys"},
+	{Title: "Notifications", URL: "/settings/notifications", Keywords: "ntfy gotify slack discord"},
+	{Title: "Usage", URL: "/settings/usage", Keywords: "quota"},
+	{Title: "Categorization Rules", URL: "/settings/rules", Keywords: "categorize tag import"},
+	{Title: "What's New", URL: "/whats-new", Keywords: "release changelog"},
+}
+
+// CommandHandler serves the entries of the command palette.
+type CommandHandler struct {
+	accountRepo *repository.AccountRepository
+	connRepo    *repository.BrokerConnectionRepository
+}
+
+// NewCommandHandler creates a new command handler.
+func NewCommandHandler(accountRepo *repository.AccountRepository, connRepo *repository.BrokerConnectionRepository) *CommandHandler {
+	return &CommandHandler{accountRepo: accountRepo, connRepo: connRepo}
+}
+
+// Commands returns the command palette entries of the user: actions, pages,
+// and the user's active accounts, each opening the account's transactions.
+func (h *CommandHandler) Commands(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	commands := []command{
+		{Title: "New transaction", Section: commandSectionActions, Action: "quick-add", Hint: "N", Keywords: "add"},
+		{Title: "Toggle dark mode", Section: commandSectionActions, Action: "toggle-theme", Keywords: "theme light"},
+	}
+
+	conns, err := h.connRepo.GetActiveByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching connections for commands: %v", err)
+	}
+	for _, conn := range conns {
+		title := "Sync " + capitalize(conn.BrokerType)
+		if conn.Username != "" {
+			title += " (" + conn.Username + ")"
+		}
+		commands = append(commands, command{
+			Title:    title,
+			Section:  commandSectionActions,
+			URL:      fmt.Sprintf("/settings/connections/%d?action=sync", conn.ID),
+			Keywords: "broker refresh",
+		})
+	}
+
+	for _, page := range commandPages {
+		page.Section = commandSectionPages
+		commands = append(commands, page)
+	}
+	if user.IsAdmin {
+		commands = append(commands, command{Title: "Admin Panel", Section: commandSectionPages, URL: "/admin", Keywords: "users database"})
+	}
+
+	accounts, err := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts for commands: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+	for _, acc := range accounts {
+		commands = append(commands, command{
+			Title:   acc.Name,
+			Section: commandSectionAccounts,
+			URL:     fmt.Sprintf("/transactions?account=%d", acc.ID),
+			Hint:    acc.Currency,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(commands); err != nil {
+		log.Printf("Error encoding commands: %v", err)
+	}
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// compareDefaultDays is how far back the compare page looks by default.
+const compareDefaultDays = 30
+
+// CompareHandler shows what changed in a user's portfolio between two dates.
+type CompareHandler struct {
+	templates       map[string]*template.Template
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	holdingRepo     *repository.HoldingRepository
+}
+
+// NewCompareHandler creates a new CompareHandler.
+func NewCompareHandler(
+	templates map[string]*template.Template,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	holdingRepo *repository.HoldingRepository,
+) *CompareHandler {
+	return &CompareHandler{
+		templates:       templates,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
+	}
+}
+
+// Compare renders the changes between the dates of the from and to query
+// parameters, by default the last 30 days.
+func (h *CompareHandler) Compare(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if d, err := time.Parse("2006-01-02", r.URL.Query().Get("to")); err == nil {
+		to = d
+	}
+	from := to.AddDate(0, 0, -compareDefaultDays)
+	if d, err := time.Parse("2006-01-02", r.URL.Query().Get("from")); err == nil {
+		from = d
+	}
+	if from.After(to) {
+		from, to = to, from
+	}
+
+	accounts, err := h.accountRepo.GetByUserIDWithHistory(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading comparison", http.StatusInternalServerError)
+		return
+	}
+	history, err := h.transactionRepo.GetBalanceHistoryByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching balance history: %v", err)
+		http.Error(w, "Error loading comparison", http.StatusInternalServerError)
+		return
+	}
+	flows, err := h.transactionRepo.GetFlowsByAccount(user.ID, from, to)
+	if err != nil {
+		log.Printf("Error fetching account flows: %v", err)
+		http.Error(w, "Error loading comparison", http.StatusInternalServerError)
+		return
+	}
+	fromHoldings, err := h.holdingRepo.GetSnapshotsAt(user.ID, from)
+	if err != nil {
+		log.Printf("Error fetching holding snapshots: %v", err)
+		http.Error(w, "Error loading comparison", http.StatusInternalServerError)
+		return
+	}
+	toHoldings, err := h.holdingRepo.GetSnapshotsAt(user.ID, to)
+	if err != nil {
+		log.Printf("Error fetching holding snapshots: %v", err)
+		http.Error(w, "Error loading comparison", http.StatusInternalServerError)
+		return
+	}
+
+	comparison := services.NewComparison(accounts, history, flows, fromHoldings, toHoldings, from, to)
+	if comparison.HoldingsSince, err = h.holdingRepo.GetFirstSnapshotDay(user.ID); err != nil {
+		log.Printf("Error fetching first holding snapshot: %v", err)
+	}
+
+	h.render(w, "compare.html", map[string]any{
+		"Title":      "Compare",
+		"User":       user,
+		"ActiveNav":  "dashboard",
+		"Comparison": comparison,
+	})
+}
+
+// render renders a template with the given data.
+func (h *CompareHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -3,41 +3,64 @@ package handlers
 import (
 	"html/template"
 	"log"
-	"math"
 	"net/http"
 	"time"
 
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+	"wealth_tracker/internal/sync"
 )
 
 // DashboardHandler handles dashboard routes.
 type DashboardHandler struct {
 	templates       map[string]*template.Template
+	userRepo        *repository.UserRepository
 	accountRepo     *repository.AccountRepository
 	transactionRepo *repository.TransactionRepository
 	goalRepo        *repository.GoalRepository
 	categoryRepo    *repository.CategoryRepository
+	milestoneRepo   *repository.MilestoneRepository
+	netWorth        *services.NetWorthService
+	netWorthChange  *services.NetWorthChangeService
+	credentials     *sync.Service
+	snapshots       *services.NetWorthSnapshotService
 }
 
 // NewDashboardHandler creates a new DashboardHandler.
 func NewDashboardHandler(
 	templates map[string]*template.Template,
+	userRepo *repository.UserRepository,
 	accountRepo *repository.AccountRepository,
 	transactionRepo *repository.TransactionRepository,
 	goalRepo *repository.GoalRepository,
 	categoryRepo *repository.CategoryRepository,
+	milestoneRepo *repository.MilestoneRepository,
+	netWorth *services.NetWorthService,
 ) *DashboardHandler {
 	return &DashboardHandler{
 		templates:       templates,
+		userRepo:        userRepo,
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
 		goalRepo:        goalRepo,
 		categoryRepo:    categoryRepo,
+		milestoneRepo:   milestoneRepo,
+		netWorth:        netWorth,
 	}
 }
 
+// SetNetWorthChangeService shows what caused recent changes in net worth.
+func (h *DashboardHandler) SetNetWorthChangeService(s *services.NetWorthChangeService) {
+	h.netWorthChange = s
+}
+
+// SetCredentialChecker warns of broker logins that are expiring or stale.
+func (h *DashboardHandler) SetCredentialChecker(s *sync.Service) {
+	h.credentials = s
+}
+
 // Dashboard renders the main dashboard page.
 func (h *DashboardHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -46,99 +69,279 @@ func (h *DashboardHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Calculate financial statistics
-	netWorth, totalAssets, totalLiabilities, assetCount, liabilityCount := h.calculateStats(user.ID)
+	// Balances as they were at the end of the as-of day, if any
+	asOf := parseAsOf(r)
+
+	// Accounts the user leaves out of net worth, such as pensions
+	netWorthToggles, excluded := h.netWorthExclusions(user)
+
+	// Goals and milestones are of the whole net worth
+	full := h.computeNetWorth(user.ID, asOf)
+	stats := full.Excluding(excluded)
 
 	// Calculate monthly change
-	monthlyChange, monthlyPercent := h.calculateMonthlyChange(user.ID, netWorth)
+	monthlyChange, monthlyPercent := h.calculateMonthlyChange(user.ID, stats.NetWorth, asOf, excluded)
+
+	// Get pinned accounts with their balances
+	pinnedAccounts := h.pinnedAccounts(user.ID, asOf)
 
 	// Get recent transactions (limit 5)
-	recentTransactions, _ := h.transactionRepo.GetRecentByUserID(user.ID, 5)
+	var recentTransactions []*models.Transaction
+	if asOf != nil {
+		recentTransactions, _ = h.transactionRepo.GetRecentByUserIDAt(user.ID, *asOf, 5)
+	} else {
+		recentTransactions, _ = h.transactionRepo.GetRecentByUserID(user.ID, 5)
+	}
 
 	// Get goals with progress
 	goals, _ := h.goalRepo.GetByUserID(user.ID)
-	goalsWithProgress := h.calculateGoalProgress(user.ID, goals, netWorth)
+	goalsWithProgress := h.calculateGoalProgress(goals, full, asOf)
 
 	// Get categories with totals for asset distribution
 	categories, _ := h.categoryRepo.GetByUserID(user.ID)
-	categoryTotals := h.calculateCategoryTotals(user.ID, categories)
+	categoryTotals := calculateCategoryTotals(categories, full)
+	emergencyFund := h.calculateEmergencyFund(user, categories, full, asOf)
+
+	// Get net worth history for chart, up to the as-of day
+	netWorthHistory := h.netWorthHistory(user, asOf, full.NetWorth)
+	chartHistory := netWorthHistory
+	if len(excluded) > 0 {
+		// Snapshots hold the whole net worth, so the history without the
+		// excluded accounts is replayed from their balances
+		chartHistory, _ = h.transactionRepo.GetNetWorthHistoryExcluding(user.ID, excluded)
+		if asOf != nil {
+			chartHistory = netWorthHistoryUntil(chartHistory, *asOf)
+		}
+	}
+
+	// Split recent changes in net worth into what caused them
+	var netWorthChanges []services.NetWorthChange
+	if h.netWorthChange != nil && asOf == nil {
+		var err error
+		if netWorthChanges, err = h.netWorthChange.Recent(user); err != nil {
+			log.Printf("Error decomposing net worth change: %v", err)
+		}
+	}
 
-	// Get net worth history for chart
-	netWorthHistory, _ := h.transactionRepo.GetNetWorthHistory(user.ID)
+	// Broker logins to renew before syncs stop
+	var credentialWarnings []sync.CredentialWarning
+	if h.credentials != nil && asOf == nil {
+		var err error
+		if credentialWarnings, err = h.credentials.CredentialWarnings(user.ID); err != nil {
+			log.Printf("Error checking broker credentials: %v", err)
+		}
+	}
 
 	// Check if admin is impersonating
 	_, impersonating := r.Cookie("admin_session_id")
 
+	// Record milestones crossed since the last visit; an impersonating admin
+	// does not use up the user's celebration. Support view is read-only, so
+	// it only shows the milestones recorded so far. Looking back does not
+	// celebrate anything.
+	var milestones, newMilestones []*models.NetWorthMilestone
+	if asOf == nil && user.SupportViewer == "" {
+		milestones, newMilestones = h.updateMilestones(user, netWorthHistory, impersonating != nil)
+	} else if asOf == nil && user.MilestoneStep > 0 {
+		var err error
+		if milestones, err = h.milestoneRepo.GetByUserID(user.ID); err != nil {
+			log.Printf("Error fetching milestones: %v", err)
+		}
+	}
+
 	h.render(w, "dashboard.html", map[string]any{
 		"Title":              "Dashboard",
 		"User":               user,
 		"ActiveNav":          "dashboard",
-		"NetWorth":           netWorth,
-		"TotalAssets":        totalAssets,
-		"TotalLiabilities":   totalLiabilities,
-		"AssetCount":         assetCount,
-		"LiabilityCount":     liabilityCount,
+		"NetWorth":           stats.NetWorth,
+		"TotalAssets":        stats.Assets,
+		"TotalLiabilities":   stats.Liabilities,
+		"AssetCount":         stats.AssetCount,
+		"LiabilityCount":     stats.LiabilityCount,
 		"MonthlyChange":      monthlyChange,
 		"MonthlyPercent":     monthlyPercent,
 		"RecentTransactions": recentTransactions,
+		"PinnedAccounts":     pinnedAccounts,
 		"Goals":              goalsWithProgress,
 		"CategoryTotals":     categoryTotals,
-		"NetWorthHistory":    netWorthHistory,
+		"EmergencyFund":      emergencyFund,
+		"NetWorthHistory":    chartHistory,
+		"NetWorthToggles":    netWorthToggles,
+		"NetWorthChanges":    netWorthChanges,
+		"CredentialWarnings": credentialWarnings,
+		"Milestones":         milestones,
+		"NewMilestones":      newMilestones,
 		"IncludeCharts":      true,
+		"AsOf":               asOfData("/dashboard", asOf),
 		"Impersonating":      impersonating == nil,
 		"DemoMode":           IsDemoMode(),
 	})
 }
 
-// calculateStats calculates net worth, assets, liabilities, and counts.
-func (h *DashboardHandler) calculateStats(userID int64) (netWorth, totalAssets, totalLiabilities float64, assetCount, liabilityCount int) {
-	accounts, err := h.accountRepo.GetByUserIDActiveOnly(userID)
-	if err != nil {
-		return 0, 0, 0, 0, 0
+// updateMilestones records the net worth milestones reached in the history
+// and returns all milestones along with those not yet celebrated. The new
+// ones are marked as celebrated if markCelebrated is set.
+func (h *DashboardHandler) updateMilestones(user *models.User, history []repository.NetWorthPoint, markCelebrated bool) (all, uncelebrated []*models.NetWorthMilestone) {
+	if user.MilestoneStep <= 0 {
+		return nil, nil
 	}
 
-	for _, acc := range accounts {
-		balance, err := h.transactionRepo.GetLatestBalance(acc.ID)
-		if err != nil {
-			continue
+	if _, err := h.milestoneRepo.Record(user.ID, user.MilestoneStep, services.DetectMilestones(history, user.MilestoneStep)); err != nil {
+		log.Printf("Error recording milestones: %v", err)
+	}
+
+	all, err := h.milestoneRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching milestones: %v", err)
+		return nil, nil
+	}
+	for _, m := range all {
+		if !m.Celebrated {
+			uncelebrated = append(uncelebrated, m)
 		}
-		if acc.IsLiability {
-			// Use absolute value to handle both positive and negative storage
-			totalLiabilities += math.Abs(balance)
-			liabilityCount++
-		} else {
-			totalAssets += balance
-			assetCount++
+	}
+	if len(uncelebrated) > 0 && markCelebrated {
+		if err := h.milestoneRepo.MarkCelebrated(user.ID); err != nil {
+			log.Printf("Error marking milestones celebrated: %v", err)
 		}
 	}
-	netWorth = totalAssets - totalLiabilities
-	return
+	return all, uncelebrated
 }
 
-// calculateMonthlyChange calculates the change in net worth this month.
-func (h *DashboardHandler) calculateMonthlyChange(userID int64, currentNetWorth float64) (change float64, percent float64) {
-	// Get start of current month
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+// accounts returns the user's active accounts, or the accounts open on the
+// as-of day if set.
+func (h *DashboardHandler) accounts(userID int64, asOf *time.Time) ([]*models.Account, error) {
+	if asOf != nil {
+		return h.accountRepo.GetByUserIDOpenOn(userID, *asOf)
+	}
+	return h.accountRepo.GetByUserIDActiveOnly(userID)
+}
+
+// netWorthHistoryUntil returns the points of a net worth history up to the
+// end of a day.
+func netWorthHistoryUntil(history []repository.NetWorthPoint, day time.Time) []repository.NetWorthPoint {
+	end := day.AddDate(0, 0, 1)
+	for i, p := range history {
+		if !p.Date.Before(end) {
+			return history[:i]
+		}
+	}
+	return history
+}
+
+// pinnedAccounts returns the user's active pinned accounts, in the user's
+// order, with their balances.
+func (h *DashboardHandler) pinnedAccounts(userID int64, asOf *time.Time) []*models.Account {
+	accounts, err := h.accounts(userID, asOf)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		return nil
+	}
 
-	// Sum all transactions this month
-	accounts, _ := h.accountRepo.GetByUserIDActiveOnly(userID)
+	var pinned []*models.Account
 	for _, acc := range accounts {
-		monthlySum, _ := h.transactionRepo.GetSumSince(acc.ID, startOfMonth)
-		if acc.IsLiability {
-			// Use absolute value to handle both positive and negative storage
-			change -= math.Abs(monthlySum)
-		} else {
-			change += monthlySum
+		if !acc.IsPinned {
+			// Pinned accounts are listed first
+			break
+		}
+		acc.Balance, _ = balanceAsOf(h.transactionRepo, acc.ID, asOf)
+		pinned = append(pinned, acc)
+	}
+	return pinned
+}
+
+// computeNetWorth returns the user's net worth, at the end of the as-of day
+// if set. A failure is logged and shows as no net worth.
+func (h *DashboardHandler) computeNetWorth(userID int64, asOf *time.Time) *services.NetWorth {
+	netWorth, err := h.netWorth.Compute(userID, asOf)
+	if err != nil {
+		log.Printf("Error computing net worth: %v", err)
+		return &services.NetWorth{}
+	}
+	return netWorth
+}
+
+// netWorthHistory returns the user's daily net worth for the chart and
+// milestones: the recorded snapshots up to the end of the as-of day, or else
+// up to today with the current net worth as today's.
+func (h *DashboardHandler) netWorthHistory(user *models.User, asOf *time.Time, current float64) []repository.NetWorthPoint {
+	if h.snapshots == nil {
+		history, _ := h.transactionRepo.GetNetWorthHistory(user.ID)
+		if asOf != nil {
+			history = netWorthHistoryUntil(history, *asOf)
+		}
+		return history
+	}
+
+	var to time.Time
+	if asOf != nil {
+		to = *asOf
+	}
+	history, err := h.snapshots.Points(user, to)
+	if err != nil {
+		log.Printf("Error getting net worth history: %v", err)
+	}
+	if asOf != nil {
+		return history
+	}
+	today := services.NetWorthDay(time.Now(), user.Timezone)
+	if n := len(history); n > 0 && !history[n-1].Date.Before(today) {
+		history = history[:n-1]
+	}
+	return append(history, repository.NetWorthPoint{Date: today, NetWorth: current})
+}
+
+// calculateEmergencyFund calculates how many months of expenses the user's
+// liquid assets cover: the worth of the asset accounts in liquid categories
+// over their average outflow per statement period.
+func (h *DashboardHandler) calculateEmergencyFund(user *models.User, categories []*models.Category, netWorth *services.NetWorth, asOf *time.Time) services.EmergencyFundCoverage {
+	userID := user.ID
+	liquid := make(map[int64]bool)
+	for _, cat := range categories {
+		if cat.Liquidity == models.LiquidityLiquid {
+			liquid[cat.ID] = true
 		}
 	}
+	if len(liquid) == 0 {
+		return services.EmergencyFundCoverage{}
+	}
 
-	// Calculate percentage
-	if currentNetWorth != 0 && currentNetWorth != change {
-		previousWorth := currentNetWorth - change
-		if previousWorth != 0 {
-			percent = (change / previousWorth) * 100
+	var liquidAssets float64
+	for _, a := range netWorth.Accounts {
+		if a.Account.IsLiability || a.Account.CategoryID == nil || !liquid[*a.Account.CategoryID] {
+			continue
 		}
+		liquidAssets += a.Worth
+	}
+
+	now := time.Now()
+	if asOf != nil {
+		now = asOf.AddDate(0, 0, 1)
+	}
+	start, end := services.EmergencyFundPeriod(now, user.PeriodStartDay)
+	outflows, months, err := h.transactionRepo.GetOutflowsByLiquidity(userID, models.LiquidityLiquid, start, end, user.PeriodStartDay)
+	if err != nil {
+		log.Printf("Error fetching liquid outflows: %v", err)
+	}
+	coverage := services.NewEmergencyFundCoverage(liquidAssets, outflows, months)
+	coverage.HasLiquid = true
+	return coverage
+}
+
+// calculateMonthlyChange calculates the change in net worth this month, or
+// in the month of the as-of day up to its end, leaving out the excluded
+// accounts. It is measured from the balances at the end of the previous
+// month, as a month's transactions may run past the as-of day.
+func (h *DashboardHandler) calculateMonthlyChange(userID int64, currentNetWorth float64, asOf *time.Time, excluded map[int64]bool) (change float64, percent float64) {
+	now := time.Now()
+	if asOf != nil {
+		now = *asOf
+	}
+	endOfPreviousMonth := time.Date(now.Year(), now.Month(), 0, 0, 0, 0, 0, time.UTC)
+	previousNetWorth := h.computeNetWorth(userID, &endOfPreviousMonth).Excluding(excluded).NetWorth
+	change = currentNetWorth - previousNetWorth
+	if previousNetWorth != 0 {
+		percent = (change / previousNetWorth) * 100
 	}
 	return
 }
@@ -153,14 +356,10 @@ type GoalWithProgress struct {
 }
 
 // calculateGoalProgress calculates progress for each goal.
-func (h *DashboardHandler) calculateGoalProgress(userID int64, goals []*models.Goal, netWorth float64) []GoalWithProgress {
+func (h *DashboardHandler) calculateGoalProgress(goals []*models.Goal, netWorth *services.NetWorth, asOf *time.Time) []GoalWithProgress {
 	result := make([]GoalWithProgress, 0, len(goals))
 	for _, goal := range goals {
-		// Determine current worth based on whether goal is category-specific
-		currentWorth := netWorth
-		if goal.CategoryID != nil {
-			currentWorth = h.calculateCategoryNetWorth(userID, *goal.CategoryID)
-		}
+		currentWorth := netWorth.ForGoal(goal)
 
 		progress := 0.0
 		if goal.TargetAmount > 0 {
@@ -170,8 +369,13 @@ func (h *DashboardHandler) calculateGoalProgress(userID int64, goals []*models.G
 			}
 		}
 
-		// Goal is reached if progress >= 100% OR already marked in database
-		isReached := goal.ReachedDate != nil || currentWorth >= goal.TargetAmount
+		// Goal is reached if progress >= 100% OR already marked in database,
+		// as of the day looked back at
+		reachedDate := goal.ReachedDate
+		if asOf != nil && reachedDate != nil && !reachedDate.Before(asOf.AddDate(0, 0, 1)) {
+			reachedDate = nil
+		}
+		isReached := reachedDate != nil || currentWorth >= goal.TargetAmount
 
 		gwp := GoalWithProgress{
 			Goal:      goal,
@@ -182,6 +386,9 @@ func (h *DashboardHandler) calculateGoalProgress(userID int64, goals []*models.G
 		// Calculate days left if deadline is set and goal not reached
 		if goal.Deadline != nil && !isReached {
 			now := time.Now()
+			if asOf != nil {
+				now = *asOf
+			}
 			daysLeft := int(goal.Deadline.Sub(now).Hours() / 24)
 			gwp.DaysLeft = &daysLeft
 			gwp.IsOverdue = daysLeft < 0
@@ -192,52 +399,18 @@ func (h *DashboardHandler) calculateGoalProgress(userID int64, goals []*models.G
 	return result
 }
 
-// calculateCategoryNetWorth calculates the net worth for a specific category.
-func (h *DashboardHandler) calculateCategoryNetWorth(userID, categoryID int64) float64 {
-	accounts, err := h.accountRepo.GetByUserIDActiveOnly(userID)
-	if err != nil {
-		return 0
-	}
-
-	netWorth := 0.0
-	for _, acc := range accounts {
-		// Skip accounts not in this category
-		if acc.CategoryID == nil || *acc.CategoryID != categoryID {
-			continue
-		}
-
-		balance, err := h.transactionRepo.GetLatestBalance(acc.ID)
-		if err != nil {
-			continue
-		}
-		if acc.IsLiability {
-			netWorth -= math.Abs(balance)
-		} else {
-			netWorth += balance
-		}
-	}
-	return netWorth
-}
-
 // CategoryTotal represents a category with its total value.
 type CategoryTotal struct {
 	*models.Category
 	Total float64
 }
 
-// calculateCategoryTotals calculates total value for each category.
-func (h *DashboardHandler) calculateCategoryTotals(userID int64, categories []*models.Category) []CategoryTotal {
+// calculateCategoryTotals returns the categories with assets, with the
+// total worth of their assets.
+func calculateCategoryTotals(categories []*models.Category, netWorth *services.NetWorth) []CategoryTotal {
 	result := make([]CategoryTotal, 0, len(categories))
 	for _, cat := range categories {
-		accounts, _ := h.accountRepo.GetByCategoryID(cat.ID)
-		total := 0.0
-		for _, acc := range accounts {
-			if acc.UserID == userID && !acc.IsLiability {
-				balance, _ := h.transactionRepo.GetLatestBalance(acc.ID)
-				total += balance
-			}
-		}
-		if total > 0 {
+		if total := netWorth.CategoryAssets[cat.ID]; total > 0 {
 			result = append(result, CategoryTotal{Category: cat, Total: total})
 		}
 	}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+)
+
+// netWorthGroupLabels are the dashboard toggles of the net worth groups.
+var netWorthGroupLabels = map[string]string{
+	models.NetWorthGroupPension: "Exclude pension",
+	models.NetWorthGroupHome:    "Exclude home equity",
+	models.NetWorthGroupSpouse:  "Exclude spouse accounts",
+}
+
+// NetWorthToggle is a dashboard toggle leaving the accounts of a net worth
+// group out of net worth.
+type NetWorthToggle struct {
+	Group    string
+	Label    string
+	Excluded bool
+	Accounts int // Accounts in the group
+}
+
+// netWorthExclusions returns the toggles of the net worth groups the user has
+// accounts in, and the accounts their exclusions leave out, including closed
+// accounts of the history.
+func (h *DashboardHandler) netWorthExclusions(user *models.User) ([]NetWorthToggle, map[int64]bool) {
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		return nil, nil
+	}
+
+	toggles := make([]NetWorthToggle, 0, len(models.NetWorthGroups))
+	excluded := make(map[int64]bool)
+	for _, group := range models.NetWorthGroups {
+		toggle := NetWorthToggle{
+			Group:    group,
+			Label:    netWorthGroupLabels[group],
+			Excluded: slices.Contains(user.NetWorthExclusions, group),
+		}
+		for _, acc := range accounts {
+			if acc.NetWorthGroup != group {
+				continue
+			}
+			toggle.Accounts++
+			if toggle.Excluded {
+				excluded[acc.ID] = true
+			}
+		}
+		if toggle.Accounts > 0 {
+			toggles = append(toggles, toggle)
+		}
+	}
+	return toggles, excluded
+}
+
+// SetNetWorthExclusions saves the net worth groups ticked on the dashboard,
+// which are left out of its net worth, and returns to the dashboard.
+func (h *DashboardHandler) SetNetWorthExclusions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	var groups []string
+	for _, group := range models.NetWorthGroups {
+		if slices.Contains(r.Form["exclude"], group) {
+			groups = append(groups, group)
+		}
+	}
+	if err := h.userRepo.SetNetWorthExclusions(user.ID, groups); err != nil {
+		log.Printf("Error saving net worth exclusions: %v", err)
+		http.Error(w, "Failed to save net worth exclusions", http.StatusInternalServerError)
+		return
+	}
+
+	target := "/dashboard"
+	if asOf := r.FormValue("asof"); asOf != "" {
+		target += "?asof=" + url.QueryEscape(asOf)
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// DataQualityHandler shows users anomalies in their data, with links to fix
+// them.
+type DataQualityHandler struct {
+	templates map[string]*template.Template
+	quality   *services.DataQualityService
+}
+
+// NewDataQualityHandler creates a new DataQualityHandler.
+func NewDataQualityHandler(templates map[string]*template.Template, quality *services.DataQualityService) *DataQualityHandler {
+	return &DataQualityHandler{
+		templates: templates,
+		quality:   quality,
+	}
+}
+
+// DataQuality renders the data quality page.
+func (h *DataQualityHandler) DataQuality(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	report, err := h.quality.Check(user.ID, time.Now())
+	if err != nil {
+		log.Printf("Error checking data quality: %v", err)
+		http.Error(w, "Error checking data quality", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "data-quality.html", map[string]any{
+		"Title":     "Data Quality",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Report":    report,
+	})
+}
+
+// render renders a template with the given data.
+func (h *DataQualityHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
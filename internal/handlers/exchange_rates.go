@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// currencyCodePattern accepts ISO codes as well as crypto tickers like USDT.
+var currencyCodePattern = regexp.MustCompile(`^[A-Z0-9]{2,10}$`)
+
+// ExchangeRateHandler handles the user's manual exchange rates.
+type ExchangeRateHandler struct {
+	templates map[string]*template.Template
+	rateRepo  *repository.ExchangeRateRepository
+}
+
+// NewExchangeRateHandler creates a new ExchangeRateHandler.
+func NewExchangeRateHandler(
+	templates map[string]*template.Template,
+	rateRepo *repository.ExchangeRateRepository,
+) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		templates: templates,
+		rateRepo:  rateRepo,
+	}
+}
+
+// ExchangeRateView is a manual exchange rate with its status for display.
+type ExchangeRateView struct {
+	*models.ManualExchangeRate
+	Status string // "active", "expired" or "upcoming"
+}
+
+// List renders the manual exchange rates page.
+func (h *ExchangeRateHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, user, "")
+}
+
+// Create handles adding a manual exchange rate.
+func (h *ExchangeRateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderPage(w, user, "Invalid form data")
+		return
+	}
+
+	from := strings.ToUpper(strings.TrimSpace(r.FormValue("from_currency")))
+	to := strings.ToUpper(strings.TrimSpace(r.FormValue("to_currency")))
+	if !currencyCodePattern.MatchString(from) || !currencyCodePattern.MatchString(to) {
+		h.renderPage(w, user, "Currency codes must be 2-10 letters or digits")
+		return
+	}
+	if from == to {
+		h.renderPage(w, user, "The currencies must differ")
+		return
+	}
+
+	rate, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(r.FormValue("rate")), ",", ".", 1), 64)
+	if err != nil || rate <= 0 {
+		h.renderPage(w, user, "Rate must be a positive number")
+		return
+	}
+
+	validFrom, err := time.Parse("2006-01-02", r.FormValue("valid_from"))
+	if err != nil {
+		h.renderPage(w, user, "Invalid start date")
+		return
+	}
+
+	var validTo *time.Time
+	if validToStr := r.FormValue("valid_to"); validToStr != "" {
+		t, err := time.Parse("2006-01-02", validToStr)
+		if err != nil {
+			h.renderPage(w, user, "Invalid end date")
+			return
+		}
+		if t.Before(validFrom) {
+			h.renderPage(w, user, "End date must not be before the start date")
+			return
+		}
+		validTo = &t
+	}
+
+	if _, err := h.rateRepo.Create(&models.ManualExchangeRate{
+		UserID:       user.ID,
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         rate,
+		ValidFrom:    validFrom,
+		ValidTo:      validTo,
+	}); err != nil {
+		log.Printf("Error creating exchange rate: %v", err)
+		h.renderPage(w, user, "Failed to save exchange rate")
+		return
+	}
+
+	http.Redirect(w, r, "/settings/exchange-rates", http.StatusSeeOther)
+}
+
+// Delete handles removing a manual exchange rate.
+func (h *ExchangeRateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid exchange rate ID", http.StatusBadRequest)
+		return
+	}
+
+	rate, err := h.rateRepo.GetByID(id)
+	if err != nil || rate == nil {
+		http.Error(w, "Exchange rate not found", http.StatusNotFound)
+		return
+	}
+	if rate.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := h.rateRepo.Delete(id); err != nil {
+		log.Printf("Error deleting exchange rate: %v", err)
+		http.Error(w, "Failed to delete exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/exchange-rates", http.StatusSeeOther)
+}
+
+// renderPage renders the exchange rates page, optionally with an error.
+func (h *ExchangeRateHandler) renderPage(w http.ResponseWriter, user *models.User, errMsg string) {
+	rates, err := h.rateRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching exchange rates: %v", err)
+		http.Error(w, "Error loading exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	today := time.Now()
+	views := make([]ExchangeRateView, 0, len(rates))
+	for _, rate := range rates {
+		status := "active"
+		if !rate.IsValidOn(today) {
+			status = "expired"
+			if today.Before(rate.ValidFrom) {
+				status = "upcoming"
+			}
+		}
+		views = append(views, ExchangeRateView{ManualExchangeRate: rate, Status: status})
+	}
+
+	data := map[string]any{
+		"Title":     "Exchange Rates",
+		"User":      user,
+		"ActiveNav": "settings",
+		"Rates":     views,
+		"Today":     today.Format("2006-01-02"),
+		"DemoMode":  IsDemoMode(),
+	}
+	if errMsg != "" {
+		data["Error"] = errMsg
+	}
+	h.render(w, "exchange-rates.html", data)
+}
+
+// render renders a template with the given data.
+func (h *ExchangeRateHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
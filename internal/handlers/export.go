@@ -3,13 +3,18 @@ package handlers
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // ExportHandler handles data export requests.
@@ -18,6 +23,10 @@ type ExportHandler struct {
 	transactionRepo *repository.TransactionRepository
 	categoryRepo    *repository.CategoryRepository
 	goalRepo        *repository.GoalRepository
+	portfolio       *services.PortfolioService
+	acquisitionRepo *repository.HoldingAcquisitionRepository
+	snapshots       *services.NetWorthSnapshotService
+	backup          *services.BackupService
 }
 
 // NewExportHandler creates a new export handler.
@@ -26,12 +35,14 @@ func NewExportHandler(
 	transactionRepo *repository.TransactionRepository,
 	categoryRepo *repository.CategoryRepository,
 	goalRepo *repository.GoalRepository,
+	portfolio *services.PortfolioService,
 ) *ExportHandler {
 	return &ExportHandler{
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
 		categoryRepo:    categoryRepo,
 		goalRepo:        goalRepo,
+		portfolio:       portfolio,
 	}
 }
 
@@ -152,7 +163,9 @@ func (h *ExportHandler) ExportAccounts(w http.ResponseWriter, r *http.Request) {
 			accType = "Liability"
 		}
 		status := "Active"
-		if !acc.IsActive {
+		if acc.IsClosed() {
+			status = "Closed"
+		} else if !acc.IsActive {
 			status = "Inactive"
 		}
 		categoryName := ""
@@ -180,11 +193,140 @@ func (h *ExportHandler) ExportAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	export := h.fullExport(user)
+
+	// Set headers for JSON download
+	filename := fmt.Sprintf("wealth_tracker_backup_%s.json", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// Write JSON with pretty formatting
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(export)
+}
+
+// ExportAllEncrypted exports all user data as JSON encrypted with the
+// submitted passphrase. The file can be imported again with the passphrase.
+func (h *ExportHandler) ExportAllEncrypted(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	passphrase := r.FormValue("passphrase")
+	if len(passphrase) < services.MinExportPassphraseLength {
+		http.Redirect(w, r, "/settings?error=passphrase_short", http.StatusSeeOther)
+		return
+	}
+	if passphrase != r.FormValue("passphrase_confirm") {
+		http.Redirect(w, r, "/settings?error=passphrase_mismatch", http.StatusSeeOther)
+		return
+	}
+
+	data, err := json.MarshalIndent(h.fullExport(user), "", "  ")
+	if err != nil {
+		log.Printf("ExportHandler.ExportAllEncrypted error: %v", err)
+		http.Error(w, "Failed to create export", http.StatusInternalServerError)
+		return
+	}
+	encrypted, err := services.EncryptExport(data, passphrase)
+	if err != nil {
+		log.Printf("ExportHandler.ExportAllEncrypted error: %v", err)
+		http.Error(w, "Failed to encrypt export", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("wealth_tracker_backup_%s.json.enc", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Write(encrypted)
+}
+
+// SetBackupService lets users restore the full backups they exported.
+func (h *ExportHandler) SetBackupService(s *services.BackupService) {
+	h.backup = s
+}
+
+// maxBackupUploadSize limits the size of an uploaded full backup.
+const maxBackupUploadSize = 50 << 20 // 50 MB
+
+// RestoreBackup adds the data of a full backup, plain or encrypted with a
+// passphrase, to the user's data.
+func (h *ExportHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBackupUploadSize)
+	if err := r.ParseMultipartForm(maxBackupUploadSize); err != nil {
+		http.Redirect(w, r, "/settings?error=backup_invalid", http.StatusSeeOther)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Redirect(w, r, "/settings?error=backup_missing", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Redirect(w, r, "/settings?error=backup_invalid", http.StatusSeeOther)
+		return
+	}
+
+	backup, err := services.ParseBackup(data, r.FormValue("passphrase"))
+	switch {
+	case errors.Is(err, services.ErrBackupPassphraseRequired):
+		http.Redirect(w, r, "/settings?error=backup_passphrase_required", http.StatusSeeOther)
+		return
+	case errors.Is(err, services.ErrExportPassphrase):
+		http.Redirect(w, r, "/settings?error=backup_passphrase", http.StatusSeeOther)
+		return
+	case err != nil:
+		http.Redirect(w, r, "/settings?error=backup_invalid", http.StatusSeeOther)
+		return
+	}
+
+	restored, err := h.backup.Restore(user.ID, backup)
+	if err != nil {
+		log.Printf("ExportHandler.RestoreBackup error: %v", err)
+		http.Redirect(w, r, "/settings?error=backup_failed", http.StatusSeeOther)
+		return
+	}
+	log.Printf("User %d restored a backup: %d accounts, %d transactions, %d goals", user.ID, restored.Accounts, restored.Transactions, restored.Goals)
+	http.Redirect(w, r, "/settings?success=backup_restored", http.StatusSeeOther)
+}
+
+// fullExport collects all data of a user for ExportAll.
+func (h *ExportHandler) fullExport(user *models.User) map[string]interface{} {
 	// Collect all data
 	accounts, _ := h.accountRepo.GetByUserID(user.ID)
 	categories, _ := h.categoryRepo.GetByUserID(user.ID)
 	goals, _ := h.goalRepo.GetByUserID(user.ID)
 
+	// Goals reference their category by name and carry their progress history
+	historyAccounts, _ := h.accountRepo.GetByUserIDWithHistory(user.ID)
+	history, _ := h.transactionRepo.GetBalanceHistoryByUserID(user.ID)
+	categoryNames := make(map[int64]string)
+	for _, cat := range categories {
+		categoryNames[cat.ID] = cat.Name
+	}
+	now := time.Now()
+	goalExports := make([]services.GoalExport, len(goals))
+	for i, goal := range goals {
+		goalExports[i] = services.GoalExport{
+			Goal:            goal,
+			ProgressHistory: services.GoalProgressHistory(goal, historyAccounts, history, now),
+		}
+		if goal.CategoryID != nil {
+			goalExports[i].CategoryName = categoryNames[*goal.CategoryID]
+		}
+	}
+
 	// Collect all transactions
 	var allTransactions []map[string]interface{}
 	for _, acc := range accounts {
@@ -198,13 +340,14 @@ func (h *ExportHandler) ExportAll(w http.ResponseWriter, r *http.Request) {
 				"amount":           tx.Amount,
 				"balance_after":    tx.BalanceAfter,
 				"description":      tx.Description,
+				"kind":             tx.Kind,
 				"transaction_date": tx.TransactionDate.Format("2006-01-02"),
 			})
 		}
 	}
 
 	// Build export structure
-	export := map[string]interface{}{
+	return map[string]interface{}{
 		"exported_at": time.Now().Format(time.RFC3339),
 		"user": map[string]interface{}{
 			"name":             user.Name,
@@ -214,16 +357,6 @@ func (h *ExportHandler) ExportAll(w http.ResponseWriter, r *http.Request) {
 		"categories":   categories,
 		"accounts":     accounts,
 		"transactions": allTransactions,
-		"goals":        goals,
+		"goals":        goalExports,
 	}
-
-	// Set headers for JSON download
-	filename := fmt.Sprintf("wealth_tracker_backup_%s.json", time.Now().Format("2006-01-02"))
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-
-	// Write JSON with pretty formatting
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	encoder.Encode(export)
 }
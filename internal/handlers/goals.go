@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"html/template"
+	"io"
 	"log"
 	"math"
 	"net/http"
@@ -11,34 +15,42 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"wealth_tracker/internal/dates"
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // GoalHandler handles goal routes.
 type GoalHandler struct {
-	templates       map[string]*template.Template
-	goalRepo        *repository.GoalRepository
-	accountRepo     *repository.AccountRepository
-	transactionRepo *repository.TransactionRepository
-	categoryRepo    *repository.CategoryRepository
+	templates        map[string]*template.Template
+	goalRepo         *repository.GoalRepository
+	goalSnapshotRepo *repository.GoalSnapshotRepository
+	accountRepo      *repository.AccountRepository
+	transactionRepo  *repository.TransactionRepository
+	categoryRepo     *repository.CategoryRepository
+	netWorth         *services.NetWorthService
 }
 
 // NewGoalHandler creates a new GoalHandler.
 func NewGoalHandler(
 	templates map[string]*template.Template,
 	goalRepo *repository.GoalRepository,
+	goalSnapshotRepo *repository.GoalSnapshotRepository,
 	accountRepo *repository.AccountRepository,
 	transactionRepo *repository.TransactionRepository,
 	categoryRepo *repository.CategoryRepository,
+	netWorth *services.NetWorthService,
 ) *GoalHandler {
 	return &GoalHandler{
-		templates:       templates,
-		goalRepo:        goalRepo,
-		accountRepo:     accountRepo,
-		transactionRepo: transactionRepo,
-		categoryRepo:    categoryRepo,
+		templates:        templates,
+		goalRepo:         goalRepo,
+		goalSnapshotRepo: goalSnapshotRepo,
+		accountRepo:      accountRepo,
+		transactionRepo:  transactionRepo,
+		categoryRepo:     categoryRepo,
+		netWorth:         netWorth,
 	}
 }
 
@@ -71,7 +83,7 @@ func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate current net worth for progress calculation
-	netWorth := h.calculateNetWorth(user.ID)
+	netWorth := h.computeNetWorth(user.ID)
 
 	// Calculate progress for each goal
 	type GoalWithProgress struct {
@@ -82,15 +94,20 @@ func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
 		IsOverdue    bool
 		CurrentWorth float64
 		Category     *models.Category
+
+		// Projection from current balances at each category's expected return
+		ProjectedDate  *time.Time
+		ExpectedReturn float64
+
+		// Contributed this statement period vs planned, for goals with
+		// funding accounts
+		Funding *services.GoalFunding
 	}
 
+	periodStart, flows := h.periodFlows(user)
 	goalsWithProgress := make([]GoalWithProgress, len(goals))
 	for i, goal := range goals {
-		// Determine current worth based on whether goal is category-specific
-		currentWorth := netWorth
-		if goal.CategoryID != nil {
-			currentWorth = h.calculateCategoryNetWorth(user.ID, *goal.CategoryID)
-		}
+		currentWorth := netWorth.ForGoal(goal)
 
 		progress := 0.0
 		if goal.TargetAmount > 0 {
@@ -113,6 +130,7 @@ func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
 			Progress:     progress,
 			IsReached:    isReached,
 			CurrentWorth: currentWorth,
+			Funding:      services.NewGoalFunding(goal, flows, periodStart),
 		}
 
 		// Add category info if goal has a category
@@ -120,6 +138,15 @@ func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
 			gwp.Category = categoryMap[*goal.CategoryID]
 		}
 
+		if !isReached {
+			buckets := projectionBuckets(netWorth, goal.CategoryID, categoryMap)
+			gwp.ExpectedReturn = services.WeightedExpectedReturn(buckets)
+			if months, ok := services.MonthsToTarget(buckets, goal.TargetAmount); ok {
+				projected := time.Now().AddDate(0, months, 0)
+				gwp.ProjectedDate = &projected
+			}
+		}
+
 		// Calculate days left if deadline is set and goal not reached
 		if goal.Deadline != nil && !isReached {
 			now := time.Now()
@@ -142,12 +169,72 @@ func (h *GoalHandler) List(w http.ResponseWriter, r *http.Request) {
 		"Goals":        goalsWithProgress,
 		"TotalGoals":   totalGoals,
 		"ReachedGoals": reachedGoals,
-		"NetWorth":     netWorth,
+		"NetWorth":     netWorth.NetWorth,
 		"Categories":   categories,
+		"Accounts":     h.fundingAccounts(user.ID),
 		"DemoMode":     IsDemoMode(),
 	})
 }
 
+// Detail renders a goal with its burn-up chart: the weekly history of its
+// progress, and the pace it is progressing at compared with the pace needed to
+// reach it by its deadline.
+func (h *GoalHandler) Detail(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		return
+	}
+
+	// Goals of other users are reported as missing
+	goal, err := h.goalRepo.GetByID(id)
+	if err != nil || goal == nil || goal.UserID != user.ID {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+
+	var category *models.Category
+	currentWorth := h.computeNetWorth(user.ID).ForGoal(goal)
+	if goal.CategoryID != nil {
+		category, _ = h.categoryRepo.GetByID(*goal.CategoryID)
+	}
+
+	progress := 0.0
+	if goal.TargetAmount > 0 {
+		progress = math.Min(currentWorth/goal.TargetAmount*100, 100)
+	}
+
+	snapshots, err := h.goalSnapshotRepo.GetByGoalID(goal.ID)
+	if err != nil {
+		log.Printf("Error fetching goal snapshots: %v", err)
+		snapshots = nil
+	}
+	trajectory := services.NewGoalTrajectory(goal, snapshots, currentWorth, time.Now())
+	trajectoryJSON, _ := json.Marshal(trajectory)
+	periodStart, flows := h.periodFlows(user)
+
+	h.render(w, "goal-detail.html", map[string]any{
+		"Title":          goal.Name,
+		"User":           user,
+		"ActiveNav":      "goals",
+		"Goal":           goal,
+		"Category":       category,
+		"CurrentWorth":   currentWorth,
+		"Progress":       progress,
+		"IsReached":      goal.ReachedDate != nil || currentWorth >= goal.TargetAmount,
+		"Trajectory":     trajectory,
+		"TrajectoryJSON": template.JS(trajectoryJSON),
+		"Funding":        services.NewGoalFunding(goal, flows, periodStart),
+		"IncludeCharts":  true,
+	})
+}
+
 // Create handles creating a new goal.
 func (h *GoalHandler) Create(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -166,6 +253,7 @@ func (h *GoalHandler) Create(w http.ResponseWriter, r *http.Request) {
 	targetCurrency := strings.TrimSpace(r.FormValue("target_currency"))
 	deadlineStr := r.FormValue("deadline")
 	categoryIDStr := r.FormValue("category_id")
+	description := strings.TrimSpace(r.FormValue("description"))
 
 	// Validate
 	if name == "" {
@@ -202,13 +290,22 @@ func (h *GoalHandler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	monthlyContribution, fundingAccountIDs, ok := parseGoalFunding(r)
+	if !ok {
+		h.renderError(w, r, user, "Monthly contribution must be zero or a positive number")
+		return
+	}
+
 	goal := &models.Goal{
-		UserID:         user.ID,
-		CategoryID:     categoryID,
-		Name:           name,
-		TargetAmount:   targetAmount,
-		TargetCurrency: targetCurrency,
-		Deadline:       deadline,
+		UserID:              user.ID,
+		CategoryID:          categoryID,
+		Name:                name,
+		TargetAmount:        targetAmount,
+		TargetCurrency:      targetCurrency,
+		Deadline:            deadline,
+		Description:         description,
+		FundingAccountIDs:   fundingAccountIDs,
+		MonthlyContribution: monthlyContribution,
 	}
 
 	_, err = h.goalRepo.Create(goal)
@@ -264,6 +361,7 @@ func (h *GoalHandler) Update(w http.ResponseWriter, r *http.Request) {
 	deadlineStr := r.FormValue("deadline")
 	reachedDateStr := r.FormValue("reached_date")
 	categoryIDStr := r.FormValue("category_id")
+	description := strings.TrimSpace(r.FormValue("description"))
 
 	// Validate
 	if name == "" {
@@ -310,8 +408,33 @@ func (h *GoalHandler) Update(w http.ResponseWriter, r *http.Request) {
 	existing.Deadline = deadline
 	existing.ReachedDate = reachedDate
 	existing.CategoryID = categoryID
+	existing.Description = description
+
+	monthlyContribution, fundingAccountIDs, ok := parseGoalFunding(r)
+	if !ok {
+		http.Error(w, "Monthly contribution must be zero or a positive number", http.StatusBadRequest)
+		return
+	}
+	existing.MonthlyContribution = monthlyContribution
+	existing.FundingAccountIDs = fundingAccountIDs
+
+	// Reject the edit if the goal changed since the form was opened
+	currentVersion := existing.UpdatedAt
+	if v := r.FormValue("version"); v != "" {
+		version, err := parseVersion(v)
+		if err != nil {
+			http.Error(w, "Invalid version", http.StatusBadRequest)
+			return
+		}
+		existing.UpdatedAt = version
+	}
 
 	err = h.goalRepo.Update(existing)
+	if errors.Is(err, repository.ErrStaleUpdate) {
+		existing.UpdatedAt = currentVersion
+		h.renderConflict(w, user, existing)
+		return
+	}
 	if err != nil {
 		log.Printf("Error updating goal: %v", err)
 		http.Error(w, "Failed to update goal", http.StatusInternalServerError)
@@ -357,56 +480,204 @@ func (h *GoalHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/goals", http.StatusSeeOther)
 }
 
-// calculateNetWorth calculates the user's current net worth.
-func (h *GoalHandler) calculateNetWorth(userID int64) float64 {
-	accounts, err := h.accountRepo.GetByUserIDActiveOnly(userID)
+// maxGoalsUploadSize limits the size of an uploaded JSON export.
+const maxGoalsUploadSize = 10 << 20 // 10 MB
+
+// Import creates goals from a JSON export, keeping deadlines and reached
+// dates. Categories are matched by name and recreated if missing; goals with
+// the name of an existing goal are skipped so re-importing is harmless.
+func (h *GoalHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGoalsUploadSize)
+	if err := r.ParseMultipartForm(maxGoalsUploadSize); err != nil {
+		h.renderError(w, r, user, "File is too large or the form is invalid")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
 	if err != nil {
-		return 0
+		h.renderError(w, r, user, "Please choose an export file to import")
+		return
 	}
+	defer file.Close()
 
-	netWorth := 0.0
-	for _, acc := range accounts {
-		// Get balance from transactions
-		balance, err := h.transactionRepo.GetLatestBalance(acc.ID)
-		if err != nil {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.renderError(w, r, user, "Failed to read the export file")
+		return
+	}
+	// Encrypted backups need the passphrase they were exported with
+	if services.IsEncryptedExport(data) {
+		passphrase := r.FormValue("passphrase")
+		if passphrase == "" {
+			h.renderError(w, r, user, "This export is encrypted; enter its passphrase to import it")
+			return
+		}
+		if data, err = services.DecryptExport(data, passphrase); err != nil {
+			h.renderError(w, r, user, "Goals import failed: "+err.Error())
+			return
+		}
+	}
+
+	imported, exportedCategories, err := services.ParseGoalsExport(bytes.NewReader(data))
+	if err != nil {
+		h.renderError(w, r, user, "Goals import failed: "+err.Error())
+		return
+	}
+
+	existingGoals, err := h.goalRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching goals: %v", err)
+		h.renderError(w, r, user, "Failed to import goals")
+		return
+	}
+	goalNames := make(map[string]bool)
+	for _, g := range existingGoals {
+		goalNames[strings.ToLower(g.Name)] = true
+	}
+
+	categories, err := h.categoryRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		h.renderError(w, r, user, "Failed to import goals")
+		return
+	}
+	categoryIDs := make(map[string]int64)
+	for _, cat := range categories {
+		categoryIDs[strings.ToLower(cat.Name)] = cat.ID
+	}
+
+	for _, g := range imported {
+		if goalNames[strings.ToLower(g.Name)] {
 			continue
 		}
-		if acc.IsLiability {
-			// Use absolute value to handle both positive and negative storage
-			netWorth -= math.Abs(balance)
-		} else {
-			netWorth += balance
+
+		goal := &models.Goal{
+			UserID:              user.ID,
+			Name:                g.Name,
+			TargetAmount:        g.TargetAmount,
+			TargetCurrency:      g.TargetCurrency,
+			Deadline:            g.Deadline,
+			ReachedDate:         g.ReachedDate,
+			Description:         g.Description,
+			MonthlyContribution: g.MonthlyContribution,
+		}
+
+		if g.CategoryName != "" {
+			key := strings.ToLower(g.CategoryName)
+			id, ok := categoryIDs[key]
+			if !ok {
+				cat := &models.Category{UserID: user.ID, Name: g.CategoryName, Color: "#6b7280"}
+				if exported, found := exportedCategories[key]; found {
+					cat.Color = exported.Color
+					cat.Icon = exported.Icon
+					cat.ExpectedReturn = exported.ExpectedReturn
+					cat.Description = exported.Description
+				}
+				id, err = h.categoryRepo.Create(cat)
+				if err != nil {
+					log.Printf("Error creating category %q: %v", g.CategoryName, err)
+					h.renderError(w, r, user, "Failed to import goals")
+					return
+				}
+				categoryIDs[key] = id
+			}
+			goal.CategoryID = &id
+		}
+
+		if _, err := h.goalRepo.Create(goal); err != nil {
+			log.Printf("Error importing goal %q: %v", g.Name, err)
+			h.renderError(w, r, user, "Failed to import goals")
+			return
 		}
+		goalNames[strings.ToLower(g.Name)] = true
 	}
-	return netWorth
+
+	http.Redirect(w, r, "/goals", http.StatusSeeOther)
+}
+
+// parseGoalFunding reads the planned monthly contribution and the funding
+// accounts of a goal form. An empty contribution plans none; ok is false for
+// one that is not zero or a positive number.
+func parseGoalFunding(r *http.Request) (monthlyContribution float64, accountIDs []int64, ok bool) {
+	if v := strings.TrimSpace(r.FormValue("monthly_contribution")); v != "" {
+		m, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(m >= 0) || math.IsInf(m, 0) {
+			return 0, nil, false
+		}
+		monthlyContribution = m
+	}
+	for _, v := range r.Form["funding_account_id"] {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil && id > 0 {
+			accountIDs = append(accountIDs, id)
+		}
+	}
+	return monthlyContribution, accountIDs, true
+}
+
+// periodFlows returns the start of the user's current statement period and
+// the money moved in and out of each of their accounts since.
+func (h *GoalHandler) periodFlows(user *models.User) (time.Time, map[int64]float64) {
+	now := time.Now()
+	periodStart := dates.PeriodStart(now, user.PeriodStartDay)
+	// End of the day before the period
+	flows, err := h.transactionRepo.GetFlowsByAccount(user.ID, periodStart.AddDate(0, 0, -1), now)
+	if err != nil {
+		log.Printf("Error fetching account flows: %v", err)
+	}
+	return periodStart, flows
 }
 
-// calculateCategoryNetWorth calculates the net worth for a specific category.
-func (h *GoalHandler) calculateCategoryNetWorth(userID, categoryID int64) float64 {
+// fundingAccounts returns the accounts a goal can be funded from.
+func (h *GoalHandler) fundingAccounts(userID int64) []*models.Account {
 	accounts, err := h.accountRepo.GetByUserIDActiveOnly(userID)
 	if err != nil {
-		return 0
+		log.Printf("Error fetching accounts: %v", err)
 	}
+	return accounts
+}
 
-	netWorth := 0.0
-	for _, acc := range accounts {
-		// Skip accounts not in this category
-		if acc.CategoryID == nil || *acc.CategoryID != categoryID {
-			continue
-		}
+// computeNetWorth returns the user's current net worth. A failure is logged
+// and shows as no net worth.
+func (h *GoalHandler) computeNetWorth(userID int64) *services.NetWorth {
+	netWorth, err := h.netWorth.Compute(userID, nil)
+	if err != nil {
+		log.Printf("Error computing net worth: %v", err)
+		return &services.NetWorth{}
+	}
+	return netWorth
+}
 
-		// Get balance from transactions
-		balance, err := h.transactionRepo.GetLatestBalance(acc.ID)
-		if err != nil {
+// projectionBuckets returns the worth of the accounts in a net worth, limited
+// to a category if categoryID is set, with the expected return of their
+// category. Liabilities are not expected to grow.
+func projectionBuckets(netWorth *services.NetWorth, categoryID *int64, categoryMap map[int64]*models.Category) []services.ProjectionBucket {
+	var buckets []services.ProjectionBucket
+	for _, a := range netWorth.Accounts {
+		acc := a.Account
+		if categoryID != nil && (acc.CategoryID == nil || *acc.CategoryID != *categoryID) {
 			continue
 		}
 		if acc.IsLiability {
-			netWorth -= math.Abs(balance)
-		} else {
-			netWorth += balance
+			buckets = append(buckets, services.ProjectionBucket{Amount: a.Worth})
+			continue
+		}
+
+		var category *models.Category
+		if acc.CategoryID != nil {
+			category = categoryMap[*acc.CategoryID]
 		}
+		buckets = append(buckets, services.ProjectionBucket{
+			Amount:         a.Worth,
+			ExpectedReturn: services.CategoryExpectedReturn(category),
+		})
 	}
-	return netWorth
+	return buckets
 }
 
 // render renders a template with the given data.
@@ -430,8 +701,20 @@ func (h *GoalHandler) render(w http.ResponseWriter, name string, data map[string
 
 // renderError re-renders the goals page with an error message.
 func (h *GoalHandler) renderError(w http.ResponseWriter, r *http.Request, user *models.User, errMsg string) {
+	h.renderPage(w, user, map[string]any{"Error": errMsg})
+}
+
+// renderConflict re-renders the goals page with the goal as edited
+// elsewhere, offering to reapply the user's edit to it. submitted holds the
+// rejected edit with the current version.
+func (h *GoalHandler) renderConflict(w http.ResponseWriter, user *models.User, submitted *models.Goal) {
+	h.renderPage(w, user, map[string]any{"Error": repository.ErrStaleUpdate.Error(), "Conflict": submitted})
+}
+
+// renderPage renders the goals page with extra data, such as an error.
+func (h *GoalHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
 	goals, _ := h.goalRepo.GetByUserID(user.ID)
-	netWorth := h.calculateNetWorth(user.ID)
+	netWorth := h.computeNetWorth(user.ID)
 
 	// Fetch categories for dropdown and display
 	categories, _ := h.categoryRepo.GetByUserID(user.ID)
@@ -448,15 +731,20 @@ func (h *GoalHandler) renderError(w http.ResponseWriter, r *http.Request, user *
 		IsOverdue    bool
 		CurrentWorth float64
 		Category     *models.Category
+
+		// Projection from current balances at each category's expected return
+		ProjectedDate  *time.Time
+		ExpectedReturn float64
+
+		// Contributed this statement period vs planned, for goals with
+		// funding accounts
+		Funding *services.GoalFunding
 	}
 
+	periodStart, flows := h.periodFlows(user)
 	goalsWithProgress := make([]GoalWithProgress, len(goals))
 	for i, goal := range goals {
-		// Determine current worth based on whether goal is category-specific
-		currentWorth := netWorth
-		if goal.CategoryID != nil {
-			currentWorth = h.calculateCategoryNetWorth(user.ID, *goal.CategoryID)
-		}
+		currentWorth := netWorth.ForGoal(goal)
 
 		progress := 0.0
 		if goal.TargetAmount > 0 {
@@ -474,6 +762,7 @@ func (h *GoalHandler) renderError(w http.ResponseWriter, r *http.Request, user *
 			Progress:     progress,
 			IsReached:    isReached,
 			CurrentWorth: currentWorth,
+			Funding:      services.NewGoalFunding(goal, flows, periodStart),
 		}
 
 		// Add category info if goal has a category
@@ -481,6 +770,15 @@ func (h *GoalHandler) renderError(w http.ResponseWriter, r *http.Request, user *
 			gwp.Category = categoryMap[*goal.CategoryID]
 		}
 
+		if !isReached {
+			buckets := projectionBuckets(netWorth, goal.CategoryID, categoryMap)
+			gwp.ExpectedReturn = services.WeightedExpectedReturn(buckets)
+			if months, ok := services.MonthsToTarget(buckets, goal.TargetAmount); ok {
+				projected := time.Now().AddDate(0, months, 0)
+				gwp.ProjectedDate = &projected
+			}
+		}
+
 		// Calculate days left if deadline is set and goal not reached
 		if goal.Deadline != nil && !isReached {
 			now := time.Now()
@@ -495,15 +793,19 @@ func (h *GoalHandler) renderError(w http.ResponseWriter, r *http.Request, user *
 	totalGoals, _ := h.goalRepo.CountByUserID(user.ID)
 	reachedGoals, _ := h.goalRepo.CountReachedByUserID(user.ID)
 
-	h.render(w, "goals.html", map[string]any{
+	data := map[string]any{
 		"Title":        "Goals",
 		"User":         user,
 		"ActiveNav":    "goals",
 		"Goals":        goalsWithProgress,
 		"TotalGoals":   totalGoals,
 		"ReachedGoals": reachedGoals,
-		"NetWorth":     netWorth,
+		"NetWorth":     netWorth.NetWorth,
 		"Categories":   categories,
-		"Error":        errMsg,
-	})
+		"Accounts":     h.fundingAccounts(user.ID),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "goals.html", data)
 }
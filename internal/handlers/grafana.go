@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// GrafanaHandler implements the SimpleJSON / Infinity datasource endpoints
+// so Grafana dashboards can be built on top of wealth data.
+type GrafanaHandler struct {
+	grafanaService *services.GrafanaService
+}
+
+// NewGrafanaHandler creates a new GrafanaHandler.
+func NewGrafanaHandler(grafanaService *services.GrafanaService) *GrafanaHandler {
+	return &GrafanaHandler{
+		grafanaService: grafanaService,
+	}
+}
+
+// TestConnection answers Grafana's datasource health check.
+func (h *GrafanaHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Search returns the list of available targets.
+func (h *GrafanaHandler) Search(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Body is optional; Grafana sends {"target": "<filter>"}
+	var req struct {
+		Target string `json:"target"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	targets, err := h.grafanaService.Search(user.ID, req.Target)
+	if err != nil {
+		log.Printf("Error searching grafana targets: %v", err)
+		http.Error(w, "Failed to search targets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(targets); err != nil {
+		log.Printf("Error encoding grafana targets: %v", err)
+	}
+}
+
+// Query returns time series for the requested targets and time range.
+func (h *GrafanaHandler) Query(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req services.GrafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.grafanaService.Query(user.ID, &req)
+	if err != nil {
+		log.Printf("Error querying grafana series: %v", err)
+		http.Error(w, "Failed to query series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(series); err != nil {
+		log.Printf("Error encoding grafana series: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxHistoryUploadSize limits the size of an uploaded history file.
+const maxHistoryUploadSize = 2 << 20 // 2 MB
+
+// SetCategorizer sets the categorizer applied to imported balances.
+func (h *AccountHandler) SetCategorizer(categorizer *services.Categorizer) {
+	h.categorizer = categorizer
+}
+
+// HistoryForm renders the page for importing net worth or account balance
+// history kept in another tool, with the outcome of the last import.
+func (h *AccountHandler) HistoryForm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderHistory(w, r, user, "", nil)
+}
+
+// ImportHistory handles uploading a CSV or JSON file of dated balance
+// snapshots, which become backdated balances so the net worth history starts
+// where the user's own records do.
+func (h *AccountHandler) ImportHistory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHistoryUploadSize)
+	if err := r.ParseMultipartForm(maxHistoryUploadSize); err != nil {
+		h.renderHistory(w, r, user, "File is too large or the form is invalid", nil)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderHistory(w, r, user, "Please choose a CSV or JSON file to import", nil)
+		return
+	}
+	defer file.Close()
+
+	snapshots, rowErrors, err := services.ParseHistoryImport(file)
+	if err != nil {
+		h.renderHistory(w, r, user, "History import failed: "+err.Error(), nil)
+		return
+	}
+	if len(rowErrors) == 0 && len(snapshots) == 0 {
+		h.renderHistory(w, r, user, "The file contains no balances", nil)
+		return
+	}
+	if len(rowErrors) == 0 {
+		var result services.HistoryImportResult
+		importer := services.NewHistoryImporter(h.accountRepo, h.transactionRepo)
+		importer.SetCategorizer(h.categorizer)
+		result, rowErrors, err = importer.Import(user, snapshots)
+		if err != nil {
+			log.Printf("Error importing history: %v", err)
+			h.renderHistory(w, r, user, "Failed to import history", nil)
+			return
+		}
+		if len(rowErrors) == 0 {
+			log.Printf("Imported %d historical balances into %d accounts for user %d", result.Balances, result.Accounts, user.ID)
+			query := url.Values{
+				"imported": {strconv.Itoa(result.Balances)},
+				"accounts": {strconv.Itoa(result.Accounts)},
+				"from":     {result.From.Format("2006-01-02")},
+			}
+			http.Redirect(w, r, "/accounts/history?"+query.Encode(), http.StatusSeeOther)
+			return
+		}
+	}
+
+	h.renderHistory(w, r, user, "History import failed, nothing was imported.", rowErrors)
+}
+
+// renderHistory renders the history import page.
+func (h *AccountHandler) renderHistory(w http.ResponseWriter, r *http.Request, user *models.User, errMsg string, rowErrors []services.HoldingImportRowError) {
+	data := map[string]any{
+		"Title":          "Import History",
+		"User":           user,
+		"ActiveNav":      "accounts",
+		"Error":          errMsg,
+		"RowErrors":      rowErrors,
+		"HistoryAccount": services.NetWorthHistoryAccount,
+		"DemoMode":       IsDemoMode(),
+	}
+
+	// Outcome of the import this page was redirected from
+	query := r.URL.Query()
+	if imported, err := strconv.Atoi(query.Get("imported")); err == nil && errMsg == "" {
+		accounts, _ := strconv.Atoi(query.Get("accounts"))
+		data["Imported"] = imported
+		data["ImportedAccounts"] = accounts
+		if from, err := time.Parse("2006-01-02", query.Get("from")); err == nil {
+			data["ImportedFrom"] = from
+		}
+	}
+
+	h.render(w, "account-history.html", data)
+}
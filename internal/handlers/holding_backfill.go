@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// SetPriceBackfillService enables backfilling the history of holdings with
+// historical prices.
+func (h *AccountHandler) SetPriceBackfillService(service *services.PriceBackfillService) {
+	h.priceBackfill = service
+}
+
+// BackfillHolding fills in a holding's history from before it was first
+// synced, from the chosen date or its first imported acquisition.
+func (h *AccountHandler) BackfillHolding(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.priceBackfill == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	holdingID, err := strconv.ParseInt(chi.URLParam(r, "holdingID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify account belongs to user and the holding to the account
+	account, err := h.accountRepo.GetByID(accountID)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if account.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	holding, err := h.holdingRepo.GetByID(holdingID)
+	if err != nil || holding == nil || holding.AccountID != accountID {
+		http.Error(w, "Holding not found", http.StatusNotFound)
+		return
+	}
+
+	var from time.Time
+	if v := r.FormValue("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			h.renderError(w, r, user, "Invalid backfill date")
+			return
+		}
+		if !from.Before(time.Now()) {
+			h.renderError(w, r, user, "The backfill date must be in the past")
+			return
+		}
+	}
+
+	added, err := h.priceBackfill.Backfill(user.ID, holding, from)
+	switch {
+	case errors.Is(err, services.ErrBackfillNotISIN), errors.Is(err, services.ErrBackfillNoStart),
+		errors.Is(err, services.ErrBackfillNoPrices), errors.Is(err, services.ErrQuotaExceeded):
+		h.renderError(w, r, user, "Backfill failed: "+err.Error())
+		return
+	case err != nil:
+		log.Printf("Error backfilling holding %d: %v", holding.ID, err)
+		h.renderError(w, r, user, "Backfill failed; the price provider could not be reached")
+		return
+	}
+
+	h.renderPage(w, user, map[string]any{"Success": fmt.Sprintf("Backfilled %d days of %s history", added, holding.Name)})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// ImportAcquisitions handles uploading a CSV of buy transactions. They are
+// used to recompute average prices, e.g. for holdings moved between depots
+// where the broker reports the transfer price. Buys of the imported symbols
+// replace earlier imports; in replace mode all earlier imports are removed.
+func (h *AccountHandler) ImportAcquisitions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify account belongs to user
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if account.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxHoldingsUploadSize)
+	if err := r.ParseMultipartForm(maxHoldingsUploadSize); err != nil {
+		h.renderError(w, r, user, "File is too large or the form is invalid")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderError(w, r, user, "Please choose a CSV file to import")
+		return
+	}
+	defer file.Close()
+
+	acquisitions, rowErrors, err := services.ParseAcquisitionsCSV(file, id)
+	if err != nil {
+		h.renderError(w, r, user, "Acquisitions import failed: "+err.Error())
+		return
+	}
+	if len(rowErrors) > 0 {
+		msgs := make([]string, len(rowErrors))
+		for i, e := range rowErrors {
+			msgs[i] = e.Error()
+		}
+		h.renderError(w, r, user, fmt.Sprintf("Acquisitions import failed, nothing was imported. %s", strings.Join(msgs, "; ")))
+		return
+	}
+	if len(acquisitions) == 0 {
+		h.renderError(w, r, user, "The file contains no acquisitions")
+		return
+	}
+
+	replace := r.FormValue("mode") == services.HoldingImportReplace
+	if err := h.acquisitionRepo.Import(id, acquisitions, replace); err != nil {
+		log.Printf("Error storing acquisitions: %v", err)
+		h.renderError(w, r, user, "Failed to import acquisitions")
+		return
+	}
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
+
+// SetCostBasisMode switches a holding between the broker's average price and
+// the one recomputed from imported acquisitions.
+func (h *AccountHandler) SetCostBasisMode(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	holdingID, err := strconv.ParseInt(chi.URLParam(r, "holdingID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	// Verify account belongs to user and the holding to the account
+	account, err := h.accountRepo.GetByID(accountID)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if account.UserID != user.ID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	holding, err := h.holdingRepo.GetByID(holdingID)
+	if err != nil || holding == nil || holding.AccountID != accountID {
+		http.Error(w, "Holding not found", http.StatusNotFound)
+		return
+	}
+
+	mode := r.FormValue("mode")
+	if mode != models.CostBasisRecomputed {
+		mode = models.CostBasisBroker
+	}
+	if err := h.holdingRepo.SetCostBasisMode(holdingID, mode); err != nil {
+		log.Printf("Error setting cost basis mode: %v", err)
+		h.renderError(w, r, user, "Failed to update cost basis")
+		return
+	}
+
+	http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+}
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// versionFormat encodes the updated_at an inline editor was opened at, as
+// the version field it posts back.
+const versionFormat = time.RFC3339Nano
+
+// transactionRow is a row of the transactions list.
+type transactionRow struct {
+	*models.Transaction
+	Account  *models.Account
+	Category *models.Category // Transaction category, or the account's if unset
+	Currency string           // Account currency, used for display precision
+	User     *models.User
+	Error    string // Why an inline edit was rejected
+}
+
+// newTransactionRow builds the list row of txn from the user's accounts and
+// categories by ID.
+func newTransactionRow(user *models.User, txn *models.Transaction, accountMap map[int64]*models.Account, categoryMap map[int64]*models.Category) transactionRow {
+	account := accountMap[txn.AccountID]
+	currency := user.DefaultCurrency
+	var category *models.Category
+	if account != nil {
+		currency = account.Currency
+		if account.CategoryID != nil {
+			category = categoryMap[*account.CategoryID]
+		}
+	}
+	if txn.CategoryID != nil {
+		category = categoryMap[*txn.CategoryID]
+	}
+	return transactionRow{
+		Transaction: txn,
+		Account:     account,
+		Category:    category,
+		Currency:    currency,
+		User:        user,
+	}
+}
+
+// TransactionRow renders a row of the transactions list, e.g. to cancel an
+// inline edit.
+func (h *TransactionHandler) TransactionRow(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	txn, account, ok := h.ownTransaction(w, r, user)
+	if !ok {
+		return
+	}
+	h.renderRow(w, http.StatusOK, "transaction-row", user, txn, account, "")
+}
+
+// EditTransactionRow renders the inline editor of a transactions list row.
+func (h *TransactionHandler) EditTransactionRow(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	txn, account, ok := h.ownTransaction(w, r, user)
+	if !ok {
+		return
+	}
+	h.renderRow(w, http.StatusOK, "transaction-row-edit", user, txn, account, "")
+}
+
+// UpdateTransactionRow saves the amount, description and date of an inline
+// edited transaction and renders its updated row. An edit of a transaction
+// changed since the editor was opened is rejected with the current values.
+func (h *TransactionHandler) UpdateTransactionRow(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	txn, account, ok := h.ownTransaction(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	version, err := parseVersion(r.FormValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(r.FormValue("amount")), 64)
+	if err != nil {
+		h.renderRow(w, http.StatusUnprocessableEntity, "transaction-row-edit", user, txn, account, "Invalid amount")
+		return
+	}
+	transactionDate, err := time.Parse("2006-01-02", r.FormValue("transaction_date"))
+	if err != nil {
+		h.renderRow(w, http.StatusUnprocessableEntity, "transaction-row-edit", user, txn, account, "Invalid date")
+		return
+	}
+
+	// Recalculate balance the same way as the edit form
+	txn.BalanceAfter += amount - txn.Amount
+	txn.Amount = amount
+	txn.Description = strings.TrimSpace(r.FormValue("description"))
+	txn.TransactionDate = transactionDate
+	txn.UpdatedAt = version
+
+	if err := h.transactionRepo.Update(txn); err != nil {
+		if errors.Is(err, repository.ErrStaleUpdate) {
+			current, _ := h.transactionRepo.GetByID(txn.ID)
+			if current != nil {
+				h.renderRow(w, http.StatusConflict, "transaction-row-edit", user, current, account, err.Error())
+				return
+			}
+		}
+		log.Printf("Error updating transaction: %v", err)
+		http.Error(w, "Failed to update transaction", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRow(w, http.StatusOK, "transaction-row", user, txn, account, "")
+}
+
+// ownTransaction loads the transaction in the URL and its account if they
+// belong to the user, writing an error response otherwise.
+func (h *TransactionHandler) ownTransaction(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Transaction, *models.Account, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	// Consistent error to prevent enumeration
+	txn, err := h.transactionRepo.GetByID(id)
+	if err != nil || txn == nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	account, err := h.accountRepo.GetByID(txn.AccountID)
+	if err != nil || account == nil || account.UserID != user.ID {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return txn, account, true
+}
+
+// renderRow renders a transactions list row fragment.
+func (h *TransactionHandler) renderRow(w http.ResponseWriter, status int, fragment string, user *models.User, txn *models.Transaction, account *models.Account, errMsg string) {
+	categoryMap := make(map[int64]*models.Category)
+	if categories, err := h.categoryRepo.GetByUserID(user.ID); err == nil {
+		for _, cat := range categories {
+			categoryMap[cat.ID] = cat
+		}
+	}
+
+	row := newTransactionRow(user, txn, map[int64]*models.Account{account.ID: account}, categoryMap)
+	row.Error = errMsg
+	renderFragment(w, h.templates, "transactions.html", fragment, status, row)
+}
+
+// accountNameEdit is the data of the inline account rename fragments.
+type accountNameEdit struct {
+	*models.Account
+	Error string // Why the rename was rejected
+}
+
+// AccountName renders an account's name in the accounts list, e.g. to cancel
+// renaming it.
+func (h *AccountHandler) AccountName(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, ok := h.ownAccount(w, r, user)
+	if !ok {
+		return
+	}
+	renderFragment(w, h.templates, "accounts.html", "account-name", http.StatusOK, account)
+}
+
+// EditAccountName renders the inline editor of an account's name.
+func (h *AccountHandler) EditAccountName(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, ok := h.ownAccount(w, r, user)
+	if !ok {
+		return
+	}
+	renderFragment(w, h.templates, "accounts.html", "account-name-edit", http.StatusOK, accountNameEdit{Account: account})
+}
+
+// UpdateAccountName renames an account from the inline editor. A rename of
+// an account changed since the editor was opened is rejected with the
+// current name.
+func (h *AccountHandler) UpdateAccountName(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	account, ok := h.ownAccount(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	version, err := parseVersion(r.FormValue("version"))
+	if err != nil {
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		renderFragment(w, h.templates, "accounts.html", "account-name-edit", http.StatusUnprocessableEntity,
+			accountNameEdit{Account: account, Error: "Name is required"})
+		return
+	}
+
+	original := *account
+	account.Name = name
+	account.UpdatedAt = version
+
+	if err := h.accountRepo.Update(account); err != nil {
+		if errors.Is(err, repository.ErrStaleUpdate) {
+			current, _ := h.accountRepo.GetByID(account.ID)
+			if current != nil {
+				renderFragment(w, h.templates, "accounts.html", "account-name-edit", http.StatusConflict,
+					accountNameEdit{Account: current, Error: err.Error()})
+				return
+			}
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			renderFragment(w, h.templates, "accounts.html", "account-name-edit", http.StatusUnprocessableEntity,
+				accountNameEdit{Account: &original, Error: "An account with this name already exists"})
+			return
+		}
+		log.Printf("Error renaming account: %v", err)
+		http.Error(w, "Failed to update account", http.StatusInternalServerError)
+		return
+	}
+
+	renderFragment(w, h.templates, "accounts.html", "account-name", http.StatusOK, account)
+}
+
+// ownAccount loads the account in the URL if it belongs to the user, writing
+// an error response otherwise.
+func (h *AccountHandler) ownAccount(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Account, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Consistent error to prevent enumeration
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil || account.UserID != user.ID {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil, false
+	}
+	return account, true
+}
+
+// parseVersion parses the version field posted by an inline editor. The zero
+// time is the version of records never edited.
+func parseVersion(s string) (time.Time, error) {
+	version, err := time.Parse(versionFormat, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if version.IsZero() {
+		return time.Time{}, nil
+	}
+	return version, nil
+}
+
+// renderFragment renders a fragment defined in a page template, for HTMX to
+// swap into the page.
+func renderFragment(w http.ResponseWriter, templates map[string]*template.Template, page, fragment string, status int, data any) {
+	tmpl, ok := templates[page]
+	if !ok {
+		http.Error(w, "Template not found: "+page, http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, fragment, data); err != nil {
+		log.Printf("Error rendering template %s of %s: %v", fragment, page, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// SetLoginLinkManager enables one-time login links, whose use is recorded in
+// the audit log.
+func (h *AuthHandler) SetLoginLinkManager(loginLinks *auth.LoginLinkManager, auditService *services.AuditService) {
+	h.loginLinks = loginLinks
+	h.auditService = auditService
+}
+
+// LoginLinkPage asks the user to confirm logging in with a login link. The
+// link is only used up by the confirmation, so chat apps previewing it do
+// not spend it.
+func (h *AuthHandler) LoginLinkPage(w http.ResponseWriter, r *http.Request) {
+	if h.loginLinks == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, "login-link.html", map[string]any{
+		"Title": "Login Link",
+		"Token": chi.URLParam(r, "token"),
+	})
+}
+
+// UseLoginLink logs the user in with a login link and has them set a new
+// password.
+func (h *AuthHandler) UseLoginLink(w http.ResponseWriter, r *http.Request) {
+	if h.loginLinks == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	session, err := h.loginLinks.Use(chi.URLParam(r, "token"))
+	if errors.Is(err, auth.ErrLoginLinkInvalid) {
+		h.renderLoginError(w, "This login link is invalid, has expired or was already used. Ask an admin for a new one.")
+		return
+	}
+	if err != nil {
+		log.Printf("UseLoginLink error: %v", err)
+		h.renderLoginError(w, "An error occurred. Please try again.")
+		return
+	}
+
+	if err := h.userRepo.SetMustChangePassword(session.UserID, true); err != nil {
+		log.Printf("UseLoginLink error setting password flag: %v", err)
+	}
+	if h.auditService != nil {
+		h.auditService.LogAction(session.UserID, session.UserID, services.AuditUserLoginLinkUsed, "user", session.UserID, nil, nil, r.RemoteAddr, r.UserAgent())
+	}
+
+	middleware.SetSessionCookie(w, session.ID, 7*24*60*60) // 7 days
+	http.Redirect(w, r, "/change-password", http.StatusSeeOther)
+}
+
+// isLinkSession returns true if the user logged in with a login link and has
+// yet to set a new password, which they may do without the current one.
+func (h *AuthHandler) isLinkSession(r *http.Request) bool {
+	user := middleware.GetUser(r)
+	if h.loginLinks == nil || user == nil || !user.MustChangePassword {
+		return false
+	}
+	cookie, err := r.Cookie(middleware.SessionCookieName)
+	if err != nil {
+		return false
+	}
+	ok, err := h.loginLinks.IsLinkSession(cookie.Value)
+	if err != nil {
+		log.Printf("AuthHandler.isLinkSession error: %v", err)
+	}
+	return ok
+}
+
+// SetLoginLinkManager lets admins create one-time login links for users.
+func (h *AdminHandler) SetLoginLinkManager(loginLinks *auth.LoginLinkManager) {
+	h.loginLinks = loginLinks
+}
+
+// CreateLoginLink creates a one-time login link for a locked-out user and
+// shows it to the admin once, to send to the user instead of a new password.
+func (h *AdminHandler) CreateLoginLink(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.loginLinks == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if id == user.ID {
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d", id), http.StatusSeeOther)
+		return
+	}
+	targetUser, err := h.userRepo.GetByID(id)
+	if err != nil || targetUser == nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	token, expiresAt, err := h.loginLinks.Create(id, user.ID)
+	if err != nil {
+		log.Printf("AdminHandler.CreateLoginLink error: %v", err)
+		http.Redirect(w, r, fmt.Sprintf("/admin/users/%d?error=login_link_failed", id), http.StatusSeeOther)
+		return
+	}
+	h.audit(r, user.ID, id, services.AuditAdminLoginLinkCreated, map[string]string{"expires_at": expiresAt.Format(time.RFC3339)})
+
+	data := h.userDetailData(r, user, targetUser)
+	data["LoginLink"] = loginLinkURL(r, token)
+	data["LoginLinkMinutes"] = int(auth.LoginLinkDuration.Minutes())
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, "admin-user-detail.html", data)
+}
+
+// loginLinkURL returns the address of a login link on the host the admin
+// reached the app by.
+func loginLinkURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/login/link/%s", scheme, r.Host, token)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// SetNetWorthSnapshotService serves the daily net worth history from s.
+func (h *DashboardHandler) SetNetWorthSnapshotService(s *services.NetWorthSnapshotService) {
+	h.snapshots = s
+}
+
+// NetWorthHistoryCategory is a category the history has totals for.
+type NetWorthHistoryCategory struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// NetWorthHistoryResponse is the daily net worth history with the
+// categories its category totals are keyed by.
+type NetWorthHistoryResponse struct {
+	Categories map[int64]NetWorthHistoryCategory `json:"categories"`
+	Snapshots  []*models.NetWorthSnapshot        `json:"snapshots"`
+}
+
+// NetWorthHistory returns the user's daily net worth snapshots as JSON,
+// optionally limited to the days from and to, as YYYY-MM-DD.
+func (h *DashboardHandler) NetWorthHistory(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.snapshots == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		day  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := r.URL.Query().Get(p.name)
+		if value == "" {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid "+p.name+" date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*p.day = day
+	}
+
+	snapshots, err := h.snapshots.History(user.ID, from, to)
+	if err != nil {
+		log.Printf("Error getting net worth history: %v", err)
+		http.Error(w, "Failed to get net worth history", http.StatusInternalServerError)
+		return
+	}
+	categories, err := h.categoryRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error getting categories: %v", err)
+		http.Error(w, "Failed to get net worth history", http.StatusInternalServerError)
+		return
+	}
+
+	resp := NetWorthHistoryResponse{Categories: make(map[int64]NetWorthHistoryCategory), Snapshots: snapshots}
+	for _, cat := range categories {
+		resp.Categories[cat.ID] = NetWorthHistoryCategory{Name: cat.Name, Color: cat.Color}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding net worth history: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/notify"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// NotificationHandler handles managing the channels a user's alerts are sent
+// to.
+type NotificationHandler struct {
+	templates   map[string]*template.Template
+	channelRepo *repository.NotificationChannelRepository
+	notifier    *services.Notifier
+}
+
+// NewNotificationHandler creates a new NotificationHandler.
+func NewNotificationHandler(
+	templates map[string]*template.Template,
+	channelRepo *repository.NotificationChannelRepository,
+	notifier *services.Notifier,
+) *NotificationHandler {
+	return &NotificationHandler{
+		templates:   templates,
+		channelRepo: channelRepo,
+		notifier:    notifier,
+	}
+}
+
+// List renders the notification channels page.
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderPage(w, user, nil)
+}
+
+// Create handles adding a notification channel.
+func (h *NotificationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if IsDemoMode() {
+		h.renderPage(w, user, map[string]any{"Error": "Notifications are disabled in demo mode"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Invalid form data"})
+		return
+	}
+
+	_, err := h.notifier.AddChannel(user.ID, r.FormValue("kind"), r.FormValue("name"), notify.Config{
+		URL:   r.FormValue("url"),
+		Topic: r.FormValue("topic"),
+		Token: r.FormValue("token"),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrChannelNameRequired) || errors.Is(err, notify.ErrUnknownKind) ||
+			errors.Is(err, notify.ErrInvalidURL) || errors.Is(err, notify.ErrPrivateURL) || errors.Is(err, notify.ErrTopicRequired) || errors.Is(err, notify.ErrTokenRequired) {
+			h.renderPage(w, user, map[string]any{"Error": err.Error()})
+			return
+		}
+		log.Printf("Error creating notification channel: %v", err)
+		h.renderPage(w, user, map[string]any{"Error": "Failed to add channel"})
+		return
+	}
+
+	http.Redirect(w, r, "/settings/notifications", http.StatusSeeOther)
+}
+
+// Test handles sending a test notification to a channel.
+func (h *NotificationHandler) Test(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	channel, ok := h.ownChannel(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.notifier.Test(channel); err != nil {
+		h.renderPage(w, user, map[string]any{"Error": "Test notification to " + channel.Name + " failed: " + err.Error()})
+		return
+	}
+	h.renderPage(w, user, map[string]any{"Success": "Test notification sent to " + channel.Name})
+}
+
+// Toggle handles pausing or resuming a channel.
+func (h *NotificationHandler) Toggle(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	channel, ok := h.ownChannel(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.channelRepo.SetActive(channel.ID, !channel.IsActive); err != nil {
+		log.Printf("Error updating notification channel: %v", err)
+		http.Error(w, "Failed to update channel", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/notifications", http.StatusSeeOther)
+}
+
+// Delete handles removing a notification channel.
+func (h *NotificationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	channel, ok := h.ownChannel(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := h.channelRepo.Delete(channel.ID); err != nil {
+		log.Printf("Error deleting notification channel: %v", err)
+		http.Error(w, "Failed to delete channel", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/notifications", http.StatusSeeOther)
+}
+
+// ownChannel loads the channel in the URL if it belongs to the user, writing
+// an error response otherwise.
+func (h *NotificationHandler) ownChannel(w http.ResponseWriter, r *http.Request, user *models.User) (*models.NotificationChannel, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Consistent error to prevent enumeration
+	channel, err := h.channelRepo.GetByID(id)
+	if err != nil || channel == nil || channel.UserID != user.ID {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return nil, false
+	}
+	return channel, true
+}
+
+// renderPage renders the notification channels page with extra data, such
+// as an error or the result of a test send.
+func (h *NotificationHandler) renderPage(w http.ResponseWriter, user *models.User, extra map[string]any) {
+	channels, err := h.channelRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching notification channels: %v", err)
+		http.Error(w, "Error loading notification channels", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":      "Notifications",
+		"User":       user,
+		"ActiveNav":  "settings",
+		"Channels":   channels,
+		"NtfyServer": notify.DefaultNtfyServer,
+		"DemoMode":   IsDemoMode(),
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "notifications.html", data)
+}
+
+// render renders a template with the given data.
+func (h *NotificationHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
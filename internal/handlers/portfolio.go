@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/go-chi/chi/v5"
+
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
@@ -21,6 +23,13 @@ type PortfolioHandler struct {
 	portfolioService *services.PortfolioService
 	targetRepo       *repository.AllocationTargetRepository
 	categoryRepo     *repository.CategoryRepository
+	sessionRepo      *repository.RebalanceSessionRepository
+	watchlistService *services.WatchlistService
+	watchlistRepo    *repository.WatchlistRepository
+	accountRepo      *repository.AccountRepository
+	exclusionRepo    *repository.AnalyticsExclusionRepository
+	labelRepo        *repository.HoldingLabelRepository
+	goalRepo         *repository.GoalRepository
 }
 
 // NewPortfolioHandler creates a new PortfolioHandler.
@@ -29,12 +38,24 @@ func NewPortfolioHandler(
 	portfolioService *services.PortfolioService,
 	targetRepo *repository.AllocationTargetRepository,
 	categoryRepo *repository.CategoryRepository,
+	sessionRepo *repository.RebalanceSessionRepository,
+	watchlistService *services.WatchlistService,
+	watchlistRepo *repository.WatchlistRepository,
+	accountRepo *repository.AccountRepository,
+	exclusionRepo *repository.AnalyticsExclusionRepository,
+	labelRepo *repository.HoldingLabelRepository,
 ) *PortfolioHandler {
 	return &PortfolioHandler{
 		templates:        templates,
 		portfolioService: portfolioService,
 		targetRepo:       targetRepo,
 		categoryRepo:     categoryRepo,
+		sessionRepo:      sessionRepo,
+		watchlistService: watchlistService,
+		watchlistRepo:    watchlistRepo,
+		accountRepo:      accountRepo,
+		exclusionRepo:    exclusionRepo,
+		labelRepo:        labelRepo,
 	}
 }
 
@@ -46,8 +67,9 @@ func (h *PortfolioHandler) Analyzer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get portfolio composition
-	composition, err := h.portfolioService.GetPortfolioComposition(user.ID)
+	// Get portfolio composition, as it was at the end of the as-of day if any
+	asOf := parseAsOf(r)
+	composition, err := h.portfolioService.GetPortfolioCompositionAsOf(user.ID, true, asOf)
 	if err != nil {
 		log.Printf("Error getting portfolio composition: %v", err)
 		composition = &services.PortfolioComposition{}
@@ -67,10 +89,29 @@ func (h *PortfolioHandler) Analyzer(w http.ResponseWriter, r *http.Request) {
 		targets = []*models.AllocationTarget{}
 	}
 
+	// Accounts a watched instrument can be bought into
+	accounts, err := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+	if err != nil {
+		log.Printf("Error getting accounts: %v", err)
+	}
+	holdingAccounts := make([]*models.Account, 0, len(accounts))
+	for _, a := range accounts {
+		if !a.IsLiability {
+			holdingAccounts = append(holdingAccounts, a)
+		}
+	}
+
+	// Returns reported by brokers, next to our own balance change
+	brokerReturns, err := h.portfolioService.GetBrokerReturns(user.ID)
+	if err != nil {
+		log.Printf("Error getting broker returns: %v", err)
+	}
+
 	// Convert to JSON for Alpine.js
 	compositionJSON, _ := json.Marshal(composition)
 	categoriesJSON, _ := json.Marshal(categories)
 	targetsJSON, _ := json.Marshal(targets)
+	accountsJSON, _ := json.Marshal(holdingAccounts)
 
 	h.render(w, "portfolio-analyzer.html", map[string]any{
 		"Title":           "Portfolio Analyzer",
@@ -82,11 +123,16 @@ func (h *PortfolioHandler) Analyzer(w http.ResponseWriter, r *http.Request) {
 		"CategoriesJSON":  template.JS(categoriesJSON),
 		"Targets":         targets,
 		"TargetsJSON":     template.JS(targetsJSON),
+		"AccountsJSON":    template.JS(accountsJSON),
+		"BrokerReturns":   brokerReturns,
+		"AsOf":            asOfData("/tools/portfolio-analyzer", asOf),
 		"DemoMode":        IsDemoMode(),
 	})
 }
 
-// GetComposition returns the portfolio composition as JSON.
+// GetComposition returns the portfolio composition as JSON. Excluded
+// instruments are left out unless the exclusions parameter is "off", and an
+// asof date returns the composition at the end of that day.
 func (h *PortfolioHandler) GetComposition(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
 	if user == nil {
@@ -94,7 +140,7 @@ func (h *PortfolioHandler) GetComposition(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	composition, err := h.portfolioService.GetPortfolioComposition(user.ID)
+	composition, err := h.portfolioService.GetPortfolioCompositionAsOf(user.ID, applyExclusions(r), parseAsOf(r))
 	if err != nil {
 		http.Error(w, "Failed to get portfolio composition", http.StatusInternalServerError)
 		return
@@ -142,13 +188,17 @@ func (h *PortfolioHandler) SaveTarget(w http.ResponseWriter, r *http.Request) {
 
 	// Validate target type
 	switch target.TargetType {
-	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency:
+	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency, models.TargetTypeLabel:
 		// Valid
 	default:
 		http.Error(w, "Invalid target_type", http.StatusBadRequest)
 		return
 	}
 
+	if target.TargetType == models.TargetTypeLabel {
+		target.TargetKey = services.NormalizeHoldingLabel(target.TargetKey)
+	}
+
 	// Validate required fields
 	if target.TargetKey == "" {
 		http.Error(w, "target_key is required", http.StatusBadRequest)
@@ -234,14 +284,14 @@ func (h *PortfolioHandler) GetComparison(w http.ResponseWriter, r *http.Request)
 
 	// Validate target type
 	switch targetType {
-	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency:
+	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency, models.TargetTypeLabel:
 		// Valid
 	default:
 		http.Error(w, "Invalid target type", http.StatusBadRequest)
 		return
 	}
 
-	comparison, err := h.portfolioService.GetAllocationComparison(user.ID, targetType)
+	comparison, err := h.portfolioService.GetAllocationComparison(user.ID, targetType, applyExclusions(r))
 	if err != nil {
 		http.Error(w, "Failed to get allocation comparison", http.StatusInternalServerError)
 		return
@@ -268,7 +318,7 @@ func (h *PortfolioHandler) GetRebalancing(w http.ResponseWriter, r *http.Request
 
 	// Validate target type
 	switch targetType {
-	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency:
+	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency, models.TargetTypeLabel:
 		// Valid
 	default:
 		http.Error(w, "Invalid target type", http.StatusBadRequest)
@@ -286,18 +336,212 @@ func (h *PortfolioHandler) GetRebalancing(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	recommendation, err := h.portfolioService.CalculateRebalancing(user.ID, targetType, newMoney)
+	recommendation, err := h.portfolioService.CalculateRebalancing(user.ID, targetType, newMoney, applyExclusions(r))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to calculate rebalancing: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// Include the previous rebalancing so the user can see what was done
+	response := struct {
+		*services.RebalanceRecommendation
+		LastSession *models.RebalanceSession `json:"last_session,omitempty"`
+	}{RebalanceRecommendation: recommendation}
+	if sessions, err := h.sessionRepo.GetByUserID(user.ID, 1); err != nil {
+		log.Printf("Error getting last rebalance session: %v", err)
+	} else if len(sessions) > 0 {
+		response.LastSession = sessions[0]
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(recommendation); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("Error encoding rebalancing: %v", err)
 	}
 }
 
+// GetRebalanceSessions returns the user's saved rebalancing checklists.
+func (h *PortfolioHandler) GetRebalanceSessions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessionRepo.GetByUserID(user.ID, 20)
+	if err != nil {
+		log.Printf("Error getting rebalance sessions: %v", err)
+		http.Error(w, "Failed to get rebalance sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		log.Printf("Error encoding rebalance sessions: %v", err)
+	}
+}
+
+// SaveRebalanceSession saves the buy and sell actions of a rebalancing
+// recommendation as a checklist.
+func (h *PortfolioHandler) SaveRebalanceSession(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		TargetType      string                     `json:"target_type"`
+		NewMoney        float64                    `json:"new_money"`
+		Recommendations []services.RebalanceAction `json:"recommendations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Validate target type
+	switch req.TargetType {
+	case models.TargetTypeCategory, models.TargetTypeAssetType, models.TargetTypeCurrency, models.TargetTypeLabel:
+		// Valid
+	default:
+		http.Error(w, "Invalid target_type", http.StatusBadRequest)
+		return
+	}
+
+	session := &models.RebalanceSession{
+		UserID:     user.ID,
+		TargetType: req.TargetType,
+		NewMoney:   req.NewMoney,
+	}
+	for _, rec := range req.Recommendations {
+		if rec.Action != "buy" && rec.Action != "sell" {
+			continue
+		}
+		session.Actions = append(session.Actions, &models.RebalanceSessionAction{
+			TargetKey:  rec.TargetKey,
+			TargetName: rec.TargetName,
+			Action:     rec.Action,
+			Amount:     rec.Amount,
+			CurrentPct: rec.CurrentPct,
+			TargetPct:  rec.TargetPct,
+		})
+	}
+	if len(session.Actions) == 0 {
+		http.Error(w, "No buy or sell actions to save", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.sessionRepo.Create(session)
+	if err != nil {
+		log.Printf("Error saving rebalance session: %v", err)
+		http.Error(w, "Failed to save rebalance session", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := h.sessionRepo.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting rebalance session %d: %v", id, err)
+		http.Error(w, "Failed to get rebalance session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		log.Printf("Error encoding rebalance session: %v", err)
+	}
+}
+
+// UpdateRebalanceAction checks or unchecks an action of a rebalancing checklist.
+func (h *PortfolioHandler) UpdateRebalanceAction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, ok := h.ownedRebalanceSession(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	actionID, err := strconv.ParseInt(chi.URLParam(r, "actionID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid action id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Completed bool `json:"completed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessionRepo.SetActionCompleted(session.ID, actionID, req.Completed); err != nil {
+		log.Printf("Error updating rebalance action %d: %v", actionID, err)
+		http.Error(w, "Action not found", http.StatusNotFound)
+		return
+	}
+
+	updated, err := h.sessionRepo.GetByID(session.ID)
+	if err != nil {
+		log.Printf("Error getting rebalance session %d: %v", session.ID, err)
+		http.Error(w, "Failed to get rebalance session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		log.Printf("Error encoding rebalance session: %v", err)
+	}
+}
+
+// DeleteRebalanceSession removes a rebalancing checklist.
+func (h *PortfolioHandler) DeleteRebalanceSession(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	session, ok := h.ownedRebalanceSession(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.sessionRepo.Delete(session.ID); err != nil {
+		http.Error(w, "Failed to delete rebalance session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "deleted"}); err != nil {
+		log.Printf("Error encoding delete response: %v", err)
+	}
+}
+
+// ownedRebalanceSession loads the session in the URL and verifies that it
+// belongs to the user. Writes an error response and returns false otherwise.
+func (h *PortfolioHandler) ownedRebalanceSession(w http.ResponseWriter, r *http.Request, userID int64) (*models.RebalanceSession, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Consistent error to prevent enumeration
+	session, err := h.sessionRepo.GetByID(id)
+	if err != nil || session == nil || session.UserID != userID {
+		http.Error(w, "Rebalance session not found", http.StatusNotFound)
+		if err != nil {
+			log.Printf("Error getting rebalance session %d: %v", id, err)
+		}
+		return nil, false
+	}
+	return session, true
+}
+
 // render renders a template with the given data.
 func (h *PortfolioHandler) render(w http.ResponseWriter, name string, data map[string]any) {
 	if data == nil {
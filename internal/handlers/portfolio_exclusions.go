@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxExclusionReasonLength limits the note kept with an exclusion.
+const maxExclusionReasonLength = 200
+
+// applyExclusions reports whether an analysis leaves out the user's excluded
+// instruments. They are left out unless the exclusions parameter is "off".
+func applyExclusions(r *http.Request) bool {
+	return r.URL.Query().Get("exclusions") != "off"
+}
+
+// GetExclusions returns the instruments the user excluded from analytics.
+func (h *PortfolioHandler) GetExclusions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	exclusions, err := h.exclusionRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error getting analytics exclusions: %v", err)
+		http.Error(w, "Failed to get exclusions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(exclusions); err != nil {
+		log.Printf("Error encoding analytics exclusions: %v", err)
+	}
+}
+
+// SaveExclusion excludes an instrument, by ISIN or ticker, from composition,
+// rebalancing and concentration. Saving an excluded instrument again updates
+// its reason.
+func (h *PortfolioHandler) SaveExclusion(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Symbol string `json:"symbol"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	exclusion := &models.AnalyticsExclusion{
+		UserID: user.ID,
+		Symbol: services.NormalizeExclusionSymbol(req.Symbol),
+		Reason: req.Reason,
+	}
+	if exclusion.Symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	if exclusion.Symbol == "ACCOUNT" || exclusion.Symbol == "CASH" {
+		http.Error(w, "Only instruments can be excluded, not account balances", http.StatusBadRequest)
+		return
+	}
+	if len(exclusion.Reason) > maxExclusionReasonLength {
+		http.Error(w, "reason is too long", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.exclusionRepo.Upsert(exclusion)
+	if err != nil {
+		log.Printf("Error saving analytics exclusion: %v", err)
+		http.Error(w, "Failed to save exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := h.exclusionRepo.GetByID(id)
+	if err != nil || saved == nil {
+		log.Printf("Error getting analytics exclusion %d: %v", id, err)
+		http.Error(w, "Failed to get exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		log.Printf("Error encoding analytics exclusion: %v", err)
+	}
+}
+
+// DeleteExclusion includes an excluded instrument in analytics again.
+func (h *PortfolioHandler) DeleteExclusion(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Consistent error to prevent enumeration
+	exclusion, err := h.exclusionRepo.GetByID(id)
+	if err != nil || exclusion == nil || exclusion.UserID != user.ID {
+		http.Error(w, "Exclusion not found", http.StatusNotFound)
+		if err != nil {
+			log.Printf("Error getting analytics exclusion %d: %v", id, err)
+		}
+		return
+	}
+
+	if err := h.exclusionRepo.Delete(exclusion.ID); err != nil {
+		http.Error(w, "Failed to delete exclusion", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "deleted"}); err != nil {
+		log.Printf("Error encoding delete response: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// GetHoldings returns the user's holdings matching the query's filters, with
+// their sums by group_by (symbol by default):
+//
+//	account      account ID; repeat or separate by commas for several
+//	type         instrument type, such as etf or stock
+//	currency     currency of the holding
+//	min_value    smallest value in base currency
+//	group_by     symbol, account, instrument_type or currency
+//	exclusions   "off" includes instruments excluded from analytics
+func (h *PortfolioHandler) GetHoldings(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := services.HoldingFilter{
+		InstrumentType:  strings.TrimSpace(query.Get("type")),
+		Currency:        strings.TrimSpace(query.Get("currency")),
+		GroupBy:         query.Get("group_by"),
+		ApplyExclusions: applyExclusions(r),
+	}
+	for _, param := range query["account"] {
+		for _, s := range strings.Split(param, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid account", http.StatusBadRequest)
+				return
+			}
+			filter.AccountIDs = append(filter.AccountIDs, id)
+		}
+	}
+	if s := query.Get("min_value"); s != "" {
+		minValue, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			http.Error(w, "Invalid min_value", http.StatusBadRequest)
+			return
+		}
+		filter.MinValue = minValue
+	}
+
+	result, err := h.portfolioService.QueryHoldings(user.ID, filter)
+	if errors.Is(err, services.ErrHoldingGroup) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error querying holdings: %v", err)
+		http.Error(w, "Failed to get holdings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding holdings: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxHoldingLabelLength limits the length of a holding label.
+const maxHoldingLabelLength = 40
+
+// GetLabels returns the labels the user gave instruments.
+func (h *PortfolioHandler) GetLabels(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	labels, err := h.labelRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error getting holding labels: %v", err)
+		http.Error(w, "Failed to get labels", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(labels); err != nil {
+		log.Printf("Error encoding holding labels: %v", err)
+	}
+}
+
+// SaveLabel labels an instrument, by ISIN or ticker, in every account, such
+// as "core" or "satellite". Saving a labelled instrument again replaces its
+// label.
+func (h *PortfolioHandler) SaveLabel(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Symbol string `json:"symbol"`
+		Label  string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	label := &models.HoldingLabel{
+		UserID: user.ID,
+		Symbol: services.NormalizeExclusionSymbol(req.Symbol),
+		Label:  services.NormalizeHoldingLabel(req.Label),
+	}
+	if label.Symbol == "" || label.Label == "" {
+		http.Error(w, "symbol and label are required", http.StatusBadRequest)
+		return
+	}
+	if label.Symbol == "ACCOUNT" || label.Symbol == "CASH" {
+		http.Error(w, "Only instruments can be labelled, not account balances", http.StatusBadRequest)
+		return
+	}
+	if len(label.Label) > maxHoldingLabelLength {
+		http.Error(w, "label is too long", http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.labelRepo.Upsert(label)
+	if err != nil {
+		log.Printf("Error saving holding label: %v", err)
+		http.Error(w, "Failed to save label", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := h.labelRepo.GetByID(id)
+	if err != nil || saved == nil {
+		log.Printf("Error getting holding label %d: %v", id, err)
+		http.Error(w, "Failed to get label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(saved); err != nil {
+		log.Printf("Error encoding holding label: %v", err)
+	}
+}
+
+// DeleteLabel removes the label from an instrument.
+func (h *PortfolioHandler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Consistent error to prevent enumeration
+	label, err := h.labelRepo.GetByID(id)
+	if err != nil || label == nil || label.UserID != user.ID {
+		http.Error(w, "Label not found", http.StatusNotFound)
+		if err != nil {
+			log.Printf("Error getting holding label %d: %v", id, err)
+		}
+		return
+	}
+
+	if err := h.labelRepo.Delete(label.ID); err != nil {
+		http.Error(w, "Failed to delete label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "deleted"}); err != nil {
+		log.Printf("Error encoding delete response: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// Liquidation renders the estimated cash from selling the accounts and
+// holdings ticked in the query. Costs are read from the query, and default to
+// DefaultLiquidationSettings.
+func (h *PortfolioHandler) Liquidation(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	settings := services.DefaultLiquidationSettings()
+	q := r.URL.Query()
+	for param, cost := range map[string]*float64{
+		"fee_pct":   &settings.FeePct,
+		"min_fee":   &settings.MinFee,
+		"fx_spread": &settings.FXSpreadPct,
+	} {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(q.Get(param)), 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			*cost = v
+		}
+	}
+	settings.Married = q.Get("married") == "1"
+	for param, ids := range map[string]*[]int64{
+		"account": &settings.AccountIDs,
+		"holding": &settings.HoldingIDs,
+	} {
+		for _, s := range q[param] {
+			if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+				*ids = append(*ids, id)
+			}
+		}
+	}
+
+	data := map[string]any{
+		"Title":     "Liquidation Value",
+		"User":      user,
+		"ActiveNav": "tools",
+		"Settings":  settings,
+		"DemoMode":  IsDemoMode(),
+	}
+	if err := services.ValidateLiquidationSettings(settings); err != nil {
+		data["Error"] = capitalize(err.Error())
+		h.render(w, "liquidation.html", data)
+		return
+	}
+
+	result, err := h.portfolioService.EstimateLiquidation(user.ID, settings)
+	if err != nil {
+		log.Printf("Error estimating liquidation: %v", err)
+		http.Error(w, "Error estimating liquidation", http.StatusInternalServerError)
+		return
+	}
+	data["Result"] = result
+
+	h.render(w, "liquidation.html", data)
+}
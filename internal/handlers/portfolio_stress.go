@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// SetGoalRepository enables showing the impact of stress scenarios on goals.
+func (h *PortfolioHandler) SetGoalRepository(goalRepo *repository.GoalRepository) {
+	h.goalRepo = goalRepo
+}
+
+// StressTest renders the stress test of the current composition. Shocks are
+// read from the query, and default to DefaultStressScenario.
+func (h *PortfolioHandler) StressTest(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	scenario := services.DefaultStressScenario()
+	q := r.URL.Query()
+	for param, shock := range map[string]*float64{
+		"equity":          &scenario.EquityShock,
+		"crypto":          &scenario.CryptoShock,
+		"currency_shock":  &scenario.CurrencyShock,
+		"debt_rate_shock": &scenario.DebtRateShock,
+	} {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(q.Get(param)), 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			*shock = v
+		}
+	}
+	if q.Has("currency") {
+		scenario.Currency = q.Get("currency")
+	}
+
+	err := services.ValidateStressScenario(&scenario)
+	data := map[string]any{
+		"Title":     "Stress Test",
+		"User":      user,
+		"ActiveNav": "tools",
+		"Scenario":  scenario,
+		"DemoMode":  IsDemoMode(),
+	}
+	if err != nil {
+		data["Error"] = capitalize(err.Error())
+		h.render(w, "stress-test.html", data)
+		return
+	}
+
+	var goals []*models.Goal
+	if h.goalRepo != nil {
+		if goals, err = h.goalRepo.GetByUserID(user.ID); err != nil {
+			log.Printf("Error getting goals: %v", err)
+		}
+	}
+
+	result, err := h.portfolioService.RunStressTest(user.ID, scenario, goals)
+	if err != nil {
+		log.Printf("Error running stress test: %v", err)
+		http.Error(w, "Error running stress test", http.StatusInternalServerError)
+		return
+	}
+	data["Result"] = result
+
+	h.render(w, "stress-test.html", data)
+}
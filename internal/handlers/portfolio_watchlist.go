@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// watchlistUserErrors are the watchlist errors reported back to the user.
+var watchlistUserErrors = []error{
+	services.ErrWatchlistSymbolRequired,
+	services.ErrWatchlistDuplicate,
+	services.ErrWatchlistInvalidPrice,
+	services.ErrWatchlistInvalidBuy,
+	services.ErrWatchlistBrokerAccount,
+}
+
+// GetWatchlist returns the user's watchlist with current prices.
+func (h *PortfolioHandler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := h.watchlistService.List(user.ID)
+	if err != nil {
+		log.Printf("Error getting watchlist: %v", err)
+		http.Error(w, "Failed to get watchlist", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeWatchlistJSON(w, items)
+}
+
+// AddWatchlistItem puts an instrument on the user's watchlist.
+func (h *PortfolioHandler) AddWatchlistItem(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Symbol         string  `json:"symbol"`
+		Name           string  `json:"name"`
+		Currency       string  `json:"currency"`
+		InstrumentType string  `json:"instrument_type"`
+		Notes          string  `json:"notes"`
+		Price          float64 `json:"price"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.watchlistService.Add(&models.WatchlistItem{
+		UserID:         user.ID,
+		Symbol:         req.Symbol,
+		Name:           req.Name,
+		Currency:       req.Currency,
+		InstrumentType: req.InstrumentType,
+		Notes:          req.Notes,
+	})
+	if err != nil {
+		h.watchlistError(w, "adding watchlist item", err)
+		return
+	}
+
+	// Without a synced price, start from the price the user entered
+	if item.CurrentPrice == nil && req.Price > 0 {
+		if item, err = h.watchlistService.SetManualPrice(item, req.Price); err != nil {
+			h.watchlistError(w, "setting watchlist price", err)
+			return
+		}
+	}
+
+	h.writeWatchlistJSON(w, item)
+}
+
+// SetWatchlistPrice records a manually looked up price for a watchlist item.
+func (h *PortfolioHandler) SetWatchlistPrice(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	item, ok := h.ownedWatchlistItem(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Price float64 `json:"price"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.watchlistService.SetManualPrice(item, req.Price)
+	if err != nil {
+		h.watchlistError(w, "setting watchlist price", err)
+		return
+	}
+
+	h.writeWatchlistJSON(w, updated)
+}
+
+// ConvertWatchlistItem records the purchase of a watched instrument as a
+// holding of one of the user's accounts and removes it from the watchlist.
+func (h *PortfolioHandler) ConvertWatchlistItem(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	item, ok := h.ownedWatchlistItem(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		AccountID int64   `json:"account_id"`
+		Quantity  float64 `json:"quantity"`
+		Price     float64 `json:"price"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Consistent error to prevent enumeration
+	account, err := h.accountRepo.GetByID(req.AccountID)
+	if err != nil || account == nil || account.UserID != user.ID {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	holding, err := h.watchlistService.Convert(item, account, req.Quantity, req.Price)
+	if err != nil {
+		h.watchlistError(w, "converting watchlist item", err)
+		return
+	}
+
+	h.writeWatchlistJSON(w, holding)
+}
+
+// DeleteWatchlistItem removes an instrument from the watchlist.
+func (h *PortfolioHandler) DeleteWatchlistItem(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	item, ok := h.ownedWatchlistItem(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.watchlistRepo.Delete(item.ID); err != nil {
+		http.Error(w, "Failed to delete watchlist item", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeWatchlistJSON(w, map[string]string{"status": "deleted"})
+}
+
+// ownedWatchlistItem loads the watchlist item in the URL and verifies that it
+// belongs to the user. Writes an error response and returns false otherwise.
+func (h *PortfolioHandler) ownedWatchlistItem(w http.ResponseWriter, r *http.Request, userID int64) (*models.WatchlistItem, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Consistent error to prevent enumeration
+	item, err := h.watchlistRepo.GetByID(id)
+	if err != nil || item == nil || item.UserID != userID {
+		http.Error(w, "Watchlist item not found", http.StatusNotFound)
+		if err != nil {
+			log.Printf("Error getting watchlist item %d: %v", id, err)
+		}
+		return nil, false
+	}
+	return item, true
+}
+
+// watchlistError reports validation errors to the user and logs the rest.
+func (h *PortfolioHandler) watchlistError(w http.ResponseWriter, action string, err error) {
+	for _, userErr := range watchlistUserErrors {
+		if errors.Is(err, userErr) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	log.Printf("Error %s: %v", action, err)
+	http.Error(w, "Failed to update watchlist", http.StatusInternalServerError)
+}
+
+// writeWatchlistJSON writes v as a JSON response.
+func (h *PortfolioHandler) writeWatchlistJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding watchlist response: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxQuickAddDescriptionLength bounds descriptions from the quick-add form.
+const maxQuickAddDescriptionLength = 200
+
+// quickAddAccount is an account offered in the quick-add form.
+type quickAddAccount struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Currency    string  `json:"currency"`
+	IsLiability bool    `json:"is_liability"`
+	Balance     float64 `json:"balance"`
+}
+
+// QuickAddAccounts returns the user's active accounts for the quick-add form:
+// the accounts most recently added to first, then the rest in the user's
+// order.
+func (h *TransactionHandler) QuickAddAccounts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, err := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+	recent, err := h.transactionRepo.GetRecentlyUsedAccountIDs(user.ID)
+	if err != nil {
+		log.Printf("Error fetching recently used accounts: %v", err)
+	}
+
+	byID := make(map[int64]*models.Account, len(accounts))
+	for _, acc := range accounts {
+		byID[acc.ID] = acc
+	}
+	ordered := make([]*models.Account, 0, len(accounts))
+	for _, id := range recent {
+		if acc, ok := byID[id]; ok {
+			ordered = append(ordered, acc)
+			delete(byID, id)
+		}
+	}
+	for _, acc := range accounts {
+		if _, ok := byID[acc.ID]; ok {
+			ordered = append(ordered, acc)
+		}
+	}
+
+	result := make([]quickAddAccount, 0, len(ordered))
+	for _, acc := range ordered {
+		balance, _ := h.transactionRepo.GetLatestBalance(acc.ID)
+		result = append(result, quickAddAccount{
+			ID:          acc.ID,
+			Name:        acc.Name,
+			Currency:    acc.Currency,
+			IsLiability: acc.IsLiability,
+			Balance:     balance,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// QuickAdd adds a transaction from the quick-add form. A balance that breaks
+// the account's trend is refused with 409 Conflict and the anomaly, unless
+// the request confirms it.
+func (h *TransactionHandler) QuickAdd(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		AccountID   int64    `json:"account_id"`
+		Amount      *float64 `json:"amount"`
+		Date        string   `json:"date"`
+		Description string   `json:"description"`
+		Confirm     bool     `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Amount == nil || *req.Amount == 0 || math.IsNaN(*req.Amount) || math.IsInf(*req.Amount, 0) {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(req.Description)
+	if len(description) > maxQuickAddDescriptionLength {
+		http.Error(w, "Description is too long", http.StatusBadRequest)
+		return
+	}
+
+	transactionDate := time.Now()
+	if req.Date != "" {
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			http.Error(w, "Invalid date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		transactionDate = date
+	}
+
+	// Consistent error to prevent enumeration
+	account, err := h.accountRepo.GetByID(req.AccountID)
+	if err != nil || account == nil || account.UserID != user.ID {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	currentBalance, err := h.transactionRepo.GetLatestBalance(account.ID)
+	if err != nil {
+		log.Printf("Error getting balance of account %d: %v", account.ID, err)
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+	newBalance := currentBalance + *req.Amount
+
+	if !req.Confirm {
+		anomaly, err := h.balanceChecker.Check(account.ID, newBalance)
+		if err != nil {
+			log.Printf("Error checking balance of account %d: %v", account.ID, err)
+		}
+		if anomaly != nil {
+			writeQuickAddJSON(w, http.StatusConflict, map[string]any{"anomaly": quickAddAnomaly(anomaly)})
+			return
+		}
+	}
+
+	txn := &models.Transaction{
+		AccountID:       account.ID,
+		Amount:          *req.Amount,
+		BalanceAfter:    newBalance,
+		Description:     description,
+		TransactionDate: transactionDate,
+	}
+	id, err := h.transactionRepo.Create(txn)
+	if err != nil {
+		log.Printf("Error creating transaction: %v", err)
+		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
+		return
+	}
+	txn.ID = id
+
+	writeQuickAddJSON(w, http.StatusCreated, map[string]any{
+		"transaction":  txn,
+		"account_name": account.Name,
+		"balance":      newBalance,
+	})
+}
+
+// quickAddAnomaly describes a balance anomaly for the quick-add form.
+func quickAddAnomaly(a *services.BalanceAnomaly) map[string]float64 {
+	return map[string]float64{
+		"previous":          a.Previous,
+		"expected":          a.Expected,
+		"new":               a.New,
+		"deviation_percent": a.DeviationPercent,
+	}
+}
+
+// writeQuickAddJSON writes v as a JSON response with the given status.
+func writeQuickAddJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding quick-add response: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/release"
+	"wealth_tracker/internal/repository"
+)
+
+// ReleaseHandler handles the what's new page.
+type ReleaseHandler struct {
+	templates   map[string]*template.Template
+	userRepo    *repository.UserRepository
+	versionRepo *repository.AppVersionRepository
+}
+
+// NewReleaseHandler creates a new ReleaseHandler.
+func NewReleaseHandler(
+	templates map[string]*template.Template,
+	userRepo *repository.UserRepository,
+	versionRepo *repository.AppVersionRepository,
+) *ReleaseHandler {
+	return &ReleaseHandler{
+		templates:   templates,
+		userRepo:    userRepo,
+		versionRepo: versionRepo,
+	}
+}
+
+// ShowWhatsNew redirects to the what's new page once after an upgrade.
+// Users created since the upgrade, impersonating admins and releases whose
+// notice an admin dismissed are let through.
+func (h *ReleaseHandler) ShowWhatsNew(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := middleware.GetUser(r)
+		if user != nil && h.noticePending(user, r) {
+			http.Redirect(w, r, "/whats-new", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *ReleaseHandler) noticePending(user *models.User, r *http.Request) bool {
+	if user.SeenVersion == release.Version || user.SupportViewer != "" {
+		return false
+	}
+	if _, err := r.Cookie(ImpersonationCookieName); err == nil {
+		return false
+	}
+	v, err := h.versionRepo.Get(release.Version)
+	if err != nil {
+		log.Printf("Error getting app version: %v", err)
+		return false
+	}
+	return v != nil && !v.NoticeDismissed && user.CreatedAt.Before(v.FirstStartedAt)
+}
+
+// WhatsNew renders the changes since the user's last seen release and marks
+// the current release as seen.
+func (h *ReleaseHandler) WhatsNew(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notes := release.Since(user.SeenVersion)
+	if r.URL.Query().Get("all") == "1" {
+		notes = release.Notes
+	}
+
+	_, err := r.Cookie(ImpersonationCookieName)
+	impersonating := err == nil
+	if !impersonating && user.SeenVersion != release.Version {
+		if err := h.userRepo.SetSeenVersion(user.ID, release.Version); err != nil {
+			log.Printf("Error setting seen version: %v", err)
+		}
+	}
+
+	var dismissed bool
+	if user.IsAdmin {
+		if v, err := h.versionRepo.Get(release.Version); err == nil && v != nil {
+			dismissed = v.NoticeDismissed
+		}
+	}
+
+	h.render(w, "whats-new.html", map[string]any{
+		"Title":           "What's New",
+		"User":            user,
+		"ActiveNav":       "",
+		"Version":         release.Version,
+		"Notes":           notes,
+		"ShowingAll":      len(notes) == len(release.Notes),
+		"NoticeDismissed": dismissed,
+		"Impersonating":   impersonating,
+		"DemoMode":        IsDemoMode(),
+	})
+}
+
+// DismissNotice stops redirecting anyone to the current release's what's
+// new page. Admin only.
+func (h *ReleaseHandler) DismissNotice(w http.ResponseWriter, r *http.Request) {
+	if err := h.versionRepo.DismissNotice(release.Version); err != nil {
+		log.Printf("Error dismissing release notice: %v", err)
+		http.Error(w, "Failed to dismiss notice", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/whats-new", http.StatusSeeOther)
+}
+
+// render renders a template with the given data.
+func (h *ReleaseHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
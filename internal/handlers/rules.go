@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// Limits of a dry run of categorization rules: the most recent transactions
+// it is run against, and the matches it lists.
+const (
+	rulePreviewHistory = 2000
+	rulePreviewListed  = 100
+)
+
+// RuleHandler handles managing the rules that categorize imported and synced
+// transactions.
+type RuleHandler struct {
+	templates       map[string]*template.Template
+	ruleRepo        *repository.CategorizationRuleRepository
+	categoryRepo    *repository.CategoryRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+}
+
+// NewRuleHandler creates a new RuleHandler.
+func NewRuleHandler(
+	templates map[string]*template.Template,
+	ruleRepo *repository.CategorizationRuleRepository,
+	categoryRepo *repository.CategoryRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+) *RuleHandler {
+	return &RuleHandler{
+		templates:       templates,
+		ruleRepo:        ruleRepo,
+		categoryRepo:    categoryRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+	}
+}
+
+// ruleRow is a rule as listed, with the name of the category it sets.
+type ruleRow struct {
+	*models.CategorizationRule
+	Category string
+}
+
+// rulePreviewRow is a transaction matched in a dry run, with the names of
+// its account and the category it would get.
+type rulePreviewRow struct {
+	services.RuleMatch
+	Account  *models.Account
+	Category string
+}
+
+// List renders the categorization rules page. With a preview parameter, the
+// saved rule of that ID is dry-run against the user's transactions.
+func (h *RuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	idStr := r.URL.Query().Get("preview")
+	if idStr == "" {
+		h.renderPage(w, user, nil, nil)
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+	rule, err := h.ruleRepo.GetByID(id)
+	if err != nil || rule == nil || rule.UserID != user.ID {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+	h.renderPage(w, user, rule, nil)
+}
+
+// Create handles adding a categorization rule.
+func (h *RuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rule, errMsg := h.parseRule(r, user)
+	if errMsg != "" {
+		h.renderPage(w, user, nil, map[string]any{"Error": errMsg, "Form": rule})
+		return
+	}
+
+	if _, err := h.ruleRepo.Create(rule); err != nil {
+		log.Printf("Error creating categorization rule: %v", err)
+		h.renderPage(w, user, nil, map[string]any{"Error": "Failed to add rule", "Form": rule})
+		return
+	}
+
+	http.Redirect(w, r, "/settings/rules", http.StatusSeeOther)
+}
+
+// Preview dry-runs the rule in the form against the user's transactions
+// without saving it, keeping the form filled in.
+func (h *RuleHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rule, errMsg := h.parseRule(r, user)
+	if errMsg != "" {
+		h.renderPage(w, user, nil, map[string]any{"Error": errMsg, "Form": rule})
+		return
+	}
+	h.renderPage(w, user, rule, map[string]any{"Form": rule})
+}
+
+// Delete handles removing a categorization rule.
+func (h *RuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	// Consistent error to prevent enumeration
+	rule, err := h.ruleRepo.GetByID(id)
+	if err != nil || rule == nil || rule.UserID != user.ID {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.ruleRepo.Delete(rule.ID); err != nil {
+		log.Printf("Error deleting categorization rule: %v", err)
+		http.Error(w, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/settings/rules", http.StatusSeeOther)
+}
+
+// parseRule reads a categorization rule from the form and validates it.
+// Returns the rule, filled in as far as it could be read, and an error
+// message if it is invalid.
+func (h *RuleHandler) parseRule(r *http.Request, user *models.User) (*models.CategorizationRule, string) {
+	rule := &models.CategorizationRule{UserID: user.ID}
+	if err := r.ParseForm(); err != nil {
+		return rule, "Invalid form data"
+	}
+
+	rule.Name = r.FormValue("name")
+	rule.Pattern = r.FormValue("pattern")
+	rule.Payee = r.FormValue("payee")
+	rule.Tag = r.FormValue("tag")
+	rule.Kind = r.FormValue("kind")
+
+	var ok bool
+	if rule.MinAmount, ok = parseOptionalAmount(r.FormValue("min_amount")); !ok {
+		return rule, "Minimum amount must be a number"
+	}
+	if rule.MaxAmount, ok = parseOptionalAmount(r.FormValue("max_amount")); !ok {
+		return rule, "Maximum amount must be a number"
+	}
+	if v := strings.TrimSpace(r.FormValue("priority")); v != "" {
+		priority, err := strconv.Atoi(v)
+		if err != nil {
+			return rule, "Priority must be a whole number"
+		}
+		rule.Priority = priority
+	}
+	if v := r.FormValue("category_id"); v != "" && v != "0" {
+		categoryID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return rule, "Invalid category"
+		}
+		category, err := h.categoryRepo.GetByID(categoryID)
+		if err != nil || category == nil || category.UserID != user.ID {
+			return rule, "Invalid category"
+		}
+		rule.CategoryID = &categoryID
+	}
+
+	if err := services.ValidateRule(rule); err != nil {
+		return rule, capitalize(err.Error())
+	}
+	return rule, ""
+}
+
+// parseOptionalAmount parses an optional amount bound. An empty value means
+// no bound.
+func parseOptionalAmount(value string) (*float64, bool) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, ",", "."))
+	if value == "" {
+		return nil, true
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &amount, true
+}
+
+// renderPage renders the categorization rules page with extra data, such as
+// an error, and the dry run of preview against the user's most recent
+// transactions if it is set.
+func (h *RuleHandler) renderPage(w http.ResponseWriter, user *models.User, preview *models.CategorizationRule, extra map[string]any) {
+	rules, err := h.ruleRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching categorization rules: %v", err)
+		http.Error(w, "Error loading rules", http.StatusInternalServerError)
+		return
+	}
+	categories, err := h.categoryRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		http.Error(w, "Error loading categories", http.StatusInternalServerError)
+		return
+	}
+	categoryNames := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		categoryNames[c.ID] = c.Name
+	}
+
+	rows := make([]ruleRow, len(rules))
+	for i, rule := range rules {
+		rows[i] = ruleRow{CategorizationRule: rule}
+		if rule.CategoryID != nil {
+			rows[i].Category = categoryNames[*rule.CategoryID]
+		}
+	}
+
+	data := map[string]any{
+		"Title":      "Categorization Rules",
+		"User":       user,
+		"ActiveNav":  "settings",
+		"Rules":      rows,
+		"Categories": categories,
+		"DemoMode":   IsDemoMode(),
+	}
+
+	if preview != nil {
+		previewRows, matched, err := h.preview(user, preview, categoryNames)
+		if err != nil {
+			log.Printf("Error previewing categorization rule: %v", err)
+			http.Error(w, "Error loading transactions", http.StatusInternalServerError)
+			return
+		}
+		data["Preview"] = preview
+		data["PreviewRows"] = previewRows
+		data["PreviewMatched"] = matched
+		data["PreviewHistory"] = rulePreviewHistory
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+	formCategoryID := int64(0)
+	if form, ok := data["Form"].(*models.CategorizationRule); ok && form.CategoryID != nil {
+		formCategoryID = *form.CategoryID
+	}
+	data["FormCategoryID"] = formCategoryID
+	h.render(w, "rules.html", data)
+}
+
+// preview dry-runs a rule against the user's most recent transactions and
+// returns the first matches to list, with the total number matched.
+func (h *RuleHandler) preview(user *models.User, rule *models.CategorizationRule, categoryNames map[int64]string) ([]rulePreviewRow, int, error) {
+	transactions, err := h.transactionRepo.GetByUserID(user.ID, rulePreviewHistory, 0)
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting transactions: %w", err)
+	}
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("getting accounts: %w", err)
+	}
+	accountsByID := make(map[int64]*models.Account, len(accounts))
+	for _, a := range accounts {
+		accountsByID[a.ID] = a
+	}
+
+	matches := services.PreviewRules([]*models.CategorizationRule{rule}, transactions)
+	rows := make([]rulePreviewRow, 0, min(len(matches), rulePreviewListed))
+	for _, m := range matches {
+		if len(rows) == rulePreviewListed {
+			break
+		}
+		row := rulePreviewRow{RuleMatch: m, Account: accountsByID[m.Transaction.AccountID]}
+		if m.Result.CategoryID != nil {
+			row.Category = categoryNames[*m.Result.CategoryID]
+		}
+		rows = append(rows, row)
+	}
+	return rows, len(matches), nil
+}
+
+// render renders a template with the given data.
+func (h *RuleHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
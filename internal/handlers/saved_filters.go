@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// maxSavedFilterNameLength bounds the name of a saved filter.
+const maxSavedFilterNameLength = 100
+
+// SetSavedFilterRepository sets the repository of the named filters listed
+// as views on the transactions page.
+func (h *TransactionHandler) SetSavedFilterRepository(repo *repository.SavedFilterRepository) {
+	h.savedFilterRepo = repo
+}
+
+// SaveFilter saves the criteria of the transactions page under a name and
+// redirects to the new view.
+func (h *TransactionHandler) SaveFilter(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if len(name) > maxSavedFilterNameLength {
+		http.Error(w, fmt.Sprintf("Name must be at most %d characters", maxSavedFilterNameLength), http.StatusBadRequest)
+		return
+	}
+
+	filter := parseTransactionFilter(r.PostForm)
+	if filter.IsEmpty() {
+		http.Error(w, "Choose at least one filter to save", http.StatusBadRequest)
+		return
+	}
+	if filter.AccountID != nil {
+		account, err := h.accountRepo.GetByID(*filter.AccountID)
+		if err != nil || account == nil || account.UserID != user.ID {
+			http.Error(w, "Invalid account", http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.CategoryID != nil {
+		category, err := h.categoryRepo.GetByID(*filter.CategoryID)
+		if err != nil || category == nil || category.UserID != user.ID {
+			http.Error(w, "Invalid category", http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, err := h.savedFilterRepo.Create(&models.SavedFilter{UserID: user.ID, Name: name, TransactionFilter: filter})
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint") {
+			http.Error(w, "A view with this name already exists", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error saving filter: %v", err)
+		http.Error(w, "Error saving view", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/transactions?filter=%d", id), http.StatusSeeOther)
+}
+
+// DeleteFilter deletes a saved filter.
+func (h *TransactionHandler) DeleteFilter(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid view ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.ownedFilter(w, id, user.ID); !ok {
+		return
+	}
+
+	if err := h.savedFilterRepo.Delete(id); err != nil {
+		log.Printf("Error deleting saved filter %d: %v", id, err)
+		http.Error(w, "Error deleting view", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/transactions", http.StatusSeeOther)
+}
+
+// ownedFilter returns the user's saved filter. Otherwise it responds with an
+// error and returns false.
+func (h *TransactionHandler) ownedFilter(w http.ResponseWriter, id, userID int64) (*models.SavedFilter, bool) {
+	filter, err := h.savedFilterRepo.GetByID(id)
+	if err != nil || filter == nil {
+		http.Error(w, "View not found", http.StatusNotFound)
+		return nil, false
+	}
+	if filter.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return filter, true
+}
+
+// parseTransactionFilter reads the criteria of a transaction filter from
+// query or form values. Blank and invalid values are left unset.
+func parseTransactionFilter(values url.Values) models.TransactionFilter {
+	var filter models.TransactionFilter
+	if id, err := strconv.ParseInt(values.Get("account"), 10, 64); err == nil && id > 0 {
+		filter.AccountID = &id
+	}
+	if id, err := strconv.ParseInt(values.Get("category"), 10, 64); err == nil && id > 0 {
+		filter.CategoryID = &id
+	}
+	filter.Tag = strings.TrimSpace(values.Get("tag"))
+	if amount, err := strconv.ParseFloat(values.Get("min"), 64); err == nil {
+		filter.MinAmount = &amount
+	}
+	if amount, err := strconv.ParseFloat(values.Get("max"), 64); err == nil {
+		filter.MaxAmount = &amount
+	}
+	if date, err := time.Parse("2006-01-02", values.Get("from")); err == nil {
+		filter.From = &date
+	}
+	if date, err := time.Parse("2006-01-02", values.Get("to")); err == nil {
+		filter.To = &date
+	}
+	return filter
+}
+
+// transactionFilterValues returns the query values parseTransactionFilter
+// reads filter back from.
+func transactionFilterValues(filter models.TransactionFilter) url.Values {
+	values := url.Values{}
+	if filter.AccountID != nil {
+		values.Set("account", strconv.FormatInt(*filter.AccountID, 10))
+	}
+	if filter.CategoryID != nil {
+		values.Set("category", strconv.FormatInt(*filter.CategoryID, 10))
+	}
+	if filter.Tag != "" {
+		values.Set("tag", filter.Tag)
+	}
+	if filter.MinAmount != nil {
+		values.Set("min", strconv.FormatFloat(*filter.MinAmount, 'f', -1, 64))
+	}
+	if filter.MaxAmount != nil {
+		values.Set("max", strconv.FormatFloat(*filter.MaxAmount, 'f', -1, 64))
+	}
+	if filter.From != nil {
+		values.Set("from", filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		values.Set("to", filter.To.Format("2006-01-02"))
+	}
+	return values
+}
@@ -1,22 +1,47 @@
 package handlers
 
 import (
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
+	"wealth_tracker/internal/dates"
 	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // SettingsHandler handles settings routes.
 type SettingsHandler struct {
-	templates map[string]*template.Template
-	userRepo  *repository.UserRepository
+	templates    map[string]*template.Template
+	userRepo     *repository.UserRepository
+	emailEnabled bool
 }
 
+// timezones are the time zones offered in the settings; others can be set
+// by name.
+var timezones = []string{
+	"Europe/Copenhagen",
+	"Europe/Stockholm",
+	"Europe/Oslo",
+	"Europe/Helsinki",
+	"Europe/Berlin",
+	"Europe/Paris",
+	"Europe/London",
+	"UTC",
+	"America/New_York",
+	"America/Los_Angeles",
+	"Asia/Tokyo",
+}
+
+// minMilestoneStep keeps the number of net worth milestones reasonable.
+const minMilestoneStep = 1000.0
+
 // NewSettingsHandler creates a new SettingsHandler.
 func NewSettingsHandler(
 	templates map[string]*template.Template,
@@ -28,6 +53,12 @@ func NewSettingsHandler(
 	}
 }
 
+// SetEmailEnabled tells the handler whether the server can send email, which
+// the digest setting depends on.
+func (h *SettingsHandler) SetEmailEnabled(enabled bool) {
+	h.emailEnabled = enabled
+}
+
 // isDemoMode checks if the app is running in demo mode.
 func isDemoMode() bool {
 	return os.Getenv("DEMO_MODE") == "true"
@@ -46,9 +77,29 @@ func (h *SettingsHandler) Settings(w http.ResponseWriter, r *http.Request) {
 		"User":      user,
 		"ActiveNav": "settings",
 		"DemoMode":  isDemoMode(),
+		"Error":     backupErrors[r.URL.Query().Get("error")],
+		"Success":   backupSuccesses[r.URL.Query().Get("success")],
 	})
 }
 
+// backupErrors maps the error codes ExportAllEncrypted and RestoreBackup
+// redirect with to messages.
+var backupErrors = map[string]string{
+	"passphrase_short":           fmt.Sprintf("The backup passphrase must be at least %d characters", services.MinExportPassphraseLength),
+	"passphrase_mismatch":        "The backup passphrases do not match",
+	"backup_missing":             "Please choose a backup file to restore",
+	"backup_invalid":             "The file is not a valid backup",
+	"backup_passphrase_required": "This backup is encrypted; enter its passphrase to restore it",
+	"backup_passphrase":          "Wrong passphrase or damaged backup",
+	"backup_failed":              "Failed to restore the backup",
+}
+
+// backupSuccesses maps the success codes RestoreBackup redirects with to
+// messages.
+var backupSuccesses = map[string]string{
+	"backup_restored": "The backup was restored. Accounts, categories and goals you already had were kept as they are.",
+}
+
 // Update handles updating user settings.
 func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -97,11 +148,67 @@ func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		theme = "dark"
 	}
 
+	// Validate milestone step (0 turns milestones off)
+	milestoneStep := user.MilestoneStep
+	if stepStr := strings.TrimSpace(r.FormValue("milestone_step")); stepStr != "" {
+		step, err := strconv.ParseFloat(stepStr, 64)
+		if err != nil || step < 0 || (step > 0 && step < minMilestoneStep) {
+			h.renderError(w, user, fmt.Sprintf("Milestone step must be 0 or at least %.0f", minMilestoneStep))
+			return
+		}
+		milestoneStep = step
+	}
+
+	// Validate description templates (empty selects the default)
+	balanceDescription := strings.TrimSpace(r.FormValue("balance_description"))
+	syncDescription := strings.TrimSpace(r.FormValue("sync_description"))
+	for _, tmpl := range []string{balanceDescription, syncDescription} {
+		if err := services.ValidateDescriptionTemplate(tmpl); err != nil {
+			h.renderError(w, user, "Description template: "+err.Error())
+			return
+		}
+	}
+
+	// Validate digest frequency (disabled selects are not submitted)
+	digestFrequency := user.DigestFrequency
+	if frequency := r.FormValue("digest_frequency"); frequency != "" {
+		if frequency != models.DigestWeekly && frequency != models.DigestMonthly && frequency != models.DigestOff {
+			h.renderError(w, user, "Invalid digest frequency")
+			return
+		}
+		digestFrequency = frequency
+	}
+
+	// Validate time zone (empty uses the server's)
+	timezone := strings.TrimSpace(r.FormValue("timezone"))
+	if !dates.ValidTimezone(timezone) {
+		h.renderError(w, user, "Unknown time zone")
+		return
+	}
+
+	// Validate statement period start day (absent keeps the current one)
+	periodStartDay := user.PeriodStartDay
+	if dayStr := strings.TrimSpace(r.FormValue("period_start_day")); dayStr != "" {
+		day, err := strconv.Atoi(dayStr)
+		if err != nil || day < 1 || day > dates.MaxPeriodStartDay {
+			h.renderError(w, user, fmt.Sprintf("Statement periods must start on a day from 1 to %d", dates.MaxPeriodStartDay))
+			return
+		}
+		periodStartDay = day
+	}
+
 	// Update user
 	user.Name = name
 	user.DefaultCurrency = defaultCurrency
 	user.NumberFormat = numberFormat
+	user.HideDecimals = r.FormValue("hide_decimals") == "1"
 	user.Theme = theme
+	user.MilestoneStep = milestoneStep
+	user.BalanceDescription = balanceDescription
+	user.SyncDescription = syncDescription
+	user.DigestFrequency = digestFrequency
+	user.Timezone = timezone
+	user.PeriodStartDay = periodStartDay
 
 	err := h.userRepo.Update(user)
 	if err != nil {
@@ -123,6 +230,13 @@ func (h *SettingsHandler) render(w http.ResponseWriter, name string, data map[st
 	if data == nil {
 		data = make(map[string]any)
 	}
+	data["MinPassphraseLength"] = services.MinExportPassphraseLength
+	data["DescriptionPlaceholders"] = services.DescriptionPlaceholders
+	data["DefaultBalanceDescription"] = services.DefaultBalanceDescription
+	data["DefaultSyncDescription"] = services.DefaultSyncDescription
+	data["EmailEnabled"] = h.emailEnabled
+	data["Timezones"] = timezones
+	data["MaxPeriodStartDay"] = dates.MaxPeriodStartDay
 
 	tmpl, ok := h.templates[name]
 	if !ok {
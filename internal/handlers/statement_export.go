@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/pdf"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// SetAcquisitionRepository sets the repository of imported buys and sells,
+// from which statements compute realized gains.
+func (h *ExportHandler) SetAcquisitionRepository(repo *repository.HoldingAcquisitionRepository) {
+	h.acquisitionRepo = repo
+}
+
+// ExportStatement exports the yearly statement of the account in the account
+// parameter for the tax year in the year parameter (default last year), as
+// CSV or, with format=pdf, as PDF.
+func (h *ExportHandler) ExportStatement(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("account"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return
+	}
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil || account.UserID != user.ID {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	year := time.Now().Year() - 1
+	if v := r.URL.Query().Get("year"); v != "" {
+		year, err = strconv.Atoi(v)
+		if err != nil || year < 1900 || year > time.Now().Year() {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "pdf" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.transactionRepo.GetBalanceHistoryByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error getting balance history for statement: %v", err)
+		http.Error(w, "Failed to get balance history", http.StatusInternalServerError)
+		return
+	}
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions, err := h.transactionRepo.GetByDateRange(account.ID, start, start.AddDate(1, 0, -1))
+	if err != nil {
+		log.Printf("Error getting transactions for statement: %v", err)
+		http.Error(w, "Failed to get transactions", http.StatusInternalServerError)
+		return
+	}
+	var acquisitions []*models.HoldingAcquisition
+	if h.acquisitionRepo != nil {
+		if acquisitions, err = h.acquisitionRepo.GetByAccountID(account.ID); err != nil {
+			log.Printf("Error getting acquisitions for statement: %v", err)
+			http.Error(w, "Failed to get acquisitions", http.StatusInternalServerError)
+			return
+		}
+	}
+	categoryNames := make(map[int64]string)
+	if categories, err := h.categoryRepo.GetByUserID(user.ID); err == nil {
+		for _, c := range categories {
+			categoryNames[c.ID] = c.Name
+		}
+	}
+
+	statement := services.NewAccountStatement(account, year, history[account.ID], transactions, acquisitions, categoryNames)
+	name := fmt.Sprintf("statement_%d_%d", account.ID, year)
+	if format == "pdf" {
+		writeStatementPDF(w, name, statement)
+		return
+	}
+	writeStatementCSV(w, name, statement)
+}
+
+// writeStatementCSV writes a statement as a CSV of consecutive sections:
+// transactions between the opening and closing balance, dividends and
+// realized gains.
+func writeStatementCSV(w http.ResponseWriter, name string, s *services.AccountStatement) {
+	writer := newCSVDownload(w, name)
+	defer writer.Flush()
+
+	writer.Write([]string{"Account", s.Account.Name})
+	writer.Write([]string{"Currency", s.Account.Currency})
+	writer.Write([]string{"Year", strconv.Itoa(s.Year)})
+	writer.Write(nil)
+
+	writer.Write([]string{"Date", "Description", "Amount", "Balance"})
+	writer.Write([]string{fmt.Sprintf("%d-01-01", s.Year), "Opening balance", "", formatAmount(s.OpeningBalance)})
+	for _, t := range s.Transactions {
+		writer.Write([]string{t.TransactionDate.Format("2006-01-02"), statementDescription(t), formatAmount(t.Amount), formatAmount(t.BalanceAfter)})
+	}
+	writer.Write([]string{fmt.Sprintf("%d-12-31", s.Year), "Closing balance", "", formatAmount(s.ClosingBalance)})
+	writer.Write(nil)
+
+	writer.Write([]string{"Dividend date", "Description", "Amount"})
+	for _, t := range s.Dividends {
+		writer.Write([]string{t.TransactionDate.Format("2006-01-02"), t.Description, formatAmount(t.Amount)})
+	}
+	writer.Write([]string{"Total dividends", "", formatAmount(s.DividendTotal())})
+	writer.Write(nil)
+
+	if !s.HasLots {
+		writer.Write([]string{"Realized gains", "Unknown: no buys imported for this account"})
+		return
+	}
+	writer.Write([]string{"Sale date", "Symbol", "Quantity", "Proceeds", "Cost basis", "Gain"})
+	for _, g := range s.RealizedGains {
+		writer.Write([]string{
+			g.Date.Format("2006-01-02"),
+			g.Symbol,
+			strconv.FormatFloat(g.Quantity, 'f', -1, 64),
+			formatAmount(g.Proceeds),
+			formatAmount(g.CostBasis),
+			formatAmount(g.Gain()),
+		})
+	}
+	writer.Write([]string{"Total realized gains", "", "", "", "", formatAmount(s.RealizedGainTotal())})
+}
+
+// writeStatementPDF writes a statement as a PDF with the same sections as the
+// CSV, laid out in fixed-width columns.
+func writeStatementPDF(w http.ResponseWriter, name string, s *services.AccountStatement) {
+	doc := pdf.New()
+	doc.Linef("Yearly statement %d: %s (%s)", s.Year, s.Account.Name, s.Account.Currency)
+	doc.Linef("Generated %s", time.Now().Format("2006-01-02"))
+	doc.Line("")
+
+	row := "%-10s  %-44s %15s %15s"
+	doc.Linef(row, "Date", "Description", "Amount", "Balance")
+	doc.Linef(row, fmt.Sprintf("%d-01-01", s.Year), "Opening balance", "", formatAmount(s.OpeningBalance))
+	for _, t := range s.Transactions {
+		doc.Linef(row, t.TransactionDate.Format("2006-01-02"), truncate(statementDescription(t), 44), formatAmount(t.Amount), formatAmount(t.BalanceAfter))
+	}
+	doc.Linef(row, fmt.Sprintf("%d-12-31", s.Year), "Closing balance", "", formatAmount(s.ClosingBalance))
+	doc.Line("")
+
+	doc.Line("Dividends")
+	for _, t := range s.Dividends {
+		doc.Linef(row, t.TransactionDate.Format("2006-01-02"), truncate(t.Description, 44), formatAmount(t.Amount), "")
+	}
+	doc.Linef(row, "", "Total dividends", formatAmount(s.DividendTotal()), "")
+	doc.Line("")
+
+	doc.Line("Realized gains (average cost method)")
+	if !s.HasLots {
+		doc.Line("Unknown: no buys imported for this account")
+	} else {
+		gainRow := "%-10s  %-14s %12s %16s %16s %16s"
+		doc.Linef(gainRow, "Date", "Symbol", "Quantity", "Proceeds", "Cost basis", "Gain")
+		for _, g := range s.RealizedGains {
+			doc.Linef(gainRow, g.Date.Format("2006-01-02"), truncate(g.Symbol, 14), strconv.FormatFloat(g.Quantity, 'f', -1, 64),
+				formatAmount(g.Proceeds), formatAmount(g.CostBasis), formatAmount(g.Gain()))
+		}
+		doc.Linef(gainRow, "", "Total", "", "", "", formatAmount(s.RealizedGainTotal()))
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_%s.pdf\"", name, time.Now().Format("2006-01-02")))
+	if _, err := doc.WriteTo(w); err != nil {
+		log.Printf("Error writing statement PDF: %v", err)
+	}
+}
+
+// statementDescription returns a transaction's description, naming
+// revaluations that have none so every row explains itself.
+func statementDescription(t *models.Transaction) string {
+	if t.Description == "" && t.Kind == models.TransactionValuation {
+		return "Valuation"
+	}
+	return t.Description
+}
+
+// truncate cuts text to at most n characters so it fits its column.
+func truncate(text string, n int) string {
+	if r := []rune(text); len(r) > n {
+		return string(r[:n])
+	}
+	return text
+}
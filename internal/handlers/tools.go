@@ -10,6 +10,7 @@ import (
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // ToolsHandler handles tools/calculator routes.
@@ -18,6 +19,8 @@ type ToolsHandler struct {
 	accountRepo     *repository.AccountRepository
 	transactionRepo *repository.TransactionRepository
 	categoryRepo    *repository.CategoryRepository
+	currencyService *services.CurrencyService // Nil disables the currency converter
+	categorizer     *services.Categorizer     // Nil leaves imported transactions uncategorized
 }
 
 // NewToolsHandler creates a new ToolsHandler.
@@ -114,12 +117,16 @@ func (h *ToolsHandler) FIRECalculator(w http.ResponseWriter, r *http.Request) {
 }
 
 // calculateFIREAccountTotals categorizes account balances into FIRE account types.
+// expectedReturn is the balance-weighted expected return of the categories.
 func (h *ToolsHandler) calculateFIREAccountTotals(accounts []*models.Account, categories []*models.Category) map[string]float64 {
 	// Create category lookup by ID
 	categoryMap := make(map[int64]string)
+	categoryByID := make(map[int64]*models.Category)
 	for _, cat := range categories {
 		categoryMap[cat.ID] = strings.ToLower(cat.Name)
+		categoryByID[cat.ID] = cat
 	}
+	var buckets []services.ProjectionBucket
 
 	totals := map[string]float64{
 		"frieMidler":  0,
@@ -129,7 +136,7 @@ func (h *ToolsHandler) calculateFIREAccountTotals(accounts []*models.Account, ca
 	}
 
 	for _, acc := range accounts {
-		if !acc.IsActive {
+		if !acc.IsActive || acc.IsClosed() {
 			continue
 		}
 
@@ -153,9 +160,15 @@ func (h *ToolsHandler) calculateFIREAccountTotals(accounts []*models.Account, ca
 
 		// Determine category name
 		catName := ""
+		var category *models.Category
 		if acc.CategoryID != nil {
 			catName = categoryMap[*acc.CategoryID]
+			category = categoryByID[*acc.CategoryID]
 		}
+		buckets = append(buckets, services.ProjectionBucket{
+			Amount:         balance,
+			ExpectedReturn: services.CategoryExpectedReturn(category),
+		})
 
 		// Also check account name for classification
 		accNameLower := strings.ToLower(acc.Name)
@@ -173,6 +186,7 @@ func (h *ToolsHandler) calculateFIREAccountTotals(accounts []*models.Account, ca
 			totals["frieMidler"] += balance
 		}
 	}
+	totals["expectedReturn"] = services.WeightedExpectedReturn(buckets)
 
 	return totals
 }
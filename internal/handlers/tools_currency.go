@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// currencyHistoryRanges are the periods the rate chart of the currency
+// converter can show, in months.
+var currencyHistoryRanges = []int{1, 3, 12, 60}
+
+// SetCurrencyService enables the currency converter.
+func (h *ToolsHandler) SetCurrencyService(currencyService *services.CurrencyService) {
+	h.currencyService = currencyService
+}
+
+// CurrencyConverter renders the conversion of an amount between two
+// currencies at today's rate and, if a date is given, at the rate of that
+// day, along with the rate history of the pair. The currencies offered are
+// those of the user's accounts and those rates have been fetched for.
+func (h *ToolsHandler) CurrencyConverter(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if h.currencyService == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	currencies := h.trackedCurrencies(user)
+	q := r.URL.Query()
+	from := strings.ToUpper(strings.TrimSpace(q.Get("from")))
+	to := strings.ToUpper(strings.TrimSpace(q.Get("to")))
+	if to == "" {
+		to = user.DefaultCurrency
+	}
+	if from == "" {
+		from = "EUR"
+		for _, c := range currencies {
+			if c != to {
+				from = c
+				break
+			}
+		}
+	}
+	amount := 1.0
+	if v := strings.TrimSpace(q.Get("amount")); v != "" {
+		amount, _ = strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+	}
+	months := 12
+	if m, err := strconv.Atoi(q.Get("months")); err == nil && slices.Contains(currencyHistoryRanges, m) {
+		months = m
+	}
+
+	data := map[string]any{
+		"Title":      "Currency Converter",
+		"User":       user,
+		"ActiveNav":  "tools",
+		"Currencies": currencies,
+		"From":       from,
+		"To":         to,
+		"Amount":     amount,
+		"Date":       q.Get("date"),
+		"Months":     months,
+		"Ranges":     currencyHistoryRanges,
+		"DemoMode":   IsDemoMode(),
+	}
+
+	if !currencyCodePattern.MatchString(from) || !currencyCodePattern.MatchString(to) {
+		data["Error"] = "Currency codes must be 2-10 letters or digits"
+		h.render(w, "currency-converter.html", data)
+		return
+	}
+	if amount <= 0 {
+		data["Error"] = "Amount must be a positive number"
+		h.render(w, "currency-converter.html", data)
+		return
+	}
+	var date time.Time
+	if v := q.Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil || d.After(time.Now()) {
+			data["Error"] = "Date must be a past date"
+			h.render(w, "currency-converter.html", data)
+			return
+		}
+		date = d
+	}
+
+	rate, source := h.currencyService.ConvertForUser(user.ID, 1, from, to)
+	data["Rate"] = rate
+	data["Converted"] = amount * rate
+	data["Source"] = string(source)
+
+	since := time.Now().AddDate(0, -months, 0)
+	if !date.IsZero() && date.Before(since) {
+		since = date
+	}
+	history, err := h.currencyService.RateHistory(from, to, since)
+	if err != nil {
+		log.Printf("Error fetching rate history %s/%s: %v", from, to, err)
+	}
+
+	if !date.IsZero() {
+		pastRate, ok := services.RateOn(history, date)
+		if !ok {
+			pastRate, ok = h.currencyService.RateForUserOn(user.ID, from, to, date)
+		}
+		if from == to {
+			pastRate, ok = 1, true
+		}
+		if ok {
+			data["PastRate"] = pastRate
+			data["PastConverted"] = amount * pastRate
+		}
+	}
+
+	// The chart shows the chosen period, even if the date is before it
+	var chart []services.RatePoint
+	chartSince := time.Now().AddDate(0, -months, 0)
+	for _, p := range history {
+		if !p.Day.Before(chartSince) {
+			chart = append(chart, p)
+		}
+	}
+	historyJSON, err := json.Marshal(chart)
+	if err != nil {
+		historyJSON = []byte("[]")
+	}
+	data["HasHistory"] = len(chart) > 1
+	data["HistoryJSON"] = template.JS(historyJSON)
+	data["IncludeCharts"] = true
+
+	h.render(w, "currency-converter.html", data)
+}
+
+// trackedCurrencies returns the user's currencies, those of their accounts,
+// and the currencies rates have been fetched for, sorted with the user's
+// currency first.
+func (h *ToolsHandler) trackedCurrencies(user *models.User) []string {
+	seen := map[string]bool{user.DefaultCurrency: true}
+	var currencies []string
+	add := func(c string) {
+		if c != "" && !seen[c] {
+			seen[c] = true
+			currencies = append(currencies, c)
+		}
+	}
+
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+	}
+	for _, acc := range accounts {
+		add(acc.Currency)
+	}
+	tracked, err := h.currencyService.TrackedCurrencies()
+	if err != nil {
+		log.Printf("Error fetching tracked currencies: %v", err)
+	}
+	for _, c := range tracked {
+		add(c)
+	}
+
+	slices.Sort(currencies)
+	return append([]string{user.DefaultCurrency}, currencies...)
+}
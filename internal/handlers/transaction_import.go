@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// maxTransactionImportSize limits the size of an uploaded CSV or OFX file.
+const maxTransactionImportSize = 5 << 20 // 5 MB
+
+// transactionImportPreviewRows is the number of CSV rows shown while mapping
+// its columns.
+const transactionImportPreviewRows = 5
+
+// SetCategorizer sets the categorizer applied to imported transactions.
+func (h *ToolsHandler) SetCategorizer(categorizer *services.Categorizer) {
+	h.categorizer = categorizer
+}
+
+// ImportForm renders the page for uploading a CSV or OFX bank export.
+func (h *ToolsHandler) ImportForm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	h.renderImport(w, user, map[string]any{"SelectedAccount": r.URL.Query().Get("account")})
+}
+
+// ImportUpload handles uploading a CSV or OFX bank export. OFX files are
+// imported right away; for a CSV the user first maps its columns.
+func (h *ToolsHandler) ImportUpload(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTransactionImportSize)
+	if err := r.ParseMultipartForm(maxTransactionImportSize); err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "File is too large or the form is invalid"})
+		return
+	}
+
+	account, ok := h.importAccount(w, r.FormValue("account_id"), user.ID)
+	if !ok {
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "Please choose a CSV or OFX file to import"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "File is too large or the form is invalid"})
+		return
+	}
+
+	if services.IsOFX(data) {
+		statement, err := services.ParseOFX(bytes.NewReader(data))
+		if err != nil {
+			h.renderImport(w, user, map[string]any{"Error": "OFX import failed: " + err.Error()})
+			return
+		}
+		h.importStatement(w, r, user, account, statement, nil)
+		return
+	}
+
+	csv, err := services.ReadTransactionCSV(bytes.NewReader(data))
+	if err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "CSV import failed: " + err.Error()})
+		return
+	}
+	h.renderMapping(w, user, account, string(data), csv, csv.GuessMapping(), nil)
+}
+
+// ImportCSV handles importing a CSV with the columns the user mapped. Nothing
+// is imported if a row fails validation, so the mapping can be corrected.
+func (h *ToolsHandler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxTransactionImportSize)
+	if err := r.ParseForm(); err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "File is too large or the form is invalid"})
+		return
+	}
+
+	account, ok := h.importAccount(w, r.FormValue("account_id"), user.ID)
+	if !ok {
+		return
+	}
+
+	data := r.FormValue("csv")
+	csv, err := services.ReadTransactionCSV(strings.NewReader(data))
+	if err != nil {
+		h.renderImport(w, user, map[string]any{"Error": "CSV import failed: " + err.Error()})
+		return
+	}
+
+	mapping := services.TransactionCSVMapping{
+		Date:        formColumn(r, "date"),
+		Amount:      formColumn(r, "amount"),
+		Description: formColumn(r, "description"),
+	}
+	statement, rowErrors, err := csv.Statement(mapping)
+	if err != nil {
+		h.renderMapping(w, user, account, data, csv, mapping, map[string]any{"Error": err.Error()})
+		return
+	}
+	if len(rowErrors) > 0 {
+		h.renderMapping(w, user, account, data, csv, mapping, map[string]any{
+			"Error":     fmt.Sprintf("%d rows could not be read with these columns; nothing was imported", len(rowErrors)),
+			"RowErrors": rowErrors,
+		})
+		return
+	}
+
+	h.importStatement(w, r, user, account, statement, func(msg string) {
+		h.renderMapping(w, user, account, data, csv, mapping, map[string]any{"Error": msg})
+	})
+}
+
+// importStatement records the entries of an uploaded statement and redirects
+// to the account's transactions. Failures are shown by fail, or on the
+// upload page if fail is nil.
+func (h *ToolsHandler) importStatement(w http.ResponseWriter, r *http.Request, user *models.User, account *models.Account, statement *services.BankStatement, fail func(string)) {
+	if fail == nil {
+		fail = func(msg string) { h.renderImport(w, user, map[string]any{"Error": msg}) }
+	}
+	if len(statement.Entries) == 0 {
+		fail("The file contains no transactions")
+		return
+	}
+
+	importer := services.NewBankStatementImporter(h.transactionRepo)
+	importer.SetCategorizer(h.categorizer)
+	result, err := importer.Import(account, statement)
+	if err != nil {
+		log.Printf("Error importing transactions into account %d: %v", account.ID, err)
+		fail("Import failed: " + err.Error())
+		return
+	}
+
+	log.Printf("Imported %d transactions into account %d for user %d, skipped %d duplicates",
+		result.Imported, account.ID, user.ID, result.Duplicates)
+	http.Redirect(w, r, fmt.Sprintf("/transactions?account=%d&imported=%d&duplicates=%d", account.ID, result.Imported, result.Duplicates), http.StatusSeeOther)
+}
+
+// importAccount returns the user's account to import into. Otherwise it
+// responds with an error and returns false.
+func (h *ToolsHandler) importAccount(w http.ResponseWriter, idStr string, userID int64) (*models.Account, bool) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	account, err := h.accountRepo.GetByID(id)
+	if err != nil || account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return nil, false
+	}
+	if account.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return account, true
+}
+
+// formColumn returns the column index chosen in a form field, or -1 if none
+// was chosen.
+func formColumn(r *http.Request, name string) int {
+	col, err := strconv.Atoi(r.FormValue(name))
+	if err != nil {
+		return -1
+	}
+	return col
+}
+
+// renderImport renders the upload step of the import page.
+func (h *ToolsHandler) renderImport(w http.ResponseWriter, user *models.User, extra map[string]any) {
+	accounts, err := h.accountRepo.GetByUserIDActiveOnly(user.ID)
+	if err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		http.Error(w, "Error loading accounts", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]any{
+		"Title":     "Import Transactions",
+		"User":      user,
+		"ActiveNav": "tools",
+		"Accounts":  accounts,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.render(w, "transaction-import.html", data)
+}
+
+// renderMapping renders the column mapping step of the import page for an
+// uploaded CSV, with a preview of its first rows.
+func (h *ToolsHandler) renderMapping(w http.ResponseWriter, user *models.User, account *models.Account, content string, csv *services.TransactionCSV, mapping services.TransactionCSVMapping, extra map[string]any) {
+	data := map[string]any{
+		"Account": account,
+		"CSV":     content,
+		"Header":  csv.Header,
+		"Preview": csv.Rows[:min(len(csv.Rows), transactionImportPreviewRows)],
+		"Rows":    len(csv.Rows),
+		"Mapping": mapping,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	h.renderImport(w, user, data)
+}
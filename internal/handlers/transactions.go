@@ -4,15 +4,18 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"wealth_tracker/internal/dates"
 	"wealth_tracker/internal/middleware"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // TransactionHandler handles transaction routes.
@@ -21,6 +24,9 @@ type TransactionHandler struct {
 	transactionRepo *repository.TransactionRepository
 	accountRepo     *repository.AccountRepository
 	categoryRepo    *repository.CategoryRepository
+	balanceChecker  *services.BalanceChecker
+	savedFilterRepo *repository.SavedFilterRepository
+	currencyService *services.CurrencyService // Converts the cash flow of foreign currency accounts; nil leaves it unconverted
 }
 
 // NewTransactionHandler creates a new TransactionHandler.
@@ -29,15 +35,50 @@ func NewTransactionHandler(
 	transactionRepo *repository.TransactionRepository,
 	accountRepo *repository.AccountRepository,
 	categoryRepo *repository.CategoryRepository,
+	balanceChecker *services.BalanceChecker,
 ) *TransactionHandler {
 	return &TransactionHandler{
 		templates:       templates,
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
 		categoryRepo:    categoryRepo,
+		balanceChecker:  balanceChecker,
 	}
 }
 
+// SetCurrencyService sets the service that converts the cash flow of
+// accounts in other currencies into the user's currency.
+func (h *TransactionHandler) SetCurrencyService(currencyService *services.CurrencyService) {
+	h.currencyService = currencyService
+}
+
+// cashFlowByCategory converts the cash flow per category and currency into
+// the user's currency and sums it per category.
+func (h *TransactionHandler) cashFlowByCategory(user *models.User, flows []repository.CategoryCashFlow) []repository.CategoryCashFlow {
+	merged := make([]repository.CategoryCashFlow, 0, len(flows))
+	index := make(map[int64]int)
+	for _, flow := range flows {
+		if h.currencyService != nil && flow.Currency != user.DefaultCurrency {
+			flow.Inflow, _ = h.currencyService.ConvertForUser(user.ID, flow.Inflow, flow.Currency, user.DefaultCurrency)
+			flow.Outflow, _ = h.currencyService.ConvertForUser(user.ID, flow.Outflow, flow.Currency, user.DefaultCurrency)
+		}
+		flow.Currency = user.DefaultCurrency
+
+		key := int64(0) // Uncategorized
+		if flow.CategoryID != nil {
+			key = *flow.CategoryID
+		}
+		if i, ok := index[key]; ok {
+			merged[i].Inflow += flow.Inflow
+			merged[i].Outflow += flow.Outflow
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, flow)
+	}
+	return merged
+}
+
 // List renders the transactions list page.
 func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
@@ -54,26 +95,26 @@ func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
 	limit := 20
 	offset := (page - 1) * limit
 
-	// Get account filter
-	accountIDStr := r.URL.Query().Get("account")
-	var accountID int64
-	if accountIDStr != "" {
-		accountID, _ = strconv.ParseInt(accountIDStr, 10, 64)
-	}
-
-	var transactions []*models.Transaction
-	var err error
-
-	if accountID > 0 {
-		// Verify account belongs to user
-		account, _ := h.accountRepo.GetByID(accountID)
-		if account != nil && account.UserID == user.ID {
-			transactions, err = h.transactionRepo.GetByAccountID(accountID, limit, offset)
+	// Criteria from the query, or those of the saved filter it names
+	query := r.URL.Query()
+	filter := parseTransactionFilter(query)
+	filterQuery := transactionFilterValues(filter)
+	var activeFilter *models.SavedFilter
+	if v := query.Get("filter"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid view ID", http.StatusBadRequest)
+			return
+		}
+		var ok bool
+		if activeFilter, ok = h.ownedFilter(w, id, user.ID); !ok {
+			return
 		}
-	} else {
-		transactions, err = h.transactionRepo.GetByUserID(user.ID, limit, offset)
+		filter = activeFilter.TransactionFilter
+		filterQuery = url.Values{"filter": {v}}
 	}
 
+	transactions, err := h.transactionRepo.GetByUserIDFiltered(user.ID, filter, limit, offset)
 	if err != nil {
 		log.Printf("Error fetching transactions: %v", err)
 		http.Error(w, "Error loading transactions", http.StatusInternalServerError)
@@ -87,30 +128,73 @@ func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) {
 		accountMap[acc.ID] = acc
 	}
 
-	// Build transactions with account info
-	type TransactionWithAccount struct {
-		*models.Transaction
-		Account *models.Account
+	categories, _ := h.categoryRepo.GetByUserID(user.ID)
+	categoryMap := make(map[int64]*models.Category)
+	for _, cat := range categories {
+		categoryMap[cat.ID] = cat
 	}
-	txnsWithAccount := make([]TransactionWithAccount, len(transactions))
+
+	// Build transactions with account info
+	txnsWithAccount := make([]transactionRow, len(transactions))
 	for i, txn := range transactions {
-		txnsWithAccount[i] = TransactionWithAccount{
-			Transaction: txn,
-			Account:     accountMap[txn.AccountID],
-		}
+		txnsWithAccount[i] = newTransactionRow(user, txn, accountMap, categoryMap)
 	}
 
-	h.render(w, "transactions.html", map[string]any{
-		"Title":           "Transactions",
-		"User":            user,
-		"ActiveNav":       "transactions",
-		"Transactions":    txnsWithAccount,
-		"Accounts":        accounts,
-		"SelectedAccount": accountID,
-		"Page":            page,
-		"HasMore":         len(transactions) == limit,
-		"DemoMode":        IsDemoMode(),
-	})
+	tags, err := h.transactionRepo.GetTagsByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching tags: %v", err)
+	}
+	savedFilters, err := h.savedFilterRepo.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("Error fetching saved filters: %v", err)
+	}
+
+	// Cash flow per category for the current statement period
+	periodStart := dates.PeriodStart(time.Now(), user.PeriodStartDay)
+	cashFlow, err := h.transactionRepo.GetCashFlowByCategory(user.ID, periodStart, dates.PeriodEnd(periodStart))
+	if err != nil {
+		log.Printf("Error fetching cash flow: %v", err)
+	}
+	cashFlow = h.cashFlowByCategory(user, cashFlow)
+
+	var selectedAccount, selectedCategory int64
+	if filter.AccountID != nil {
+		selectedAccount = *filter.AccountID
+	}
+	if filter.CategoryID != nil {
+		selectedCategory = *filter.CategoryID
+	}
+
+	data := map[string]any{
+		"Title":            "Transactions",
+		"User":             user,
+		"ActiveNav":        "transactions",
+		"Transactions":     txnsWithAccount,
+		"Accounts":         accounts,
+		"Categories":       categories,
+		"CashFlow":         cashFlow,
+		"CashFlowStart":    periodStart,
+		"CashFlowEnd":      dates.PeriodEnd(periodStart),
+		"Tags":             tags,
+		"SavedFilters":     savedFilters,
+		"SelectedAccount":  selectedAccount,
+		"SelectedCategory": selectedCategory,
+		"Filter":           transactionFilterValues(filter),
+		"FilterQuery":      template.URL(filterQuery.Encode()),
+		"Filtered":         !filter.IsEmpty(),
+		"ActiveFilter":     activeFilter,
+		"Page":             page,
+		"HasMore":          len(transactions) == limit,
+		"DemoMode":         IsDemoMode(),
+	}
+
+	// Outcome of the bank statement import this page was redirected from
+	if imported, err := strconv.Atoi(r.URL.Query().Get("imported")); err == nil {
+		duplicates, _ := strconv.Atoi(r.URL.Query().Get("duplicates"))
+		data["StatementImport"] = &services.BankStatementImportResult{Imported: imported, Duplicates: duplicates}
+	}
+
+	h.render(w, "transactions.html", data)
 }
 
 // Create handles creating a new transaction.
@@ -155,6 +239,12 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	categoryID, ok := h.formCategoryID(r, user.ID)
+	if !ok {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+
 	// Parse date
 	transactionDate, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
@@ -171,11 +261,16 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		newBalance = currentBalance + amount
 	}
 
+	if confirmBalanceAnomaly(w, r, h.render, h.balanceChecker, user, account, newBalance, "/transactions") {
+		return
+	}
+
 	txn := &models.Transaction{
 		AccountID:       accountID,
 		Amount:          amount,
 		BalanceAfter:    newBalance,
 		Description:     description,
+		CategoryID:      categoryID,
 		TransactionDate: transactionDate,
 	}
 
@@ -240,6 +335,12 @@ func (h *TransactionHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	categoryID, ok := h.formCategoryID(r, user.ID)
+	if !ok {
+		http.Error(w, "Invalid category", http.StatusBadRequest)
+		return
+	}
+
 	// Parse date
 	transactionDate, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
@@ -253,6 +354,7 @@ func (h *TransactionHandler) Update(w http.ResponseWriter, r *http.Request) {
 	existing.Amount = amount
 	existing.BalanceAfter = newBalance
 	existing.Description = description
+	existing.CategoryID = categoryID
 	existing.TransactionDate = transactionDate
 
 	err = h.transactionRepo.Update(existing)
@@ -304,6 +406,26 @@ func (h *TransactionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/transactions", http.StatusSeeOther)
 }
 
+// formCategoryID parses the optional category_id form value. An empty value
+// means the transaction uses its account's category. Returns false if the
+// category is invalid or belongs to another user.
+func (h *TransactionHandler) formCategoryID(r *http.Request, userID int64) (*int64, bool) {
+	categoryIDStr := r.FormValue("category_id")
+	if categoryIDStr == "" || categoryIDStr == "0" {
+		return nil, true
+	}
+
+	categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	category, err := h.categoryRepo.GetByID(categoryID)
+	if err != nil || category == nil || category.UserID != userID {
+		return nil, false
+	}
+	return &categoryID, true
+}
+
 // render renders a template with the given data.
 func (h *TransactionHandler) render(w http.ResponseWriter, name string, data map[string]any) {
 	if data == nil {
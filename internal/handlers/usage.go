@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"wealth_tracker/internal/middleware"
+	"wealth_tracker/internal/services"
+)
+
+// UsageHandler shows users their usage against the instance's monthly
+// quotas.
+type UsageHandler struct {
+	templates map[string]*template.Template
+	usage     *services.UsageService
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(templates map[string]*template.Template, usage *services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		templates: templates,
+		usage:     usage,
+	}
+}
+
+// Usage renders the usage page.
+func (h *UsageHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	month, err := h.usage.Month(user.ID)
+	if err != nil {
+		log.Printf("Error fetching usage: %v", err)
+		http.Error(w, "Error loading usage", http.StatusInternalServerError)
+		return
+	}
+
+	h.render(w, "usage.html", map[string]any{
+		"Title":         "Usage",
+		"User":          user,
+		"ActiveNav":     "settings",
+		"Month":         month,
+		"IncludeCharts": true,
+	})
+}
+
+// render renders a template with the given data.
+func (h *UsageHandler) render(w http.ResponseWriter, name string, data map[string]any) {
+	tmpl, ok := h.templates[name]
+	if !ok {
+		http.Error(w, "Template not found: "+name, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+		log.Printf("Error rendering template %s: %v", name, err)
+		http.Error(w, "Error rendering page", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,131 @@
+// Package logfile writes logs to a file that is rotated daily, keeping the
+// rotated files for a retention period.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayout is the date suffix of rotated files, such as access.log.2024-06-01.
+const dateLayout = "2006-01-02"
+
+// File is an io.Writer appending to a log file. On the first write of a
+// new day the file is renamed with the previous day's date as suffix, and
+// rotated files older than the retention period are deleted.
+type File struct {
+	path      string
+	retention time.Duration // 0 keeps rotated files forever
+	now       func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	day  string // Date the open file holds lines of
+}
+
+// Open opens the log file at path for appending, creating it and its
+// directory if needed. Rotated files are kept for retentionDays; 0 keeps
+// them forever.
+func Open(path string, retentionDays int) (*File, error) {
+	f := &File{path: path, retention: time.Duration(retentionDays) * 24 * time.Hour, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	// A file left from an earlier day is rotated on the first write
+	if info, err := f.file.Stat(); err == nil && info.Size() > 0 {
+		f.day = info.ModTime().Format(dateLayout)
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating it first if the day has changed.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	today := f.now().Format(dateLayout)
+	if f.day != "" && f.day != today {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	f.day = today
+	return f.file.Write(p)
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// open opens the log file for appending.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	f.file = file
+	return nil
+}
+
+// rotate renames the file with the date of the lines it holds, starts a new
+// one and deletes rotated files past the retention period.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("closing log file: %w", err)
+	}
+	rotated := f.path + "." + f.day
+	if _, err := os.Stat(rotated); err == nil {
+		// Several rotations in a day, such as after the clock was turned back
+		rotated += "." + f.now().Format("150405")
+	}
+	if err := os.Rename(f.path, rotated); err != nil {
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.prune()
+	return nil
+}
+
+// prune deletes rotated files whose date is past the retention period.
+func (f *File) prune() {
+	if f.retention <= 0 {
+		return
+	}
+	cutoff := f.now().Add(-f.retention).Format(dateLayout)
+	for _, path := range f.Rotated() {
+		suffix := strings.TrimPrefix(path, f.path+".")
+		if len(suffix) >= len(dateLayout) && suffix[:len(dateLayout)] < cutoff {
+			os.Remove(path)
+		}
+	}
+}
+
+// Rotated returns the paths of the rotated files, oldest first.
+func (f *File) Rotated() []string {
+	matches, _ := filepath.Glob(f.path + ".*")
+	var rotated []string
+	for _, path := range matches {
+		suffix := strings.TrimPrefix(path, f.path+".")
+		if len(suffix) < len(dateLayout) {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, suffix[:len(dateLayout)]); err == nil {
+			rotated = append(rotated, path)
+		}
+	}
+	sort.Strings(rotated)
+	return rotated
+}
@@ -0,0 +1,64 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile_RotatesDailyAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "access.log")
+	f, err := Open(path, 2)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	// A rotated file from long ago is pruned on the next rotation
+	old := path + ".2024-05-01"
+	if err := os.WriteFile(old, []byte("old\n"), 0640); err != nil {
+		t.Fatalf("writing old file: %v", err)
+	}
+
+	day := time.Date(2024, 6, 1, 23, 0, 0, 0, time.Local)
+	f.now = func() time.Time { return day }
+	f.Write([]byte("first\n"))
+	f.Write([]byte("second\n"))
+
+	day = day.Add(2 * time.Hour)
+	f.Write([]byte("third\n"))
+
+	if data, _ := os.ReadFile(path + ".2024-06-01"); string(data) != "first\nsecond\n" {
+		t.Errorf("rotated file = %q; want the first day's lines", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third\n" {
+		t.Errorf("log file = %q; want the new day's line", data)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("rotated file past the retention period was kept")
+	}
+	if rotated := f.Rotated(); len(rotated) != 1 {
+		t.Errorf("Rotated = %v; want the first day's file", rotated)
+	}
+}
+
+func TestFile_KeepsRotatedFilesWithoutRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	f, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	day := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		f.now = func() time.Time { return day }
+		f.Write([]byte("line\n"))
+		day = day.AddDate(0, 0, 30)
+	}
+	if rotated := f.Rotated(); len(rotated) != 2 {
+		t.Errorf("Rotated = %v; want every earlier day kept", rotated)
+	}
+}
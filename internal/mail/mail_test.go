@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC)
+	data, err := buildMessage("tracker@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Din ugentlige oversigt",
+		Body:    "Net worth: 100\nChange: +5\n",
+	}, date)
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+
+	got := string(data)
+	for _, want := range []string{
+		"From: tracker@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Din ugentlige oversigt\r\n",
+		"Date: Mon, 04 Mar 2024 07:00:00 +0000\r\n",
+		"\r\n\r\nNet worth: 100\r\nChange: +5\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message does not contain %q:\n%s", want, got)
+		}
+	}
+
+	if _, err := buildMessage("tracker@example.com", Message{To: "user@example.com\r\nBcc: other@example.com"}, date); err == nil {
+		t.Error("buildMessage() accepted a recipient with a line break")
+	}
+}
+
+func TestBuildMessage_EncodesNonASCIISubject(t *testing.T) {
+	data, err := buildMessage("a@example.com", Message{To: "b@example.com", Subject: "Formue øget"}, time.Now())
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+	if !strings.Contains(string(data), "Subject: =?utf-8?q?Formue_=C3=B8get?=\r\n") {
+		t.Errorf("subject not encoded:\n%s", data)
+	}
+}
@@ -0,0 +1,199 @@
+// Package markdown renders the Markdown of notes and descriptions to HTML.
+//
+// Only a subset of Markdown is supported: headings, paragraphs, lists, block
+// quotes, code, rules, emphasis and links. All text is escaped before markup
+// is added, so raw HTML in the source is shown as text, and links may only
+// point to http, https and mailto addresses or to pages of the app.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	rulePattern      = regexp.MustCompile(`^([-*_])(\s*([-*_]))*$`)
+	unorderedPattern = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedPattern   = regexp.MustCompile(`^\d{1,9}[.)]\s+(.*)$`)
+	linkPattern      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)|https?://[^\s<>"]*[^\s<>".,;:!?)\]']`)
+
+	// Emphasis is applied to escaped text, which contains no markup yet
+	strongPattern = regexp.MustCompile(`\*\*([^*\s](?:[^*]*[^*\s])?)\*\*|__([^_\s](?:[^_]*[^_\s])?)__`)
+	emPattern     = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*|\b_([^_\s](?:[^_]*[^_\s])?)_\b`)
+	strikePattern = regexp.MustCompile(`~~([^~\s](?:[^~]*[^~\s])?)~~`)
+)
+
+// Render returns the HTML of Markdown source. Single line breaks within a
+// paragraph are kept, as notes are usually written line by line.
+func Render(src string) template.HTML {
+	return template.HTML(renderBlocks(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")))
+}
+
+// Excerpt returns the first line of Markdown source as plain text, for
+// showing a note collapsed.
+func Excerpt(src string) string {
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "```") || rulePattern.MatchString(line) {
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			line = m[2]
+		} else if m := unorderedPattern.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "> "))
+		return strings.NewReplacer("**", "", "__", "", "~~", "", "`", "").Replace(line)
+	}
+	return ""
+}
+
+// renderBlocks renders lines as block elements.
+func renderBlocks(lines []string) string {
+	var b strings.Builder
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			i++
+
+		case strings.HasPrefix(line, "```"):
+			// Fenced code runs to the closing fence, or the end
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			// Notes are shown within pages, so their headings start at h3
+			tag := "h" + string(rune('0'+min(len(m[1])+2, 6)))
+			b.WriteString("<" + tag + ">" + renderInline(m[2]) + "</" + tag + ">\n")
+			i++
+
+		case len(line) >= 3 && rulePattern.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(line, ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			b.WriteString("<blockquote>\n" + renderBlocks(quoted) + "</blockquote>\n")
+
+		case unorderedPattern.MatchString(line):
+			i = renderList(&b, lines, i, "ul", unorderedPattern)
+
+		case orderedPattern.MatchString(line):
+			i = renderList(&b, lines, i, "ol", orderedPattern)
+
+		default:
+			// A paragraph runs to a blank line or the start of another block
+			var paragraph []string
+			for ; i < len(lines); i++ {
+				l := strings.TrimSpace(lines[i])
+				if l == "" || (len(paragraph) > 0 && startsBlock(l)) {
+					break
+				}
+				paragraph = append(paragraph, renderInline(l))
+			}
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+		}
+	}
+	return b.String()
+}
+
+// startsBlock reports whether a line starts a block other than a paragraph.
+func startsBlock(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, ">") ||
+		headingPattern.MatchString(line) || (len(line) >= 3 && rulePattern.MatchString(line)) ||
+		unorderedPattern.MatchString(line) || orderedPattern.MatchString(line)
+}
+
+// renderList renders the consecutive items of a list starting at line i and
+// returns the line after it.
+func renderList(b *strings.Builder, lines []string, i int, tag string, item *regexp.Regexp) int {
+	b.WriteString("<" + tag + ">\n")
+	for ; i < len(lines); i++ {
+		m := item.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if m == nil {
+			break
+		}
+		b.WriteString("<li>" + renderInline(m[1]) + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderInline renders code spans, links and emphasis within a line.
+func renderInline(text string) string {
+	var b strings.Builder
+	// Backticks delimit code spans; an unmatched backtick is shown as is
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			b.WriteString(renderLinks(part))
+		case i == len(parts)-1:
+			b.WriteString("`" + renderLinks(part))
+		default:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		}
+	}
+	return b.String()
+}
+
+// renderLinks renders the links of text, and the emphasis around them.
+func renderLinks(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(renderEmphasis(text[last:m[0]]))
+		last = m[1]
+
+		label, href := text[m[0]:m[1]], text[m[0]:m[1]]
+		if m[2] >= 0 {
+			label, href = text[m[2]:m[3]], text[m[4]:m[5]]
+		}
+		if !safeURL(href) {
+			b.WriteString(renderEmphasis(label))
+			continue
+		}
+		b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">` + renderEmphasis(label) + "</a>")
+	}
+	b.WriteString(renderEmphasis(text[last:]))
+	return b.String()
+}
+
+// renderEmphasis escapes text and renders its bold, italic and struck
+// through spans.
+func renderEmphasis(text string) string {
+	s := html.EscapeString(text)
+	s = strongPattern.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = emPattern.ReplaceAllString(s, "<em>$1$2</em>")
+	return strikePattern.ReplaceAllString(s, "<del>$1</del>")
+}
+
+// safeURL reports whether a link may be followed: web and mail addresses,
+// and paths of the app.
+func safeURL(href string) bool {
+	if strings.HasPrefix(href, "/") {
+		return !strings.HasPrefix(href, "//") && !strings.HasPrefix(href, "/\\")
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
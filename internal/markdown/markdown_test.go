@@ -0,0 +1,64 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"paragraph", "Line one\nline two", "<p>Line one<br>\nline two</p>\n"},
+		{"heading", "# Terms", "<h3>Terms</h3>\n"},
+		{"list", "- a\n- **b**", "<ul>\n<li>a</li>\n<li><strong>b</strong></li>\n</ul>\n"},
+		{"ordered", "1. first\n2. second", "<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"},
+		{"quote", "> rate *3%*", "<blockquote>\n<p>rate <em>3%</em></p>\n</blockquote>\n"},
+		{"code", "```\n<b>\n```", "<pre><code>&lt;b&gt;</code></pre>\n"},
+		{"code span", "use `a*b*`", "<p>use <code>a*b*</code></p>\n"},
+		{"rule", "---", "<hr>\n"},
+		{"strike", "~~old~~ new", "<p><del>old</del> new</p>\n"},
+		{"snake case", "loan_rate_fixed", "<p>loan_rate_fixed</p>\n"},
+		{"link", "[Terms](https://example.com/a?b=1&c=2)",
+			`<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer" target="_blank">Terms</a></p>` + "\n"},
+		{"bare link", "See https://example.com.", `<p>See <a href="https://example.com" rel="nofollow noopener noreferrer" target="_blank">https://example.com</a>.</p>` + "\n"},
+		{"app link", "[Goal](/goals/1)", `<p><a href="/goals/1" rel="nofollow noopener noreferrer" target="_blank">Goal</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Render(tt.src)); got != tt.want {
+			t.Errorf("%s: Render(%q) = %q; want %q", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRender_EscapesUnsafeInput(t *testing.T) {
+	tests := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`[click](javascript:alert(1))`,
+		`[click](//evil.example)`,
+		`[x](https://example.com/"onmouseover="alert(1))`,
+	}
+	for _, src := range tests {
+		got := string(Render(src))
+		for _, bad := range []string{"<script", "<img", "javascript:", `href="//`, `"onmouseover`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q) = %q; contains %q", src, got, bad)
+			}
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"\n## **Terms**\nbody":   "Terms",
+		"- first item\n- second": "first item",
+		"> quoted":               "quoted",
+	}
+	for src, want := range tests {
+		if got := Excerpt(src); got != want {
+			t.Errorf("Excerpt(%q) = %q; want %q", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package market provides the trading calendars of the Nordic exchanges.
+package market
+
+import "time"
+
+// holidayRules are the days the exchange of a country is closed on besides
+// weekends, by connection country. Each rule returns whether a day of a year
+// is a holiday, given that year's Easter Sunday.
+var holidayRules = map[string][]func(day, easter time.Time) bool{
+	// Nasdaq Copenhagen
+	"dk": {
+		fixed(time.January, 1),
+		easterOffset(-3), // Maundy Thursday
+		easterOffset(-2), // Good Friday
+		easterOffset(1),  // Easter Monday
+		func(day, easter time.Time) bool { // Great Prayer Day, abolished from 2024
+			return day.Year() < 2024 && sameDay(day, easter.AddDate(0, 0, 26))
+		},
+		easterOffset(39), // Ascension Day
+		easterOffset(40), // Day after Ascension Day
+		easterOffset(50), // Whit Monday
+		fixed(time.June, 5),
+		fixed(time.December, 24),
+		fixed(time.December, 25),
+		fixed(time.December, 26),
+		fixed(time.December, 31),
+	},
+	// Nasdaq Stockholm
+	"se": {
+		fixed(time.January, 1),
+		fixed(time.January, 6),
+		easterOffset(-2),
+		easterOffset(1),
+		fixed(time.May, 1),
+		easterOffset(39),
+		fixed(time.June, 6),
+		midsummerEve,
+		fixed(time.December, 24),
+		fixed(time.December, 25),
+		fixed(time.December, 26),
+		fixed(time.December, 31),
+	},
+	// Oslo Børs
+	"no": {
+		fixed(time.January, 1),
+		easterOffset(-3),
+		easterOffset(-2),
+		easterOffset(1),
+		fixed(time.May, 1),
+		fixed(time.May, 17),
+		easterOffset(39),
+		easterOffset(50),
+		fixed(time.December, 24),
+		fixed(time.December, 25),
+		fixed(time.December, 26),
+		fixed(time.December, 31),
+	},
+	// Nasdaq Helsinki
+	"fi": {
+		fixed(time.January, 1),
+		fixed(time.January, 6),
+		easterOffset(-2),
+		easterOffset(1),
+		fixed(time.May, 1),
+		easterOffset(39),
+		midsummerEve,
+		fixed(time.December, 6),
+		fixed(time.December, 24),
+		fixed(time.December, 25),
+		fixed(time.December, 26),
+		fixed(time.December, 31),
+	},
+}
+
+// IsHoliday reports whether the exchange of a country is closed on a
+// weekday for a holiday. Only the date of day counts, so it should be in the
+// market's time zone. Unknown countries use the Danish calendar.
+func IsHoliday(country string, day time.Time) bool {
+	rules, ok := holidayRules[country]
+	if !ok {
+		rules = holidayRules["dk"]
+	}
+	easter := Easter(day.Year())
+	for _, rule := range rules {
+		if rule(day, easter) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTradingDay reports whether the exchange of a country is open on a day:
+// a weekday that is not a holiday.
+func IsTradingDay(country string, day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !IsHoliday(country, day)
+}
+
+// LastTradingDay returns the start of the latest trading day on or before
+// day, in day's time zone, such as the Thursday before Easter for Easter
+// Monday in Sweden.
+func LastTradingDay(country string, day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	// No exchange is closed for more than a couple of weeks in a row
+	for range 14 {
+		if IsTradingDay(country, day) {
+			return day
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// Easter returns Easter Sunday of a year in the Gregorian calendar, at
+// midnight UTC.
+func Easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// fixed returns a rule for a holiday on the same date every year.
+func fixed(month time.Month, dayOfMonth int) func(day, easter time.Time) bool {
+	return func(day, _ time.Time) bool {
+		return day.Month() == month && day.Day() == dayOfMonth
+	}
+}
+
+// easterOffset returns a rule for a holiday a number of days from Easter
+// Sunday.
+func easterOffset(days int) func(day, easter time.Time) bool {
+	return func(day, easter time.Time) bool {
+		return sameDay(day, easter.AddDate(0, 0, days))
+	}
+}
+
+// midsummerEve is the Friday between 19 and 25 June.
+func midsummerEve(day, _ time.Time) bool {
+	return day.Month() == time.June && day.Day() >= 19 && day.Day() <= 25 && day.Weekday() == time.Friday
+}
+
+// sameDay reports whether two times have the same date, each in its own
+// time zone.
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
@@ -0,0 +1,79 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestEaster(t *testing.T) {
+	for year, want := range map[int]time.Time{
+		2019: date(2019, time.April, 21),
+		2024: date(2024, time.March, 31),
+		2025: date(2025, time.April, 20),
+		2038: date(2038, time.April, 25),
+	} {
+		if got := Easter(year); !sameDay(got, want) {
+			t.Errorf("Easter(%d) = %v; want %v", year, got.Format("2006-01-02"), want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		day     time.Time
+		want    bool
+	}{
+		{"ordinary weekday", "dk", date(2024, time.May, 15), true},
+		{"saturday", "dk", date(2024, time.May, 18), false},
+		{"maundy thursday in Denmark", "dk", date(2024, time.March, 28), false},
+		{"maundy thursday in Sweden", "se", date(2024, time.March, 28), true},
+		{"easter monday", "fi", date(2024, time.April, 1), false},
+		{"day after ascension in Denmark", "dk", date(2024, time.May, 10), false},
+		{"great prayer day before 2024", "dk", date(2023, time.May, 5), false},
+		{"great prayer day abolished", "dk", date(2024, time.April, 26), true},
+		{"constitution day", "dk", date(2024, time.June, 5), false},
+		{"swedish national day", "se", date(2024, time.June, 6), false},
+		{"midsummer eve", "se", date(2024, time.June, 21), false},
+		{"norwegian constitution day", "no", date(2024, time.May, 17), false},
+		{"finnish independence day", "fi", date(2024, time.December, 6), false},
+		{"christmas eve", "no", date(2024, time.December, 24), false},
+		{"unknown country uses Denmark", "de", date(2024, time.June, 5), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTradingDay(tt.country, tt.day); got != tt.want {
+				t.Errorf("IsTradingDay(%q, %v) = %v; want %v", tt.country, tt.day.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastTradingDay(t *testing.T) {
+	cph, err := time.LoadLocation("Europe/Copenhagen")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	tests := []struct {
+		name string
+		day  time.Time
+		want time.Time
+	}{
+		{"trading day itself", time.Date(2024, time.May, 15, 21, 0, 0, 0, cph), time.Date(2024, time.May, 15, 0, 0, 0, 0, cph)},
+		{"weekend", time.Date(2024, time.May, 19, 9, 0, 0, 0, cph), time.Date(2024, time.May, 17, 0, 0, 0, 0, cph)},
+		{"easter monday", time.Date(2024, time.April, 1, 9, 0, 0, 0, cph), time.Date(2024, time.March, 27, 0, 0, 0, 0, cph)},
+		{"christmas", time.Date(2024, time.December, 26, 9, 0, 0, 0, cph), time.Date(2024, time.December, 23, 0, 0, 0, 0, cph)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastTradingDay("dk", tt.day); !got.Equal(tt.want) {
+				t.Errorf("LastTradingDay() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		addr, mode, want string
+	}{
+		{"203.0.113.77:5123", IPFull, "203.0.113.77"},
+		{"203.0.113.77:5123", IPTruncate, "203.0.113.0"},
+		{"[2001:db8:abcd:12:1:2:3:4]:443", IPTruncate, "2001:db8:abcd::"},
+		{"203.0.113.77", IPTruncate, "203.0.113.0"},
+		{"203.0.113.77:5123", "bogus", "203.0.113.0"},
+		{"203.0.113.77:5123", IPOff, ""},
+		{"not-an-ip", IPTruncate, "-"},
+	}
+	for _, tt := range tests {
+		if got := AnonymizeIP(tt.addr, tt.mode, ""); got != tt.want {
+			t.Errorf("AnonymizeIP(%q, %q) = %q, want %q", tt.addr, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestAnonymizeIP_Hash(t *testing.T) {
+	a := AnonymizeIP("203.0.113.77:1", IPHash, "secret")
+	if a != AnonymizeIP("203.0.113.77:2", IPHash, "secret") {
+		t.Error("same IP hashed differently")
+	}
+	if a == AnonymizeIP("203.0.113.78:1", IPHash, "secret") {
+		t.Error("different IPs hashed the same")
+	}
+	if a == AnonymizeIP("203.0.113.77:1", IPHash, "other") {
+		t.Error("hash does not depend on the key")
+	}
+	if strings.Contains(a, "203.0.113") {
+		t.Errorf("hash %q contains the IP", a)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	mw := AccessLog(log.New(&buf, "", 0), AccessLogConfig{IPMode: IPTruncate, RedactParams: []string{"email"}})
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short"))
+	}))
+
+	req := httptest.NewRequest("GET", "/callback?code=abc123&Email=me@example.com&page=2", nil)
+	req.RemoteAddr = "198.51.100.23:40000"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	for _, leaked := range []string{"abc123", "me@example.com", "198.51.100.23"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("log line leaks %q: %s", leaked, line)
+		}
+	}
+	for _, want := range []string{`"GET http://example.com/callback?`, "code=REDACTED", "page=2", "from 198.51.100.0", " - 418 5B in "} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line missing %q: %s", want, line)
+		}
+	}
+}
+
+func TestAccessLog_RedactsLoginLinkTokens(t *testing.T) {
+	var buf bytes.Buffer
+	handler := AccessLog(log.New(&buf, "", 0), AccessLogConfig{IPMode: IPOff})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, target := range []string{"/login/link/s3cr3t-t0ken", "/login/link/s3cr3t-t0ken?next=%2F"} {
+		buf.Reset()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+
+		line := buf.String()
+		if strings.Contains(line, "s3cr3t-t0ken") {
+			t.Errorf("log line leaks the login link token: %s", line)
+		}
+		if !strings.Contains(line, "/login/link/REDACTED") {
+			t.Errorf("log line missing the redacted path: %s", line)
+		}
+	}
+}
+
+func TestRedactPath(t *testing.T) {
+	tests := map[string]string{
+		"/login/link/abc":      "/login/link/REDACTED",
+		"/login/link/abc/next": "/login/link/REDACTED/next",
+		"/login/link/":         "/login/link/",
+		"/login":               "/login",
+		"/accounts/12":         "/accounts/12",
+	}
+	for path, want := range tests {
+		if got := redactPath(path); got != want {
+			t.Errorf("redactPath(%q) = %q; want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"wealth_tracker/internal/auth"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// AccountAPIKeyContextKey is the context key for the authenticated
+// account-scoped API key.
+const AccountAPIKeyContextKey ContextKey = "account_api_key"
+
+// AccountAPIKeyAuth authenticates automation requests by an account-scoped
+// API key in the Authorization header.
+type AccountAPIKeyAuth struct {
+	keyRepo *repository.AccountAPIKeyRepository
+}
+
+// NewAccountAPIKeyAuth creates a new AccountAPIKeyAuth.
+func NewAccountAPIKeyAuth(keyRepo *repository.AccountAPIKeyRepository) *AccountAPIKeyAuth {
+	return &AccountAPIKeyAuth{keyRepo: keyRepo}
+}
+
+// RequireKey is middleware that requires a valid "Authorization: Bearer"
+// API key. Returns 401 Unauthorized otherwise. Session cookies are not
+// accepted, so these routes cannot be reached from a browser session.
+func (m *AccountAPIKeyAuth) RequireKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		token = strings.TrimSpace(token)
+		if !ok || !strings.HasPrefix(token, auth.APIKeyPrefix) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := m.keyRepo.GetByHash(auth.HashAPIKey(token))
+		if err != nil || key == nil {
+			if err != nil {
+				log.Printf("Error looking up API key: %v", err)
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := m.keyRepo.TouchLastUsed(key.ID); err != nil {
+			log.Printf("Error recording use of API key %d: %v", key.ID, err)
+		}
+
+		ctx := context.WithValue(r.Context(), AccountAPIKeyContextKey, key)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetAccountAPIKey retrieves the authenticated API key from the request
+// context. Returns nil if the request was not authenticated by a key.
+func GetAccountAPIKey(r *http.Request) *models.AccountAPIKey {
+	key, ok := r.Context().Value(AccountAPIKeyContextKey).(*models.AccountAPIKey)
+	if !ok {
+		return nil
+	}
+	return key
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"wealth_tracker/internal/models"
+)
+
+// FeatureChecker reports whether an experimental feature is on for a user.
+type FeatureChecker interface {
+	Enabled(name string, user *models.User) bool
+}
+
+// RequireFeature is middleware that answers 404 Not Found for routes of a
+// feature that is off for the signed-in user, so unreleased pages do not
+// exist for users without the feature.
+func RequireFeature(features FeatureChecker, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !features.Enabled(name, GetUser(r)) {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
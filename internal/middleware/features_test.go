@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+// userFeatures turns features on for the users of the listed IDs.
+type userFeatures map[string][]int64
+
+func (f userFeatures) Enabled(name string, user *models.User) bool {
+	for _, id := range f[name] {
+		if user != nil && user.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRequireFeature(t *testing.T) {
+	handler := RequireFeature(userFeatures{"analyzer": {1}}, "analyzer")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	tests := []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{"feature on", &models.User{ID: 1}, http.StatusTeapot},
+		{"feature off", &models.User{ID: 2}, http.StatusNotFound},
+		{"signed out", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/analyzer", nil)
+		if tt.user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), UserContextKey, tt.user))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d; want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
@@ -107,6 +107,24 @@ func (m *AuthMiddleware) RedirectIfAuthenticated(next http.Handler) http.Handler
 	})
 }
 
+// RequireAPIUser is middleware for the JSON API that requires a user who has
+// changed their password. Unlike RequireAuth it answers with a status code
+// rather than redirecting to the login page.
+func (m *AuthMiddleware) RequireAPIUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUser(r)
+		if user == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if user.MustChangePassword {
+			http.Error(w, "Password change required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RequirePasswordChanged is middleware that redirects users who must change their password.
 // Use this on protected routes to enforce password change before accessing the app.
 func (m *AuthMiddleware) RequirePasswordChanged(next http.Handler) http.Handler {
@@ -2,16 +2,21 @@
 package middleware
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
+
+	"wealth_tracker/internal/store"
 )
 
 // RateLimiter provides per-IP rate limiting.
 type RateLimiter struct {
+	name     string // Identifies the limiter's counters in the shared store
 	visitors map[string]*visitor
 	mu       sync.RWMutex
 	rate     rate.Limit
@@ -28,18 +33,52 @@ type visitor struct {
 // r is requests per second, b is burst size.
 func NewRateLimiter(r float64, b int) *RateLimiter {
 	rl := &RateLimiter{
+		name:     fmt.Sprintf("%g/%d", r, b),
 		visitors: make(map[string]*visitor),
 		rate:     rate.Limit(r),
 		burst:    b,
 		cleanup:  3 * time.Minute,
 	}
 
-	// Start background cleanup
-	go rl.cleanupLoop()
+	// Idle visitors are removed by SweepRateLimiters
+	limitersMu.Lock()
+	limiters = append(limiters, rl)
+	limitersMu.Unlock()
 
 	return rl
 }
 
+// limiters are all rate limiters created, swept by SweepRateLimiters.
+var (
+	limiters   []*RateLimiter
+	limitersMu sync.Mutex
+)
+
+// sharedStore, if set, holds the request counts of all rate limiters, so
+// instances behind a load balancer enforce the limits together.
+var sharedStore store.Store
+
+// SetRateLimitStore makes rate limiters count requests in a store shared by
+// all instances. Each limiter then allows burst requests per IP in every
+// window of burst/rate seconds. It must be called before serving requests.
+func SetRateLimitStore(s store.Store) {
+	sharedStore = s
+}
+
+// allow reports whether a request from an IP is within the limit. Requests
+// are counted locally if the shared store fails.
+func (rl *RateLimiter) allow(ip string) bool {
+	if sharedStore != nil {
+		window := time.Duration(float64(rl.burst) / float64(rl.rate) * float64(time.Second))
+		count, err := sharedStore.Incr("ratelimit:"+rl.name+":"+ip, window)
+		if err == nil {
+			return count <= int64(rl.burst)
+		}
+		log.Printf("Rate limit store error, limiting locally: %v", err)
+	}
+	return rl.getVisitor(ip).Allow()
+}
+
 // getVisitor returns the rate limiter for an IP, creating one if needed.
 func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
 	rl.mu.Lock()
@@ -56,29 +95,39 @@ func (rl *RateLimiter) getVisitor(ip string) *rate.Limiter {
 	return v.limiter
 }
 
-// cleanupLoop removes old visitors periodically.
-func (rl *RateLimiter) cleanupLoop() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		for ip, v := range rl.visitors {
-			if time.Since(v.lastSeen) > rl.cleanup {
-				delete(rl.visitors, ip)
-			}
+// Sweep removes visitors not seen for a while and returns how many it
+// removed.
+func (rl *RateLimiter) Sweep(now time.Time) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	removed := 0
+	for ip, v := range rl.visitors {
+		if now.Sub(v.lastSeen) > rl.cleanup {
+			delete(rl.visitors, ip)
+			removed++
 		}
-		rl.mu.Unlock()
 	}
+	return removed
+}
+
+// SweepRateLimiters removes the idle visitors of all rate limiters and
+// returns how many it removed.
+func SweepRateLimiters(now time.Time) int {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	removed := 0
+	for _, rl := range limiters {
+		removed += rl.Sweep(now)
+	}
+	return removed
 }
 
 // Limit is middleware that rate limits requests by IP.
 func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getIP(r)
-		limiter := rl.getVisitor(ip)
-
-		if !limiter.Allow() {
+		if !rl.allow(getIP(r)) {
 			http.Error(w, "Too many requests", http.StatusTooManyRequests)
 			return
 		}
@@ -100,6 +149,7 @@ var (
 func LimitStrict(next http.Handler) http.Handler {
 	if strictLimiter == nil {
 		strictLimiter = NewRateLimiter(0.5, 3)
+		strictLimiter.name = "strict"
 	}
 	return strictLimiter.Limit(next)
 }
@@ -109,6 +159,7 @@ func LimitStrict(next http.Handler) http.Handler {
 func LimitAuth(next http.Handler) http.Handler {
 	if authLimiter == nil {
 		authLimiter = NewRateLimiter(1, 5)
+		authLimiter.name = "auth"
 	}
 	return authLimiter.Limit(next)
 }
@@ -118,6 +169,7 @@ func LimitAuth(next http.Handler) http.Handler {
 func LimitAPI(next http.Handler) http.Handler {
 	if apiLimiter == nil {
 		apiLimiter = NewRateLimiter(10, 20)
+		apiLimiter.name = "api"
 	}
 	return apiLimiter.Limit(next)
 }
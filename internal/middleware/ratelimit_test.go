@@ -3,7 +3,13 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/store"
 )
 
 func TestGetIP_XForwardedFor_SingleIP(t *testing.T) {
@@ -155,6 +161,62 @@ func TestRateLimiter_DifferentIPs(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_Sweep(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	handler := limiter.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if removed := limiter.Sweep(time.Now()); removed != 0 {
+		t.Errorf("Sweep right after a request removed %d visitors, want 0", removed)
+	}
+	if removed := limiter.Sweep(time.Now().Add(time.Hour)); removed != 1 {
+		t.Errorf("Sweep an hour later removed %d visitors, want 1", removed)
+	}
+	if len(limiter.visitors) != 0 {
+		t.Errorf("Got %d visitors after sweep, want 0", len(limiter.visitors))
+	}
+}
+
+func TestRateLimiter_SharedStore(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	encryptor, err := broker.NewEncryptor("test-secret-that-is-32-chars-long")
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	shared := store.NewSQLite(db, encryptor)
+	SetRateLimitStore(shared)
+	t.Cleanup(func() { SetRateLimitStore(nil) })
+
+	// Two instances each have their own limiter
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	instances := []http.Handler{NewRateLimiter(0.01, 2).Limit(ok), NewRateLimiter(0.01, 2).Limit(ok)}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, status := range want {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rec := httptest.NewRecorder()
+		instances[i%2].ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("Request %d: got status %d, want %d", i+1, rec.Code, status)
+		}
+	}
+}
+
 func TestLimitAuth_Creates(t *testing.T) {
 	handler := LimitAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
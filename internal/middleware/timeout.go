@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// timeoutGrace is how long past a route's timeout the connection stays
+// writable, so the timeout response itself can still be sent.
+const timeoutGrace = 5 * time.Second
+
+// Timeout is middleware that answers 503 Service Unavailable when a handler
+// takes longer than d, cancelling the request's context. The server's read
+// and write deadlines are moved to match, so routes can allow more time
+// than the server default, such as for uploads and exports.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := http.TimeoutHandler(next, d, "The request took too long. Please try again.")
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Not every ResponseWriter supports deadlines, e.g. in tests
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Now().Add(d))
+			_ = rc.SetWriteDeadline(time.Now().Add(d + timeoutGrace))
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout_SlowHandler(t *testing.T) {
+	handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			t.Error("request context was not cancelled")
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestTimeout_FastHandler(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("HX-Trigger", "saved")
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("HX-Trigger") != "saved" {
+		t.Errorf("Got status %d and HX-Trigger %q, want 201 and saved", rec.Code, rec.Header().Get("HX-Trigger"))
+	}
+}
+
+func TestTimeout_ExtendsServerWriteTimeout(t *testing.T) {
+	server := httptest.NewUnstartedServer(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(150 * time.Millisecond)
+		w.Write([]byte("done"))
+	})))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request past the server write timeout failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
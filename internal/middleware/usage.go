@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// UsageLimiter counts a user's requests against a monthly quota.
+type UsageLimiter interface {
+	Use(userID int64, kind string) error
+}
+
+// CountUsage is middleware that counts requests of the signed-in or API key
+// user as kind, and refuses them with 429 Too Many Requests once the user's
+// quota is used up.
+func CountUsage(limiter UsageLimiter, kind string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var userID int64
+			if key := GetAccountAPIKey(r); key != nil {
+				userID = key.UserID
+			} else if user := GetUser(r); user != nil {
+				userID = user.ID
+			}
+			if userID != 0 {
+				if err := limiter.Use(userID, kind); err != nil {
+					http.Error(w, err.Error(), http.StatusTooManyRequests)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -12,12 +12,29 @@ type User struct {
 	DefaultCurrency    string    `json:"default_currency"`
 	NumberFormat       string    `json:"number_format"` // "da" (Danish: 1.234,56), "en" (English: 1,234.56), "de" (German: 1.234,56), "fr" (French: 1 234,56)
 	Theme              string    `json:"theme"`
+	HideDecimals       bool      `json:"hide_decimals"`  // Show money amounts rounded to whole units
+	MilestoneStep      float64   `json:"milestone_step"` // Net worth milestone interval, 0 = off
 	IsAdmin            bool      `json:"is_admin"`
 	MustChangePassword bool      `json:"must_change_password"`
+	SeenVersion        string    `json:"-"`                              // Last release whose what's new page was shown
+	BalanceDescription string    `json:"balance_description,omitempty"`  // Template for manual balance updates, empty = default
+	SyncDescription    string    `json:"sync_description,omitempty"`     // Template for synced balances, empty = default
+	DigestFrequency    string    `json:"digest_frequency"`               // DigestWeekly, DigestMonthly or DigestOff
+	Timezone           string    `json:"timezone,omitempty"`             // IANA time zone of displayed times, empty = server time zone
+	NetWorthExclusions []string  `json:"net_worth_exclusions,omitempty"` // Net worth groups the dashboard leaves out of net worth
+	PeriodStartDay     int       `json:"period_start_day"`               // Day of month statement periods start on, 1 = calendar months
+	SupportViewer      string    `json:"-"`                              // Name of the admin viewing the user's pages in support mode; not stored
 	CreatedAt          time.Time `json:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at"`
 }
 
+// Email digest frequencies.
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+	DigestOff     = "off"
+)
+
 // Category represents an asset category (e.g., Aktier, Krypto, Pension).
 type Category struct {
 	ID        int64     `json:"id"`
@@ -27,22 +44,62 @@ type Category struct {
 	Icon      string    `json:"icon,omitempty"`
 	SortOrder int       `json:"sort_order"`
 	CreatedAt time.Time `json:"created_at"`
+
+	ExpectedReturn *float64 `json:"expected_return,omitempty"` // Annual %, NULL = global default
+	Liquidity      string   `json:"liquidity,omitempty"`       // LiquidityLiquid, LiquidityIlliquid, LiquidityLocked or empty
+	Description    string   `json:"description,omitempty"`     // Markdown
 }
 
+// Category liquidity classes.
+const (
+	LiquidityLiquid   = "liquid"   // Cash and anything sellable within days
+	LiquidityIlliquid = "illiquid" // Sellable, but slowly or at a cost, such as property
+	LiquidityLocked   = "locked"   // Not available before a date, such as pensions
+)
+
 // Account represents a financial account (e.g., Nordnet, SaxoInvester).
 type Account struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	CategoryID  *int64    `json:"category_id,omitempty"`
-	Name        string    `json:"name"`
-	Currency    string    `json:"currency"`
-	IsLiability bool      `json:"is_liability"`
-	IsActive    bool      `json:"is_active"`
-	Notes       string    `json:"notes,omitempty"`
-	Balance     float64   `json:"balance"` // Calculated from transactions
-	CreatedAt   time.Time `json:"created_at"`
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	CategoryID    *int64     `json:"category_id,omitempty"`
+	Name          string     `json:"name"`
+	Currency      string     `json:"currency"`
+	IsLiability   bool       `json:"is_liability"`
+	IsActive      bool       `json:"is_active"`
+	Notes         string     `json:"notes,omitempty"`
+	OpenedAt      *time.Time `json:"opened_at,omitempty"`       // Earlier balances count as the opening balance
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`       // Excluded from net worth from this date
+	InterestRate  float64    `json:"interest_rate,omitempty"`   // Annual percentage accrued monthly on liabilities
+	SortOrder     int        `json:"sort_order"`                // Position in the user's own order
+	IsPinned      bool       `json:"is_pinned"`                 // Listed first and shown on the dashboard
+	NetWorthGroup string     `json:"net_worth_group,omitempty"` // NetWorthGroupPension, NetWorthGroupHome, NetWorthGroupSpouse or empty
+	Balance       float64    `json:"balance"`                   // Calculated from transactions
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"` // Zero until first edited; stale edits are rejected
+}
+
+// IsClosed reports whether the account is closed today. An account can be
+// given a closing date in the future and closes when it comes.
+func (a *Account) IsClosed() bool {
+	if a.ClosedAt == nil {
+		return false
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return !a.ClosedAt.After(today)
 }
 
+// Net worth groups of accounts, which the dashboard can leave out of net
+// worth, as different planning questions need different definitions of it.
+const (
+	NetWorthGroupPension = "pension" // Pension savings, locked until retirement
+	NetWorthGroupHome    = "home"    // The home and its mortgage, whose sum is the home equity
+	NetWorthGroupSpouse  = "spouse"  // Accounts of a spouse or partner
+)
+
+// NetWorthGroups lists the net worth groups in the order they are shown.
+var NetWorthGroups = []string{NetWorthGroupPension, NetWorthGroupHome, NetWorthGroupSpouse}
+
 // Transaction represents a financial transaction.
 type Transaction struct {
 	ID              int64     `json:"id"`
@@ -50,10 +107,19 @@ type Transaction struct {
 	Amount          float64   `json:"amount"`
 	BalanceAfter    float64   `json:"balance_after"`
 	Description     string    `json:"description,omitempty"`
+	CategoryID      *int64    `json:"category_id,omitempty"` // NULL = the account's category
+	Kind            string    `json:"kind,omitempty"`        // TransactionValuation, or empty for money moved in or out
+	Tag             string    `json:"tag,omitempty"`         // Set by categorization rules
 	TransactionDate time.Time `json:"transaction_date"`
 	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"` // Zero until first edited; stale edits are rejected
 }
 
+// TransactionValuation is the kind of transactions that revalue an account,
+// such as balance updates, broker syncs and interest, rather than move money
+// in or out of it.
+const TransactionValuation = "valuation"
+
 // Goal represents a wealth milestone goal.
 type Goal struct {
 	ID             int64      `json:"id"`
@@ -64,8 +130,63 @@ type Goal struct {
 	TargetCurrency string     `json:"target_currency"`
 	Deadline       *time.Time `json:"deadline,omitempty"`
 	ReachedDate    *time.Time `json:"reached_date,omitempty"`
-	Progress       float64    `json:"progress"` // Calculated field (0-100)
+	Description    string     `json:"description,omitempty"` // Markdown
+	Progress       float64    `json:"progress"`              // Calculated field (0-100)
 	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"` // Zero until first edited; stale edits are rejected
+
+	// Accounts saved into for the goal, and how much is planned to go into
+	// them each month; 0 means no plan.
+	FundingAccountIDs   []int64 `json:"funding_account_ids,omitempty"`
+	MonthlyContribution float64 `json:"monthly_contribution,omitempty"`
+}
+
+// GoalSnapshot records a goal's progress in a week. WeekStart is the Monday
+// of the week; the snapshot holds the latest figures of that week.
+type GoalSnapshot struct {
+	ID           int64     `json:"id"`
+	GoalID       int64     `json:"goal_id"`
+	WeekStart    time.Time `json:"week_start"`
+	CurrentWorth float64   `json:"current_worth"`
+	TargetAmount float64   `json:"target_amount"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
+
+// InterestAccrual is the interest posted to a liability for the month ending
+// at PeriodEnd. TransactionID is nil once the posting has been deleted.
+type InterestAccrual struct {
+	ID            int64     `json:"id"`
+	AccountID     int64     `json:"account_id"`
+	TransactionID *int64    `json:"transaction_id,omitempty"`
+	PeriodEnd     time.Time `json:"period_end"`
+	Rate          float64   `json:"rate"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// NetWorthMilestone records the first day net worth reached a multiple of the
+// user's milestone step. Celebrated is set once the user has been shown it.
+type NetWorthMilestone struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Amount     float64   `json:"amount"`
+	ReachedAt  time.Time `json:"reached_at"`
+	Celebrated bool      `json:"celebrated"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NetWorthSnapshot records a user's net worth at the end of a day. It holds
+// the latest figures of that day; Categories are the asset totals by
+// category ID.
+type NetWorthSnapshot struct {
+	ID          int64             `json:"id"`
+	UserID      int64             `json:"user_id"`
+	Day         time.Time         `json:"day"`
+	Assets      float64           `json:"assets"`
+	Liabilities float64           `json:"liabilities"`
+	NetWorth    float64           `json:"net_worth"`
+	Categories  map[int64]float64 `json:"categories"`
+	RecordedAt  time.Time         `json:"recorded_at"`
 }
 
 // CurrencyRate represents an exchange rate between two currencies.
@@ -77,6 +198,28 @@ type CurrencyRate struct {
 	FetchedAt    time.Time `json:"fetched_at"`
 }
 
+// ManualExchangeRate is an exchange rate entered by a user for a currency pair
+// the rate provider does not cover. ValidTo is nil while the rate is open-ended.
+type ManualExchangeRate struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	FromCurrency string     `json:"from_currency"`
+	ToCurrency   string     `json:"to_currency"`
+	Rate         float64    `json:"rate"`
+	ValidFrom    time.Time  `json:"valid_from"`
+	ValidTo      *time.Time `json:"valid_to,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// IsValidOn reports whether the rate applies on the given day.
+func (r *ManualExchangeRate) IsValidOn(day time.Time) bool {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(r.ValidFrom) {
+		return false
+	}
+	return r.ValidTo == nil || !day.After(*r.ValidTo)
+}
+
 // Session represents a user session for authentication.
 type Session struct {
 	ID        string    `json:"id"`
@@ -95,20 +238,35 @@ func (s *Session) IsExpired() bool {
 type BrokerConnection struct {
 	ID             int64      `json:"id"`
 	UserID         int64      `json:"user_id"`
-	BrokerType     string     `json:"broker_type"` // "nordnet", "saxo", etc.
-	Username       string     `json:"username"`    // MitID user identifier (Nordnet) or empty (Saxo)
-	CPR            string     `json:"-"`           // CPR number for Signicat verification (never expose in JSON)
-	Country        string     `json:"country"`     // "dk", "se", "no", "fi"
-	AppKey         string     `json:"-"`           // Saxo App Key (client_id) - never expose in JSON
-	AppSecret      string     `json:"-"`           // Saxo App Secret (client_secret) - for non-PKCE flow, never expose
+	BrokerType     string     `json:"broker_type"`  // "nordnet", "saxo", "psd2", "coinbase", etc.
+	Username       string     `json:"username"`     // MitID user identifier (Nordnet) or empty (Saxo)
+	CPR            string     `json:"-"`            // CPR number for Signicat verification (never expose in JSON)
+	Country        string     `json:"country"`      // "dk", "se", "no", "fi"
+	AppKey         string     `json:"-"`            // Saxo App Key (client_id) - never expose in JSON
+	AppSecret      string     `json:"-"`            // Saxo App Secret (client_secret) - for non-PKCE flow, never expose
 	RedirectURI    string     `json:"redirect_uri"` // Saxo OAuth redirect URI (registered in developer portal)
 	IsActive       bool       `json:"is_active"`
 	LastSyncAt     *time.Time `json:"last_sync_at,omitempty"`
 	LastSyncStatus string     `json:"last_sync_status,omitempty"` // "success", "error", "auth_failed"
 	LastSyncError  string     `json:"last_sync_error,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"` // Last sync that got past the broker login
+	// When the owner was last reminded of an expiring or stale login
+	CredentialRemindedAt *time.Time `json:"-"`
+	// Hours and days unattended syncs may run, in the broker's market time zone
+	SyncWindowStart int  `json:"sync_window_start"` // Hour from which syncs may start (0-23)
+	SyncWindowEnd   int  `json:"sync_window_end"`   // Hour before which syncs must start (1-24); before the start for overnight windows
+	SkipWeekends    bool `json:"skip_weekends"`     // Also skips the market's holidays
+	// When the connection is synced unattended: "daily", "weekly" or a cron
+	// expression, in the market time zone; empty syncs only on request
+	SyncSchedule string `json:"sync_schedule,omitempty"`
+	// Outbound proxy and User-Agent of broker requests; empty uses the global default
+	ProxyURL  string `json:"-"` // May contain proxy credentials
+	UserAgent string `json:"user_agent,omitempty"`
+	// Base URL of the bank's Berlin Group API (PSD2); the TPP token is kept in AppSecret
+	APIURL string `json:"api_url,omitempty"`
 	// Saxo OAuth2 token storage (encrypted)
-	RefreshTokenEncrypted string     `json:"-"`                           // Encrypted refresh token (never expose)
-	TokenExpiresAt        *time.Time `json:"token_expires_at,omitempty"`  // Access token expiry
+	RefreshTokenEncrypted string     `json:"-"`                            // Encrypted refresh token (never expose)
+	TokenExpiresAt        *time.Time `json:"token_expires_at,omitempty"`   // Access token expiry
 	RefreshExpiresAt      *time.Time `json:"refresh_expires_at,omitempty"` // Refresh token expiry
 	CreatedAt             time.Time  `json:"created_at"`
 	UpdatedAt             time.Time  `json:"updated_at"`
@@ -140,37 +298,115 @@ type Holding struct {
 	CurrentPrice   float64   `json:"current_price,omitempty"` // Latest price
 	CurrentValue   float64   `json:"current_value"`           // Quantity * CurrentPrice
 	Currency       string    `json:"currency"`
-	InstrumentType string    `json:"instrument_type,omitempty"` // "stock", "etf", "fund", "bond", "cash"
+	InstrumentType string    `json:"instrument_type,omitempty"` // "stock", "etf", "fund", "bond", "cash", "crypto"
+	CostBasisMode  string    `json:"cost_basis_mode"`           // CostBasisBroker or CostBasisRecomputed
 	LastUpdated    time.Time `json:"last_updated"`
 	CreatedAt      time.Time `json:"created_at"`
+
+	// RecomputedAvgPrice is the average price derived from imported
+	// acquisitions, or 0 when there are none. Not stored.
+	RecomputedAvgPrice float64 `json:"recomputed_avg_price,omitempty"`
+}
+
+// Holding cost basis modes.
+const (
+	CostBasisBroker     = "broker"     // Average price as reported by the broker
+	CostBasisRecomputed = "recomputed" // Average price from imported acquisitions
+)
+
+// UsesRecomputedCostBasis reports whether P/L is based on the recomputed
+// average price. It falls back to the broker's price without acquisitions.
+func (h *Holding) UsesRecomputedCostBasis() bool {
+	return h.CostBasisMode == CostBasisRecomputed && h.RecomputedAvgPrice > 0
+}
+
+// CostBasisPrice returns the average price used for P/L.
+func (h *Holding) CostBasisPrice() float64 {
+	if h.UsesRecomputedCostBasis() {
+		return h.RecomputedAvgPrice
+	}
+	return h.AvgPrice
 }
 
 // ProfitLoss returns the unrealized P/L for this holding.
 func (h *Holding) ProfitLoss() float64 {
-	if h.AvgPrice == 0 {
+	avgPrice := h.CostBasisPrice()
+	if avgPrice == 0 {
 		return 0
 	}
-	return h.CurrentValue - (h.Quantity * h.AvgPrice)
+	return h.CurrentValue - (h.Quantity * avgPrice)
 }
 
 // ProfitLossPercent returns the unrealized P/L percentage.
 func (h *Holding) ProfitLossPercent() float64 {
-	cost := h.Quantity * h.AvgPrice
+	cost := h.Quantity * h.CostBasisPrice()
 	if cost == 0 {
 		return 0
 	}
 	return ((h.CurrentValue - cost) / cost) * 100
 }
 
+// HoldingAcquisition is an imported buy of an instrument in an account, or a
+// sell when Quantity is negative. Symbol matches the holding's symbol (ISIN
+// when known).
+type HoldingAcquisition struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	Symbol    string    `json:"symbol"`
+	TradeDate time.Time `json:"trade_date"`
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Fees      float64   `json:"fees"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArchivedHolding is a holding removed by a sync, kept so it can be restored.
+// The embedded Holding's ID is the ID of the archive entry.
+type ArchivedHolding struct {
+	Holding
+	AccountName string     `json:"account_name"`
+	UserEmail   string     `json:"user_email"`
+	Reason      string     `json:"reason"` // "stale"
+	DeletedAt   time.Time  `json:"deleted_at"`
+	RestoredAt  *time.Time `json:"restored_at,omitempty"`
+}
+
+// OrphanedHolding is a broker-synced holding that no sync will ever update
+// again, because its account is inactive or no longer mapped to a broker.
+type OrphanedHolding struct {
+	Holding
+	AccountName string `json:"account_name"`
+	UserEmail   string `json:"user_email"`
+	Reason      string `json:"reason"` // "unmapped" or "inactive"
+}
+
 // AccountMapping links a broker account to a local account.
 type AccountMapping struct {
-	ID                  int64     `json:"id"`
-	ConnectionID        int64     `json:"connection_id"`
-	LocalAccountID      int64     `json:"local_account_id"`
-	ExternalAccountID   string    `json:"external_account_id"`   // Broker's account ID
-	ExternalAccountName string    `json:"external_account_name"` // Display name from broker
-	AutoSync            bool      `json:"auto_sync"`
-	CreatedAt           time.Time `json:"created_at"`
+	ID                  int64      `json:"id"`
+	ConnectionID        int64      `json:"connection_id"`
+	LocalAccountID      int64      `json:"local_account_id"`
+	ExternalAccountID   string     `json:"external_account_id"`   // Broker's account ID
+	ExternalAccountName string     `json:"external_account_name"` // Display name from broker
+	AutoSync            bool       `json:"auto_sync"`
+	HeldDeletionsSince  *time.Time `json:"held_deletions_since,omitempty"` // Set when a sync kept stale holdings pending confirmation
+	HeldBalance         *float64   `json:"held_balance,omitempty"`         // Synced balance kept pending confirmation as it broke the account's trend
+	HeldBalanceAt       *time.Time `json:"held_balance_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// BrokerPerformance is the return a broker reports for a mapped account over
+// a standard period, such as "Year". ReturnFraction is 0.05 for 5%.
+type BrokerPerformance struct {
+	ID             int64     `json:"id"`
+	MappingID      int64     `json:"mapping_id"`
+	Period         string    `json:"period"`
+	ReturnFraction float64   `json:"return_fraction"`
+	FetchedAt      time.Time `json:"fetched_at"`
+
+	// Joined from the mapping for display. Not stored.
+	LocalAccountID int64  `json:"local_account_id"`
+	AccountName    string `json:"account_name"`
+	BrokerType     string `json:"broker_type"`
 }
 
 // SyncHistory tracks broker sync operations for auditing.
@@ -187,13 +423,251 @@ type SyncHistory struct {
 	DurationMs      int64      `json:"duration_ms,omitempty"`
 }
 
+// MitIDAttempt records a MitID authentication attempt for a broker connection.
+// Used to enforce a cool-down after repeated failures, since too many failed
+// attempts can temporarily block the user's MitID identity.
+type MitIDAttempt struct {
+	ID           int64      `json:"id"`
+	ConnectionID int64      `json:"connection_id"`
+	Purpose      string     `json:"purpose"` // "sync", "dry_run", "accounts", "ftn"
+	Status       string     `json:"status"`  // "started", "success", "failed"
+	ErrorMessage string     `json:"error_message,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// RebalanceSession is a saved set of rebalancing actions that the user checks
+// off as the trades are executed. CompletedAt is set once every action is done.
+type RebalanceSession struct {
+	ID          int64                     `json:"id"`
+	UserID      int64                     `json:"user_id"`
+	TargetType  string                    `json:"target_type"`
+	NewMoney    float64                   `json:"new_money"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	CompletedAt *time.Time                `json:"completed_at,omitempty"`
+	Actions     []*RebalanceSessionAction `json:"actions"`
+}
+
+// RebalanceSessionAction is one suggested trade of a rebalancing session.
+// CurrentPct records the allocation before the trade, i.e. the drift at the
+// point of intervention.
+type RebalanceSessionAction struct {
+	ID          int64      `json:"id"`
+	SessionID   int64      `json:"session_id"`
+	TargetKey   string     `json:"target_key"`
+	TargetName  string     `json:"target_name"`
+	Action      string     `json:"action"` // "buy", "sell", "hold"
+	Amount      float64    `json:"amount"`
+	CurrentPct  float64    `json:"current_pct"`
+	TargetPct   float64    `json:"target_pct"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// WatchlistItem is an instrument the user is watching before buying it.
+// AddedPrice is the price when it was added, to show the change since.
+type WatchlistItem struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	Symbol         string     `json:"symbol"` // ISIN or ticker
+	Name           string     `json:"name"`
+	Currency       string     `json:"currency"`
+	InstrumentType string     `json:"instrument_type,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	AddedPrice     *float64   `json:"added_price,omitempty"`
+	CurrentPrice   *float64   `json:"current_price,omitempty"`
+	PriceSource    string     `json:"price_source,omitempty"` // WatchlistPriceSynced or WatchlistPriceManual
+	PriceUpdatedAt *time.Time `json:"price_updated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// ChangePercent is the price change since the item was added, or nil if
+	// either price is unknown. Not stored.
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// Watchlist price sources.
+const (
+	WatchlistPriceSynced = "synced" // Latest price of a broker-synced holding
+	WatchlistPriceManual = "manual"
+)
+
+// AnalyticsExclusion is an instrument the user leaves out of portfolio
+// composition, rebalancing and concentration, such as employer shares under
+// lockup or a spouse's positions in a shared depot.
+type AnalyticsExclusion struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Symbol    string    `json:"symbol"` // ISIN or ticker, upper case
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HoldingLabel is a user-defined strategy bucket, such as "core",
+// "satellite" or "speculative", that holdings of an instrument belong to in
+// every account.
+type HoldingLabel struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Symbol    string    `json:"symbol"` // ISIN or ticker, upper case
+	Label     string    `json:"label"`  // Lower case
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountSnapshot is an account's balance and holdings at the moment its
+// owner took a snapshot, independent of syncs.
+type AccountSnapshot struct {
+	ID        int64                    `json:"id"`
+	AccountID int64                    `json:"account_id"`
+	Balance   float64                  `json:"balance"`
+	Note      string                   `json:"note,omitempty"`
+	TakenAt   time.Time                `json:"taken_at"`
+	Holdings  []AccountSnapshotHolding `json:"holdings,omitempty"` // Only loaded by GetByID
+}
+
+// AccountSnapshotHolding is a holding as recorded in an AccountSnapshot.
+type AccountSnapshotHolding struct {
+	Symbol       string  `json:"symbol"`
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	CurrentPrice float64 `json:"current_price"`
+	CurrentValue float64 `json:"current_value"`
+	Currency     string  `json:"currency"`
+}
+
+// NotificationChannel is where a user's alerts are delivered. URL is the
+// ntfy or Gotify server or the Slack or Discord webhook; Topic is used by
+// ntfy only. URL and Token hold secrets and are never sent to clients.
+type NotificationChannel struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Kind       string     `json:"kind"` // ntfy, gotify, slack, discord
+	Name       string     `json:"name"`
+	URL        string     `json:"-"`
+	Topic      string     `json:"topic,omitempty"`
+	Token      string     `json:"-"`
+	IsActive   bool       `json:"is_active"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// EmailDigest is a digest email sent to a user. Snapshot is the JSON of the
+// figures it reported, which the next digest is compared with.
+type EmailDigest struct {
+	ID       int64     `json:"id"`
+	UserID   int64     `json:"user_id"`
+	SentAt   time.Time `json:"sent_at"`
+	Snapshot string    `json:"-"`
+}
+
+// AccountAPIKey lets an automation script update the balance of a single
+// account and add transactions to it. The key itself is only shown when it
+// is created; KeyPrefix identifies it afterwards.
+type AccountAPIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	AccountID  int64      `json:"account_id"`
+	Name       string     `json:"name"`
+	KeyHash    string     `json:"-"`
+	KeyPrefix  string     `json:"key_prefix"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// AccountName is joined in for display. Not stored.
+	AccountName string `json:"account_name,omitempty"`
+}
+
+// APIToken lets a script use the exports, the portfolio API and the REST API
+// as its user, without a session. The token itself is only shown when it is
+// created; TokenPrefix identifies it afterwards.
+type APIToken struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	Scope       string     `json:"scope"` // APITokenScopeRead or APITokenScopeReadWrite
+	TokenHash   string     `json:"-"`
+	TokenPrefix string     `json:"token_prefix"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// API token scopes.
+const (
+	APITokenScopeRead      = "read"       // GET requests only
+	APITokenScopeReadWrite = "read_write" // Any request
+)
+
+// CanWrite reports whether the token may make requests that change data.
+func (t *APIToken) CanWrite() bool {
+	return t.Scope == APITokenScopeReadWrite
+}
+
+// AdvisorShare gives another user, such as a financial advisor, read access
+// to one account or goal of its owner, and lets them comment on it. Exactly
+// one of AccountID and GoalID is set.
+type AdvisorShare struct {
+	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"owner_id"`
+	AdvisorID int64     `json:"advisor_id"`
+	AccountID *int64    `json:"account_id,omitempty"`
+	GoalID    *int64    `json:"goal_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Joined in for display. Not stored.
+	OwnerEmail   string `json:"owner_email,omitempty"`
+	AdvisorEmail string `json:"advisor_email,omitempty"`
+	ItemName     string `json:"item_name,omitempty"` // Name of the account or goal
+}
+
+// Comment is a message in the thread of an account or goal, written by its
+// owner or an advisor it is shared with. Exactly one of AccountID and GoalID
+// is set.
+type Comment struct {
+	ID        int64     `json:"id"`
+	AuthorID  int64     `json:"author_id"`
+	AccountID *int64    `json:"account_id,omitempty"`
+	GoalID    *int64    `json:"goal_id,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// AuthorEmail is joined in for display. Not stored.
+	AuthorEmail string `json:"author_email,omitempty"`
+}
+
+// TransactionFilter narrows the transactions list. Unset criteria match
+// every transaction.
+type TransactionFilter struct {
+	AccountID  *int64     `json:"account_id,omitempty"`
+	CategoryID *int64     `json:"category_id,omitempty"` // The transaction's category, or else its account's
+	Tag        string     `json:"tag,omitempty"`
+	MinAmount  *float64   `json:"min_amount,omitempty"` // Signed, so negative for money out
+	MaxAmount  *float64   `json:"max_amount,omitempty"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+}
+
+// IsEmpty reports whether the filter has no criteria.
+func (f TransactionFilter) IsEmpty() bool {
+	return f == TransactionFilter{}
+}
+
+// SavedFilter is a named transaction filter, listed as a view on the
+// transactions page.
+type SavedFilter struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	TransactionFilter
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // AllocationTarget represents a user-defined portfolio allocation target.
 // Used by the Portfolio Analyzer to compare actual vs desired allocations.
 type AllocationTarget struct {
 	ID         int64     `json:"id"`
 	UserID     int64     `json:"user_id"`
-	TargetType string    `json:"target_type"` // "category", "asset_type", "currency"
-	TargetKey  string    `json:"target_key"`  // Category ID, asset type name, or currency code
+	TargetType string    `json:"target_type"` // "category", "asset_type", "currency", "label"
+	TargetKey  string    `json:"target_key"`  // Category ID, asset type name, currency code or label
 	TargetPct  float64   `json:"target_pct"`  // Target percentage (0-100)
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
@@ -204,4 +678,112 @@ const (
 	TargetTypeCategory  = "category"
 	TargetTypeAssetType = "asset_type"
 	TargetTypeCurrency  = "currency"
+	TargetTypeLabel     = "label"
+)
+
+// AppVersion records a release the app has been started with.
+type AppVersion struct {
+	Version         string    `json:"version"`
+	SchemaVersion   int       `json:"schema_version"`
+	FirstStartedAt  time.Time `json:"first_started_at"`
+	NoticeDismissed bool      `json:"notice_dismissed"` // What's new was dismissed for all users
+}
+
+// Usage kinds counted against monthly quotas.
+const (
+	UsageSync       = "sync"        // Broker syncs, scheduled or started by the user
+	UsageMarketData = "market_data" // Exchange rates and price history fetched from providers
+	UsageAPI        = "api"         // Requests to the API key and Grafana endpoints
+)
+
+// UsageDay is how often a user used something on one day.
+type UsageDay struct {
+	Day   time.Time `json:"day"`
+	Kind  string    `json:"kind"`
+	Count int       `json:"count"`
+}
+
+// CategorizationRule sets the category, tag and kind of imported and synced
+// transactions that match it. Empty conditions match every transaction.
+type CategorizationRule struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Name       string    `json:"name"`
+	Pattern    string    `json:"pattern,omitempty"`    // Regular expression matched against the description, case-insensitively
+	Payee      string    `json:"payee,omitempty"`      // Text the description must contain, case-insensitively
+	MinAmount  *float64  `json:"min_amount,omitempty"` // Inclusive bounds on the amount
+	MaxAmount  *float64  `json:"max_amount,omitempty"`
+	CategoryID *int64    `json:"category_id,omitempty"` // NULL leaves the category unchanged
+	Tag        string    `json:"tag,omitempty"`         // Empty leaves the tag unchanged
+	Kind       string    `json:"kind,omitempty"`        // RuleKindValuation, RuleKindFlow, or empty to leave the kind unchanged
+	Priority   int       `json:"priority"`              // Lower is tried first
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Kinds a categorization rule can give a transaction.
+const (
+	RuleKindValuation = TransactionValuation
+	RuleKindFlow      = "flow" // Money moved in or out, stored as an empty kind
 )
+
+// ChartColor is the color assigned to an entity without a color of its own, so
+// it is drawn in the same color on every chart.
+type ChartColor struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	EntityType string    `json:"entity_type"` // One of the ChartColor* entity types
+	EntityKey  string    `json:"entity_key"`  // Asset type, currency, label or account ID
+	Color      string    `json:"color"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Entity types with a chart color.
+const (
+	ChartColorAssetType = "asset_type"
+	ChartColorCurrency  = "currency"
+	ChartColorLabel     = "label"
+	ChartColorAccount   = "account" // Accounts without a category
+)
+
+// RetentionPolicy is how long the rows of a history table are kept. Zero days
+// keeps rows forever.
+type RetentionPolicy struct {
+	TableName     string     `json:"table_name"`
+	KeepDays      int        `json:"keep_days"`       // Rows older than this are deleted
+	ThinAfterDays int        `json:"thin_after_days"` // Rows older than this are thinned to one a month
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastDeleted   int64      `json:"last_deleted"` // Rows deleted by the last run
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// FeatureFlag is an admin's setting of an experimental feature, for everyone
+// or, with a UserID, for one user, which overrides the setting for everyone.
+type FeatureFlag struct {
+	Name      string    `json:"name"`
+	UserID    *int64    `json:"user_id,omitempty"` // Nil for everyone
+	UserEmail string    `json:"user_email,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaxParameters are the Danish tax figures of a year. Rates are percentages.
+type TaxParameters struct {
+	Year                  int       `json:"year"`
+	ASKMaxDeposit         float64   `json:"ask_max_deposit"`         // Max ASK contribution
+	StockGainThreshold    float64   `json:"stock_gain_threshold"`    // Stock income taxed at the low rate
+	StockGainLowRate      float64   `json:"stock_gain_low_rate"`     // Tax rate below the threshold
+	StockGainHighRate     float64   `json:"stock_gain_high_rate"`    // Tax rate above the threshold
+	ASKTaxRate            float64   `json:"ask_tax_rate"`            // Flat tax on ASK
+	PensionWithdrawalRate float64   `json:"pension_withdrawal_rate"` // Approximate pension income tax
+	BuiltIn               bool      `json:"built_in"`                // Not stored; the figures compiled into the app
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// PendingInvestment is the total of an account's savings plan buys that were
+// debited but not yet executed, which the account value includes.
+type PendingInvestment struct {
+	AccountID  int64     `json:"account_id"`
+	Amount     float64   `json:"amount"`
+	OrderCount int       `json:"order_count"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,125 @@
+// Package money provides currency-aware rounding and display formatting.
+package money
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultPrecision is the number of decimals used for currencies without an
+// explicit entry in currencyPrecision.
+const DefaultPrecision = 2
+
+// currencyPrecision lists currencies whose minor unit differs from 2 decimals.
+var currencyPrecision = map[string]int{
+	// ISO 4217 currencies without a minor unit
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"UGX": 0,
+	"VND": 0,
+	"XAF": 0,
+	"XOF": 0,
+	// Crypto currencies are held in fractions far below 0.01
+	"BTC":  8,
+	"XBT":  8,
+	"ETH":  8,
+	"LTC":  8,
+	"BCH":  8,
+	"SOL":  8,
+	"ADA":  8,
+	"XRP":  8,
+	"DOGE": 8,
+	"DOT":  8,
+}
+
+// Precision returns the number of decimals to display for a currency.
+func Precision(currency string) int {
+	if p, ok := currencyPrecision[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return p
+	}
+	return DefaultPrecision
+}
+
+// Round rounds n half away from zero to the given number of decimals.
+func Round(n float64, decimals int) float64 {
+	pow := math.Pow10(decimals)
+	return math.Round(n*pow) / pow
+}
+
+// Format formats n with the given number of decimals using the separators of
+// a number format preference: "da"/"de" (1.234,56), "en" (1,234.56) or
+// "fr" (1 234,56). The value is rounded, not truncated.
+func Format(n float64, format string, decimals int) string {
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	pow := math.Pow10(decimals)
+	scaled := math.Round(math.Abs(n) * pow)
+	negative := n < 0 && scaled != 0
+
+	intPart := int64(scaled / pow)
+	fracPart := int64(scaled - float64(intPart)*pow)
+
+	result := formatIntWithSeparator(intPart, format)
+	if decimals > 0 {
+		frac := strconv.FormatInt(fracPart, 10)
+		result += decimalSeparator(format) + strings.Repeat("0", decimals-len(frac)) + frac
+	}
+
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+// FormatAmount formats a money amount with the precision of its currency, or
+// without decimals if hideDecimals is set.
+func FormatAmount(n float64, currency, format string, hideDecimals bool) string {
+	decimals := Precision(currency)
+	if hideDecimals {
+		decimals = 0
+	}
+	return Format(n, format, decimals)
+}
+
+// decimalSeparator returns the decimal separator for a number format.
+func decimalSeparator(format string) string {
+	if format == "en" {
+		return "."
+	}
+	return ","
+}
+
+// formatIntWithSeparator formats an integer with thousand separators.
+func formatIntWithSeparator(n int64, format string) string {
+	// Determine separator based on format
+	sep := "."
+	switch format {
+	case "en":
+		sep = ","
+	case "fr":
+		sep = " "
+	case "da", "de":
+		sep = "."
+	}
+
+	s := strconv.FormatInt(n, 10)
+	if len(s) <= 3 {
+		return s
+	}
+
+	// Add separators every 3 digits from the right
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package money
+
+import "testing"
+
+func TestPrecision(t *testing.T) {
+	tests := map[string]int{
+		"DKK": 2,
+		"EUR": 2,
+		"JPY": 0,
+		"jpy": 0,
+		"BTC": 8,
+		"":    DefaultPrecision,
+	}
+
+	for currency, want := range tests {
+		if got := Precision(currency); got != want {
+			t.Errorf("Precision(%q) = %d; want %d", currency, got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		n        float64
+		format   string
+		decimals int
+		want     string
+	}{
+		{1234567.891, "da", 2, "1.234.567,89"},
+		{1234567.891, "en", 2, "1,234,567.89"},
+		{1234567.891, "fr", 2, "1 234 567,89"},
+		{1234.5, "da", 0, "1.235"},
+		{0.29, "en", 2, "0.29"},  // truncation used to give 0.28
+		{1.999, "en", 2, "2.00"}, // rounds into the integer part
+		{-1234.5, "en", 2, "-1,234.50"},
+		{-0.001, "en", 2, "0.00"}, // no negative zero
+		{0.00012345, "en", 8, "0.00012345"},
+		{15000, "da", 0, "15.000"},
+	}
+
+	for _, tc := range tests {
+		if got := Format(tc.n, tc.format, tc.decimals); got != tc.want {
+			t.Errorf("Format(%v, %q, %d) = %q; want %q", tc.n, tc.format, tc.decimals, got, tc.want)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	if got := FormatAmount(1234.56, "JPY", "en", false); got != "1,235" {
+		t.Errorf("FormatAmount(JPY) = %q; want 1,235", got)
+	}
+	if got := FormatAmount(0.5, "BTC", "en", false); got != "0.50000000" {
+		t.Errorf("FormatAmount(BTC) = %q; want 0.50000000", got)
+	}
+	if got := FormatAmount(1234.56, "DKK", "da", true); got != "1.235" {
+		t.Errorf("FormatAmount(hideDecimals) = %q; want 1.235", got)
+	}
+}
+
+func TestRound(t *testing.T) {
+	if got := Round(12.3456, 2); got != 12.35 {
+		t.Errorf("Round(12.3456, 2) = %v; want 12.35", got)
+	}
+	if got := Round(1234.5, 0); got != 1235 {
+		t.Errorf("Round(1234.5, 0) = %v; want 1235", got)
+	}
+}
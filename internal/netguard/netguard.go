@@ -0,0 +1,55 @@
+// Package netguard keeps outbound requests to user-configured addresses,
+// such as notification webhooks and broker proxies, away from the server's
+// own network.
+package netguard
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+)
+
+// lookupTimeout bounds the lookup of a host when it is checked.
+const lookupTimeout = 5 * time.Second
+
+// IsPrivate reports whether ip is a loopback, private, link-local or
+// unspecified address.
+func IsPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// ResolvesPrivate reports whether host is, or resolves to, a private
+// address. Hosts that do not resolve are not private; connections to them
+// are checked again by Control.
+func ResolvesPrivate(host string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if IsPrivate(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Control returns a net.Dialer Control function that fails with err on
+// connections to private addresses. It checks the address actually dialed,
+// so DNS changes and redirects cannot get around a check made earlier.
+func Control(err error) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, splitErr := net.SplitHostPort(address)
+		if splitErr != nil {
+			return splitErr
+		}
+		if ip := net.ParseIP(host); ip == nil || IsPrivate(ip) {
+			return err
+		}
+		return nil
+	}
+}
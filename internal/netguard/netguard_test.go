@@ -0,0 +1,52 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		ip      string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.1.10", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsPrivate(net.ParseIP(tt.ip)); got != tt.private {
+			t.Errorf("IsPrivate(%s) = %v; want %v", tt.ip, got, tt.private)
+		}
+	}
+}
+
+func TestResolvesPrivate(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if !ResolvesPrivate(host) {
+			t.Errorf("ResolvesPrivate(%q) = false; want true", host)
+		}
+	}
+	if ResolvesPrivate("93.184.216.34") {
+		t.Error("ResolvesPrivate() of a public address = true; want false")
+	}
+}
+
+func TestControl(t *testing.T) {
+	errPrivate := errors.New("private")
+	control := Control(errPrivate)
+	if err := control("tcp", "127.0.0.1:80", nil); err != errPrivate {
+		t.Errorf("Control() of loopback = %v; want %v", err, errPrivate)
+	}
+	if err := control("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Control() of a public address = %v; want nil", err)
+	}
+}
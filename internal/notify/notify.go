@@ -0,0 +1,249 @@
+// Package notify delivers notifications through the services self-hosters
+// already run: ntfy, Gotify, Slack and Discord.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/netguard"
+)
+
+// Channel kinds.
+const (
+	KindNtfy    = "ntfy"
+	KindGotify  = "gotify"
+	KindSlack   = "slack"
+	KindDiscord = "discord"
+)
+
+// DefaultNtfyServer is used for ntfy channels without a server URL.
+const DefaultNtfyServer = "https://ntfy.sh"
+
+// sendTimeout bounds the time a channel may take to accept a notification.
+const sendTimeout = 10 * time.Second
+
+// Notification is a short alert with an optional link to the page it is
+// about.
+type Notification struct {
+	Title   string
+	Message string
+	URL     string
+}
+
+// Channel delivers notifications.
+type Channel interface {
+	Send(n Notification) error
+}
+
+// Config configures a channel. URL is the server of ntfy and Gotify and the
+// webhook of Slack and Discord; Topic is the ntfy topic; Token is the ntfy
+// access token or the Gotify application token.
+type Config struct {
+	URL   string
+	Topic string
+	Token string
+
+	// AllowPrivateNetworks lets the channel reach loopback, private and
+	// link-local addresses, such as a Gotify server on the LAN. Channels are
+	// configured by users, so this is off unless the admin enables it.
+	AllowPrivateNetworks bool
+}
+
+var (
+	ErrUnknownKind   = errors.New("unknown notification channel")
+	ErrInvalidURL    = errors.New("URL must be an http or https address")
+	ErrPrivateURL    = errors.New("URL must not point to a private or local address")
+	ErrTopicRequired = errors.New("ntfy channels need a topic")
+	ErrTokenRequired = errors.New("Gotify channels need an application token")
+)
+
+// New creates a channel of the given kind, validating its configuration.
+func New(kind string, cfg Config) (Channel, error) {
+	client := newClient(cfg.AllowPrivateNetworks)
+	switch kind {
+	case KindNtfy:
+		if cfg.URL == "" {
+			cfg.URL = DefaultNtfyServer
+		}
+		if cfg.Topic == "" || strings.Contains(cfg.Topic, "/") {
+			return nil, ErrTopicRequired
+		}
+		if err := validateURL(cfg.URL, cfg.AllowPrivateNetworks); err != nil {
+			return nil, err
+		}
+		return &ntfyChannel{client: client, server: strings.TrimRight(cfg.URL, "/"), topic: cfg.Topic, token: cfg.Token}, nil
+	case KindGotify:
+		if err := validateURL(cfg.URL, cfg.AllowPrivateNetworks); err != nil {
+			return nil, err
+		}
+		if cfg.Token == "" {
+			return nil, ErrTokenRequired
+		}
+		return &gotifyChannel{client: client, server: strings.TrimRight(cfg.URL, "/"), token: cfg.Token}, nil
+	case KindSlack:
+		if err := validateURL(cfg.URL, cfg.AllowPrivateNetworks); err != nil {
+			return nil, err
+		}
+		return &slackChannel{client: client, webhook: cfg.URL}, nil
+	case KindDiscord:
+		if err := validateURL(cfg.URL, cfg.AllowPrivateNetworks); err != nil {
+			return nil, err
+		}
+		return &discordChannel{client: client, webhook: cfg.URL}, nil
+	default:
+		return nil, ErrUnknownKind
+	}
+}
+
+// validateURL checks that raw is an absolute http or https URL and, unless
+// private networks are allowed, that its host does not resolve to a private
+// or local address. Hosts that do not resolve are left to fail when sent to.
+func validateURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if !allowPrivate && netguard.ResolvesPrivate(u.Hostname()) {
+		return ErrPrivateURL
+	}
+	return nil
+}
+
+// newClient creates the client notifications are sent with. Unless private
+// networks are allowed, it refuses to connect to private addresses.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	if !allowPrivate {
+		dialer.Control = netguard.Control(ErrPrivateURL)
+	}
+	return &http.Client{
+		Timeout: sendTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: sendTimeout,
+		},
+	}
+}
+
+// ntfyChannel publishes to an ntfy topic.
+type ntfyChannel struct {
+	client *http.Client
+	server string
+	topic  string
+	token  string
+}
+
+func (c *ntfyChannel) Send(n Notification) error {
+	req, err := http.NewRequest(http.MethodPost, c.server+"/"+url.PathEscape(c.topic), strings.NewReader(n.Message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", n.Title)
+	if n.URL != "" {
+		req.Header.Set("Click", n.URL)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return do(c.client, req)
+}
+
+// gotifyChannel posts messages with a Gotify application token.
+type gotifyChannel struct {
+	client *http.Client
+	server string
+	token  string
+}
+
+func (c *gotifyChannel) Send(n Notification) error {
+	payload := map[string]any{"title": n.Title, "message": n.Message}
+	if n.URL != "" {
+		payload["extras"] = map[string]any{
+			"client::notification": map[string]any{"click": map[string]string{"url": n.URL}},
+		}
+	}
+	req, err := newJSONRequest(c.server+"/message", payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Gotify-Key", c.token)
+	return do(c.client, req)
+}
+
+// slackChannel posts to a Slack incoming webhook.
+type slackChannel struct {
+	client  *http.Client
+	webhook string
+}
+
+func (c *slackChannel) Send(n Notification) error {
+	text := "*" + n.Title + "*\n" + n.Message
+	if n.URL != "" {
+		text += "\n<" + n.URL + ">"
+	}
+	req, err := newJSONRequest(c.webhook, map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return do(c.client, req)
+}
+
+// discordChannel posts to a Discord webhook.
+type discordChannel struct {
+	client  *http.Client
+	webhook string
+}
+
+func (c *discordChannel) Send(n Notification) error {
+	embed := map[string]string{"title": n.Title, "description": n.Message}
+	if n.URL != "" {
+		embed["url"] = n.URL
+	}
+	req, err := newJSONRequest(c.webhook, map[string]any{"embeds": []map[string]string{embed}})
+	if err != nil {
+		return err
+	}
+	return do(c.client, req)
+}
+
+// newJSONRequest creates a POST request with payload as its JSON body.
+func newJSONRequest(target string, payload any) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// do sends req and fails on responses other than 2xx. The request URL is left
+// out of errors, as webhook URLs contain their secret, and so is the response
+// body, which is shown to the user and could be any page the URL points to.
+func do(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sending notification: status %d", resp.StatusCode)
+	}
+	return nil
+}
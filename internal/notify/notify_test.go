@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// request is a request received by a test server.
+type request struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+// newServer starts a server recording requests and answering with status.
+func newServer(t *testing.T, status int) (*httptest.Server, *[]request) {
+	t.Helper()
+	var received []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, request{path: r.URL.Path, header: r.Header, body: string(body)})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &received
+}
+
+func TestChannels_Send(t *testing.T) {
+	n := Notification{Title: "Sync failed", Message: "Nordnet: login expired", URL: "https://wealth.example.com/settings/connections/1"}
+
+	tests := []struct {
+		kind  string
+		cfg   func(url string) Config
+		check func(t *testing.T, r request)
+	}{
+		{KindNtfy, func(url string) Config {
+			return Config{URL: url, Topic: "wealth", Token: "tk_1", AllowPrivateNetworks: true}
+		}, func(t *testing.T, r request) {
+			if r.path != "/wealth" || r.body != n.Message || r.header.Get("Title") != n.Title || r.header.Get("Click") != n.URL || r.header.Get("Authorization") != "Bearer tk_1" {
+				t.Errorf("ntfy request = %+v", r)
+			}
+		}},
+		{KindGotify, func(url string) Config { return Config{URL: url + "/", Token: "app-token", AllowPrivateNetworks: true} }, func(t *testing.T, r request) {
+			var payload map[string]any
+			json.Unmarshal([]byte(r.body), &payload)
+			if r.path != "/message" || r.header.Get("X-Gotify-Key") != "app-token" || payload["title"] != n.Title || payload["message"] != n.Message {
+				t.Errorf("gotify request = %+v", r)
+			}
+		}},
+		{KindSlack, func(url string) Config {
+			return Config{URL: url + "/services/T0/B0/secret", AllowPrivateNetworks: true}
+		}, func(t *testing.T, r request) {
+			var payload map[string]string
+			json.Unmarshal([]byte(r.body), &payload)
+			if r.path != "/services/T0/B0/secret" || !strings.Contains(payload["text"], "*Sync failed*") || !strings.Contains(payload["text"], n.URL) {
+				t.Errorf("slack request = %+v", r)
+			}
+		}},
+		{KindDiscord, func(url string) Config {
+			return Config{URL: url + "/api/webhooks/1/secret", AllowPrivateNetworks: true}
+		}, func(t *testing.T, r request) {
+			var payload struct {
+				Embeds []map[string]string `json:"embeds"`
+			}
+			json.Unmarshal([]byte(r.body), &payload)
+			if len(payload.Embeds) != 1 || payload.Embeds[0]["title"] != n.Title || payload.Embeds[0]["url"] != n.URL {
+				t.Errorf("discord request = %+v", r)
+			}
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.kind, func(t *testing.T) {
+			srv, received := newServer(t, http.StatusOK)
+			channel, err := New(tc.kind, tc.cfg(srv.URL))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := channel.Send(n); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if len(*received) != 1 {
+				t.Fatalf("server received %d requests; want 1", len(*received))
+			}
+			tc.check(t, (*received)[0])
+		})
+	}
+}
+
+func TestChannel_SendErrorHidesWebhook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal admin page", http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	channel, err := New(KindDiscord, Config{URL: srv.URL + "/api/webhooks/1/secret", AllowPrivateNetworks: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = channel.Send(Notification{Title: "Test"})
+	if err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Fatalf("Send() error = %v; want status 404", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error %q contains the webhook URL", err)
+	}
+	if strings.Contains(err.Error(), "admin page") {
+		t.Errorf("error %q contains the response body", err)
+	}
+}
+
+func TestNew_RejectsPrivateAddresses(t *testing.T) {
+	for _, raw := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook"} {
+		if _, err := New(KindSlack, Config{URL: raw}); !errors.Is(err, ErrPrivateURL) {
+			t.Errorf("New(%q) error = %v; want %v", raw, err, ErrPrivateURL)
+		}
+		if _, err := New(KindSlack, Config{URL: raw, AllowPrivateNetworks: true}); err != nil {
+			t.Errorf("New(%q) with private networks allowed error = %v", raw, err)
+		}
+	}
+}
+
+func TestChannel_SendRefusesPrivateAddresses(t *testing.T) {
+	// A host that resolved to a public address when the channel was saved
+	// may point somewhere else by the time it is sent to
+	srv, received := newServer(t, http.StatusOK)
+	channel := &slackChannel{client: newClient(false), webhook: srv.URL + "/hook"}
+	if err := channel.Send(Notification{Title: "Test"}); !errors.Is(err, ErrPrivateURL) {
+		t.Fatalf("Send() error = %v; want %v", err, ErrPrivateURL)
+	}
+	if len(*received) != 0 {
+		t.Errorf("server received %d requests; want none", len(*received))
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		kind string
+		cfg  Config
+		want error
+	}{
+		{"email", Config{URL: "https://example.com"}, ErrUnknownKind},
+		{KindNtfy, Config{}, ErrTopicRequired},
+		{KindNtfy, Config{URL: "ftp://ntfy.example.com", Topic: "wealth"}, ErrInvalidURL},
+		{KindGotify, Config{URL: "https://gotify.example.com"}, ErrTokenRequired},
+		{KindSlack, Config{URL: "hooks.slack.com/services/x"}, ErrInvalidURL},
+		{KindDiscord, Config{}, ErrInvalidURL},
+	}
+	for _, tc := range tests {
+		if _, err := New(tc.kind, tc.cfg); !errors.Is(err, tc.want) {
+			t.Errorf("New(%q, %+v) error = %v; want %v", tc.kind, tc.cfg, err, tc.want)
+		}
+	}
+
+	// ntfy defaults to the public server
+	channel, err := New(KindNtfy, Config{Topic: "wealth"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if c := channel.(*ntfyChannel); c.server != DefaultNtfyServer {
+		t.Errorf("ntfy server = %q; want %q", c.server, DefaultNtfyServer)
+	}
+}
@@ -0,0 +1,131 @@
+// Package pdf writes simple text documents as PDF, without fonts to embed.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout in points: A4 with a monospaced font, so columns can be
+// aligned with spaces.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+	fontSize   = 9
+	lineHeight = 12
+
+	// LineWidth is the number of characters that fit on a line.
+	LineWidth = (pageWidth - 2*margin) * 10 / (fontSize * 6)
+)
+
+// linesPerPage is the number of lines that fit on a page.
+const linesPerPage = (pageHeight - 2*margin) / lineHeight
+
+// Document is a text document of lines in Courier, broken into pages as it
+// grows. Characters outside Latin-1 are written as '?'.
+type Document struct {
+	pages [][]string
+}
+
+// New creates an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// Line appends a line of text, starting a new page when the current one is
+// full. Lines longer than LineWidth are cut off.
+func (d *Document) Line(text string) {
+	if n := len(d.pages); n == 0 || len(d.pages[n-1]) == linesPerPage {
+		d.pages = append(d.pages, nil)
+	}
+	if r := []rune(text); len(r) > LineWidth {
+		text = string(r[:LineWidth])
+	}
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], text)
+}
+
+// Linef appends a formatted line.
+func (d *Document) Linef(format string, args ...any) {
+	d.Line(fmt.Sprintf(format, args...))
+}
+
+// PageBreak starts a new page unless the current one is empty.
+func (d *Document) PageBreak() {
+	if n := len(d.pages); n > 0 && len(d.pages[n-1]) > 0 {
+		d.pages = append(d.pages, nil)
+	}
+}
+
+// WriteTo writes the document as a PDF file.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = [][]string{nil}
+	}
+
+	// Objects 1-3 are the catalog, page tree and font, followed by a page
+	// and its content stream for each page
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, margin, pageHeight-margin)
+		for _, line := range lines {
+			content.WriteByte('(')
+			content.Write(encode(line))
+			content.WriteString(") '\n")
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// encode converts text to WinAnsiEncoding and escapes it for a PDF string.
+// Latin-1 characters share their code with WinAnsi; the euro sign is mapped
+// separately as it is common in amounts.
+func encode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out = append(out, '\\', byte(r))
+		case r == '€':
+			out = append(out, 0x80)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc := New()
+	doc.Line("Kontoudtog (2024)")
+	for i := 0; i < linesPerPage; i++ {
+		doc.Linef("Line %d", i)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Error("output is not framed as a PDF file")
+	}
+	if !strings.Contains(out, "/Count 2") {
+		t.Error("document should break onto a second page")
+	}
+	if !strings.Contains(out, `(Kontoudtog \(2024\)) '`) {
+		t.Error("parentheses in text should be escaped")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	if got := encode("Æblegrød €5 ✓"); !bytes.Equal(got, []byte("\xc6blegr\xf8d \x805 ?")) {
+		t.Errorf("encode() = %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Package release describes the running release and what changed in it.
+package release
+
+// Version is the release of this build. Bump it together with a new entry
+// in Notes; users see the what's new page once after an upgrade.
+const Version = "1.1.0"
+
+// Note lists the user-facing changes of one release.
+type Note struct {
+	Version string
+	Date    string
+	Changes []string
+}
+
+// Notes holds the changelog, newest release first.
+var Notes = []Note{
+	{
+		Version: "1.1.0",
+		Date:    "2026-10-15",
+		Changes: []string{
+			"Download the data behind the net worth, balance, composition and allocation charts as CSV.",
+			"Accounts can have an opening and closing date; closed accounts stay in your history.",
+			"Import your buys to recompute a holding's average price when the broker's is wrong.",
+			"Nordnet accounts are synced concurrently, and a failing account no longer fails the whole sync.",
+			"Saxo holdings are fetched across all pages.",
+			"This page, shown once after each upgrade.",
+		},
+	},
+	{
+		Version: "1.0.0",
+		Date:    "2026-09-01",
+		Changes: []string{
+			"Net worth milestones, goal import and export, manual exchange rates and account merging.",
+			"Rebalancing checklists, transaction categories and per-category expected returns.",
+			"Nordnet and Saxo broker sync with dry-run previews and deletion safeguards.",
+		},
+	},
+}
+
+// Since returns the notes of the releases after version, newest first. If
+// version is unknown, e.g. for users from before release tracking, only the
+// current release's notes are returned.
+func Since(version string) []Note {
+	for i, n := range Notes {
+		if n.Version == version {
+			return Notes[:i]
+		}
+	}
+	if len(Notes) == 0 {
+		return nil
+	}
+	return Notes[:1]
+}
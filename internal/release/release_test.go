@@ -0,0 +1,22 @@
+package release
+
+import "testing"
+
+func TestSince(t *testing.T) {
+	if got := Since(Version); len(got) != 0 {
+		t.Errorf("Since(current) = %d notes; want 0", len(got))
+	}
+	if got := Since(""); len(got) != 1 || got[0].Version != Version {
+		t.Errorf("Since(\"\") = %+v; want only the current release", got)
+	}
+	last := Notes[len(Notes)-1].Version
+	if got := Since(last); len(got) != len(Notes)-1 {
+		t.Errorf("Since(%q) = %d notes; want %d", last, len(got), len(Notes)-1)
+	}
+}
+
+func TestNotes_StartWithCurrentVersion(t *testing.T) {
+	if len(Notes) == 0 || Notes[0].Version != Version {
+		t.Fatalf("Notes[0] should describe Version %s", Version)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"wealth_tracker/internal/database"
 	"wealth_tracker/internal/models"
@@ -18,13 +19,20 @@ func NewAccountRepository(db *database.DB) *AccountRepository {
 	return &AccountRepository{db: db}
 }
 
-// Create inserts a new account and returns its ID.
+// Create inserts a new account and returns its ID. New accounts have no
+// position yet and are listed after the ordered ones, by name.
 func (r *AccountRepository) Create(account *models.Account) (int64, error) {
-	result, err := r.db.Exec(`
-		INSERT INTO accounts (user_id, category_id, name, currency, is_liability, is_active, notes)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+	return createAccount(r.db, account)
+}
+
+// createAccount inserts an account on the database or within a transaction.
+func createAccount(db execer, account *models.Account) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO accounts (user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, is_pinned, net_worth_group)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, account.UserID, account.CategoryID, account.Name, account.Currency,
-		boolToInt(account.IsLiability), boolToInt(account.IsActive), account.Notes)
+		boolToInt(account.IsLiability), boolToInt(account.IsActive), account.Notes, account.OpenedAt, account.ClosedAt, account.InterestRate,
+		boolToInt(account.IsPinned), account.NetWorthGroup)
 	if err != nil {
 		return 0, err
 	}
@@ -34,15 +42,16 @@ func (r *AccountRepository) Create(account *models.Account) (int64, error) {
 // GetByID retrieves an account by ID.
 func (r *AccountRepository) GetByID(id int64) (*models.Account, error) {
 	row := r.db.QueryRow(`
-		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, created_at
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
 		FROM accounts
 		WHERE id = ?
 	`, id)
 
 	account := &models.Account{}
 	var categoryID sql.NullInt64
-	var isLiability, isActive int
+	var isLiability, isActive, isPinned int
 	var notes sql.NullString
+	var openedAt, closedAt, updatedAt sql.NullTime
 
 	err := row.Scan(
 		&account.ID,
@@ -53,7 +62,14 @@ func (r *AccountRepository) GetByID(id int64) (*models.Account, error) {
 		&isLiability,
 		&isActive,
 		&notes,
+		&openedAt,
+		&closedAt,
+		&account.InterestRate,
+		&account.SortOrder,
+		&isPinned,
+		&account.NetWorthGroup,
 		&account.CreatedAt,
+		&updatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -67,43 +83,106 @@ func (r *AccountRepository) GetByID(id int64) (*models.Account, error) {
 	}
 	account.IsLiability = isLiability == 1
 	account.IsActive = isActive == 1
+	account.IsPinned = isPinned == 1
 	if notes.Valid {
 		account.Notes = notes.String
 	}
+	if openedAt.Valid {
+		account.OpenedAt = &openedAt.Time
+	}
+	if closedAt.Valid {
+		account.ClosedAt = &closedAt.Time
+	}
+	if updatedAt.Valid {
+		account.UpdatedAt = updatedAt.Time
+	}
 
 	return account, nil
 }
 
-// GetByUserID retrieves all accounts for a user, sorted by name.
+// GetByUserID retrieves all accounts for a user: pinned accounts first, then
+// in the user's order, with accounts never reordered last by name.
 func (r *AccountRepository) GetByUserID(userID int64) ([]*models.Account, error) {
 	return r.queryAccounts(`
-		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, created_at
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
 		FROM accounts
 		WHERE user_id = ?
-		ORDER BY name ASC
+		ORDER BY is_pinned DESC, sort_order = 0, sort_order ASC, name ASC
 	`, userID)
 }
 
-// GetByUserIDActiveOnly retrieves only active accounts for a user.
+// accountOpen returns the SQL condition that the account with the closing
+// date column closedAt is not closed today. Accounts can be given a closing
+// date in the future and close when it comes, so this is checked when read
+// rather than stored.
+func accountOpen(closedAt string) string {
+	return `(` + closedAt + ` IS NULL OR substr(` + closedAt + `, 1, 10) > date('now', 'localtime'))`
+}
+
+// GetByUserIDActiveOnly retrieves only active accounts for a user that are
+// not closed.
 func (r *AccountRepository) GetByUserIDActiveOnly(userID int64) ([]*models.Account, error) {
 	return r.queryAccounts(`
-		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, created_at
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
 		FROM accounts
-		WHERE user_id = ? AND is_active = 1
-		ORDER BY name ASC
+		WHERE user_id = ? AND is_active = 1 AND `+accountOpen("closed_at")+`
+		ORDER BY is_pinned DESC, sort_order = 0, sort_order ASC, name ASC
 	`, userID)
 }
 
+// GetByUserIDWithHistory retrieves the accounts that count towards a user's
+// net worth history: active accounts and accounts closed on a given date.
+func (r *AccountRepository) GetByUserIDWithHistory(userID int64) ([]*models.Account, error) {
+	return r.queryAccounts(`
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
+		FROM accounts
+		WHERE user_id = ? AND (is_active = 1 OR closed_at IS NOT NULL)
+		ORDER BY is_pinned DESC, sort_order = 0, sort_order ASC, name ASC
+	`, userID)
+}
+
+// GetByUserIDOpenOn retrieves the accounts that counted towards a user's net
+// worth on a day: accounts of the history opened by then and not yet closed.
+func (r *AccountRepository) GetByUserIDOpenOn(userID int64, day time.Time) ([]*models.Account, error) {
+	accounts, err := r.GetByUserIDWithHistory(userID)
+	if err != nil {
+		return nil, err
+	}
+	end := day.AddDate(0, 0, 1)
+	open := make([]*models.Account, 0, len(accounts))
+	for _, a := range accounts {
+		if a.OpenedAt != nil && !a.OpenedAt.Before(end) {
+			continue
+		}
+		if a.ClosedAt != nil && a.ClosedAt.Before(end) {
+			continue
+		}
+		open = append(open, a)
+	}
+	return open, nil
+}
+
 // GetByCategoryID retrieves all accounts for a specific category.
 func (r *AccountRepository) GetByCategoryID(categoryID int64) ([]*models.Account, error) {
 	return r.queryAccounts(`
-		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, created_at
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
 		FROM accounts
 		WHERE category_id = ?
-		ORDER BY name ASC
+		ORDER BY is_pinned DESC, sort_order = 0, sort_order ASC, name ASC
 	`, categoryID)
 }
 
+// GetInterestBearingLiabilities retrieves the active, open liabilities of all
+// users that accrue interest.
+func (r *AccountRepository) GetInterestBearingLiabilities() ([]*models.Account, error) {
+	return r.queryAccounts(`
+		SELECT id, user_id, category_id, name, currency, is_liability, is_active, notes, opened_at, closed_at, interest_rate, sort_order, is_pinned, net_worth_group, created_at, updated_at
+		FROM accounts
+		WHERE is_liability = 1 AND is_active = 1 AND ` + accountOpen("closed_at") + ` AND interest_rate > 0
+		ORDER BY id ASC
+	`)
+}
+
 // queryAccounts is a helper to query multiple accounts.
 func (r *AccountRepository) queryAccounts(query string, args ...any) ([]*models.Account, error) {
 	rows, err := r.db.Query(query, args...)
@@ -116,8 +195,9 @@ func (r *AccountRepository) queryAccounts(query string, args ...any) ([]*models.
 	for rows.Next() {
 		account := &models.Account{}
 		var categoryID sql.NullInt64
-		var isLiability, isActive int
+		var isLiability, isActive, isPinned int
 		var notes sql.NullString
+		var openedAt, closedAt, updatedAt sql.NullTime
 
 		err := rows.Scan(
 			&account.ID,
@@ -128,7 +208,14 @@ func (r *AccountRepository) queryAccounts(query string, args ...any) ([]*models.
 			&isLiability,
 			&isActive,
 			&notes,
+			&openedAt,
+			&closedAt,
+			&account.InterestRate,
+			&account.SortOrder,
+			&isPinned,
+			&account.NetWorthGroup,
 			&account.CreatedAt,
+			&updatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -139,23 +226,41 @@ func (r *AccountRepository) queryAccounts(query string, args ...any) ([]*models.
 		}
 		account.IsLiability = isLiability == 1
 		account.IsActive = isActive == 1
+		account.IsPinned = isPinned == 1
 		if notes.Valid {
 			account.Notes = notes.String
 		}
+		if openedAt.Valid {
+			account.OpenedAt = &openedAt.Time
+		}
+		if closedAt.Valid {
+			account.ClosedAt = &closedAt.Time
+		}
+		if updatedAt.Valid {
+			account.UpdatedAt = updatedAt.Time
+		}
 
 		accounts = append(accounts, account)
 	}
 	return accounts, rows.Err()
 }
 
-// Update updates an existing account.
+// Update updates an existing account. It returns ErrStaleUpdate if the
+// account was edited since it was read, and sets account.UpdatedAt.
 func (r *AccountRepository) Update(account *models.Account) error {
-	result, err := r.db.Exec(`
+	return updateAccount(r.db, account)
+}
+
+// updateAccount saves an account on the database or within a transaction.
+func updateAccount(db execQuerier, account *models.Account) error {
+	now := time.Now().UTC()
+	result, err := db.Exec(`
 		UPDATE accounts
-		SET category_id = ?, name = ?, currency = ?, is_liability = ?, is_active = ?, notes = ?
-		WHERE id = ?
+		SET category_id = ?, name = ?, currency = ?, is_liability = ?, is_active = ?, notes = ?, opened_at = ?, closed_at = ?, interest_rate = ?, net_worth_group = ?, updated_at = ?
+		WHERE id = ? AND updated_at IS ?
 	`, account.CategoryID, account.Name, account.Currency,
-		boolToInt(account.IsLiability), boolToInt(account.IsActive), account.Notes, account.ID)
+		boolToInt(account.IsLiability), boolToInt(account.IsActive), account.Notes, account.OpenedAt, account.ClosedAt, account.InterestRate, account.NetWorthGroup, now,
+		account.ID, versionArg(account.UpdatedAt))
 	if err != nil {
 		return err
 	}
@@ -165,14 +270,76 @@ func (r *AccountRepository) Update(account *models.Account) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("account not found")
+		return staleOrNotFound(db, "accounts", account.ID, errors.New("account not found"))
 	}
+	account.UpdatedAt = now
 	return nil
 }
 
-// Delete removes an account by ID.
+// SetPinned pins an account to the top of lists and the dashboard, or unpins it.
+func (r *AccountRepository) SetPinned(id int64, pinned bool) error {
+	_, err := r.db.Exec(`UPDATE accounts SET is_pinned = ? WHERE id = ?`, boolToInt(pinned), id)
+	return err
+}
+
+// Reorder stores the user's order of accounts: each account gets its position
+// in ids. Accounts of other users are left alone.
+func (r *AccountRepository) Reorder(userID int64, ids []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, id := range ids {
+		if _, err := tx.Exec(`UPDATE accounts SET sort_order = ? WHERE id = ? AND user_id = ?`, i+1, id, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetCategory moves the user's accounts in ids to a category, or out of any
+// if categoryID is nil. Either all of them move or, if one is not the user's,
+// none do.
+func (r *AccountRepository) SetCategory(userID int64, ids []int64, categoryID *int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		result, err := tx.Exec(`UPDATE accounts SET category_id = ?, updated_at = ? WHERE id = ? AND user_id = ?`, categoryID, now, id, userID)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return errors.New("account not found")
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete removes an account by ID, along with the goals' links to it. The
+// links are removed explicitly so goals never point at a deleted account,
+// even on a connection with foreign keys off.
 func (r *AccountRepository) Delete(id int64) error {
-	result, err := r.db.Exec(`DELETE FROM accounts WHERE id = ?`, id)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM goal_funding_accounts WHERE account_id = ?`, id); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM accounts WHERE id = ?`, id)
 	if err != nil {
 		return err
 	}
@@ -184,7 +351,7 @@ func (r *AccountRepository) Delete(id int64) error {
 	if rowsAffected == 0 {
 		return errors.New("account not found")
 	}
-	return nil
+	return tx.Commit()
 }
 
 // CountByUserID returns the number of accounts for a user.
@@ -201,7 +368,7 @@ func (r *AccountRepository) CountActiveAssets(userID int64) (int, error) {
 	var count int
 	err := r.db.QueryRow(`
 		SELECT COUNT(*) FROM accounts
-		WHERE user_id = ? AND is_liability = 0 AND is_active = 1
+		WHERE user_id = ? AND is_liability = 0 AND is_active = 1 AND `+accountOpen("closed_at")+`
 	`, userID).Scan(&count)
 	return count, err
 }
@@ -211,7 +378,7 @@ func (r *AccountRepository) CountActiveLiabilities(userID int64) (int, error) {
 	var count int
 	err := r.db.QueryRow(`
 		SELECT COUNT(*) FROM accounts
-		WHERE user_id = ? AND is_liability = 1 AND is_active = 1
+		WHERE user_id = ? AND is_liability = 1 AND is_active = 1 AND `+accountOpen("closed_at")+`
 	`, userID).Scan(&count)
 	return count, err
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// AccountAPIKeyRepository handles account-scoped API key database operations.
+type AccountAPIKeyRepository struct {
+	db *database.DB
+}
+
+// NewAccountAPIKeyRepository creates a new AccountAPIKeyRepository.
+func NewAccountAPIKeyRepository(db *database.DB) *AccountAPIKeyRepository {
+	return &AccountAPIKeyRepository{db: db}
+}
+
+// Create inserts a new API key and returns its ID.
+func (r *AccountAPIKeyRepository) Create(key *models.AccountAPIKey) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO account_api_keys (user_id, account_id, name, key_hash, key_prefix, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, key.UserID, key.AccountID, key.Name, key.KeyHash, key.KeyPrefix, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves an API key by ID.
+func (r *AccountAPIKeyRepository) GetByID(id int64) (*models.AccountAPIKey, error) {
+	key, err := scanAccountAPIKey(r.db.QueryRow(`
+		SELECT k.id, k.user_id, k.account_id, k.name, k.key_hash, k.key_prefix, k.last_used_at, k.created_at, a.name
+		FROM account_api_keys k
+		JOIN accounts a ON a.id = k.account_id
+		WHERE k.id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetByHash retrieves the API key with the given hash, or nil if there is none.
+func (r *AccountAPIKeyRepository) GetByHash(hash string) (*models.AccountAPIKey, error) {
+	key, err := scanAccountAPIKey(r.db.QueryRow(`
+		SELECT k.id, k.user_id, k.account_id, k.name, k.key_hash, k.key_prefix, k.last_used_at, k.created_at, a.name
+		FROM account_api_keys k
+		JOIN accounts a ON a.id = k.account_id
+		WHERE k.key_hash = ?
+	`, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetByUserID retrieves all API keys of a user, ordered by account.
+func (r *AccountAPIKeyRepository) GetByUserID(userID int64) ([]*models.AccountAPIKey, error) {
+	rows, err := r.db.Query(`
+		SELECT k.id, k.user_id, k.account_id, k.name, k.key_hash, k.key_prefix, k.last_used_at, k.created_at, a.name
+		FROM account_api_keys k
+		JOIN accounts a ON a.id = k.account_id
+		WHERE k.user_id = ?
+		ORDER BY a.name, k.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.AccountAPIKey
+	for rows.Next() {
+		key, err := scanAccountAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// TouchLastUsed records that an API key was just used.
+func (r *AccountAPIKeyRepository) TouchLastUsed(id int64) error {
+	_, err := r.db.Exec(`UPDATE account_api_keys SET last_used_at = ? WHERE id = ?`, time.Now(), id)
+	return err
+}
+
+// Delete revokes an API key.
+func (r *AccountAPIKeyRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM account_api_keys WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("api key not found")
+	}
+	return nil
+}
+
+// scanAccountAPIKey scans an account_api_keys row joined with its account name.
+func scanAccountAPIKey(row interface{ Scan(...any) error }) (*models.AccountAPIKey, error) {
+	key := &models.AccountAPIKey{}
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.AccountID,
+		&key.Name,
+		&key.KeyHash,
+		&key.KeyPrefix,
+		&lastUsedAt,
+		&key.CreatedAt,
+		&key.AccountName,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return key, nil
+}
@@ -3,11 +3,16 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"wealth_tracker/internal/database"
 	"wealth_tracker/internal/models"
 )
 
+// ErrAccountAlreadyMapped is returned when mappings are moved to an account
+// that is already mapped on the same connection.
+var ErrAccountAlreadyMapped = errors.New("account is already mapped on the same connection")
+
 // AccountMappingRepository handles account mapping database operations.
 type AccountMappingRepository struct {
 	db *database.DB
@@ -33,7 +38,7 @@ func (r *AccountMappingRepository) Create(mapping *models.AccountMapping) (int64
 // GetByID retrieves an account mapping by ID.
 func (r *AccountMappingRepository) GetByID(id int64) (*models.AccountMapping, error) {
 	row := r.db.QueryRow(`
-		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, created_at
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
 		FROM account_mappings
 		WHERE id = ?
 	`, id)
@@ -44,7 +49,7 @@ func (r *AccountMappingRepository) GetByID(id int64) (*models.AccountMapping, er
 // GetByConnectionID retrieves all mappings for a broker connection.
 func (r *AccountMappingRepository) GetByConnectionID(connectionID int64) ([]*models.AccountMapping, error) {
 	rows, err := r.db.Query(`
-		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, created_at
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
 		FROM account_mappings
 		WHERE connection_id = ?
 		ORDER BY created_at ASC
@@ -60,7 +65,7 @@ func (r *AccountMappingRepository) GetByConnectionID(connectionID int64) ([]*mod
 // GetByLocalAccountID retrieves the mapping for a local account.
 func (r *AccountMappingRepository) GetByLocalAccountID(localAccountID int64) (*models.AccountMapping, error) {
 	row := r.db.QueryRow(`
-		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, created_at
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
 		FROM account_mappings
 		WHERE local_account_id = ?
 	`, localAccountID)
@@ -68,10 +73,27 @@ func (r *AccountMappingRepository) GetByLocalAccountID(localAccountID int64) (*m
 	return r.scanMapping(row)
 }
 
+// GetAllByLocalAccountID retrieves the mappings of a local account on all
+// connections.
+func (r *AccountMappingRepository) GetAllByLocalAccountID(localAccountID int64) ([]*models.AccountMapping, error) {
+	rows, err := r.db.Query(`
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
+		FROM account_mappings
+		WHERE local_account_id = ?
+		ORDER BY created_at ASC
+	`, localAccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanMappings(rows)
+}
+
 // GetByExternalAccountID retrieves a mapping by external account ID within a connection.
 func (r *AccountMappingRepository) GetByExternalAccountID(connectionID int64, externalAccountID string) (*models.AccountMapping, error) {
 	row := r.db.QueryRow(`
-		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, created_at
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
 		FROM account_mappings
 		WHERE connection_id = ? AND external_account_id = ?
 	`, connectionID, externalAccountID)
@@ -82,7 +104,7 @@ func (r *AccountMappingRepository) GetByExternalAccountID(connectionID int64, ex
 // GetAutoSyncByConnectionID retrieves all auto-sync enabled mappings for a connection.
 func (r *AccountMappingRepository) GetAutoSyncByConnectionID(connectionID int64) ([]*models.AccountMapping, error) {
 	rows, err := r.db.Query(`
-		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, created_at
+		SELECT id, connection_id, local_account_id, external_account_id, external_account_name, auto_sync, held_deletions_since, held_balance, held_balance_at, created_at
 		FROM account_mappings
 		WHERE connection_id = ? AND auto_sync = 1
 		ORDER BY created_at ASC
@@ -135,6 +157,24 @@ func (r *AccountMappingRepository) SetAutoSync(id int64, autoSync bool) error {
 	return nil
 }
 
+// SetHeldDeletionsSince records the sync time from which stale holdings were
+// kept instead of deleted, pending user confirmation. Pass nil to clear it.
+func (r *AccountMappingRepository) SetHeldDeletionsSince(id int64, since *time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE account_mappings SET held_deletions_since = ? WHERE id = ?
+	`, since, id)
+	return err
+}
+
+// SetHeldBalance records a synced balance that was kept instead of written,
+// pending user confirmation, and the time of its sync. Pass nil to clear it.
+func (r *AccountMappingRepository) SetHeldBalance(id int64, balance *float64, at *time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE account_mappings SET held_balance = ?, held_balance_at = ? WHERE id = ?
+	`, balance, at, id)
+	return err
+}
+
 // Delete removes an account mapping by ID.
 func (r *AccountMappingRepository) Delete(id int64) error {
 	result, err := r.db.Exec(`DELETE FROM account_mappings WHERE id = ?`, id)
@@ -152,6 +192,37 @@ func (r *AccountMappingRepository) Delete(id int64) error {
 	return nil
 }
 
+// MoveToAccount re-points the mappings of one local account to another, so
+// later syncs write to it, and returns the number moved. It fails without
+// moving any if the other account is already mapped on the same connection.
+func (r *AccountMappingRepository) MoveToAccount(fromAccountID, toAccountID int64) (int64, error) {
+	if fromAccountID == toAccountID {
+		return 0, errors.New("cannot move mappings to the same account")
+	}
+
+	var conflicts int
+	if err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM account_mappings
+		WHERE local_account_id = ? AND connection_id IN (
+			SELECT connection_id FROM account_mappings WHERE local_account_id = ?
+		)
+	`, toAccountID, fromAccountID).Scan(&conflicts); err != nil {
+		return 0, err
+	}
+	if conflicts > 0 {
+		return 0, ErrAccountAlreadyMapped
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE account_mappings SET local_account_id = ?, held_deletions_since = NULL, held_balance = NULL, held_balance_at = NULL
+		WHERE local_account_id = ?
+	`, toAccountID, fromAccountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // DeleteByConnectionID removes all mappings for a connection.
 func (r *AccountMappingRepository) DeleteByConnectionID(connectionID int64) error {
 	_, err := r.db.Exec(`DELETE FROM account_mappings WHERE connection_id = ?`, connectionID)
@@ -163,6 +234,9 @@ func (r *AccountMappingRepository) scanMapping(row *sql.Row) (*models.AccountMap
 	mapping := &models.AccountMapping{}
 	var autoSync int
 	var externalAccountName sql.NullString
+	var heldDeletionsSince sql.NullTime
+	var heldBalance sql.NullFloat64
+	var heldBalanceAt sql.NullTime
 
 	err := row.Scan(
 		&mapping.ID,
@@ -171,6 +245,9 @@ func (r *AccountMappingRepository) scanMapping(row *sql.Row) (*models.AccountMap
 		&mapping.ExternalAccountID,
 		&externalAccountName,
 		&autoSync,
+		&heldDeletionsSince,
+		&heldBalance,
+		&heldBalanceAt,
 		&mapping.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -184,6 +261,13 @@ func (r *AccountMappingRepository) scanMapping(row *sql.Row) (*models.AccountMap
 	if externalAccountName.Valid {
 		mapping.ExternalAccountName = externalAccountName.String
 	}
+	if heldDeletionsSince.Valid {
+		mapping.HeldDeletionsSince = &heldDeletionsSince.Time
+	}
+	if heldBalance.Valid && heldBalanceAt.Valid {
+		mapping.HeldBalance = &heldBalance.Float64
+		mapping.HeldBalanceAt = &heldBalanceAt.Time
+	}
 
 	return mapping, nil
 }
@@ -196,6 +280,9 @@ func (r *AccountMappingRepository) scanMappings(rows *sql.Rows) ([]*models.Accou
 		mapping := &models.AccountMapping{}
 		var autoSync int
 		var externalAccountName sql.NullString
+		var heldDeletionsSince sql.NullTime
+		var heldBalance sql.NullFloat64
+		var heldBalanceAt sql.NullTime
 
 		err := rows.Scan(
 			&mapping.ID,
@@ -204,6 +291,9 @@ func (r *AccountMappingRepository) scanMappings(rows *sql.Rows) ([]*models.Accou
 			&mapping.ExternalAccountID,
 			&externalAccountName,
 			&autoSync,
+			&heldDeletionsSince,
+			&heldBalance,
+			&heldBalanceAt,
 			&mapping.CreatedAt,
 		)
 		if err != nil {
@@ -214,6 +304,13 @@ func (r *AccountMappingRepository) scanMappings(rows *sql.Rows) ([]*models.Accou
 		if externalAccountName.Valid {
 			mapping.ExternalAccountName = externalAccountName.String
 		}
+		if heldDeletionsSince.Valid {
+			mapping.HeldDeletionsSince = &heldDeletionsSince.Time
+		}
+		if heldBalance.Valid && heldBalanceAt.Valid {
+			mapping.HeldBalance = &heldBalance.Float64
+			mapping.HeldBalanceAt = &heldBalanceAt.Time
+		}
 
 		mappings = append(mappings, mapping)
 	}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestAccountMappingRepository_MoveToAccount(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+	mappingRepo := NewAccountMappingRepository(db)
+	connRepo := NewBrokerConnectionRepository(db)
+
+	oldID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Old", Currency: "DKK", IsActive: true})
+	newID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "New", Currency: "DKK", IsActive: true})
+	mappedID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Mapped", Currency: "DKK", IsActive: true})
+	nordnetID, _ := connRepo.Create(&models.BrokerConnection{UserID: userID, BrokerType: "nordnet", Username: "user", Country: "dk", IsActive: true})
+	saxoID, _ := connRepo.Create(&models.BrokerConnection{UserID: userID, BrokerType: "saxo", IsActive: true})
+
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: nordnetID, LocalAccountID: oldID, ExternalAccountID: "1", AutoSync: true})
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: saxoID, LocalAccountID: oldID, ExternalAccountID: "2", AutoSync: true})
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: saxoID, LocalAccountID: mappedID, ExternalAccountID: "3", AutoSync: true})
+
+	// Mapped already has a Saxo mapping, so nothing moves
+	if _, err := mappingRepo.MoveToAccount(oldID, mappedID); !errors.Is(err, ErrAccountAlreadyMapped) {
+		t.Fatalf("MoveToAccount() to an account mapped on the same connection: error = %v; want ErrAccountAlreadyMapped", err)
+	}
+	if mappings, _ := mappingRepo.GetAllByLocalAccountID(oldID); len(mappings) != 2 {
+		t.Fatalf("old account has %d mappings after a failed move; want 2", len(mappings))
+	}
+
+	moved, err := mappingRepo.MoveToAccount(oldID, newID)
+	if err != nil {
+		t.Fatalf("MoveToAccount() error = %v", err)
+	}
+	if moved != 2 {
+		t.Errorf("MoveToAccount() moved %d mappings; want 2", moved)
+	}
+	if mappings, _ := mappingRepo.GetAllByLocalAccountID(newID); len(mappings) != 2 {
+		t.Errorf("new account has %d mappings; want 2", len(mappings))
+	}
+
+	// Deleting the old account no longer takes the mappings with it
+	if err := accountRepo.Delete(oldID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if mappings, _ := mappingRepo.GetByConnectionID(saxoID); len(mappings) != 2 {
+		t.Errorf("Saxo connection has %d mappings after deleting the old account; want 2", len(mappings))
+	}
+}
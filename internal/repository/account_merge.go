@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMergeIntoItself is returned when an account is merged into itself.
+var ErrMergeIntoItself = errors.New("cannot merge an account into itself")
+
+// AccountMergeResult counts the records moved by a merge.
+type AccountMergeResult struct {
+	Transactions int64
+	Holdings     int64
+	Mappings     int64
+}
+
+// Merge moves everything of the source account into the target account in one
+// database transaction and archives the source account.
+//
+// Transactions keep their balance_after, so the merged history is the union
+// of both histories and the most recent entry sets the balance. Where both
+// accounts hold the same symbol, the more recently updated holding is kept.
+// Imported acquisitions of a symbol move unless the target has its own, as
+// re-importing would replace them. Broker mappings move unless the target is
+// already mapped on the same connection, in which case the source mapping is
+// dropped. Goals funded from the source are funded from the target instead.
+func (r *AccountRepository) Merge(targetID, sourceID int64) (*AccountMergeResult, error) {
+	if targetID == sourceID {
+		return nil, ErrMergeIntoItself
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &AccountMergeResult{}
+
+	res, err := tx.Exec(`UPDATE transactions SET account_id = ? WHERE account_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("moving transactions: %w", err)
+	}
+	result.Transactions, _ = res.RowsAffected()
+
+	if err := snapshotRemovedHoldings(tx, "account_id = ?", sourceID); err != nil {
+		return nil, fmt.Errorf("recording holding snapshots: %w", err)
+	}
+
+	// Resolve holdings of the same symbol before moving, keeping the newest
+	if _, err := tx.Exec(`
+		DELETE FROM holdings
+		WHERE account_id = ? AND symbol IN (
+			SELECT s.symbol FROM holdings s
+			WHERE s.account_id = ? AND s.last_updated >= holdings.last_updated
+		)
+	`, targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("resolving duplicate holdings: %w", err)
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM holdings
+		WHERE account_id = ? AND symbol IN (SELECT symbol FROM holdings WHERE account_id = ?)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("resolving duplicate holdings: %w", err)
+	}
+	res, err = tx.Exec(`UPDATE holdings SET account_id = ? WHERE account_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("moving holdings: %w", err)
+	}
+	result.Holdings, _ = res.RowsAffected()
+	if err := snapshotHoldings(tx, "account_id = ?", targetID); err != nil {
+		return nil, fmt.Errorf("recording holding snapshots: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE holding_history SET account_id = ? WHERE account_id = ?`, targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("moving holding history: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM holding_acquisitions
+		WHERE account_id = ? AND symbol IN (SELECT symbol FROM holding_acquisitions WHERE account_id = ?)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("resolving duplicate acquisitions: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE holding_acquisitions SET account_id = ? WHERE account_id = ?`, targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("moving acquisitions: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM account_mappings
+		WHERE local_account_id = ? AND connection_id IN (
+			SELECT connection_id FROM account_mappings WHERE local_account_id = ?
+		)
+	`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("resolving duplicate mappings: %w", err)
+	}
+	res, err = tx.Exec(`UPDATE account_mappings SET local_account_id = ? WHERE local_account_id = ?`, targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("moving mappings: %w", err)
+	}
+	result.Mappings, _ = res.RowsAffected()
+
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO goal_funding_accounts (goal_id, account_id)
+		SELECT goal_id, ? FROM goal_funding_accounts WHERE account_id = ?
+	`, targetID, sourceID); err != nil {
+		return nil, fmt.Errorf("moving goal funding accounts: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM goal_funding_accounts WHERE account_id = ?`, sourceID); err != nil {
+		return nil, fmt.Errorf("moving goal funding accounts: %w", err)
+	}
+
+	res, err = tx.Exec(`UPDATE accounts SET is_active = 0 WHERE id = ?`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("archiving account: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, errors.New("account not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
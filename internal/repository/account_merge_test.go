@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestAccountRepository_Merge_MovesEverythingAndArchivesSource(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+	txnRepo := NewTransactionRepository(db)
+	holdingRepo := NewHoldingRepository(db)
+	mappingRepo := NewAccountMappingRepository(db)
+
+	targetID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Nordnet (manual)", Currency: "DKK", IsActive: true})
+	sourceID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Nordnet", Currency: "DKK", IsActive: true})
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	txnRepo.Create(&models.Transaction{AccountID: targetID, Amount: 1000, BalanceAfter: 1000, TransactionDate: day(1)})
+	txnRepo.Create(&models.Transaction{AccountID: sourceID, Amount: 1200, BalanceAfter: 1200, TransactionDate: day(5)})
+
+	// Both accounts hold AAPL; the source's is newer and wins. MSFT only
+	// exists in the target, VWRL only in the source.
+	holdingRepo.Create(&models.Holding{AccountID: targetID, Symbol: "AAPL", Quantity: 1, Currency: "USD"})
+	holdingRepo.Create(&models.Holding{AccountID: targetID, Symbol: "MSFT", Quantity: 1, Currency: "USD"})
+	holdingRepo.Create(&models.Holding{AccountID: sourceID, Symbol: "AAPL", Quantity: 2, Currency: "USD"})
+	holdingRepo.Create(&models.Holding{AccountID: sourceID, Symbol: "VWRL", Quantity: 3, Currency: "EUR"})
+	db.Exec(`UPDATE holdings SET last_updated = ? WHERE account_id = ?`, day(1), targetID)
+	db.Exec(`UPDATE holdings SET last_updated = ? WHERE account_id = ?`, day(5), sourceID)
+
+	connID, _ := NewBrokerConnectionRepository(db).Create(&models.BrokerConnection{
+		UserID: userID, BrokerType: "nordnet", Username: "user", Country: "dk", IsActive: true,
+	})
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: connID, LocalAccountID: sourceID, ExternalAccountID: "1", AutoSync: true})
+
+	result, err := accountRepo.Merge(targetID, sourceID)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if result.Transactions != 1 || result.Holdings != 2 || result.Mappings != 1 {
+		t.Errorf("Merge() = %+v; want 1 transaction, 2 holdings, 1 mapping", result)
+	}
+
+	if count, _ := txnRepo.CountByAccountID(targetID); count != 2 {
+		t.Errorf("target has %d transactions, want 2", count)
+	}
+	if balance, _ := txnRepo.GetLatestBalance(targetID); balance != 1200 {
+		t.Errorf("target balance = %.2f, want 1200", balance)
+	}
+
+	holdings, _ := holdingRepo.GetByAccountID(targetID)
+	quantities := make(map[string]float64)
+	for _, h := range holdings {
+		quantities[h.Symbol] = h.Quantity
+	}
+	if len(holdings) != 3 || quantities["AAPL"] != 2 || quantities["MSFT"] != 1 || quantities["VWRL"] != 3 {
+		t.Errorf("target holdings = %v; want AAPL 2, MSFT 1, VWRL 3", quantities)
+	}
+
+	if mapping, _ := mappingRepo.GetByLocalAccountID(targetID); mapping == nil || mapping.ExternalAccountID != "1" {
+		t.Errorf("target mapping = %+v; want external account 1", mapping)
+	}
+
+	source, _ := accountRepo.GetByID(sourceID)
+	if source == nil || source.IsActive {
+		t.Errorf("source account = %+v; want archived", source)
+	}
+}
+
+func TestAccountRepository_Merge_DropsSourceMappingOnSameConnection(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+	mappingRepo := NewAccountMappingRepository(db)
+
+	targetID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "A", Currency: "DKK", IsActive: true})
+	sourceID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "B", Currency: "DKK", IsActive: true})
+	connID, _ := NewBrokerConnectionRepository(db).Create(&models.BrokerConnection{
+		UserID: userID, BrokerType: "nordnet", Username: "user", Country: "dk", IsActive: true,
+	})
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: connID, LocalAccountID: targetID, ExternalAccountID: "1"})
+	mappingRepo.Create(&models.AccountMapping{ConnectionID: connID, LocalAccountID: sourceID, ExternalAccountID: "2"})
+
+	result, err := accountRepo.Merge(targetID, sourceID)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if result.Mappings != 0 {
+		t.Errorf("Merge() moved %d mappings, want 0", result.Mappings)
+	}
+	if mapping, _ := mappingRepo.GetByLocalAccountID(targetID); mapping == nil || mapping.ExternalAccountID != "1" {
+		t.Errorf("target mapping = %+v; want external account 1", mapping)
+	}
+}
+
+func TestAccountRepository_Merge_SameAccount_ReturnsError(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+
+	id, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "A", Currency: "DKK", IsActive: true})
+	if _, err := accountRepo.Merge(id, id); !errors.Is(err, ErrMergeIntoItself) {
+		t.Errorf("Merge() into itself error = %v; want %v", err, ErrMergeIntoItself)
+	}
+}
+
+func TestAccountRepository_Merge_MovesAcquisitionsAndGoalFunding(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+	acquisitionRepo := NewHoldingAcquisitionRepository(db)
+	goalRepo := NewGoalRepository(db)
+
+	targetID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "A", Currency: "DKK", IsActive: true})
+	sourceID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "B", Currency: "DKK", IsActive: true})
+
+	// The target's own AAPL buys are kept; the source's VWRL buys move
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	acquisitionRepo.ReplaceSymbols(targetID, []*models.HoldingAcquisition{{Symbol: "AAPL", TradeDate: day, Quantity: 1, Price: 100}})
+	acquisitionRepo.ReplaceSymbols(sourceID, []*models.HoldingAcquisition{
+		{Symbol: "AAPL", TradeDate: day, Quantity: 1, Price: 100},
+		{Symbol: "VWRL", TradeDate: day, Quantity: 3, Price: 90},
+	})
+
+	// One goal is funded from both accounts, another from the source only
+	both, _ := goalRepo.Create(&models.Goal{UserID: userID, Name: "Both", TargetAmount: 1000, FundingAccountIDs: []int64{targetID, sourceID}})
+	sourceOnly, _ := goalRepo.Create(&models.Goal{UserID: userID, Name: "Source", TargetAmount: 1000, FundingAccountIDs: []int64{sourceID}})
+
+	if _, err := accountRepo.Merge(targetID, sourceID); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	acquisitions, _ := acquisitionRepo.GetByAccountID(targetID)
+	if len(acquisitions) != 2 || acquisitions[0].Symbol == acquisitions[1].Symbol {
+		t.Errorf("target acquisitions = %+v; want one AAPL and one VWRL", acquisitions)
+	}
+	if left, _ := acquisitionRepo.GetByAccountID(sourceID); len(left) != 0 {
+		t.Errorf("source acquisitions = %+v; want none", left)
+	}
+
+	for _, id := range []int64{both, sourceOnly} {
+		goal, _ := goalRepo.GetByID(id)
+		if goal == nil || len(goal.FundingAccountIDs) != 1 || goal.FundingAccountIDs[0] != targetID {
+			t.Errorf("goal %d funding accounts = %+v; want only the target", id, goal)
+		}
+	}
+}
+
+func TestTransactionRepository_GetCombinedBalanceHistory(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	accountRepo := NewAccountRepository(db)
+	txnRepo := NewTransactionRepository(db)
+
+	a, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "A", Currency: "DKK", IsActive: true})
+	b, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "B", Currency: "DKK", IsActive: true})
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	txnRepo.Create(&models.Transaction{AccountID: a, BalanceAfter: 100, TransactionDate: day(1)})
+	txnRepo.Create(&models.Transaction{AccountID: b, BalanceAfter: 150, TransactionDate: day(3)})
+	txnRepo.Create(&models.Transaction{AccountID: a, BalanceAfter: 200, TransactionDate: day(5)})
+	txnRepo.Create(&models.Transaction{AccountID: b, BalanceAfter: 210, TransactionDate: day(5)})
+
+	points, err := txnRepo.GetCombinedBalanceHistory(a, b)
+	if err != nil {
+		t.Fatalf("GetCombinedBalanceHistory() error = %v", err)
+	}
+	want := []float64{100, 150, 210}
+	if len(points) != len(want) {
+		t.Fatalf("GetCombinedBalanceHistory() returned %d points, want %d", len(points), len(want))
+	}
+	for i, p := range points {
+		if p.Balance != want[i] {
+			t.Errorf("point %d balance = %.0f, want %.0f", i, p.Balance, want[i])
+		}
+	}
+}
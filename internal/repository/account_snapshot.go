@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// AccountSnapshotRepository handles snapshots of accounts taken on demand.
+type AccountSnapshotRepository struct {
+	db *database.DB
+}
+
+// NewAccountSnapshotRepository creates a new AccountSnapshotRepository.
+func NewAccountSnapshotRepository(db *database.DB) *AccountSnapshotRepository {
+	return &AccountSnapshotRepository{db: db}
+}
+
+// Create records the current balance and holdings of an account in one
+// transaction, so they match even while a sync runs, and returns the
+// snapshot's ID. Today's holding snapshots are updated as well, so the
+// holdings history includes the moment.
+func (r *AccountSnapshotRepository) Create(accountID int64, note string, at time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var balance sql.NullFloat64
+	err = tx.QueryRow(`
+		SELECT balance_after
+		FROM transactions
+		WHERE account_id = ?
+		ORDER BY transaction_date DESC, id DESC
+		LIMIT 1
+	`, accountID).Scan(&balance)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO account_snapshots (account_id, balance, note, taken_at)
+		VALUES (?, ?, ?, ?)
+	`, accountID, balance.Float64, note, at)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO account_snapshot_holdings (snapshot_id, symbol, name, quantity, current_price, current_value, currency)
+		SELECT ?, symbol, name, quantity, current_price, current_value, currency
+		FROM holdings
+		WHERE account_id = ?
+	`, id, accountID); err != nil {
+		return 0, err
+	}
+	if err := snapshotHoldings(tx, "account_id = ?", accountID); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// GetByAccountID returns the snapshots of an account without their
+// holdings, newest first.
+func (r *AccountSnapshotRepository) GetByAccountID(accountID int64) ([]*models.AccountSnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT id, account_id, balance, note, taken_at
+		FROM account_snapshots
+		WHERE account_id = ?
+		ORDER BY taken_at DESC, id DESC
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]*models.AccountSnapshot, 0)
+	for rows.Next() {
+		s := &models.AccountSnapshot{}
+		if err := rows.Scan(&s.ID, &s.AccountID, &s.Balance, &s.Note, &s.TakenAt); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetByID returns a snapshot with its holdings ordered by symbol, or nil if
+// not found.
+func (r *AccountSnapshotRepository) GetByID(id int64) (*models.AccountSnapshot, error) {
+	s := &models.AccountSnapshot{}
+	err := r.db.QueryRow(`
+		SELECT id, account_id, balance, note, taken_at
+		FROM account_snapshots
+		WHERE id = ?
+	`, id).Scan(&s.ID, &s.AccountID, &s.Balance, &s.Note, &s.TakenAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT symbol, name, quantity, current_price, current_value, currency
+		FROM account_snapshot_holdings
+		WHERE snapshot_id = ?
+		ORDER BY symbol
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.AccountSnapshotHolding
+		if err := rows.Scan(&h.Symbol, &h.Name, &h.Quantity, &h.CurrentPrice, &h.CurrentValue, &h.Currency); err != nil {
+			return nil, err
+		}
+		s.Holdings = append(s.Holdings, h)
+	}
+	return s, rows.Err()
+}
+
+// Delete removes a snapshot and its holdings.
+func (r *AccountSnapshotRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM account_snapshots WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestAccountSnapshotRepository_CapturesBalanceAndHoldings(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	holdingRepo := NewHoldingRepository(db)
+	repo := NewAccountSnapshotRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	if _, err := NewTransactionRepository(db).Create(&models.Transaction{
+		AccountID: accountID, Amount: 25000, BalanceAfter: 25000, TransactionDate: time.Now(),
+	}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	if err := holdingRepo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "DK0062498333", Name: "Novo",
+		Quantity: 20, CurrentPrice: 800, CurrentValue: 16000, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	takenAt := time.Now().UTC().Truncate(time.Second)
+	id, err := repo.Create(accountID, "Before selling Novo", takenAt)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Later changes leave the snapshot alone
+	if err := holdingRepo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "DK0062498333", Name: "Novo",
+		Quantity: 5, CurrentPrice: 800, CurrentValue: 4000, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	s, err := repo.GetByID(id)
+	if err != nil || s == nil {
+		t.Fatalf("GetByID() = %v, %v", s, err)
+	}
+	if s.Balance != 25000 || s.Note != "Before selling Novo" || !s.TakenAt.Equal(takenAt) {
+		t.Errorf("snapshot = %+v; want balance 25000, the note and the time taken", s)
+	}
+	if len(s.Holdings) != 1 || s.Holdings[0].Quantity != 20 || s.Holdings[0].CurrentValue != 16000 {
+		t.Errorf("snapshot holdings = %+v; want 20 Novo worth 16000", s.Holdings)
+	}
+
+	list, err := repo.GetByAccountID(accountID)
+	if err != nil || len(list) != 1 || list[0].ID != id {
+		t.Fatalf("GetByAccountID() = %v, %v; want the snapshot", list, err)
+	}
+
+	if err := repo.Delete(id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if s, _ := repo.GetByID(id); s != nil {
+		t.Error("snapshot still found after Delete()")
+	}
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"errors"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"wealth_tracker/internal/database"
 	"wealth_tracker/internal/models"
@@ -395,6 +399,51 @@ func TestAccountRepository_Update_RemoveCategory_Succeeds(t *testing.T) {
 	}
 }
 
+func TestAccountRepository_SetCategory_MovesAllAccounts(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	id1, _ := repo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK"})
+	id2, _ := repo.Create(&models.Account{UserID: userID, CategoryID: &categoryID, Name: "Broker", Currency: "DKK"})
+
+	result, _ := db.Exec(`INSERT INTO categories (user_id, name, color) VALUES (?, ?, ?)`, userID, "Pension", "#10b981")
+	pensionID, _ := result.LastInsertId()
+
+	if err := repo.SetCategory(userID, []int64{id1, id2}, &pensionID); err != nil {
+		t.Fatalf("SetCategory() error = %v, want nil", err)
+	}
+	for _, id := range []int64{id1, id2} {
+		found, _ := repo.GetByID(id)
+		if found.CategoryID == nil || *found.CategoryID != pensionID {
+			t.Errorf("account %d category = %v; want %d", id, found.CategoryID, pensionID)
+		}
+	}
+
+	if err := repo.SetCategory(userID, []int64{id1}, nil); err != nil {
+		t.Fatalf("SetCategory(nil) error = %v, want nil", err)
+	}
+	if found, _ := repo.GetByID(id1); found.CategoryID != nil {
+		t.Errorf("category after clearing = %v; want nil", found.CategoryID)
+	}
+}
+
+func TestAccountRepository_SetCategory_OtherUsersAccount_MovesNone(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	result, _ := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES (?, ?, ?)`, "other@example.com", "hashedpassword", "Other")
+	otherID, _ := result.LastInsertId()
+	own, _ := repo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK"})
+	theirs, _ := repo.Create(&models.Account{UserID: otherID, Name: "Theirs", Currency: "DKK"})
+
+	if err := repo.SetCategory(userID, []int64{own, theirs}, &categoryID); err == nil {
+		t.Fatal("SetCategory() with another user's account succeeded; want an error")
+	}
+	if found, _ := repo.GetByID(own); found.CategoryID != nil {
+		t.Error("SetCategory() moved an account although the batch failed")
+	}
+}
+
 func TestAccountRepository_Update_NonExistent_ReturnsError(t *testing.T) {
 	db, userID, _ := setupAccountTestDB(t)
 	repo := NewAccountRepository(db)
@@ -412,6 +461,33 @@ func TestAccountRepository_Update_NonExistent_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestAccountRepository_Update_StaleCopy_ReturnsErrStaleUpdate(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	id, _ := repo.Create(&models.Account{UserID: userID, Name: "Account", Currency: "DKK", IsActive: true})
+	first, _ := repo.GetByID(id)
+	second, _ := repo.GetByID(id)
+
+	first.Name = "First tab"
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	second.Name = "Second tab"
+	if err := repo.Update(second); !errors.Is(err, ErrStaleUpdate) {
+		t.Fatalf("Update() of stale copy error = %v, want ErrStaleUpdate", err)
+	}
+
+	found, _ := repo.GetByID(id)
+	if found.Name != "First tab" {
+		t.Errorf("Name = %s, want First tab", found.Name)
+	}
+	found.Name = "Reloaded"
+	if err := repo.Update(found); err != nil {
+		t.Errorf("Update() after reload error = %v, want nil", err)
+	}
+}
+
 // Delete tests
 
 func TestAccountRepository_Delete_ExistingAccount_Succeeds(t *testing.T) {
@@ -439,6 +515,33 @@ func TestAccountRepository_Delete_ExistingAccount_Succeeds(t *testing.T) {
 	}
 }
 
+func TestAccountRepository_Delete_RemovesGoalFundingLinks(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+	goalRepo := NewGoalRepository(db)
+
+	keptID, _ := repo.Create(&models.Account{UserID: userID, Name: "Kept", Currency: "DKK", IsActive: true})
+	deletedID, _ := repo.Create(&models.Account{UserID: userID, Name: "Deleted", Currency: "DKK", IsActive: true})
+	goalID, err := goalRepo.Create(&models.Goal{UserID: userID, Name: "House", TargetAmount: 1000, FundingAccountIDs: []int64{keptID, deletedID}})
+	if err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	if err := repo.Delete(deletedID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	goal, _ := goalRepo.GetByID(goalID)
+	if goal == nil || len(goal.FundingAccountIDs) != 1 || goal.FundingAccountIDs[0] != keptID {
+		t.Errorf("goal after deleting a funding account = %+v; want only the kept account", goal)
+	}
+	var links int
+	db.QueryRow(`SELECT COUNT(*) FROM goal_funding_accounts WHERE account_id = ?`, deletedID).Scan(&links)
+	if links != 0 {
+		t.Errorf("%d goal links to the deleted account remain; want none", links)
+	}
+}
+
 func TestAccountRepository_Delete_NonExistent_ReturnsError(t *testing.T) {
 	db, _, _ := setupAccountTestDB(t)
 	repo := NewAccountRepository(db)
@@ -506,3 +609,111 @@ func TestAccountRepository_CountActiveLiabilities_ReturnsCorrectCount(t *testing
 		t.Errorf("CountActiveLiabilities() = %d, want 2", count)
 	}
 }
+
+func TestAccountRepository_GetByUserIDOpenOn_BoundsByOpenedAndClosed(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	opened := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	closed := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	repo.Create(&models.Account{UserID: userID, Name: "Always", Currency: "DKK", IsActive: true})
+	repo.Create(&models.Account{UserID: userID, Name: "Opened", Currency: "DKK", IsActive: true, OpenedAt: &opened})
+	repo.Create(&models.Account{UserID: userID, Name: "Closed", Currency: "DKK", IsActive: false, ClosedAt: &closed})
+	repo.Create(&models.Account{UserID: userID, Name: "Inactive", Currency: "DKK", IsActive: false})
+
+	for day, want := range map[string]string{
+		"2024-02-29": "Always,Closed",
+		"2024-03-01": "Always,Closed,Opened",
+		"2024-05-31": "Always,Closed,Opened",
+		"2024-06-01": "Always,Opened",
+	} {
+		d, _ := time.Parse("2006-01-02", day)
+		accounts, err := repo.GetByUserIDOpenOn(userID, d)
+		if err != nil {
+			t.Fatalf("GetByUserIDOpenOn(%s) error = %v", day, err)
+		}
+		names := make([]string, len(accounts))
+		for i, a := range accounts {
+			names[i] = a.Name
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != want {
+			t.Errorf("GetByUserIDOpenOn(%s) = %s; want %s", day, got, want)
+		}
+	}
+}
+
+func TestAccountRepository_GetByUserIDActiveOnly_ClosesOnClosingDate(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+	repo.Create(&models.Account{UserID: userID, Name: "Closing", Currency: "DKK", IsActive: true, ClosedAt: &tomorrow})
+	repo.Create(&models.Account{UserID: userID, Name: "Closed", Currency: "DKK", IsActive: true, ClosedAt: &today})
+
+	// An account counts until its closing date comes, without being saved again
+	accounts, err := repo.GetByUserIDActiveOnly(userID)
+	if err != nil {
+		t.Fatalf("GetByUserIDActiveOnly() error = %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].Name != "Closing" || accounts[0].IsClosed() {
+		t.Errorf("GetByUserIDActiveOnly() = %v; want only the account closing tomorrow", accounts)
+	}
+	if count, _ := repo.CountActiveAssets(userID); count != 1 {
+		t.Errorf("CountActiveAssets() = %d; want 1", count)
+	}
+
+	if _, err := db.Exec(`UPDATE accounts SET closed_at = ? WHERE name = 'Closing'`, today.AddDate(0, 0, -1)); err != nil {
+		t.Fatalf("moving closing date: %v", err)
+	}
+	if accounts, _ := repo.GetByUserIDActiveOnly(userID); len(accounts) != 0 {
+		t.Errorf("GetByUserIDActiveOnly() after the closing date = %v; want none", accounts)
+	}
+}
+
+func TestAccountRepository_Reorder_PinnedFirstThenUserOrder(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewAccountRepository(db)
+
+	zebra, _ := repo.Create(&models.Account{UserID: userID, Name: "Zebra Bank", Currency: "DKK"})
+	alpha, _ := repo.Create(&models.Account{UserID: userID, Name: "Alpha Invest", Currency: "DKK"})
+	middle, _ := repo.Create(&models.Account{UserID: userID, Name: "Middle Fund", Currency: "DKK"})
+
+	if err := repo.Reorder(userID, []int64{zebra, alpha}); err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	if err := repo.SetPinned(middle, true); err != nil {
+		t.Fatalf("SetPinned() error = %v", err)
+	}
+	repo.Create(&models.Account{UserID: userID, Name: "Beta Savings", Currency: "DKK"})
+
+	found, err := repo.GetByUserID(userID)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v, want nil", err)
+	}
+	var names []string
+	for _, a := range found {
+		names = append(names, a.Name)
+	}
+	want := []string{"Middle Fund", "Zebra Bank", "Alpha Invest", "Beta Savings"}
+	for i := range want {
+		if i >= len(names) || names[i] != want[i] {
+			t.Fatalf("accounts = %v, want %v", names, want)
+		}
+	}
+	if !found[0].IsPinned || found[1].SortOrder != 1 {
+		t.Errorf("pinned = %v, sort order = %d; want pinned and position 1", found[0].IsPinned, found[1].SortOrder)
+	}
+
+	// Accounts of other users are not reordered
+	result, _ := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES ('other@example.com', 'x', 'Other')`)
+	otherID, _ := result.LastInsertId()
+	if err := repo.Reorder(otherID, []int64{alpha}); err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	if account, _ := repo.GetByID(alpha); account.SortOrder != 2 {
+		t.Errorf("sort order = %d after another user's reorder, want 2", account.SortOrder)
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// AdvisorShareRepository handles the accounts and goals users share with
+// advisors.
+type AdvisorShareRepository struct {
+	db *database.DB
+}
+
+// NewAdvisorShareRepository creates a new AdvisorShareRepository.
+func NewAdvisorShareRepository(db *database.DB) *AdvisorShareRepository {
+	return &AdvisorShareRepository{db: db}
+}
+
+// advisorShareSelect selects shares joined with both users' emails and the
+// name of the shared account or goal.
+const advisorShareSelect = `
+	SELECT s.id, s.owner_id, s.advisor_id, s.account_id, s.goal_id, s.created_at,
+		o.email, a.email, COALESCE(acc.name, g.name, '')
+	FROM advisor_shares s
+	JOIN users o ON o.id = s.owner_id
+	JOIN users a ON a.id = s.advisor_id
+	LEFT JOIN accounts acc ON acc.id = s.account_id
+	LEFT JOIN goals g ON g.id = s.goal_id
+`
+
+// SetShares replaces what an owner shares with an advisor by the given
+// accounts and goals. Sharing nothing removes the advisor.
+func (r *AdvisorShareRepository) SetShares(ownerID, advisorID int64, accountIDs, goalIDs []int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM advisor_shares WHERE owner_id = ? AND advisor_id = ?`, ownerID, advisorID); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, id := range accountIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO advisor_shares (owner_id, advisor_id, account_id, created_at)
+			VALUES (?, ?, ?, ?)
+		`, ownerID, advisorID, id, now); err != nil {
+			return err
+		}
+	}
+	for _, id := range goalIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO advisor_shares (owner_id, advisor_id, goal_id, created_at)
+			VALUES (?, ?, ?, ?)
+		`, ownerID, advisorID, id, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetByOwnerID retrieves everything a user shares, ordered by advisor.
+func (r *AdvisorShareRepository) GetByOwnerID(ownerID int64) ([]*models.AdvisorShare, error) {
+	return r.queryShares(advisorShareSelect+`
+		WHERE s.owner_id = ?
+		ORDER BY a.email, s.goal_id IS NOT NULL, COALESCE(acc.name, g.name)
+	`, ownerID)
+}
+
+// GetByAdvisorID retrieves everything shared with a user, ordered by owner.
+func (r *AdvisorShareRepository) GetByAdvisorID(advisorID int64) ([]*models.AdvisorShare, error) {
+	return r.queryShares(advisorShareSelect+`
+		WHERE s.advisor_id = ?
+		ORDER BY o.email, s.goal_id IS NOT NULL, COALESCE(acc.name, g.name)
+	`, advisorID)
+}
+
+// SharesAccount reports whether an account is shared with a user.
+func (r *AdvisorShareRepository) SharesAccount(advisorID, accountID int64) (bool, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM advisor_shares WHERE advisor_id = ? AND account_id = ?`, advisorID, accountID).Scan(&n)
+	return n > 0, err
+}
+
+// SharesGoal reports whether a goal is shared with a user.
+func (r *AdvisorShareRepository) SharesGoal(advisorID, goalID int64) (bool, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM advisor_shares WHERE advisor_id = ? AND goal_id = ?`, advisorID, goalID).Scan(&n)
+	return n > 0, err
+}
+
+// queryShares runs a query selecting advisorShareSelect columns.
+func (r *AdvisorShareRepository) queryShares(query string, args ...any) ([]*models.AdvisorShare, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*models.AdvisorShare
+	for rows.Next() {
+		share := &models.AdvisorShare{}
+		var accountID, goalID sql.NullInt64
+		if err := rows.Scan(
+			&share.ID,
+			&share.OwnerID,
+			&share.AdvisorID,
+			&accountID,
+			&goalID,
+			&share.CreatedAt,
+			&share.OwnerEmail,
+			&share.AdvisorEmail,
+			&share.ItemName,
+		); err != nil {
+			return nil, err
+		}
+		if accountID.Valid {
+			share.AccountID = &accountID.Int64
+		}
+		if goalID.Valid {
+			share.GoalID = &goalID.Int64
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
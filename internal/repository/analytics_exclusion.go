@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// AnalyticsExclusionRepository handles analytics exclusion database operations.
+type AnalyticsExclusionRepository struct {
+	db *database.DB
+}
+
+// NewAnalyticsExclusionRepository creates a new AnalyticsExclusionRepository.
+func NewAnalyticsExclusionRepository(db *database.DB) *AnalyticsExclusionRepository {
+	return &AnalyticsExclusionRepository{db: db}
+}
+
+// Upsert excludes an instrument for the user, or updates the reason if it is
+// already excluded, and returns the exclusion's ID.
+func (r *AnalyticsExclusionRepository) Upsert(exclusion *models.AnalyticsExclusion) (int64, error) {
+	_, err := r.db.Exec(`
+		INSERT INTO analytics_exclusions (user_id, symbol, reason, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, symbol) DO UPDATE SET reason = excluded.reason
+	`, exclusion.UserID, exclusion.Symbol, exclusion.Reason, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	// LastInsertId is not reliable for the update branch of an upsert
+	var id int64
+	err = r.db.QueryRow(`
+		SELECT id FROM analytics_exclusions WHERE user_id = ? AND symbol = ?
+	`, exclusion.UserID, exclusion.Symbol).Scan(&id)
+	return id, err
+}
+
+// GetByID retrieves an exclusion by ID.
+func (r *AnalyticsExclusionRepository) GetByID(id int64) (*models.AnalyticsExclusion, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, reason, created_at
+		FROM analytics_exclusions
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exclusions, err := r.scanExclusions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(exclusions) == 0 {
+		return nil, nil
+	}
+	return exclusions[0], nil
+}
+
+// GetByUserID retrieves a user's exclusions ordered by symbol.
+func (r *AnalyticsExclusionRepository) GetByUserID(userID int64) ([]*models.AnalyticsExclusion, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, reason, created_at
+		FROM analytics_exclusions
+		WHERE user_id = ?
+		ORDER BY symbol
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanExclusions(rows)
+}
+
+// Delete removes an exclusion.
+func (r *AnalyticsExclusionRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM analytics_exclusions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("analytics exclusion not found")
+	}
+	return nil
+}
+
+// scanExclusions scans analytics exclusion rows.
+func (r *AnalyticsExclusionRepository) scanExclusions(rows *sql.Rows) ([]*models.AnalyticsExclusion, error) {
+	exclusions := make([]*models.AnalyticsExclusion, 0)
+	for rows.Next() {
+		e := &models.AnalyticsExclusion{}
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Symbol, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, e)
+	}
+	return exclusions, rows.Err()
+}
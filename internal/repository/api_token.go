@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// APITokenRepository handles user-wide API token database operations.
+type APITokenRepository struct {
+	db *database.DB
+}
+
+// NewAPITokenRepository creates a new APITokenRepository.
+func NewAPITokenRepository(db *database.DB) *APITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+// Create inserts a new API token and returns its ID.
+func (r *APITokenRepository) Create(token *models.APIToken) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO api_tokens (user_id, name, scope, token_hash, token_prefix, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, token.UserID, token.Name, token.Scope, token.TokenHash, token.TokenPrefix, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves an API token by ID, or nil if there is none.
+func (r *APITokenRepository) GetByID(id int64) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(`
+		SELECT id, user_id, name, scope, token_hash, token_prefix, last_used_at, created_at
+		FROM api_tokens
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// GetByHash retrieves the API token with the given hash, or nil if there is
+// none.
+func (r *APITokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(`
+		SELECT id, user_id, name, scope, token_hash, token_prefix, last_used_at, created_at
+		FROM api_tokens
+		WHERE token_hash = ?
+	`, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// GetByUserID retrieves all API tokens of a user, ordered by name.
+func (r *APITokenRepository) GetByUserID(userID int64) ([]*models.APIToken, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, scope, token_hash, token_prefix, last_used_at, created_at
+		FROM api_tokens
+		WHERE user_id = ?
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// TouchLastUsed records that an API token was just used.
+func (r *APITokenRepository) TouchLastUsed(id int64) error {
+	_, err := r.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, time.Now(), id)
+	return err
+}
+
+// Delete revokes an API token.
+func (r *APITokenRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("api token not found")
+	}
+	return nil
+}
+
+// scanAPIToken scans an api_tokens row.
+func scanAPIToken(row interface{ Scan(...any) error }) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Scope,
+		&token.TokenHash,
+		&token.TokenPrefix,
+		&lastUsedAt,
+		&token.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
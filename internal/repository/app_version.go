@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// AppVersionRepository handles the releases the app has been started with.
+type AppVersionRepository struct {
+	db *database.DB
+}
+
+// NewAppVersionRepository creates a new AppVersionRepository.
+func NewAppVersionRepository(db *database.DB) *AppVersionRepository {
+	return &AppVersionRepository{db: db}
+}
+
+// Record stores a release on its first start; later starts leave it as is.
+func (r *AppVersionRepository) Record(version string, schemaVersion int) error {
+	_, err := r.db.Exec(`
+		INSERT OR IGNORE INTO app_versions (version, schema_version, first_started_at)
+		VALUES (?, ?, ?)
+	`, version, schemaVersion, time.Now())
+	return err
+}
+
+// Get retrieves a release. Returns nil if it was never started.
+func (r *AppVersionRepository) Get(version string) (*models.AppVersion, error) {
+	v := &models.AppVersion{}
+	var dismissed int
+	err := r.db.QueryRow(`
+		SELECT version, schema_version, first_started_at, notice_dismissed
+		FROM app_versions
+		WHERE version = ?
+	`, version).Scan(&v.Version, &v.SchemaVersion, &v.FirstStartedAt, &dismissed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.NoticeDismissed = dismissed == 1
+	return v, nil
+}
+
+// MaxSchemaVersion returns the highest schema version any release has run.
+func (r *AppVersionRepository) MaxSchemaVersion() (int, error) {
+	var max int
+	err := r.db.QueryRow(`SELECT COALESCE(MAX(schema_version), 0) FROM app_versions`).Scan(&max)
+	return max, err
+}
+
+// DismissNotice stops showing a release's what's new page to anyone.
+func (r *AppVersionRepository) DismissNotice(version string) error {
+	_, err := r.db.Exec(`UPDATE app_versions SET notice_dismissed = 1 WHERE version = ?`, version)
+	return err
+}
@@ -23,9 +23,10 @@ func NewBrokerConnectionRepository(db *database.DB) *BrokerConnectionRepository
 // Note: CPR is stored for Signicat MitID-CPR verification (should be encrypted in production).
 func (r *BrokerConnectionRepository) Create(conn *models.BrokerConnection) (int64, error) {
 	result, err := r.db.Exec(`
-		INSERT INTO broker_connections (user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, conn.UserID, conn.BrokerType, conn.Username, conn.CPR, conn.Country, conn.AppKey, conn.AppSecret, conn.RedirectURI, boolToInt(conn.IsActive))
+		INSERT INTO broker_connections (user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri, is_active, sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, conn.UserID, conn.BrokerType, conn.Username, conn.CPR, conn.Country, conn.AppKey, conn.AppSecret, conn.RedirectURI, boolToInt(conn.IsActive),
+		conn.SyncWindowStart, syncWindowEnd(conn.SyncWindowEnd), boolToInt(conn.SkipWeekends), conn.SyncSchedule, conn.ProxyURL, conn.UserAgent, conn.APIURL)
 	if err != nil {
 		return 0, err
 	}
@@ -36,7 +37,8 @@ func (r *BrokerConnectionRepository) Create(conn *models.BrokerConnection) (int6
 func (r *BrokerConnectionRepository) GetByID(id int64) (*models.BrokerConnection, error) {
 	row := r.db.QueryRow(`
 		SELECT id, user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri,
-		       is_active, last_sync_at, last_sync_status, last_sync_error, created_at, updated_at
+		       is_active, last_sync_at, last_sync_status, last_sync_error, last_success_at, credential_reminded_at,
+		       sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url, created_at, updated_at
 		FROM broker_connections
 		WHERE id = ?
 	`, id)
@@ -48,7 +50,8 @@ func (r *BrokerConnectionRepository) GetByID(id int64) (*models.BrokerConnection
 func (r *BrokerConnectionRepository) GetByUserID(userID int64) ([]*models.BrokerConnection, error) {
 	rows, err := r.db.Query(`
 		SELECT id, user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri,
-		       is_active, last_sync_at, last_sync_status, last_sync_error, created_at, updated_at
+		       is_active, last_sync_at, last_sync_status, last_sync_error, last_success_at, credential_reminded_at,
+		       sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url, created_at, updated_at
 		FROM broker_connections
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -65,7 +68,8 @@ func (r *BrokerConnectionRepository) GetByUserID(userID int64) ([]*models.Broker
 func (r *BrokerConnectionRepository) GetByUserAndBroker(userID int64, brokerType string) (*models.BrokerConnection, error) {
 	row := r.db.QueryRow(`
 		SELECT id, user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri,
-		       is_active, last_sync_at, last_sync_status, last_sync_error, created_at, updated_at
+		       is_active, last_sync_at, last_sync_status, last_sync_error, last_success_at, credential_reminded_at,
+		       sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url, created_at, updated_at
 		FROM broker_connections
 		WHERE user_id = ? AND broker_type = ?
 	`, userID, brokerType)
@@ -77,7 +81,8 @@ func (r *BrokerConnectionRepository) GetByUserAndBroker(userID int64, brokerType
 func (r *BrokerConnectionRepository) GetActiveByUserID(userID int64) ([]*models.BrokerConnection, error) {
 	rows, err := r.db.Query(`
 		SELECT id, user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri,
-		       is_active, last_sync_at, last_sync_status, last_sync_error, created_at, updated_at
+		       is_active, last_sync_at, last_sync_status, last_sync_error, last_success_at, credential_reminded_at,
+		       sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url, created_at, updated_at
 		FROM broker_connections
 		WHERE user_id = ? AND is_active = 1
 		ORDER BY created_at DESC
@@ -90,13 +95,35 @@ func (r *BrokerConnectionRepository) GetActiveByUserID(userID int64) ([]*models.
 	return r.scanConnections(rows)
 }
 
+// GetAllActive retrieves the active broker connections of all users, oldest
+// first.
+func (r *BrokerConnectionRepository) GetAllActive() ([]*models.BrokerConnection, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, broker_type, username, cpr, country, app_key, app_secret, redirect_uri,
+		       is_active, last_sync_at, last_sync_status, last_sync_error, last_success_at, credential_reminded_at,
+		       sync_window_start, sync_window_end, skip_weekends, sync_schedule, proxy_url, user_agent, api_url, created_at, updated_at
+		FROM broker_connections
+		WHERE is_active = 1
+		ORDER BY created_at ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanConnections(rows)
+}
+
 // Update updates an existing broker connection.
 func (r *BrokerConnectionRepository) Update(conn *models.BrokerConnection) error {
 	result, err := r.db.Exec(`
 		UPDATE broker_connections
-		SET username = ?, cpr = ?, country = ?, app_key = ?, app_secret = ?, redirect_uri = ?, is_active = ?, updated_at = CURRENT_TIMESTAMP
+		SET username = ?, cpr = ?, country = ?, app_key = ?, app_secret = ?, redirect_uri = ?, is_active = ?,
+		    sync_window_start = ?, sync_window_end = ?, skip_weekends = ?, sync_schedule = ?,
+		    proxy_url = ?, user_agent = ?, api_url = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, conn.Username, conn.CPR, conn.Country, conn.AppKey, conn.AppSecret, conn.RedirectURI, boolToInt(conn.IsActive), conn.ID)
+	`, conn.Username, conn.CPR, conn.Country, conn.AppKey, conn.AppSecret, conn.RedirectURI, boolToInt(conn.IsActive),
+		conn.SyncWindowStart, syncWindowEnd(conn.SyncWindowEnd), boolToInt(conn.SkipWeekends), conn.SyncSchedule, conn.ProxyURL, conn.UserAgent, conn.APIURL, conn.ID)
 	if err != nil {
 		return err
 	}
@@ -111,13 +138,17 @@ func (r *BrokerConnectionRepository) Update(conn *models.BrokerConnection) error
 	return nil
 }
 
-// UpdateSyncStatus updates the sync status of a connection.
+// UpdateSyncStatus updates the sync status of a connection. A "success"
+// status also records the time as the last successful sync.
 func (r *BrokerConnectionRepository) UpdateSyncStatus(id int64, status, errorMsg string) error {
+	now := time.Now()
 	result, err := r.db.Exec(`
 		UPDATE broker_connections
-		SET last_sync_at = ?, last_sync_status = ?, last_sync_error = ?, updated_at = CURRENT_TIMESTAMP
+		SET last_sync_at = ?, last_sync_status = ?, last_sync_error = ?,
+		    last_success_at = CASE WHEN ? = 'success' THEN ? ELSE last_success_at END,
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, time.Now(), status, errorMsg, id)
+	`, now, status, errorMsg, status, now, id)
 	if err != nil {
 		return err
 	}
@@ -132,6 +163,13 @@ func (r *BrokerConnectionRepository) UpdateSyncStatus(id int64, status, errorMsg
 	return nil
 }
 
+// SetCredentialReminded records when the owner of a connection was reminded
+// to log in to the broker again.
+func (r *BrokerConnectionRepository) SetCredentialReminded(id int64, at time.Time) error {
+	_, err := r.db.Exec(`UPDATE broker_connections SET credential_reminded_at = ? WHERE id = ?`, at, id)
+	return err
+}
+
 // Delete removes a broker connection by ID.
 func (r *BrokerConnectionRepository) Delete(id int64) error {
 	result, err := r.db.Exec(`DELETE FROM broker_connections WHERE id = ?`, id)
@@ -149,11 +187,19 @@ func (r *BrokerConnectionRepository) Delete(id int64) error {
 	return nil
 }
 
+// syncWindowEnd stores an unset sync window end as the end of the day.
+func syncWindowEnd(end int) int {
+	if end == 0 {
+		return 24
+	}
+	return end
+}
+
 // scanConnection scans a single row into a BrokerConnection.
 func (r *BrokerConnectionRepository) scanConnection(row *sql.Row) (*models.BrokerConnection, error) {
 	conn := &models.BrokerConnection{}
-	var isActive int
-	var lastSyncAt sql.NullTime
+	var isActive, skipWeekends int
+	var lastSyncAt, lastSuccessAt, remindedAt sql.NullTime
 	var lastSyncStatus, lastSyncError, cpr, appKey, appSecret, redirectURI sql.NullString
 
 	err := row.Scan(
@@ -170,6 +216,15 @@ func (r *BrokerConnectionRepository) scanConnection(row *sql.Row) (*models.Broke
 		&lastSyncAt,
 		&lastSyncStatus,
 		&lastSyncError,
+		&lastSuccessAt,
+		&remindedAt,
+		&conn.SyncWindowStart,
+		&conn.SyncWindowEnd,
+		&skipWeekends,
+		&conn.SyncSchedule,
+		&conn.ProxyURL,
+		&conn.UserAgent,
+		&conn.APIURL,
 		&conn.CreatedAt,
 		&conn.UpdatedAt,
 	)
@@ -181,6 +236,7 @@ func (r *BrokerConnectionRepository) scanConnection(row *sql.Row) (*models.Broke
 	}
 
 	conn.IsActive = isActive == 1
+	conn.SkipWeekends = skipWeekends == 1
 	if cpr.Valid {
 		conn.CPR = cpr.String
 	}
@@ -202,6 +258,12 @@ func (r *BrokerConnectionRepository) scanConnection(row *sql.Row) (*models.Broke
 	if lastSyncError.Valid {
 		conn.LastSyncError = lastSyncError.String
 	}
+	if lastSuccessAt.Valid {
+		conn.LastSuccessAt = &lastSuccessAt.Time
+	}
+	if remindedAt.Valid {
+		conn.CredentialRemindedAt = &remindedAt.Time
+	}
 
 	return conn, nil
 }
@@ -212,8 +274,8 @@ func (r *BrokerConnectionRepository) scanConnections(rows *sql.Rows) ([]*models.
 
 	for rows.Next() {
 		conn := &models.BrokerConnection{}
-		var isActive int
-		var lastSyncAt sql.NullTime
+		var isActive, skipWeekends int
+		var lastSyncAt, lastSuccessAt, remindedAt sql.NullTime
 		var lastSyncStatus, lastSyncError, cpr, appKey, appSecret, redirectURI sql.NullString
 
 		err := rows.Scan(
@@ -230,6 +292,15 @@ func (r *BrokerConnectionRepository) scanConnections(rows *sql.Rows) ([]*models.
 			&lastSyncAt,
 			&lastSyncStatus,
 			&lastSyncError,
+			&lastSuccessAt,
+			&remindedAt,
+			&conn.SyncWindowStart,
+			&conn.SyncWindowEnd,
+			&skipWeekends,
+			&conn.SyncSchedule,
+			&conn.ProxyURL,
+			&conn.UserAgent,
+			&conn.APIURL,
 			&conn.CreatedAt,
 			&conn.UpdatedAt,
 		)
@@ -238,6 +309,7 @@ func (r *BrokerConnectionRepository) scanConnections(rows *sql.Rows) ([]*models.
 		}
 
 		conn.IsActive = isActive == 1
+		conn.SkipWeekends = skipWeekends == 1
 		if cpr.Valid {
 			conn.CPR = cpr.String
 		}
@@ -259,6 +331,12 @@ func (r *BrokerConnectionRepository) scanConnections(rows *sql.Rows) ([]*models.
 		if lastSyncError.Valid {
 			conn.LastSyncError = lastSyncError.String
 		}
+		if lastSuccessAt.Valid {
+			conn.LastSuccessAt = &lastSuccessAt.Time
+		}
+		if remindedAt.Valid {
+			conn.CredentialRemindedAt = &remindedAt.Time
+		}
 
 		connections = append(connections, conn)
 	}
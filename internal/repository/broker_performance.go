@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// BrokerPerformanceRepository handles the returns brokers report for mapped
+// accounts.
+type BrokerPerformanceRepository struct {
+	db *database.DB
+}
+
+// NewBrokerPerformanceRepository creates a new BrokerPerformanceRepository.
+func NewBrokerPerformanceRepository(db *database.DB) *BrokerPerformanceRepository {
+	return &BrokerPerformanceRepository{db: db}
+}
+
+// Upsert stores the return of a mapping for a period, replacing the figure
+// from the previous sync.
+func (r *BrokerPerformanceRepository) Upsert(perf *models.BrokerPerformance) error {
+	_, err := r.db.Exec(`
+		INSERT INTO broker_performance (mapping_id, period, return_fraction, fetched_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(mapping_id, period) DO UPDATE SET
+			return_fraction = excluded.return_fraction,
+			fetched_at = excluded.fetched_at
+	`, perf.MappingID, perf.Period, perf.ReturnFraction, perf.FetchedAt)
+	return err
+}
+
+// GetByUserID returns the stored returns of all of a user's mapped accounts,
+// ordered by account name.
+func (r *BrokerPerformanceRepository) GetByUserID(userID int64) ([]*models.BrokerPerformance, error) {
+	rows, err := r.db.Query(`
+		SELECT bp.id, bp.mapping_id, bp.period, bp.return_fraction, bp.fetched_at,
+		       a.id, a.name, bc.broker_type
+		FROM broker_performance bp
+		JOIN account_mappings m ON m.id = bp.mapping_id
+		JOIN broker_connections bc ON bc.id = m.connection_id
+		JOIN accounts a ON a.id = m.local_account_id
+		WHERE a.user_id = ?
+		ORDER BY a.name, a.id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*models.BrokerPerformance
+	for rows.Next() {
+		perf := &models.BrokerPerformance{}
+		if err := rows.Scan(&perf.ID, &perf.MappingID, &perf.Period, &perf.ReturnFraction, &perf.FetchedAt,
+			&perf.LocalAccountID, &perf.AccountName, &perf.BrokerType); err != nil {
+			return nil, err
+		}
+		results = append(results, perf)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// CategorizationRuleRepository handles categorization rule database
+// operations.
+type CategorizationRuleRepository struct {
+	db *database.DB
+}
+
+// NewCategorizationRuleRepository creates a new CategorizationRuleRepository.
+func NewCategorizationRuleRepository(db *database.DB) *CategorizationRuleRepository {
+	return &CategorizationRuleRepository{db: db}
+}
+
+// Create creates a new categorization rule and returns its ID.
+func (r *CategorizationRuleRepository) Create(rule *models.CategorizationRule) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO categorization_rules (user_id, name, pattern, payee, min_amount, max_amount, category_id, tag, kind, priority, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.UserID, rule.Name, rule.Pattern, rule.Payee, rule.MinAmount, rule.MaxAmount, rule.CategoryID,
+		rule.Tag, rule.Kind, rule.Priority, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves a categorization rule by ID, or nil if not found.
+func (r *CategorizationRuleRepository) GetByID(id int64) (*models.CategorizationRule, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, pattern, payee, min_amount, max_amount, category_id, tag, kind, priority, created_at
+		FROM categorization_rules
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules, err := r.scanRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules[0], nil
+}
+
+// GetByUserID retrieves a user's categorization rules in the order they are
+// tried: by priority, then in the order they were added.
+func (r *CategorizationRuleRepository) GetByUserID(userID int64) ([]*models.CategorizationRule, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, pattern, payee, min_amount, max_amount, category_id, tag, kind, priority, created_at
+		FROM categorization_rules
+		WHERE user_id = ?
+		ORDER BY priority, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRules(rows)
+}
+
+// GetByAccountID retrieves the categorization rules of an account's owner,
+// in the order they are tried.
+func (r *CategorizationRuleRepository) GetByAccountID(accountID int64) ([]*models.CategorizationRule, error) {
+	rows, err := r.db.Query(`
+		SELECT cr.id, cr.user_id, cr.name, cr.pattern, cr.payee, cr.min_amount, cr.max_amount, cr.category_id, cr.tag, cr.kind, cr.priority, cr.created_at
+		FROM categorization_rules cr
+		JOIN accounts a ON a.user_id = cr.user_id
+		WHERE a.id = ?
+		ORDER BY cr.priority, cr.id
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRules(rows)
+}
+
+// Delete removes a categorization rule. Transactions it categorized keep
+// their category and tag.
+func (r *CategorizationRuleRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM categorization_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("categorization rule not found")
+	}
+	return nil
+}
+
+// scanRules scans categorization rule rows.
+func (r *CategorizationRuleRepository) scanRules(rows *sql.Rows) ([]*models.CategorizationRule, error) {
+	rules := make([]*models.CategorizationRule, 0)
+	for rows.Next() {
+		rule := &models.CategorizationRule{}
+		var minAmount, maxAmount sql.NullFloat64
+		var categoryID sql.NullInt64
+		if err := rows.Scan(&rule.ID, &rule.UserID, &rule.Name, &rule.Pattern, &rule.Payee, &minAmount, &maxAmount,
+			&categoryID, &rule.Tag, &rule.Kind, &rule.Priority, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		if minAmount.Valid {
+			rule.MinAmount = &minAmount.Float64
+		}
+		if maxAmount.Valid {
+			rule.MaxAmount = &maxAmount.Float64
+		}
+		if categoryID.Valid {
+			rule.CategoryID = &categoryID.Int64
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
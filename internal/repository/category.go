@@ -21,9 +21,9 @@ func NewCategoryRepository(db *database.DB) *CategoryRepository {
 // Create inserts a new category and returns its ID.
 func (r *CategoryRepository) Create(category *models.Category) (int64, error) {
 	result, err := r.db.Exec(`
-		INSERT INTO categories (user_id, name, color, icon, sort_order)
-		VALUES (?, ?, ?, ?, ?)
-	`, category.UserID, category.Name, category.Color, category.Icon, category.SortOrder)
+		INSERT INTO categories (user_id, name, color, icon, sort_order, expected_return, liquidity, description)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, category.UserID, category.Name, category.Color, category.Icon, category.SortOrder, category.ExpectedReturn, category.Liquidity, category.Description)
 	if err != nil {
 		return 0, err
 	}
@@ -33,12 +33,13 @@ func (r *CategoryRepository) Create(category *models.Category) (int64, error) {
 // GetByID retrieves a category by ID.
 func (r *CategoryRepository) GetByID(id int64) (*models.Category, error) {
 	row := r.db.QueryRow(`
-		SELECT id, user_id, name, color, icon, sort_order, created_at
+		SELECT id, user_id, name, color, icon, sort_order, expected_return, liquidity, description, created_at
 		FROM categories
 		WHERE id = ?
 	`, id)
 
 	category := &models.Category{}
+	var expectedReturn sql.NullFloat64
 	err := row.Scan(
 		&category.ID,
 		&category.UserID,
@@ -46,6 +47,9 @@ func (r *CategoryRepository) GetByID(id int64) (*models.Category, error) {
 		&category.Color,
 		&category.Icon,
 		&category.SortOrder,
+		&expectedReturn,
+		&category.Liquidity,
+		&category.Description,
 		&category.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -54,13 +58,16 @@ func (r *CategoryRepository) GetByID(id int64) (*models.Category, error) {
 	if err != nil {
 		return nil, err
 	}
+	if expectedReturn.Valid {
+		category.ExpectedReturn = &expectedReturn.Float64
+	}
 	return category, nil
 }
 
 // GetByUserID retrieves all categories for a user, sorted by sort_order.
 func (r *CategoryRepository) GetByUserID(userID int64) ([]*models.Category, error) {
 	rows, err := r.db.Query(`
-		SELECT id, user_id, name, color, icon, sort_order, created_at
+		SELECT id, user_id, name, color, icon, sort_order, expected_return, liquidity, description, created_at
 		FROM categories
 		WHERE user_id = ?
 		ORDER BY sort_order ASC, name ASC
@@ -73,6 +80,7 @@ func (r *CategoryRepository) GetByUserID(userID int64) ([]*models.Category, erro
 	categories := make([]*models.Category, 0)
 	for rows.Next() {
 		category := &models.Category{}
+		var expectedReturn sql.NullFloat64
 		err := rows.Scan(
 			&category.ID,
 			&category.UserID,
@@ -80,11 +88,17 @@ func (r *CategoryRepository) GetByUserID(userID int64) ([]*models.Category, erro
 			&category.Color,
 			&category.Icon,
 			&category.SortOrder,
+			&expectedReturn,
+			&category.Liquidity,
+			&category.Description,
 			&category.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if expectedReturn.Valid {
+			category.ExpectedReturn = &expectedReturn.Float64
+		}
 		categories = append(categories, category)
 	}
 	return categories, rows.Err()
@@ -94,9 +108,9 @@ func (r *CategoryRepository) GetByUserID(userID int64) ([]*models.Category, erro
 func (r *CategoryRepository) Update(category *models.Category) error {
 	result, err := r.db.Exec(`
 		UPDATE categories
-		SET name = ?, color = ?, icon = ?, sort_order = ?
+		SET name = ?, color = ?, icon = ?, sort_order = ?, expected_return = ?, liquidity = ?, description = ?
 		WHERE id = ?
-	`, category.Name, category.Color, category.Icon, category.SortOrder, category.ID)
+	`, category.Name, category.Color, category.Icon, category.SortOrder, category.ExpectedReturn, category.Liquidity, category.Description, category.ID)
 	if err != nil {
 		return err
 	}
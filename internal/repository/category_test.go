@@ -276,6 +276,38 @@ func TestCategoryRepository_Update_ValidData_UpdatesCategory(t *testing.T) {
 	}
 }
 
+func TestCategoryRepository_Update_ExpectedReturn_SetsAndClears(t *testing.T) {
+	db, userID := setupCategoryTestDB(t)
+	repo := NewCategoryRepository(db)
+
+	category := &models.Category{UserID: userID, Name: "Pension", Color: "#6366f1"}
+	id, _ := repo.Create(category)
+
+	found, _ := repo.GetByID(id)
+	if found.ExpectedReturn != nil {
+		t.Fatalf("GetByID() ExpectedReturn = %v, want nil", *found.ExpectedReturn)
+	}
+
+	expected := 5.5
+	found.ExpectedReturn = &expected
+	if err := repo.Update(found); err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	found, _ = repo.GetByID(id)
+	if found.ExpectedReturn == nil || *found.ExpectedReturn != 5.5 {
+		t.Fatalf("Update() ExpectedReturn = %v, want 5.5", found.ExpectedReturn)
+	}
+
+	found.ExpectedReturn = nil
+	if err := repo.Update(found); err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	categories, _ := repo.GetByUserID(userID)
+	if len(categories) != 1 || categories[0].ExpectedReturn != nil {
+		t.Errorf("GetByUserID() ExpectedReturn = %v, want nil", categories[0].ExpectedReturn)
+	}
+}
+
 func TestCategoryRepository_Update_NonExistent_ReturnsError(t *testing.T) {
 	db, userID := setupCategoryTestDB(t)
 	repo := NewCategoryRepository(db)
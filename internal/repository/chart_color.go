@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// ChartColorRepository handles chart color database operations.
+type ChartColorRepository struct {
+	db *database.DB
+}
+
+// NewChartColorRepository creates a new ChartColorRepository.
+func NewChartColorRepository(db *database.DB) *ChartColorRepository {
+	return &ChartColorRepository{db: db}
+}
+
+// Create assigns a color to an entity. An entity keeps the color it was
+// assigned first, so a concurrent assignment is ignored.
+func (r *ChartColorRepository) Create(color *models.ChartColor) error {
+	_, err := r.db.Exec(`
+		INSERT INTO chart_colors (user_id, entity_type, entity_key, color, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, entity_type, entity_key) DO NOTHING
+	`, color.UserID, color.EntityType, color.EntityKey, color.Color, time.Now())
+	return err
+}
+
+// GetByUserID retrieves the colors assigned for a user, oldest first.
+func (r *ChartColorRepository) GetByUserID(userID int64) ([]*models.ChartColor, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, entity_type, entity_key, color, created_at
+		FROM chart_colors
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	colors := make([]*models.ChartColor, 0)
+	for rows.Next() {
+		c := &models.ChartColor{}
+		if err := rows.Scan(&c.ID, &c.UserID, &c.EntityType, &c.EntityKey, &c.Color, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, rows.Err()
+}
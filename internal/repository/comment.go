@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// CommentRepository handles the comment threads of accounts and goals.
+type CommentRepository struct {
+	db *database.DB
+}
+
+// NewCommentRepository creates a new CommentRepository.
+func NewCommentRepository(db *database.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create inserts a new comment and returns its ID.
+func (r *CommentRepository) Create(comment *models.Comment) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO comments (author_id, account_id, goal_id, body, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, comment.AuthorID, comment.AccountID, comment.GoalID, comment.Body, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves a comment by ID, or nil if there is none.
+func (r *CommentRepository) GetByID(id int64) (*models.Comment, error) {
+	comments, err := r.queryComments(`WHERE c.id = ?`, id)
+	if err != nil || len(comments) == 0 {
+		return nil, err
+	}
+	return comments[0], nil
+}
+
+// GetByAccountID retrieves the thread of an account, oldest first.
+func (r *CommentRepository) GetByAccountID(accountID int64) ([]*models.Comment, error) {
+	return r.queryComments(`WHERE c.account_id = ? ORDER BY c.created_at, c.id`, accountID)
+}
+
+// GetByGoalID retrieves the thread of a goal, oldest first.
+func (r *CommentRepository) GetByGoalID(goalID int64) ([]*models.Comment, error) {
+	return r.queryComments(`WHERE c.goal_id = ? ORDER BY c.created_at, c.id`, goalID)
+}
+
+// Delete removes a comment.
+func (r *CommentRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM comments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("comment not found")
+	}
+	return nil
+}
+
+// queryComments selects comments joined with their author's email, filtered
+// and ordered by the given clause.
+func (r *CommentRepository) queryComments(clause string, args ...any) ([]*models.Comment, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, c.author_id, c.account_id, c.goal_id, c.body, c.created_at, u.email
+		FROM comments c
+		JOIN users u ON u.id = c.author_id
+		`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		comment := &models.Comment{}
+		var accountID, goalID sql.NullInt64
+		if err := rows.Scan(
+			&comment.ID,
+			&comment.AuthorID,
+			&accountID,
+			&goalID,
+			&comment.Body,
+			&comment.CreatedAt,
+			&comment.AuthorEmail,
+		); err != nil {
+			return nil, err
+		}
+		if accountID.Valid {
+			comment.AccountID = &accountID.Int64
+		}
+		if goalID.Valid {
+			comment.GoalID = &goalID.Int64
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
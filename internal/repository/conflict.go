@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrStaleUpdate is returned when a record was edited by someone else after
+// it was read, so saving would silently overwrite their changes.
+var ErrStaleUpdate = errors.New("this was changed elsewhere after you opened it; reload and apply your changes again")
+
+// rowQuerier looks up a row on the database or within a transaction.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// versionArg returns the updated_at a record was read with, for comparing
+// with IS: NULL for records never edited.
+func versionArg(updatedAt time.Time) any {
+	if updatedAt.IsZero() {
+		return nil
+	}
+	return updatedAt
+}
+
+// staleOrNotFound reports why an update of the row id in table changed
+// nothing: ErrStaleUpdate if the row exists, notFound otherwise.
+func staleOrNotFound(db rowQuerier, table string, id int64, notFound error) error {
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrStaleUpdate
+	}
+	return notFound
+}
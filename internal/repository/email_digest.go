@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"database/sql"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// EmailDigestRepository handles the record of sent digest emails.
+type EmailDigestRepository struct {
+	db *database.DB
+}
+
+// NewEmailDigestRepository creates a new EmailDigestRepository.
+func NewEmailDigestRepository(db *database.DB) *EmailDigestRepository {
+	return &EmailDigestRepository{db: db}
+}
+
+// Create records a sent digest and returns its ID.
+func (r *EmailDigestRepository) Create(digest *models.EmailDigest) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO email_digests (user_id, sent_at, snapshot)
+		VALUES (?, ?, ?)
+	`, digest.UserID, digest.SentAt, digest.Snapshot)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Claim records a digest about to be sent, unless a digest was recorded for
+// the user after the one with ID afterID, or at all when afterID is 0. It
+// returns the new digest's ID and false if another digest got there first,
+// so of instances sending at once only one sends.
+func (r *EmailDigestRepository) Claim(digest *models.EmailDigest, afterID int64) (int64, bool, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO email_digests (user_id, sent_at, snapshot)
+		SELECT ?, ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM email_digests WHERE user_id = ? AND id > ?)
+	`, digest.UserID, digest.SentAt, digest.Snapshot, digest.UserID, afterID)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return 0, false, err
+	}
+	id, err := result.LastInsertId()
+	return id, err == nil, err
+}
+
+// Delete removes the record of a digest, such as a claimed one that could
+// not be sent.
+func (r *EmailDigestRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM email_digests WHERE id = ?`, id)
+	return err
+}
+
+// GetLatest returns the last digest sent to a user, or nil if none was sent.
+func (r *EmailDigestRepository) GetLatest(userID int64) (*models.EmailDigest, error) {
+	digest := &models.EmailDigest{}
+	err := r.db.QueryRow(`
+		SELECT id, user_id, sent_at, snapshot
+		FROM email_digests
+		WHERE user_id = ?
+		ORDER BY sent_at DESC, id DESC
+		LIMIT 1
+	`, userID).Scan(&digest.ID, &digest.UserID, &digest.SentAt, &digest.Snapshot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestEmailDigestRepository_ClaimOnce(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewEmailDigestRepository(db)
+	now := time.Now()
+
+	// Two instances find the first digest due at once
+	first, ok, err := repo.Claim(&models.EmailDigest{UserID: userID, SentAt: now, Snapshot: "{}"}, 0)
+	if err != nil || !ok {
+		t.Fatalf("Claim() = %d, %v, %v; want the digest claimed", first, ok, err)
+	}
+	if _, ok, err := repo.Claim(&models.EmailDigest{UserID: userID, SentAt: now, Snapshot: "{}"}, 0); err != nil || ok {
+		t.Errorf("second Claim() = %v, %v; want it refused", ok, err)
+	}
+
+	// The next digest follows the first
+	next, ok, err := repo.Claim(&models.EmailDigest{UserID: userID, SentAt: now.AddDate(0, 0, 7), Snapshot: "{}"}, first)
+	if err != nil || !ok {
+		t.Fatalf("Claim() after the first = %v, %v; want the digest claimed", ok, err)
+	}
+	if err := repo.Delete(next); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	latest, err := repo.GetLatest(userID)
+	if err != nil || latest == nil || latest.ID != first {
+		t.Errorf("GetLatest() after Delete = %+v, %v; want digest %d", latest, err, first)
+	}
+}
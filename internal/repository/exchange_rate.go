@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// ExchangeRateRepository handles user-defined exchange rate database operations.
+type ExchangeRateRepository struct {
+	db *database.DB
+}
+
+// NewExchangeRateRepository creates a new ExchangeRateRepository.
+func NewExchangeRateRepository(db *database.DB) *ExchangeRateRepository {
+	return &ExchangeRateRepository{db: db}
+}
+
+// Create inserts a new manual exchange rate and returns its ID.
+func (r *ExchangeRateRepository) Create(rate *models.ManualExchangeRate) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO manual_exchange_rates (user_id, from_currency, to_currency, rate, valid_from, valid_to, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, rate.UserID, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.ValidFrom, rate.ValidTo, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves a manual exchange rate by ID.
+func (r *ExchangeRateRepository) GetByID(id int64) (*models.ManualExchangeRate, error) {
+	rate, err := scanManualExchangeRate(r.db.QueryRow(`
+		SELECT id, user_id, from_currency, to_currency, rate, valid_from, valid_to, created_at
+		FROM manual_exchange_rates
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rate, err
+}
+
+// GetByUserID retrieves all manual exchange rates for a user, grouped by
+// currency pair with the most recent rate first.
+func (r *ExchangeRateRepository) GetByUserID(userID int64) ([]*models.ManualExchangeRate, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, from_currency, to_currency, rate, valid_from, valid_to, created_at
+		FROM manual_exchange_rates
+		WHERE user_id = ?
+		ORDER BY from_currency, to_currency, valid_from DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []*models.ManualExchangeRate
+	for rows.Next() {
+		rate, err := scanManualExchangeRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// FindRate returns the user's rate for converting from one currency to
+// another on the given day. A rate entered for the opposite direction is
+// inverted. Of several valid rates, the one with the latest start wins.
+func (r *ExchangeRateRepository) FindRate(userID int64, from, to string, day time.Time) (float64, bool, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, from_currency, to_currency, rate, valid_from, valid_to, created_at
+		FROM manual_exchange_rates
+		WHERE user_id = ? AND ((from_currency = ? AND to_currency = ?) OR (from_currency = ? AND to_currency = ?))
+		ORDER BY valid_from DESC, id DESC
+	`, userID, from, to, to, from)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rate, err := scanManualExchangeRate(rows)
+		if err != nil {
+			return 0, false, err
+		}
+		if rate.Rate <= 0 || !rate.IsValidOn(day) {
+			continue
+		}
+		if rate.FromCurrency == from {
+			return rate.Rate, true, nil
+		}
+		return 1 / rate.Rate, true, nil
+	}
+	return 0, false, rows.Err()
+}
+
+// Delete removes a manual exchange rate.
+func (r *ExchangeRateRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM manual_exchange_rates WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("exchange rate not found")
+	}
+	return nil
+}
+
+// scanManualExchangeRate scans a manual_exchange_rates row.
+func scanManualExchangeRate(row interface{ Scan(...any) error }) (*models.ManualExchangeRate, error) {
+	rate := &models.ManualExchangeRate{}
+	var validTo sql.NullTime
+	if err := row.Scan(
+		&rate.ID,
+		&rate.UserID,
+		&rate.FromCurrency,
+		&rate.ToCurrency,
+		&rate.Rate,
+		&rate.ValidFrom,
+		&validTo,
+		&rate.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if validTo.Valid {
+		rate.ValidTo = &validTo.Time
+	}
+	return rate, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// FeatureFlagRepository handles the settings of experimental features.
+type FeatureFlagRepository struct {
+	db *database.DB
+}
+
+// NewFeatureFlagRepository creates a new FeatureFlagRepository.
+func NewFeatureFlagRepository(db *database.DB) *FeatureFlagRepository {
+	return &FeatureFlagRepository{db: db}
+}
+
+// GetAll retrieves all feature flags, by name with the setting for everyone
+// before those of users.
+func (r *FeatureFlagRepository) GetAll() ([]*models.FeatureFlag, error) {
+	return r.queryFlags(`ORDER BY f.name, f.user_id IS NOT NULL, u.email`)
+}
+
+// GetForUser retrieves the flags that apply to a user: the settings for
+// everyone and the user's own.
+func (r *FeatureFlagRepository) GetForUser(userID int64) ([]*models.FeatureFlag, error) {
+	return r.queryFlags(`WHERE f.user_id IS NULL OR f.user_id = ?`, userID)
+}
+
+// Set turns a feature on or off for everyone, with a nil userID, or for a
+// user.
+func (r *FeatureFlagRepository) Set(name string, userID *int64, enabled bool) error {
+	_, err := r.db.Exec(`
+		INSERT INTO feature_flags (name, user_id, enabled, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name, IFNULL(user_id, 0)) DO UPDATE SET
+			enabled = excluded.enabled,
+			updated_at = excluded.updated_at
+	`, name, userID, boolToInt(enabled), time.Now())
+	return err
+}
+
+// Delete removes the setting of a feature for everyone, with a nil userID,
+// or for a user, so the default applies again.
+func (r *FeatureFlagRepository) Delete(name string, userID *int64) error {
+	_, err := r.db.Exec(`DELETE FROM feature_flags WHERE name = ? AND IFNULL(user_id, 0) = IFNULL(?, 0)`, name, userID)
+	return err
+}
+
+// queryFlags selects feature flags with the email of their user, filtered
+// and ordered by the given clause.
+func (r *FeatureFlagRepository) queryFlags(clause string, args ...any) ([]*models.FeatureFlag, error) {
+	rows, err := r.db.Query(`
+		SELECT f.name, f.user_id, IFNULL(u.email, ''), f.enabled, f.updated_at
+		FROM feature_flags f
+		LEFT JOIN users u ON u.id = f.user_id
+		`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*models.FeatureFlag
+	for rows.Next() {
+		flag := &models.FeatureFlag{}
+		var userID sql.NullInt64
+		var enabled int
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&flag.Name, &userID, &flag.UserEmail, &enabled, &updatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			flag.UserID = &userID.Int64
+		}
+		flag.Enabled = enabled == 1
+		flag.UpdatedAt = updatedAt.Time
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
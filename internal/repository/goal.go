@@ -3,6 +3,8 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"wealth_tracker/internal/database"
 	"wealth_tracker/internal/models"
@@ -18,29 +20,43 @@ func NewGoalRepository(db *database.DB) *GoalRepository {
 	return &GoalRepository{db: db}
 }
 
-// Create inserts a new goal and returns its ID.
+// Create inserts a new goal with its funding accounts and returns its ID.
+// ReachedDate is normally nil and only set when importing goals.
 func (r *GoalRepository) Create(goal *models.Goal) (int64, error) {
-	result, err := r.db.Exec(`
-		INSERT INTO goals (user_id, category_id, name, target_amount, target_currency, deadline)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, goal.UserID, goal.CategoryID, goal.Name, goal.TargetAmount, goal.TargetCurrency, goal.Deadline)
+	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO goals (user_id, category_id, name, target_amount, target_currency, deadline, reached_date, description, monthly_contribution)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, goal.UserID, goal.CategoryID, goal.Name, goal.TargetAmount, goal.TargetCurrency, goal.Deadline, goal.ReachedDate, goal.Description, goal.MonthlyContribution)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := setFundingAccounts(tx, id, goal.UserID, goal.FundingAccountIDs); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
 // GetByID retrieves a goal by ID.
 func (r *GoalRepository) GetByID(id int64) (*models.Goal, error) {
 	row := r.db.QueryRow(`
-		SELECT id, user_id, category_id, name, target_amount, target_currency, deadline, reached_date, created_at
+		SELECT id, user_id, category_id, name, target_amount, target_currency, deadline, reached_date, description, monthly_contribution, created_at, updated_at
 		FROM goals
 		WHERE id = ?
 	`, id)
 
 	goal := &models.Goal{}
 	var categoryID sql.NullInt64
-	var deadline, reachedDate sql.NullTime
+	var deadline, reachedDate, updatedAt sql.NullTime
 
 	err := row.Scan(
 		&goal.ID,
@@ -51,7 +67,10 @@ func (r *GoalRepository) GetByID(id int64) (*models.Goal, error) {
 		&goal.TargetCurrency,
 		&deadline,
 		&reachedDate,
+		&goal.Description,
+		&goal.MonthlyContribution,
 		&goal.CreatedAt,
+		&updatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -69,14 +88,20 @@ func (r *GoalRepository) GetByID(id int64) (*models.Goal, error) {
 	if reachedDate.Valid {
 		goal.ReachedDate = &reachedDate.Time
 	}
+	if updatedAt.Valid {
+		goal.UpdatedAt = updatedAt.Time
+	}
 
+	if err := r.loadFundingAccounts([]*models.Goal{goal}); err != nil {
+		return nil, err
+	}
 	return goal, nil
 }
 
 // GetByUserID retrieves all goals for a user, sorted by deadline then name.
 func (r *GoalRepository) GetByUserID(userID int64) ([]*models.Goal, error) {
 	rows, err := r.db.Query(`
-		SELECT id, user_id, category_id, name, target_amount, target_currency, deadline, reached_date, created_at
+		SELECT id, user_id, category_id, name, target_amount, target_currency, deadline, reached_date, description, monthly_contribution, created_at, updated_at
 		FROM goals
 		WHERE user_id = ?
 		ORDER BY COALESCE(deadline, '9999-12-31') ASC, name ASC
@@ -90,7 +115,7 @@ func (r *GoalRepository) GetByUserID(userID int64) ([]*models.Goal, error) {
 	for rows.Next() {
 		goal := &models.Goal{}
 		var categoryID sql.NullInt64
-		var deadline, reachedDate sql.NullTime
+		var deadline, reachedDate, updatedAt sql.NullTime
 
 		err := rows.Scan(
 			&goal.ID,
@@ -101,7 +126,10 @@ func (r *GoalRepository) GetByUserID(userID int64) ([]*models.Goal, error) {
 			&goal.TargetCurrency,
 			&deadline,
 			&reachedDate,
+			&goal.Description,
+			&goal.MonthlyContribution,
 			&goal.CreatedAt,
+			&updatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -116,19 +144,38 @@ func (r *GoalRepository) GetByUserID(userID int64) ([]*models.Goal, error) {
 		if reachedDate.Valid {
 			goal.ReachedDate = &reachedDate.Time
 		}
+		if updatedAt.Valid {
+			goal.UpdatedAt = updatedAt.Time
+		}
 
 		goals = append(goals, goal)
 	}
-	return goals, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadFundingAccounts(goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
 }
 
-// Update updates an existing goal.
+// Update updates an existing goal and replaces its funding accounts. It
+// returns ErrStaleUpdate if the goal was edited since it was read, and sets
+// goal.UpdatedAt.
 func (r *GoalRepository) Update(goal *models.Goal) error {
-	result, err := r.db.Exec(`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	result, err := tx.Exec(`
 		UPDATE goals
-		SET category_id = ?, name = ?, target_amount = ?, target_currency = ?, deadline = ?, reached_date = ?
-		WHERE id = ?
-	`, goal.CategoryID, goal.Name, goal.TargetAmount, goal.TargetCurrency, goal.Deadline, goal.ReachedDate, goal.ID)
+		SET category_id = ?, name = ?, target_amount = ?, target_currency = ?, deadline = ?, reached_date = ?, description = ?, monthly_contribution = ?, updated_at = ?
+		WHERE id = ? AND updated_at IS ?
+	`, goal.CategoryID, goal.Name, goal.TargetAmount, goal.TargetCurrency, goal.Deadline, goal.ReachedDate, goal.Description, goal.MonthlyContribution, now,
+		goal.ID, versionArg(goal.UpdatedAt))
 	if err != nil {
 		return err
 	}
@@ -138,11 +185,68 @@ func (r *GoalRepository) Update(goal *models.Goal) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("goal not found")
+		tx.Rollback()
+		return staleOrNotFound(r.db, "goals", goal.ID, errors.New("goal not found"))
+	}
+	if _, err := tx.Exec(`DELETE FROM goal_funding_accounts WHERE goal_id = ?`, goal.ID); err != nil {
+		return err
+	}
+	if err := setFundingAccounts(tx, goal.ID, goal.UserID, goal.FundingAccountIDs); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	goal.UpdatedAt = now
+	return nil
+}
+
+// setFundingAccounts links a new or cleared goal to its funding accounts.
+// Accounts of other users are left out.
+func setFundingAccounts(tx *sql.Tx, goalID, userID int64, accountIDs []int64) error {
+	for _, accountID := range accountIDs {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO goal_funding_accounts (goal_id, account_id)
+			SELECT ?, id FROM accounts WHERE id = ? AND user_id = ?
+		`, goalID, accountID, userID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// loadFundingAccounts sets the funding accounts of goals.
+func (r *GoalRepository) loadFundingAccounts(goals []*models.Goal) error {
+	if len(goals) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.Goal, len(goals))
+	args := make([]any, len(goals))
+	for i, goal := range goals {
+		byID[goal.ID] = goal
+		args[i] = goal.ID
+	}
+
+	rows, err := r.db.Query(`
+		SELECT goal_id, account_id
+		FROM goal_funding_accounts
+		WHERE goal_id IN (?`+strings.Repeat(", ?", len(goals)-1)+`)
+		ORDER BY account_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var goalID, accountID int64
+		if err := rows.Scan(&goalID, &accountID); err != nil {
+			return err
+		}
+		byID[goalID].FundingAccountIDs = append(byID[goalID].FundingAccountIDs, accountID)
+	}
+	return rows.Err()
+}
+
 // Delete removes a goal by ID.
 func (r *GoalRepository) Delete(id int64) error {
 	result, err := r.db.Exec(`DELETE FROM goals WHERE id = ?`, id)
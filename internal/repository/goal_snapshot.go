@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// GoalSnapshotRepository handles the weekly progress history of goals.
+type GoalSnapshotRepository struct {
+	db *database.DB
+}
+
+// NewGoalSnapshotRepository creates a new GoalSnapshotRepository.
+func NewGoalSnapshotRepository(db *database.DB) *GoalSnapshotRepository {
+	return &GoalSnapshotRepository{db: db}
+}
+
+// Upsert stores the progress of a goal in a week, replacing the figures
+// recorded earlier in the same week.
+func (r *GoalSnapshotRepository) Upsert(snapshot *models.GoalSnapshot) error {
+	_, err := r.db.Exec(`
+		INSERT INTO goal_snapshots (goal_id, week_start, current_worth, target_amount, recorded_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(goal_id, week_start) DO UPDATE SET
+			current_worth = excluded.current_worth,
+			target_amount = excluded.target_amount,
+			recorded_at = excluded.recorded_at
+	`, snapshot.GoalID, snapshot.WeekStart, snapshot.CurrentWorth, snapshot.TargetAmount, snapshot.RecordedAt)
+	return err
+}
+
+// GetByGoalID returns the snapshots of a goal, oldest first.
+func (r *GoalSnapshotRepository) GetByGoalID(goalID int64) ([]*models.GoalSnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT id, goal_id, week_start, current_worth, target_amount, recorded_at
+		FROM goal_snapshots
+		WHERE goal_id = ?
+		ORDER BY week_start ASC
+	`, goalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]*models.GoalSnapshot, 0)
+	for rows.Next() {
+		s := &models.GoalSnapshot{}
+		if err := rows.Scan(&s.ID, &s.GoalID, &s.WeekStart, &s.CurrentWorth, &s.TargetAmount, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"fmt"
+
+	"wealth_tracker/internal/models"
+)
+
+// HistoryImport is a backdated history of balances to add to an account.
+type HistoryImport struct {
+	Account     *models.Account // Created if its ID is 0
+	SaveAccount bool            // Saves changes to an existing account, such as its closing date
+
+	// Balances are the imported balances, oldest first. Their AccountID is
+	// set to the account's.
+	Balances []*models.Transaction
+
+	// First is the account's first balance before the import, saved with
+	// its amount changed to follow on from the imported ones; nil if the
+	// account had none.
+	First *models.Transaction
+}
+
+// ImportHistory adds the histories in one database transaction, so either all
+// of them are imported or none. It returns ErrStaleUpdate if an account or
+// first balance was edited since it was read.
+func (r *TransactionRepository) ImportHistory(imports []*HistoryImport) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, imp := range imports {
+		switch {
+		case imp.Account.ID == 0:
+			id, err := createAccount(tx, imp.Account)
+			if err != nil {
+				return fmt.Errorf("creating %s account: %w", imp.Account.Name, err)
+			}
+			imp.Account.ID = id
+		case imp.SaveAccount:
+			if err := updateAccount(tx, imp.Account); err != nil {
+				return fmt.Errorf("updating %s account: %w", imp.Account.Name, err)
+			}
+		}
+
+		for _, txn := range imp.Balances {
+			txn.AccountID = imp.Account.ID
+			id, err := createTransaction(tx, txn)
+			if err != nil {
+				return fmt.Errorf("importing into %s: %w", imp.Account.Name, err)
+			}
+			txn.ID = id
+		}
+		if imp.First != nil {
+			if err := updateTransaction(tx, imp.First); err != nil {
+				return fmt.Errorf("importing into %s: %w", imp.Account.Name, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -29,12 +29,50 @@ func (r *HoldingRepository) Create(holding *models.Holding) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, snapshotHoldings(r.db, `id = ?`, id)
 }
 
 // Upsert inserts or updates a holding based on account_id and symbol.
 func (r *HoldingRepository) Upsert(holding *models.Holding) error {
-	_, err := r.db.Exec(`
+	return upsertHolding(r.db, holding)
+}
+
+// ImportManual writes imported holdings of a manually tracked account in one
+// transaction, so a failed import leaves the holdings as they were. When
+// replace is set, the manual holdings of the account are removed first.
+func (r *HoldingRepository) ImportManual(accountID int64, holdings []*models.Holding, replace bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if replace {
+		if err := snapshotRemovedHoldings(tx, `account_id = ? AND (external_id IS NULL OR external_id = '')`, accountID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			DELETE FROM holdings
+			WHERE account_id = ? AND (external_id IS NULL OR external_id = '')
+		`, accountID); err != nil {
+			return err
+		}
+	}
+	for _, holding := range holdings {
+		if err := upsertHolding(tx, holding); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// upsertHolding inserts or updates a holding based on account_id and symbol.
+func upsertHolding(db execer, holding *models.Holding) error {
+	_, err := db.Exec(`
 		INSERT INTO holdings (account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(account_id, symbol) DO UPDATE SET
@@ -44,30 +82,77 @@ func (r *HoldingRepository) Upsert(holding *models.Holding) error {
 			avg_price = excluded.avg_price,
 			current_price = excluded.current_price,
 			current_value = excluded.current_value,
-			currency = excluded.currency,
+			currency = COALESCE(NULLIF(excluded.currency, ''), holdings.currency),
 			instrument_type = excluded.instrument_type,
 			last_updated = excluded.last_updated
 	`, holding.AccountID, holding.ExternalID, holding.Symbol, holding.Name, holding.Quantity,
 		holding.AvgPrice, holding.CurrentPrice, holding.CurrentValue, holding.Currency,
 		holding.InstrumentType, time.Now())
-	return err
+	if err != nil {
+		return err
+	}
+	return snapshotHoldings(db, `account_id = ? AND symbol = ?`, holding.AccountID, holding.Symbol)
+}
+
+// inferredCurrency is the currency of a holding h synced without one: that
+// of the same instrument elsewhere in the user's accounts, current or
+// archived, or else its account's currency. Other users' holdings are not
+// looked at, so they cannot set the currency of this user's portfolio.
+const inferredCurrency = `COALESCE(
+	(SELECT o.currency FROM (
+		SELECT account_id, symbol, currency, last_updated FROM holdings
+		UNION ALL
+		SELECT account_id, symbol, currency, last_updated FROM holding_history
+	) o JOIN accounts oa ON oa.id = o.account_id
+	WHERE o.symbol = h.symbol AND o.currency != ''
+	  AND oa.user_id = (SELECT a.user_id FROM accounts a WHERE a.id = h.account_id)
+	ORDER BY o.last_updated DESC LIMIT 1),
+	(SELECT a.currency FROM accounts a WHERE a.id = h.account_id AND a.currency != ''),
+	'DKK')`
+
+// BackfillCurrencies fills in the currency of current and archived holdings
+// synced before currencies were recorded, so currency exposure reflects the
+// instruments rather than the accounts they are in. Returns how many
+// holdings were updated.
+func (r *HoldingRepository) BackfillCurrencies() (int64, error) {
+	var total int64
+	for _, table := range []string{"holdings", "holding_history"} {
+		result, err := r.db.Exec(`UPDATE ` + table + ` AS h SET currency = ` + inferredCurrency + `
+			WHERE h.currency IS NULL OR TRIM(h.currency) = ''`)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
 }
 
 // GetByID retrieves a holding by ID.
 func (r *HoldingRepository) GetByID(id int64) (*models.Holding, error) {
 	row := r.db.QueryRow(`
-		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated, created_at
+		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, cost_basis_mode, last_updated, created_at
 		FROM holdings
 		WHERE id = ?
 	`, id)
 
-	return r.scanHolding(row)
+	holding, err := r.scanHolding(row)
+	if err != nil || holding == nil {
+		return holding, err
+	}
+	if err := r.applyRecomputedAvgPrices(holding.AccountID, []*models.Holding{holding}); err != nil {
+		return nil, err
+	}
+	return holding, nil
 }
 
 // GetByAccountID retrieves all holdings for an account.
 func (r *HoldingRepository) GetByAccountID(accountID int64) ([]*models.Holding, error) {
 	rows, err := r.db.Query(`
-		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated, created_at
+		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, cost_basis_mode, last_updated, created_at
 		FROM holdings
 		WHERE account_id = ?
 		ORDER BY current_value DESC
@@ -77,13 +162,20 @@ func (r *HoldingRepository) GetByAccountID(accountID int64) ([]*models.Holding,
 	}
 	defer rows.Close()
 
-	return r.scanHoldings(rows)
+	holdings, err := r.scanHoldings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.applyRecomputedAvgPrices(accountID, holdings); err != nil {
+		return nil, err
+	}
+	return holdings, nil
 }
 
 // GetByAccountIDWithValue retrieves holdings for an account with minimum value.
 func (r *HoldingRepository) GetByAccountIDWithValue(accountID int64, minValue float64) ([]*models.Holding, error) {
 	rows, err := r.db.Query(`
-		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated, created_at
+		SELECT id, account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, cost_basis_mode, last_updated, created_at
 		FROM holdings
 		WHERE account_id = ? AND current_value >= ?
 		ORDER BY current_value DESC
@@ -93,7 +185,36 @@ func (r *HoldingRepository) GetByAccountIDWithValue(accountID int64, minValue fl
 	}
 	defer rows.Close()
 
-	return r.scanHoldings(rows)
+	holdings, err := r.scanHoldings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.applyRecomputedAvgPrices(accountID, holdings); err != nil {
+		return nil, err
+	}
+	return holdings, nil
+}
+
+// GetLatestSyncedPrice returns the most recently updated price of a symbol
+// across broker-synced holdings of a user's accounts. It returns a nil price
+// if no synced holding of the user has a price for the symbol.
+func (r *HoldingRepository) GetLatestSyncedPrice(userID int64, symbol string) (*float64, time.Time, error) {
+	var price float64
+	var updatedAt time.Time
+	err := r.db.QueryRow(`
+		SELECT h.current_price, h.last_updated FROM holdings h
+		JOIN accounts a ON a.id = h.account_id
+		WHERE a.user_id = ? AND h.symbol = ? AND h.external_id IS NOT NULL AND h.external_id != '' AND h.current_price > 0
+		ORDER BY h.last_updated DESC
+		LIMIT 1
+	`, userID, symbol).Scan(&price, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return &price, updatedAt, nil
 }
 
 // GetTotalValueByAccountID returns the total value of all holdings for an account.
@@ -131,11 +252,47 @@ func (r *HoldingRepository) Update(holding *models.Holding) error {
 	if rowsAffected == 0 {
 		return errors.New("holding not found")
 	}
+	return snapshotHoldings(r.db, `id = ?`, holding.ID)
+}
+
+// SetCostBasisMode selects the average price used for a holding's P/L.
+func (r *HoldingRepository) SetCostBasisMode(id int64, mode string) error {
+	result, err := r.db.Exec(`UPDATE holdings SET cost_basis_mode = ? WHERE id = ?`, mode, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("holding not found")
+	}
+	return nil
+}
+
+// applyRecomputedAvgPrices sets RecomputedAvgPrice on holdings that have
+// imported acquisitions.
+func (r *HoldingRepository) applyRecomputedAvgPrices(accountID int64, holdings []*models.Holding) error {
+	if len(holdings) == 0 {
+		return nil
+	}
+	prices, err := recomputedAvgPrices(r.db, accountID)
+	if err != nil {
+		return err
+	}
+	for _, h := range holdings {
+		h.RecomputedAvgPrice = prices[h.Symbol]
+	}
 	return nil
 }
 
 // Delete removes a holding by ID.
 func (r *HoldingRepository) Delete(id int64) error {
+	if err := snapshotRemovedHoldings(r.db, `id = ?`, id); err != nil {
+		return err
+	}
 	result, err := r.db.Exec(`DELETE FROM holdings WHERE id = ?`, id)
 	if err != nil {
 		return err
@@ -153,14 +310,161 @@ func (r *HoldingRepository) Delete(id int64) error {
 
 // DeleteByAccountID removes all holdings for an account.
 func (r *HoldingRepository) DeleteByAccountID(accountID int64) error {
+	if err := snapshotRemovedHoldings(r.db, `account_id = ?`, accountID); err != nil {
+		return err
+	}
 	_, err := r.db.Exec(`DELETE FROM holdings WHERE account_id = ?`, accountID)
 	return err
 }
 
+// CountStaleHoldings returns the number of holdings that haven't been updated since the given time.
+func (r *HoldingRepository) CountStaleHoldings(accountID int64, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM holdings WHERE account_id = ? AND last_updated < ?
+	`, accountID, since).Scan(&count)
+	return count, err
+}
+
 // DeleteStaleHoldings removes holdings that haven't been updated since the given time.
+// Removed holdings are archived to holding_history so they can be restored.
 func (r *HoldingRepository) DeleteStaleHoldings(accountID int64, since time.Time) error {
-	_, err := r.db.Exec(`DELETE FROM holdings WHERE account_id = ? AND last_updated < ?`, accountID, since)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO holding_history (account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated, reason)
+		SELECT account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated, 'stale'
+		FROM holdings
+		WHERE account_id = ? AND last_updated < ?
+	`, accountID, since); err != nil {
+		return err
+	}
+	if err := snapshotRemovedHoldings(tx, `account_id = ? AND last_updated < ?`, accountID, since); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM holdings WHERE account_id = ? AND last_updated < ?`, accountID, since); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetArchived retrieves the most recently archived holdings across all users.
+func (r *HoldingRepository) GetArchived(limit int) ([]*models.ArchivedHolding, error) {
+	rows, err := r.db.Query(`
+		SELECT h.id, h.account_id, h.external_id, h.symbol, h.name, h.quantity, h.avg_price, h.current_price, h.current_value, h.currency, h.instrument_type,
+		       a.name, u.email, h.reason, h.deleted_at, h.restored_at
+		FROM holding_history h
+		JOIN accounts a ON a.id = h.account_id
+		JOIN users u ON u.id = a.user_id
+		ORDER BY h.deleted_at DESC, h.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archived := make([]*models.ArchivedHolding, 0)
+	for rows.Next() {
+		a := &models.ArchivedHolding{}
+		var externalID, instrumentType sql.NullString
+		var avgPrice, currentPrice sql.NullFloat64
+		var restoredAt sql.NullTime
+
+		if err := rows.Scan(
+			&a.ID, &a.AccountID, &externalID, &a.Symbol, &a.Name, &a.Quantity, &avgPrice, &currentPrice,
+			&a.CurrentValue, &a.Currency, &instrumentType,
+			&a.AccountName, &a.UserEmail, &a.Reason, &a.DeletedAt, &restoredAt,
+		); err != nil {
+			return nil, err
+		}
+
+		a.ExternalID = externalID.String
+		a.InstrumentType = instrumentType.String
+		a.AvgPrice = avgPrice.Float64
+		a.CurrentPrice = currentPrice.Float64
+		if restoredAt.Valid {
+			a.RestoredAt = &restoredAt.Time
+		}
+		archived = append(archived, a)
+	}
+
+	return archived, rows.Err()
+}
+
+// RestoreArchived re-creates an archived holding in its account and marks the
+// archive entry as restored. Fails if the account already holds the symbol.
+func (r *HoldingRepository) RestoreArchived(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO holdings (account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, last_updated)
+		SELECT account_id, external_id, symbol, name, quantity, avg_price, current_price, current_value, currency, instrument_type, ?
+		FROM holding_history
+		WHERE id = ? AND restored_at IS NULL
+		ON CONFLICT(account_id, symbol) DO NOTHING
+	`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("archived holding not found or symbol already held")
+	}
+
+	if _, err := tx.Exec(`UPDATE holding_history SET restored_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return err
+	}
+	if err := snapshotHoldings(tx, `(account_id, symbol) = (SELECT account_id, symbol FROM holding_history WHERE id = ?)`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOrphaned retrieves broker-synced holdings whose account is inactive,
+// closed or no longer mapped to a broker connection, so no sync will update them.
+func (r *HoldingRepository) GetOrphaned() ([]*models.OrphanedHolding, error) {
+	rows, err := r.db.Query(`
+		SELECT h.id, h.account_id, h.external_id, h.symbol, h.name, h.quantity, h.current_value, h.currency, h.last_updated,
+		       a.name, u.email,
+		       CASE WHEN a.is_active = 0 OR NOT ` + accountOpen("a.closed_at") + ` THEN 'inactive' ELSE 'unmapped' END
+		FROM holdings h
+		JOIN accounts a ON a.id = h.account_id
+		JOIN users u ON u.id = a.user_id
+		WHERE h.external_id IS NOT NULL AND h.external_id != ''
+		  AND (a.is_active = 0 OR NOT ` + accountOpen("a.closed_at") + ` OR NOT EXISTS (SELECT 1 FROM account_mappings m WHERE m.local_account_id = a.id))
+		ORDER BY u.email, a.name, h.current_value DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orphaned := make([]*models.OrphanedHolding, 0)
+	for rows.Next() {
+		o := &models.OrphanedHolding{}
+		if err := rows.Scan(
+			&o.ID, &o.AccountID, &o.ExternalID, &o.Symbol, &o.Name, &o.Quantity, &o.CurrentValue, &o.Currency, &o.LastUpdated,
+			&o.AccountName, &o.UserEmail, &o.Reason,
+		); err != nil {
+			return nil, err
+		}
+		orphaned = append(orphaned, o)
+	}
+
+	return orphaned, rows.Err()
 }
 
 // CountByAccountID returns the number of holdings for an account.
@@ -188,6 +492,7 @@ func (r *HoldingRepository) scanHolding(row *sql.Row) (*models.Holding, error) {
 		&holding.CurrentValue,
 		&holding.Currency,
 		&instrumentType,
+		&holding.CostBasisMode,
 		&holding.LastUpdated,
 		&holding.CreatedAt,
 	)
@@ -235,6 +540,7 @@ func (r *HoldingRepository) scanHoldings(rows *sql.Rows) ([]*models.Holding, err
 			&holding.CurrentValue,
 			&holding.Currency,
 			&instrumentType,
+			&holding.CostBasisMode,
 			&holding.LastUpdated,
 			&holding.CreatedAt,
 		)
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// HoldingAcquisitionRepository handles imported buy transactions.
+type HoldingAcquisitionRepository struct {
+	db *database.DB
+}
+
+// NewHoldingAcquisitionRepository creates a new HoldingAcquisitionRepository.
+func NewHoldingAcquisitionRepository(db *database.DB) *HoldingAcquisitionRepository {
+	return &HoldingAcquisitionRepository{db: db}
+}
+
+// ReplaceSymbols stores acquisitions for an account. Existing acquisitions of
+// the imported symbols are removed first, so re-importing a file does not
+// count the same buys twice; other symbols are left alone.
+func (r *HoldingAcquisitionRepository) ReplaceSymbols(accountID int64, acquisitions []*models.HoldingAcquisition) error {
+	return r.Import(accountID, acquisitions, false)
+}
+
+// Import stores imported acquisitions for an account like ReplaceSymbols, in
+// one transaction. When replace is set, all earlier acquisitions of the
+// account are removed first. Nothing is changed if any acquisition fails.
+func (r *HoldingAcquisitionRepository) Import(accountID int64, acquisitions []*models.HoldingAcquisition, replace bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if replace {
+		if _, err := tx.Exec(`DELETE FROM holding_acquisitions WHERE account_id = ?`, accountID); err != nil {
+			return err
+		}
+	}
+
+	cleared := make(map[string]bool)
+	for _, a := range acquisitions {
+		if cleared[a.Symbol] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM holding_acquisitions WHERE account_id = ? AND symbol = ?`, accountID, a.Symbol); err != nil {
+			return err
+		}
+		cleared[a.Symbol] = true
+	}
+
+	for _, a := range acquisitions {
+		if _, err := tx.Exec(`
+			INSERT INTO holding_acquisitions (account_id, symbol, trade_date, quantity, price, fees, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, accountID, a.Symbol, a.TradeDate, a.Quantity, a.Price, a.Fees, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByAccountID retrieves all acquisitions for an account, oldest first.
+func (r *HoldingAcquisitionRepository) GetByAccountID(accountID int64) ([]*models.HoldingAcquisition, error) {
+	rows, err := r.db.Query(`
+		SELECT id, account_id, symbol, trade_date, quantity, price, fees, created_at
+		FROM holding_acquisitions
+		WHERE account_id = ?
+		ORDER BY trade_date, id
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acquisitions := make([]*models.HoldingAcquisition, 0)
+	for rows.Next() {
+		a := &models.HoldingAcquisition{}
+		if err := rows.Scan(&a.ID, &a.AccountID, &a.Symbol, &a.TradeDate, &a.Quantity, &a.Price, &a.Fees, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		acquisitions = append(acquisitions, a)
+	}
+	return acquisitions, rows.Err()
+}
+
+// DeleteByAccountID removes all acquisitions of an account.
+func (r *HoldingAcquisitionRepository) DeleteByAccountID(accountID int64) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM holding_acquisitions WHERE account_id = ?`, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// recomputedAvgPrices returns the average price per symbol over an account's
+// acquisitions, with fees included in the cost.
+func recomputedAvgPrices(db *database.DB, accountID int64) (map[string]float64, error) {
+	rows, err := db.Query(`
+		SELECT symbol, SUM(quantity * price + fees) / SUM(quantity)
+		FROM holding_acquisitions
+		WHERE account_id = ? AND quantity > 0
+		GROUP BY symbol
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[string]float64)
+	for rows.Next() {
+		var symbol string
+		var price float64
+		if err := rows.Scan(&symbol, &price); err != nil {
+			return nil, err
+		}
+		prices[symbol] = price
+	}
+	return prices, rows.Err()
+}
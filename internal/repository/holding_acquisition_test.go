@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestHoldingAcquisitionRepository_RecomputesAvgPrice(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	holdingRepo := NewHoldingRepository(db)
+	repo := NewHoldingAcquisitionRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	if err := holdingRepo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "DK0062498333", Name: "Novo",
+		Quantity: 20, AvgPrice: 900, CurrentValue: 16000, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	date := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	buys := []*models.HoldingAcquisition{
+		{Symbol: "DK0062498333", TradeDate: date, Quantity: 10, Price: 500, Fees: 20},
+		{Symbol: "DK0062498333", TradeDate: date.AddDate(0, 6, 0), Quantity: 10, Price: 700, Fees: 20},
+	}
+	if err := repo.ReplaceSymbols(accountID, buys); err != nil {
+		t.Fatalf("ReplaceSymbols() error = %v", err)
+	}
+	// Importing the same file again must not double count
+	if err := repo.ReplaceSymbols(accountID, buys); err != nil {
+		t.Fatalf("ReplaceSymbols() error = %v", err)
+	}
+
+	holdings, err := holdingRepo.GetByAccountID(accountID)
+	if err != nil || len(holdings) != 1 {
+		t.Fatalf("GetByAccountID() = %d holdings, %v", len(holdings), err)
+	}
+	h := holdings[0]
+	if math.Abs(h.RecomputedAvgPrice-602) > 1e-9 {
+		t.Errorf("RecomputedAvgPrice = %f; want 602 (12040 cost / 20 shares)", h.RecomputedAvgPrice)
+	}
+	if h.CostBasisMode != models.CostBasisBroker || h.CostBasisPrice() != 900 {
+		t.Errorf("default cost basis = %s/%f; want broker/900", h.CostBasisMode, h.CostBasisPrice())
+	}
+
+	if err := holdingRepo.SetCostBasisMode(h.ID, models.CostBasisRecomputed); err != nil {
+		t.Fatalf("SetCostBasisMode() error = %v", err)
+	}
+	// A sync must not reset the chosen mode
+	if err := holdingRepo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "DK0062498333", Name: "Novo",
+		Quantity: 20, AvgPrice: 900, CurrentValue: 16000, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	h, err = holdingRepo.GetByID(h.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !h.UsesRecomputedCostBasis() {
+		t.Fatalf("cost basis mode = %s; want recomputed after sync", h.CostBasisMode)
+	}
+	if math.Abs(h.ProfitLoss()-(16000-20*602)) > 1e-9 {
+		t.Errorf("ProfitLoss() = %f; want %f", h.ProfitLoss(), 16000-20*602.0)
+	}
+
+	if n, err := repo.DeleteByAccountID(accountID); err != nil || n != 2 {
+		t.Fatalf("DeleteByAccountID() = %d, %v; want 2", n, err)
+	}
+	h, _ = holdingRepo.GetByID(h.ID)
+	if h.UsesRecomputedCostBasis() || h.CostBasisPrice() != 900 {
+		t.Errorf("without acquisitions cost basis = %f; want broker price 900", h.CostBasisPrice())
+	}
+}
+
+func TestHoldingAcquisitionRepository_Import(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingAcquisitionRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	date := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := repo.Import(accountID, []*models.HoldingAcquisition{
+		{Symbol: "AAA", TradeDate: date, Quantity: 1, Price: 100},
+		{Symbol: "BBB", TradeDate: date, Quantity: 1, Price: 100},
+	}, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// Merging keeps the buys of other symbols
+	if err := repo.Import(accountID, []*models.HoldingAcquisition{{Symbol: "AAA", TradeDate: date, Quantity: 2, Price: 100}}, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got, _ := repo.GetByAccountID(accountID); len(got) != 2 {
+		t.Fatalf("acquisitions after merge = %d; want 2", len(got))
+	}
+
+	// Replacing removes them
+	if err := repo.Import(accountID, []*models.HoldingAcquisition{{Symbol: "CCC", TradeDate: date, Quantity: 1, Price: 50}}, true); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	got, _ := repo.GetByAccountID(accountID)
+	if len(got) != 1 || got[0].Symbol != "CCC" {
+		t.Errorf("acquisitions after replace = %+v; want only CCC", got)
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// HoldingLabelRepository handles holding label database operations.
+type HoldingLabelRepository struct {
+	db *database.DB
+}
+
+// NewHoldingLabelRepository creates a new HoldingLabelRepository.
+func NewHoldingLabelRepository(db *database.DB) *HoldingLabelRepository {
+	return &HoldingLabelRepository{db: db}
+}
+
+// Upsert labels an instrument for the user, replacing any label it had, and
+// returns the label's ID.
+func (r *HoldingLabelRepository) Upsert(label *models.HoldingLabel) (int64, error) {
+	_, err := r.db.Exec(`
+		INSERT INTO holding_labels (user_id, symbol, label, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, symbol) DO UPDATE SET label = excluded.label
+	`, label.UserID, label.Symbol, label.Label, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	// LastInsertId is not reliable for the update branch of an upsert
+	var id int64
+	err = r.db.QueryRow(`
+		SELECT id FROM holding_labels WHERE user_id = ? AND symbol = ?
+	`, label.UserID, label.Symbol).Scan(&id)
+	return id, err
+}
+
+// GetByID retrieves a label by ID.
+func (r *HoldingLabelRepository) GetByID(id int64) (*models.HoldingLabel, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, label, created_at
+		FROM holding_labels
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels, err := r.scanLabels(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels[0], nil
+}
+
+// GetByUserID retrieves a user's labels ordered by symbol.
+func (r *HoldingLabelRepository) GetByUserID(userID int64) ([]*models.HoldingLabel, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, label, created_at
+		FROM holding_labels
+		WHERE user_id = ?
+		ORDER BY symbol
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanLabels(rows)
+}
+
+// Delete removes a label.
+func (r *HoldingLabelRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM holding_labels WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("holding label not found")
+	}
+	return nil
+}
+
+// scanLabels scans holding label rows.
+func (r *HoldingLabelRepository) scanLabels(rows *sql.Rows) ([]*models.HoldingLabel, error) {
+	labels := make([]*models.HoldingLabel, 0)
+	for rows.Next() {
+		l := &models.HoldingLabel{}
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Symbol, &l.Label, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// execer runs statements on the database or within a transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// execQuerier runs statements and looks up rows on the database or within a
+// transaction.
+type execQuerier interface {
+	execer
+	rowQuerier
+}
+
+// snapshotHoldings records today's quantity and value of the holdings
+// matching where, replacing an earlier snapshot of the same day.
+func snapshotHoldings(db execer, where string, args ...any) error {
+	_, err := db.Exec(`
+		INSERT INTO holding_snapshots (account_id, symbol, name, day, quantity, current_value)
+		SELECT account_id, symbol, name, ?, quantity, current_value
+		FROM holdings
+		WHERE `+where+`
+		ON CONFLICT(account_id, symbol, day) DO UPDATE SET
+			name = excluded.name,
+			quantity = excluded.quantity,
+			current_value = excluded.current_value
+	`, append([]any{time.Now().Format("2006-01-02")}, args...)...)
+	return err
+}
+
+// snapshotRemovedHoldings records a zero quantity today for the holdings
+// matching where, before they are deleted.
+func snapshotRemovedHoldings(db execer, where string, args ...any) error {
+	_, err := db.Exec(`
+		INSERT INTO holding_snapshots (account_id, symbol, name, day, quantity, current_value)
+		SELECT account_id, symbol, name, ?, 0, 0
+		FROM holdings
+		WHERE `+where+`
+		ON CONFLICT(account_id, symbol, day) DO UPDATE SET
+			quantity = 0,
+			current_value = 0
+	`, append([]any{time.Now().Format("2006-01-02")}, args...)...)
+	return err
+}
+
+// HoldingSnapshot is a holding's quantity and value at the end of a day.
+type HoldingSnapshot struct {
+	AccountID    int64
+	Symbol       string
+	Name         string
+	Day          time.Time
+	Quantity     float64
+	CurrentValue float64
+}
+
+// GetSnapshotsAt returns the last snapshot on or before a date of every
+// holding a user's accounts have had, including removed holdings with a zero
+// quantity.
+func (r *HoldingRepository) GetSnapshotsAt(userID int64, at time.Time) ([]HoldingSnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT s.account_id, s.symbol, s.name, s.day, s.quantity, s.current_value
+		FROM holding_snapshots s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE a.user_id = ? AND s.day = (
+			SELECT MAX(day) FROM holding_snapshots
+			WHERE account_id = s.account_id AND symbol = s.symbol AND day <= ?
+		)
+		ORDER BY s.account_id, s.symbol
+	`, userID, at.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []HoldingSnapshot
+	for rows.Next() {
+		var s HoldingSnapshot
+		var day string
+		if err := rows.Scan(&s.AccountID, &s.Symbol, &s.Name, &day, &s.Quantity, &s.CurrentValue); err != nil {
+			return nil, err
+		}
+		s.Day = parseDate(day)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetHoldingsAt returns the holdings of a user's accounts at the end of a day,
+// keyed by account ID, rebuilt from their snapshots. Holdings only have a
+// symbol, name, quantity and value, with the currency and instrument type of
+// the holding as it is now or was when last removed.
+func (r *HoldingRepository) GetHoldingsAt(userID int64, day time.Time) (map[int64][]*models.Holding, error) {
+	rows, err := r.db.Query(`
+		SELECT s.account_id, s.symbol, s.name, s.quantity, s.current_value,
+			COALESCE(
+				(SELECT currency FROM holdings WHERE account_id = s.account_id AND symbol = s.symbol LIMIT 1),
+				(SELECT currency FROM holding_history WHERE account_id = s.account_id AND symbol = s.symbol ORDER BY deleted_at DESC LIMIT 1),
+				''
+			),
+			COALESCE(
+				(SELECT instrument_type FROM holdings WHERE account_id = s.account_id AND symbol = s.symbol LIMIT 1),
+				(SELECT instrument_type FROM holding_history WHERE account_id = s.account_id AND symbol = s.symbol ORDER BY deleted_at DESC LIMIT 1),
+				''
+			)
+		FROM holding_snapshots s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE a.user_id = ? AND s.quantity != 0 AND s.day = (
+			SELECT MAX(day) FROM holding_snapshots
+			WHERE account_id = s.account_id AND symbol = s.symbol AND day <= ?
+		)
+		ORDER BY s.account_id, s.current_value DESC
+	`, userID, day.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holdings := make(map[int64][]*models.Holding)
+	for rows.Next() {
+		h := &models.Holding{}
+		if err := rows.Scan(&h.AccountID, &h.Symbol, &h.Name, &h.Quantity, &h.CurrentValue, &h.Currency, &h.InstrumentType); err != nil {
+			return nil, err
+		}
+		holdings[h.AccountID] = append(holdings[h.AccountID], h)
+	}
+	return holdings, rows.Err()
+}
+
+// GetFirstSnapshotDay returns the day holdings were first recorded for a
+// user, or the zero time if never.
+func (r *HoldingRepository) GetFirstSnapshotDay(userID int64) (time.Time, error) {
+	var day sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MIN(s.day)
+		FROM holding_snapshots s
+		JOIN accounts a ON a.id = s.account_id
+		WHERE a.user_id = ?
+	`, userID).Scan(&day)
+	if err != nil || !day.Valid {
+		return time.Time{}, err
+	}
+	return parseDate(day.String), nil
+}
+
+// GetFirstHoldingSnapshotDay returns the day a holding of an account was
+// first recorded, or the zero time if never.
+func (r *HoldingRepository) GetFirstHoldingSnapshotDay(accountID int64, symbol string) (time.Time, error) {
+	var day sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MIN(day) FROM holding_snapshots WHERE account_id = ? AND symbol = ?
+	`, accountID, symbol).Scan(&day)
+	if err != nil || !day.Valid {
+		return time.Time{}, err
+	}
+	return parseDate(day.String), nil
+}
+
+// AddSnapshots records snapshots for days a holding has no snapshot of yet,
+// such as backfilled history, and returns how many were added. Snapshots
+// already recorded are kept.
+func (r *HoldingRepository) AddSnapshots(snapshots []HoldingSnapshot) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, s := range snapshots {
+		result, err := tx.Exec(`
+			INSERT INTO holding_snapshots (account_id, symbol, name, day, quantity, current_value)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(account_id, symbol, day) DO NOTHING
+		`, s.AccountID, s.Symbol, s.Name, s.Day.Format("2006-01-02"), s.Quantity, s.CurrentValue)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		added += int(n)
+	}
+	return added, tx.Commit()
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func createTestHoldingAccount(t *testing.T, repo *AccountRepository, userID, categoryID int64) int64 {
+	t.Helper()
+	id, err := repo.Create(&models.Account{
+		UserID:     userID,
+		CategoryID: &categoryID,
+		Name:       "Broker",
+		Currency:   "DKK",
+		IsActive:   true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	return id
+}
+
+func TestHoldingRepository_DeleteStaleHoldings_ArchivesAndRestores(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	for _, symbol := range []string{"KEEP", "GONE"} {
+		if err := repo.Upsert(&models.Holding{
+			AccountID: accountID, ExternalID: "1", Symbol: symbol, Name: symbol,
+			Quantity: 1, CurrentValue: 100, Currency: "DKK",
+		}); err != nil {
+			t.Fatalf("Upsert(%s) error = %v", symbol, err)
+		}
+	}
+
+	// Only KEEP is touched by the "sync"
+	time.Sleep(10 * time.Millisecond)
+	syncTime := time.Now()
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "KEEP", Name: "KEEP",
+		Quantity: 2, CurrentValue: 200, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert(KEEP) error = %v", err)
+	}
+
+	stale, err := repo.CountStaleHoldings(accountID, syncTime)
+	if err != nil || stale != 1 {
+		t.Fatalf("CountStaleHoldings() = %d, %v; want 1, nil", stale, err)
+	}
+
+	if err := repo.DeleteStaleHoldings(accountID, syncTime); err != nil {
+		t.Fatalf("DeleteStaleHoldings() error = %v", err)
+	}
+	if count, _ := repo.CountByAccountID(accountID); count != 1 {
+		t.Fatalf("CountByAccountID() = %d after delete; want 1", count)
+	}
+
+	archived, err := repo.GetArchived(10)
+	if err != nil {
+		t.Fatalf("GetArchived() error = %v", err)
+	}
+	if len(archived) != 1 || archived[0].Symbol != "GONE" || archived[0].Reason != "stale" {
+		t.Fatalf("GetArchived() = %+v; want one stale GONE entry", archived)
+	}
+
+	if err := repo.RestoreArchived(archived[0].ID); err != nil {
+		t.Fatalf("RestoreArchived() error = %v", err)
+	}
+	if count, _ := repo.CountByAccountID(accountID); count != 2 {
+		t.Errorf("CountByAccountID() = %d after restore; want 2", count)
+	}
+	if err := repo.RestoreArchived(archived[0].ID); err == nil {
+		t.Error("RestoreArchived() twice error = nil; want error")
+	}
+}
+
+func TestHoldingRepository_GetOrphaned_ReturnsUnmappedBrokerHoldings(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "42", Symbol: "SYNCED", Name: "Synced",
+		Quantity: 1, CurrentValue: 100, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, Symbol: "MANUAL", Name: "Manual",
+		Quantity: 1, CurrentValue: 100, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	orphaned, err := repo.GetOrphaned()
+	if err != nil {
+		t.Fatalf("GetOrphaned() error = %v", err)
+	}
+	if len(orphaned) != 1 || orphaned[0].Symbol != "SYNCED" || orphaned[0].Reason != "unmapped" {
+		t.Errorf("GetOrphaned() = %+v; want only the unmapped broker holding", orphaned)
+	}
+}
+
+func TestHoldingRepository_BackfillCurrencies(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	// AAPL is known to be in USD from another holding; NOVO is not known
+	for _, h := range []*models.Holding{
+		{AccountID: accountID, Symbol: "AAPL", Name: "Apple", Quantity: 1, CurrentValue: 100},
+		{AccountID: accountID, Symbol: "NOVO", Name: "Novo", Quantity: 1, CurrentValue: 100},
+	} {
+		if err := repo.Upsert(h); err != nil {
+			t.Fatalf("Upsert(%s) error = %v", h.Symbol, err)
+		}
+	}
+	otherID, err := NewAccountRepository(db).Create(&models.Account{
+		UserID: userID, CategoryID: &categoryID, Name: "Other", Currency: "DKK", IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if err := repo.Upsert(&models.Holding{AccountID: otherID, Symbol: "AAPL", Name: "Apple", Quantity: 1, CurrentValue: 100, Currency: "USD"}); err != nil {
+		t.Fatalf("Upsert(AAPL) error = %v", err)
+	}
+	// Another user's holding does not count
+	result, _ := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES ('other@example.com', 'x', 'Other')`)
+	otherUserID, _ := result.LastInsertId()
+	strangerID, err := NewAccountRepository(db).Create(&models.Account{UserID: otherUserID, Name: "Stranger", Currency: "SEK", IsActive: true})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if err := repo.Upsert(&models.Holding{AccountID: strangerID, Symbol: "NOVO", Name: "Novo", Quantity: 1, CurrentValue: 100, Currency: "SEK"}); err != nil {
+		t.Fatalf("Upsert(NOVO) error = %v", err)
+	}
+
+	n, err := repo.BackfillCurrencies()
+	if err != nil || n != 2 {
+		t.Fatalf("BackfillCurrencies() = %d, %v; want 2, nil", n, err)
+	}
+
+	holdings, err := repo.GetByAccountID(accountID)
+	if err != nil {
+		t.Fatalf("GetByAccountID() error = %v", err)
+	}
+	want := map[string]string{"AAPL": "USD", "NOVO": "DKK"}
+	for _, h := range holdings {
+		if h.Currency != want[h.Symbol] {
+			t.Errorf("%s currency = %q; want %q", h.Symbol, h.Currency, want[h.Symbol])
+		}
+	}
+
+	// A later sync without a currency keeps the backfilled one
+	if err := repo.Upsert(&models.Holding{AccountID: accountID, Symbol: "AAPL", Name: "Apple", Quantity: 2, CurrentValue: 200}); err != nil {
+		t.Fatalf("Upsert(AAPL) error = %v", err)
+	}
+	if n, _ := repo.BackfillCurrencies(); n != 0 {
+		t.Errorf("BackfillCurrencies() = %d after a sync without currency; want 0", n)
+	}
+}
+
+func TestHoldingRepository_GetHoldingsAt_RebuildsFromSnapshots(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "SPY", Name: "S&P 500",
+		Quantity: 3, CurrentValue: 300, Currency: "USD", InstrumentType: "etf",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	for _, s := range []struct {
+		symbol, day string
+		quantity    float64
+	}{
+		{"SPY", "2024-01-10", 1},
+		{"SPY", "2024-03-10", 2},
+		{"OLD", "2024-01-05", 5},
+		{"OLD", "2024-02-01", 0},
+	} {
+		if _, err := db.Exec(`INSERT INTO holding_snapshots (account_id, symbol, name, day, quantity, current_value) VALUES (?, ?, ?, ?, ?, ?)`,
+			accountID, s.symbol, s.symbol, s.day, s.quantity, s.quantity*100); err != nil {
+			t.Fatalf("inserting snapshot: %v", err)
+		}
+	}
+
+	holdings, err := repo.GetHoldingsAt(userID, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetHoldingsAt() error = %v", err)
+	}
+	if len(holdings[accountID]) != 2 {
+		t.Fatalf("GetHoldingsAt(January) = %d holdings; want SPY and OLD", len(holdings[accountID]))
+	}
+	if spy := holdings[accountID][1]; spy.Symbol != "SPY" || spy.Quantity != 1 || spy.Currency != "USD" || spy.InstrumentType != "etf" {
+		t.Errorf("SPY in January = %+v; want 1 USD etf from the current holding", spy)
+	}
+
+	// OLD was sold in February
+	holdings, err = repo.GetHoldingsAt(userID, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetHoldingsAt() error = %v", err)
+	}
+	if len(holdings[accountID]) != 1 || holdings[accountID][0].Symbol != "SPY" {
+		t.Errorf("GetHoldingsAt(February) = %+v; want only SPY", holdings[accountID])
+	}
+}
+
+func TestHoldingRepository_AddSnapshots_KeepsRecordedDays(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	// Syncing records today's snapshot
+	if err := repo.Upsert(&models.Holding{AccountID: accountID, ExternalID: "1", Symbol: "DK0010244508", Name: "Maersk B", Quantity: 2, CurrentValue: 24000, Currency: "DKK"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	today := time.Now()
+	first, err := repo.GetFirstHoldingSnapshotDay(accountID, "DK0010244508")
+	if err != nil || first.Format("2006-01-02") != today.Format("2006-01-02") {
+		t.Fatalf("GetFirstHoldingSnapshotDay() = %v, %v; want today", first, err)
+	}
+
+	day := func(daysAgo int) time.Time { return today.AddDate(0, 0, -daysAgo) }
+	added, err := repo.AddSnapshots([]HoldingSnapshot{
+		{AccountID: accountID, Symbol: "DK0010244508", Name: "Maersk B", Day: day(2), Quantity: 2, CurrentValue: 22000},
+		{AccountID: accountID, Symbol: "DK0010244508", Name: "Maersk B", Day: day(1), Quantity: 2, CurrentValue: 23000},
+		{AccountID: accountID, Symbol: "DK0010244508", Name: "Maersk B", Day: day(0), Quantity: 2, CurrentValue: 1},
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("AddSnapshots() = %d, %v; want the 2 days before today added", added, err)
+	}
+
+	snapshots, err := repo.GetSnapshotsAt(userID, today)
+	if err != nil || len(snapshots) != 1 || snapshots[0].CurrentValue != 24000 {
+		t.Errorf("GetSnapshotsAt(today) = %+v, %v; want the synced snapshot kept", snapshots, err)
+	}
+	if first, _ = repo.GetFirstHoldingSnapshotDay(accountID, "DK0010244508"); first.Format("2006-01-02") != day(2).Format("2006-01-02") {
+		t.Errorf("GetFirstHoldingSnapshotDay() after backfill = %v; want 2 days ago", first)
+	}
+}
+
+func TestHoldingRepository_ImportManual_RollsBackOnError(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, Symbol: "KEEP", Name: "KEEP", Quantity: 1, CurrentValue: 100, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	// The second holding references a missing account and fails the import
+	err := repo.ImportManual(accountID, []*models.Holding{
+		{AccountID: accountID, Symbol: "NEW", Name: "NEW", Quantity: 1, CurrentValue: 50, Currency: "DKK"},
+		{AccountID: accountID + 1000, Symbol: "BAD", Name: "BAD", Quantity: 1, CurrentValue: 50, Currency: "DKK"},
+	}, true)
+	if err == nil {
+		t.Fatal("ImportManual() error = nil; want the failing holding's error")
+	}
+
+	holdings, err := repo.GetByAccountID(accountID)
+	if err != nil {
+		t.Fatalf("GetByAccountID() error = %v", err)
+	}
+	if len(holdings) != 1 || holdings[0].Symbol != "KEEP" {
+		t.Errorf("holdings after failed import = %+v; want only KEEP", holdings)
+	}
+
+	if err := repo.ImportManual(accountID, []*models.Holding{
+		{AccountID: accountID, Symbol: "NEW", Name: "NEW", Quantity: 1, CurrentValue: 50, Currency: "DKK"},
+	}, true); err != nil {
+		t.Fatalf("ImportManual() error = %v", err)
+	}
+	holdings, _ = repo.GetByAccountID(accountID)
+	if len(holdings) != 1 || holdings[0].Symbol != "NEW" {
+		t.Errorf("holdings after replace = %+v; want only NEW", holdings)
+	}
+}
+
+func TestHoldingRepository_GetLatestSyncedPrice_OnlyUsersHoldings(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewHoldingRepository(db)
+	accountID := createTestHoldingAccount(t, NewAccountRepository(db), userID, categoryID)
+
+	result, err := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES ('other@example.com', 'x', 'Other')`)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	otherID, _ := result.LastInsertId()
+	otherAccountID := createTestHoldingAccount(t, NewAccountRepository(db), otherID, categoryID)
+
+	if err := repo.Upsert(&models.Holding{
+		AccountID: otherAccountID, ExternalID: "1", Symbol: "DK0010274414", Name: "Danske Bank",
+		Quantity: 1, CurrentPrice: 200, CurrentValue: 200, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if price, _, err := repo.GetLatestSyncedPrice(userID, "DK0010274414"); err != nil || price != nil {
+		t.Fatalf("GetLatestSyncedPrice() = %v, %v; want no price from another user's holding", price, err)
+	}
+
+	if err := repo.Upsert(&models.Holding{
+		AccountID: accountID, ExternalID: "1", Symbol: "DK0010274414", Name: "Danske Bank",
+		Quantity: 1, CurrentPrice: 190, CurrentValue: 190, Currency: "DKK",
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if price, _, err := repo.GetLatestSyncedPrice(userID, "DK0010274414"); err != nil || price == nil || *price != 190 {
+		t.Errorf("GetLatestSyncedPrice() = %v, %v; want the user's price 190", price, err)
+	}
+}
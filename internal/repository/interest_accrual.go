@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// InterestAccrualRepository handles the interest posted to liabilities.
+type InterestAccrualRepository struct {
+	db *database.DB
+}
+
+// NewInterestAccrualRepository creates a new InterestAccrualRepository.
+func NewInterestAccrualRepository(db *database.DB) *InterestAccrualRepository {
+	return &InterestAccrualRepository{db: db}
+}
+
+// Post inserts the interest transaction of an accrual and records the
+// accrual in one database transaction, and returns the accrual's ID.
+func (r *InterestAccrualRepository) Post(accrual *models.InterestAccrual, txn *models.Transaction) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO transactions (account_id, amount, balance_after, description, category_id, kind, tag, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, txn.AccountID, txn.Amount, txn.BalanceAfter, txn.Description, txn.CategoryID, txn.Kind, txn.Tag, txn.TransactionDate.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	txnID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err = tx.Exec(`
+		INSERT INTO interest_accruals (account_id, transaction_id, period_end, rate, amount)
+		VALUES (?, ?, ?, ?, ?)
+	`, accrual.AccountID, txnID, accrual.PeriodEnd.Format("2006-01-02"), accrual.Rate, accrual.Amount)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	txn.ID = txnID
+	accrual.TransactionID = &txnID
+	return id, nil
+}
+
+// GetLatestPeriodEnd returns the end of the last month interest was posted
+// for on an account, or nil if none was.
+func (r *InterestAccrualRepository) GetLatestPeriodEnd(accountID int64) (*time.Time, error) {
+	var periodEnd sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MAX(period_end) FROM interest_accruals WHERE account_id = ?
+	`, accountID).Scan(&periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if !periodEnd.Valid {
+		return nil, nil
+	}
+	t := parseDate(periodEnd.String)
+	return &t, nil
+}
+
+// GetTotalsByUserID returns the interest posted to each of a user's accounts,
+// by account ID. Postings that have since been deleted are not counted.
+func (r *InterestAccrualRepository) GetTotalsByUserID(userID int64) (map[int64]float64, error) {
+	rows, err := r.db.Query(`
+		SELECT i.account_id, SUM(ABS(t.amount))
+		FROM interest_accruals i
+		JOIN transactions t ON t.id = i.transaction_id
+		JOIN accounts a ON a.id = i.account_id
+		WHERE a.user_id = ?
+		GROUP BY i.account_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int64]float64)
+	for rows.Next() {
+		var accountID int64
+		var total float64
+		if err := rows.Scan(&accountID, &total); err != nil {
+			return nil, err
+		}
+		totals[accountID] = total
+	}
+	return totals, rows.Err()
+}
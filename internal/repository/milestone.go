@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// MilestoneRepository handles net worth milestone database operations.
+type MilestoneRepository struct {
+	db *database.DB
+}
+
+// NewMilestoneRepository creates a new MilestoneRepository.
+func NewMilestoneRepository(db *database.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+// Record stores milestones reached under the given step that are not yet
+// recorded for the user and returns how many were new. When a user's first
+// milestones are recorded at once, only the highest is left to celebrate.
+// Milestones first recorded under a new step were reached before the step
+// changed, so none of them are celebrated.
+func (r *MilestoneRepository) Record(userID int64, step float64, milestones []*models.NetWorthMilestone) (int, error) {
+	if len(milestones) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var existing, sameStep int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(step = ?), 0) FROM net_worth_milestones WHERE user_id = ?
+	`, step, userID).Scan(&existing, &sameStep); err != nil {
+		return 0, err
+	}
+	stepChanged := existing > 0 && sameStep == 0
+
+	added := 0
+	for _, m := range milestones {
+		result, err := tx.Exec(`
+			INSERT INTO net_worth_milestones (user_id, amount, reached_at, celebrated, step, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, amount) DO NOTHING
+		`, userID, m.Amount, m.ReachedAt, stepChanged, step, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		n, _ := result.RowsAffected()
+		added += int(n)
+	}
+
+	if existing == 0 && added > 1 {
+		if _, err := tx.Exec(`
+			UPDATE net_worth_milestones SET celebrated = 1
+			WHERE user_id = ? AND amount < (SELECT MAX(amount) FROM net_worth_milestones WHERE user_id = ?)
+		`, userID, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	return added, tx.Commit()
+}
+
+// GetByUserID retrieves all milestones for a user, highest first.
+func (r *MilestoneRepository) GetByUserID(userID int64) ([]*models.NetWorthMilestone, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, amount, reached_at, celebrated, created_at
+		FROM net_worth_milestones
+		WHERE user_id = ?
+		ORDER BY amount DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var milestones []*models.NetWorthMilestone
+	for rows.Next() {
+		m := &models.NetWorthMilestone{}
+		var celebrated int
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Amount, &m.ReachedAt, &celebrated, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Celebrated = celebrated == 1
+		milestones = append(milestones, m)
+	}
+	return milestones, rows.Err()
+}
+
+// MarkCelebrated marks all of a user's milestones as celebrated.
+func (r *MilestoneRepository) MarkCelebrated(userID int64) error {
+	_, err := r.db.Exec(`UPDATE net_worth_milestones SET celebrated = 1 WHERE user_id = ? AND celebrated = 0`, userID)
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestMilestoneRepository_Record_OnlyCelebratesHighestOnFirstRun(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewMilestoneRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	added, err := repo.Record(userID, 100000, []*models.NetWorthMilestone{
+		{Amount: 100000, ReachedAt: day(1)},
+		{Amount: 200000, ReachedAt: day(2)},
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("Record() = %d, %v; want 2, nil", added, err)
+	}
+
+	milestones, _ := repo.GetByUserID(userID)
+	if len(milestones) != 2 || milestones[0].Amount != 200000 || milestones[0].Celebrated || !milestones[1].Celebrated {
+		t.Fatalf("GetByUserID() = %+v; want 200000 uncelebrated, 100000 celebrated", milestones)
+	}
+
+	if err := repo.MarkCelebrated(userID); err != nil {
+		t.Fatalf("MarkCelebrated() error = %v", err)
+	}
+
+	// Re-recording is a no-op; a newly crossed milestone is left to celebrate
+	added, err = repo.Record(userID, 100000, []*models.NetWorthMilestone{
+		{Amount: 100000, ReachedAt: day(1)},
+		{Amount: 200000, ReachedAt: day(2)},
+		{Amount: 300000, ReachedAt: day(3)},
+	})
+	if err != nil || added != 1 {
+		t.Fatalf("Record() = %d, %v; want 1, nil", added, err)
+	}
+	milestones, _ = repo.GetByUserID(userID)
+	if len(milestones) != 3 || milestones[0].Celebrated || !milestones[1].Celebrated {
+		t.Errorf("GetByUserID() = %+v; want only 300000 uncelebrated", milestones)
+	}
+}
+
+func TestMilestoneRepository_Record_StepChangeCelebratesNothing(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewMilestoneRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	if _, err := repo.Record(userID, 100000, []*models.NetWorthMilestone{
+		{Amount: 100000, ReachedAt: day(1)},
+		{Amount: 200000, ReachedAt: day(2)},
+	}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := repo.MarkCelebrated(userID); err != nil {
+		t.Fatalf("MarkCelebrated() error = %v", err)
+	}
+
+	// A smaller step adds milestones that were passed long ago
+	added, err := repo.Record(userID, 50000, []*models.NetWorthMilestone{
+		{Amount: 50000, ReachedAt: day(1)},
+		{Amount: 100000, ReachedAt: day(1)},
+		{Amount: 150000, ReachedAt: day(2)},
+		{Amount: 200000, ReachedAt: day(2)},
+	})
+	if err != nil || added != 2 {
+		t.Fatalf("Record() = %d, %v; want 2, nil", added, err)
+	}
+	milestones, _ := repo.GetByUserID(userID)
+	for _, m := range milestones {
+		if !m.Celebrated {
+			t.Errorf("milestone %.0f left to celebrate after the step changed", m.Amount)
+		}
+	}
+
+	// Milestones crossed under the new step are celebrated again
+	if _, err := repo.Record(userID, 50000, []*models.NetWorthMilestone{{Amount: 250000, ReachedAt: day(3)}}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	milestones, _ = repo.GetByUserID(userID)
+	if len(milestones) != 5 || milestones[0].Amount != 250000 || milestones[0].Celebrated {
+		t.Errorf("GetByUserID() = %+v; want 250000 left to celebrate", milestones)
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// MitIDAttemptRepository handles MitID authentication attempt database operations.
+type MitIDAttemptRepository struct {
+	db *database.DB
+}
+
+// NewMitIDAttemptRepository creates a new MitIDAttemptRepository.
+func NewMitIDAttemptRepository(db *database.DB) *MitIDAttemptRepository {
+	return &MitIDAttemptRepository{db: db}
+}
+
+// Start records a new attempt with status "started" and returns its ID.
+func (r *MitIDAttemptRepository) Start(connectionID int64, purpose string) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO mitid_attempts (connection_id, purpose, status, started_at)
+		VALUES (?, ?, 'started', ?)
+	`, connectionID, purpose, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Complete marks an attempt as successful.
+func (r *MitIDAttemptRepository) Complete(id int64) error {
+	_, err := r.db.Exec(`
+		UPDATE mitid_attempts SET status = 'success', completed_at = ? WHERE id = ?
+	`, time.Now(), id)
+	return err
+}
+
+// Fail marks an attempt as failed with an error message.
+func (r *MitIDAttemptRepository) Fail(id int64, errorMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE mitid_attempts SET status = 'failed', error_message = ?, completed_at = ? WHERE id = ?
+	`, errorMsg, time.Now(), id)
+	return err
+}
+
+// GetByConnectionID retrieves attempts for a connection, most recent first.
+func (r *MitIDAttemptRepository) GetByConnectionID(connectionID int64, limit int) ([]*models.MitIDAttempt, error) {
+	rows, err := r.db.Query(`
+		SELECT id, connection_id, purpose, status, error_message, started_at, completed_at
+		FROM mitid_attempts
+		WHERE connection_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, connectionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]*models.MitIDAttempt, 0)
+	for rows.Next() {
+		attempt := &models.MitIDAttempt{}
+		var errorMsg sql.NullString
+		var completedAt sql.NullTime
+
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.ConnectionID,
+			&attempt.Purpose,
+			&attempt.Status,
+			&errorMsg,
+			&attempt.StartedAt,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if errorMsg.Valid {
+			attempt.ErrorMessage = errorMsg.String
+		}
+		if completedAt.Valid {
+			attempt.CompletedAt = &completedAt.Time
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// GetConsecutiveFailures returns the number of failed attempts since the last
+// successful one, and the time of the most recent failure. Attempts still in
+// progress are ignored.
+func (r *MitIDAttemptRepository) GetConsecutiveFailures(connectionID int64) (int, time.Time, error) {
+	rows, err := r.db.Query(`
+		SELECT status, started_at, completed_at
+		FROM mitid_attempts
+		WHERE connection_id = ? AND status != 'started'
+		ORDER BY started_at DESC, id DESC
+	`, connectionID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer rows.Close()
+
+	var failures int
+	var lastFailure time.Time
+	for rows.Next() {
+		var status string
+		var startedAt time.Time
+		var completedAt sql.NullTime
+		if err := rows.Scan(&status, &startedAt, &completedAt); err != nil {
+			return 0, time.Time{}, err
+		}
+		if status != "failed" {
+			break
+		}
+		if failures == 0 {
+			lastFailure = startedAt
+			if completedAt.Valid {
+				lastFailure = completedAt.Time
+			}
+		}
+		failures++
+	}
+
+	return failures, lastFailure, rows.Err()
+}
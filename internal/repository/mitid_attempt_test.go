@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestMitIDAttemptRepository_GetConsecutiveFailures(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	connID, err := NewBrokerConnectionRepository(db).Create(&models.BrokerConnection{
+		UserID: userID, BrokerType: "nordnet", Username: "user", Country: "dk", IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create connection: %v", err)
+	}
+	repo := NewMitIDAttemptRepository(db)
+
+	record := func(success bool) {
+		t.Helper()
+		id, err := repo.Start(connID, "sync")
+		if err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		if success {
+			err = repo.Complete(id)
+		} else {
+			err = repo.Fail(id, "timed out")
+		}
+		if err != nil {
+			t.Fatalf("recording attempt: %v", err)
+		}
+	}
+
+	record(false)
+	record(true)
+	record(false)
+	record(false)
+
+	// An attempt still in progress does not count either way
+	if _, err := repo.Start(connID, "sync"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	failures, lastFailure, err := repo.GetConsecutiveFailures(connID)
+	if err != nil {
+		t.Fatalf("GetConsecutiveFailures() error = %v", err)
+	}
+	if failures != 2 {
+		t.Errorf("failures = %d; want 2", failures)
+	}
+	if lastFailure.IsZero() {
+		t.Error("lastFailure is zero; want time of latest failure")
+	}
+
+	attempts, err := repo.GetByConnectionID(connID, 10)
+	if err != nil {
+		t.Fatalf("GetByConnectionID() error = %v", err)
+	}
+	if len(attempts) != 5 {
+		t.Fatalf("len(attempts) = %d; want 5", len(attempts))
+	}
+	if attempts[0].Status != "started" || attempts[1].ErrorMessage != "timed out" {
+		t.Errorf("unexpected attempt order or content: %+v, %+v", attempts[0], attempts[1])
+	}
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// NetWorthSnapshotRepository handles the daily net worth history of users.
+type NetWorthSnapshotRepository struct {
+	db *database.DB
+}
+
+// NewNetWorthSnapshotRepository creates a new NetWorthSnapshotRepository.
+func NewNetWorthSnapshotRepository(db *database.DB) *NetWorthSnapshotRepository {
+	return &NetWorthSnapshotRepository{db: db}
+}
+
+// Upsert stores a user's net worth on a day with its category totals,
+// replacing the figures recorded earlier on the same day.
+func (r *NetWorthSnapshotRepository) Upsert(snapshot *models.NetWorthSnapshot) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO net_worth_snapshots (user_id, day, assets, liabilities, net_worth, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, day) DO UPDATE SET
+			assets = excluded.assets,
+			liabilities = excluded.liabilities,
+			net_worth = excluded.net_worth,
+			recorded_at = excluded.recorded_at
+	`, snapshot.UserID, snapshot.Day, snapshot.Assets, snapshot.Liabilities, snapshot.NetWorth, snapshot.RecordedAt)
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	if err := tx.QueryRow(`SELECT id FROM net_worth_snapshots WHERE user_id = ? AND day = ?`, snapshot.UserID, snapshot.Day).Scan(&id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM net_worth_snapshot_categories WHERE snapshot_id = ?`, id); err != nil {
+		return err
+	}
+	for categoryID, total := range snapshot.Categories {
+		if _, err := tx.Exec(`
+			INSERT INTO net_worth_snapshot_categories (snapshot_id, category_id, total)
+			VALUES (?, ?, ?)
+		`, id, categoryID, total); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	snapshot.ID = id
+	return nil
+}
+
+// GetFirstDay returns the day of the user's earliest snapshot, or the zero
+// time if they have none.
+func (r *NetWorthSnapshotRepository) GetFirstDay(userID int64) (time.Time, error) {
+	var day sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MIN(substr(day, 1, 10)) FROM net_worth_snapshots WHERE user_id = ?
+	`, userID).Scan(&day)
+	if err != nil || !day.Valid {
+		return time.Time{}, err
+	}
+	return parseDate(day.String), nil
+}
+
+// GetByUserID returns the snapshots of a user from one day to another,
+// inclusive, oldest first. A zero from or to leaves that end open.
+func (r *NetWorthSnapshotRepository) GetByUserID(userID int64, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	query := `
+		SELECT id, user_id, day, assets, liabilities, net_worth, recorded_at
+		FROM net_worth_snapshots
+		WHERE user_id = ?`
+	args := []any{userID}
+	if !from.IsZero() {
+		query += ` AND day >= ?`
+		args = append(args, from)
+	}
+	if !to.IsZero() {
+		query += ` AND day <= ?`
+		args = append(args, to)
+	}
+	query += ` ORDER BY day ASC`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]*models.NetWorthSnapshot, 0)
+	byID := make(map[int64]*models.NetWorthSnapshot)
+	for rows.Next() {
+		s := &models.NetWorthSnapshot{Categories: make(map[int64]float64)}
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Day, &s.Assets, &s.Liabilities, &s.NetWorth, &s.RecordedAt); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+		byID[s.ID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return snapshots, nil
+	}
+
+	catRows, err := r.db.Query(`
+		SELECT c.snapshot_id, c.category_id, c.total
+		FROM net_worth_snapshot_categories c
+		JOIN net_worth_snapshots s ON s.id = c.snapshot_id
+		WHERE s.user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer catRows.Close()
+	for catRows.Next() {
+		var snapshotID, categoryID int64
+		var total float64
+		if err := catRows.Scan(&snapshotID, &categoryID, &total); err != nil {
+			return nil, err
+		}
+		if s := byID[snapshotID]; s != nil {
+			s.Categories[categoryID] = total
+		}
+	}
+	return snapshots, catRows.Err()
+}
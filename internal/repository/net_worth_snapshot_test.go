@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestNetWorthSnapshotRepository_UpsertReplacesTheDay(t *testing.T) {
+	db, userID, categoryID := setupAccountTestDB(t)
+	repo := NewNetWorthSnapshotRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	for _, s := range []*models.NetWorthSnapshot{
+		{UserID: userID, Day: day(1), Assets: 1000, NetWorth: 1000, Categories: map[int64]float64{categoryID: 1000}},
+		{UserID: userID, Day: day(2), Assets: 1500, Liabilities: 200, NetWorth: 1300, Categories: map[int64]float64{categoryID: 1500}},
+		// Later the same day
+		{UserID: userID, Day: day(2), Assets: 1600, Liabilities: 200, NetWorth: 1400},
+		{UserID: userID, Day: day(3), Assets: 1700, NetWorth: 1700, Categories: map[int64]float64{categoryID: 1700}},
+	} {
+		s.RecordedAt = s.Day.Add(12 * time.Hour)
+		if err := repo.Upsert(s); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+	}
+
+	all, err := repo.GetByUserID(userID, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if len(all) != 3 || all[1].NetWorth != 1400 || len(all[1].Categories) != 0 || all[2].Categories[categoryID] != 1700 {
+		t.Fatalf("GetByUserID() = %+v; want 3 days with the second replaced", all)
+	}
+
+	ranged, err := repo.GetByUserID(userID, day(2), day(2))
+	if err != nil || len(ranged) != 1 || !ranged[0].Day.Equal(day(2)) {
+		t.Errorf("GetByUserID(day 2, day 2) = %+v, %v; want only day 2", ranged, err)
+	}
+}
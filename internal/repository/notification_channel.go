@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// NotificationChannelRepository handles notification channel database
+// operations. Channel URLs and tokens are encrypted with the owner's key, as
+// webhook URLs and tokens let anyone post to the channel.
+type NotificationChannelRepository struct {
+	db        *database.DB
+	encryptor *broker.Encryptor
+}
+
+// NewNotificationChannelRepository creates a new NotificationChannelRepository.
+func NewNotificationChannelRepository(db *database.DB, encryptor *broker.Encryptor) *NotificationChannelRepository {
+	return &NotificationChannelRepository{db: db, encryptor: encryptor}
+}
+
+// Create creates a new notification channel and returns its ID.
+func (r *NotificationChannelRepository) Create(channel *models.NotificationChannel) (int64, error) {
+	url, err := r.seal(channel.URL, channel.UserID)
+	if err != nil {
+		return 0, err
+	}
+	token, err := r.seal(channel.Token, channel.UserID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO notification_channels (user_id, kind, name, url, topic, token, secrets_encrypted, is_active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?)
+	`, channel.UserID, channel.Kind, channel.Name, url, channel.Topic, token, channel.IsActive, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// EncryptStored encrypts the URLs and tokens of channels saved before they
+// were encrypted, and returns how many channels it encrypted.
+func (r *NotificationChannelRepository) EncryptStored() (int, error) {
+	rows, err := r.db.Query(`SELECT id, user_id, url, token FROM notification_channels WHERE secrets_encrypted = 0`)
+	if err != nil {
+		return 0, err
+	}
+	type plainChannel struct {
+		id, userID int64
+		url, token string
+	}
+	var plain []plainChannel
+	for rows.Next() {
+		var c plainChannel
+		if err := rows.Scan(&c.id, &c.userID, &c.url, &c.token); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plain = append(plain, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, c := range plain {
+		url, err := r.seal(c.url, c.userID)
+		if err != nil {
+			return 0, err
+		}
+		token, err := r.seal(c.token, c.userID)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := r.db.Exec(`UPDATE notification_channels SET url = ?, token = ?, secrets_encrypted = 1 WHERE id = ? AND secrets_encrypted = 0`,
+			url, token, c.id); err != nil {
+			return 0, err
+		}
+	}
+	return len(plain), nil
+}
+
+// GetByID retrieves a notification channel by ID.
+func (r *NotificationChannelRepository) GetByID(id int64) (*models.NotificationChannel, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, kind, name, url, topic, token, secrets_encrypted, is_active, last_sent_at, last_error, created_at
+		FROM notification_channels
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels, err := r.scanChannels(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	return channels[0], nil
+}
+
+// GetByUserID retrieves a user's notification channels in the order they
+// were added.
+func (r *NotificationChannelRepository) GetByUserID(userID int64) ([]*models.NotificationChannel, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, kind, name, url, topic, token, secrets_encrypted, is_active, last_sent_at, last_error, created_at
+		FROM notification_channels
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanChannels(rows)
+}
+
+// GetActiveByUserID retrieves a user's active notification channels.
+func (r *NotificationChannelRepository) GetActiveByUserID(userID int64) ([]*models.NotificationChannel, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, kind, name, url, topic, token, secrets_encrypted, is_active, last_sent_at, last_error, created_at
+		FROM notification_channels
+		WHERE user_id = ? AND is_active = 1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanChannels(rows)
+}
+
+// SetActive enables or disables a notification channel.
+func (r *NotificationChannelRepository) SetActive(id int64, active bool) error {
+	_, err := r.db.Exec(`UPDATE notification_channels SET is_active = ? WHERE id = ?`, active, id)
+	return err
+}
+
+// RecordDelivery records the outcome of a delivery attempt. A successful
+// delivery clears the last error; a failed one keeps the last sent time.
+func (r *NotificationChannelRepository) RecordDelivery(id int64, at time.Time, deliveryErr error) error {
+	if deliveryErr != nil {
+		_, err := r.db.Exec(`UPDATE notification_channels SET last_error = ? WHERE id = ?`, deliveryErr.Error(), id)
+		return err
+	}
+	_, err := r.db.Exec(`UPDATE notification_channels SET last_sent_at = ?, last_error = '' WHERE id = ?`, at, id)
+	return err
+}
+
+// Delete removes a notification channel.
+func (r *NotificationChannelRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM notification_channels WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("notification channel not found")
+	}
+	return nil
+}
+
+// scanChannels scans notification channel rows.
+func (r *NotificationChannelRepository) scanChannels(rows *sql.Rows) ([]*models.NotificationChannel, error) {
+	channels := make([]*models.NotificationChannel, 0)
+	for rows.Next() {
+		c := &models.NotificationChannel{}
+		var lastSentAt sql.NullTime
+		var encrypted bool
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Kind, &c.Name, &c.URL, &c.Topic, &c.Token, &encrypted,
+			&c.IsActive, &lastSentAt, &c.LastError, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if encrypted {
+			var err error
+			if c.URL, err = r.open(c.URL, c.UserID); err != nil {
+				return nil, fmt.Errorf("decrypting channel %d: %w", c.ID, err)
+			}
+			if c.Token, err = r.open(c.Token, c.UserID); err != nil {
+				return nil, fmt.Errorf("decrypting channel %d: %w", c.ID, err)
+			}
+		}
+		if lastSentAt.Valid {
+			c.LastSentAt = &lastSentAt.Time
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+// seal encrypts a channel secret of a user, as the base64 of the nonce
+// followed by the ciphertext.
+func (r *NotificationChannelRepository) seal(value string, userID int64) (string, error) {
+	ciphertext, nonce, err := r.encryptor.Encrypt(value, userID)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(append(nonce, ciphertext...)), nil
+}
+
+// open decrypts a channel secret sealed by seal.
+func (r *NotificationChannelRepository) open(sealed string, userID int64) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < broker.NonceSize {
+		return "", broker.ErrInvalidCiphertext
+	}
+	return r.encryptor.Decrypt(data[broker.NonceSize:], data[:broker.NonceSize], userID)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// PendingInvestmentRepository handles pending investment database operations.
+type PendingInvestmentRepository struct {
+	db *database.DB
+}
+
+// NewPendingInvestmentRepository creates a new PendingInvestmentRepository.
+func NewPendingInvestmentRepository(db *database.DB) *PendingInvestmentRepository {
+	return &PendingInvestmentRepository{db: db}
+}
+
+// Set records an account's pending investments, removing them if there are
+// no pending orders.
+func (r *PendingInvestmentRepository) Set(accountID int64, amount float64, orderCount int) error {
+	if orderCount == 0 {
+		_, err := r.db.Exec(`DELETE FROM pending_investments WHERE account_id = ?`, accountID)
+		return err
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO pending_investments (account_id, amount, order_count, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(account_id) DO UPDATE SET
+			amount = excluded.amount,
+			order_count = excluded.order_count,
+			updated_at = excluded.updated_at
+	`, accountID, amount, orderCount, time.Now())
+	return err
+}
+
+// GetByUserID retrieves the pending investments of a user's accounts by
+// account ID.
+func (r *PendingInvestmentRepository) GetByUserID(userID int64) (map[int64]*models.PendingInvestment, error) {
+	rows, err := r.db.Query(`
+		SELECT p.account_id, p.amount, p.order_count, p.updated_at
+		FROM pending_investments p
+		JOIN accounts a ON a.id = p.account_id
+		WHERE a.user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make(map[int64]*models.PendingInvestment)
+	for rows.Next() {
+		p := &models.PendingInvestment{}
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&p.AccountID, &p.Amount, &p.OrderCount, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = updatedAt.Time
+		pending[p.AccountID] = p
+	}
+	return pending, rows.Err()
+}
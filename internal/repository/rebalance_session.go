@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// RebalanceSessionRepository handles rebalancing checklist database operations.
+type RebalanceSessionRepository struct {
+	db *database.DB
+}
+
+// NewRebalanceSessionRepository creates a new RebalanceSessionRepository.
+func NewRebalanceSessionRepository(db *database.DB) *RebalanceSessionRepository {
+	return &RebalanceSessionRepository{db: db}
+}
+
+// Create inserts a session with its actions and returns the session ID.
+func (r *RebalanceSessionRepository) Create(session *models.RebalanceSession) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO rebalance_sessions (user_id, target_type, new_money, created_at)
+		VALUES (?, ?, ?, ?)
+	`, session.UserID, session.TargetType, session.NewMoney, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, action := range session.Actions {
+		if _, err := tx.Exec(`
+			INSERT INTO rebalance_actions (session_id, target_key, target_name, action, amount, current_pct, target_pct)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, id, action.TargetKey, action.TargetName, action.Action, action.Amount, action.CurrentPct, action.TargetPct); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, tx.Commit()
+}
+
+// GetByID retrieves a session with its actions.
+func (r *RebalanceSessionRepository) GetByID(id int64) (*models.RebalanceSession, error) {
+	session := &models.RebalanceSession{}
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, user_id, target_type, new_money, created_at, completed_at
+		FROM rebalance_sessions
+		WHERE id = ?
+	`, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.TargetType,
+		&session.NewMoney,
+		&session.CreatedAt,
+		&completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		session.CompletedAt = &completedAt.Time
+	}
+
+	session.Actions, err = r.getActions(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetByUserID retrieves a user's sessions with their actions, most recent first.
+func (r *RebalanceSessionRepository) GetByUserID(userID int64, limit int) ([]*models.RebalanceSession, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, target_type, new_money, created_at, completed_at
+		FROM rebalance_sessions
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*models.RebalanceSession, 0)
+	for rows.Next() {
+		session := &models.RebalanceSession{}
+		var completedAt sql.NullTime
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.TargetType,
+			&session.NewMoney,
+			&session.CreatedAt,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			session.CompletedAt = &completedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if session.Actions, err = r.getActions(session.ID); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+// SetActionCompleted checks or unchecks an action of a session. The session
+// is marked completed when all its actions are done, and reopened otherwise.
+func (r *RebalanceSessionRepository) SetActionCompleted(sessionID, actionID int64, completed bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var completedAt any
+	if completed {
+		completedAt = time.Now()
+	}
+	result, err := tx.Exec(`
+		UPDATE rebalance_actions SET completed = ?, completed_at = ?
+		WHERE id = ? AND session_id = ?
+	`, boolToInt(completed), completedAt, actionID, sessionID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("rebalance action not found")
+	}
+
+	var remaining int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM rebalance_actions WHERE session_id = ? AND completed = 0
+	`, sessionID).Scan(&remaining); err != nil {
+		return err
+	}
+
+	var sessionCompletedAt any
+	if remaining == 0 {
+		sessionCompletedAt = time.Now()
+	}
+	if _, err := tx.Exec(`
+		UPDATE rebalance_sessions SET completed_at = ? WHERE id = ?
+	`, sessionCompletedAt, sessionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a session and its actions.
+func (r *RebalanceSessionRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM rebalance_sessions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("rebalance session not found")
+	}
+	return nil
+}
+
+// getActions retrieves the actions of a session in the order they were suggested.
+func (r *RebalanceSessionRepository) getActions(sessionID int64) ([]*models.RebalanceSessionAction, error) {
+	rows, err := r.db.Query(`
+		SELECT id, session_id, target_key, target_name, action, amount, current_pct, target_pct, completed, completed_at
+		FROM rebalance_actions
+		WHERE session_id = ?
+		ORDER BY id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := make([]*models.RebalanceSessionAction, 0)
+	for rows.Next() {
+		action := &models.RebalanceSessionAction{}
+		var completed int
+		var completedAt sql.NullTime
+		err := rows.Scan(
+			&action.ID,
+			&action.SessionID,
+			&action.TargetKey,
+			&action.TargetName,
+			&action.Action,
+			&action.Amount,
+			&action.CurrentPct,
+			&action.TargetPct,
+			&completed,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		action.Completed = completed == 1
+		if completedAt.Valid {
+			action.CompletedAt = &completedAt.Time
+		}
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
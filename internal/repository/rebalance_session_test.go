@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func createTestRebalanceSession(t *testing.T, repo *RebalanceSessionRepository, userID int64) int64 {
+	t.Helper()
+	id, err := repo.Create(&models.RebalanceSession{
+		UserID:     userID,
+		TargetType: models.TargetTypeCategory,
+		NewMoney:   5000,
+		Actions: []*models.RebalanceSessionAction{
+			{TargetKey: "1", TargetName: "Stocks", Action: "buy", Amount: 4000, CurrentPct: 50, TargetPct: 60},
+			{TargetKey: "2", TargetName: "Bonds", Action: "sell", Amount: 1000, CurrentPct: 50, TargetPct: 40},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return id
+}
+
+func TestRebalanceSessionRepository_Create_StoresActions(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewRebalanceSessionRepository(db)
+
+	id := createTestRebalanceSession(t, repo, userID)
+
+	session, err := repo.GetByID(id)
+	if err != nil || session == nil {
+		t.Fatalf("GetByID() = %v, %v; want session", session, err)
+	}
+	if session.NewMoney != 5000 || session.CompletedAt != nil {
+		t.Errorf("GetByID() NewMoney = %v, CompletedAt = %v; want 5000, nil", session.NewMoney, session.CompletedAt)
+	}
+	if len(session.Actions) != 2 {
+		t.Fatalf("GetByID() returned %d actions, want 2", len(session.Actions))
+	}
+	if a := session.Actions[0]; a.TargetName != "Stocks" || a.Action != "buy" || a.Amount != 4000 || a.Completed {
+		t.Errorf("first action = %+v; want uncompleted buy of Stocks for 4000", a)
+	}
+}
+
+func TestRebalanceSessionRepository_SetActionCompleted_CompletesSession(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewRebalanceSessionRepository(db)
+
+	id := createTestRebalanceSession(t, repo, userID)
+	session, _ := repo.GetByID(id)
+
+	if err := repo.SetActionCompleted(id, session.Actions[0].ID, true); err != nil {
+		t.Fatalf("SetActionCompleted() error = %v", err)
+	}
+	session, _ = repo.GetByID(id)
+	if !session.Actions[0].Completed || session.Actions[0].CompletedAt == nil {
+		t.Error("first action not marked completed")
+	}
+	if session.CompletedAt != nil {
+		t.Error("session completed with an action still open")
+	}
+
+	if err := repo.SetActionCompleted(id, session.Actions[1].ID, true); err != nil {
+		t.Fatalf("SetActionCompleted() error = %v", err)
+	}
+	session, _ = repo.GetByID(id)
+	if session.CompletedAt == nil {
+		t.Error("session not completed after all actions were done")
+	}
+
+	// Unchecking an action reopens the session
+	if err := repo.SetActionCompleted(id, session.Actions[1].ID, false); err != nil {
+		t.Fatalf("SetActionCompleted() error = %v", err)
+	}
+	session, _ = repo.GetByID(id)
+	if session.CompletedAt != nil || session.Actions[1].Completed {
+		t.Error("session still completed after unchecking an action")
+	}
+}
+
+func TestRebalanceSessionRepository_SetActionCompleted_OtherSession_ReturnsError(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewRebalanceSessionRepository(db)
+
+	first := createTestRebalanceSession(t, repo, userID)
+	second := createTestRebalanceSession(t, repo, userID)
+	session, _ := repo.GetByID(first)
+
+	if err := repo.SetActionCompleted(second, session.Actions[0].ID, true); err == nil {
+		t.Error("SetActionCompleted() with an action of another session should return error")
+	}
+}
+
+func TestRebalanceSessionRepository_GetByUserID_MostRecentFirst(t *testing.T) {
+	db, userID, _ := setupAccountTestDB(t)
+	repo := NewRebalanceSessionRepository(db)
+
+	first := createTestRebalanceSession(t, repo, userID)
+	second := createTestRebalanceSession(t, repo, userID)
+
+	sessions, err := repo.GetByUserID(userID, 10)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != second || sessions[1].ID != first {
+		t.Fatalf("GetByUserID() returned %d sessions in wrong order", len(sessions))
+	}
+	if len(sessions[0].Actions) != 2 {
+		t.Errorf("GetByUserID() session has %d actions, want 2", len(sessions[0].Actions))
+	}
+
+	if err := repo.Delete(second); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if sessions, _ := repo.GetByUserID(userID, 10); len(sessions) != 1 {
+		t.Errorf("GetByUserID() after Delete() returned %d sessions, want 1", len(sessions))
+	}
+}
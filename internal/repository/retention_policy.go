@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// RetentionPolicyRepository handles retention policy database operations and
+// prunes history tables. Table and column names are never user input; callers
+// pass them from a fixed list.
+type RetentionPolicyRepository struct {
+	db *database.DB
+}
+
+// NewRetentionPolicyRepository creates a new RetentionPolicyRepository.
+func NewRetentionPolicyRepository(db *database.DB) *RetentionPolicyRepository {
+	return &RetentionPolicyRepository{db: db}
+}
+
+// GetAll retrieves the policies of all tables that have one.
+func (r *RetentionPolicyRepository) GetAll() ([]*models.RetentionPolicy, error) {
+	rows, err := r.db.Query(`
+		SELECT table_name, keep_days, thin_after_days, last_run_at, last_deleted, updated_at
+		FROM retention_policies
+		ORDER BY table_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]*models.RetentionPolicy, 0)
+	for rows.Next() {
+		p := &models.RetentionPolicy{}
+		var lastRunAt, updatedAt sql.NullTime
+		if err := rows.Scan(&p.TableName, &p.KeepDays, &p.ThinAfterDays, &lastRunAt, &p.LastDeleted, &updatedAt); err != nil {
+			return nil, err
+		}
+		if lastRunAt.Valid {
+			p.LastRunAt = &lastRunAt.Time
+		}
+		p.UpdatedAt = updatedAt.Time
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// Save creates or updates the policy of a table.
+func (r *RetentionPolicyRepository) Save(policy *models.RetentionPolicy) error {
+	_, err := r.db.Exec(`
+		INSERT INTO retention_policies (table_name, keep_days, thin_after_days, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(table_name) DO UPDATE SET
+			keep_days = excluded.keep_days,
+			thin_after_days = excluded.thin_after_days,
+			updated_at = excluded.updated_at
+	`, policy.TableName, policy.KeepDays, policy.ThinAfterDays, time.Now())
+	return err
+}
+
+// RecordRun records when a table's policy was last enforced and how many rows
+// were deleted.
+func (r *RetentionPolicyRepository) RecordRun(tableName string, at time.Time, deleted int64) error {
+	_, err := r.db.Exec(`
+		UPDATE retention_policies SET last_run_at = ?, last_deleted = ? WHERE table_name = ?
+	`, at, deleted, tableName)
+	return err
+}
+
+// CountRows returns the number of rows in a table.
+func (r *RetentionPolicyRepository) CountRows(table string) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count)
+	return count, err
+}
+
+// DeleteBefore deletes the rows of a table dated before the cutoff day.
+func (r *RetentionPolicyRepository) DeleteBefore(table, dateColumn string, cutoff time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM `+table+` WHERE `+dateColumn+` < ?`, cutoff.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ThinBefore deletes the rows of a table dated before the cutoff day that
+// have a later row of the same key in the same month, keeping the last one
+// of each month.
+func (r *RetentionPolicyRepository) ThinBefore(table, dateColumn string, keyColumns []string, cutoff time.Time) (int64, error) {
+	conditions := make([]string, 0, len(keyColumns)+2)
+	for _, column := range keyColumns {
+		conditions = append(conditions, `later.`+column+` = `+table+`.`+column)
+	}
+	conditions = append(conditions,
+		`substr(later.`+dateColumn+`, 1, 7) = substr(`+table+`.`+dateColumn+`, 1, 7)`,
+		`later.`+dateColumn+` > `+table+`.`+dateColumn,
+	)
+
+	res, err := r.db.Exec(`
+		DELETE FROM `+table+`
+		WHERE `+dateColumn+` < ?
+		AND EXISTS (
+			SELECT 1 FROM `+table+` later
+			WHERE `+strings.Join(conditions, ` AND `)+`
+		)
+	`, cutoff.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
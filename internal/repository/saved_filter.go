@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// SavedFilterRepository handles the named transaction filters of users.
+type SavedFilterRepository struct {
+	db *database.DB
+}
+
+// NewSavedFilterRepository creates a new SavedFilterRepository.
+func NewSavedFilterRepository(db *database.DB) *SavedFilterRepository {
+	return &SavedFilterRepository{db: db}
+}
+
+// Create inserts a new saved filter and returns its ID.
+func (r *SavedFilterRepository) Create(filter *models.SavedFilter) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO saved_filters (user_id, name, account_id, category_id, tag, min_amount, max_amount, date_from, date_to, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, filter.UserID, filter.Name, filter.AccountID, filter.CategoryID, filter.Tag, filter.MinAmount, filter.MaxAmount,
+		formatFilterDate(filter.From), formatFilterDate(filter.To), time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves a saved filter by ID, or nil if there is none.
+func (r *SavedFilterRepository) GetByID(id int64) (*models.SavedFilter, error) {
+	filters, err := r.queryFilters(`WHERE id = ?`, id)
+	if err != nil || len(filters) == 0 {
+		return nil, err
+	}
+	return filters[0], nil
+}
+
+// GetByUserID retrieves the saved filters of a user, sorted by name.
+func (r *SavedFilterRepository) GetByUserID(userID int64) ([]*models.SavedFilter, error) {
+	return r.queryFilters(`WHERE user_id = ? ORDER BY name COLLATE NOCASE`, userID)
+}
+
+// Delete removes a saved filter.
+func (r *SavedFilterRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM saved_filters WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("saved filter not found")
+	}
+	return nil
+}
+
+// formatFilterDate returns a filter date as stored, or nil if it is unset.
+func formatFilterDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+// queryFilters selects saved filters, filtered and ordered by the given
+// clause.
+func (r *SavedFilterRepository) queryFilters(clause string, args ...any) ([]*models.SavedFilter, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, account_id, category_id, tag, min_amount, max_amount, date_from, date_to, created_at
+		FROM saved_filters
+		`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var filters []*models.SavedFilter
+	for rows.Next() {
+		filter := &models.SavedFilter{}
+		var accountID, categoryID sql.NullInt64
+		var minAmount, maxAmount sql.NullFloat64
+		var from, to sql.NullString
+		if err := rows.Scan(
+			&filter.ID,
+			&filter.UserID,
+			&filter.Name,
+			&accountID,
+			&categoryID,
+			&filter.Tag,
+			&minAmount,
+			&maxAmount,
+			&from,
+			&to,
+			&filter.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if accountID.Valid {
+			filter.AccountID = &accountID.Int64
+		}
+		if categoryID.Valid {
+			filter.CategoryID = &categoryID.Int64
+		}
+		if minAmount.Valid {
+			filter.MinAmount = &minAmount.Float64
+		}
+		if maxAmount.Valid {
+			filter.MaxAmount = &maxAmount.Float64
+		}
+		if from.Valid {
+			date := parseDate(from.String)
+			filter.From = &date
+		}
+		if to.Valid {
+			date := parseDate(to.String)
+			filter.To = &date
+		}
+		filters = append(filters, filter)
+	}
+	return filters, rows.Err()
+}
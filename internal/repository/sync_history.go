@@ -30,15 +30,16 @@ func (r *SyncHistoryRepository) Start(connectionID int64, syncType string) (int6
 	return result.LastInsertId()
 }
 
-// Complete marks a sync as successful.
-func (r *SyncHistoryRepository) Complete(id int64, accountsSynced, positionsSynced int) error {
+// Complete marks a sync as successful. errorMsg lists accounts that failed
+// while the others were synced, or is empty.
+func (r *SyncHistoryRepository) Complete(id int64, accountsSynced, positionsSynced int, errorMsg string) error {
 	now := time.Now()
 	_, err := r.db.Exec(`
 		UPDATE sync_history
-		SET status = 'success', accounts_synced = ?, positions_synced = ?, completed_at = ?,
+		SET status = 'success', accounts_synced = ?, positions_synced = ?, error_message = ?, completed_at = ?,
 		    duration_ms = (julianday(?) - julianday(started_at)) * 86400000
 		WHERE id = ?
-	`, accountsSynced, positionsSynced, now, now, id)
+	`, accountsSynced, positionsSynced, sql.NullString{String: errorMsg, Valid: errorMsg != ""}, now, now, id)
 	return err
 }
 
@@ -54,6 +55,26 @@ func (r *SyncHistoryRepository) Fail(id int64, errorMsg string) error {
 	return err
 }
 
+// SaveDiagnostics stores the redacted request trail of a sync.
+func (r *SyncHistoryRepository) SaveDiagnostics(id int64, requests string) error {
+	_, err := r.db.Exec(`
+		INSERT OR REPLACE INTO sync_diagnostics (sync_history_id, requests, created_at)
+		VALUES (?, ?, ?)
+	`, id, requests, time.Now())
+	return err
+}
+
+// GetDiagnostics retrieves the request trail of a sync. Returns "" if none
+// was stored.
+func (r *SyncHistoryRepository) GetDiagnostics(id int64) (string, error) {
+	var requests string
+	err := r.db.QueryRow(`SELECT requests FROM sync_diagnostics WHERE sync_history_id = ?`, id).Scan(&requests)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return requests, err
+}
+
 // GetByID retrieves a sync history entry by ID.
 func (r *SyncHistoryRepository) GetByID(id int64) (*models.SyncHistory, error) {
 	row := r.db.QueryRow(`
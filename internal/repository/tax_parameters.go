@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// TaxParameterRepository handles tax parameter database operations.
+type TaxParameterRepository struct {
+	db *database.DB
+}
+
+// NewTaxParameterRepository creates a new TaxParameterRepository.
+func NewTaxParameterRepository(db *database.DB) *TaxParameterRepository {
+	return &TaxParameterRepository{db: db}
+}
+
+// GetAll retrieves the tax parameters of every stored year, latest first.
+func (r *TaxParameterRepository) GetAll() ([]*models.TaxParameters, error) {
+	rows, err := r.db.Query(`
+		SELECT year, ask_max_deposit, stock_gain_threshold, stock_gain_low_rate, stock_gain_high_rate, ask_tax_rate, pension_withdrawal_rate, updated_at
+		FROM tax_parameters
+		ORDER BY year DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	params := make([]*models.TaxParameters, 0)
+	for rows.Next() {
+		p := &models.TaxParameters{}
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&p.Year, &p.ASKMaxDeposit, &p.StockGainThreshold, &p.StockGainLowRate, &p.StockGainHighRate, &p.ASKTaxRate, &p.PensionWithdrawalRate, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt = updatedAt.Time
+		params = append(params, p)
+	}
+	return params, rows.Err()
+}
+
+// Save creates or updates the tax parameters of a year.
+func (r *TaxParameterRepository) Save(p *models.TaxParameters) error {
+	_, err := r.db.Exec(`
+		INSERT INTO tax_parameters (year, ask_max_deposit, stock_gain_threshold, stock_gain_low_rate, stock_gain_high_rate, ask_tax_rate, pension_withdrawal_rate, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(year) DO UPDATE SET
+			ask_max_deposit = excluded.ask_max_deposit,
+			stock_gain_threshold = excluded.stock_gain_threshold,
+			stock_gain_low_rate = excluded.stock_gain_low_rate,
+			stock_gain_high_rate = excluded.stock_gain_high_rate,
+			ask_tax_rate = excluded.ask_tax_rate,
+			pension_withdrawal_rate = excluded.pension_withdrawal_rate,
+			updated_at = excluded.updated_at
+	`, p.Year, p.ASKMaxDeposit, p.StockGainThreshold, p.StockGainLowRate, p.StockGainHighRate, p.ASKTaxRate, p.PensionWithdrawalRate, time.Now())
+	return err
+}
+
+// Delete removes the tax parameters of a year.
+func (r *TaxParameterRepository) Delete(year int) error {
+	_, err := r.db.Exec(`DELETE FROM tax_parameters WHERE year = ?`, year)
+	return err
+}
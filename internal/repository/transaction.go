@@ -3,6 +3,11 @@ package repository
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"wealth_tracker/internal/database"
@@ -21,27 +26,65 @@ func NewTransactionRepository(db *database.DB) *TransactionRepository {
 
 // Create inserts a new transaction and returns its ID.
 func (r *TransactionRepository) Create(txn *models.Transaction) (int64, error) {
-	result, err := r.db.Exec(`
-		INSERT INTO transactions (account_id, amount, balance_after, description, transaction_date)
-		VALUES (?, ?, ?, ?, ?)
-	`, txn.AccountID, txn.Amount, txn.BalanceAfter, txn.Description, txn.TransactionDate.Format("2006-01-02"))
+	return createTransaction(r.db, txn)
+}
+
+// createTransaction inserts a transaction on the database or within a
+// transaction.
+func createTransaction(db execer, txn *models.Transaction) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO transactions (account_id, amount, balance_after, description, category_id, kind, tag, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, txn.AccountID, txn.Amount, txn.BalanceAfter, txn.Description, txn.CategoryID, txn.Kind, txn.Tag, txn.TransactionDate.Format("2006-01-02"))
 	if err != nil {
 		return 0, err
 	}
 	return result.LastInsertId()
 }
 
+// CreateBatch inserts transactions in one database transaction, so either
+// all of them are recorded or none.
+func (r *TransactionRepository) CreateBatch(txns []*models.Transaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO transactions (account_id, amount, balance_after, description, category_id, kind, tag, transaction_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, txn := range txns {
+		result, err := stmt.Exec(txn.AccountID, txn.Amount, txn.BalanceAfter, txn.Description, txn.CategoryID, txn.Kind, txn.Tag, txn.TransactionDate.Format("2006-01-02"))
+		if err != nil {
+			return err
+		}
+		if txn.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // GetByID retrieves a transaction by ID.
 func (r *TransactionRepository) GetByID(id int64) (*models.Transaction, error) {
 	row := r.db.QueryRow(`
-		SELECT id, account_id, amount, balance_after, description, transaction_date, created_at
+		SELECT id, account_id, amount, balance_after, description, category_id, kind, tag, transaction_date, created_at, updated_at
 		FROM transactions
 		WHERE id = ?
 	`, id)
 
 	txn := &models.Transaction{}
 	var description sql.NullString
+	var categoryID sql.NullInt64
 	var transactionDate string
+	var updatedAt sql.NullTime
 
 	err := row.Scan(
 		&txn.ID,
@@ -49,8 +92,12 @@ func (r *TransactionRepository) GetByID(id int64) (*models.Transaction, error) {
 		&txn.Amount,
 		&txn.BalanceAfter,
 		&description,
+		&categoryID,
+		&txn.Kind,
+		&txn.Tag,
 		&transactionDate,
 		&txn.CreatedAt,
+		&updatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -62,7 +109,13 @@ func (r *TransactionRepository) GetByID(id int64) (*models.Transaction, error) {
 	if description.Valid {
 		txn.Description = description.String
 	}
+	if categoryID.Valid {
+		txn.CategoryID = &categoryID.Int64
+	}
 	txn.TransactionDate = parseDate(transactionDate)
+	if updatedAt.Valid {
+		txn.UpdatedAt = updatedAt.Time
+	}
 
 	return txn, nil
 }
@@ -86,7 +139,7 @@ func parseDate(s string) time.Time {
 // GetByAccountID retrieves transactions for an account with pagination.
 func (r *TransactionRepository) GetByAccountID(accountID int64, limit, offset int) ([]*models.Transaction, error) {
 	return r.queryTransactions(`
-		SELECT id, account_id, amount, balance_after, description, transaction_date, created_at
+		SELECT id, account_id, amount, balance_after, description, category_id, kind, tag, transaction_date, created_at, updated_at
 		FROM transactions
 		WHERE account_id = ?
 		ORDER BY transaction_date DESC, id DESC
@@ -94,10 +147,42 @@ func (r *TransactionRepository) GetByAccountID(accountID int64, limit, offset in
 	`, accountID, limit, offset)
 }
 
+// GetFirstByAccountID returns the earliest transaction of an account, or nil
+// if it has none.
+func (r *TransactionRepository) GetFirstByAccountID(accountID int64) (*models.Transaction, error) {
+	txns, err := r.queryTransactions(`
+		SELECT id, account_id, amount, balance_after, description, category_id, kind, tag, transaction_date, created_at, updated_at
+		FROM transactions
+		WHERE account_id = ?
+		ORDER BY transaction_date ASC, id ASC
+		LIMIT 1
+	`, accountID)
+	if err != nil || len(txns) == 0 {
+		return nil, err
+	}
+	return txns[0], nil
+}
+
+// GetFirstDayByUserID returns the day of the user's earliest transaction on
+// the accounts of their history, or the zero time if they have none.
+func (r *TransactionRepository) GetFirstDayByUserID(userID int64) (time.Time, error) {
+	var day sql.NullString
+	err := r.db.QueryRow(`
+		SELECT MIN(substr(t.transaction_date, 1, 10))
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = ? AND (a.is_active = 1 OR a.closed_at IS NOT NULL)
+	`, userID).Scan(&day)
+	if err != nil || !day.Valid {
+		return time.Time{}, err
+	}
+	return parseDate(day.String), nil
+}
+
 // GetByUserID retrieves all transactions for a user across all accounts.
 func (r *TransactionRepository) GetByUserID(userID int64, limit, offset int) ([]*models.Transaction, error) {
 	return r.queryTransactions(`
-		SELECT t.id, t.account_id, t.amount, t.balance_after, t.description, t.transaction_date, t.created_at
+		SELECT t.id, t.account_id, t.amount, t.balance_after, t.description, t.category_id, t.kind, t.tag, t.transaction_date, t.created_at, t.updated_at
 		FROM transactions t
 		JOIN accounts a ON t.account_id = a.id
 		WHERE a.user_id = ?
@@ -106,10 +191,80 @@ func (r *TransactionRepository) GetByUserID(userID int64, limit, offset int) ([]
 	`, userID, limit, offset)
 }
 
+// GetByUserIDFiltered retrieves a user's transactions matching filter, newest
+// first.
+func (r *TransactionRepository) GetByUserIDFiltered(userID int64, filter models.TransactionFilter, limit, offset int) ([]*models.Transaction, error) {
+	where := "a.user_id = ?"
+	args := []any{userID}
+	if filter.AccountID != nil {
+		where += " AND t.account_id = ?"
+		args = append(args, *filter.AccountID)
+	}
+	if filter.CategoryID != nil {
+		where += " AND COALESCE(t.category_id, a.category_id) = ?"
+		args = append(args, *filter.CategoryID)
+	}
+	if filter.Tag != "" {
+		where += " AND t.tag = ?"
+		args = append(args, filter.Tag)
+	}
+	if filter.MinAmount != nil {
+		where += " AND t.amount >= ?"
+		args = append(args, *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where += " AND t.amount <= ?"
+		args = append(args, *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		where += " AND t.transaction_date >= ?"
+		args = append(args, filter.From.Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		where += " AND t.transaction_date <= ?"
+		args = append(args, filter.To.Format("2006-01-02"))
+	}
+	args = append(args, limit, offset)
+
+	return r.queryTransactions(`
+		SELECT t.id, t.account_id, t.amount, t.balance_after, t.description, t.category_id, t.kind, t.tag, t.transaction_date, t.created_at, t.updated_at
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE `+where+`
+		ORDER BY t.transaction_date DESC, t.id DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+}
+
+// GetTagsByUserID returns the distinct tags on a user's transactions, sorted.
+func (r *TransactionRepository) GetTagsByUserID(userID int64) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT t.tag
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = ? AND t.tag != ''
+		ORDER BY t.tag
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
 // GetByDateRange retrieves transactions for an account within a date range.
 func (r *TransactionRepository) GetByDateRange(accountID int64, start, end time.Time) ([]*models.Transaction, error) {
 	return r.queryTransactions(`
-		SELECT id, account_id, amount, balance_after, description, transaction_date, created_at
+		SELECT id, account_id, amount, balance_after, description, category_id, kind, tag, transaction_date, created_at, updated_at
 		FROM transactions
 		WHERE account_id = ? AND transaction_date >= ? AND transaction_date <= ?
 		ORDER BY transaction_date DESC, id DESC
@@ -128,7 +283,9 @@ func (r *TransactionRepository) queryTransactions(query string, args ...any) ([]
 	for rows.Next() {
 		txn := &models.Transaction{}
 		var description sql.NullString
+		var categoryID sql.NullInt64
 		var transactionDate string
+		var updatedAt sql.NullTime
 
 		err := rows.Scan(
 			&txn.ID,
@@ -136,8 +293,12 @@ func (r *TransactionRepository) queryTransactions(query string, args ...any) ([]
 			&txn.Amount,
 			&txn.BalanceAfter,
 			&description,
+			&categoryID,
+			&txn.Kind,
+			&txn.Tag,
 			&transactionDate,
 			&txn.CreatedAt,
+			&updatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -146,20 +307,35 @@ func (r *TransactionRepository) queryTransactions(query string, args ...any) ([]
 		if description.Valid {
 			txn.Description = description.String
 		}
+		if categoryID.Valid {
+			txn.CategoryID = &categoryID.Int64
+		}
 		txn.TransactionDate = parseDate(transactionDate)
+		if updatedAt.Valid {
+			txn.UpdatedAt = updatedAt.Time
+		}
 
 		transactions = append(transactions, txn)
 	}
 	return transactions, rows.Err()
 }
 
-// Update updates an existing transaction.
+// Update updates an existing transaction. It returns ErrStaleUpdate if the
+// transaction was edited since txn was read, and sets txn.UpdatedAt.
 func (r *TransactionRepository) Update(txn *models.Transaction) error {
-	result, err := r.db.Exec(`
+	return updateTransaction(r.db, txn)
+}
+
+// updateTransaction saves a transaction on the database or within a
+// transaction.
+func updateTransaction(db execQuerier, txn *models.Transaction) error {
+	now := time.Now().UTC()
+	result, err := db.Exec(`
 		UPDATE transactions
-		SET amount = ?, balance_after = ?, description = ?, transaction_date = ?
-		WHERE id = ?
-	`, txn.Amount, txn.BalanceAfter, txn.Description, txn.TransactionDate.Format("2006-01-02"), txn.ID)
+		SET amount = ?, balance_after = ?, description = ?, category_id = ?, transaction_date = ?, updated_at = ?
+		WHERE id = ? AND updated_at IS ?
+	`, txn.Amount, txn.BalanceAfter, txn.Description, txn.CategoryID, txn.TransactionDate.Format("2006-01-02"), now,
+		txn.ID, versionArg(txn.UpdatedAt))
 	if err != nil {
 		return err
 	}
@@ -169,8 +345,9 @@ func (r *TransactionRepository) Update(txn *models.Transaction) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("transaction not found")
+		return staleOrNotFound(db, "transactions", txn.ID, errors.New("transaction not found"))
 	}
+	txn.UpdatedAt = now
 	return nil
 }
 
@@ -200,6 +377,19 @@ func (r *TransactionRepository) CountByAccountID(accountID int64) (int, error) {
 	return count, err
 }
 
+// CountCreatedSince returns the number of transactions recorded on a user's
+// accounts after since, whatever their transaction date.
+func (r *TransactionRepository) CountCreatedSince(userID int64, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = ? AND t.created_at > ?
+	`, userID, since.UTC().Format("2006-01-02 15:04:05")).Scan(&count)
+	return count, err
+}
+
 // GetLatestBalance returns the balance after the most recent transaction for an account.
 func (r *TransactionRepository) GetLatestBalance(accountID int64) (float64, error) {
 	var balance sql.NullFloat64
@@ -220,16 +410,85 @@ func (r *TransactionRepository) GetLatestBalance(accountID int64) (float64, erro
 	return balance.Float64, nil
 }
 
+// GetBalanceAt returns the balance of an account at the end of a day: the
+// balance after its last transaction dated on or before it.
+func (r *TransactionRepository) GetBalanceAt(accountID int64, day time.Time) (float64, error) {
+	var balance sql.NullFloat64
+	err := r.db.QueryRow(`
+		SELECT balance_after
+		FROM transactions
+		WHERE account_id = ? AND transaction_date < ?
+		ORDER BY transaction_date DESC, id DESC
+		LIMIT 1
+	`, accountID, day.AddDate(0, 0, 1).Format("2006-01-02")).Scan(&balance)
+
+	if err == sql.ErrNoRows || !balance.Valid {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return balance.Float64, nil
+}
+
+// GetRecentBalances returns the balances after the last limit transactions
+// of an account, oldest first.
+func (r *TransactionRepository) GetRecentBalances(accountID int64, limit int) ([]float64, error) {
+	rows, err := r.db.Query(`
+		SELECT balance_after
+		FROM transactions
+		WHERE account_id = ?
+		ORDER BY transaction_date DESC, id DESC
+		LIMIT ?
+	`, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []float64
+	for rows.Next() {
+		var balance float64
+		if err := rows.Scan(&balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(balances)
+	return balances, nil
+}
+
 // GetRecentByUserID retrieves the most recent transactions for a user.
 func (r *TransactionRepository) GetRecentByUserID(userID int64, limit int) ([]*models.Transaction, error) {
+	return r.getRecentByUserID(userID, nil, limit)
+}
+
+// GetRecentByUserIDAt retrieves the most recent transactions for a user dated
+// on or before a day.
+func (r *TransactionRepository) GetRecentByUserIDAt(userID int64, day time.Time, limit int) ([]*models.Transaction, error) {
+	return r.getRecentByUserID(userID, &day, limit)
+}
+
+// getRecentByUserID retrieves the most recent transactions for a user, up to
+// the end of day if it is set.
+func (r *TransactionRepository) getRecentByUserID(userID int64, day *time.Time, limit int) ([]*models.Transaction, error) {
+	where := "a.user_id = ?"
+	args := []any{userID}
+	if day != nil {
+		where += " AND t.transaction_date < ?"
+		args = append(args, day.AddDate(0, 0, 1).Format("2006-01-02"))
+	}
 	rows, err := r.db.Query(`
-		SELECT t.id, t.account_id, t.amount, t.balance_after, t.description, t.transaction_date, t.created_at
+		SELECT t.id, t.account_id, t.amount, t.balance_after, t.description, t.category_id, t.kind, t.tag, t.transaction_date, t.created_at, t.updated_at
 		FROM transactions t
 		JOIN accounts a ON t.account_id = a.id
-		WHERE a.user_id = ?
+		WHERE `+where+`
 		ORDER BY t.transaction_date DESC, t.id DESC
 		LIMIT ?
-	`, userID, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +498,9 @@ func (r *TransactionRepository) GetRecentByUserID(userID int64, limit int) ([]*m
 	for rows.Next() {
 		txn := &models.Transaction{}
 		var description sql.NullString
+		var categoryID sql.NullInt64
 		var transactionDate string
+		var updatedAt sql.NullTime
 
 		err := rows.Scan(
 			&txn.ID,
@@ -247,8 +508,12 @@ func (r *TransactionRepository) GetRecentByUserID(userID int64, limit int) ([]*m
 			&txn.Amount,
 			&txn.BalanceAfter,
 			&description,
+			&categoryID,
+			&txn.Kind,
+			&txn.Tag,
 			&transactionDate,
 			&txn.CreatedAt,
+			&updatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -257,13 +522,46 @@ func (r *TransactionRepository) GetRecentByUserID(userID int64, limit int) ([]*m
 		if description.Valid {
 			txn.Description = description.String
 		}
+		if categoryID.Valid {
+			txn.CategoryID = &categoryID.Int64
+		}
 		txn.TransactionDate = parseDate(transactionDate)
+		if updatedAt.Valid {
+			txn.UpdatedAt = updatedAt.Time
+		}
 
 		transactions = append(transactions, txn)
 	}
 	return transactions, rows.Err()
 }
 
+// GetRecentlyUsedAccountIDs returns the IDs of a user's accounts that have
+// transactions, the account with the latest added transaction first.
+func (r *TransactionRepository) GetRecentlyUsedAccountIDs(userID int64) ([]int64, error) {
+	rows, err := r.db.Query(`
+		SELECT t.account_id
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = ?
+		GROUP BY t.account_id
+		ORDER BY MAX(t.id) DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // GetSumSince returns the sum of transaction amounts since a given date.
 func (r *TransactionRepository) GetSumSince(accountID int64, since time.Time) (float64, error) {
 	var sum sql.NullFloat64
@@ -282,84 +580,106 @@ func (r *TransactionRepository) GetSumSince(accountID int64, since time.Time) (f
 	return sum.Float64, nil
 }
 
-// NetWorthPoint represents net worth at a specific date.
-type NetWorthPoint struct {
-	Date     time.Time
-	NetWorth float64
-}
-
-// GetNetWorthHistory returns the net worth history for a user.
-// It calculates net worth at each transaction date by tracking account balances.
-func (r *TransactionRepository) GetNetWorthHistory(userID int64) ([]NetWorthPoint, error) {
-	// Get all transactions with account liability info, ordered by date
+// GetFlowsByAccount sums the money moved in and out of each of a user's
+// accounts after one date up to and including another, leaving out balance
+// updates that only revalue an account.
+func (r *TransactionRepository) GetFlowsByAccount(userID int64, after, upTo time.Time) (map[int64]float64, error) {
 	rows, err := r.db.Query(`
-		SELECT t.transaction_date, t.balance_after, a.id, a.is_liability
+		SELECT t.account_id, SUM(t.amount)
 		FROM transactions t
 		JOIN accounts a ON t.account_id = a.id
-		WHERE a.user_id = ? AND a.is_active = 1
-		ORDER BY t.transaction_date ASC, t.id ASC
-	`, userID)
+		WHERE a.user_id = ? AND t.kind != ? AND t.transaction_date > ? AND t.transaction_date <= ?
+		GROUP BY t.account_id
+	`, userID, models.TransactionValuation, after.Format("2006-01-02"), upTo.Format("2006-01-02"))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	// Track latest balance for each account
-	accountBalances := make(map[int64]float64)
-	accountIsLiability := make(map[int64]bool)
-
-	// Track net worth at each date
-	dateNetWorth := make(map[string]float64)
-	var dates []string
-
+	flows := make(map[int64]float64)
 	for rows.Next() {
-		var dateStr string
-		var balance float64
 		var accountID int64
-		var isLiability int
-
-		if err := rows.Scan(&dateStr, &balance, &accountID, &isLiability); err != nil {
+		var sum float64
+		if err := rows.Scan(&accountID, &sum); err != nil {
 			return nil, err
 		}
+		flows[accountID] = sum
+	}
+	return flows, rows.Err()
+}
 
-		// Update account balance
-		accountBalances[accountID] = balance
-		accountIsLiability[accountID] = isLiability == 1
+// NetWorthPoint represents net worth at a specific date.
+type NetWorthPoint struct {
+	Date     time.Time
+	NetWorth float64
+}
 
-		// Calculate current net worth
-		netWorth := 0.0
-		for accID, bal := range accountBalances {
-			if accountIsLiability[accID] {
-				// Use absolute value for liabilities
-				if bal < 0 {
-					netWorth -= -bal
-				} else {
-					netWorth -= bal
-				}
-			} else {
-				netWorth += bal
-			}
-		}
+// GetNetWorthHistory returns the net worth history for a user.
+// It calculates net worth at each date an account balance changed, using the
+// same account lifetimes as GetBalanceHistoryByUserID.
+func (r *TransactionRepository) GetNetWorthHistory(userID int64) ([]NetWorthPoint, error) {
+	return r.GetNetWorthHistoryExcluding(userID, nil)
+}
 
-		// Store or update net worth for this date
-		if _, exists := dateNetWorth[dateStr]; !exists {
-			dates = append(dates, dateStr)
-		}
-		dateNetWorth[dateStr] = netWorth
+// GetNetWorthHistoryExcluding returns the net worth history for a user like
+// GetNetWorthHistory, leaving out the excluded accounts.
+func (r *TransactionRepository) GetNetWorthHistoryExcluding(userID int64, excluded map[int64]bool) ([]NetWorthPoint, error) {
+	history, err := r.GetBalanceHistoryByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for accountID := range excluded {
+		delete(history, accountID)
 	}
 
+	liabilities := make(map[int64]bool)
+	rows, err := r.db.Query(`SELECT id FROM accounts WHERE user_id = ? AND is_liability = 1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		liabilities[id] = true
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	// Build result
+	// Balance changes per date, applied in date order
+	changes := make(map[time.Time]map[int64]float64)
+	var dates []time.Time
+	for accountID, points := range history {
+		for _, p := range points {
+			if changes[p.Date] == nil {
+				changes[p.Date] = make(map[int64]float64)
+				dates = append(dates, p.Date)
+			}
+			changes[p.Date][accountID] = p.Balance
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	accountBalances := make(map[int64]float64)
 	result := make([]NetWorthPoint, len(dates))
-	for i, dateStr := range dates {
-		date := parseDate(dateStr)
-		result[i] = NetWorthPoint{
-			Date:     date,
-			NetWorth: dateNetWorth[dateStr],
+	for i, date := range dates {
+		for accountID, balance := range changes[date] {
+			accountBalances[accountID] = balance
+		}
+
+		netWorth := 0.0
+		for accountID, balance := range accountBalances {
+			if liabilities[accountID] {
+				// Use absolute value for liabilities
+				netWorth -= math.Abs(balance)
+			} else {
+				netWorth += balance
+			}
 		}
+		result[i] = NetWorthPoint{Date: date, NetWorth: netWorth}
 	}
 
 	return result, nil
@@ -421,7 +741,7 @@ func (r *TransactionRepository) SumBalancesByUserID(userID int64) (float64, erro
 			0
 		) as latest_balance
 		FROM accounts a
-		WHERE a.user_id = ? AND a.is_active = 1
+		WHERE a.user_id = ? AND a.is_active = 1 AND `+accountOpen("a.closed_at")+`
 	`, userID)
 	if err != nil {
 		return 0, err
@@ -438,3 +758,241 @@ func (r *TransactionRepository) SumBalancesByUserID(userID int64) (float64, erro
 	}
 	return total, rows.Err()
 }
+
+// BalancePoint represents an account balance at a specific date.
+type BalancePoint struct {
+	Date    time.Time
+	Balance float64
+}
+
+// GetBalanceHistoryByUserID returns the end-of-day balance history for each of
+// a user's active and closed accounts, keyed by account ID and ordered oldest
+// first. Histories are bounded by the account's opening and closing dates.
+func (r *TransactionRepository) GetBalanceHistoryByUserID(userID int64) (map[int64][]BalancePoint, error) {
+	rows, err := r.db.Query(`
+		SELECT t.account_id, t.transaction_date, t.balance_after
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		WHERE a.user_id = ? AND (a.is_active = 1 OR a.closed_at IS NOT NULL)
+		ORDER BY t.transaction_date ASC, t.id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[int64][]BalancePoint)
+	for rows.Next() {
+		var accountID int64
+		var dateStr string
+		var balance float64
+
+		if err := rows.Scan(&accountID, &dateStr, &balance); err != nil {
+			return nil, err
+		}
+
+		date := parseDate(dateStr)
+		points := history[accountID]
+		// Keep only the last balance of each day
+		if n := len(points); n > 0 && points[n-1].Date.Equal(date) {
+			points[n-1].Balance = balance
+			continue
+		}
+		history[accountID] = append(points, BalancePoint{Date: date, Balance: balance})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bounds, err := r.db.Query(`
+		SELECT id, opened_at, closed_at
+		FROM accounts
+		WHERE user_id = ? AND (opened_at IS NOT NULL OR closed_at IS NOT NULL)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer bounds.Close()
+
+	for bounds.Next() {
+		var accountID int64
+		var openedAt, closedAt sql.NullTime
+		if err := bounds.Scan(&accountID, &openedAt, &closedAt); err != nil {
+			return nil, err
+		}
+		points, ok := history[accountID]
+		if !ok {
+			continue
+		}
+		if openedAt.Valid {
+			points = boundOpened(points, openedAt.Time.UTC())
+		}
+		if closedAt.Valid {
+			points = boundClosed(points, closedAt.Time.UTC())
+		}
+		history[accountID] = points
+	}
+
+	return history, bounds.Err()
+}
+
+// boundOpened moves balances dated before an account was opened to the
+// opening date, so the account starts contributing with its opening balance.
+func boundOpened(points []BalancePoint, openedAt time.Time) []BalancePoint {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(openedAt) })
+	if i == 0 {
+		return points
+	}
+	if i < len(points) && points[i].Date.Equal(openedAt) {
+		return points[i:]
+	}
+	opening := BalancePoint{Date: openedAt, Balance: points[i-1].Balance}
+	return append([]BalancePoint{opening}, points[i:]...)
+}
+
+// boundClosed drops balances from the closing date on and ends the history
+// with a zero balance, so a closed account keeps its contribution up to the
+// day it was closed.
+func boundClosed(points []BalancePoint, closedAt time.Time) []BalancePoint {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(closedAt) })
+	if i == 0 {
+		return nil
+	}
+	return append(points[:i:i], BalancePoint{Date: closedAt, Balance: 0})
+}
+
+// GetCombinedBalanceHistory returns the end-of-day balance history the
+// accounts would have if their transactions were in one account, ordered
+// oldest first. Used to preview merging duplicate accounts.
+func (r *TransactionRepository) GetCombinedBalanceHistory(accountIDs ...int64) ([]BalancePoint, error) {
+	if len(accountIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(accountIDs)), ",")
+	args := make([]any, len(accountIDs))
+	for i, id := range accountIDs {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query(`
+		SELECT transaction_date, balance_after
+		FROM transactions
+		WHERE account_id IN (`+placeholders+`)
+		ORDER BY transaction_date ASC, id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]BalancePoint, 0)
+	for rows.Next() {
+		var dateStr string
+		var balance float64
+		if err := rows.Scan(&dateStr, &balance); err != nil {
+			return nil, err
+		}
+
+		date := parseDate(dateStr)
+		// Keep only the last balance of each day
+		if n := len(points); n > 0 && points[n-1].Date.Equal(date) {
+			points[n-1].Balance = balance
+			continue
+		}
+		points = append(points, BalancePoint{Date: date, Balance: balance})
+	}
+	return points, rows.Err()
+}
+
+// isTransfer matches a transaction t of an account a that moved money to or
+// from another account of the same user: one in the same currency with the
+// opposite amount on the same day. Such transactions are neither spending
+// nor income. Transactions the user gave a category of their own are never
+// transfers. Otherwise unrelated income and spending of the same amount on
+// the same day, such as a salary paid into one account and rent of the same
+// amount paid from another, are taken for a transfer too.
+const isTransfer = `(t.category_id IS NULL AND EXISTS (
+	SELECT 1 FROM transactions o
+	JOIN accounts oa ON oa.id = o.account_id
+	WHERE oa.user_id = a.user_id AND o.account_id != t.account_id
+	  AND oa.currency = a.currency AND o.category_id IS NULL
+	  AND o.kind != 'valuation' AND o.amount = -t.amount
+	  AND date(o.transaction_date) = date(t.transaction_date)))`
+
+// CategoryCashFlow is the money flowing in and out of a category over a period.
+type CategoryCashFlow struct {
+	CategoryID *int64 // nil for transactions without a category
+	Name       string
+	Color      string
+	Currency   string // Currency of the accounts the amounts are in
+	Inflow     float64
+	Outflow    float64 // Positive amount
+}
+
+// Net returns inflow minus outflow.
+func (c CategoryCashFlow) Net() float64 {
+	return c.Inflow - c.Outflow
+}
+
+// GetCashFlowByCategory sums a user's transaction amounts per category and
+// account currency within a date range. A transaction's own category takes
+// precedence over the category of its account. Revaluations and transfers
+// between the user's accounts are left out, as they are neither income nor
+// spending.
+func (r *TransactionRepository) GetCashFlowByCategory(userID int64, start, end time.Time) ([]CategoryCashFlow, error) {
+	rows, err := r.db.Query(`
+		SELECT c.id, COALESCE(c.name, ''), COALESCE(c.color, ''), a.currency,
+		       COALESCE(SUM(CASE WHEN t.amount > 0 THEN t.amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN t.amount < 0 THEN -t.amount ELSE 0 END), 0)
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		LEFT JOIN categories c ON c.id = COALESCE(t.category_id, a.category_id)
+		WHERE a.user_id = ? AND t.kind != ? AND NOT `+isTransfer+`
+		  AND t.transaction_date >= ? AND t.transaction_date <= ?
+		GROUP BY c.id, a.currency
+		ORDER BY c.id IS NULL, c.sort_order, c.name, a.currency
+	`, userID, models.TransactionValuation, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flows := make([]CategoryCashFlow, 0)
+	for rows.Next() {
+		var flow CategoryCashFlow
+		var categoryID sql.NullInt64
+		if err := rows.Scan(&categoryID, &flow.Name, &flow.Color, &flow.Currency, &flow.Inflow, &flow.Outflow); err != nil {
+			return nil, err
+		}
+		if categoryID.Valid {
+			flow.CategoryID = &categoryID.Int64
+		}
+		flows = append(flows, flow)
+	}
+	return flows, rows.Err()
+}
+
+// GetOutflowsByLiquidity sums the outflows of a user's active asset accounts
+// in categories of the given liquidity within a date range, and returns them
+// with the number of statement periods, starting on periodStartDay of the
+// month, in the range that have transactions on those accounts. Revaluations
+// and transfers between the user's accounts are not outflows.
+func (r *TransactionRepository) GetOutflowsByLiquidity(userID int64, liquidity string, start, end time.Time, periodStartDay int) (outflows float64, months int, err error) {
+	if periodStartDay < 1 {
+		periodStartDay = 1
+	}
+	// Moving dates back to the first of the month puts each period in one
+	// calendar month
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN t.amount < 0 THEN -t.amount ELSE 0 END), 0),
+		       COUNT(DISTINCT substr(date(t.transaction_date, ?), 1, 7))
+		FROM transactions t
+		JOIN accounts a ON t.account_id = a.id
+		JOIN categories c ON c.id = a.category_id
+		WHERE a.user_id = ? AND a.is_liability = 0 AND a.is_active = 1 AND `+accountOpen("a.closed_at")+` AND c.liquidity = ?
+		  AND t.kind != ? AND NOT `+isTransfer+`
+		  AND t.transaction_date >= ? AND t.transaction_date <= ?
+	`, fmt.Sprintf("-%d days", periodStartDay-1), userID, liquidity, models.TransactionValuation, start.Format("2006-01-02"), end.Format("2006-01-02")).Scan(&outflows, &months)
+	return outflows, months, err
+}
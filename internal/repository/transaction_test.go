@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -355,6 +356,38 @@ func TestTransactionRepository_Update_NonExistent_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestTransactionRepository_Update_StaleCopy_ReturnsErrStaleUpdate(t *testing.T) {
+	db, _, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	id, _ := repo.Create(&models.Transaction{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: time.Now()})
+	first, _ := repo.GetByID(id)
+	second, _ := repo.GetByID(id)
+
+	first.Description = "First tab"
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("Update() error = %v, want nil", err)
+	}
+	second.Description = "Second tab"
+	if err := repo.Update(second); !errors.Is(err, ErrStaleUpdate) {
+		t.Fatalf("Update() of stale copy error = %v, want ErrStaleUpdate", err)
+	}
+
+	// The winning copy and a fresh read can be saved again
+	first.Amount = 120
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("Update() of current copy error = %v, want nil", err)
+	}
+	found, _ := repo.GetByID(id)
+	if found.Description != "First tab" || found.Amount != 120 {
+		t.Errorf("found = %+v; want the first tab's edits", found)
+	}
+	found.Description = "Reloaded"
+	if err := repo.Update(found); err != nil {
+		t.Errorf("Update() after reload error = %v, want nil", err)
+	}
+}
+
 // Delete tests
 
 func TestTransactionRepository_Delete_ExistingTransaction_Succeeds(t *testing.T) {
@@ -464,6 +497,26 @@ func TestTransactionRepository_GetLatestBalance_NoTransactions_ReturnsZero(t *te
 	}
 }
 
+func TestTransactionRepository_GetBalanceAt_ReturnsBalanceAtEndOfDay(t *testing.T) {
+	db, _, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 200, BalanceAfter: 300, TransactionDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: -50, BalanceAfter: 250, TransactionDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)})
+
+	for day, want := range map[string]float64{"2023-12-31": 0, "2024-01-31": 100, "2024-02-01": 250, "2024-06-01": 250} {
+		d, _ := time.Parse("2006-01-02", day)
+		balance, err := repo.GetBalanceAt(accountID, d)
+		if err != nil {
+			t.Fatalf("GetBalanceAt(%s) error = %v", day, err)
+		}
+		if balance != want {
+			t.Errorf("GetBalanceAt(%s) = %f, want %f", day, balance, want)
+		}
+	}
+}
+
 // SumByUserID tests
 
 func TestTransactionRepository_SumByUserID_ReturnsCorrectTotals(t *testing.T) {
@@ -484,3 +537,378 @@ func TestTransactionRepository_SumByUserID_ReturnsCorrectTotals(t *testing.T) {
 		t.Errorf("SumBalancesByUserID() = %f, want 1300", total)
 	}
 }
+
+// GetBalanceHistoryByUserID tests
+
+func TestTransactionRepository_GetBalanceHistoryByUserID_KeepsLastBalancePerDay(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, TransactionDate: day1})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 500, BalanceAfter: 1500, TransactionDate: day1})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: -200, BalanceAfter: 1300, TransactionDate: day2})
+
+	history, err := repo.GetBalanceHistoryByUserID(userID)
+	if err != nil {
+		t.Fatalf("GetBalanceHistoryByUserID() error = %v, want nil", err)
+	}
+
+	points := history[accountID]
+	if len(points) != 2 {
+		t.Fatalf("GetBalanceHistoryByUserID() returned %d points, want 2", len(points))
+	}
+	if !points[0].Date.Equal(day1) || points[0].Balance != 1500 {
+		t.Errorf("points[0] = %+v, want 1500 on %v", points[0], day1)
+	}
+	if !points[1].Date.Equal(day2) || points[1].Balance != 1300 {
+		t.Errorf("points[1] = %+v, want 1300 on %v", points[1], day2)
+	}
+}
+
+func TestTransactionRepository_GetBalanceHistoryByUserID_BoundsByOpenedAndClosed(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	accountRepo := NewAccountRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: day(1)})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 50, BalanceAfter: 150, TransactionDate: day(3)})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 50, BalanceAfter: 200, TransactionDate: day(10)})
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 50, BalanceAfter: 250, TransactionDate: day(20)})
+
+	account, _ := accountRepo.GetByID(accountID)
+	opened, closed := day(5), day(15)
+	account.OpenedAt = &opened
+	account.ClosedAt = &closed
+	account.IsActive = false
+	if err := accountRepo.Update(account); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	history, err := repo.GetBalanceHistoryByUserID(userID)
+	if err != nil {
+		t.Fatalf("GetBalanceHistoryByUserID() error = %v, want nil", err)
+	}
+	want := []BalancePoint{{day(5), 150}, {day(10), 200}, {day(15), 0}}
+	points := history[accountID]
+	if len(points) != len(want) {
+		t.Fatalf("GetBalanceHistoryByUserID() = %+v, want %+v", points, want)
+	}
+	for i := range want {
+		if !points[i].Date.Equal(want[i].Date) || points[i].Balance != want[i].Balance {
+			t.Errorf("points[%d] = %+v, want %+v", i, points[i], want[i])
+		}
+	}
+
+	netWorth, err := repo.GetNetWorthHistory(userID)
+	if err != nil {
+		t.Fatalf("GetNetWorthHistory() error = %v, want nil", err)
+	}
+	if len(netWorth) != 3 || netWorth[1].NetWorth != 200 || netWorth[2].NetWorth != 0 {
+		t.Errorf("GetNetWorthHistory() = %+v, want 150, 200 then 0 once closed", netWorth)
+	}
+}
+
+// Category tests
+
+func TestTransactionRepository_GetCashFlowByCategory_GroupsByTransactionCategory(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	categoryRepo := NewCategoryRepository(db)
+
+	cashID, _ := categoryRepo.Create(&models.Category{UserID: userID, Name: "Cash", Color: "#10b981"})
+	dividendsID, _ := categoryRepo.Create(&models.Category{UserID: userID, Name: "Dividends", Color: "#6366f1"})
+	if _, err := db.Exec(`UPDATE accounts SET category_id = ? WHERE id = ?`, cashID, accountID); err != nil {
+		t.Fatalf("failed to set account category: %v", err)
+	}
+	result, err := db.Exec(`INSERT INTO accounts (user_id, name, currency, is_liability, is_active) VALUES (?, 'Euro Depot', 'EUR', 0, 1)`, userID)
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	euroID, _ := result.LastInsertId()
+	result, err = db.Exec(`INSERT INTO accounts (user_id, name, currency, is_liability, is_active) VALUES (?, 'Savings', 'DKK', 0, 1)`, userID)
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	savingsID, _ := result.LastInsertId()
+
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for _, txn := range []*models.Transaction{
+		{AccountID: accountID, Amount: 1000, BalanceAfter: 1000, TransactionDate: date},
+		{AccountID: accountID, Amount: -300, BalanceAfter: 700, TransactionDate: date},
+		{AccountID: accountID, Amount: 50, BalanceAfter: 750, CategoryID: &dividendsID, TransactionDate: date},
+		{AccountID: euroID, Amount: 20, BalanceAfter: 20, CategoryID: &dividendsID, TransactionDate: date},
+		{AccountID: accountID, Amount: 80, BalanceAfter: 830, Kind: models.TransactionValuation, TransactionDate: date},
+		{AccountID: accountID, Amount: -400, BalanceAfter: 430, TransactionDate: date.AddDate(0, 0, 1)}, // Transfer out
+		{AccountID: savingsID, Amount: 400, BalanceAfter: 400, TransactionDate: date.AddDate(0, 0, 1)},  // Transfer in
+		{AccountID: accountID, Amount: -250, BalanceAfter: 180, TransactionDate: date.AddDate(0, 0, 2)}, // Spent in DKK
+		{AccountID: euroID, Amount: 250, BalanceAfter: 270, TransactionDate: date.AddDate(0, 0, 2)},     // Received in EUR
+		{AccountID: accountID, Amount: 999, BalanceAfter: 1179, TransactionDate: date.AddDate(0, 1, 0)}, // Outside range
+	} {
+		if _, err := repo.Create(txn); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	flows, err := repo.GetCashFlowByCategory(userID, date.AddDate(0, 0, -9), date.AddDate(0, 0, 21))
+	if err != nil {
+		t.Fatalf("GetCashFlowByCategory() error = %v", err)
+	}
+	if len(flows) != 4 {
+		t.Fatalf("len(flows) = %d; want 4: %+v", len(flows), flows)
+	}
+
+	byKey := map[string]CategoryCashFlow{}
+	for _, f := range flows {
+		byKey[f.Name+"/"+f.Currency] = f
+	}
+	if div := byKey["Dividends/DKK"]; div.Inflow != 50 || div.Outflow != 0 {
+		t.Errorf("Dividends DKK flow = %+v; want in 50", div)
+	}
+	if div := byKey["Dividends/EUR"]; div.Inflow != 20 {
+		t.Errorf("Dividends EUR flow = %+v; want in 20 kept apart from DKK", div)
+	}
+	// Without a transaction category, the account's category applies.
+	// Opposite amounts in different currencies are no transfer.
+	if cash := byKey["Cash/DKK"]; cash.CategoryID == nil || *cash.CategoryID != cashID || cash.Inflow != 1000 || cash.Outflow != 550 {
+		t.Errorf("Cash flow = %+v; want in 1000, out 550 without revaluations and transfers", cash)
+	}
+	if none := byKey["/EUR"]; none.CategoryID != nil || none.Inflow != 250 {
+		t.Errorf("uncategorized EUR flow = %+v; want in 250", none)
+	}
+}
+
+func TestTransactionRepository_GetCashFlowByCategory_CategorizedTransactionsAreNoTransfers(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	salaryID, _ := NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Salary", Color: "#10b981"})
+	result, err := db.Exec(`INSERT INTO accounts (user_id, name, currency, is_liability, is_active) VALUES (?, 'Budget', 'DKK', 0, 1)`, userID)
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	budgetID, _ := result.LastInsertId()
+
+	// A salary and rent of the same amount on the same day
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	repo.Create(&models.Transaction{AccountID: accountID, Amount: 9000, BalanceAfter: 9000, CategoryID: &salaryID, TransactionDate: date})
+	repo.Create(&models.Transaction{AccountID: budgetID, Amount: -9000, BalanceAfter: -9000, TransactionDate: date})
+
+	flows, err := repo.GetCashFlowByCategory(userID, date, date)
+	if err != nil {
+		t.Fatalf("GetCashFlowByCategory() error = %v", err)
+	}
+	var in, out float64
+	for _, f := range flows {
+		in, out = in+f.Inflow, out+f.Outflow
+	}
+	if in != 9000 || out != 9000 {
+		t.Errorf("flows = %+v; want the categorized salary and the rent counted", flows)
+	}
+}
+
+func TestTransactionRepository_CategoryID_RoundTrips(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	categoryID, _ := NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Dividends", Color: "#6366f1"})
+
+	id, err := repo.Create(&models.Transaction{AccountID: accountID, Amount: 10, BalanceAfter: 10, CategoryID: &categoryID, TransactionDate: time.Now()})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	txn, _ := repo.GetByID(id)
+	if txn.CategoryID == nil || *txn.CategoryID != categoryID {
+		t.Fatalf("CategoryID = %v; want %d", txn.CategoryID, categoryID)
+	}
+
+	txn.CategoryID = nil
+	if err := repo.Update(txn); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	txns, _ := repo.GetByAccountID(accountID, 10, 0)
+	if len(txns) != 1 || txns[0].CategoryID != nil {
+		t.Errorf("CategoryID after clearing = %v; want nil", txns[0].CategoryID)
+	}
+}
+
+func TestTransactionRepository_CreateBatch_CreatesAll(t *testing.T) {
+	db, _, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	txns := []*models.Transaction{
+		{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: time.Now().AddDate(0, 0, -1)},
+		{AccountID: accountID, Amount: -40, BalanceAfter: 60, TransactionDate: time.Now()},
+	}
+	if err := repo.CreateBatch(txns); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	if txns[0].ID <= 0 || txns[1].ID <= txns[0].ID {
+		t.Errorf("IDs = %d, %d; want them set in order", txns[0].ID, txns[1].ID)
+	}
+	if balance, _ := repo.GetLatestBalance(accountID); balance != 60 {
+		t.Errorf("GetLatestBalance() = %v; want 60", balance)
+	}
+}
+
+func TestTransactionRepository_CreateBatch_FailureCreatesNone(t *testing.T) {
+	db, _, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+
+	txns := []*models.Transaction{
+		{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: time.Now()},
+		{AccountID: accountID + 1000, Amount: 50, BalanceAfter: 150, TransactionDate: time.Now()},
+	}
+	if err := repo.CreateBatch(txns); err == nil {
+		t.Fatal("CreateBatch() with an unknown account succeeded; want an error")
+	}
+	if count, _ := repo.CountByAccountID(accountID); count != 0 {
+		t.Errorf("CountByAccountID() = %d; want 0 after a failed batch", count)
+	}
+}
+
+func TestTransactionRepository_GetByUserIDFiltered_AppliesAllCriteria(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	categoryID, _ := NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Pension", Color: "#6366f1"})
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	txns := []*models.Transaction{
+		{AccountID: accountID, Amount: 500, BalanceAfter: 500, CategoryID: &categoryID, Tag: "pension", TransactionDate: day("2024-01-15")},
+		{AccountID: accountID, Amount: 1500, BalanceAfter: 2000, CategoryID: &categoryID, Tag: "pension", TransactionDate: day("2024-02-15")},
+		{AccountID: accountID, Amount: 800, BalanceAfter: 2800, Tag: "pension", TransactionDate: day("2024-03-15")},
+		{AccountID: accountID, Amount: 900, BalanceAfter: 3700, CategoryID: &categoryID, TransactionDate: day("2025-01-15")},
+	}
+	if err := repo.CreateBatch(txns); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	minAmount := 100.0
+	from, to := day("2024-01-01"), day("2024-12-31")
+	got, err := repo.GetByUserIDFiltered(userID, models.TransactionFilter{
+		CategoryID: &categoryID,
+		Tag:        "pension",
+		MinAmount:  &minAmount,
+		From:       &from,
+		To:         &to,
+	}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetByUserIDFiltered() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != txns[1].ID || got[1].ID != txns[0].ID {
+		t.Fatalf("GetByUserIDFiltered() returned %d transactions; want the two 2024 pension ones, newest first", len(got))
+	}
+
+	maxAmount := 1000.0
+	got, _ = repo.GetByUserIDFiltered(userID, models.TransactionFilter{Tag: "pension", MaxAmount: &maxAmount}, 10, 0)
+	if len(got) != 2 {
+		t.Errorf("GetByUserIDFiltered() with a maximum returned %d transactions; want 2", len(got))
+	}
+
+	got, _ = repo.GetByUserIDFiltered(userID+1, models.TransactionFilter{}, 10, 0)
+	if len(got) != 0 {
+		t.Errorf("GetByUserIDFiltered() for another user returned %d transactions; want 0", len(got))
+	}
+}
+
+func TestTransactionRepository_GetByUserIDFiltered_CategoryFallsBackToAccount(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	categoryID, _ := NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Crypto", Color: "#f59e0b"})
+	if _, err := db.Exec(`UPDATE accounts SET category_id = ? WHERE id = ?`, categoryID, accountID); err != nil {
+		t.Fatalf("failed to set account category: %v", err)
+	}
+
+	if _, err := repo.Create(&models.Transaction{AccountID: accountID, Amount: 250, BalanceAfter: 250, TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := repo.GetByUserIDFiltered(userID, models.TransactionFilter{CategoryID: &categoryID}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetByUserIDFiltered() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("GetByUserIDFiltered() returned %d transactions; want the one in the account's category", len(got))
+	}
+}
+
+func TestTransactionRepository_GetOutflowsByLiquidity_SkipsValuationsAndTransfers(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	categoryRepo := NewCategoryRepository(db)
+
+	cashID, _ := categoryRepo.Create(&models.Category{UserID: userID, Name: "Cash", Color: "#10b981", Liquidity: models.LiquidityLiquid})
+	if _, err := db.Exec(`UPDATE accounts SET category_id = ? WHERE id = ?`, cashID, accountID); err != nil {
+		t.Fatalf("failed to set account category: %v", err)
+	}
+	result, err := db.Exec(`INSERT INTO accounts (user_id, name, currency, is_liability, is_active) VALUES (?, 'Depot', 'DKK', 0, 1)`, userID)
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	depotID, _ := result.LastInsertId()
+
+	date := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for _, txn := range []*models.Transaction{
+		{AccountID: accountID, Amount: 10000, BalanceAfter: 10000, TransactionDate: date},
+		{AccountID: accountID, Amount: -1200, BalanceAfter: 8800, TransactionDate: date},                                   // Spending
+		{AccountID: accountID, Amount: -500, BalanceAfter: 8300, Kind: models.TransactionValuation, TransactionDate: date}, // Revaluation
+		{AccountID: accountID, Amount: -3000, BalanceAfter: 5300, TransactionDate: date.AddDate(0, 0, 1)},                  // Transfer out
+		{AccountID: depotID, Amount: 3000, BalanceAfter: 3000, TransactionDate: date.AddDate(0, 0, 1)},                     // Transfer in
+	} {
+		if _, err := repo.Create(txn); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	outflows, months, err := repo.GetOutflowsByLiquidity(userID, models.LiquidityLiquid, date.AddDate(0, 0, -9), date.AddDate(0, 0, 20), 1)
+	if err != nil {
+		t.Fatalf("GetOutflowsByLiquidity() error = %v", err)
+	}
+	if outflows != 1200 || months != 1 {
+		t.Errorf("GetOutflowsByLiquidity() = %.2f, %d; want only the spending of 1200 in 1 month", outflows, months)
+	}
+}
+
+func TestTransactionRepository_ImportHistory_StaleFirstImportsNothing(t *testing.T) {
+	db, userID, accountID := setupTransactionTestDB(t)
+	repo := NewTransactionRepository(db)
+	accountRepo := NewAccountRepository(db)
+
+	date := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	firstID, _ := repo.Create(&models.Transaction{AccountID: accountID, Amount: 500, BalanceAfter: 500, TransactionDate: date})
+	first, _ := repo.GetByID(firstID)
+
+	// Someone else edits the first balance after it was read
+	edited, _ := repo.GetByID(firstID)
+	edited.Description = "Edited"
+	if err := repo.Update(edited); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	first.Amount = 100
+	err := repo.ImportHistory([]*HistoryImport{
+		{
+			Account:  &models.Account{UserID: userID, Name: "History", Currency: "DKK"},
+			Balances: []*models.Transaction{{Amount: 300, BalanceAfter: 300, TransactionDate: date.AddDate(-1, 0, 0)}},
+		},
+		{
+			Account:  &models.Account{ID: accountID},
+			Balances: []*models.Transaction{{Amount: 400, BalanceAfter: 400, TransactionDate: date.AddDate(0, -1, 0)}},
+			First:    first,
+		},
+	})
+	if !errors.Is(err, ErrStaleUpdate) {
+		t.Fatalf("ImportHistory() error = %v; want ErrStaleUpdate", err)
+	}
+
+	if accounts, _ := accountRepo.GetByUserID(userID); len(accounts) != 1 {
+		t.Errorf("got %d accounts after a failed import; want 1", len(accounts))
+	}
+	if n, _ := repo.CountByAccountID(accountID); n != 1 {
+		t.Errorf("account has %d transactions after a failed import; want 1", n)
+	}
+}
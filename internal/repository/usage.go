@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// usageDayFormat is how usage days are stored.
+const usageDayFormat = "2006-01-02"
+
+// UsageRepository handles usage count database operations.
+type UsageRepository struct {
+	db *database.DB
+}
+
+// NewUsageRepository creates a new UsageRepository.
+func NewUsageRepository(db *database.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Increment counts one use of kind by the user on the UTC day of at.
+func (r *UsageRepository) Increment(userID int64, kind string, at time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO usage_counts (user_id, day, kind, count)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT(user_id, day, kind) DO UPDATE SET count = count + 1
+	`, userID, at.UTC().Format(usageDayFormat), kind)
+	return err
+}
+
+// CountSince returns how often the user used kind from the UTC day of since.
+func (r *UsageRepository) CountSince(userID int64, kind string, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(count), 0) FROM usage_counts
+		WHERE user_id = ? AND kind = ? AND day >= ?
+	`, userID, kind, since.UTC().Format(usageDayFormat)).Scan(&count)
+	return count, err
+}
+
+// GetDailySince returns the user's usage per day and kind from the UTC day
+// of since, oldest first. Days without usage are left out.
+func (r *UsageRepository) GetDailySince(userID int64, since time.Time) ([]*models.UsageDay, error) {
+	rows, err := r.db.Query(`
+		SELECT day, kind, count FROM usage_counts
+		WHERE user_id = ? AND day >= ?
+		ORDER BY day, kind
+	`, userID, since.UTC().Format(usageDayFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make([]*models.UsageDay, 0)
+	for rows.Next() {
+		var day string
+		usage := &models.UsageDay{}
+		if err := rows.Scan(&day, &usage.Kind, &usage.Count); err != nil {
+			return nil, err
+		}
+		if usage.Day, err = time.Parse(usageDayFormat, day); err != nil {
+			return nil, err
+		}
+		days = append(days, usage)
+	}
+	return days, rows.Err()
+}
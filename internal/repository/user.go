@@ -4,6 +4,7 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"wealth_tracker/internal/database"
@@ -56,13 +57,14 @@ func (r *UserRepository) Create(user *models.User) (int64, error) {
 func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 	query := `
 		SELECT id, email, password_hash, name, default_currency, COALESCE(number_format, 'da'), theme,
-		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), created_at, updated_at
+		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), COALESCE(hide_decimals, 0), COALESCE(milestone_step, 100000), COALESCE(seen_version, ''), balance_description, sync_description, digest_frequency, timezone, net_worth_exclusions, period_start_day, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
 
 	user := &models.User{}
-	var isAdmin, mustChangePassword int
+	var isAdmin, mustChangePassword, hideDecimals int
+	var exclusions string
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID,
 		&user.Email,
@@ -73,6 +75,15 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 		&user.Theme,
 		&isAdmin,
 		&mustChangePassword,
+		&hideDecimals,
+		&user.MilestoneStep,
+		&user.SeenVersion,
+		&user.BalanceDescription,
+		&user.SyncDescription,
+		&user.DigestFrequency,
+		&user.Timezone,
+		&exclusions,
+		&user.PeriodStartDay,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -86,6 +97,8 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 
 	user.IsAdmin = isAdmin == 1
 	user.MustChangePassword = mustChangePassword == 1
+	user.HideDecimals = hideDecimals == 1
+	user.NetWorthExclusions = splitList(exclusions)
 	return user, nil
 }
 
@@ -93,13 +106,14 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
 		SELECT id, email, password_hash, name, default_currency, COALESCE(number_format, 'da'), theme,
-		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), created_at, updated_at
+		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), COALESCE(hide_decimals, 0), COALESCE(milestone_step, 100000), COALESCE(seen_version, ''), balance_description, sync_description, digest_frequency, timezone, net_worth_exclusions, period_start_day, created_at, updated_at
 		FROM users
 		WHERE email = ?
 	`
 
 	user := &models.User{}
-	var isAdmin, mustChangePassword int
+	var isAdmin, mustChangePassword, hideDecimals int
+	var exclusions string
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID,
 		&user.Email,
@@ -110,6 +124,15 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Theme,
 		&isAdmin,
 		&mustChangePassword,
+		&hideDecimals,
+		&user.MilestoneStep,
+		&user.SeenVersion,
+		&user.BalanceDescription,
+		&user.SyncDescription,
+		&user.DigestFrequency,
+		&user.Timezone,
+		&exclusions,
+		&user.PeriodStartDay,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -123,6 +146,8 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 
 	user.IsAdmin = isAdmin == 1
 	user.MustChangePassword = mustChangePassword == 1
+	user.HideDecimals = hideDecimals == 1
+	user.NetWorthExclusions = splitList(exclusions)
 	return user, nil
 }
 
@@ -130,7 +155,8 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 func (r *UserRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
-		SET name = ?, default_currency = ?, number_format = ?, theme = ?, updated_at = ?
+		SET name = ?, default_currency = ?, number_format = ?, theme = ?, hide_decimals = ?, milestone_step = ?,
+		    balance_description = ?, sync_description = ?, digest_frequency = ?, timezone = ?, period_start_day = ?, updated_at = ?
 		WHERE id = ?
 	`
 
@@ -139,6 +165,13 @@ func (r *UserRepository) Update(user *models.User) error {
 		user.DefaultCurrency,
 		user.NumberFormat,
 		user.Theme,
+		boolToInt(user.HideDecimals),
+		user.MilestoneStep,
+		user.BalanceDescription,
+		user.SyncDescription,
+		user.DigestFrequency,
+		user.Timezone,
+		user.PeriodStartDay,
 		time.Now(),
 		user.ID,
 	)
@@ -194,7 +227,7 @@ func (r *UserRepository) EmailExists(email string) (bool, error) {
 func (r *UserRepository) GetAll() ([]*models.User, error) {
 	query := `
 		SELECT id, email, password_hash, name, default_currency, COALESCE(number_format, 'da'), theme,
-		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), created_at, updated_at
+		       COALESCE(is_admin, 0), COALESCE(must_change_password, 0), COALESCE(hide_decimals, 0), COALESCE(milestone_step, 100000), COALESCE(seen_version, ''), balance_description, sync_description, digest_frequency, timezone, net_worth_exclusions, period_start_day, created_at, updated_at
 		FROM users
 		ORDER BY id ASC
 	`
@@ -208,7 +241,8 @@ func (r *UserRepository) GetAll() ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		var isAdmin, mustChangePassword int
+		var isAdmin, mustChangePassword, hideDecimals int
+		var exclusions string
 		err := rows.Scan(
 			&user.ID,
 			&user.Email,
@@ -219,6 +253,15 @@ func (r *UserRepository) GetAll() ([]*models.User, error) {
 			&user.Theme,
 			&isAdmin,
 			&mustChangePassword,
+			&hideDecimals,
+			&user.MilestoneStep,
+			&user.SeenVersion,
+			&user.BalanceDescription,
+			&user.SyncDescription,
+			&user.DigestFrequency,
+			&user.Timezone,
+			&exclusions,
+			&user.PeriodStartDay,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -227,6 +270,8 @@ func (r *UserRepository) GetAll() ([]*models.User, error) {
 		}
 		user.IsAdmin = isAdmin == 1
 		user.MustChangePassword = mustChangePassword == 1
+		user.HideDecimals = hideDecimals == 1
+		user.NetWorthExclusions = splitList(exclusions)
 		users = append(users, user)
 	}
 
@@ -246,6 +291,44 @@ func (r *UserRepository) CountAll() (int, error) {
 	return count, nil
 }
 
+// SetExpiresAt marks a user as temporary, to be deleted after the given time.
+func (r *UserRepository) SetExpiresAt(userID int64, expiresAt time.Time) error {
+	query := `UPDATE users SET expires_at = ?, updated_at = ? WHERE id = ?`
+
+	_, err := r.db.Exec(query, expiresAt, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("setting user expiry: %w", err)
+	}
+
+	return nil
+}
+
+// CountTemporary returns the number of temporary users that have not expired.
+func (r *UserRepository) CountTemporary(now time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM users WHERE expires_at IS NOT NULL AND expires_at >= ?`
+
+	var count int
+	err := r.db.QueryRow(query, now).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting temporary users: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteExpired deletes temporary users whose expiry has passed, with all
+// their data, and returns the number deleted.
+func (r *UserRepository) DeleteExpired(now time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE expires_at IS NOT NULL AND expires_at < ?`
+
+	result, err := r.db.Exec(query, now)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired users: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 // SetAdmin updates a user's admin status.
 func (r *UserRepository) SetAdmin(userID int64, isAdmin bool) error {
 	query := `UPDATE users SET is_admin = ?, updated_at = ? WHERE id = ?`
@@ -270,6 +353,31 @@ func (r *UserRepository) SetMustChangePassword(userID int64, must bool) error {
 	return nil
 }
 
+// SetSeenVersion records the last release whose what's new page the user saw.
+func (r *UserRepository) SetSeenVersion(userID int64, version string) error {
+	query := `UPDATE users SET seen_version = ? WHERE id = ?`
+
+	_, err := r.db.Exec(query, version, userID)
+	if err != nil {
+		return fmt.Errorf("setting seen version: %w", err)
+	}
+
+	return nil
+}
+
+// SetNetWorthExclusions sets the net worth groups the user leaves out of the
+// dashboard's net worth.
+func (r *UserRepository) SetNetWorthExclusions(userID int64, groups []string) error {
+	query := `UPDATE users SET net_worth_exclusions = ? WHERE id = ?`
+
+	_, err := r.db.Exec(query, strings.Join(groups, ","), userID)
+	if err != nil {
+		return fmt.Errorf("setting net worth exclusions: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateEmailAndName updates a user's email and name.
 func (r *UserRepository) UpdateEmailAndName(userID int64, email, name string) error {
 	query := `UPDATE users SET email = ?, name = ?, updated_at = ? WHERE id = ?`
@@ -281,3 +389,11 @@ func (r *UserRepository) UpdateEmailAndName(userID int64, email, name string) er
 
 	return nil
 }
+
+// splitList splits a comma-separated column into its values, or nil if empty.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
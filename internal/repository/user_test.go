@@ -260,3 +260,35 @@ func TestUserRepository_Create_SetsDefaults(t *testing.T) {
 		t.Errorf("Create() default theme = %q, want %q", found.Theme, "dark")
 	}
 }
+
+func TestUserRepository_SetNetWorthExclusions_RoundTrips(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+
+	id, _ := repo.Create(&models.User{
+		Email:        "test@example.com",
+		PasswordHash: "hashedpassword123",
+		Name:         "Test User",
+	})
+
+	found, _ := repo.GetByID(id)
+	if found.NetWorthExclusions != nil {
+		t.Errorf("new user NetWorthExclusions = %v, want nil", found.NetWorthExclusions)
+	}
+
+	if err := repo.SetNetWorthExclusions(id, []string{models.NetWorthGroupPension, models.NetWorthGroupSpouse}); err != nil {
+		t.Fatalf("SetNetWorthExclusions() error = %v, want nil", err)
+	}
+	found, _ = repo.GetByID(id)
+	if len(found.NetWorthExclusions) != 2 || found.NetWorthExclusions[1] != models.NetWorthGroupSpouse {
+		t.Errorf("NetWorthExclusions = %v, want [pension spouse]", found.NetWorthExclusions)
+	}
+
+	if err := repo.SetNetWorthExclusions(id, nil); err != nil {
+		t.Fatalf("SetNetWorthExclusions(nil) error = %v, want nil", err)
+	}
+	found, _ = repo.GetByID(id)
+	if found.NetWorthExclusions != nil {
+		t.Errorf("cleared NetWorthExclusions = %v, want nil", found.NetWorthExclusions)
+	}
+}
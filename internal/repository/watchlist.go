@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+)
+
+// WatchlistRepository handles watchlist database operations.
+type WatchlistRepository struct {
+	db *database.DB
+}
+
+// NewWatchlistRepository creates a new WatchlistRepository.
+func NewWatchlistRepository(db *database.DB) *WatchlistRepository {
+	return &WatchlistRepository{db: db}
+}
+
+// Create inserts a new watchlist item and returns its ID.
+func (r *WatchlistRepository) Create(item *models.WatchlistItem) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO watchlist_items (user_id, symbol, name, currency, instrument_type, notes, added_price, current_price, price_source, price_updated_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, item.UserID, item.Symbol, item.Name, item.Currency, item.InstrumentType, item.Notes,
+		item.AddedPrice, item.CurrentPrice, item.PriceSource, item.PriceUpdatedAt, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetByID retrieves a watchlist item by ID.
+func (r *WatchlistRepository) GetByID(id int64) (*models.WatchlistItem, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, name, currency, instrument_type, notes, added_price, current_price, price_source, price_updated_at, created_at
+		FROM watchlist_items
+		WHERE id = ?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items, err := r.scanItems(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// GetByUserID retrieves a user's watchlist ordered by symbol.
+func (r *WatchlistRepository) GetByUserID(userID int64) ([]*models.WatchlistItem, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, symbol, name, currency, instrument_type, notes, added_price, current_price, price_source, price_updated_at, created_at
+		FROM watchlist_items
+		WHERE user_id = ?
+		ORDER BY symbol
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanItems(rows)
+}
+
+// SetPrice records the latest price of an item and where it came from.
+func (r *WatchlistRepository) SetPrice(id int64, price float64, source string, at time.Time) error {
+	result, err := r.db.Exec(`
+		UPDATE watchlist_items SET current_price = ?, price_source = ?, price_updated_at = ?
+		WHERE id = ?
+	`, price, source, at, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("watchlist item not found")
+	}
+	return nil
+}
+
+// SetAddedPrice records the price an item had when it was added, for items
+// added before any price was known.
+func (r *WatchlistRepository) SetAddedPrice(id int64, price float64) error {
+	_, err := r.db.Exec(`UPDATE watchlist_items SET added_price = ? WHERE id = ?`, price, id)
+	return err
+}
+
+// Delete removes a watchlist item.
+func (r *WatchlistRepository) Delete(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM watchlist_items WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("watchlist item not found")
+	}
+	return nil
+}
+
+// scanItems scans watchlist item rows.
+func (r *WatchlistRepository) scanItems(rows *sql.Rows) ([]*models.WatchlistItem, error) {
+	items := make([]*models.WatchlistItem, 0)
+	for rows.Next() {
+		item := &models.WatchlistItem{}
+		var addedPrice, currentPrice sql.NullFloat64
+		var priceUpdatedAt sql.NullTime
+		err := rows.Scan(
+			&item.ID,
+			&item.UserID,
+			&item.Symbol,
+			&item.Name,
+			&item.Currency,
+			&item.InstrumentType,
+			&item.Notes,
+			&addedPrice,
+			&currentPrice,
+			&item.PriceSource,
+			&priceUpdatedAt,
+			&item.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if addedPrice.Valid {
+			item.AddedPrice = &addedPrice.Float64
+		}
+		if currentPrice.Valid {
+			item.CurrentPrice = &currentPrice.Float64
+		}
+		if priceUpdatedAt.Valid {
+			item.PriceUpdatedAt = &priceUpdatedAt.Time
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
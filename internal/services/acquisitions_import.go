@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// acquisitionImportColumns maps accepted header names to canonical columns.
+var acquisitionImportColumns = map[string]string{
+	"date":       "date",
+	"trade_date": "date",
+	"trade date": "date",
+	"symbol":     "symbol",
+	"ticker":     "symbol",
+	"isin":       "isin",
+	"quantity":   "quantity",
+	"qty":        "quantity",
+	"price":      "price",
+	"fees":       "fees",
+	"fee":        "fees",
+	"commission": "fees",
+	"side":       "side",
+	"type":       "side",
+	"action":     "side",
+}
+
+// acquisitionSells are the side values that mark a row as a sell. Any other
+// value, or no side column, is a buy.
+var acquisitionSells = map[string]bool{"sell": true, "s": true, "salg": true, "sælg": true}
+
+// acquisitionDateFormats are the trade date formats accepted on import.
+var acquisitionDateFormats = []string{"2006-01-02", "02-01-2006", "02.01.2006"}
+
+// ParseAcquisitionsCSV parses a CSV of buy transactions for the given account.
+// The header row must contain date, quantity, price and at least one of
+// symbol or isin; fees are optional and added to the cost. An optional side
+// column marks sells, which are stored with a negative quantity. As with
+// holdings, ISIN is used as the key when present so the buys match synced
+// holdings.
+func ParseAcquisitionsCSV(r io.Reader, accountID int64) ([]*models.HoldingAcquisition, []HoldingImportRowError, error) {
+	reader, columns, err := openImportCSV(r, acquisitionImportColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hasSymbol := columns["symbol"]
+	_, hasISIN := columns["isin"]
+	if !hasSymbol && !hasISIN {
+		return nil, nil, errors.New("header must contain a symbol or isin column")
+	}
+	for _, required := range []string{"date", "quantity", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("header must contain a %s column", required)
+		}
+	}
+
+	var acquisitions []*models.HoldingAcquisition
+	var rowErrors []HoldingImportRowError
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: "malformed CSV line"})
+			continue
+		}
+		if row-1 > maxHoldingImportRows {
+			return nil, nil, fmt.Errorf("file exceeds %d rows", maxHoldingImportRows)
+		}
+
+		field := func(col string) string {
+			i, ok := columns[col]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		acquisition, msg := parseAcquisitionRow(field, accountID)
+		if msg != "" {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: msg})
+			continue
+		}
+		acquisitions = append(acquisitions, acquisition)
+	}
+
+	return acquisitions, rowErrors, nil
+}
+
+// parseAcquisitionRow validates a single row and returns the acquisition or an error message.
+func parseAcquisitionRow(field func(string) string, accountID int64) (*models.HoldingAcquisition, string) {
+	symbol := strings.ToUpper(field("symbol"))
+	isin := strings.ToUpper(field("isin"))
+	if symbol == "" && isin == "" {
+		return nil, "symbol or ISIN is required"
+	}
+	if isin != "" && !isValidISIN(isin) {
+		return nil, fmt.Sprintf("invalid ISIN %q", isin)
+	}
+	key := isin
+	if key == "" {
+		key = symbol
+	}
+
+	var tradeDate time.Time
+	var err error
+	for _, layout := range acquisitionDateFormats {
+		if tradeDate, err = time.Parse(layout, field("date")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Sprintf("invalid date %q", field("date"))
+	}
+
+	quantity, err := parseImportDecimal(field("quantity"))
+	if err != nil {
+		return nil, fmt.Sprintf("invalid quantity %q", field("quantity"))
+	}
+	if quantity <= 0 {
+		return nil, "quantity must be positive"
+	}
+
+	price, err := parseImportDecimal(field("price"))
+	if err != nil {
+		return nil, fmt.Sprintf("invalid price %q", field("price"))
+	}
+	if price < 0 {
+		return nil, "price cannot be negative"
+	}
+
+	var fees float64
+	if v := field("fees"); v != "" {
+		fees, err = parseImportDecimal(v)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid fees %q", v)
+		}
+		if fees < 0 {
+			return nil, "fees cannot be negative"
+		}
+	}
+
+	if acquisitionSells[strings.ToLower(field("side"))] {
+		quantity = -quantity
+	}
+
+	return &models.HoldingAcquisition{
+		AccountID: accountID,
+		Symbol:    key,
+		TradeDate: tradeDate,
+		Quantity:  quantity,
+		Price:     price,
+		Fees:      fees,
+	}, ""
+}
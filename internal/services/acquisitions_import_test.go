@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAcquisitionsCSV_ValidRows(t *testing.T) {
+	csv := "date;isin;symbol;quantity;price;fees\n2023-03-01;DK0062498333;NOVO B;10;600,50;29\n15-06-2024;;aapl;2;150;\n"
+
+	acquisitions, rowErrors, err := ParseAcquisitionsCSV(strings.NewReader(csv), 1)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ParseAcquisitionsCSV() err = %v, rowErrors = %v", err, rowErrors)
+	}
+	if len(acquisitions) != 2 {
+		t.Fatalf("ParseAcquisitionsCSV() returned %d acquisitions, want 2", len(acquisitions))
+	}
+
+	novo := acquisitions[0]
+	if novo.Symbol != "DK0062498333" || novo.Price != 600.5 || novo.Fees != 29 {
+		t.Errorf("first row = %+v; want ISIN key, price 600.5 and fees 29", novo)
+	}
+	if got := novo.TradeDate.Format("2006-01-02"); got != "2023-03-01" {
+		t.Errorf("TradeDate = %s; want 2023-03-01", got)
+	}
+	if aapl := acquisitions[1]; aapl.Symbol != "AAPL" || aapl.TradeDate.Format("2006-01-02") != "2024-06-15" {
+		t.Errorf("second row = %+v; want AAPL bought 2024-06-15", aapl)
+	}
+}
+
+func TestParseAcquisitionsCSV_RowErrors(t *testing.T) {
+	csv := `date,symbol,quantity,price
+yesterday,AAPL,1,100
+2024-01-01,AAPL,-1,100
+2024-01-01,,1,100
+`
+	acquisitions, rowErrors, err := ParseAcquisitionsCSV(strings.NewReader(csv), 1)
+	if err != nil {
+		t.Fatalf("ParseAcquisitionsCSV() error = %v", err)
+	}
+	if len(acquisitions) != 0 || len(rowErrors) != 3 {
+		t.Fatalf("got %d acquisitions and %d row errors; want 0 and 3", len(acquisitions), len(rowErrors))
+	}
+	if rowErrors[0].Row != 2 || !strings.Contains(rowErrors[0].Message, "invalid date") {
+		t.Errorf("rowErrors[0] = %v; want invalid date on row 2", rowErrors[0])
+	}
+}
+
+func TestParseAcquisitionsCSV_MissingColumn(t *testing.T) {
+	if _, _, err := ParseAcquisitionsCSV(strings.NewReader("symbol,quantity,price\nAAPL,1,100\n"), 1); err == nil {
+		t.Error("ParseAcquisitionsCSV() without a date column should fail")
+	}
+}
+
+func TestParseAcquisitionsCSV_Sells(t *testing.T) {
+	csv := "date,symbol,side,quantity,price\n2024-01-02,AAPL,buy,10,100\n2024-05-02,AAPL,Salg,4,120\n"
+
+	acquisitions, rowErrors, err := ParseAcquisitionsCSV(strings.NewReader(csv), 1)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ParseAcquisitionsCSV() err = %v, rowErrors = %v", err, rowErrors)
+	}
+	if len(acquisitions) != 2 || acquisitions[0].Quantity != 10 || acquisitions[1].Quantity != -4 {
+		t.Errorf("quantities = %v; want the buy positive and the sell negative", acquisitions)
+	}
+}
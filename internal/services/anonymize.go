@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/money"
+)
+
+// Range of the random factor anonymized amounts are scaled by.
+const (
+	minAnonymizeFactor = 0.5
+	maxAnonymizeFactor = 2.0
+)
+
+// UserData is the data of a user an anonymized export is made from.
+type UserData struct {
+	User         *models.User
+	Categories   []*models.Category
+	Accounts     []*models.Account
+	Transactions []*models.Transaction
+	Holdings     []*models.Holding
+	Goals        []*models.Goal
+}
+
+// AnonymizedExport is a copy of a user's data with the structure of the
+// stored records, for reproducing bugs without their owner's finances.
+// IDs, dates, currencies, instruments and flags are kept; names and free
+// text are hashed, amounts scaled and secrets left out.
+type AnonymizedExport struct {
+	ExportedAt   time.Time             `json:"exported_at"`
+	Note         string                `json:"note"`
+	User         *models.User          `json:"user"`
+	Categories   []*models.Category    `json:"categories"`
+	Accounts     []*models.Account     `json:"accounts"`
+	Transactions []*models.Transaction `json:"transactions"`
+	Holdings     []*models.Holding     `json:"holdings"`
+	Goals        []*models.Goal        `json:"goals"`
+}
+
+// Anonymizer hashes names with a random key and scales amounts by a random
+// factor, both discarded with the anonymizer. The same name hashes the same
+// within an export, so references by name still match, but cannot be looked
+// up from its hash.
+type Anonymizer struct {
+	key    []byte
+	factor float64
+}
+
+// NewAnonymizer creates an Anonymizer with a random key and factor.
+func NewAnonymizer() (*Anonymizer, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	unit := float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+	return &Anonymizer{key: key, factor: minAnonymizeFactor + unit*(maxAnonymizeFactor-minAnonymizeFactor)}, nil
+}
+
+// Hash replaces a name with kind and a short hash of it, such as
+// "account-3fa9c01b2d". Empty names stay empty.
+func (a *Anonymizer) Hash(kind, name string) string {
+	if name == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(name))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// Scale scales an amount by the anonymizer's factor, rounded to the
+// precision of the currency.
+func (a *Anonymizer) Scale(amount float64, currency string) float64 {
+	return money.Round(amount*a.factor, money.Precision(currency))
+}
+
+// Anonymize returns an anonymized copy of the data; the data itself is not
+// changed.
+func (a *Anonymizer) Anonymize(data *UserData, now time.Time) *AnonymizedExport {
+	export := &AnonymizedExport{
+		ExportedAt:   now,
+		Note:         "Anonymized export: names and text are hashed, amounts are scaled by a random factor and secrets are left out.",
+		Categories:   make([]*models.Category, 0, len(data.Categories)),
+		Accounts:     make([]*models.Account, 0, len(data.Accounts)),
+		Transactions: make([]*models.Transaction, 0, len(data.Transactions)),
+		Holdings:     make([]*models.Holding, 0, len(data.Holdings)),
+		Goals:        make([]*models.Goal, 0, len(data.Goals)),
+	}
+
+	if data.User != nil {
+		u := *data.User
+		u.Email = a.Hash("user", data.User.Email) + "@example.invalid"
+		u.Name = a.Hash("name", u.Name)
+		u.PasswordHash = ""
+		u.MilestoneStep = a.Scale(u.MilestoneStep, u.DefaultCurrency)
+		u.BalanceDescription = a.Hash("text", u.BalanceDescription)
+		u.SyncDescription = a.Hash("text", u.SyncDescription)
+		export.User = &u
+	}
+
+	for _, c := range data.Categories {
+		cat := *c
+		cat.Name = a.Hash("category", cat.Name)
+		cat.Description = a.Hash("text", cat.Description)
+		export.Categories = append(export.Categories, &cat)
+	}
+
+	currencies := make(map[int64]string, len(data.Accounts))
+	for _, acc := range data.Accounts {
+		account := *acc
+		currencies[account.ID] = account.Currency
+		account.Name = a.Hash("account", account.Name)
+		account.Notes = a.Hash("note", account.Notes)
+		account.Balance = a.Scale(account.Balance, account.Currency)
+		export.Accounts = append(export.Accounts, &account)
+	}
+
+	for _, t := range data.Transactions {
+		txn := *t
+		currency := currencies[txn.AccountID]
+		txn.Amount = a.Scale(txn.Amount, currency)
+		txn.BalanceAfter = a.Scale(txn.BalanceAfter, currency)
+		txn.Description = a.Hash("text", txn.Description)
+		txn.Tag = a.Hash("tag", txn.Tag)
+		export.Transactions = append(export.Transactions, &txn)
+	}
+
+	// Prices are kept and quantities scaled, so values scale like the
+	// balances they add up to
+	for _, h := range data.Holdings {
+		holding := *h
+		holding.Name = a.Hash("instrument", holding.Name)
+		holding.Quantity = holding.Quantity * a.factor
+		holding.CurrentValue = a.Scale(holding.CurrentValue, holding.Currency)
+		export.Holdings = append(export.Holdings, &holding)
+	}
+
+	for _, g := range data.Goals {
+		goal := *g
+		goal.Name = a.Hash("goal", goal.Name)
+		goal.Description = a.Hash("text", goal.Description)
+		goal.TargetAmount = a.Scale(goal.TargetAmount, goal.TargetCurrency)
+		goal.MonthlyContribution = a.Scale(goal.MonthlyContribution, goal.TargetCurrency)
+		export.Goals = append(export.Goals, &goal)
+	}
+
+	return export
+}
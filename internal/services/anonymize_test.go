@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestAnonymizer_Anonymize(t *testing.T) {
+	a, err := NewAnonymizer()
+	if err != nil {
+		t.Fatalf("NewAnonymizer() error = %v", err)
+	}
+	if a.factor < minAnonymizeFactor || a.factor >= maxAnonymizeFactor {
+		t.Fatalf("factor = %v; want within [%v, %v)", a.factor, minAnonymizeFactor, maxAnonymizeFactor)
+	}
+
+	categoryID := int64(3)
+	data := &UserData{
+		User:         &models.User{ID: 7, Email: "jens@example.com", Name: "Jens Hansen", PasswordHash: "secret-hash", DefaultCurrency: "DKK"},
+		Categories:   []*models.Category{{ID: 3, UserID: 7, Name: "Pension"}},
+		Accounts:     []*models.Account{{ID: 11, UserID: 7, CategoryID: &categoryID, Name: "Nordnet Jens", Currency: "DKK", Notes: "Konto 1234-5678", Balance: 100000}},
+		Transactions: []*models.Transaction{{ID: 21, AccountID: 11, Amount: 100000, BalanceAfter: 100000, Description: "Løn fra Firma A/S"}},
+		Holdings:     []*models.Holding{{ID: 31, AccountID: 11, Symbol: "DK0060534915", Name: "Novo Nordisk B", Quantity: 10, CurrentPrice: 700, CurrentValue: 7000, Currency: "DKK"}},
+		Goals:        []*models.Goal{{ID: 41, UserID: 7, Name: "Hus i Aarhus", TargetAmount: 500000, TargetCurrency: "DKK", MonthlyContribution: 100000}},
+	}
+
+	export := a.Anonymize(data, time.Now())
+
+	encoded, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("encoding export: %v", err)
+	}
+	for _, private := range []string{"jens@example.com", "Jens", "Pension", "1234-5678", "Firma", "Novo Nordisk", "Aarhus", "secret-hash"} {
+		if strings.Contains(string(encoded), private) {
+			t.Errorf("export contains %q: %s", private, encoded)
+		}
+	}
+
+	// Structure is kept
+	if export.User.ID != 7 || export.Accounts[0].ID != 11 || *export.Accounts[0].CategoryID != 3 || export.Holdings[0].Symbol != "DK0060534915" {
+		t.Errorf("export lost IDs or references: %s", encoded)
+	}
+	if !strings.HasPrefix(export.Accounts[0].Name, "account-") {
+		t.Errorf("account name = %q; want a hash", export.Accounts[0].Name)
+	}
+
+	// Amounts are scaled by the same factor
+	want := 100000 * a.factor
+	for name, got := range map[string]float64{
+		"balance":       export.Accounts[0].Balance,
+		"amount":        export.Transactions[0].Amount,
+		"balance after": export.Transactions[0].BalanceAfter,
+		"contribution":  export.Goals[0].MonthlyContribution,
+	} {
+		if got < want-0.01 || got > want+0.01 {
+			t.Errorf("%s = %.2f; want %.2f", name, got, want)
+		}
+	}
+	if got := export.Holdings[0].Quantity * export.Holdings[0].CurrentPrice; got < export.Holdings[0].CurrentValue-0.01 || got > export.Holdings[0].CurrentValue+0.01 {
+		t.Errorf("holding value %.2f does not match quantity times price %.2f", export.Holdings[0].CurrentValue, got)
+	}
+
+	// The original data is not changed
+	if data.Accounts[0].Name != "Nordnet Jens" || data.Transactions[0].Amount != 100000 {
+		t.Error("Anonymize() changed its input")
+	}
+}
+
+func TestAnonymizer_Hash(t *testing.T) {
+	a, _ := NewAnonymizer()
+	b, _ := NewAnonymizer()
+
+	if a.Hash("account", "Savings") != a.Hash("account", "Savings") {
+		t.Error("the same name hashes differently within an export")
+	}
+	if a.Hash("account", "Savings") == b.Hash("account", "Savings") {
+		t.Error("the same name hashes the same in separate exports")
+	}
+	if a.Hash("account", "") != "" {
+		t.Error("an empty name is not kept empty")
+	}
+}
+
+// anonymizeKeptFields are the string fields an anonymized export keeps as
+// they are, as they describe structure rather than the user's finances.
+// Fields left out of JSON are not exported and need no entry.
+var anonymizeKeptFields = map[string]bool{
+	"User.DefaultCurrency":   true,
+	"User.NumberFormat":      true,
+	"User.Theme":             true,
+	"User.DigestFrequency":   true,
+	"User.Timezone":          true,
+	"Category.Color":         true,
+	"Category.Icon":          true,
+	"Category.Liquidity":     true,
+	"Account.Currency":       true,
+	"Account.NetWorthGroup":  true,
+	"Transaction.Kind":       true,
+	"Holding.ExternalID":     true,
+	"Holding.Symbol":         true,
+	"Holding.Currency":       true,
+	"Holding.InstrumentType": true,
+	"Holding.CostBasisMode":  true,
+	"Goal.TargetCurrency":    true,
+}
+
+func TestAnonymizer_HandlesEveryStringField(t *testing.T) {
+	a, _ := NewAnonymizer()
+	data := &UserData{
+		User:         &models.User{},
+		Categories:   []*models.Category{{}},
+		Accounts:     []*models.Account{{}},
+		Transactions: []*models.Transaction{{}},
+		Holdings:     []*models.Holding{{}},
+		Goals:        []*models.Goal{{}},
+	}
+	records := []any{data.User, data.Categories[0], data.Accounts[0], data.Transactions[0], data.Holdings[0], data.Goals[0]}
+	for _, record := range records {
+		v := reflect.ValueOf(record).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.Kind() == reflect.String {
+				f.SetString("private " + v.Type().Field(i).Name)
+			}
+		}
+	}
+
+	export := a.Anonymize(data, time.Now())
+
+	exported := []any{export.User, export.Categories[0], export.Accounts[0], export.Transactions[0], export.Holdings[0], export.Goals[0]}
+	for i, record := range exported {
+		v := reflect.ValueOf(record).Elem()
+		original := reflect.ValueOf(records[i]).Elem()
+		for j := 0; j < v.NumField(); j++ {
+			field := v.Type().Field(j)
+			name := v.Type().Name() + "." + field.Name
+			if field.Type.Kind() != reflect.String || field.Tag.Get("json") == "-" || anonymizeKeptFields[name] {
+				continue
+			}
+			if v.Field(j).String() == original.Field(j).String() {
+				t.Errorf("%s is exported as it is; anonymize it or list it as kept", name)
+			}
+		}
+	}
+}
@@ -21,6 +21,7 @@ const (
 	AuditUserLogin    AuditAction = "user.login"
 	AuditUserLogout   AuditAction = "user.logout"
 	AuditPasswordChanged AuditAction = "user.password_changed"
+	AuditUserLoginLinkUsed AuditAction = "user.login_link_used"
 
 	// Admin actions
 	AuditAdminUserCreated    AuditAction = "admin.user_created"
@@ -28,6 +29,11 @@ const (
 	AuditAdminUserDeleted    AuditAction = "admin.user_deleted"
 	AuditAdminPasswordReset  AuditAction = "admin.password_reset"
 	AuditAdminSettingsChanged AuditAction = "admin.settings_changed"
+	AuditAdminSupportStarted AuditAction = "admin.support_started"
+	AuditAdminSupportViewed  AuditAction = "admin.support_viewed"
+	AuditAdminSupportEnded   AuditAction = "admin.support_ended"
+	AuditAdminLoginLinkCreated AuditAction = "admin.login_link_created"
+	AuditAdminAnonymizedExport AuditAction = "admin.anonymized_export"
 
 	// Account actions
 	AuditAccountCreated AuditAction = "account.created"
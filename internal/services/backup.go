@@ -0,0 +1,283 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Backup restore errors shown to the user.
+var (
+	ErrBackupPassphraseRequired = errors.New("this backup is encrypted; enter its passphrase to restore it")
+	ErrBackupEmpty              = errors.New("the file contains no accounts, categories or goals")
+)
+
+// Backup is a full backup of a user's data, as written by the full JSON
+// export.
+type Backup struct {
+	Categories   []*models.Category  `json:"categories"`
+	Accounts     []*models.Account   `json:"accounts"`
+	Transactions []BackupTransaction `json:"transactions"`
+	Goals        []GoalExport        `json:"goals"`
+}
+
+// BackupTransaction is a transaction as written to the full JSON export.
+type BackupTransaction struct {
+	AccountID       int64   `json:"account_id"` // The account's ID on the exporting instance
+	Amount          float64 `json:"amount"`
+	BalanceAfter    float64 `json:"balance_after"`
+	Description     string  `json:"description"`
+	Kind            string  `json:"kind,omitempty"`
+	TransactionDate string  `json:"transaction_date"` // YYYY-MM-DD
+}
+
+// ParseBackup reads a full JSON export, decrypting it with the passphrase if
+// it was exported encrypted.
+func ParseBackup(data []byte, passphrase string) (*Backup, error) {
+	if IsEncryptedExport(data) {
+		if passphrase == "" {
+			return nil, ErrBackupPassphraseRequired
+		}
+		var err error
+		if data, err = DecryptExport(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("invalid backup file: %w", err)
+	}
+	if len(backup.Accounts) == 0 && len(backup.Categories) == 0 && len(backup.Goals) == 0 {
+		return nil, ErrBackupEmpty
+	}
+	if len(backup.Goals) > maxGoalImportCount {
+		return nil, fmt.Errorf("too many goals (max %d)", maxGoalImportCount)
+	}
+	for _, a := range backup.Accounts {
+		if a == nil || strings.TrimSpace(a.Name) == "" {
+			return nil, errors.New("every account needs a name")
+		}
+	}
+	for _, t := range backup.Transactions {
+		if _, err := time.Parse("2006-01-02", t.TransactionDate); err != nil {
+			return nil, fmt.Errorf("invalid transaction date %q", t.TransactionDate)
+		}
+	}
+	for _, g := range backup.Goals {
+		if g.Goal == nil || strings.TrimSpace(g.Name) == "" {
+			return nil, errors.New("every goal needs a name")
+		}
+		if g.TargetAmount <= 0 {
+			return nil, fmt.Errorf("goal %q: target amount must be positive", g.Name)
+		}
+	}
+	return &backup, nil
+}
+
+// BackupRestore counts what a restore added.
+type BackupRestore struct {
+	Categories   int
+	Accounts     int
+	Transactions int
+	Goals        int
+}
+
+// BackupService restores full backups.
+type BackupService struct {
+	categoryRepo    *repository.CategoryRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	goalRepo        *repository.GoalRepository
+}
+
+// NewBackupService creates a new BackupService.
+func NewBackupService(
+	categoryRepo *repository.CategoryRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	goalRepo *repository.GoalRepository,
+) *BackupService {
+	return &BackupService{
+		categoryRepo:    categoryRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		goalRepo:        goalRepo,
+	}
+}
+
+// Restore adds a backup to the user's data. Categories, accounts and goals
+// are matched by name; those the user already has are kept as they are, so
+// restoring a backup twice is harmless. The accounts are added with their
+// transactions in one database transaction.
+func (s *BackupService) Restore(userID int64, backup *Backup) (*BackupRestore, error) {
+	restored := &BackupRestore{}
+
+	categories, err := s.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+	categoryIDs := make(map[string]int64)
+	for _, c := range categories {
+		categoryIDs[strings.ToLower(c.Name)] = c.ID
+	}
+	exportedCategories := make(map[int64]*models.Category)
+	for _, c := range backup.Categories {
+		if c != nil && strings.TrimSpace(c.Name) != "" {
+			exportedCategories[c.ID] = c
+		}
+	}
+	categoryID := func(c *models.Category) (int64, error) {
+		key := strings.ToLower(strings.TrimSpace(c.Name))
+		if id, ok := categoryIDs[key]; ok {
+			return id, nil
+		}
+		category := &models.Category{
+			UserID:         userID,
+			Name:           strings.TrimSpace(c.Name),
+			Color:          c.Color,
+			Icon:           c.Icon,
+			ExpectedReturn: c.ExpectedReturn,
+			Liquidity:      c.Liquidity,
+			Description:    c.Description,
+		}
+		if category.Color == "" {
+			category.Color = "#6b7280"
+		}
+		id, err := s.categoryRepo.Create(category)
+		if err != nil {
+			return 0, fmt.Errorf("creating category %q: %w", c.Name, err)
+		}
+		categoryIDs[key] = id
+		restored.Categories++
+		return id, nil
+	}
+	for _, c := range backup.Categories {
+		if c != nil && strings.TrimSpace(c.Name) != "" {
+			if _, err := categoryID(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+	accountIDs := make(map[string]int64)
+	for _, a := range accounts {
+		accountIDs[strings.ToLower(a.Name)] = a.ID
+	}
+
+	// The export lists transactions newest first
+	balances := make(map[int64][]*models.Transaction)
+	for i := len(backup.Transactions) - 1; i >= 0; i-- {
+		t := backup.Transactions[i]
+		day, _ := time.Parse("2006-01-02", t.TransactionDate)
+		balances[t.AccountID] = append(balances[t.AccountID], &models.Transaction{
+			Amount:          t.Amount,
+			BalanceAfter:    t.BalanceAfter,
+			Description:     t.Description,
+			Kind:            t.Kind,
+			TransactionDate: day,
+		})
+	}
+	// Exported account IDs are mapped to the accounts they are restored as
+	restoredIDs := make(map[int64]int64)
+	var imports []*repository.HistoryImport
+	var exportedIDs []int64
+	for _, a := range backup.Accounts {
+		name := strings.TrimSpace(a.Name)
+		if id, ok := accountIDs[strings.ToLower(name)]; ok {
+			restoredIDs[a.ID] = id
+			continue
+		}
+		account := &models.Account{
+			UserID:        userID,
+			Name:          name,
+			Currency:      a.Currency,
+			IsLiability:   a.IsLiability,
+			IsActive:      a.IsActive,
+			Notes:         a.Notes,
+			OpenedAt:      a.OpenedAt,
+			ClosedAt:      a.ClosedAt,
+			InterestRate:  a.InterestRate,
+			IsPinned:      a.IsPinned,
+			NetWorthGroup: a.NetWorthGroup,
+		}
+		if account.Currency == "" {
+			account.Currency = "DKK"
+		}
+		if a.CategoryID != nil {
+			if c, ok := exportedCategories[*a.CategoryID]; ok {
+				id := categoryIDs[strings.ToLower(strings.TrimSpace(c.Name))]
+				account.CategoryID = &id
+			}
+		}
+		txns := balances[a.ID]
+		sort.SliceStable(txns, func(i, j int) bool { return txns[i].TransactionDate.Before(txns[j].TransactionDate) })
+		imports = append(imports, &repository.HistoryImport{Account: account, Balances: txns})
+		exportedIDs = append(exportedIDs, a.ID)
+		accountIDs[strings.ToLower(name)] = 0 // Names in the backup are restored once
+		restored.Transactions += len(txns)
+	}
+	if err := s.transactionRepo.ImportHistory(imports); err != nil {
+		return nil, fmt.Errorf("restoring accounts: %w", err)
+	}
+	for i, imp := range imports {
+		restoredIDs[exportedIDs[i]] = imp.Account.ID
+	}
+	restored.Accounts = len(imports)
+
+	goals, err := s.goalRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting goals: %w", err)
+	}
+	goalNames := make(map[string]bool)
+	for _, g := range goals {
+		goalNames[strings.ToLower(g.Name)] = true
+	}
+	for _, g := range backup.Goals {
+		name := strings.TrimSpace(g.Name)
+		if goalNames[strings.ToLower(name)] {
+			continue
+		}
+		goal := &models.Goal{
+			UserID:              userID,
+			Name:                name,
+			TargetAmount:        g.TargetAmount,
+			TargetCurrency:      g.TargetCurrency,
+			Deadline:            g.Deadline,
+			ReachedDate:         g.ReachedDate,
+			Description:         g.Description,
+			MonthlyContribution: g.MonthlyContribution,
+		}
+		if goal.TargetCurrency == "" {
+			goal.TargetCurrency = "DKK"
+		}
+		if g.CategoryName != "" {
+			id, err := categoryID(&models.Category{Name: g.CategoryName})
+			if err != nil {
+				return nil, err
+			}
+			goal.CategoryID = &id
+		}
+		for _, id := range g.FundingAccountIDs {
+			if restoredID := restoredIDs[id]; restoredID != 0 {
+				goal.FundingAccountIDs = append(goal.FundingAccountIDs, restoredID)
+			}
+		}
+		if _, err := s.goalRepo.Create(goal); err != nil {
+			return nil, fmt.Errorf("restoring goal %q: %w", name, err)
+		}
+		goalNames[strings.ToLower(name)] = true
+		restored.Goals++
+	}
+	return restored, nil
+}
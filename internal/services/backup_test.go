@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestBackupService_RestoreEncryptedBackup(t *testing.T) {
+	_, db, userID := setupCurrencyTest(t)
+	categoryRepo := repository.NewCategoryRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	goalRepo := repository.NewGoalRepository(db)
+	s := NewBackupService(categoryRepo, accountRepo, transactionRepo, goalRepo)
+
+	// Savings already exists and is kept; the other account is restored
+	if _, err := accountRepo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK", IsActive: true}); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	backup := []byte(`{
+		"categories": [{"id": 7, "name": "Stocks", "color": "#10b981"}],
+		"accounts": [
+			{"id": 3, "name": "Depot", "currency": "DKK", "category_id": 7, "is_active": true},
+			{"id": 4, "name": "savings", "currency": "DKK", "is_active": true}
+		],
+		"transactions": [
+			{"account_id": 3, "amount": 500, "balance_after": 1500, "description": "Deposit", "transaction_date": "2024-02-01"},
+			{"account_id": 3, "amount": 1000, "balance_after": 1000, "kind": "valuation", "transaction_date": "2024-01-01"},
+			{"account_id": 4, "amount": 9999, "balance_after": 9999, "transaction_date": "2024-01-01"}
+		],
+		"goals": [{"name": "Retire", "target_amount": 100000, "category_name": "Stocks", "funding_account_ids": [3]}]
+	}`)
+	encrypted, err := EncryptExport(backup, "correct horse battery")
+	if err != nil {
+		t.Fatalf("EncryptExport() error = %v", err)
+	}
+
+	if _, err := ParseBackup(encrypted, ""); !errors.Is(err, ErrBackupPassphraseRequired) {
+		t.Errorf("ParseBackup() without passphrase error = %v; want %v", err, ErrBackupPassphraseRequired)
+	}
+	if _, err := ParseBackup(encrypted, "wrong horse battery"); !errors.Is(err, ErrExportPassphrase) {
+		t.Errorf("ParseBackup() with wrong passphrase error = %v; want %v", err, ErrExportPassphrase)
+	}
+	parsed, err := ParseBackup(encrypted, "correct horse battery")
+	if err != nil {
+		t.Fatalf("ParseBackup() error = %v", err)
+	}
+
+	restored, err := s.Restore(userID, parsed)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if *restored != (BackupRestore{Categories: 1, Accounts: 1, Transactions: 2, Goals: 1}) {
+		t.Errorf("Restore() = %+v; want 1 category, 1 account with 2 transactions and 1 goal", *restored)
+	}
+
+	accounts, _ := accountRepo.GetByUserID(userID)
+	var depot *models.Account
+	for _, a := range accounts {
+		if a.Name == "Depot" {
+			depot = a
+		}
+	}
+	if len(accounts) != 2 || depot == nil || depot.CategoryID == nil {
+		t.Fatalf("accounts after restore = %+v; want Savings and Depot in its category", accounts)
+	}
+	if balance, _ := transactionRepo.GetLatestBalance(depot.ID); balance != 1500 {
+		t.Errorf("Depot balance = %v; want 1500", balance)
+	}
+	txns, _ := transactionRepo.GetByAccountID(depot.ID, 10, 0)
+	if len(txns) != 2 || txns[1].Kind != "valuation" {
+		t.Errorf("Depot transactions = %+v; want the deposit after the valuation", txns)
+	}
+
+	goals, _ := goalRepo.GetByUserID(userID)
+	if len(goals) != 1 || goals[0].CategoryID == nil || *goals[0].CategoryID != *depot.CategoryID ||
+		len(goals[0].FundingAccountIDs) != 1 || goals[0].FundingAccountIDs[0] != depot.ID {
+		t.Errorf("goals after restore = %+v; want Retire in Stocks funded from Depot", goals)
+	}
+
+	// Restoring again adds nothing
+	again, err := s.Restore(userID, parsed)
+	if err != nil {
+		t.Fatalf("second Restore() error = %v", err)
+	}
+	if *again != (BackupRestore{}) {
+		t.Errorf("second Restore() = %+v; want nothing restored", *again)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"math"
+
+	"wealth_tracker/internal/repository"
+)
+
+// balanceTrendWindow is the number of recent balances the trend is fitted to.
+const balanceTrendWindow = 6
+
+// BalanceAnomaly describes a new balance that breaks an account's trend.
+type BalanceAnomaly struct {
+	Previous         float64 // Latest recorded balance
+	Expected         float64 // Balance the recent trend predicts
+	New              float64
+	DeviationPercent float64 // Deviation of New from Expected, in percent of Expected
+}
+
+// DetectBalanceAnomaly compares a new balance with the trend of the recent
+// balances, oldest first. The trend continues the latest balance by the
+// average change between the recent balances. It returns nil if the new
+// balance is within thresholdPercent of the trend, if there is no trend to
+// compare with, or if the threshold is not positive.
+func DetectBalanceAnomaly(recent []float64, newBalance, thresholdPercent float64) *BalanceAnomaly {
+	if thresholdPercent <= 0 || len(recent) == 0 {
+		return nil
+	}
+
+	previous := recent[len(recent)-1]
+	expected := previous
+	if len(recent) > 1 {
+		expected += (previous - recent[0]) / float64(len(recent)-1)
+	}
+	// An empty account, or one trending to zero, has no scale to compare with
+	if expected == 0 || previous == 0 {
+		return nil
+	}
+
+	deviation := math.Abs(newBalance-expected) / math.Abs(expected) * 100
+	if deviation <= thresholdPercent {
+		return nil
+	}
+	return &BalanceAnomaly{
+		Previous:         previous,
+		Expected:         expected,
+		New:              newBalance,
+		DeviationPercent: deviation,
+	}
+}
+
+// BalanceChecker flags new account balances that deviate too far from the
+// account's recent trend, to catch broker API glitches and typos such as a
+// missing digit before they are recorded.
+type BalanceChecker struct {
+	transactionRepo  *repository.TransactionRepository
+	thresholdPercent float64
+}
+
+// NewBalanceChecker creates a BalanceChecker. A threshold of 0 disables it.
+func NewBalanceChecker(transactionRepo *repository.TransactionRepository, thresholdPercent float64) *BalanceChecker {
+	return &BalanceChecker{
+		transactionRepo:  transactionRepo,
+		thresholdPercent: thresholdPercent,
+	}
+}
+
+// Check returns the anomaly if newBalance breaks the account's trend, or nil.
+// A nil checker never flags anything.
+func (c *BalanceChecker) Check(accountID int64, newBalance float64) (*BalanceAnomaly, error) {
+	if c == nil || c.thresholdPercent <= 0 {
+		return nil, nil
+	}
+	recent, err := c.transactionRepo.GetRecentBalances(accountID, balanceTrendWindow)
+	if err != nil {
+		return nil, err
+	}
+	return DetectBalanceAnomaly(recent, newBalance, c.thresholdPercent), nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import "testing"
+
+func TestDetectBalanceAnomaly(t *testing.T) {
+	tests := []struct {
+		name       string
+		recent     []float64
+		newBalance float64
+		want       bool
+	}{
+		{"follows the trend", []float64{100000, 110000, 120000}, 131000, false},
+		{"missing digit", []float64{100000, 110000, 120000}, 13000, true},
+		{"extra digit", []float64{100000, 110000, 120000}, 1300000, true},
+		{"broker returned zero", []float64{50000, 51000}, 0, true},
+		{"single previous balance", []float64{20000}, 24000, false},
+		{"no history", nil, 1000000, false},
+		{"empty account", []float64{1000, 0}, 50000, false},
+		{"liability paid down", []float64{-200000, -195000, -190000}, -185000, false},
+		{"liability typo", []float64{-200000, -195000, -190000}, -18500, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectBalanceAnomaly(tt.recent, tt.newBalance, 50)
+			if (got != nil) != tt.want {
+				t.Errorf("DetectBalanceAnomaly(%v, %.0f) = %+v; want anomaly %v", tt.recent, tt.newBalance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectBalanceAnomaly_ReportsExpectedBalance(t *testing.T) {
+	got := DetectBalanceAnomaly([]float64{100, 200, 300}, 1000, 50)
+	if got == nil {
+		t.Fatal("DetectBalanceAnomaly() = nil; want an anomaly")
+	}
+	if got.Previous != 300 || got.Expected != 400 || got.DeviationPercent != 150 {
+		t.Errorf("DetectBalanceAnomaly() = %+v; want previous 300, expected 400, deviation 150%%", got)
+	}
+}
+
+func TestDetectBalanceAnomaly_DisabledThreshold(t *testing.T) {
+	if got := DetectBalanceAnomaly([]float64{100000}, 1, 0); got != nil {
+		t.Errorf("DetectBalanceAnomaly() with threshold 0 = %+v; want nil", got)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"wealth_tracker/internal/repository"
+)
+
+// BrokerReturnPeriod is a return over one standard period, as reported by the
+// broker and as seen in our own balance history.
+type BrokerReturnPeriod struct {
+	Period string
+	// BrokerPercent is the broker's time-weighted return.
+	BrokerPercent float64
+	// BalanceChangePercent is the change of the recorded balance over the
+	// same period, deposits and withdrawals included. Only set if
+	// HasBalanceChange, which needs a positive starting balance.
+	BalanceChangePercent float64
+	HasBalanceChange     bool
+}
+
+// BrokerReturns are the broker-reported returns of one mapped account.
+type BrokerReturns struct {
+	AccountID   int64
+	AccountName string
+	BrokerType  string
+	FetchedAt   time.Time
+	Periods     []BrokerReturnPeriod
+}
+
+// SetBrokerPerformanceRepository enables GetBrokerReturns.
+func (s *PortfolioService) SetBrokerPerformanceRepository(perfRepo *repository.BrokerPerformanceRepository) {
+	s.perfRepo = perfRepo
+}
+
+// GetBrokerReturns returns the stored broker-reported returns of the user's
+// mapped accounts next to the balance change recorded over the same periods.
+// Returns nil if the repository is not set or nothing was stored.
+func (s *PortfolioService) GetBrokerReturns(userID int64) ([]*BrokerReturns, error) {
+	if s.perfRepo == nil {
+		return nil, nil
+	}
+	perfs, err := s.perfRepo.GetByUserID(userID)
+	if err != nil || len(perfs) == 0 {
+		return nil, err
+	}
+	history, err := s.transactionRepo.GetBalanceHistoryByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*BrokerReturns
+	byAccount := make(map[int64]*BrokerReturns)
+	for _, perf := range perfs {
+		ret := byAccount[perf.LocalAccountID]
+		if ret == nil {
+			ret = &BrokerReturns{
+				AccountID:   perf.LocalAccountID,
+				AccountName: perf.AccountName,
+				BrokerType:  perf.BrokerType,
+				FetchedAt:   perf.FetchedAt,
+			}
+			byAccount[perf.LocalAccountID] = ret
+			results = append(results, ret)
+		}
+		if perf.FetchedAt.After(ret.FetchedAt) {
+			ret.FetchedAt = perf.FetchedAt
+		}
+		change, ok := balanceChangePercent(history[perf.LocalAccountID], perf.Period, perf.FetchedAt)
+		ret.Periods = append(ret.Periods, BrokerReturnPeriod{
+			Period:               perf.Period,
+			BrokerPercent:        perf.ReturnFraction * 100,
+			BalanceChangePercent: change,
+			HasBalanceChange:     ok,
+		})
+	}
+
+	for _, ret := range results {
+		sort.SliceStable(ret.Periods, func(i, j int) bool {
+			return brokerPeriodOrder[ret.Periods[i].Period] < brokerPeriodOrder[ret.Periods[j].Period]
+		})
+	}
+	return results, nil
+}
+
+// brokerPeriodOrder orders standard periods from shortest to longest.
+var brokerPeriodOrder = map[string]int{"Month": 0, "Quarter": 1, "Year": 2, "AllTime": 3}
+
+// balanceChangePercent returns the change of an account's balance over a
+// standard period ending at end, in percent. The period starts a month,
+// quarter or year before end; "AllTime" starts at the first balance. ok is
+// false if there is no positive balance at the start of the period.
+func balanceChangePercent(points []repository.BalancePoint, period string, end time.Time) (change float64, ok bool) {
+	if len(points) == 0 {
+		return 0, false
+	}
+
+	var start time.Time
+	switch period {
+	case "Month":
+		start = end.AddDate(0, -1, 0)
+	case "Quarter":
+		start = end.AddDate(0, -3, 0)
+	case "Year":
+		start = end.AddDate(-1, 0, 0)
+	case "AllTime":
+		start = points[0].Date
+	default:
+		return 0, false
+	}
+
+	// Balances at the start and the end are the last ones recorded by then
+	var startBalance, endBalance float64
+	haveStart := false
+	for _, p := range points {
+		if !p.Date.After(start) {
+			startBalance, haveStart = p.Balance, true
+		}
+		if !p.Date.After(end) {
+			endBalance = p.Balance
+		}
+	}
+	if !haveStart || startBalance <= 0 {
+		return 0, false
+	}
+	return (endBalance - startBalance) / startBalance * 100, true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/repository"
+)
+
+func TestBalanceChangePercent(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	points := []repository.BalancePoint{
+		{Date: day("2023-06-01"), Balance: 500},
+		{Date: day("2023-09-01"), Balance: 800},
+		{Date: day("2024-01-01"), Balance: 1000},
+		{Date: day("2024-05-15"), Balance: 1100},
+		{Date: day("2024-06-20"), Balance: 1210},
+	}
+	end := day("2024-07-01")
+
+	tests := []struct {
+		period string
+		want   float64
+		ok     bool
+	}{
+		{"Month", 10, true},    // From 1100 on 2024-05-15
+		{"Quarter", 21, true},  // From 1000 on 2024-01-01
+		{"Year", 142, true},    // From 500 on 2023-06-01
+		{"AllTime", 142, true}, // From the first balance
+		{"Decade", 0, false},   // Unknown period
+	}
+	for _, tc := range tests {
+		got, ok := balanceChangePercent(points, tc.period, end)
+		if ok != tc.ok || math.Abs(got-tc.want) > 0.001 {
+			t.Errorf("%s: got %.2f, %v; want %.2f, %v", tc.period, got, ok, tc.want, tc.ok)
+		}
+	}
+
+	if _, ok := balanceChangePercent(nil, "Year", end); ok {
+		t.Error("no history: got a change; want none")
+	}
+	zeroStart := []repository.BalancePoint{{Date: day("2024-01-01")}, {Date: day("2024-06-01"), Balance: 100}}
+	if _, ok := balanceChangePercent(zeroStart, "AllTime", end); ok {
+		t.Error("zero start: got a change; want none")
+	}
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// maxStatementEntries limits the size of a single bank statement upload.
+const maxStatementEntries = 10000
+
+// BankStatement is a bank account statement from an ISO 20022 camt.053 file.
+// Statements of several days in one file are merged.
+type BankStatement struct {
+	IBAN           string
+	Currency       string
+	OpeningBalance *float64 // Booked balance before the first entry, if the file has it
+	ClosingBalance *float64 // Booked balance after the last entry, if the file has it
+	Entries        []BankStatementEntry
+}
+
+// BankStatementEntry is a booked entry of a bank statement.
+type BankStatementEntry struct {
+	Date        time.Time // Booking date
+	Amount      float64   // Negative for debits
+	Description string
+}
+
+// camtDocument is the part of a camt.053 document that is imported. Element
+// names are matched regardless of namespace, so every version of the
+// message (camt.053.001.02 to .13) is accepted.
+type camtDocument struct {
+	XMLName    xml.Name        `xml:"Document"`
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN     string        `xml:"Acct>Id>IBAN"`
+	OtherID  string        `xml:"Acct>Id>Othr>Id"`
+	Currency string        `xml:"Acct>Ccy"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Amount      camtAmount       `xml:"Amt"`
+	Indicator   string           `xml:"CdtDbtInd"`
+	Reversal    bool             `xml:"RvslInd"`
+	Status      camtStatus       `xml:"Sts"`
+	BookingDate camtDate         `xml:"BookgDt"`
+	ValueDate   camtDate         `xml:"ValDt"`
+	Info        string           `xml:"AddtlNtryInf"`
+	Details     []camtTxnDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTxnDetails struct {
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty    string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Info         string   `xml:"AddtlTxInf"`
+}
+
+// camtStatus is the booking status of an entry: the status itself before
+// camt.053.001.08 and a code element from then on.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// ParseCamt053 parses an ISO 20022 camt.053 bank statement. Only booked
+// entries are returned, oldest first; pending ones may still change.
+func ParseCamt053(r io.Reader) (*BankStatement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.New("not a camt.053 XML file")
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("the file contains no statement")
+	}
+
+	statement := &BankStatement{}
+	var opening, closing *camtBalance
+	for i, stmt := range doc.Statements {
+		iban := stmt.IBAN
+		if iban == "" {
+			iban = stmt.OtherID
+		}
+		if i == 0 {
+			statement.IBAN, statement.Currency = iban, stmt.Currency
+		} else if iban != statement.IBAN {
+			return nil, fmt.Errorf("the file has statements of several accounts (%s and %s)", statement.IBAN, iban)
+		}
+
+		for j := range stmt.Balances {
+			b := &stmt.Balances[j]
+			switch b.Code {
+			case "OPBD", "PRCD":
+				if opening == nil || b.Date.time().Before(opening.Date.time()) {
+					opening = b
+				}
+			case "CLBD":
+				if closing == nil || !b.Date.time().Before(closing.Date.time()) {
+					closing = b
+				}
+			}
+		}
+
+		for _, ntry := range stmt.Entries {
+			if status := ntry.Status.String(); status != "" && status != "BOOK" {
+				continue
+			}
+			entry, err := ntry.entry()
+			if err != nil {
+				return nil, err
+			}
+			if statement.Currency == "" {
+				statement.Currency = ntry.Amount.Currency
+			}
+			statement.Entries = append(statement.Entries, entry)
+			if len(statement.Entries) > maxStatementEntries {
+				return nil, fmt.Errorf("file exceeds %d entries", maxStatementEntries)
+			}
+		}
+	}
+
+	for _, b := range []struct {
+		balance *camtBalance
+		target  **float64
+	}{{opening, &statement.OpeningBalance}, {closing, &statement.ClosingBalance}} {
+		if b.balance == nil {
+			continue
+		}
+		amount, err := b.balance.Amount.signed(b.balance.Indicator)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s balance: %w", b.balance.Code, err)
+		}
+		*b.target = &amount
+	}
+
+	sort.SliceStable(statement.Entries, func(i, j int) bool {
+		return statement.Entries[i].Date.Before(statement.Entries[j].Date)
+	})
+	return statement, nil
+}
+
+// String returns the status code, such as BOOK or PDNG.
+func (s camtStatus) String() string {
+	if s.Code != "" {
+		return strings.TrimSpace(s.Code)
+	}
+	return strings.TrimSpace(s.Value)
+}
+
+// entry converts a camt.053 entry to a statement entry.
+func (e *camtEntry) entry() (BankStatementEntry, error) {
+	indicator := e.Indicator
+	if e.Reversal {
+		// A reversed debit is money coming back, and vice versa
+		if indicator == "DBIT" {
+			indicator = "CRDT"
+		} else {
+			indicator = "DBIT"
+		}
+	}
+	amount, err := e.Amount.signed(indicator)
+	if err != nil {
+		return BankStatementEntry{}, fmt.Errorf("invalid entry amount: %w", err)
+	}
+
+	date := e.BookingDate.time()
+	if date.IsZero() {
+		date = e.ValueDate.time()
+	}
+	if date.IsZero() {
+		return BankStatementEntry{}, fmt.Errorf("entry of %.2f has no booking date", amount)
+	}
+
+	return BankStatementEntry{
+		Date:        date,
+		Amount:      amount,
+		Description: e.description(amount < 0),
+	}, nil
+}
+
+// description returns the counterparty and the remittance information of an
+// entry, falling back to the bank's own description of it.
+func (e *camtEntry) description(debit bool) string {
+	var counterparty string
+	var remittance []string
+	for _, d := range e.Details {
+		if counterparty == "" {
+			if debit {
+				counterparty = firstNonEmpty(d.Creditor, d.CreditorPty)
+			} else {
+				counterparty = firstNonEmpty(d.Debtor, d.DebtorPty)
+			}
+		}
+		for _, u := range d.Unstructured {
+			if u = strings.TrimSpace(u); u != "" {
+				remittance = append(remittance, u)
+			}
+		}
+		if len(remittance) == 0 && strings.TrimSpace(d.Info) != "" {
+			remittance = append(remittance, strings.TrimSpace(d.Info))
+		}
+	}
+
+	var parts []string
+	if counterparty = strings.TrimSpace(counterparty); counterparty != "" {
+		parts = append(parts, counterparty)
+	}
+	if len(remittance) > 0 {
+		parts = append(parts, strings.Join(remittance, " "))
+	}
+	if len(parts) == 0 {
+		if info := strings.TrimSpace(e.Info); info != "" {
+			return info
+		}
+		return "Bank transaction"
+	}
+	return strings.Join(parts, " - ")
+}
+
+// signed returns the amount, negated for debits.
+func (a camtAmount) signed(indicator string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", a.Value)
+	}
+	if indicator == "DBIT" {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// time returns the date, or the date of the date and time, or the zero time
+// if neither is set.
+func (d camtDate) time() time.Time {
+	if t, err := time.Parse("2006-01-02", strings.TrimSpace(d.Date)); err == nil {
+		return t
+	}
+	if dt := strings.TrimSpace(d.DateTime); len(dt) >= 10 {
+		if t, err := time.Parse("2006-01-02", dt[:10]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// BankStatementImportResult is the outcome of importing a bank statement.
+type BankStatementImportResult struct {
+	Imported   int
+	Duplicates int // Entries already recorded, e.g. by an overlapping statement
+}
+
+// BankStatementImporter records the entries of bank statements as
+// transactions of an account.
+type BankStatementImporter struct {
+	transactionRepo *repository.TransactionRepository
+
+	// categorizer applies the account owner's categorization rules to the
+	// imported entries; nil leaves them uncategorized.
+	categorizer *Categorizer
+}
+
+// NewBankStatementImporter creates a new BankStatementImporter.
+func NewBankStatementImporter(transactionRepo *repository.TransactionRepository) *BankStatementImporter {
+	return &BankStatementImporter{transactionRepo: transactionRepo}
+}
+
+// SetCategorizer sets the categorizer applied to imported entries.
+func (i *BankStatementImporter) SetCategorizer(categorizer *Categorizer) {
+	i.categorizer = categorizer
+}
+
+// Import records the entries of a statement as money moved in or out of the
+// account, with the balance after each entry taken from the statement's
+// opening or closing balance, or continuing from the account's latest
+// balance if the statement has neither. Entries already recorded on the same
+// day with the same amount and description are skipped, so overlapping
+// statements can be imported. The entries are recorded together, or none of
+// them if one fails.
+func (i *BankStatementImporter) Import(account *models.Account, statement *BankStatement) (BankStatementImportResult, error) {
+	var result BankStatementImportResult
+	if statement.Currency != "" && !strings.EqualFold(statement.Currency, account.Currency) {
+		return result, fmt.Errorf("the statement is in %s but %s is in %s", statement.Currency, account.Name, account.Currency)
+	}
+	if len(statement.Entries) == 0 {
+		return result, nil
+	}
+
+	total := 0.0
+	for _, e := range statement.Entries {
+		total += e.Amount
+	}
+	var balance float64
+	continuing := false // From the latest balance rather than the statement's
+	switch {
+	case statement.OpeningBalance != nil:
+		balance = *statement.OpeningBalance
+	case statement.ClosingBalance != nil:
+		balance = *statement.ClosingBalance - total
+	default:
+		latest, err := i.transactionRepo.GetLatestBalance(account.ID)
+		if err != nil {
+			return result, fmt.Errorf("getting balance: %w", err)
+		}
+		balance, continuing = latest, true
+	}
+
+	first, last := statement.Entries[0].Date, statement.Entries[len(statement.Entries)-1].Date
+	existing, err := i.transactionRepo.GetByDateRange(account.ID, first, last)
+	if err != nil {
+		return result, fmt.Errorf("getting transactions: %w", err)
+	}
+	recorded := make(map[string]int)
+	for _, txn := range existing {
+		recorded[statementEntryKey(txn.TransactionDate, txn.Amount, txn.Description)]++
+	}
+
+	rules := i.categorizer.RulesFor(account.ID)
+	var txns []*models.Transaction
+	for _, e := range statement.Entries {
+		key := statementEntryKey(e.Date, e.Amount, e.Description)
+		if recorded[key] > 0 {
+			recorded[key]--
+			result.Duplicates++
+			// The latest balance already includes the recorded entries
+			if !continuing {
+				balance += e.Amount
+			}
+			continue
+		}
+		balance += e.Amount
+
+		txn := &models.Transaction{
+			AccountID:       account.ID,
+			Amount:          e.Amount,
+			BalanceAfter:    math.Round(balance*100) / 100,
+			Description:     e.Description,
+			TransactionDate: e.Date,
+		}
+		rules.Apply(txn)
+		txns = append(txns, txn)
+	}
+
+	if err := i.transactionRepo.CreateBatch(txns); err != nil {
+		return result, fmt.Errorf("recording entries: %w", err)
+	}
+	result.Imported = len(txns)
+	return result, nil
+}
+
+// statementEntryKey identifies an entry for duplicate detection: its day,
+// amount in cents and description.
+func statementEntryKey(date time.Time, amount float64, description string) string {
+	return fmt.Sprintf("%s|%d|%s", date.Format("2006-01-02"), int64(math.Round(amount*100)), strings.TrimSpace(description))
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// sampleCamt053 is a camt.053.001.02 statement with a salary, a card payment
+// and a pending entry.
+const sampleCamt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Acct><Id><IBAN>DK5000400440116243</IBAN></Id><Ccy>DKK</Ccy></Acct>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="DKK">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2025-03-01</Dt></Dt>
+      </Bal>
+      <Bal>
+        <Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+        <Amt Ccy="DKK">30750.50</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+        <Dt><Dt>2025-03-31</Dt></Dt>
+      </Bal>
+      <Ntry>
+        <Amt Ccy="DKK">249.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-03-05</Dt></BookgDt>
+        <NtryDtls><TxDtls>
+          <RltdPties><Cdtr><Nm>Netto</Nm></Cdtr></RltdPties>
+          <RmtInf><Ustrd>Dankort 1234</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="DKK">30000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-03-01T08:00:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <RltdPties><Dbtr><Nm>Employer A/S</Nm></Dbtr></RltdPties>
+          <RmtInf><Ustrd>Løn marts</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="DKK">99.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts>
+        <BookgDt><Dt>2025-03-31</Dt></BookgDt>
+        <AddtlNtryInf>Pending card payment</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseCamt053(t *testing.T) {
+	statement, err := ParseCamt053(strings.NewReader(sampleCamt053))
+	if err != nil {
+		t.Fatalf("ParseCamt053() error = %v", err)
+	}
+
+	if statement.IBAN != "DK5000400440116243" || statement.Currency != "DKK" {
+		t.Errorf("account = %s %s; want the DKK IBAN", statement.IBAN, statement.Currency)
+	}
+	if statement.OpeningBalance == nil || *statement.OpeningBalance != 1000 {
+		t.Errorf("opening balance = %v; want 1000", statement.OpeningBalance)
+	}
+	if statement.ClosingBalance == nil || *statement.ClosingBalance != 30750.5 {
+		t.Errorf("closing balance = %v; want 30750.50", statement.ClosingBalance)
+	}
+
+	if len(statement.Entries) != 2 {
+		t.Fatalf("got %d entries; want the 2 booked ones", len(statement.Entries))
+	}
+	salary, payment := statement.Entries[0], statement.Entries[1]
+	if !salary.Date.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || salary.Amount != 30000 || salary.Description != "Employer A/S - Løn marts" {
+		t.Errorf("entry 0 = %+v; want the salary first", salary)
+	}
+	if payment.Amount != -249.5 || payment.Description != "Netto - Dankort 1234" {
+		t.Errorf("entry 1 = %+v; want the card payment as a debit", payment)
+	}
+}
+
+func TestParseCamt053_StatusCode(t *testing.T) {
+	// From camt.053.001.08 on, the status is a code element
+	xml := `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08"><BkToCstmrStmt><Stmt>
+		<Acct><Id><Othr><Id>12345678</Id></Othr></Id></Acct>
+		<Ntry><Amt Ccy="EUR">10.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><RvslInd>true</RvslInd><Sts><Cd>BOOK</Cd></Sts>
+			<BookgDt><Dt>2025-01-02</Dt></BookgDt><AddtlNtryInf>Refund</AddtlNtryInf></Ntry>
+		<Ntry><Amt Ccy="EUR">5.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts>
+			<BookgDt><Dt>2025-01-03</Dt></BookgDt></Ntry>
+	</Stmt></BkToCstmrStmt></Document>`
+	statement, err := ParseCamt053(strings.NewReader(xml))
+	if err != nil {
+		t.Fatalf("ParseCamt053() error = %v", err)
+	}
+	if statement.IBAN != "12345678" || statement.Currency != "EUR" {
+		t.Errorf("account = %s %s; want the other ID in EUR", statement.IBAN, statement.Currency)
+	}
+	if len(statement.Entries) != 1 || statement.Entries[0].Amount != 10 || statement.Entries[0].Description != "Refund" {
+		t.Errorf("entries = %+v; want the reversed debit as a credit", statement.Entries)
+	}
+}
+
+func TestParseCamt053_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not xml":      "date;amount\n",
+		"no statement": `<Document><BkToCstmrStmt></BkToCstmrStmt></Document>`,
+		"bad amount":   `<Document><BkToCstmrStmt><Stmt><Ntry><Amt>x</Amt><BookgDt><Dt>2025-01-02</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>`,
+	} {
+		if _, err := ParseCamt053(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: ParseCamt053() succeeded; want an error", name)
+		}
+	}
+}
+
+func TestBankStatementImporter_Import(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	userID, err := userRepo.Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	account := &models.Account{UserID: userID, Name: "Checking", Currency: "DKK", IsActive: true}
+	if account.ID, err = accountRepo.Create(account); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	statement, err := ParseCamt053(strings.NewReader(sampleCamt053))
+	if err != nil {
+		t.Fatalf("ParseCamt053() error = %v", err)
+	}
+	importer := NewBankStatementImporter(transactionRepo)
+
+	result, err := importer.Import(account, statement)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.Imported != 2 || result.Duplicates != 0 {
+		t.Errorf("result = %+v; want 2 imported", result)
+	}
+	if balance, _ := transactionRepo.GetLatestBalance(account.ID); balance != 30750.5 {
+		t.Errorf("balance = %.2f; want the closing balance 30750.50", balance)
+	}
+
+	// Importing the statement again records nothing
+	result, err = importer.Import(account, statement)
+	if err != nil {
+		t.Fatalf("Import() again error = %v", err)
+	}
+	if result.Imported != 0 || result.Duplicates != 2 {
+		t.Errorf("result = %+v; want 2 duplicates", result)
+	}
+	if n, _ := transactionRepo.CountByAccountID(account.ID); n != 2 {
+		t.Errorf("account has %d transactions; want 2", n)
+	}
+
+	eur := &models.Account{ID: account.ID, Name: "Checking", Currency: "EUR"}
+	if _, err := importer.Import(eur, statement); err == nil {
+		t.Error("Import() into an account of another currency succeeded; want an error")
+	}
+}
+
+func TestBankStatementImporter_Import_OverlapWithoutBalances(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	account := &models.Account{UserID: userID, Name: "Checking", Currency: "DKK", IsActive: true}
+	if account.ID, err = repository.NewAccountRepository(db).Create(account); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	transactionRepo := repository.NewTransactionRepository(db)
+	importer := NewBankStatementImporter(transactionRepo)
+
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	first := &BankStatement{Entries: []BankStatementEntry{
+		{Date: day(1), Amount: 1000, Description: "Salary"},
+		{Date: day(5), Amount: -200, Description: "Rent"},
+	}}
+	if _, err := importer.Import(account, first); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// The overlapping entry is skipped and does not count twice
+	second := &BankStatement{Entries: []BankStatementEntry{
+		{Date: day(5), Amount: -200, Description: "Rent"},
+		{Date: day(9), Amount: -50, Description: "Groceries"},
+	}}
+	result, err := importer.Import(account, second)
+	if err != nil {
+		t.Fatalf("Import() again error = %v", err)
+	}
+	if result.Imported != 1 || result.Duplicates != 1 {
+		t.Errorf("result = %+v; want 1 imported and 1 duplicate", result)
+	}
+	if balance, _ := transactionRepo.GetLatestBalance(account.ID); balance != 750 {
+		t.Errorf("balance = %.2f; want 750", balance)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// maxRuleTagLength bounds the tag a categorization rule sets.
+const maxRuleTagLength = 50
+
+// Categorization rule validation errors, shown to the user as is.
+var (
+	ErrRuleNameRequired  = errors.New("rule name is required")
+	ErrRuleNoAction      = errors.New("a rule must set a category, a tag or a kind")
+	ErrRuleAmountRange   = errors.New("the minimum amount must not exceed the maximum")
+	ErrRuleUnknownKind   = errors.New("unknown kind")
+	ErrRuleTagTooLong    = fmt.Errorf("tag must be at most %d characters", maxRuleTagLength)
+	ErrRuleInvalidRegexp = errors.New("description pattern is not a valid regular expression")
+)
+
+// ValidateRule trims a categorization rule and checks it can be saved.
+func ValidateRule(rule *models.CategorizationRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
+	rule.Payee = strings.TrimSpace(rule.Payee)
+	rule.Tag = strings.TrimSpace(rule.Tag)
+
+	if rule.Name == "" {
+		return ErrRuleNameRequired
+	}
+	if rule.Pattern != "" {
+		if _, err := regexp.Compile("(?i)" + rule.Pattern); err != nil {
+			return ErrRuleInvalidRegexp
+		}
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return ErrRuleAmountRange
+	}
+	switch rule.Kind {
+	case "", models.RuleKindValuation, models.RuleKindFlow:
+	default:
+		return ErrRuleUnknownKind
+	}
+	if len([]rune(rule.Tag)) > maxRuleTagLength {
+		return ErrRuleTagTooLong
+	}
+	if rule.CategoryID == nil && rule.Tag == "" && rule.Kind == "" {
+		return ErrRuleNoAction
+	}
+	return nil
+}
+
+// RuleSet is a user's categorization rules, compiled and in the order they
+// are tried.
+type RuleSet struct {
+	rules    []*models.CategorizationRule
+	patterns []*regexp.Regexp // nil for rules without a description pattern
+}
+
+// NewRuleSet compiles rules for matching. Rules with an invalid pattern,
+// which validation keeps from being saved, never match.
+func NewRuleSet(rules []*models.CategorizationRule) *RuleSet {
+	set := &RuleSet{}
+	for _, rule := range rules {
+		var pattern *regexp.Regexp
+		if rule.Pattern != "" {
+			var err error
+			if pattern, err = regexp.Compile("(?i)" + rule.Pattern); err != nil {
+				log.Printf("Skipping categorization rule %d with invalid pattern: %v", rule.ID, err)
+				continue
+			}
+		}
+		set.rules = append(set.rules, rule)
+		set.patterns = append(set.patterns, pattern)
+	}
+	return set
+}
+
+// Match returns the first rule a transaction matches, or nil if none does.
+func (s *RuleSet) Match(txn *models.Transaction) *models.CategorizationRule {
+	if s == nil {
+		return nil
+	}
+	for i, rule := range s.rules {
+		if s.patterns[i] != nil && !s.patterns[i].MatchString(txn.Description) {
+			continue
+		}
+		if rule.Payee != "" && !strings.Contains(strings.ToLower(txn.Description), strings.ToLower(rule.Payee)) {
+			continue
+		}
+		if rule.MinAmount != nil && txn.Amount < *rule.MinAmount {
+			continue
+		}
+		if rule.MaxAmount != nil && txn.Amount > *rule.MaxAmount {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// Apply categorizes a transaction by the first rule it matches and returns
+// that rule, or nil if none matched.
+func (s *RuleSet) Apply(txn *models.Transaction) *models.CategorizationRule {
+	rule := s.Match(txn)
+	if rule != nil {
+		applyRule(rule, txn)
+	}
+	return rule
+}
+
+// applyRule sets what a rule sets on a transaction and reports whether
+// anything changed.
+func applyRule(rule *models.CategorizationRule, txn *models.Transaction) bool {
+	changed := false
+	if rule.CategoryID != nil && (txn.CategoryID == nil || *txn.CategoryID != *rule.CategoryID) {
+		categoryID := *rule.CategoryID
+		txn.CategoryID = &categoryID
+		changed = true
+	}
+	if rule.Tag != "" && txn.Tag != rule.Tag {
+		txn.Tag = rule.Tag
+		changed = true
+	}
+	kind := rule.Kind
+	if kind == models.RuleKindFlow {
+		kind = ""
+	}
+	if rule.Kind != "" && txn.Kind != kind {
+		txn.Kind = kind
+		changed = true
+	}
+	return changed
+}
+
+// RuleMatch is a transaction matched in a dry run of categorization rules,
+// with the category, tag and kind it would get.
+type RuleMatch struct {
+	Transaction *models.Transaction
+	Rule        *models.CategorizationRule
+	Result      models.Transaction
+	Changed     bool // False if the transaction is already categorized this way
+}
+
+// PreviewRules dry-runs categorization rules against existing transactions
+// and returns the ones a rule matches, without changing them.
+func PreviewRules(rules []*models.CategorizationRule, transactions []*models.Transaction) []RuleMatch {
+	set := NewRuleSet(rules)
+	var matches []RuleMatch
+	for _, txn := range transactions {
+		rule := set.Match(txn)
+		if rule == nil {
+			continue
+		}
+		result := *txn
+		changed := applyRule(rule, &result)
+		matches = append(matches, RuleMatch{Transaction: txn, Rule: rule, Result: result, Changed: changed})
+	}
+	return matches
+}
+
+// Categorizer categorizes imported and synced transactions by the rules of
+// their account's owner before they are recorded. A nil Categorizer leaves
+// transactions unchanged.
+type Categorizer struct {
+	ruleRepo *repository.CategorizationRuleRepository
+}
+
+// NewCategorizer creates a new Categorizer.
+func NewCategorizer(ruleRepo *repository.CategorizationRuleRepository) *Categorizer {
+	return &Categorizer{ruleRepo: ruleRepo}
+}
+
+// RulesFor returns the rules of an account's owner, to categorize many
+// transactions of the account. A failure to load them is logged and yields
+// no rules, so imports go ahead uncategorized.
+func (c *Categorizer) RulesFor(accountID int64) *RuleSet {
+	if c == nil {
+		return nil
+	}
+	rules, err := c.ruleRepo.GetByAccountID(accountID)
+	if err != nil {
+		log.Printf("Error loading categorization rules for account %d: %v", accountID, err)
+		return nil
+	}
+	return NewRuleSet(rules)
+}
+
+// RulesForUser returns a user's rules, to categorize transactions of
+// accounts that may not exist yet. Failures are handled as by RulesFor.
+func (c *Categorizer) RulesForUser(userID int64) *RuleSet {
+	if c == nil {
+		return nil
+	}
+	rules, err := c.ruleRepo.GetByUserID(userID)
+	if err != nil {
+		log.Printf("Error loading categorization rules for user %d: %v", userID, err)
+		return nil
+	}
+	return NewRuleSet(rules)
+}
+
+// Categorize applies the first matching rule of the account owner to a
+// transaction.
+func (c *Categorizer) Categorize(txn *models.Transaction) {
+	c.RulesFor(txn.AccountID).Apply(txn)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestValidateRule(t *testing.T) {
+	categoryID := int64(1)
+	lo, hi := 100.0, 10.0
+	tests := []struct {
+		name string
+		rule models.CategorizationRule
+		want error
+	}{
+		{"valid", models.CategorizationRule{Name: " Salary ", Pattern: "^løn", CategoryID: &categoryID}, nil},
+		{"no name", models.CategorizationRule{Tag: "x"}, ErrRuleNameRequired},
+		{"no action", models.CategorizationRule{Name: "Salary", Pattern: "løn"}, ErrRuleNoAction},
+		{"bad pattern", models.CategorizationRule{Name: "Salary", Pattern: "(", Tag: "x"}, ErrRuleInvalidRegexp},
+		{"bad range", models.CategorizationRule{Name: "Salary", MinAmount: &lo, MaxAmount: &hi, Tag: "x"}, ErrRuleAmountRange},
+		{"bad kind", models.CategorizationRule{Name: "Salary", Kind: "income"}, ErrRuleUnknownKind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateRule(&tt.rule); err != tt.want {
+				t.Errorf("ValidateRule() = %v; want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleSet_Apply(t *testing.T) {
+	salaryID, rentID := int64(1), int64(2)
+	lo := 10000.0
+	rules := []*models.CategorizationRule{
+		{ID: 1, Name: "Big salary", Pattern: "^løn", MinAmount: &lo, CategoryID: &salaryID, Tag: "salary", Kind: models.RuleKindFlow},
+		{ID: 2, Name: "Rent", Payee: "boligselskab", CategoryID: &rentID},
+		{ID: 3, Name: "Broken", Pattern: "(", Tag: "never"},
+	}
+	set := NewRuleSet(rules)
+
+	txn := &models.Transaction{Description: "LØN marts", Amount: 25000, Kind: models.TransactionValuation}
+	if rule := set.Apply(txn); rule == nil || rule.ID != 1 {
+		t.Fatalf("Apply() = %v; want the salary rule", rule)
+	}
+	if txn.CategoryID == nil || *txn.CategoryID != salaryID || txn.Tag != "salary" || txn.Kind != "" {
+		t.Errorf("transaction = %+v; want salary category and tag, moved money", txn)
+	}
+
+	small := &models.Transaction{Description: "Løn", Amount: 500}
+	if rule := set.Apply(small); rule != nil {
+		t.Errorf("Apply() = %v for an amount below the minimum; want no rule", rule)
+	}
+
+	rent := &models.Transaction{Description: "Husleje KAB Boligselskab", Amount: -8000}
+	if rule := set.Apply(rent); rule == nil || rule.ID != 2 || *rent.CategoryID != rentID {
+		t.Errorf("Apply() = %v, category %v; want the rent rule", rule, rent.CategoryID)
+	}
+
+	var none *RuleSet
+	if rule := none.Apply(rent); rule != nil {
+		t.Errorf("nil RuleSet Apply() = %v; want nil", rule)
+	}
+}
+
+func TestPreviewRules(t *testing.T) {
+	categoryID := int64(7)
+	rules := []*models.CategorizationRule{{ID: 1, Name: "Sync", Pattern: "sync$", CategoryID: &categoryID}}
+	transactions := []*models.Transaction{
+		{ID: 1, Description: "Nordnet sync"},
+		{ID: 2, Description: "Nordnet sync", CategoryID: &categoryID},
+		{ID: 3, Description: "Coffee"},
+	}
+
+	matches := PreviewRules(rules, transactions)
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches; want 2", len(matches))
+	}
+	if !matches[0].Changed || *matches[0].Result.CategoryID != categoryID {
+		t.Errorf("match 0 = %+v; want a category change", matches[0])
+	}
+	if matches[1].Changed {
+		t.Errorf("match 1 changed; want it already categorized")
+	}
+	if transactions[0].CategoryID != nil {
+		t.Errorf("preview changed the transaction")
+	}
+}
+
+func TestCategorizer_Categorize(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	ruleRepo := repository.NewCategorizationRuleRepository(db)
+
+	var accountIDs []int64
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		userID, err := userRepo.Create(&models.User{Email: email, PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+		if err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		accountID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Depot", Currency: "DKK", IsActive: true})
+		accountIDs = append(accountIDs, accountID)
+		if len(accountIDs) == 1 {
+			ruleRepo.Create(&models.CategorizationRule{UserID: userID, Name: "Syncs", Pattern: "sync", Tag: "broker"})
+		}
+	}
+
+	categorizer := NewCategorizer(ruleRepo)
+	own := &models.Transaction{AccountID: accountIDs[0], Description: "Nordnet sync", TransactionDate: time.Now()}
+	categorizer.Categorize(own)
+	if own.Tag != "broker" {
+		t.Errorf("tag = %q; want the owner's rule applied", own.Tag)
+	}
+	other := &models.Transaction{AccountID: accountIDs[1], Description: "Nordnet sync", TransactionDate: time.Now()}
+	categorizer.Categorize(other)
+	if other.Tag != "" {
+		t.Errorf("tag = %q; want another user's rules ignored", other.Tag)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// UncategorizedColor is the chart color of uncategorized accounts' category,
+// unlabeled holdings and unknown asset types.
+const UncategorizedColor = "#9ca3af"
+
+// ChartPalette is the colors assigned, in order, to entities without a color
+// of their own.
+var ChartPalette = []string{
+	"#8b5cf6", "#ec4899", "#06b6d4", "#f59e0b", "#10b981", "#6366f1", "#f97316", "#84cc16",
+	"#3b82f6", "#22c55e", "#eab308", "#ef4444", "#a855f7", "#14b8a6", "#0ea5e9", "#f43f5e",
+}
+
+// accountShades are the amounts accounts of a category are lightened, or
+// darkened if negative, from the category color, in order of creation.
+var accountShades = []float64{0, 0.3, -0.3, 0.55, -0.55, 0.15, -0.15}
+
+// ChartColorService assigns each category, account, asset type, currency and
+// label of a user one color, used by every chart.
+type ChartColorService struct {
+	colorRepo    *repository.ChartColorRepository
+	categoryRepo *repository.CategoryRepository
+	accountRepo  *repository.AccountRepository
+}
+
+// NewChartColorService creates a new ChartColorService.
+func NewChartColorService(
+	colorRepo *repository.ChartColorRepository,
+	categoryRepo *repository.CategoryRepository,
+	accountRepo *repository.AccountRepository,
+) *ChartColorService {
+	return &ChartColorService{
+		colorRepo:    colorRepo,
+		categoryRepo: categoryRepo,
+		accountRepo:  accountRepo,
+	}
+}
+
+// ForUser returns the chart colors of a user. Colors assigned while drawing a
+// chart are kept once saved.
+func (s *ChartColorService) ForUser(userID int64) (*ChartColors, error) {
+	categories, err := s.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+	assigned, err := s.colorRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting chart colors: %w", err)
+	}
+	colors := NewChartColors(userID, categories, accounts, assigned)
+	colors.repo = s.colorRepo
+	return colors, nil
+}
+
+// SetChartColorService makes the portfolio's charts use the user's persisted
+// chart colors. Without it, colors are assigned afresh for every chart.
+func (s *PortfolioService) SetChartColorService(chartColors *ChartColorService) {
+	s.chartColors = chartColors
+}
+
+// chartColorsFor returns the chart colors of a user.
+func (s *PortfolioService) chartColorsFor(userID int64, categories []*models.Category, accounts []*models.Account) (*ChartColors, error) {
+	if s.chartColors == nil {
+		return NewChartColors(userID, categories, accounts, nil), nil
+	}
+	return s.chartColors.ForUser(userID)
+}
+
+// ChartColors resolves the chart colors of a user's entities. Categories are
+// the source of truth: an account is drawn in a shade of its category's color,
+// and an asset type named like a category, as account balances are, in the
+// category's color. Other entities get the next free palette color the first
+// time they are drawn.
+type ChartColors struct {
+	repo            *repository.ChartColorRepository
+	userID          int64
+	categories      map[int64]string
+	categoryNames   map[string]string
+	accountCategory map[int64]int64
+	accountShade    map[int64]int
+	assigned        map[string]string
+	used            map[string]map[string]bool
+	counts          map[string]int
+	pending         []*models.ChartColor
+}
+
+// NewChartColors creates the chart colors of a user from their categories,
+// accounts and previously assigned colors. Colors it assigns are only kept
+// if it was returned by ChartColorService.ForUser.
+func NewChartColors(userID int64, categories []*models.Category, accounts []*models.Account, assigned []*models.ChartColor) *ChartColors {
+	c := &ChartColors{
+		userID:          userID,
+		categories:      make(map[int64]string, len(categories)),
+		categoryNames:   make(map[string]string, len(categories)),
+		accountCategory: make(map[int64]int64, len(accounts)),
+		accountShade:    make(map[int64]int, len(accounts)),
+		assigned:        make(map[string]string, len(assigned)),
+		used:            make(map[string]map[string]bool),
+		counts:          make(map[string]int),
+	}
+	for _, cat := range categories {
+		color := cat.Color
+		if color == "" {
+			color = UncategorizedColor
+		}
+		c.categories[cat.ID] = color
+		c.categoryNames[strings.ToLower(cat.Name)] = color
+	}
+
+	// Shades follow the order accounts were created in, so they are stable
+	sorted := make([]*models.Account, len(accounts))
+	copy(sorted, accounts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	inCategory := make(map[int64]int)
+	for _, a := range sorted {
+		if a.CategoryID == nil {
+			continue
+		}
+		c.accountCategory[a.ID] = *a.CategoryID
+		c.accountShade[a.ID] = inCategory[*a.CategoryID]
+		inCategory[*a.CategoryID]++
+	}
+
+	for _, a := range assigned {
+		c.remember(a.EntityType, a.EntityKey, a.Color)
+	}
+	return c
+}
+
+// Category returns the color of a category, or UncategorizedColor for nil.
+func (c *ChartColors) Category(categoryID *int64) string {
+	if categoryID == nil {
+		return UncategorizedColor
+	}
+	if color, ok := c.categories[*categoryID]; ok {
+		return color
+	}
+	return UncategorizedColor
+}
+
+// Account returns the color of an account: a shade of its category's color,
+// or a palette color if it has no category.
+func (c *ChartColors) Account(accountID int64) string {
+	categoryID, ok := c.accountCategory[accountID]
+	if !ok {
+		return c.Entity(models.ChartColorAccount, strconv.FormatInt(accountID, 10))
+	}
+	shade := accountShades[c.accountShade[accountID]%len(accountShades)]
+	return shadeColor(c.Category(&categoryID), shade)
+}
+
+// Entity returns the color of an asset type, currency, label or uncategorized
+// account, assigning one if it has none yet.
+func (c *ChartColors) Entity(entityType, key string) string {
+	if key == "" || key == "unknown" {
+		return UncategorizedColor
+	}
+	if entityType == models.ChartColorAssetType {
+		if color, ok := c.categoryNames[strings.ToLower(key)]; ok {
+			return color
+		}
+	}
+	if color, ok := c.assigned[entityType+"\x00"+key]; ok {
+		return color
+	}
+
+	// The first palette color not taken by a category or an entity of the
+	// same type; once all are taken, colors repeat
+	color := ChartPalette[c.counts[entityType]%len(ChartPalette)]
+	for _, candidate := range ChartPalette {
+		if !c.used[entityType][candidate] && !c.isCategoryColor(candidate) {
+			color = candidate
+			break
+		}
+	}
+	c.remember(entityType, key, color)
+	c.pending = append(c.pending, &models.ChartColor{UserID: c.userID, EntityType: entityType, EntityKey: key, Color: color})
+	return color
+}
+
+// Save keeps the colors assigned since the chart colors were loaded.
+func (c *ChartColors) Save() error {
+	if c.repo == nil {
+		return nil
+	}
+	for _, color := range c.pending {
+		if err := c.repo.Create(color); err != nil {
+			return fmt.Errorf("saving chart color of %s %s: %w", color.EntityType, color.EntityKey, err)
+		}
+	}
+	c.pending = nil
+	return nil
+}
+
+// remember records the color of an entity.
+func (c *ChartColors) remember(entityType, key, color string) {
+	c.assigned[entityType+"\x00"+key] = color
+	if c.used[entityType] == nil {
+		c.used[entityType] = make(map[string]bool)
+	}
+	c.used[entityType][color] = true
+	c.counts[entityType]++
+}
+
+// isCategoryColor reports whether a category has the color.
+func (c *ChartColors) isCategoryColor(color string) bool {
+	for _, categoryColor := range c.categories {
+		if strings.EqualFold(categoryColor, color) {
+			return true
+		}
+	}
+	return false
+}
+
+// shadeColor lightens a #rrggbb color by amount towards white, or darkens it
+// towards black if amount is negative. Other colors are returned unchanged.
+func shadeColor(color string, amount float64) string {
+	if amount == 0 || len(color) != 7 || color[0] != '#' {
+		return color
+	}
+	rgb, err := strconv.ParseUint(color[1:], 16, 32)
+	if err != nil {
+		return color
+	}
+	channels := [3]float64{float64(rgb >> 16 & 0xff), float64(rgb >> 8 & 0xff), float64(rgb & 0xff)}
+	for i, v := range channels {
+		if amount > 0 {
+			v += (255 - v) * amount
+		} else {
+			v *= 1 + amount
+		}
+		channels[i] = math.Round(v)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", int(channels[0]), int(channels[1]), int(channels[2]))
+}
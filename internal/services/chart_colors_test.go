@@ -0,0 +1,104 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestChartColors(t *testing.T) {
+	stocksID := int64(1)
+	categories := []*models.Category{{ID: stocksID, Name: "Aktier", Color: ChartPalette[0]}}
+	accounts := []*models.Account{
+		{ID: 12, CategoryID: &stocksID},
+		{ID: 10, CategoryID: &stocksID},
+		{ID: 11},
+	}
+	assigned := []*models.ChartColor{{EntityType: models.ChartColorCurrency, EntityKey: "USD", Color: "#123456"}}
+	colors := NewChartColors(1, categories, accounts, assigned)
+
+	if got := colors.Entity(models.ChartColorAssetType, "aktier"); got != ChartPalette[0] {
+		t.Errorf("asset type named like a category = %s; want the category color %s", got, ChartPalette[0])
+	}
+	if got := colors.Entity(models.ChartColorCurrency, "USD"); got != "#123456" {
+		t.Errorf("assigned currency = %s; want #123456", got)
+	}
+	// The category's color is skipped for new entities
+	if got := colors.Entity(models.ChartColorCurrency, "DKK"); got != ChartPalette[1] {
+		t.Errorf("new currency = %s; want %s", got, ChartPalette[1])
+	}
+	if got := colors.Entity(models.ChartColorCurrency, "DKK"); got != ChartPalette[1] {
+		t.Errorf("currency drawn again = %s; want the same %s", got, ChartPalette[1])
+	}
+	if got := colors.Entity(models.ChartColorLabel, ""); got != UncategorizedColor {
+		t.Errorf("unlabeled = %s; want %s", got, UncategorizedColor)
+	}
+
+	// The oldest account of a category has its color, later ones a shade
+	if got := colors.Account(10); got != ChartPalette[0] {
+		t.Errorf("first account = %s; want the category color", got)
+	}
+	if got := colors.Account(12); got == ChartPalette[0] || got == "" {
+		t.Errorf("second account = %s; want a shade of the category color", got)
+	}
+	if got := colors.Account(11); got != ChartPalette[1] {
+		t.Errorf("uncategorized account = %s; want %s", got, ChartPalette[1])
+	}
+}
+
+func TestShadeColor(t *testing.T) {
+	tests := []struct {
+		color  string
+		amount float64
+		want   string
+	}{
+		{"#808080", 0, "#808080"},
+		{"#808080", 0.5, "#c0c0c0"},
+		{"#808080", -0.5, "#404040"},
+		{"red", 0.5, "red"},
+	}
+	for _, tt := range tests {
+		if got := shadeColor(tt.color, tt.amount); got != tt.want {
+			t.Errorf("shadeColor(%s, %v) = %s; want %s", tt.color, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestChartColorService_PersistsAssignments(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	service := NewChartColorService(repository.NewChartColorRepository(db), repository.NewCategoryRepository(db), repository.NewAccountRepository(db))
+
+	colors, err := service.ForUser(userID)
+	if err != nil {
+		t.Fatalf("ForUser() error = %v", err)
+	}
+	colors.Entity(models.ChartColorLabel, "core")
+	satellite := colors.Entity(models.ChartColorLabel, "satellite")
+	if err := colors.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Another chart draws only the second label, in the same color
+	colors, err = service.ForUser(userID)
+	if err != nil {
+		t.Fatalf("ForUser() error = %v", err)
+	}
+	if got := colors.Entity(models.ChartColorLabel, "satellite"); got != satellite {
+		t.Errorf("satellite = %s on the next chart; want %s", got, satellite)
+	}
+}
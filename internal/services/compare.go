@@ -0,0 +1,209 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Statuses of an account or holding in a Comparison.
+const (
+	CompareAdded   = "added"
+	CompareRemoved = "removed"
+	CompareChanged = "changed"
+)
+
+// AccountChange is how an account changed between the two dates of a
+// Comparison. Amounts are in the account's currency and signed by their
+// effect on net worth, so a growing loan has a negative change.
+type AccountChange struct {
+	Account       *models.Account
+	Status        string
+	FromBalance   float64
+	ToBalance     float64
+	Contributions float64 // Money moved in (out if negative)
+	Market        float64 // The rest of the change: returns, interest, revaluations
+}
+
+// Change returns the change of the account's net worth.
+func (c AccountChange) Change() float64 {
+	return c.Contributions + c.Market
+}
+
+// HoldingChange is how a holding's quantity and value changed between the
+// two dates of a Comparison.
+type HoldingChange struct {
+	Account      *models.Account
+	Symbol       string
+	Name         string
+	Status       string
+	FromQuantity float64
+	ToQuantity   float64
+	FromValue    float64
+	ToValue      float64
+}
+
+// QuantityChange returns the change in quantity.
+func (c HoldingChange) QuantityChange() float64 {
+	return c.ToQuantity - c.FromQuantity
+}
+
+// ValueChange returns the change in value.
+func (c HoldingChange) ValueChange() float64 {
+	return c.ToValue - c.FromValue
+}
+
+// NetWorthBridge splits the change of net worth between two dates into the
+// money moved in and out and the market movement, which is what is left.
+type NetWorthBridge struct {
+	Start         float64
+	Contributions float64
+	Market        float64
+	End           float64
+}
+
+// Comparison is what changed in a user's accounts and holdings between two
+// dates, at the end of each day.
+type Comparison struct {
+	From     time.Time
+	To       time.Time
+	Accounts []AccountChange // Unchanged accounts are left out
+	Holdings []HoldingChange // Unchanged holdings are left out
+	Bridge   NetWorthBridge
+
+	// HoldingsSince is the first day holdings were recorded; holding
+	// changes before it are unknown. Zero if holdings were never recorded.
+	HoldingsSince time.Time
+}
+
+// NewComparison compares the balance history and holding snapshots of a
+// user's accounts at two dates. flows are the money moved in and out of each
+// account between the dates, as summed by GetFlowsByAccount.
+//
+// Like the net worth history, balances are not converted between currencies
+// and liabilities count by their absolute balance.
+func NewComparison(
+	accounts []*models.Account,
+	history map[int64][]repository.BalancePoint,
+	flows map[int64]float64,
+	fromHoldings, toHoldings []repository.HoldingSnapshot,
+	from, to time.Time,
+) *Comparison {
+	c := &Comparison{From: from, To: to}
+
+	byID := make(map[int64]*models.Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
+
+		points := history[account.ID]
+		fromBalance, toBalance := balanceAt(points, from), balanceAt(points, to)
+		hadFrom := startedBy(points, from) && !closedBy(account, from)
+		hasTo := startedBy(points, to) && !closedBy(account, to)
+		if !hadFrom && !hasTo {
+			continue
+		}
+
+		fromWorth, toWorth, flow := netWorthEffect(account, fromBalance, toBalance, flows[account.ID])
+
+		change := AccountChange{
+			Account:       account,
+			FromBalance:   fromWorth,
+			ToBalance:     toWorth,
+			Contributions: flow,
+			Market:        toWorth - fromWorth - flow,
+		}
+		switch {
+		case !hadFrom:
+			change.Status = CompareAdded
+		case !hasTo:
+			change.Status = CompareRemoved
+		case fromWorth != toWorth || flow != 0:
+			change.Status = CompareChanged
+		}
+
+		c.Bridge.Start += fromWorth
+		c.Bridge.End += toWorth
+		c.Bridge.Contributions += flow
+		if change.Status != "" {
+			c.Accounts = append(c.Accounts, change)
+		}
+	}
+	c.Bridge.Market = c.Bridge.End - c.Bridge.Start - c.Bridge.Contributions
+
+	type key struct {
+		accountID int64
+		symbol    string
+	}
+	changes := make(map[key]*HoldingChange)
+	var keys []key
+	get := func(s repository.HoldingSnapshot) *HoldingChange {
+		k := key{s.AccountID, s.Symbol}
+		if changes[k] == nil {
+			changes[k] = &HoldingChange{Account: byID[s.AccountID], Symbol: s.Symbol}
+			keys = append(keys, k)
+		}
+		return changes[k]
+	}
+	for _, s := range fromHoldings {
+		h := get(s)
+		h.Name, h.FromQuantity, h.FromValue = s.Name, s.Quantity, s.CurrentValue
+	}
+	for _, s := range toHoldings {
+		h := get(s)
+		h.Name, h.ToQuantity, h.ToValue = s.Name, s.Quantity, s.CurrentValue
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].accountID != keys[j].accountID {
+			return keys[i].accountID < keys[j].accountID
+		}
+		return keys[i].symbol < keys[j].symbol
+	})
+	for _, k := range keys {
+		h := changes[k]
+		if h.Account == nil {
+			continue
+		}
+		switch {
+		case h.FromQuantity == 0 && h.ToQuantity != 0:
+			h.Status = CompareAdded
+		case h.FromQuantity != 0 && h.ToQuantity == 0:
+			h.Status = CompareRemoved
+		case h.FromQuantity != h.ToQuantity || h.FromValue != h.ToValue:
+			h.Status = CompareChanged
+		default:
+			continue
+		}
+		c.Holdings = append(c.Holdings, *h)
+	}
+
+	return c
+}
+
+// netWorthEffect returns what an account's balances and money moved add to
+// net worth: the same for assets, and negative for liabilities, whatever the
+// sign they are recorded with.
+func netWorthEffect(account *models.Account, fromBalance, toBalance, flow float64) (fromWorth, toWorth, flowWorth float64) {
+	if !account.IsLiability {
+		return fromBalance, toBalance, flow
+	}
+	// Paying off a loan lowers its balance and raises net worth, unless the
+	// loan is recorded with a negative balance
+	if toBalance > 0 || (toBalance == 0 && fromBalance > 0) {
+		flow = -flow
+	}
+	return -math.Abs(fromBalance), -math.Abs(toBalance), flow
+}
+
+// closedBy returns true if the account was closed by the end of a day.
+func closedBy(account *models.Account, day time.Time) bool {
+	return account.ClosedAt != nil && !account.ClosedAt.After(day)
+}
+
+// startedBy returns true if a balance history had started by the end of a
+// day.
+func startedBy(points []repository.BalancePoint, day time.Time) bool {
+	return len(points) > 0 && !points[0].Date.After(day)
+}
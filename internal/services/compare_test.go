@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestNewComparison(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	closed := day("2024-02-10")
+	savings := &models.Account{ID: 1, Name: "Savings"}
+	broker := &models.Account{ID: 2, Name: "Broker"}
+	loan := &models.Account{ID: 3, Name: "Loan", IsLiability: true}
+	newAccount := &models.Account{ID: 4, Name: "New"}
+	old := &models.Account{ID: 5, Name: "Old", ClosedAt: &closed}
+	idle := &models.Account{ID: 6, Name: "Idle"}
+
+	history := map[int64][]repository.BalancePoint{
+		1: {{Date: day("2024-01-01"), Balance: 1000}, {Date: day("2024-02-15"), Balance: 1500}},
+		2: {{Date: day("2024-01-01"), Balance: 5000}, {Date: day("2024-02-20"), Balance: 5600}},
+		3: {{Date: day("2024-01-01"), Balance: 10000}, {Date: day("2024-02-01"), Balance: 9000}},
+		4: {{Date: day("2024-02-05"), Balance: 200}},
+		5: {{Date: day("2024-01-01"), Balance: 300}, {Date: day("2024-02-10"), Balance: 0}},
+		6: {{Date: day("2024-01-01"), Balance: 50}},
+	}
+	flows := map[int64]float64{
+		1: 500,   // Deposit
+		2: 400,   // Bought for 400, grew 200
+		3: -1000, // Loan payment
+		4: 200,
+		5: -300,
+	}
+	fromHoldings := []repository.HoldingSnapshot{
+		{AccountID: 2, Symbol: "AAA", Name: "A", Quantity: 10, CurrentValue: 5000},
+		{AccountID: 2, Symbol: "OLD", Name: "Old", Quantity: 1, CurrentValue: 100},
+		{AccountID: 2, Symbol: "SAME", Name: "Same", Quantity: 1, CurrentValue: 10},
+	}
+	toHoldings := []repository.HoldingSnapshot{
+		{AccountID: 2, Symbol: "AAA", Name: "A", Quantity: 12, CurrentValue: 5600},
+		{AccountID: 2, Symbol: "BBB", Name: "B", Quantity: 3, CurrentValue: 300},
+		{AccountID: 2, Symbol: "OLD", Name: "Old", Quantity: 0, CurrentValue: 0},
+		{AccountID: 2, Symbol: "SAME", Name: "Same", Quantity: 1, CurrentValue: 10},
+		{AccountID: 99, Symbol: "GONE", Name: "Deleted account", Quantity: 1, CurrentValue: 1},
+	}
+
+	c := NewComparison([]*models.Account{savings, broker, loan, newAccount, old, idle}, history, flows, fromHoldings, toHoldings, day("2024-01-31"), day("2024-02-29"))
+
+	wantAccounts := map[string]struct {
+		status        string
+		contributions float64
+		market        float64
+	}{
+		"Savings": {CompareChanged, 500, 0},
+		"Broker":  {CompareChanged, 400, 200},
+		"Loan":    {CompareChanged, 1000, 0},
+		"New":     {CompareAdded, 200, 0},
+		"Old":     {CompareRemoved, -300, 0},
+	}
+	if len(c.Accounts) != len(wantAccounts) {
+		t.Fatalf("got %d account changes; want %d", len(c.Accounts), len(wantAccounts))
+	}
+	for _, a := range c.Accounts {
+		want, ok := wantAccounts[a.Account.Name]
+		if !ok {
+			t.Errorf("unexpected change of %s", a.Account.Name)
+			continue
+		}
+		if a.Status != want.status || a.Contributions != want.contributions || a.Market != want.market {
+			t.Errorf("%s = %s, %v contributed, %v market; want %s, %v, %v",
+				a.Account.Name, a.Status, a.Contributions, a.Market, want.status, want.contributions, want.market)
+		}
+	}
+
+	// 1000 + 5000 - 10000 + 300 + 50 before, 1500 + 5600 - 9000 + 200 + 50 after
+	wantBridge := NetWorthBridge{Start: -3650, Contributions: 1800, Market: 200, End: -1650}
+	if c.Bridge != wantBridge {
+		t.Errorf("Bridge = %+v; want %+v", c.Bridge, wantBridge)
+	}
+
+	wantHoldings := []struct {
+		symbol string
+		status string
+		qty    float64
+	}{
+		{"AAA", CompareChanged, 2},
+		{"BBB", CompareAdded, 3},
+		{"OLD", CompareRemoved, -1},
+	}
+	if len(c.Holdings) != len(wantHoldings) {
+		t.Fatalf("got %d holding changes; want %d", len(c.Holdings), len(wantHoldings))
+	}
+	for i, want := range wantHoldings {
+		h := c.Holdings[i]
+		if h.Symbol != want.symbol || h.Status != want.status || h.QuantityChange() != want.qty {
+			t.Errorf("holding %d = %s %s %v; want %s %s %v", i, h.Symbol, h.Status, h.QuantityChange(), want.symbol, want.status, want.qty)
+		}
+	}
+}
@@ -2,7 +2,9 @@
 package services
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +13,8 @@ import (
 	"time"
 
 	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
 )
 
 // CurrencyRate represents an exchange rate.
@@ -21,21 +25,54 @@ type CurrencyRate struct {
 	FetchedAt time.Time
 }
 
+// RateSource tells where the rate used for a conversion came from.
+type RateSource string
+
+const (
+	RateSourceProvider RateSource = "provider" // Exchange rate API (or same currency)
+	RateSourceManual   RateSource = "manual"   // Rate entered by the user
+	RateSourceFallback RateSource = "fallback" // No rate found, converted 1:1
+)
+
 // CurrencyService provides currency conversion functionality.
 type CurrencyService struct {
-	db     *database.DB
-	cache  map[string]CurrencyRate
-	mu     sync.RWMutex
-	maxAge time.Duration
+	db          *database.DB
+	manualRates *repository.ExchangeRateRepository
+	fetch       func(from, to string) (float64, error)
+	usage       *UsageService // Counts provider fetches for users; nil leaves them unlimited
+	cache       map[string]CurrencyRate
+	failures    map[string]rateFailure // Failed provider fetches by pair
+	mu          sync.RWMutex
+	maxAge      time.Duration
+	failureAge  time.Duration
+}
+
+// rateFailure is a failed provider fetch, remembered so a pair the provider
+// does not cover is not fetched again on every page render.
+type rateFailure struct {
+	err error
+	at  time.Time
 }
 
 // NewCurrencyService creates a new CurrencyService.
 func NewCurrencyService(db *database.DB) *CurrencyService {
-	return &CurrencyService{
-		db:     db,
-		cache:  make(map[string]CurrencyRate),
-		maxAge: 24 * time.Hour, // Rates are cached for 24 hours
+	s := &CurrencyService{
+		db:          db,
+		manualRates: repository.NewExchangeRateRepository(db),
+		cache:       make(map[string]CurrencyRate),
+		failures:    make(map[string]rateFailure),
+		maxAge:      24 * time.Hour,   // Rates are cached for 24 hours
+		failureAge:  10 * time.Minute, // Failed fetches are retried after 10 minutes
 	}
+	s.fetch = s.fetchRate
+	return s
+}
+
+// SetUsage counts the provider fetches made for a user against their
+// monthly market data quota. Users over quota get stored rates, even if
+// stale.
+func (s *CurrencyService) SetUsage(usage *UsageService) {
+	s.usage = usage
 }
 
 // Convert converts an amount from one currency to another.
@@ -52,10 +89,47 @@ func (s *CurrencyService) Convert(amount float64, from, to string) (float64, err
 	return amount * rate, nil
 }
 
+// ConvertForUser converts an amount using the provider rate, falling back to
+// the user's manual rates for pairs the provider does not cover. If neither
+// has a rate, the amount is returned unconverted with RateSourceFallback.
+func (s *CurrencyService) ConvertForUser(userID int64, amount float64, from, to string) (float64, RateSource) {
+	if from == to {
+		return amount, RateSourceProvider
+	}
+
+	allowFetch := s.usage == nil || s.usage.Allow(userID, models.UsageMarketData) == nil
+	rate, fetched, err := s.getRate(from, to, allowFetch)
+	if fetched && s.usage != nil {
+		s.usage.Record(userID, models.UsageMarketData)
+	}
+	if err == nil {
+		return amount * rate, RateSourceProvider
+	}
+
+	manual, ok, mErr := s.manualRates.FindRate(userID, from, to, time.Now())
+	if mErr != nil {
+		log.Printf("Failed to look up manual rate %s/%s: %v", from, to, mErr)
+	}
+	if ok {
+		return amount * manual, RateSourceManual
+	}
+
+	log.Printf("No exchange rate for %s/%s, converting 1:1: %v", from, to, err)
+	return amount, RateSourceFallback
+}
+
 // GetRate returns the exchange rate from one currency to another.
 func (s *CurrencyService) GetRate(from, to string) (float64, error) {
+	rate, _, err := s.getRate(from, to, true)
+	return rate, err
+}
+
+// getRate returns the exchange rate from one currency to another, and
+// whether it was fetched from the provider. Without allowFetch, stored rates
+// are used however old they are.
+func (s *CurrencyService) getRate(from, to string, allowFetch bool) (float64, bool, error) {
 	if from == to {
-		return 1.0, nil
+		return 1.0, false, nil
 	}
 
 	cacheKey := from + "_" + to
@@ -64,7 +138,7 @@ func (s *CurrencyService) GetRate(from, to string) (float64, error) {
 	s.mu.RLock()
 	if rate, ok := s.cache[cacheKey]; ok && time.Since(rate.FetchedAt) < s.maxAge {
 		s.mu.RUnlock()
-		return rate.Rate, nil
+		return rate.Rate, false, nil
 	}
 	s.mu.RUnlock()
 
@@ -74,18 +148,24 @@ func (s *CurrencyService) GetRate(from, to string) (float64, error) {
 		s.mu.Lock()
 		s.cache[cacheKey] = rate
 		s.mu.Unlock()
-		return rate.Rate, nil
+		return rate.Rate, false, nil
+	}
+	if !allowFetch {
+		if rate.Rate > 0 {
+			return rate.Rate, false, nil
+		}
+		return 0, false, fmt.Errorf("no stored exchange rate %s/%s: %w", from, to, ErrQuotaExceeded)
 	}
 
-	// Fetch fresh rate from API
-	freshRate, err := s.fetchRate(from, to)
+	// Fetch fresh rate from API, unless that failed moments ago
+	freshRate, err := s.fetchUnlessFailed(from, to)
 	if err != nil {
 		// If API fails, try to use stale rate from DB
 		if rate.Rate > 0 {
 			log.Printf("Using stale rate for %s/%s due to API error: %v", from, to, err)
-			return rate.Rate, nil
+			return rate.Rate, false, nil
 		}
-		return 0, fmt.Errorf("failed to get exchange rate %s/%s: %w", from, to, err)
+		return 0, false, fmt.Errorf("failed to get exchange rate %s/%s: %w", from, to, err)
 	}
 
 	// Store in DB and cache
@@ -102,7 +182,31 @@ func (s *CurrencyService) GetRate(from, to string) (float64, error) {
 	}
 	s.mu.Unlock()
 
-	return freshRate, nil
+	return freshRate, true, nil
+}
+
+// fetchUnlessFailed fetches a rate from the provider. A failed fetch is
+// remembered for failureAge, during which its error is returned without
+// asking the provider again.
+func (s *CurrencyService) fetchUnlessFailed(from, to string) (float64, error) {
+	cacheKey := from + "_" + to
+
+	s.mu.RLock()
+	failure, ok := s.failures[cacheKey]
+	s.mu.RUnlock()
+	if ok && time.Since(failure.at) < s.failureAge {
+		return 0, failure.err
+	}
+
+	rate, err := s.fetch(from, to)
+	s.mu.Lock()
+	if err != nil {
+		s.failures[cacheKey] = rateFailure{err: err, at: time.Now()}
+	} else {
+		delete(s.failures, cacheKey)
+	}
+	s.mu.Unlock()
+	return rate, err
 }
 
 // getFromDB retrieves a rate from the database.
@@ -117,17 +221,57 @@ func (s *CurrencyService) getFromDB(from, to string) (CurrencyRate, error) {
 	return rate, err
 }
 
-// saveToDB stores a rate in the database.
+// saveToDB stores a rate in the database, as the latest rate and as the
+// rate of the day.
 func (s *CurrencyService) saveToDB(from, to string, rate float64) error {
-	_, err := s.db.Exec(`
+	now := time.Now()
+	if _, err := s.db.Exec(`
 		INSERT INTO currency_rates (from_currency, to_currency, rate, fetched_at)
 		VALUES (?, ?, ?, ?)
 		ON CONFLICT(from_currency, to_currency)
 		DO UPDATE SET rate = excluded.rate, fetched_at = excluded.fetched_at
-	`, from, to, rate, time.Now())
+	`, from, to, rate, now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO currency_rate_history (from_currency, to_currency, day, rate)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(from_currency, to_currency, day)
+		DO UPDATE SET rate = excluded.rate
+	`, from, to, now.Format("2006-01-02"), rate)
 	return err
 }
 
+// RateForUserOn returns the exchange rate of a past day: the last provider
+// rate fetched on or before it, or else the user's manual rate valid on it.
+// It returns false if neither is known, as provider rates are only recorded
+// from when they are first fetched.
+func (s *CurrencyService) RateForUserOn(userID int64, from, to string, day time.Time) (float64, bool) {
+	if from == to {
+		return 1.0, true
+	}
+
+	var rate float64
+	err := s.db.QueryRow(`
+		SELECT rate FROM currency_rate_history
+		WHERE from_currency = ? AND to_currency = ? AND day <= ?
+		ORDER BY day DESC
+		LIMIT 1
+	`, from, to, day.Format("2006-01-02")).Scan(&rate)
+	if err == nil {
+		return rate, true
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Failed to look up rate %s/%s on %s: %v", from, to, day.Format("2006-01-02"), err)
+	}
+
+	manual, ok, err := s.manualRates.FindRate(userID, from, to, day)
+	if err != nil {
+		log.Printf("Failed to look up manual rate %s/%s: %v", from, to, err)
+	}
+	return manual, ok
+}
+
 // fetchRate fetches a rate from an external API.
 // Uses the free exchangerate-api.com service.
 func (s *CurrencyService) fetchRate(from, to string) (float64, error) {
@@ -197,9 +341,10 @@ func (s *CurrencyService) RefreshRates(baseCurrency string) error {
 	return nil
 }
 
-// ClearCache clears the in-memory rate cache.
+// ClearCache clears the in-memory rate cache and the failed fetches.
 func (s *CurrencyService) ClearCache() {
 	s.mu.Lock()
 	s.cache = make(map[string]CurrencyRate)
+	s.failures = make(map[string]rateFailure)
 	s.mu.Unlock()
 }
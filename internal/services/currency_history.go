@@ -0,0 +1,144 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+// RatePoint is the exchange rate of a currency pair on a day.
+type RatePoint struct {
+	Day  time.Time `json:"day"`
+	Rate float64   `json:"rate"`
+}
+
+// TrackedCurrencies returns the currencies rates have been fetched for,
+// sorted by code.
+func (s *CurrencyService) TrackedCurrencies() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT from_currency FROM currency_rates
+		UNION
+		SELECT to_currency FROM currency_rates
+		ORDER BY 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var currencies []string
+	for rows.Next() {
+		var currency string
+		if err := rows.Scan(&currency); err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, currency)
+	}
+	return currencies, rows.Err()
+}
+
+// RateHistory returns the recorded daily rates of a currency pair since a
+// day, oldest first. Pairs only recorded the other way round are inverted.
+// Rates are mostly fetched to the users' base currencies, so pairs of two
+// other currencies are crossed through a currency both were fetched to.
+func (s *CurrencyService) RateHistory(from, to string, since time.Time) ([]RatePoint, error) {
+	if from == to {
+		return nil, nil
+	}
+
+	points, err := s.pairHistory(from, to, since)
+	if err != nil || len(points) > 0 {
+		return points, err
+	}
+
+	vias, err := s.TrackedCurrencies()
+	if err != nil {
+		return nil, err
+	}
+	for _, via := range vias {
+		if via == from || via == to {
+			continue
+		}
+		fromVia, err := s.pairHistory(from, via, since)
+		if err != nil {
+			return nil, err
+		}
+		if len(fromVia) == 0 {
+			continue
+		}
+		toVia, err := s.pairHistory(to, via, since)
+		if err != nil {
+			return nil, err
+		}
+		if len(toVia) > 0 {
+			return crossRates(fromVia, toVia), nil
+		}
+	}
+	return nil, nil
+}
+
+// pairHistory returns the recorded daily rates of a currency pair, recorded
+// either way round, since a day. Rates recorded as asked for win over
+// inverted ones of the same day.
+func (s *CurrencyService) pairHistory(from, to string, since time.Time) ([]RatePoint, error) {
+	rows, err := s.db.Query(`
+		SELECT day, rate, from_currency = ?
+		FROM currency_rate_history
+		WHERE ((from_currency = ? AND to_currency = ?) OR (from_currency = ? AND to_currency = ?))
+		  AND day >= ? AND rate > 0
+		ORDER BY day ASC
+	`, from, from, to, to, from, since.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []RatePoint
+	direct := make(map[int]bool)
+	for rows.Next() {
+		var p RatePoint
+		var isDirect bool
+		if err := rows.Scan(&p.Day, &p.Rate, &isDirect); err != nil {
+			return nil, err
+		}
+		if !isDirect {
+			p.Rate = 1 / p.Rate
+		}
+
+		// Both directions of a day are next to each other
+		if n := len(points); n > 0 && points[n-1].Day.Equal(p.Day) {
+			if isDirect && !direct[n-1] {
+				points[n-1] = p
+				direct[n-1] = true
+			}
+			continue
+		}
+		direct[len(points)] = isDirect
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// crossRates returns the rates of from to to, given the rates of both to a
+// third currency, on the days of the from rates. The to rate of a day is the
+// latest recorded on or before it; days before the first are skipped.
+func crossRates(fromVia, toVia []RatePoint) []RatePoint {
+	var points []RatePoint
+	for _, p := range fromVia {
+		// Index of the first to rate after the day
+		i := sort.Search(len(toVia), func(i int) bool { return toVia[i].Day.After(p.Day) })
+		if i == 0 {
+			continue
+		}
+		points = append(points, RatePoint{Day: p.Day, Rate: p.Rate / toVia[i-1].Rate})
+	}
+	return points
+}
+
+// RateOn returns the rate of the latest point on or before a day.
+func RateOn(points []RatePoint, day time.Time) (float64, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].Day.After(day) })
+	if i == 0 {
+		return 0, false
+	}
+	return points[i-1].Rate, true
+}
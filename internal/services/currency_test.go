@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// setupCurrencyTest returns a CurrencyService whose provider knows only
+// USD/DKK, and a user to attach manual rates to.
+func setupCurrencyTest(t *testing.T) (*CurrencyService, *database.DB, int64) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "test@example.com", PasswordHash: "x", Name: "Test"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	s := NewCurrencyService(db)
+	s.fetch = func(from, to string) (float64, error) {
+		if from == "USD" && to == "DKK" {
+			return 7, nil
+		}
+		return 0, errors.New("no rate found")
+	}
+	return s, db, userID
+}
+
+func TestConvertForUser_PrefersProviderRate(t *testing.T) {
+	s, db, userID := setupCurrencyTest(t)
+	repository.NewExchangeRateRepository(db).Create(&models.ManualExchangeRate{
+		UserID: userID, FromCurrency: "USD", ToCurrency: "DKK", Rate: 6, ValidFrom: time.Now().AddDate(0, -1, 0),
+	})
+
+	got, source := s.ConvertForUser(userID, 10, "USD", "DKK")
+	if got != 70 || source != RateSourceProvider {
+		t.Errorf("ConvertForUser() = %v, %s; want 70, provider", got, source)
+	}
+}
+
+func TestConvertForUser_UsesValidManualRate(t *testing.T) {
+	s, db, userID := setupCurrencyTest(t)
+	repo := repository.NewExchangeRateRepository(db)
+	expired := time.Now().AddDate(0, -1, 0)
+	repo.Create(&models.ManualExchangeRate{
+		UserID: userID, FromCurrency: "XYZ", ToCurrency: "DKK", Rate: 1, ValidFrom: time.Now().AddDate(-1, 0, 0), ValidTo: &expired,
+	})
+	repo.Create(&models.ManualExchangeRate{
+		UserID: userID, FromCurrency: "XYZ", ToCurrency: "DKK", Rate: 2.5, ValidFrom: time.Now().AddDate(0, 0, -7),
+	})
+
+	got, source := s.ConvertForUser(userID, 10, "XYZ", "DKK")
+	if got != 25 || source != RateSourceManual {
+		t.Errorf("ConvertForUser() = %v, %s; want 25, manual", got, source)
+	}
+
+	// The rate is inverted for the opposite direction
+	got, source = s.ConvertForUser(userID, 25, "DKK", "XYZ")
+	if got != 10 || source != RateSourceManual {
+		t.Errorf("ConvertForUser() inverse = %v, %s; want 10, manual", got, source)
+	}
+}
+
+func TestConvertForUser_FallsBackOneToOne(t *testing.T) {
+	s, db, userID := setupCurrencyTest(t)
+	// A rate that only starts in the future does not apply yet
+	repository.NewExchangeRateRepository(db).Create(&models.ManualExchangeRate{
+		UserID: userID, FromCurrency: "XYZ", ToCurrency: "DKK", Rate: 2.5, ValidFrom: time.Now().AddDate(0, 1, 0),
+	})
+
+	got, source := s.ConvertForUser(userID, 10, "XYZ", "DKK")
+	if got != 10 || source != RateSourceFallback {
+		t.Errorf("ConvertForUser() = %v, %s; want 10, fallback", got, source)
+	}
+
+	// Manual rates are per user
+	got, source = s.ConvertForUser(userID+1, 10, "XYZ", "DKK")
+	if source != RateSourceFallback {
+		t.Errorf("ConvertForUser() for another user = %v, %s; want fallback", got, source)
+	}
+}
+
+func TestGetRate_RemembersFailedFetches(t *testing.T) {
+	s, _, _ := setupCurrencyTest(t)
+	fetches := 0
+	s.fetch = func(from, to string) (float64, error) {
+		fetches++
+		return 0, errors.New("no rate found")
+	}
+
+	for range 3 {
+		if _, err := s.GetRate("XYZ", "DKK"); err == nil {
+			t.Fatal("GetRate() error = nil; want error")
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("provider asked %d times; want once while the failure is remembered", fetches)
+	}
+
+	// The pair is tried again once the failure has expired
+	s.failureAge = 0
+	s.GetRate("XYZ", "DKK")
+	if fetches != 2 {
+		t.Errorf("provider asked %d times after the failure expired; want 2", fetches)
+	}
+}
+
+func TestRateForUserOn(t *testing.T) {
+	s, db, userID := setupCurrencyTest(t)
+	if _, err := s.GetRate("USD", "DKK"); err != nil {
+		t.Fatalf("GetRate() error = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('USD', 'DKK', '2024-01-01', 6.5)`); err != nil {
+		t.Fatalf("inserting rate: %v", err)
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	if got, ok := s.RateForUserOn(userID, "USD", "DKK", day("2024-06-01")); got != 6.5 || !ok {
+		t.Errorf("RateForUserOn(2024-06-01) = %v, %v; want 6.5, true", got, ok)
+	}
+	// Fetched rates are recorded for the day they were fetched
+	if got, ok := s.RateForUserOn(userID, "USD", "DKK", time.Now()); got != 7 || !ok {
+		t.Errorf("RateForUserOn(today) = %v, %v; want 7, true", got, ok)
+	}
+	if _, ok := s.RateForUserOn(userID, "USD", "DKK", day("2023-12-31")); ok {
+		t.Error("RateForUserOn() before the first recorded rate = true; want false")
+	}
+
+	// Manual rates cover days without provider rates
+	repository.NewExchangeRateRepository(db).Create(&models.ManualExchangeRate{
+		UserID: userID, FromCurrency: "XYZ", ToCurrency: "DKK", Rate: 2.5, ValidFrom: day("2023-01-01"),
+	})
+	if got, ok := s.RateForUserOn(userID, "XYZ", "DKK", day("2023-06-01")); got != 2.5 || !ok {
+		t.Errorf("RateForUserOn() manual = %v, %v; want 2.5, true", got, ok)
+	}
+}
+
+func TestRateHistory(t *testing.T) {
+	s, db, _ := setupCurrencyTest(t)
+	for _, r := range []struct {
+		from, to, day string
+		rate          float64
+	}{
+		{"USD", "DKK", "2024-01-01", 7},
+		{"USD", "DKK", "2024-01-02", 6.8},
+		{"DKK", "USD", "2024-01-02", 0.1}, // The direct rate of the day wins
+		{"DKK", "USD", "2024-01-03", 0.125},
+		{"EUR", "DKK", "2024-01-02", 7.5},
+		{"EUR", "DKK", "2024-01-03", 7.4},
+	} {
+		if _, err := db.Exec(`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES (?, ?, ?, ?)`, r.from, r.to, r.day, r.rate); err != nil {
+			t.Fatalf("inserting rate: %v", err)
+		}
+	}
+	// Crossing goes through currencies rates were fetched for
+	if _, err := s.GetRate("USD", "DKK"); err != nil {
+		t.Fatalf("GetRate() error = %v", err)
+	}
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	points, err := s.RateHistory("USD", "DKK", since)
+	if err != nil {
+		t.Fatalf("RateHistory() error = %v", err)
+	}
+	if len(points) < 3 || points[0].Rate != 7 || points[1].Rate != 6.8 || points[2].Rate != 8 {
+		t.Errorf("RateHistory(USD, DKK) = %+v; want 7, 6.8 and the inverted 8", points)
+	}
+	if points[0].Day.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("first day = %v; want 2024-01-01", points[0].Day)
+	}
+
+	// EUR/USD is crossed through DKK from the first day both are known
+	points, err = s.RateHistory("EUR", "USD", since)
+	if err != nil {
+		t.Fatalf("RateHistory() error = %v", err)
+	}
+	if len(points) != 2 || points[0].Rate != 7.5/6.8 || points[1].Rate != 7.4/8 {
+		t.Errorf("RateHistory(EUR, USD) = %+v; want %v and %v", points, 7.5/6.8, 7.4/8)
+	}
+	if rate, ok := RateOn(points, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)); !ok || rate != 7.4/8 {
+		t.Errorf("RateOn() = %v, %v; want the latest rate", rate, ok)
+	}
+}
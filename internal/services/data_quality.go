@@ -0,0 +1,203 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Data quality checks, in the order they are shown.
+const (
+	DataQualityStaleAccount    = "stale_account"
+	DataQualityZeroAmount      = "zero_amount"
+	DataQualityBalanceMismatch = "balance_mismatch"
+	DataQualityHoldingCurrency = "holding_currency"
+	DataQualityHoldingPrice    = "holding_price"
+	DataQualityUncategorized   = "uncategorized"
+)
+
+// StaleAccountDays is how long an active account can go without
+// transactions before it is reported as stale.
+const StaleAccountDays = 90
+
+// balanceTolerance absorbs rounding when checking balances against amounts.
+const balanceTolerance = 0.01
+
+// DataQualityFinding is an anomaly in a user's data, with a link to fix it.
+type DataQualityFinding struct {
+	AccountID   int64
+	AccountName string
+	Holding     string     // Name of the holding, for holding checks
+	Count       int        // Number of transactions affected, for transaction checks
+	Date        *time.Time // Last transaction of stale accounts, first affected transaction otherwise
+	FixURL      string
+	FixLabel    string
+}
+
+// DataQualityCheck is a check with its findings.
+type DataQualityCheck struct {
+	Kind        string
+	Name        string
+	Description string
+	Findings    []DataQualityFinding
+}
+
+// DataQualityReport is the result of every data quality check of a user.
+type DataQualityReport struct {
+	Checks []DataQualityCheck
+}
+
+// FindingCount returns the number of findings across checks.
+func (r *DataQualityReport) FindingCount() int {
+	var n int
+	for _, c := range r.Checks {
+		n += len(c.Findings)
+	}
+	return n
+}
+
+// DataQualityService finds anomalies in a user's accounts, transactions and
+// holdings.
+type DataQualityService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	holdingRepo     *repository.HoldingRepository
+}
+
+// NewDataQualityService creates a new DataQualityService.
+func NewDataQualityService(accountRepo *repository.AccountRepository, transactionRepo *repository.TransactionRepository, holdingRepo *repository.HoldingRepository) *DataQualityService {
+	return &DataQualityService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
+	}
+}
+
+// Check runs every data quality check on a user's data as of now.
+func (s *DataQualityService) Check(userID int64, now time.Time) (*DataQualityReport, error) {
+	accounts, err := s.accountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+
+	checks := map[string]*DataQualityCheck{
+		DataQualityStaleAccount: {
+			Name:        "Stale accounts",
+			Description: fmt.Sprintf("Active accounts without transactions in the last %d days", StaleAccountDays),
+		},
+		DataQualityZeroAmount: {
+			Name:        "Zero amounts",
+			Description: "Transactions that neither add nor remove money",
+		},
+		DataQualityBalanceMismatch: {
+			Name:        "Inconsistent balances",
+			Description: "Balances that are not the previous balance plus the transaction amount",
+		},
+		DataQualityHoldingCurrency: {
+			Name:        "Holdings without currency",
+			Description: "Holdings whose value cannot be converted to your base currency",
+		},
+		DataQualityHoldingPrice: {
+			Name:        "Holdings without price",
+			Description: "Holdings with a quantity but no current price or value",
+		},
+		DataQualityUncategorized: {
+			Name:        "Uncategorized accounts",
+			Description: "Accounts left out of category breakdowns and allocation targets",
+		},
+	}
+	add := func(kind string, f DataQualityFinding) {
+		checks[kind].Findings = append(checks[kind].Findings, f)
+	}
+
+	staleBefore := now.AddDate(0, 0, -StaleAccountDays)
+	for _, acc := range accounts {
+		finding := DataQualityFinding{AccountID: acc.ID, AccountName: acc.Name}
+		fixURL := func(fix string) string {
+			return fmt.Sprintf("/accounts?account=%d&fix=%s", acc.ID, fix)
+		}
+		transactionsURL := fmt.Sprintf("/transactions?account=%d", acc.ID)
+
+		if acc.CategoryID == nil {
+			f := finding
+			f.FixURL, f.FixLabel = fixURL("edit"), "Set category"
+			add(DataQualityUncategorized, f)
+		}
+
+		// Transactions come latest first
+		txns, err := s.transactionRepo.GetByDateRange(acc.ID, time.Time{}, now)
+		if err != nil {
+			return nil, fmt.Errorf("getting transactions of account %d: %w", acc.ID, err)
+		}
+
+		if acc.IsActive && acc.ClosedAt == nil && (len(txns) == 0 || txns[0].TransactionDate.Before(staleBefore)) {
+			f := finding
+			if len(txns) > 0 {
+				f.Date = &txns[0].TransactionDate
+			}
+			f.FixURL, f.FixLabel = fixURL("balance"), "Update balance"
+			add(DataQualityStaleAccount, f)
+		}
+
+		zero, mismatched := finding, finding
+		for i := len(txns) - 1; i >= 0; i-- {
+			txn := txns[i]
+			if txn.Amount == 0 {
+				if zero.Count == 0 {
+					zero.Date = &txn.TransactionDate
+				}
+				zero.Count++
+			}
+			if i < len(txns)-1 && math.Abs(txns[i+1].BalanceAfter+txn.Amount-txn.BalanceAfter) > balanceTolerance {
+				if mismatched.Count == 0 {
+					mismatched.Date = &txn.TransactionDate
+				}
+				mismatched.Count++
+			}
+		}
+		if zero.Count > 0 {
+			zero.FixURL, zero.FixLabel = transactionsURL, "Review transactions"
+			add(DataQualityZeroAmount, zero)
+		}
+		if mismatched.Count > 0 {
+			mismatched.FixURL, mismatched.FixLabel = transactionsURL, "Review transactions"
+			add(DataQualityBalanceMismatch, mismatched)
+		}
+
+		holdings, err := s.holdingRepo.GetByAccountID(acc.ID)
+		if err != nil {
+			return nil, fmt.Errorf("getting holdings of account %d: %w", acc.ID, err)
+		}
+		for _, h := range holdings {
+			f := finding
+			f.Holding = holdingName(h)
+			f.FixURL, f.FixLabel = fixURL("holdings"), "Re-import holdings"
+			if h.Currency == "" {
+				add(DataQualityHoldingCurrency, f)
+			}
+			if h.Quantity != 0 && (h.CurrentPrice == 0 || h.CurrentValue == 0) {
+				add(DataQualityHoldingPrice, f)
+			}
+		}
+	}
+
+	report := &DataQualityReport{}
+	for _, kind := range []string{DataQualityStaleAccount, DataQualityZeroAmount, DataQualityBalanceMismatch, DataQualityHoldingCurrency, DataQualityHoldingPrice, DataQualityUncategorized} {
+		check := checks[kind]
+		check.Kind = kind
+		report.Checks = append(report.Checks, *check)
+	}
+	return report, nil
+}
+
+// holdingName returns the name a holding is shown by, falling back to its
+// symbol.
+func holdingName(h *models.Holding) string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Symbol
+}
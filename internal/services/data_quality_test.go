@@ -0,0 +1,91 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestDataQualityService_Check(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	result, err := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES ('quality@example.com', 'hash', 'Quality')`)
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	userID, _ := result.LastInsertId()
+
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	holdingRepo := repository.NewHoldingRepository(db)
+	categoryID, err := repository.NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Cash"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	healthyID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Healthy", Currency: "DKK", CategoryID: &categoryID, IsActive: true})
+	messyID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Messy", Currency: "DKK", IsActive: true})
+	for _, txn := range []*models.Transaction{
+		{AccountID: healthyID, Amount: 1000, BalanceAfter: 1000, TransactionDate: now.AddDate(0, 0, -10)},
+		{AccountID: healthyID, Amount: 500, BalanceAfter: 1500, TransactionDate: now.AddDate(0, 0, -5)},
+		{AccountID: messyID, Amount: 1000, BalanceAfter: 1000, TransactionDate: now.AddDate(0, -6, 0)},
+		{AccountID: messyID, Amount: 0, BalanceAfter: 1000, TransactionDate: now.AddDate(0, -5, 0)},
+		{AccountID: messyID, Amount: 200, BalanceAfter: 1500, TransactionDate: now.AddDate(0, -4, 0)}, // Should be 1200
+	} {
+		if _, err := transactionRepo.Create(txn); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: healthyID, Symbol: "SPY", Name: "S&P 500", Quantity: 1, CurrentPrice: 100, CurrentValue: 100, Currency: "USD"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: messyID, Symbol: "XYZ", Quantity: 5}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+
+	report, err := NewDataQualityService(accountRepo, transactionRepo, holdingRepo).Check(userID, now)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	want := map[string]int{
+		DataQualityStaleAccount:    1,
+		DataQualityZeroAmount:      1,
+		DataQualityBalanceMismatch: 1,
+		DataQualityHoldingCurrency: 1,
+		DataQualityHoldingPrice:    1,
+		DataQualityUncategorized:   1,
+	}
+	for _, check := range report.Checks {
+		if len(check.Findings) != want[check.Kind] {
+			t.Errorf("%s findings = %+v; want %d", check.Kind, check.Findings, want[check.Kind])
+			continue
+		}
+		for _, f := range check.Findings {
+			if f.AccountID != messyID {
+				t.Errorf("%s finding for account %d; want only the messy account", check.Kind, f.AccountID)
+			}
+			if f.FixURL == "" {
+				t.Errorf("%s finding has no fix link", check.Kind)
+			}
+		}
+	}
+	if report.FindingCount() != 6 {
+		t.Errorf("FindingCount() = %d; want 6", report.FindingCount())
+	}
+	mismatch := report.Checks[2].Findings[0]
+	if mismatch.Count != 1 || !mismatch.Date.Equal(now.AddDate(0, -4, 0)) {
+		t.Errorf("balance mismatch = %d from %v; want 1 from the last transaction", mismatch.Count, mismatch.Date)
+	}
+}
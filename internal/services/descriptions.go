@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/money"
+)
+
+// Default descriptions of system-generated transactions, used when the user
+// has not set a template.
+const (
+	DefaultBalanceDescription = "Balance update"
+	DefaultSyncDescription    = "{broker} sync"
+)
+
+// MaxDescriptionTemplateLength bounds description templates.
+const MaxDescriptionTemplateLength = 100
+
+// DescriptionPlaceholders lists the placeholders description templates may
+// use, with what they are replaced by.
+var DescriptionPlaceholders = []struct {
+	Name        string
+	Description string
+}{
+	{"{broker}", "Broker name, for synced balances"},
+	{"{date}", "Date of the balance, as YYYY-MM-DD"},
+	{"{delta}", "Change in balance, with sign"},
+	{"{balance}", "New balance"},
+}
+
+// descriptionPlaceholderPattern matches anything that looks like a placeholder.
+var descriptionPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// DescriptionValues are the values a description template is filled with.
+type DescriptionValues struct {
+	Broker   string
+	Date     time.Time
+	Delta    float64
+	Balance  float64
+	Currency string // Sets the decimals of amounts; optional
+}
+
+// ValidateDescriptionTemplate returns an error if the template is too long or
+// uses an unknown placeholder. An empty template selects the default.
+func ValidateDescriptionTemplate(tmpl string) error {
+	if len(tmpl) > MaxDescriptionTemplateLength {
+		return fmt.Errorf("description templates can be at most %d characters", MaxDescriptionTemplateLength)
+	}
+	for _, placeholder := range descriptionPlaceholderPattern.FindAllString(tmpl, -1) {
+		if !isDescriptionPlaceholder(placeholder) {
+			return fmt.Errorf("unknown placeholder %s", placeholder)
+		}
+	}
+	return nil
+}
+
+// RenderDescription fills a description template, or fallback if the
+// template is empty. Amounts use the user's number format; user may be nil.
+func RenderDescription(tmpl, fallback string, values DescriptionValues, user *models.User) string {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = fallback
+	}
+
+	format, hideDecimals := "da", false
+	if user != nil {
+		format, hideDecimals = user.NumberFormat, user.HideDecimals
+	}
+	delta := money.FormatAmount(values.Delta, values.Currency, format, hideDecimals)
+	if values.Delta > 0 {
+		delta = "+" + delta
+	}
+
+	return strings.NewReplacer(
+		"{broker}", values.Broker,
+		"{date}", values.Date.Format("2006-01-02"),
+		"{delta}", delta,
+		"{balance}", money.FormatAmount(values.Balance, values.Currency, format, hideDecimals),
+	).Replace(tmpl)
+}
+
+// BalanceDescription returns the description of a manual balance update.
+func BalanceDescription(user *models.User, values DescriptionValues) string {
+	tmpl := ""
+	if user != nil {
+		tmpl = user.BalanceDescription
+	}
+	return RenderDescription(tmpl, DefaultBalanceDescription, values, user)
+}
+
+// SyncDescription returns the description of a balance recorded by a broker
+// sync.
+func SyncDescription(user *models.User, values DescriptionValues) string {
+	tmpl := ""
+	if user != nil {
+		tmpl = user.SyncDescription
+	}
+	return RenderDescription(tmpl, DefaultSyncDescription, values, user)
+}
+
+// isDescriptionPlaceholder returns true if placeholder is a known placeholder.
+func isDescriptionPlaceholder(placeholder string) bool {
+	for _, p := range DescriptionPlaceholders {
+		if p.Name == placeholder {
+			return true
+		}
+	}
+	return false
+}
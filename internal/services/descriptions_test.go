@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestRenderDescription(t *testing.T) {
+	values := DescriptionValues{Broker: "Nordnet", Date: time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC), Delta: 1234.5, Balance: 25000, Currency: "DKK"}
+	en := &models.User{NumberFormat: "en"}
+	tests := []struct {
+		name string
+		tmpl string
+		user *models.User
+		want string
+	}{
+		{"default", "", nil, "Nordnet sync"},
+		{"placeholders", "{broker} {date}: {delta} → {balance}", en, "Nordnet 2024-03-05: +1,234.50 → 25,000.00"},
+		{"user number format", "Kursregulering {delta}", &models.User{NumberFormat: "da", HideDecimals: true}, "Kursregulering +1.235"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderDescription(tt.tmpl, DefaultSyncDescription, values, tt.user); got != tt.want {
+				t.Errorf("RenderDescription(%q) = %q; want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+
+	values.Delta = -500
+	if got := BalanceDescription(&models.User{NumberFormat: "en", BalanceDescription: "Adjusted {delta}"}, values); got != "Adjusted -500.00" {
+		t.Errorf("BalanceDescription() = %q; want negative delta without plus", got)
+	}
+}
+
+func TestValidateDescriptionTemplate(t *testing.T) {
+	for _, tmpl := range []string{"", "Balance update", "{broker} sync {date}", "Sync {delta} to {balance}"} {
+		if err := ValidateDescriptionTemplate(tmpl); err != nil {
+			t.Errorf("ValidateDescriptionTemplate(%q) error = %v", tmpl, err)
+		}
+	}
+	for _, tmpl := range []string{"{account} sync", "{Broker}", string(make([]byte, MaxDescriptionTemplateLength+1))} {
+		if err := ValidateDescriptionTemplate(tmpl); err == nil {
+			t.Errorf("ValidateDescriptionTemplate(%q) = nil; want error", tmpl)
+		}
+	}
+}
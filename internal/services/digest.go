@@ -0,0 +1,369 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"text/template"
+	"time"
+
+	"wealth_tracker/internal/dates"
+	"wealth_tracker/internal/mail"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/money"
+	"wealth_tracker/internal/repository"
+)
+
+const (
+	// maxDigestMovers is the number of accounts listed as biggest movers.
+	maxDigestMovers = 3
+
+	// digestDeadlineDays is how far ahead goal deadlines are listed.
+	digestDeadlineDays = 30
+)
+
+//go:embed templates/digest.txt
+var digestTemplateText string
+
+// DigestSnapshot holds the figures a digest reported. It is stored with the
+// digest so the next one can show what changed since.
+type DigestSnapshot struct {
+	NetWorth float64                       `json:"net_worth"`
+	Accounts map[int64]DigestAccountFigure `json:"accounts"`
+	Goals    map[int64]DigestGoalFigure    `json:"goals"`
+}
+
+// DigestAccountFigure is an account's contribution to net worth in the
+// user's currency; liabilities are negative.
+type DigestAccountFigure struct {
+	Name    string  `json:"name"`
+	Balance float64 `json:"balance"`
+}
+
+// DigestGoalFigure is a goal's progress, in percent.
+type DigestGoalFigure struct {
+	Name     string     `json:"name"`
+	Progress float64    `json:"progress"`
+	Reached  bool       `json:"reached"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// DigestMover is an account whose balance changed since the previous digest.
+type DigestMover struct {
+	Name    string
+	Change  float64
+	Balance float64
+}
+
+// DigestGoalChange is a goal whose progress changed since the previous digest.
+type DigestGoalChange struct {
+	Name         string
+	Before       float64
+	After        float64
+	NewlyReached bool
+}
+
+// DigestDeadline is an unreached goal with a deadline coming up.
+type DigestDeadline struct {
+	Name     string
+	Deadline time.Time
+	DaysLeft int
+	Progress float64
+}
+
+// Digest is the content of a digest email.
+type Digest struct {
+	User            *models.User
+	Frequency       string
+	Since           *time.Time // When the previous digest was sent; nil for the first
+	Snapshot        DigestSnapshot
+	HasPrevious     bool
+	NetWorthDelta   float64
+	NewTransactions int
+	Movers          []DigestMover
+	GoalChanges     []DigestGoalChange
+	Deadlines       []DigestDeadline
+}
+
+// DigestDue returns true if a digest with the given frequency is due at now,
+// given when the last one was sent. Digests are due from the start of the
+// day a week or a month after the last one, so the hour they are sent at
+// does not drift. Monthly digests of users whose statement periods start on
+// a later day than the 1st are due when the next period starts, so they
+// cover whole periods from payday to payday.
+func DigestDue(frequency string, lastSent *time.Time, now time.Time, periodStartDay int) bool {
+	if frequency != models.DigestWeekly && frequency != models.DigestMonthly {
+		return false
+	}
+	if lastSent == nil {
+		return true
+	}
+	last := lastSent.In(now.Location())
+	next := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case frequency == models.DigestWeekly:
+		next = next.AddDate(0, 0, 7)
+	case periodStartDay > 1:
+		next = dates.PeriodStart(next, periodStartDay).AddDate(0, 1, 0)
+	default:
+		next = next.AddDate(0, 1, 0)
+	}
+	return !now.Before(next)
+}
+
+// NewDigest compares the current figures with those of the previous digest,
+// which may be nil.
+func NewDigest(user *models.User, previous *DigestSnapshot, since *time.Time, current DigestSnapshot, newTransactions int, now time.Time) *Digest {
+	d := &Digest{
+		User:            user,
+		Frequency:       user.DigestFrequency,
+		Since:           since,
+		Snapshot:        current,
+		HasPrevious:     previous != nil,
+		NewTransactions: newTransactions,
+	}
+
+	if previous != nil {
+		d.NetWorthDelta = current.NetWorth - previous.NetWorth
+
+		for id, account := range current.Accounts {
+			change := account.Balance
+			if before, ok := previous.Accounts[id]; ok {
+				change -= before.Balance
+			}
+			if math.Abs(change) >= 0.005 {
+				d.Movers = append(d.Movers, DigestMover{Name: account.Name, Change: change, Balance: account.Balance})
+			}
+		}
+		sort.Slice(d.Movers, func(i, j int) bool {
+			if a, b := math.Abs(d.Movers[i].Change), math.Abs(d.Movers[j].Change); a != b {
+				return a > b
+			}
+			return d.Movers[i].Name < d.Movers[j].Name
+		})
+		if len(d.Movers) > maxDigestMovers {
+			d.Movers = d.Movers[:maxDigestMovers]
+		}
+
+		for id, goal := range current.Goals {
+			before, ok := previous.Goals[id]
+			if !ok || math.Abs(goal.Progress-before.Progress) < 0.05 && goal.Reached == before.Reached {
+				continue
+			}
+			d.GoalChanges = append(d.GoalChanges, DigestGoalChange{
+				Name:         goal.Name,
+				Before:       before.Progress,
+				After:        goal.Progress,
+				NewlyReached: goal.Reached && !before.Reached,
+			})
+		}
+		sort.Slice(d.GoalChanges, func(i, j int) bool { return d.GoalChanges[i].Name < d.GoalChanges[j].Name })
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, goal := range current.Goals {
+		if goal.Reached || goal.Deadline == nil {
+			continue
+		}
+		deadline := time.Date(goal.Deadline.Year(), goal.Deadline.Month(), goal.Deadline.Day(), 0, 0, 0, 0, time.UTC)
+		daysLeft := int(deadline.Sub(today).Hours() / 24)
+		if daysLeft < 0 || daysLeft > digestDeadlineDays {
+			continue
+		}
+		d.Deadlines = append(d.Deadlines, DigestDeadline{Name: goal.Name, Deadline: deadline, DaysLeft: daysLeft, Progress: goal.Progress})
+	}
+	sort.Slice(d.Deadlines, func(i, j int) bool { return d.Deadlines[i].Deadline.Before(d.Deadlines[j].Deadline) })
+
+	return d
+}
+
+// RenderDigest returns the subject and plain-text body of a digest email.
+// Amounts use the user's number format.
+func RenderDigest(d *Digest) (subject, body string, err error) {
+	format, hideDecimals, currency := d.User.NumberFormat, d.User.HideDecimals, d.User.DefaultCurrency
+	amount := func(n float64) string { return money.FormatAmount(n, currency, format, hideDecimals) }
+	tmpl, err := template.New("digest").Funcs(template.FuncMap{
+		"amount": amount,
+		"signed": func(n float64) string {
+			if n > 0 {
+				return "+" + amount(n)
+			}
+			return amount(n)
+		},
+		"percent": func(n float64) string { return fmt.Sprintf("%.0f%%", n) },
+	}).Parse(digestTemplateText)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing digest template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return "", "", fmt.Errorf("rendering digest: %w", err)
+	}
+	return fmt.Sprintf("Your %s wealth digest", d.Frequency), buf.String(), nil
+}
+
+// DigestService sends users their email digest.
+type DigestService struct {
+	userRepo        *repository.UserRepository
+	netWorth        *NetWorthService
+	transactionRepo *repository.TransactionRepository
+	goalRepo        *repository.GoalRepository
+	digestRepo      *repository.EmailDigestRepository
+	sender          mail.Sender
+}
+
+// NewDigestService creates a new DigestService.
+func NewDigestService(
+	userRepo *repository.UserRepository,
+	netWorth *NetWorthService,
+	transactionRepo *repository.TransactionRepository,
+	goalRepo *repository.GoalRepository,
+	digestRepo *repository.EmailDigestRepository,
+	sender mail.Sender,
+) *DigestService {
+	return &DigestService{
+		userRepo:        userRepo,
+		netWorth:        netWorth,
+		transactionRepo: transactionRepo,
+		goalRepo:        goalRepo,
+		digestRepo:      digestRepo,
+		sender:          sender,
+	}
+}
+
+// SendDue sends the digests that are due at now and returns how many were
+// sent. A failure for one user is logged and does not stop the others.
+func (s *DigestService) SendDue(now time.Time) (int, error) {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("getting users: %w", err)
+	}
+
+	sent := 0
+	for _, user := range users {
+		if user.DigestFrequency != models.DigestWeekly && user.DigestFrequency != models.DigestMonthly {
+			continue
+		}
+		ok, err := s.sendIfDue(user, now)
+		if err != nil {
+			log.Printf("[Digest] Sending digest to user %d failed: %v", user.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendIfDue sends and records the user's digest if it is due.
+func (s *DigestService) sendIfDue(user *models.User, now time.Time) (bool, error) {
+	last, err := s.digestRepo.GetLatest(user.ID)
+	if err != nil {
+		return false, fmt.Errorf("getting last digest: %w", err)
+	}
+
+	var previous *DigestSnapshot
+	var since *time.Time
+	if last != nil {
+		if !DigestDue(user.DigestFrequency, &last.SentAt, now, user.PeriodStartDay) {
+			return false, nil
+		}
+		previous = &DigestSnapshot{}
+		if err := json.Unmarshal([]byte(last.Snapshot), previous); err != nil {
+			log.Printf("[Digest] Ignoring unreadable snapshot of digest %d: %v", last.ID, err)
+			previous = nil
+		}
+		since = &last.SentAt
+	}
+
+	current, err := s.Snapshot(user.ID)
+	if err != nil {
+		return false, fmt.Errorf("taking snapshot: %w", err)
+	}
+
+	// The first digest counts the transactions of one period
+	countFrom := now.AddDate(0, 0, -7)
+	if user.DigestFrequency == models.DigestMonthly {
+		countFrom = now.AddDate(0, -1, 0)
+	}
+	if since != nil {
+		countFrom = *since
+	}
+	newTransactions, err := s.transactionRepo.CountCreatedSince(user.ID, countFrom)
+	if err != nil {
+		return false, fmt.Errorf("counting transactions: %w", err)
+	}
+
+	subject, body, err := RenderDigest(NewDigest(user, previous, since, current, newTransactions, now))
+	if err != nil {
+		return false, err
+	}
+	snapshot, err := json.Marshal(current)
+	if err != nil {
+		return false, fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	// Record the digest before sending it, so another instance finding it
+	// due at the same time does not send it too
+	var lastID int64
+	if last != nil {
+		lastID = last.ID
+	}
+	id, claimed, err := s.digestRepo.Claim(&models.EmailDigest{UserID: user.ID, SentAt: now, Snapshot: string(snapshot)}, lastID)
+	if err != nil {
+		return false, fmt.Errorf("recording digest: %w", err)
+	}
+	if !claimed {
+		return false, nil
+	}
+	if err := s.sender.Send(mail.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		if err := s.digestRepo.Delete(id); err != nil {
+			log.Printf("[Digest] Removing the record of unsent digest %d failed: %v", id, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Snapshot returns the user's current net worth, account balances and goal
+// progress, as the dashboard shows them.
+func (s *DigestService) Snapshot(userID int64) (DigestSnapshot, error) {
+	snapshot := DigestSnapshot{
+		Accounts: make(map[int64]DigestAccountFigure),
+		Goals:    make(map[int64]DigestGoalFigure),
+	}
+
+	netWorth, err := s.netWorth.Compute(userID, nil)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.NetWorth = netWorth.NetWorth
+	for _, a := range netWorth.Accounts {
+		snapshot.Accounts[a.Account.ID] = DigestAccountFigure{Name: a.Account.Name, Balance: a.Worth}
+	}
+
+	goals, err := s.goalRepo.GetByUserID(userID)
+	if err != nil {
+		return snapshot, fmt.Errorf("getting goals: %w", err)
+	}
+	for _, goal := range goals {
+		worth := netWorth.ForGoal(goal)
+		progress := 0.0
+		if goal.TargetAmount > 0 {
+			progress = math.Min(worth/goal.TargetAmount*100, 100)
+		}
+		snapshot.Goals[goal.ID] = DigestGoalFigure{
+			Name:     goal.Name,
+			Progress: progress,
+			Reached:  goal.ReachedDate != nil || worth >= goal.TargetAmount,
+			Deadline: goal.Deadline,
+		}
+	}
+	return snapshot, nil
+}
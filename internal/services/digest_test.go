@@ -0,0 +1,200 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/mail"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestDigestDue(t *testing.T) {
+	last := time.Date(2024, 3, 4, 7, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		frequency string
+		lastSent  *time.Time
+		now       time.Time
+		startDay  int
+		want      bool
+	}{
+		{"off", models.DigestOff, nil, last, 1, false},
+		{"first weekly", models.DigestWeekly, nil, last, 1, true},
+		{"weekly too early", models.DigestWeekly, &last, time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC), 1, false},
+		{"weekly at start of day", models.DigestWeekly, &last, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), 1, true},
+		{"monthly too early", models.DigestMonthly, &last, time.Date(2024, 4, 3, 12, 0, 0, 0, time.UTC), 1, false},
+		{"monthly", models.DigestMonthly, &last, time.Date(2024, 4, 4, 6, 0, 0, 0, time.UTC), 1, true},
+		{"monthly before payday", models.DigestMonthly, &last, time.Date(2024, 3, 24, 12, 0, 0, 0, time.UTC), 25, false},
+		{"monthly on payday", models.DigestMonthly, &last, time.Date(2024, 3, 25, 6, 0, 0, 0, time.UTC), 25, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DigestDue(tt.frequency, tt.lastSent, tt.now, tt.startDay); got != tt.want {
+				t.Errorf("DigestDue() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDigest_ComparesWithPrevious(t *testing.T) {
+	now := time.Date(2024, 3, 11, 7, 0, 0, 0, time.UTC)
+	soon := now.AddDate(0, 0, 10)
+	later := now.AddDate(0, 3, 0)
+	previous := &DigestSnapshot{
+		NetWorth: 100000,
+		Accounts: map[int64]DigestAccountFigure{
+			1: {Name: "Depot", Balance: 80000},
+			2: {Name: "Savings", Balance: 30000},
+			3: {Name: "Loan", Balance: -10000},
+		},
+		Goals: map[int64]DigestGoalFigure{
+			1: {Name: "House", Progress: 50},
+			2: {Name: "Buffer", Progress: 95},
+		},
+	}
+	current := DigestSnapshot{
+		NetWorth: 112000,
+		Accounts: map[int64]DigestAccountFigure{
+			1: {Name: "Depot", Balance: 90000},
+			2: {Name: "Savings", Balance: 30000},
+			3: {Name: "Loan", Balance: -9000},
+			4: {Name: "Crypto", Balance: 2000},
+		},
+		Goals: map[int64]DigestGoalFigure{
+			1: {Name: "House", Progress: 56, Deadline: &later},
+			2: {Name: "Buffer", Progress: 100, Reached: true, Deadline: &soon},
+			3: {Name: "Car", Progress: 20, Deadline: &soon},
+		},
+	}
+
+	d := NewDigest(&models.User{DigestFrequency: models.DigestWeekly}, previous, &now, current, 4, now)
+
+	if d.NetWorthDelta != 12000 {
+		t.Errorf("NetWorthDelta = %.0f; want 12000", d.NetWorthDelta)
+	}
+	var movers []string
+	for _, m := range d.Movers {
+		movers = append(movers, m.Name)
+	}
+	if got := strings.Join(movers, ","); got != "Depot,Crypto,Loan" {
+		t.Errorf("movers = %s; want Depot,Crypto,Loan", got)
+	}
+	if len(d.GoalChanges) != 2 || d.GoalChanges[0].Name != "Buffer" || !d.GoalChanges[0].NewlyReached || d.GoalChanges[1].After != 56 {
+		t.Errorf("goal changes = %+v; want Buffer reached and House at 56%%", d.GoalChanges)
+	}
+	if len(d.Deadlines) != 1 || d.Deadlines[0].Name != "Car" || d.Deadlines[0].DaysLeft != 10 {
+		t.Errorf("deadlines = %+v; want Car in 10 days", d.Deadlines)
+	}
+}
+
+// recordingSender records the messages it is asked to send.
+type recordingSender struct {
+	sent []mail.Message
+}
+
+func (s *recordingSender) Send(msg mail.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestDigestService_SendDue(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	sender := &recordingSender{}
+	netWorth := NewNetWorthService(userRepo, accountRepo, transactionRepo, nil)
+	s := NewDigestService(userRepo, netWorth, transactionRepo, repository.NewGoalRepository(db), repository.NewEmailDigestRepository(db), sender)
+
+	userID, err := userRepo.Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", NumberFormat: "en"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	if _, err := userRepo.Create(&models.User{Email: "off@example.com", PasswordHash: "x", Name: "Off"}); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	user, _ := userRepo.GetByID(userID)
+	user.DigestFrequency = models.DigestWeekly
+	if err := userRepo.Update(user); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+	accountID, err := accountRepo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	addBalance := func(balance float64) {
+		t.Helper()
+		if _, err := transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: balance, BalanceAfter: balance, TransactionDate: time.Now()}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+	addBalance(1000)
+
+	now := time.Now()
+	if sent, err := s.SendDue(now); err != nil || sent != 1 {
+		t.Fatalf("first SendDue() = %d, %v; want 1 digest", sent, err)
+	}
+	if sent, _ := s.SendDue(now.Add(time.Hour)); sent != 0 {
+		t.Errorf("SendDue() an hour later sent %d digests; want none", sent)
+	}
+
+	addBalance(1500)
+	if sent, err := s.SendDue(now.AddDate(0, 0, 8)); err != nil || sent != 1 {
+		t.Fatalf("second SendDue() = %d, %v; want 1 digest", sent, err)
+	}
+	if len(sender.sent) != 2 || sender.sent[1].To != "user@example.com" {
+		t.Fatalf("sent %+v; want two digests to user@example.com", sender.sent)
+	}
+	body := sender.sent[1].Body
+	for _, want := range []string{"Net worth: 1,500.00 DKK (+500.00)", "Savings: +500.00 to 1,500.00"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("second digest does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestDigestService_SnapshotConvertsLikeTheDashboard(t *testing.T) {
+	currency, db, userID := setupCurrencyTest(t)
+	userRepo := repository.NewUserRepository(db)
+	user, _ := userRepo.GetByID(userID)
+	user.DefaultCurrency = "DKK"
+	if err := userRepo.Update(user); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	goalRepo := repository.NewGoalRepository(db)
+	accountID, err := accountRepo.Create(&models.Account{UserID: userID, Name: "Depot", Currency: "USD", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if _, err := transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: 100, BalanceAfter: 100, TransactionDate: time.Now()}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	goalID, err := goalRepo.Create(&models.Goal{UserID: userID, Name: "House", TargetAmount: 1400, TargetCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating goal: %v", err)
+	}
+
+	s := NewDigestService(userRepo, NewNetWorthService(userRepo, accountRepo, transactionRepo, currency), transactionRepo, goalRepo, repository.NewEmailDigestRepository(db), &recordingSender{})
+	snapshot, err := s.Snapshot(userID)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	// 100 USD is 700 DKK, half the goal
+	if snapshot.NetWorth != 700 || snapshot.Accounts[accountID].Balance != 700 || snapshot.Goals[goalID].Progress != 50 {
+		t.Errorf("Snapshot() = %+v; want the depot converted to 700 DKK and the goal half way", snapshot)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"strings"
+
+	"wealth_tracker/internal/repository"
+)
+
+// SetExclusionRepository enables leaving the user's excluded instruments out
+// of composition and rebalancing.
+func (s *PortfolioService) SetExclusionRepository(exclusionRepo *repository.AnalyticsExclusionRepository) {
+	s.exclusionRepo = exclusionRepo
+}
+
+// NormalizeExclusionSymbol returns the form an ISIN or ticker is excluded
+// and matched in, so "us0378331005" excludes holdings of US0378331005.
+func NormalizeExclusionSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// excludedSymbols returns the normalized symbols the user excluded from
+// analytics, or nil if the repository is not set.
+func (s *PortfolioService) excludedSymbols(userID int64) (map[string]bool, error) {
+	if s.exclusionRepo == nil {
+		return nil, nil
+	}
+	exclusions, err := s.exclusionRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool, len(exclusions))
+	for _, e := range exclusions {
+		excluded[NormalizeExclusionSymbol(e.Symbol)] = true
+	}
+	return excluded, nil
+}
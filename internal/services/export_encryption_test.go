@@ -0,0 +1,49 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptExport_RoundTrip(t *testing.T) {
+	plaintext := []byte(`{"accounts":[{"name":"Savings"}]}`)
+	encrypted, err := EncryptExport(plaintext, "correct horse battery")
+	if err != nil {
+		t.Fatalf("EncryptExport() error = %v", err)
+	}
+	if !IsEncryptedExport(encrypted) || bytes.Contains(encrypted, []byte("Savings")) {
+		t.Fatal("EncryptExport() output is not an encrypted export")
+	}
+
+	got, err := DecryptExport(encrypted, "correct horse battery")
+	if err != nil {
+		t.Fatalf("DecryptExport() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptExport() = %s; want %s", got, plaintext)
+	}
+}
+
+func TestEncryptExport_RejectsShortPassphrase(t *testing.T) {
+	if _, err := EncryptExport([]byte("{}"), "short"); err != ErrExportPassphraseShort {
+		t.Errorf("EncryptExport() error = %v; want %v", err, ErrExportPassphraseShort)
+	}
+}
+
+func TestDecryptExport_WrongPassphraseOrTampered(t *testing.T) {
+	encrypted, err := EncryptExport([]byte("{}"), "correct horse battery")
+	if err != nil {
+		t.Fatalf("EncryptExport() error = %v", err)
+	}
+	if _, err := DecryptExport(encrypted, "wrong horse battery"); err != ErrExportPassphrase {
+		t.Errorf("DecryptExport() with wrong passphrase error = %v; want %v", err, ErrExportPassphrase)
+	}
+
+	encrypted[len(encrypted)-1] ^= 1
+	if _, err := DecryptExport(encrypted, "correct horse battery"); err != ErrExportPassphrase {
+		t.Errorf("DecryptExport() of tampered file error = %v; want %v", err, ErrExportPassphrase)
+	}
+	if _, err := DecryptExport([]byte("{}"), "correct horse battery"); err != ErrExportNotEncrypted {
+		t.Errorf("DecryptExport() of plain JSON error = %v; want %v", err, ErrExportNotEncrypted)
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// ErrUnknownFeature is returned for a feature name that is not in Features.
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// Feature is an experimental feature that admins turn on for everyone or
+// for single users.
+type Feature struct {
+	Name        string
+	Description string
+}
+
+// Features are the features behind flags. List a feature here only with the
+// RequireFeature route or feature template check that it switches, and
+// remove it, and its checks, once it is released to everyone. There are
+// none at the moment.
+var Features = []Feature{}
+
+// UnknownFeatures returns the names that are not in Features.
+func UnknownFeatures(names []string) []string {
+	var unknown []string
+	for _, name := range names {
+		if findFeature(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// findFeature returns the feature of a name, or nil if there is none.
+func findFeature(name string) *Feature {
+	for i := range Features {
+		if Features[i].Name == name {
+			return &Features[i]
+		}
+	}
+	return nil
+}
+
+// FeatureStatus is how a feature is set, for the admin page.
+type FeatureStatus struct {
+	Feature
+	Default  bool                  // On without a setting for everyone, from FEATURE_FLAGS
+	Everyone *bool                 // Admin setting for everyone; nil uses the default
+	Users    []*models.FeatureFlag // Settings of single users, which win
+}
+
+// Enabled returns whether the feature is on for everyone.
+func (s FeatureStatus) Enabled() bool {
+	if s.Everyone != nil {
+		return *s.Everyone
+	}
+	return s.Default
+}
+
+// State returns the setting for everyone as "on", "off" or "default".
+func (s FeatureStatus) State() string {
+	switch {
+	case s.Everyone == nil:
+		return "default"
+	case *s.Everyone:
+		return "on"
+	}
+	return "off"
+}
+
+// FeatureFlagService decides which experimental features are on for a user.
+// A user's own setting wins over the setting for everyone, which wins over
+// the instance's default. Settings are read on every check, so changes
+// apply at once on all instances.
+type FeatureFlagService struct {
+	repo     *repository.FeatureFlagRepository
+	defaults map[string]bool
+}
+
+// NewFeatureFlagService creates a new FeatureFlagService. defaults are the
+// features on unless admins set them otherwise, such as those dark-launched
+// on the demo instance, where the admin panel is disabled.
+func NewFeatureFlagService(repo *repository.FeatureFlagRepository, defaults []string) *FeatureFlagService {
+	s := &FeatureFlagService{repo: repo, defaults: make(map[string]bool, len(defaults))}
+	for _, name := range defaults {
+		s.defaults[name] = true
+	}
+	return s
+}
+
+// Enabled reports whether a feature is on for a user, or for everyone if
+// user is nil. Features stay off if their settings cannot be read.
+func (s *FeatureFlagService) Enabled(name string, user *models.User) bool {
+	var userID int64
+	if user != nil {
+		userID = user.ID
+	}
+	flags, err := s.repo.GetForUser(userID)
+	if err != nil {
+		log.Printf("[Features] Error loading feature flags: %v", err)
+		return false
+	}
+
+	enabled := s.defaults[name]
+	for _, flag := range flags {
+		if flag.Name != name {
+			continue
+		}
+		if flag.UserID != nil {
+			return flag.Enabled
+		}
+		enabled = flag.Enabled
+	}
+	return enabled
+}
+
+// Statuses returns the settings of every feature, in the order of Features.
+func (s *FeatureFlagService) Statuses() ([]FeatureStatus, error) {
+	flags, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]FeatureStatus, len(Features))
+	for i, feature := range Features {
+		statuses[i] = FeatureStatus{Feature: feature, Default: s.defaults[feature.Name]}
+		for _, flag := range flags {
+			if flag.Name != feature.Name {
+				continue
+			}
+			if flag.UserID == nil {
+				statuses[i].Everyone = &flag.Enabled
+			} else {
+				statuses[i].Users = append(statuses[i].Users, flag)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// SetForEveryone turns a feature on or off for everyone, or with a nil
+// enabled returns it to the instance's default.
+func (s *FeatureFlagService) SetForEveryone(name string, enabled *bool) error {
+	if findFeature(name) == nil {
+		return ErrUnknownFeature
+	}
+	if enabled == nil {
+		return s.repo.Delete(name, nil)
+	}
+	return s.repo.Set(name, nil, *enabled)
+}
+
+// SetForUser turns a feature on or off for a user, whatever it is for
+// everyone, or with a nil enabled removes the user's setting.
+func (s *FeatureFlagService) SetForUser(name string, userID int64, enabled *bool) error {
+	if findFeature(name) == nil {
+		return ErrUnknownFeature
+	}
+	if enabled == nil {
+		return s.repo.Delete(name, &userID)
+	}
+	return s.repo.Set(name, &userID, *enabled)
+}
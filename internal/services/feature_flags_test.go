@@ -0,0 +1,101 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// withFeatures replaces Features for the duration of a test.
+func withFeatures(t *testing.T, names ...string) {
+	t.Helper()
+	saved := Features
+	t.Cleanup(func() { Features = saved })
+	Features = nil
+	for _, name := range names {
+		Features = append(Features, Feature{Name: name, Description: name})
+	}
+}
+
+func TestFeatureFlagService_Enabled(t *testing.T) {
+	withFeatures(t, "analyzer", "trades_import")
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	userRepo := repository.NewUserRepository(db)
+	var users []*models.User
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		user := &models.User{Email: email, PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"}
+		if user.ID, err = userRepo.Create(user); err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		users = append(users, user)
+	}
+	alice, bob := users[0], users[1]
+	service := NewFeatureFlagService(repository.NewFeatureFlagRepository(db), []string{"trades_import"})
+
+	check := func(step, name string, user *models.User, want bool) {
+		t.Helper()
+		if got := service.Enabled(name, user); got != want {
+			t.Errorf("%s: Enabled(%s, %v) = %v; want %v", step, name, user != nil, got, want)
+		}
+	}
+	on, off := true, false
+
+	// Without settings, the instance's defaults apply
+	check("defaults", "analyzer", alice, false)
+	check("defaults", "trades_import", alice, true)
+	check("defaults", "trades_import", nil, true)
+
+	// A user's setting wins over the setting for everyone
+	if err := service.SetForUser("analyzer", alice.ID, &on); err != nil {
+		t.Fatalf("SetForUser: %v", err)
+	}
+	check("on for alice", "analyzer", alice, true)
+	check("on for alice", "analyzer", bob, false)
+
+	if err := service.SetForEveryone("analyzer", &on); err != nil {
+		t.Fatalf("SetForEveryone: %v", err)
+	}
+	if err := service.SetForUser("analyzer", alice.ID, &off); err != nil {
+		t.Fatalf("SetForUser: %v", err)
+	}
+	check("on for everyone but alice", "analyzer", alice, false)
+	check("on for everyone but alice", "analyzer", bob, true)
+	check("on for everyone but alice", "analyzer", nil, true)
+
+	// Removing the settings returns to the defaults
+	if err := service.SetForUser("analyzer", alice.ID, nil); err != nil {
+		t.Fatalf("SetForUser: %v", err)
+	}
+	if err := service.SetForEveryone("analyzer", nil); err != nil {
+		t.Fatalf("SetForEveryone: %v", err)
+	}
+	if err := service.SetForEveryone("trades_import", &off); err != nil {
+		t.Fatalf("SetForEveryone: %v", err)
+	}
+	check("cleared", "analyzer", alice, false)
+	check("default turned off", "trades_import", bob, false)
+
+	statuses, err := service.Statuses()
+	if err != nil || len(statuses) != len(Features) {
+		t.Fatalf("Statuses() = %d, %v; want %d", len(statuses), err, len(Features))
+	}
+	for _, status := range statuses {
+		if status.Name == "trades_import" && (!status.Default || status.Enabled() || len(status.Users) != 0) {
+			t.Errorf("status of %s = %+v; want on by default, turned off for everyone", status.Name, status)
+		}
+	}
+
+	if err := service.SetForEveryone("no_such_feature", &on); err != ErrUnknownFeature {
+		t.Errorf("SetForEveryone(unknown) = %v; want ErrUnknownFeature", err)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// maxGoalImportCount limits the number of goals in a single import.
+const maxGoalImportCount = 500
+
+// GoalProgressPoint is the value counted towards a goal at the end of a month.
+type GoalProgressPoint struct {
+	Date     string  `json:"date"` // YYYY-MM-DD, the last day of the month or today
+	Value    float64 `json:"value"`
+	Progress float64 `json:"progress"` // 0-100
+}
+
+// GoalExport is a goal as written to the JSON export. The category is
+// referenced by name so goals can be imported on another instance.
+type GoalExport struct {
+	*models.Goal
+	CategoryName    string              `json:"category_name,omitempty"`
+	ProgressHistory []GoalProgressPoint `json:"progress_history,omitempty"`
+}
+
+// GoalProgressHistory computes the month-end progress of a goal from the
+// balance history of the user's accounts. Net worth goals count all accounts;
+// category goals only the category's accounts. Liabilities are subtracted.
+func GoalProgressHistory(goal *models.Goal, accounts []*models.Account, history map[int64][]repository.BalancePoint, now time.Time) []GoalProgressPoint {
+	points := combineHistories(accounts, history, func(acc *models.Account) bool {
+		return goal.CategoryID == nil || (acc.CategoryID != nil && *acc.CategoryID == *goal.CategoryID)
+	})
+	if len(points) == 0 {
+		return nil
+	}
+
+	var series []GoalProgressPoint
+	month := time.Date(points[0].Date.Year(), points[0].Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(now) {
+		end := month.AddDate(0, 1, -1)
+		if end.After(now) {
+			end = now
+		}
+		value := balanceAt(points, end)
+		progress := 0.0
+		if goal.TargetAmount > 0 {
+			progress = value / goal.TargetAmount * 100
+			if progress > 100 {
+				progress = 100
+			}
+			if progress < 0 {
+				progress = 0
+			}
+		}
+		series = append(series, GoalProgressPoint{
+			Date:     end.Format("2006-01-02"),
+			Value:    value,
+			Progress: progress,
+		})
+		month = month.AddDate(0, 1, 0)
+	}
+	return series
+}
+
+// ParseGoalsExport reads the goals of a JSON export created by ExportAll,
+// along with the exported categories keyed by lower-case name so missing
+// categories can be recreated.
+func ParseGoalsExport(r io.Reader) ([]GoalExport, map[string]*models.Category, error) {
+	var export struct {
+		Categories []*models.Category `json:"categories"`
+		Goals      []GoalExport       `json:"goals"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, nil, fmt.Errorf("invalid export file: %w", err)
+	}
+	if len(export.Goals) == 0 {
+		return nil, nil, errors.New("the file contains no goals")
+	}
+	if len(export.Goals) > maxGoalImportCount {
+		return nil, nil, fmt.Errorf("too many goals (max %d)", maxGoalImportCount)
+	}
+
+	for i, g := range export.Goals {
+		if g.Goal == nil || strings.TrimSpace(g.Name) == "" {
+			return nil, nil, fmt.Errorf("goal %d: name is required", i+1)
+		}
+		if g.TargetAmount <= 0 {
+			return nil, nil, fmt.Errorf("goal %q: target amount must be positive", g.Name)
+		}
+		g.Name = strings.TrimSpace(g.Name)
+		if g.TargetCurrency == "" {
+			g.TargetCurrency = "DKK"
+		}
+		export.Goals[i] = g
+	}
+
+	categories := make(map[string]*models.Category)
+	for _, c := range export.Categories {
+		if c != nil && c.Name != "" {
+			categories[strings.ToLower(c.Name)] = c
+		}
+	}
+	return export.Goals, categories, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestGoalProgressHistory_MonthEnds(t *testing.T) {
+	catID := int64(1)
+	accounts := []*models.Account{
+		{ID: 1, CategoryID: &catID},
+		{ID: 2},
+	}
+	history := map[int64][]repository.BalancePoint{
+		1: {
+			{Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), Balance: 100},
+			{Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Balance: 300},
+		},
+		2: {
+			{Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Balance: 1000},
+		},
+	}
+	now := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	goal := &models.Goal{CategoryID: &catID, TargetAmount: 200}
+	got := GoalProgressHistory(goal, accounts, history, now)
+	want := []GoalProgressPoint{
+		{Date: "2024-01-31", Value: 100, Progress: 50},
+		{Date: "2024-02-29", Value: 100, Progress: 50},
+		{Date: "2024-03-15", Value: 300, Progress: 100},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GoalProgressHistory() returned %d points, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("GoalProgressHistory()[%d] = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+
+	// A net worth goal counts every account
+	got = GoalProgressHistory(&models.Goal{TargetAmount: 2600}, accounts, history, now)
+	if len(got) != 3 || got[1].Value != 1100 || got[2].Progress != 50 {
+		t.Errorf("GoalProgressHistory() for net worth = %+v", got)
+	}
+}
+
+func TestParseGoalsExport(t *testing.T) {
+	input := `{
+		"categories": [{"id": 7, "name": "Stocks", "color": "#10b981"}],
+		"goals": [
+			{"id": 1, "name": " House ", "target_amount": 500000, "target_currency": "DKK",
+			 "deadline": "2030-01-01T00:00:00Z", "reached_date": "2024-05-01T12:00:00Z",
+			 "category_id": 7, "category_name": "Stocks", "progress_history": [{"date": "2024-01-31", "value": 1, "progress": 0}]},
+			{"name": "Car", "target_amount": 100000}
+		]
+	}`
+
+	goals, categories, err := ParseGoalsExport(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseGoalsExport() error = %v", err)
+	}
+	if len(goals) != 2 {
+		t.Fatalf("ParseGoalsExport() returned %d goals, want 2", len(goals))
+	}
+	house := goals[0]
+	if house.Name != "House" || house.CategoryName != "Stocks" || house.Deadline == nil || house.ReachedDate == nil {
+		t.Errorf("first goal = %+v; want House with category, deadline and reached date", house.Goal)
+	}
+	if goals[1].TargetCurrency != "DKK" {
+		t.Errorf("second goal currency = %q; want DKK default", goals[1].TargetCurrency)
+	}
+	if c := categories["stocks"]; c == nil || c.Color != "#10b981" {
+		t.Errorf("categories[stocks] = %+v; want exported category", c)
+	}
+}
+
+func TestParseGoalsExport_Invalid(t *testing.T) {
+	tests := []string{
+		`not json`,
+		`{"goals": []}`,
+		`{"goals": [{"name": "", "target_amount": 10}]}`,
+		`{"goals": [{"name": "House", "target_amount": 0}]}`,
+	}
+	for _, input := range tests {
+		if _, _, err := ParseGoalsExport(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseGoalsExport(%q) should return error", input)
+		}
+	}
+}
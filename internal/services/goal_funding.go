@@ -0,0 +1,53 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// GoalFunding compares what was put into a goal's funding accounts in the
+// current statement period with the contribution planned for it.
+type GoalFunding struct {
+	PeriodStart time.Time `json:"period_start"`
+
+	// Contributed is the money moved into the funding accounts in the
+	// period, less what was taken out. Revaluations are not contributions.
+	Contributed float64 `json:"contributed"`
+	Planned     float64 `json:"planned"`
+}
+
+// NewGoalFunding sums the period's flows of the goal's funding accounts, as
+// summed by GetFlowsByAccount. It returns nil for a goal without funding
+// accounts.
+func NewGoalFunding(goal *models.Goal, flows map[int64]float64, periodStart time.Time) *GoalFunding {
+	if len(goal.FundingAccountIDs) == 0 {
+		return nil
+	}
+	funding := &GoalFunding{PeriodStart: periodStart, Planned: goal.MonthlyContribution}
+	for _, accountID := range goal.FundingAccountIDs {
+		funding.Contributed += flows[accountID]
+	}
+	return funding
+}
+
+// HasPlan reports whether a monthly contribution is planned.
+func (f *GoalFunding) HasPlan() bool {
+	return f.Planned > 0
+}
+
+// Percent returns the contribution as a percentage of the plan, between 0
+// and 100, or 0 without a plan.
+func (f *GoalFunding) Percent() float64 {
+	if !f.HasPlan() {
+		return 0
+	}
+	return math.Max(0, math.Min(f.Contributed/f.Planned*100, 100))
+}
+
+// Remaining returns how much is still to be contributed this period to
+// follow the plan.
+func (f *GoalFunding) Remaining() float64 {
+	return math.Max(0, f.Planned-f.Contributed)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestNewGoalFunding(t *testing.T) {
+	periodStart := time.Date(2024, 6, 25, 0, 0, 0, 0, time.UTC)
+	flows := map[int64]float64{1: 1500, 2: -200, 3: 9999}
+
+	if f := NewGoalFunding(&models.Goal{MonthlyContribution: 2000}, flows, periodStart); f != nil {
+		t.Errorf("NewGoalFunding() without funding accounts = %+v; want nil", f)
+	}
+
+	goal := &models.Goal{FundingAccountIDs: []int64{1, 2, 4}, MonthlyContribution: 2600}
+	f := NewGoalFunding(goal, flows, periodStart)
+	if f == nil || f.Contributed != 1300 || f.Planned != 2600 || !f.PeriodStart.Equal(periodStart) {
+		t.Fatalf("NewGoalFunding() = %+v; want 1300 of 2600 contributed from the funding accounts", f)
+	}
+	if f.Percent() != 50 || f.Remaining() != 1300 {
+		t.Errorf("Percent(), Remaining() = %v, %v; want 50, 1300", f.Percent(), f.Remaining())
+	}
+
+	f.Contributed = 3000
+	if f.Percent() != 100 || f.Remaining() != 0 {
+		t.Errorf("over the plan: Percent(), Remaining() = %v, %v; want 100, 0", f.Percent(), f.Remaining())
+	}
+
+	unplanned := NewGoalFunding(&models.Goal{FundingAccountIDs: []int64{1}}, flows, periodStart)
+	if unplanned.HasPlan() || unplanned.Percent() != 0 || unplanned.Contributed != 1500 {
+		t.Errorf("without a plan = %+v; want the contribution and no percentage", unplanned)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// week is the length of a goal snapshot period.
+const week = 7 * 24 * time.Hour
+
+// GoalWeekStart returns the Monday of the week containing t, at midnight UTC.
+func GoalWeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+	return day.AddDate(0, 0, -offset)
+}
+
+// GoalTrajectoryPoint is a goal's worth and target at a date.
+type GoalTrajectoryPoint struct {
+	Date   time.Time `json:"date"`
+	Worth  float64   `json:"worth"`
+	Target float64   `json:"target"`
+}
+
+// GoalTrajectory compares how fast a goal is actually progressing with how
+// fast it must progress to be reached by its deadline.
+type GoalTrajectory struct {
+	// Points is the weekly history of the goal, ending with today.
+	Points []GoalTrajectoryPoint `json:"points"`
+
+	// ActualPerWeek is the average weekly change in worth over the history.
+	// It needs a history of at least a week.
+	ActualPerWeek float64 `json:"actual_per_week"`
+	HasActual     bool    `json:"has_actual"`
+
+	// RequiredPerWeek is the weekly change needed from today to reach the
+	// target by the deadline. It needs an unreached goal with a deadline
+	// in the future.
+	RequiredPerWeek float64    `json:"required_per_week"`
+	HasRequired     bool       `json:"has_required"`
+	Deadline        *time.Time `json:"deadline,omitempty"`
+
+	// ProjectedAtDeadline is the worth at the deadline if the actual pace
+	// continues. Set when both paces are known.
+	ProjectedAtDeadline float64 `json:"projected_at_deadline"`
+	OnTrack             bool    `json:"on_track"`
+}
+
+// NewGoalTrajectory builds the trajectory of a goal from its snapshots and
+// its current worth. The snapshot of the current week is replaced by the
+// current worth.
+func NewGoalTrajectory(goal *models.Goal, snapshots []*models.GoalSnapshot, currentWorth float64, now time.Time) GoalTrajectory {
+	thisWeek := GoalWeekStart(now)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	t := GoalTrajectory{Deadline: goal.Deadline}
+	for _, s := range snapshots {
+		if !s.WeekStart.Before(thisWeek) {
+			continue
+		}
+		t.Points = append(t.Points, GoalTrajectoryPoint{Date: s.WeekStart, Worth: s.CurrentWorth, Target: s.TargetAmount})
+	}
+	t.Points = append(t.Points, GoalTrajectoryPoint{Date: today, Worth: currentWorth, Target: goal.TargetAmount})
+
+	first := t.Points[0]
+	if weeks := today.Sub(first.Date).Hours() / week.Hours(); weeks >= 1 {
+		t.ActualPerWeek = (currentWorth - first.Worth) / weeks
+		t.HasActual = true
+	}
+
+	if goal.Deadline != nil && goal.ReachedDate == nil && currentWorth < goal.TargetAmount {
+		deadline := time.Date(goal.Deadline.Year(), goal.Deadline.Month(), goal.Deadline.Day(), 0, 0, 0, 0, time.UTC)
+		if weeksLeft := deadline.Sub(today).Hours() / week.Hours(); weeksLeft > 0 {
+			t.RequiredPerWeek = (goal.TargetAmount - currentWorth) / weeksLeft
+			t.HasRequired = true
+			if t.HasActual {
+				t.ProjectedAtDeadline = currentWorth + t.ActualPerWeek*weeksLeft
+				t.OnTrack = t.ActualPerWeek >= t.RequiredPerWeek
+			}
+		}
+	}
+	return t
+}
+
+// GoalSnapshotService records the weekly progress of goals.
+type GoalSnapshotService struct {
+	userRepo     *repository.UserRepository
+	netWorth     *NetWorthService
+	goalRepo     *repository.GoalRepository
+	snapshotRepo *repository.GoalSnapshotRepository
+}
+
+// NewGoalSnapshotService creates a new GoalSnapshotService.
+func NewGoalSnapshotService(
+	userRepo *repository.UserRepository,
+	netWorth *NetWorthService,
+	goalRepo *repository.GoalRepository,
+	snapshotRepo *repository.GoalSnapshotRepository,
+) *GoalSnapshotService {
+	return &GoalSnapshotService{
+		userRepo:     userRepo,
+		netWorth:     netWorth,
+		goalRepo:     goalRepo,
+		snapshotRepo: snapshotRepo,
+	}
+}
+
+// RecordAll records the progress of every unreached goal of every user in
+// the week of now and returns how many goals were recorded. A failure for
+// one user is logged and does not stop the others.
+func (s *GoalSnapshotService) RecordAll(now time.Time) (int, error) {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("getting users: %w", err)
+	}
+
+	recorded := 0
+	for _, user := range users {
+		n, err := s.Record(user.ID, now)
+		if err != nil {
+			log.Printf("[Goals] Recording goal progress of user %d failed: %v", user.ID, err)
+		}
+		recorded += n
+	}
+	return recorded, nil
+}
+
+// Record records the progress of the user's unreached goals in the week of
+// now. Reached goals keep the history they had when they were reached.
+func (s *GoalSnapshotService) Record(userID int64, now time.Time) (int, error) {
+	goals, err := s.goalRepo.GetByUserID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("getting goals: %w", err)
+	}
+	if len(goals) == 0 {
+		return 0, nil
+	}
+
+	netWorth, err := s.netWorth.Compute(userID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for _, goal := range goals {
+		if goal.ReachedDate != nil {
+			continue
+		}
+		err := s.snapshotRepo.Upsert(&models.GoalSnapshot{
+			GoalID:       goal.ID,
+			WeekStart:    GoalWeekStart(now),
+			CurrentWorth: netWorth.ForGoal(goal),
+			TargetAmount: goal.TargetAmount,
+			RecordedAt:   now,
+		})
+		if err != nil {
+			return recorded, fmt.Errorf("recording goal %d: %w", goal.ID, err)
+		}
+		recorded++
+	}
+	return recorded, nil
+}
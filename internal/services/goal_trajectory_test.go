@@ -0,0 +1,75 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestGoalWeekStart(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-03-11", "2024-03-11"}, // Monday
+		{"2024-03-13", "2024-03-11"},
+		{"2024-03-17", "2024-03-11"}, // Sunday
+		{"2024-03-01", "2024-02-26"},
+	}
+	for _, tc := range tests {
+		date, _ := time.Parse("2006-01-02", tc.date)
+		if got := GoalWeekStart(date.Add(15 * time.Hour)).Format("2006-01-02"); got != tc.want {
+			t.Errorf("GoalWeekStart(%s) = %s; want %s", tc.date, got, tc.want)
+		}
+	}
+}
+
+func TestNewGoalTrajectory(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	deadline := day("2024-04-10")
+	goal := &models.Goal{TargetAmount: 2000, Deadline: &deadline}
+	snapshots := []*models.GoalSnapshot{
+		{WeekStart: day("2024-02-12"), CurrentWorth: 1000, TargetAmount: 1800},
+		{WeekStart: day("2024-02-19"), CurrentWorth: 1100, TargetAmount: 2000},
+		{WeekStart: day("2024-03-11"), CurrentWorth: 9999, TargetAmount: 2000}, // This week, replaced by today
+	}
+	now := day("2024-03-13").Add(10 * time.Hour)
+
+	got := NewGoalTrajectory(goal, snapshots, 1500, now)
+
+	if len(got.Points) != 3 {
+		t.Fatalf("Points = %+v; want 2 snapshots and today", got.Points)
+	}
+	if last := got.Points[2]; !last.Date.Equal(day("2024-03-13")) || last.Worth != 1500 || last.Target != 2000 {
+		t.Errorf("last point = %+v; want today's worth", last)
+	}
+	if got.Points[0].Target != 1800 {
+		t.Errorf("first point target = %v; want the target of the time", got.Points[0].Target)
+	}
+
+	// 500 gained over 30 days; 500 needed in the 4 weeks to the deadline
+	if !got.HasActual || math.Abs(got.ActualPerWeek-500/(30.0/7)) > 0.01 {
+		t.Errorf("ActualPerWeek = %v, %v; want %v", got.ActualPerWeek, got.HasActual, 500/(30.0/7))
+	}
+	if !got.HasRequired || math.Abs(got.RequiredPerWeek-125) > 0.01 {
+		t.Errorf("RequiredPerWeek = %v, %v; want 125", got.RequiredPerWeek, got.HasRequired)
+	}
+	if math.Abs(got.ProjectedAtDeadline-(1500+4*500/(30.0/7))) > 0.01 || got.OnTrack {
+		t.Errorf("ProjectedAtDeadline = %v, OnTrack = %v; want behind schedule", got.ProjectedAtDeadline, got.OnTrack)
+	}
+
+	// Without history there is no actual pace, and reached goals need none
+	got = NewGoalTrajectory(goal, nil, 1500, now)
+	if got.HasActual || !got.HasRequired || len(got.Points) != 1 {
+		t.Errorf("trajectory without history = %+v", got)
+	}
+	got = NewGoalTrajectory(goal, snapshots, 2500, now)
+	if got.HasRequired {
+		t.Errorf("reached goal has a required pace: %+v", got)
+	}
+}
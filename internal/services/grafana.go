@@ -0,0 +1,295 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Grafana target identifiers. Per-account and per-category targets are
+// suffixed with the entity ID, e.g. "account:12" or "allocation:3".
+const (
+	GrafanaTargetNetWorth   = "net_worth"
+	GrafanaTargetAccount    = "account"
+	GrafanaTargetCategory   = "category"
+	GrafanaTargetAllocation = "allocation"
+)
+
+// GrafanaService exposes wealth data as time series following the
+// SimpleJSON / Infinity datasource contract used by Grafana.
+type GrafanaService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	categoryRepo    *repository.CategoryRepository
+	netWorth        *NetWorthService // Nil adds up balances in any currency as they are
+}
+
+// NewGrafanaService creates a new GrafanaService.
+func NewGrafanaService(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	categoryRepo *repository.CategoryRepository,
+	netWorth *NetWorthService,
+) *GrafanaService {
+	return &GrafanaService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		categoryRepo:    categoryRepo,
+		netWorth:        netWorth,
+	}
+}
+
+// GrafanaTarget is a selectable metric returned by the search endpoint.
+type GrafanaTarget struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// GrafanaQueryRequest is the body Grafana posts to the query endpoint.
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	} `json:"targets"`
+}
+
+// GrafanaTimeSeries is a single series in a query response.
+// Datapoints are [value, unix milliseconds] pairs.
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// Search returns all targets available to a user whose name contains the filter.
+func (s *GrafanaService) Search(userID int64, filter string) ([]GrafanaTarget, error) {
+	accounts, err := s.accountRepo.GetByUserIDActiveOnly(userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []GrafanaTarget{{Text: "Net Worth", Value: GrafanaTargetNetWorth}}
+	for _, acc := range accounts {
+		targets = append(targets, GrafanaTarget{
+			Text:  "Account: " + acc.Name,
+			Value: fmt.Sprintf("%s:%d", GrafanaTargetAccount, acc.ID),
+		})
+	}
+	for _, cat := range categories {
+		targets = append(targets,
+			GrafanaTarget{
+				Text:  "Category: " + cat.Name,
+				Value: fmt.Sprintf("%s:%d", GrafanaTargetCategory, cat.ID),
+			},
+			GrafanaTarget{
+				Text:  "Allocation %: " + cat.Name,
+				Value: fmt.Sprintf("%s:%d", GrafanaTargetAllocation, cat.ID),
+			},
+		)
+	}
+
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return targets, nil
+	}
+
+	filtered := make([]GrafanaTarget, 0, len(targets))
+	for _, t := range targets {
+		if strings.Contains(strings.ToLower(t.Text), filter) || strings.Contains(t.Value, filter) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+// Query resolves each requested target into a time series within the range.
+// Unknown targets or targets belonging to other users yield an empty series.
+func (s *GrafanaService) Query(userID int64, req *GrafanaQueryRequest) ([]GrafanaTimeSeries, error) {
+	accounts, err := s.accountRepo.GetByUserIDWithHistory(userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.transactionRepo.GetBalanceHistoryByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	worth, err := s.worthHistories(userID, accounts, history)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := req.Range.From, req.Range.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	result := make([]GrafanaTimeSeries, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		if t.Target == "" {
+			continue
+		}
+		points := s.resolveTarget(t.Target, accounts, history, worth)
+		result = append(result, GrafanaTimeSeries{
+			Target:     t.Target,
+			Datapoints: toDatapoints(points, from, to),
+		})
+	}
+	return result, nil
+}
+
+// worthHistories converts the balance histories of the accounts into the
+// user's default currency, at the rate of each day where known, as
+// NetWorthService does for the dashboard.
+func (s *GrafanaService) worthHistories(userID int64, accounts []*models.Account, history map[int64][]repository.BalancePoint) (map[int64][]repository.BalancePoint, error) {
+	if s.netWorth == nil {
+		return history, nil
+	}
+	user, err := s.netWorth.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+
+	worth := make(map[int64][]repository.BalancePoint, len(history))
+	for _, acc := range accounts {
+		points := make([]repository.BalancePoint, len(history[acc.ID]))
+		for i, p := range history[acc.ID] {
+			points[i] = repository.BalancePoint{Date: p.Date, Balance: s.netWorth.convert(user, p.Balance, acc.Currency, &p.Date)}
+		}
+		worth[acc.ID] = points
+	}
+	return worth, nil
+}
+
+// resolveTarget builds the full balance history for a target. Account series
+// stay in the account's currency; combined series use the converted worth.
+func (s *GrafanaService) resolveTarget(target string, accounts []*models.Account, history, worth map[int64][]repository.BalancePoint) []repository.BalancePoint {
+	if target == GrafanaTargetNetWorth {
+		return combineHistories(accounts, worth, func(*models.Account) bool { return true })
+	}
+
+	kind, idStr, ok := strings.Cut(target, ":")
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	inCategory := func(acc *models.Account) bool {
+		return !acc.IsLiability && acc.CategoryID != nil && *acc.CategoryID == id
+	}
+
+	switch kind {
+	case GrafanaTargetAccount:
+		for _, acc := range accounts {
+			if acc.ID == id {
+				return history[acc.ID]
+			}
+		}
+	case GrafanaTargetCategory:
+		return combineHistories(accounts, worth, inCategory)
+	case GrafanaTargetAllocation:
+		category := combineHistories(accounts, worth, inCategory)
+		total := combineHistories(accounts, worth, func(acc *models.Account) bool { return !acc.IsLiability })
+		return percentOf(category, total)
+	}
+	return nil
+}
+
+// combineHistories sums the balance histories of all accounts matching the
+// predicate into one series with a point at every date any of them changed.
+// Liabilities are subtracted using their absolute value, as on the dashboard.
+func combineHistories(accounts []*models.Account, history map[int64][]repository.BalancePoint, include func(*models.Account) bool) []repository.BalancePoint {
+	var selected []*models.Account
+	dateSet := make(map[time.Time]bool)
+	for _, acc := range accounts {
+		if !include(acc) {
+			continue
+		}
+		selected = append(selected, acc)
+		for _, p := range history[acc.ID] {
+			dateSet[p.Date] = true
+		}
+	}
+
+	dates := make([]time.Time, 0, len(dateSet))
+	for d := range dateSet {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	combined := make([]repository.BalancePoint, len(dates))
+	for i, d := range dates {
+		sum := 0.0
+		for _, acc := range selected {
+			bal := balanceAt(history[acc.ID], d)
+			if acc.IsLiability {
+				sum -= math.Abs(bal)
+			} else {
+				sum += bal
+			}
+		}
+		combined[i] = repository.BalancePoint{Date: d, Balance: sum}
+	}
+	return combined
+}
+
+// percentOf expresses part as a percentage of total at every date of total.
+func percentOf(part, total []repository.BalancePoint) []repository.BalancePoint {
+	result := make([]repository.BalancePoint, len(total))
+	for i, p := range total {
+		pct := 0.0
+		if p.Balance != 0 {
+			pct = balanceAt(part, p.Date) / p.Balance * 100
+		}
+		result[i] = repository.BalancePoint{Date: p.Date, Balance: pct}
+	}
+	return result
+}
+
+// balanceAt returns the balance of the last point on or before t, or 0 if none.
+func balanceAt(points []repository.BalancePoint, t time.Time) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i].Date.After(t) })
+	if i == 0 {
+		return 0
+	}
+	return points[i-1].Balance
+}
+
+// toDatapoints clips a balance history to [from, to]. The balance carried into
+// the range is emitted at from so the series starts at the correct level.
+func toDatapoints(points []repository.BalancePoint, from, to time.Time) [][2]float64 {
+	datapoints := make([][2]float64, 0, len(points)+1)
+	for i, p := range points {
+		if p.Date.After(to) {
+			break
+		}
+		if p.Date.Before(from) {
+			// Only the last point before the range is relevant
+			if i+1 < len(points) && !points[i+1].Date.After(from) {
+				continue
+			}
+			datapoints = append(datapoints, [2]float64{p.Balance, float64(from.UnixMilli())})
+			continue
+		}
+		datapoints = append(datapoints, [2]float64{p.Balance, float64(p.Date.UnixMilli())})
+	}
+	return datapoints
+}
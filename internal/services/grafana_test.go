@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestToDatapoints_CarriesBalanceIntoRange(t *testing.T) {
+	points := []repository.BalancePoint{
+		{Date: day(1), Balance: 100},
+		{Date: day(3), Balance: 200},
+		{Date: day(6), Balance: 300},
+		{Date: day(9), Balance: 400},
+	}
+
+	got := toDatapoints(points, day(5), day(7))
+	want := [][2]float64{
+		{200, float64(day(5).UnixMilli())},
+		{300, float64(day(6).UnixMilli())},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("toDatapoints() returned %d points, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("toDatapoints()[%d] = %v; want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCombineHistories_SubtractsLiabilities(t *testing.T) {
+	catID := int64(1)
+	accounts := []*models.Account{
+		{ID: 1, CategoryID: &catID},
+		{ID: 2, IsLiability: true},
+	}
+	history := map[int64][]repository.BalancePoint{
+		1: {{Date: day(1), Balance: 1000}, {Date: day(3), Balance: 1500}},
+		2: {{Date: day(2), Balance: -400}},
+	}
+
+	got := combineHistories(accounts, history, func(*models.Account) bool { return true })
+	want := []float64{1000, 600, 1100}
+
+	if len(got) != len(want) {
+		t.Fatalf("combineHistories() returned %d points, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Balance != w {
+			t.Errorf("combineHistories()[%d] = %f; want %f", i, got[i].Balance, w)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	part := []repository.BalancePoint{{Date: day(1), Balance: 25}}
+	total := []repository.BalancePoint{{Date: day(1), Balance: 100}, {Date: day(2), Balance: 0}}
+
+	got := percentOf(part, total)
+	if got[0].Balance != 25 {
+		t.Errorf("percentOf()[0] = %f; want 25", got[0].Balance)
+	}
+	if got[1].Balance != 0 {
+		t.Errorf("percentOf()[1] = %f; want 0 for zero total", got[1].Balance)
+	}
+}
+
+func TestGrafanaQuery_ConvertsCombinedSeries(t *testing.T) {
+	currency, db, userID := setupCurrencyTest(t)
+	userRepo := repository.NewUserRepository(db)
+	user, _ := userRepo.GetByID(userID)
+	user.DefaultCurrency = "DKK"
+	if err := userRepo.Update(user); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	accounts := make(map[string]int64)
+	for _, a := range []struct {
+		name, currency string
+		balance        float64
+	}{{"Depot", "USD", 100}, {"Cash", "DKK", 300}} {
+		id, err := accountRepo.Create(&models.Account{UserID: userID, Name: a.name, Currency: a.currency, IsActive: true})
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		accounts[a.name] = id
+		if _, err := transactionRepo.Create(&models.Transaction{AccountID: id, Amount: a.balance, BalanceAfter: a.balance, TransactionDate: day(1)}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	s := NewGrafanaService(accountRepo, transactionRepo, categoryRepo, NewNetWorthService(userRepo, accountRepo, transactionRepo, currency))
+	req := &GrafanaQueryRequest{}
+	req.Range.From, req.Range.To = day(1), day(2)
+	req.Targets = make([]struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	}, 2)
+	req.Targets[0].Target = GrafanaTargetNetWorth
+	req.Targets[1].Target = fmt.Sprintf("%s:%d", GrafanaTargetAccount, accounts["Depot"])
+
+	series, err := s.Query(userID, req)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	// The depot's 100 USD is 700 DKK
+	if len(series) != 2 || len(series[0].Datapoints) != 1 || series[0].Datapoints[0][0] != 1000 {
+		t.Errorf("net worth series = %+v; want 1000 DKK", series)
+	}
+	if len(series[1].Datapoints) != 1 || series[1].Datapoints[0][0] != 100 {
+		t.Errorf("account series = %+v; want 100 in the account's own currency", series[1])
+	}
+}
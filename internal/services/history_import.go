@@ -0,0 +1,365 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// NetWorthHistoryAccount is the name of the closed account that holds
+// imported net worth snapshots, which belong to no particular account.
+const NetWorthHistoryAccount = "Net worth history"
+
+// maxHistoryImportRows limits the size of a single history upload: twenty
+// accounts with monthly balances for twenty years.
+const maxHistoryImportRows = 5000
+
+// HistorySnapshot is an imported balance of an account, or of the whole net
+// worth if Account is empty, at a date.
+type HistorySnapshot struct {
+	Row     int
+	Date    time.Time
+	Account string
+	Balance float64
+}
+
+// historyImportColumns maps accepted header names to canonical columns.
+var historyImportColumns = map[string]string{
+	"date":         "date",
+	"month":        "date",
+	"account":      "account",
+	"account_name": "account",
+	"account name": "account",
+	"balance":      "balance",
+	"value":        "balance",
+	"amount":       "balance",
+	"net_worth":    "balance",
+	"net worth":    "balance",
+	"networth":     "balance",
+}
+
+// historyJSONSnapshot is a snapshot in a JSON history file.
+type historyJSONSnapshot struct {
+	Date     string   `json:"date"`
+	Account  string   `json:"account"`
+	Balance  *float64 `json:"balance"`
+	NetWorth *float64 `json:"net_worth"`
+}
+
+// ParseHistoryImport parses a file of dated balance snapshots from another
+// tool. CSV files need date and balance columns and may have an account
+// column; JSON files are an array of objects with date, balance and an
+// optional account. Snapshots without an account are of the whole net worth.
+// Rows that fail validation are reported individually.
+func ParseHistoryImport(r io.Reader) ([]HistorySnapshot, []HoldingImportRowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return parseHistoryJSON(trimmed)
+	}
+	return parseHistoryCSV(data)
+}
+
+// parseHistoryCSV parses a CSV history file.
+func parseHistoryCSV(data []byte) ([]HistorySnapshot, []HoldingImportRowError, error) {
+	reader, columns, err := openImportCSV(bytes.NewReader(data), historyImportColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, required := range []string{"date", "balance"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("header must contain a %s column", required)
+		}
+	}
+
+	var snapshots []HistorySnapshot
+	var rowErrors []HoldingImportRowError
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: "malformed CSV line"})
+			continue
+		}
+		if row-1 > maxHistoryImportRows {
+			return nil, nil, fmt.Errorf("file exceeds %d rows", maxHistoryImportRows)
+		}
+
+		field := func(col string) string {
+			i, ok := columns[col]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		date, ok := parseHistoryDate(field("date"))
+		if !ok {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: fmt.Sprintf("invalid date %q", field("date"))})
+			continue
+		}
+		balance, err := parseImportDecimal(field("balance"))
+		if err != nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: fmt.Sprintf("invalid balance %q", field("balance"))})
+			continue
+		}
+		snapshots = append(snapshots, HistorySnapshot{Row: row, Date: date, Account: field("account"), Balance: balance})
+	}
+
+	return snapshots, append(rowErrors, duplicateSnapshotErrors(snapshots)...), nil
+}
+
+// parseHistoryJSON parses a JSON history file. Rows are numbered from 1.
+func parseHistoryJSON(data []byte) ([]HistorySnapshot, []HoldingImportRowError, error) {
+	var entries []historyJSONSnapshot
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, nil, errors.New("not a valid JSON array of snapshots")
+	}
+	if len(entries) > maxHistoryImportRows {
+		return nil, nil, fmt.Errorf("file exceeds %d rows", maxHistoryImportRows)
+	}
+
+	var snapshots []HistorySnapshot
+	var rowErrors []HoldingImportRowError
+	for i, entry := range entries {
+		row := i + 1
+		date, ok := parseHistoryDate(strings.TrimSpace(entry.Date))
+		if !ok {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: fmt.Sprintf("invalid date %q", entry.Date)})
+			continue
+		}
+		balance := entry.Balance
+		if balance == nil {
+			balance = entry.NetWorth
+		}
+		if balance == nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: "balance is required"})
+			continue
+		}
+		snapshots = append(snapshots, HistorySnapshot{Row: row, Date: date, Account: strings.TrimSpace(entry.Account), Balance: *balance})
+	}
+
+	return snapshots, append(rowErrors, duplicateSnapshotErrors(snapshots)...), nil
+}
+
+// parseHistoryDate parses an ISO snapshot date. Other tools write days and
+// months in either order, so other formats are rejected rather than guessed.
+// Months ("2015-01") are taken as their last day.
+func parseHistoryDate(s string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return MonthEnd(t), true
+	}
+	return time.Time{}, false
+}
+
+// duplicateSnapshotErrors reports snapshots of the same account on the same
+// date as an earlier row.
+func duplicateSnapshotErrors(snapshots []HistorySnapshot) []HoldingImportRowError {
+	var rowErrors []HoldingImportRowError
+	seen := make(map[string]int)
+	for _, s := range snapshots {
+		key := strings.ToLower(s.Account) + "|" + s.Date.Format("2006-01-02")
+		if prev, ok := seen[key]; ok {
+			rowErrors = append(rowErrors, HoldingImportRowError{
+				Row:     s.Row,
+				Message: fmt.Sprintf("duplicate of row %d", prev),
+			})
+			continue
+		}
+		seen[key] = s.Row
+	}
+	return rowErrors
+}
+
+// HistoryImportResult summarizes an imported history.
+type HistoryImportResult struct {
+	Balances int       // Backdated balances created
+	Accounts int       // Accounts that got them
+	From     time.Time // Date of the earliest snapshot
+}
+
+// HistoryImporter creates backdated balances from imported snapshots, so the
+// net worth history reaches back before the user started using the app.
+type HistoryImporter struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+
+	// categorizer applies the account owners' categorization rules to the
+	// imported balances; nil leaves them uncategorized.
+	categorizer *Categorizer
+}
+
+// NewHistoryImporter creates a new HistoryImporter.
+func NewHistoryImporter(accountRepo *repository.AccountRepository, transactionRepo *repository.TransactionRepository) *HistoryImporter {
+	return &HistoryImporter{accountRepo: accountRepo, transactionRepo: transactionRepo}
+}
+
+// SetCategorizer sets the categorizer applied to imported balances.
+func (h *HistoryImporter) SetCategorizer(categorizer *Categorizer) {
+	h.categorizer = categorizer
+}
+
+// historyTarget is an account with the snapshots to import into it.
+type historyTarget struct {
+	account   *models.Account // nil for a net worth history account yet to be created
+	first     *models.Transaction
+	snapshots []HistorySnapshot
+}
+
+// Import adds the snapshots as backdated balances of the user's accounts,
+// matched by name. Snapshots must be dated before the first balance already
+// recorded for the account, so the history leads up to it without changing
+// it. Net worth snapshots go into a closed NetWorthHistoryAccount that
+// counts towards net worth until the user's first recorded balance. Nothing
+// is imported if any snapshot is rejected or saving fails.
+func (h *HistoryImporter) Import(user *models.User, snapshots []HistorySnapshot) (HistoryImportResult, []HoldingImportRowError, error) {
+	accounts, err := h.accountRepo.GetByUserID(user.ID)
+	if err != nil {
+		return HistoryImportResult{}, nil, fmt.Errorf("getting accounts: %w", err)
+	}
+	byName := make(map[string]*models.Account)
+	firsts := make(map[int64]*models.Transaction)
+	var trackedFrom *time.Time // Earliest balance outside the net worth history
+	for _, account := range accounts {
+		byName[strings.ToLower(account.Name)] = account
+		first, err := h.transactionRepo.GetFirstByAccountID(account.ID)
+		if err != nil {
+			return HistoryImportResult{}, nil, fmt.Errorf("getting first balance of %s: %w", account.Name, err)
+		}
+		firsts[account.ID] = first
+		if first != nil && account.Name != NetWorthHistoryAccount && (trackedFrom == nil || first.TransactionDate.Before(*trackedFrom)) {
+			trackedFrom = &first.TransactionDate
+		}
+	}
+
+	targets := make(map[string]*historyTarget)
+	var rowErrors []HoldingImportRowError
+	for _, s := range snapshots {
+		name := s.Account
+		if name == "" {
+			name = NetWorthHistoryAccount
+		}
+		key := strings.ToLower(name)
+		target, ok := targets[key]
+		if !ok {
+			account := byName[key]
+			if account == nil && key != strings.ToLower(NetWorthHistoryAccount) {
+				rowErrors = append(rowErrors, HoldingImportRowError{Row: s.Row, Message: fmt.Sprintf("unknown account %q; create it first", s.Account)})
+				continue
+			}
+			target = &historyTarget{account: account}
+			if account != nil {
+				target.first = firsts[account.ID]
+			}
+			targets[key] = target
+		}
+
+		switch {
+		case target.first != nil && !s.Date.Before(target.first.TransactionDate):
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: s.Row, Message: fmt.Sprintf("must be dated before the first balance of %s on %s", name, target.first.TransactionDate.Format("2006-01-02"))})
+		case key == strings.ToLower(NetWorthHistoryAccount) && trackedFrom != nil && !s.Date.Before(*trackedFrom):
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: s.Row, Message: fmt.Sprintf("net worth must be dated before your first recorded balance on %s", trackedFrom.Format("2006-01-02"))})
+		case target.account != nil && target.account.OpenedAt != nil && s.Date.Before(*target.account.OpenedAt):
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: s.Row, Message: fmt.Sprintf("dated before %s was opened", name)})
+		default:
+			target.snapshots = append(target.snapshots, s)
+		}
+	}
+	if len(rowErrors) > 0 {
+		sort.Slice(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+		return HistoryImportResult{}, rowErrors, nil
+	}
+
+	rules := h.categorizer.RulesForUser(user.ID)
+	var result HistoryImportResult
+	var imports []*repository.HistoryImport
+	for _, target := range targets {
+		if len(target.snapshots) == 0 {
+			continue
+		}
+		sort.Slice(target.snapshots, func(i, j int) bool { return target.snapshots[i].Date.Before(target.snapshots[j].Date) })
+
+		imp := &repository.HistoryImport{Account: target.account, First: target.first}
+		if target.account == nil || target.account.Name == NetWorthHistoryAccount {
+			prepareNetWorthAccount(user, imp, target.snapshots, trackedFrom)
+		}
+		previous := 0.0
+		for _, s := range target.snapshots {
+			txn := &models.Transaction{
+				Amount:          s.Balance - previous,
+				BalanceAfter:    s.Balance,
+				Description:     "Imported balance",
+				Kind:            models.TransactionValuation,
+				TransactionDate: s.Date,
+			}
+			rules.Apply(txn)
+			imp.Balances = append(imp.Balances, txn)
+			previous = s.Balance
+		}
+		if imp.First != nil {
+			imp.First.Amount = imp.First.BalanceAfter - previous
+		}
+		imports = append(imports, imp)
+
+		result.Balances += len(target.snapshots)
+		result.Accounts++
+		if from := target.snapshots[0].Date; result.From.IsZero() || from.Before(result.From) {
+			result.From = from
+		}
+	}
+
+	if err := h.transactionRepo.ImportHistory(imports); err != nil {
+		return HistoryImportResult{}, nil, err
+	}
+	return result, nil, nil
+}
+
+// prepareNetWorthAccount sets up the net worth history account to be created
+// if needed, and closes it when the tracked history starts, or the day after
+// the last snapshot if nothing is tracked yet.
+func prepareNetWorthAccount(user *models.User, imp *repository.HistoryImport, snapshots []HistorySnapshot, trackedFrom *time.Time) {
+	closedAt := snapshots[len(snapshots)-1].Date.AddDate(0, 0, 1)
+	if trackedFrom != nil {
+		closedAt = *trackedFrom
+	}
+
+	if imp.Account == nil {
+		currency := user.DefaultCurrency
+		if currency == "" {
+			currency = "DKK"
+		}
+		imp.Account = &models.Account{
+			UserID:   user.ID,
+			Name:     NetWorthHistoryAccount,
+			Currency: currency,
+			Notes:    "Net worth imported from before the other accounts were tracked",
+			ClosedAt: &closedAt,
+		}
+		return
+	}
+
+	if imp.Account.ClosedAt == nil || imp.Account.ClosedAt.Before(closedAt) {
+		imp.Account.ClosedAt = &closedAt
+		imp.SaveAccount = true
+	}
+}
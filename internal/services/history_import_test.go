@@ -0,0 +1,139 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestParseHistoryImport_CSV(t *testing.T) {
+	csv := "Date;Account;Balance\n2015-01;;250.000,00\n2015-01-31;Savings;100000\n2015-02-28;Savings;nope\n2015-01-31;;1\n31-01-2015;;1\n01/02/2015;;1\n"
+	snapshots, rowErrors, err := ParseHistoryImport(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("ParseHistoryImport() error = %v", err)
+	}
+
+	if len(snapshots) != 3 {
+		t.Fatalf("got %d snapshots; want 3", len(snapshots))
+	}
+	if want := time.Date(2015, 1, 31, 0, 0, 0, 0, time.UTC); !snapshots[0].Date.Equal(want) || snapshots[0].Balance != 250000 || snapshots[0].Account != "" {
+		t.Errorf("snapshot 0 = %+v; want net worth 250000 at the end of January", snapshots[0])
+	}
+	if snapshots[1].Account != "Savings" || snapshots[1].Balance != 100000 {
+		t.Errorf("snapshot 1 = %+v; want Savings at 100000", snapshots[1])
+	}
+
+	if len(rowErrors) != 4 {
+		t.Fatalf("got row errors %v; want 4", rowErrors)
+	}
+	if rowErrors[0].Row != 4 || !strings.Contains(rowErrors[0].Message, "invalid balance") {
+		t.Errorf("row error 0 = %v; want an invalid balance on row 4", rowErrors[0])
+	}
+	// Dates that are not ISO are ambiguous between tools
+	for i, row := range []int{6, 7} {
+		if e := rowErrors[i+1]; e.Row != row || !strings.Contains(e.Message, "invalid date") {
+			t.Errorf("row error %d = %v; want an invalid date on row %d", i+1, e, row)
+		}
+	}
+	if rowErrors[3].Row != 5 || !strings.Contains(rowErrors[3].Message, "duplicate of row 2") {
+		t.Errorf("row error 3 = %v; want a duplicate on row 5", rowErrors[3])
+	}
+}
+
+func TestParseHistoryImport_JSON(t *testing.T) {
+	data := `[{"date": "2015-01-31", "net_worth": 250000}, {"date": "2015-01-31", "account": "Savings", "balance": 100000}, {"date": "2015-02-28"}]`
+	snapshots, rowErrors, err := ParseHistoryImport(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseHistoryImport() error = %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Balance != 250000 || snapshots[1].Account != "Savings" {
+		t.Errorf("snapshots = %+v; want net worth and Savings", snapshots)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 3 {
+		t.Errorf("row errors = %v; want a missing balance on row 3", rowErrors)
+	}
+}
+
+func TestHistoryImporter_Import(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	importer := NewHistoryImporter(accountRepo, transactionRepo)
+
+	user := &models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"}
+	if user.ID, err = userRepo.Create(user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	savingsID, _ := accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	tracked := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	firstID, _ := transactionRepo.Create(&models.Transaction{AccountID: savingsID, Amount: 50000, BalanceAfter: 50000, TransactionDate: tracked})
+
+	date := func(y int, m time.Month) time.Time { return MonthEnd(time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)) }
+
+	// Snapshots from the time the account was tracked are rejected, and
+	// so is everything else with them
+	_, rowErrors, err := importer.Import(user, []HistorySnapshot{
+		{Row: 2, Date: date(2023, 12), Account: "savings", Balance: 45000},
+		{Row: 3, Date: date(2024, 1), Account: "Savings", Balance: 52000},
+		{Row: 4, Date: date(2023, 12), Account: "Checking", Balance: 1000},
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 3 || rowErrors[1].Row != 4 {
+		t.Fatalf("row errors = %v; want rows 3 and 4", rowErrors)
+	}
+	if n, _ := transactionRepo.CountByAccountID(savingsID); n != 1 {
+		t.Errorf("Savings has %d transactions after a rejected import; want 1", n)
+	}
+
+	result, rowErrors, err := importer.Import(user, []HistorySnapshot{
+		{Row: 2, Date: date(2015, 1), Balance: 250000},
+		{Row: 3, Date: date(2023, 11), Balance: 400000},
+		{Row: 4, Date: date(2023, 12), Account: "Savings", Balance: 45000},
+	})
+	if err != nil || len(rowErrors) > 0 {
+		t.Fatalf("Import() = %v, %v", rowErrors, err)
+	}
+	if result.Balances != 3 || result.Accounts != 2 || !result.From.Equal(date(2015, 1)) {
+		t.Errorf("result = %+v; want 3 balances in 2 accounts from January 2015", result)
+	}
+
+	// The first tracked balance now changes from the imported one
+	first, _ := transactionRepo.GetByID(firstID)
+	if first.Amount != 5000 {
+		t.Errorf("first tracked amount = %.0f; want 5000", first.Amount)
+	}
+
+	// Net worth snapshots count until the tracked history starts
+	accounts, _ := accountRepo.GetByUserID(user.ID)
+	var history *models.Account
+	for _, account := range accounts {
+		if account.Name == NetWorthHistoryAccount {
+			history = account
+		}
+	}
+	if history == nil {
+		t.Fatal("no net worth history account was created")
+	}
+	if history.IsActive || history.ClosedAt == nil || !history.ClosedAt.Equal(tracked) {
+		t.Errorf("history account active = %v, closed %v; want closed on %s", history.IsActive, history.ClosedAt, tracked)
+	}
+	if balance, _ := transactionRepo.GetLatestBalance(history.ID); balance != 400000 {
+		t.Errorf("history balance = %.0f; want 400000", balance)
+	}
+}
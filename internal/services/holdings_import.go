@@ -0,0 +1,379 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"wealth_tracker/internal/models"
+)
+
+// Holdings import modes.
+const (
+	HoldingImportMerge   = "merge"   // Update matching symbols, keep the rest
+	HoldingImportReplace = "replace" // Remove manual holdings not in the file
+)
+
+// maxHoldingImportRows limits the size of a single holdings upload.
+const maxHoldingImportRows = 1000
+
+// HoldingImportRowError describes a validation problem on a single CSV row.
+type HoldingImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (e HoldingImportRowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// holdingImportColumns maps accepted header names to canonical columns.
+var holdingImportColumns = map[string]string{
+	"symbol":        "symbol",
+	"ticker":        "symbol",
+	"isin":          "isin",
+	"name":          "name",
+	"quantity":      "quantity",
+	"qty":           "quantity",
+	"avg_price":     "avg_price",
+	"average_price": "avg_price",
+	"avg price":     "avg_price",
+	"current_price": "current_price",
+	"current price": "current_price",
+	"price":         "current_price",
+	"currency":      "currency",
+}
+
+// ImportedHolding is a holding parsed from a row of a holdings CSV, with the
+// identifiers it was given so it can be matched with existing holdings.
+type ImportedHolding struct {
+	*models.Holding
+	ISIN   string
+	Ticker string
+}
+
+// keys returns the identifiers of the holding, ISIN first.
+func (h *ImportedHolding) keys() []string {
+	var keys []string
+	for _, k := range []string{h.ISIN, h.Ticker} {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// ParseHoldingsCSV parses a holdings CSV into holdings for the given account.
+// The header row must contain quantity, current_price and at least one of
+// symbol or isin; name, avg_price and currency are optional. Both comma and
+// semicolon delimited files are accepted. Rows that fail validation, or
+// repeat the ISIN or ticker of an earlier row, are reported individually;
+// the returned holdings only contain valid rows.
+func ParseHoldingsCSV(r io.Reader, accountID int64, defaultCurrency string) ([]*ImportedHolding, []HoldingImportRowError, error) {
+	reader, columns, err := openImportCSV(r, holdingImportColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hasSymbol := columns["symbol"]
+	_, hasISIN := columns["isin"]
+	if !hasSymbol && !hasISIN {
+		return nil, nil, errors.New("header must contain a symbol or isin column")
+	}
+	for _, required := range []string{"quantity", "current_price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("header must contain a %s column", required)
+		}
+	}
+
+	var holdings []*ImportedHolding
+	var rowErrors []HoldingImportRowError
+	seen := make(map[string]int)
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: "malformed CSV line"})
+			continue
+		}
+		if row-1 > maxHoldingImportRows {
+			return nil, nil, fmt.Errorf("file exceeds %d rows", maxHoldingImportRows)
+		}
+
+		field := func(col string) string {
+			i, ok := columns[col]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		// Skip blank lines (e.g. trailing separators from spreadsheets)
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		holding, msg := parseHoldingRow(field, accountID, defaultCurrency)
+		if msg != "" {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: msg})
+			continue
+		}
+		if msg := duplicateHoldingRow(seen, holding); msg != "" {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: msg})
+			continue
+		}
+		for _, k := range holding.keys() {
+			seen[k] = row
+		}
+		holdings = append(holdings, holding)
+	}
+
+	return holdings, rowErrors, nil
+}
+
+// duplicateHoldingRow returns an error message when the holding has the ISIN
+// or ticker of an earlier row.
+func duplicateHoldingRow(seen map[string]int, holding *ImportedHolding) string {
+	for _, k := range holding.keys() {
+		if prev, ok := seen[k]; ok {
+			return fmt.Sprintf("duplicate of row %d (%s)", prev, k)
+		}
+	}
+	return ""
+}
+
+// MatchImportedHoldings gives imported holdings the symbol of the existing
+// holding of the account with the same ISIN or ticker, so a file keyed
+// differently than earlier imports updates the holdings rather than adding
+// them again. What the file leaves empty is kept from the matched holding:
+// its broker ID, so a synced holding stays linked to its broker, its average
+// price and its instrument type. Holdings matching none keep their own key.
+func MatchImportedHoldings(imported []*ImportedHolding, existing []*models.Holding) []*models.Holding {
+	bySymbol := make(map[string]*models.Holding, len(existing))
+	for _, e := range existing {
+		bySymbol[strings.ToUpper(e.Symbol)] = e
+	}
+
+	holdings := make([]*models.Holding, 0, len(imported))
+	for _, h := range imported {
+		for _, k := range h.keys() {
+			if e, ok := bySymbol[k]; ok {
+				h.Symbol = e.Symbol
+				if h.ExternalID == "" {
+					h.ExternalID = e.ExternalID
+				}
+				if h.AvgPrice == 0 {
+					h.AvgPrice = e.AvgPrice
+				}
+				if h.InstrumentType == "" {
+					h.InstrumentType = e.InstrumentType
+				}
+				break
+			}
+		}
+		holdings = append(holdings, h.Holding)
+	}
+	return holdings
+}
+
+// openImportCSV prepares an uploaded CSV for reading and maps the recognised
+// header names to their column index. Both comma and semicolon delimited
+// files are accepted.
+func openImportCSV(r io.Reader, aliases map[string]string) (*csv.Reader, map[string]int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, header, err := newImportCSVReader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, h := range header {
+		if col, ok := aliases[strings.ToLower(h)]; ok {
+			columns[col] = i
+		}
+	}
+	return reader, columns, nil
+}
+
+// newImportCSVReader returns a reader of an uploaded CSV, after its header
+// row, with the header names trimmed. The delimiter is the one of comma and
+// semicolon the header uses most.
+func newImportCSVReader(data []byte) (*csv.Reader, []string, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %w", err)
+	}
+	for i, h := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	return reader, header, nil
+}
+
+// parseHoldingRow validates a single row and returns the holding or an error message.
+func parseHoldingRow(field func(string) string, accountID int64, defaultCurrency string) (*ImportedHolding, string) {
+	symbol := strings.ToUpper(field("symbol"))
+	isin := strings.ToUpper(field("isin"))
+	if symbol == "" && isin == "" {
+		return nil, "symbol or ISIN is required"
+	}
+	if isin != "" && !isValidISIN(isin) {
+		return nil, fmt.Sprintf("invalid ISIN %q", isin)
+	}
+
+	quantity, err := parseImportDecimal(field("quantity"))
+	if err != nil {
+		return nil, fmt.Sprintf("invalid quantity %q", field("quantity"))
+	}
+	if quantity <= 0 {
+		return nil, "quantity must be positive"
+	}
+
+	currentPrice, err := parseImportDecimal(field("current_price"))
+	if err != nil {
+		return nil, fmt.Sprintf("invalid current price %q", field("current_price"))
+	}
+	if currentPrice < 0 {
+		return nil, "current price cannot be negative"
+	}
+
+	var avgPrice float64
+	if v := field("avg_price"); v != "" {
+		avgPrice, err = parseImportDecimal(v)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid average price %q", v)
+		}
+		if avgPrice < 0 {
+			return nil, "average price cannot be negative"
+		}
+	}
+
+	currency := strings.ToUpper(field("currency"))
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	if len(currency) != 3 {
+		return nil, fmt.Sprintf("invalid currency %q", currency)
+	}
+
+	// ISIN is preferred as the holding key to match broker-synced holdings
+	key := isin
+	if key == "" {
+		key = symbol
+	}
+	name := field("name")
+	if name == "" {
+		name = symbol
+	}
+	if name == "" {
+		name = isin
+	}
+
+	return &ImportedHolding{
+		Holding: &models.Holding{
+			AccountID:    accountID,
+			Symbol:       key,
+			Name:         name,
+			Quantity:     quantity,
+			AvgPrice:     avgPrice,
+			CurrentPrice: currentPrice,
+			CurrentValue: quantity * currentPrice,
+			Currency:     currency,
+		},
+		ISIN:   isin,
+		Ticker: symbol,
+	}, ""
+}
+
+// parseImportDecimal parses a number using either "." or "," as decimal separator.
+// Thousand separators are accepted when both separators are present, or when
+// the one used is repeated. A single separator followed by exactly three
+// digits, as in "1,234", could be either and is rejected, as are NaN and
+// infinities, which cannot be stored.
+func parseImportDecimal(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if s == "" {
+		return 0, errors.New("empty value")
+	}
+	lastDot := strings.LastIndex(s, ".")
+	lastComma := strings.LastIndex(s, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			// Danish style: 1.234,56
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			// English style: 1,234.56
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		sep := "."
+		if lastComma >= 0 {
+			sep = ","
+		}
+		if strings.Count(s, sep) > 1 {
+			// Repeated thousands separators: 1.234.567
+			groups := strings.Split(s, sep)
+			for _, g := range groups[1:] {
+				if len(g) != 3 {
+					return 0, fmt.Errorf("invalid number %q", s)
+				}
+			}
+			s = strings.Join(groups, "")
+			break
+		}
+		whole, frac, _ := strings.Cut(s, sep)
+		whole = strings.TrimLeft(whole, "+-")
+		if len(frac) == 3 && whole != "" && whole != "0" {
+			return 0, fmt.Errorf("ambiguous separator in %q", s)
+		}
+		s = strings.Replace(s, sep, ".", 1)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return v, nil
+}
+
+// isValidISIN checks the ISIN format: 2 letter country code, 9 alphanumerics, 1 check digit.
+func isValidISIN(isin string) bool {
+	if len(isin) != 12 {
+		return false
+	}
+	for i, c := range isin {
+		switch {
+		case i < 2 && (c < 'A' || c > 'Z'):
+			return false
+		case i >= 2 && i < 11 && !((c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')):
+			return false
+		case i == 11 && (c < '0' || c > '9'):
+			return false
+		}
+	}
+	return true
+}
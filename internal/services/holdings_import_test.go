@@ -0,0 +1,214 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestParseHoldingsCSV_ValidRows(t *testing.T) {
+	csv := `symbol,isin,quantity,avg_price,current_price
+NOVO B,DK0062498333,10,650.50,700
+AAPL,,2.5,150,180
+`
+	holdings, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK")
+	if err != nil {
+		t.Fatalf("ParseHoldingsCSV() error = %v, want nil", err)
+	}
+	if len(rowErrors) != 0 {
+		t.Fatalf("ParseHoldingsCSV() row errors = %v, want none", rowErrors)
+	}
+	if len(holdings) != 2 {
+		t.Fatalf("ParseHoldingsCSV() returned %d holdings, want 2", len(holdings))
+	}
+
+	novo := holdings[0]
+	if novo.Symbol != "DK0062498333" {
+		t.Errorf("Symbol = %s; want ISIN DK0062498333", novo.Symbol)
+	}
+	if novo.Name != "NOVO B" {
+		t.Errorf("Name = %s; want NOVO B", novo.Name)
+	}
+	if novo.CurrentValue != 7000 {
+		t.Errorf("CurrentValue = %f; want 7000", novo.CurrentValue)
+	}
+	if novo.Currency != "DKK" || novo.AccountID != 1 {
+		t.Errorf("Currency/AccountID = %s/%d; want DKK/1", novo.Currency, novo.AccountID)
+	}
+
+	if holdings[1].Symbol != "AAPL" {
+		t.Errorf("Symbol = %s; want AAPL when ISIN is empty", holdings[1].Symbol)
+	}
+}
+
+func TestParseHoldingsCSV_SemicolonAndDecimalComma(t *testing.T) {
+	csv := "isin;quantity;current_price;currency\nDK0062498333;10;12,5;eur\n"
+
+	holdings, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK")
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ParseHoldingsCSV() err = %v, rowErrors = %v", err, rowErrors)
+	}
+	if len(holdings) != 1 {
+		t.Fatalf("ParseHoldingsCSV() returned %d holdings, want 1", len(holdings))
+	}
+	if holdings[0].CurrentPrice != 12.5 {
+		t.Errorf("CurrentPrice = %f; want 12.5", holdings[0].CurrentPrice)
+	}
+	if holdings[0].Currency != "EUR" {
+		t.Errorf("Currency = %s; want EUR", holdings[0].Currency)
+	}
+}
+
+func TestParseHoldingsCSV_ReportsRowErrors(t *testing.T) {
+	csv := `symbol,isin,quantity,current_price
+AAPL,,abc,100
+,,1,100
+MSFT,BADISIN,1,100
+GOOG,,1,100
+GOOG,,2,100
+`
+	holdings, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "USD")
+	if err != nil {
+		t.Fatalf("ParseHoldingsCSV() error = %v, want nil", err)
+	}
+	if len(holdings) != 1 {
+		t.Errorf("ParseHoldingsCSV() returned %d valid holdings, want 1", len(holdings))
+	}
+
+	wantRows := []int{2, 3, 4, 6}
+	if len(rowErrors) != len(wantRows) {
+		t.Fatalf("ParseHoldingsCSV() returned %d row errors, want %d: %v", len(rowErrors), len(wantRows), rowErrors)
+	}
+	for i, row := range wantRows {
+		if rowErrors[i].Row != row {
+			t.Errorf("rowErrors[%d].Row = %d; want %d", i, rowErrors[i].Row, row)
+		}
+	}
+}
+
+func TestParseHoldingsCSV_MissingColumns(t *testing.T) {
+	tests := []string{
+		"",
+		"name,quantity,current_price\n",
+		"symbol,quantity\n",
+	}
+
+	for _, csv := range tests {
+		if _, _, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK"); err == nil {
+			t.Errorf("ParseHoldingsCSV(%q) error = nil, want error", csv)
+		}
+	}
+}
+
+func TestParseImportDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"100", 100},
+		{"12.5", 12.5},
+		{"12,5", 12.5},
+		{"1.234,56", 1234.56},
+		{"1,234.56", 1234.56},
+		{"1 234,56", 1234.56},
+		{"1.234.567", 1234567},
+		{"1,234,567", 1234567},
+		{"0,125", 0.125},
+		{"12,34", 12.34},
+	}
+
+	for _, tc := range tests {
+		got, err := parseImportDecimal(tc.input)
+		if err != nil {
+			t.Errorf("parseImportDecimal(%q) error = %v", tc.input, err)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("parseImportDecimal(%q) = %f; want %f", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestParseImportDecimal_RejectsAmbiguousSeparators(t *testing.T) {
+	for _, input := range []string{"1,234", "1.234", "-12,500", "1,23,4"} {
+		if got, err := parseImportDecimal(input); err == nil {
+			t.Errorf("parseImportDecimal(%q) = %f; want an error", input, got)
+		}
+	}
+}
+
+func TestParseImportDecimal_RejectsNonFiniteNumbers(t *testing.T) {
+	for _, input := range []string{"NaN", "nan", "Inf", "-infinity", "1e400"} {
+		if got, err := parseImportDecimal(input); err == nil {
+			t.Errorf("parseImportDecimal(%q) = %f; want an error", input, got)
+		}
+	}
+}
+
+func TestParseHoldingsCSV_DuplicateISINOrTicker(t *testing.T) {
+	csv := `symbol,isin,quantity,current_price
+NOVO B,DK0062498333,10,700
+NOVO B,,5,700
+,DK0062498333,5,700
+`
+	holdings, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK")
+	if err != nil {
+		t.Fatalf("ParseHoldingsCSV() error = %v, want nil", err)
+	}
+	if len(holdings) != 1 {
+		t.Errorf("ParseHoldingsCSV() returned %d holdings, want 1", len(holdings))
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 3 || rowErrors[1].Row != 4 {
+		t.Errorf("ParseHoldingsCSV() row errors = %v; want rows 3 and 4 reported as duplicates", rowErrors)
+	}
+}
+
+func TestMatchImportedHoldings(t *testing.T) {
+	csv := `symbol,isin,quantity,current_price
+NOVO B,DK0062498333,10,700
+AAPL,US0378331005,2,180
+MSFT,,1,400
+`
+	imported, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK")
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ParseHoldingsCSV() err = %v, rowErrors = %v", err, rowErrors)
+	}
+	existing := []*models.Holding{
+		{AccountID: 1, Symbol: "NOVO B"},       // imported earlier by ticker
+		{AccountID: 1, Symbol: "US0378331005"}, // imported earlier by ISIN
+	}
+
+	holdings := MatchImportedHoldings(imported, existing)
+	var symbols []string
+	for _, h := range holdings {
+		symbols = append(symbols, h.Symbol)
+	}
+	if want := []string{"NOVO B", "US0378331005", "MSFT"}; strings.Join(symbols, "|") != strings.Join(want, "|") {
+		t.Errorf("MatchImportedHoldings() symbols = %v; want %v", symbols, want)
+	}
+}
+
+func TestMatchImportedHoldings_KeepsWhatTheFileLeavesEmpty(t *testing.T) {
+	csv := `isin,quantity,current_price,avg_price
+DK0062498333,12,700,
+US0378331005,3,180,150
+`
+	imported, rowErrors, err := ParseHoldingsCSV(strings.NewReader(csv), 1, "DKK")
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ParseHoldingsCSV() err = %v, rowErrors = %v", err, rowErrors)
+	}
+	existing := []*models.Holding{
+		{AccountID: 1, Symbol: "DK0062498333", ExternalID: "16099874", AvgPrice: 550, InstrumentType: "ESH"},
+		{AccountID: 1, Symbol: "US0378331005", AvgPrice: 120},
+	}
+
+	holdings := MatchImportedHoldings(imported, existing)
+	novo, apple := holdings[0], holdings[1]
+	if novo.ExternalID != "16099874" || novo.AvgPrice != 550 || novo.InstrumentType != "ESH" || novo.Quantity != 12 {
+		t.Errorf("synced holding after import = %+v; want its broker ID, average price and type kept", novo)
+	}
+	if apple.AvgPrice != 150 {
+		t.Errorf("average price = %v; want the imported 150", apple.AvgPrice)
+	}
+}
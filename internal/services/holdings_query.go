@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keys holdings can be grouped by.
+const (
+	HoldingGroupSymbol         = "symbol"
+	HoldingGroupAccount        = "account"
+	HoldingGroupInstrumentType = "instrument_type"
+	HoldingGroupCurrency       = "currency"
+)
+
+// ErrHoldingGroup is returned for an unknown grouping key.
+var ErrHoldingGroup = errors.New("group_by must be symbol, account, instrument_type or currency")
+
+// HoldingFilter selects the holdings of a query. Zero values match all.
+type HoldingFilter struct {
+	AccountIDs     []int64
+	InstrumentType string
+	Currency       string
+	MinValue       float64 // In base currency
+	GroupBy        string  // Defaults to HoldingGroupSymbol
+	// ApplyExclusions leaves out the user's excluded instruments
+	ApplyExclusions bool
+}
+
+// HoldingRow is a holding of one account with its value in base currency.
+type HoldingRow struct {
+	ID             int64   `json:"id"`
+	AccountID      int64   `json:"account_id"`
+	AccountName    string  `json:"account_name"`
+	Symbol         string  `json:"symbol"`
+	Name           string  `json:"name"`
+	InstrumentType string  `json:"instrument_type"`
+	Currency       string  `json:"currency"`
+	Quantity       float64 `json:"quantity"`
+	AvgPrice       float64 `json:"avg_price"`
+	CurrentPrice   float64 `json:"current_price"`
+	Value          float64 `json:"value"`         // In the holding's currency
+	ValueInBase    float64 `json:"value_in_base"` // Converted to base currency
+	ProfitLoss     float64 `json:"profit_loss"`   // In base currency
+	ProfitLossPct  float64 `json:"profit_loss_pct"`
+	Percentage     float64 `json:"percentage"` // Of the total of the matching holdings
+	Label          string  `json:"label,omitempty"`
+}
+
+// HoldingGroup sums the holdings sharing a grouping key, such as the same
+// ETF held in several accounts.
+type HoldingGroup struct {
+	Key            string  `json:"key"`
+	Name           string  `json:"name"`
+	InstrumentType string  `json:"instrument_type,omitempty"` // For symbol groups
+	Quantity       float64 `json:"quantity,omitempty"`        // For symbol groups
+	ValueInBase    float64 `json:"value_in_base"`
+	ProfitLoss     float64 `json:"profit_loss"` // In base currency
+	ProfitLossPct  float64 `json:"profit_loss_pct"`
+	Percentage     float64 `json:"percentage"`
+	Count          int     `json:"count"`
+	AccountIDs     []int64 `json:"account_ids"`
+}
+
+// HoldingQueryResult holds the matching holdings and their groups.
+type HoldingQueryResult struct {
+	BaseCurrency string         `json:"base_currency"`
+	GroupBy      string         `json:"group_by"`
+	TotalValue   float64        `json:"total_value"` // In base currency
+	Holdings     []HoldingRow   `json:"holdings"`
+	Groups       []HoldingGroup `json:"groups"`
+	// Currencies without a provider or manual rate, counted 1:1
+	UnconvertedCurrencies []string `json:"unconverted_currencies,omitempty"`
+}
+
+// QueryHoldings returns the holdings of the user's active accounts matching
+// a filter, largest first, and sums them by the filter's grouping key.
+func (s *PortfolioService) QueryHoldings(userID int64, filter HoldingFilter) (*HoldingQueryResult, error) {
+	if filter.GroupBy == "" {
+		filter.GroupBy = HoldingGroupSymbol
+	}
+	switch filter.GroupBy {
+	case HoldingGroupSymbol, HoldingGroupAccount, HoldingGroupInstrumentType, HoldingGroupCurrency:
+	default:
+		return nil, ErrHoldingGroup
+	}
+
+	accounts, err := s.accountRepo.GetByUserIDActiveOnly(userID)
+	if err != nil {
+		return nil, err
+	}
+	var excluded map[string]bool
+	if filter.ApplyExclusions {
+		if excluded, err = s.excludedSymbols(userID); err != nil {
+			return nil, err
+		}
+	}
+	labels, err := s.labelsBySymbol(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	wantAccount := make(map[int64]bool, len(filter.AccountIDs))
+	for _, id := range filter.AccountIDs {
+		wantAccount[id] = true
+	}
+
+	result := &HoldingQueryResult{
+		BaseCurrency: s.baseCurrency,
+		GroupBy:      filter.GroupBy,
+		Holdings:     make([]HoldingRow, 0),
+		Groups:       make([]HoldingGroup, 0),
+	}
+	unconverted := make(map[string]bool)
+
+	for _, account := range accounts {
+		if len(wantAccount) > 0 && !wantAccount[account.ID] {
+			continue
+		}
+		holdings, err := s.holdingRepo.GetByAccountID(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range holdings {
+			symbol := NormalizeExclusionSymbol(h.Symbol)
+			if excluded[symbol] {
+				continue
+			}
+			currency := h.Currency
+			if currency == "" {
+				currency = account.Currency
+			}
+			if filter.InstrumentType != "" && !strings.EqualFold(h.InstrumentType, filter.InstrumentType) {
+				continue
+			}
+			if filter.Currency != "" && !strings.EqualFold(currency, filter.Currency) {
+				continue
+			}
+
+			valueInBase, ok := s.convertToBase(userID, h.CurrentValue, currency)
+			if !ok {
+				unconverted[currency] = true
+			}
+			if valueInBase < filter.MinValue {
+				continue
+			}
+			profitLoss, _ := s.convertToBase(userID, h.ProfitLoss(), currency)
+
+			result.TotalValue += valueInBase
+			result.Holdings = append(result.Holdings, HoldingRow{
+				ID:             h.ID,
+				AccountID:      account.ID,
+				AccountName:    account.Name,
+				Symbol:         h.Symbol,
+				Name:           h.Name,
+				InstrumentType: h.InstrumentType,
+				Currency:       currency,
+				Quantity:       h.Quantity,
+				AvgPrice:       h.CostBasisPrice(),
+				CurrentPrice:   h.CurrentPrice,
+				Value:          h.CurrentValue,
+				ValueInBase:    valueInBase,
+				ProfitLoss:     profitLoss,
+				ProfitLossPct:  h.ProfitLossPercent(),
+				Label:          labels[symbol],
+			})
+		}
+	}
+
+	sort.SliceStable(result.Holdings, func(i, j int) bool {
+		return result.Holdings[i].ValueInBase > result.Holdings[j].ValueInBase
+	})
+	for i := range result.Holdings {
+		if result.TotalValue > 0 {
+			result.Holdings[i].Percentage = result.Holdings[i].ValueInBase / result.TotalValue * 100
+		}
+	}
+	result.Groups = GroupHoldings(result.Holdings, filter.GroupBy, result.TotalValue)
+
+	for currency := range unconverted {
+		result.UnconvertedCurrencies = append(result.UnconvertedCurrencies, currency)
+	}
+	sort.Strings(result.UnconvertedCurrencies)
+	return result, nil
+}
+
+// GroupHoldings sums holdings by a grouping key, largest group first, with
+// percentages of total. Symbols are compared case-insensitively, so the same
+// ISIN in different accounts forms one group.
+func GroupHoldings(rows []HoldingRow, groupBy string, total float64) []HoldingGroup {
+	groups := make([]HoldingGroup, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		var key, name string
+		switch groupBy {
+		case HoldingGroupAccount:
+			key, name = strconv.FormatInt(row.AccountID, 10), row.AccountName
+		case HoldingGroupInstrumentType:
+			key = row.InstrumentType
+			if key == "" {
+				key = "unknown"
+			}
+			name = key
+		case HoldingGroupCurrency:
+			key, name = row.Currency, row.Currency
+		default:
+			key, name = NormalizeExclusionSymbol(row.Symbol), row.Name
+			if name == "" {
+				name = row.Symbol
+			}
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, HoldingGroup{Key: key, Name: name})
+			if groupBy == HoldingGroupSymbol {
+				groups[i].InstrumentType = row.InstrumentType
+			}
+		}
+		g := &groups[i]
+		if groupBy == HoldingGroupSymbol {
+			g.Quantity += row.Quantity
+		}
+		g.ValueInBase += row.ValueInBase
+		g.ProfitLoss += row.ProfitLoss
+		g.Count++
+		if !slices.Contains(g.AccountIDs, row.AccountID) {
+			g.AccountIDs = append(g.AccountIDs, row.AccountID)
+		}
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		if cost := g.ValueInBase - g.ProfitLoss; cost > 0 {
+			g.ProfitLossPct = g.ProfitLoss / cost * 100
+		}
+		if total > 0 {
+			g.Percentage = g.ValueInBase / total * 100
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].ValueInBase > groups[j].ValueInBase
+	})
+	return groups
+}
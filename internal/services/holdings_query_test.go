@@ -0,0 +1,64 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGroupHoldings_SameSymbolAcrossAccounts(t *testing.T) {
+	rows := []HoldingRow{
+		{AccountID: 1, Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", InstrumentType: "etf", Quantity: 10, ValueInBase: 6000, ProfitLoss: 1000},
+		{AccountID: 2, Symbol: "ie00b4l5y983 ", Name: "iShares Core MSCI World", InstrumentType: "etf", Quantity: 5, ValueInBase: 3000, ProfitLoss: 500},
+		{AccountID: 2, Symbol: "DK0010274414", Name: "Danske Bank", InstrumentType: "stock", Quantity: 20, ValueInBase: 1000, ProfitLoss: -250},
+	}
+
+	groups := GroupHoldings(rows, HoldingGroupSymbol, 10000)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	world := groups[0]
+	if world.Key != "IE00B4L5Y983" || world.Count != 2 || world.Quantity != 15 || world.ValueInBase != 9000 {
+		t.Errorf("world group = %+v; want both accounts' 15 units worth 9000", world)
+	}
+	if len(world.AccountIDs) != 2 || world.Percentage != 90 {
+		t.Errorf("world group accounts %v, percentage %v; want 2 accounts and 90%%", world.AccountIDs, world.Percentage)
+	}
+	if math.Abs(world.ProfitLossPct-20) > 0.001 {
+		t.Errorf("world group P/L = %v%%, want 20%%", world.ProfitLossPct)
+	}
+	if groups[1].ProfitLossPct != -20 {
+		t.Errorf("stock group P/L = %v%%, want -20%%", groups[1].ProfitLossPct)
+	}
+}
+
+func TestGroupHoldings_OtherKeys(t *testing.T) {
+	rows := []HoldingRow{
+		{AccountID: 1, AccountName: "Nordnet", Currency: "DKK", InstrumentType: "etf", Quantity: 1, ValueInBase: 100},
+		{AccountID: 2, AccountName: "Saxo", Currency: "USD", Quantity: 1, ValueInBase: 350},
+		{AccountID: 2, AccountName: "Saxo", Currency: "DKK", InstrumentType: "etf", Quantity: 1, ValueInBase: 200},
+	}
+
+	tests := []struct {
+		groupBy  string
+		wantKeys []string
+	}{
+		{HoldingGroupAccount, []string{"2", "1"}},
+		{HoldingGroupCurrency, []string{"USD", "DKK"}},
+		{HoldingGroupInstrumentType, []string{"unknown", "etf"}},
+	}
+	for _, tc := range tests {
+		groups := GroupHoldings(rows, tc.groupBy, 650)
+		if len(groups) != len(tc.wantKeys) {
+			t.Errorf("%s: got %d groups, want %d", tc.groupBy, len(groups), len(tc.wantKeys))
+			continue
+		}
+		for i, key := range tc.wantKeys {
+			if groups[i].Key != key {
+				t.Errorf("%s: group %d key = %q, want %q", tc.groupBy, i, groups[i].Key, key)
+			}
+			if groups[i].Quantity != 0 {
+				t.Errorf("%s: group %q sums quantities of different instruments", tc.groupBy, groups[i].Key)
+			}
+		}
+	}
+}
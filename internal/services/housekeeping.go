@@ -0,0 +1,77 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// HousekeepingTask cleans up one kind of leftover, such as expired sessions,
+// and returns how many items it removed.
+type HousekeepingTask struct {
+	Name  string
+	Clean func(now time.Time) (int, error)
+}
+
+// HousekeepingResult is the outcome of a task in a housekeeping run.
+type HousekeepingResult struct {
+	Task    string
+	Removed int
+	Total   int // Removed by the task since the server started
+	Err     error
+}
+
+// HousekeepingRun is the outcome of a housekeeping run.
+type HousekeepingRun struct {
+	At      time.Time
+	Results []HousekeepingResult
+}
+
+// Removed returns the number of items removed by all tasks of the run.
+func (r HousekeepingRun) Removed() int {
+	n := 0
+	for _, result := range r.Results {
+		n += result.Removed
+	}
+	return n
+}
+
+// HousekeepingService runs housekeeping tasks and remembers the last run,
+// so admins can see what was cleaned up.
+type HousekeepingService struct {
+	tasks []HousekeepingTask
+
+	mu     sync.Mutex
+	last   *HousekeepingRun
+	totals map[string]int
+}
+
+// NewHousekeepingService creates a HousekeepingService running tasks in
+// order.
+func NewHousekeepingService(tasks ...HousekeepingTask) *HousekeepingService {
+	return &HousekeepingService{tasks: tasks, totals: make(map[string]int)}
+}
+
+// Run runs every task. A failing task does not stop the others.
+func (s *HousekeepingService) Run(now time.Time) HousekeepingRun {
+	run := HousekeepingRun{At: now}
+	for _, task := range s.tasks {
+		removed, err := task.Clean(now)
+		run.Results = append(run.Results, HousekeepingResult{Task: task.Name, Removed: removed, Err: err})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range run.Results {
+		s.totals[run.Results[i].Task] += run.Results[i].Removed
+		run.Results[i].Total = s.totals[run.Results[i].Task]
+	}
+	s.last = &run
+	return run
+}
+
+// LastRun returns the last run, or nil if housekeeping has not run yet.
+func (s *HousekeepingService) LastRun() *HousekeepingRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHousekeepingService_Run(t *testing.T) {
+	failure := errors.New("disk full")
+	svc := NewHousekeepingService(
+		HousekeepingTask{Name: "sessions", Clean: func(time.Time) (int, error) { return 2, nil }},
+		HousekeepingTask{Name: "files", Clean: func(time.Time) (int, error) { return 1, failure }},
+	)
+
+	if svc.LastRun() != nil {
+		t.Fatal("LastRun before the first run should be nil")
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.Run(now)
+	run := svc.Run(now.Add(time.Minute))
+
+	if got := svc.LastRun(); got == nil || !got.At.Equal(now.Add(time.Minute)) {
+		t.Fatalf("LastRun = %+v, want the second run", got)
+	}
+	if run.Removed() != 3 {
+		t.Errorf("Removed() = %d, want 3", run.Removed())
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+	if r := run.Results[0]; r.Task != "sessions" || r.Removed != 2 || r.Total != 4 || r.Err != nil {
+		t.Errorf("sessions result = %+v, want 2 removed, 4 in total", r)
+	}
+	if r := run.Results[1]; r.Task != "files" || r.Total != 2 || !errors.Is(r.Err, failure) {
+		t.Errorf("files result = %+v, want the error and 2 in total", r)
+	}
+}
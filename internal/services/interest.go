@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// MonthEnd returns the last day of the month containing t, at midnight UTC.
+func MonthEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthlyInterest returns the interest a liability with the given balance
+// accrues in a month at an annual rate in percent, rounded to cents. The
+// interest has the sign of the balance, so it always increases the debt.
+func MonthlyInterest(balance, annualRate float64) float64 {
+	interest := math.Round(math.Abs(balance)*annualRate/100/12*100) / 100
+	if balance < 0 {
+		return -interest
+	}
+	return interest
+}
+
+// InterestAccrualService posts the monthly interest of liabilities that have
+// an interest rate, as transactions separate from payments.
+type InterestAccrualService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	accrualRepo     *repository.InterestAccrualRepository
+}
+
+// NewInterestAccrualService creates a new InterestAccrualService.
+func NewInterestAccrualService(
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	accrualRepo *repository.InterestAccrualRepository,
+) *InterestAccrualService {
+	return &InterestAccrualService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		accrualRepo:     accrualRepo,
+	}
+}
+
+// AccrueAll posts the interest of every interest-bearing liability for the
+// months completed by now and returns the number of postings. A failure for
+// one account is logged and does not stop the others.
+func (s *InterestAccrualService) AccrueAll(now time.Time) (int, error) {
+	accounts, err := s.accountRepo.GetInterestBearingLiabilities()
+	if err != nil {
+		return 0, fmt.Errorf("getting liabilities: %w", err)
+	}
+
+	posted := 0
+	for _, account := range accounts {
+		n, err := s.Accrue(account, now)
+		if err != nil {
+			log.Printf("[Interest] Accruing interest of account %d failed: %v", account.ID, err)
+		}
+		posted += n
+	}
+	return posted, nil
+}
+
+// Accrue posts the interest of a liability for each month completed by now
+// that has not been posted, dated the last day of the month. Interest is
+// only posted for months ending on or after the account's latest
+// transaction: balances entered later in time already include the interest
+// the lender charged, so earlier months are left alone.
+func (s *InterestAccrualService) Accrue(account *models.Account, now time.Time) (int, error) {
+	if !account.IsLiability || account.InterestRate <= 0 {
+		return 0, nil
+	}
+
+	latest, err := s.transactionRepo.GetByAccountID(account.ID, 1, 0)
+	if err != nil {
+		return 0, fmt.Errorf("getting latest transaction: %w", err)
+	}
+	if len(latest) == 0 {
+		return 0, nil // No balance to accrue interest on
+	}
+	balance := latest[0].BalanceAfter
+
+	periodEnd := MonthEnd(latest[0].TransactionDate)
+	last, err := s.accrualRepo.GetLatestPeriodEnd(account.ID)
+	if err != nil {
+		return 0, fmt.Errorf("getting last accrual: %w", err)
+	}
+	if last != nil && !periodEnd.After(*last) {
+		periodEnd = MonthEnd(last.AddDate(0, 0, 1))
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	posted := 0
+	for ; periodEnd.Before(today); periodEnd = MonthEnd(periodEnd.AddDate(0, 0, 1)) {
+		interest := MonthlyInterest(balance, account.InterestRate)
+		if interest == 0 {
+			continue
+		}
+		balance += interest
+
+		accrual := &models.InterestAccrual{
+			AccountID: account.ID,
+			PeriodEnd: periodEnd,
+			Rate:      account.InterestRate,
+			Amount:    interest,
+		}
+		txn := &models.Transaction{
+			AccountID:       account.ID,
+			Amount:          interest,
+			BalanceAfter:    balance,
+			Description:     fmt.Sprintf("Interest %s (%.2f%% p.a.)", periodEnd.Format("January 2006"), account.InterestRate),
+			Kind:            models.TransactionValuation,
+			TransactionDate: periodEnd,
+		}
+		if _, err := s.accrualRepo.Post(accrual, txn); err != nil {
+			return posted, fmt.Errorf("posting interest for %s: %w", periodEnd.Format("2006-01"), err)
+		}
+		posted++
+	}
+	return posted, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestMonthlyInterest(t *testing.T) {
+	tests := []struct {
+		balance, rate, want float64
+	}{
+		{120000, 6, 600},
+		{-120000, 6, -600},
+		{1000, 4.5, 3.75},
+		{0, 6, 0},
+	}
+	for _, tt := range tests {
+		if got := MonthlyInterest(tt.balance, tt.rate); got != tt.want {
+			t.Errorf("MonthlyInterest(%.0f, %.1f) = %.2f; want %.2f", tt.balance, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestInterestAccrualService_Accrue(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	accrualRepo := repository.NewInterestAccrualRepository(db)
+	s := NewInterestAccrualService(accountRepo, transactionRepo, accrualRepo)
+
+	userID, err := userRepo.Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	account := &models.Account{UserID: userID, Name: "Loan", Currency: "DKK", IsLiability: true, IsActive: true, InterestRate: 6}
+	if account.ID, err = accountRepo.Create(account); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	transactionRepo.Create(&models.Transaction{
+		AccountID: account.ID, Amount: 100000, BalanceAfter: 100000,
+		TransactionDate: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	})
+
+	// March is not over yet, so January and February are posted
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	posted, err := s.Accrue(account, now)
+	if err != nil {
+		t.Fatalf("Accrue() error = %v", err)
+	}
+	if posted != 2 {
+		t.Fatalf("Accrue() posted %d months; want 2", posted)
+	}
+	if balance, _ := transactionRepo.GetLatestBalance(account.ID); balance != 101002.5 {
+		t.Errorf("balance = %.2f; want 101002.50 with compounded interest", balance)
+	}
+	txns, _ := transactionRepo.GetByAccountID(account.ID, 1, 0)
+	if want := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC); !txns[0].TransactionDate.Equal(want) || txns[0].Amount != 502.5 {
+		t.Errorf("latest posting = %.2f on %s; want 502.50 on %s", txns[0].Amount, txns[0].TransactionDate, want)
+	}
+
+	// Running again in the same month posts nothing
+	if posted, _ := s.Accrue(account, now); posted != 0 {
+		t.Errorf("second Accrue() posted %d months; want 0", posted)
+	}
+
+	// A payment in April leaves March alone, since its balance was entered
+	// after March ended
+	transactionRepo.Create(&models.Transaction{
+		AccountID: account.ID, Amount: -5000, BalanceAfter: 96002.5,
+		TransactionDate: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC),
+	})
+	posted, err = s.Accrue(account, time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Accrue() error = %v", err)
+	}
+	if posted != 1 {
+		t.Errorf("Accrue() posted %d months; want 1 for April", posted)
+	}
+
+	totals, err := accrualRepo.GetTotalsByUserID(userID)
+	if err != nil {
+		t.Fatalf("GetTotalsByUserID() error = %v", err)
+	}
+	if want := 500 + 502.5 + 480.01; math.Abs(totals[account.ID]-want) > 0.005 {
+		t.Errorf("total interest = %.2f; want %.2f", totals[account.ID], want)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"strings"
+
+	"wealth_tracker/internal/repository"
+)
+
+// SetLabelRepository enables grouping holdings by the labels the user gave
+// their instruments in composition, comparison and rebalancing.
+func (s *PortfolioService) SetLabelRepository(labelRepo *repository.HoldingLabelRepository) {
+	s.labelRepo = labelRepo
+}
+
+// NormalizeHoldingLabel returns the form a label is stored and grouped in,
+// so "Core " and "core" are the same bucket.
+func NormalizeHoldingLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// labelsBySymbol returns the label of each of the user's labelled
+// instruments by normalized symbol, or nil if the repository is not set.
+func (s *PortfolioService) labelsBySymbol(userID int64) (map[string]string, error) {
+	if s.labelRepo == nil {
+		return nil, nil
+	}
+	labels, err := s.labelRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	bySymbol := make(map[string]string, len(labels))
+	for _, l := range labels {
+		bySymbol[NormalizeExclusionSymbol(l.Symbol)] = l.Label
+	}
+	return bySymbol, nil
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// How the sale of an account's holdings is taxed.
+const (
+	LiquidationStock   = "stock"   // Stock income: the low rate up to the threshold, the high rate above
+	LiquidationASK     = "ask"     // Aktiesparekonto: the flat ASK rate on the gain
+	LiquidationPension = "pension" // Paid out: the withdrawal rate on the whole value
+	LiquidationCash    = "cash"    // Balances without holdings: neither taxed nor charged fees
+)
+
+// ErrLiquidationNegative is returned for negative fees or spreads.
+var ErrLiquidationNegative = errors.New("fees and spreads cannot be negative")
+
+// LiquidationSettings are the costs of selling holdings and the accounts and
+// holdings to sell. Percentages are of the value sold; MinFee is in base
+// currency.
+type LiquidationSettings struct {
+	FeePct      float64 `json:"fee_pct"`       // Brokerage fee per trade
+	MinFee      float64 `json:"min_fee"`       // Smallest brokerage fee per trade
+	FXSpreadPct float64 `json:"fx_spread_pct"` // Exchange spread on positions in foreign currency
+	Married     bool    `json:"married"`       // Doubles the stock income threshold
+	AccountIDs  []int64 `json:"account_ids"`   // Accounts sold in full
+	HoldingIDs  []int64 `json:"holding_ids"`   // Single holdings sold
+}
+
+// DefaultLiquidationSettings returns typical Danish online broker costs with
+// nothing selected.
+func DefaultLiquidationSettings() LiquidationSettings {
+	return LiquidationSettings{
+		FeePct:      0.1,
+		MinFee:      29,
+		FXSpreadPct: 0.25,
+	}
+}
+
+// ValidateLiquidationSettings checks that costs are not negative.
+func ValidateLiquidationSettings(settings LiquidationSettings) error {
+	if settings.FeePct < 0 || settings.MinFee < 0 || settings.FXSpreadPct < 0 {
+		return ErrLiquidationNegative
+	}
+	return nil
+}
+
+// LiquidationPosition is a holding, or an account balance, that can be sold.
+// Amounts are in the base currency.
+type LiquidationPosition struct {
+	AccountID    int64   `json:"account_id"`
+	HoldingID    int64   `json:"holding_id,omitempty"` // 0 for account balances
+	AccountName  string  `json:"account_name"`
+	Symbol       string  `json:"symbol,omitempty"`
+	Name         string  `json:"name"`
+	Currency     string  `json:"currency"`
+	TaxType      string  `json:"tax_type"`
+	Value        float64 `json:"value"`
+	Gain         float64 `json:"gain"`
+	HasCostBasis bool    `json:"has_cost_basis"`
+	Fee          float64 `json:"fee"`
+	FXCost       float64 `json:"fx_cost"`
+	Selected     bool    `json:"selected"`
+}
+
+// Costs returns the brokerage fee and exchange spread of selling the position.
+func (p LiquidationPosition) Costs() float64 {
+	return p.Fee + p.FXCost
+}
+
+// LiquidationAccount is an account with the positions it can sell, largest
+// first.
+type LiquidationAccount struct {
+	ID        int64                 `json:"id"`
+	Name      string                `json:"name"`
+	TaxType   string                `json:"tax_type"`
+	Value     float64               `json:"value"`
+	Selected  bool                  `json:"selected"` // Sold in full
+	Positions []LiquidationPosition `json:"positions"`
+}
+
+// LiquidationResult estimates the cash from selling the selected positions
+// today. Amounts are in the base currency.
+type LiquidationResult struct {
+	Settings     LiquidationSettings  `json:"settings"`
+	BaseCurrency string               `json:"base_currency"`
+	TaxYear      int                  `json:"tax_year"`
+	Accounts     []LiquidationAccount `json:"accounts"` // Every account, selected or not
+	Gross        float64              `json:"gross"`
+	Fees         float64              `json:"fees"`
+	FXCosts      float64              `json:"fx_costs"`
+	StockGain    float64              `json:"stock_gain"` // Net gain taxed as stock income
+	StockTax     float64              `json:"stock_tax"`
+	ASKTax       float64              `json:"ask_tax"`
+	PensionTax   float64              `json:"pension_tax"`
+	Net          float64              `json:"net"`
+	// Selected holdings without an average price, whose gain counts as 0
+	MissingCostBasis int `json:"missing_cost_basis"`
+	// Currencies without a provider or manual rate, counted 1:1
+	UnconvertedCurrencies []string `json:"unconverted_currencies,omitempty"`
+}
+
+// Tax returns the total tax of the sale.
+func (r *LiquidationResult) Tax() float64 {
+	return r.StockTax + r.ASKTax + r.PensionTax
+}
+
+// Costs returns the total brokerage fees and exchange spreads of the sale.
+func (r *LiquidationResult) Costs() float64 {
+	return r.Fees + r.FXCosts
+}
+
+// SelectedCount returns the number of positions sold.
+func (r *LiquidationResult) SelectedCount() int {
+	var n int
+	for _, a := range r.Accounts {
+		for _, p := range a.Positions {
+			if p.Selected {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// LiquidationTaxType returns how selling an account's holdings is taxed,
+// from the words of its name and category name. Accounts without holdings
+// are cash.
+func LiquidationTaxType(accountName, categoryName string, hasHoldings bool) string {
+	if !hasHoldings {
+		return LiquidationCash
+	}
+	words := strings.FieldsFunc(strings.ToLower(accountName+" "+categoryName), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		switch {
+		case w == "ask" || w == "aktiesparekonto":
+			return LiquidationASK
+		case strings.Contains(w, "pension") || w == "aldersopsparing":
+			return LiquidationPension
+		}
+	}
+	return LiquidationStock
+}
+
+// EstimateLiquidation estimates the net cash from selling the selected
+// accounts and holdings today, after brokerage fees, exchange spreads and
+// Danish tax at this year's tax parameters. ASK tax is approximated on the
+// gain over the cost basis rather than since the start of the year, and
+// stock income already realized this year is not known.
+func (s *PortfolioService) EstimateLiquidation(userID int64, settings LiquidationSettings) (*LiquidationResult, error) {
+	if err := ValidateLiquidationSettings(settings); err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.accountRepo.GetByUserIDActiveOnly(userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	categoryNames := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		categoryNames[c.ID] = c.Name
+	}
+
+	year := time.Now().Year()
+	tax := s.TaxParameters(year)
+	result := &LiquidationResult{
+		Settings:     settings,
+		BaseCurrency: s.baseCurrency,
+		TaxYear:      year,
+		Accounts:     make([]LiquidationAccount, 0),
+	}
+	unconverted := make(map[string]bool)
+	toBase := func(amount float64, currency string) float64 {
+		converted, ok := s.convertToBase(userID, amount, currency)
+		if !ok {
+			unconverted[currency] = true
+		}
+		return converted
+	}
+
+	askGains := make(map[int64]float64)
+	for _, account := range accounts {
+		if account.IsLiability {
+			continue
+		}
+		var categoryName string
+		if account.CategoryID != nil {
+			categoryName = categoryNames[*account.CategoryID]
+		}
+		holdings, err := s.holdingRepo.GetByAccountID(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		taxType := LiquidationTaxType(account.Name, categoryName, len(holdings) > 0)
+		sellAccount := slices.Contains(settings.AccountIDs, account.ID)
+
+		var positions []LiquidationPosition
+		if len(holdings) == 0 {
+			balance, err := s.transactionRepo.GetLatestBalance(account.ID)
+			if err != nil {
+				return nil, err
+			}
+			if balance <= 0 {
+				continue
+			}
+			positions = append(positions, LiquidationPosition{
+				AccountID:    account.ID,
+				AccountName:  account.Name,
+				Name:         account.Name,
+				Currency:     account.Currency,
+				TaxType:      taxType,
+				Value:        toBase(balance, account.Currency),
+				HasCostBasis: true,
+				Selected:     sellAccount,
+			})
+		}
+		for _, h := range holdings {
+			currency := h.Currency
+			if currency == "" {
+				currency = account.Currency
+			}
+			value := toBase(h.CurrentValue, currency)
+			p := LiquidationPosition{
+				AccountID:    account.ID,
+				HoldingID:    h.ID,
+				AccountName:  account.Name,
+				Symbol:       h.Symbol,
+				Name:         holdingName(h),
+				Currency:     currency,
+				TaxType:      taxType,
+				Value:        value,
+				Gain:         toBase(h.ProfitLoss(), currency),
+				HasCostBasis: h.CostBasisPrice() > 0,
+				Fee:          math.Max(value*settings.FeePct/100, settings.MinFee),
+				Selected:     sellAccount || slices.Contains(settings.HoldingIDs, h.ID),
+			}
+			if currency != s.baseCurrency {
+				p.FXCost = value * settings.FXSpreadPct / 100
+			}
+			positions = append(positions, p)
+		}
+		sort.SliceStable(positions, func(i, j int) bool {
+			return positions[i].Value > positions[j].Value
+		})
+
+		summary := LiquidationAccount{
+			ID:        account.ID,
+			Name:      account.Name,
+			TaxType:   taxType,
+			Selected:  sellAccount,
+			Positions: positions,
+		}
+		for _, p := range positions {
+			summary.Value += p.Value
+			if !p.Selected {
+				continue
+			}
+			result.Gross += p.Value
+			result.Fees += p.Fee
+			result.FXCosts += p.FXCost
+			if !p.HasCostBasis {
+				result.MissingCostBasis++
+			}
+			switch p.TaxType {
+			case LiquidationStock:
+				result.StockGain += p.Gain
+			case LiquidationASK:
+				askGains[p.AccountID] += p.Gain
+			case LiquidationPension:
+				result.PensionTax += p.Value * tax.PensionWithdrawalRate / 100
+			}
+		}
+		result.Accounts = append(result.Accounts, summary)
+	}
+
+	if result.StockGain > 0 {
+		threshold := tax.StockGainThreshold
+		if settings.Married {
+			threshold *= 2
+		}
+		low := math.Min(result.StockGain, threshold)
+		result.StockTax = low*tax.StockGainLowRate/100 + (result.StockGain-low)*tax.StockGainHighRate/100
+	}
+	// Losses of one ASK cannot offset gains of another
+	for _, gain := range askGains {
+		if gain > 0 {
+			result.ASKTax += gain * tax.ASKTaxRate / 100
+		}
+	}
+
+	result.Net = result.Gross - result.Costs() - result.Tax()
+	for currency := range unconverted {
+		result.UnconvertedCurrencies = append(result.UnconvertedCurrencies, currency)
+	}
+	sort.Strings(result.UnconvertedCurrencies)
+	return result, nil
+}
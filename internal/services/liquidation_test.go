@@ -0,0 +1,135 @@
+package services
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestLiquidationTaxType(t *testing.T) {
+	tests := []struct {
+		account, category string
+		hasHoldings       bool
+		want              string
+	}{
+		{"Nordnet ASK", "", true, LiquidationASK},
+		{"Depot", "Aktiesparekonto", true, LiquidationASK},
+		{"Basket", "Stocks", true, LiquidationStock},
+		{"Ratepension", "", true, LiquidationPension},
+		{"Saxo", "Aldersopsparing", true, LiquidationPension},
+		{"Nordnet ASK", "", false, LiquidationCash},
+	}
+	for _, tt := range tests {
+		if got := LiquidationTaxType(tt.account, tt.category, tt.hasHoldings); got != tt.want {
+			t.Errorf("LiquidationTaxType(%q, %q, %v) = %q; want %q", tt.account, tt.category, tt.hasHoldings, got, tt.want)
+		}
+	}
+}
+
+func TestValidateLiquidationSettings(t *testing.T) {
+	settings := DefaultLiquidationSettings()
+	if err := ValidateLiquidationSettings(settings); err != nil {
+		t.Errorf("ValidateLiquidationSettings(default) = %v; want nil", err)
+	}
+	settings.MinFee = -1
+	if err := ValidateLiquidationSettings(settings); err != ErrLiquidationNegative {
+		t.Errorf("ValidateLiquidationSettings() = %v; want %v", err, ErrLiquidationNegative)
+	}
+}
+
+func TestPortfolioService_EstimateLiquidation(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	holdingRepo := repository.NewHoldingRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	service := NewPortfolioService(accountRepo, holdingRepo, categoryRepo, transactionRepo, repository.NewAllocationTargetRepository(db))
+
+	userID, err := userRepo.Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	askCategoryID, err := categoryRepo.Create(&models.Category{UserID: userID, Name: "Aktiesparekonto"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	depotID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: depotID, Symbol: "SPY", Name: "S&P 500", Quantity: 10, AvgPrice: 2000, CurrentValue: 100000, Currency: "DKK"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: depotID, Symbol: "NOVO", Name: "Novo", Quantity: 1, AvgPrice: 30000, CurrentValue: 20000, Currency: "DKK"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	askID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Nordnet", Currency: "DKK", IsActive: true, CategoryID: &askCategoryID})
+	vwrlID, err := holdingRepo.Create(&models.Holding{AccountID: askID, Symbol: "VWRL", Name: "All-World", Quantity: 1, AvgPrice: 40000, CurrentValue: 50000, Currency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: askID, Symbol: "BOND", Name: "Bonds", Quantity: 1, CurrentValue: 5000, Currency: "DKK"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	savingsID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK", IsActive: true})
+	transactionRepo.Create(&models.Transaction{AccountID: savingsID, Amount: 50000, BalanceAfter: 50000, TransactionDate: time.Now()})
+
+	settings := DefaultLiquidationSettings()
+	settings.AccountIDs = []int64{depotID}
+	settings.HoldingIDs = []int64{vwrlID}
+	result, err := service.EstimateLiquidation(userID, settings)
+	if err != nil {
+		t.Fatalf("EstimateLiquidation() error = %v", err)
+	}
+
+	if len(result.Accounts) != 3 || result.SelectedCount() != 3 {
+		t.Fatalf("accounts = %d, selected = %d; want 3 accounts with 3 positions selected", len(result.Accounts), result.SelectedCount())
+	}
+	if result.Gross != 170000 {
+		t.Errorf("Gross = %.2f; want 170000", result.Gross)
+	}
+	// 0.1% of 100k and 50k, and the minimum fee on 20k
+	if math.Abs(result.Fees-179) > 1e-6 || result.FXCosts != 0 {
+		t.Errorf("Fees = %.2f, FXCosts = %.2f; want 179 and 0", result.Fees, result.FXCosts)
+	}
+	// The Novo loss offsets the S&P gain
+	if result.StockGain != 70000 {
+		t.Errorf("StockGain = %.2f; want 70000", result.StockGain)
+	}
+	low := math.Min(70000, StockGainThreshold)
+	wantStockTax := low*StockGainLowRate/100 + (70000-low)*StockGainHighRate/100
+	if math.Abs(result.StockTax-wantStockTax) > 1e-6 {
+		t.Errorf("StockTax = %.2f; want %.2f", result.StockTax, wantStockTax)
+	}
+	if wantASKTax := 10000 * ASKTaxRate / 100; math.Abs(result.ASKTax-wantASKTax) > 1e-6 {
+		t.Errorf("ASKTax = %.2f; want %.2f", result.ASKTax, wantASKTax)
+	}
+	if want := 170000 - 179 - result.Tax(); math.Abs(result.Net-want) > 1e-6 {
+		t.Errorf("Net = %.2f; want %.2f", result.Net, want)
+	}
+	if result.MissingCostBasis != 0 {
+		t.Errorf("MissingCostBasis = %d; want 0 as the bond fund is not sold", result.MissingCostBasis)
+	}
+
+	// Married couples share a doubled threshold
+	settings.Married = true
+	married, err := service.EstimateLiquidation(userID, settings)
+	if err != nil {
+		t.Fatalf("EstimateLiquidation() error = %v", err)
+	}
+	if married.StockTax > result.StockTax {
+		t.Errorf("married StockTax = %.2f; want at most %.2f", married.StockTax, result.StockTax)
+	}
+}
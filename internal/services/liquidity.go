@@ -0,0 +1,44 @@
+package services
+
+import (
+	"time"
+
+	"wealth_tracker/internal/dates"
+)
+
+// EmergencyFundMonths is the number of complete months whose outflows are
+// averaged into the monthly expenses of the emergency fund coverage.
+const EmergencyFundMonths = 12
+
+// EmergencyFundCoverage is how many months of expenses the liquid assets
+// would cover, the emergency fund indicator.
+type EmergencyFundCoverage struct {
+	LiquidAssets    float64 // Balance of asset accounts in liquid categories
+	MonthlyExpenses float64 // Average monthly outflow from those accounts
+	Months          float64 // LiquidAssets / MonthlyExpenses
+	HasLiquid       bool    // Whether any category is tagged liquid
+	HasExpenses     bool    // Whether Months is known
+}
+
+// EmergencyFundPeriod returns the complete statement periods whose outflows
+// make up the monthly expenses at now: from the start of the period
+// EmergencyFundMonths periods back to the day before the current period,
+// for periods starting on periodStartDay of the month.
+func EmergencyFundPeriod(now time.Time, periodStartDay int) (start, end time.Time) {
+	current := dates.PeriodStart(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), periodStartDay)
+	return current.AddDate(0, -EmergencyFundMonths, 0), current.AddDate(0, 0, -1)
+}
+
+// NewEmergencyFundCoverage computes the coverage of liquid assets from the
+// outflows of the liquid accounts over a number of months. Months without
+// any transactions are left out of the average, so a short history does not
+// understate the expenses.
+func NewEmergencyFundCoverage(liquidAssets, outflows float64, activeMonths int) EmergencyFundCoverage {
+	c := EmergencyFundCoverage{LiquidAssets: liquidAssets}
+	if activeMonths > 0 && outflows > 0 {
+		c.MonthlyExpenses = outflows / float64(activeMonths)
+		c.Months = liquidAssets / c.MonthlyExpenses
+		c.HasExpenses = true
+	}
+	return c
+}
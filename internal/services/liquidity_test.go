@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmergencyFundPeriod(t *testing.T) {
+	start, end := EmergencyFundPeriod(time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), 1)
+	if want := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %s; want %s", start, want)
+	}
+	if want := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("end = %s; want %s", end, want)
+	}
+}
+
+func TestEmergencyFundPeriod_PeriodStartDay(t *testing.T) {
+	// Salary on the 25th: the current period started 25 February
+	start, end := EmergencyFundPeriod(time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), 25)
+	if want := time.Date(2023, 2, 25, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("start = %s; want %s", start, want)
+	}
+	if want := time.Date(2024, 2, 24, 0, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("end = %s; want %s", end, want)
+	}
+}
+
+func TestNewEmergencyFundCoverage(t *testing.T) {
+	// Three months of history with 30,000 spent cover 60,000 twice over
+	c := NewEmergencyFundCoverage(60000, 30000, 3)
+	if !c.HasExpenses || c.MonthlyExpenses != 10000 || c.Months != 6 {
+		t.Errorf("coverage = %+v; want 10000 a month covered for 6 months", c)
+	}
+
+	if c := NewEmergencyFundCoverage(60000, 0, 3); c.HasExpenses {
+		t.Errorf("coverage without outflows = %+v; want unknown", c)
+	}
+	if c := NewEmergencyFundCoverage(60000, 500, 0); c.HasExpenses {
+		t.Errorf("coverage without history = %+v; want unknown", c)
+	}
+}
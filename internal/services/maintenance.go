@@ -0,0 +1,149 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"wealth_tracker/internal/database"
+)
+
+// ErrMaintenanceRunning is returned when maintenance is started while
+// another run is still in progress.
+var ErrMaintenanceRunning = errors.New("database maintenance is already running")
+
+// Maintainer runs database maintenance operations, as *database.DB does.
+type Maintainer interface {
+	Maintain(op string) database.MaintenanceStep
+}
+
+// MaintenanceRun reports the progress of a maintenance run.
+type MaintenanceRun struct {
+	StartedAt  time.Time
+	Scheduled  bool     // Started by the maintenance window rather than an admin
+	Operations []string // The operations of the run, in order
+	Steps      []database.MaintenanceStep
+	Done       bool
+}
+
+// Current returns the operation in progress, or "" if the run is done.
+func (r MaintenanceRun) Current() string {
+	if r.Done || len(r.Steps) >= len(r.Operations) {
+		return ""
+	}
+	return r.Operations[len(r.Steps)]
+}
+
+// Duration returns the time the finished steps took.
+func (r MaintenanceRun) Duration() time.Duration {
+	var d time.Duration
+	for _, step := range r.Steps {
+		d += step.Duration
+	}
+	return d
+}
+
+// Failed returns the number of steps that failed.
+func (r MaintenanceRun) Failed() int {
+	n := 0
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// MaintenanceService runs database maintenance one run at a time, on demand
+// or daily in a maintenance window, and remembers the last run.
+type MaintenanceService struct {
+	db   Maintainer
+	hour int // Hour of the maintenance window, or -1 if there is none
+
+	mu            sync.Mutex
+	last          *MaintenanceRun
+	lastScheduled time.Time
+}
+
+// NewMaintenanceService creates a MaintenanceService. A full run starts
+// daily in the hour from hour:00 local time; a negative hour disables it.
+func NewMaintenanceService(db Maintainer, hour int) *MaintenanceService {
+	return &MaintenanceService{db: db, hour: hour}
+}
+
+// WindowHour returns the hour of the maintenance window, or -1 if there is
+// none.
+func (s *MaintenanceService) WindowHour() int {
+	return s.hour
+}
+
+// Start runs operations in the background and returns a channel closed when
+// they are done. A failing operation does not stop the others.
+func (s *MaintenanceService) Start(ops []string, now time.Time) (<-chan struct{}, error) {
+	return s.start(ops, now, false)
+}
+
+func (s *MaintenanceService) start(ops []string, now time.Time, scheduled bool) (<-chan struct{}, error) {
+	s.mu.Lock()
+	if s.last != nil && !s.last.Done {
+		s.mu.Unlock()
+		return nil, ErrMaintenanceRunning
+	}
+	run := &MaintenanceRun{StartedAt: now, Scheduled: scheduled, Operations: ops}
+	s.last = run
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, op := range ops {
+			step := s.db.Maintain(op)
+			s.mu.Lock()
+			run.Steps = append(run.Steps, step)
+			s.mu.Unlock()
+		}
+		s.mu.Lock()
+		run.Done = true
+		s.mu.Unlock()
+	}()
+	return done, nil
+}
+
+// StartDue starts a full run if now is in the maintenance window and none
+// has been started by it today. It returns nil if no run was started.
+func (s *MaintenanceService) StartDue(now time.Time) <-chan struct{} {
+	if s.hour < 0 || now.Hour() != s.hour {
+		return nil
+	}
+	s.mu.Lock()
+	y1, m1, d1 := s.lastScheduled.Date()
+	y2, m2, d2 := now.Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	done, err := s.start(database.MaintenanceOperations, now, true)
+	if err != nil {
+		// An admin's run is in progress; the next check tries again
+		return nil
+	}
+	s.mu.Lock()
+	s.lastScheduled = now
+	s.mu.Unlock()
+	return done
+}
+
+// LastRun returns a copy of the latest run, or nil if maintenance has not
+// run yet.
+func (s *MaintenanceService) LastRun() *MaintenanceRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return nil
+	}
+	run := *s.last
+	run.Steps = append([]database.MaintenanceStep(nil), s.last.Steps...)
+	return &run
+}
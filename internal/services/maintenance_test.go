@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+)
+
+// fakeMaintainer records the operations it runs, failing those in fail and
+// blocking each until release is closed.
+type fakeMaintainer struct {
+	ran     []string
+	fail    map[string]bool
+	release chan struct{}
+}
+
+func (f *fakeMaintainer) Maintain(op string) database.MaintenanceStep {
+	if f.release != nil {
+		<-f.release
+	}
+	f.ran = append(f.ran, op)
+	step := database.MaintenanceStep{Operation: op, Duration: time.Second, Detail: "done"}
+	if f.fail[op] {
+		step.Err = errors.New("disk full")
+	}
+	return step
+}
+
+func TestMaintenanceService_RunsAllOperationsDespiteFailures(t *testing.T) {
+	db := &fakeMaintainer{fail: map[string]bool{database.MaintenanceAnalyze: true}}
+	svc := NewMaintenanceService(db, -1)
+
+	done, err := svc.Start(database.MaintenanceOperations, time.Now())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	<-done
+
+	run := svc.LastRun()
+	if run == nil || !run.Done || len(run.Steps) != 3 || run.Failed() != 1 || run.Current() != "" {
+		t.Fatalf("last run = %+v; want 3 finished steps with 1 failure", run)
+	}
+	if run.Duration() != 3*time.Second {
+		t.Errorf("Duration = %v, want the sum of the steps", run.Duration())
+	}
+}
+
+func TestMaintenanceService_OneRunAtATime(t *testing.T) {
+	db := &fakeMaintainer{release: make(chan struct{})}
+	svc := NewMaintenanceService(db, -1)
+
+	done, err := svc.Start([]string{database.MaintenanceVacuum}, time.Now())
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if run := svc.LastRun(); run.Current() != database.MaintenanceVacuum {
+		t.Errorf("Current = %q while vacuuming", run.Current())
+	}
+	if _, err := svc.Start([]string{database.MaintenanceAnalyze}, time.Now()); !errors.Is(err, ErrMaintenanceRunning) {
+		t.Errorf("second Start err = %v, want ErrMaintenanceRunning", err)
+	}
+	close(db.release)
+	<-done
+}
+
+func TestMaintenanceService_StartDueOncePerDayInWindow(t *testing.T) {
+	db := &fakeMaintainer{}
+	svc := NewMaintenanceService(db, 3)
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+
+	if svc.StartDue(day.Add(2*time.Hour)) != nil {
+		t.Error("maintenance started before the window")
+	}
+	done := svc.StartDue(day.Add(3*time.Hour + 10*time.Minute))
+	if done == nil {
+		t.Fatal("maintenance did not start in the window")
+	}
+	<-done
+	if svc.StartDue(day.Add(3*time.Hour+40*time.Minute)) != nil {
+		t.Error("maintenance started twice in one window")
+	}
+	if !svc.LastRun().Scheduled || len(db.ran) != len(database.MaintenanceOperations) {
+		t.Errorf("scheduled run ran %v; want every operation", db.ran)
+	}
+
+	done = svc.StartDue(day.AddDate(0, 0, 1).Add(3 * time.Hour))
+	if done == nil {
+		t.Fatal("maintenance did not start in the next day's window")
+	}
+	<-done
+
+	if NewMaintenanceService(db, -1).StartDue(day.Add(3*time.Hour)) != nil {
+		t.Error("maintenance started without a window")
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// DefaultMilestoneStep is the default net worth milestone interval (DKK).
+const DefaultMilestoneStep = 100000.0
+
+// maxMilestones bounds the number of milestones detected in one history.
+const maxMilestones = 1000
+
+// DetectMilestones returns, for every multiple of step that net worth has
+// reached, the first date it did so. A milestone is only reached once, even if
+// net worth later drops below it again.
+func DetectMilestones(history []repository.NetWorthPoint, step float64) []*models.NetWorthMilestone {
+	if step <= 0 {
+		return nil
+	}
+
+	var milestones []*models.NetWorthMilestone
+	next := step
+	for _, p := range history {
+		for p.NetWorth >= next && len(milestones) < maxMilestones {
+			milestones = append(milestones, &models.NetWorthMilestone{
+				Amount:    next,
+				ReachedAt: p.Date,
+			})
+			next += step
+		}
+	}
+	return milestones
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+
+	"wealth_tracker/internal/repository"
+)
+
+func TestDetectMilestones(t *testing.T) {
+	history := []repository.NetWorthPoint{
+		{Date: day(1), NetWorth: 50000},
+		{Date: day(2), NetWorth: 120000},
+		{Date: day(3), NetWorth: 90000},  // drop below 100k
+		{Date: day(4), NetWorth: 110000}, // 100k is not reached again
+		{Date: day(5), NetWorth: 310000}, // crosses 200k and 300k at once
+	}
+
+	got := DetectMilestones(history, 100000)
+	want := []struct {
+		amount float64
+		day    int
+	}{
+		{100000, 2},
+		{200000, 5},
+		{300000, 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("DetectMilestones() returned %d milestones, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Amount != w.amount || !got[i].ReachedAt.Equal(day(w.day)) {
+			t.Errorf("milestone %d = %.0f on %s; want %.0f on %s", i, got[i].Amount, got[i].ReachedAt, w.amount, day(w.day))
+		}
+	}
+
+	if got := DetectMilestones(history, 0); got != nil {
+		t.Errorf("DetectMilestones() with step 0 = %v; want nil", got)
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// NetWorthAccount is an account's part of a user's net worth.
+type NetWorthAccount struct {
+	Account *models.Account
+	Balance float64 // In the account's currency, as recorded
+	Worth   float64 // In the user's currency, negative for liabilities
+}
+
+// NetWorth is a user's net worth in their default currency, with what it is
+// made of.
+type NetWorth struct {
+	Assets         float64
+	Liabilities    float64 // Positive, however liabilities are recorded
+	NetWorth       float64
+	AssetCount     int
+	LiabilityCount int
+	Accounts       []NetWorthAccount
+	Categories     map[int64]float64 // Net worth of each category
+	CategoryAssets map[int64]float64 // Assets of each category
+}
+
+// newNetWorth adds up the accounts' worth.
+func newNetWorth(accounts []NetWorthAccount) *NetWorth {
+	n := &NetWorth{
+		Accounts:       accounts,
+		Categories:     make(map[int64]float64),
+		CategoryAssets: make(map[int64]float64),
+	}
+	for _, a := range accounts {
+		if a.Account.IsLiability {
+			n.Liabilities -= a.Worth
+			n.LiabilityCount++
+		} else {
+			n.Assets += a.Worth
+			n.AssetCount++
+		}
+		if a.Account.CategoryID != nil {
+			n.Categories[*a.Account.CategoryID] += a.Worth
+			if !a.Account.IsLiability {
+				n.CategoryAssets[*a.Account.CategoryID] += a.Worth
+			}
+		}
+	}
+	n.NetWorth = n.Assets - n.Liabilities
+	return n
+}
+
+// Excluding returns the net worth without the excluded accounts.
+func (n *NetWorth) Excluding(excluded map[int64]bool) *NetWorth {
+	if len(excluded) == 0 {
+		return n
+	}
+	accounts := make([]NetWorthAccount, 0, len(n.Accounts))
+	for _, a := range n.Accounts {
+		if !excluded[a.Account.ID] {
+			accounts = append(accounts, a)
+		}
+	}
+	return newNetWorth(accounts)
+}
+
+// ForGoal returns the worth a goal tracks: the net worth of its category, or
+// the whole net worth.
+func (n *NetWorth) ForGoal(goal *models.Goal) float64 {
+	if goal.CategoryID != nil {
+		return n.Categories[*goal.CategoryID]
+	}
+	return n.NetWorth
+}
+
+// NetWorthService computes the net worth of users. The dashboard, goals,
+// digests and snapshots all get it from here, so they show the same figures.
+type NetWorthService struct {
+	userRepo        *repository.UserRepository
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	currency        *CurrencyService // Nil adds up balances in any currency as they are
+}
+
+// NewNetWorthService creates a new NetWorthService.
+func NewNetWorthService(
+	userRepo *repository.UserRepository,
+	accountRepo *repository.AccountRepository,
+	transactionRepo *repository.TransactionRepository,
+	currency *CurrencyService,
+) *NetWorthService {
+	return &NetWorthService{
+		userRepo:        userRepo,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		currency:        currency,
+	}
+}
+
+// Compute returns the user's net worth from the latest balances of their
+// active accounts, or if asOf is set, from the balances at the end of that
+// day of the accounts open on it. Balances are converted to the user's
+// default currency, as of a past day at its rate where known.
+func (s *NetWorthService) Compute(userID int64, asOf *time.Time) (*NetWorth, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("getting user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d not found", userID)
+	}
+
+	var accounts []*models.Account
+	if asOf != nil {
+		accounts, err = s.accountRepo.GetByUserIDOpenOn(userID, *asOf)
+	} else {
+		accounts, err = s.accountRepo.GetByUserIDActiveOnly(userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting accounts: %w", err)
+	}
+
+	parts := make([]NetWorthAccount, 0, len(accounts))
+	for _, account := range accounts {
+		var balance float64
+		if asOf != nil {
+			balance, err = s.transactionRepo.GetBalanceAt(account.ID, *asOf)
+		} else {
+			balance, err = s.transactionRepo.GetLatestBalance(account.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("getting balance of account %d: %w", account.ID, err)
+		}
+
+		worth := s.convert(user, balance, account.Currency, asOf)
+		if account.IsLiability {
+			// Liabilities count against net worth however their balance is recorded
+			worth = -math.Abs(worth)
+		}
+		parts = append(parts, NetWorthAccount{Account: account, Balance: balance, Worth: worth})
+	}
+	return newNetWorth(parts), nil
+}
+
+// convert converts an amount to the user's default currency, at the rate of
+// the as-of day where known.
+func (s *NetWorthService) convert(user *models.User, amount float64, currency string, asOf *time.Time) float64 {
+	if s.currency == nil || currency == "" || currency == user.DefaultCurrency || user.DefaultCurrency == "" {
+		return amount
+	}
+	if asOf != nil {
+		if rate, ok := s.currency.RateForUserOn(user.ID, currency, user.DefaultCurrency, *asOf); ok {
+			return amount * rate
+		}
+	}
+	converted, _ := s.currency.ConvertForUser(user.ID, amount, currency, user.DefaultCurrency)
+	return converted
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// NetWorthChange splits the change in net worth over a period into what
+// caused it, in the user's default currency.
+type NetWorthChange struct {
+	Label       string    `json:"label"`
+	From        time.Time `json:"from"` // End of the day before the period
+	To          time.Time `json:"to"`
+	Start       float64   `json:"start"`
+	Deposits    float64   `json:"deposits"`     // Money moved in and out of assets
+	Market      float64   `json:"market"`       // Balance updates, returns and interest
+	FX          float64   `json:"fx"`           // Exchange rate changes on foreign balances
+	DebtPaydown float64   `json:"debt_paydown"` // Money paid into liabilities, less new borrowing
+	End         float64   `json:"end"`
+}
+
+// DecomposeNetWorthChange splits the change in net worth between the end of
+// two days using each account's balance history and money moved, as summed
+// by GetFlowsByAccount. startRates and endRates convert each currency to the
+// user's currency at the two dates; a currency missing from them converts
+// 1:1.
+//
+// Balances at the start are revalued at the end rates as the FX effect, and
+// the rest of each account's change is taken at the end rates. Money moved
+// in or out of an asset is a deposit or withdrawal, into or out of a
+// liability debt paydown or borrowing, and whatever else changed the
+// balance, such as balance updates or accrued interest, market movement.
+func DecomposeNetWorthChange(
+	accounts []*models.Account,
+	history map[int64][]repository.BalancePoint,
+	flows map[int64]float64,
+	startRates, endRates map[string]float64,
+	from, to time.Time,
+) NetWorthChange {
+	c := NetWorthChange{From: from, To: to}
+	rate := func(rates map[string]float64, currency string) float64 {
+		if r, ok := rates[currency]; ok {
+			return r
+		}
+		return 1
+	}
+
+	for _, account := range accounts {
+		points := history[account.ID]
+		fromWorth, toWorth, flow := netWorthEffect(account, balanceAt(points, from), balanceAt(points, to), flows[account.ID])
+		if fromWorth == 0 && toWorth == 0 && flow == 0 {
+			continue
+		}
+
+		startRate, endRate := rate(startRates, account.Currency), rate(endRates, account.Currency)
+		c.Start += fromWorth * startRate
+		c.End += toWorth * endRate
+		c.FX += fromWorth * (endRate - startRate)
+		if account.IsLiability {
+			c.DebtPaydown += flow * endRate
+		} else {
+			c.Deposits += flow * endRate
+		}
+		c.Market += (toWorth - fromWorth - flow) * endRate
+	}
+	return c
+}
+
+// NetWorthChangeService decomposes the changes in users' net worth over
+// recent periods for the dashboard.
+type NetWorthChangeService struct {
+	accountRepo     *repository.AccountRepository
+	transactionRepo *repository.TransactionRepository
+	currency        *CurrencyService
+	now             func() time.Time
+}
+
+// NewNetWorthChangeService creates a new NetWorthChangeService.
+func NewNetWorthChangeService(accountRepo *repository.AccountRepository, transactionRepo *repository.TransactionRepository, currency *CurrencyService) *NetWorthChangeService {
+	return &NetWorthChangeService{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		currency:        currency,
+		now:             time.Now,
+	}
+}
+
+// Recent decomposes the change in the user's net worth this month, this year
+// and over the last 12 months, up to today.
+func (s *NetWorthChangeService) Recent(user *models.User) ([]NetWorthChange, error) {
+	now := s.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	periods := []struct {
+		label string
+		from  time.Time
+	}{
+		{"Month", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)},
+		{"Year", time.Date(now.Year()-1, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"12 months", today.AddDate(-1, 0, 0)},
+	}
+
+	accounts, err := s.accountRepo.GetByUserIDWithHistory(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.transactionRepo.GetBalanceHistoryByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	endRates := make(map[string]float64)
+	for _, account := range accounts {
+		if _, ok := endRates[account.Currency]; !ok {
+			endRates[account.Currency], _ = s.currency.ConvertForUser(user.ID, 1, account.Currency, user.DefaultCurrency)
+		}
+	}
+
+	changes := make([]NetWorthChange, 0, len(periods))
+	for _, p := range periods {
+		flows, err := s.transactionRepo.GetFlowsByAccount(user.ID, p.from, today)
+		if err != nil {
+			return nil, err
+		}
+		// Without a rate recorded at the start, no FX effect is shown
+		startRates := make(map[string]float64, len(endRates))
+		for currency, endRate := range endRates {
+			startRates[currency] = endRate
+			if r, ok := s.currency.RateForUserOn(user.ID, currency, user.DefaultCurrency, p.from); ok {
+				startRates[currency] = r
+			}
+		}
+
+		change := DecomposeNetWorthChange(accounts, history, flows, startRates, endRates, p.from, today)
+		change.Label = p.label
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestDecomposeNetWorthChange(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	accounts := []*models.Account{
+		{ID: 1, Name: "Savings", Currency: "DKK"},
+		{ID: 2, Name: "Broker", Currency: "USD"},
+		{ID: 3, Name: "Loan", Currency: "DKK", IsLiability: true},
+		{ID: 4, Name: "Empty", Currency: "EUR"},
+	}
+	history := map[int64][]repository.BalancePoint{
+		1: {{Date: day("2024-01-01"), Balance: 1000}, {Date: day("2024-02-15"), Balance: 1500}},
+		2: {{Date: day("2024-01-01"), Balance: 100}, {Date: day("2024-02-20"), Balance: 130}},
+		3: {{Date: day("2024-01-01"), Balance: 10000}, {Date: day("2024-02-01"), Balance: 9000}},
+	}
+	flows := map[int64]float64{
+		1: 500,   // Deposit
+		2: 10,    // Bought for 10, grew 20
+		3: -1000, // Loan payment
+	}
+	startRates := map[string]float64{"USD": 6}
+	endRates := map[string]float64{"USD": 7}
+
+	got := DecomposeNetWorthChange(accounts, history, flows, startRates, endRates, day("2024-01-31"), day("2024-02-29"))
+
+	want := NetWorthChange{
+		From:        day("2024-01-31"),
+		To:          day("2024-02-29"),
+		Start:       1000 + 100*6 - 10000,
+		Deposits:    500 + 10*7,
+		Market:      20 * 7,
+		FX:          100 * (7 - 6),
+		DebtPaydown: 1000,
+		End:         1500 + 130*7 - 9000,
+	}
+	if got != want {
+		t.Errorf("DecomposeNetWorthChange() = %+v; want %+v", got, want)
+	}
+	if sum := got.Start + got.Deposits + got.Market + got.FX + got.DebtPaydown; sum != got.End {
+		t.Errorf("parts add up to %v; want the end %v", sum, got.End)
+	}
+}
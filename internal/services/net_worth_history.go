@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/dates"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// NetWorthSnapshotService records the daily net worth of users and serves
+// it as history.
+type NetWorthSnapshotService struct {
+	userRepo        *repository.UserRepository
+	netWorth        *NetWorthService
+	transactionRepo *repository.TransactionRepository
+	snapshotRepo    *repository.NetWorthSnapshotRepository
+}
+
+// NewNetWorthSnapshotService creates a new NetWorthSnapshotService.
+func NewNetWorthSnapshotService(
+	userRepo *repository.UserRepository,
+	netWorth *NetWorthService,
+	transactionRepo *repository.TransactionRepository,
+	snapshotRepo *repository.NetWorthSnapshotRepository,
+) *NetWorthSnapshotService {
+	return &NetWorthSnapshotService{
+		userRepo:        userRepo,
+		netWorth:        netWorth,
+		transactionRepo: transactionRepo,
+		snapshotRepo:    snapshotRepo,
+	}
+}
+
+// RecordAll records the net worth of every user on the day of now, after
+// backfilling the days before their first snapshot, and returns how many
+// users were recorded. A failure for one user is logged and does not stop
+// the others.
+func (s *NetWorthSnapshotService) RecordAll(now time.Time) (int, error) {
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("getting users: %w", err)
+	}
+
+	recorded := 0
+	for _, user := range users {
+		if _, err := s.Backfill(user, now); err != nil {
+			log.Printf("[Net worth] Backfilling net worth of user %d failed: %v", user.ID, err)
+		}
+		if err := s.Record(user, now); err != nil {
+			log.Printf("[Net worth] Recording net worth of user %d failed: %v", user.ID, err)
+			continue
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// Record records the user's current assets, liabilities and net worth, with
+// the assets per category, as the snapshot of the day of now in the user's
+// time zone. Later records on the same day replace it.
+func (s *NetWorthSnapshotService) Record(user *models.User, now time.Time) error {
+	netWorth, err := s.netWorth.Compute(user.ID, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.snapshotRepo.Upsert(newNetWorthSnapshot(user.ID, NetWorthDay(now, user.Timezone), netWorth, now)); err != nil {
+		return fmt.Errorf("saving snapshot: %w", err)
+	}
+	return nil
+}
+
+// Backfill records the user's net worth at the end of the days before their
+// first snapshot that their balances changed on, back to their first
+// transaction, and returns how many days were added. It finds nothing to add
+// once done, until older balances are imported.
+func (s *NetWorthSnapshotService) Backfill(user *models.User, now time.Time) (int, error) {
+	first, err := s.transactionRepo.GetFirstDayByUserID(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("getting first transaction: %w", err)
+	}
+	end, err := s.snapshotRepo.GetFirstDay(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("getting first snapshot: %w", err)
+	}
+	if end.IsZero() {
+		// Today is recorded by Record
+		end = NetWorthDay(now, user.Timezone)
+	}
+	if first.IsZero() || !first.Before(end) {
+		return 0, nil
+	}
+
+	// The first day is recorded even if the history leaves it out, so the
+	// next backfill finds the snapshots reaching back to it
+	history, err := s.transactionRepo.GetNetWorthHistory(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("getting net worth history: %w", err)
+	}
+	days := []time.Time{first}
+	for _, p := range history {
+		if p.Date.After(first) && p.Date.Before(end) {
+			days = append(days, p.Date)
+		}
+	}
+
+	for i, day := range days {
+		netWorth, err := s.netWorth.Compute(user.ID, &day)
+		if err != nil {
+			return i, err
+		}
+		if err := s.snapshotRepo.Upsert(newNetWorthSnapshot(user.ID, day, netWorth, now)); err != nil {
+			return i, fmt.Errorf("saving snapshot: %w", err)
+		}
+	}
+	return len(days), nil
+}
+
+// newNetWorthSnapshot returns the snapshot of a net worth on a day, with the
+// assets per category.
+func newNetWorthSnapshot(userID int64, day time.Time, netWorth *NetWorth, now time.Time) *models.NetWorthSnapshot {
+	return &models.NetWorthSnapshot{
+		UserID:      userID,
+		Day:         day,
+		Assets:      netWorth.Assets,
+		Liabilities: netWorth.Liabilities,
+		NetWorth:    netWorth.NetWorth,
+		Categories:  netWorth.CategoryAssets,
+		RecordedAt:  now,
+	}
+}
+
+// History returns the user's daily snapshots from one day to another,
+// inclusive, oldest first. A zero from or to leaves that end open.
+func (s *NetWorthSnapshotService) History(userID int64, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	return s.snapshotRepo.GetByUserID(userID, from, to)
+}
+
+// Points returns the user's daily net worth for charts, oldest first, up to
+// the end of a day if to is not zero. The days before the first snapshot are
+// backfilled first, so the history reaches back to the first transaction.
+func (s *NetWorthSnapshotService) Points(user *models.User, to time.Time) ([]repository.NetWorthPoint, error) {
+	if _, err := s.Backfill(user, time.Now()); err != nil {
+		return nil, fmt.Errorf("backfilling: %w", err)
+	}
+	snapshots, err := s.snapshotRepo.GetByUserID(user.ID, time.Time{}, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]repository.NetWorthPoint, len(snapshots))
+	for i, snapshot := range snapshots {
+		points[i] = repository.NetWorthPoint{Date: snapshot.Day, NetWorth: snapshot.NetWorth}
+	}
+	return points, nil
+}
+
+// NetWorthDay returns the day a snapshot taken at t belongs to: midnight UTC
+// of the date in the time zone, or the server's if empty.
+func NetWorthDay(t time.Time, timezone string) time.Time {
+	t = t.In(dates.Location(timezone))
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestNetWorthSnapshotService_Record(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	user := &models.User{Email: "test@example.com", PasswordHash: "x", Name: "Test", Timezone: "Pacific/Auckland"}
+	if user.ID, err = userRepo.Create(user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	categoryID, err := repository.NewCategoryRepository(db).Create(&models.Category{UserID: user.ID, Name: "Aktier", Color: "#000000"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	balances := []struct {
+		account models.Account
+		balance float64
+	}{
+		{models.Account{Name: "Depot", CategoryID: &categoryID}, 1000},
+		{models.Account{Name: "Cash"}, 250},
+		{models.Account{Name: "Loan", IsLiability: true}, -400},
+		{models.Account{Name: "Closed"}, 5000},
+	}
+	for i, b := range balances {
+		account := b.account
+		account.UserID, account.Currency, account.IsActive = user.ID, "DKK", i < 3
+		accountID, err := accountRepo.Create(&account)
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		if _, err := transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: b.balance, BalanceAfter: b.balance, TransactionDate: time.Now()}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+
+	netWorth := NewNetWorthService(userRepo, accountRepo, transactionRepo, nil)
+	s := NewNetWorthSnapshotService(userRepo, netWorth, transactionRepo, repository.NewNetWorthSnapshotRepository(db))
+	// Late evening UTC is the next morning in Auckland
+	now := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+	if err := s.Record(user, now); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	history, err := s.History(user.ID, time.Time{}, time.Time{})
+	if err != nil || len(history) != 1 {
+		t.Fatalf("History() = %+v, %v; want one snapshot", history, err)
+	}
+	got := history[0]
+	if !got.Day.Equal(time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Day = %v; want the day in the user's time zone", got.Day)
+	}
+	if got.Assets != 1250 || got.Liabilities != 400 || got.NetWorth != 850 {
+		t.Errorf("snapshot = %+v; want assets 1250, liabilities 400, net worth 850 of the active accounts", got)
+	}
+	if len(got.Categories) != 1 || got.Categories[categoryID] != 1000 {
+		t.Errorf("Categories = %v; want the depot's category", got.Categories)
+	}
+}
+
+func TestNetWorthSnapshotService_Backfill(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	user := &models.User{Email: "test@example.com", PasswordHash: "x", Name: "Test", Timezone: "UTC"}
+	if user.ID, err = userRepo.Create(user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	accountID, err := accountRepo.Create(&models.Account{UserID: user.ID, Name: "Savings", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	addBalance := func(day time.Time, balance float64) {
+		t.Helper()
+		if _, err := transactionRepo.Create(&models.Transaction{AccountID: accountID, Amount: balance, BalanceAfter: balance, TransactionDate: day}); err != nil {
+			t.Fatalf("creating transaction: %v", err)
+		}
+	}
+	addBalance(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 1000)
+	addBalance(time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), 1500)
+
+	s := NewNetWorthSnapshotService(userRepo, NewNetWorthService(userRepo, accountRepo, transactionRepo, nil), transactionRepo, repository.NewNetWorthSnapshotRepository(db))
+	now := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.Record(user, now); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	if n, err := s.Backfill(user, now); err != nil || n != 2 {
+		t.Fatalf("Backfill() = %d, %v; want the 2 days before the first snapshot", n, err)
+	}
+	if n, _ := s.Backfill(user, now); n != 0 {
+		t.Errorf("second Backfill() added %d days; want none", n)
+	}
+
+	// An older balance imported later is backfilled too
+	addBalance(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 500)
+	if n, err := s.Backfill(user, now); err != nil || n != 1 {
+		t.Fatalf("Backfill() after an import = %d, %v; want the imported day", n, err)
+	}
+
+	points, err := s.Points(user, time.Time{})
+	if err != nil {
+		t.Fatalf("Points() error = %v", err)
+	}
+	want := []repository.NetWorthPoint{
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), NetWorth: 500},
+		{Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), NetWorth: 1000},
+		{Date: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), NetWorth: 1500},
+		{Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), NetWorth: 1500},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("Points() = %+v; want %+v", points, want)
+	}
+	for i := range want {
+		if !points[i].Date.Equal(want[i].Date) || points[i].NetWorth != want[i].NetWorth {
+			t.Errorf("Points()[%d] = %+v; want %+v", i, points[i], want[i])
+		}
+	}
+}
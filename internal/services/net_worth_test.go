@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestNetWorthService_Compute(t *testing.T) {
+	currency, db, userID := setupCurrencyTest(t)
+	userRepo := repository.NewUserRepository(db)
+	user, _ := userRepo.GetByID(userID)
+	user.DefaultCurrency = "DKK"
+	if err := userRepo.Update(user); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+	categoryID, err := repository.NewCategoryRepository(db).Create(&models.Category{UserID: userID, Name: "Aktier", Color: "#000000"})
+	if err != nil {
+		t.Fatalf("creating category: %v", err)
+	}
+
+	accountRepo := repository.NewAccountRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	earlier := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	accounts := make(map[string]int64)
+	for _, a := range []struct {
+		account  models.Account
+		balances map[time.Time]float64
+	}{
+		{models.Account{Name: "Depot", Currency: "USD", CategoryID: &categoryID, IsActive: true}, map[time.Time]float64{earlier: 50, time.Now(): 100}},
+		{models.Account{Name: "Cash", Currency: "DKK", IsActive: true}, map[time.Time]float64{earlier: 100, time.Now(): 300}},
+		{models.Account{Name: "Loan", Currency: "DKK", CategoryID: &categoryID, IsLiability: true, IsActive: true}, map[time.Time]float64{time.Now(): -200}},
+		{models.Account{Name: "Closed", Currency: "DKK"}, map[time.Time]float64{time.Now(): 5000}},
+	} {
+		account := a.account
+		account.UserID = userID
+		id, err := accountRepo.Create(&account)
+		if err != nil {
+			t.Fatalf("creating account: %v", err)
+		}
+		accounts[account.Name] = id
+		for day, balance := range a.balances {
+			if _, err := transactionRepo.Create(&models.Transaction{AccountID: id, Amount: balance, BalanceAfter: balance, TransactionDate: day}); err != nil {
+				t.Fatalf("creating transaction: %v", err)
+			}
+		}
+	}
+
+	s := NewNetWorthService(userRepo, accountRepo, transactionRepo, currency)
+	got, err := s.Compute(userID, nil)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+	// The depot's 100 USD is 700 DKK
+	if got.Assets != 1000 || got.Liabilities != 200 || got.NetWorth != 800 || got.AssetCount != 2 || got.LiabilityCount != 1 {
+		t.Errorf("Compute() = %+v; want assets 1000, liabilities 200 and net worth 800 of the active accounts in DKK", got)
+	}
+	if got.Categories[categoryID] != 500 || got.CategoryAssets[categoryID] != 700 {
+		t.Errorf("Categories, CategoryAssets = %v, %v; want 500 net and 700 of assets", got.Categories, got.CategoryAssets)
+	}
+	if worth := got.ForGoal(&models.Goal{CategoryID: &categoryID}); worth != 500 {
+		t.Errorf("ForGoal() of a category goal = %v; want 500", worth)
+	}
+	if worth := got.ForGoal(&models.Goal{}); worth != 800 {
+		t.Errorf("ForGoal() of a net worth goal = %v; want 800", worth)
+	}
+
+	without := got.Excluding(map[int64]bool{accounts["Cash"]: true})
+	if without.NetWorth != 500 || without.AssetCount != 1 || got.NetWorth != 800 {
+		t.Errorf("Excluding() = %+v; want 500 without the cash, leaving the whole net worth as it was", without)
+	}
+
+	asOf := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	then, err := s.Compute(userID, &asOf)
+	if err != nil {
+		t.Fatalf("Compute() as of %v error = %v", asOf, err)
+	}
+	if then.NetWorth != 450 || then.Liabilities != 0 {
+		t.Errorf("Compute() as of %v = %+v; want 450 from the balances then", asOf, then)
+	}
+}
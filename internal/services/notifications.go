@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/notify"
+	"wealth_tracker/internal/repository"
+)
+
+// maxChannelName is the longest name a notification channel may have.
+const maxChannelName = 100
+
+// ErrChannelNameRequired is returned for notification channels without a name.
+var ErrChannelNameRequired = errors.New("name is required")
+
+// Notifier sends a user's alerts to their notification channels.
+type Notifier struct {
+	channelRepo *repository.NotificationChannelRepository
+
+	// allowPrivateNetworks lets channels reach private and local addresses.
+	allowPrivateNetworks bool
+
+	// newChannel creates the channel a notification is delivered through;
+	// replaced in tests.
+	newChannel func(kind string, cfg notify.Config) (notify.Channel, error)
+}
+
+// NewNotifier creates a new Notifier.
+func NewNotifier(channelRepo *repository.NotificationChannelRepository) *Notifier {
+	return &Notifier{channelRepo: channelRepo, newChannel: notify.New}
+}
+
+// SetAllowPrivateNetworks lets channels reach loopback, private and
+// link-local addresses, such as notification servers on the LAN.
+func (n *Notifier) SetAllowPrivateNetworks(allow bool) {
+	n.allowPrivateNetworks = allow
+}
+
+// AddChannel validates and stores a notification channel for the user.
+func (n *Notifier) AddChannel(userID int64, kind, name string, cfg notify.Config) (*models.NotificationChannel, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrChannelNameRequired
+	}
+	if len(name) > maxChannelName {
+		name = name[:maxChannelName]
+	}
+	cfg.URL = strings.TrimSpace(cfg.URL)
+	cfg.Topic = strings.TrimSpace(cfg.Topic)
+	cfg.Token = strings.TrimSpace(cfg.Token)
+	if kind == notify.KindNtfy && cfg.URL == "" {
+		cfg.URL = notify.DefaultNtfyServer
+	}
+	cfg.AllowPrivateNetworks = n.allowPrivateNetworks
+	if _, err := n.newChannel(kind, cfg); err != nil {
+		return nil, err
+	}
+
+	channel := &models.NotificationChannel{
+		UserID:   userID,
+		Kind:     kind,
+		Name:     name,
+		URL:      cfg.URL,
+		Topic:    cfg.Topic,
+		Token:    cfg.Token,
+		IsActive: true,
+	}
+	id, err := n.channelRepo.Create(channel)
+	if err != nil {
+		return nil, err
+	}
+	channel.ID = id
+	return channel, nil
+}
+
+// Notify sends a notification to each of the user's active channels and
+// returns how many accepted it. Failures are logged and recorded on the
+// channel, and do not stop the other channels.
+func (n *Notifier) Notify(userID int64, notification notify.Notification) int {
+	channels, err := n.channelRepo.GetActiveByUserID(userID)
+	if err != nil {
+		log.Printf("[Notify] Error getting channels of user %d: %v", userID, err)
+		return 0
+	}
+
+	sent := 0
+	for _, channel := range channels {
+		if err := n.send(channel, notification); err != nil {
+			log.Printf("[Notify] Sending to channel %d of user %d failed: %v", channel.ID, userID, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// Test sends a test notification to a channel, whether or not it is active.
+func (n *Notifier) Test(channel *models.NotificationChannel) error {
+	return n.send(channel, notify.Notification{
+		Title:   "Wealth Tracker test notification",
+		Message: "Notifications from Wealth Tracker reach you through " + channel.Name + ".",
+	})
+}
+
+// send delivers a notification through a channel and records the outcome.
+func (n *Notifier) send(channel *models.NotificationChannel, notification notify.Notification) error {
+	c, err := n.newChannel(channel.Kind, notify.Config{
+		URL:                  channel.URL,
+		Topic:                channel.Topic,
+		Token:                channel.Token,
+		AllowPrivateNetworks: n.allowPrivateNetworks,
+	})
+	if err == nil {
+		err = c.Send(notification)
+	}
+	if recordErr := n.channelRepo.RecordDelivery(channel.ID, time.Now(), err); recordErr != nil {
+		log.Printf("[Notify] Error recording delivery to channel %d: %v", channel.ID, recordErr)
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/notify"
+	"wealth_tracker/internal/repository"
+)
+
+// recordingChannel records the notifications it is asked to send, failing
+// if err is set.
+type recordingChannel struct {
+	sent []notify.Notification
+	err  error
+}
+
+func (c *recordingChannel) Send(n notify.Notification) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	encryptor, err := broker.NewEncryptor("test-encryption-secret-32-chars!")
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+	channelRepo := repository.NewNotificationChannelRepository(db, encryptor)
+	n := NewNotifier(channelRepo)
+	channels := map[string]*recordingChannel{
+		"https://ntfy.example.com":           {},
+		"https://hooks.slack.com/services/x": {err: errors.New("status 500")},
+		"https://discord.com/api/webhooks/1": {},
+	}
+	n.newChannel = func(kind string, cfg notify.Config) (notify.Channel, error) {
+		if _, err := notify.New(kind, cfg); err != nil {
+			return nil, err
+		}
+		return channels[cfg.URL], nil
+	}
+
+	if _, err := n.AddChannel(userID, notify.KindNtfy, " Phone ", notify.Config{URL: "https://ntfy.example.com", Topic: "wealth"}); err != nil {
+		t.Fatalf("AddChannel(ntfy) error = %v", err)
+	}
+	slack, err := n.AddChannel(userID, notify.KindSlack, "Slack", notify.Config{URL: "https://hooks.slack.com/services/x"})
+	if err != nil {
+		t.Fatalf("AddChannel(slack) error = %v", err)
+	}
+	discord, err := n.AddChannel(userID, notify.KindDiscord, "Discord", notify.Config{URL: "https://discord.com/api/webhooks/1"})
+	if err != nil {
+		t.Fatalf("AddChannel(discord) error = %v", err)
+	}
+	if err := channelRepo.SetActive(discord.ID, false); err != nil {
+		t.Fatalf("disabling channel: %v", err)
+	}
+	if _, err := n.AddChannel(userID, notify.KindGotify, "Gotify", notify.Config{URL: "https://gotify.example.com"}); !errors.Is(err, notify.ErrTokenRequired) {
+		t.Errorf("AddChannel() without token error = %v; want %v", err, notify.ErrTokenRequired)
+	}
+	if _, err := n.AddChannel(userID, notify.KindNtfy, " ", notify.Config{Topic: "wealth"}); !errors.Is(err, ErrChannelNameRequired) {
+		t.Errorf("AddChannel() without name error = %v; want %v", err, ErrChannelNameRequired)
+	}
+
+	if sent := n.Notify(userID, notify.Notification{Title: "Sync failed"}); sent != 1 {
+		t.Errorf("Notify() = %d; want 1 channel", sent)
+	}
+	if len(channels["https://ntfy.example.com"].sent) != 1 || len(channels["https://discord.com/api/webhooks/1"].sent) != 0 {
+		t.Error("Notify() did not send to exactly the active channels")
+	}
+
+	stored, _ := channelRepo.GetByUserID(userID)
+	if stored[0].Name != "Phone" || stored[0].LastSentAt == nil || stored[0].LastError != "" {
+		t.Errorf("ntfy channel = %+v; want a recorded delivery", stored[0])
+	}
+	if stored[1].LastSentAt != nil || stored[1].LastError != "status 500" {
+		t.Errorf("slack channel = %+v; want the failure recorded", stored[1])
+	}
+
+	// Webhook URLs are encrypted at rest, including those saved before
+	var rawURL string
+	if err := db.QueryRow(`SELECT url FROM notification_channels WHERE id = ?`, slack.ID).Scan(&rawURL); err != nil || strings.Contains(rawURL, "slack") {
+		t.Errorf("stored URL = %q, %v; want it encrypted", rawURL, err)
+	}
+	if _, err := db.Exec(`INSERT INTO notification_channels (user_id, kind, name, url, is_active) VALUES (?, 'discord', 'Old', 'https://discord.com/api/webhooks/2', 0)`, userID); err != nil {
+		t.Fatalf("inserting plaintext channel: %v", err)
+	}
+	if n, err := channelRepo.EncryptStored(); err != nil || n != 1 {
+		t.Errorf("EncryptStored() = %d, %v; want 1 channel", n, err)
+	}
+	if stored, _ := channelRepo.GetByUserID(userID); len(stored) != 4 || stored[3].URL != "https://discord.com/api/webhooks/2" {
+		t.Errorf("channels after EncryptStored() = %+v; want the old channel's URL readable", stored)
+	}
+
+	// Test sends to inactive channels too
+	if err := n.Test(discord); err != nil {
+		t.Errorf("Test() error = %v", err)
+	}
+	if err := n.Test(slack); err == nil {
+		t.Error("Test() of a failing channel returned no error")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"time"
 
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
@@ -19,6 +20,11 @@ type PortfolioService struct {
 	targetRepo      *repository.AllocationTargetRepository
 	currencyService *CurrencyService
 	baseCurrency    string
+	perfRepo        *repository.BrokerPerformanceRepository
+	exclusionRepo   *repository.AnalyticsExclusionRepository
+	labelRepo       *repository.HoldingLabelRepository
+	chartColors     *ChartColorService
+	taxParams       *TaxParameterService // Nil uses the built-in tax constants
 }
 
 // NewPortfolioService creates a new PortfolioService.
@@ -64,29 +70,47 @@ func NewPortfolioServiceWithCurrency(
 }
 
 // convertToBase converts an amount from the given currency to the base currency.
-func (s *PortfolioService) convertToBase(amount float64, currency string) float64 {
+// The second return value is false if no rate was found and the amount was
+// returned unconverted.
+func (s *PortfolioService) convertToBase(userID int64, amount float64, currency string) (float64, bool) {
 	if s.currencyService == nil || currency == s.baseCurrency || currency == "" {
-		return amount
+		return amount, true
 	}
-	converted, err := s.currencyService.Convert(amount, currency, s.baseCurrency)
-	if err != nil {
-		// If conversion fails, return original amount
-		return amount
+	converted, source := s.currencyService.ConvertForUser(userID, amount, currency, s.baseCurrency)
+	return converted, source != RateSourceFallback
+}
+
+// convertToBaseOn converts an amount to the base currency at the rate of a
+// past day where known, or else the current rate. A nil day uses the current
+// rate.
+func (s *PortfolioService) convertToBaseOn(userID int64, amount float64, currency string, day *time.Time) (float64, bool) {
+	if day != nil && s.currencyService != nil && currency != s.baseCurrency && currency != "" {
+		if rate, ok := s.currencyService.RateForUserOn(userID, currency, s.baseCurrency, *day); ok {
+			return amount * rate, true
+		}
 	}
-	return converted
+	return s.convertToBase(userID, amount, currency)
 }
 
 // PortfolioComposition represents the breakdown of a portfolio.
 type PortfolioComposition struct {
 	TotalValue       float64                     `json:"total_value"`
-	TotalPositions   int                         `json:"total_positions"`
+	TotalPositions   int                         `json:"total_positions"` // Instruments, counted once across accounts
 	BaseCurrency     string                      `json:"base_currency"`
 	ByCategory       []CategoryAllocation        `json:"by_category"`
 	ByAssetType      []AssetTypeAllocation       `json:"by_asset_type"`
 	ByCurrency       []CurrencyAllocation        `json:"by_currency"`
+	ByLabel          []LabelAllocation           `json:"by_label"`
 	Holdings         []HoldingAllocation         `json:"holdings"`
-	TopHolding       *HoldingAllocation          `json:"top_holding,omitempty"`
-	ConcentrationPct float64                     `json:"concentration_pct"` // Top 5 holdings %
+	Positions        []PositionAllocation        `json:"positions"` // Holdings combined across accounts
+	TopHolding       *PositionAllocation         `json:"top_holding,omitempty"`
+	ConcentrationPct float64                     `json:"concentration_pct"` // Top 5 positions %
+	// Currencies without a provider or manual rate, counted 1:1
+	UnconvertedCurrencies []string `json:"unconverted_currencies,omitempty"`
+	// Holdings left out by the user's analytics exclusions
+	ExclusionsApplied bool    `json:"exclusions_applied"`
+	ExcludedValue     float64 `json:"excluded_value"` // In base currency
+	ExcludedPositions int     `json:"excluded_positions"`
 }
 
 // CategoryAllocation represents allocation to a category.
@@ -102,6 +126,7 @@ type CategoryAllocation struct {
 // AssetTypeAllocation represents allocation to an asset type.
 type AssetTypeAllocation struct {
 	AssetType  string  `json:"asset_type"`
+	Color      string  `json:"color"`
 	Value      float64 `json:"value"`
 	Percentage float64 `json:"percentage"`
 	Count      int     `json:"count"`
@@ -110,14 +135,34 @@ type AssetTypeAllocation struct {
 // CurrencyAllocation represents allocation by currency.
 type CurrencyAllocation struct {
 	Currency   string  `json:"currency"`
+	Color      string  `json:"color"`
+	Value      float64 `json:"value"`
+	Percentage float64 `json:"percentage"`
+}
+
+// LabelAllocation represents allocation to a holding label. Holdings
+// without a label and account balances have an empty label.
+type LabelAllocation struct {
+	Label      string  `json:"label"`
+	Color      string  `json:"color"`
 	Value      float64 `json:"value"`
 	Percentage float64 `json:"percentage"`
+	Count      int     `json:"count"`
+}
+
+// Name returns the name the label is shown with.
+func (l LabelAllocation) Name() string {
+	if l.Label == "" {
+		return "Unlabeled"
+	}
+	return l.Label
 }
 
 // HoldingAllocation represents a single holding's allocation.
 type HoldingAllocation struct {
 	AccountID      int64   `json:"account_id"`
 	AccountName    string  `json:"account_name"`
+	AccountColor   string  `json:"account_color"`
 	Symbol         string  `json:"symbol"`
 	Name           string  `json:"name"`
 	Value          float64 `json:"value"`           // Value in original currency
@@ -127,6 +172,7 @@ type HoldingAllocation struct {
 	ProfitLossPct  float64 `json:"profit_loss_pct"`
 	InstrumentType string  `json:"instrument_type"`
 	Currency       string  `json:"currency"`
+	Label          string  `json:"label,omitempty"`
 }
 
 // AllocationComparison compares actual vs target allocation.
@@ -173,8 +219,10 @@ type TaxTip struct {
 	Action      string `json:"action,omitempty"`
 }
 
-// Danish tax constants (2025)
+// Built-in Danish tax constants (2025), used for years admins have not set
+// tax parameters for; see TaxParameterService.
 const (
+	BuiltInTaxYear        = 2025     // Year of the built-in constants
 	ASKMaxDeposit         = 166200.0 // Max ASK contribution 2025
 	StockGainThreshold    = 67500.0  // Threshold for 27% vs 42% tax
 	StockGainLowRate      = 27.0     // Tax rate below threshold
@@ -198,10 +246,41 @@ func inferSymbolFromCategory(categoryName string) string {
 	return "ACCOUNT" // Generic marker for category-based positions
 }
 
-// GetPortfolioComposition calculates the current portfolio breakdown.
-func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComposition, error) {
+// GetPortfolioComposition calculates the current portfolio breakdown. With
+// applyExclusions, holdings of the user's excluded instruments are left out,
+// as if their accounts did not hold them.
+func (s *PortfolioService) GetPortfolioComposition(userID int64, applyExclusions bool) (*PortfolioComposition, error) {
+	return s.GetPortfolioCompositionAsOf(userID, applyExclusions, nil)
+}
+
+// GetPortfolioCompositionAsOf calculates the portfolio composition at the end
+// of the as-of day, from the accounts open on it, their balances and the
+// snapshots of their holdings, converted at the exchange rates of the day
+// where known. A nil day calculates the current composition.
+func (s *PortfolioService) GetPortfolioCompositionAsOf(userID int64, applyExclusions bool, asOf *time.Time) (*PortfolioComposition, error) {
 	// Get all active accounts (assets only, not liabilities)
-	accounts, err := s.accountRepo.GetByUserIDActiveOnly(userID)
+	var accounts []*models.Account
+	var holdingsAt map[int64][]*models.Holding
+	var err error
+	if asOf != nil {
+		if accounts, err = s.accountRepo.GetByUserIDOpenOn(userID, *asOf); err != nil {
+			return nil, err
+		}
+		if holdingsAt, err = s.holdingRepo.GetHoldingsAt(userID, *asOf); err != nil {
+			return nil, err
+		}
+	} else if accounts, err = s.accountRepo.GetByUserIDActiveOnly(userID); err != nil {
+		return nil, err
+	}
+
+	var excluded map[string]bool
+	if applyExclusions {
+		if excluded, err = s.excludedSymbols(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	labels, err := s.labelsBySymbol(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -216,18 +295,34 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 		categoryMap[c.ID] = c
 	}
 
+	colors, err := s.chartColorsFor(userID, categories, accounts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build composition
 	composition := &PortfolioComposition{
-		BaseCurrency: s.baseCurrency,
-		ByCategory:   make([]CategoryAllocation, 0),
-		ByAssetType:  make([]AssetTypeAllocation, 0),
-		ByCurrency:   make([]CurrencyAllocation, 0),
-		Holdings:     make([]HoldingAllocation, 0),
+		BaseCurrency:      s.baseCurrency,
+		ByCategory:        make([]CategoryAllocation, 0),
+		ByAssetType:       make([]AssetTypeAllocation, 0),
+		ByCurrency:        make([]CurrencyAllocation, 0),
+		ByLabel:           make([]LabelAllocation, 0),
+		Holdings:          make([]HoldingAllocation, 0),
+		ExclusionsApplied: len(excluded) > 0,
 	}
 
 	categoryTotals := make(map[int64]*CategoryAllocation)
 	assetTypeTotals := make(map[string]*AssetTypeAllocation)
 	currencyTotals := make(map[string]*CurrencyAllocation)
+	labelTotals := make(map[string]*LabelAllocation)
+	addToLabel := func(label string, value float64) {
+		if _, exists := labelTotals[label]; !exists {
+			labelTotals[label] = &LabelAllocation{Label: label}
+		}
+		labelTotals[label].Value += value
+		labelTotals[label].Count++
+	}
+	unconverted := make(map[string]bool)
 
 	for _, account := range accounts {
 		// Skip liabilities
@@ -236,27 +331,54 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 		}
 
 		// Get account balance from latest transaction
-		balance, err := s.transactionRepo.GetLatestBalance(account.ID)
+		var balance float64
+		if asOf != nil {
+			balance, err = s.transactionRepo.GetBalanceAt(account.ID, *asOf)
+		} else {
+			balance, err = s.transactionRepo.GetLatestBalance(account.ID)
+		}
 		if err != nil {
 			balance = 0
 		}
 
 		// Get holdings for this account
-		holdings, err := s.holdingRepo.GetByAccountID(account.ID)
-		if err != nil {
-			holdings = []*models.Holding{}
+		holdings := holdingsAt[account.ID]
+		if asOf == nil {
+			if holdings, err = s.holdingRepo.GetByAccountID(account.ID); err != nil {
+				holdings = []*models.Holding{}
+			}
 		}
 
 		// Calculate account value (holdings or balance)
 		accountValue := balance
 		if len(holdings) > 0 {
-			holdingsTotal := 0.0
+			holdingsTotal, excludedTotal := 0.0, 0.0
+			included := make([]*models.Holding, 0, len(holdings))
 			for _, h := range holdings {
 				holdingsTotal += h.CurrentValue
+				if !excluded[NormalizeExclusionSymbol(h.Symbol)] {
+					included = append(included, h)
+					continue
+				}
+				excludedTotal += h.CurrentValue
+
+				currency := h.Currency
+				if currency == "" {
+					currency = account.Currency
+				}
+				valueInBase, _ := s.convertToBaseOn(userID, h.CurrentValue, currency, asOf)
+				composition.ExcludedValue += valueInBase
+				composition.ExcludedPositions++
 			}
 			if holdingsTotal > 0 {
-				accountValue = holdingsTotal
+				accountValue = holdingsTotal - excludedTotal
 			}
+
+			// An account holding only excluded instruments is left out
+			if len(included) == 0 {
+				continue
+			}
+			holdings = included
 		}
 
 		composition.TotalValue += accountValue
@@ -268,15 +390,13 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 		}
 		if _, exists := categoryTotals[catID]; !exists {
 			catName := "Uncategorized"
-			catColor := "#6b7280"
 			if cat, ok := categoryMap[catID]; ok && catID > 0 {
 				catName = cat.Name
-				catColor = cat.Color
 			}
 			categoryTotals[catID] = &CategoryAllocation{
 				CategoryID:   catID,
 				CategoryName: catName,
-				Color:        catColor,
+				Color:        colors.Category(account.CategoryID),
 			}
 		}
 		categoryTotals[catID].Value += accountValue
@@ -284,8 +404,6 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 
 		// Process holdings for asset type and individual allocations
 		for _, h := range holdings {
-			composition.TotalPositions++
-
 			// Determine currency
 			currency := h.Currency
 			if currency == "" {
@@ -293,7 +411,10 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 			}
 
 			// Convert to base currency for aggregation
-			valueInBase := s.convertToBase(h.CurrentValue, currency)
+			valueInBase, ok := s.convertToBaseOn(userID, h.CurrentValue, currency, asOf)
+			if !ok {
+				unconverted[currency] = true
+			}
 
 			// Asset type
 			assetType := h.InstrumentType
@@ -316,10 +437,14 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 			}
 			currencyTotals[currency].Value += valueInBase
 
+			label := labels[NormalizeExclusionSymbol(h.Symbol)]
+			addToLabel(label, valueInBase)
+
 			// Individual holding
 			composition.Holdings = append(composition.Holdings, HoldingAllocation{
 				AccountID:      account.ID,
 				AccountName:    account.Name,
+				AccountColor:   colors.Account(account.ID),
 				Symbol:         h.Symbol,
 				Name:           h.Name,
 				Value:          h.CurrentValue,
@@ -328,15 +453,17 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 				ProfitLossPct:  h.ProfitLossPercent(),
 				InstrumentType: h.InstrumentType,
 				Currency:       currency,
+				Label:          label,
 			})
 		}
 
 		// If no holdings, add account balance - infer asset type from category
 		if len(holdings) == 0 && balance > 0 {
-			composition.TotalPositions++
-
 			currency := account.Currency
-			valueInBase := s.convertToBase(balance, currency)
+			valueInBase, ok := s.convertToBaseOn(userID, balance, currency, asOf)
+			if !ok {
+				unconverted[currency] = true
+			}
 
 			// Infer asset type from category name
 			assetType := inferAssetTypeFromCategory(categoryTotals[catID].CategoryName)
@@ -354,12 +481,14 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 				}
 			}
 			currencyTotals[currency].Value += valueInBase
+			addToLabel("", valueInBase)
 
 			// Use category-based symbol instead of generic CASH
 			symbol := inferSymbolFromCategory(categoryTotals[catID].CategoryName)
 			composition.Holdings = append(composition.Holdings, HoldingAllocation{
 				AccountID:      account.ID,
 				AccountName:    account.Name,
+				AccountColor:   colors.Account(account.ID),
 				Symbol:         symbol,
 				Name:           account.Name,
 				Value:          balance,
@@ -390,6 +519,9 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 	sort.Slice(composition.ByAssetType, func(i, j int) bool {
 		return composition.ByAssetType[i].Value > composition.ByAssetType[j].Value
 	})
+	for i := range composition.ByAssetType {
+		composition.ByAssetType[i].Color = colors.Entity(models.ChartColorAssetType, composition.ByAssetType[i].AssetType)
+	}
 
 	for _, cur := range currencyTotals {
 		if composition.TotalValue > 0 {
@@ -400,6 +532,30 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 	sort.Slice(composition.ByCurrency, func(i, j int) bool {
 		return composition.ByCurrency[i].Value > composition.ByCurrency[j].Value
 	})
+	for i := range composition.ByCurrency {
+		composition.ByCurrency[i].Color = colors.Entity(models.ChartColorCurrency, composition.ByCurrency[i].Currency)
+	}
+
+	for _, l := range labelTotals {
+		if composition.TotalValue > 0 {
+			l.Percentage = (l.Value / composition.TotalValue) * 100
+		}
+		composition.ByLabel = append(composition.ByLabel, *l)
+	}
+	sort.Slice(composition.ByLabel, func(i, j int) bool {
+		return composition.ByLabel[i].Value > composition.ByLabel[j].Value
+	})
+	for i := range composition.ByLabel {
+		composition.ByLabel[i].Color = colors.Entity(models.ChartColorLabel, composition.ByLabel[i].Label)
+	}
+	if err := colors.Save(); err != nil {
+		return nil, err
+	}
+
+	for currency := range unconverted {
+		composition.UnconvertedCurrencies = append(composition.UnconvertedCurrencies, currency)
+	}
+	sort.Strings(composition.UnconvertedCurrencies)
 
 	// Sort holdings by value and calculate percentages
 	sort.Slice(composition.Holdings, func(i, j int) bool {
@@ -411,15 +567,18 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 		}
 	}
 
-	// Top holding and concentration
-	if len(composition.Holdings) > 0 {
-		top := composition.Holdings[0]
+	// Top holding and concentration, of instruments rather than the holdings
+	// of each account, so one ETF in several depots is not diversification
+	composition.Positions = aggregatePositions(composition.Holdings, composition.TotalValue)
+	composition.TotalPositions = len(composition.Positions)
+	if len(composition.Positions) > 0 {
+		top := composition.Positions[0]
 		composition.TopHolding = &top
 
 		// Top 5 concentration
 		top5Value := 0.0
-		for i := 0; i < min(5, len(composition.Holdings)); i++ {
-			top5Value += composition.Holdings[i].Value
+		for i := 0; i < min(5, len(composition.Positions)); i++ {
+			top5Value += composition.Positions[i].ValueInBase
 		}
 		if composition.TotalValue > 0 {
 			composition.ConcentrationPct = (top5Value / composition.TotalValue) * 100
@@ -429,9 +588,10 @@ func (s *PortfolioService) GetPortfolioComposition(userID int64) (*PortfolioComp
 	return composition, nil
 }
 
-// GetAllocationComparison compares actual allocation to targets.
-func (s *PortfolioService) GetAllocationComparison(userID int64, targetType string) (*AllocationComparison, error) {
-	composition, err := s.GetPortfolioComposition(userID)
+// GetAllocationComparison compares actual allocation to targets, leaving out
+// excluded instruments with applyExclusions.
+func (s *PortfolioService) GetAllocationComparison(userID int64, targetType string, applyExclusions bool) (*AllocationComparison, error) {
+	composition, err := s.GetPortfolioComposition(userID, applyExclusions)
 	if err != nil {
 		return nil, err
 	}
@@ -492,6 +652,7 @@ func (s *PortfolioService) GetAllocationComparison(userID int64, targetType stri
 			item := AllocationComparisonItem{
 				Key:         at.AssetType,
 				Name:        at.AssetType,
+				Color:       at.Color,
 				ActualPct:   at.Percentage,
 				ActualValue: at.Value,
 			}
@@ -519,6 +680,7 @@ func (s *PortfolioService) GetAllocationComparison(userID int64, targetType stri
 			item := AllocationComparisonItem{
 				Key:         cur.Currency,
 				Name:        cur.Currency,
+				Color:       cur.Color,
 				ActualPct:   cur.Percentage,
 				ActualValue: cur.Value,
 			}
@@ -540,14 +702,43 @@ func (s *PortfolioService) GetAllocationComparison(userID int64, targetType stri
 				})
 			}
 		}
+
+	case models.TargetTypeLabel:
+		for _, l := range composition.ByLabel {
+			item := AllocationComparisonItem{
+				Key:         l.Label,
+				Name:        l.Name(),
+				Color:       l.Color,
+				ActualPct:   l.Percentage,
+				ActualValue: l.Value,
+			}
+			if target, ok := targetMap[l.Label]; ok {
+				item.TargetPct = target.TargetPct
+				item.DriftPct = item.ActualPct - item.TargetPct
+				matchedTargets[l.Label] = true
+			}
+			comparison.Items = append(comparison.Items, item)
+		}
+		// Add targets without holdings
+		for _, t := range targets {
+			if !matchedTargets[t.TargetKey] {
+				comparison.Items = append(comparison.Items, AllocationComparisonItem{
+					Key:       t.TargetKey,
+					Name:      t.TargetKey,
+					TargetPct: t.TargetPct,
+					DriftPct:  -t.TargetPct, // 0% actual - target%
+				})
+			}
+		}
 	}
 
 	return comparison, nil
 }
 
-// CalculateRebalancing calculates buy/sell recommendations to reach target allocation.
-func (s *PortfolioService) CalculateRebalancing(userID int64, targetType string, newMoney float64) (*RebalanceRecommendation, error) {
-	composition, err := s.GetPortfolioComposition(userID)
+// CalculateRebalancing calculates buy/sell recommendations to reach target
+// allocation, leaving out excluded instruments with applyExclusions.
+func (s *PortfolioService) CalculateRebalancing(userID int64, targetType string, newMoney float64, applyExclusions bool) (*RebalanceRecommendation, error) {
+	composition, err := s.GetPortfolioComposition(userID, applyExclusions)
 	if err != nil {
 		return nil, err
 	}
@@ -601,6 +792,14 @@ func (s *PortfolioService) CalculateRebalancing(userID int64, targetType string,
 			allocations[cur.Currency] = cur.Value
 			names[cur.Currency] = cur.Currency
 		}
+
+	case models.TargetTypeLabel:
+		allocations = make(map[string]float64)
+		names = make(map[string]string)
+		for _, l := range composition.ByLabel {
+			allocations[l.Label] = l.Value
+			names[l.Label] = l.Name()
+		}
 	}
 
 	// Calculate recommendations
@@ -649,6 +848,7 @@ func (s *PortfolioService) CalculateRebalancing(userID int64, targetType string,
 // generateTaxTips creates Danish tax optimization tips based on portfolio state.
 func (s *PortfolioService) generateTaxTips(composition *PortfolioComposition, newMoney float64) []TaxTip {
 	tips := make([]TaxTip, 0)
+	tax := s.currentTaxParameters()
 
 	// Check for ASK account optimization
 	askValue := 0.0
@@ -659,12 +859,12 @@ func (s *PortfolioService) generateTaxTips(composition *PortfolioComposition, ne
 		}
 	}
 
-	if askValue < ASKMaxDeposit {
-		remaining := ASKMaxDeposit - askValue
+	if askValue < tax.ASKMaxDeposit {
+		remaining := tax.ASKMaxDeposit - askValue
 		tips = append(tips, TaxTip{
 			Priority:    1,
 			Title:       "Max out ASK",
-			Description: "Du kan stadig indsætte " + formatDKK(remaining) + " på din ASK (17% skat vs 27-42%)",
+			Description: "Du kan stadig indsætte " + formatDKK(remaining) + " på din ASK (" + formatPct(tax.ASKTaxRate) + " skat vs " + formatNumberDK(tax.StockGainLowRate) + "-" + formatPct(tax.StockGainHighRate) + ")",
 			Action:      "Prioriter ASK for nye investeringer",
 		})
 	}
@@ -674,7 +874,7 @@ func (s *PortfolioService) generateTaxTips(composition *PortfolioComposition, ne
 		tips = append(tips, TaxTip{
 			Priority:    2,
 			Title:       "Prioriter skatteeffektive konti",
-			Description: "Ved nye investeringer: ASK (17%) → Frie midler → Pension",
+			Description: "Ved nye investeringer: ASK (" + formatPct(tax.ASKTaxRate) + ") → Frie midler → Pension",
 			Action:      "Placer " + formatDKK(newMoney) + " i ASK først hvis muligt",
 		})
 	}
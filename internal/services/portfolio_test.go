@@ -105,19 +105,19 @@ func TestConvertToBase(t *testing.T) {
 	ps := &PortfolioService{baseCurrency: "DKK"}
 
 	amount := 100.0
-	got := ps.convertToBase(amount, "USD")
+	got, _ := ps.convertToBase(1, amount, "USD")
 	if got != amount {
 		t.Errorf("convertToBase without service: got %f; want %f", got, amount)
 	}
 
 	// Test same currency (should return original amount)
-	got = ps.convertToBase(amount, "DKK")
+	got, _ = ps.convertToBase(1, amount, "DKK")
 	if got != amount {
 		t.Errorf("convertToBase same currency: got %f; want %f", got, amount)
 	}
 
 	// Test empty currency (should return original amount)
-	got = ps.convertToBase(amount, "")
+	got, _ = ps.convertToBase(1, amount, "")
 	if got != amount {
 		t.Errorf("convertToBase empty currency: got %f; want %f", got, amount)
 	}
@@ -244,3 +244,24 @@ func TestRebalanceActionOrder(t *testing.T) {
 		t.Error("sell should come before hold")
 	}
 }
+
+func TestLabelAllocationName(t *testing.T) {
+	tests := []struct {
+		label    string
+		expected string
+	}{
+		{"", "Unlabeled"},
+		{"core", "core"},
+	}
+
+	for _, tc := range tests {
+		got := LabelAllocation{Label: tc.label}.Name()
+		if got != tc.expected {
+			t.Errorf("LabelAllocation{Label: %q}.Name() = %q; want %q", tc.label, got, tc.expected)
+		}
+	}
+
+	if got := NormalizeHoldingLabel("  Satellite "); got != "satellite" {
+		t.Errorf("NormalizeHoldingLabel = %q; want %q", got, "satellite")
+	}
+}
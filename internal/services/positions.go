@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PositionAllocation is an instrument held across accounts, such as the same
+// ETF in three depots, with the holding of each account. Account balances
+// without holdings are positions of their own.
+type PositionAllocation struct {
+	Key            string              `json:"key"` // Normalized symbol, or the account of a balance
+	Symbol         string              `json:"symbol"`
+	Name           string              `json:"name"`
+	InstrumentType string              `json:"instrument_type"`
+	Label          string              `json:"label,omitempty"`
+	ValueInBase    float64             `json:"value_in_base"`
+	Percentage     float64             `json:"percentage"`
+	ProfitLoss     float64             `json:"profit_loss"`
+	Accounts       []HoldingAllocation `json:"accounts"` // Largest first
+}
+
+// aggregatePositions combines holdings of the same instrument across
+// accounts into positions, largest first, with percentages of total.
+func aggregatePositions(holdings []HoldingAllocation, total float64) []PositionAllocation {
+	positions := make([]PositionAllocation, 0)
+	index := make(map[string]int)
+	for _, h := range holdings {
+		key := NormalizeExclusionSymbol(h.Symbol)
+		if key == "ACCOUNT" || key == "CASH" {
+			key = fmt.Sprintf("%s:%d", key, h.AccountID)
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(positions)
+			index[key] = i
+			positions = append(positions, PositionAllocation{
+				Key:            key,
+				Symbol:         h.Symbol,
+				Name:           h.Name,
+				InstrumentType: h.InstrumentType,
+				Label:          h.Label,
+			})
+		}
+		p := &positions[i]
+		p.ValueInBase += h.ValueInBase
+		p.ProfitLoss += h.ProfitLoss
+		p.Accounts = append(p.Accounts, h)
+	}
+
+	for i := range positions {
+		p := &positions[i]
+		if total > 0 {
+			p.Percentage = p.ValueInBase / total * 100
+		}
+		sort.SliceStable(p.Accounts, func(a, b int) bool {
+			return p.Accounts[a].ValueInBase > p.Accounts[b].ValueInBase
+		})
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return positions[i].ValueInBase > positions[j].ValueInBase
+	})
+	return positions
+}
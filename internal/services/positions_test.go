@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestAggregatePositions(t *testing.T) {
+	holdings := []HoldingAllocation{
+		{AccountID: 1, AccountName: "Nordnet", Symbol: "IE00B4L5Y983", Name: "iShares Core MSCI World", ValueInBase: 40000, ProfitLoss: 4000},
+		{AccountID: 2, AccountName: "Saxo", Symbol: "DK0060534915", Name: "Novo Nordisk", ValueInBase: 30000},
+		{AccountID: 3, AccountName: "Pension", Symbol: "ie00b4l5y983", Name: "iShares Core MSCI World", ValueInBase: 20000, ProfitLoss: 1000},
+		{AccountID: 4, AccountName: "Savings", Symbol: "ACCOUNT", Name: "Savings", ValueInBase: 5000},
+		{AccountID: 5, AccountName: "Buffer", Symbol: "ACCOUNT", Name: "Buffer", ValueInBase: 5000},
+	}
+
+	positions := aggregatePositions(holdings, 100000)
+	if len(positions) != 4 {
+		t.Fatalf("got %d positions, want the ETF once, the stock and both balances", len(positions))
+	}
+	world := positions[0]
+	if world.Key != "IE00B4L5Y983" || world.ValueInBase != 60000 || world.Percentage != 60 || world.ProfitLoss != 5000 {
+		t.Errorf("ETF position = %+v; want both accounts' 60000 as 60%%", world)
+	}
+	if len(world.Accounts) != 2 || world.Accounts[0].AccountName != "Nordnet" || world.Accounts[1].AccountName != "Pension" {
+		t.Errorf("ETF accounts = %+v; want Nordnet then Pension", world.Accounts)
+	}
+	if positions[2].Key == positions[3].Key {
+		t.Error("account balances were combined into one position")
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Backfill errors shown to the user.
+var (
+	ErrBackfillNotISIN  = errors.New("only holdings identified by an ISIN can be backfilled")
+	ErrBackfillNoStart  = errors.New("choose a date to backfill from; the holding has no imported acquisitions")
+	ErrBackfillNoPrices = errors.New("the price provider has no prices for the holding before its history starts")
+)
+
+// PricePoint is the closing price of an instrument on a day.
+type PricePoint struct {
+	Day   time.Time
+	Price float64
+}
+
+// PriceBackfillService fills in the history of holdings from before they
+// were first synced with historical prices, so charts and performance
+// figures do not start at the day the integration was set up.
+type PriceBackfillService struct {
+	holdingRepo     *repository.HoldingRepository
+	acquisitionRepo *repository.HoldingAcquisitionRepository
+	fetch           func(isin string, from, to time.Time) ([]PricePoint, error)
+	usage           *UsageService // Counts price fetches for users; nil leaves them unlimited
+}
+
+// NewPriceBackfillService creates a new PriceBackfillService that fetches
+// prices from urlTemplate, with {isin}, {from} and {to} replaced by the ISIN
+// and the first and last day as YYYY-MM-DD. The provider must respond with
+// a JSON array of {"date": "YYYY-MM-DD", "close": price}.
+func NewPriceBackfillService(
+	holdingRepo *repository.HoldingRepository,
+	acquisitionRepo *repository.HoldingAcquisitionRepository,
+	urlTemplate string,
+) *PriceBackfillService {
+	s := &PriceBackfillService{
+		holdingRepo:     holdingRepo,
+		acquisitionRepo: acquisitionRepo,
+	}
+	s.fetch = func(isin string, from, to time.Time) ([]PricePoint, error) {
+		return fetchPriceHistory(urlTemplate, isin, from, to)
+	}
+	return s
+}
+
+// SetUsage counts the price fetches made for a user against their monthly
+// market data quota.
+func (s *PriceBackfillService) SetUsage(usage *UsageService) {
+	s.usage = usage
+}
+
+// Backfill records daily snapshots of a holding of the user from a day up
+// to the day before its history starts, valued at the historical closing
+// prices, and returns how many days were added. A zero from starts at the
+// first imported acquisition of the holding. The quantity on each day is
+// rebuilt from the acquisitions when there are any, and is the current
+// quantity otherwise. Days already recorded are kept.
+func (s *PriceBackfillService) Backfill(userID int64, holding *models.Holding, from time.Time) (int, error) {
+	if !isValidISIN(holding.Symbol) {
+		return 0, ErrBackfillNotISIN
+	}
+
+	acquisitions, err := s.acquisitionRepo.GetByAccountID(holding.AccountID)
+	if err != nil {
+		return 0, fmt.Errorf("getting acquisitions: %w", err)
+	}
+	var trades []*models.HoldingAcquisition
+	for _, a := range acquisitions {
+		if a.Symbol == holding.Symbol {
+			trades = append(trades, a)
+		}
+	}
+	if from.IsZero() {
+		if len(trades) == 0 {
+			return 0, ErrBackfillNoStart
+		}
+		from = trades[0].TradeDate
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+
+	// History ends the day before the first snapshot, or yesterday
+	firstDay, err := s.holdingRepo.GetFirstHoldingSnapshotDay(holding.AccountID, holding.Symbol)
+	if err != nil {
+		return 0, fmt.Errorf("getting first snapshot: %w", err)
+	}
+	if firstDay.IsZero() {
+		now := time.Now()
+		firstDay = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	to := firstDay.AddDate(0, 0, -1)
+	if to.Before(from) {
+		return 0, nil
+	}
+
+	if s.usage != nil {
+		if err := s.usage.Use(userID, models.UsageMarketData); err != nil {
+			return 0, err
+		}
+	}
+	prices, err := s.fetch(holding.Symbol, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshots := historicSnapshots(holding, trades, prices, from, to)
+	if len(snapshots) == 0 {
+		return 0, ErrBackfillNoPrices
+	}
+	return s.holdingRepo.AddSnapshots(snapshots)
+}
+
+// historicSnapshots values the holding on each day with a price from one
+// day to another. Days the holding was not held are left out.
+func historicSnapshots(holding *models.Holding, trades []*models.HoldingAcquisition, prices []PricePoint, from, to time.Time) []repository.HoldingSnapshot {
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Day.Before(prices[j].Day) })
+
+	var snapshots []repository.HoldingSnapshot
+	for _, p := range prices {
+		if p.Day.Before(from) || p.Day.After(to) || p.Price <= 0 {
+			continue
+		}
+		quantity := holding.Quantity
+		if len(trades) > 0 {
+			quantity = 0
+			nextDay := p.Day.AddDate(0, 0, 1)
+			for _, t := range trades {
+				if t.TradeDate.Before(nextDay) {
+					quantity += t.Quantity
+				}
+			}
+		}
+		if quantity <= 0 {
+			continue
+		}
+		snapshots = append(snapshots, repository.HoldingSnapshot{
+			AccountID:    holding.AccountID,
+			Symbol:       holding.Symbol,
+			Name:         holding.Name,
+			Day:          p.Day,
+			Quantity:     quantity,
+			CurrentValue: quantity * p.Price,
+		})
+	}
+	return snapshots
+}
+
+// fetchPriceHistory fetches the daily closing prices of an ISIN from the
+// price provider.
+func fetchPriceHistory(urlTemplate, isin string, from, to time.Time) ([]PricePoint, error) {
+	u := strings.NewReplacer(
+		"{isin}", url.PathEscape(isin),
+		"{from}", from.Format("2006-01-02"),
+		"{to}", to.Format("2006-01-02"),
+	).Replace(urlTemplate)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("fetching price history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("price provider returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result []struct {
+		Date  string  `json:"date"`
+		Close float64 `json:"close"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("parsing price history: %w", err)
+	}
+
+	prices := make([]PricePoint, 0, len(result))
+	for _, r := range result {
+		day, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			return nil, fmt.Errorf("parsing price history: invalid date %q", r.Date)
+		}
+		prices = append(prices, PricePoint{Day: day, Price: r.Close})
+	}
+	return prices, nil
+}
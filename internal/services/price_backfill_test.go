@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestPriceBackfillService_Backfill(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	user := &models.User{Email: "test@example.com", PasswordHash: "x", Name: "Test"}
+	if user.ID, err = repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	accountID, err := repository.NewAccountRepository(db).Create(&models.Account{UserID: user.ID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	holdingRepo := repository.NewHoldingRepository(db)
+	acquisitionRepo := repository.NewHoldingAcquisitionRepository(db)
+	holding := &models.Holding{AccountID: accountID, ExternalID: "1", Symbol: "DK0010244508", Name: "Maersk B", Quantity: 3, CurrentValue: 36000, Currency: "DKK"}
+	if err := holdingRepo.Upsert(holding); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(daysAgo int) time.Time { return today.AddDate(0, 0, -daysAgo) }
+	if err := acquisitionRepo.ReplaceSymbols(accountID, []*models.HoldingAcquisition{
+		{AccountID: accountID, Symbol: "DK0010244508", TradeDate: day(10), Quantity: 1, Price: 10000},
+		{AccountID: accountID, Symbol: "DK0010244508", TradeDate: day(5), Quantity: 2, Price: 11000},
+	}); err != nil {
+		t.Fatalf("ReplaceSymbols() error = %v", err)
+	}
+
+	s := NewPriceBackfillService(holdingRepo, acquisitionRepo, "")
+	var fetchedFrom, fetchedTo time.Time
+	s.fetch = func(isin string, from, to time.Time) ([]PricePoint, error) {
+		fetchedFrom, fetchedTo = from, to
+		return []PricePoint{
+			{Day: day(10), Price: 10000},
+			{Day: day(6), Price: 10500},
+			{Day: day(5), Price: 11000},
+			{Day: day(1), Price: 11500},
+			{Day: day(0), Price: 99999}, // Recorded by the sync already
+		}, nil
+	}
+
+	added, err := s.Backfill(user.ID, holding, time.Time{})
+	if err != nil || added != 4 {
+		t.Fatalf("Backfill() = %d, %v; want 4 days from the first acquisition", added, err)
+	}
+	if !fetchedFrom.Equal(day(10)) || !fetchedTo.Equal(day(1)) {
+		t.Errorf("fetched %v to %v; want from the first acquisition to yesterday", fetchedFrom, fetchedTo)
+	}
+
+	for _, tc := range []struct {
+		daysAgo  int
+		quantity float64
+		value    float64
+	}{
+		{6, 1, 10500},
+		{5, 3, 33000},
+		{0, 3, 36000},
+	} {
+		snapshots, err := holdingRepo.GetSnapshotsAt(user.ID, day(tc.daysAgo))
+		if err != nil || len(snapshots) != 1 || snapshots[0].Quantity != tc.quantity || snapshots[0].CurrentValue != tc.value {
+			t.Errorf("GetSnapshotsAt(%d days ago) = %+v, %v; want %v worth %v", tc.daysAgo, snapshots, err, tc.quantity, tc.value)
+		}
+	}
+
+	// Backfilling further back ends where the history now starts
+	if _, err := s.Backfill(user.ID, holding, day(20)); !errors.Is(err, ErrBackfillNoPrices) {
+		t.Fatalf("Backfill(20 days ago) error = %v; want ErrBackfillNoPrices", err)
+	}
+	if !fetchedFrom.Equal(day(20)) || !fetchedTo.Equal(day(11)) {
+		t.Errorf("fetched %v to %v; want up to the day before the backfilled history", fetchedFrom, fetchedTo)
+	}
+
+	if _, err := s.Backfill(user.ID, &models.Holding{AccountID: accountID, Symbol: "CASH"}, day(20)); !errors.Is(err, ErrBackfillNotISIN) {
+		t.Errorf("Backfill(CASH) error = %v; want ErrBackfillNotISIN", err)
+	}
+	other := &models.Holding{AccountID: accountID, Symbol: "US0378331005", Name: "Apple", Quantity: 1}
+	if _, err := s.Backfill(user.ID, other, time.Time{}); !errors.Is(err, ErrBackfillNoStart) {
+		t.Errorf("Backfill() without acquisitions or a date error = %v; want ErrBackfillNoStart", err)
+	}
+}
+
+func TestFetchPriceHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prices/DK0010244508" || r.URL.Query().Get("from") != "2024-01-01" || r.URL.Query().Get("to") != "2024-01-31" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"date": "2024-01-02", "close": 11000.5}, {"date": "2024-01-03", "close": 11100}]`))
+	}))
+	defer srv.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices, err := fetchPriceHistory(srv.URL+"/prices/{isin}?from={from}&to={to}", "DK0010244508", from, from.AddDate(0, 0, 30))
+	if err != nil || len(prices) != 2 || prices[0].Price != 11000.5 || prices[1].Day.Day() != 3 {
+		t.Fatalf("fetchPriceHistory() = %+v, %v; want 2 prices", prices, err)
+	}
+
+	if _, err := fetchPriceHistory(srv.URL+"/missing/{isin}", "DK0010244508", from, from); err == nil {
+		t.Error("fetchPriceHistory() from a failing provider succeeded")
+	}
+}
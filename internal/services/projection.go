@@ -0,0 +1,73 @@
+package services
+
+import (
+	"math"
+
+	"wealth_tracker/internal/models"
+)
+
+// DefaultExpectedReturn is the annual return, in percent, assumed for
+// categories without their own expected return.
+const DefaultExpectedReturn = 7.0
+
+// maxProjectionMonths caps projections that never reach their target.
+const maxProjectionMonths = 100 * 12
+
+// ProjectionBucket is an amount growing at its own expected annual return.
+type ProjectionBucket struct {
+	Amount         float64
+	ExpectedReturn float64 // Annual %
+}
+
+// CategoryExpectedReturn returns the expected annual return of a category, or
+// DefaultExpectedReturn for uncategorized accounts and categories without one.
+func CategoryExpectedReturn(category *models.Category) float64 {
+	if category == nil || category.ExpectedReturn == nil {
+		return DefaultExpectedReturn
+	}
+	return *category.ExpectedReturn
+}
+
+// WeightedExpectedReturn returns the expected annual return of the buckets
+// weighted by their positive amounts. Debt does not dilute the return of the
+// assets. Returns DefaultExpectedReturn if there are no assets.
+func WeightedExpectedReturn(buckets []ProjectionBucket) float64 {
+	var total, weighted float64
+	for _, b := range buckets {
+		if b.Amount <= 0 {
+			continue
+		}
+		total += b.Amount
+		weighted += b.Amount * b.ExpectedReturn
+	}
+	if total == 0 {
+		return DefaultExpectedReturn
+	}
+	return weighted / total
+}
+
+// MonthsToTarget returns how many months it takes for the sum of the buckets,
+// each compounding monthly at its own return, to reach target. Returns false
+// if the target is not reached within 100 years.
+func MonthsToTarget(buckets []ProjectionBucket, target float64) (int, bool) {
+	amounts := make([]float64, len(buckets))
+	rates := make([]float64, len(buckets))
+	for i, b := range buckets {
+		amounts[i] = b.Amount
+		rates[i] = math.Pow(1+b.ExpectedReturn/100, 1.0/12) - 1
+	}
+
+	for month := 0; month <= maxProjectionMonths; month++ {
+		var total float64
+		for i := range amounts {
+			total += amounts[i]
+		}
+		if total >= target {
+			return month, true
+		}
+		for i := range amounts {
+			amounts[i] *= 1 + rates[i]
+		}
+	}
+	return 0, false
+}
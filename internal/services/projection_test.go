@@ -0,0 +1,71 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestCategoryExpectedReturn(t *testing.T) {
+	pension := 5.0
+	tests := []struct {
+		name     string
+		category *models.Category
+		want     float64
+	}{
+		{"uncategorized", nil, DefaultExpectedReturn},
+		{"no assumption", &models.Category{Name: "Stocks"}, DefaultExpectedReturn},
+		{"own assumption", &models.Category{Name: "Pension", ExpectedReturn: &pension}, 5},
+	}
+
+	for _, tc := range tests {
+		if got := CategoryExpectedReturn(tc.category); got != tc.want {
+			t.Errorf("%s: CategoryExpectedReturn() = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWeightedExpectedReturn(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets []ProjectionBucket
+		want    float64
+	}{
+		{"empty", nil, DefaultExpectedReturn},
+		{"single", []ProjectionBucket{{Amount: 1000, ExpectedReturn: 4}}, 4},
+		{"weighted", []ProjectionBucket{{Amount: 3000, ExpectedReturn: 8}, {Amount: 1000, ExpectedReturn: 0}}, 6},
+		{"debt ignored", []ProjectionBucket{{Amount: 1000, ExpectedReturn: 8}, {Amount: -500, ExpectedReturn: 0}}, 8},
+	}
+
+	for _, tc := range tests {
+		if got := WeightedExpectedReturn(tc.buckets); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: WeightedExpectedReturn() = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMonthsToTarget(t *testing.T) {
+	// Already reached
+	if months, ok := MonthsToTarget([]ProjectionBucket{{Amount: 100, ExpectedReturn: 5}}, 100); !ok || months != 0 {
+		t.Errorf("MonthsToTarget() reached = %d, %v; want 0, true", months, ok)
+	}
+
+	// 100 doubles to 200 at 7.2% in about ten years
+	months, ok := MonthsToTarget([]ProjectionBucket{{Amount: 100, ExpectedReturn: 7.2}}, 200)
+	if !ok || months < 118 || months > 122 {
+		t.Errorf("MonthsToTarget() doubling = %d, %v; want ~120, true", months, ok)
+	}
+
+	// Cash at 0% never grows
+	if _, ok := MonthsToTarget([]ProjectionBucket{{Amount: 100, ExpectedReturn: 0}}, 200); ok {
+		t.Error("MonthsToTarget() with 0% return reached target")
+	}
+
+	// A higher-returning bucket gets there sooner than the same money in cash
+	mixed, _ := MonthsToTarget([]ProjectionBucket{{Amount: 50, ExpectedReturn: 10}, {Amount: 50, ExpectedReturn: 0}}, 150)
+	equities, _ := MonthsToTarget([]ProjectionBucket{{Amount: 100, ExpectedReturn: 10}}, 150)
+	if equities >= mixed {
+		t.Errorf("MonthsToTarget() equities = %d, mixed = %d; want equities sooner", equities, mixed)
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Retention policy validation errors.
+var (
+	ErrRetentionUnknownTable = errors.New("unknown history table")
+	ErrRetentionNegative     = errors.New("days cannot be negative")
+	ErrRetentionCannotThin   = errors.New("this table cannot be thinned to monthly")
+	ErrRetentionThinAfter    = errors.New("thinning must start before rows are deleted")
+)
+
+// RetentionTable is a history table a retention policy can be set for.
+type RetentionTable struct {
+	Name        string
+	Label       string
+	Description string
+	DateColumn  string
+	ThinKey     []string // Columns identifying a series of rows; nil if rows cannot be thinned
+}
+
+// CanThin reports whether the table's rows can be thinned to monthly.
+func (t RetentionTable) CanThin() bool {
+	return t.ThinKey != nil
+}
+
+// RetentionTables are the history tables that grow without bound, in the
+// order they are shown.
+var RetentionTables = []RetentionTable{
+	{
+		Name:        "holding_snapshots",
+		Label:       "Holding snapshots",
+		Description: "Daily quantity and value of each holding, used to compare holdings between dates",
+		DateColumn:  "day",
+		ThinKey:     []string{"account_id", "symbol"},
+	},
+	{
+		Name:        "goal_snapshots",
+		Label:       "Goal snapshots",
+		Description: "Weekly goal progress shown on the burn-up charts",
+		DateColumn:  "week_start",
+		ThinKey:     []string{"goal_id"},
+	},
+	{
+		Name:        "currency_rate_history",
+		Label:       "Exchange rate history",
+		Description: "Daily exchange rates, used to explain currency effects on net worth",
+		DateColumn:  "day",
+		ThinKey:     []string{"from_currency", "to_currency"},
+	},
+	{
+		Name:        "net_worth_snapshots",
+		Label:       "Net worth snapshots",
+		Description: "Daily net worth with category totals, served by the net worth history API",
+		DateColumn:  "day",
+		ThinKey:     []string{"user_id"},
+	},
+	{
+		Name:        "holding_history",
+		Label:       "Holding history",
+		Description: "Holdings removed by syncs, which can be restored from the holdings report",
+		DateColumn:  "deleted_at",
+	},
+	{
+		Name:        "sync_history",
+		Label:       "Sync history",
+		Description: "Broker sync runs and their errors",
+		DateColumn:  "started_at",
+	},
+	{
+		Name:        "audit_log",
+		Label:       "Audit log",
+		Description: "User and admin actions",
+		DateColumn:  "created_at",
+	},
+}
+
+// retentionTable returns the history table with the name.
+func retentionTable(name string) (RetentionTable, bool) {
+	for _, t := range RetentionTables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return RetentionTable{}, false
+}
+
+// RetentionStatus is a history table with its policy and size.
+type RetentionStatus struct {
+	Table  RetentionTable
+	Policy *models.RetentionPolicy
+	Rows   int64
+}
+
+// RetentionService keeps history tables within the retention policies set
+// by admins.
+type RetentionService struct {
+	repo *repository.RetentionPolicyRepository
+}
+
+// NewRetentionService creates a new RetentionService.
+func NewRetentionService(repo *repository.RetentionPolicyRepository) *RetentionService {
+	return &RetentionService{repo: repo}
+}
+
+// Statuses returns every history table with its policy, which keeps rows
+// forever if none was set, and its number of rows.
+func (s *RetentionService) Statuses() ([]RetentionStatus, error) {
+	policies, err := s.policies()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]RetentionStatus, 0, len(RetentionTables))
+	for _, t := range RetentionTables {
+		rows, err := s.repo.CountRows(t.Name)
+		if err != nil {
+			return nil, fmt.Errorf("counting %s: %w", t.Name, err)
+		}
+		policy, ok := policies[t.Name]
+		if !ok {
+			policy = &models.RetentionPolicy{TableName: t.Name}
+		}
+		statuses = append(statuses, RetentionStatus{Table: t, Policy: policy, Rows: rows})
+	}
+	return statuses, nil
+}
+
+// SetPolicy validates and saves the policy of a history table.
+func (s *RetentionService) SetPolicy(policy *models.RetentionPolicy) error {
+	if err := ValidateRetentionPolicy(policy); err != nil {
+		return err
+	}
+	return s.repo.Save(policy)
+}
+
+// ValidateRetentionPolicy checks that a policy is for a known history table
+// and that it thins rows before deleting them.
+func ValidateRetentionPolicy(policy *models.RetentionPolicy) error {
+	t, ok := retentionTable(policy.TableName)
+	if !ok {
+		return ErrRetentionUnknownTable
+	}
+	if policy.KeepDays < 0 || policy.ThinAfterDays < 0 {
+		return ErrRetentionNegative
+	}
+	if policy.ThinAfterDays > 0 && !t.CanThin() {
+		return ErrRetentionCannotThin
+	}
+	if policy.ThinAfterDays > 0 && policy.KeepDays > 0 && policy.ThinAfterDays >= policy.KeepDays {
+		return ErrRetentionThinAfter
+	}
+	return nil
+}
+
+// Enforce deletes and thins the rows of every history table with a policy,
+// counting days back from now, and returns the number of rows deleted per
+// table. A failing table does not stop the others.
+func (s *RetentionService) Enforce(now time.Time) (map[string]int64, error) {
+	policies, err := s.policies()
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]int64)
+	var errs []error
+	for _, t := range RetentionTables {
+		policy, ok := policies[t.Name]
+		if !ok || (policy.KeepDays == 0 && policy.ThinAfterDays == 0) {
+			continue
+		}
+		n, err := s.enforce(t, policy, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Name, err))
+			continue
+		}
+		deleted[t.Name] = n
+		if err := s.repo.RecordRun(t.Name, now, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: recording run: %w", t.Name, err))
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// enforce applies a policy to its table.
+func (s *RetentionService) enforce(t RetentionTable, policy *models.RetentionPolicy, now time.Time) (int64, error) {
+	var total int64
+	if policy.KeepDays > 0 {
+		n, err := s.repo.DeleteBefore(t.Name, t.DateColumn, now.AddDate(0, 0, -policy.KeepDays))
+		if err != nil {
+			return total, fmt.Errorf("deleting old rows: %w", err)
+		}
+		total += n
+	}
+	if policy.ThinAfterDays > 0 && t.CanThin() {
+		n, err := s.repo.ThinBefore(t.Name, t.DateColumn, t.ThinKey, now.AddDate(0, 0, -policy.ThinAfterDays))
+		if err != nil {
+			return total, fmt.Errorf("thinning rows: %w", err)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// policies returns the saved policies by table name.
+func (s *RetentionService) policies() (map[string]*models.RetentionPolicy, error) {
+	saved, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("getting retention policies: %w", err)
+	}
+	policies := make(map[string]*models.RetentionPolicy, len(saved))
+	for _, p := range saved {
+		policies[p.TableName] = p
+	}
+	return policies, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestValidateRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		policy models.RetentionPolicy
+		want   error
+	}{
+		{models.RetentionPolicy{TableName: "holding_snapshots", KeepDays: 1825, ThinAfterDays: 730}, nil},
+		{models.RetentionPolicy{TableName: "audit_log", KeepDays: 365}, nil},
+		{models.RetentionPolicy{TableName: "users", KeepDays: 365}, ErrRetentionUnknownTable},
+		{models.RetentionPolicy{TableName: "sync_history", KeepDays: -1}, ErrRetentionNegative},
+		{models.RetentionPolicy{TableName: "audit_log", ThinAfterDays: 30}, ErrRetentionCannotThin},
+		{models.RetentionPolicy{TableName: "goal_snapshots", KeepDays: 365, ThinAfterDays: 365}, ErrRetentionThinAfter},
+	}
+	for _, tt := range tests {
+		if got := ValidateRetentionPolicy(&tt.policy); got != tt.want {
+			t.Errorf("ValidateRetentionPolicy(%+v) = %v; want %v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestRetentionService_Enforce(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	// Daily rates of two pairs in two old months and a few recent ones
+	for _, day := range []string{"2023-01-05", "2023-01-20", "2023-01-31", "2023-02-10", "2023-02-11", "2024-12-01", "2025-06-01"} {
+		for _, pair := range [][2]string{{"USD", "DKK"}, {"EUR", "DKK"}} {
+			if _, err := db.Exec(`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES (?, ?, ?, 1)`, pair[0], pair[1], day); err != nil {
+				t.Fatalf("inserting rate: %v", err)
+			}
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO audit_log (user_id, actor_id, action, entity_type, created_at) VALUES (1, 1, 'update', 'account', '2022-03-01 10:00:00'), (1, 1, 'update', 'account', '2025-05-01 10:00:00')`); err != nil {
+		t.Fatalf("inserting audit entries: %v", err)
+	}
+
+	service := NewRetentionService(repository.NewRetentionPolicyRepository(db))
+	if err := service.SetPolicy(&models.RetentionPolicy{TableName: "currency_rate_history", KeepDays: 730, ThinAfterDays: 365}); err != nil {
+		t.Fatalf("SetPolicy() error = %v", err)
+	}
+	if err := service.SetPolicy(&models.RetentionPolicy{TableName: "audit_log", KeepDays: 730}); err != nil {
+		t.Fatalf("SetPolicy() error = %v", err)
+	}
+
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	deleted, err := service.Enforce(now)
+	if err != nil {
+		t.Fatalf("Enforce() error = %v", err)
+	}
+
+	// 2023 is past the deletion cutoff; later rates are too recent to thin
+	if deleted["currency_rate_history"] != 10 || deleted["audit_log"] != 1 {
+		t.Errorf("deleted = %v; want 10 rates and 1 audit entry", deleted)
+	}
+
+	// Thinning keeps the last rate of each pair and month
+	if _, err := db.Exec(`DELETE FROM currency_rate_history`); err != nil {
+		t.Fatalf("clearing rates: %v", err)
+	}
+	for _, day := range []string{"2023-01-05", "2023-01-31", "2023-02-10"} {
+		db.Exec(`INSERT INTO currency_rate_history (from_currency, to_currency, day, rate) VALUES ('USD', 'DKK', ?, 1)`, day)
+	}
+	if err := service.SetPolicy(&models.RetentionPolicy{TableName: "currency_rate_history", ThinAfterDays: 365}); err != nil {
+		t.Fatalf("SetPolicy() error = %v", err)
+	}
+	if deleted, err = service.Enforce(now); err != nil || deleted["currency_rate_history"] != 1 {
+		t.Fatalf("Enforce() = %v, %v; want 1 rate thinned", deleted, err)
+	}
+	var remaining int
+	db.QueryRow(`SELECT COUNT(*) FROM currency_rate_history WHERE day IN ('2023-01-31', '2023-02-10')`).Scan(&remaining)
+	if remaining != 2 {
+		t.Errorf("%d of the month-end rates remain; want 2", remaining)
+	}
+
+	statuses, err := service.Statuses()
+	if err != nil {
+		t.Fatalf("Statuses() error = %v", err)
+	}
+	if len(statuses) != len(RetentionTables) || statuses[2].Policy.LastRunAt == nil || statuses[0].Policy.KeepDays != 0 {
+		t.Errorf("statuses = %+v; want every table, with the rate history's last run", statuses)
+	}
+}
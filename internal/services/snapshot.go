@@ -0,0 +1,58 @@
+package services
+
+import (
+	"sort"
+
+	"wealth_tracker/internal/models"
+)
+
+// SnapshotComparison is how an account changed since a snapshot of it.
+type SnapshotComparison struct {
+	Snapshot   *models.AccountSnapshot
+	BalanceNow float64
+	// Holdings held at the snapshot or now, ordered by symbol. Unchanged
+	// holdings are included with an empty Status.
+	Holdings []HoldingChange
+}
+
+// BalanceChange returns the change of the balance since the snapshot.
+func (c *SnapshotComparison) BalanceChange() float64 {
+	return c.BalanceNow - c.Snapshot.Balance
+}
+
+// CompareSnapshot compares a snapshot of an account, with its holdings, to
+// the account's current balance and holdings.
+func CompareSnapshot(account *models.Account, snapshot *models.AccountSnapshot, balance float64, holdings []*models.Holding) *SnapshotComparison {
+	changes := make(map[string]*HoldingChange)
+	get := func(symbol string) *HoldingChange {
+		if changes[symbol] == nil {
+			changes[symbol] = &HoldingChange{Account: account, Symbol: symbol}
+		}
+		return changes[symbol]
+	}
+	for _, h := range snapshot.Holdings {
+		c := get(h.Symbol)
+		c.Name, c.FromQuantity, c.FromValue = h.Name, h.Quantity, h.CurrentValue
+	}
+	for _, h := range holdings {
+		c := get(h.Symbol)
+		c.Name, c.ToQuantity, c.ToValue = h.Name, h.Quantity, h.CurrentValue
+	}
+
+	comparison := &SnapshotComparison{Snapshot: snapshot, BalanceNow: balance}
+	for _, c := range changes {
+		switch {
+		case c.FromQuantity == 0 && c.ToQuantity != 0:
+			c.Status = CompareAdded
+		case c.FromQuantity != 0 && c.ToQuantity == 0:
+			c.Status = CompareRemoved
+		case c.FromQuantity != c.ToQuantity || c.FromValue != c.ToValue:
+			c.Status = CompareChanged
+		}
+		comparison.Holdings = append(comparison.Holdings, *c)
+	}
+	sort.Slice(comparison.Holdings, func(i, j int) bool {
+		return comparison.Holdings[i].Symbol < comparison.Holdings[j].Symbol
+	})
+	return comparison
+}
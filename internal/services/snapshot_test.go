@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestCompareSnapshot(t *testing.T) {
+	snapshot := &models.AccountSnapshot{
+		Balance: 50000,
+		Holdings: []models.AccountSnapshotHolding{
+			{Symbol: "NOVO", Name: "Novo", Quantity: 20, CurrentValue: 16000},
+			{Symbol: "DSV", Name: "DSV", Quantity: 5, CurrentValue: 7000},
+			{Symbol: "MAERSK", Name: "Maersk", Quantity: 1, CurrentValue: 11000},
+		},
+	}
+	holdings := []*models.Holding{
+		{Symbol: "NOVO", Name: "Novo", Quantity: 5, CurrentValue: 4000},
+		{Symbol: "DSV", Name: "DSV", Quantity: 5, CurrentValue: 7000},
+		{Symbol: "IWDA", Name: "iShares World", Quantity: 30, CurrentValue: 23000},
+	}
+
+	c := CompareSnapshot(&models.Account{ID: 1}, snapshot, 49500, holdings)
+	if c.BalanceChange() != -500 {
+		t.Errorf("BalanceChange() = %v; want -500", c.BalanceChange())
+	}
+
+	want := map[string]string{"DSV": "", "IWDA": CompareAdded, "MAERSK": CompareRemoved, "NOVO": CompareChanged}
+	if len(c.Holdings) != len(want) {
+		t.Fatalf("Holdings = %+v; want %d", c.Holdings, len(want))
+	}
+	for i, h := range c.Holdings {
+		if h.Status != want[h.Symbol] {
+			t.Errorf("%s status = %q; want %q", h.Symbol, h.Status, want[h.Symbol])
+		}
+		if i > 0 && c.Holdings[i-1].Symbol > h.Symbol {
+			t.Error("holdings are not ordered by symbol")
+		}
+	}
+	if novo := c.Holdings[3]; novo.QuantityChange() != -15 || novo.ValueChange() != -12000 {
+		t.Errorf("NOVO change = %v shares, %v value; want -15 and -12000", novo.QuantityChange(), novo.ValueChange())
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// AccountStatement is an account's statement for a tax year, as handed to an
+// accountant.
+type AccountStatement struct {
+	Account        *models.Account
+	Year           int
+	OpeningBalance float64               // Balance at the end of the previous year
+	ClosingBalance float64               // Balance at the end of the year
+	Transactions   []*models.Transaction // Oldest first
+	Dividends      []*models.Transaction
+	RealizedGains  []RealizedGain
+	HasLots        bool // Buys were imported, so realized gains are known
+}
+
+// RealizedGain is a sale of an instrument, with its cost computed by the
+// average cost method.
+type RealizedGain struct {
+	Date      time.Time
+	Symbol    string
+	Quantity  float64
+	Proceeds  float64 // Sale value less fees
+	CostBasis float64
+}
+
+// Gain returns the proceeds less the cost basis.
+func (g RealizedGain) Gain() float64 {
+	return g.Proceeds - g.CostBasis
+}
+
+// DividendTotal returns the sum of the year's dividends.
+func (s *AccountStatement) DividendTotal() float64 {
+	var total float64
+	for _, t := range s.Dividends {
+		total += t.Amount
+	}
+	return total
+}
+
+// RealizedGainTotal returns the sum of the year's realized gains and losses.
+func (s *AccountStatement) RealizedGainTotal() float64 {
+	var total float64
+	for _, g := range s.RealizedGains {
+		total += g.Gain()
+	}
+	return total
+}
+
+// NewAccountStatement builds the statement of an account for year from its
+// end-of-day balance history (oldest first), its transactions and its
+// imported buys and sells. categoryNames maps category IDs to names and is
+// used to recognize dividends.
+func NewAccountStatement(account *models.Account, year int, points []repository.BalancePoint, transactions []*models.Transaction, acquisitions []*models.HoldingAcquisition, categoryNames map[int64]string) *AccountStatement {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	statement := &AccountStatement{
+		Account:        account,
+		Year:           year,
+		OpeningBalance: balanceAt(points, start.AddDate(0, 0, -1)),
+		ClosingBalance: balanceAt(points, end.AddDate(0, 0, -1)),
+		HasLots:        len(acquisitions) > 0,
+	}
+
+	for _, t := range transactions {
+		if t.TransactionDate.Before(start) || !t.TransactionDate.Before(end) {
+			continue
+		}
+		statement.Transactions = append(statement.Transactions, t)
+	}
+	sort.SliceStable(statement.Transactions, func(i, j int) bool {
+		a, b := statement.Transactions[i], statement.Transactions[j]
+		if !a.TransactionDate.Equal(b.TransactionDate) {
+			return a.TransactionDate.Before(b.TransactionDate)
+		}
+		return a.ID < b.ID
+	})
+
+	for _, t := range statement.Transactions {
+		var category string
+		if t.CategoryID != nil {
+			category = categoryNames[*t.CategoryID]
+		}
+		if t.Amount > 0 && (IsDividend(t.Description) || IsDividend(category)) {
+			statement.Dividends = append(statement.Dividends, t)
+		}
+	}
+
+	for _, g := range RealizedGains(acquisitions) {
+		if !g.Date.Before(start) && g.Date.Before(end) {
+			statement.RealizedGains = append(statement.RealizedGains, g)
+		}
+	}
+	return statement
+}
+
+// IsDividend reports whether a transaction description or category name
+// denotes a dividend, in English or Danish.
+func IsDividend(text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(text, "dividend") || strings.Contains(text, "udbytte")
+}
+
+// RealizedGains returns the sales among acquisitions (oldest first) with the
+// cost of each computed by the average cost method, as used by the Danish tax
+// authorities: buys, including fees, raise the average cost of a symbol and
+// sales take their share of it. Sales beyond the quantity held have no cost.
+func RealizedGains(acquisitions []*models.HoldingAcquisition) []RealizedGain {
+	type position struct {
+		quantity float64
+		cost     float64
+	}
+	positions := make(map[string]*position)
+
+	var gains []RealizedGain
+	for _, a := range acquisitions {
+		p := positions[a.Symbol]
+		if p == nil {
+			p = &position{}
+			positions[a.Symbol] = p
+		}
+
+		if a.Quantity > 0 {
+			p.quantity += a.Quantity
+			p.cost += a.Quantity*a.Price + a.Fees
+			continue
+		}
+
+		sold := -a.Quantity
+		var cost float64
+		if p.quantity > 0 {
+			cost = p.cost * math.Min(sold, p.quantity) / p.quantity
+			p.cost -= cost
+			p.quantity = math.Max(p.quantity-sold, 0)
+		}
+		gains = append(gains, RealizedGain{
+			Date:      a.TradeDate,
+			Symbol:    a.Symbol,
+			Quantity:  sold,
+			Proceeds:  sold*a.Price - a.Fees,
+			CostBasis: cost,
+		})
+	}
+	return gains
+}
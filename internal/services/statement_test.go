@@ -0,0 +1,69 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestNewAccountStatement(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	dividendsID := int64(7)
+
+	points := []repository.BalancePoint{
+		{Date: day(2023, 6, 1), Balance: 1000},
+		{Date: day(2024, 3, 1), Balance: 1200},
+		{Date: day(2024, 12, 31), Balance: 1500},
+		{Date: day(2025, 1, 2), Balance: 9999},
+	}
+	transactions := []*models.Transaction{
+		{ID: 4, Amount: 5000, Description: "Next year", TransactionDate: day(2025, 1, 2)},
+		{ID: 3, Amount: 300, CategoryID: &dividendsID, TransactionDate: day(2024, 12, 31)},
+		{ID: 2, Amount: 200, Description: "Udbytte NOVO B", TransactionDate: day(2024, 3, 1)},
+		{ID: 1, Amount: 1000, Description: "Deposit", TransactionDate: day(2023, 6, 1)},
+	}
+	acquisitions := []*models.HoldingAcquisition{
+		{Symbol: "AAPL", TradeDate: day(2023, 1, 10), Quantity: 10, Price: 100, Fees: 10},
+		{Symbol: "AAPL", TradeDate: day(2023, 5, 10), Quantity: 10, Price: 120, Fees: 10},
+		{Symbol: "AAPL", TradeDate: day(2023, 8, 10), Quantity: -5, Price: 130},
+		{Symbol: "AAPL", TradeDate: day(2024, 2, 10), Quantity: -5, Price: 150, Fees: 5},
+	}
+
+	s := NewAccountStatement(&models.Account{ID: 1}, 2024, points, transactions, acquisitions, map[int64]string{dividendsID: "Dividends"})
+
+	if s.OpeningBalance != 1000 || s.ClosingBalance != 1500 {
+		t.Errorf("balances = %v to %v; want 1000 to 1500", s.OpeningBalance, s.ClosingBalance)
+	}
+	if len(s.Transactions) != 2 || s.Transactions[0].ID != 2 || s.Transactions[1].ID != 3 {
+		t.Errorf("Transactions = %v; want IDs 2 and 3, oldest first", s.Transactions)
+	}
+	if len(s.Dividends) != 2 || s.DividendTotal() != 500 {
+		t.Errorf("dividends = %d totalling %v; want 2 totalling 500", len(s.Dividends), s.DividendTotal())
+	}
+
+	// Average cost is (1010 + 1210) / 20 = 111 per share, unchanged by the
+	// first sale
+	if len(s.RealizedGains) != 1 {
+		t.Fatalf("RealizedGains = %v; want only the 2024 sale", s.RealizedGains)
+	}
+	g := s.RealizedGains[0]
+	if g.Quantity != 5 || g.Proceeds != 745 || math.Abs(g.CostBasis-555) > 1e-9 {
+		t.Errorf("gain = %+v; want 5 sold for 745 at a cost of 555", g)
+	}
+	if !s.HasLots || math.Abs(s.RealizedGainTotal()-190) > 1e-9 {
+		t.Errorf("RealizedGainTotal() = %v; want 190", s.RealizedGainTotal())
+	}
+}
+
+func TestRealizedGains_SaleBeyondHolding(t *testing.T) {
+	gains := RealizedGains([]*models.HoldingAcquisition{
+		{Symbol: "X", Quantity: 2, Price: 10},
+		{Symbol: "X", Quantity: -3, Price: 20},
+	})
+	if len(gains) != 1 || gains[0].CostBasis != 20 || gains[0].Gain() != 40 {
+		t.Errorf("gains = %+v; want a cost of 20 for the 2 shares held", gains)
+	}
+}
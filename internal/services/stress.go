@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"wealth_tracker/internal/models"
+)
+
+// Asset classes a stress scenario shocks.
+const (
+	StressEquity = "equity"
+	StressCrypto = "crypto"
+	StressOther  = "other"
+)
+
+// ErrStressShockRange is returned for a shock that would make values negative.
+var ErrStressShockRange = errors.New("shocks cannot be below -100%")
+
+// StressScenario is a set of shocks applied to the current composition. Shocks
+// are percent changes, e.g. -30 for a 30% fall.
+type StressScenario struct {
+	EquityShock   float64 `json:"equity_shock"`
+	CryptoShock   float64 `json:"crypto_shock"`
+	Currency      string  `json:"currency"`        // Foreign currency whose rate is shocked
+	CurrencyShock float64 `json:"currency_shock"`  // Change of Currency against the base currency
+	DebtRateShock float64 `json:"debt_rate_shock"` // Percentage points added to the interest rate of debt
+}
+
+// DefaultStressScenario returns a severe but plausible downturn: a stock
+// market crash, a weaker dollar, a crypto winter and higher interest rates.
+func DefaultStressScenario() StressScenario {
+	return StressScenario{
+		EquityShock:   -30,
+		CryptoShock:   -50,
+		Currency:      "USD",
+		CurrencyShock: -10,
+		DebtRateShock: 2,
+	}
+}
+
+// StressPosition is a holding, or an account balance, before and after the
+// shocks. Values are in the base currency.
+type StressPosition struct {
+	AccountName string  `json:"account_name"`
+	Symbol      string  `json:"symbol"`
+	Name        string  `json:"name"`
+	Currency    string  `json:"currency"`
+	AssetClass  string  `json:"asset_class"`
+	Before      float64 `json:"before"`
+	After       float64 `json:"after"`
+}
+
+// Change returns the change of the position's value.
+func (p StressPosition) Change() float64 {
+	return p.After - p.Before
+}
+
+// StressLiability is a debt and the extra interest a year of the rate shock
+// costs on it. Amounts are in the base currency.
+type StressLiability struct {
+	AccountName   string  `json:"account_name"`
+	Balance       float64 `json:"balance"`
+	InterestRate  float64 `json:"interest_rate"`
+	ShockedRate   float64 `json:"shocked_rate"`
+	ExtraInterest float64 `json:"extra_interest"`
+}
+
+// StressClassImpact is the value of an asset class before and after the shocks.
+type StressClassImpact struct {
+	AssetClass string  `json:"asset_class"`
+	Before     float64 `json:"before"`
+	After      float64 `json:"after"`
+}
+
+// StressGoalImpact is a goal's progress before and after the shocks. Target
+// and worth are in the base currency.
+type StressGoalImpact struct {
+	Goal           *models.Goal `json:"goal"`
+	Target         float64      `json:"target"`
+	WorthBefore    float64      `json:"worth_before"`
+	WorthAfter     float64      `json:"worth_after"`
+	ProgressBefore float64      `json:"progress_before"`
+	ProgressAfter  float64      `json:"progress_after"`
+}
+
+// Shortfall returns how far the goal's worth falls below its target after the
+// shocks, or 0 if it stays reached.
+func (g StressGoalImpact) Shortfall() float64 {
+	return math.Max(0, g.Target-g.WorthAfter)
+}
+
+// StressTestResult is the outcome of a stress scenario. Net worth after the
+// shocks includes a year of the extra interest on debt.
+type StressTestResult struct {
+	Scenario       StressScenario      `json:"scenario"`
+	BaseCurrency   string              `json:"base_currency"`
+	AssetsBefore   float64             `json:"assets_before"`
+	AssetsAfter    float64             `json:"assets_after"`
+	Debt           float64             `json:"debt"`
+	ExtraInterest  float64             `json:"extra_interest"`
+	NetWorthBefore float64             `json:"net_worth_before"`
+	NetWorthAfter  float64             `json:"net_worth_after"`
+	ByAssetClass   []StressClassImpact `json:"by_asset_class"`
+	Positions      []StressPosition    `json:"positions"`
+	Liabilities    []StressLiability   `json:"liabilities"`
+	Goals          []StressGoalImpact  `json:"goals"`
+	// Currencies without a provider or manual rate, counted 1:1
+	UnconvertedCurrencies []string `json:"unconverted_currencies,omitempty"`
+}
+
+// Change returns the change of net worth.
+func (r *StressTestResult) Change() float64 {
+	return r.NetWorthAfter - r.NetWorthBefore
+}
+
+// ChangePct returns the change of net worth as a percentage of net worth
+// before the shocks.
+func (r *StressTestResult) ChangePct() float64 {
+	if r.NetWorthBefore == 0 {
+		return 0
+	}
+	return r.Change() / math.Abs(r.NetWorthBefore) * 100
+}
+
+// StressAssetClass returns the asset class of an instrument type or, for
+// account balances, of the account's category name.
+func StressAssetClass(instrumentType string) string {
+	t := strings.ToLower(instrumentType)
+	for _, crypto := range []string{"crypto", "bitcoin"} {
+		if strings.Contains(t, crypto) {
+			return StressCrypto
+		}
+	}
+	for _, equity := range []string{"stock", "etf", "equit", "share", "fund", "aktie", "esh"} {
+		if strings.Contains(t, equity) {
+			return StressEquity
+		}
+	}
+	return StressOther
+}
+
+// Apply returns a position's value after the shocks of the scenario, in the
+// base currency.
+func (sc StressScenario) Apply(value float64, assetClass, currency string) float64 {
+	switch assetClass {
+	case StressEquity:
+		value *= 1 + sc.EquityShock/100
+	case StressCrypto:
+		value *= 1 + sc.CryptoShock/100
+	}
+	if sc.Currency != "" && strings.EqualFold(currency, sc.Currency) {
+		value *= 1 + sc.CurrencyShock/100
+	}
+	return value
+}
+
+// RunStressTest applies the scenario to the user's current composition and
+// debt, and works out the impact on the goals. Analytics exclusions are not
+// applied, as the test is about the user's actual net worth.
+func (s *PortfolioService) RunStressTest(userID int64, scenario StressScenario, goals []*models.Goal) (*StressTestResult, error) {
+	composition, err := s.GetPortfolioComposition(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.accountRepo.GetByUserIDActiveOnly(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StressTestResult{
+		Scenario:              scenario,
+		BaseCurrency:          s.baseCurrency,
+		Positions:             make([]StressPosition, 0, len(composition.Holdings)),
+		UnconvertedCurrencies: composition.UnconvertedCurrencies,
+	}
+
+	// Worth by category, before and after, for category goals
+	accountCategory := make(map[int64]int64, len(accounts))
+	for _, account := range accounts {
+		if account.CategoryID != nil {
+			accountCategory[account.ID] = *account.CategoryID
+		}
+	}
+	categoryBefore := make(map[int64]float64)
+	categoryAfter := make(map[int64]float64)
+
+	classes := make(map[string]*StressClassImpact)
+	for _, h := range composition.Holdings {
+		assetClass := StressAssetClass(h.InstrumentType)
+		after := scenario.Apply(h.ValueInBase, assetClass, h.Currency)
+
+		result.Positions = append(result.Positions, StressPosition{
+			AccountName: h.AccountName,
+			Symbol:      h.Symbol,
+			Name:        h.Name,
+			Currency:    h.Currency,
+			AssetClass:  assetClass,
+			Before:      h.ValueInBase,
+			After:       after,
+		})
+		result.AssetsBefore += h.ValueInBase
+		result.AssetsAfter += after
+
+		if _, exists := classes[assetClass]; !exists {
+			classes[assetClass] = &StressClassImpact{AssetClass: assetClass}
+		}
+		classes[assetClass].Before += h.ValueInBase
+		classes[assetClass].After += after
+
+		if categoryID, ok := accountCategory[h.AccountID]; ok {
+			categoryBefore[categoryID] += h.ValueInBase
+			categoryAfter[categoryID] += after
+		}
+	}
+
+	for _, account := range accounts {
+		if !account.IsLiability {
+			continue
+		}
+		balance, err := s.transactionRepo.GetLatestBalance(account.ID)
+		if err != nil {
+			return nil, err
+		}
+		debt, _ := s.convertToBase(userID, math.Abs(balance), account.Currency)
+		if debt == 0 {
+			continue
+		}
+		extra := debt * scenario.DebtRateShock / 100
+		result.Liabilities = append(result.Liabilities, StressLiability{
+			AccountName:   account.Name,
+			Balance:       debt,
+			InterestRate:  account.InterestRate,
+			ShockedRate:   account.InterestRate + scenario.DebtRateShock,
+			ExtraInterest: extra,
+		})
+		result.Debt += debt
+		result.ExtraInterest += extra
+
+		if account.CategoryID != nil {
+			categoryBefore[*account.CategoryID] -= debt
+			categoryAfter[*account.CategoryID] -= debt + extra
+		}
+	}
+
+	result.NetWorthBefore = result.AssetsBefore - result.Debt
+	result.NetWorthAfter = result.AssetsAfter - result.Debt - result.ExtraInterest
+
+	for _, class := range classes {
+		result.ByAssetClass = append(result.ByAssetClass, *class)
+	}
+	sort.Slice(result.ByAssetClass, func(i, j int) bool {
+		return result.ByAssetClass[i].Before > result.ByAssetClass[j].Before
+	})
+	sort.SliceStable(result.Positions, func(i, j int) bool {
+		return result.Positions[i].Change() < result.Positions[j].Change()
+	})
+
+	for _, goal := range goals {
+		target, ok := s.convertToBase(userID, goal.TargetAmount, goal.TargetCurrency)
+		if !ok && !slices.Contains(result.UnconvertedCurrencies, goal.TargetCurrency) {
+			result.UnconvertedCurrencies = append(result.UnconvertedCurrencies, goal.TargetCurrency)
+		}
+		impact := StressGoalImpact{Goal: goal, Target: target, WorthBefore: result.NetWorthBefore, WorthAfter: result.NetWorthAfter}
+		if goal.CategoryID != nil {
+			impact.WorthBefore = categoryBefore[*goal.CategoryID]
+			impact.WorthAfter = categoryAfter[*goal.CategoryID]
+		}
+		impact.ProgressBefore = goalProgress(impact.WorthBefore, impact.Target)
+		impact.ProgressAfter = goalProgress(impact.WorthAfter, impact.Target)
+		result.Goals = append(result.Goals, impact)
+	}
+
+	return result, nil
+}
+
+// goalProgress returns the percentage of the target a worth reaches, between
+// 0 and 100.
+func goalProgress(worth, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(100, worth/target*100))
+}
+
+// ValidateStressScenario checks that no shock wipes out more than the whole
+// value, and normalizes the shocked currency.
+func ValidateStressScenario(sc *StressScenario) error {
+	sc.Currency = strings.ToUpper(strings.TrimSpace(sc.Currency))
+	for _, shock := range []float64{sc.EquityShock, sc.CryptoShock, sc.CurrencyShock} {
+		if shock < -100 {
+			return ErrStressShockRange
+		}
+	}
+	return nil
+}
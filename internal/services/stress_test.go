@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestStressAssetClass(t *testing.T) {
+	for instrumentType, want := range map[string]string{
+		"stock":      StressEquity,
+		"ETF":        StressEquity,
+		"MutualFund": StressEquity,
+		"Crypto":     StressCrypto,
+		"Bond":       StressOther,
+		"":           StressOther,
+		"Savings":    StressOther,
+	} {
+		if got := StressAssetClass(instrumentType); got != want {
+			t.Errorf("StressAssetClass(%q) = %q; want %q", instrumentType, got, want)
+		}
+	}
+}
+
+func TestStressScenario_Apply(t *testing.T) {
+	sc := DefaultStressScenario()
+	tests := []struct {
+		assetClass, currency string
+		want                 float64
+	}{
+		{StressEquity, "DKK", 700},
+		{StressEquity, "usd", 630},
+		{StressCrypto, "USD", 450},
+		{StressOther, "DKK", 1000},
+	}
+	for _, tt := range tests {
+		if got := sc.Apply(1000, tt.assetClass, tt.currency); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Apply(1000, %s, %s) = %.2f; want %.2f", tt.assetClass, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStressScenario(t *testing.T) {
+	sc := StressScenario{EquityShock: -30, Currency: " usd "}
+	if err := ValidateStressScenario(&sc); err != nil || sc.Currency != "USD" {
+		t.Errorf("ValidateStressScenario() = %v, currency %q; want valid USD", err, sc.Currency)
+	}
+	sc.CryptoShock = -120
+	if err := ValidateStressScenario(&sc); err != ErrStressShockRange {
+		t.Errorf("ValidateStressScenario() = %v; want %v", err, ErrStressShockRange)
+	}
+}
+
+func TestPortfolioService_RunStressTest(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	holdingRepo := repository.NewHoldingRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
+	currency := NewCurrencyService(db)
+	currency.fetch = func(from, to string) (float64, error) {
+		if from == "EUR" && to == "DKK" {
+			return 7.5, nil
+		}
+		return 0, errors.New("no rate found")
+	}
+	service := NewPortfolioServiceWithCurrency(accountRepo, holdingRepo, categoryRepo, transactionRepo, repository.NewAllocationTargetRepository(db), currency, "DKK")
+
+	userID, err := userRepo.Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test", DefaultCurrency: "DKK"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	now := time.Now()
+	depotID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Depot", Currency: "DKK", IsActive: true})
+	if _, err := holdingRepo.Create(&models.Holding{AccountID: depotID, Symbol: "SPY", Name: "S&P 500", Quantity: 1, CurrentValue: 100000, Currency: "DKK", InstrumentType: "etf"}); err != nil {
+		t.Fatalf("creating holding: %v", err)
+	}
+	savingsID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Savings", Currency: "DKK", IsActive: true})
+	transactionRepo.Create(&models.Transaction{AccountID: savingsID, Amount: 50000, BalanceAfter: 50000, TransactionDate: now})
+	loanID, _ := accountRepo.Create(&models.Account{UserID: userID, Name: "Loan", Currency: "DKK", IsActive: true, IsLiability: true, InterestRate: 4})
+	transactionRepo.Create(&models.Transaction{AccountID: loanID, Amount: -20000, BalanceAfter: -20000, TransactionDate: now})
+
+	goals := []*models.Goal{
+		{ID: 1, Name: "Freedom", TargetAmount: 120000, TargetCurrency: "DKK"},
+		{ID: 2, Name: "Holiday home", TargetAmount: 16000, TargetCurrency: "EUR"},
+	}
+	result, err := service.RunStressTest(userID, DefaultStressScenario(), goals)
+	if err != nil {
+		t.Fatalf("RunStressTest() error = %v", err)
+	}
+
+	// 100k of equities fall 30%, 20k of debt costs 2% more a year
+	if result.NetWorthBefore != 130000 || math.Abs(result.NetWorthAfter-99600) > 1e-6 {
+		t.Errorf("net worth = %.2f -> %.2f; want 130000 -> 99600", result.NetWorthBefore, result.NetWorthAfter)
+	}
+	if len(result.Liabilities) != 1 || result.Liabilities[0].ExtraInterest != 400 || result.Liabilities[0].ShockedRate != 6 {
+		t.Errorf("liabilities = %+v; want the loan at 6%% costing 400", result.Liabilities)
+	}
+	if len(result.Positions) != 2 || result.Positions[0].Symbol != "SPY" {
+		t.Errorf("positions = %+v; want the ETF first as the biggest loss", result.Positions)
+	}
+	if len(result.Goals) != 2 || result.Goals[0].ProgressBefore != 100 || result.Goals[0].ProgressAfter != 83 {
+		t.Errorf("goals = %+v; want progress 100%% -> 83%%", result.Goals)
+	}
+	if shortfall := result.Goals[0].Shortfall(); math.Abs(shortfall-20400) > 1e-6 {
+		t.Errorf("shortfall = %.2f; want 20400", shortfall)
+	}
+	// 16000 EUR is 120000 DKK
+	if euro := result.Goals[1]; euro.Target != 120000 || euro.ProgressAfter != 83 || math.Abs(euro.Shortfall()-20400) > 1e-6 {
+		t.Errorf("EUR goal = %+v, shortfall %.2f; want its target converted to 120000 DKK", euro, euro.Shortfall())
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Tax parameter validation errors.
+var (
+	ErrTaxYear      = errors.New("year must be between 2000 and 2100")
+	ErrTaxNegative  = errors.New("amounts cannot be negative")
+	ErrTaxRate      = errors.New("rates must be between 0 and 100")
+	ErrTaxRateOrder = errors.New("the high stock income rate cannot be below the low rate")
+)
+
+// BuiltInTaxParameters returns the tax figures compiled into the app.
+func BuiltInTaxParameters() *models.TaxParameters {
+	return &models.TaxParameters{
+		Year:                  BuiltInTaxYear,
+		ASKMaxDeposit:         ASKMaxDeposit,
+		StockGainThreshold:    StockGainThreshold,
+		StockGainLowRate:      StockGainLowRate,
+		StockGainHighRate:     StockGainHighRate,
+		ASKTaxRate:            ASKTaxRate,
+		PensionWithdrawalRate: PensionWithdrawalRate,
+		BuiltIn:               true,
+	}
+}
+
+// TaxParameterService manages the Danish tax figures of each year, which
+// admins update when they change.
+type TaxParameterService struct {
+	repo *repository.TaxParameterRepository
+}
+
+// NewTaxParameterService creates a new TaxParameterService.
+func NewTaxParameterService(repo *repository.TaxParameterRepository) *TaxParameterService {
+	return &TaxParameterService{repo: repo}
+}
+
+// All returns the tax parameters of every stored year, latest first, with
+// the built-in figures unless their year is stored.
+func (s *TaxParameterService) All() ([]*models.TaxParameters, error) {
+	params, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	builtIn := BuiltInTaxParameters()
+	for i, p := range params {
+		if p.Year == builtIn.Year {
+			return params, nil
+		}
+		if p.Year < builtIn.Year {
+			return append(params[:i], append([]*models.TaxParameters{builtIn}, params[i:]...)...), nil
+		}
+	}
+	return append(params, builtIn), nil
+}
+
+// ForYear returns the tax parameters that apply in a year: those of the year
+// itself, or else of the latest earlier year, since figures carry over until
+// an admin enters the new ones. Years before any known year get the earliest.
+func (s *TaxParameterService) ForYear(year int) (*models.TaxParameters, error) {
+	params, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		if p.Year <= year {
+			return p, nil
+		}
+	}
+	return params[len(params)-1], nil
+}
+
+// Save validates and saves the tax parameters of a year.
+func (s *TaxParameterService) Save(p *models.TaxParameters) error {
+	if err := ValidateTaxParameters(p); err != nil {
+		return err
+	}
+	return s.repo.Save(p)
+}
+
+// Delete removes the stored tax parameters of a year, after which the year
+// gets those of the year before it.
+func (s *TaxParameterService) Delete(year int) error {
+	return s.repo.Delete(year)
+}
+
+// ValidateTaxParameters checks that a year's amounts are not negative and its
+// rates are percentages.
+func ValidateTaxParameters(p *models.TaxParameters) error {
+	if p.Year < 2000 || p.Year > 2100 {
+		return ErrTaxYear
+	}
+	if p.ASKMaxDeposit < 0 || p.StockGainThreshold < 0 {
+		return ErrTaxNegative
+	}
+	for _, rate := range []float64{p.StockGainLowRate, p.StockGainHighRate, p.ASKTaxRate, p.PensionWithdrawalRate} {
+		if rate < 0 || rate > 100 {
+			return ErrTaxRate
+		}
+	}
+	if p.StockGainHighRate < p.StockGainLowRate {
+		return ErrTaxRateOrder
+	}
+	return nil
+}
+
+// SetTaxParameterService makes tax tips use the tax figures admins set for
+// the current year instead of the built-in ones.
+func (s *PortfolioService) SetTaxParameterService(taxParams *TaxParameterService) {
+	s.taxParams = taxParams
+}
+
+// TaxParameters returns the tax figures of a year, falling back to the
+// built-in ones if none are set or they cannot be loaded.
+func (s *PortfolioService) TaxParameters(year int) *models.TaxParameters {
+	if s.taxParams == nil {
+		return BuiltInTaxParameters()
+	}
+	p, err := s.taxParams.ForYear(year)
+	if err != nil {
+		return BuiltInTaxParameters()
+	}
+	return p
+}
+
+// currentTaxParameters returns the tax figures of this year.
+func (s *PortfolioService) currentTaxParameters() *models.TaxParameters {
+	return s.TaxParameters(time.Now().Year())
+}
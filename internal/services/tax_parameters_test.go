@@ -0,0 +1,95 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestValidateTaxParameters(t *testing.T) {
+	valid := *BuiltInTaxParameters()
+	tests := []struct {
+		change func(p *models.TaxParameters)
+		want   error
+	}{
+		{func(p *models.TaxParameters) {}, nil},
+		{func(p *models.TaxParameters) { p.Year = 1999 }, ErrTaxYear},
+		{func(p *models.TaxParameters) { p.ASKMaxDeposit = -1 }, ErrTaxNegative},
+		{func(p *models.TaxParameters) { p.ASKTaxRate = 117 }, ErrTaxRate},
+		{func(p *models.TaxParameters) { p.StockGainHighRate = 20 }, ErrTaxRateOrder},
+	}
+	for i, tt := range tests {
+		p := valid
+		tt.change(&p)
+		if got := ValidateTaxParameters(&p); got != tt.want {
+			t.Errorf("case %d: ValidateTaxParameters() = %v; want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestTaxParameterService_ForYear(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	service := NewTaxParameterService(repository.NewTaxParameterRepository(db))
+
+	// Without stored years, every year gets the built-in figures
+	for _, year := range []int{2020, BuiltInTaxYear, 2030} {
+		if p, err := service.ForYear(year); err != nil || !p.BuiltIn {
+			t.Errorf("ForYear(%d) = %+v, %v; want the built-in figures", year, p, err)
+		}
+	}
+
+	next := *BuiltInTaxParameters()
+	next.Year, next.ASKMaxDeposit, next.StockGainThreshold = BuiltInTaxYear+1, 174200, 79400
+	if err := service.Save(&next); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	older := *BuiltInTaxParameters()
+	older.Year, older.ASKMaxDeposit = BuiltInTaxYear-1, 135900
+	if err := service.Save(&older); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		year    int
+		deposit float64
+	}{
+		{BuiltInTaxYear - 5, 135900}, // Before any known year: the earliest
+		{BuiltInTaxYear - 1, 135900},
+		{BuiltInTaxYear, ASKMaxDeposit},
+		{BuiltInTaxYear + 1, 174200},
+		{BuiltInTaxYear + 3, 174200}, // Carried over until the new figures are entered
+	}
+	for _, tt := range tests {
+		p, err := service.ForYear(tt.year)
+		if err != nil {
+			t.Fatalf("ForYear(%d) error = %v", tt.year, err)
+		}
+		if p.ASKMaxDeposit != tt.deposit {
+			t.Errorf("ForYear(%d).ASKMaxDeposit = %v; want %v", tt.year, p.ASKMaxDeposit, tt.deposit)
+		}
+	}
+
+	all, err := service.All()
+	if err != nil || len(all) != 3 || all[0].Year != BuiltInTaxYear+1 || !all[1].BuiltIn || all[2].Year != BuiltInTaxYear-1 {
+		t.Errorf("All() = %+v, %v; want both stored years around the built-in one", all, err)
+	}
+
+	// Tax tips use the current year's ASK ceiling
+	ps := &PortfolioService{baseCurrency: "DKK", taxParams: service}
+	tips := ps.generateTaxTips(&PortfolioComposition{}, 0)
+	want := formatDKK(ps.currentTaxParameters().ASKMaxDeposit)
+	if len(tips) == 0 || !strings.Contains(tips[0].Description, want) {
+		t.Errorf("tips = %+v; want the ASK tip with %s left", tips, want)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTransactionImportRows limits the size of a single CSV transactions
+// upload.
+const maxTransactionImportRows = 10000
+
+// TransactionCSV is an uploaded CSV of bank transactions, read before its
+// columns are mapped.
+type TransactionCSV struct {
+	Header []string
+	Rows   [][]string
+}
+
+// TransactionCSVMapping tells which CSV column holds which field. Columns
+// are 0-based; Description is -1 if the file has no description column.
+type TransactionCSVMapping struct {
+	Date        int
+	Amount      int
+	Description int
+}
+
+// transactionCSVColumns maps header names of common bank exports to the
+// field they hold, for guessing the mapping.
+var transactionCSVColumns = map[string]string{
+	"date":           "date",
+	"booking date":   "date",
+	"posting date":   "date",
+	"dato":           "date",
+	"bogføringsdato": "date",
+	"bogført":        "date",
+	"amount":         "amount",
+	"beløb":          "amount",
+	"beløb i dkk":    "amount",
+	"description":    "description",
+	"text":           "description",
+	"tekst":          "description",
+	"memo":           "description",
+	"beskrivelse":    "description",
+	"payee":          "description",
+}
+
+// bankDateFormats are the transaction date formats accepted in bank exports:
+// ISO, or day first as Danish banks write them.
+var bankDateFormats = []string{"2006-01-02", "02-01-2006", "02.01.2006"}
+
+// parseBankDate parses the date of a transaction in a bank export.
+func parseBankDate(s string) (time.Time, bool) {
+	for _, layout := range bankDateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ReadTransactionCSV reads an uploaded CSV of bank transactions. The first
+// row is taken as the header. Both comma and semicolon delimited files are
+// accepted.
+func ReadTransactionCSV(r io.Reader) (*TransactionCSV, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader, header, err := newImportCSVReader(data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV: %w", err)
+	}
+	if len(rows) > maxTransactionImportRows {
+		return nil, fmt.Errorf("file exceeds %d rows", maxTransactionImportRows)
+	}
+	return &TransactionCSV{Header: header, Rows: rows}, nil
+}
+
+// GuessMapping guesses the columns from the header names, falling back to
+// the first three columns as date, amount and description.
+func (f *TransactionCSV) GuessMapping() TransactionCSVMapping {
+	mapping := TransactionCSVMapping{Date: -1, Amount: -1, Description: -1}
+	for i, h := range f.Header {
+		switch transactionCSVColumns[strings.ToLower(strings.TrimSpace(h))] {
+		case "date":
+			if mapping.Date < 0 {
+				mapping.Date = i
+			}
+		case "amount":
+			if mapping.Amount < 0 {
+				mapping.Amount = i
+			}
+		case "description":
+			if mapping.Description < 0 {
+				mapping.Description = i
+			}
+		}
+	}
+	if mapping.Date < 0 {
+		mapping.Date = 0
+	}
+	if mapping.Amount < 0 && len(f.Header) > 1 {
+		mapping.Amount = 1
+	}
+	if mapping.Description < 0 && len(f.Header) > 2 {
+		mapping.Description = 2
+	}
+	return mapping
+}
+
+// Statement converts the rows to statement entries with the given mapping.
+// Rows that fail validation are reported individually, numbered as in the
+// file with the header as row 1; the statement only contains valid rows.
+func (f *TransactionCSV) Statement(mapping TransactionCSVMapping) (*BankStatement, []HoldingImportRowError, error) {
+	columns := len(f.Header)
+	if mapping.Date < 0 || mapping.Date >= columns || mapping.Amount < 0 || mapping.Amount >= columns || mapping.Description >= columns {
+		return nil, nil, errors.New("choose the date and amount columns")
+	}
+	if mapping.Date == mapping.Amount {
+		return nil, nil, errors.New("date and amount must be different columns")
+	}
+
+	statement := &BankStatement{}
+	var rowErrors []HoldingImportRowError
+	for i, record := range f.Rows {
+		row := i + 2
+		field := func(col int) string {
+			if col < 0 || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		date, ok := parseBankDate(field(mapping.Date))
+		if !ok {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: fmt.Sprintf("invalid date %q", field(mapping.Date))})
+			continue
+		}
+		amount, err := parseImportDecimal(field(mapping.Amount))
+		if err != nil {
+			rowErrors = append(rowErrors, HoldingImportRowError{Row: row, Message: fmt.Sprintf("invalid amount %q", field(mapping.Amount))})
+			continue
+		}
+		description := field(mapping.Description)
+		if description == "" {
+			description = "Bank transaction"
+		}
+		statement.Entries = append(statement.Entries, BankStatementEntry{Date: date, Amount: amount, Description: description})
+	}
+
+	sort.SliceStable(statement.Entries, func(i, j int) bool {
+		return statement.Entries[i].Date.Before(statement.Entries[j].Date)
+	})
+	return statement, rowErrors, nil
+}
+
+// IsOFX reports whether an upload is an OFX or QFX file rather than a CSV.
+func IsOFX(data []byte) bool {
+	head := bytes.ToUpper(data[:min(len(data), 1024)])
+	return bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>"))
+}
+
+// ofxTag matches an OFX element: its name and the value up to the next tag.
+// Both the SGML flavour of OFX 1.x, which leaves elements unclosed, and the
+// XML of OFX 2.x are matched this way.
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+// ParseOFX parses the bank statement of an OFX or QFX file. Transactions are
+// returned oldest first, with the ledger balance as closing balance.
+func ParseOFX(r io.Reader) (*BankStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsOFX(data) {
+		return nil, errors.New("not an OFX file")
+	}
+
+	statement := &BankStatement{}
+	var entry *BankStatementEntry
+	var name, memo string
+	var inLedgerBalance bool
+	for _, m := range ofxTag.FindAllSubmatch(data, -1) {
+		closing, tag, value := len(m[1]) > 0, strings.ToUpper(string(m[2])), strings.TrimSpace(string(m[3]))
+
+		switch {
+		case tag == "STMTTRN" && !closing:
+			entry, name, memo = &BankStatementEntry{}, "", ""
+		case tag == "STMTTRN" && closing:
+			if entry == nil {
+				continue
+			}
+			if entry.Date.IsZero() {
+				return nil, fmt.Errorf("transaction of %.2f has no date", entry.Amount)
+			}
+			entry.Description = ofxDescription(name, memo)
+			statement.Entries = append(statement.Entries, *entry)
+			if len(statement.Entries) > maxStatementEntries {
+				return nil, fmt.Errorf("file exceeds %d entries", maxStatementEntries)
+			}
+			entry = nil
+		case tag == "LEDGERBAL" && !closing:
+			inLedgerBalance = true
+		case tag == "LEDGERBAL" && closing:
+			inLedgerBalance = false
+		case closing:
+		case tag == "CURDEF":
+			statement.Currency = value
+		case tag == "ACCTID":
+			statement.IBAN = value
+		case tag == "BALAMT" && inLedgerBalance:
+			balance, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ledger balance %q", value)
+			}
+			statement.ClosingBalance = &balance
+		case entry == nil:
+		case tag == "DTPOSTED":
+			date, err := parseOFXDate(value)
+			if err != nil {
+				return nil, err
+			}
+			entry.Date = date
+		case tag == "TRNAMT":
+			amount, err := parseImportDecimal(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid transaction amount %q", value)
+			}
+			entry.Amount = amount
+		case tag == "NAME":
+			name = value
+		case tag == "MEMO":
+			memo = value
+		}
+	}
+	sort.SliceStable(statement.Entries, func(i, j int) bool {
+		return statement.Entries[i].Date.Before(statement.Entries[j].Date)
+	})
+	return statement, nil
+}
+
+// parseOFXDate parses the date of an OFX datetime such as
+// 20240515120000.000[+1:CET]; the time and time zone are ignored.
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return date, nil
+}
+
+// ofxDescription returns the payee and memo of a transaction, leaving out the
+// memo when it repeats the payee.
+func ofxDescription(name, memo string) string {
+	switch {
+	case name == "" && memo == "":
+		return "Bank transaction"
+	case memo == "" || strings.EqualFold(name, memo):
+		return name
+	case name == "":
+		return memo
+	}
+	return name + " - " + memo
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTransactionCSV_GuessMapping(t *testing.T) {
+	data := "\ufeffBogføringsdato;Tekst;Beløb;Saldo\n" +
+		"15-05-2024;Netto;-249,50;9.750,50\n" +
+		"01.05.2024;Løn;30.000,00;10.000,00\n"
+	file, err := ReadTransactionCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadTransactionCSV() error = %v", err)
+	}
+	if len(file.Header) != 4 || file.Header[0] != "Bogføringsdato" || len(file.Rows) != 2 {
+		t.Fatalf("file = %+v; want 4 columns and 2 rows", file)
+	}
+
+	mapping := file.GuessMapping()
+	if mapping != (TransactionCSVMapping{Date: 0, Amount: 2, Description: 1}) {
+		t.Errorf("GuessMapping() = %+v; want date 0, amount 2, description 1", mapping)
+	}
+
+	statement, rowErrors, err := file.Statement(mapping)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("Statement() = %v, %v", rowErrors, err)
+	}
+	if len(statement.Entries) != 2 {
+		t.Fatalf("got %d entries; want 2", len(statement.Entries))
+	}
+	salary, payment := statement.Entries[0], statement.Entries[1]
+	if !salary.Date.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || salary.Amount != 30000 || salary.Description != "Løn" {
+		t.Errorf("entry 0 = %+v; want the salary first", salary)
+	}
+	if payment.Amount != -249.5 || payment.Description != "Netto" {
+		t.Errorf("entry 1 = %+v; want the card payment", payment)
+	}
+}
+
+func TestTransactionCSV_Statement_RowErrors(t *testing.T) {
+	file := &TransactionCSV{
+		Header: []string{"When", "What", "How much"},
+		Rows: [][]string{
+			{"2024-05-01", "Rent", "-8000"},
+			{"yesterday", "Coffee", "-35"},
+			{"2024-05-03", "Refund", "lots"},
+			{"", "", ""},
+		},
+	}
+	mapping := TransactionCSVMapping{Date: 0, Amount: 2, Description: 1}
+	statement, rowErrors, err := file.Statement(mapping)
+	if err != nil {
+		t.Fatalf("Statement() error = %v", err)
+	}
+	if len(statement.Entries) != 1 || statement.Entries[0].Description != "Rent" {
+		t.Errorf("entries = %+v; want only the rent", statement.Entries)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 3 || rowErrors[1].Row != 4 {
+		t.Errorf("row errors = %+v; want rows 3 and 4", rowErrors)
+	}
+
+	if _, _, err := file.Statement(TransactionCSVMapping{Date: 0, Amount: 0, Description: -1}); err == nil {
+		t.Error("Statement() with the same date and amount column succeeded; want an error")
+	}
+	if _, _, err := file.Statement(TransactionCSVMapping{Date: 0, Amount: 3, Description: -1}); err == nil {
+		t.Error("Statement() with a missing column succeeded; want an error")
+	}
+}
+
+// sampleOFX is an OFX 1.x (SGML) bank statement with unclosed elements.
+const sampleOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>DKK
+<BANKACCTFROM><BANKID>0400<ACCTID>4401162435<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240515120000.000[+1:CET]<TRNAMT>-249.50<NAME>Netto<MEMO>Dankort 1234</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240501<TRNAMT>30000.00<NAME>Employer A/S<MEMO>Employer A/S</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>39750.50<DTASOF>20240531</LEDGERBAL>
+<AVAILBAL><BALAMT>1.00<DTASOF>20240531</AVAILBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+func TestParseOFX(t *testing.T) {
+	statement, err := ParseOFX(strings.NewReader(sampleOFX))
+	if err != nil {
+		t.Fatalf("ParseOFX() error = %v", err)
+	}
+	if statement.IBAN != "4401162435" || statement.Currency != "DKK" {
+		t.Errorf("account = %s %s; want the DKK account", statement.IBAN, statement.Currency)
+	}
+	if statement.ClosingBalance == nil || *statement.ClosingBalance != 39750.5 {
+		t.Errorf("closing balance = %v; want the ledger balance", statement.ClosingBalance)
+	}
+	if len(statement.Entries) != 2 {
+		t.Fatalf("got %d entries; want 2", len(statement.Entries))
+	}
+	salary, payment := statement.Entries[0], statement.Entries[1]
+	if !salary.Date.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || salary.Amount != 30000 || salary.Description != "Employer A/S" {
+		t.Errorf("entry 0 = %+v; want the salary first, its memo left out", salary)
+	}
+	if !payment.Date.Equal(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)) || payment.Amount != -249.5 || payment.Description != "Netto - Dankort 1234" {
+		t.Errorf("entry 1 = %+v; want the card payment", payment)
+	}
+}
+
+func TestParseOFX_XML(t *testing.T) {
+	data := `<?xml version="1.0"?><?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>EUR</CURDEF><BANKTRANLIST>
+<STMTTRN><DTPOSTED>20250102</DTPOSTED><TRNAMT>10.00</TRNAMT><MEMO>Refund</MEMO></STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	statement, err := ParseOFX(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseOFX() error = %v", err)
+	}
+	if statement.Currency != "EUR" || statement.ClosingBalance != nil {
+		t.Errorf("statement = %+v; want EUR without a balance", statement)
+	}
+	if len(statement.Entries) != 1 || statement.Entries[0].Amount != 10 || statement.Entries[0].Description != "Refund" {
+		t.Errorf("entries = %+v; want the refund", statement.Entries)
+	}
+}
+
+func TestParseOFX_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"csv":      "date;amount\n",
+		"no date":  "<OFX><STMTTRN><TRNAMT>5</STMTTRN></OFX>",
+		"bad date": "<OFX><STMTTRN><DTPOSTED>May 1<TRNAMT>5</STMTTRN></OFX>",
+	} {
+		if _, err := ParseOFX(strings.NewReader(data)); err == nil {
+			t.Errorf("%s: ParseOFX() succeeded; want an error", name)
+		}
+	}
+}
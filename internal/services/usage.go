@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// ErrQuotaExceeded is returned when a user has used up a monthly quota.
+var ErrQuotaExceeded = errors.New("monthly quota reached")
+
+// usageKinds are the kinds of usage shown to users, in order, with their
+// display names.
+var usageKinds = []struct {
+	Kind, Name, Singular string
+}{
+	{models.UsageSync, "Broker syncs", "broker sync"},
+	{models.UsageMarketData, "Market data refreshes", "market data refresh"},
+	{models.UsageAPI, "API calls", "API call"},
+}
+
+// QuotaError tells which quota was used up and when it resets.
+type QuotaError struct {
+	Kind     string
+	Quota    int
+	ResetsAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	name := e.Kind
+	for _, k := range usageKinds {
+		if k.Kind == e.Kind {
+			name = k.Singular
+		}
+	}
+	return fmt.Sprintf("monthly %s quota of %d reached; it resets on %s", name, e.Quota, e.ResetsAt.Format("2 January"))
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match quota errors.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// UsageMeter is a user's usage of one kind this month.
+type UsageMeter struct {
+	Kind  string
+	Name  string
+	Used  int
+	Quota int   // 0 is unlimited
+	Daily []int // Usage per day of the month so far
+}
+
+// Percent returns how much of the quota is used, capped at 100, or 0 for
+// unlimited usage.
+func (m UsageMeter) Percent() float64 {
+	if m.Quota <= 0 {
+		return 0
+	}
+	return math.Min(float64(m.Used)/float64(m.Quota)*100, 100)
+}
+
+// Exceeded returns true if the quota is used up.
+func (m UsageMeter) Exceeded() bool {
+	return m.Quota > 0 && m.Used >= m.Quota
+}
+
+// UsageMonth is a user's usage in the current month.
+type UsageMonth struct {
+	Start    time.Time
+	ResetsAt time.Time
+	Days     []string // Labels of the days of the month so far
+	Meters   []UsageMeter
+}
+
+// UsageService counts what users do against monthly quotas, which hosted
+// instances set to limit load on brokers and data providers. Months are
+// calendar months in UTC.
+type UsageService struct {
+	repo   *repository.UsageRepository
+	quotas map[string]int
+	now    func() time.Time
+}
+
+// NewUsageService creates a new UsageService with the monthly quota of each
+// usage kind. Kinds without a quota, or with 0, are unlimited.
+func NewUsageService(repo *repository.UsageRepository, quotas map[string]int) *UsageService {
+	return &UsageService{repo: repo, quotas: quotas, now: time.Now}
+}
+
+// monthStart returns the start of the month of t, and of the next month.
+func monthStart(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Allow returns a *QuotaError if the user has used up this month's quota of
+// kind. Usage that cannot be counted is allowed.
+func (s *UsageService) Allow(userID int64, kind string) error {
+	quota := s.quotas[kind]
+	if quota <= 0 {
+		return nil
+	}
+	start, next := monthStart(s.now())
+	used, err := s.repo.CountSince(userID, kind, start)
+	if err != nil {
+		log.Printf("Error counting %s usage of user %d: %v", kind, userID, err)
+		return nil
+	}
+	if used >= quota {
+		return &QuotaError{Kind: kind, Quota: quota, ResetsAt: next}
+	}
+	return nil
+}
+
+// Record counts one use of kind by the user.
+func (s *UsageService) Record(userID int64, kind string) {
+	if err := s.repo.Increment(userID, kind, s.now()); err != nil {
+		log.Printf("Error recording %s usage of user %d: %v", kind, userID, err)
+	}
+}
+
+// Use counts one use of kind by the user if the quota allows it, and
+// returns a *QuotaError otherwise.
+func (s *UsageService) Use(userID int64, kind string) error {
+	if err := s.Allow(userID, kind); err != nil {
+		return err
+	}
+	s.Record(userID, kind)
+	return nil
+}
+
+// Month returns the user's usage this month, per kind and day.
+func (s *UsageService) Month(userID int64) (*UsageMonth, error) {
+	now := s.now().UTC()
+	start, next := monthStart(now)
+	days, err := s.repo.GetDailySince(userID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	month := &UsageMonth{Start: start, ResetsAt: next}
+	for d := 1; d <= now.Day(); d++ {
+		month.Days = append(month.Days, start.AddDate(0, 0, d-1).Format("2 Jan"))
+	}
+	for _, k := range usageKinds {
+		meter := UsageMeter{Kind: k.Kind, Name: k.Name, Quota: s.quotas[k.Kind], Daily: make([]int, now.Day())}
+		for _, day := range days {
+			if day.Kind != k.Kind || day.Day.Day() > now.Day() {
+				continue
+			}
+			meter.Daily[day.Day.Day()-1] += day.Count
+			meter.Used += day.Count
+		}
+		month.Meters = append(month.Meters, meter)
+	}
+	return month, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestUsageService_QuotaResetsMonthly(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "user@example.com", PasswordHash: "x", Name: "Test"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	s := NewUsageService(repository.NewUsageRepository(db), map[string]int{models.UsageSync: 2})
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := s.Use(userID, models.UsageSync); err != nil {
+			t.Fatalf("Use() #%d error = %v", i+1, err)
+		}
+	}
+	err = s.Use(userID, models.UsageSync)
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Use() over quota error = %v; want a QuotaError", err)
+	}
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !quotaErr.ResetsAt.Equal(want) {
+		t.Errorf("ResetsAt = %v; want %v", quotaErr.ResetsAt, want)
+	}
+
+	// Unlimited kinds are counted but never refused
+	for i := 0; i < 5; i++ {
+		if err := s.Use(userID, models.UsageAPI); err != nil {
+			t.Fatalf("Use(api) error = %v", err)
+		}
+	}
+
+	month, err := s.Month(userID)
+	if err != nil {
+		t.Fatalf("Month() error = %v", err)
+	}
+	if len(month.Days) != 30 {
+		t.Errorf("len(Days) = %d; want 30", len(month.Days))
+	}
+	sync := month.Meters[0]
+	if sync.Used != 2 || !sync.Exceeded() || sync.Percent() != 100 || sync.Daily[29] != 2 {
+		t.Errorf("sync meter = %+v; want 2 of 2 used on day 30", sync)
+	}
+	if api := month.Meters[2]; api.Used != 5 || api.Exceeded() {
+		t.Errorf("api meter = %+v; want 5 used, unlimited", api)
+	}
+
+	// A new month starts from zero
+	now = time.Date(2026, 4, 1, 0, 30, 0, 0, time.UTC)
+	if err := s.Allow(userID, models.UsageSync); err != nil {
+		t.Errorf("Allow() in a new month error = %v", err)
+	}
+}
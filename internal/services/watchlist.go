@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// Watchlist errors, safe to show to the user.
+var (
+	ErrWatchlistSymbolRequired = errors.New("symbol is required")
+	ErrWatchlistDuplicate      = errors.New("symbol is already on the watchlist")
+	ErrWatchlistInvalidPrice   = errors.New("price must be positive")
+	ErrWatchlistInvalidBuy     = errors.New("quantity and price must be positive")
+	ErrWatchlistBrokerAccount  = errors.New("holdings can only be added to accounts without a broker connection")
+)
+
+// WatchlistService tracks instruments a user is considering buying. Prices
+// come from the latest broker-synced holding of the same symbol, on any
+// account of the instance, or are entered manually.
+type WatchlistService struct {
+	watchlistRepo *repository.WatchlistRepository
+	holdingRepo   *repository.HoldingRepository
+}
+
+// NewWatchlistService creates a new WatchlistService.
+func NewWatchlistService(watchlistRepo *repository.WatchlistRepository, holdingRepo *repository.HoldingRepository) *WatchlistService {
+	return &WatchlistService{
+		watchlistRepo: watchlistRepo,
+		holdingRepo:   holdingRepo,
+	}
+}
+
+// List returns the user's watchlist with refreshed prices.
+func (s *WatchlistService) List(userID int64) ([]*models.WatchlistItem, error) {
+	items, err := s.watchlistRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if err := s.refreshPrice(item); err != nil {
+			return nil, err
+		}
+		item.ChangePercent = watchlistChange(item)
+	}
+	return items, nil
+}
+
+// Add puts an instrument on the user's watchlist. The price when it was added
+// is the synced price if one is known, otherwise the first manual price.
+func (s *WatchlistService) Add(item *models.WatchlistItem) (*models.WatchlistItem, error) {
+	item.Symbol = strings.ToUpper(strings.TrimSpace(item.Symbol))
+	item.Name = strings.TrimSpace(item.Name)
+	item.Currency = strings.ToUpper(strings.TrimSpace(item.Currency))
+	if item.Symbol == "" {
+		return nil, ErrWatchlistSymbolRequired
+	}
+	if item.Name == "" {
+		item.Name = item.Symbol
+	}
+	if item.Currency == "" {
+		item.Currency = "DKK"
+	}
+
+	existing, err := s.watchlistRepo.GetByUserID(item.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		if e.Symbol == item.Symbol {
+			return nil, ErrWatchlistDuplicate
+		}
+	}
+
+	price, updatedAt, err := s.holdingRepo.GetLatestSyncedPrice(item.UserID, item.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if price != nil {
+		item.AddedPrice = price
+		item.CurrentPrice = price
+		item.PriceSource = models.WatchlistPriceSynced
+		item.PriceUpdatedAt = &updatedAt
+	}
+
+	id, err := s.watchlistRepo.Create(item)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(id)
+}
+
+// SetManualPrice records a price the user looked up themselves.
+func (s *WatchlistService) SetManualPrice(item *models.WatchlistItem, price float64) (*models.WatchlistItem, error) {
+	if price <= 0 {
+		return nil, ErrWatchlistInvalidPrice
+	}
+	if err := s.watchlistRepo.SetPrice(item.ID, price, models.WatchlistPriceManual, time.Now()); err != nil {
+		return nil, err
+	}
+	if item.AddedPrice == nil {
+		if err := s.watchlistRepo.SetAddedPrice(item.ID, price); err != nil {
+			return nil, err
+		}
+	}
+	return s.get(item.ID)
+}
+
+// Convert records the purchase of a watched instrument as a manual holding of
+// account and removes it from the watchlist. Buying more of an instrument the
+// account already holds adds to that holding at the weighted average price.
+func (s *WatchlistService) Convert(item *models.WatchlistItem, account *models.Account, quantity, price float64) (*models.Holding, error) {
+	if quantity <= 0 || price <= 0 {
+		return nil, ErrWatchlistInvalidBuy
+	}
+
+	// Broker-synced accounts manage their own holdings
+	existing, err := s.holdingRepo.GetByAccountID(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	holding := &models.Holding{
+		AccountID:      account.ID,
+		Symbol:         item.Symbol,
+		Name:           item.Name,
+		Currency:       item.Currency,
+		InstrumentType: item.InstrumentType,
+	}
+	for _, h := range existing {
+		if h.ExternalID != "" {
+			return nil, ErrWatchlistBrokerAccount
+		}
+		if h.Symbol == item.Symbol {
+			holding.Quantity = h.Quantity
+			holding.AvgPrice = h.AvgPrice
+		}
+	}
+
+	cost := holding.Quantity*holding.AvgPrice + quantity*price
+	holding.Quantity += quantity
+	holding.AvgPrice = cost / holding.Quantity
+	holding.CurrentPrice = price
+	if item.CurrentPrice != nil {
+		holding.CurrentPrice = *item.CurrentPrice
+	}
+	holding.CurrentValue = holding.Quantity * holding.CurrentPrice
+
+	if err := s.holdingRepo.Upsert(holding); err != nil {
+		return nil, err
+	}
+	if err := s.watchlistRepo.Delete(item.ID); err != nil {
+		return nil, err
+	}
+	return holding, nil
+}
+
+// get loads an item with its price change.
+func (s *WatchlistService) get(id int64) (*models.WatchlistItem, error) {
+	item, err := s.watchlistRepo.GetByID(id)
+	if err != nil || item == nil {
+		return item, err
+	}
+	item.ChangePercent = watchlistChange(item)
+	return item, nil
+}
+
+// refreshPrice updates an item's price if a newer synced price is known. A
+// manual price is kept until a sync reports a price after it.
+func (s *WatchlistService) refreshPrice(item *models.WatchlistItem) error {
+	price, updatedAt, err := s.holdingRepo.GetLatestSyncedPrice(item.UserID, item.Symbol)
+	if err != nil || price == nil {
+		return err
+	}
+	if item.PriceUpdatedAt != nil && !updatedAt.After(*item.PriceUpdatedAt) {
+		return nil
+	}
+
+	if err := s.watchlistRepo.SetPrice(item.ID, *price, models.WatchlistPriceSynced, updatedAt); err != nil {
+		return err
+	}
+	if item.AddedPrice == nil {
+		if err := s.watchlistRepo.SetAddedPrice(item.ID, *price); err != nil {
+			return err
+		}
+		item.AddedPrice = price
+	}
+	item.CurrentPrice = price
+	item.PriceSource = models.WatchlistPriceSynced
+	item.PriceUpdatedAt = &updatedAt
+	return nil
+}
+
+// watchlistChange returns the price change since the item was added, in
+// percent, or nil if either price is unknown.
+func watchlistChange(item *models.WatchlistItem) *float64 {
+	if item.AddedPrice == nil || item.CurrentPrice == nil || *item.AddedPrice == 0 {
+		return nil
+	}
+	change := (*item.CurrentPrice - *item.AddedPrice) / *item.AddedPrice * 100
+	return &change
+}
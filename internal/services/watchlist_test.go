@@ -0,0 +1,125 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// setupWatchlistTest returns a WatchlistService, the holding repository and
+// an account for a new user.
+func setupWatchlistTest(t *testing.T) (*WatchlistService, *repository.HoldingRepository, *models.Account) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("running migrations: %v", err)
+	}
+
+	userID, err := repository.NewUserRepository(db).Create(&models.User{Email: "test@example.com", PasswordHash: "x", Name: "Test"})
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	account := &models.Account{UserID: userID, Name: "Depot", Currency: "DKK", IsActive: true}
+	if account.ID, err = repository.NewAccountRepository(db).Create(account); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	holdingRepo := repository.NewHoldingRepository(db)
+	return NewWatchlistService(repository.NewWatchlistRepository(db), holdingRepo), holdingRepo, account
+}
+
+func TestWatchlistService_TracksSyncedPrice(t *testing.T) {
+	s, holdingRepo, account := setupWatchlistTest(t)
+	synced := &models.Holding{AccountID: account.ID, ExternalID: "123", Symbol: "DK0010274414", Name: "Danske Bank", Quantity: 1, CurrentPrice: 200, CurrentValue: 200, Currency: "DKK"}
+	if err := holdingRepo.Upsert(synced); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	item, err := s.Add(&models.WatchlistItem{UserID: account.UserID, Symbol: " dk0010274414 "})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if item.Symbol != "DK0010274414" || item.AddedPrice == nil || *item.AddedPrice != 200 {
+		t.Fatalf("Add() = %+v; want normalized symbol with added price 200", item)
+	}
+	if _, err := s.Add(&models.WatchlistItem{UserID: account.UserID, Symbol: "DK0010274414"}); err != ErrWatchlistDuplicate {
+		t.Errorf("Add() of duplicate error = %v; want %v", err, ErrWatchlistDuplicate)
+	}
+
+	synced.CurrentPrice = 250
+	if err := holdingRepo.Upsert(synced); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	items, err := s.List(account.UserID)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List() = %v, %v; want one item", items, err)
+	}
+	if got := items[0]; *got.CurrentPrice != 250 || got.ChangePercent == nil || *got.ChangePercent != 25 {
+		t.Errorf("List() item = %+v; want current price 250, up 25%%", got)
+	}
+}
+
+func TestWatchlistService_ManualPrice(t *testing.T) {
+	s, _, account := setupWatchlistTest(t)
+	item, err := s.Add(&models.WatchlistItem{UserID: account.UserID, Symbol: "NOVO-B"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if item.CurrentPrice != nil || item.ChangePercent != nil {
+		t.Fatalf("Add() without a synced price = %+v; want no price", item)
+	}
+
+	if item, err = s.SetManualPrice(item, 800); err != nil {
+		t.Fatalf("SetManualPrice() error = %v", err)
+	}
+	if item, err = s.SetManualPrice(item, 720); err != nil {
+		t.Fatalf("SetManualPrice() error = %v", err)
+	}
+	if *item.AddedPrice != 800 || *item.CurrentPrice != 720 || item.PriceSource != models.WatchlistPriceManual || *item.ChangePercent != -10 {
+		t.Errorf("SetManualPrice() = %+v; want added 800, current 720 manual, down 10%%", item)
+	}
+	if _, err := s.SetManualPrice(item, 0); err != ErrWatchlistInvalidPrice {
+		t.Errorf("SetManualPrice(0) error = %v; want %v", err, ErrWatchlistInvalidPrice)
+	}
+}
+
+func TestWatchlistService_Convert(t *testing.T) {
+	s, holdingRepo, account := setupWatchlistTest(t)
+	if err := holdingRepo.Upsert(&models.Holding{AccountID: account.ID, Symbol: "NOVO-B", Name: "Novo Nordisk", Quantity: 10, AvgPrice: 600, CurrentPrice: 700, CurrentValue: 7000, Currency: "DKK"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	item, _ := s.Add(&models.WatchlistItem{UserID: account.UserID, Symbol: "NOVO-B", Name: "Novo Nordisk"})
+
+	holding, err := s.Convert(item, account, 10, 800)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if holding.Quantity != 20 || holding.AvgPrice != 700 || holding.CurrentValue != 16000 {
+		t.Errorf("Convert() = %+v; want 20 at average 700, worth 16000", holding)
+	}
+	if items, _ := s.List(account.UserID); len(items) != 0 {
+		t.Errorf("List() after Convert() = %d items; want 0", len(items))
+	}
+}
+
+func TestWatchlistService_ConvertRejectsBrokerAccount(t *testing.T) {
+	s, holdingRepo, account := setupWatchlistTest(t)
+	if err := holdingRepo.Upsert(&models.Holding{AccountID: account.ID, ExternalID: "1", Symbol: "VWRL", Name: "Vanguard", Quantity: 1, CurrentValue: 100, Currency: "DKK"}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	item, _ := s.Add(&models.WatchlistItem{UserID: account.UserID, Symbol: "NOVO-B"})
+
+	if _, err := s.Convert(item, account, 1, 800); err != ErrWatchlistBrokerAccount {
+		t.Errorf("Convert() error = %v; want %v", err, ErrWatchlistBrokerAccount)
+	}
+	if _, err := s.Convert(item, account, 0, 800); err != ErrWatchlistInvalidBuy {
+		t.Errorf("Convert() with no quantity error = %v; want %v", err, ErrWatchlistInvalidBuy)
+	}
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+)
+
+// SQLite is a Store in the shared_state table of the app's database, for
+// instances sharing the database file. Values are encrypted, as they include
+// broker tokens.
+type SQLite struct {
+	db        *database.DB
+	encryptor *broker.Encryptor
+}
+
+// NewSQLite creates a Store in db encrypting values with encryptor.
+func NewSQLite(db *database.DB, encryptor *broker.Encryptor) *SQLite {
+	return &SQLite{db: db, encryptor: encryptor}
+}
+
+// Get returns the value of a key, or ErrNotFound.
+func (s *SQLite) Get(key string) ([]byte, error) {
+	var sealed []byte
+	err := s.db.QueryRow(`SELECT value FROM shared_state WHERE key = ? AND expires_at > ?`,
+		key, time.Now().UnixMilli()).Scan(&sealed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", key, err)
+	}
+
+	value, err := s.encryptor.Open(sealed, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// Set sets the value of a key until ttl has passed.
+func (s *SQLite) Set(key string, value []byte, ttl time.Duration) error {
+	sealed, err := s.encryptor.Seal(value, []byte(key))
+	if err != nil {
+		return fmt.Errorf("encrypting %s: %w", key, err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO shared_state (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+	`, key, sealed, time.Now().Add(ttl).UnixMilli())
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes a key.
+func (s *SQLite) Delete(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM shared_state WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// Incr counts a hit on a key in the current fixed window. Counters hold no
+// secrets and are stored unencrypted, so they can be incremented in place.
+func (s *SQLite) Incr(key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	start := now.Truncate(window)
+	windowKey := key + "@" + strconv.FormatInt(start.UnixMilli(), 10)
+
+	var count int64
+	err := s.db.QueryRow(`
+		INSERT INTO shared_state (key, value, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET value = value + 1
+		RETURNING value
+	`, windowKey, start.Add(window).UnixMilli()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting %s: %w", key, err)
+	}
+	return count, nil
+}
+
+// Claim takes a key for owner until ttl has passed. Like counters, claims
+// hold no secrets and are stored unencrypted, so the owner can be compared
+// in place.
+func (s *SQLite) Claim(key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	var holder []byte
+	err := s.db.QueryRow(`
+		INSERT INTO shared_state (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+		WHERE shared_state.expires_at <= ? OR shared_state.value = excluded.value
+		RETURNING value
+	`, key, []byte(owner), now.Add(ttl).UnixMilli(), now.UnixMilli()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claiming %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// DeleteExpired removes keys that expired before now.
+func (s *SQLite) DeleteExpired(now time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM shared_state WHERE expires_at <= ?`, now.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired state: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("getting affected rows: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/database"
+)
+
+func setupTestStore(t *testing.T) *SQLite {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	encryptor, err := broker.NewEncryptor("test-secret-that-is-32-chars-long")
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return NewSQLite(db, encryptor)
+}
+
+func TestSQLite_SetGetDelete(t *testing.T) {
+	s := setupTestStore(t)
+
+	if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := s.Set("token", []byte("secret"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := s.Get("token")
+	if err != nil || string(got) != "secret" {
+		t.Errorf("Get() = %q, %v; want secret", got, err)
+	}
+
+	var stored []byte
+	if err := s.db.QueryRow(`SELECT value FROM shared_state WHERE key = 'token'`).Scan(&stored); err != nil {
+		t.Fatalf("reading row: %v", err)
+	}
+	if bytes.Contains(stored, []byte("secret")) {
+		t.Error("value is stored unencrypted")
+	}
+
+	if err := s.Delete("token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLite_Expiry(t *testing.T) {
+	s := setupTestStore(t)
+
+	if err := s.Set("old", []byte("x"), -time.Second); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := s.Set("new", []byte("y"), time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := s.Get("old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(expired) error = %v, want ErrNotFound", err)
+	}
+
+	n, err := s.DeleteExpired(time.Now())
+	if err != nil || n != 1 {
+		t.Errorf("DeleteExpired() = %d, %v; want 1", n, err)
+	}
+	if _, err := s.Get("new"); err != nil {
+		t.Errorf("Get(new) after DeleteExpired error = %v", err)
+	}
+}
+
+func TestSQLite_Incr(t *testing.T) {
+	s := setupTestStore(t)
+
+	for want := int64(1); want <= 3; want++ {
+		got, err := s.Incr("hits", time.Hour)
+		if err != nil {
+			t.Fatalf("Incr() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Incr() = %d, want %d", got, want)
+		}
+	}
+	if got, _ := s.Incr("other", time.Hour); got != 1 {
+		t.Errorf("Incr(other) = %d, want 1", got)
+	}
+}
+
+func TestSQLite_Claim(t *testing.T) {
+	s := setupTestStore(t)
+
+	if ok, err := s.Claim("lease", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("Claim(a) = %v, %v; want true", ok, err)
+	}
+	if ok, err := s.Claim("lease", "b", time.Minute); err != nil || ok {
+		t.Errorf("Claim(b) of a held key = %v, %v; want false", ok, err)
+	}
+	if ok, err := s.Claim("lease", "a", -time.Second); err != nil || !ok {
+		t.Errorf("Claim(a) renewing = %v, %v; want true", ok, err)
+	}
+	if ok, err := s.Claim("lease", "b", time.Minute); err != nil || !ok {
+		t.Errorf("Claim(b) of an expired key = %v, %v; want true", ok, err)
+	}
+	if ok, _ := s.Claim("lease", "a", time.Minute); ok {
+		t.Error("Claim(a) after b took over = true, want false")
+	}
+}
@@ -0,0 +1,38 @@
+// Package store holds short-lived state, such as rate limit counters and
+// cached broker sessions, that several instances serving the app behind a
+// load balancer need to share.
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned for keys that are not set or have expired.
+var ErrNotFound = errors.New("key not found")
+
+// Store is state shared by all instances serving the app. Keys expire after
+// the TTL they were set with.
+type Store interface {
+	// Get returns the value of a key, or ErrNotFound.
+	Get(key string) ([]byte, error)
+
+	// Set sets the value of a key until ttl has passed.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Delete removes a key. Removing a missing key is not an error.
+	Delete(key string) error
+
+	// Incr counts a hit on a key in the current fixed window of the given
+	// length and returns the number of hits in the window so far.
+	Incr(key string, window time.Duration) (int64, error)
+
+	// Claim takes a key for owner until ttl has passed, unless another
+	// owner holds it, and reports whether owner holds it. Claiming a key
+	// the owner already holds extends it.
+	Claim(key, owner string, ttl time.Duration) (bool, error)
+
+	// DeleteExpired removes keys that expired before now and returns how
+	// many it removed.
+	DeleteExpired(now time.Time) (int, error)
+}
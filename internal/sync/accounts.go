@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// maxConcurrentFetches bounds how many accounts of a connection are fetched
+// at once. The requests share one session and the client still spaces them
+// out, so this overlaps their latency rather than raising the request rate.
+const maxConcurrentFetches = 3
+
+// AccountError is a mapped account that could not be synced.
+type AccountError struct {
+	LocalAccountID      int64
+	ExternalAccountID   string
+	ExternalAccountName string
+	Err                 error
+}
+
+func (e AccountError) Error() string {
+	name := e.ExternalAccountName
+	if name == "" {
+		name = e.ExternalAccountID
+	}
+	return fmt.Sprintf("%s: %v", name, e.Err)
+}
+
+// fetchResult is the outcome of fetching one mapped account.
+type fetchResult struct {
+	snapshot *accountSnapshot
+	err      error
+}
+
+// fetchConcurrently fetches the snapshot of every mapping on up to workers
+// goroutines and returns the results in mapping order.
+func fetchConcurrently(mappings []*models.AccountMapping, workers int, fetch func(*models.AccountMapping) (*accountSnapshot, error)) []fetchResult {
+	results := make([]fetchResult, len(mappings))
+
+	indexes := make(chan int)
+	var wg stdsync.WaitGroup
+	for range min(workers, len(mappings)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].snapshot, results[i].err = fetch(mappings[i])
+			}
+		}()
+	}
+	for i := range mappings {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// syncMappings fetches all mappings, up to workers at a time, and then
+// applies the snapshots one by one. An account that fails is reported in
+// the result's AccountErrors and does not stop the others.
+func (s *Service) syncMappings(mappings []*models.AccountMapping, workers int, fetch func(*models.AccountMapping) (*accountSnapshot, error), syncTime time.Time, describe balanceDescriber) *SyncResult {
+	result := &SyncResult{}
+	for i, fetched := range fetchConcurrently(mappings, workers, fetch) {
+		mapping := mappings[i]
+		if fetched.err != nil {
+			log.Printf("[Sync] Error fetching account %s: %v", mapping.ExternalAccountID, fetched.err)
+			result.AccountErrors = append(result.AccountErrors, AccountError{
+				LocalAccountID:      mapping.LocalAccountID,
+				ExternalAccountID:   mapping.ExternalAccountID,
+				ExternalAccountName: mapping.ExternalAccountName,
+				Err:                 fetched.err,
+			})
+			continue
+		}
+
+		held, heldBalance := s.applySnapshot(mapping, fetched.snapshot, syncTime, describe)
+		result.AccountsSynced++
+		result.PositionsSynced += len(fetched.snapshot.holdings)
+		result.HeldDeletions += held
+		if heldBalance {
+			result.HeldBalances++
+		}
+	}
+	return result
+}
+
+// completeSync records a finished sync. Failed accounts are stored as the
+// error message of the sync and the connection.
+func (s *Service) completeSync(historyID, connectionID int64, result *SyncResult) {
+	errorMsg := accountErrorsMessage(result.AccountErrors, result.AccountsSynced)
+	s.connRepo.UpdateSyncStatus(connectionID, "success", errorMsg)
+	s.historyRepo.Complete(historyID, result.AccountsSynced, result.PositionsSynced, errorMsg)
+	s.finishTrail(historyID, errorMsg != "")
+	result.Success = true
+	if errorMsg != "" {
+		s.notifySyncFailure(connectionID, errorMsg)
+	}
+}
+
+// accountErrorsMessage summarizes failed accounts, or returns "" if none failed.
+func accountErrorsMessage(errs []AccountError, synced int) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d of %d accounts failed: %s", len(errs), len(errs)+synced, strings.Join(msgs, "; "))
+}
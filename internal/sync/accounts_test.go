@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"errors"
+	"strings"
+	stdsync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestFetchConcurrently_BoundsWorkersAndKeepsOrder(t *testing.T) {
+	var mappings []*models.AccountMapping
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		mappings = append(mappings, &models.AccountMapping{ExternalAccountID: id})
+	}
+
+	var running, peak atomic.Int32
+	var mu stdsync.Mutex
+	results := fetchConcurrently(mappings, 2, func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		mu.Lock()
+		if n > peak.Load() {
+			peak.Store(n)
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+
+		if mapping.ExternalAccountID == "c" {
+			return nil, errors.New("boom")
+		}
+		return &accountSnapshot{totalValue: float64(mapping.ExternalAccountID[0])}, nil
+	})
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrent fetches = %d; want 2", got)
+	}
+	for i, r := range results {
+		id := mappings[i].ExternalAccountID
+		if id == "c" {
+			if r.err == nil {
+				t.Errorf("result %d error = nil; want error", i)
+			}
+			continue
+		}
+		if r.err != nil || r.snapshot.totalValue != float64(id[0]) {
+			t.Errorf("result %d = %+v, %v; want snapshot of %s", i, r.snapshot, r.err, id)
+		}
+	}
+}
+
+func TestSyncConnection_ReportsFailedAccounts(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+
+	accountRepo := repository.NewAccountRepository(db)
+	account, _ := accountRepo.GetByID(accountID)
+	closedID, err := accountRepo.Create(&models.Account{
+		UserID: account.UserID, Name: "Closed", Currency: "DKK", IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if _, err := repository.NewAccountMappingRepository(db).Create(&models.AccountMapping{
+		ConnectionID: connID, LocalAccountID: closedID,
+		ExternalAccountID: "unknown", ExternalAccountName: "Closed depot", AutoSync: true,
+	}); err != nil {
+		t.Fatalf("failed to create mapping: %v", err)
+	}
+
+	result, err := svc.SyncConnection(connID)
+	if err != nil {
+		t.Fatalf("SyncConnection() error = %v", err)
+	}
+	if result.AccountsSynced != 1 || len(result.AccountErrors) != 1 {
+		t.Fatalf("SyncConnection() synced %d accounts with %d errors; want 1 and 1", result.AccountsSynced, len(result.AccountErrors))
+	}
+	if got := result.AccountErrors[0].ExternalAccountName; got != "Closed depot" {
+		t.Errorf("failed account = %q; want Closed depot", got)
+	}
+
+	history, err := repository.NewSyncHistoryRepository(db).GetLatestByConnectionID(connID)
+	if err != nil || history == nil {
+		t.Fatalf("GetLatestByConnectionID() = %v, %v", history, err)
+	}
+	if history.Status != "success" || !strings.HasPrefix(history.ErrorMessage, "1 of 2 accounts failed: Closed depot") {
+		t.Errorf("sync history = %s %q; want success with the failed account", history.Status, history.ErrorMessage)
+	}
+
+	conn, _ := repository.NewBrokerConnectionRepository(db).GetByID(connID)
+	if conn.LastSyncError != history.ErrorMessage {
+		t.Errorf("connection error = %q; want %q", conn.LastSyncError, history.ErrorMessage)
+	}
+}
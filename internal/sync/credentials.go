@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/notify"
+)
+
+// credentialReminderInterval is how often the owner of a connection is
+// reminded of the same expiring or stale login.
+const credentialReminderInterval = 24 * time.Hour
+
+// CredentialWarning is a connection whose broker login has expired or is
+// about to, so it will stop syncing until its owner logs in again.
+type CredentialWarning struct {
+	Connection *models.BrokerConnection
+	Message    string
+	Expired    bool // The connection cannot sync until its owner logs in
+}
+
+// SetCredentialFreshness sets when connections are flagged: Saxo logins whose
+// refresh token expires within saxoWarn, and Nordnet connections without a
+// successful sync for nordnetStale. Zero turns the check off.
+func (s *Service) SetCredentialFreshness(saxoWarn, nordnetStale time.Duration) {
+	s.saxoRefreshWarn = saxoWarn
+	s.nordnetAuthStale = nordnetStale
+}
+
+// CheckCredentialFreshness returns a warning if a connection's login needs
+// renewing at now, or nil. saxoRefreshExpiry is when the refresh token of a
+// Saxo connection's cached session expires, nil without a session. A Nordnet
+// connection that never synced counts from when it was created.
+func CheckCredentialFreshness(conn *models.BrokerConnection, saxoRefreshExpiry *time.Time, now time.Time, saxoWarn, nordnetStale time.Duration) *CredentialWarning {
+	switch conn.BrokerType {
+	case "saxo":
+		if saxoWarn <= 0 {
+			return nil
+		}
+		if saxoRefreshExpiry == nil || !saxoRefreshExpiry.After(now) {
+			return &CredentialWarning{Connection: conn, Message: "Saxo login has expired. Log in again to resume syncing.", Expired: true}
+		}
+		if left := saxoRefreshExpiry.Sub(now); left < saxoWarn {
+			return &CredentialWarning{Connection: conn, Message: "Saxo login expires in " + formatDuration(left) + ". Log in again to keep syncing."}
+		}
+
+	case "nordnet":
+		if nordnetStale <= 0 {
+			return nil
+		}
+		since := conn.CreatedAt
+		if conn.LastSuccessAt != nil {
+			since = *conn.LastSuccessAt
+		}
+		if age := now.Sub(since); age >= nordnetStale {
+			return &CredentialWarning{Connection: conn, Message: "Nordnet has not synced successfully for " + formatDuration(age) + ". Log in with MitID to resume syncing."}
+		}
+	}
+	return nil
+}
+
+// CredentialWarnings returns the warnings of the user's active connections.
+func (s *Service) CredentialWarnings(userID int64) ([]CredentialWarning, error) {
+	conns, err := s.connRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.credentialWarnings(conns, time.Now()), nil
+}
+
+// RemindStaleCredentials notifies the owners of active connections whose
+// login has expired or is about to, at most once per
+// credentialReminderInterval per connection, and returns how many were
+// reminded.
+func (s *Service) RemindStaleCredentials(now time.Time) (int, error) {
+	if s.notifier == nil {
+		return 0, nil
+	}
+	conns, err := s.connRepo.GetAllActive()
+	if err != nil {
+		return 0, fmt.Errorf("getting connections: %w", err)
+	}
+
+	reminded := 0
+	for _, w := range s.credentialWarnings(conns, now) {
+		conn := w.Connection
+		if conn.CredentialRemindedAt != nil && now.Sub(*conn.CredentialRemindedAt) < credentialReminderInterval {
+			continue
+		}
+		s.notifier.Notify(conn.UserID, notify.Notification{
+			Title:   brokerDisplayName(conn.BrokerType) + " login needs renewing",
+			Message: w.Message,
+		})
+		if err := s.connRepo.SetCredentialReminded(conn.ID, now); err != nil {
+			log.Printf("[Sync] Error recording credential reminder of connection %d: %v", conn.ID, err)
+		}
+		reminded++
+	}
+	return reminded, nil
+}
+
+// credentialWarnings checks connections against their cached sessions.
+func (s *Service) credentialWarnings(conns []*models.BrokerConnection, now time.Time) []CredentialWarning {
+	var warnings []CredentialWarning
+	for _, conn := range conns {
+		var refreshExpiry *time.Time
+		if conn.BrokerType == "saxo" {
+			if session := saxo.GetCachedSession(conn.ID); session != nil {
+				refreshExpiry = &session.RefreshExpiresAt
+			}
+		}
+		if w := CheckCredentialFreshness(conn, refreshExpiry, now, s.saxoRefreshWarn, s.nordnetAuthStale); w != nil {
+			warnings = append(warnings, *w)
+		}
+	}
+	return warnings
+}
+
+// formatDuration formats a duration in whole days, or hours below a day.
+func formatDuration(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	if d >= 24*time.Hour {
+		return "1 day"
+	}
+	switch hours := int(d / time.Hour); hours {
+	case 0:
+		return "less than an hour"
+	case 1:
+		return "1 hour"
+	default:
+		return fmt.Sprintf("%d hours", hours)
+	}
+}
@@ -0,0 +1,61 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestCheckCredentialFreshness(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	in := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name        string
+		conn        models.BrokerConnection
+		refresh     *time.Time
+		want        string
+		wantExpired bool
+	}{
+		{"saxo fresh", models.BrokerConnection{BrokerType: "saxo"}, in(10 * day), "", false},
+		{"saxo expiring", models.BrokerConnection{BrokerType: "saxo"}, in(2 * day), "Saxo login expires in 2 days. Log in again to keep syncing.", false},
+		{"saxo expired", models.BrokerConnection{BrokerType: "saxo"}, ago(time.Hour), "Saxo login has expired. Log in again to resume syncing.", true},
+		{"saxo without session", models.BrokerConnection{BrokerType: "saxo"}, nil, "Saxo login has expired. Log in again to resume syncing.", true},
+		{"nordnet recent", models.BrokerConnection{BrokerType: "nordnet", LastSuccessAt: ago(3 * day)}, nil, "", false},
+		{"nordnet stale", models.BrokerConnection{BrokerType: "nordnet", LastSuccessAt: ago(9 * day)}, nil, "Nordnet has not synced successfully for 9 days. Log in with MitID to resume syncing.", false},
+		{"nordnet never synced", models.BrokerConnection{BrokerType: "nordnet", CreatedAt: now.Add(-8 * day)}, nil, "Nordnet has not synced successfully for 8 days. Log in with MitID to resume syncing.", false},
+		{"nordnet new", models.BrokerConnection{BrokerType: "nordnet", CreatedAt: now.Add(-time.Hour)}, nil, "", false},
+		{"other broker", models.BrokerConnection{BrokerType: "mock", CreatedAt: now.Add(-30 * day)}, nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckCredentialFreshness(&tt.conn, tt.refresh, now, 3*day, 7*day)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("got warning %q; want none", got.Message)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("got no warning; want %q", tt.want)
+			}
+			if got.Message != tt.want || got.Expired != tt.wantExpired {
+				t.Errorf("got %q (expired %v); want %q (expired %v)", got.Message, got.Expired, tt.want, tt.wantExpired)
+			}
+		})
+	}
+
+	// Zero turns the checks off
+	if w := CheckCredentialFreshness(&models.BrokerConnection{BrokerType: "saxo"}, nil, now, 0, 0); w != nil {
+		t.Errorf("got warning %q with the checks off", w.Message)
+	}
+}
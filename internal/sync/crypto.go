@@ -0,0 +1,177 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/broker/crypto"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// cryptoSpotAccount is the external account ID of an exchange's spot
+// balances, the one account an exchange connection offers for mapping.
+const cryptoSpotAccount = "spot"
+
+// SetAccountRepository lets crypto exchange syncs value holdings in the
+// currency of the mapped local account. Without it, exchange connections
+// cannot be synced.
+func (s *Service) SetAccountRepository(accountRepo *repository.AccountRepository) {
+	s.accountRepo = accountRepo
+}
+
+// SyncCryptoConnection synchronizes the mapped accounts of a crypto exchange
+// connection: every asset held becomes a holding valued at the exchange's
+// last price in the local account's currency.
+func (s *Service) SyncCryptoConnection(connectionID int64) (*SyncResult, error) {
+	historyID, err := s.historyRepo.Start(connectionID, "full")
+	if err != nil {
+		return nil, fmt.Errorf("starting sync history: %w", err)
+	}
+
+	conn, err := s.connRepo.GetByID(connectionID)
+	if err != nil {
+		s.failSync(historyID, connectionID, fmt.Sprintf("getting connection: %v", err))
+		return nil, fmt.Errorf("getting connection: %w", err)
+	}
+	if conn == nil {
+		s.failSync(historyID, connectionID, "connection not found")
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	exchange, err := newCryptoExchange(conn)
+	if err != nil {
+		s.failSync(historyID, connectionID, err.Error())
+		return nil, err
+	}
+	exchange.SetTrail(s.startTrail(historyID))
+
+	syncTime := time.Now()
+	fetch, err := s.cryptoFetcher(exchange, syncTime)
+	if err != nil {
+		if errors.Is(err, crypto.ErrInvalidKey) {
+			s.connRepo.UpdateSyncStatus(connectionID, "auth_failed", err.Error())
+		}
+		s.failSync(historyID, connectionID, err.Error())
+		return nil, err
+	}
+
+	mappings, err := s.mappingRepo.GetAutoSyncByConnectionID(connectionID)
+	if err != nil {
+		s.failSync(historyID, connectionID, fmt.Sprintf("getting mappings: %v", err))
+		return nil, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	result := s.syncMappings(mappings, 1, fetch, syncTime, s.describeSync(conn))
+
+	s.completeSync(historyID, connectionID, result)
+	return result, nil
+}
+
+// cryptoFetcher fetches the balances of an exchange account once and
+// returns a function that values them in the currency of a mapped account.
+func (s *Service) cryptoFetcher(exchange crypto.Exchange, syncTime time.Time) (func(*models.AccountMapping) (*accountSnapshot, error), error) {
+	if s.accountRepo == nil {
+		return nil, fmt.Errorf("crypto exchanges cannot be synced without account currencies")
+	}
+	balances, err := exchange.Balances()
+	if err != nil {
+		return nil, fmt.Errorf("fetching balances: %w", err)
+	}
+	log.Printf("[Crypto Sync] Got %d balances", len(balances))
+
+	return func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+		account, err := s.accountRepo.GetByID(mapping.LocalAccountID)
+		if err != nil {
+			return nil, fmt.Errorf("getting account: %w", err)
+		}
+		if account == nil {
+			return nil, fmt.Errorf("account %d not found", mapping.LocalAccountID)
+		}
+		return cryptoSnapshot(exchange, balances, mapping, account.Currency, syncTime)
+	}, nil
+}
+
+// cryptoSnapshot converts the balances of an exchange account to holdings
+// priced in currency. A balance in the currency itself is cash, counted in
+// the account's value only. Assets the exchange has no market for in the
+// currency are kept at their quantity without a value, so one small token
+// does not fail the sync.
+func cryptoSnapshot(exchange crypto.Exchange, balances []crypto.Balance, mapping *models.AccountMapping, currency string, syncTime time.Time) (*accountSnapshot, error) {
+	snapshot := &accountSnapshot{hasData: len(balances) > 0}
+	for _, balance := range balances {
+		price, value, err := crypto.Value(exchange, balance, currency)
+		if errors.Is(err, crypto.ErrNoMarket) {
+			log.Printf("[Crypto Sync] No %s market for %s, keeping %.8f without a value", currency, balance.Asset, balance.Amount)
+		} else if err != nil {
+			return nil, fmt.Errorf("pricing %s: %w", balance.Asset, err)
+		}
+		snapshot.totalValue += value
+
+		if strings.EqualFold(balance.Asset, currency) {
+			continue
+		}
+		snapshot.holdings = append(snapshot.holdings, &models.Holding{
+			AccountID:      mapping.LocalAccountID,
+			ExternalID:     balance.Asset,
+			Symbol:         balance.Asset,
+			Name:           balance.Asset,
+			Quantity:       balance.Amount,
+			CurrentPrice:   price,
+			CurrentValue:   value,
+			Currency:       currency,
+			InstrumentType: "crypto",
+			LastUpdated:    syncTime,
+		})
+	}
+	log.Printf("[Crypto Sync] Account %d: %d holdings worth %.2f %s", mapping.LocalAccountID, len(snapshot.holdings), snapshot.totalValue, currency)
+	return snapshot, nil
+}
+
+// getCryptoExternalAccounts returns the spot account of an exchange
+// connection, after checking the API key can read its balances.
+func (s *Service) getCryptoExternalAccounts(conn *models.BrokerConnection) ([]ExternalAccount, error) {
+	exchange, err := newCryptoExchange(conn)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := exchange.Balances()
+	if err != nil {
+		return nil, fmt.Errorf("fetching balances: %w", err)
+	}
+
+	assets := make([]string, len(balances))
+	for i, balance := range balances {
+		assets[i] = balance.Asset
+	}
+	name := crypto.DisplayName(conn.BrokerType) + " spot"
+	if len(assets) > 0 {
+		name += " (" + strings.Join(assets, ", ") + ")"
+	}
+	return []ExternalAccount{{
+		ID:            cryptoSpotAccount,
+		AccountNumber: cryptoSpotAccount,
+		Name:          name,
+		Type:          "crypto",
+		Active:        true,
+	}}, nil
+}
+
+// newCryptoExchange creates the client of a connection's exchange using its
+// API key, kept in AppKey and AppSecret, and its proxy and User-Agent. An
+// API URL override set by the user is only reached on a public address.
+func newCryptoExchange(conn *models.BrokerConnection) (crypto.Exchange, error) {
+	exchange, err := crypto.New(conn.BrokerType, conn.APIURL, conn.AppKey, conn.AppSecret)
+	if err != nil {
+		return nil, err
+	}
+	cfg := useHTTPConfig(conn)
+	if conn.APIURL != "" {
+		cfg = cfg.PublicAPI()
+	}
+	exchange.SetHTTPConfig(cfg)
+	return exchange, nil
+}
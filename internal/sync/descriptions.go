@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"log"
+	"time"
+
+	"wealth_tracker/internal/broker/crypto"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+// balanceDescriber returns the description of a synced balance change.
+type balanceDescriber func(delta, balance float64, date time.Time) string
+
+// SetUserRepository lets syncs describe balance changes with the connection
+// owner's description template. Without it the default description is used.
+func (s *Service) SetUserRepository(userRepo *repository.UserRepository) {
+	s.userRepo = userRepo
+}
+
+// describeSync returns the describer for balances synced from a connection.
+func (s *Service) describeSync(conn *models.BrokerConnection) balanceDescriber {
+	var owner *models.User
+	if s.userRepo != nil {
+		var err error
+		if owner, err = s.userRepo.GetByID(conn.UserID); err != nil {
+			log.Printf("[Sync] Error getting owner of connection %d: %v", conn.ID, err)
+		}
+	}
+
+	broker := brokerDisplayName(conn.BrokerType)
+	return func(delta, balance float64, date time.Time) string {
+		return services.SyncDescription(owner, services.DescriptionValues{
+			Broker:  broker,
+			Date:    date,
+			Delta:   delta,
+			Balance: balance,
+		})
+	}
+}
+
+// brokerDisplayName returns the name of a broker type as shown to users.
+func brokerDisplayName(brokerType string) string {
+	switch brokerType {
+	case "nordnet":
+		return "Nordnet"
+	case "saxo":
+		return "Saxo"
+	case "psd2":
+		return "Bank"
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		return crypto.DisplayName(brokerType)
+	}
+	return brokerType
+}
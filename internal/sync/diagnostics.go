@@ -0,0 +1,59 @@
+package sync
+
+import (
+	"encoding/json"
+	"log"
+
+	"wealth_tracker/internal/broker"
+)
+
+// Diagnostics is the stored request trail of a failed or partial sync.
+type Diagnostics struct {
+	Requests []broker.TrailEntry `json:"requests"`
+	Dropped  int                 `json:"dropped,omitempty"` // Requests not recorded once the trail was full
+}
+
+// startTrail returns a new request trail for the sync with the given history
+// ID; finishTrail stores it when the sync ends.
+func (s *Service) startTrail(historyID int64) *broker.Trail {
+	trail := broker.NewTrail()
+	s.trailsMu.Lock()
+	s.trails[historyID] = trail
+	s.trailsMu.Unlock()
+	return trail
+}
+
+// finishTrail stores the request trail of a finished sync if keep is set,
+// i.e. when the sync or some of its accounts failed.
+func (s *Service) finishTrail(historyID int64, keep bool) {
+	s.trailsMu.Lock()
+	trail, ok := s.trails[historyID]
+	delete(s.trails, historyID)
+	s.trailsMu.Unlock()
+	if !ok || !keep {
+		return
+	}
+
+	data, err := json.Marshal(Diagnostics{Requests: trail.Entries(), Dropped: trail.Dropped()})
+	if err != nil {
+		log.Printf("[Sync] Error encoding request trail: %v", err)
+		return
+	}
+	if err := s.historyRepo.SaveDiagnostics(historyID, string(data)); err != nil {
+		log.Printf("[Sync] Error storing request trail: %v", err)
+	}
+}
+
+// GetDiagnostics returns the stored request trail of a sync, or nil if none
+// was stored.
+func (s *Service) GetDiagnostics(historyID int64) (*Diagnostics, error) {
+	data, err := s.historyRepo.GetDiagnostics(historyID)
+	if err != nil || data == "" {
+		return nil, err
+	}
+	var d Diagnostics
+	if err := json.Unmarshal([]byte(data), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,285 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"wealth_tracker/internal/broker/crypto"
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/models"
+)
+
+// Holding change actions reported by a dry run.
+const (
+	HoldingAdded   = "add"
+	HoldingUpdated = "update"
+	HoldingRemoved = "remove"
+	HoldingHeld    = "hold" // Missing, but kept by the deletion safety threshold
+)
+
+// accountSnapshot is the state of a broker account fetched during a sync,
+// converted to local holdings and a total account value.
+type accountSnapshot struct {
+	holdings   []*models.Holding
+	totalValue float64
+	hasData    bool         // False when the broker returned neither positions nor cash
+	pending    *pendingBuys // Included in totalValue; nil if the broker does not report them
+}
+
+// HoldingChange describes how a single holding would change during a sync.
+type HoldingChange struct {
+	Symbol      string  `json:"symbol"`
+	Name        string  `json:"name"`
+	Action      string  `json:"action"`
+	OldQuantity float64 `json:"old_quantity"`
+	NewQuantity float64 `json:"new_quantity"`
+	OldValue    float64 `json:"old_value"`
+	NewValue    float64 `json:"new_value"`
+}
+
+// AccountChanges describes the would-be changes for one mapped account.
+type AccountChanges struct {
+	LocalAccountID      int64           `json:"local_account_id"`
+	ExternalAccountID   string          `json:"external_account_id"`
+	ExternalAccountName string          `json:"external_account_name"`
+	Holdings            []HoldingChange `json:"holdings"`
+	UnchangedHoldings   int             `json:"unchanged_holdings"`
+	CurrentBalance      float64         `json:"current_balance"`
+	NewBalance          float64         `json:"new_balance"`
+	BalanceChanged      bool            `json:"balance_changed"`
+	HeldDeletions       int             `json:"held_deletions,omitempty"`
+	Error               string          `json:"error,omitempty"`
+}
+
+// applySnapshot writes a fetched snapshot: upserts holdings, removes holdings
+// no longer reported by the broker and records a balance transaction if the
+// account value changed. A snapshot missing too many holdings to delete them
+// without confirmation is likely partial, so its balance is not recorded
+// either. Returns the number of stale holdings kept because removing them
+// exceeded the deletion safety threshold, and whether the balance was kept
+// because it broke the account's trend.
+func (s *Service) applySnapshot(mapping *models.AccountMapping, snapshot *accountSnapshot, syncTime time.Time, describe balanceDescriber) (int, bool) {
+	for _, holding := range snapshot.holdings {
+		log.Printf("[Sync] Upserting holding: Symbol=%s, Name=%s, Qty=%.2f, Value=%.2f",
+			holding.Symbol, holding.Name, holding.Quantity, holding.CurrentValue)
+
+		if err := s.holdingRepo.Upsert(holding); err != nil {
+			log.Printf("[Sync] Error upserting holding %s: %v", holding.Symbol, err)
+		}
+	}
+
+	// Delete stale holdings (positions that no longer exist)
+	held := s.deleteStaleHoldings(mapping, syncTime)
+
+	s.recordPendingBuys(mapping.LocalAccountID, snapshot.pending)
+
+	// Update account balance if it changed
+	if !snapshot.hasData {
+		return held, false
+	}
+	if held > 0 {
+		log.Printf("[Sync] Skipping balance of account %d: snapshot is partial, %d holdings pending confirmation",
+			mapping.LocalAccountID, held)
+		return held, false
+	}
+	return held, s.recordBalance(mapping, snapshot.totalValue, syncTime, describe)
+}
+
+// DryRunConnection authenticates and fetches positions for every auto-sync
+// mapping of a connection, and reports the holding and balance changes a real
+// sync would make. Nothing is written, including sync history and status.
+func (s *Service) DryRunConnection(connectionID int64) (*SyncResult, error) {
+	conn, err := s.connRepo.GetByID(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting connection: %w", err)
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	mappings, err := s.mappingRepo.GetAutoSyncByConnectionID(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	syncTime := time.Now()
+	var fetch func(mapping *models.AccountMapping) (*accountSnapshot, error)
+
+	switch conn.BrokerType {
+	case "nordnet":
+		client, err := s.createClient(conn)
+		if err != nil {
+			return nil, fmt.Errorf("creating client: %w", err)
+		}
+		session, err := s.authenticateNordnet(conn, "dry_run")
+		if err != nil {
+			return nil, fmt.Errorf("MitID authentication failed: %w", err)
+		}
+		fetch = func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+			return s.fetchNordnetSnapshot(client, session, mapping, syncTime)
+		}
+	case "saxo":
+		session, err := s.authenticateSaxo(conn)
+		if err != nil {
+			return nil, fmt.Errorf("OAuth authentication failed: %w", err)
+		}
+		fetch, err = s.saxoFetcher(newSaxoClient(conn), session, mappings, syncTime)
+		if err != nil {
+			return nil, err
+		}
+	case "psd2":
+		consent := psd2.GetConsent(conn.ID)
+		if !consent.IsValid(syncTime) {
+			return nil, psd2.ErrConsentRequired
+		}
+		client := newPSD2Client(conn)
+		fetch = func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+			return fetchPSD2Snapshot(client, consent, mapping)
+		}
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		exchange, err := newCryptoExchange(conn)
+		if err != nil {
+			return nil, err
+		}
+		fetch, err = s.cryptoFetcher(exchange, syncTime)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported broker type: %s", conn.BrokerType)
+	}
+
+	result := &SyncResult{DryRun: true}
+	for _, mapping := range mappings {
+		snapshot, err := fetch(mapping)
+		if err != nil {
+			result.Changes = append(result.Changes, AccountChanges{
+				LocalAccountID:      mapping.LocalAccountID,
+				ExternalAccountID:   mapping.ExternalAccountID,
+				ExternalAccountName: mapping.ExternalAccountName,
+				Error:               err.Error(),
+			})
+			continue
+		}
+
+		changes, err := s.diffSnapshot(mapping, snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("comparing account %d: %w", mapping.LocalAccountID, err)
+		}
+		result.Changes = append(result.Changes, *changes)
+		result.AccountsSynced++
+		result.PositionsSynced += len(snapshot.holdings)
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// diffSnapshot compares a fetched snapshot with the stored holdings and balance.
+func (s *Service) diffSnapshot(mapping *models.AccountMapping, snapshot *accountSnapshot) (*AccountChanges, error) {
+	existing, err := s.holdingRepo.GetByAccountID(mapping.LocalAccountID)
+	if err != nil {
+		return nil, err
+	}
+	currentBalance, err := s.txnRepo.GetLatestBalance(mapping.LocalAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &AccountChanges{
+		LocalAccountID:      mapping.LocalAccountID,
+		ExternalAccountID:   mapping.ExternalAccountID,
+		ExternalAccountName: mapping.ExternalAccountName,
+		Holdings:            make([]HoldingChange, 0),
+		CurrentBalance:      currentBalance,
+		NewBalance:          currentBalance,
+	}
+	if snapshot.hasData && snapshot.totalValue != currentBalance {
+		changes.NewBalance = snapshot.totalValue
+		changes.BalanceChanged = true
+	}
+
+	changes.Holdings, changes.UnchangedHoldings = diffHoldings(existing, snapshot.holdings)
+
+	// A sync keeps the missing holdings and skips the balance if removing
+	// them exceeds the deletion safety threshold
+	var added, removed int
+	for _, h := range changes.Holdings {
+		switch h.Action {
+		case HoldingAdded:
+			added++
+		case HoldingRemoved:
+			removed++
+		}
+	}
+	if exceedsStaleThreshold(removed, len(existing)+added, s.staleDeleteThreshold) {
+		for i := range changes.Holdings {
+			if changes.Holdings[i].Action == HoldingRemoved {
+				changes.Holdings[i].Action = HoldingHeld
+			}
+		}
+		changes.HeldDeletions = removed
+		changes.NewBalance = currentBalance
+		changes.BalanceChanged = false
+	}
+	return changes, nil
+}
+
+// diffHoldings compares stored holdings with fetched ones by symbol. Stored
+// holdings missing from the fetch are reported as removed, since a sync
+// deletes every holding it did not update.
+func diffHoldings(existing, fetched []*models.Holding) ([]HoldingChange, int) {
+	bySymbol := make(map[string]*models.Holding, len(existing))
+	for _, h := range existing {
+		bySymbol[h.Symbol] = h
+	}
+
+	changes := make([]HoldingChange, 0)
+	unchanged := 0
+	seen := make(map[string]bool, len(fetched))
+
+	for _, h := range fetched {
+		seen[h.Symbol] = true
+		old, ok := bySymbol[h.Symbol]
+		if !ok {
+			changes = append(changes, HoldingChange{
+				Symbol:      h.Symbol,
+				Name:        h.Name,
+				Action:      HoldingAdded,
+				NewQuantity: h.Quantity,
+				NewValue:    h.CurrentValue,
+			})
+			continue
+		}
+		if old.Quantity == h.Quantity && math.Abs(old.CurrentValue-h.CurrentValue) < 0.005 {
+			unchanged++
+			continue
+		}
+		changes = append(changes, HoldingChange{
+			Symbol:      h.Symbol,
+			Name:        h.Name,
+			Action:      HoldingUpdated,
+			OldQuantity: old.Quantity,
+			NewQuantity: h.Quantity,
+			OldValue:    old.CurrentValue,
+			NewValue:    h.CurrentValue,
+		})
+	}
+
+	for _, h := range existing {
+		if seen[h.Symbol] {
+			continue
+		}
+		changes = append(changes, HoldingChange{
+			Symbol:      h.Symbol,
+			Name:        h.Name,
+			Action:      HoldingRemoved,
+			OldQuantity: h.Quantity,
+			OldValue:    h.CurrentValue,
+		})
+	}
+
+	return changes, unchanged
+}
@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"testing"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestDiffHoldings(t *testing.T) {
+	existing := []*models.Holding{
+		{Symbol: "KEEP", Quantity: 10, CurrentValue: 1000},
+		{Symbol: "CHANGE", Quantity: 5, CurrentValue: 500},
+		{Symbol: "GONE", Quantity: 1, CurrentValue: 100},
+	}
+	fetched := []*models.Holding{
+		{Symbol: "KEEP", Quantity: 10, CurrentValue: 1000.001},
+		{Symbol: "CHANGE", Quantity: 6, CurrentValue: 650},
+		{Symbol: "NEW", Quantity: 2, CurrentValue: 200},
+	}
+
+	changes, unchanged := diffHoldings(existing, fetched)
+
+	if unchanged != 1 {
+		t.Errorf("unchanged = %d; want 1", unchanged)
+	}
+
+	want := map[string]string{
+		"CHANGE": HoldingUpdated,
+		"NEW":    HoldingAdded,
+		"GONE":   HoldingRemoved,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes; want %d: %+v", len(changes), len(want), changes)
+	}
+	for _, c := range changes {
+		if want[c.Symbol] != c.Action {
+			t.Errorf("change for %s = %s; want %s", c.Symbol, c.Action, want[c.Symbol])
+		}
+		if c.Symbol == "CHANGE" && (c.OldValue != 500 || c.NewValue != 650) {
+			t.Errorf("CHANGE values = %f -> %f; want 500 -> 650", c.OldValue, c.NewValue)
+		}
+	}
+}
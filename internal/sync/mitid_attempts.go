@@ -0,0 +1,143 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/models"
+)
+
+// MitID attempt limits. Repeated failed MitID logins can get the user's
+// identity temporarily blocked, so after MitIDMaxConsecutiveFailures failed
+// attempts in a row no new attempt is started until MitIDCooldown has passed
+// since the last failure.
+const (
+	MitIDMaxConsecutiveFailures = 3
+	MitIDCooldown               = 15 * time.Minute
+)
+
+// mitidCooldownRemaining returns how long new MitID attempts are blocked
+// given the number of consecutive failures and the time of the last one.
+func mitidCooldownRemaining(failures int, lastFailure, now time.Time) time.Duration {
+	if failures < MitIDMaxConsecutiveFailures {
+		return 0
+	}
+	remaining := lastFailure.Add(MitIDCooldown).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// MitIDCooldownRemaining returns how long MitID authentication is blocked for
+// a connection, or 0 if a new attempt may be started.
+func (s *Service) MitIDCooldownRemaining(connectionID int64) (time.Duration, error) {
+	failures, lastFailure, err := s.mitidRepo.GetConsecutiveFailures(connectionID)
+	if err != nil {
+		return 0, err
+	}
+	return mitidCooldownRemaining(failures, lastFailure, time.Now()), nil
+}
+
+// MitIDAttempts returns the most recent MitID attempts for a connection.
+func (s *Service) MitIDAttempts(connectionID int64, limit int) ([]*models.MitIDAttempt, error) {
+	return s.mitidRepo.GetByConnectionID(connectionID, limit)
+}
+
+// authenticateNordnet authenticates a Nordnet connection via MitID or BankID,
+// depending on its country, enforcing the failure cool-down and recording the
+// outcome of each attempt. Cached sessions and requests joining an
+// authentication already in progress do not count as attempts. Finnish
+// connections need a session from a bank login completed on the connection
+// page.
+func (s *Service) authenticateNordnet(conn *models.BrokerConnection, purpose string) (*nordnet.Session, error) {
+	if session := nordnet.GetValidatedSession(conn.ID); session != nil {
+		return session, nil
+	}
+	if s.signIn != nil {
+		if err := s.signIn(conn); err != nil {
+			return nil, err
+		}
+		if session := nordnet.GetCachedSession(conn.ID); session != nil {
+			return session, nil
+		}
+		return nil, nordnet.ErrSessionExpired
+	}
+	if nordnet.AuthMethod(conn.Country) == nordnet.AuthFTN {
+		return nil, nordnet.ErrFTNLoginRequired
+	}
+	if nordnet.GetActiveMitIDSessionNative(conn.ID) != nil {
+		return s.loginNordnet(conn)
+	}
+
+	remaining, err := s.MitIDCooldownRemaining(conn.ID)
+	if err != nil {
+		log.Printf("[Sync] Error checking MitID cool-down for connection %d: %v", conn.ID, err)
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("too many failed MitID attempts - try again in %d minutes", int(remaining.Minutes())+1)
+	}
+
+	attemptID, err := s.mitidRepo.Start(conn.ID, purpose)
+	if err != nil {
+		log.Printf("[Sync] Error recording MitID attempt for connection %d: %v", conn.ID, err)
+	}
+
+	session, err := s.loginNordnet(conn)
+	if attemptID != 0 {
+		if err != nil {
+			s.mitidRepo.Fail(attemptID, err.Error())
+		} else {
+			s.mitidRepo.Complete(attemptID)
+		}
+	}
+	return session, err
+}
+
+// SetSignIn replaces the interactive Nordnet and Saxo logins (MitID, BankID
+// and Saxo OAuth) with signIn, which must cache a session for the
+// connection. The mock broker uses it, as recorded payloads cannot stand in
+// for a login.
+func (s *Service) SetSignIn(signIn func(conn *models.BrokerConnection) error) {
+	s.signIn = signIn
+}
+
+// loginNordnet runs the interactive login of a connection's country. The
+// CPR field holds the national identity number for Norwegian BankID.
+func (s *Service) loginNordnet(conn *models.BrokerConnection) (*nordnet.Session, error) {
+	useHTTPConfig(conn)
+	switch nordnet.AuthMethod(conn.Country) {
+	case nordnet.AuthBankIDSE, nordnet.AuthBankIDNO:
+		return nordnet.AuthenticateWithBankID(conn.ID, conn.Country, conn.CPR)
+	}
+	return nordnet.AuthenticateWithMitIDNative(conn.ID, conn.Country, conn.Username, conn.CPR, "APP", s.scriptDir)
+}
+
+// StartFTNLogin returns the address at which the owner of a Finnish Nordnet
+// connection logs in with their bank.
+func (s *Service) StartFTNLogin(conn *models.BrokerConnection) string {
+	return nordnet.StartFTNLogin(conn.ID, conn.Country)
+}
+
+// CompleteFTNLogin creates the Nordnet session of a Finnish connection from
+// the address Nordnet returned to after the bank login, and records the
+// attempt like MitID logins.
+func (s *Service) CompleteFTNLogin(conn *models.BrokerConnection, redirectURL string) error {
+	attemptID, err := s.mitidRepo.Start(conn.ID, "ftn")
+	if err != nil {
+		log.Printf("[Sync] Error recording bank login attempt for connection %d: %v", conn.ID, err)
+	}
+
+	useHTTPConfig(conn)
+	_, err = nordnet.CompleteFTNLogin(conn.ID, redirectURL)
+	if attemptID != 0 {
+		if err != nil {
+			s.mitidRepo.Fail(attemptID, err.Error())
+		} else {
+			s.mitidRepo.Complete(attemptID)
+		}
+	}
+	return err
+}
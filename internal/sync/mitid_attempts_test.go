@@ -0,0 +1,28 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMitIDCooldownRemaining(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		failures    int
+		lastFailure time.Time
+		want        time.Duration
+	}{
+		{"below limit", MitIDMaxConsecutiveFailures - 1, now, 0},
+		{"at limit, recent failure", MitIDMaxConsecutiveFailures, now.Add(-5 * time.Minute), MitIDCooldown - 5*time.Minute},
+		{"at limit, cool-down over", MitIDMaxConsecutiveFailures, now.Add(-MitIDCooldown - time.Second), 0},
+		{"above limit", MitIDMaxConsecutiveFailures + 2, now, MitIDCooldown},
+	}
+
+	for _, tc := range tests {
+		if got := mitidCooldownRemaining(tc.failures, tc.lastFailure, now); got != tc.want {
+			t.Errorf("%s: mitidCooldownRemaining() = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"path/filepath"
+	"testing"
+
+	"wealth_tracker/internal/broker/mock"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/database"
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+// setupMockSync creates a sync service backed by a fresh database and a
+// signed in Nordnet connection answered by the mock broker, with account
+// "1" mapped to a local account.
+func setupMockSync(t *testing.T) (*Service, *mock.Server, *database.DB, int64, int64) {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.RunMigrations(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	result, err := db.Exec(`INSERT INTO users (email, password_hash, name) VALUES ('sync@example.com', 'hash', 'Sync')`)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	userID, _ := result.LastInsertId()
+
+	accountID, err := repository.NewAccountRepository(db).Create(&models.Account{
+		UserID: userID, Name: "Nordnet", Currency: "DKK", IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	connRepo := repository.NewBrokerConnectionRepository(db)
+	conn := &models.BrokerConnection{UserID: userID, BrokerType: "nordnet", Country: "dk", IsActive: true}
+	conn.ID, err = connRepo.Create(conn)
+	if err != nil {
+		t.Fatalf("failed to create connection: %v", err)
+	}
+
+	mappingRepo := repository.NewAccountMappingRepository(db)
+	if _, err := mappingRepo.Create(&models.AccountMapping{
+		ConnectionID: conn.ID, LocalAccountID: accountID,
+		ExternalAccountID: "1", ExternalAccountName: "Frie midler", AutoSync: true,
+	}); err != nil {
+		t.Fatalf("failed to create mapping: %v", err)
+	}
+
+	server := mock.Start()
+	t.Cleanup(server.Close)
+	if err := server.SignIn(conn); err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+
+	svc := NewService(
+		connRepo,
+		repository.NewHoldingRepository(db),
+		mappingRepo,
+		repository.NewSyncHistoryRepository(db),
+		repository.NewTransactionRepository(db),
+		repository.NewMitIDAttemptRepository(db),
+		"",
+	)
+	return svc, server, db, conn.ID, accountID
+}
+
+func TestSyncConnection_MockBroker(t *testing.T) {
+	svc, server, db, connID, accountID := setupMockSync(t)
+	holdingRepo := repository.NewHoldingRepository(db)
+	txnRepo := repository.NewTransactionRepository(db)
+
+	result, err := svc.SyncConnection(connID)
+	if err != nil {
+		t.Fatalf("SyncConnection() error = %v", err)
+	}
+	if !result.Success || result.AccountsSynced != 1 || result.PositionsSynced != 2 {
+		t.Fatalf("result = %+v; want 1 account and 2 positions synced", result)
+	}
+
+	if count, _ := holdingRepo.CountByAccountID(accountID); count != 2 {
+		t.Errorf("holdings = %d; want 2", count)
+	}
+	// Positions 7000 + 14920 plus 1080 cash
+	if balance, _ := txnRepo.GetLatestBalance(accountID); balance != 23000 {
+		t.Errorf("balance = %.2f; want 23000", balance)
+	}
+	history, _ := repository.NewSyncHistoryRepository(db).GetLatestByConnectionID(connID)
+	if history == nil || history.Status != "success" {
+		t.Errorf("sync history = %+v; want success", history)
+	}
+
+	// The broker stops reporting one holding: a dry run previews the removal
+	// and the next sync applies it.
+	server.SetFixture("nordnet/positions_1.json", `[
+		{"accno": 12345678, "instrument": {"instrument_id": 16099874, "isin_code": "DK0060534915", "name": "Novo Nordisk B", "currency": "DKK", "instrument_type": "ESH"},
+		 "qty": 10, "market_value_acc": {"currency": "DKK", "value": 7000}, "market_value": {"currency": "DKK", "value": 7000}, "acq_price": {"currency": "DKK", "value": 550}}
+	]`)
+
+	preview, err := svc.DryRunConnection(connID)
+	if err != nil {
+		t.Fatalf("DryRunConnection() error = %v", err)
+	}
+	if len(preview.Changes) != 1 || len(preview.Changes[0].Holdings) != 1 || preview.Changes[0].Holdings[0].Action != HoldingRemoved {
+		t.Fatalf("dry run changes = %+v; want one removal", preview.Changes)
+	}
+	if count, _ := holdingRepo.CountByAccountID(accountID); count != 2 {
+		t.Errorf("holdings after dry run = %d; want 2", count)
+	}
+
+	if _, err := svc.SyncConnection(connID); err != nil {
+		t.Fatalf("second SyncConnection() error = %v", err)
+	}
+	if count, _ := holdingRepo.CountByAccountID(accountID); count != 1 {
+		t.Errorf("holdings after second sync = %d; want 1", count)
+	}
+}
+
+func TestGetExternalAccounts_MockBroker(t *testing.T) {
+	svc, _, _, connID, _ := setupMockSync(t)
+
+	accounts, err := svc.GetExternalAccounts(connID)
+	if err != nil {
+		t.Fatalf("GetExternalAccounts() error = %v", err)
+	}
+	if len(accounts) != 2 || accounts[1].Name != "ASK" || accounts[1].AccountNumber != "87654321" {
+		t.Errorf("accounts = %+v; want Frie midler and ASK", accounts)
+	}
+}
+
+func TestSetSignIn_ReplacesInteractiveLogins(t *testing.T) {
+	svc, server, db, connID, _ := setupMockSync(t)
+	nordnet.InvalidateCachedSession(connID)
+
+	conn, _ := repository.NewBrokerConnectionRepository(db).GetByID(connID)
+	if svc.CanSyncUnattended(conn) {
+		t.Fatal("CanSyncUnattended() without a session = true; want false")
+	}
+
+	svc.SetSignIn(server.SignIn)
+	if !svc.CanSyncUnattended(conn) {
+		t.Error("CanSyncUnattended() with SetSignIn = false; want true")
+	}
+	if _, err := svc.GetExternalAccounts(connID); err != nil {
+		t.Errorf("GetExternalAccounts() error = %v; want the mock broker to sign in", err)
+	}
+	attempts, _ := svc.MitIDAttempts(connID, 10)
+	if len(attempts) != 0 {
+		t.Errorf("MitID attempts = %d; want none", len(attempts))
+	}
+}
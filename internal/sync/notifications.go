@@ -0,0 +1,34 @@
+package sync
+
+import (
+	"log"
+
+	"wealth_tracker/internal/notify"
+	"wealth_tracker/internal/services"
+)
+
+// SetNotifier lets syncs alert connection owners through their notification
+// channels when a sync or some of its accounts fail.
+func (s *Service) SetNotifier(notifier *services.Notifier) {
+	s.notifier = notifier
+}
+
+// notifySyncFailure alerts the owner of a connection that its sync failed.
+// Channels are sent to in the background so a slow webhook does not hold up
+// the sync.
+func (s *Service) notifySyncFailure(connectionID int64, errorMsg string) {
+	if s.notifier == nil {
+		return
+	}
+	conn, err := s.connRepo.GetByID(connectionID)
+	if err != nil || conn == nil {
+		log.Printf("[Sync] Error getting connection %d to notify: %v", connectionID, err)
+		return
+	}
+
+	n := notify.Notification{
+		Title:   brokerDisplayName(conn.BrokerType) + " sync failed",
+		Message: errorMsg,
+	}
+	go s.notifier.Notify(conn.UserID, n)
+}
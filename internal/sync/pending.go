@@ -0,0 +1,31 @@
+package sync
+
+import (
+	"log"
+
+	"wealth_tracker/internal/repository"
+)
+
+// pendingBuys are the savings plan buys of a broker account that were
+// debited but not yet executed.
+type pendingBuys struct {
+	amount float64
+	orders int
+}
+
+// SetPendingInvestmentRepository makes syncs record the pending savings plan
+// buys of each mapped account, shown as a separate line of its value.
+func (s *Service) SetPendingInvestmentRepository(pendingRepo *repository.PendingInvestmentRepository) {
+	s.pendingRepo = pendingRepo
+}
+
+// recordPendingBuys stores an account's pending savings plan buys. Failures
+// are logged only, as the buys are already part of the synced balance.
+func (s *Service) recordPendingBuys(accountID int64, pending *pendingBuys) {
+	if s.pendingRepo == nil || pending == nil {
+		return
+	}
+	if err := s.pendingRepo.Set(accountID, pending.amount, pending.orders); err != nil {
+		log.Printf("[Sync] Error recording pending investments for account %d: %v", accountID, err)
+	}
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/repository"
+)
+
+func TestApplySnapshot_RecordsPendingBuys(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+	pendingRepo := repository.NewPendingInvestmentRepository(db)
+	svc.SetPendingInvestmentRepository(pendingRepo)
+
+	conn, _ := repository.NewBrokerConnectionRepository(db).GetByID(connID)
+	mappings, err := repository.NewAccountMappingRepository(db).GetAutoSyncByConnectionID(connID)
+	if err != nil || len(mappings) != 1 {
+		t.Fatalf("mappings = %v, %v; want one", mappings, err)
+	}
+	apply := func(pending *pendingBuys) {
+		snapshot := &accountSnapshot{totalValue: 12500, hasData: true, pending: pending}
+		svc.applySnapshot(mappings[0], snapshot, time.Now(), svc.describeSync(conn))
+	}
+
+	apply(&pendingBuys{amount: 2500, orders: 2})
+	pending, err := pendingRepo.GetByUserID(conn.UserID)
+	if err != nil || pending[accountID] == nil || pending[accountID].Amount != 2500 || pending[accountID].OrderCount != 2 {
+		t.Fatalf("pending investments = %v, %v; want 2500 in 2 orders", pending, err)
+	}
+	if balance, _ := repository.NewTransactionRepository(db).GetLatestBalance(accountID); balance != 12500 {
+		t.Errorf("balance = %.2f; want 12500 including the pending buys", balance)
+	}
+
+	// Brokers that do not report orders leave the last known buys
+	apply(nil)
+	if pending, _ := pendingRepo.GetByUserID(conn.UserID); pending[accountID] == nil {
+		t.Error("pending investments removed by a snapshot without orders")
+	}
+
+	// Executed buys are cleared
+	apply(&pendingBuys{})
+	if pending, _ := pendingRepo.GetByUserID(conn.UserID); pending[accountID] != nil {
+		t.Errorf("pending investments = %+v; want none once executed", pending[accountID])
+	}
+}
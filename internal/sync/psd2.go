@@ -0,0 +1,161 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/models"
+)
+
+// SyncPSD2Connection synchronizes all mapped accounts for a PSD2 bank
+// connection. The account holder authorizes access at the bank beforehand,
+// see StartPSD2Consent; bank accounts have no holdings, so only their
+// balances are recorded.
+func (s *Service) SyncPSD2Connection(connectionID int64) (*SyncResult, error) {
+	historyID, err := s.historyRepo.Start(connectionID, "full")
+	if err != nil {
+		return nil, fmt.Errorf("starting sync history: %w", err)
+	}
+
+	conn, err := s.connRepo.GetByID(connectionID)
+	if err != nil {
+		s.failSync(historyID, connectionID, fmt.Sprintf("getting connection: %v", err))
+		return nil, fmt.Errorf("getting connection: %w", err)
+	}
+	if conn == nil {
+		s.failSync(historyID, connectionID, "connection not found")
+		return nil, fmt.Errorf("connection not found")
+	}
+
+	consent := psd2.GetConsent(connectionID)
+	if !consent.IsValid(time.Now()) {
+		err := psd2.ErrConsentRequired
+		s.connRepo.UpdateSyncStatus(connectionID, "auth_failed", err.Error())
+		s.failSync(historyID, connectionID, err.Error())
+		return nil, err
+	}
+
+	mappings, err := s.mappingRepo.GetAutoSyncByConnectionID(connectionID)
+	if err != nil {
+		s.failSync(historyID, connectionID, fmt.Sprintf("getting mappings: %v", err))
+		return nil, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	syncTime := time.Now()
+	client := newPSD2Client(conn)
+	client.SetTrail(s.startTrail(historyID))
+	fetch := func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+		return fetchPSD2Snapshot(client, consent, mapping)
+	}
+
+	result := s.syncMappings(mappings, 1, fetch, syncTime, s.describeSync(conn))
+
+	s.completeSync(historyID, connectionID, result)
+	return result, nil
+}
+
+// fetchPSD2Snapshot fetches the balance of a mapped bank account.
+func fetchPSD2Snapshot(client *psd2.Client, consent *psd2.Consent, mapping *models.AccountMapping) (*accountSnapshot, error) {
+	balances, err := client.GetBalances(consent.ID, mapping.ExternalAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching balances: %w", err)
+	}
+
+	balance, ok := psd2.PreferredBalance(balances)
+	if !ok {
+		log.Printf("[PSD2 Sync] No balance for account %s", mapping.ExternalAccountID)
+		return &accountSnapshot{}, nil
+	}
+	value, err := balance.BalanceAmount.Value()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s balance %q: %w", balance.BalanceType, balance.BalanceAmount.Amount, err)
+	}
+	log.Printf("[PSD2 Sync] Account %s: %s balance %.2f %s", mapping.ExternalAccountID, balance.BalanceType, value, balance.BalanceAmount.Currency)
+
+	return &accountSnapshot{hasData: true, totalValue: value}, nil
+}
+
+// getPSD2ExternalAccounts fetches the bank accounts a connection's consent
+// covers.
+func (s *Service) getPSD2ExternalAccounts(conn *models.BrokerConnection) ([]ExternalAccount, error) {
+	consent := psd2.GetConsent(conn.ID)
+	if !consent.IsValid(time.Now()) {
+		return nil, psd2.ErrConsentRequired
+	}
+
+	accounts, err := newPSD2Client(conn).GetAccounts(consent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching accounts: %w", err)
+	}
+
+	result := make([]ExternalAccount, len(accounts))
+	for i, acc := range accounts {
+		result[i] = ExternalAccount{
+			ID:            acc.ResourceID,
+			AccountNumber: acc.Number(),
+			Name:          acc.DisplayName(),
+			Currency:      acc.Currency,
+			Type:          acc.CashAccountType,
+			Active:        acc.IsActive(),
+		}
+	}
+	return result, nil
+}
+
+// StartPSD2Consent asks the bank of a PSD2 connection for access to the
+// account holder's accounts and returns the bank page where they authorize
+// it. The bank then sends them to redirectURI, where CompletePSD2Consent
+// checks the outcome. psuIP is the IP address of their browser.
+func (s *Service) StartPSD2Consent(conn *models.BrokerConnection, redirectURI, psuIP string) (string, error) {
+	response, err := newPSD2Client(conn).CreateConsent(redirectURI, psuIP)
+	if err != nil {
+		return "", err
+	}
+	psd2.SaveConsent(conn.ID, &psd2.Consent{
+		ID:         response.ConsentID,
+		Status:     response.ConsentStatus,
+		ValidUntil: time.Now().Add(psd2.ConsentValidity),
+	})
+	return response.Links.SCARedirect.Href, nil
+}
+
+// CompletePSD2Consent checks with the bank whether the account holder
+// authorized the consent started by StartPSD2Consent. A rejected consent is
+// removed and returns psd2.ErrConsentRejected.
+func (s *Service) CompletePSD2Consent(conn *models.BrokerConnection) error {
+	consent := psd2.GetConsent(conn.ID)
+	if consent == nil {
+		return psd2.ErrConsentRequired
+	}
+
+	status, err := newPSD2Client(conn).ConsentStatus(consent.ID)
+	if err != nil {
+		return fmt.Errorf("checking consent: %w", err)
+	}
+	log.Printf("[PSD2 Sync] Consent %s of connection %d is %s", consent.ID, conn.ID, status)
+
+	if status != "valid" {
+		psd2.ClearConsent(conn.ID)
+		return fmt.Errorf("%w (status %s)", psd2.ErrConsentRejected, status)
+	}
+	consent.Status = status
+	psd2.SaveConsent(conn.ID, consent)
+	return nil
+}
+
+// PSD2Consent returns the consent of a PSD2 connection, or nil if there is
+// none.
+func (s *Service) PSD2Consent(connectionID int64) *psd2.Consent {
+	return psd2.GetConsent(connectionID)
+}
+
+// newPSD2Client creates a client of a connection's bank API using the
+// connection's proxy and User-Agent. The user set the API URL, so it is only
+// reached on a public address.
+func newPSD2Client(conn *models.BrokerConnection) *psd2.Client {
+	client := psd2.NewClient(conn.APIURL, conn.AppSecret)
+	client.SetHTTPConfig(useHTTPConfig(conn).PublicAPI())
+	return client
+}
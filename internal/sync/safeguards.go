@@ -0,0 +1,259 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// DefaultStaleDeleteThreshold is the largest fraction of an account's holdings
+// a single sync deletes without confirmation. A broker API that returns a
+// partial or empty position list would otherwise wipe real holdings.
+const DefaultStaleDeleteThreshold = 0.5
+
+// SetStaleDeleteThreshold sets the fraction (0-1] of an account's holdings a
+// sync may delete without confirmation. Values outside the range are ignored.
+func (s *Service) SetStaleDeleteThreshold(threshold float64) {
+	if threshold <= 0 || threshold > 1 {
+		log.Printf("[Sync] Ignoring invalid stale delete threshold %.2f", threshold)
+		return
+	}
+	s.staleDeleteThreshold = threshold
+}
+
+// exceedsStaleThreshold reports whether deleting stale of total holdings would
+// remove more than the allowed fraction.
+func exceedsStaleThreshold(stale, total int, threshold float64) bool {
+	if stale == 0 || total == 0 {
+		return false
+	}
+	return float64(stale)/float64(total) > threshold
+}
+
+// deleteStaleHoldings removes holdings of a mapped account that the sync did
+// not update. If that would remove too large a share of the account, the
+// holdings are kept, the mapping is flagged for confirmation and the number
+// of kept holdings is returned.
+func (s *Service) deleteStaleHoldings(mapping *models.AccountMapping, syncTime time.Time) int {
+	stale, err := s.holdingRepo.CountStaleHoldings(mapping.LocalAccountID, syncTime)
+	if err != nil {
+		log.Printf("[Sync] Error counting stale holdings for account %d: %v", mapping.LocalAccountID, err)
+		return 0
+	}
+	if stale == 0 {
+		s.clearHeldDeletions(mapping)
+		return 0
+	}
+
+	total, err := s.holdingRepo.CountByAccountID(mapping.LocalAccountID)
+	if err != nil {
+		log.Printf("[Sync] Error counting holdings for account %d: %v", mapping.LocalAccountID, err)
+		return 0
+	}
+
+	if exceedsStaleThreshold(stale, total, s.staleDeleteThreshold) {
+		log.Printf("[Sync] Keeping %d of %d holdings for account %d: deletion exceeds %.0f%% threshold, confirmation required",
+			stale, total, mapping.LocalAccountID, s.staleDeleteThreshold*100)
+		if err := s.mappingRepo.SetHeldDeletionsSince(mapping.ID, &syncTime); err != nil {
+			log.Printf("[Sync] Error flagging held deletions for mapping %d: %v", mapping.ID, err)
+		}
+		return stale
+	}
+
+	if err := s.holdingRepo.DeleteStaleHoldings(mapping.LocalAccountID, syncTime); err != nil {
+		log.Printf("[Sync] Error deleting stale holdings for account %d: %v", mapping.LocalAccountID, err)
+		return 0
+	}
+	s.clearHeldDeletions(mapping)
+	return 0
+}
+
+// clearHeldDeletions removes the pending confirmation flag from a mapping.
+func (s *Service) clearHeldDeletions(mapping *models.AccountMapping) {
+	if mapping.HeldDeletionsSince == nil {
+		return
+	}
+	if err := s.mappingRepo.SetHeldDeletionsSince(mapping.ID, nil); err != nil {
+		log.Printf("[Sync] Error clearing held deletions for mapping %d: %v", mapping.ID, err)
+	}
+}
+
+// PendingDeletions returns the number of holdings across a connection's
+// accounts that the last sync kept because deleting them needs confirmation.
+func (s *Service) PendingDeletions(connectionID int64) (int, error) {
+	mappings, err := s.mappingRepo.GetByConnectionID(connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	pending := 0
+	for _, mapping := range mappings {
+		if mapping.HeldDeletionsSince == nil {
+			continue
+		}
+		count, err := s.holdingRepo.CountStaleHoldings(mapping.LocalAccountID, *mapping.HeldDeletionsSince)
+		if err != nil {
+			return 0, fmt.Errorf("counting stale holdings: %w", err)
+		}
+		pending += count
+	}
+	return pending, nil
+}
+
+// ConfirmDeletions deletes the holdings a previous sync kept back, for every
+// flagged account of a connection. Deleted holdings are archived and can be
+// restored. Returns the number of holdings deleted.
+func (s *Service) ConfirmDeletions(connectionID int64) (int, error) {
+	mappings, err := s.mappingRepo.GetByConnectionID(connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	deleted := 0
+	for _, mapping := range mappings {
+		if mapping.HeldDeletionsSince == nil {
+			continue
+		}
+		since := *mapping.HeldDeletionsSince
+		count, err := s.holdingRepo.CountStaleHoldings(mapping.LocalAccountID, since)
+		if err != nil {
+			return deleted, fmt.Errorf("counting stale holdings: %w", err)
+		}
+		if err := s.holdingRepo.DeleteStaleHoldings(mapping.LocalAccountID, since); err != nil {
+			return deleted, fmt.Errorf("deleting stale holdings: %w", err)
+		}
+		if err := s.mappingRepo.SetHeldDeletionsSince(mapping.ID, nil); err != nil {
+			return deleted, fmt.Errorf("clearing held deletions: %w", err)
+		}
+		deleted += count
+	}
+	return deleted, nil
+}
+
+// SetBalanceChecker sets the checker that flags synced balances deviating too
+// far from an account's trend. Flagged balances are kept for confirmation
+// instead of recorded, as they usually come from a broker API glitch.
+func (s *Service) SetBalanceChecker(checker *services.BalanceChecker) {
+	s.balanceChecker = checker
+}
+
+// SetCategorizer sets the categorizer applied to synced balances before they
+// are recorded.
+func (s *Service) SetCategorizer(categorizer *services.Categorizer) {
+	s.categorizer = categorizer
+}
+
+// recordBalance records a synced account balance as a transaction if it
+// changed. A balance that breaks the account's trend is kept on the mapping
+// for confirmation instead, and true is returned.
+func (s *Service) recordBalance(mapping *models.AccountMapping, balance float64, syncTime time.Time, describe balanceDescriber) bool {
+	currentBalance, _ := s.txnRepo.GetLatestBalance(mapping.LocalAccountID)
+	if balance == currentBalance {
+		s.clearHeldBalance(mapping)
+		return false
+	}
+
+	anomaly, err := s.balanceChecker.Check(mapping.LocalAccountID, balance)
+	if err != nil {
+		log.Printf("[Sync] Error checking balance of account %d: %v", mapping.LocalAccountID, err)
+	}
+	if anomaly != nil {
+		log.Printf("[Sync] Keeping balance %.2f of account %d: deviates %.0f%% from expected %.2f, confirmation required",
+			balance, mapping.LocalAccountID, anomaly.DeviationPercent, anomaly.Expected)
+		if err := s.mappingRepo.SetHeldBalance(mapping.ID, &balance, &syncTime); err != nil {
+			log.Printf("[Sync] Error holding balance for mapping %d: %v", mapping.ID, err)
+		}
+		return true
+	}
+
+	txn := &models.Transaction{
+		AccountID:       mapping.LocalAccountID,
+		Amount:          balance - currentBalance,
+		BalanceAfter:    balance,
+		Description:     describe(balance-currentBalance, balance, syncTime),
+		Kind:            models.TransactionValuation,
+		TransactionDate: syncTime,
+	}
+	s.categorizer.Categorize(txn)
+	s.txnRepo.Create(txn)
+	s.clearHeldBalance(mapping)
+	return false
+}
+
+// clearHeldBalance removes a balance pending confirmation from a mapping.
+func (s *Service) clearHeldBalance(mapping *models.AccountMapping) {
+	if mapping.HeldBalance == nil {
+		return
+	}
+	if err := s.mappingRepo.SetHeldBalance(mapping.ID, nil, nil); err != nil {
+		log.Printf("[Sync] Error clearing held balance for mapping %d: %v", mapping.ID, err)
+	}
+}
+
+// PendingBalances returns the mappings of a connection whose last synced
+// balance was kept because it broke the account's trend.
+func (s *Service) PendingBalances(connectionID int64) ([]*models.AccountMapping, error) {
+	mappings, err := s.mappingRepo.GetByConnectionID(connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting mappings: %w", err)
+	}
+
+	var pending []*models.AccountMapping
+	for _, mapping := range mappings {
+		if mapping.HeldBalance != nil {
+			pending = append(pending, mapping)
+		}
+	}
+	return pending, nil
+}
+
+// ConfirmBalances records the balances a previous sync kept back, for every
+// flagged account of a connection, as of the sync that fetched them. Returns
+// the number of balances recorded.
+func (s *Service) ConfirmBalances(connectionID int64) (int, error) {
+	pending, err := s.PendingBalances(connectionID)
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	conn, err := s.connRepo.GetByID(connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("getting connection: %w", err)
+	}
+	if conn == nil {
+		return 0, fmt.Errorf("connection not found")
+	}
+	describe := s.describeSync(conn)
+
+	confirmed := 0
+	for _, mapping := range pending {
+		currentBalance, err := s.txnRepo.GetLatestBalance(mapping.LocalAccountID)
+		if err != nil {
+			return confirmed, fmt.Errorf("getting balance: %w", err)
+		}
+		if *mapping.HeldBalance != currentBalance {
+			txn := &models.Transaction{
+				AccountID:       mapping.LocalAccountID,
+				Amount:          *mapping.HeldBalance - currentBalance,
+				BalanceAfter:    *mapping.HeldBalance,
+				Description:     describe(*mapping.HeldBalance-currentBalance, *mapping.HeldBalance, *mapping.HeldBalanceAt),
+				Kind:            models.TransactionValuation,
+				TransactionDate: *mapping.HeldBalanceAt,
+			}
+			s.categorizer.Categorize(txn)
+			if _, err := s.txnRepo.Create(txn); err != nil {
+				return confirmed, fmt.Errorf("recording balance: %w", err)
+			}
+		}
+		if err := s.mappingRepo.SetHeldBalance(mapping.ID, nil, nil); err != nil {
+			return confirmed, fmt.Errorf("clearing held balance: %w", err)
+		}
+		confirmed++
+	}
+	return confirmed, nil
+}
@@ -0,0 +1,172 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
+)
+
+func TestExceedsStaleThreshold(t *testing.T) {
+	tests := []struct {
+		stale, total int
+		threshold    float64
+		want         bool
+	}{
+		{0, 10, 0.5, false},
+		{1, 10, 0.5, false},
+		{5, 10, 0.5, false},
+		{6, 10, 0.5, true},
+		{10, 10, 0.5, true},
+		{1, 1, 0.5, true},
+		{1, 1, 1, false},
+		{0, 0, 0.5, false},
+	}
+
+	for _, tc := range tests {
+		if got := exceedsStaleThreshold(tc.stale, tc.total, tc.threshold); got != tc.want {
+			t.Errorf("exceedsStaleThreshold(%d, %d, %.2f) = %v; want %v", tc.stale, tc.total, tc.threshold, got, tc.want)
+		}
+	}
+}
+
+func TestSetStaleDeleteThreshold_IgnoresInvalidValues(t *testing.T) {
+	s := &Service{staleDeleteThreshold: DefaultStaleDeleteThreshold}
+
+	for _, v := range []float64{0, -0.1, 1.5} {
+		s.SetStaleDeleteThreshold(v)
+		if s.staleDeleteThreshold != DefaultStaleDeleteThreshold {
+			t.Errorf("SetStaleDeleteThreshold(%v) changed threshold to %v", v, s.staleDeleteThreshold)
+		}
+	}
+
+	s.SetStaleDeleteThreshold(0.25)
+	if s.staleDeleteThreshold != 0.25 {
+		t.Errorf("staleDeleteThreshold = %v; want 0.25", s.staleDeleteThreshold)
+	}
+}
+
+func TestSyncConnection_HoldsBalanceBreakingTrend(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+	txnRepo := repository.NewTransactionRepository(db)
+	svc.SetBalanceChecker(services.NewBalanceChecker(txnRepo, 50))
+
+	// The fixture reports 23000, a tenth of the recorded balance
+	if _, err := txnRepo.Create(&models.Transaction{
+		AccountID: accountID, Amount: 230000, BalanceAfter: 230000, TransactionDate: time.Now().AddDate(0, 0, -1),
+	}); err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	result, err := svc.SyncConnection(connID)
+	if err != nil {
+		t.Fatalf("SyncConnection() error = %v", err)
+	}
+	if result.HeldBalances != 1 {
+		t.Errorf("HeldBalances = %d; want 1", result.HeldBalances)
+	}
+	if balance, _ := txnRepo.GetLatestBalance(accountID); balance != 230000 {
+		t.Errorf("balance after sync = %.2f; want 230000 kept", balance)
+	}
+	pending, err := svc.PendingBalances(connID)
+	if err != nil || len(pending) != 1 || *pending[0].HeldBalance != 23000 {
+		t.Fatalf("PendingBalances() = %v, %v; want the synced balance held", pending, err)
+	}
+
+	confirmed, err := svc.ConfirmBalances(connID)
+	if err != nil || confirmed != 1 {
+		t.Fatalf("ConfirmBalances() = %d, %v; want 1, nil", confirmed, err)
+	}
+	if balance, _ := txnRepo.GetLatestBalance(accountID); balance != 23000 {
+		t.Errorf("balance after confirming = %.2f; want 23000", balance)
+	}
+	if pending, _ := svc.PendingBalances(connID); len(pending) != 0 {
+		t.Errorf("PendingBalances() after confirming = %d; want none", len(pending))
+	}
+}
+
+func TestSyncConnection_UsesOwnersDescriptionTemplate(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+	userRepo := repository.NewUserRepository(db)
+	svc.SetUserRepository(userRepo)
+
+	conn, _ := repository.NewBrokerConnectionRepository(db).GetByID(connID)
+	owner, _ := userRepo.GetByID(conn.UserID)
+	owner.NumberFormat = "en"
+	owner.SyncDescription = "Synced from {broker}: {delta}"
+	if err := userRepo.Update(owner); err != nil {
+		t.Fatalf("updating user: %v", err)
+	}
+
+	if _, err := svc.SyncConnection(connID); err != nil {
+		t.Fatalf("SyncConnection() error = %v", err)
+	}
+	txns, err := repository.NewTransactionRepository(db).GetByAccountID(accountID, 10, 0)
+	if err != nil || len(txns) != 1 {
+		t.Fatalf("transactions = %v, %v; want one", txns, err)
+	}
+	if want := "Synced from Nordnet: +23,000.00"; txns[0].Description != want {
+		t.Errorf("description = %q; want %q", txns[0].Description, want)
+	}
+}
+
+func TestApplySnapshot_SkipsBalanceOfPartialSnapshot(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+	holdingRepo := repository.NewHoldingRepository(db)
+	txnRepo := repository.NewTransactionRepository(db)
+
+	conn, _ := repository.NewBrokerConnectionRepository(db).GetByID(connID)
+	mappings, err := repository.NewAccountMappingRepository(db).GetAutoSyncByConnectionID(connID)
+	if err != nil || len(mappings) != 1 {
+		t.Fatalf("mappings = %v, %v; want one", mappings, err)
+	}
+	for _, symbol := range []string{"AAA", "BBB"} {
+		if err := holdingRepo.Upsert(&models.Holding{
+			AccountID: accountID, ExternalID: symbol, Symbol: symbol, Name: symbol, Quantity: 1, CurrentValue: 5000, Currency: "DKK",
+		}); err != nil {
+			t.Fatalf("Upsert(%s) error = %v", symbol, err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// The broker reports a value, but none of the holdings
+	snapshot := &accountSnapshot{totalValue: 100, hasData: true}
+	held, heldBalance := svc.applySnapshot(mappings[0], snapshot, time.Now(), svc.describeSync(conn))
+	if held != 2 || heldBalance {
+		t.Errorf("applySnapshot() = %d, %v; want 2 holdings held", held, heldBalance)
+	}
+	if balance, _ := txnRepo.GetLatestBalance(accountID); balance != 0 {
+		t.Errorf("balance = %.2f; want none recorded from a partial snapshot", balance)
+	}
+}
+
+func TestDiffSnapshot_ReportsHeldDeletions(t *testing.T) {
+	svc, _, db, connID, accountID := setupMockSync(t)
+	holdingRepo := repository.NewHoldingRepository(db)
+
+	mappings, err := repository.NewAccountMappingRepository(db).GetAutoSyncByConnectionID(connID)
+	if err != nil || len(mappings) != 1 {
+		t.Fatalf("mappings = %v, %v; want one", mappings, err)
+	}
+	for _, symbol := range []string{"AAA", "BBB"} {
+		if err := holdingRepo.Upsert(&models.Holding{
+			AccountID: accountID, ExternalID: symbol, Symbol: symbol, Name: symbol, Quantity: 1, CurrentValue: 5000, Currency: "DKK",
+		}); err != nil {
+			t.Fatalf("Upsert(%s) error = %v", symbol, err)
+		}
+	}
+
+	// The broker reports a value, but none of the holdings
+	changes, err := svc.diffSnapshot(mappings[0], &accountSnapshot{totalValue: 100, hasData: true})
+	if err != nil {
+		t.Fatalf("diffSnapshot() error = %v", err)
+	}
+	if changes.HeldDeletions != 2 || len(changes.Holdings) != 2 || changes.Holdings[0].Action != HoldingHeld {
+		t.Errorf("changes = %+v; want both holdings held", changes)
+	}
+	if changes.BalanceChanged || changes.NewBalance != changes.CurrentBalance {
+		t.Errorf("balance = %.2f -> %.2f, changed %v; want it unchanged like a real sync", changes.CurrentBalance, changes.NewBalance, changes.BalanceChanged)
+	}
+}
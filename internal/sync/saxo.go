@@ -7,6 +7,7 @@ import (
 
 	"wealth_tracker/internal/broker/saxo"
 	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
 )
 
 // SyncSaxoConnection synchronizes all mapped accounts for a Saxo broker connection.
@@ -18,8 +19,6 @@ func (s *Service) SyncSaxoConnection(connectionID int64) (*SyncResult, error) {
 		return nil, fmt.Errorf("starting sync history: %w", err)
 	}
 
-	result := &SyncResult{}
-
 	// Get connection
 	conn, err := s.connRepo.GetByID(connectionID)
 	if err != nil {
@@ -31,22 +30,13 @@ func (s *Service) SyncSaxoConnection(connectionID int64) (*SyncResult, error) {
 		return nil, fmt.Errorf("connection not found")
 	}
 
-	// Get or refresh OAuth session
-	session, err := saxo.GetOrRefreshSession(connectionID)
+	session, err := s.authenticateSaxo(conn)
 	if err != nil {
-		// Need new OAuth authentication
-		log.Printf("[Saxo Sync] No valid session, starting OAuth flow for connection %d", connectionID)
-		session, err = saxo.AuthenticateWithOAuth(connectionID, conn.AppKey, conn.AppSecret, conn.RedirectURI)
-		if err != nil {
-			s.connRepo.UpdateSyncStatus(connectionID, "auth_failed", err.Error())
-			s.failSync(historyID, connectionID, fmt.Sprintf("OAuth authentication failed: %v", err))
-			return nil, fmt.Errorf("OAuth authentication failed: %w", err)
-		}
+		s.connRepo.UpdateSyncStatus(connectionID, "auth_failed", err.Error())
+		s.failSync(historyID, connectionID, fmt.Sprintf("OAuth authentication failed: %v", err))
+		return nil, fmt.Errorf("OAuth authentication failed: %w", err)
 	}
 
-	// Create Saxo client
-	client := saxo.NewClient()
-
 	// Get account mappings
 	mappings, err := s.mappingRepo.GetAutoSyncByConnectionID(connectionID)
 	if err != nil {
@@ -54,54 +44,138 @@ func (s *Service) SyncSaxoConnection(connectionID int64) (*SyncResult, error) {
 		return nil, fmt.Errorf("getting mappings: %w", err)
 	}
 
-	// Sync each mapped account
+	// Fetch all mapped accounts in one pass
+	syncTime := time.Now()
+	client := newSaxoClient(conn)
+	client.SetTrail(s.startTrail(historyID))
+	fetch, err := s.saxoFetcher(client, session, mappings, syncTime)
+	if err != nil {
+		s.failSync(historyID, connectionID, err.Error())
+		return nil, err
+	}
+
+	// The data is already fetched, so the snapshots are converted in order
+	result := s.syncMappings(mappings, 1, fetch, syncTime, s.describeSync(conn))
+	s.fetchSaxoPerformance(client, session, mappings, syncTime)
+
+	s.completeSync(historyID, connectionID, result)
+	return result, nil
+}
+
+// SetPerformanceRepository makes Saxo syncs store the time-weighted returns
+// Saxo reports for each mapped account.
+func (s *Service) SetPerformanceRepository(perfRepo *repository.BrokerPerformanceRepository) {
+	s.perfRepo = perfRepo
+}
+
+// fetchSaxoPerformance stores the returns Saxo reports for each mapping and
+// standard period. Failures are logged only: the figures are informational
+// and must not fail an otherwise successful sync.
+func (s *Service) fetchSaxoPerformance(client *saxo.Client, session *saxo.Session, mappings []*models.AccountMapping, syncTime time.Time) {
+	if s.perfRepo == nil {
+		return
+	}
 	for _, mapping := range mappings {
-		posCount, err := s.syncSaxoAccountPositions(client, session, mapping)
-		if err != nil {
-			log.Printf("[Saxo Sync] Error syncing account %s: %v", mapping.ExternalAccountID, err)
-			// Log error but continue with other accounts
-			continue
+		for _, period := range saxo.PerformancePeriods {
+			perf, err := client.GetPerformance(session, mapping.ExternalAccountID, period)
+			if err != nil {
+				log.Printf("[Saxo Sync] Error fetching %s performance for account %s: %v", period, mapping.ExternalAccountID, err)
+				continue
+			}
+			fraction, ok := perf.ReturnFraction()
+			if !ok {
+				continue
+			}
+			if err := s.perfRepo.Upsert(&models.BrokerPerformance{
+				MappingID:      mapping.ID,
+				Period:         period,
+				ReturnFraction: fraction,
+				FetchedAt:      syncTime,
+			}); err != nil {
+				log.Printf("[Saxo Sync] Error storing %s performance for mapping %d: %v", period, mapping.ID, err)
+			}
+		}
+	}
+}
+
+// newSaxoClient creates a Saxo client using the connection's proxy and
+// User-Agent.
+func newSaxoClient(conn *models.BrokerConnection) *saxo.Client {
+	client := saxo.NewClient()
+	client.SetHTTPConfig(useHTTPConfig(conn))
+	return client
+}
+
+// authenticateSaxo returns the cached or refreshed OAuth session for a connection,
+// starting a new browser-based OAuth flow if none is available.
+func (s *Service) authenticateSaxo(conn *models.BrokerConnection) (*saxo.Session, error) {
+	useHTTPConfig(conn)
+	session, err := saxo.GetOrRefreshSession(conn.ID)
+	if err == nil {
+		return session, nil
+	}
+	if s.signIn != nil {
+		if err := s.signIn(conn); err != nil {
+			return nil, err
 		}
-		result.AccountsSynced++
-		result.PositionsSynced += posCount
+		return saxo.GetOrRefreshSession(conn.ID)
 	}
 
-	// Update connection status
-	s.connRepo.UpdateSyncStatus(connectionID, "success", "")
+	// Need new OAuth authentication
+	log.Printf("[Saxo Sync] No valid session, starting OAuth flow for connection %d", conn.ID)
+	return saxo.AuthenticateWithOAuth(conn.ID, conn.AppKey, conn.AppSecret, conn.RedirectURI)
+}
 
-	// Complete sync history
-	s.historyRepo.Complete(historyID, result.AccountsSynced, result.PositionsSynced)
+// saxoFetcher fetches positions and balances for all mappings in one pass
+// and returns a function that converts the data of a mapped account to a
+// snapshot.
+func (s *Service) saxoFetcher(client *saxo.Client, session *saxo.Session, mappings []*models.AccountMapping, syncTime time.Time) (func(*models.AccountMapping) (*accountSnapshot, error), error) {
+	// ExternalAccountID for Saxo is the AccountKey
+	accountKeys := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		accountKeys[i] = mapping.ExternalAccountID
+	}
 
-	result.Success = true
-	return result, nil
+	started := time.Now()
+	data, err := client.FetchAccounts(session, accountKeys)
+	if err != nil {
+		return nil, fmt.Errorf("fetching accounts: %w", err)
+	}
+	log.Printf("[Saxo Sync] Fetched %d accounts in %s", len(accountKeys), time.Since(started).Round(time.Millisecond))
+
+	return func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+		account := data[mapping.ExternalAccountID]
+		if account == nil {
+			return nil, fmt.Errorf("account %s was not fetched", mapping.ExternalAccountID)
+		}
+		return saxoSnapshot(mapping, account, syncTime)
+	}, nil
 }
 
-// syncSaxoAccountPositions syncs positions for a single Saxo account mapping.
-func (s *Service) syncSaxoAccountPositions(client *saxo.Client, session *saxo.Session, mapping *models.AccountMapping) (int, error) {
+// saxoSnapshot converts the positions and balance of a Saxo account to local
+// holdings.
+func saxoSnapshot(mapping *models.AccountMapping, account *saxo.AccountData, syncTime time.Time) (*accountSnapshot, error) {
 	log.Printf("[Saxo Sync] Syncing positions for account mapping: LocalAccountID=%d, ExternalAccountID=%s", mapping.LocalAccountID, mapping.ExternalAccountID)
 
-	// ExternalAccountID for Saxo is the AccountKey
 	accountKey := mapping.ExternalAccountID
-
-	// Fetch positions with instrument details
-	positions, err := client.GetPositionsWithDetails(session, accountKey)
-	if err != nil {
-		log.Printf("[Saxo Sync] Error fetching positions for account %s: %v", accountKey, err)
-		return 0, fmt.Errorf("fetching positions: %w", err)
+	if account.Err != nil {
+		log.Printf("[Saxo Sync] Error fetching positions for account %s: %v", accountKey, account.Err)
+		return nil, fmt.Errorf("fetching positions: %w", account.Err)
 	}
+	positions := account.Positions
 
 	log.Printf("[Saxo Sync] Got %d positions for account %s", len(positions), accountKey)
 
-	// Fetch balance
-	balance, err := client.GetBalance(session, accountKey)
-	if err != nil {
-		log.Printf("[Saxo Sync] Error fetching balance for account %s: %v (continuing without cash)", accountKey, err)
+	balance := account.Balance
+	if account.BalanceErr != nil {
+		log.Printf("[Saxo Sync] Error fetching balance for account %s: %v (continuing without cash)", accountKey, account.BalanceErr)
 		// Don't fail - continue without cash balance
-	} else {
+		balance = nil
+	} else if balance != nil {
 		log.Printf("[Saxo Sync] Balance for account %s: Cash=%.2f %s", accountKey, balance.CashBalance, balance.Currency)
 	}
 
-	syncTime := time.Now()
+	snapshot := &accountSnapshot{hasData: len(positions) > 0 || balance != nil}
 	var positionsValue float64
 	var cashValue float64
 
@@ -125,7 +199,7 @@ func (s *Service) syncSaxoAccountPositions(client *saxo.Client, session *saxo.Se
 		totalCostBasis += pos.AbsQuantity() * pos.OpenPrice()
 	}
 
-	// Upsert each position as a holding
+	// Convert each position to a holding
 	for _, pos := range positions {
 		// Debug: log all price/value fields to see what's available
 		log.Printf("[Saxo Sync] Position %s raw values: CurrentPrice=%.4f, MarketValue=%.2f, MarketValueInBase=%.2f, Exposure=%.2f, ExposureInBase=%.2f, OpenPrice=%.4f",
@@ -154,7 +228,7 @@ func (s *Service) syncSaxoAccountPositions(client *saxo.Client, session *saxo.Se
 				pos.Symbol(), costBasis, costBasis/totalCostBasis, holdingValue)
 		}
 
-		holding := &models.Holding{
+		snapshot.holdings = append(snapshot.holdings, &models.Holding{
 			AccountID:      mapping.LocalAccountID,
 			ExternalID:     fmt.Sprintf("%d", pos.Uic()),
 			Symbol:         pos.Symbol(),
@@ -166,15 +240,7 @@ func (s *Service) syncSaxoAccountPositions(client *saxo.Client, session *saxo.Se
 			Currency:       pos.Currency(),
 			InstrumentType: pos.AssetType(),
 			LastUpdated:    syncTime,
-		}
-
-		log.Printf("[Saxo Sync] Upserting holding: Symbol=%s, Name=%s, Qty=%.2f, Price=%.4f, Value=%.2f",
-			holding.Symbol, holding.Name, holding.Quantity, holding.CurrentPrice, holding.CurrentValue)
-
-		if err := s.holdingRepo.Upsert(holding); err != nil {
-			log.Printf("[Saxo Sync] Error upserting holding %s: %v", holding.Symbol, err)
-			continue
-		}
+		})
 		positionsValue += holdingValue
 	}
 
@@ -193,23 +259,9 @@ func (s *Service) syncSaxoAccountPositions(client *saxo.Client, session *saxo.Se
 	log.Printf("[Saxo Sync] Account %s: Positions=%.2f, Cash=%.2f, BalanceTotalValue=%.2f",
 		accountKey, positionsValue, cashValue, totalValue)
 
-	// Delete stale holdings (positions that no longer exist)
-	s.holdingRepo.DeleteStaleHoldings(mapping.LocalAccountID, syncTime)
-
-	// Update account balance if it changed
-	currentBalance, _ := s.txnRepo.GetLatestBalance(mapping.LocalAccountID)
-	if totalValue != currentBalance && (len(positions) > 0 || balance != nil) {
-		txn := &models.Transaction{
-			AccountID:       mapping.LocalAccountID,
-			Amount:          totalValue - currentBalance,
-			BalanceAfter:    totalValue,
-			Description:     "Saxo sync",
-			TransactionDate: syncTime,
-		}
-		s.txnRepo.Create(txn)
-	}
+	snapshot.totalValue = totalValue
 
-	return len(positions), nil
+	return snapshot, nil
 }
 
 // GetSaxoExternalAccounts fetches accounts from Saxo for account mapping setup.
@@ -224,19 +276,14 @@ func (s *Service) GetSaxoExternalAccounts(connectionID int64) ([]saxo.Account, e
 		return nil, fmt.Errorf("connection not found")
 	}
 
-	// Get or refresh OAuth session
-	session, err := saxo.GetOrRefreshSession(connectionID)
+	// Get or refresh OAuth session, or start a new OAuth flow
+	session, err := s.authenticateSaxo(conn)
 	if err != nil {
-		// Need new OAuth authentication
-		log.Printf("[Saxo Sync] No valid session, starting OAuth flow for connection %d", connectionID)
-		session, err = saxo.AuthenticateWithOAuth(connectionID, conn.AppKey, conn.AppSecret, conn.RedirectURI)
-		if err != nil {
-			return nil, fmt.Errorf("OAuth authentication failed: %w", err)
-		}
+		return nil, fmt.Errorf("OAuth authentication failed: %w", err)
 	}
 
 	// Create Saxo client
-	client := saxo.NewClient()
+	client := newSaxoClient(conn)
 
 	// Fetch accounts
 	accounts, err := client.GetAccounts(session)
@@ -271,6 +318,7 @@ func (s *Service) StartSaxoOAuth(connectionID int64) error {
 
 	// Clear any stale OAuth session before starting new one
 	saxo.ClearActiveOAuthSession(connectionID)
+	useHTTPConfig(conn)
 
 	appKey := conn.AppKey
 	appSecret := conn.AppSecret
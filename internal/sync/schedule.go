@@ -0,0 +1,174 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// Schedule is when a connection is synced unattended: the minutes, hours,
+// days and months of a cron expression, in the market's time zone.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // Bit sets of the allowed values
+	anyDOM, anyDOW                bool   // Whether the day fields are "*"
+}
+
+// scheduleFields are the fields of a cron expression with their ranges.
+var scheduleFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseSchedule parses a sync schedule. "daily" syncs every day and "weekly"
+// every Monday, both at the start of the sync window; anything else is a
+// cron expression of minute, hour, day of month, month and day of week, such
+// as "30 18 * * 1-5". An empty schedule returns nil.
+func ParseSchedule(spec string, windowStart int) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "":
+		return nil, nil
+	case "daily":
+		spec = fmt.Sprintf("0 %d * * *", windowStart)
+	case "weekly":
+		spec = fmt.Sprintf("0 %d * * 1", windowStart)
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(scheduleFields) {
+		return nil, errors.New("a schedule needs 5 fields: minute, hour, day of month, month and day of week")
+	}
+	var sets [5]uint64
+	for i, field := range scheduleFields {
+		set, err := parseScheduleField(parts[i], field.min, field.max)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+		sets[i] = set
+	}
+
+	s := &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDOM: parts[2] == "*",
+		anyDOW: parts[4] == "*",
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, errors.New("the schedule never runs")
+	}
+	return s, nil
+}
+
+// parseScheduleField parses a comma-separated list of "*", values and
+// ranges, each optionally with a step such as "*/15", into a bit set.
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				hi = max
+			}
+			if lo < min || hi > max || lo > hi {
+				return 0, fmt.Errorf("%q is outside %d-%d", rng, min, max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t that the schedule runs, in t's time
+// zone, or the zero time if it does not run within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Five years cover every February 29th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the schedule runs on t's day. As in cron, a day
+// matches either day field when both are restricted.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.anyDOM && s.anyDOW:
+		return true
+	case s.anyDOM:
+		return dow
+	case s.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// NextScheduledSync returns when a connection is next due for a scheduled
+// sync after t: the next scheduled time, moved to the start of its sync
+// window if it falls outside. It returns false if the connection has no
+// valid schedule.
+func NextScheduledSync(conn *models.BrokerConnection, t time.Time) (time.Time, bool) {
+	schedule, err := ParseSchedule(conn.SyncSchedule, conn.SyncWindowStart)
+	if err != nil || schedule == nil {
+		return time.Time{}, false
+	}
+	next := schedule.Next(t.In(MarketLocation(conn.Country)))
+	if next.IsZero() {
+		return time.Time{}, false
+	}
+	return NextSyncWindow(conn, next), true
+}
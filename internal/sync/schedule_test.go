@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestScheduleNext(t *testing.T) {
+	cph := MarketLocation("dk")
+	// Wednesday 15 May 2024, 10:30
+	from := time.Date(2024, 5, 15, 10, 30, 0, 0, cph)
+
+	tests := []struct {
+		spec        string
+		windowStart int
+		want        time.Time
+	}{
+		{"daily", 18, time.Date(2024, 5, 15, 18, 0, 0, 0, cph)},
+		{"daily", 6, time.Date(2024, 5, 16, 6, 0, 0, 0, cph)},
+		{"weekly", 0, time.Date(2024, 5, 20, 0, 0, 0, 0, cph)},
+		{"*/15 * * * *", 0, time.Date(2024, 5, 15, 10, 45, 0, 0, cph)},
+		{"30 18 * * 1-5", 0, time.Date(2024, 5, 15, 18, 30, 0, 0, cph)},
+		{"0 9 * * 6,7", 0, time.Date(2024, 5, 18, 9, 0, 0, 0, cph)},
+		{"0 0 1 * *", 0, time.Date(2024, 6, 1, 0, 0, 0, 0, cph)},
+		{"0 12 29 2 *", 0, time.Date(2028, 2, 29, 12, 0, 0, 0, cph)},
+		// Both day fields restricted: either matches
+		{"0 8 20 * 5", 0, time.Date(2024, 5, 17, 8, 0, 0, 0, cph)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec, tt.windowStart)
+			if err != nil {
+				t.Fatalf("ParseSchedule() error = %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{"hourly", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "0 0 30 2 *", "a b c d e"} {
+		if _, err := ParseSchedule(spec, 0); err == nil {
+			t.Errorf("ParseSchedule(%q) error = nil; want an error", spec)
+		}
+	}
+	if schedule, err := ParseSchedule("", 0); schedule != nil || err != nil {
+		t.Errorf("ParseSchedule(\"\") = %v, %v; want no schedule", schedule, err)
+	}
+}
+
+func TestNextScheduledSync_WaitsForSyncWindow(t *testing.T) {
+	cph := MarketLocation("dk")
+	conn := &models.BrokerConnection{Country: "dk", SyncSchedule: "0 12 * * *", SyncWindowStart: 18, SyncWindowEnd: 24}
+
+	next, ok := NextScheduledSync(conn, time.Date(2024, 5, 15, 10, 0, 0, 0, cph))
+	if want := time.Date(2024, 5, 15, 18, 0, 0, 0, cph); !ok || !next.Equal(want) {
+		t.Errorf("NextScheduledSync() = %v, %v; want %v", next, ok, want)
+	}
+	if _, ok := NextScheduledSync(&models.BrokerConnection{SyncWindowEnd: 24}, time.Now()); ok {
+		t.Error("NextScheduledSync() without a schedule = true; want false")
+	}
+}
+
+func TestRunScheduledSyncs(t *testing.T) {
+	svc, _, db, connID, _ := setupMockSync(t)
+	connRepo := repository.NewBrokerConnectionRepository(db)
+	conn, err := connRepo.GetByID(connID)
+	if err != nil {
+		t.Fatalf("getting connection: %v", err)
+	}
+
+	// Without a schedule nothing is synced
+	now := conn.CreatedAt.Add(48 * time.Hour)
+	if run, err := svc.RunScheduledSyncs(now); err != nil || run != (ScheduledSyncRun{}) {
+		t.Fatalf("RunScheduledSyncs() = %+v, %v; want nothing synced", run, err)
+	}
+
+	conn.SyncSchedule = "daily"
+	if err := connRepo.Update(conn); err != nil {
+		t.Fatalf("updating connection: %v", err)
+	}
+	run, err := svc.RunScheduledSyncs(now)
+	if err != nil || run.Synced != 1 {
+		t.Fatalf("RunScheduledSyncs() = %+v, %v; want 1 synced", run, err)
+	}
+
+	// Not due again until the next day
+	if run, err := svc.RunScheduledSyncs(now.Add(time.Minute)); err != nil || run.Synced != 0 {
+		t.Errorf("RunScheduledSyncs() right after = %+v, %v; want nothing synced", run, err)
+	}
+}
@@ -0,0 +1,83 @@
+package sync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+// ScheduledSyncRun counts the outcome of a check for due scheduled syncs.
+type ScheduledSyncRun struct {
+	Synced  int
+	Failed  int
+	Skipped int // Due connections that need an interactive login
+}
+
+// RunScheduledSyncs syncs, one after another, every active connection whose
+// schedule has fallen due by now and whose sync window is open. A due
+// connection that needs its owner to log in, or whose sync fails, waits for
+// its next scheduled time rather than being retried on every check.
+func (s *Service) RunScheduledSyncs(now time.Time) (ScheduledSyncRun, error) {
+	var run ScheduledSyncRun
+	conns, err := s.connRepo.GetAllActive()
+	if err != nil {
+		return run, fmt.Errorf("getting connections: %w", err)
+	}
+
+	for _, conn := range conns {
+		if !s.scheduledSyncDue(conn, now) {
+			continue
+		}
+		s.scheduledMu.Lock()
+		s.scheduledAt[conn.ID] = now
+		s.scheduledMu.Unlock()
+
+		if !s.CanSyncUnattended(conn) {
+			log.Printf("[Scheduled Sync] Skipping connection %d: it needs a login", conn.ID)
+			run.Skipped++
+			continue
+		}
+
+		result, err := s.SyncConnection(conn.ID)
+		if err == nil && result != nil && result.Success {
+			run.Synced++
+			continue
+		}
+		if err == nil && result != nil {
+			err = result.Error
+		}
+		log.Printf("[Scheduled Sync] Sync of connection %d failed: %v", conn.ID, err)
+		run.Failed++
+	}
+	return run, nil
+}
+
+// NextScheduledSync returns when a connection is next due for a scheduled
+// sync, counting from its last sync, and false if it has no schedule.
+func (s *Service) NextScheduledSync(conn *models.BrokerConnection) (time.Time, bool) {
+	return NextScheduledSync(conn, s.lastScheduledSync(conn))
+}
+
+// scheduledSyncDue reports whether a connection's scheduled sync may start
+// now.
+func (s *Service) scheduledSyncDue(conn *models.BrokerConnection, now time.Time) bool {
+	next, ok := s.NextScheduledSync(conn)
+	return ok && !next.After(now) && InSyncWindow(conn, now)
+}
+
+// lastScheduledSync returns the time the schedule of a connection counts
+// from: its last sync, the last time it fell due, or else its creation.
+func (s *Service) lastScheduledSync(conn *models.BrokerConnection) time.Time {
+	last := conn.CreatedAt
+	if conn.LastSyncAt != nil && conn.LastSyncAt.After(last) {
+		last = *conn.LastSyncAt
+	}
+	s.scheduledMu.Lock()
+	defer s.scheduledMu.Unlock()
+	if at, ok := s.scheduledAt[conn.ID]; ok && at.After(last) {
+		last = at
+	}
+	return last
+}
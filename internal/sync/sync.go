@@ -4,11 +4,15 @@ package sync
 import (
 	"fmt"
 	"log"
+	stdsync "sync"
 	"time"
 
+	"wealth_tracker/internal/broker"
+	"wealth_tracker/internal/broker/crypto"
 	"wealth_tracker/internal/broker/nordnet"
 	"wealth_tracker/internal/models"
 	"wealth_tracker/internal/repository"
+	"wealth_tracker/internal/services"
 )
 
 // Service orchestrates broker synchronization.
@@ -18,7 +22,66 @@ type Service struct {
 	mappingRepo *repository.AccountMappingRepository
 	historyRepo *repository.SyncHistoryRepository
 	txnRepo     *repository.TransactionRepository
+	mitidRepo   *repository.MitIDAttemptRepository
 	scriptDir   string // Directory containing MitID Python scripts
+
+	// signIn, if set, replaces the interactive Nordnet and Saxo logins; see
+	// SetSignIn.
+	signIn func(conn *models.BrokerConnection) error
+
+	// staleDeleteThreshold is the largest fraction of an account's holdings a
+	// sync may delete without confirmation.
+	staleDeleteThreshold float64
+
+	// userRepo looks up connection owners for their description templates;
+	// nil uses the default descriptions.
+	userRepo *repository.UserRepository
+
+	// balanceChecker flags synced balances that break an account's trend;
+	// nil records every balance.
+	balanceChecker *services.BalanceChecker
+
+	// categorizer applies the connection owner's categorization rules to
+	// synced balances; nil leaves them uncategorized.
+	categorizer *services.Categorizer
+
+	// perfRepo stores the returns brokers report for mapped accounts; nil
+	// skips fetching them.
+	perfRepo *repository.BrokerPerformanceRepository
+
+	// notifier alerts connection owners of failed syncs; nil sends no alerts.
+	notifier *services.Notifier
+
+	// saxoRefreshWarn and nordnetAuthStale flag logins that need renewing
+	// (see SetCredentialFreshness); zero turns a check off.
+	saxoRefreshWarn  time.Duration
+	nordnetAuthStale time.Duration
+
+	// usage counts syncs against their owners' monthly quota; nil leaves
+	// syncs unlimited.
+	usage *services.UsageService
+
+	// pendingRepo stores pending savings plan buys of mapped accounts; nil
+	// skips recording them.
+	pendingRepo *repository.PendingInvestmentRepository
+
+	// accountRepo looks up the currency crypto exchange holdings are priced
+	// in; nil leaves exchange connections unsyncable.
+	accountRepo *repository.AccountRepository
+
+	// trails holds the request trails of running syncs by history ID.
+	trailsMu stdsync.Mutex
+	trails   map[int64]*broker.Trail
+
+	// syncAll is the latest sync of all connections; nil if none has run.
+	syncAllMu stdsync.Mutex
+	syncAll   *SyncAllStatus
+
+	// scheduledAt is when connections last fell due for a scheduled sync,
+	// by connection ID, so one that could not be synced is not retried on
+	// every check.
+	scheduledMu stdsync.Mutex
+	scheduledAt map[int64]time.Time
 }
 
 // NewService creates a new sync service.
@@ -28,6 +91,7 @@ func NewService(
 	mappingRepo *repository.AccountMappingRepository,
 	historyRepo *repository.SyncHistoryRepository,
 	txnRepo *repository.TransactionRepository,
+	mitidRepo *repository.MitIDAttemptRepository,
 	scriptDir string,
 ) *Service {
 	return &Service{
@@ -36,15 +100,25 @@ func NewService(
 		mappingRepo: mappingRepo,
 		historyRepo: historyRepo,
 		txnRepo:     txnRepo,
+		mitidRepo:   mitidRepo,
 		scriptDir:   scriptDir,
+
+		staleDeleteThreshold: DefaultStaleDeleteThreshold,
+		trails:               make(map[int64]*broker.Trail),
+		scheduledAt:          make(map[int64]time.Time),
 	}
 }
 
 // SyncResult contains the result of a sync operation.
 type SyncResult struct {
 	Success         bool
+	DryRun          bool
 	AccountsSynced  int
 	PositionsSynced int
+	HeldDeletions   int              // Stale holdings kept pending confirmation
+	HeldBalances    int              // Account balances kept pending confirmation as they broke the trend
+	AccountErrors   []AccountError   // Mapped accounts that failed; the others were synced
+	Changes         []AccountChanges // Would-be changes, only set for dry runs
 	Error           error
 }
 
@@ -59,6 +133,9 @@ func (s *Service) SyncConnection(connectionID int64) (*SyncResult, error) {
 	if conn == nil {
 		return nil, fmt.Errorf("connection not found")
 	}
+	if err := s.useSyncQuota(conn); err != nil {
+		return nil, err
+	}
 
 	// Route to broker-specific sync
 	switch conn.BrokerType {
@@ -66,6 +143,10 @@ func (s *Service) SyncConnection(connectionID int64) (*SyncResult, error) {
 		return s.SyncNordnetConnection(connectionID)
 	case "saxo":
 		return s.SyncSaxoConnection(connectionID)
+	case "psd2":
+		return s.SyncPSD2Connection(connectionID)
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		return s.SyncCryptoConnection(connectionID)
 	default:
 		return nil, fmt.Errorf("unsupported broker type: %s", conn.BrokerType)
 	}
@@ -80,8 +161,6 @@ func (s *Service) SyncNordnetConnection(connectionID int64) (*SyncResult, error)
 		return nil, fmt.Errorf("starting sync history: %w", err)
 	}
 
-	result := &SyncResult{}
-
 	// Get connection
 	conn, err := s.connRepo.GetByID(connectionID)
 	if err != nil {
@@ -94,16 +173,14 @@ func (s *Service) SyncNordnetConnection(connectionID int64) (*SyncResult, error)
 	}
 
 	// Create broker client based on type
-	client, err := s.createClient(conn.BrokerType, conn.Country)
+	client, err := s.createClient(conn)
 	if err != nil {
 		s.failSync(historyID, connectionID, fmt.Sprintf("creating client: %v", err))
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
+	client.SetTrail(s.startTrail(historyID))
 
-	// Authenticate using MitID (user must approve in MitID app)
-	// Username field stores the MitID user identifier
-	// Using native Go implementation instead of Python subprocess
-	session, err := nordnet.AuthenticateWithMitIDNative(connectionID, conn.Country, conn.Username, conn.CPR, "APP", s.scriptDir)
+	session, err := s.authenticateNordnet(conn, "sync")
 	if err != nil {
 		s.connRepo.UpdateSyncStatus(connectionID, "auth_failed", err.Error())
 		s.failSync(historyID, connectionID, fmt.Sprintf("MitID authentication failed: %v", err))
@@ -117,36 +194,26 @@ func (s *Service) SyncNordnetConnection(connectionID int64) (*SyncResult, error)
 		return nil, fmt.Errorf("getting mappings: %w", err)
 	}
 
-	// Sync each mapped account
-	for _, mapping := range mappings {
-		posCount, err := s.syncAccountPositions(client, session, mapping)
-		if err != nil {
-			// Log error but continue with other accounts
-			continue
-		}
-		result.AccountsSynced++
-		result.PositionsSynced += posCount
-	}
-
-	// Update connection status
-	s.connRepo.UpdateSyncStatus(connectionID, "success", "")
-
-	// Complete sync history
-	s.historyRepo.Complete(historyID, result.AccountsSynced, result.PositionsSynced)
+	// Fetch the mapped accounts concurrently over the shared session
+	syncTime := time.Now()
+	result := s.syncMappings(mappings, maxConcurrentFetches, func(mapping *models.AccountMapping) (*accountSnapshot, error) {
+		return s.fetchNordnetSnapshot(client, session, mapping, syncTime)
+	}, syncTime, s.describeSync(conn))
 
-	result.Success = true
+	s.completeSync(historyID, connectionID, result)
 	return result, nil
 }
 
-// syncAccountPositions syncs positions for a single account mapping.
-func (s *Service) syncAccountPositions(client *nordnet.Client, session *nordnet.Session, mapping *models.AccountMapping) (int, error) {
+// fetchNordnetSnapshot fetches positions and cash for a Nordnet account and
+// converts them to local holdings without writing anything.
+func (s *Service) fetchNordnetSnapshot(client *nordnet.Client, session *nordnet.Session, mapping *models.AccountMapping, syncTime time.Time) (*accountSnapshot, error) {
 	log.Printf("[Sync] Syncing positions for account mapping: LocalAccountID=%d, ExternalAccountID=%s", mapping.LocalAccountID, mapping.ExternalAccountID)
 
 	// Fetch positions from broker
 	positions, err := client.GetPositions(session, mapping.ExternalAccountID)
 	if err != nil {
 		log.Printf("[Sync] Error fetching positions for account %s: %v", mapping.ExternalAccountID, err)
-		return 0, fmt.Errorf("fetching positions: %w", err)
+		return nil, fmt.Errorf("fetching positions: %w", err)
 	}
 
 	log.Printf("[Sync] Got %d positions for account %s", len(positions), mapping.ExternalAccountID)
@@ -164,13 +231,19 @@ func (s *Service) syncAccountPositions(client *nordnet.Client, session *nordnet.
 		}
 	}
 
-	syncTime := time.Now()
+	// Fetch orders for savings plan buys that were debited but not executed
+	orders, ordersErr := client.GetOrders(session, mapping.ExternalAccountID)
+	if ordersErr != nil {
+		log.Printf("[Sync] Error fetching orders for account %s: %v (continuing without pending investments)", mapping.ExternalAccountID, ordersErr)
+	}
+
+	snapshot := &accountSnapshot{hasData: len(positions) > 0 || len(ledgers) > 0}
 	var positionsValue float64
 	var cashValue float64
 
-	// Upsert each position as a holding
+	// Convert each position to a holding
 	for _, pos := range positions {
-		holding := &models.Holding{
+		snapshot.holdings = append(snapshot.holdings, &models.Holding{
 			AccountID:      mapping.LocalAccountID,
 			ExternalID:     fmt.Sprintf("%d", pos.InstrumentID()),
 			Symbol:         pos.ISIN(),
@@ -182,15 +255,7 @@ func (s *Service) syncAccountPositions(client *nordnet.Client, session *nordnet.
 			Currency:       pos.Currency(),
 			InstrumentType: pos.InstrumentType(),
 			LastUpdated:    syncTime,
-		}
-
-		log.Printf("[Sync] Upserting holding: Symbol=%s, Name=%s, Qty=%.2f, Value=%.2f",
-			holding.Symbol, holding.Name, holding.Quantity, holding.CurrentValue)
-
-		if err := s.holdingRepo.Upsert(holding); err != nil {
-			log.Printf("[Sync] Error upserting holding %s: %v", holding.Symbol, err)
-			continue
-		}
+		})
 		positionsValue += pos.MarketValueAccValue()
 	}
 
@@ -200,45 +265,53 @@ func (s *Service) syncAccountPositions(client *nordnet.Client, session *nordnet.
 		cashValue += ledger.AccountSum.Value
 	}
 
-	log.Printf("[Sync] Account %s: Positions=%.2f, Cash=%.2f, Total=%.2f",
-		mapping.ExternalAccountID, positionsValue, cashValue, positionsValue+cashValue)
-
-	// Delete stale holdings (positions that no longer exist)
-	s.holdingRepo.DeleteStaleHoldings(mapping.LocalAccountID, syncTime)
+	// Savings plan buys are debited from cash days before they show up as
+	// positions; count them so the account value does not dip meanwhile
+	var pendingValue float64
+	if ordersErr == nil {
+		amount, count := nordnet.PendingSavingsPlanBuys(orders)
+		snapshot.pending = &pendingBuys{amount: amount, orders: count}
+		pendingValue = amount
+	}
 
-	// Total value = positions + cash
-	totalValue := positionsValue + cashValue
+	log.Printf("[Sync] Account %s: Positions=%.2f, Cash=%.2f, Pending=%.2f, Total=%.2f",
+		mapping.ExternalAccountID, positionsValue, cashValue, pendingValue, positionsValue+cashValue+pendingValue)
 
-	// Update account balance if it changed
-	currentBalance, _ := s.txnRepo.GetLatestBalance(mapping.LocalAccountID)
-	if totalValue != currentBalance && (len(positions) > 0 || len(ledgers) > 0) {
-		txn := &models.Transaction{
-			AccountID:       mapping.LocalAccountID,
-			Amount:          totalValue - currentBalance,
-			BalanceAfter:    totalValue,
-			Description:     "Nordnet sync",
-			TransactionDate: syncTime,
-		}
-		s.txnRepo.Create(txn)
-	}
+	// Total value = positions + cash + pending investments
+	snapshot.totalValue = positionsValue + cashValue + pendingValue
 
-	return len(positions), nil
+	return snapshot, nil
 }
 
-// createClient creates a broker client based on broker type.
-func (s *Service) createClient(brokerType, country string) (*nordnet.Client, error) {
-	switch brokerType {
+// createClient creates a broker client based on the connection's broker type.
+func (s *Service) createClient(conn *models.BrokerConnection) (*nordnet.Client, error) {
+	switch conn.BrokerType {
 	case "nordnet":
-		return nordnet.NewClient(country)
+		client, err := nordnet.NewClient(conn.Country)
+		if err != nil {
+			return nil, err
+		}
+		client.SetHTTPConfig(useHTTPConfig(conn))
+		return client, nil
 	default:
-		return nil, fmt.Errorf("unsupported broker type: %s", brokerType)
+		return nil, fmt.Errorf("unsupported broker type: %s", conn.BrokerType)
 	}
 }
 
+// useHTTPConfig applies the connection's proxy and User-Agent to its broker
+// requests, including those of login flows that only know its ID, and
+// returns the config in effect.
+func useHTTPConfig(conn *models.BrokerConnection) broker.HTTPConfig {
+	broker.SetConnectionHTTPConfig(conn.ID, broker.HTTPConfig{ProxyURL: conn.ProxyURL, UserAgent: conn.UserAgent})
+	return broker.HTTPConfigFor(conn.ID)
+}
+
 // failSync marks a sync as failed and updates connection status.
 func (s *Service) failSync(historyID, connectionID int64, errorMsg string) {
 	s.historyRepo.Fail(historyID, errorMsg)
+	s.finishTrail(historyID, true)
 	s.connRepo.UpdateSyncStatus(connectionID, "error", errorMsg)
+	s.notifySyncFailure(connectionID, errorMsg)
 }
 
 // ExternalAccount is a generic interface for broker accounts.
@@ -268,6 +341,10 @@ func (s *Service) GetExternalAccounts(connectionID int64) ([]ExternalAccount, er
 		return s.getNordnetExternalAccounts(connectionID, conn)
 	case "saxo":
 		return s.getSaxoExternalAccountsGeneric(connectionID)
+	case "psd2":
+		return s.getPSD2ExternalAccounts(conn)
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		return s.getCryptoExternalAccounts(conn)
 	default:
 		return nil, fmt.Errorf("unsupported broker type: %s", conn.BrokerType)
 	}
@@ -276,13 +353,13 @@ func (s *Service) GetExternalAccounts(connectionID int64) ([]ExternalAccount, er
 // getNordnetExternalAccounts fetches accounts from Nordnet using MitID.
 func (s *Service) getNordnetExternalAccounts(connectionID int64, conn *models.BrokerConnection) ([]ExternalAccount, error) {
 	// Create client
-	client, err := s.createClient(conn.BrokerType, conn.Country)
+	client, err := s.createClient(conn)
 	if err != nil {
 		return nil, fmt.Errorf("creating client: %w", err)
 	}
 
 	// Authenticate using MitID (user must approve in MitID app)
-	session, err := nordnet.AuthenticateWithMitIDNative(connectionID, conn.Country, conn.Username, conn.CPR, "APP", s.scriptDir)
+	session, err := s.authenticateNordnet(conn, "accounts")
 	if err != nil {
 		return nil, fmt.Errorf("MitID authentication failed: %w", err)
 	}
@@ -349,6 +426,12 @@ func (s *Service) TestConnection(brokerType, country, username, password string)
 	case "saxo":
 		// Saxo uses OAuth - can't test without user interaction
 		return nil
+	case "psd2":
+		// Banks need the account holder to authorize access first
+		return nil
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		// The API key is checked when the accounts are fetched for mapping
+		return nil
 	default:
 		return fmt.Errorf("unsupported broker type: %s", brokerType)
 	}
@@ -0,0 +1,187 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	stdsync "sync"
+	"time"
+
+	"wealth_tracker/internal/broker/crypto"
+	"wealth_tracker/internal/broker/nordnet"
+	"wealth_tracker/internal/broker/psd2"
+	"wealth_tracker/internal/broker/saxo"
+	"wealth_tracker/internal/models"
+)
+
+// DefaultSyncAllSpread is the period the syncs of a sync-all run are spread
+// over, so brokers are not hit by every connection at once.
+const DefaultSyncAllSpread = 10 * time.Minute
+
+// ErrSyncAllRunning is returned when a sync of all connections is started
+// while another one is still running.
+var ErrSyncAllRunning = errors.New("a sync of all connections is already running")
+
+// SyncAllStatus reports the progress of a sync of all connections.
+type SyncAllStatus struct {
+	StartedAt time.Time
+	Spread    time.Duration
+	Queued    int // Connections scheduled for a sync
+	Skipped   int // Active connections that need an interactive login
+	Deferred  int // Queued connections held back until their sync window
+	Succeeded int
+	Failed    int
+	Done      bool
+}
+
+// CanSyncUnattended reports whether a connection can be synced without its
+// owner: Nordnet connections with a cached session, Saxo connections with a
+// valid or refreshable token, and brokers that log in by themselves. With
+// SetSignIn, Nordnet and Saxo connections log in by themselves too.
+func (s *Service) CanSyncUnattended(conn *models.BrokerConnection) bool {
+	switch conn.BrokerType {
+	case "nordnet":
+		return s.signIn != nil || nordnet.GetCachedSession(conn.ID) != nil
+	case "saxo":
+		if s.signIn != nil {
+			return true
+		}
+		session := saxo.GetCachedSession(conn.ID)
+		return session != nil && (!session.NeedsRefresh() || session.CanRefresh())
+	case "psd2":
+		return psd2.GetConsent(conn.ID).IsValid(time.Now())
+	case crypto.Coinbase, crypto.Kraken, crypto.Binance:
+		return true
+	}
+	return false
+}
+
+// StartSyncAll schedules a sync of every active connection that can be
+// synced unattended, at jittered times spread over the given period, for use
+// after a server migration or downtime. Connections needing an interactive
+// login are skipped, and syncs falling outside a connection's sync window
+// wait for it to open. The returned channel is closed when all syncs are done.
+func (s *Service) StartSyncAll(spread time.Duration) (<-chan struct{}, error) {
+	s.syncAllMu.Lock()
+	if s.syncAll != nil && !s.syncAll.Done {
+		s.syncAllMu.Unlock()
+		return nil, ErrSyncAllRunning
+	}
+	status := &SyncAllStatus{StartedAt: time.Now(), Spread: spread}
+	s.syncAll = status
+	s.syncAllMu.Unlock()
+
+	conns, err := s.connRepo.GetAllActive()
+	if err != nil {
+		s.finishSyncAll(status)
+		return nil, fmt.Errorf("getting connections: %w", err)
+	}
+
+	var queued []*models.BrokerConnection
+	for _, conn := range conns {
+		if s.CanSyncUnattended(conn) {
+			queued = append(queued, conn)
+		}
+	}
+	rand.Shuffle(len(queued), func(i, j int) { queued[i], queued[j] = queued[j], queued[i] })
+
+	now := time.Now()
+	delays := syncAllDelays(len(queued), spread, rand.Float64)
+	deferred := 0
+	for i, conn := range queued {
+		if at := NextSyncWindow(conn, now.Add(delays[i])); at.After(now.Add(delays[i])) {
+			delays[i] = at.Sub(now)
+			deferred++
+		}
+	}
+
+	s.syncAllMu.Lock()
+	status.Queued = len(queued)
+	status.Skipped = len(conns) - len(queued)
+	status.Deferred = deferred
+	s.syncAllMu.Unlock()
+	log.Printf("[Sync All] Scheduled %d connection(s) over %v, %d deferred to their sync window, skipped %d needing a login",
+		len(queued), spread, deferred, len(conns)-len(queued))
+
+	done := make(chan struct{})
+	var wg stdsync.WaitGroup
+	for i, delay := range delays {
+		conn := queued[i]
+		wg.Add(1)
+		time.AfterFunc(delay, func() {
+			defer wg.Done()
+			s.runScheduledSync(conn, status)
+		})
+	}
+	go func() {
+		wg.Wait()
+		s.finishSyncAll(status)
+		close(done)
+	}()
+	return done, nil
+}
+
+// SyncAllStatus returns a copy of the status of the latest sync of all
+// connections, or nil if none has run.
+func (s *Service) SyncAllStatus() *SyncAllStatus {
+	s.syncAllMu.Lock()
+	defer s.syncAllMu.Unlock()
+	if s.syncAll == nil {
+		return nil
+	}
+	copied := *s.syncAll
+	return &copied
+}
+
+// runScheduledSync syncs a connection of a sync-all run. The session may have
+// expired while the sync waited, in which case it is skipped rather than
+// starting a login nobody will approve.
+func (s *Service) runScheduledSync(conn *models.BrokerConnection, status *SyncAllStatus) {
+	if !s.CanSyncUnattended(conn) {
+		log.Printf("[Sync All] Skipping connection %d: its session expired", conn.ID)
+		s.syncAllMu.Lock()
+		status.Queued--
+		status.Skipped++
+		s.syncAllMu.Unlock()
+		return
+	}
+
+	result, err := s.SyncConnection(conn.ID)
+	ok := err == nil && result != nil && result.Success
+	if !ok {
+		if err == nil && result != nil {
+			err = result.Error
+		}
+		log.Printf("[Sync All] Sync of connection %d failed: %v", conn.ID, err)
+	}
+
+	s.syncAllMu.Lock()
+	defer s.syncAllMu.Unlock()
+	if ok {
+		status.Succeeded++
+	} else {
+		status.Failed++
+	}
+}
+
+// finishSyncAll marks a sync-all run as done.
+func (s *Service) finishSyncAll(status *SyncAllStatus) {
+	s.syncAllMu.Lock()
+	defer s.syncAllMu.Unlock()
+	status.Done = true
+}
+
+// syncAllDelays returns the start delays of n syncs spread over a period:
+// each gets an equal slot and a random time within it.
+func syncAllDelays(n int, spread time.Duration, random func() float64) []time.Duration {
+	delays := make([]time.Duration, n)
+	if n == 0 || spread <= 0 {
+		return delays
+	}
+	slot := spread / time.Duration(n)
+	for i := range delays {
+		delays[i] = time.Duration(i)*slot + time.Duration(random()*float64(slot))
+	}
+	return delays
+}
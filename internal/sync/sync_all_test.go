@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/repository"
+)
+
+func TestStartSyncAll_SyncsUnattendedConnections(t *testing.T) {
+	svc, _, db, _, _ := setupMockSync(t)
+
+	// A Saxo connection without a cached session needs an OAuth login
+	var userID int64
+	if err := db.QueryRow(`SELECT id FROM users`).Scan(&userID); err != nil {
+		t.Fatalf("getting user: %v", err)
+	}
+	if _, err := repository.NewBrokerConnectionRepository(db).Create(&models.BrokerConnection{
+		UserID: userID, BrokerType: "saxo", Country: "dk", IsActive: true,
+	}); err != nil {
+		t.Fatalf("failed to create connection: %v", err)
+	}
+
+	done, err := svc.StartSyncAll(0)
+	if err != nil {
+		t.Fatalf("StartSyncAll() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync of all connections did not finish")
+	}
+
+	status := svc.SyncAllStatus()
+	if status == nil || !status.Done || status.Queued != 1 || status.Skipped != 1 || status.Succeeded != 1 || status.Failed != 0 {
+		t.Errorf("SyncAllStatus() = %+v; want 1 synced and 1 skipped", status)
+	}
+}
+
+func TestStartSyncAll_DefersSyncsToTheirWindow(t *testing.T) {
+	svc, _, db, connID, _ := setupMockSync(t)
+
+	// A window that opens two hours from now
+	connRepo := repository.NewBrokerConnectionRepository(db)
+	conn, err := connRepo.GetByID(connID)
+	if err != nil {
+		t.Fatalf("getting connection: %v", err)
+	}
+	hour := time.Now().In(MarketLocation(conn.Country)).Hour()
+	conn.SyncWindowStart, conn.SyncWindowEnd = (hour+2)%24, (hour+3)%24
+	if conn.SyncWindowEnd == 0 {
+		conn.SyncWindowEnd = 24
+	}
+	if err := connRepo.Update(conn); err != nil {
+		t.Fatalf("updating connection: %v", err)
+	}
+
+	done, err := svc.StartSyncAll(0)
+	if err != nil {
+		t.Fatalf("StartSyncAll() error = %v", err)
+	}
+	select {
+	case <-done:
+		t.Fatal("sync ran outside its sync window")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	status := svc.SyncAllStatus()
+	if status.Queued != 1 || status.Deferred != 1 || status.Succeeded != 0 {
+		t.Errorf("SyncAllStatus() = %+v; want 1 queued and deferred", status)
+	}
+}
+
+func TestStartSyncAll_RefusesWhileRunning(t *testing.T) {
+	svc, _, _, _, _ := setupMockSync(t)
+	svc.syncAll = &SyncAllStatus{StartedAt: time.Now()}
+
+	if _, err := svc.StartSyncAll(0); !errors.Is(err, ErrSyncAllRunning) {
+		t.Errorf("StartSyncAll() error = %v; want ErrSyncAllRunning", err)
+	}
+}
+
+func TestSyncAllDelays(t *testing.T) {
+	got := syncAllDelays(4, 8*time.Minute, func() float64 { return 0.5 })
+	want := []time.Duration{time.Minute, 3 * time.Minute, 5 * time.Minute, 7 * time.Minute}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("syncAllDelays()[%d] = %v; want %v", i, got[i], want[i])
+		}
+	}
+
+	for _, d := range syncAllDelays(3, 0, func() float64 { return 0.5 }) {
+		if d != 0 {
+			t.Errorf("syncAllDelays() without spread = %v; want 0", d)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package sync
+
+import (
+	"wealth_tracker/internal/models"
+	"wealth_tracker/internal/services"
+)
+
+// SetUsage counts syncs against their owners' monthly sync quota.
+func (s *Service) SetUsage(usage *services.UsageService) {
+	s.usage = usage
+}
+
+// useSyncQuota counts a sync of the connection, or refuses it once its
+// owner has used up this month's quota. The reason is shown on the
+// connection like a failed sync.
+func (s *Service) useSyncQuota(conn *models.BrokerConnection) error {
+	if s.usage == nil {
+		return nil
+	}
+	if err := s.usage.Use(conn.UserID, models.UsageSync); err != nil {
+		s.connRepo.UpdateSyncStatus(conn.ID, "error", "Sync skipped: "+err.Error())
+		return err
+	}
+	return nil
+}
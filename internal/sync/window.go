@@ -0,0 +1,74 @@
+package sync
+
+import (
+	"time"
+	_ "time/tzdata" // Market time zones work in containers without zoneinfo
+
+	"wealth_tracker/internal/market"
+	"wealth_tracker/internal/models"
+)
+
+// marketZones are the time zones of the broker markets, by connection
+// country. Sync windows are in the market's time zone, so "after market
+// close" means the same whatever the server's time zone is.
+var marketZones = map[string]string{
+	"dk": "Europe/Copenhagen",
+	"se": "Europe/Stockholm",
+	"no": "Europe/Oslo",
+	"fi": "Europe/Helsinki",
+}
+
+// MarketLocation returns the time zone of a connection's market. Unknown
+// countries use Danish time.
+func MarketLocation(country string) *time.Location {
+	name, ok := marketZones[country]
+	if !ok {
+		name = marketZones["dk"]
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// InSyncWindow reports whether an unattended sync of a connection may start
+// at t: within its preferred hours, and on a trading day of its market if it
+// skips weekends and holidays. A window ending before it starts runs
+// overnight, such as 22 to 6.
+func InSyncWindow(conn *models.BrokerConnection, t time.Time) bool {
+	local := t.In(MarketLocation(conn.Country))
+	if conn.SkipWeekends && !market.IsTradingDay(conn.Country, local) {
+		return false
+	}
+
+	start, end, hour := conn.SyncWindowStart, conn.SyncWindowEnd, local.Hour()
+	switch {
+	case start == end || (start == 0 && end >= 24):
+		return true
+	case start < end:
+		return hour >= start && hour < end
+	default:
+		return hour >= start || hour < end
+	}
+}
+
+// NextSyncWindow returns the first time from t at which an unattended sync
+// of a connection may start: t itself if it is within the connection's sync
+// window, otherwise the start of the next window hour.
+func NextSyncWindow(conn *models.BrokerConnection, t time.Time) time.Time {
+	if InSyncWindow(conn, t) {
+		return t
+	}
+
+	// Step through the whole hours of the coming two weeks, which covers
+	// every combination of hours, weekends and holidays
+	next := t.Truncate(time.Hour)
+	for range 15 * 24 {
+		next = next.Add(time.Hour)
+		if InSyncWindow(conn, next) {
+			return next
+		}
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"wealth_tracker/internal/models"
+)
+
+func TestInSyncWindow(t *testing.T) {
+	cph := MarketLocation("dk")
+	// Wednesday 15 May 2024 and the Saturday after
+	wednesday := func(hour int) time.Time { return time.Date(2024, 5, 15, hour, 30, 0, 0, cph) }
+	saturday := time.Date(2024, 5, 18, 20, 0, 0, 0, cph)
+
+	tests := []struct {
+		name string
+		conn models.BrokerConnection
+		at   time.Time
+		want bool
+	}{
+		{"unrestricted", models.BrokerConnection{SyncWindowEnd: 24}, wednesday(10), true},
+		{"after close", models.BrokerConnection{SyncWindowStart: 18, SyncWindowEnd: 24}, wednesday(19), true},
+		{"before close", models.BrokerConnection{SyncWindowStart: 18, SyncWindowEnd: 24}, wednesday(10), false},
+		{"overnight late", models.BrokerConnection{SyncWindowStart: 22, SyncWindowEnd: 6}, wednesday(23), true},
+		{"overnight early", models.BrokerConnection{SyncWindowStart: 22, SyncWindowEnd: 6}, wednesday(5), true},
+		{"overnight day", models.BrokerConnection{SyncWindowStart: 22, SyncWindowEnd: 6}, wednesday(12), false},
+		{"weekend allowed", models.BrokerConnection{SyncWindowEnd: 24}, saturday, true},
+		{"weekend skipped", models.BrokerConnection{SyncWindowEnd: 24, SkipWeekends: true}, saturday, false},
+		{"holiday skipped", models.BrokerConnection{Country: "dk", SyncWindowEnd: 24, SkipWeekends: true}, time.Date(2024, 12, 24, 10, 0, 0, 0, cph), false},
+		{"other market's holiday", models.BrokerConnection{Country: "se", SyncWindowEnd: 24, SkipWeekends: true}, time.Date(2024, 6, 5, 10, 0, 0, 0, cph), true},
+		// 17:30 UTC is 19:30 in Copenhagen summer time
+		{"market time zone", models.BrokerConnection{Country: "dk", SyncWindowStart: 18, SyncWindowEnd: 24}, time.Date(2024, 5, 15, 17, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InSyncWindow(&tt.conn, tt.at); got != tt.want {
+				t.Errorf("InSyncWindow() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextSyncWindow(t *testing.T) {
+	cph := MarketLocation("dk")
+	friday := time.Date(2024, 5, 17, 10, 15, 0, 0, cph)
+
+	conn := &models.BrokerConnection{SyncWindowStart: 18, SyncWindowEnd: 24}
+	if got, want := NextSyncWindow(conn, friday), time.Date(2024, 5, 17, 18, 0, 0, 0, cph); !got.Equal(want) {
+		t.Errorf("NextSyncWindow() = %v; want %v", got, want)
+	}
+
+	// Skipping weekends and holidays moves a Friday night sync past the
+	// window, the weekend and Whit Monday to Tuesday
+	conn = &models.BrokerConnection{SyncWindowStart: 0, SyncWindowEnd: 6, SkipWeekends: true}
+	if got, want := NextSyncWindow(conn, friday), time.Date(2024, 5, 21, 0, 0, 0, 0, cph); !got.Equal(want) {
+		t.Errorf("NextSyncWindow() with weekends skipped = %v; want %v", got, want)
+	}
+
+	inWindow := time.Date(2024, 5, 17, 19, 0, 0, 0, cph)
+	if got := NextSyncWindow(&models.BrokerConnection{SyncWindowStart: 18, SyncWindowEnd: 24}, inWindow); !got.Equal(inWindow) {
+		t.Errorf("NextSyncWindow() in the window = %v; want it unchanged", got)
+	}
+}
@@ -107,6 +107,160 @@ document.addEventListener('alpine:init', () => {
             this.formToSubmit = null;
         }
     });
+
+    // Quick-add transaction modal, opened from the sidebar or with the "n"
+    // key on any page
+    Alpine.store('quickAdd', {
+        visible: false,
+        accounts: [],
+        accountId: '',
+        amount: '',
+        date: '',
+        description: '',
+        anomaly: null,
+        saving: false,
+        error: '',
+
+        async open() {
+            this.reset();
+            this.visible = true;
+            try {
+                const response = await fetch('/api/transactions/quick-add/accounts');
+                if (!response.ok) throw new Error(await response.text());
+                this.accounts = await response.json();
+                if (this.accounts.length > 0) this.accountId = String(this.accounts[0].id);
+            } catch (e) {
+                this.error = 'Failed to load accounts';
+            }
+        },
+
+        close() {
+            this.visible = false;
+        },
+
+        reset() {
+            this.amount = '';
+            this.date = new Date().toISOString().slice(0, 10);
+            this.description = '';
+            this.anomaly = null;
+            this.error = '';
+        },
+
+        account() {
+            return this.accounts.find(a => String(a.id) === this.accountId);
+        },
+
+        // Save the transaction; a balance breaking the account's trend is
+        // shown for confirmation and saved on the second submit
+        async save() {
+            if (this.saving) return;
+            this.saving = true;
+            this.error = '';
+            try {
+                const response = await fetch('/api/transactions/quick-add', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        account_id: Number(this.accountId),
+                        amount: NumberFormat.parse(this.amount),
+                        date: this.date,
+                        description: this.description,
+                        confirm: this.anomaly !== null
+                    })
+                });
+                if (response.status === 409) {
+                    this.anomaly = (await response.json()).anomaly;
+                    return;
+                }
+                if (!response.ok) {
+                    this.error = (await response.text()).trim() || 'Failed to add transaction';
+                    return;
+                }
+                const result = await response.json();
+                this.close();
+                Alpine.store('toast').success('Added to ' + result.account_name + ', balance ' +
+                    NumberFormat.formatMoney(result.balance, this.account()?.currency) + ' ' + (this.account()?.currency || ''));
+            } catch (e) {
+                this.error = 'Failed to add transaction';
+            } finally {
+                this.saving = false;
+            }
+        }
+    });
+
+    // Command palette, opened with Ctrl+K or Cmd+K: jump to a page or an
+    // account, or run an action such as adding a transaction
+    Alpine.store('palette', {
+        visible: false,
+        commands: [],
+        query: '',
+        selected: 0,
+
+        async open() {
+            this.query = '';
+            this.selected = 0;
+            this.visible = true;
+            try {
+                const response = await fetch('/api/commands');
+                if (!response.ok) throw new Error(await response.text());
+                this.commands = await response.json();
+            } catch (e) {
+                Alpine.store('toast').error('Failed to load commands');
+            }
+        },
+
+        close() {
+            this.visible = false;
+        },
+
+        // Commands whose title, section or keywords contain every word of
+        // the query
+        results() {
+            const words = this.query.toLowerCase().split(/\s+/).filter(Boolean);
+            return this.commands.filter(c => {
+                const text = (c.title + ' ' + c.section + ' ' + (c.keywords || '')).toLowerCase();
+                return words.every(w => text.includes(w));
+            }).slice(0, 50);
+        },
+
+        move(delta) {
+            const count = this.results().length;
+            if (count === 0) return;
+            this.selected = (this.selected + delta + count) % count;
+            document.getElementById('palette-item-' + this.selected)?.scrollIntoView({ block: 'nearest' });
+        },
+
+        run(command) {
+            if (!command) return;
+            this.close();
+            if (command.action === 'quick-add') {
+                Alpine.store('quickAdd').open();
+            } else if (command.action === 'toggle-theme') {
+                Alpine.store('theme').toggle();
+            } else if (command.url) {
+                window.location.href = command.url;
+            }
+        }
+    });
+});
+
+// Open the quick-add modal with "n", unless typing in a field
+document.addEventListener('keydown', (e) => {
+    if (e.key !== 'n' || e.ctrlKey || e.metaKey || e.altKey) return;
+    if (document.body.dataset.authenticated !== 'true' || typeof Alpine === 'undefined') return;
+    const target = e.target;
+    if (target.isContentEditable || ['INPUT', 'TEXTAREA', 'SELECT'].includes(target.tagName)) return;
+    e.preventDefault();
+    Alpine.store('quickAdd').open();
+});
+
+// Open the command palette with Ctrl+K or Cmd+K, also while typing in a field
+document.addEventListener('keydown', (e) => {
+    if (e.key !== 'k' || !(e.ctrlKey || e.metaKey) || e.altKey) return;
+    if (document.body.dataset.authenticated !== 'true' || typeof Alpine === 'undefined') return;
+    e.preventDefault();
+    const palette = Alpine.store('palette');
+    palette.visible ? palette.close() : palette.open();
 });
 
 // Initialize theme immediately to prevent flash
@@ -124,6 +278,16 @@ document.addEventListener('htmx:afterSwap', (event) => {
     }
 });
 
+// Inline editors answer rejected saves with the editor and a message, sent
+// as 409 Conflict or 422 Unprocessable Entity; swap it in instead of erroring
+document.addEventListener('htmx:beforeSwap', (event) => {
+    const status = event.detail.xhr.status;
+    if (status === 409 || status === 422) {
+        event.detail.shouldSwap = true;
+        event.detail.isError = false;
+    }
+});
+
 document.addEventListener('htmx:responseError', (event) => {
     // Show error toast on failed requests
     const message = event.detail.xhr.responseText || 'An error occurred';
@@ -132,12 +296,44 @@ document.addEventListener('htmx:responseError', (event) => {
     }
 });
 
+// Start a broker fetch or sync and wait for it. The server runs broker work
+// in the background and answers the post with the URL of the task's status,
+// which is polled until the task is done. Resolves with a Response holding
+// the task's result as JSON, or its error as text, like a plain fetch would.
+async function runBrokerTask(url) {
+    const response = await fetch(url, { method: 'POST' });
+    if (response.status !== 202) {
+        return response;
+    }
+    const { task_url: taskUrl } = await response.json();
+
+    for (;;) {
+        await new Promise(resolve => setTimeout(resolve, 2000));
+        const status = await fetch(taskUrl);
+        if (!status.ok) {
+            return status;
+        }
+        const task = await status.json();
+        if (!task.done) {
+            continue;
+        }
+        if (task.error) {
+            return new Response(task.error, { status: 500 });
+        }
+        return new Response(JSON.stringify(task.result), {
+            status: 200,
+            headers: { 'Content-Type': 'application/json' }
+        });
+    }
+}
+
 // Format currency values
 function formatCurrency(amount, currency = 'DKK') {
+    const decimals = window.NumberFormat.moneyDecimals(currency);
     return new Intl.NumberFormat('da-DK', {
         style: 'decimal',
-        minimumFractionDigits: 2,
-        maximumFractionDigits: 2,
+        minimumFractionDigits: decimals,
+        maximumFractionDigits: decimals,
     }).format(amount) + ' ' + currency.toLowerCase() + '.';
 }
 
@@ -174,6 +370,25 @@ window.NumberFormat = {
         return (1000).toLocaleString(locale).charAt(1);
     },
 
+    // Currencies whose minor unit differs from 2 decimals (mirrors internal/money)
+    currencyPrecision: {
+        'CLP': 0, 'ISK': 0, 'JPY': 0, 'KRW': 0, 'PYG': 0, 'UGX': 0, 'VND': 0, 'XAF': 0, 'XOF': 0,
+        'BTC': 8, 'XBT': 8, 'ETH': 8, 'LTC': 8, 'BCH': 8, 'SOL': 8, 'ADA': 8, 'XRP': 8, 'DOGE': 8, 'DOT': 8
+    },
+
+    // Get number of decimals to show for a money amount, honouring the
+    // user's "hide decimals" setting
+    moneyDecimals(currency) {
+        if (document.body.dataset.hideDecimals === 'true') return 0;
+        const precision = this.currencyPrecision[(currency || '').toUpperCase()];
+        return precision === undefined ? 2 : precision;
+    },
+
+    // Format a money amount with the precision of its currency
+    formatMoney(value, currency) {
+        return this.format(value, this.moneyDecimals(currency));
+    },
+
     // Format number with thousand separators
     format(value, decimals = 0) {
         const locale = this.getLocale();
@@ -309,6 +524,39 @@ function formatDate(dateStr) {
     });
 }
 
+// Short confetti burst for celebrations such as net worth milestones.
+// Respects the user's reduced motion preference.
+function celebrate(pieces = 120) {
+    if (window.matchMedia('(prefers-reduced-motion: reduce)').matches) return;
+
+    const colors = ['#f59e0b', '#10b981', '#6366f1', '#ec4899', '#3b82f6'];
+    for (let i = 0; i < pieces; i++) {
+        const piece = document.createElement('div');
+        const size = 6 + Math.random() * 6;
+        Object.assign(piece.style, {
+            position: 'fixed',
+            top: '-20px',
+            left: Math.random() * 100 + 'vw',
+            width: size + 'px',
+            height: size * 0.4 + 'px',
+            background: colors[i % colors.length],
+            zIndex: 9999,
+            pointerEvents: 'none',
+        });
+        document.body.appendChild(piece);
+
+        const drift = (Math.random() - 0.5) * 200;
+        piece.animate([
+            { transform: 'translate(0, 0) rotate(0deg)', opacity: 1 },
+            { transform: `translate(${drift}px, 105vh) rotate(${Math.random() * 720}deg)`, opacity: 0.8 }
+        ], {
+            duration: 2500 + Math.random() * 1500,
+            delay: Math.random() * 400,
+            easing: 'cubic-bezier(0.25, 0.5, 0.5, 1)',
+        }).onfinish = () => piece.remove();
+    }
+}
+
 // Chart.js default configuration for dark mode
 if (typeof Chart !== 'undefined') {
     const isDark = () => document.documentElement.classList.contains('dark');